@@ -0,0 +1,266 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package acsclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cihub/seelog"
+)
+
+// ResponseQueue is a bounded queue of the responses to ACS that couldn't be sent because the
+// websocket was disconnected. It outlives the connections of a session, and its responses are
+// persisted in the data client so that they are replayed after the agent restarts too. Its
+// responses are replayed in the order they were queued once the agent reconnects. Each response
+// is given a local sequence number that keeps that order across restarts; the ACS messages have
+// no field to carry it, so responses are deduplicated by their type and message id instead, and a
+// response that's retried while queued is only replayed once.
+type ResponseQueue struct {
+	lock       sync.Mutex
+	maxSize    int
+	sequence   uint64
+	responses  []queuedResponse
+	dataClient data.Client
+}
+
+// queuedResponse is a response waiting to be replayed
+type queuedResponse struct {
+	sequence uint64
+	key      string
+	message  interface{}
+}
+
+// persistedResponse is the form in which a queued response is saved in the data client
+type persistedResponse struct {
+	Sequence uint64
+	Message  json.RawMessage
+}
+
+// NewResponseQueue creates a queue that holds up to maxSize responses, the oldest responses are
+// dropped once it's full. The responses that were persisted in the data client by a previous run
+// of the agent are loaded into the queue. The responses aren't persisted without a data client.
+func NewResponseQueue(maxSize int, dataClient data.Client) *ResponseQueue {
+	if dataClient == nil {
+		dataClient = data.NewNoopClient()
+	}
+	queue := &ResponseQueue{
+		maxSize:    maxSize,
+		dataClient: dataClient,
+	}
+	if err := queue.load(); err != nil {
+		seelog.Errorf("Unable to load the queued ACS responses: %v", err)
+	}
+	return queue
+}
+
+// load adds the persisted responses to the queue, in the order they were queued
+func (queue *ResponseQueue) load() error {
+	saved, err := queue.dataClient.GetACSResponses()
+	if err != nil {
+		return err
+	}
+	for key, data := range saved {
+		response, err := decodeResponse(key, data)
+		if err != nil {
+			seelog.Warnf("Dropping queued ACS response %s that can't be decoded: %v", key, err)
+			queue.deleteResponse(key)
+			continue
+		}
+		queue.responses = append(queue.responses, response)
+		if response.sequence > queue.sequence {
+			queue.sequence = response.sequence
+		}
+	}
+	sort.Slice(queue.responses, func(i, j int) bool {
+		return queue.responses[i].sequence < queue.responses[j].sequence
+	})
+	for len(queue.responses) > queue.maxSize {
+		queue.deleteResponse(queue.responses[0].key)
+		queue.responses = queue.responses[1:]
+	}
+	if len(queue.responses) > 0 {
+		seelog.Infof("Loaded %d queued ACS responses", len(queue.responses))
+	}
+	return nil
+}
+
+// decodeResponse decodes a persisted response, its message type is the prefix of its key
+func decodeResponse(key string, data []byte) (queuedResponse, error) {
+	var persisted persistedResponse
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return queuedResponse{}, err
+	}
+	var message interface{}
+	switch strings.SplitN(key, "/", 2)[0] {
+	case "AckRequest":
+		message = &ecsacs.AckRequest{}
+	case "NackRequest":
+		message = &ecsacs.NackRequest{}
+	case "TaskStopVerificationMessage":
+		message = &ecsacs.TaskStopVerificationMessage{}
+	default:
+		return queuedResponse{}, fmt.Errorf("unknown response type in key %s", key)
+	}
+	if err := json.Unmarshal(persisted.Message, message); err != nil {
+		return queuedResponse{}, err
+	}
+	return queuedResponse{
+		sequence: persisted.Sequence,
+		key:      key,
+		message:  message,
+	}, nil
+}
+
+// saveResponse persists a queued response. A response that can't be persisted stays queued,
+// it's only lost if the agent restarts before it's replayed.
+func (queue *ResponseQueue) saveResponse(response queuedResponse) {
+	message, err := json.Marshal(response.message)
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(persistedResponse{
+			Sequence: response.sequence,
+			Message:  message,
+		})
+		if err == nil {
+			err = queue.dataClient.SaveACSResponse(response.key, data)
+		}
+	}
+	if err != nil {
+		seelog.Errorf("Unable to save queued ACS response %s: %v", response.key, err)
+	}
+}
+
+// deleteResponse removes a response that's no longer queued from the data client
+func (queue *ResponseQueue) deleteResponse(key string) {
+	if err := queue.dataClient.DeleteACSResponse(key); err != nil {
+		seelog.Errorf("Unable to delete queued ACS response %s: %v", key, err)
+	}
+}
+
+// Len returns the number of responses waiting to be replayed
+func (queue *ResponseQueue) Len() int {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	return len(queue.responses)
+}
+
+// enqueue adds a response to the queue, unless a response with the same key is already queued
+func (queue *ResponseQueue) enqueue(key string, message interface{}) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	for _, response := range queue.responses {
+		if response.key == key {
+			return
+		}
+	}
+	if len(queue.responses) >= queue.maxSize {
+		seelog.Warnf("ACS response queue is full, dropping response %s", queue.responses[0].key)
+		queue.deleteResponse(queue.responses[0].key)
+		queue.responses = queue.responses[1:]
+	}
+	queue.sequence++
+	response := queuedResponse{
+		sequence: queue.sequence,
+		key:      key,
+		message:  message,
+	}
+	queue.saveResponse(response)
+	queue.responses = append(queue.responses, response)
+}
+
+// remove removes a replayed response from the queue
+func (queue *ResponseQueue) remove(sequence uint64) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	for i, response := range queue.responses {
+		if response.sequence == sequence {
+			queue.deleteResponse(response.key)
+			queue.responses = append(queue.responses[:i], queue.responses[i+1:]...)
+			return
+		}
+	}
+}
+
+// Replay sends the queued responses in order over the connection of the client. It stops at the
+// first response that can't be sent, which remains queued along with the ones after it.
+func (queue *ResponseQueue) Replay(client wsclient.ClientServer) error {
+	queue.lock.Lock()
+	responses := make([]queuedResponse, len(queue.responses))
+	copy(responses, queue.responses)
+	queue.lock.Unlock()
+
+	for _, response := range responses {
+		if err := client.MakeRequest(response.message); err != nil {
+			return fmt.Errorf("unable to replay response %s: %v", response.key, err)
+		}
+		seelog.Infof("Replayed queued ACS response %s", response.key)
+		queue.remove(response.sequence)
+	}
+	return nil
+}
+
+// responseQueueKey returns the deduplication key of the responses that are queued when they can't
+// be sent. Other messages are only meaningful on the connection they are sent on.
+func responseQueueKey(message interface{}) (string, bool) {
+	switch msg := message.(type) {
+	case *ecsacs.AckRequest:
+		return "AckRequest/" + aws.StringValue(msg.MessageId), true
+	case *ecsacs.NackRequest:
+		return "NackRequest/" + aws.StringValue(msg.MessageId), true
+	case *ecsacs.TaskStopVerificationMessage:
+		return "TaskStopVerificationMessage/" + aws.StringValue(msg.MessageId), true
+	}
+	return "", false
+}
+
+// queuedClientServer queues the responses that fail to be sent over the wrapped client
+type queuedClientServer struct {
+	wsclient.ClientServer
+	queue *ResponseQueue
+}
+
+// NewQueuedClientServer wraps the client so that the acks, nacks and task manifest responses that can't
+// be sent are added to the queue, to be replayed after reconnecting
+func NewQueuedClientServer(client wsclient.ClientServer, queue *ResponseQueue) wsclient.ClientServer {
+	return &queuedClientServer{
+		ClientServer: client,
+		queue:        queue,
+	}
+}
+
+// MakeRequest sends the message, and queues it if it can't be sent. The error is returned even
+// if the message was queued, as it hasn't been delivered yet.
+func (cs *queuedClientServer) MakeRequest(input interface{}) error {
+	err := cs.ClientServer.MakeRequest(input)
+	if err == nil {
+		return nil
+	}
+	key, ok := responseQueueKey(input)
+	if !ok {
+		return err
+	}
+	seelog.Infof("Unable to send ACS response %s, queueing it to be replayed after reconnecting: %v", key, err)
+	cs.queue.enqueue(key, input)
+	return err
+}
@@ -0,0 +1,137 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package acsclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/data"
+	mock_wsclient "github.com/aws/amazon-ecs-agent/agent/wsclient/mock"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueuedClientServerQueuesFailedResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockWsClient := mock_wsclient.NewMockClientServer(ctrl)
+	queue := NewResponseQueue(10, data.NewNoopClient())
+	client := NewQueuedClientServer(mockWsClient, queue)
+
+	sentAck := &ecsacs.AckRequest{MessageId: aws.String("sent")}
+	failedAck := &ecsacs.AckRequest{MessageId: aws.String("failed")}
+	stopVerification := &ecsacs.TaskStopVerificationMessage{MessageId: aws.String("failed")}
+	heartbeatAck := &ecsacs.HeartbeatAckRequest{MessageId: aws.String("failed")}
+	connErr := errors.New("websocket is not connected")
+	mockWsClient.EXPECT().MakeRequest(sentAck).Return(nil)
+	mockWsClient.EXPECT().MakeRequest(failedAck).Return(connErr).Times(2)
+	mockWsClient.EXPECT().MakeRequest(stopVerification).Return(connErr)
+	mockWsClient.EXPECT().MakeRequest(heartbeatAck).Return(connErr)
+
+	assert.NoError(t, client.MakeRequest(sentAck))
+	assert.Equal(t, connErr, client.MakeRequest(failedAck), "queued responses are still reported as failed")
+	assert.Equal(t, connErr, client.MakeRequest(failedAck))
+	assert.Equal(t, connErr, client.MakeRequest(stopVerification))
+	assert.Equal(t, connErr, client.MakeRequest(heartbeatAck), "heartbeat acks are not queued")
+	assert.Equal(t, 2, queue.Len(), "retried responses should only be queued once")
+}
+
+func TestResponseQueueReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockWsClient := mock_wsclient.NewMockClientServer(ctrl)
+	queue := NewResponseQueue(10, data.NewNoopClient())
+	first := &ecsacs.AckRequest{MessageId: aws.String("first")}
+	second := &ecsacs.TaskStopVerificationMessage{MessageId: aws.String("second")}
+	third := &ecsacs.AckRequest{MessageId: aws.String("third")}
+	for _, message := range []interface{}{first, second, third} {
+		key, ok := responseQueueKey(message)
+		require.True(t, ok)
+		queue.enqueue(key, message)
+	}
+
+	gomock.InOrder(
+		mockWsClient.EXPECT().MakeRequest(first).Return(nil),
+		mockWsClient.EXPECT().MakeRequest(second).Return(errors.New("connection reset")),
+	)
+	assert.Error(t, queue.Replay(mockWsClient))
+	assert.Equal(t, 2, queue.Len(), "responses that weren't replayed should remain queued")
+
+	gomock.InOrder(
+		mockWsClient.EXPECT().MakeRequest(second).Return(nil),
+		mockWsClient.EXPECT().MakeRequest(third).Return(nil),
+	)
+	assert.NoError(t, queue.Replay(mockWsClient))
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestResponseQueueDropsOldestResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockWsClient := mock_wsclient.NewMockClientServer(ctrl)
+	queue := NewResponseQueue(2, data.NewNoopClient())
+	for _, id := range []string{"first", "second", "third"} {
+		queue.enqueue("AckRequest/"+id, &ecsacs.AckRequest{MessageId: aws.String(id)})
+	}
+	assert.Equal(t, 2, queue.Len())
+
+	gomock.InOrder(
+		mockWsClient.EXPECT().MakeRequest(&ecsacs.AckRequest{MessageId: aws.String("second")}).Return(nil),
+		mockWsClient.EXPECT().MakeRequest(&ecsacs.AckRequest{MessageId: aws.String("third")}).Return(nil),
+	)
+	assert.NoError(t, queue.Replay(mockWsClient))
+}
+
+func TestResponseQueuePersistsResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockWsClient := mock_wsclient.NewMockClientServer(ctrl)
+	dataClient, err := data.NewWithSetup(t.TempDir())
+	require.NoError(t, err)
+	defer dataClient.Close()
+
+	queue := NewResponseQueue(10, dataClient)
+	first := &ecsacs.NackRequest{MessageId: aws.String("first"), Reason: aws.String("reason")}
+	second := &ecsacs.AckRequest{MessageId: aws.String("second"), Cluster: aws.String("cluster")}
+	for _, message := range []interface{}{first, second} {
+		key, ok := responseQueueKey(message)
+		require.True(t, ok)
+		queue.enqueue(key, message)
+	}
+
+	// The queue of a new run of the agent replays the persisted responses in order.
+	restoredQueue := NewResponseQueue(10, dataClient)
+	assert.Equal(t, 2, restoredQueue.Len())
+	gomock.InOrder(
+		mockWsClient.EXPECT().MakeRequest(first).Return(nil),
+		mockWsClient.EXPECT().MakeRequest(second).Return(nil),
+	)
+	assert.NoError(t, restoredQueue.Replay(mockWsClient))
+
+	responses, err := dataClient.GetACSResponses()
+	require.NoError(t, err)
+	assert.Empty(t, responses, "replayed responses should be deleted")
+}
+
+func TestResponseQueueWithoutDataClient(t *testing.T) {
+	queue := NewResponseQueue(10, nil)
+	queue.enqueue("AckRequest/id", &ecsacs.AckRequest{MessageId: aws.String("id")})
+	assert.Equal(t, 1, queue.Len())
+}
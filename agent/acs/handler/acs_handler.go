@@ -68,11 +68,17 @@ const (
 	// 1: default protocol version
 	// 2: ACS will proactively close the connection when heartbeat acks are missing
 	acsProtocolVersion = 2
+	// maxQueuedResponses is the number of acks and task manifest responses that are kept to be
+	// replayed when they can't be sent because the connection to ACS was lost
+	maxQueuedResponses = 100
 )
 
 // Session defines an interface for handler's long-lived connection with ACS.
 type Session interface {
 	Start() error
+	// Reconnect closes the connection to ACS and connects again right away, requesting
+	// the credentials of all the tasks
+	Reconnect()
 }
 
 // session encapsulates all arguments needed by the handler to connect to ACS
@@ -94,6 +100,8 @@ type session struct {
 	backoff                         retry.Backoff
 	resources                       sessionResources
 	latestSeqNumTaskManifest        *int64
+	refreshCredentials              chan struct{}
+	responseQueue                   *acsclient.ResponseQueue
 	_heartbeatTimeout               time.Duration
 	_heartbeatJitter                time.Duration
 	_inactiveInstanceReconnectDelay time.Duration
@@ -134,6 +142,9 @@ type sessionState interface {
 	// getSendCredentialsURLParameter retrieves the value for
 	// the 'sendCredentials' URL parameter
 	getSendCredentialsURLParameter() string
+	// requestCredentials indicates that ACS should send credentials for
+	// all tasks on the next connection
+	requestCredentials()
 }
 
 // NewSession creates a new Session object
@@ -169,6 +180,8 @@ func NewSession(ctx context.Context,
 		backoff:                         backoff,
 		resources:                       resources,
 		latestSeqNumTaskManifest:        latestSeqNumTaskManifest,
+		refreshCredentials:              make(chan struct{}, 1),
+		responseQueue:                   acsclient.NewResponseQueue(maxQueuedResponses, dataClient),
 		_heartbeatTimeout:               heartbeatTimeout,
 		_heartbeatJitter:                heartbeatJitter,
 		_inactiveInstanceReconnectDelay: inactiveInstanceReconnectDelay,
@@ -191,6 +204,10 @@ func (acsSession *session) Start() error {
 	go func() {
 		connectToACS <- struct{}{}
 	}()
+	// Request credentials from ACS when task credentials are about to expire
+	credentialsExpiryMonitor := newCredentialsExpiryMonitor(acsSession.credentialsManager, acsSession.taskEngine,
+		acsSession.agentConfig.TaskCredentialsRefreshWindow, acsSession.refreshCredentials)
+	go credentialsExpiryMonitor.start(acsSession.ctx)
 	for {
 		select {
 		case <-connectToACS:
@@ -269,6 +286,13 @@ func (acsSession *session) startSessionOnce() error {
 func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 	cfg := acsSession.agentConfig
 
+	// Attachment acks and task manifest responses that can't be sent are queued, and replayed
+	// once the agent reconnects to ACS
+	responseClient := client
+	if acsSession.responseQueue != nil {
+		responseClient = acsclient.NewQueuedClientServer(client, acsSession.responseQueue)
+	}
+
 	refreshCredsHandler := newRefreshCredentialsHandler(acsSession.ctx, cfg.Cluster, acsSession.containerInstanceARN,
 		client, acsSession.credentialsManager, acsSession.taskEngine)
 	defer refreshCredsHandler.clearAcks()
@@ -282,7 +306,7 @@ func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 		acsSession.ctx,
 		cfg.Cluster,
 		acsSession.containerInstanceARN,
-		client,
+		responseClient,
 		acsSession.state,
 		acsSession.dataClient,
 	)
@@ -296,7 +320,7 @@ func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 		acsSession.ctx,
 		cfg.Cluster,
 		acsSession.containerInstanceARN,
-		client,
+		responseClient,
 		acsSession.state,
 		acsSession.dataClient,
 	)
@@ -307,7 +331,7 @@ func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 
 	// Add TaskManifestHandler
 	taskManifestHandler := newTaskManifestHandler(acsSession.ctx, cfg.Cluster, acsSession.containerInstanceARN,
-		client, acsSession.dataClient, acsSession.taskEngine, acsSession.latestSeqNumTaskManifest)
+		responseClient, acsSession.dataClient, acsSession.taskEngine, acsSession.latestSeqNumTaskManifest)
 
 	defer taskManifestHandler.clearAcks()
 	taskManifestHandler.start()
@@ -316,6 +340,16 @@ func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 	client.AddRequestHandler(taskManifestHandler.handlerFuncTaskManifestMessage())
 	client.AddRequestHandler(taskManifestHandler.handlerFuncTaskStopVerificationMessage())
 
+	// Add TaskUpdateHandler if the task engine can update tasks in place
+	if engineUpdater, ok := acsSession.taskEngine.(taskUpdater); ok {
+		taskUpdateHandler := newTaskUpdateHandler(acsSession.ctx, cfg.Cluster, acsSession.containerInstanceARN,
+			responseClient, engineUpdater)
+		taskUpdateHandler.start()
+		defer taskUpdateHandler.stop()
+
+		client.AddRequestHandler(taskUpdateHandler.handlerFunc())
+	}
+
 	// Add request handler for handling payload messages from ACS
 	payloadHandler := newPayloadRequestHandler(
 		acsSession.ctx,
@@ -360,6 +394,12 @@ func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 
 	acsSession.resources.connectedToACS()
 
+	if acsSession.responseQueue != nil && acsSession.responseQueue.Len() > 0 {
+		if err := acsSession.responseQueue.Replay(client); err != nil {
+			seelog.Warnf("Unable to replay queued responses to ACS: %v", err)
+		}
+	}
+
 	backoffResetTimer := time.AfterFunc(
 		retry.AddJitter(acsSession.heartbeatTimeout(), acsSession.heartbeatJitter()), func() {
 			// If we do not have an error connecting and remain connected for at
@@ -392,10 +432,27 @@ func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 				seelog.Errorf("Error: lost websocket connection with Agent Communication Service (ACS): %v", err)
 			}
 			return err
+		case <-acsSession.refreshCredentials:
+			// Reconnect to ACS with the 'sendCredentials' URL parameter set, so that
+			// ACS sends the credentials of all the tasks again
+			seelog.Info("Reconnecting to ACS to request task IAM role credentials")
+			acsSession.resources.requestCredentials()
+			return nil
 		}
 	}
 }
 
+// Reconnect closes the connection to ACS and connects again right away, requesting
+// the credentials of all the tasks. It's used when the connection is known to be
+// stale, such as after the instance resumed from a hibernation.
+func (acsSession *session) Reconnect() {
+	select {
+	case acsSession.refreshCredentials <- struct{}{}:
+	default:
+		// A reconnection is already pending
+	}
+}
+
 func (acsSession *session) computeReconnectDelay(isInactiveInstance bool) time.Duration {
 	if isInactiveInstance {
 		return acsSession._inactiveInstanceReconnectDelay
@@ -436,6 +493,12 @@ func (acsResources *acsSessionResources) connectedToACS() {
 	acsResources.sendCredentials = false
 }
 
+// requestCredentials sets sendCredentials to true, so that ACS sends credentials
+// for all tasks on the next connection
+func (acsResources *acsSessionResources) requestCredentials() {
+	acsResources.sendCredentials = true
+}
+
 // getSendCredentialsURLParameter gets the value to be set for the
 // 'sendCredentials' URL parameter
 func (acsResources *acsSessionResources) getSendCredentialsURLParameter() string {
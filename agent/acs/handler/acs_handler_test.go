@@ -31,6 +31,8 @@ import (
 
 	"context"
 
+	acsclient "github.com/aws/amazon-ecs-agent/agent/acs/client"
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	mock_api "github.com/aws/amazon-ecs-agent/agent/api/mocks"
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
@@ -53,6 +55,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/golang/mock/gomock"
 )
@@ -159,6 +162,9 @@ func (m *mockSessionResources) getSendCredentialsURLParameter() string {
 	return "true"
 }
 
+func (m *mockSessionResources) requestCredentials() {
+}
+
 // TestACSWSURL tests if the URL is constructed correctly when connecting to ACS
 func TestACSWSURL(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -998,6 +1004,17 @@ func TestACSSessionResourcesCorrectlySetsSendCredentials(t *testing.T) {
 	}
 }
 
+// TestSessionReconnect tests that Reconnect requests a single reconnection to ACS
+// with the credentials of the tasks, however many times it's called
+func TestSessionReconnect(t *testing.T) {
+	acsSession := &session{
+		refreshCredentials: make(chan struct{}, 1),
+	}
+	acsSession.Reconnect()
+	acsSession.Reconnect()
+	assert.Len(t, acsSession.refreshCredentials, 1)
+}
+
 // TestHandlerReconnectsCorrectlySetsSendCredentialsURLParameter tests if
 // the 'sendCredentials' URL parameter is set correctly for successive
 // invocations of startACSSession
@@ -1147,3 +1164,50 @@ func validateSendCredentialsInSession(t *testing.T, state sessionState, expected
 		t.Errorf("Incorrect value set for sendCredentials, expected: %s, got: %s", expected, sendCredentials)
 	}
 }
+
+// TestHandlerReplaysQueuedResponsesOnConnect tests that the responses that couldn't be sent
+// over a previous connection are replayed once the session connects to ACS again
+func TestHandlerReplaysQueuedResponsesOnConnect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	taskEngine.EXPECT().Version().Return("Docker: 1.5.0", nil).AnyTimes()
+
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	ctx, cancel := context.WithCancel(context.Background())
+	taskHandler := eventhandler.NewTaskHandler(ctx, data.NewNoopClient(), nil, nil)
+	defer cancel()
+
+	ack := &ecsacs.AckRequest{MessageId: aws.String("attachmentMessageId")}
+	responseQueue := acsclient.NewResponseQueue(maxQueuedResponses, data.NewNoopClient())
+	disconnectedClient := mock_wsclient.NewMockClientServer(ctrl)
+	disconnectedClient.EXPECT().MakeRequest(ack).Return(errors.New("websocket is not connected"))
+	require.Error(t, acsclient.NewQueuedClientServer(disconnectedClient, responseQueue).MakeRequest(ack))
+	require.Equal(t, 1, responseQueue.Len())
+
+	mockWsClient := mock_wsclient.NewMockClientServer(ctrl)
+	mockWsClient.EXPECT().SetAnyRequestHandler(gomock.Any()).AnyTimes()
+	mockWsClient.EXPECT().AddRequestHandler(gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockWsClient.EXPECT().Connect().Return(nil),
+		mockWsClient.EXPECT().MakeRequest(ack).Return(nil),
+		mockWsClient.EXPECT().Serve().Return(io.EOF),
+	)
+	acsSession := session{
+		containerInstanceARN: "myArn",
+		credentialsProvider:  testCreds,
+		agentConfig:          testConfig,
+		taskEngine:           taskEngine,
+		ecsClient:            ecsClient,
+		dataClient:           data.NewNoopClient(),
+		taskHandler:          taskHandler,
+		ctx:                  ctx,
+		backoff:              retry.NewExponentialBackoff(connectionBackoffMin, connectionBackoffMax, connectionBackoffJitter, connectionBackoffMultiplier),
+		resources:            &mockSessionResources{},
+		responseQueue:        responseQueue,
+		_heartbeatTimeout:    20 * time.Millisecond,
+		_heartbeatJitter:     10 * time.Millisecond,
+	}
+	assert.Equal(t, io.EOF, acsSession.startACSSession(mockWsClient))
+	assert.Equal(t, 0, responseQueue.Len())
+}
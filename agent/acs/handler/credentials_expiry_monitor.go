@@ -0,0 +1,133 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"time"
+
+	rolecredentials "github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// credentialsExpiryCheckInterval is how often the expiry of task credentials is checked
+	credentialsExpiryCheckInterval = 1 * time.Minute
+	// credentialsRefreshRequestInterval is the minimum time between two requests for
+	// credentials, so that the agent does not keep reconnecting to ACS when ACS does
+	// not send new credentials for a task
+	credentialsRefreshRequestInterval = 5 * time.Minute
+)
+
+// credentialsExpiryMonitor tracks the time to expiry of the IAM role credentials of
+// running tasks. It requests credentials from ACS when some of them are about to
+// expire, and reports the tasks running with expired credentials.
+type credentialsExpiryMonitor struct {
+	credentialsManager rolecredentials.Manager
+	taskEngine         engine.TaskEngine
+	// refreshWindow is how long before their expiry credentials are requested
+	refreshWindow time.Duration
+	// refreshCredentials is used to request credentials from ACS
+	refreshCredentials chan<- struct{}
+	lastRefreshRequest time.Time
+}
+
+// newCredentialsExpiryMonitor returns a new credentialsExpiryMonitor object
+func newCredentialsExpiryMonitor(credentialsManager rolecredentials.Manager, taskEngine engine.TaskEngine,
+	refreshWindow time.Duration, refreshCredentials chan<- struct{}) *credentialsExpiryMonitor {
+	return &credentialsExpiryMonitor{
+		credentialsManager: credentialsManager,
+		taskEngine:         taskEngine,
+		refreshWindow:      refreshWindow,
+		refreshCredentials: refreshCredentials,
+	}
+}
+
+// start checks the expiry of task credentials periodically, until the context is cancelled
+func (monitor *credentialsExpiryMonitor) start(ctx context.Context) {
+	ticker := time.NewTicker(credentialsExpiryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			monitor.checkExpiry(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkExpiry records the time to expiry of the current credentials of running tasks,
+// and requests credentials from ACS if some of them expire within the refresh window.
+// It returns true if credentials were requested.
+func (monitor *credentialsExpiryMonitor) checkExpiry(now time.Time) bool {
+	expired := map[string]int{
+		rolecredentials.ApplicationRoleType: 0,
+		rolecredentials.ExecutionRoleType:   0,
+	}
+	minTimeToExpiry := make(map[string]time.Duration)
+	for _, expiry := range monitor.credentialsManager.GetCredentialsExpiry() {
+		task, ok := monitor.taskEngine.GetTaskByArn(expiry.TaskARN)
+		if !ok || task.GetDesiredStatus().Terminal() {
+			continue
+		}
+		// Skip credentials that were replaced by newer ones for the task
+		if (expiry.RoleType == rolecredentials.ApplicationRoleType && expiry.CredentialsID != task.GetCredentialsID()) ||
+			(expiry.RoleType == rolecredentials.ExecutionRoleType && expiry.CredentialsID != task.GetExecutionCredentialsID()) {
+			continue
+		}
+
+		timeToExpiry := expiry.Expiration.Sub(now)
+		if min, ok := minTimeToExpiry[expiry.RoleType]; !ok || timeToExpiry < min {
+			minTimeToExpiry[expiry.RoleType] = timeToExpiry
+		}
+		if timeToExpiry <= 0 {
+			expired[expiry.RoleType]++
+			seelog.Warnf("Task is running with expired IAM role credentials: task: %s, credentials ID: %s, role type: %s, expired: %s ago",
+				expiry.TaskARN, expiry.CredentialsID, expiry.RoleType, -timeToExpiry)
+		}
+	}
+
+	for roleType, count := range expired {
+		secondsToExpiry := 0.0
+		if timeToExpiry, ok := minTimeToExpiry[roleType]; ok {
+			secondsToExpiry = timeToExpiry.Seconds()
+		}
+		metrics.MetricsEngineGlobal.SetTaskCredentialsExpiry(roleType, count, secondsToExpiry)
+	}
+
+	var expiringRoleTypes []string
+	for roleType, timeToExpiry := range minTimeToExpiry {
+		if timeToExpiry < monitor.refreshWindow {
+			expiringRoleTypes = append(expiringRoleTypes, roleType)
+		}
+	}
+	if len(expiringRoleTypes) == 0 || now.Sub(monitor.lastRefreshRequest) < credentialsRefreshRequestInterval {
+		return false
+	}
+	select {
+	case monitor.refreshCredentials <- struct{}{}:
+	default:
+		// A request for credentials is already pending
+		return false
+	}
+	monitor.lastRefreshRequest = now
+	for _, roleType := range expiringRoleTypes {
+		metrics.MetricsEngineGlobal.RecordTaskCredentialsRefreshRequest(roleType)
+	}
+	seelog.Infof("Task IAM role credentials expire within %s, requesting credentials from ACS", monitor.refreshWindow)
+	return true
+}
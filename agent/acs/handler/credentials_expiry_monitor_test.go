@@ -0,0 +1,124 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"testing"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func setTestTaskCredentials(t *testing.T, manager credentials.Manager, task *apitask.Task, credentialsID string, expiration time.Time) {
+	err := manager.SetTaskCredentials(&credentials.TaskIAMRoleCredentials{
+		ARN: task.Arn,
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			CredentialsID: credentialsID,
+			RoleType:      credentials.ApplicationRoleType,
+			Expiration:    expiration.UTC().Format(time.RFC3339),
+		},
+	})
+	assert.NoError(t, err)
+	task.SetCredentialsID(credentialsID)
+}
+
+// TestCredentialsExpiryMonitorRequestsCredentials tests that credentials are requested
+// from ACS when credentials of a running task expire within the refresh window
+func TestCredentialsExpiryMonitorRequestsCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	manager := credentials.NewManager()
+	now := time.Now()
+
+	task := &apitask.Task{Arn: "t1", DesiredStatusUnsafe: apitaskstatus.TaskRunning}
+	setTestTaskCredentials(t, manager, task, "cid1", now.Add(10*time.Minute))
+	taskEngine.EXPECT().GetTaskByArn("t1").Return(task, true).AnyTimes()
+
+	refreshCredentials := make(chan struct{}, 1)
+	monitor := newCredentialsExpiryMonitor(manager, taskEngine, 15*time.Minute, refreshCredentials)
+	assert.True(t, monitor.checkExpiry(now))
+	assert.Len(t, refreshCredentials, 1)
+	<-refreshCredentials
+
+	// credentials are not requested again until the request interval elapsed
+	assert.False(t, monitor.checkExpiry(now.Add(time.Minute)))
+	assert.True(t, monitor.checkExpiry(now.Add(credentialsRefreshRequestInterval)))
+}
+
+// TestCredentialsExpiryMonitorSkipsFreshCredentials tests that credentials are not
+// requested from ACS when no credentials expire within the refresh window
+func TestCredentialsExpiryMonitorSkipsFreshCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	manager := credentials.NewManager()
+	now := time.Now()
+
+	task := &apitask.Task{Arn: "t1", DesiredStatusUnsafe: apitaskstatus.TaskRunning}
+	setTestTaskCredentials(t, manager, task, "cid1", now.Add(time.Hour))
+	taskEngine.EXPECT().GetTaskByArn("t1").Return(task, true)
+
+	refreshCredentials := make(chan struct{}, 1)
+	monitor := newCredentialsExpiryMonitor(manager, taskEngine, 15*time.Minute, refreshCredentials)
+	assert.False(t, monitor.checkExpiry(now))
+	assert.Len(t, refreshCredentials, 0)
+}
+
+// TestCredentialsExpiryMonitorSkipsStoppedTasks tests that the expired credentials of
+// stopped tasks, and of tasks that are not managed anymore, are ignored
+func TestCredentialsExpiryMonitorSkipsStoppedTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	manager := credentials.NewManager()
+	now := time.Now()
+
+	stoppedTask := &apitask.Task{Arn: "t1", DesiredStatusUnsafe: apitaskstatus.TaskStopped}
+	setTestTaskCredentials(t, manager, stoppedTask, "cid1", now.Add(-time.Minute))
+	unknownTask := &apitask.Task{Arn: "t2"}
+	setTestTaskCredentials(t, manager, unknownTask, "cid2", now.Add(-time.Minute))
+	taskEngine.EXPECT().GetTaskByArn("t1").Return(stoppedTask, true)
+	taskEngine.EXPECT().GetTaskByArn("t2").Return(nil, false)
+
+	refreshCredentials := make(chan struct{}, 1)
+	monitor := newCredentialsExpiryMonitor(manager, taskEngine, 15*time.Minute, refreshCredentials)
+	assert.False(t, monitor.checkExpiry(now))
+}
+
+// TestCredentialsExpiryMonitorSkipsReplacedCredentials tests that credentials replaced
+// by newer ones for a task are ignored
+func TestCredentialsExpiryMonitorSkipsReplacedCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	manager := credentials.NewManager()
+	now := time.Now()
+
+	task := &apitask.Task{Arn: "t1", DesiredStatusUnsafe: apitaskstatus.TaskRunning}
+	setTestTaskCredentials(t, manager, task, "cid1", now.Add(-time.Minute))
+	setTestTaskCredentials(t, manager, task, "cid2", now.Add(time.Hour))
+	taskEngine.EXPECT().GetTaskByArn("t1").Return(task, true).Times(2)
+
+	refreshCredentials := make(chan struct{}, 1)
+	monitor := newCredentialsExpiryMonitor(manager, taskEngine, 15*time.Minute, refreshCredentials)
+	assert.False(t, monitor.checkExpiry(now))
+}
@@ -15,6 +15,7 @@ package handler
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/amazon-ecs-agent/agent/api"
@@ -26,6 +27,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/data"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -153,7 +155,15 @@ func (payloadHandler *payloadRequestHandler) handleSingleMessage(payload *ecsacs
 		return fmt.Errorf("received a payload with no message id")
 	}
 	seelog.Debugf("Received payload message, message id: %s", aws.StringValue(payload.MessageId))
+	span := tracing.StartSpan("HandleACSPayload",
+		tracing.String(tracing.MessageIDKey, aws.StringValue(payload.MessageId)),
+		tracing.String(tracing.TaskCountKey, strconv.Itoa(len(payload.Tasks))))
 	credentialsAcks, allTasksHandled := payloadHandler.addPayloadTasks(payload)
+	if !allTasksHandled {
+		span.End(fmt.Errorf("did not handle all tasks"))
+	} else {
+		span.End(nil)
+	}
 
 	// Update latestSeqNumberTaskManifest for it to get updated in state file
 	if payloadHandler.latestSeqNumberTaskManifest != nil && payload.SeqNum != nil &&
@@ -0,0 +1,140 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"context"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// taskUpdater is implemented by task engines that can update running tasks in place
+type taskUpdater interface {
+	UpdateTask(engine.TaskUpdate) error
+}
+
+// taskUpdateHandler handles task update messages from ACS. A message is acked once
+// the update is applied, and nacked with the reason of the failure otherwise.
+type taskUpdateHandler struct {
+	messageBuffer     chan *ecsacs.TaskUpdateMessage
+	ctx               context.Context
+	cancel            context.CancelFunc
+	cluster           *string
+	containerInstance *string
+	acsClient         wsclient.ClientServer
+	taskUpdater       taskUpdater
+}
+
+// newTaskUpdateHandler returns an instance of the taskUpdateHandler struct
+func newTaskUpdateHandler(ctx context.Context,
+	cluster string,
+	containerInstanceArn string,
+	acsClient wsclient.ClientServer,
+	taskUpdater taskUpdater) taskUpdateHandler {
+
+	// Create a cancelable context from the parent context
+	derivedContext, cancel := context.WithCancel(ctx)
+	return taskUpdateHandler{
+		messageBuffer:     make(chan *ecsacs.TaskUpdateMessage),
+		ctx:               derivedContext,
+		cancel:            cancel,
+		cluster:           aws.String(cluster),
+		containerInstance: aws.String(containerInstanceArn),
+		acsClient:         acsClient,
+		taskUpdater:       taskUpdater,
+	}
+}
+
+// handlerFunc returns a function to enqueue requests onto the buffer
+func (handler *taskUpdateHandler) handlerFunc() func(message *ecsacs.TaskUpdateMessage) {
+	return func(message *ecsacs.TaskUpdateMessage) {
+		handler.messageBuffer <- message
+	}
+}
+
+// start invokes handleMessages to apply each enqueued update
+func (handler *taskUpdateHandler) start() {
+	go handler.handleMessages()
+}
+
+// stop is used to invoke a cancellation function
+func (handler *taskUpdateHandler) stop() {
+	handler.cancel()
+}
+
+// handleMessages handles each message one at a time, so that the updates of a task
+// are applied in the order they were sent
+func (handler *taskUpdateHandler) handleMessages() {
+	for {
+		select {
+		case <-handler.ctx.Done():
+			return
+		case message := <-handler.messageBuffer:
+			if err := handler.handleSingleMessage(message); err != nil {
+				seelog.Warnf("Unable to handle task update message [%s]: %v", message.String(), err)
+			}
+		}
+	}
+}
+
+// handleSingleMessage applies the update of the message and responds to it
+func (handler *taskUpdateHandler) handleSingleMessage(message *ecsacs.TaskUpdateMessage) error {
+	if err := validateTaskUpdateMessage(message); err != nil {
+		return errors.Wrap(err, "task update handler: error validating TaskUpdate message received from ECS")
+	}
+
+	update := engine.TaskUpdate{
+		TaskARN:                 aws.StringValue(message.TaskArn),
+		RefreshEnvironmentFiles: aws.BoolValue(message.RefreshEnvironmentFiles),
+		RefreshSecrets:          aws.BoolValue(message.RefreshSecrets),
+		StopContainers:          aws.StringValueSlice(message.StopContainers),
+	}
+	if err := handler.taskUpdater.UpdateTask(update); err != nil {
+		handler.sendNack(message.MessageId, err)
+		return err
+	}
+	sendAck(handler.acsClient, handler.cluster, handler.containerInstance, message.MessageId)
+	return nil
+}
+
+// sendNack lets ACS know that the update of the message was not applied
+func (handler *taskUpdateHandler) sendNack(messageId *string, reason error) {
+	if err := handler.acsClient.MakeRequest(&ecsacs.NackRequest{
+		Cluster:           handler.cluster,
+		ContainerInstance: handler.containerInstance,
+		MessageId:         messageId,
+		Reason:            aws.String(reason.Error()),
+	}); err != nil {
+		seelog.Warnf("Failed to nack request with messageId: %s, error: %v", aws.StringValue(messageId), err)
+	}
+}
+
+// validateTaskUpdateMessage performs validation checks on the TaskUpdateMessage
+func validateTaskUpdateMessage(message *ecsacs.TaskUpdateMessage) error {
+	if message == nil {
+		return errors.New("task update handler validation: empty TaskUpdate message received from ECS")
+	}
+	if aws.StringValue(message.MessageId) == "" {
+		return errors.New("task update handler validation: message id not set in TaskUpdate message received from ECS")
+	}
+	if aws.StringValue(message.TaskArn) == "" {
+		return errors.New("task update handler validation: taskArn not set in TaskUpdate message received from ECS")
+	}
+	return nil
+}
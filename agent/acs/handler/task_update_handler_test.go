@@ -0,0 +1,129 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	mock_wsclient "github.com/aws/amazon-ecs-agent/agent/wsclient/mock"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const taskUpdateMessageId = "update-1"
+
+type fakeTaskUpdater struct {
+	updates []engine.TaskUpdate
+	err     error
+}
+
+func (updater *fakeTaskUpdater) UpdateTask(update engine.TaskUpdate) error {
+	updater.updates = append(updater.updates, update)
+	return updater.err
+}
+
+func newTestTaskUpdateMessage() *ecsacs.TaskUpdateMessage {
+	return &ecsacs.TaskUpdateMessage{
+		MessageId:            aws.String(taskUpdateMessageId),
+		ClusterArn:           aws.String(clusterName),
+		ContainerInstanceArn: aws.String(containerInstanceArn),
+		TaskArn:              aws.String(taskArn),
+		RefreshSecrets:       aws.Bool(true),
+		StopContainers:       aws.StringSlice([]string{"sidecar"}),
+	}
+}
+
+// TestTaskUpdateMessageValidation checks the validator against messages missing
+// required fields
+func TestTaskUpdateMessageValidation(t *testing.T) {
+	assert.Error(t, validateTaskUpdateMessage(nil))
+
+	message := newTestTaskUpdateMessage()
+	message.MessageId = nil
+	assert.Error(t, validateTaskUpdateMessage(message))
+
+	message = newTestTaskUpdateMessage()
+	message.TaskArn = aws.String("")
+	assert.Error(t, validateTaskUpdateMessage(message))
+
+	assert.NoError(t, validateTaskUpdateMessage(newTestTaskUpdateMessage()))
+}
+
+// TestTaskUpdateHandlerAcksAppliedUpdate checks that the update of the message is
+// applied to the task engine and acked
+func TestTaskUpdateHandlerAcksAppliedUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
+	updater := &fakeTaskUpdater{}
+	handler := newTaskUpdateHandler(context.TODO(), clusterName, containerInstanceArn, mockWSClient, updater)
+
+	mockWSClient.EXPECT().MakeRequest(&ecsacs.AckRequest{
+		Cluster:           aws.String(clusterName),
+		ContainerInstance: aws.String(containerInstanceArn),
+		MessageId:         aws.String(taskUpdateMessageId),
+	}).Return(nil)
+
+	assert.NoError(t, handler.handleSingleMessage(newTestTaskUpdateMessage()))
+	assert.Equal(t, []engine.TaskUpdate{{
+		TaskARN:        taskArn,
+		RefreshSecrets: true,
+		StopContainers: []string{"sidecar"},
+	}}, updater.updates)
+}
+
+// TestTaskUpdateHandlerNacksFailedUpdate checks that an update that can't be applied
+// is nacked with the reason of the failure
+func TestTaskUpdateHandlerNacksFailedUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
+	updater := &fakeTaskUpdater{err: errors.New("unable to refresh resource ssmsecret")}
+	handler := newTaskUpdateHandler(context.TODO(), clusterName, containerInstanceArn, mockWSClient, updater)
+
+	mockWSClient.EXPECT().MakeRequest(&ecsacs.NackRequest{
+		Cluster:           aws.String(clusterName),
+		ContainerInstance: aws.String(containerInstanceArn),
+		MessageId:         aws.String(taskUpdateMessageId),
+		Reason:            aws.String("unable to refresh resource ssmsecret"),
+	}).Return(nil)
+
+	assert.Error(t, handler.handleSingleMessage(newTestTaskUpdateMessage()))
+}
+
+// TestTaskUpdateHandlerIgnoresInvalidMessage checks that an invalid message is
+// neither applied nor responded to
+func TestTaskUpdateHandlerIgnoresInvalidMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
+	updater := &fakeTaskUpdater{}
+	handler := newTaskUpdateHandler(context.TODO(), clusterName, containerInstanceArn, mockWSClient, updater)
+
+	message := newTestTaskUpdateMessage()
+	message.TaskArn = nil
+	assert.Error(t, handler.handleSingleMessage(message))
+	assert.Empty(t, updater.updates)
+}
@@ -369,6 +369,8 @@ type Container struct {
 
 	DockerConfig *DockerConfig `locationName:"dockerConfig" type:"structure"`
 
+	EfaEnabled *bool `locationName:"efaEnabled" type:"boolean"`
+
 	EntryPoint []*string `locationName:"entryPoint" type:"list"`
 
 	Environment map[string]*string `locationName:"environment" type:"map"`
@@ -377,8 +379,12 @@ type Container struct {
 
 	Essential *bool `locationName:"essential" type:"boolean"`
 
+	EvictionPriority *int64 `locationName:"evictionPriority" type:"integer"`
+
 	FirelensConfiguration *FirelensConfiguration `locationName:"firelensConfiguration" type:"structure"`
 
+	HealthCheckProbes []*HealthCheckProbe `locationName:"healthCheckProbes" type:"list"`
+
 	HealthCheckType *string `locationName:"healthCheckType" type:"string" enum:"HealthCheckType"`
 
 	Image *string `locationName:"image" type:"string"`
@@ -395,16 +401,30 @@ type Container struct {
 
 	Name *string `locationName:"name" type:"string"`
 
+	NeuronCores *int64 `locationName:"neuronCores" type:"integer"`
+
 	Overrides *string `locationName:"overrides" type:"string"`
 
 	PortMappings []*PortMapping `locationName:"portMappings" type:"list"`
 
+	PreStopExec *ContainerPreStopExec `locationName:"preStopExec" type:"structure"`
+
 	RegistryAuthentication *RegistryAuthenticationData `locationName:"registryAuthentication" type:"structure"`
 
+	RestartPolicy *ContainerRestartPolicy `locationName:"restartPolicy" type:"structure"`
+
 	Secrets []*Secret `locationName:"secrets" type:"list"`
 
+	SecretsRefreshSignal *string `locationName:"secretsRefreshSignal" type:"string"`
+
+	SecurityProfiles []*SecurityProfile `locationName:"securityProfiles" type:"list"`
+
+	ShutdownGracePeriod *int64 `locationName:"shutdownGracePeriod" type:"integer"`
+
 	StartTimeout *int64 `locationName:"startTimeout" type:"integer"`
 
+	StopSignalSequence []*ContainerStopSignal `locationName:"stopSignalSequence" type:"list"`
+
 	StopTimeout *int64 `locationName:"stopTimeout" type:"integer"`
 
 	VolumesFrom []*VolumeFrom `locationName:"volumesFrom" type:"list"`
@@ -426,6 +446,8 @@ type ContainerDependency struct {
 	Condition *string `locationName:"condition" type:"string" enum:"ContainerCondition"`
 
 	ContainerName *string `locationName:"containerName" type:"string"`
+
+	Timeout *int64 `locationName:"timeout" type:"integer"`
 }
 
 // String returns the string representation
@@ -438,6 +460,82 @@ func (s ContainerDependency) GoString() string {
 	return s.String()
 }
 
+type ContainerPreStopExec struct {
+	_ struct{} `type:"structure"`
+
+	Command []*string `locationName:"command" type:"list"`
+
+	TimeoutSeconds *int64 `locationName:"timeoutSeconds" type:"integer"`
+}
+
+// String returns the string representation
+func (s ContainerPreStopExec) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s ContainerPreStopExec) GoString() string {
+	return s.String()
+}
+
+type ContainerRestartPolicy struct {
+	_ struct{} `type:"structure"`
+
+	BackoffSeconds *int64 `locationName:"backoffSeconds" type:"integer"`
+
+	MaxAttempts *int64 `locationName:"maxAttempts" type:"integer"`
+
+	ResetWindowSeconds *int64 `locationName:"resetWindowSeconds" type:"integer"`
+}
+
+// String returns the string representation
+func (s ContainerRestartPolicy) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s ContainerRestartPolicy) GoString() string {
+	return s.String()
+}
+
+type ContainerStopSignal struct {
+	_ struct{} `type:"structure"`
+
+	Signal *string `locationName:"signal" type:"string"`
+
+	WaitSeconds *int64 `locationName:"waitSeconds" type:"integer"`
+}
+
+// String returns the string representation
+func (s ContainerStopSignal) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s ContainerStopSignal) GoString() string {
+	return s.String()
+}
+
+type DnsConfiguration struct {
+	_ struct{} `type:"structure"`
+
+	CacheSize *int64 `locationName:"cacheSize" type:"integer"`
+
+	LocalCache *bool `locationName:"localCache" type:"boolean"`
+
+	MaxQueriesPerSecond *int64 `locationName:"maxQueriesPerSecond" type:"integer"`
+}
+
+// String returns the string representation
+func (s DnsConfiguration) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s DnsConfiguration) GoString() string {
+	return s.String()
+}
+
 type DockerConfig struct {
 	_ struct{} `type:"structure"`
 
@@ -559,6 +657,8 @@ type ElasticNetworkInterface struct {
 
 	InterfaceAssociationProtocol *string `locationName:"interfaceAssociationProtocol" type:"string" enum:"NetworkInterfaceAssociationProtocol"`
 
+	InterfaceType *string `locationName:"interfaceType" type:"string" enum:"NetworkInterfaceType"`
+
 	InterfaceVlanProperties *NetworkInterfaceVlanProperties `locationName:"interfaceVlanProperties" type:"structure"`
 
 	Ipv4Addresses []*IPv4AddressAssignment `locationName:"ipv4Addresses" type:"list"`
@@ -603,6 +703,10 @@ func (s EncodedString) GoString() string {
 type EnvironmentFile struct {
 	_ struct{} `type:"structure"`
 
+	Etag *string `locationName:"etag" type:"string"`
+
+	Sha256 *string `locationName:"sha256" type:"string"`
+
 	Type *string `locationName:"type" type:"string" enum:"EnvironmentFileType"`
 
 	Value *string `locationName:"value" type:"string"`
@@ -618,6 +722,22 @@ func (s EnvironmentFile) GoString() string {
 	return s.String()
 }
 
+type EphemeralStorage struct {
+	_ struct{} `type:"structure"`
+
+	SizeInGiB *int64 `locationName:"sizeInGiB" type:"integer"`
+}
+
+// String returns the string representation
+func (s EphemeralStorage) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s EphemeralStorage) GoString() string {
+	return s.String()
+}
+
 type ErrorInput struct {
 	_ struct{} `type:"structure"`
 
@@ -720,6 +840,34 @@ func (s FirelensConfiguration) GoString() string {
 	return s.String()
 }
 
+type HealthCheckProbe struct {
+	_ struct{} `type:"structure"`
+
+	IntervalSeconds *int64 `locationName:"intervalSeconds" type:"integer"`
+
+	Path *string `locationName:"path" type:"string"`
+
+	Port *int64 `locationName:"port" type:"integer"`
+
+	Retries *int64 `locationName:"retries" type:"integer"`
+
+	StartPeriodSeconds *int64 `locationName:"startPeriodSeconds" type:"integer"`
+
+	TimeoutSeconds *int64 `locationName:"timeoutSeconds" type:"integer"`
+
+	Type *string `locationName:"type" type:"string" enum:"HealthCheckProbeType"`
+}
+
+// String returns the string representation
+func (s HealthCheckProbe) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s HealthCheckProbe) GoString() string {
+	return s.String()
+}
+
 type HeartbeatAckRequest struct {
 	_ struct{} `type:"structure"`
 
@@ -788,6 +936,24 @@ func (s HeartbeatOutput) GoString() string {
 	return s.String()
 }
 
+type HostEntry struct {
+	_ struct{} `type:"structure"`
+
+	Hostname *string `locationName:"hostname" type:"string"`
+
+	IpAddress *string `locationName:"ipAddress" type:"string"`
+}
+
+// String returns the string representation
+func (s HostEntry) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s HostEntry) GoString() string {
+	return s.String()
+}
+
 type HostVolumeProperties struct {
 	_ struct{} `type:"structure"`
 
@@ -1071,6 +1237,30 @@ func (s *InvalidInstanceException) RequestID() string {
 	return s.RespMetadata.RequestID
 }
 
+type IoLimit struct {
+	_ struct{} `type:"structure"`
+
+	Device *string `locationName:"device" type:"string"`
+
+	ReadBps *int64 `locationName:"readBps" type:"long"`
+
+	ReadIops *int64 `locationName:"readIops" type:"long"`
+
+	WriteBps *int64 `locationName:"writeBps" type:"long"`
+
+	WriteIops *int64 `locationName:"writeIops" type:"long"`
+}
+
+// String returns the string representation
+func (s IoLimit) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s IoLimit) GoString() string {
+	return s.String()
+}
+
 type ManagedAgent struct {
 	_ struct{} `type:"structure"`
 
@@ -1149,6 +1339,46 @@ func (s NetworkInterfaceVlanProperties) GoString() string {
 	return s.String()
 }
 
+type NetworkPolicy struct {
+	_ struct{} `type:"structure"`
+
+	Rules []*NetworkPolicyRule `locationName:"rules" type:"list"`
+}
+
+// String returns the string representation
+func (s NetworkPolicy) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s NetworkPolicy) GoString() string {
+	return s.String()
+}
+
+type NetworkPolicyRule struct {
+	_ struct{} `type:"structure"`
+
+	Action *string `locationName:"action" type:"string"`
+
+	Cidr *string `locationName:"cidr" type:"string"`
+
+	FromPort *int64 `locationName:"fromPort" type:"integer"`
+
+	Protocol *string `locationName:"protocol" type:"string"`
+
+	ToPort *int64 `locationName:"toPort" type:"integer"`
+}
+
+// String returns the string representation
+func (s NetworkPolicyRule) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s NetworkPolicyRule) GoString() string {
+	return s.String()
+}
+
 type PayloadInput struct {
 	_ struct{} `type:"structure"`
 
@@ -1451,6 +1681,28 @@ func (s RegistryAuthenticationData) GoString() string {
 	return s.String()
 }
 
+type ResourceControls struct {
+	_ struct{} `type:"structure"`
+
+	CpusetCpus *string `locationName:"cpusetCpus" type:"string"`
+
+	CpusetMems *string `locationName:"cpusetMems" type:"string"`
+
+	IoLimits []*IoLimit `locationName:"ioLimits" type:"list"`
+
+	NumaAligned *bool `locationName:"numaAligned" type:"boolean"`
+}
+
+// String returns the string representation
+func (s ResourceControls) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s ResourceControls) GoString() string {
+	return s.String()
+}
+
 type Secret struct {
 	_ struct{} `type:"structure"`
 
@@ -1479,6 +1731,24 @@ func (s Secret) GoString() string {
 	return s.String()
 }
 
+type SecurityProfile struct {
+	_ struct{} `type:"structure"`
+
+	Name *string `locationName:"name" type:"string"`
+
+	Type *string `locationName:"type" type:"string" enum:"SecurityProfileType"`
+}
+
+// String returns the string representation
+func (s SecurityProfile) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s SecurityProfile) GoString() string {
+	return s.String()
+}
+
 type ServerException struct {
 	_            struct{}                  `type:"structure"`
 	RespMetadata protocol.ResponseMetadata `json:"-" xml:"-"`
@@ -1605,30 +1875,54 @@ type Task struct {
 
 	Associations []*Association `locationName:"associations" type:"list"`
 
+	BlockInstanceMetadata *bool `locationName:"blockInstanceMetadata" type:"boolean"`
+
+	CheckpointEnabled *bool `locationName:"checkpointEnabled" type:"boolean"`
+
+	ContainerRuntime *string `locationName:"containerRuntime" type:"string"`
+
+	ContainerStartConcurrency *int64 `locationName:"containerStartConcurrency" type:"integer"`
+
 	Containers []*Container `locationName:"containers" type:"list"`
 
 	Cpu *float64 `locationName:"cpu" type:"double"`
 
 	DesiredStatus *string `locationName:"desiredStatus" type:"string"`
 
+	DnsConfiguration *DnsConfiguration `locationName:"dnsConfiguration" type:"structure"`
+
 	ElasticNetworkInterfaces []*ElasticNetworkInterface `locationName:"elasticNetworkInterfaces" type:"list"`
 
+	EphemeralStorage *EphemeralStorage `locationName:"ephemeralStorage" type:"structure"`
+
 	ExecutionRoleCredentials *IAMRoleCredentials `locationName:"executionRoleCredentials" type:"structure"`
 
+	ExtraHosts []*HostEntry `locationName:"extraHosts" type:"list"`
+
 	Family *string `locationName:"family" type:"string"`
 
+	Hostname *string `locationName:"hostname" type:"string"`
+
+	InitProcessEnabled *bool `locationName:"initProcessEnabled" type:"boolean"`
+
+	InstanceMetadataAccess *string `locationName:"instanceMetadataAccess" type:"string" enum:"InstanceMetadataAccess"`
+
 	IpcMode *string `locationName:"ipcMode" type:"string"`
 
 	LaunchType *string `locationName:"launchType" type:"string"`
 
 	Memory *int64 `locationName:"memory" type:"integer"`
 
+	NetworkPolicy *NetworkPolicy `locationName:"networkPolicy" type:"structure"`
+
 	Overrides *string `locationName:"overrides" type:"string"`
 
 	PidMode *string `locationName:"pidMode" type:"string"`
 
 	ProxyConfiguration *ProxyConfiguration `locationName:"proxyConfiguration" type:"structure"`
 
+	ResourceControls *ResourceControls `locationName:"resourceControls" type:"structure"`
+
 	RoleCredentials *IAMRoleCredentials `locationName:"roleCredentials" type:"structure"`
 
 	TaskDefinitionAccountId *string `locationName:"taskDefinitionAccountId" type:"string"`
@@ -1732,6 +2026,34 @@ func (s TaskStopVerificationMessage) GoString() string {
 	return s.String()
 }
 
+type TaskUpdateMessage struct {
+	_ struct{} `type:"structure"`
+
+	ClusterArn *string `locationName:"clusterArn" type:"string"`
+
+	ContainerInstanceArn *string `locationName:"containerInstanceArn" type:"string"`
+
+	MessageId *string `locationName:"messageId" type:"string"`
+
+	RefreshEnvironmentFiles *bool `locationName:"refreshEnvironmentFiles" type:"boolean"`
+
+	RefreshSecrets *bool `locationName:"refreshSecrets" type:"boolean"`
+
+	StopContainers []*string `locationName:"stopContainers" type:"list"`
+
+	TaskArn *string `locationName:"taskArn" type:"string"`
+}
+
+// String returns the string representation
+func (s TaskUpdateMessage) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s TaskUpdateMessage) GoString() string {
+	return s.String()
+}
+
 type UpdateFailureInput struct {
 	_ struct{} `type:"structure"`
 
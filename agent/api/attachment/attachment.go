@@ -0,0 +1,41 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package attachment
+
+// Attachment is a resource that ECS attaches to the container instance, or to
+// a task running on it, and that the agent must acknowledge by submitting an
+// attachment state change before the attachment expires. ENI attachments are
+// one implementation; other resource types (e.g. EBS volumes) implement the
+// same interface so that the event handler can track and acknowledge them
+// without knowing their type.
+type Attachment interface {
+	// GetAttachmentARN returns the identifier of the attachment
+	GetAttachmentARN() string
+	// GetAttachmentType returns the type of the attachment
+	GetAttachmentType() string
+	// GetAttachmentStatus returns the status of the attachment as recognized
+	// by the SubmitAttachmentStateChanges API
+	GetAttachmentStatus() string
+	// IsSent returns true if the attachment status has been sent to ECS
+	IsSent() bool
+	// SetSentStatus marks the attachment status as sent to ECS
+	SetSentStatus()
+	// StopAckTimer stops the timer that expires the attachment if its status
+	// isn't sent in time
+	StopAckTimer()
+	// HasExpired returns true if the attachment status can no longer be sent
+	HasExpired() bool
+	// String returns a string representation of the attachment
+	String() string
+}
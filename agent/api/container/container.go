@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"github.com/cihub/seelog"
 	"github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
 )
 
 const (
@@ -45,6 +47,19 @@ const (
 	// DockerHealthCheckType is the type of container health check provided by docker
 	DockerHealthCheckType = "docker"
 
+	// HealthCheckProbeHTTP is the type of health check probe that sends a HTTP GET
+	// request to the container
+	HealthCheckProbeHTTP = "HTTP"
+	// HealthCheckProbeTCP is the type of health check probe that opens a TCP
+	// connection to the container
+	HealthCheckProbeTCP = "TCP"
+
+	// healthHistorySize is the number of health check results kept per container
+	healthHistorySize = 10
+	// maxHealthHistoryOutputLength is the maximum length of the health check output kept
+	// in the health history
+	maxHealthHistoryOutputLength = 256
+
 	// AuthTypeECR is to use image pull auth over ECR
 	AuthTypeECR = "ecr"
 
@@ -70,14 +85,28 @@ const (
 	// SecretProviderASM is to show secret provider being ASM
 	SecretProviderASM = "asm"
 
+	// SecretProviderVault is to show secret provider being HashiCorp Vault
+	SecretProviderVault = "vault"
+
 	// SecretTypeEnv is to show secret type being ENVIRONMENT_VARIABLE
 	SecretTypeEnv = "ENVIRONMENT_VARIABLE"
 
+	// SecretTypeFile is to show secret type being FILE, written to a file named after the
+	// secret in the ContainerPath directory of the container, and rewritten when rotated
+	SecretTypeFile = "FILE"
+
+	// DefaultSecretFilesContainerPath is the directory of the file secrets that don't set
+	// their ContainerPath
+	DefaultSecretFilesContainerPath = "/run/secrets"
+
 	// TargetLogDriver is to show secret target being "LOG_DRIVER", the default will be "CONTAINER"
 	SecretTargetLogDriver = "LOG_DRIVER"
 
 	// neuronVisibleDevicesEnvVar is the env which indicates that the container wants to use inferentia devices.
 	neuronVisibleDevicesEnvVar = "AWS_NEURON_VISIBLE_DEVICES"
+	// neuronVisibleCoresEnvVar is the env from which the neuron runtime reads the neuron cores the
+	// container may use.
+	neuronVisibleCoresEnvVar = "NEURON_RT_VISIBLE_CORES"
 )
 
 var (
@@ -164,6 +193,14 @@ type Container struct {
 	CPU uint `json:"Cpu"`
 	// GPUIDs is the list of GPU ids for a container
 	GPUIDs []string
+	// NeuronCores is the number of neuron cores the container requires
+	NeuronCores int `json:"NeuronCores,omitempty"`
+	// NeuronCoreIDs are the instance wide indexes of the neuron cores assigned to the container
+	NeuronCoreIDs []int `json:"NeuronCoreIDs,omitempty"`
+	// NeuronDevices are the paths of the Neuron devices of the cores assigned to the container
+	NeuronDevices []string `json:"NeuronDevices,omitempty"`
+	// EFAEnabled specifies whether the Elastic Fabric Adapter of the task is passed to the container
+	EFAEnabled bool `json:"EFAEnabled,omitempty"`
 	// Memory is the memory limitation of the container which is specified in the task definition
 	Memory uint
 	// Links contains a list of containers to link, corresponding to docker option: --link
@@ -178,8 +215,14 @@ type Container struct {
 	Ports []PortBinding `json:"portMappings"`
 	// Secrets contains a list of secret
 	Secrets []Secret `json:"secrets"`
+	// SecretsRefreshSignal is the signal sent to the container when its file secrets are
+	// rotated. The agent default is used when it's empty
+	SecretsRefreshSignal string `json:"secretsRefreshSignal,omitempty"`
 	// Essential denotes whether the container is essential or not
 	Essential bool
+	// EvictionPriority orders the non-essential containers of the task stopped when its
+	// memory is under pressure, from the lowest priority
+	EvictionPriority int64 `json:"EvictionPriority,omitempty"`
 	// EntryPoint is entrypoint of the container, corresponding to docker option: --entrypoint
 	EntryPoint *[]string
 	// Environment is the environment variable set in the container
@@ -206,6 +249,37 @@ type Container struct {
 	StartTimeout uint
 	// StopTimeout specifies the time value to be passed as StopContainer api call
 	StopTimeout uint
+	// StopSignalSequence lists the signals sent to the container, in order, before
+	// it is stopped with StopTimeout
+	StopSignalSequence []StopSignal `json:"stopSignalSequence,omitempty"`
+	// PreStopExec is the command run inside the container before it's sent its stop
+	// signals, like the preStop exec hooks of Kubernetes
+	PreStopExec *PreStopExec `json:"preStopExec,omitempty"`
+	// ShutdownGracePeriod specifies how long, in seconds, the container keeps running
+	// after the containers depending on it stopped, when its task stops
+	ShutdownGracePeriod uint
+	// RestartPolicy specifies whether and how the agent restarts the container
+	// when it exits while the task is running
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+	// HealthCheckProbes lists the health checks executed by the agent against the
+	// container. When set, they determine the health status of the container instead
+	// of the docker health check
+	HealthCheckProbes []HealthCheckProbe `json:"healthCheckProbes,omitempty"`
+	// SecurityProfilesUnsafe are the seccomp and AppArmor profiles managed by the agent
+	// the container references by name, with whether they were applied. This field
+	// should be accessed via GetSecurityProfiles and SetSecurityProfiles
+	SecurityProfilesUnsafe []SecurityProfile `json:"securityProfiles,omitempty"`
+	// OCIRuntimeUnsafe is the OCI runtime docker runs the container with, such as runc
+	// or runsc. This field should be accessed via GetOCIRuntime and SetOCIRuntime
+	OCIRuntimeUnsafe string `json:"ociRuntime,omitempty"`
+	// UlimitsUnsafe are the effective ulimits of the container, after the agent default
+	// ulimits were added and clamped. This field should be accessed via GetUlimits and
+	// SetUlimits
+	UlimitsUnsafe []*units.Ulimit `json:"ulimits,omitempty"`
+	// EffectiveResourcesUnsafe are the cpu and memory limits docker runs the container
+	// with, and its cgroup. This field should be accessed via GetEffectiveResources and
+	// SetEffectiveResources
+	EffectiveResourcesUnsafe *EffectiveResources `json:"effectiveResources,omitempty"`
 
 	// lock is used for fields that are accessed and updated concurrently
 	lock sync.RWMutex
@@ -279,6 +353,30 @@ type Container struct {
 	// and `SetKnownExitCode`.
 	KnownExitCodeUnsafe *int `json:"KnownExitCode"`
 
+	// RestartCountUnsafe is the number of times the agent restarted the container
+	// per its restart policy.
+	// NOTE: Do not access RestartCountUnsafe directly. Instead, use `GetRestartCount`
+	// and `SetRestartCount`.
+	RestartCountUnsafe int `json:"RestartCount,omitempty"`
+
+	// HealthHistoryUnsafe holds the results of the last health checks of the container,
+	// oldest first.
+	// NOTE: Do not access HealthHistoryUnsafe directly. Instead, use `GetHealthHistory`
+	// and `AddHealthCheckResults`.
+	HealthHistoryUnsafe []HealthCheckResult `json:"HealthHistory,omitempty"`
+
+	// CheckpointIDUnsafe is the ID of the latest checkpoint of the container, which
+	// it's restored from when it's restarted per its restart policy.
+	// NOTE: Do not access CheckpointIDUnsafe directly. Instead, use `GetCheckpointID`
+	// and `SetCheckpointID`.
+	CheckpointIDUnsafe string `json:"CheckpointID,omitempty"`
+
+	// AWSLogsRelayOptionsUnsafe are the awslogs log driver options of the container whose
+	// logs are relayed to CloudWatch Logs by the agent, when the awslogs relay is enabled.
+	// NOTE: Do not access AWSLogsRelayOptionsUnsafe directly. Instead, use
+	// `GetAWSLogsRelayOptions` and `SetAWSLogsRelayOptions`.
+	AWSLogsRelayOptionsUnsafe map[string]string `json:"AWSLogsRelayOptions,omitempty"`
+
 	// KnownPortBindingsUnsafe is an array of port bindings for the container.
 	KnownPortBindingsUnsafe []PortBinding `json:"KnownPortBindings"`
 
@@ -315,6 +413,95 @@ type Container struct {
 type DependsOn struct {
 	ContainerName string `json:"containerName"`
 	Condition     string `json:"condition"`
+	// Timeout is the time in seconds the dependency container has to complete
+	// in, for the COMPLETE_WITHIN condition
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// StopSignal is a step of the sequence of signals sent to a container before it
+// is stopped
+type StopSignal struct {
+	Signal string `json:"signal"`
+	// WaitSeconds is how long the container is given to exit after the signal
+	// is sent, before the next step
+	WaitSeconds int64 `json:"waitSeconds,omitempty"`
+}
+
+// PreStopExec is a command run inside a container before it is stopped, so that it
+// can drain or hand over its work
+type PreStopExec struct {
+	Command []string `json:"command"`
+	// TimeoutSeconds is how long the command is given to exit before the container is
+	// stopped anyway. Zero means the stop timeout of the container
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+// RestartPolicy specifies how the agent restarts a container that exits while its
+// task is running
+type RestartPolicy struct {
+	// MaxAttempts is the number of restarts after which the container is let to stop.
+	// Zero means no limit
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// BackoffSeconds is the delay before the first restart, doubled for each
+	// following one
+	BackoffSeconds int64 `json:"backoffSeconds,omitempty"`
+	// ResetWindowSeconds is how long the container has to run for its restart
+	// attempts to be reset. Zero means they are never reset
+	ResetWindowSeconds int64 `json:"resetWindowSeconds,omitempty"`
+}
+
+// Statuses of the security profiles of a container
+const (
+	// SecurityProfileApplied is the status of the profiles applied to the container
+	SecurityProfileApplied = "APPLIED"
+	// SecurityProfileFailed is the status of the profiles that couldn't be applied
+	SecurityProfileFailed = "FAILED"
+)
+
+// SecurityProfile is a seccomp or AppArmor profile managed by the agent, which the
+// container references by name
+type SecurityProfile struct {
+	// Type is the type of the profile, seccomp or apparmor
+	Type string `json:"type"`
+	// Name is the name of the profile
+	Name string `json:"name"`
+	// Status is APPLIED or FAILED once the container is created
+	Status string `json:"status,omitempty"`
+	// Source is the path or the S3 ARN the profile was read from
+	Source string `json:"source,omitempty"`
+	// Reason is why the profile couldn't be applied
+	Reason string `json:"reason,omitempty"`
+}
+
+// HealthCheckResult is the result of a single run of a container health check
+type HealthCheckResult struct {
+	// Timestamp is when the health check ran
+	Timestamp time.Time `json:"timestamp"`
+	// ExitCode is 0 if the health check succeeded
+	ExitCode int `json:"exitCode"`
+	// Output is the beginning of the output of the health check
+	Output string `json:"output,omitempty"`
+}
+
+// HealthCheckProbe is a health check executed by the agent, without exec'ing into the
+// container
+type HealthCheckProbe struct {
+	// Type is either HealthCheckProbeHTTP or HealthCheckProbeTCP
+	Type string `json:"type"`
+	// Port is the container port probed
+	Port uint16 `json:"port"`
+	// Path is the path requested by HTTP probes
+	Path string `json:"path,omitempty"`
+	// IntervalSeconds is the time between two probes
+	IntervalSeconds int64 `json:"intervalSeconds,omitempty"`
+	// TimeoutSeconds is the time after which a probe fails
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+	// Retries is the number of consecutive failures after which the container is
+	// considered unhealthy
+	Retries int `json:"retries,omitempty"`
+	// StartPeriodSeconds is the time after the container starts during which failures
+	// aren't counted
+	StartPeriodSeconds int64 `json:"startPeriodSeconds,omitempty"`
 }
 
 // DockerContainer is a mapping between containers-as-docker-knows-them and
@@ -331,6 +518,10 @@ type DockerContainer struct {
 type EnvironmentFile struct {
 	Value string `json:"value"`
 	Type  string `json:"type"`
+	// ETag and Sha256 pin the content of the file. The download fails if the file doesn't
+	// match them, and the pinned files are cached for the other tasks using them.
+	ETag   string `json:"etag,omitempty"`
+	Sha256 string `json:"sha256,omitempty"`
 }
 
 // MountPoint describes the in-container location of a Volume and references
@@ -370,6 +561,15 @@ func (s *Secret) GetSecretResourceCacheKey() string {
 	return s.ValueFrom + "_" + s.Region
 }
 
+// GetFileContainerPath returns the directory of the container the file secret is
+// written to
+func (s *Secret) GetFileContainerPath() string {
+	if s.ContainerPath == "" {
+		return DefaultSecretFilesContainerPath
+	}
+	return s.ContainerPath
+}
+
 // String returns a human readable string representation of DockerContainer
 func (dc *DockerContainer) String() string {
 	if dc == nil {
@@ -464,6 +664,132 @@ func (c *Container) GetKnownExitCode() *int {
 	return c.KnownExitCodeUnsafe
 }
 
+// GetSecurityProfiles returns a copy of the security profiles of the container
+func (c *Container) GetSecurityProfiles() []SecurityProfile {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]SecurityProfile(nil), c.SecurityProfilesUnsafe...)
+}
+
+// SetSecurityProfiles records the security profiles of the container, with their status
+func (c *Container) SetSecurityProfiles(profiles []SecurityProfile) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.SecurityProfilesUnsafe = profiles
+}
+
+// GetOCIRuntime returns the OCI runtime docker runs the container with
+func (c *Container) GetOCIRuntime() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.OCIRuntimeUnsafe
+}
+
+// SetOCIRuntime records the OCI runtime docker runs the container with
+func (c *Container) SetOCIRuntime(runtime string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.OCIRuntimeUnsafe = runtime
+}
+
+// GetUlimits returns the effective ulimits of the container
+func (c *Container) GetUlimits() []*units.Ulimit {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.UlimitsUnsafe
+}
+
+// SetUlimits records the effective ulimits of the container
+func (c *Container) SetUlimits(ulimits []*units.Ulimit) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.UlimitsUnsafe = ulimits
+}
+
+// GetEffectiveResources returns the cpu and memory limits docker runs the container
+// with, and its cgroup
+func (c *Container) GetEffectiveResources() *EffectiveResources {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.EffectiveResourcesUnsafe
+}
+
+// SetEffectiveResources records the cpu and memory limits docker runs the container
+// with, and its cgroup
+func (c *Container) SetEffectiveResources(resources *EffectiveResources) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.EffectiveResourcesUnsafe = resources
+}
+
+// GetRestartPolicy returns the restart policy of the container, if any
+func (c *Container) GetRestartPolicy() *RestartPolicy {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.RestartPolicy
+}
+
+// GetRestartCount returns the number of times the container was restarted per its
+// restart policy
+func (c *Container) GetRestartCount() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.RestartCountUnsafe
+}
+
+// SetRestartCount sets the number of times the container was restarted per its
+// restart policy
+func (c *Container) SetRestartCount(restartCount int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.RestartCountUnsafe = restartCount
+}
+
+// GetCheckpointID returns the ID of the latest checkpoint of the container
+func (c *Container) GetCheckpointID() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.CheckpointIDUnsafe
+}
+
+// SetCheckpointID sets the ID of the latest checkpoint of the container
+func (c *Container) SetCheckpointID(checkpointID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.CheckpointIDUnsafe = checkpointID
+}
+
+// GetAWSLogsRelayOptions returns the awslogs log driver options of the container
+// whose logs are relayed by the agent, or nil when they aren't relayed
+func (c *Container) GetAWSLogsRelayOptions() map[string]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.AWSLogsRelayOptionsUnsafe
+}
+
+// SetAWSLogsRelayOptions sets the awslogs log driver options of the container whose
+// logs are relayed by the agent
+func (c *Container) SetAWSLogsRelayOptions(options map[string]string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.AWSLogsRelayOptionsUnsafe = options
+}
+
 // SetRegistryAuthCredentials sets the credentials for pulling image from ECR
 func (c *Container) SetRegistryAuthCredentials(credential credentials.IAMRoleCredentials) {
 	c.lock.Lock()
@@ -762,7 +1088,13 @@ func (c *Container) GetNetworkMode() string {
 // HealthStatusShouldBeReported returns true if the health check is defined in
 // the task definition
 func (c *Container) HealthStatusShouldBeReported() bool {
-	return c.HealthCheckType == DockerHealthCheckType
+	return c.HealthCheckType == DockerHealthCheckType || c.HasHealthCheckProbes()
+}
+
+// HasHealthCheckProbes returns true if the health of the container is checked by the
+// agent, rather than by docker
+func (c *Container) HasHealthCheckProbes() bool {
+	return len(c.HealthCheckProbes) > 0
 }
 
 // SetHealthStatus sets the container health status
@@ -799,6 +1131,35 @@ func (c *Container) GetHealthStatus() HealthStatus {
 	return copyHealth
 }
 
+// AddHealthCheckResults adds the results of health checks that ran after the last
+// result of the health history, keeping only the most recent results
+func (c *Container) AddHealthCheckResults(results ...HealthCheckResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, result := range results {
+		if n := len(c.HealthHistoryUnsafe); n > 0 && !result.Timestamp.After(c.HealthHistoryUnsafe[n-1].Timestamp) {
+			continue
+		}
+		if len(result.Output) > maxHealthHistoryOutputLength {
+			result.Output = result.Output[:maxHealthHistoryOutputLength]
+		}
+		c.HealthHistoryUnsafe = append(c.HealthHistoryUnsafe, result)
+	}
+	if n := len(c.HealthHistoryUnsafe); n > healthHistorySize {
+		c.HealthHistoryUnsafe = append([]HealthCheckResult(nil), c.HealthHistoryUnsafe[n-healthHistorySize:]...)
+	}
+}
+
+// GetHealthHistory returns the results of the last health checks of the container,
+// oldest first
+func (c *Container) GetHealthHistory() []HealthCheckResult {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]HealthCheckResult(nil), c.HealthHistoryUnsafe...)
+}
+
 // BuildContainerDependency adds a new dependency container and satisfied status
 // to the dependent container
 func (c *Container) BuildContainerDependency(contName string,
@@ -972,6 +1333,20 @@ func (c *Container) ShouldCreateWithASMSecret() bool {
 	return false
 }
 
+// ShouldCreateWithVaultSecret returns true if this container needs to get secret
+// value from HashiCorp Vault
+func (c *Container) ShouldCreateWithVaultSecret() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, secret := range c.Secrets {
+		if secret.Provider == SecretProviderVault {
+			return true
+		}
+	}
+	return false
+}
+
 // ShouldCreateWithEnvFiles returns true if this container needs to
 // retrieve environment variable files
 func (c *Container) ShouldCreateWithEnvFiles() bool {
@@ -1059,6 +1434,47 @@ func (c *Container) GetStopTimeout() time.Duration {
 	return time.Duration(c.StopTimeout) * time.Second
 }
 
+// GetShutdownGracePeriod returns how long the container keeps running after the
+// containers depending on it stopped
+func (c *Container) GetShutdownGracePeriod() time.Duration {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return time.Duration(c.ShutdownGracePeriod) * time.Second
+}
+
+// GetStopSignalSequence returns the signals to send to the container before stopping it
+func (c *Container) GetStopSignalSequence() []StopSignal {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.StopSignalSequence
+}
+
+// GetPreStopExec returns the command to run inside the container before stopping it, if any
+func (c *Container) GetPreStopExec() *PreStopExec {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.PreStopExec
+}
+
+// GetSecretsRefreshSignal returns the signal to send to the container when its file
+// secrets are rotated, if it sets one
+func (c *Container) GetSecretsRefreshSignal() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.SecretsRefreshSignal
+}
+
+// HasSecretFiles returns whether secrets are written to files in the container
+func (c *Container) HasSecretFiles() bool {
+	return c.HasSecret(func(s Secret) bool {
+		return s.Type == SecretTypeFile
+	})
+}
+
 func (c *Container) GetDependsOn() []DependsOn {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -1198,6 +1614,43 @@ func (c *Container) RequireNeuronRuntime() bool {
 	return ok
 }
 
+// SetNeuronCores records the neuron cores assigned to the container and the devices they
+// belong to, and makes the cores visible to the neuron runtime of the container. The
+// visible cores are numbered among the cores of the devices passed to the container.
+func (c *Container) SetNeuronCores(coreIDs []int, devices []string, visibleCores []int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.NeuronCoreIDs = coreIDs
+	c.NeuronDevices = devices
+	cores := make([]string, len(visibleCores))
+	for i, core := range visibleCores {
+		cores[i] = strconv.Itoa(core)
+	}
+	if c.Environment == nil {
+		c.Environment = make(map[string]string)
+	}
+	c.Environment[neuronVisibleCoresEnvVar] = strings.Join(cores, ",")
+}
+
+// GetNeuronCoreIDs returns the instance wide indexes of the neuron cores assigned to the
+// container.
+func (c *Container) GetNeuronCoreIDs() []int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.NeuronCoreIDs
+}
+
+// GetNeuronDevices returns the paths of the Neuron devices of the cores assigned to the
+// container.
+func (c *Container) GetNeuronDevices() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.NeuronDevices
+}
+
 // SetTaskARN sets the task arn of the container.
 func (c *Container) SetTaskARN(arn string) {
 	c.lock.Lock()
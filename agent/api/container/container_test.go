@@ -18,6 +18,7 @@ package container
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type configPair struct {
@@ -206,6 +208,35 @@ func TestSetHealthStatus(t *testing.T) {
 	assert.NotEqual(t, health3.Since, health2.Since)
 }
 
+func TestAddHealthCheckResults(t *testing.T) {
+	container := Container{}
+	start := time.Now()
+	for i := 0; i < healthHistorySize+2; i++ {
+		container.AddHealthCheckResults(HealthCheckResult{
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			ExitCode:  i,
+		})
+	}
+	// results already in the history aren't added again
+	container.AddHealthCheckResults(HealthCheckResult{Timestamp: start.Add(time.Second)})
+
+	history := container.GetHealthHistory()
+	require.Len(t, history, healthHistorySize)
+	assert.Equal(t, 2, history[0].ExitCode)
+	assert.Equal(t, healthHistorySize+1, history[healthHistorySize-1].ExitCode)
+
+	container.AddHealthCheckResults(HealthCheckResult{
+		Timestamp: start.Add(time.Hour),
+		Output:    strings.Repeat("x", maxHealthHistoryOutputLength+1),
+	})
+	history = container.GetHealthHistory()
+	assert.Len(t, history[healthHistorySize-1].Output, maxHealthHistoryOutputLength)
+
+	// the returned history is a copy
+	history[0].ExitCode = -1
+	assert.Equal(t, 3, container.GetHealthHistory()[0].ExitCode)
+}
+
 func TestHealthStatusShouldBeReported(t *testing.T) {
 	container := Container{}
 	assert.False(t, container.HealthStatusShouldBeReported(), "Health status of container that does not have HealthCheckType set should not be reported")
@@ -213,6 +244,8 @@ func TestHealthStatusShouldBeReported(t *testing.T) {
 	assert.True(t, container.HealthStatusShouldBeReported(), "Health status of container that has docker HealthCheckType set should be reported")
 	container.HealthCheckType = "unknown"
 	assert.False(t, container.HealthStatusShouldBeReported(), "Health status of container that has non-docker HealthCheckType set should not be reported")
+	container.HealthCheckProbes = []HealthCheckProbe{{Type: HealthCheckProbeTCP, Port: 80}}
+	assert.True(t, container.HealthStatusShouldBeReported(), "Health status of container that has health check probes should be reported")
 }
 
 func TestBuildContainerDependency(t *testing.T) {
@@ -734,6 +767,22 @@ func TestShouldCreateWithEnvfiles(t *testing.T) {
 	}
 }
 
+func TestSetNeuronCores(t *testing.T) {
+	container := &Container{
+		Name:        "c",
+		NeuronCores: 2,
+		Environment: map[string]string{"foo": "bar"},
+	}
+	container.SetNeuronCores([]int{5, 6}, []string{"/dev/neuron2", "/dev/neuron3"}, []int{1, 2})
+
+	assert.Equal(t, []int{5, 6}, container.GetNeuronCoreIDs())
+	assert.Equal(t, []string{"/dev/neuron2", "/dev/neuron3"}, container.GetNeuronDevices())
+	assert.Equal(t, map[string]string{
+		"foo":                     "bar",
+		"NEURON_RT_VISIBLE_CORES": "1,2",
+	}, container.Environment)
+}
+
 func TestMergeEnvironmentVariablesFromEnvfiles(t *testing.T) {
 	cases := []struct {
 		Name                   string
@@ -0,0 +1,40 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package container
+
+// EffectiveResources are the cpu and memory limits docker runs a container with, and the
+// cgroup it runs in. They let the processes of the container read their actual limits
+// instead of inferring them from /proc, which reports the resources of the instance
+type EffectiveResources struct {
+	// CPUShares is the relative cpu weight of the container
+	CPUShares int64 `json:"CPUShares,omitempty"`
+	// CPUQuota is the cpu time, in microseconds, the container may use every CPUPeriod
+	CPUQuota int64 `json:"CPUQuota,omitempty"`
+	// CPUPeriod is the period of the cpu quota of the container, in microseconds
+	CPUPeriod int64 `json:"CPUPeriod,omitempty"`
+	// CpusetCpus are the cpus the container is pinned to, in the cpuset list format
+	CpusetCpus string `json:"CpusetCpus,omitempty"`
+	// Memory is the hard memory limit of the container, in bytes
+	Memory int64 `json:"Memory,omitempty"`
+	// MemoryReservation is the soft memory limit of the container, in bytes
+	MemoryReservation int64 `json:"MemoryReservation,omitempty"`
+	// MemorySwap is the limit of the memory and the swap of the container, in bytes. It's
+	// -1 when the swap of the container is unlimited
+	MemorySwap int64 `json:"MemorySwap,omitempty"`
+	// CgroupParent is the cgroup the cgroup of the container is created under, if set
+	CgroupParent string `json:"CgroupParent,omitempty"`
+	// CgroupPath is the path of the cgroup of the container in the unified (v2) cgroup
+	// hierarchy. It's only set when the instance uses cgroup v2
+	CgroupPath string `json:"CgroupPath,omitempty"`
+}
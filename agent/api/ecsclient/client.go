@@ -46,6 +46,13 @@ const (
 	azAttrName              = "ecs.availability-zone"
 	cpuArchAttrName         = "ecs.cpu-architecture"
 	osTypeAttrName          = "ecs.os-type"
+	// getTaskProtectionMaxTasks and updateTaskProtectionMaxTasks are the maximum
+	// numbers of tasks in a GetTaskProtection and an UpdateTaskProtection call
+	getTaskProtectionMaxTasks    = 100
+	updateTaskProtectionMaxTasks = 10
+	// putAttributesMaxAttributes is the maximum number of attributes in a PutAttributes call
+	putAttributesMaxAttributes  = 10
+	containerInstanceTargetType = "container-instance"
 )
 
 // APIECSClient implements ECSClient
@@ -529,22 +536,22 @@ func (client *APIECSClient) SubmitContainerStateChange(change api.ContainerState
 	return nil
 }
 
-func (client *APIECSClient) SubmitAttachmentStateChange(change api.AttachmentStateChange) error {
-	attachmentStatus := change.Attachment.Status.String()
-
+// SubmitAttachmentStateChanges submits a batch of attachment state changes in a
+// single SubmitAttachmentStateChanges call
+func (client *APIECSClient) SubmitAttachmentStateChanges(changes []api.AttachmentStateChange) error {
 	req := ecs.SubmitAttachmentStateChangesInput{
 		Cluster: &client.config.Cluster,
-		Attachments: []*ecs.AttachmentStateChange{
-			{
-				AttachmentArn: aws.String(change.Attachment.AttachmentARN),
-				Status:        aws.String(attachmentStatus),
-			},
-		},
+	}
+	for _, change := range changes {
+		req.Attachments = append(req.Attachments, &ecs.AttachmentStateChange{
+			AttachmentArn: aws.String(change.Attachment.GetAttachmentARN()),
+			Status:        aws.String(change.Attachment.GetAttachmentStatus()),
+		})
 	}
 
 	_, err := client.submitStateChangeClient.SubmitAttachmentStateChanges(&req)
 	if err != nil {
-		seelog.Warnf("Could not submit attachment state change [%s]: %v", change.String(), err)
+		seelog.Warnf("Could not submit %d attachment state changes: %v", len(changes), err)
 		return err
 	}
 
@@ -616,3 +623,103 @@ func (client *APIECSClient) UpdateContainerInstancesState(instanceARN string, st
 	})
 	return err
 }
+
+// GetProtectedTasks returns the ARNs of the given tasks whose scale-in protection is
+// enabled. Tasks that aren't part of a service can't be protected, ECS reports them
+// as failures which are ignored.
+func (client *APIECSClient) GetProtectedTasks(taskARNs []string) ([]string, error) {
+	var protectedTasks []string
+	for begin := 0; begin < len(taskARNs); begin += getTaskProtectionMaxTasks {
+		end := begin + getTaskProtectionMaxTasks
+		if end > len(taskARNs) {
+			end = len(taskARNs)
+		}
+		seelog.Debugf("Invoking GetTaskProtection for %d tasks", end-begin)
+		output, err := client.standardClient.GetTaskProtection(&ecs.GetTaskProtectionInput{
+			Cluster: &client.config.Cluster,
+			Tasks:   aws.StringSlice(taskARNs[begin:end]),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range output.ProtectedTasks {
+			if aws.BoolValue(task.ProtectionEnabled) {
+				protectedTasks = append(protectedTasks, aws.StringValue(task.TaskArn))
+			}
+		}
+	}
+	return protectedTasks, nil
+}
+
+// DisableTaskProtection disables the scale-in protection of the given tasks
+func (client *APIECSClient) DisableTaskProtection(taskARNs []string) error {
+	for begin := 0; begin < len(taskARNs); begin += updateTaskProtectionMaxTasks {
+		end := begin + updateTaskProtectionMaxTasks
+		if end > len(taskARNs) {
+			end = len(taskARNs)
+		}
+		seelog.Debugf("Invoking UpdateTaskProtection to disable the protection of %d tasks", end-begin)
+		output, err := client.standardClient.UpdateTaskProtection(&ecs.UpdateTaskProtectionInput{
+			Cluster:           &client.config.Cluster,
+			Tasks:             aws.StringSlice(taskARNs[begin:end]),
+			ProtectionEnabled: aws.Bool(false),
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.Failures) > 0 {
+			return fmt.Errorf("unable to disable the protection of task %s: %s",
+				aws.StringValue(output.Failures[0].Arn), aws.StringValue(output.Failures[0].Reason))
+		}
+	}
+	return nil
+}
+
+// PutAttributes creates or updates the given attributes of a container instance
+func (client *APIECSClient) PutAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error {
+	return forEachAttributesBatch(containerInstanceArn, attributes, func(targetAttributes []*ecs.Attribute) error {
+		seelog.Debugf("Invoking PutAttributes for %d attributes of container instance %s", len(targetAttributes), containerInstanceArn)
+		_, err := client.standardClient.PutAttributes(&ecs.PutAttributesInput{
+			Cluster:    &client.config.Cluster,
+			Attributes: targetAttributes,
+		})
+		return err
+	})
+}
+
+// DeleteAttributes deletes the given attributes of a container instance
+func (client *APIECSClient) DeleteAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error {
+	return forEachAttributesBatch(containerInstanceArn, attributes, func(targetAttributes []*ecs.Attribute) error {
+		seelog.Debugf("Invoking DeleteAttributes for %d attributes of container instance %s", len(targetAttributes), containerInstanceArn)
+		_, err := client.standardClient.DeleteAttributes(&ecs.DeleteAttributesInput{
+			Cluster:    &client.config.Cluster,
+			Attributes: targetAttributes,
+		})
+		return err
+	})
+}
+
+// forEachAttributesBatch calls fn with the attributes targeting the container instance,
+// at most putAttributesMaxAttributes at a time, until it fails
+func forEachAttributesBatch(containerInstanceArn string, attributes []*ecs.Attribute,
+	fn func([]*ecs.Attribute) error) error {
+	for begin := 0; begin < len(attributes); begin += putAttributesMaxAttributes {
+		end := begin + putAttributesMaxAttributes
+		if end > len(attributes) {
+			end = len(attributes)
+		}
+		var targetAttributes []*ecs.Attribute
+		for _, attribute := range attributes[begin:end] {
+			targetAttributes = append(targetAttributes, &ecs.Attribute{
+				Name:       attribute.Name,
+				Value:      attribute.Value,
+				TargetId:   aws.String(containerInstanceArn),
+				TargetType: aws.String(containerInstanceTargetType),
+			})
+		}
+		if err := fn(targetAttributes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -797,6 +797,124 @@ func TestUpdateContainerInstancesStateError(t *testing.T) {
 	assert.Error(t, err, "Expected an error calling UpdateContainerInstancesState but got nil")
 }
 
+func TestGetProtectedTasks(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc, _ := NewMockClient(mockCtrl, ec2.NewBlackholeEC2MetadataClient(), nil)
+
+	taskARNs := []string{"task1", "task2", "task3"}
+	mc.EXPECT().GetTaskProtection(&ecs.GetTaskProtectionInput{
+		Cluster: aws.String(configuredCluster),
+		Tasks:   aws.StringSlice(taskARNs),
+	}).Return(&ecs.GetTaskProtectionOutput{
+		ProtectedTasks: []*ecs.ProtectedTask{
+			{TaskArn: aws.String("task1"), ProtectionEnabled: aws.Bool(true)},
+			{TaskArn: aws.String("task2"), ProtectionEnabled: aws.Bool(false)},
+		},
+		Failures: []*ecs.Failure{{Arn: aws.String("task3"), Reason: aws.String("TASK_NOT_VALID")}},
+	}, nil)
+
+	protectedTasks, err := client.GetProtectedTasks(taskARNs)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task1"}, protectedTasks)
+}
+
+func TestDisableTaskProtection(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc, _ := NewMockClient(mockCtrl, ec2.NewBlackholeEC2MetadataClient(), nil)
+
+	var taskARNs []string
+	for i := 0; i < updateTaskProtectionMaxTasks+1; i++ {
+		taskARNs = append(taskARNs, fmt.Sprintf("task%d", i))
+	}
+	gomock.InOrder(
+		mc.EXPECT().UpdateTaskProtection(&ecs.UpdateTaskProtectionInput{
+			Cluster:           aws.String(configuredCluster),
+			Tasks:             aws.StringSlice(taskARNs[:updateTaskProtectionMaxTasks]),
+			ProtectionEnabled: aws.Bool(false),
+		}).Return(&ecs.UpdateTaskProtectionOutput{}, nil),
+		mc.EXPECT().UpdateTaskProtection(&ecs.UpdateTaskProtectionInput{
+			Cluster:           aws.String(configuredCluster),
+			Tasks:             aws.StringSlice(taskARNs[updateTaskProtectionMaxTasks:]),
+			ProtectionEnabled: aws.Bool(false),
+		}).Return(&ecs.UpdateTaskProtectionOutput{}, nil),
+	)
+
+	assert.NoError(t, client.DisableTaskProtection(taskARNs))
+}
+
+func TestDisableTaskProtectionFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc, _ := NewMockClient(mockCtrl, ec2.NewBlackholeEC2MetadataClient(), nil)
+
+	mc.EXPECT().UpdateTaskProtection(gomock.Any()).Return(&ecs.UpdateTaskProtectionOutput{
+		Failures: []*ecs.Failure{{Arn: aws.String("task1"), Reason: aws.String("TASK_NOT_VALID")}},
+	}, nil)
+
+	assert.Error(t, client.DisableTaskProtection([]string{"task1"}))
+}
+
+func TestPutAttributes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc, _ := NewMockClient(mockCtrl, ec2.NewBlackholeEC2MetadataClient(), nil)
+
+	instanceARN := "myInstanceARN"
+	var attributes, targetAttributes []*ecs.Attribute
+	for i := 0; i < putAttributesMaxAttributes+1; i++ {
+		name := fmt.Sprintf("attribute%d", i)
+		attributes = append(attributes, &ecs.Attribute{Name: aws.String(name), Value: aws.String("value")})
+		targetAttributes = append(targetAttributes, &ecs.Attribute{
+			Name:       aws.String(name),
+			Value:      aws.String("value"),
+			TargetId:   aws.String(instanceARN),
+			TargetType: aws.String("container-instance"),
+		})
+	}
+	gomock.InOrder(
+		mc.EXPECT().PutAttributes(&ecs.PutAttributesInput{
+			Cluster:    aws.String(configuredCluster),
+			Attributes: targetAttributes[:putAttributesMaxAttributes],
+		}).Return(&ecs.PutAttributesOutput{}, nil),
+		mc.EXPECT().PutAttributes(&ecs.PutAttributesInput{
+			Cluster:    aws.String(configuredCluster),
+			Attributes: targetAttributes[putAttributesMaxAttributes:],
+		}).Return(&ecs.PutAttributesOutput{}, nil),
+	)
+
+	assert.NoError(t, client.PutAttributes(instanceARN, attributes))
+}
+
+func TestPutAttributesError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc, _ := NewMockClient(mockCtrl, ec2.NewBlackholeEC2MetadataClient(), nil)
+
+	mc.EXPECT().PutAttributes(gomock.Any()).Return(nil, errors.New("error"))
+
+	assert.Error(t, client.PutAttributes("myInstanceARN",
+		[]*ecs.Attribute{{Name: aws.String("attribute"), Value: aws.String("value")}}))
+}
+
+func TestDeleteAttributes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc, _ := NewMockClient(mockCtrl, ec2.NewBlackholeEC2MetadataClient(), nil)
+
+	mc.EXPECT().DeleteAttributes(&ecs.DeleteAttributesInput{
+		Cluster: aws.String(configuredCluster),
+		Attributes: []*ecs.Attribute{{
+			Name:       aws.String("attribute"),
+			TargetId:   aws.String("myInstanceARN"),
+			TargetType: aws.String("container-instance"),
+		}},
+	}).Return(&ecs.DeleteAttributesOutput{}, nil)
+
+	assert.NoError(t, client.DeleteAttributes("myInstanceARN", []*ecs.Attribute{{Name: aws.String("attribute")}}))
+}
+
 func TestGetResourceTags(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -963,6 +1081,44 @@ func TestSubmitTaskStateChangeWithAttachments(t *testing.T) {
 	assert.NoError(t, err, "Unable to submit task state change with attachments")
 }
 
+// TestSubmitAttachmentStateChanges tests that the SubmitAttachmentStateChanges API
+// sends all the attachment state changes in a single request
+func TestSubmitAttachmentStateChanges(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	client, _, mockSubmitStateClient := NewMockClient(mockCtrl, ec2.NewBlackholeEC2MetadataClient(), nil)
+	mockSubmitStateClient.EXPECT().SubmitAttachmentStateChanges(&ecs.SubmitAttachmentStateChangesInput{
+		Cluster: aws.String(configuredCluster),
+		Attachments: []*ecs.AttachmentStateChange{
+			{
+				AttachmentArn: aws.String("eni_arn1"),
+				Status:        aws.String("ATTACHED"),
+			},
+			{
+				AttachmentArn: aws.String("eni_arn2"),
+				Status:        aws.String("ATTACHED"),
+			},
+		},
+	}).Return(&ecs.SubmitAttachmentStateChangesOutput{}, nil)
+
+	err := client.SubmitAttachmentStateChanges([]api.AttachmentStateChange{
+		{
+			Attachment: &apieni.ENIAttachment{
+				AttachmentARN: "eni_arn1",
+				Status:        apieni.ENIAttached,
+			},
+		},
+		{
+			Attachment: &apieni.ENIAttachment{
+				AttachmentARN: "eni_arn2",
+				Status:        apieni.ENIAttached,
+			},
+		},
+	})
+	assert.NoError(t, err, "Unable to submit attachment state changes")
+}
+
 func TestSubmitTaskStateChangeWithoutAttachments(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -53,6 +53,8 @@ type ENI struct {
 	// InterfaceVlanProperties contains information for an interface
 	// that is supposed to be used as a VLAN device
 	InterfaceVlanProperties *InterfaceVlanProperties `json:",omitempty"`
+	// InterfaceType is the EC2 type of the ENI, valid value: "interface", "efa"
+	InterfaceType string `json:",omitempty"`
 
 	// Due to historical reasons, the IPv4 subnet prefix length is sent with IPv4 subnet gateway
 	// address instead of the ENI's IP addresses. However, CNI plugins and many OS APIs expect it
@@ -80,12 +82,28 @@ const (
 	// VLANInterfaceAssociationProtocol represents the ENI with trunking enabled.
 	VLANInterfaceAssociationProtocol = "vlan"
 
+	// DefaultInterfaceType represents the standard ENI type.
+	DefaultInterfaceType = "interface"
+
+	// EFAInterfaceType represents the ENI with an Elastic Fabric Adapter.
+	EFAInterfaceType = "efa"
+
 	// IPv6SubnetPrefixLength is the IPv6 global unicast address prefix length, consisting of
 	// global routing prefix and subnet ID lengths as specified in IPv6 addressing architecture
 	// (RFC 4291 section 2.5.4) and IPv6 Global Unicast Address Format (RFC 3587).
 	// The ACS ENI payload structure does not contain an IPv6 subnet prefix length because "/64" is
 	// the only allowed length per RFCs above, and the only one that VPC supports.
 	IPv6SubnetPrefixLength = "64"
+
+	// AmazonIPv6DNSServer is the IPv6 address of the Amazon provided DNS server (Route 53
+	// Resolver). It is reachable from any IPv6 enabled subnet and performs DNS64 synthesis
+	// for IPv6-only subnets that have it enabled, allowing tasks to resolve IPv4-only
+	// destinations through the VPC's NAT64 gateway.
+	AmazonIPv6DNSServer = "fd00:ec2::253"
+
+	// AmazonIPv4DNSServer is the IPv4 link-local address of the Amazon provided DNS server,
+	// reachable from any subnet with an IPv4 CIDR block
+	AmazonIPv4DNSServer = "169.254.169.253"
 )
 
 var (
@@ -190,6 +208,60 @@ func (eni *ENI) GetSubnetGatewayIPv4Address() string {
 	return gwAddr
 }
 
+// GetSubnetGatewayIPv6Address returns the subnet gateway IPv6 address for the ENI. The VPC
+// router is always reachable at the first host address of the ENI's IPv6 subnet.
+func (eni *ENI) GetSubnetGatewayIPv6Address() string {
+	subnet := eni.GetIPv6SubnetCIDRBlock()
+	if subnet == "" {
+		return ""
+	}
+	ip, _, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return ""
+	}
+	ip[len(ip)-1] = 1
+
+	return ip.String()
+}
+
+// GetSubnetGatewayIPAddresses returns the subnet gateway addresses that task network
+// configuration should route through. IPv6-only ENIs have no IPv4 gateway, so the IPv6
+// gateway is used instead.
+func (eni *ENI) GetSubnetGatewayIPAddresses() []string {
+	if eni.IPv6Only() {
+		return []string{eni.GetSubnetGatewayIPv6Address()}
+	}
+
+	return []string{eni.GetSubnetGatewayIPv4Address()}
+}
+
+// IPv6Only returns true if the ENI has IPv6 addresses and no IPv4 addresses.
+func (eni *ENI) IPv6Only() bool {
+	return len(eni.IPV4Addresses) == 0 && len(eni.IPV6Addresses) > 0
+}
+
+// GetDomainNameServers returns the nameservers tasks using the ENI should be configured
+// with. IPv4 nameservers are unreachable from an IPv6-only ENI, so they are dropped and
+// the Amazon provided DNS64 capable server is used when no IPv6 nameserver remains.
+func (eni *ENI) GetDomainNameServers() []string {
+	if !eni.IPv6Only() {
+		return eni.DomainNameServers
+	}
+
+	var nameservers []string
+	for _, nameserver := range eni.DomainNameServers {
+		ip := net.ParseIP(nameserver)
+		if ip != nil && ip.To4() == nil {
+			nameservers = append(nameservers, nameserver)
+		}
+	}
+	if len(nameservers) == 0 {
+		nameservers = []string{AmazonIPv6DNSServer}
+	}
+
+	return nameservers
+}
+
 // GetHostname returns the hostname assigned to the ENI
 func (eni *ENI) GetHostname() string {
 	return eni.PrivateDNSName
@@ -318,6 +390,7 @@ func ENIFromACS(acsENI *ecsacs.ElasticNetworkInterface) (*ENI, error) {
 		PrivateDNSName:               aws.StringValue(acsENI.PrivateDnsName),
 		InterfaceAssociationProtocol: aws.StringValue(acsENI.InterfaceAssociationProtocol),
 		InterfaceVlanProperties:      &interfaceVlanProperties,
+		InterfaceType:                aws.StringValue(acsENI.InterfaceType),
 	}
 
 	for _, nameserverIP := range acsENI.DomainNameServers {
@@ -332,19 +405,31 @@ func ENIFromACS(acsENI *ecsacs.ElasticNetworkInterface) (*ENI, error) {
 
 // ValidateTaskENI validates the ENI information sent from ACS.
 func ValidateTaskENI(acsENI *ecsacs.ElasticNetworkInterface) error {
-	// At least one IPv4 address should be associated with the ENI.
+	// At least one IPv4 address should be associated with the ENI, unless the
+	// ENI is IPv6-only, in which case at least one IPv6 address is required.
 	if len(acsENI.Ipv4Addresses) < 1 {
-		return errors.Errorf("eni message validation: no ipv4 addresses in the message")
+		if len(acsENI.Ipv6Addresses) < 1 {
+			return errors.Errorf("eni message validation: no ipv4 or ipv6 addresses in the message")
+		}
+		for _, ipv6Addr := range acsENI.Ipv6Addresses {
+			if net.ParseIP(aws.StringValue(ipv6Addr.Address)) == nil {
+				return errors.Errorf(
+					"eni message validation: invalid ipv6 address %s", aws.StringValue(ipv6Addr.Address))
+			}
+		}
 	}
 
-	if acsENI.SubnetGatewayIpv4Address == nil {
-		return errors.Errorf("eni message validation: no subnet gateway ipv4 address in the message")
-	}
-	gwIPv4Addr := aws.StringValue(acsENI.SubnetGatewayIpv4Address)
-	s := strings.Split(gwIPv4Addr, "/")
-	if len(s) != 2 {
-		return errors.Errorf(
-			"eni message validation: invalid subnet gateway ipv4 address %s", gwIPv4Addr)
+	// IPv6-only ENIs do not have an IPv4 subnet gateway.
+	if len(acsENI.Ipv4Addresses) > 0 || acsENI.SubnetGatewayIpv4Address != nil {
+		if acsENI.SubnetGatewayIpv4Address == nil {
+			return errors.Errorf("eni message validation: no subnet gateway ipv4 address in the message")
+		}
+		gwIPv4Addr := aws.StringValue(acsENI.SubnetGatewayIpv4Address)
+		s := strings.Split(gwIPv4Addr, "/")
+		if len(s) != 2 {
+			return errors.Errorf(
+				"eni message validation: invalid subnet gateway ipv4 address %s", gwIPv4Addr)
+		}
 	}
 
 	if acsENI.MacAddress == nil {
@@ -372,5 +457,22 @@ func ValidateTaskENI(acsENI *ecsacs.ElasticNetworkInterface) error {
 		}
 	}
 
+	// The interface type, if specified, must be a supported value. An EFA can't be a branch
+	// of a trunk interface.
+	switch aws.StringValue(acsENI.InterfaceType) {
+	case "", DefaultInterfaceType:
+	case EFAInterfaceType:
+		if aws.StringValue(acsENI.InterfaceAssociationProtocol) == VLANInterfaceAssociationProtocol {
+			return errors.New("efa interfaces can't be associated as vlan interfaces")
+		}
+	default:
+		return errors.Errorf("invalid interface type: %s", aws.StringValue(acsENI.InterfaceType))
+	}
+
 	return nil
 }
+
+// IsEFA returns whether the ENI has an Elastic Fabric Adapter
+func (eni *ENI) IsEFA() bool {
+	return eni.InterfaceType == EFAInterfaceType
+}
@@ -147,6 +147,38 @@ func TestGetSubnetGatewayIPv4Address(t *testing.T) {
 	assert.Equal(t, ipv4Gw, testENI.GetSubnetGatewayIPv4Address())
 }
 
+func TestGetSubnetGatewayIPv6Address(t *testing.T) {
+	assert.Equal(t, "abcd:dcba:1234:4321::1", testENI.GetSubnetGatewayIPv6Address())
+	assert.Empty(t, (&ENI{}).GetSubnetGatewayIPv6Address())
+}
+
+func TestIPv6Only(t *testing.T) {
+	assert.False(t, testENI.IPv6Only())
+	assert.True(t, getTestIPv6OnlyENI().IPv6Only())
+	assert.False(t, (&ENI{}).IPv6Only())
+}
+
+func TestGetSubnetGatewayIPAddresses(t *testing.T) {
+	assert.Equal(t, []string{ipv4Gw}, testENI.GetSubnetGatewayIPAddresses())
+	assert.Equal(t, []string{"abcd:dcba:1234:4321::1"}, getTestIPv6OnlyENI().GetSubnetGatewayIPAddresses())
+}
+
+func TestGetDomainNameServers(t *testing.T) {
+	dualStackENI := &ENI{
+		IPV4Addresses:     testENI.IPV4Addresses,
+		IPV6Addresses:     testENI.IPV6Addresses,
+		DomainNameServers: []string{defaultDNS, customDNS},
+	}
+	assert.Equal(t, []string{defaultDNS, customDNS}, dualStackENI.GetDomainNameServers())
+
+	ipv6OnlyENI := getTestIPv6OnlyENI()
+	ipv6OnlyENI.DomainNameServers = []string{defaultDNS, customDNS}
+	assert.Equal(t, []string{AmazonIPv6DNSServer}, ipv6OnlyENI.GetDomainNameServers())
+
+	ipv6OnlyENI.DomainNameServers = []string{defaultDNS, "2001:db8::53"}
+	assert.Equal(t, []string{"2001:db8::53"}, ipv6OnlyENI.GetDomainNameServers())
+}
+
 // TestGetLinkNameSuccess tests the retrieval of ENIs name on the instance.
 func TestGetLinkNameSuccess(t *testing.T) {
 	netInterfaces = validNetInterfacesFunc
@@ -212,6 +244,49 @@ func TestValidateENIFromACS(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// TestValidateIPv6OnlyENIFromACS tests the validation of IPv6-only enis from acs
+func TestValidateIPv6OnlyENIFromACS(t *testing.T) {
+	acsENI := getTestACSENI()
+	acsENI.Ipv4Addresses = nil
+	acsENI.SubnetGatewayIpv4Address = nil
+	eni, err := ENIFromACS(acsENI)
+	assert.NoError(t, err)
+	assert.True(t, eni.IPv6Only())
+	assert.Empty(t, eni.GetSubnetGatewayIPv4Address())
+
+	acsENI.Ipv6Addresses[0].Address = aws.String("ipv6")
+	err = ValidateTaskENI(acsENI)
+	assert.Error(t, err)
+}
+
+func TestEFAENIFromACS(t *testing.T) {
+	acsENI := getTestACSENI()
+	acsENI.InterfaceType = aws.String(EFAInterfaceType)
+	eni, err := ENIFromACS(acsENI)
+	assert.NoError(t, err)
+	assert.True(t, eni.IsEFA())
+
+	acsENI.InterfaceType = aws.String(DefaultInterfaceType)
+	eni, err = ENIFromACS(acsENI)
+	assert.NoError(t, err)
+	assert.False(t, eni.IsEFA())
+}
+
+func TestInvalidENIInterfaceType(t *testing.T) {
+	acsENI := getTestACSENI()
+	acsENI.InterfaceType = aws.String("trunk")
+	assert.Error(t, ValidateTaskENI(acsENI))
+
+	// an EFA can't be a branch of a trunk interface
+	acsENI.InterfaceType = aws.String(EFAInterfaceType)
+	acsENI.InterfaceAssociationProtocol = aws.String(VLANInterfaceAssociationProtocol)
+	acsENI.InterfaceVlanProperties = &ecsacs.NetworkInterfaceVlanProperties{
+		VlanId:                   aws.String("12345"),
+		TrunkInterfaceMacAddress: aws.String("macTrunk"),
+	}
+	assert.Error(t, ValidateTaskENI(acsENI))
+}
+
 func TestInvalidENIInterfaceVlanPropertyMissing(t *testing.T) {
 	acsENI := &ecsacs.ElasticNetworkInterface{
 		InterfaceAssociationProtocol: aws.String(VLANInterfaceAssociationProtocol),
@@ -267,6 +342,18 @@ func TestInvalidSubnetGatewayAddress(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func getTestIPv6OnlyENI() *ENI {
+	return &ENI{
+		ID:                           "eni-123",
+		InterfaceAssociationProtocol: DefaultInterfaceAssociationProtocol,
+		IPV6Addresses: []*ENIIPV6Address{
+			{
+				Address: ipv6Addr,
+			},
+		},
+	}
+}
+
 func getTestACSENI() *ecsacs.ElasticNetworkInterface {
 	return &ecsacs.ElasticNetworkInterface{
 		AttachmentArn: aws.String("arn"),
@@ -97,6 +97,30 @@ func (eni *ENIAttachment) Initialize(timeoutFunc func()) error {
 	return nil
 }
 
+// GetAttachmentARN returns the attachment arn of the ENI attachment
+func (eni *ENIAttachment) GetAttachmentARN() string {
+	eni.guard.RLock()
+	defer eni.guard.RUnlock()
+
+	return eni.AttachmentARN
+}
+
+// GetAttachmentType returns the type of the ENI attachment
+func (eni *ENIAttachment) GetAttachmentType() string {
+	eni.guard.RLock()
+	defer eni.guard.RUnlock()
+
+	return eni.AttachmentType
+}
+
+// GetAttachmentStatus returns the status of the ENI attachment
+func (eni *ENIAttachment) GetAttachmentStatus() string {
+	eni.guard.RLock()
+	defer eni.guard.RUnlock()
+
+	return eni.Status.String()
+}
+
 // IsSent checks if the eni attached status has been sent
 func (eni *ENIAttachment) IsSent() bool {
 	eni.guard.RLock()
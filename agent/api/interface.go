@@ -35,9 +35,9 @@ type ECSClient interface {
 	// SubmitContainerStateChange sends a state change and returns an error
 	// indicating if it was submitted
 	SubmitContainerStateChange(change ContainerStateChange) error
-	// SubmitAttachmentStateChange sends an attachment state change and returns an error
-	// indicating if it was submitted
-	SubmitAttachmentStateChange(change AttachmentStateChange) error
+	// SubmitAttachmentStateChanges sends a batch of attachment state changes and
+	// returns an error indicating if they were submitted
+	SubmitAttachmentStateChanges(changes []AttachmentStateChange) error
 	// DiscoverPollEndpoint takes a ContainerInstanceARN and returns the
 	// endpoint at which this Agent should contact ACS
 	DiscoverPollEndpoint(containerInstanceArn string) (string, error)
@@ -49,6 +49,15 @@ type ECSClient interface {
 	// UpdateContainerInstancesState updates the given container Instance ID with
 	// the given status. Only valid statuses are ACTIVE and DRAINING.
 	UpdateContainerInstancesState(instanceARN, status string) error
+	// GetProtectedTasks returns the ARNs of the given tasks whose scale-in
+	// protection is enabled
+	GetProtectedTasks(taskARNs []string) ([]string, error)
+	// DisableTaskProtection disables the scale-in protection of the given tasks
+	DisableTaskProtection(taskARNs []string) error
+	// PutAttributes creates or updates the given attributes of a container instance
+	PutAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error
+	// DeleteAttributes deletes the given attributes of a container instance
+	DeleteAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error
 }
 
 // ECSSDK is an interface that specifies the subset of the AWS Go SDK's ECS
@@ -60,6 +69,10 @@ type ECSSDK interface {
 	DiscoverPollEndpoint(*ecs.DiscoverPollEndpointInput) (*ecs.DiscoverPollEndpointOutput, error)
 	ListTagsForResource(*ecs.ListTagsForResourceInput) (*ecs.ListTagsForResourceOutput, error)
 	UpdateContainerInstancesState(input *ecs.UpdateContainerInstancesStateInput) (*ecs.UpdateContainerInstancesStateOutput, error)
+	GetTaskProtection(input *ecs.GetTaskProtectionInput) (*ecs.GetTaskProtectionOutput, error)
+	UpdateTaskProtection(input *ecs.UpdateTaskProtectionInput) (*ecs.UpdateTaskProtectionOutput, error)
+	PutAttributes(input *ecs.PutAttributesInput) (*ecs.PutAttributesOutput, error)
+	DeleteAttributes(input *ecs.DeleteAttributesInput) (*ecs.DeleteAttributesOutput, error)
 }
 
 // ECSSubmitStateSDK is an interface with customized ecs client that
@@ -64,6 +64,21 @@ func (mr *MockECSSDKMockRecorder) CreateCluster(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCluster", reflect.TypeOf((*MockECSSDK)(nil).CreateCluster), arg0)
 }
 
+// DeleteAttributes mocks base method
+func (m *MockECSSDK) DeleteAttributes(arg0 *ecs.DeleteAttributesInput) (*ecs.DeleteAttributesOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAttributes", arg0)
+	ret0, _ := ret[0].(*ecs.DeleteAttributesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAttributes indicates an expected call of DeleteAttributes
+func (mr *MockECSSDKMockRecorder) DeleteAttributes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAttributes", reflect.TypeOf((*MockECSSDK)(nil).DeleteAttributes), arg0)
+}
+
 // DiscoverPollEndpoint mocks base method
 func (m *MockECSSDK) DiscoverPollEndpoint(arg0 *ecs.DiscoverPollEndpointInput) (*ecs.DiscoverPollEndpointOutput, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +94,21 @@ func (mr *MockECSSDKMockRecorder) DiscoverPollEndpoint(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiscoverPollEndpoint", reflect.TypeOf((*MockECSSDK)(nil).DiscoverPollEndpoint), arg0)
 }
 
+// GetTaskProtection mocks base method
+func (m *MockECSSDK) GetTaskProtection(arg0 *ecs.GetTaskProtectionInput) (*ecs.GetTaskProtectionOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTaskProtection", arg0)
+	ret0, _ := ret[0].(*ecs.GetTaskProtectionOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTaskProtection indicates an expected call of GetTaskProtection
+func (mr *MockECSSDKMockRecorder) GetTaskProtection(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTaskProtection", reflect.TypeOf((*MockECSSDK)(nil).GetTaskProtection), arg0)
+}
+
 // ListTagsForResource mocks base method
 func (m *MockECSSDK) ListTagsForResource(arg0 *ecs.ListTagsForResourceInput) (*ecs.ListTagsForResourceOutput, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +124,21 @@ func (mr *MockECSSDKMockRecorder) ListTagsForResource(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTagsForResource", reflect.TypeOf((*MockECSSDK)(nil).ListTagsForResource), arg0)
 }
 
+// PutAttributes mocks base method
+func (m *MockECSSDK) PutAttributes(arg0 *ecs.PutAttributesInput) (*ecs.PutAttributesOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutAttributes", arg0)
+	ret0, _ := ret[0].(*ecs.PutAttributesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutAttributes indicates an expected call of PutAttributes
+func (mr *MockECSSDKMockRecorder) PutAttributes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutAttributes", reflect.TypeOf((*MockECSSDK)(nil).PutAttributes), arg0)
+}
+
 // RegisterContainerInstance mocks base method
 func (m *MockECSSDK) RegisterContainerInstance(arg0 *ecs.RegisterContainerInstanceInput) (*ecs.RegisterContainerInstanceOutput, error) {
 	m.ctrl.T.Helper()
@@ -124,6 +169,21 @@ func (mr *MockECSSDKMockRecorder) UpdateContainerInstancesState(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateContainerInstancesState", reflect.TypeOf((*MockECSSDK)(nil).UpdateContainerInstancesState), arg0)
 }
 
+// UpdateTaskProtection mocks base method
+func (m *MockECSSDK) UpdateTaskProtection(arg0 *ecs.UpdateTaskProtectionInput) (*ecs.UpdateTaskProtectionOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTaskProtection", arg0)
+	ret0, _ := ret[0].(*ecs.UpdateTaskProtectionOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTaskProtection indicates an expected call of UpdateTaskProtection
+func (mr *MockECSSDKMockRecorder) UpdateTaskProtection(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTaskProtection", reflect.TypeOf((*MockECSSDK)(nil).UpdateTaskProtection), arg0)
+}
+
 // MockECSSubmitStateSDK is a mock of ECSSubmitStateSDK interface
 type MockECSSubmitStateSDK struct {
 	ctrl     *gomock.Controller
@@ -215,6 +275,34 @@ func (m *MockECSClient) EXPECT() *MockECSClientMockRecorder {
 	return m.recorder
 }
 
+// DeleteAttributes mocks base method
+func (m *MockECSClient) DeleteAttributes(arg0 string, arg1 []*ecs.Attribute) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAttributes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAttributes indicates an expected call of DeleteAttributes
+func (mr *MockECSClientMockRecorder) DeleteAttributes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAttributes", reflect.TypeOf((*MockECSClient)(nil).DeleteAttributes), arg0, arg1)
+}
+
+// DisableTaskProtection mocks base method
+func (m *MockECSClient) DisableTaskProtection(arg0 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTaskProtection", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTaskProtection indicates an expected call of DisableTaskProtection
+func (mr *MockECSClientMockRecorder) DisableTaskProtection(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTaskProtection", reflect.TypeOf((*MockECSClient)(nil).DisableTaskProtection), arg0)
+}
+
 // DiscoverPollEndpoint mocks base method
 func (m *MockECSClient) DiscoverPollEndpoint(arg0 string) (string, error) {
 	m.ctrl.T.Helper()
@@ -245,6 +333,21 @@ func (mr *MockECSClientMockRecorder) DiscoverTelemetryEndpoint(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiscoverTelemetryEndpoint", reflect.TypeOf((*MockECSClient)(nil).DiscoverTelemetryEndpoint), arg0)
 }
 
+// GetProtectedTasks mocks base method
+func (m *MockECSClient) GetProtectedTasks(arg0 []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProtectedTasks", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProtectedTasks indicates an expected call of GetProtectedTasks
+func (mr *MockECSClientMockRecorder) GetProtectedTasks(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProtectedTasks", reflect.TypeOf((*MockECSClient)(nil).GetProtectedTasks), arg0)
+}
+
 // GetResourceTags mocks base method
 func (m *MockECSClient) GetResourceTags(arg0 string) ([]*ecs.Tag, error) {
 	m.ctrl.T.Helper()
@@ -260,6 +363,20 @@ func (mr *MockECSClientMockRecorder) GetResourceTags(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResourceTags", reflect.TypeOf((*MockECSClient)(nil).GetResourceTags), arg0)
 }
 
+// PutAttributes mocks base method
+func (m *MockECSClient) PutAttributes(arg0 string, arg1 []*ecs.Attribute) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutAttributes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutAttributes indicates an expected call of PutAttributes
+func (mr *MockECSClientMockRecorder) PutAttributes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutAttributes", reflect.TypeOf((*MockECSClient)(nil).PutAttributes), arg0, arg1)
+}
+
 // RegisterContainerInstance mocks base method
 func (m *MockECSClient) RegisterContainerInstance(arg0 string, arg1 []*ecs.Attribute, arg2 []*ecs.Tag, arg3 string, arg4 []*ecs.PlatformDevice, arg5 string) (string, string, error) {
 	m.ctrl.T.Helper()
@@ -276,18 +393,18 @@ func (mr *MockECSClientMockRecorder) RegisterContainerInstance(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterContainerInstance", reflect.TypeOf((*MockECSClient)(nil).RegisterContainerInstance), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
-// SubmitAttachmentStateChange mocks base method
-func (m *MockECSClient) SubmitAttachmentStateChange(arg0 api.AttachmentStateChange) error {
+// SubmitAttachmentStateChanges mocks base method
+func (m *MockECSClient) SubmitAttachmentStateChanges(arg0 []api.AttachmentStateChange) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SubmitAttachmentStateChange", arg0)
+	ret := m.ctrl.Call(m, "SubmitAttachmentStateChanges", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SubmitAttachmentStateChange indicates an expected call of SubmitAttachmentStateChange
-func (mr *MockECSClientMockRecorder) SubmitAttachmentStateChange(arg0 interface{}) *gomock.Call {
+// SubmitAttachmentStateChanges indicates an expected call of SubmitAttachmentStateChanges
+func (mr *MockECSClientMockRecorder) SubmitAttachmentStateChanges(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitAttachmentStateChange", reflect.TypeOf((*MockECSClient)(nil).SubmitAttachmentStateChange), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitAttachmentStateChanges", reflect.TypeOf((*MockECSClient)(nil).SubmitAttachmentStateChanges), arg0)
 }
 
 // SubmitContainerStateChange mocks base method
@@ -18,6 +18,7 @@ import (
 	"strconv"
 	"time"
 
+	apiattachment "github.com/aws/amazon-ecs-agent/agent/api/attachment"
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
@@ -97,8 +98,8 @@ type TaskStateChange struct {
 // AttachmentStateChange represents a state change that needs to be sent to the
 // SubmitAttachmentStateChanges API
 type AttachmentStateChange struct {
-	// Attachment is the eni attachment object to send
-	Attachment *apieni.ENIAttachment
+	// Attachment is the attachment object to send
+	Attachment apiattachment.Attachment
 }
 
 // NewTaskStateChangeEvent creates a new task state change event
@@ -200,9 +201,9 @@ func NewManagedAgentChangeEvent(task *apitask.Task, cont *apicontainer.Container
 }
 
 // NewAttachmentStateChangeEvent creates a new attachment state change event
-func NewAttachmentStateChangeEvent(eniAttachment *apieni.ENIAttachment) AttachmentStateChange {
+func NewAttachmentStateChangeEvent(attachment apiattachment.Attachment) AttachmentStateChange {
 	return AttachmentStateChange{
-		Attachment: eniAttachment,
+		Attachment: attachment,
 	}
 }
 
@@ -296,7 +297,7 @@ func (change *TaskStateChange) String() string {
 // String returns a human readable string representation of this object
 func (change *AttachmentStateChange) String() string {
 	if change.Attachment != nil {
-		return fmt.Sprintf("%s -> %s, %s", change.Attachment.AttachmentARN, change.Attachment.Status.String(),
+		return fmt.Sprintf("%s -> %s, %s", change.Attachment.GetAttachmentARN(), change.Attachment.GetAttachmentStatus(),
 			change.Attachment.String())
 	}
 
@@ -0,0 +1,73 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"fmt"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/cihub/seelog"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+const (
+	// ContainerRuntimeGVisor is the name of the gVisor runtime
+	ContainerRuntimeGVisor = "runsc"
+	// ContainerRuntimeKata is the name of the Kata Containers runtime
+	ContainerRuntimeKata = "kata-runtime"
+)
+
+// IsSupportedContainerRuntime returns true if the runtime is one of the sandboxed
+// runtimes task definitions can request. Other runtimes configured in docker, such
+// as the GPU runtimes, are chosen by the agent instead.
+func IsSupportedContainerRuntime(runtime string) bool {
+	switch runtime {
+	case ContainerRuntimeGVisor, ContainerRuntimeKata:
+		return true
+	}
+	return false
+}
+
+// overrideSandboxedContainerRuntime runs the container with the runtime requested by
+// the task. The sandboxed runtimes can't pass GPU or Inferentia devices to containers,
+// so the containers that need them fail instead of running without them. The internal
+// containers, such as the pause container, keep the default runtime: the containers of
+// a task can't share the PID and IPC namespaces of the namespace pause container from
+// within a sandbox, and the Kata VMs can't join the network namespace of the awsvpc
+// pause container either, so these tasks fail too.
+func (task *Task) overrideSandboxedContainerRuntime(container *apicontainer.Container,
+	hostCfg *dockercontainer.HostConfig, cfg *config.Config) *apierrors.HostConfigError {
+	if !IsSupportedContainerRuntime(task.ContainerRuntime) {
+		return &apierrors.HostConfigError{Msg: fmt.Sprintf("unsupported container runtime %q", task.ContainerRuntime)}
+	}
+	if (task.isGPUEnabled() && task.shouldRequireNvidiaRuntime(container)) ||
+		(cfg.InferentiaSupportEnabled && container.RequireNeuronRuntime()) {
+		return &apierrors.HostConfigError{Msg: fmt.Sprintf(
+			"container runtime %s can't be used by container %s, which requires accelerator devices",
+			task.ContainerRuntime, container.Name)}
+	}
+	if task.getPIDMode() == pidModeTask || task.getIPCMode() == ipcModeTask {
+		return &apierrors.HostConfigError{Msg: fmt.Sprintf(
+			"container runtime %s can't be used by tasks sharing their PID or IPC namespace", task.ContainerRuntime)}
+	}
+	if task.ContainerRuntime == ContainerRuntimeKata && task.IsNetworkModeAWSVPC() {
+		return &apierrors.HostConfigError{Msg: fmt.Sprintf(
+			"container runtime %s can't be used by tasks with the awsvpc network mode", task.ContainerRuntime)}
+	}
+	seelog.Debugf("Setting runtime as %s for container %s", task.ContainerRuntime, container.Name)
+	hostCfg.Runtime = task.ContainerRuntime
+	return nil
+}
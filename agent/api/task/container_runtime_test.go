@@ -0,0 +1,120 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newContainerRuntimeTestTask(runtime string) *Task {
+	return &Task{
+		Arn:              "test",
+		ContainerRuntime: runtime,
+		Containers: []*apicontainer.Container{
+			{
+				Name:  "app",
+				Image: "image:tag",
+			},
+			{
+				Name: NetworkPauseContainerName,
+				Type: apicontainer.ContainerCNIPause,
+			},
+		},
+	}
+}
+
+func TestDockerHostConfigContainerRuntime(t *testing.T) {
+	for _, runtime := range []string{ContainerRuntimeGVisor, ContainerRuntimeKata} {
+		t.Run(runtime, func(t *testing.T) {
+			testTask := newContainerRuntimeTestTask(runtime)
+			dockerHostConfig, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+				defaultDockerClientAPIVersion, &config.Config{})
+			require.Nil(t, err)
+			assert.Equal(t, runtime, dockerHostConfig.Runtime)
+		})
+	}
+}
+
+func TestDockerHostConfigContainerRuntimeInternalContainer(t *testing.T) {
+	testTask := newContainerRuntimeTestTask(ContainerRuntimeGVisor)
+	dockerHostConfig, err := testTask.DockerHostConfig(testTask.Containers[1], dockerMap(testTask),
+		defaultDockerClientAPIVersion, &config.Config{})
+	require.Nil(t, err)
+	assert.Empty(t, dockerHostConfig.Runtime)
+}
+
+func TestDockerHostConfigUnsupportedContainerRuntime(t *testing.T) {
+	// The accelerator runtimes are chosen by the agent, not by the task definition
+	testTask := newContainerRuntimeTestTask(config.DefaultNvidiaRuntime)
+	_, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+		defaultDockerClientAPIVersion, &config.Config{})
+	assert.NotNil(t, err)
+}
+
+func TestDockerHostConfigContainerRuntimeWithNeuronDevices(t *testing.T) {
+	testTask := newContainerRuntimeTestTask(ContainerRuntimeKata)
+	testTask.Containers[0].Environment = map[string]string{
+		"AWS_NEURON_VISIBLE_DEVICES": "all",
+	}
+	_, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+		defaultDockerClientAPIVersion, &config.Config{InferentiaSupportEnabled: true})
+	assert.NotNil(t, err)
+}
+
+func TestDockerHostConfigContainerRuntimeSharedNamespaces(t *testing.T) {
+	for _, runtime := range []string{ContainerRuntimeGVisor, ContainerRuntimeKata} {
+		t.Run(runtime, func(t *testing.T) {
+			testTask := newContainerRuntimeTestTask(runtime)
+			testTask.PIDMode = pidModeTask
+			_, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+				defaultDockerClientAPIVersion, &config.Config{})
+			assert.NotNil(t, err, "sandboxed containers can't share the namespaces of the pause container")
+		})
+	}
+}
+
+func TestDockerHostConfigContainerRuntimeAWSVPC(t *testing.T) {
+	testTask := newContainerRuntimeTestTask(ContainerRuntimeKata)
+	testTask.ENIs = []*apieni.ENI{{ID: "eni-id"}}
+	_, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+		defaultDockerClientAPIVersion, &config.Config{})
+	assert.NotNil(t, err, "Kata containers can't join the network namespace of the pause container")
+
+	testTask = newContainerRuntimeTestTask(ContainerRuntimeGVisor)
+	testTask.ENIs = []*apieni.ENI{{ID: "eni-id"}}
+	dockerHostConfig, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+		defaultDockerClientAPIVersion, &config.Config{})
+	require.Nil(t, err)
+	assert.Equal(t, ContainerRuntimeGVisor, dockerHostConfig.Runtime)
+}
+
+func TestTaskFromACSContainerRuntime(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		ContainerRuntime: aws.String(ContainerRuntimeGVisor),
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	require.NoError(t, err)
+	assert.Equal(t, ContainerRuntimeGVisor, task.ContainerRuntime)
+}
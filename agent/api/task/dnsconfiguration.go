@@ -0,0 +1,56 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
+)
+
+// DNSConfiguration is the DNS configuration of an awsvpc task
+type DNSConfiguration struct {
+	// LocalCache specifies whether the queries of the task are answered by a cache the
+	// agent runs in its network namespace
+	LocalCache bool `json:"LocalCache,omitempty"`
+	// MaxQueriesPerSecond is the number of queries per second the cache forwards to the
+	// resolvers of the task. The instance level limit applies when it's not set
+	MaxQueriesPerSecond int `json:"MaxQueriesPerSecond,omitempty"`
+	// CacheSize is the number of responses the cache holds
+	CacheSize int `json:"CacheSize,omitempty"`
+}
+
+// UsesLocalDNSCache returns true if the queries of the task are answered by a DNS cache
+// in its network namespace, which is only available to awsvpc tasks
+func (task *Task) UsesLocalDNSCache() bool {
+	return task.IsNetworkModeAWSVPC() && task.DNSConfiguration != nil && task.DNSConfiguration.LocalCache
+}
+
+// GetDNSCache returns the DNS cache of the task once it's started, and the pid of the
+// process in whose network namespace it was started. The pid is kept across agent
+// restarts, so that the cache can be started again.
+func (task *Task) GetDNSCache() (*dnscache.Cache, string) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	return task.dnsCache, task.DNSCachePIDUnsafe
+}
+
+// SetDNSCache records the DNS cache of the task and the pid of the process in whose
+// network namespace it was started
+func (task *Task) SetDNSCache(cache *dnscache.Cache, pid string) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	task.dnsCache = cache
+	task.DNSCachePIDUnsafe = pid
+}
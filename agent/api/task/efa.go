@@ -0,0 +1,115 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+)
+
+const (
+	// efaDeviceCgroupPermissions allows containers to read and write their EFA devices
+	efaDeviceCgroupPermissions = "rw"
+	// memlockUlimit is the ulimit of the memory containers may lock, which libfabric needs
+	// to register the memory of the EFA queues
+	memlockUlimit = "memlock"
+	// unlimited is the value of the ulimits without limit
+	unlimited = -1
+)
+
+// EFA is the Elastic Fabric Adapter of a task, which is the ENI of the task when it is
+// attached as an EFA
+type EFA struct {
+	// Devices are the paths of the verbs device files of the EFA
+	Devices []string `json:"Devices"`
+	// LibfabricPath is the directory libfabric is installed in on the instance, mounted
+	// read-only in the containers that use the EFA. It's empty when libfabric isn't installed,
+	// in which case it must be in the images of the containers
+	LibfabricPath string `json:"LibfabricPath,omitempty"`
+}
+
+// RequiresEFA returns whether a container of the task uses the EFA of the task
+func (task *Task) RequiresEFA() bool {
+	for _, container := range task.Containers {
+		if container.EFAEnabled {
+			return true
+		}
+	}
+	return false
+}
+
+// GetEFA returns the EFA of the task, once its devices are looked up
+func (task *Task) GetEFA() *EFA {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	return task.EFAUnsafe
+}
+
+// SetEFA records the EFA of the task
+func (task *Task) SetEFA(efa *EFA) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	task.EFAUnsafe = efa
+}
+
+// validateEFA validates that the ENI of a task whose containers use an EFA is attached as
+// an EFA
+func (task *Task) validateEFA() error {
+	if !task.RequiresEFA() {
+		return nil
+	}
+	eni := task.GetPrimaryENI()
+	if eni == nil {
+		return errors.New("containers use an EFA, but the task doesn't use the awsvpc network mode")
+	}
+	if !eni.IsEFA() {
+		return errors.New("containers use an EFA, but the network interface of the task isn't attached as an EFA")
+	}
+	return nil
+}
+
+// addEFADevices passes the EFA devices of the task to a container that uses them, mounts
+// libfabric in it, and lifts the limit of the memory it may lock
+func (task *Task) addEFADevices(container *apicontainer.Container, hostConfig *dockercontainer.HostConfig) {
+	efa := task.GetEFA()
+	if !container.EFAEnabled || efa == nil {
+		return
+	}
+	for _, device := range efa.Devices {
+		hostConfig.Devices = append(hostConfig.Devices, dockercontainer.DeviceMapping{
+			PathOnHost:        device,
+			PathInContainer:   device,
+			CgroupPermissions: efaDeviceCgroupPermissions,
+		})
+	}
+	if efa.LibfabricPath != "" {
+		hostConfig.Binds = append(hostConfig.Binds, efa.LibfabricPath+":"+efa.LibfabricPath+":ro")
+	}
+	for _, ulimit := range hostConfig.Ulimits {
+		if ulimit.Name == memlockUlimit {
+			// the limit set in the task definition takes precedence
+			return
+		}
+	}
+	hostConfig.Ulimits = append(hostConfig.Ulimits, &units.Ulimit{
+		Name: memlockUlimit,
+		Soft: unlimited,
+		Hard: unlimited,
+	})
+}
@@ -0,0 +1,105 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/aws-sdk-go/aws"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func efaTask(interfaceType string) *Task {
+	return &Task{
+		Arn: "test",
+		ENIs: []*apieni.ENI{
+			{ID: "eni-1", MacAddress: "0a:1b:2c:3d:4e:5f", InterfaceType: interfaceType},
+		},
+		Containers: []*apicontainer.Container{
+			{Name: "mpi", Image: "image:tag", EFAEnabled: true},
+			{Name: "sidecar", Image: "image:tag"},
+		},
+	}
+}
+
+func TestTaskFromACSEFAEnabled(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{Name: aws.String("mpi"), EfaEnabled: aws.Bool(true)},
+		},
+	}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	require.NoError(t, err)
+	assert.True(t, task.Containers[0].EFAEnabled)
+	assert.True(t, task.RequiresEFA())
+}
+
+func TestValidateEFA(t *testing.T) {
+	assert.NoError(t, efaTask(apieni.EFAInterfaceType).validateEFA())
+	assert.Error(t, efaTask(apieni.DefaultInterfaceType).validateEFA())
+
+	task := efaTask(apieni.EFAInterfaceType)
+	task.ENIs = nil
+	assert.Error(t, task.validateEFA(), "EFAs require the awsvpc network mode")
+
+	task = efaTask("")
+	task.Containers[0].EFAEnabled = false
+	assert.NoError(t, task.validateEFA())
+}
+
+func TestDockerHostConfigEFA(t *testing.T) {
+	task := efaTask(apieni.EFAInterfaceType)
+	task.SetEFA(&EFA{
+		Devices:       []string{"/dev/infiniband/uverbs0"},
+		LibfabricPath: "/opt/amazon/efa",
+	})
+
+	hostConfig, err := task.DockerHostConfig(task.Containers[0], dockerMap(task), defaultDockerClientAPIVersion,
+		&config.Config{})
+	require.Nil(t, err)
+	assert.Equal(t, []dockercontainer.DeviceMapping{
+		{PathOnHost: "/dev/infiniband/uverbs0", PathInContainer: "/dev/infiniband/uverbs0", CgroupPermissions: "rw"},
+	}, hostConfig.Devices)
+	assert.Contains(t, hostConfig.Binds, "/opt/amazon/efa:/opt/amazon/efa:ro")
+	assert.Equal(t, []*units.Ulimit{{Name: "memlock", Soft: -1, Hard: -1}}, hostConfig.Ulimits)
+
+	// the containers that don't use the EFA don't get it
+	hostConfig, err = task.DockerHostConfig(task.Containers[1], dockerMap(task), defaultDockerClientAPIVersion,
+		&config.Config{})
+	require.Nil(t, err)
+	assert.Empty(t, hostConfig.Devices)
+	assert.Empty(t, hostConfig.Ulimits)
+}
+
+func TestDockerHostConfigEFAKeepsMemlockUlimit(t *testing.T) {
+	task := efaTask(apieni.EFAInterfaceType)
+	task.SetEFA(&EFA{Devices: []string{"/dev/infiniband/uverbs0"}})
+	hostConfig := `{"Ulimits":[{"Name":"memlock","Soft":1024,"Hard":2048}]}`
+	task.Containers[0].DockerConfig.HostConfig = &hostConfig
+
+	dockerHostConfig, err := task.DockerHostConfig(task.Containers[0], dockerMap(task), defaultDockerClientAPIVersion,
+		&config.Config{})
+	require.Nil(t, err)
+	assert.Equal(t, []*units.Ulimit{{Name: "memlock", Soft: 1024, Hard: 2048}}, dockerHostConfig.Ulimits)
+	assert.Empty(t, dockerHostConfig.Binds, "libfabric isn't mounted when it isn't installed")
+}
@@ -0,0 +1,31 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+// bytesPerGiB is the number of bytes in a GiB
+const bytesPerGiB = 1024 * 1024 * 1024
+
+// EphemeralStorage is the ephemeral storage of a task, as specified in its task definition
+type EphemeralStorage struct {
+	// SizeInGiB is the size of the ephemeral storage of the task
+	SizeInGiB int64 `json:"SizeInGiB"`
+}
+
+// SizeInBytes returns the size of the ephemeral storage, in bytes
+func (storage *EphemeralStorage) SizeInBytes() uint64 {
+	if storage == nil || storage.SizeInGiB <= 0 {
+		return 0
+	}
+	return uint64(storage.SizeInGiB) * bytesPerGiB
+}
@@ -0,0 +1,138 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// hostnameVariableTaskID, hostnameVariableTaskFamily and hostnameVariableTaskRevision
+	// are the variables hostname templates can reference, as ${TASK_ID} for instance
+	hostnameVariableTaskID       = "TASK_ID"
+	hostnameVariableTaskFamily   = "TASK_FAMILY"
+	hostnameVariableTaskRevision = "TASK_REVISION"
+	// maxHostnameLength is the length of the longest hostname the kernel accepts
+	maxHostnameLength = 64
+	// maxHostnameLabelLength is the length of the longest label of a hostname
+	maxHostnameLabelLength = 63
+)
+
+var (
+	// hostnameVariableRegex matches the references to variables in hostname templates
+	hostnameVariableRegex = regexp.MustCompile(`\$\{([^}]*)\}`)
+	// invalidHostnameCharsRegex matches the characters variables are stripped of, as
+	// hostname labels are made of letters, digits and hyphens
+	invalidHostnameCharsRegex = regexp.MustCompile(`[^a-z0-9-]+`)
+	// hostnameLabelRegex matches the valid labels of hostnames
+	hostnameLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+)
+
+// HostEntry is an entry the agent adds to the /etc/hosts file of the containers of an
+// awsvpc task
+type HostEntry struct {
+	// Hostname is the hostname of the entry, which may reference the same variables as
+	// the hostname template of the task
+	Hostname string `json:"Hostname"`
+	// IPAddress is the IPv4 or IPv6 address the hostname resolves to
+	IPAddress string `json:"IPAddress"`
+}
+
+// expandHostnameTemplate replaces the variables a hostname template references with
+// the values they take for the task, and validates the hostname it results in. The
+// values are lowercased, and the characters hostnames can't have are replaced with
+// hyphens.
+func (task *Task) expandHostnameTemplate(template string) (string, error) {
+	taskID, err := task.GetID()
+	if err != nil {
+		return "", err
+	}
+	values := map[string]string{
+		hostnameVariableTaskID:       taskID,
+		hostnameVariableTaskFamily:   task.Family,
+		hostnameVariableTaskRevision: task.Version,
+	}
+
+	var expandErr error
+	hostname := hostnameVariableRegex.ReplaceAllStringFunc(template, func(reference string) string {
+		name := hostnameVariableRegex.FindStringSubmatch(reference)[1]
+		value, ok := values[name]
+		if !ok {
+			expandErr = errors.Errorf("unknown variable %s in hostname template %s", reference, template)
+			return reference
+		}
+		return invalidHostnameCharsRegex.ReplaceAllString(strings.ToLower(value), "-")
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	if err := validateHostname(hostname); err != nil {
+		return "", errors.Wrapf(err, "hostname template %s", template)
+	}
+	return hostname, nil
+}
+
+// validateHostname validates that a hostname is made of dot separated labels of letters,
+// digits and hyphens, and that it isn't longer than the kernel allows
+func validateHostname(hostname string) error {
+	if hostname == "" {
+		return errors.New("empty hostname")
+	}
+	if len(hostname) > maxHostnameLength {
+		return errors.Errorf("hostname %s is longer than %d characters", hostname, maxHostnameLength)
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if len(label) > maxHostnameLabelLength || !hostnameLabelRegex.MatchString(label) {
+			return errors.Errorf("invalid hostname %s", hostname)
+		}
+	}
+	return nil
+}
+
+// containerHostname returns the hostname of the containers of an awsvpc task: the
+// hostname its template expands to if the task has one, the hostname of its ENI
+// otherwise
+func (task *Task) containerHostname() (string, error) {
+	if task.Hostname != "" {
+		return task.expandHostnameTemplate(task.Hostname)
+	}
+	eni := task.GetPrimaryENI()
+	if eni == nil {
+		return "", nil
+	}
+	return eni.GetHostname(), nil
+}
+
+// generateTaskExtraHosts returns the entries of the task in the "hostname:ip" form of the
+// ExtraHosts of a docker host config
+func (task *Task) generateTaskExtraHosts() ([]string, error) {
+	var extraHosts []string
+	for _, entry := range task.ExtraHosts {
+		hostname, err := task.expandHostnameTemplate(entry.Hostname)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid extra host")
+		}
+		if net.ParseIP(entry.IPAddress) == nil {
+			return nil, errors.Errorf("invalid extra host %s: invalid ip address %s", entry.Hostname,
+				entry.IPAddress)
+		}
+		extraHosts = append(extraHosts, fmt.Sprintf("%s:%s", hostname, entry.IPAddress))
+	}
+	return extraHosts, nil
+}
@@ -0,0 +1,127 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hostnameTestTaskARN = "arn:aws:ecs:us-west-2:123456789012:task/cluster/abc123def456"
+
+func newHostnameTestTask(hostname string, extraHosts ...HostEntry) *Task {
+	return &Task{
+		Arn:        hostnameTestTaskARN,
+		Family:     "my_Service",
+		Version:    "7",
+		Hostname:   hostname,
+		ExtraHosts: extraHosts,
+		ENIs: []*apieni.ENI{{
+			ID:             "eni-1",
+			PrivateDNSName: "ip-10-0-0-1.us-west-2.compute.internal",
+			IPV4Addresses:  []*apieni.ENIIPV4Address{{Primary: true, Address: "10.0.0.1"}},
+		}},
+		Containers: []*apicontainer.Container{
+			{Name: NetworkPauseContainerName, Type: apicontainer.ContainerCNIPause},
+		},
+	}
+}
+
+func TestExpandHostnameTemplate(t *testing.T) {
+	testCases := []struct {
+		template string
+		expected string
+		valid    bool
+	}{
+		{template: "web-${TASK_ID}", expected: "web-abc123def456", valid: true},
+		{template: "${TASK_FAMILY}-${TASK_REVISION}.internal", expected: "my-service-7.internal", valid: true},
+		{template: "static", expected: "static", valid: true},
+		{template: "${CLUSTER}", valid: false},
+		{template: "-${TASK_ID}", valid: false},
+		{template: "a..b", valid: false},
+		{template: "${TASK_ID}-0123456789012345678901234567890123456789012345678901234567890", valid: false},
+	}
+	task := newHostnameTestTask("")
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			hostname, err := task.expandHostnameTemplate(tc.template)
+			if !tc.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, hostname)
+		})
+	}
+}
+
+func TestPauseContainerHostname(t *testing.T) {
+	// The hostname of the ENI is used without a template.
+	task := newHostnameTestTask("")
+	dockerConfig, configErr := task.DockerConfig(task.Containers[0], defaultDockerClientAPIVersion)
+	require.Nil(t, configErr)
+	assert.Equal(t, "ip-10-0-0-1.us-west-2.compute.internal", dockerConfig.Hostname)
+
+	task = newHostnameTestTask("web-${TASK_ID}")
+	dockerConfig, configErr = task.DockerConfig(task.Containers[0], defaultDockerClientAPIVersion)
+	require.Nil(t, configErr)
+	assert.Equal(t, "web-abc123def456", dockerConfig.Hostname)
+
+	task = newHostnameTestTask("${UNKNOWN}")
+	_, configErr = task.DockerConfig(task.Containers[0], defaultDockerClientAPIVersion)
+	assert.NotNil(t, configErr)
+}
+
+func TestPauseContainerExtraHosts(t *testing.T) {
+	task := newHostnameTestTask("web-${TASK_ID}",
+		HostEntry{Hostname: "db.internal", IPAddress: "10.0.1.5"},
+		HostEntry{Hostname: "${TASK_FAMILY}.local", IPAddress: "fd00::5"},
+	)
+	hostConfig, configErr := task.DockerHostConfig(task.Containers[0], dockerMap(task),
+		defaultDockerClientAPIVersion, &config.Config{})
+	require.Nil(t, configErr)
+	assert.Equal(t, []string{
+		"web-abc123def456:10.0.0.1",
+		"db.internal:10.0.1.5",
+		"my-service.local:fd00::5",
+	}, hostConfig.ExtraHosts)
+
+	task = newHostnameTestTask("", HostEntry{Hostname: "db.internal", IPAddress: "not-an-ip"})
+	_, configErr = task.DockerHostConfig(task.Containers[0], dockerMap(task),
+		defaultDockerClientAPIVersion, &config.Config{})
+	assert.NotNil(t, configErr)
+}
+
+func TestTaskFromACSHostname(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Hostname: aws.String("web-${TASK_ID}"),
+		ExtraHosts: []*ecsacs.HostEntry{
+			{Hostname: aws.String("db.internal"), IpAddress: aws.String("10.0.1.5")},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	require.NoError(t, err)
+	assert.Equal(t, "web-${TASK_ID}", task.Hostname)
+	assert.Equal(t, []HostEntry{{Hostname: "db.internal", IPAddress: "10.0.1.5"}}, task.ExtraHosts)
+}
@@ -0,0 +1,46 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import "time"
+
+// Sources of the interruption notices of the instance
+const (
+	// InterruptionSourceSpot is the spot interruption notice, sent two minutes before
+	// EC2 stops, hibernates or terminates the spot instance
+	InterruptionSourceSpot = "SpotInterruption"
+	// InterruptionSourceRebalance is the rebalance recommendation, sent when the spot
+	// instance is at an elevated risk of interruption
+	InterruptionSourceRebalance = "RebalanceRecommendation"
+	// InterruptionSourceASGTermination is sent when the auto scaling group of the
+	// instance starts terminating it
+	InterruptionSourceASGTermination = "AutoScalingTermination"
+)
+
+// Interruption is the notice that the instance a task runs on is going to be
+// interrupted, as reported to the task by the task metadata endpoint
+type Interruption struct {
+	// Source is what sent the notice
+	Source string `json:"Source"`
+	// Action is what EC2 is going to do to the spot instance: terminate, stop or
+	// hibernate. It's only set for spot interruption notices
+	Action string `json:"Action,omitempty"`
+	// Time is when the instance is going to be interrupted, if known
+	Time *time.Time `json:"Time,omitempty"`
+	// NoticeTime is when the notice was sent
+	NoticeTime time.Time `json:"NoticeTime"`
+	// TaskProtectionDisabled is true when the agent disabled the scale-in protection
+	// of the task because of the notice
+	TaskProtectionDisabled bool `json:"TaskProtectionDisabled,omitempty"`
+}
@@ -0,0 +1,66 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import "time"
+
+// MemoryPressure is the last time the memory of a task went under pressure, as reported
+// to the task by the task metadata endpoint, so that it can act before the kernel OOM
+// killer does
+type MemoryPressure struct {
+	// DetectedAt is when the memory of the task went under pressure
+	DetectedAt time.Time `json:"DetectedAt"`
+	// RelievedAt is when the memory of the task was no longer under pressure, once it's
+	// relieved
+	RelievedAt *time.Time `json:"RelievedAt,omitempty"`
+	// StallPercent is the share of the last 10 seconds, in percent, in which some process
+	// of the task stalled waiting for memory, when last checked under pressure. It's only
+	// known on cgroup v2 hosts
+	StallPercent *float64 `json:"StallPercent,omitempty"`
+	// UsageBytes is the memory used by the task, without the reclaimable page cache,
+	// when last checked under pressure
+	UsageBytes uint64 `json:"UsageBytes"`
+	// LimitBytes is the memory limit of the task, if it has one
+	LimitBytes uint64 `json:"LimitBytes,omitempty"`
+	// EvictedContainers are the names of the containers the agent stopped to relieve the
+	// pressure
+	EvictedContainers []string `json:"EvictedContainers,omitempty"`
+}
+
+// Relieved returns true if the memory of the task is no longer under pressure
+func (pressure *MemoryPressure) Relieved() bool {
+	return pressure.RelievedAt != nil
+}
+
+// GetMemoryPressure returns a copy of the last memory pressure of the task, or nil if its
+// memory was never under pressure
+func (task *Task) GetMemoryPressure() *MemoryPressure {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	if task.MemoryPressureUnsafe == nil {
+		return nil
+	}
+	pressure := *task.MemoryPressureUnsafe
+	pressure.EvictedContainers = append([]string(nil), pressure.EvictedContainers...)
+	return &pressure
+}
+
+// SetMemoryPressure records the memory pressure of the task
+func (task *Task) SetMemoryPressure(pressure MemoryPressure) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	task.MemoryPressureUnsafe = &pressure
+}
@@ -0,0 +1,46 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+// ResourceControls are the controls of a task over the resources of the instance beyond
+// its cpu and memory limits. They are applied to the task cgroup, so they require the
+// task cpu and memory limits to be enabled
+type ResourceControls struct {
+	// CpusetCpus are the cpus the containers of the task are pinned to, in the cpuset
+	// list format (e.g. "0-3,6")
+	CpusetCpus string `json:"CpusetCpus,omitempty"`
+	// CpusetMems are the memory nodes the containers of the task are pinned to, in the
+	// cpuset list format
+	CpusetMems string `json:"CpusetMems,omitempty"`
+	// IOLimits throttle the io of the containers of the task on the block devices
+	IOLimits []IOLimit `json:"IOLimits,omitempty"`
+	// NUMAAligned specifies whether the cpus and the memory of the task are pinned to a
+	// single NUMA node, chosen by the agent. The cpusets are then set by the agent
+	NUMAAligned bool `json:"NUMAAligned,omitempty"`
+}
+
+// IOLimit throttles the io of a task on a block device. Limits that aren't set, or that
+// are zero, are left unlimited
+type IOLimit struct {
+	// Device is the path of the block device, or its "major:minor" numbers
+	Device string `json:"Device"`
+	// ReadBps is the maximum number of bytes read per second
+	ReadBps int64 `json:"ReadBps,omitempty"`
+	// WriteBps is the maximum number of bytes written per second
+	WriteBps int64 `json:"WriteBps,omitempty"`
+	// ReadIOPS is the maximum number of read operations per second
+	ReadIOPS int64 `json:"ReadIOPS,omitempty"`
+	// WriteIOPS is the maximum number of write operations per second
+	WriteIOPS int64 `json:"WriteIOPS,omitempty"`
+}
@@ -0,0 +1,184 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup"
+	resourcetype "github.com/aws/amazon-ecs-agent/agent/taskresource/types"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// cpusetListRegex matches the cpuset list format, like "0-3,6"
+	cpusetListRegex = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`)
+	// deviceNumbersRegex matches block devices given by their "major:minor" numbers
+	deviceNumbersRegex = regexp.MustCompile(`^([0-9]+):([0-9]+)$`)
+)
+
+// blockDeviceNumbers returns the major and minor numbers of the block device at the path
+var blockDeviceNumbers = func(path string) (int64, int64, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return 0, 0, errors.Errorf("%s is not a block device", path)
+	}
+	rdev := uint64(stat.Rdev)
+	return int64(unix.Major(rdev)), int64(unix.Minor(rdev)), nil
+}
+
+// buildLinuxResourceControlsSpec adds the cpuset pinning and the io limits of the task to
+// its resource spec
+func (task *Task) buildLinuxResourceControlsSpec(linuxResourceSpec *specs.LinuxResources) error {
+	controls := task.ResourceControls
+	for _, cpuset := range []string{controls.CpusetCpus, controls.CpusetMems} {
+		if cpuset != "" && !cpusetListRegex.MatchString(cpuset) {
+			return errors.Errorf("task resource controls spec builder: invalid cpuset: %s", cpuset)
+		}
+	}
+	if controls.CpusetCpus != "" || controls.CpusetMems != "" {
+		if linuxResourceSpec.CPU == nil {
+			linuxResourceSpec.CPU = &specs.LinuxCPU{}
+		}
+		linuxResourceSpec.CPU.Cpus = controls.CpusetCpus
+		linuxResourceSpec.CPU.Mems = controls.CpusetMems
+	}
+
+	if len(controls.IOLimits) == 0 {
+		return nil
+	}
+	blockIO := &specs.LinuxBlockIO{}
+	for _, limit := range controls.IOLimits {
+		major, minor, err := deviceNumbers(limit.Device)
+		if err != nil {
+			return errors.Wrapf(err, "task resource controls spec builder: unable to get the numbers of device %s",
+				limit.Device)
+		}
+		throttle := func(devices []specs.LinuxThrottleDevice, rate int64) []specs.LinuxThrottleDevice {
+			if rate <= 0 {
+				return devices
+			}
+			device := specs.LinuxThrottleDevice{Rate: uint64(rate)}
+			device.Major, device.Minor = major, minor
+			return append(devices, device)
+		}
+		blockIO.ThrottleReadBpsDevice = throttle(blockIO.ThrottleReadBpsDevice, limit.ReadBps)
+		blockIO.ThrottleWriteBpsDevice = throttle(blockIO.ThrottleWriteBpsDevice, limit.WriteBps)
+		blockIO.ThrottleReadIOPSDevice = throttle(blockIO.ThrottleReadIOPSDevice, limit.ReadIOPS)
+		blockIO.ThrottleWriteIOPSDevice = throttle(blockIO.ThrottleWriteIOPSDevice, limit.WriteIOPS)
+	}
+	linuxResourceSpec.BlockIO = blockIO
+	return nil
+}
+
+// deviceNumbers returns the major and minor numbers of a block device given by its path
+// or by its "major:minor" numbers
+func deviceNumbers(device string) (int64, int64, error) {
+	if match := deviceNumbersRegex.FindStringSubmatch(device); match != nil {
+		major, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		minor, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, minor, nil
+	}
+	return blockDeviceNumbers(device)
+}
+
+// GetAppliedResourceControls returns the cpuset pinning and the io limits applied to the
+// task cgroup, read from its resource spec once it's created, or nil if none are. The
+// devices of the io limits are given by their "major:minor" numbers
+func (task *Task) GetAppliedResourceControls() *ResourceControls {
+	task.lock.RLock()
+	resources := task.ResourcesMapUnsafe[resourcetype.CgroupKey]
+	task.lock.RUnlock()
+
+	for _, resource := range resources {
+		cgroupResource, ok := resource.(*cgroup.CgroupResource)
+		if !ok || !cgroupResource.KnownCreated() {
+			continue
+		}
+		return resourceControlsFromSpec(cgroupResource.GetResourceSpec())
+	}
+	return nil
+}
+
+// GetCgroupPath returns the path of the task cgroup, relative to the root of the cgroup
+// hierarchies, once it's created, or an empty string if the task has none
+func (task *Task) GetCgroupPath() string {
+	task.lock.RLock()
+	resources := task.ResourcesMapUnsafe[resourcetype.CgroupKey]
+	task.lock.RUnlock()
+
+	for _, resource := range resources {
+		cgroupResource, ok := resource.(*cgroup.CgroupResource)
+		if !ok || !cgroupResource.KnownCreated() {
+			continue
+		}
+		return cgroupResource.GetCgroupRoot()
+	}
+	return ""
+}
+
+// resourceControlsFromSpec returns the cpuset pinning and the io limits of a resource spec,
+// or nil if it has none
+func resourceControlsFromSpec(linuxResourceSpec specs.LinuxResources) *ResourceControls {
+	controls := &ResourceControls{}
+	if linuxResourceSpec.CPU != nil {
+		controls.CpusetCpus = linuxResourceSpec.CPU.Cpus
+		controls.CpusetMems = linuxResourceSpec.CPU.Mems
+	}
+	if blockIO := linuxResourceSpec.BlockIO; blockIO != nil {
+		// indexes are the indexes of the io limits of the devices
+		indexes := make(map[string]int)
+		limit := func(device specs.LinuxThrottleDevice) *IOLimit {
+			name := fmt.Sprintf("%d:%d", device.Major, device.Minor)
+			index, ok := indexes[name]
+			if !ok {
+				index = len(controls.IOLimits)
+				indexes[name] = index
+				controls.IOLimits = append(controls.IOLimits, IOLimit{Device: name})
+			}
+			return &controls.IOLimits[index]
+		}
+		for _, device := range blockIO.ThrottleReadBpsDevice {
+			limit(device).ReadBps = int64(device.Rate)
+		}
+		for _, device := range blockIO.ThrottleWriteBpsDevice {
+			limit(device).WriteBps = int64(device.Rate)
+		}
+		for _, device := range blockIO.ThrottleReadIOPSDevice {
+			limit(device).ReadIOPS = int64(device.Rate)
+		}
+		for _, device := range blockIO.ThrottleWriteIOPSDevice {
+			limit(device).WriteIOPS = int64(device.Rate)
+		}
+	}
+	if controls.CpusetCpus == "" && controls.CpusetMems == "" && len(controls.IOLimits) == 0 {
+		return nil
+	}
+	return controls
+}
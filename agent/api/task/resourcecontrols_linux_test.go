@@ -0,0 +1,141 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	resourcetype "github.com/aws/amazon-ecs-agent/agent/taskresource/types"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildLinuxResourceSpecResourceControls(t *testing.T) {
+	defer func(original func(string) (int64, int64, error)) {
+		blockDeviceNumbers = original
+	}(blockDeviceNumbers)
+	blockDeviceNumbers = func(path string) (int64, int64, error) {
+		if path != "/dev/nvme1n1" {
+			return 0, 0, errors.New("no such device")
+		}
+		return 259, 1, nil
+	}
+
+	task := &Task{
+		Arn: validTaskArn,
+		CPU: float64(taskVCPULimit),
+		ResourceControls: &ResourceControls{
+			CpusetCpus: "0-3,6",
+			CpusetMems: "0",
+			IOLimits: []IOLimit{
+				{Device: "259:0", ReadBps: 1048576, WriteIOPS: 100},
+				{Device: "/dev/nvme1n1", WriteBps: 2097152},
+			},
+		},
+	}
+
+	linuxResourceSpec, err := task.BuildLinuxResourceSpec(defaultCPUPeriod)
+	require.NoError(t, err)
+	require.NotNil(t, linuxResourceSpec.CPU)
+	assert.NotNil(t, linuxResourceSpec.CPU.Quota)
+	assert.Equal(t, "0-3,6", linuxResourceSpec.CPU.Cpus)
+	assert.Equal(t, "0", linuxResourceSpec.CPU.Mems)
+
+	throttleDevice := func(major, minor int64, rate uint64) specs.LinuxThrottleDevice {
+		device := specs.LinuxThrottleDevice{Rate: rate}
+		device.Major, device.Minor = major, minor
+		return device
+	}
+	assert.Equal(t, &specs.LinuxBlockIO{
+		ThrottleReadBpsDevice:   []specs.LinuxThrottleDevice{throttleDevice(259, 0, 1048576)},
+		ThrottleWriteBpsDevice:  []specs.LinuxThrottleDevice{throttleDevice(259, 1, 2097152)},
+		ThrottleWriteIOPSDevice: []specs.LinuxThrottleDevice{throttleDevice(259, 0, 100)},
+	}, linuxResourceSpec.BlockIO)
+
+	// The applied values are read back from the cgroup resource once it's created
+	task.ResourcesMapUnsafe = make(map[string][]taskresource.TaskResource)
+	cgroupResource := cgroup.NewCgroupResource(task.Arn, nil, nil, "/ecs/task-id", "/sys/fs/cgroup", linuxResourceSpec)
+	task.AddResource(resourcetype.CgroupKey, cgroupResource)
+	assert.Nil(t, task.GetAppliedResourceControls())
+
+	cgroupResource.SetKnownStatus(resourcestatus.ResourceStatus(cgroup.CgroupCreated))
+	assert.Equal(t, &ResourceControls{
+		CpusetCpus: "0-3,6",
+		CpusetMems: "0",
+		IOLimits: []IOLimit{
+			{Device: "259:0", ReadBps: 1048576, WriteIOPS: 100},
+			{Device: "259:1", WriteBps: 2097152},
+		},
+	}, task.GetAppliedResourceControls())
+}
+
+func TestBuildLinuxResourceSpecInvalidResourceControls(t *testing.T) {
+	defer func(original func(string) (int64, int64, error)) {
+		blockDeviceNumbers = original
+	}(blockDeviceNumbers)
+	blockDeviceNumbers = func(path string) (int64, int64, error) {
+		return 0, 0, errors.New("no such device")
+	}
+
+	for _, controls := range []*ResourceControls{
+		{CpusetCpus: "0-3,"},
+		{CpusetMems: "all"},
+		{IOLimits: []IOLimit{{Device: "/dev/missing", ReadBps: 1}}},
+	} {
+		task := &Task{
+			Arn:              validTaskArn,
+			ResourceControls: controls,
+		}
+		_, err := task.BuildLinuxResourceSpec(defaultCPUPeriod)
+		assert.Error(t, err)
+	}
+}
+
+func TestGetAppliedResourceControlsWithoutControls(t *testing.T) {
+	task := &Task{
+		Arn:                validTaskArn,
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+	}
+	linuxResourceSpec, err := task.BuildLinuxResourceSpec(defaultCPUPeriod)
+	require.NoError(t, err)
+	cgroupResource := cgroup.NewCgroupResource(task.Arn, nil, nil, "/ecs/task-id", "/sys/fs/cgroup", linuxResourceSpec)
+	cgroupResource.SetKnownStatus(resourcestatus.ResourceStatus(cgroup.CgroupCreated))
+	task.AddResource(resourcetype.CgroupKey, cgroupResource)
+
+	assert.Nil(t, task.GetAppliedResourceControls())
+}
+
+func TestGetCgroupPath(t *testing.T) {
+	task := &Task{
+		Arn:                validTaskArn,
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+	}
+	assert.Empty(t, task.GetCgroupPath())
+
+	cgroupResource := cgroup.NewCgroupResource(task.Arn, nil, nil, "/ecs/task-id", "/sys/fs/cgroup", specs.LinuxResources{})
+	task.AddResource(resourcetype.CgroupKey, cgroupResource)
+	// the path is only reported once the cgroup is created
+	assert.Empty(t, task.GetCgroupPath())
+
+	cgroupResource.SetKnownStatus(resourcestatus.ResourceStatus(cgroup.CgroupCreated))
+	assert.Equal(t, "/ecs/task-id", task.GetCgroupPath())
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+// GetAppliedResourceControls returns nil, as resource controls are only applied to the
+// task cgroups on Linux
+func (task *Task) GetAppliedResourceControls() *ResourceControls {
+	return nil
+}
+
+// GetCgroupPath returns an empty string, as tasks only have cgroups on Linux
+func (task *Task) GetCgroupPath() string {
+	return ""
+}
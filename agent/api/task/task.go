@@ -41,8 +41,10 @@ import (
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/networkpolicy"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmauth"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
@@ -51,6 +53,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	resourcetype "github.com/aws/amazon-ecs-agent/agent/taskresource/types"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
 	taskresourcevolume "github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
@@ -79,6 +82,10 @@ const (
 
 	// neuronRuntime is the name of the neuron docker runtime.
 	neuronRuntime = "neuron"
+	// neuronDevicePathFormat is the format of the paths of the Neuron devices in containers
+	neuronDevicePathFormat = "/dev/neuron%d"
+	// neuronDeviceCgroupPermissions allows containers to read, write and mknod their Neuron devices
+	neuronDeviceCgroupPermissions = "rwm"
 
 	ContainerOrderingCreateCondition = "CREATE"
 	ContainerOrderingStartCondition  = "START"
@@ -101,11 +108,21 @@ const (
 	ipcModeSharable = "shareable"
 	ipcModeNone     = "none"
 
+	// instanceMetadataAccessBlock and instanceMetadataAccessProxy are the modes of the
+	// access of a task to the instance metadata service. Proxying the access isn't
+	// supported, so tasks requesting it fail.
+	instanceMetadataAccessBlock = "BLOCK"
+	instanceMetadataAccessProxy = "PROXY"
+
 	// firelensConfigBindFormatFluentd and firelensConfigBindFormatFluentbit specify the format of the firelens
 	// config file bind mount for fluentd and fluentbit firelens container respectively.
 	// First placeholder is host data dir, second placeholder is taskID.
 	firelensConfigBindFormatFluentd   = "%s/data/firelens/%s/config/fluent.conf:/fluentd/etc/fluent.conf"
 	firelensConfigBindFormatFluentbit = "%s/data/firelens/%s/config/fluent.conf:/fluent-bit/etc/fluent-bit.conf"
+	// firelensConfigBindFormatOtelcol specifies the format of the config file bind mount for an OpenTelemetry
+	// Collector firelens container, at the default config path of the collector contrib image, which provides the
+	// receiver of the logs sent by the fluentd log driver.
+	firelensConfigBindFormatOtelcol = "%s/data/firelens/%s/config/" + firelens.OtelcolConfigFile + ":/etc/otelcol-contrib/config.yaml"
 
 	// firelensS3ConfigBindFormat specifies the format of the bind mount for the firelens config file downloaded from S3.
 	// First placeholder is host data dir, second placeholder is taskID, third placeholder is the s3 config path inside
@@ -129,6 +146,9 @@ const (
 	// placeholder format expected by fluentd and fluentbit respectively.
 	firelensConfigVarPlaceholderFmtFluentd   = "\"#{ENV['%s']}\""
 	firelensConfigVarPlaceholderFmtFluentbit = "${%s}"
+	// firelensConfigVarPlaceholderFmtOtelcol specifies the config var placeholder format expected by the
+	// OpenTelemetry Collector.
+	firelensConfigVarPlaceholderFmtOtelcol = "${env:%s}"
 
 	// awsExecutionEnvKey is the key of the env specifying the execution environment.
 	awsExecutionEnvKey = "AWS_EXECUTION_ENV"
@@ -147,6 +167,14 @@ const (
 	sysctlValueOff = "0"
 )
 
+// secretProviderResourceNames are the names of the resources retrieving the secrets of
+// each secret provider
+var secretProviderResourceNames = map[string]string{
+	apicontainer.SecretProviderSSM:   ssmsecret.ResourceName,
+	apicontainer.SecretProviderASM:   asmsecret.ResourceName,
+	apicontainer.SecretProviderVault: vaultsecret.ResourceName,
+}
+
 // TaskOverrides are the overrides applied to a task
 type TaskOverrides struct{}
 
@@ -227,6 +255,9 @@ type Task struct {
 	// used to look up the credentials for task in the credentials manager
 	credentialsID                string
 	credentialsRelativeURIUnsafe string
+	// credentialsFetchCountUnsafe counts, by role type, the credentials of the task
+	// served by the credentials endpoint
+	credentialsFetchCountUnsafe map[string]int
 
 	// ENIs is the list of Elastic Network Interfaces assigned to this task. The
 	// TaskENIs type is helpful when decoding state files which might have stored
@@ -256,9 +287,100 @@ type Task struct {
 	// containers of the Task
 	IPCMode string `json:"IpcMode,omitempty"`
 
+	// ContainerStartConcurrency is the maximum number of containers of the Task
+	// that are created or started at the same time. Zero means the limit from the
+	// agent configuration is used
+	ContainerStartConcurrency int64 `json:"ContainerStartConcurrency,omitempty"`
+
+	// CheckpointEnabled specifies whether the running containers of the Task are
+	// periodically checkpointed, to be restored from their latest checkpoint when
+	// the agent restarts them per their restart policy. This is experimental and
+	// requires CRIU on the host
+	CheckpointEnabled bool `json:"CheckpointEnabled,omitempty"`
+
+	// EphemeralStorage is the ephemeral storage of the Task, which bounds the size of
+	// the writable layers of its containers when the quota is enforced
+	EphemeralStorage *EphemeralStorage `json:"EphemeralStorage,omitempty"`
+
+	// EphemeralStorageQuotaEnforcedUnsafe is true once the ephemeral storage quota of the
+	// Task has been applied to its containers. This field should be accessed via
+	// IsEphemeralStorageQuotaEnforced and SetEphemeralStorageQuotaEnforced.
+	EphemeralStorageQuotaEnforcedUnsafe bool `json:"EphemeralStorageQuotaEnforced,omitempty"`
+
+	// NetworkPolicy is the egress network policy of the Task, enforced in its network
+	// namespace in place of the instance level policy
+	NetworkPolicy *networkpolicy.Policy `json:"NetworkPolicy,omitempty"`
+
+	// BlockInstanceMetadata specifies whether the access of the Task to the instance
+	// metadata service is blocked, regardless of the instance level setting
+	BlockInstanceMetadata bool `json:"BlockInstanceMetadata,omitempty"`
+	// InstanceMetadataAccess is the mode of the access of the Task to the instance
+	// metadata service, BLOCK or PROXY. BLOCK is the same as BlockInstanceMetadata, and
+	// PROXY isn't supported.
+	InstanceMetadataAccess string `json:"InstanceMetadataAccess,omitempty"`
+
+	// InstanceMetadataBlockedUnsafe is true once the access of the Task to the instance
+	// metadata service has been blocked in its network namespace, and
+	// InstanceMetadataCounterPIDUnsafe is the pid of its pause container, in whose network
+	// namespace the blocked attempts are counted, if they are.
+	// InstanceMetadataCounterNetNSUnsafe is the identity of that namespace, checked before
+	// counting as the pid may be reused. These fields should be accessed via
+	// GetInstanceMetadataBlock and SetInstanceMetadataBlocked.
+	InstanceMetadataBlockedUnsafe      bool   `json:"InstanceMetadataBlocked,omitempty"`
+	InstanceMetadataCounterPIDUnsafe   string `json:"InstanceMetadataCounterPID,omitempty"`
+	InstanceMetadataCounterNetNSUnsafe string `json:"InstanceMetadataCounterNetNS,omitempty"`
+
+	// DNSConfiguration is the DNS configuration of the Task
+	DNSConfiguration *DNSConfiguration `json:"DNSConfiguration,omitempty"`
+
+	// Hostname is the template of the hostname of the containers of the Task in awsvpc
+	// mode, where it takes precedence over the hostname of its ENI. It may reference the
+	// ${TASK_ID}, ${TASK_FAMILY} and ${TASK_REVISION} variables
+	Hostname string `json:"Hostname,omitempty"`
+
+	// ExtraHosts are the entries added to the /etc/hosts file of the containers of the
+	// Task in awsvpc mode, where docker doesn't let them set their own
+	ExtraHosts []HostEntry `json:"ExtraHosts,omitempty"`
+
+	// DNSCachePIDUnsafe is the pid of the pause container of the Task, in whose network
+	// namespace its DNS cache runs, and dnsCache is the running cache. These fields should
+	// be accessed via GetDNSCache and SetDNSCache.
+	DNSCachePIDUnsafe string `json:"DNSCachePID,omitempty"`
+	dnsCache          *dnscache.Cache
+
+	// ResourceControls are the cpuset pinning and the io limits of the Task, applied to
+	// its cgroup along with its cpu and memory limits
+	ResourceControls *ResourceControls `json:"ResourceControls,omitempty"`
+
+	// NUMANodeUnsafe is the NUMA node the Task is placed on, when its resource controls
+	// request it. This field should be accessed via GetNUMANode and SetNUMANode.
+	NUMANodeUnsafe *int `json:"NUMANode,omitempty"`
+
+	// EFAUnsafe is the Elastic Fabric Adapter of the Task, once its devices are looked up.
+	// This field should be accessed via GetEFA and SetEFA.
+	EFAUnsafe *EFA `json:"EFA,omitempty"`
+
+	// InterruptionUnsafe is the notice that the instance the Task runs on is going to be
+	// interrupted, once the agent received one. This field should be accessed via
+	// GetInterruption and SetInterruption.
+	InterruptionUnsafe *Interruption `json:"Interruption,omitempty"`
+
+	// MemoryPressureUnsafe is the last time the memory of the task went under pressure,
+	// when the memory pressure of the tasks is checked. This field should be accessed via
+	// GetMemoryPressure and SetMemoryPressure.
+	MemoryPressureUnsafe *MemoryPressure `json:"MemoryPressure,omitempty"`
+
 	// NvidiaRuntime is the runtime to pass Nvidia GPU devices to containers
 	NvidiaRuntime string `json:"NvidiaRuntime,omitempty"`
 
+	// ContainerRuntime is the sandboxed OCI runtime, such as runsc or kata-runtime, the
+	// task definition requests for the containers of the task
+	ContainerRuntime string `json:"ContainerRuntime,omitempty"`
+
+	// InitProcessEnabled, when set, is whether the task definition runs an init process
+	// as PID 1 of the containers of the task. The agent default is used otherwise.
+	InitProcessEnabled *bool `json:"InitProcessEnabled,omitempty"`
+
 	// LocalIPAddressUnsafe stores the local IP address allocated to the bridge that connects the task network
 	// namespace and the host network namespace, for tasks in awsvpc network mode (tasks in other network mode won't
 	// have a value for this). This field should be accessed via GetLocalIPAddress and SetLocalIPAddress.
@@ -314,6 +436,7 @@ func (task *Task) initializeVolumes(cfg *config.Config, dockerClient dockerapi.D
 	if err != nil {
 		return apierrors.NewResourceInitError(task.Arn, err)
 	}
+	task.initializeEphemeralVolumeEncryption(cfg)
 	return nil
 }
 
@@ -358,6 +481,16 @@ func (task *Task) PostUnmarshalTask(cfg *config.Config,
 		return apierrors.NewResourceInitError(task.Arn, err)
 	}
 
+	if err := task.validateEFA(); err != nil {
+		seelog.Errorf("Task [%s]: could not initialize EFA devices: %v", task.Arn, err)
+		return apierrors.NewResourceInitError(task.Arn, err)
+	}
+
+	if err := task.initializeInstanceMetadataAccess(); err != nil {
+		seelog.Errorf("Task [%s]: could not initialize instance metadata access: %v", task.Arn, err)
+		return apierrors.NewResourceInitError(task.Arn, err)
+	}
+
 	task.initializeContainersV3MetadataEndpoint(utils.NewDynamicUUIDProvider())
 	task.initializeContainersV4MetadataEndpoint(utils.NewDynamicUUIDProvider())
 	if err := task.addNetworkResourceProvisioningDependency(cfg); err != nil {
@@ -421,6 +554,10 @@ func (task *Task) initSecretResources(credentialsManager credentials.Manager,
 	if task.requiresASMSecret() {
 		task.initializeASMSecretResource(credentialsManager, resourceFields)
 	}
+
+	if task.requiresVaultSecret() {
+		task.initializeVaultSecretResource(credentialsManager, resourceFields)
+	}
 }
 
 func (task *Task) applyFirelensSetup(cfg *config.Config, resourceFields *taskresource.ResourceFields,
@@ -529,6 +666,23 @@ func (task *Task) initializeDockerLocalVolumes(dockerClient dockerapi.DockerClie
 	return nil
 }
 
+// initializeEphemeralVolumeEncryption backs the ephemeral volumes of the task with encrypted
+// devices when enabled, so that their data is unreadable once the task stops
+func (task *Task) initializeEphemeralVolumeEncryption(cfg *config.Config) {
+	if !cfg.EphemeralVolumeEncryption.Enabled() {
+		return
+	}
+	for _, resource := range task.GetResources() {
+		volumeResource, ok := resource.(*taskresourcevolume.VolumeResource)
+		if !ok || !volumeResource.IsEphemeral() {
+			continue
+		}
+		volumeResource.Encryption = taskresourcevolume.NewEncryptionConfig(
+			filepath.Join(cfg.DataDir, taskresourcevolume.EncryptedVolumesDir),
+			volumeResource.VolumeConfig.DockerVolumeName, cfg.EncryptedVolumeSizeMB)
+	}
+}
+
 func (task *Task) volumeName(name string) string {
 	return "ecs-" + task.Family + "-" + task.Version + "-" + name + "-" + utils.RandHex()
 }
@@ -832,7 +986,7 @@ func (task *Task) requiresSSMSecret() bool {
 func (task *Task) initializeSSMSecretResource(credentialsManager credentials.Manager,
 	resourceFields *taskresource.ResourceFields) {
 	ssmSecretResource := ssmsecret.NewSSMSecretResource(task.Arn, task.getAllSSMSecretRequirements(),
-		task.ExecutionCredentialsID, credentialsManager, resourceFields.SSMClientCreator)
+		task.ExecutionCredentialsID, credentialsManager, resourceFields.SSMClientCreator, resourceFields.SecretCache)
 	task.AddResource(ssmsecret.ResourceName, ssmSecretResource)
 
 	// for every container that needs ssm secret vending as env, it needs to wait all secrets got retrieved
@@ -900,7 +1054,7 @@ func (task *Task) requiresASMSecret() bool {
 func (task *Task) initializeASMSecretResource(credentialsManager credentials.Manager,
 	resourceFields *taskresource.ResourceFields) {
 	asmSecretResource := asmsecret.NewASMSecretResource(task.Arn, task.getAllASMSecretRequirements(),
-		task.ExecutionCredentialsID, credentialsManager, resourceFields.ASMClientCreator)
+		task.ExecutionCredentialsID, credentialsManager, resourceFields.ASMClientCreator, resourceFields.SecretCache)
 	task.AddResource(asmsecret.ResourceName, asmSecretResource)
 
 	// for every container that needs asm secret vending as envvar, it needs to wait all secrets got retrieved
@@ -922,11 +1076,54 @@ func (task *Task) initializeASMSecretResource(credentialsManager credentials.Man
 
 // getAllASMSecretRequirements stores secrets in a task in a map
 func (task *Task) getAllASMSecretRequirements() map[string]apicontainer.Secret {
+	return task.getAllSecretRequirements(apicontainer.SecretProviderASM)
+}
+
+// requiresVaultSecret returns true if at least one container in the task
+// needs to retrieve secret from HashiCorp Vault
+func (task *Task) requiresVaultSecret() bool {
+	for _, container := range task.Containers {
+		if container.ShouldCreateWithVaultSecret() {
+			return true
+		}
+	}
+	return false
+}
+
+// initializeVaultSecretResource builds the resource dependency map for the vaultsecret resource.
+// The secrets are read with the task role credentials, or the execution role credentials when
+// the task has no role.
+func (task *Task) initializeVaultSecretResource(credentialsManager credentials.Manager,
+	resourceFields *taskresource.ResourceFields) {
+	vaultSecretResource := vaultsecret.NewVaultSecretResource(task.Arn,
+		task.getAllSecretRequirements(apicontainer.SecretProviderVault), task.GetCredentialsID(),
+		task.ExecutionCredentialsID, credentialsManager, resourceFields.VaultClientCreator)
+	task.AddResource(vaultsecret.ResourceName, vaultSecretResource)
+
+	// for every container that needs vault secret vending as envvar, it needs to wait all secrets got retrieved
+	for _, container := range task.Containers {
+		if container.ShouldCreateWithVaultSecret() {
+			container.BuildResourceDependency(vaultSecretResource.GetName(),
+				resourcestatus.ResourceStatus(vaultsecret.VaultSecretCreated),
+				apicontainerstatus.ContainerCreated)
+		}
+
+		// Firelens container needs to depends on secret if other containers use secret log options.
+		if container.GetFirelensConfig() != nil && task.firelensDependsOnSecretResource(apicontainer.SecretProviderVault) {
+			container.BuildResourceDependency(vaultSecretResource.GetName(),
+				resourcestatus.ResourceStatus(vaultsecret.VaultSecretCreated),
+				apicontainerstatus.ContainerCreated)
+		}
+	}
+}
+
+// getAllSecretRequirements stores the secrets of a provider in a task in a map
+func (task *Task) getAllSecretRequirements(provider string) map[string]apicontainer.Secret {
 	reqs := make(map[string]apicontainer.Secret)
 
 	for _, container := range task.Containers {
 		for _, secret := range container.Secrets {
-			if secret.Provider == apicontainer.SecretProviderASM {
+			if secret.Provider == provider {
 				secretKey := secret.GetSecretResourceCacheKey()
 				if _, ok := reqs[secretKey]; !ok {
 					reqs[secretKey] = secret
@@ -947,6 +1144,19 @@ func (task *Task) GetFirelensContainer() *apicontainer.Container {
 	return nil
 }
 
+// GetFirelensResource returns the firelens resource of the task, if there is one.
+func (task *Task) GetFirelensResource() (*firelens.FirelensResource, bool) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	res, ok := task.ResourcesMapUnsafe[firelens.ResourceName]
+	if !ok || len(res) == 0 {
+		return nil, false
+	}
+	firelensResource, ok := res[0].(*firelens.FirelensResource)
+	return firelensResource, ok
+}
+
 // initializeFirelensResource initializes the firelens task resource and adds it as a dependency of the
 // firelens container.
 func (task *Task) initializeFirelensResource(config *config.Config, resourceFields *taskresource.ResourceFields,
@@ -982,9 +1192,18 @@ func (task *Task) initializeFirelensResource(config *config.Config, resourceFiel
 			} else {
 				networkMode = container.GetNetworkModeFromHostConfig()
 			}
+			var logRouterUser string
+			if container.DockerConfig.Config != nil {
+				containerConfig := &dockercontainer.Config{}
+				if err := json.Unmarshal([]byte(aws.StringValue(container.DockerConfig.Config)), containerConfig); err != nil {
+					return errors.Wrapf(err, "unable to decode docker config of firelens container")
+				}
+				logRouterUser = containerConfig.User
+			}
+
 			firelensResource, err := firelens.NewFirelensResource(config.Cluster, task.Arn, task.Family+":"+task.Version,
 				ec2InstanceID, config.DataDir, firelensConfig.Type, config.AWSRegion, networkMode, firelensConfig.Options, containerToLogOptions,
-				credentialsManager, task.ExecutionCredentialsID)
+				credentialsManager, task.ExecutionCredentialsID, logRouterUser)
 			if err != nil {
 				return errors.Wrap(err, "unable to initialize firelens resource")
 			}
@@ -1089,6 +1308,8 @@ func (task *Task) collectFirelensLogEnvOptions(containerToLogOptions map[string]
 		placeholderFmt = firelensConfigVarPlaceholderFmtFluentd
 	case firelens.FirelensConfigTypeFluentbit:
 		placeholderFmt = firelensConfigVarPlaceholderFmtFluentbit
+	case firelens.FirelensConfigTypeOtelcol:
+		placeholderFmt = firelensConfigVarPlaceholderFmtOtelcol
 	default:
 		return errors.Errorf("unsupported firelens config type %s", firelensConfigType)
 	}
@@ -1112,6 +1333,48 @@ func (task *Task) collectFirelensLogEnvOptions(containerToLogOptions map[string]
 	return nil
 }
 
+// GetFirelensLogOptions collects the log options of the containers using the awsfirelens log driver again, for the
+// config of the firelens container to be regenerated. Secret log options reference environment variables of the
+// firelens container, which are only set when it's created, so the ones whose secret value changed since then, e.g.
+// after the secrets of the task were refreshed, are resolved to their current value instead. The returned boolean
+// tells whether the log options hold such values.
+func (task *Task) GetFirelensLogOptions(firelensContainer *apicontainer.Container) (map[string]map[string]string, bool, error) {
+	firelensConfig := firelensContainer.GetFirelensConfig()
+	if firelensConfig == nil {
+		return nil, false, errors.New("firelens container config doesn't exist")
+	}
+
+	containerToLogOptions := make(map[string]map[string]string)
+	if err := task.collectFirelensLogOptions(containerToLogOptions); err != nil {
+		return nil, false, err
+	}
+	if err := task.collectFirelensLogEnvOptions(containerToLogOptions, firelensConfig.Type); err != nil {
+		return nil, false, err
+	}
+
+	hasSecretValues := false
+	secretResources := task.getSecretResources()
+	for _, container := range task.Containers {
+		if container.GetLogDriver() != firelensDriverName {
+			continue
+		}
+
+		logDriverSecretData, err := collectLogDriverSecretData(container.Secrets, secretResources)
+		if err != nil {
+			return nil, false, err
+		}
+
+		idx := task.GetContainerIndex(container.Name)
+		for key, value := range logDriverSecretData {
+			if firelensContainer.Environment[fmt.Sprintf(firelensConfigVarFmt, key, idx)] != value {
+				containerToLogOptions[container.Name][key] = value
+				hasSecretValues = true
+			}
+		}
+	}
+	return containerToLogOptions, hasSecretValues, nil
+}
+
 // AddFirelensContainerBindMounts adds config file bind mount and socket directory bind mount to the firelens
 // container's host config.
 func (task *Task) AddFirelensContainerBindMounts(firelensConfig *apicontainer.FirelensConfig, hostConfig *dockercontainer.HostConfig,
@@ -1129,6 +1392,9 @@ func (task *Task) AddFirelensContainerBindMounts(firelensConfig *apicontainer.Fi
 	case firelens.FirelensConfigTypeFluentbit:
 		configBind = fmt.Sprintf(firelensConfigBindFormatFluentbit, config.DataDirOnHost, taskID)
 		s3ConfigBind = fmt.Sprintf(firelensS3ConfigBindFormat, config.DataDirOnHost, taskID, firelens.S3ConfigPathFluentbit)
+	case firelens.FirelensConfigTypeOtelcol:
+		// External config files aren't supported by the OpenTelemetry Collector firelens container.
+		configBind = fmt.Sprintf(firelensConfigBindFormatOtelcol, config.DataDirOnHost, taskID)
 	default:
 		return &apierrors.HostConfigError{Msg: fmt.Sprintf("encounter invalid firelens configuration type %s",
 			firelensConfig.Type)}
@@ -1397,7 +1663,7 @@ func (task *Task) dockerConfig(container *apicontainer.Container, apiVersion doc
 
 	if container.Type == apicontainer.ContainerCNIPause {
 		// apply hostname to pause container's docker config
-		return task.applyENIHostname(containerConfig), nil
+		return task.applyHostname(containerConfig)
 	}
 
 	return containerConfig, nil
@@ -1476,6 +1742,8 @@ func (task *Task) dockerHostConfig(container *apicontainer.Container, dockerCont
 		return nil, err
 	}
 
+	task.overrideInitProcess(container, hostConfig, cfg)
+
 	if container.DockerConfig.HostConfig != nil {
 		err := json.Unmarshal([]byte(*container.DockerConfig.HostConfig), hostConfig)
 		if err != nil {
@@ -1487,6 +1755,12 @@ func (task *Task) dockerHostConfig(container *apicontainer.Container, dockerCont
 		return nil, &apierrors.HostConfigError{Msg: err.Error()}
 	}
 
+	addNeuronDevices(container, hostConfig)
+	task.addEFADevices(container, hostConfig)
+	if err := task.applyUlimits(container, hostConfig, cfg); err != nil {
+		return nil, err
+	}
+
 	// Determine if network mode should be overridden and override it if needed
 	ok, networkMode := task.shouldOverrideNetworkMode(container, dockerContainerMap)
 	if ok {
@@ -1494,9 +1768,18 @@ func (task *Task) dockerHostConfig(container *apicontainer.Container, dockerCont
 		// Override 'awsvpc' parameters if needed
 		if container.Type == apicontainer.ContainerCNIPause {
 			// apply ExtraHosts to HostConfig for pause container
-			if hosts := task.generateENIExtraHosts(); hosts != nil {
+			hostname, err := task.containerHostname()
+			if err != nil {
+				return nil, &apierrors.HostConfigError{Msg: err.Error()}
+			}
+			if hosts := task.generateENIExtraHosts(hostname); hosts != nil {
 				hostConfig.ExtraHosts = append(hostConfig.ExtraHosts, hosts...)
 			}
+			taskHosts, err := task.generateTaskExtraHosts()
+			if err != nil {
+				return nil, &apierrors.HostConfigError{Msg: err.Error()}
+			}
+			hostConfig.ExtraHosts = append(hostConfig.ExtraHosts, taskHosts...)
 
 			if task.shouldEnableIPv6() {
 				// By default, the disable ipv6 setting is turned on, so need to turn it off to enable it.
@@ -1525,6 +1808,10 @@ func (task *Task) dockerHostConfig(container *apicontainer.Container, dockerCont
 // overrideContainerRuntime overrides the runtime for the container in host config if needed.
 func (task *Task) overrideContainerRuntime(container *apicontainer.Container, hostCfg *dockercontainer.HostConfig,
 	cfg *config.Config) *apierrors.HostConfigError {
+	if task.ContainerRuntime != "" && !container.IsInternal() {
+		return task.overrideSandboxedContainerRuntime(container, hostCfg, cfg)
+	}
+
 	if task.isGPUEnabled() && task.shouldRequireNvidiaRuntime(container) {
 		if task.NvidiaRuntime == "" {
 			return &apierrors.HostConfigError{Msg: "Runtime is not set for GPU containers"}
@@ -1540,6 +1827,38 @@ func (task *Task) overrideContainerRuntime(container *apicontainer.Container, ho
 	return nil
 }
 
+// overrideInitProcess runs an init process as PID 1 of the container, which forwards
+// signals to the process of the container and reaps the zombie processes it leaves,
+// when the task or the agent config asks for it. The host config of the container is
+// applied after it, so the init process setting of a container in its task definition
+// takes precedence. The internal containers keep the docker default.
+func (task *Task) overrideInitProcess(container *apicontainer.Container, hostCfg *dockercontainer.HostConfig,
+	cfg *config.Config) {
+	if container.IsInternal() {
+		return
+	}
+	if task.InitProcessEnabled != nil {
+		hostCfg.Init = aws.Bool(aws.BoolValue(task.InitProcessEnabled))
+		return
+	}
+	if cfg.TaskInitProcessEnabled.Enabled() {
+		hostCfg.Init = aws.Bool(true)
+	}
+}
+
+// addNeuronDevices passes the Neuron devices of the cores assigned to the container to it,
+// which also allows them in the device cgroup of the container. The devices are renumbered
+// from zero in the container, as the neuron runtime numbers the visible cores among them
+func addNeuronDevices(container *apicontainer.Container, hostConfig *dockercontainer.HostConfig) {
+	for i, device := range container.GetNeuronDevices() {
+		hostConfig.Devices = append(hostConfig.Devices, dockercontainer.DeviceMapping{
+			PathOnHost:        device,
+			PathInContainer:   fmt.Sprintf(neuronDevicePathFormat, i),
+			CgroupPermissions: neuronDeviceCgroupPermissions,
+		})
+	}
+}
+
 // Requires an *apicontainer.Container and returns the Resources for the HostConfig struct
 func (task *Task) getDockerResources(container *apicontainer.Container) dockercontainer.Resources {
 	// Convert MB to B and set Memory
@@ -1606,6 +1925,10 @@ func (task *Task) shouldOverrideNetworkMode(container *apicontainer.Container, d
 // true:
 // 1. Task has an ENI associated with it
 // 2. ENI has custom DNS IPs and search list associated with it
+// Tasks using a local DNS cache are given the address of the cache, which forwards
+// the queries it can't answer to the DNS IPs of the ENI.
+// IPv6-only ENIs are given IPv6 nameservers only, so that the DNS64 capable Amazon
+// provided DNS server is used to reach IPv4-only destinations via NAT64.
 // This should only be done for the pause container as other containers inherit
 // /etc/resolv.conf of this container (they share the network namespace)
 func (task *Task) overrideDNS(hostConfig *dockercontainer.HostConfig) *dockercontainer.HostConfig {
@@ -1614,46 +1937,52 @@ func (task *Task) overrideDNS(hostConfig *dockercontainer.HostConfig) *dockercon
 		return hostConfig
 	}
 
-	hostConfig.DNS = eni.DomainNameServers
+	hostConfig.DNS = eni.GetDomainNameServers()
+	if task.UsesLocalDNSCache() {
+		hostConfig.DNS = []string{dnscache.ListenAddress}
+	}
 	hostConfig.DNSSearch = eni.DomainNameSearchList
 
 	return hostConfig
 }
 
-// applyENIHostname adds the hostname provided by the ENI message to the
-// container's docker config. At the time of implmentation, we are only using it
-// to configure the pause container for awsvpc tasks
-func (task *Task) applyENIHostname(dockerConfig *dockercontainer.Config) *dockercontainer.Config {
-	eni := task.GetPrimaryENI()
-	if eni == nil {
-		return dockerConfig
+// applyHostname adds the hostname of the task, from its hostname template or
+// provided by the ENI message, to the container's docker config. At the time of
+// implmentation, we are only using it to configure the pause container for awsvpc
+// tasks
+func (task *Task) applyHostname(dockerConfig *dockercontainer.Config) (*dockercontainer.Config, *apierrors.DockerClientConfigError) {
+	hostname, err := task.containerHostname()
+	if err != nil {
+		return nil, &apierrors.DockerClientConfigError{Msg: err.Error()}
 	}
-
-	hostname := eni.GetHostname()
 	if hostname == "" {
-		return dockerConfig
+		return dockerConfig, nil
 	}
 
 	dockerConfig.Hostname = hostname
-	return dockerConfig
+	return dockerConfig, nil
 }
 
 // generateENIExtraHosts returns a slice of strings of the form "hostname:ip"
-// that is generated using the hostname and ip addresses allocated to the ENI
-func (task *Task) generateENIExtraHosts() []string {
+// that is generated using the hostname of the task and ip addresses allocated
+// to the ENI. IPv6 addresses are used for IPv6-only ENIs.
+func (task *Task) generateENIExtraHosts(hostname string) []string {
 	eni := task.GetPrimaryENI()
 	if eni == nil {
 		return nil
 	}
 
-	hostname := eni.GetHostname()
 	if hostname == "" {
 		return nil
 	}
 
 	extraHosts := []string{}
 
-	for _, ip := range eni.GetIPV4Addresses() {
+	addresses := eni.GetIPV4Addresses()
+	if eni.IPv6Only() {
+		addresses = eni.GetIPV6Addresses()
+	}
+	for _, ip := range addresses {
 		host := fmt.Sprintf("%s:%s", hostname, ip)
 		extraHosts = append(extraHosts, host)
 	}
@@ -2019,6 +2348,34 @@ func (task *Task) GetCredentialsRelativeURI() string {
 	return task.credentialsRelativeURIUnsafe
 }
 
+// RecordCredentialsFetch records that credentials of the role type were served for
+// the task by the credentials endpoint
+func (task *Task) RecordCredentialsFetch(roleType string) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	if task.credentialsFetchCountUnsafe == nil {
+		task.credentialsFetchCountUnsafe = make(map[string]int)
+	}
+	task.credentialsFetchCountUnsafe[roleType]++
+}
+
+// GetCredentialsFetchCount returns the number of times credentials were served for
+// the task by the credentials endpoint, by role type
+func (task *Task) GetCredentialsFetchCount() map[string]int {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	if len(task.credentialsFetchCountUnsafe) == 0 {
+		return nil
+	}
+	fetchCount := make(map[string]int, len(task.credentialsFetchCountUnsafe))
+	for roleType, count := range task.credentialsFetchCountUnsafe {
+		fetchCount[roleType] = count
+	}
+	return fetchCount
+}
+
 // SetExecutionRoleCredentialsID sets the ID for the task execution role credentials
 func (task *Task) SetExecutionRoleCredentialsID(id string) {
 	task.lock.Lock()
@@ -2325,31 +2682,47 @@ func (task *Task) getSSMSecretsResource() ([]taskresource.TaskResource, bool) {
 
 // PopulateSecrets appends secrets to container's env var map and hostconfig section
 func (task *Task) PopulateSecrets(hostConfig *dockercontainer.HostConfig, container *apicontainer.Container) *apierrors.DockerClientConfigError {
-	var ssmRes *ssmsecret.SSMSecretResource
-	var asmRes *asmsecret.ASMSecretResource
-
-	if container.ShouldCreateWithSSMSecret() {
-		resource, ok := task.getSSMSecretsResource()
-		if !ok {
-			return &apierrors.DockerClientConfigError{Msg: "task secret data: unable to fetch SSM Secrets resource"}
+	secretResources := task.getSecretResources()
+	for _, secret := range container.Secrets {
+		if _, ok := secretResources[secret.Provider]; !ok {
+			return &apierrors.DockerClientConfigError{
+				Msg: fmt.Sprintf("task secret data: unable to fetch %s Secrets resource", strings.ToUpper(secret.Provider)),
+			}
 		}
-		ssmRes = resource[0].(*ssmsecret.SSMSecretResource)
 	}
 
-	if container.ShouldCreateWithASMSecret() {
-		resource, ok := task.getASMSecretsResource()
-		if !ok {
-			return &apierrors.DockerClientConfigError{Msg: "task secret data: unable to fetch ASM Secrets resource"}
-		}
-		asmRes = resource[0].(*asmsecret.ASMSecretResource)
+	populateContainerSecrets(hostConfig, container, secretResources)
+	return nil
+}
+
+// GetSecretValue returns the value of the secret retrieved by the secrets resource of its
+// provider
+func (task *Task) GetSecretValue(secret apicontainer.Secret) (string, bool) {
+	if resource, ok := task.getSecretResources()[secret.Provider]; ok {
+		return resource.GetCachedSecretValue(secret.GetSecretResourceCacheKey())
 	}
+	return "", false
+}
 
-	populateContainerSecrets(hostConfig, container, ssmRes, asmRes)
-	return nil
+// getSecretResources returns the secrets resources of the task by the secret provider
+// they retrieve the secrets of
+func (task *Task) getSecretResources() map[string]taskresource.SecretResource {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	secretResources := make(map[string]taskresource.SecretResource)
+	for provider, resourceName := range secretProviderResourceNames {
+		if res, ok := task.ResourcesMapUnsafe[resourceName]; ok && len(res) > 0 {
+			if secretResource, ok := res[0].(taskresource.SecretResource); ok {
+				secretResources[provider] = secretResource
+			}
+		}
+	}
+	return secretResources
 }
 
 func populateContainerSecrets(hostConfig *dockercontainer.HostConfig, container *apicontainer.Container,
-	ssmRes *ssmsecret.SSMSecretResource, asmRes *asmsecret.ASMSecretResource) {
+	secretResources map[string]taskresource.SecretResource) {
 	envVars := make(map[string]string)
 
 	logDriverTokenName := ""
@@ -2358,16 +2731,9 @@ func populateContainerSecrets(hostConfig *dockercontainer.HostConfig, container
 	for _, secret := range container.Secrets {
 		secretVal := ""
 
-		if secret.Provider == apicontainer.SecretProviderSSM {
+		if secretRes, ok := secretResources[secret.Provider]; ok {
 			k := secret.GetSecretResourceCacheKey()
-			if secretValue, ok := ssmRes.GetCachedSecretValue(k); ok {
-				secretVal = secretValue
-			}
-		}
-
-		if secret.Provider == apicontainer.SecretProviderASM {
-			k := secret.GetSecretResourceCacheKey()
-			if secretValue, ok := asmRes.GetCachedSecretValue(k); ok {
+			if secretValue, ok := secretRes.GetCachedSecretValue(k); ok {
 				secretVal = secretValue
 			}
 		}
@@ -2406,26 +2772,14 @@ func populateContainerSecrets(hostConfig *dockercontainer.HostConfig, container
 // file variables constructed for secret log options when loading the config file.
 func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer *apicontainer.Container) *apierrors.DockerClientConfigError {
 	firelensENVs := make(map[string]string)
-
-	var ssmRes *ssmsecret.SSMSecretResource
-	var asmRes *asmsecret.ASMSecretResource
-
-	resource, ok := task.getSSMSecretsResource()
-	if ok {
-		ssmRes = resource[0].(*ssmsecret.SSMSecretResource)
-	}
-
-	resource, ok = task.getASMSecretsResource()
-	if ok {
-		asmRes = resource[0].(*asmsecret.ASMSecretResource)
-	}
+	secretResources := task.getSecretResources()
 
 	for _, container := range task.Containers {
 		if container.GetLogDriver() != firelensDriverName {
 			continue
 		}
 
-		logDriverSecretData, err := collectLogDriverSecretData(container.Secrets, ssmRes, asmRes)
+		logDriverSecretData, err := collectLogDriverSecretData(container.Secrets, secretResources)
 		if err != nil {
 			return &apierrors.DockerClientConfigError{
 				Msg: fmt.Sprintf("unable to generate config to create firelens container: %v", err),
@@ -2449,8 +2803,8 @@ func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer
 }
 
 // collectLogDriverSecretData collects all the secret values for log driver secrets.
-func collectLogDriverSecretData(secrets []apicontainer.Secret, ssmRes *ssmsecret.SSMSecretResource,
-	asmRes *asmsecret.ASMSecretResource) (map[string]string, error) {
+func collectLogDriverSecretData(secrets []apicontainer.Secret,
+	secretResources map[string]taskresource.SecretResource) (map[string]string, error) {
 	secretData := make(map[string]string)
 	for _, secret := range secrets {
 		if secret.Target != apicontainer.SecretTargetLogDriver {
@@ -2458,21 +2812,13 @@ func collectLogDriverSecretData(secrets []apicontainer.Secret, ssmRes *ssmsecret
 		}
 
 		secretVal := ""
-		cacheKey := secret.GetSecretResourceCacheKey()
-		if secret.Provider == apicontainer.SecretProviderSSM {
-			if ssmRes == nil {
-				return nil, errors.Errorf("missing secret value for secret %s", secret.Name)
-			}
-
-			if secretValue, ok := ssmRes.GetCachedSecretValue(cacheKey); ok {
-				secretVal = secretValue
-			}
-		} else if secret.Provider == apicontainer.SecretProviderASM {
-			if asmRes == nil {
+		if _, ok := secretProviderResourceNames[secret.Provider]; ok {
+			secretRes, ok := secretResources[secret.Provider]
+			if !ok {
 				return nil, errors.Errorf("missing secret value for secret %s", secret.Name)
 			}
 
-			if secretValue, ok := asmRes.GetCachedSecretValue(cacheKey); ok {
+			if secretValue, ok := secretRes.GetCachedSecretValue(secret.GetSecretResourceCacheKey()); ok {
 				secretVal = secretValue
 			}
 		}
@@ -2652,6 +2998,114 @@ func (task *Task) SetLocalIPAddress(addr string) {
 	task.LocalIPAddressUnsafe = addr
 }
 
+// IsEphemeralStorageQuotaEnforced returns true if the ephemeral storage quota of the task
+// has been applied to its containers.
+func (task *Task) IsEphemeralStorageQuotaEnforced() bool {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	return task.EphemeralStorageQuotaEnforcedUnsafe
+}
+
+// SetEphemeralStorageQuotaEnforced records that the ephemeral storage quota of the task
+// has been applied to its containers.
+func (task *Task) SetEphemeralStorageQuotaEnforced() {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	task.EphemeralStorageQuotaEnforcedUnsafe = true
+}
+
+// GetInstanceMetadataBlock returns whether the access of the task to the instance metadata
+// service is blocked, and the pid of the process in whose network namespace the blocked
+// attempts are counted, along with the identity of the namespace, if they are
+func (task *Task) GetInstanceMetadataBlock() (bool, string, string) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	return task.InstanceMetadataBlockedUnsafe, task.InstanceMetadataCounterPIDUnsafe,
+		task.InstanceMetadataCounterNetNSUnsafe
+}
+
+// SetInstanceMetadataBlocked records that the access of the task to the instance metadata
+// service is blocked, and the pid of the process in whose network namespace the blocked
+// attempts are counted, along with the identity of the namespace, if any
+func (task *Task) SetInstanceMetadataBlocked(counterPID string, counterNetNS string) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	task.InstanceMetadataBlockedUnsafe = true
+	task.InstanceMetadataCounterPIDUnsafe = counterPID
+	task.InstanceMetadataCounterNetNSUnsafe = counterNetNS
+}
+
+// initializeInstanceMetadataAccess applies the mode of the access of the task to the
+// instance metadata service. Proxying the access isn't supported.
+func (task *Task) initializeInstanceMetadataAccess() error {
+	switch task.InstanceMetadataAccess {
+	case "":
+		return nil
+	case instanceMetadataAccessBlock:
+		task.BlockInstanceMetadata = true
+		return nil
+	case instanceMetadataAccessProxy:
+		return errors.New("proxying the access of tasks to the instance metadata service is not supported")
+	default:
+		return errors.Errorf("unknown instance metadata access mode %q", task.InstanceMetadataAccess)
+	}
+}
+
+// GetInterruption returns a copy of the interruption notice of the instance the task
+// runs on, or nil if there's none
+func (task *Task) GetInterruption() *Interruption {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	if task.InterruptionUnsafe == nil {
+		return nil
+	}
+	interruption := *task.InterruptionUnsafe
+	return &interruption
+}
+
+// SetInterruption records the interruption notice of the instance the task runs on
+func (task *Task) SetInterruption(interruption Interruption) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	task.InterruptionUnsafe = &interruption
+}
+
+// RequiresNUMAPlacement returns true if the task requests to be placed on a single NUMA node
+func (task *Task) RequiresNUMAPlacement() bool {
+	return task.ResourceControls != nil && task.ResourceControls.NUMAAligned
+}
+
+// GetNUMANode returns the NUMA node the task is placed on, if it is
+func (task *Task) GetNUMANode() (int, bool) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	if task.NUMANodeUnsafe == nil {
+		return 0, false
+	}
+	return *task.NUMANodeUnsafe, true
+}
+
+// SetNUMANode records the NUMA node the task is placed on, and pins the cpus and the
+// memory of the task to it
+func (task *Task) SetNUMANode(node int, cpus string) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	task.NUMANodeUnsafe = &node
+	if task.ResourceControls == nil {
+		task.ResourceControls = &ResourceControls{}
+	}
+	task.ResourceControls.CpusetCpus = cpus
+	task.ResourceControls.CpusetMems = strconv.Itoa(node)
+}
+
 // UpdateTaskENIsLinkName updates the link name of all the enis associated with the task.
 func (task *Task) UpdateTaskENIsLinkName() {
 	task.lock.Lock()
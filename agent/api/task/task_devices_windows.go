@@ -0,0 +1,188 @@
+// +build windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// deviceIDTypeClass is the IDType of the devices that are mapped in the containers by their
+	// device interface class, every device of the class present on the host is mapped
+	deviceIDTypeClass = "class"
+	// deviceIDTypeLocationPath is the IDType of the PCI devices that are assigned to Hyper-V isolated
+	// containers with Discrete Device Assignment (DDA)
+	deviceIDTypeLocationPath = "vpci-location-path"
+	// comPortDeviceClass is the GUID_DEVINTERFACE_COMPORT device interface class, which maps every
+	// serial port of the host
+	comPortDeviceClass = "86E0D1E0-8089-11D0-9CE4-08003E301F73"
+	// gpuDeviceClass is the GUID_DEVINTERFACE_DISPLAY_ADAPTER device interface class, which gives
+	// containers access to the GPUs of the host through DirectX
+	gpuDeviceClass = "5B45201D-F2F2-4F3B-85BB-30FF1F953599"
+	// gpuDeviceName is the name used in task definitions to map the GPUs of the host
+	gpuDeviceName = "gpu"
+
+	digcfPresent         = 0x2
+	digcfDeviceInterface = 0x10
+)
+
+var (
+	comPortRegex = regexp.MustCompile(`^(?i)(\\\\\.\\)?(COM[0-9]+)$`)
+	guidRegex    = regexp.MustCompile(`^\{?([0-9A-Fa-f]{8})-([0-9A-Fa-f]{4})-([0-9A-Fa-f]{4})-([0-9A-Fa-f]{4})-([0-9A-Fa-f]{12})\}?$`)
+
+	// deviceClassPresent is used to validate the devices before the containers are created,
+	// it's a variable so that it can be mocked in tests
+	deviceClassPresent = hostDeviceClassPresent
+
+	setupapi                         = windows.NewLazySystemDLL("setupapi.dll")
+	procSetupDiGetClassDevsW         = setupapi.NewProc("SetupDiGetClassDevsW")
+	procSetupDiEnumDeviceInterfaces  = setupapi.NewProc("SetupDiEnumDeviceInterfaces")
+	procSetupDiDestroyDeviceInfoList = setupapi.NewProc("SetupDiDestroyDeviceInfoList")
+)
+
+// spDeviceInterfaceData is the SP_DEVICE_INTERFACE_DATA structure of the SetupAPI
+type spDeviceInterfaceData struct {
+	cbSize             uint32
+	interfaceClassGUID windows.GUID
+	flags              uint32
+	reserved           uintptr
+}
+
+// overrideDevicesForWindows converts the devices of the container to the IDType/ID format
+// that docker expects on Windows, and validates that they exist on the host. Devices can be
+// set in the task definition as:
+// * gpu, which maps the GPUs of the host through DirectX
+// * a device interface class, e.g. class/86E0D1E0-8089-11D0-9CE4-08003E301F73
+// * a PCI location path assigned with DDA, e.g. vpci-location-path/PCIROOT(0)#PCI(0100)
+func overrideDevicesForWindows(hostConfig *dockercontainer.HostConfig) error {
+	if len(hostConfig.Devices) == 0 {
+		return nil
+	}
+	var devices []dockercontainer.DeviceMapping
+	mapped := make(map[string]bool)
+	for _, device := range hostConfig.Devices {
+		windowsDevice, err := windowsDevice(device.PathOnHost)
+		if err != nil {
+			return err
+		}
+		if mapped[windowsDevice] {
+			continue
+		}
+		mapped[windowsDevice] = true
+		// Windows devices have no path in the container nor cgroup permissions
+		devices = append(devices, dockercontainer.DeviceMapping{PathOnHost: windowsDevice})
+	}
+	hostConfig.Devices = devices
+	return nil
+}
+
+// windowsDevice returns the IDType/ID of a device of the task definition, after checking
+// that the device exists on the host
+func windowsDevice(name string) (string, error) {
+	if matches := comPortRegex.FindStringSubmatch(name); matches != nil {
+		// Process isolated containers can only be given whole device interface classes, which
+		// would give the container every serial port of the host rather than the one requested
+		return "", errors.Errorf("unsupported device %s: single serial ports can't be mapped on Windows, use %s/%s to map all the serial ports of the host",
+			name, deviceIDTypeClass, comPortDeviceClass)
+	}
+	if strings.EqualFold(name, gpuDeviceName) {
+		return classDevice(gpuDeviceClass, "GPU")
+	}
+
+	idType, id := splitDeviceID(name)
+	switch strings.ToLower(idType) {
+	case deviceIDTypeClass:
+		return classDevice(id, "device")
+	case deviceIDTypeLocationPath:
+		// assigned devices are dismounted from the host, so their presence can't be checked here
+		if id == "" {
+			return "", errors.Errorf("invalid device %s: empty location path", name)
+		}
+		return deviceIDTypeLocationPath + "/" + id, nil
+	}
+	return "", errors.Errorf("unsupported device %s: Windows devices must be a serial port, %s, %s/<interface class GUID> or %s/<location path>",
+		name, gpuDeviceName, deviceIDTypeClass, deviceIDTypeLocationPath)
+}
+
+// splitDeviceID splits a device in docker's IDType/ID or IDType://ID format
+func splitDeviceID(name string) (string, string) {
+	if parts := strings.SplitN(name, "://", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return name, ""
+}
+
+// classDevice returns the device of an interface class, after checking that at least one
+// device of the class is present on the host
+func classDevice(class string, description string) (string, error) {
+	guid, err := parseGUID(class)
+	if err != nil {
+		return "", err
+	}
+	present, err := deviceClassPresent(guid)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to look up %s interface class %s", description, class)
+	}
+	if !present {
+		return "", errors.Errorf("no %s of interface class %s is present on the host", description, class)
+	}
+	return deviceIDTypeClass + "/" + strings.ToUpper(strings.Trim(class, "{}")), nil
+}
+
+// parseGUID parses a GUID in the 86E0D1E0-8089-11D0-9CE4-08003E301F73 format, with or without braces
+func parseGUID(s string) (windows.GUID, error) {
+	matches := guidRegex.FindStringSubmatch(s)
+	if matches == nil {
+		return windows.GUID{}, errors.Errorf("invalid device interface class %s", s)
+	}
+	data1, _ := strconv.ParseUint(matches[1], 16, 32)
+	data2, _ := strconv.ParseUint(matches[2], 16, 16)
+	data3, _ := strconv.ParseUint(matches[3], 16, 16)
+	guid := windows.GUID{Data1: uint32(data1), Data2: uint16(data2), Data3: uint16(data3)}
+	data4 := matches[4] + matches[5]
+	for i := range guid.Data4 {
+		b, _ := strconv.ParseUint(data4[2*i:2*i+2], 16, 8)
+		guid.Data4[i] = byte(b)
+	}
+	return guid, nil
+}
+
+// hostDeviceClassPresent returns true if at least one device of the interface class is present
+func hostDeviceClassPresent(class windows.GUID) (bool, error) {
+	devInfo, _, err := procSetupDiGetClassDevsW.Call(uintptr(unsafe.Pointer(&class)), 0, 0,
+		digcfPresent|digcfDeviceInterface)
+	if windows.Handle(devInfo) == windows.InvalidHandle {
+		return false, fmt.Errorf("SetupDiGetClassDevs failed: %v", err)
+	}
+	defer procSetupDiDestroyDeviceInfoList.Call(devInfo)
+
+	data := spDeviceInterfaceData{}
+	data.cbSize = uint32(unsafe.Sizeof(data))
+	found, _, _ := procSetupDiEnumDeviceInterfaces.Call(devInfo, 0, uintptr(unsafe.Pointer(&class)), 0,
+		uintptr(unsafe.Pointer(&data)))
+	return found != 0, nil
+}
@@ -113,6 +113,13 @@ func (task *Task) BuildLinuxResourceSpec(cGroupCPUPeriod time.Duration) (specs.L
 		linuxResourceSpec.Memory = &linuxMemorySpec
 	}
 
+	// Pin the task to its cpuset and throttle its io when requested
+	if task.ResourceControls != nil {
+		if err := task.buildLinuxResourceControlsSpec(&linuxResourceSpec); err != nil {
+			return specs.LinuxResources{}, err
+		}
+	}
+
 	return linuxResourceSpec, nil
 }
 
@@ -929,6 +929,15 @@ func TestCollectFirelensLogEnvOptions(t *testing.T) {
 	assert.Equal(t, "\"#{ENV['secret-name_0']}\"", containerToLogOptions["logsender"]["secret-name"])
 }
 
+func TestCollectFirelensLogEnvOptionsOtelcol(t *testing.T) {
+	task := getFirelensTask(t)
+
+	containerToLogOptions := make(map[string]map[string]string)
+	err := task.collectFirelensLogEnvOptions(containerToLogOptions, firelens.FirelensConfigTypeOtelcol)
+	assert.NoError(t, err)
+	assert.Equal(t, "${env:secret-name_0}", containerToLogOptions["logsender"]["secret-name"])
+}
+
 func TestAddFirelensContainerDependency(t *testing.T) {
 	testCases := []struct {
 		name                string
@@ -1031,6 +1040,21 @@ func TestAddFirelensContainerBindMounts(t *testing.T) {
 				"testDataDirOnHost/data/firelens/task-id/config/external.conf:/fluent-bit/etc/external.conf",
 			},
 		},
+		{
+			name: "test add bind mounts for otelcol firelens container",
+			task: func() *Task {
+				task := getFirelensTask(t)
+				task.Containers[1].FirelensConfig.Type = firelens.FirelensConfigTypeOtelcol
+				return task
+			}(),
+			hostCfg:    &dockercontainer.HostConfig{},
+			cfg:        cfg,
+			shouldFail: false,
+			expectedBindMounts: []string{
+				"testDataDirOnHost/data/firelens/task-id/config/otelcol.yaml:/etc/otelcol-contrib/config.yaml",
+				"testDataDirOnHost/data/firelens/task-id/socket/:/var/run/",
+			},
+		},
 		{
 			name: "test add bind mounts invalid firelens configuration type",
 			task: func() *Task {
@@ -1111,6 +1135,32 @@ func TestPopulateSecretLogOptionsToFirelensContainer(t *testing.T) {
 	assert.Equal(t, "secret-val", task.Containers[1].Environment["secret-name_0"])
 }
 
+func TestGetFirelensLogOptions(t *testing.T) {
+	task := getFirelensTask(t)
+	ssmRes := &ssmsecret.SSMSecretResource{}
+	ssmRes.SetCachedSecretValue("secret-value-from_us-west-2", "secret-val")
+	task.AddResource(ssmsecret.ResourceName, ssmRes)
+	require.Nil(t, task.PopulateSecretLogOptionsToFirelensContainer(task.Containers[1]))
+
+	logOptions, hasSecretValues, err := task.GetFirelensLogOptions(task.Containers[1])
+	require.NoError(t, err)
+	assert.False(t, hasSecretValues)
+	assert.Equal(t, map[string]map[string]string{
+		"logsender": {
+			"key1":        "value1",
+			"key2":        "value2",
+			"secret-name": "\"#{ENV['secret-name_0']}\"",
+		},
+	}, logOptions)
+
+	// The secret is rotated after the firelens container was created.
+	ssmRes.SetCachedSecretValue("secret-value-from_us-west-2", "rotated-secret-val")
+	logOptions, hasSecretValues, err = task.GetFirelensLogOptions(task.Containers[1])
+	require.NoError(t, err)
+	assert.True(t, hasSecretValues)
+	assert.Equal(t, "rotated-secret-val", logOptions["logsender"]["secret-name"])
+}
+
 func TestCollectLogDriverSecretData(t *testing.T) {
 	ssmRes := &ssmsecret.SSMSecretResource{}
 	ssmRes.SetCachedSecretValue("secret-value-from_us-west-2", "secret-val")
@@ -1135,7 +1185,10 @@ func TestCollectLogDriverSecretData(t *testing.T) {
 		},
 	}
 
-	secretData, err := collectLogDriverSecretData(secrets, ssmRes, asmRes)
+	secretData, err := collectLogDriverSecretData(secrets, map[string]taskresource.SecretResource{
+		apicontainer.SecretProviderSSM: ssmRes,
+		apicontainer.SecretProviderASM: asmRes,
+	})
 	assert.NoError(t, err)
 	assert.Len(t, secretData, 2)
 	assert.Equal(t, "secret-val", secretData["secret-name"])
@@ -1256,6 +1309,30 @@ func TestBuildCNIConfigRegularENIWithAppMesh(t *testing.T) {
 	}
 }
 
+func TestBuildCNIConfigIPv6OnlyENI(t *testing.T) {
+	testTask := &Task{}
+	testTask.AddTaskENI(&apieni.ENI{
+		ID:         "TestBuildCNIConfigIPv6OnlyENI",
+		MacAddress: mac,
+		IPV6Addresses: []*apieni.ENIIPV6Address{
+			{
+				Address: "2001:db8:1:2::10",
+			},
+		},
+	})
+
+	cniConfig, err := testTask.BuildCNIConfig(true, &ecscni.Config{})
+	assert.NoError(t, err)
+	// We expect 2 NetworkConfig objects in the cni Config wrapper object:
+	// ENI and Bridge.
+	require.Len(t, cniConfig.NetworkConfigs, 2)
+	var eniConfig ecscni.ENIConfig
+	err = json.Unmarshal(cniConfig.NetworkConfigs[0].CNINetworkConfig.Bytes, &eniConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2001:db8:1:2::10" + ipv6Block}, eniConfig.IPAddresses)
+	assert.Equal(t, []string{"2001:db8:1:2::1"}, eniConfig.GatewayIPAddresses)
+}
+
 func TestBuildCNIConfigTrunkBranchENI(t *testing.T) {
 	for _, blockIMDS := range []bool{true, false} {
 		t.Run(fmt.Sprintf("When BlockInstanceMetadata is %t", blockIMDS), func(t *testing.T) {
@@ -34,6 +34,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
@@ -48,6 +49,8 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
+	mock_vaultfactory "github.com/aws/amazon-ecs-agent/agent/vault/factory/mocks"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
@@ -338,6 +341,15 @@ func TestDockerHostConfigPauseContainer(t *testing.T) {
 	assert.Nil(t, dockerConfigErr)
 	assert.Equal(t, "eni.ip.region.compute.internal", dockerconfig.Hostname)
 
+	// Verify IPv6-only ENIs get IPv6 nameservers and ExtraHosts for the pause container
+	testTask.ENIs[0].IPV4Addresses = nil
+	testTask.ENIs[0].SubnetGatewayIPV4Address = ""
+	testTask.ENIs[0].IPV6Addresses = []*apieni.ENIIPV6Address{{Address: "2001:db8::10"}}
+	cfg, err = testTask.DockerHostConfig(pauseContainer, dockerMap(testTask), defaultDockerClientAPIVersion,
+		&config.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{apieni.AmazonIPv6DNSServer}, cfg.DNS)
+	assert.Equal(t, []string{"eni.ip.region.compute.internal:2001:db8::10"}, cfg.ExtraHosts)
 }
 
 func TestBadDockerHostConfigRawConfig(t *testing.T) {
@@ -2843,6 +2855,81 @@ func TestPopulateSecretsAsEnvOnlySSM(t *testing.T) {
 	assert.Equal(t, 1, len(container.Environment))
 }
 
+func TestInitializeAndPopulateVaultSecrets(t *testing.T) {
+	vaultSecret := apicontainer.Secret{
+		Provider:  apicontainer.SecretProviderVault,
+		Name:      "DB_PASSWORD",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "secret/db#password",
+	}
+	ssmSecret := apicontainer.Secret{
+		Provider:  apicontainer.SecretProviderSSM,
+		Name:      "secret3",
+		Region:    "us-west-2",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "/test/secretName",
+	}
+
+	container := &apicontainer.Container{
+		Name:                      "myName",
+		Image:                     "image:tag",
+		Secrets:                   []apicontainer.Secret{vaultSecret, ssmSecret},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	task := &Task{
+		Arn:                    "test",
+		ResourcesMapUnsafe:     make(map[string][]taskresource.TaskResource),
+		Containers:             []*apicontainer.Container{container},
+		credentialsID:          "task-creds-id",
+		ExecutionCredentialsID: "exec-creds-id",
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	resFields := &taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+			VaultClientCreator: mock_vaultfactory.NewMockClientCreator(ctrl),
+			CredentialsManager: credentialsManager,
+		},
+	}
+	require.True(t, task.requiresVaultSecret())
+	task.initializeVaultSecretResource(credentialsManager, resFields)
+
+	resourceDep := apicontainer.ResourceDependency{
+		Name:           vaultsecret.ResourceName,
+		RequiredStatus: resourcestatus.ResourceStatus(vaultsecret.VaultSecretCreated),
+	}
+	assert.Equal(t, resourceDep, task.Containers[0].TransitionDependenciesMap[apicontainerstatus.ContainerCreated].ResourceDependencies[0])
+
+	hostConfig := &dockercontainer.HostConfig{}
+	// the ssm secrets resource is missing
+	assert.NotNil(t, task.PopulateSecrets(hostConfig, container))
+
+	ssmRes := &ssmsecret.SSMSecretResource{}
+	ssmRes.SetCachedSecretValue(secretKeyWest1, "secretValue3")
+	task.AddResource(ssmsecret.ResourceName, ssmRes)
+
+	resources := task.GetResources()
+	var vaultRes *vaultsecret.VaultSecretResource
+	for _, res := range resources {
+		if res.GetName() == vaultsecret.ResourceName {
+			vaultRes = res.(*vaultsecret.VaultSecretResource)
+		}
+	}
+	require.NotNil(t, vaultRes)
+	vaultRes.SetCachedSecretValue(vaultSecret.GetSecretResourceCacheKey(), "hunter2")
+
+	assert.Nil(t, task.PopulateSecrets(hostConfig, container))
+	assert.Equal(t, "hunter2", container.Environment["DB_PASSWORD"])
+	assert.Equal(t, "secretValue3", container.Environment["secret3"])
+
+	value, ok := task.GetSecretValue(vaultSecret)
+	assert.True(t, ok)
+	assert.Equal(t, "hunter2", value)
+}
+
 func TestAddGPUResource(t *testing.T) {
 	container := &apicontainer.Container{
 		Name:  "myName",
@@ -3054,6 +3141,29 @@ func TestDockerHostConfigNeuronRuntime(t *testing.T) {
 	assert.Equal(t, neuronRuntime, dockerHostConfig.Runtime)
 }
 
+func TestDockerHostConfigNeuronDevices(t *testing.T) {
+	testTask := &Task{
+		Arn: "test",
+		Containers: []*apicontainer.Container{
+			{
+				Name:        "myName1",
+				Image:       "image:tag",
+				NeuronCores: 3,
+			},
+		},
+	}
+	testTask.Containers[0].SetNeuronCores([]int{3, 4, 5}, []string{"/dev/neuron1", "/dev/neuron2"}, []int{1, 2, 3})
+
+	dockerHostConfig, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask), defaultDockerClientAPIVersion,
+		&config.Config{InferentiaSupportEnabled: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []dockercontainer.DeviceMapping{
+		{PathOnHost: "/dev/neuron1", PathInContainer: "/dev/neuron0", CgroupPermissions: "rwm"},
+		{PathOnHost: "/dev/neuron2", PathInContainer: "/dev/neuron1", CgroupPermissions: "rwm"},
+	}, dockerHostConfig.Devices)
+	assert.Empty(t, dockerHostConfig.Runtime, "The devices are passed without the neuron runtime")
+}
+
 func TestAssociationsByTypeAndContainer(t *testing.T) {
 	associationType := "elastic-inference"
 	container1 := &apicontainer.Container{
@@ -3258,6 +3368,416 @@ func TestTaskFromACSPerContainerTimeouts(t *testing.T) {
 	assert.Equal(t, task.Containers[0].StopTimeout, expectedTimeout)
 }
 
+func TestTaskFromACSPreStopExec(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				PreStopExec: &ecsacs.ContainerPreStopExec{
+					Command:        aws.StringSlice([]string{"/bin/drain", "--graceful"}),
+					TimeoutSeconds: aws.Int64(20),
+				},
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, &apicontainer.PreStopExec{
+		Command:        []string{"/bin/drain", "--graceful"},
+		TimeoutSeconds: 20,
+	}, task.Containers[0].GetPreStopExec())
+}
+
+func TestTaskFromACSSecretFiles(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				Secrets: []*ecsacs.Secret{
+					{
+						Name:          aws.String("db-password"),
+						ValueFrom:     aws.String("/app/db-password"),
+						Provider:      aws.String("ssm"),
+						Type:          aws.String("FILE"),
+						ContainerPath: aws.String("/etc/app/secrets"),
+					},
+				},
+				SecretsRefreshSignal: aws.String("SIGUSR1"),
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	container := task.Containers[0]
+	assert.True(t, container.HasSecretFiles())
+	assert.Equal(t, "/etc/app/secrets", container.Secrets[0].GetFileContainerPath())
+	assert.Equal(t, "SIGUSR1", container.GetSecretsRefreshSignal())
+}
+
+func TestTaskFromACSPinnedEnvironmentFiles(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				EnvironmentFiles: []*ecsacs.EnvironmentFile{
+					{
+						Value:  aws.String("arn:aws:s3:::bucket/app.env"),
+						Type:   aws.String("s3"),
+						Etag:   aws.String("\"d41d8cd98f00b204e9800998ecf8427e\""),
+						Sha256: aws.String("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"),
+					},
+				},
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, []apicontainer.EnvironmentFile{
+		{
+			Value:  "arn:aws:s3:::bucket/app.env",
+			Type:   "s3",
+			ETag:   "\"d41d8cd98f00b204e9800998ecf8427e\"",
+			Sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}, task.Containers[0].EnvironmentFiles)
+}
+
+func TestTaskFromACSContainerStartConcurrency(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		ContainerStartConcurrency: aws.Int64(2),
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, int64(2), task.ContainerStartConcurrency)
+}
+
+func TestTaskFromACSCheckpointEnabled(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		CheckpointEnabled: aws.Bool(true),
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.True(t, task.CheckpointEnabled)
+}
+
+func TestTaskFromACSEphemeralStorage(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		EphemeralStorage: &ecsacs.EphemeralStorage{SizeInGiB: aws.Int64(30)},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	require.NotNil(t, task.EphemeralStorage)
+	assert.Equal(t, int64(30), task.EphemeralStorage.SizeInGiB)
+	assert.Equal(t, uint64(30*1024*1024*1024), task.EphemeralStorage.SizeInBytes())
+
+	task, err = TaskFromACS(&ecsacs.Task{}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Zero(t, task.EphemeralStorage.SizeInBytes())
+}
+
+func TestTaskFromACSNetworkPolicy(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		NetworkPolicy: &ecsacs.NetworkPolicy{
+			Rules: []*ecsacs.NetworkPolicyRule{
+				{Action: aws.String("deny"), Cidr: aws.String("169.254.169.254/32")},
+				{Action: aws.String("allow"), Cidr: aws.String("10.0.0.0/8"), Protocol: aws.String("tcp"),
+					FromPort: aws.Int64(443), ToPort: aws.Int64(445)},
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	require.NotNil(t, task.NetworkPolicy)
+	require.Len(t, task.NetworkPolicy.Rules, 2)
+	assert.Equal(t, "deny", task.NetworkPolicy.Rules[0].Action)
+	assert.Equal(t, "169.254.169.254/32", task.NetworkPolicy.Rules[0].CIDR)
+	assert.Equal(t, "tcp", task.NetworkPolicy.Rules[1].Protocol)
+	assert.Equal(t, 443, task.NetworkPolicy.Rules[1].FromPort)
+	assert.Equal(t, 445, task.NetworkPolicy.Rules[1].ToPort)
+}
+
+func TestTaskFromACSBlockInstanceMetadata(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{BlockInstanceMetadata: aws.Bool(true)}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.True(t, task.BlockInstanceMetadata)
+	blocked, _, _ := task.GetInstanceMetadataBlock()
+	assert.False(t, blocked, "The access is only blocked once the task network is set up")
+}
+
+func TestInitializeInstanceMetadataAccess(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{InstanceMetadataAccess: aws.String("BLOCK")},
+		&ecsacs.PayloadMessage{SeqNum: &seqNum})
+	require.NoError(t, err)
+	assert.NoError(t, task.initializeInstanceMetadataAccess())
+	assert.True(t, task.BlockInstanceMetadata)
+
+	task = &Task{InstanceMetadataAccess: "PROXY"}
+	err = task.initializeInstanceMetadataAccess()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported")
+	assert.False(t, task.BlockInstanceMetadata)
+
+	assert.Error(t, (&Task{InstanceMetadataAccess: "ALLOW"}).initializeInstanceMetadataAccess())
+	assert.NoError(t, (&Task{}).initializeInstanceMetadataAccess())
+}
+
+func TestTaskFromACSDNSConfiguration(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		DnsConfiguration: &ecsacs.DnsConfiguration{
+			LocalCache:          aws.Bool(true),
+			MaxQueriesPerSecond: aws.Int64(100),
+			CacheSize:           aws.Int64(1000),
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, &DNSConfiguration{LocalCache: true, MaxQueriesPerSecond: 100, CacheSize: 1000},
+		task.DNSConfiguration)
+	assert.False(t, task.UsesLocalDNSCache(), "Only awsvpc tasks use a local DNS cache")
+}
+
+func TestOverrideDNSLocalCache(t *testing.T) {
+	task := &Task{
+		DNSConfiguration: &DNSConfiguration{LocalCache: true},
+		ENIs: []*apieni.ENI{{
+			DomainNameServers:    []string{"10.0.0.2"},
+			DomainNameSearchList: []string{"us-west-2.compute.internal"},
+		}},
+	}
+	hostConfig := task.overrideDNS(&dockercontainer.HostConfig{})
+	assert.Equal(t, []string{dnscache.ListenAddress}, hostConfig.DNS)
+	assert.Equal(t, []string{"us-west-2.compute.internal"}, hostConfig.DNSSearch)
+
+	task.DNSConfiguration.LocalCache = false
+	hostConfig = task.overrideDNS(&dockercontainer.HostConfig{})
+	assert.Equal(t, []string{"10.0.0.2"}, hostConfig.DNS)
+}
+
+func TestTaskFromACSResourceControls(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{
+		ResourceControls: &ecsacs.ResourceControls{
+			CpusetCpus: aws.String("2-3"),
+			CpusetMems: aws.String("0"),
+			IoLimits: []*ecsacs.IoLimit{
+				{
+					Device:    aws.String("/dev/nvme1n1"),
+					ReadBps:   aws.Int64(1048576),
+					WriteBps:  aws.Int64(2097152),
+					ReadIops:  aws.Int64(100),
+					WriteIops: aws.Int64(200),
+				},
+			},
+		},
+	}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, &ResourceControls{
+		CpusetCpus: "2-3",
+		CpusetMems: "0",
+		IOLimits: []IOLimit{
+			{Device: "/dev/nvme1n1", ReadBps: 1048576, WriteBps: 2097152, ReadIOPS: 100, WriteIOPS: 200},
+		},
+	}, task.ResourceControls)
+}
+
+func TestTaskFromACSNUMAAligned(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{
+		ResourceControls: &ecsacs.ResourceControls{NumaAligned: aws.Bool(true)},
+	}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.True(t, task.RequiresNUMAPlacement())
+	_, placed := task.GetNUMANode()
+	assert.False(t, placed, "The task is only placed by the task engine")
+}
+
+func TestTaskFromACSNeuronCores(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{Name: aws.String("c1"), NeuronCores: aws.Int64(2)},
+		},
+	}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, 2, task.Containers[0].NeuronCores)
+	assert.Empty(t, task.Containers[0].GetNeuronCoreIDs(), "The cores are only assigned by the task engine")
+}
+
+func TestTaskFromACSEvictionPriority(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{Name: aws.String("c1"), Essential: aws.Bool(false), EvictionPriority: aws.Int64(-5)},
+			{Name: aws.String("c2"), Essential: aws.Bool(true)},
+		},
+	}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, int64(-5), task.Containers[0].EvictionPriority)
+	assert.Zero(t, task.Containers[1].EvictionPriority)
+}
+
+func TestTaskFromACSSecurityProfiles(t *testing.T) {
+	seqNum := int64(42)
+	task, err := TaskFromACS(&ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				Name: aws.String("c1"),
+				SecurityProfiles: []*ecsacs.SecurityProfile{
+					{Type: aws.String("seccomp"), Name: aws.String("strict")},
+					{Type: aws.String("apparmor"), Name: aws.String("web")},
+				},
+			},
+		},
+	}, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, []apicontainer.SecurityProfile{
+		{Type: "seccomp", Name: "strict"},
+		{Type: "apparmor", Name: "web"},
+	}, task.Containers[0].GetSecurityProfiles())
+}
+
+func TestGetMemoryPressureReturnsCopy(t *testing.T) {
+	task := &Task{}
+	assert.Nil(t, task.GetMemoryPressure())
+
+	task.SetMemoryPressure(MemoryPressure{DetectedAt: time.Now(), EvictedContainers: []string{"c1"}})
+	pressure := task.GetMemoryPressure()
+	require.NotNil(t, pressure)
+	pressure.EvictedContainers[0] = "c2"
+	assert.Equal(t, []string{"c1"}, task.GetMemoryPressure().EvictedContainers)
+	assert.False(t, pressure.Relieved())
+}
+
+func TestSetNUMANode(t *testing.T) {
+	task := &Task{ResourceControls: &ResourceControls{NUMAAligned: true}}
+	task.SetNUMANode(1, "8-15")
+
+	node, placed := task.GetNUMANode()
+	assert.True(t, placed)
+	assert.Equal(t, 1, node)
+	assert.Equal(t, "8-15", task.ResourceControls.CpusetCpus)
+	assert.Equal(t, "1", task.ResourceControls.CpusetMems)
+
+	// the node is persisted with the task
+	data, err := json.Marshal(task)
+	require.NoError(t, err)
+	var restored Task
+	require.NoError(t, json.Unmarshal(data, &restored))
+	node, placed = restored.GetNUMANode()
+	assert.True(t, placed)
+	assert.Equal(t, 1, node)
+}
+
+func TestTaskFromACSStopSignalSequence(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				Name: aws.String("c1"),
+				StopSignalSequence: []*ecsacs.ContainerStopSignal{
+					{Signal: aws.String("SIGUSR1"), WaitSeconds: aws.Int64(10)},
+					{Signal: aws.String("SIGTERM")},
+				},
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, []apicontainer.StopSignal{
+		{Signal: "SIGUSR1", WaitSeconds: 10},
+		{Signal: "SIGTERM"},
+	}, task.Containers[0].GetStopSignalSequence())
+}
+
+func TestTaskFromACSRestartPolicy(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				Name: aws.String("c1"),
+				RestartPolicy: &ecsacs.ContainerRestartPolicy{
+					MaxAttempts:        aws.Int64(3),
+					BackoffSeconds:     aws.Int64(5),
+					ResetWindowSeconds: aws.Int64(300),
+				},
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, &apicontainer.RestartPolicy{
+		MaxAttempts:        3,
+		BackoffSeconds:     5,
+		ResetWindowSeconds: 300,
+	}, task.Containers[0].GetRestartPolicy())
+}
+
+func TestTaskFromACSShutdownGracePeriod(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				Name:                aws.String("log-router"),
+				ShutdownGracePeriod: aws.Int64(15),
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, 15*time.Second, task.Containers[0].GetShutdownGracePeriod())
+}
+
+func TestTaskFromACSHealthCheckProbes(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Containers: []*ecsacs.Container{
+			{
+				Name: aws.String("c1"),
+				HealthCheckProbes: []*ecsacs.HealthCheckProbe{
+					{
+						Type:            aws.String("HTTP"),
+						Port:            aws.Int64(8080),
+						Path:            aws.String("/health"),
+						IntervalSeconds: aws.Int64(10),
+						TimeoutSeconds:  aws.Int64(2),
+						Retries:         aws.Int64(3),
+					},
+					{
+						Type:               aws.String("TCP"),
+						Port:               aws.Int64(5432),
+						StartPeriodSeconds: aws.Int64(30),
+					},
+				},
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	assert.Equal(t, []apicontainer.HealthCheckProbe{
+		{
+			Type:            apicontainer.HealthCheckProbeHTTP,
+			Port:            8080,
+			Path:            "/health",
+			IntervalSeconds: 10,
+			TimeoutSeconds:  2,
+			Retries:         3,
+		},
+		{
+			Type:               apicontainer.HealthCheckProbeTCP,
+			Port:               5432,
+			StartPeriodSeconds: 30,
+		},
+	}, task.Containers[0].HealthCheckProbes)
+	assert.True(t, task.Containers[0].HealthStatusShouldBeReported())
+}
+
 func TestGetContainerIndex(t *testing.T) {
 	task := &Task{
 		Containers: []*apicontainer.Container{
@@ -3454,3 +3974,124 @@ func TestPostUnmarshalTaskWithOptions(t *testing.T) {
 	task.PostUnmarshalTask(&config.Config{}, nil, nil, nil, nil, opt, opt)
 	assert.Equal(t, 2, numCalls)
 }
+
+func TestRecordCredentialsFetch(t *testing.T) {
+	task := &Task{}
+	assert.Nil(t, task.GetCredentialsFetchCount())
+
+	task.RecordCredentialsFetch(credentials.ApplicationRoleType)
+	task.RecordCredentialsFetch(credentials.ApplicationRoleType)
+	task.RecordCredentialsFetch(credentials.ExecutionRoleType)
+	fetchCount := task.GetCredentialsFetchCount()
+	assert.Equal(t, map[string]int{
+		credentials.ApplicationRoleType: 2,
+		credentials.ExecutionRoleType:   1,
+	}, fetchCount)
+
+	// the returned counts are a copy
+	fetchCount[credentials.ApplicationRoleType] = 10
+	assert.Equal(t, 2, task.GetCredentialsFetchCount()[credentials.ApplicationRoleType])
+}
+
+func TestTaskInterruption(t *testing.T) {
+	task := &Task{}
+	assert.Nil(t, task.GetInterruption())
+
+	noticeTime := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	task.SetInterruption(Interruption{
+		Source:     InterruptionSourceRebalance,
+		NoticeTime: noticeTime,
+	})
+	interruption := task.GetInterruption()
+	require.NotNil(t, interruption)
+	assert.Equal(t, InterruptionSourceRebalance, interruption.Source)
+
+	// the returned notice is a copy
+	interruption.TaskProtectionDisabled = true
+	assert.False(t, task.GetInterruption().TaskProtectionDisabled)
+
+	data, err := json.Marshal(task)
+	require.NoError(t, err)
+	var restored Task
+	require.NoError(t, json.Unmarshal(data, &restored))
+	assert.Equal(t, noticeTime, restored.GetInterruption().NoticeTime)
+}
+
+func TestTaskFromACSInitProcessEnabled(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		InitProcessEnabled: aws.Bool(true),
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	assert.Nil(t, err, "Should be able to handle acs task")
+	require.NotNil(t, task.InitProcessEnabled)
+	assert.True(t, *task.InitProcessEnabled)
+}
+
+func TestDockerHostConfigInitProcess(t *testing.T) {
+	enabled := config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	testCases := []struct {
+		name            string
+		taskInitProcess *bool
+		containerType   apicontainer.ContainerType
+		hostConfig      *string
+		agentDefault    config.BooleanDefaultFalse
+		expectedInit    *bool
+	}{
+		{
+			name:         "not set",
+			expectedInit: nil,
+		},
+		{
+			name:         "agent default",
+			agentDefault: enabled,
+			expectedInit: aws.Bool(true),
+		},
+		{
+			name:            "task disables agent default",
+			taskInitProcess: aws.Bool(false),
+			agentDefault:    enabled,
+			expectedInit:    aws.Bool(false),
+		},
+		{
+			name:            "task enabled",
+			taskInitProcess: aws.Bool(true),
+			expectedInit:    aws.Bool(true),
+		},
+		{
+			name:            "container host config takes precedence",
+			taskInitProcess: aws.Bool(true),
+			hostConfig:      strptr(`{"Init":false}`),
+			expectedInit:    aws.Bool(false),
+		},
+		{
+			name:            "internal container",
+			taskInitProcess: aws.Bool(true),
+			containerType:   apicontainer.ContainerCNIPause,
+			agentDefault:    enabled,
+			expectedInit:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &Task{
+				Arn:                "arn:aws:ecs:us-west-2:123456789012:task/test",
+				InitProcessEnabled: tc.taskInitProcess,
+				Containers: []*apicontainer.Container{
+					{
+						Name: "c1",
+						Type: tc.containerType,
+						DockerConfig: apicontainer.DockerConfig{
+							HostConfig: tc.hostConfig,
+						},
+					},
+				},
+			}
+			hostConfig, err := task.DockerHostConfig(task.Containers[0], dockerMap(task), defaultDockerClientAPIVersion,
+				&config.Config{TaskInitProcessEnabled: tc.agentDefault})
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedInit, hostConfig.Init)
+		})
+	}
+}
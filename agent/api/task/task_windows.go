@@ -105,7 +105,7 @@ func (task *Task) platformHostConfigOverride(hostConfig *dockercontainer.HostCon
 		hostConfig.MemoryReservation = 0
 	}
 
-	return nil
+	return overrideDevicesForWindows(hostConfig)
 }
 
 // dockerCPUShares converts containerCPU shares if needed as per the logic stated below:
@@ -141,7 +141,8 @@ func (task *Task) requiresCredentialSpecResource() bool {
 func (task *Task) initializeCredentialSpecResource(config *config.Config, credentialsManager credentials.Manager,
 	resourceFields *taskresource.ResourceFields) error {
 	credentialspecResource, err := credentialspec.NewCredentialSpecResource(task.Arn, config.AWSRegion, task.getAllCredentialSpecRequirements(),
-		task.ExecutionCredentialsID, credentialsManager, resourceFields.SSMClientCreator, resourceFields.S3ClientCreator)
+		task.ExecutionCredentialsID, credentialsManager, resourceFields.SSMClientCreator, resourceFields.S3ClientCreator,
+		resourceFields.ASMClientCreator, config.GMSACredentialSpecCacheTTL)
 	if err != nil {
 		return err
 	}
@@ -40,6 +40,7 @@ import (
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows"
 
 	mock_asm_factory "github.com/aws/amazon-ecs-agent/agent/asm/factory/mocks"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
@@ -158,6 +159,77 @@ func TestWindowsPlatformHostConfigOverride(t *testing.T) {
 	assert.Empty(t, hostConfig.CPUShares)
 }
 
+// mockHostDevices mocks the device interface classes present on the host
+func mockHostDevices(classes []string) func() {
+	deviceClassPresentFunc := deviceClassPresent
+	deviceClassPresent = func(class windows.GUID) (bool, error) {
+		for _, c := range classes {
+			guid, _ := parseGUID(c)
+			if guid == class {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return func() {
+		deviceClassPresent = deviceClassPresentFunc
+	}
+}
+
+func TestWindowsPlatformHostConfigOverrideDevices(t *testing.T) {
+	defer mockHostDevices([]string{comPortDeviceClass, gpuDeviceClass})()
+
+	hostConfig := &dockercontainer.HostConfig{Resources: dockercontainer.Resources{Devices: []dockercontainer.DeviceMapping{
+		{PathOnHost: "class/86e0d1e0-8089-11d0-9ce4-08003e301f73", PathInContainer: "COM1", CgroupPermissions: "rwm"},
+		{PathOnHost: "class/{86E0D1E0-8089-11D0-9CE4-08003E301F73}"},
+		{PathOnHost: "GPU"},
+		{PathOnHost: "class://5b45201d-f2f2-4f3b-85bb-30ff1f953599"},
+		{PathOnHost: "vpci-location-path/PCIROOT(0)#PCI(0100)"},
+	}}}
+
+	require.NoError(t, (&Task{}).platformHostConfigOverride(hostConfig))
+	assert.Equal(t, []dockercontainer.DeviceMapping{
+		{PathOnHost: "class/" + comPortDeviceClass},
+		{PathOnHost: "class/" + gpuDeviceClass},
+		{PathOnHost: "vpci-location-path/PCIROOT(0)#PCI(0100)"},
+	}, hostConfig.Devices)
+}
+
+func TestWindowsPlatformHostConfigOverrideDevicesErrors(t *testing.T) {
+	defer mockHostDevices([]string{comPortDeviceClass})()
+
+	for _, device := range []string{
+		"COM1",
+		`\\.\COM1`,
+		"gpu",
+		"class/5B45201D-F2F2-4F3B-85BB-30FF1F953599",
+		"class/not-a-guid",
+		"vpci-location-path/",
+		"/dev/ttyS0",
+	} {
+		t.Run(device, func(t *testing.T) {
+			hostConfig := &dockercontainer.HostConfig{Resources: dockercontainer.Resources{Devices: []dockercontainer.DeviceMapping{
+				{PathOnHost: device},
+			}}}
+			assert.Error(t, (&Task{}).platformHostConfigOverride(hostConfig))
+		})
+	}
+}
+
+func TestParseGUID(t *testing.T) {
+	guid, err := parseGUID("{86E0D1E0-8089-11D0-9CE4-08003E301F73}")
+	require.NoError(t, err)
+	assert.Equal(t, windows.GUID{
+		Data1: 0x86E0D1E0,
+		Data2: 0x8089,
+		Data3: 0x11D0,
+		Data4: [8]byte{0x9C, 0xE4, 0x08, 0x00, 0x3E, 0x30, 0x1F, 0x73},
+	}, guid)
+
+	_, err = parseGUID("86E0D1E0-8089-11D0-9CE4")
+	assert.Error(t, err)
+}
+
 func TestDockerHostConfigRawConfigMerging(t *testing.T) {
 	// Use a struct that will marshal to the actual message we expect; not
 	// dockercontainer.HostConfig which will include a lot of zero values.
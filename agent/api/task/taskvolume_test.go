@@ -271,6 +271,64 @@ func TestInitializeLocalDockerVolume(t *testing.T) {
 	assert.Len(t, testTask.Containers[0].TransitionDependenciesMap, 1, "expect a volume resource as the container dependency")
 }
 
+func TestInitializeEphemeralVolumeEncryption(t *testing.T) {
+	testTask := &Task{
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers: []*apicontainer.Container{
+			{
+				MountPoints: []apicontainer.MountPoint{
+					{SourceVolume: "empty-volume-test", ContainerPath: "/ecs"},
+					{SourceVolume: "task-volume-test", ContainerPath: "/task"},
+					{SourceVolume: "driver-opts-volume-test", ContainerPath: "/opts"},
+				},
+				TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+			},
+		},
+		Volumes: []TaskVolume{
+			{
+				Name:   "empty-volume-test",
+				Type:   "host",
+				Volume: &taskresourcevolume.LocalDockerVolume{},
+			},
+			{
+				Name: "task-volume-test",
+				Type: "docker",
+				Volume: &taskresourcevolume.DockerVolumeConfig{
+					Scope:  "task",
+					Driver: "local",
+				},
+			},
+			{
+				Name: "driver-opts-volume-test",
+				Type: "docker",
+				Volume: &taskresourcevolume.DockerVolumeConfig{
+					Scope:      "task",
+					Driver:     "local",
+					DriverOpts: map[string]string{"type": "nfs"},
+				},
+			},
+		},
+	}
+	cfg := &config.Config{
+		DataDir:                   "/data",
+		EphemeralVolumeEncryption: config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+		EncryptedVolumeSizeMB:     1024,
+	}
+	require.NoError(t, testTask.initializeVolumes(cfg, nil, nil))
+
+	encrypted := make(map[string]*taskresourcevolume.EncryptionConfig)
+	for _, resource := range testTask.GetResources() {
+		volumeResource := resource.(*taskresourcevolume.VolumeResource)
+		encrypted[volumeResource.Name] = volumeResource.Encryption
+	}
+	require.Len(t, encrypted, 3)
+	require.NotNil(t, encrypted["empty-volume-test"])
+	assert.Equal(t, 1024, encrypted["empty-volume-test"].SizeMB)
+	assert.Contains(t, encrypted["empty-volume-test"].BackingFile, "/data/encrypted-volumes/")
+	assert.NotNil(t, encrypted["task-volume-test"])
+	assert.Nil(t, encrypted["driver-opts-volume-test"], "volumes with driver options should not be encrypted")
+}
+
 func TestInitializeSharedProvisionedVolume(t *testing.T) {
 	sharedVolumeMatchFullConfig := config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
 	ctrl := gomock.NewController(t)
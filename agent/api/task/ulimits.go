@@ -0,0 +1,79 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"math"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+)
+
+// applyUlimits adds the default ulimits of the agent config that the container doesn't
+// set in its task definition, lowered to the hard limits of the host when higher, and
+// checks the soft limits of all the ulimits of the container don't exceed their hard
+// limits, which docker would only report when starting the container. The internal
+// containers don't get the default ulimits.
+func (task *Task) applyUlimits(container *apicontainer.Container, hostConfig *dockercontainer.HostConfig,
+	cfg *config.Config) *apierrors.HostConfigError {
+	if !container.IsInternal() {
+		for _, defaultUlimit := range cfg.ContainerDefaultUlimits {
+			if hasUlimit(hostConfig.Ulimits, defaultUlimit.Name) {
+				// the limit set in the task definition takes precedence
+				continue
+			}
+			// copy the ulimit, as the config is shared by all the containers
+			ulimit := *defaultUlimit
+			clampUlimit(container.Name, &ulimit)
+			hostConfig.Ulimits = append(hostConfig.Ulimits, &ulimit)
+		}
+	}
+
+	for _, ulimit := range hostConfig.Ulimits {
+		if err := validateUlimit(ulimit); err != nil {
+			return &apierrors.HostConfigError{Msg: fmt.Sprintf("invalid ulimit %s of container %s: %v",
+				ulimit.Name, container.Name, err)}
+		}
+	}
+	return nil
+}
+
+func hasUlimit(ulimits []*units.Ulimit, name string) bool {
+	for _, ulimit := range ulimits {
+		if ulimit.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func validateUlimit(ulimit *units.Ulimit) error {
+	if ulimitValue(ulimit.Soft) > ulimitValue(ulimit.Hard) {
+		return fmt.Errorf("soft limit %d is higher than hard limit %d", ulimit.Soft, ulimit.Hard)
+	}
+	return nil
+}
+
+// ulimitValue returns the value of the limit as docker passes it to the kernel, where
+// negative values mean unlimited
+func ulimitValue(limit int64) uint64 {
+	if limit < 0 {
+		return math.MaxUint64
+	}
+	return uint64(limit)
+}
@@ -0,0 +1,167 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/cihub/seelog"
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+)
+
+const (
+	// dockerdComm is the command name of the docker daemon, whose limits are the ones
+	// docker gives the containers it runs by default
+	dockerdComm = "dockerd"
+	// limitsNameWidth is the width of the name column of /proc/<pid>/limits, whose rows
+	// are in the order of the resource numbers
+	limitsNameWidth = 26
+	limitsUnlimited = "unlimited"
+)
+
+var (
+	// hostProcFSPath is where the proc filesystem of the host is mounted in the agent
+	// container
+	hostProcFSPath = "/host/proc"
+	// getrlimit reads the limits of the agent process, which are used when the ones of
+	// the docker daemon can't be read. It's a variable so that tests can replace it.
+	getrlimit = syscall.Getrlimit
+)
+
+// clampUlimit lowers the ulimit to the hard limit of the docker daemon when it's higher,
+// as docker would fail to create the container otherwise. The number of open files is
+// also capped by the fs.nr_open limit of the host.
+func clampUlimit(containerName string, ulimit *units.Ulimit) {
+	rlimit, err := ulimit.GetRlimit()
+	if err != nil {
+		// the ulimit is rejected when it's validated
+		return
+	}
+	hostMax, err := hostHardLimit(rlimit.Type)
+	if err != nil {
+		seelog.Warnf("Unable to read the host limit of ulimit %s for container %s: %v",
+			ulimit.Name, containerName, err)
+		return
+	}
+	if ulimitValue(ulimit.Hard) <= hostMax {
+		return
+	}
+	seelog.Warnf("Lowering ulimit %s of container %s from %s to the host hard limit %d",
+		ulimit.Name, containerName, ulimit.String(), hostMax)
+	ulimit.Hard = int64(hostMax)
+	if ulimitValue(ulimit.Soft) > hostMax {
+		ulimit.Soft = ulimit.Hard
+	}
+}
+
+// hostHardLimit returns the hard limit of the resource of the docker daemon, or of the
+// agent when the daemon isn't found
+func hostHardLimit(resource int) (uint64, error) {
+	var hostMax uint64
+	if max, err := dockerdHardLimit(resource); err == nil {
+		hostMax = max
+	} else {
+		seelog.Debugf("Unable to read the limits of the docker daemon, using the ones of the agent: %v", err)
+		var rlimit syscall.Rlimit
+		if err := getrlimit(resource, &rlimit); err != nil {
+			return 0, err
+		}
+		hostMax = rlimit.Max
+	}
+	if resource == syscall.RLIMIT_NOFILE {
+		nrOpen, err := readNrOpen()
+		if err != nil {
+			seelog.Debugf("Unable to read the maximum number of open files of the host: %v", err)
+		} else if nrOpen < hostMax {
+			hostMax = nrOpen
+		}
+	}
+	return hostMax, nil
+}
+
+// dockerdHardLimit returns the hard limit of the resource of the docker daemon, from the
+// limits file of its process in the host proc filesystem
+func dockerdHardLimit(resource int) (uint64, error) {
+	pid, err := findDockerdPID()
+	if err != nil {
+		return 0, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(hostProcFSPath, pid, "limits"))
+	if err != nil {
+		return 0, err
+	}
+	return parseHardLimit(data, resource)
+}
+
+// findDockerdPID returns the pid of the docker daemon in the host proc filesystem
+func findDockerdPID() (string, error) {
+	entries, err := ioutil.ReadDir(hostProcFSPath)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		comm, err := ioutil.ReadFile(filepath.Join(hostProcFSPath, entry.Name(), "comm"))
+		if err == nil && strings.TrimSpace(string(comm)) == dockerdComm {
+			return entry.Name(), nil
+		}
+	}
+	return "", errors.Errorf("no %s process in %s", dockerdComm, hostProcFSPath)
+}
+
+// parseHardLimit returns the hard limit of the resource in the content of a
+// /proc/<pid>/limits file
+func parseHardLimit(data []byte, resource int) (uint64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// the first line is the header
+	for row := -1; scanner.Scan(); row++ {
+		if row != resource {
+			continue
+		}
+		line := scanner.Text()
+		if len(line) <= limitsNameWidth {
+			break
+		}
+		fields := strings.Fields(line[limitsNameWidth:])
+		if len(fields) < 2 {
+			break
+		}
+		if fields[1] == limitsUnlimited {
+			return math.MaxUint64, nil
+		}
+		return strconv.ParseUint(fields[1], 10, 64)
+	}
+	return 0, errors.Errorf("no limit of resource %d", resource)
+}
+
+// readNrOpen returns the maximum number of open files of a process on the host
+func readNrOpen() (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(hostProcFSPath, "sys", "fs", "nr_open"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
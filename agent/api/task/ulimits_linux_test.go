@@ -0,0 +1,172 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/docker/go-units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClampUlimit(t *testing.T) {
+	defer func() {
+		getrlimit = syscall.Getrlimit
+		hostProcFSPath = "/host/proc"
+	}()
+	// the limits of the agent are used when the docker daemon isn't found
+	hostProcFSPath = t.TempDir()
+	getrlimit = func(resource int, rlim *syscall.Rlimit) error {
+		assert.Equal(t, syscall.RLIMIT_NOFILE, resource)
+		rlim.Cur, rlim.Max = 1024, 4096
+		return nil
+	}
+
+	testCases := []struct {
+		name     string
+		ulimit   units.Ulimit
+		expected units.Ulimit
+	}{
+		{
+			name:     "below host limit",
+			ulimit:   units.Ulimit{Name: "nofile", Soft: 1024, Hard: 2048},
+			expected: units.Ulimit{Name: "nofile", Soft: 1024, Hard: 2048},
+		},
+		{
+			name:     "hard limit above host limit",
+			ulimit:   units.Ulimit{Name: "nofile", Soft: 1024, Hard: 65536},
+			expected: units.Ulimit{Name: "nofile", Soft: 1024, Hard: 4096},
+		},
+		{
+			name:     "both limits above host limit",
+			ulimit:   units.Ulimit{Name: "nofile", Soft: 8192, Hard: 65536},
+			expected: units.Ulimit{Name: "nofile", Soft: 4096, Hard: 4096},
+		},
+		{
+			name:     "unlimited",
+			ulimit:   units.Ulimit{Name: "nofile", Soft: -1, Hard: -1},
+			expected: units.Ulimit{Name: "nofile", Soft: 4096, Hard: 4096},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ulimit := tc.ulimit
+			clampUlimit("c1", &ulimit)
+			assert.Equal(t, tc.expected, ulimit)
+		})
+	}
+}
+
+func TestClampUlimitHostLimitError(t *testing.T) {
+	defer func() {
+		getrlimit = syscall.Getrlimit
+		hostProcFSPath = "/host/proc"
+	}()
+	// the limits of the agent are used when the docker daemon isn't found
+	hostProcFSPath = t.TempDir()
+	getrlimit = func(resource int, rlim *syscall.Rlimit) error {
+		return errors.New("error")
+	}
+
+	ulimit := units.Ulimit{Name: "nofile", Soft: 8192, Hard: 65536}
+	clampUlimit("c1", &ulimit)
+	assert.Equal(t, units.Ulimit{Name: "nofile", Soft: 8192, Hard: 65536}, ulimit)
+}
+
+const testDockerdLimits = `Limit                     Soft Limit           Hard Limit           Units     
+Max cpu time              unlimited            unlimited            seconds   
+Max file size             unlimited            unlimited            bytes     
+Max data size             unlimited            unlimited            bytes     
+Max stack size            8388608              unlimited            bytes     
+Max core file size        unlimited            unlimited            bytes     
+Max resident set          unlimited            unlimited            bytes     
+Max processes             unlimited            unlimited            processes 
+Max open files            1048576              1048576              files     
+Max locked memory         65536                65536                bytes     
+Max address space         unlimited            unlimited            bytes     
+Max file locks            unlimited            unlimited            locks     
+Max pending signals       63372                63372                signals   
+Max msgqueue size         819200               819200               bytes     
+Max nice priority         0                    0                    
+Max realtime priority     0                    0                    
+Max realtime timeout      unlimited            unlimited            us        
+`
+
+// setupHostProcFS creates a host proc filesystem with a docker daemon with the test
+// limits, and an fs.nr_open limit
+func setupHostProcFS(t *testing.T, nrOpen string) func() {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "1", "comm"), []byte("systemd\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "42"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "42", "comm"), []byte("dockerd\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "42", "limits"), []byte(testDockerdLimits), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sys", "fs"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sys", "fs", "nr_open"), []byte(nrOpen), 0644))
+	hostProcFSPath = dir
+	getrlimit = func(resource int, rlim *syscall.Rlimit) error {
+		t.Errorf("the limits of the agent are read")
+		return errors.New("error")
+	}
+	return func() {
+		hostProcFSPath = "/host/proc"
+		getrlimit = syscall.Getrlimit
+	}
+}
+
+func TestClampUlimitDockerdLimits(t *testing.T) {
+	defer setupHostProcFS(t, "2097152\n")()
+
+	ulimit := units.Ulimit{Name: "nofile", Soft: 65536, Hard: 4194304}
+	clampUlimit("c1", &ulimit)
+	assert.Equal(t, units.Ulimit{Name: "nofile", Soft: 65536, Hard: 1048576}, ulimit)
+
+	ulimit = units.Ulimit{Name: "memlock", Soft: -1, Hard: -1}
+	clampUlimit("c1", &ulimit)
+	assert.Equal(t, units.Ulimit{Name: "memlock", Soft: 65536, Hard: 65536}, ulimit)
+
+	ulimit = units.Ulimit{Name: "stack", Soft: -1, Hard: -1}
+	clampUlimit("c1", &ulimit)
+	assert.Equal(t, units.Ulimit{Name: "stack", Soft: -1, Hard: -1}, ulimit)
+}
+
+func TestClampUlimitNrOpen(t *testing.T) {
+	defer setupHostProcFS(t, "524288\n")()
+
+	ulimit := units.Ulimit{Name: "nofile", Soft: 1048576, Hard: 1048576}
+	clampUlimit("c1", &ulimit)
+	assert.Equal(t, units.Ulimit{Name: "nofile", Soft: 524288, Hard: 524288}, ulimit)
+}
+
+func TestParseHardLimit(t *testing.T) {
+	limit, err := parseHardLimit([]byte(testDockerdLimits), syscall.RLIMIT_NOFILE)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1048576), limit)
+
+	limit, err = parseHardLimit([]byte(testDockerdLimits), syscall.RLIMIT_CPU)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), limit)
+
+	_, err = parseHardLimit([]byte(testDockerdLimits), 16)
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/docker/go-units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerHostConfigDefaultUlimits(t *testing.T) {
+	cfg := &config.Config{
+		ContainerDefaultUlimits: []*units.Ulimit{
+			{Name: "nofile", Soft: 64, Hard: 128},
+			{Name: "core", Soft: 0, Hard: 0},
+		},
+	}
+	testTask := &Task{
+		Arn: "arn:aws:ecs:us-west-2:123456789012:task/test",
+		Containers: []*apicontainer.Container{
+			{
+				Name: "c1",
+				DockerConfig: apicontainer.DockerConfig{
+					HostConfig: strptr(`{"Ulimits":[{"Name":"nofile","Soft":32,"Hard":32}]}`),
+				},
+			},
+			{
+				Name: NetworkPauseContainerName,
+				Type: apicontainer.ContainerCNIPause,
+			},
+		},
+	}
+
+	hostConfig, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+		defaultDockerClientAPIVersion, cfg)
+	require.Nil(t, err)
+	assert.Equal(t, []*units.Ulimit{
+		{Name: "nofile", Soft: 32, Hard: 32},
+		{Name: "core", Soft: 0, Hard: 0},
+	}, hostConfig.Ulimits)
+
+	hostConfig, err = testTask.DockerHostConfig(testTask.Containers[1], dockerMap(testTask),
+		defaultDockerClientAPIVersion, cfg)
+	require.Nil(t, err)
+	assert.Empty(t, hostConfig.Ulimits)
+	assert.Len(t, cfg.ContainerDefaultUlimits, 2, "config ulimits shouldn't be modified")
+}
+
+func TestDockerHostConfigInvalidUlimits(t *testing.T) {
+	for _, ulimits := range []string{
+		`[{"Name":"nofile","Soft":4096,"Hard":1024}]`,
+		`[{"Name":"nofile","Soft":-1,"Hard":1024}]`,
+	} {
+		t.Run(ulimits, func(t *testing.T) {
+			testTask := &Task{
+				Arn: "arn:aws:ecs:us-west-2:123456789012:task/test",
+				Containers: []*apicontainer.Container{
+					{
+						Name: "c1",
+						DockerConfig: apicontainer.DockerConfig{
+							HostConfig: strptr(`{"Ulimits":` + ulimits + `}`),
+						},
+					},
+				},
+			}
+			_, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask),
+				defaultDockerClientAPIVersion, &config.Config{})
+			assert.NotNil(t, err)
+		})
+	}
+}
@@ -15,9 +15,9 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/eni/watcher"
@@ -36,6 +36,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/containermetadata"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/diagnostics"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/sdkclientfactory"
@@ -48,15 +49,20 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/handlers"
+	"github.com/aws/amazon-ecs-agent/agent/instanceattributes"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 	tcshandler "github.com/aws/amazon-ecs-agent/agent/tcs/handler"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/mobypkgwrapper"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/aws/amazon-ecs-agent/agent/vault"
 	"github.com/aws/amazon-ecs-agent/agent/version"
 	"github.com/aws/aws-sdk-go/aws"
 	aws_credentials "github.com/aws/aws-sdk-go/aws/credentials"
@@ -76,6 +82,10 @@ const (
 
 	blackholed = "blackholed"
 
+	// diagnosticsBundleDir is the directory of the data directory the diagnostics
+	// bundles requested with SIGUSR2 are written to
+	diagnosticsBundleDir = "diagnostics"
+
 	instanceIdBackoffMin      = time.Second
 	instanceIdBackoffMax      = time.Second * 5
 	instanceIdBackoffJitter   = 0.2
@@ -114,6 +124,7 @@ type ecsAgent struct {
 	dataClient                  data.Client
 	dockerClient                dockerapi.DockerClient
 	containerInstanceARN        string
+	ec2InstanceID               string
 	credentialProvider          *aws_credentials.Credentials
 	stateManagerFactory         factory.StateManager
 	saveableOptionFactory       factory.SaveableOption
@@ -129,6 +140,11 @@ type ecsAgent struct {
 	resourceFields              *taskresource.ResourceFields
 	availabilityZone            string
 	latestSeqNumberTaskManifest *int64
+	taskMetadataPipeServer      *handlers.TaskMetadataPipeServer
+	attributeDiscoverer         *instanceattributes.Discoverer
+	// containerRuntimes lists the OCI runtimes of docker, the sandboxed runtimes aren't
+	// advertised when it's nil
+	containerRuntimes func() ([]string, error)
 }
 
 // newAgent returns a new ecsAgent object, but does not start anything
@@ -154,6 +170,7 @@ func newAgent(blackholeEC2Metadata bool, acceptInsecureCert *bool) (agent, error
 	}
 	seelog.Infof("Amazon ECS agent Version: %s, Commit: %s", version.Version, version.GitShortHash)
 	seelog.Debugf("Loaded config: %s", cfg.String())
+	seelog.Infof("Effective configuration: %s", cfg.Report())
 
 	if cfg.External.Enabled() {
 		seelog.Info("Running in external mode.")
@@ -173,12 +190,17 @@ func newAgent(blackholeEC2Metadata bool, acceptInsecureCert *bool) (agent, error
 
 	var dataClient data.Client
 	if cfg.Checkpoint.Enabled() {
-		dataClient, err = data.New(cfg.DataDir)
+		dataClient, err = data.NewWithBackend(cfg.DataBackend, cfg.DataDir)
 		if err != nil {
 			seelog.Criticalf("Error creating data client: %v", err)
 			cancel()
 			return nil, err
 		}
+		if cfg.PersistECRTokenCache.Enabled() {
+			if err := dockerClient.PersistECRTokenCache(dataClient); err != nil {
+				seelog.Warnf("Unable to persist the ECR auth token cache, tokens will only be cached in memory: %v", err)
+			}
+		}
 	} else {
 		dataClient = data.NewNoopClient()
 	}
@@ -212,6 +234,10 @@ func newAgent(blackholeEC2Metadata bool, acceptInsecureCert *bool) (agent, error
 		terminationHandler:          sighandlers.StartDefaultTerminationHandler,
 		mobyPlugins:                 mobypkgwrapper.NewPlugins(),
 		latestSeqNumberTaskManifest: &initialSeqNumber,
+		attributeDiscoverer:         instanceattributes.NewDiscoverer(cfg.InstanceAttributeProviders, cfg.InstanceAttributePluginsDir),
+		containerRuntimes: func() ([]string, error) {
+			return dockerContainerRuntimes(ctx, dockerClient)
+		},
 	}, nil
 }
 
@@ -244,7 +270,8 @@ func (agent *ecsAgent) start() int {
 	client := ecsclient.NewECSClient(agent.credentialProvider, agent.cfg, agent.ec2MetadataClient)
 
 	agent.initializeResourceFields(credentialsManager)
-	return agent.doStart(containerChangeEventStream, credentialsManager, state, imageManager, client, execcmd.NewManager())
+	execCmdMgr := execcmd.NewManagerWithConfig(agent.cfg)
+	return agent.doStart(containerChangeEventStream, credentialsManager, state, imageManager, client, execCmdMgr)
 }
 
 // doStart is the worker invoked by start for starting the ECS Agent. This involves
@@ -275,6 +302,18 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 			return exitcodes.ExitError
 		}
 	}
+	if agent.cfg.InferentiaSupportEnabled {
+		if err := agent.initializeNeuronManager(); err != nil {
+			seelog.Criticalf("Could not initialize Neuron Manager: %v", err)
+			return exitcodes.ExitError
+		}
+	}
+	if agent.cfg.EphemeralVolumeEncryption.Enabled() {
+		if err := volume.CheckEncryptedVolumeSupport(); err != nil {
+			seelog.Criticalf("Unable to encrypt ephemeral volumes: %v", err)
+			return exitcodes.ExitTerminal
+		}
+	}
 
 	// Create the task engine
 	taskEngine, currentEC2InstanceID, err := agent.newTaskEngine(containerChangeEventStream,
@@ -283,7 +322,9 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 		seelog.Criticalf("Unable to initialize new task engine: %v", err)
 		return exitcodes.ExitTerminal
 	}
+	agent.ec2InstanceID = currentEC2InstanceID
 	agent.initMetricsEngine()
+	agent.initTracing()
 
 	loadPauseErr := agent.loadPauseContainer()
 	if loadPauseErr != nil {
@@ -326,6 +367,12 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 		}
 	}
 
+	// Instances in the warm pool of an auto scaling group are registered once they're
+	// put in service
+	if agent.cfg.WarmPoolsCheck.Enabled() && !agent.waitUntilInService() {
+		return exitcodes.ExitSuccess
+	}
+
 	// Register the container instance
 	err = agent.registerContainerInstance(client, vpcSubnetAttributes)
 	if err != nil {
@@ -351,6 +398,13 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 		agent.saveMetadata(data.EC2InstanceIDKey, currentEC2InstanceID)
 	}
 
+	// Serve the task metadata endpoints over named pipes, the pipes of the restored tasks
+	// are created again when the engine is initialized
+	if dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine); ok && agent.cfg.TaskMetadataNamedPipeEnabled.Enabled() {
+		agent.taskMetadataPipeServer = handlers.NewTaskMetadataPipeServer(state)
+		dockerTaskEngine.SetTaskMetadataPipeServer(agent.taskMetadataPipeServer)
+	}
+
 	// Begin listening to the docker daemon and saving changes
 	taskEngine.SetDataClient(agent.dataClient)
 	imageManager.SetDataClient(agent.dataClient)
@@ -423,9 +477,27 @@ func (agent *ecsAgent) newTaskEngine(containerChangeEventStream *eventstream.Eve
 	agent.availabilityZone = savedData.availabilityZone
 	agent.latestSeqNumberTaskManifest = &savedData.latestTaskManifestSeqNum
 
+	if agent.dataClient.Recovered() {
+		agent.recoverContainersFromLabels(savedData.taskEngine)
+	}
+
 	return savedData.taskEngine, currentEC2InstanceID, nil
 }
 
+// recoverContainersFromLabels lets the task engine find the containers of the tasks
+// that were lost with a corrupted data store, so that they are adopted rather than
+// created again when their tasks are sent again
+func (agent *ecsAgent) recoverContainersFromLabels(taskEngine engine.TaskEngine) {
+	dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine)
+	if !ok {
+		return
+	}
+	seelog.Warn("The data store was recovered from a corruption; looking for the containers of lost tasks")
+	if err := dockerTaskEngine.RecoverContainersFromLabels(agent.ctx); err != nil {
+		seelog.Warnf("Unable to recover the containers of lost tasks: %v", err)
+	}
+}
+
 func (agent *ecsAgent) initMetricsEngine() {
 	// In case of a panic during set-up, we will recover quietly and resume
 	// normal Agent execution.
@@ -440,6 +512,16 @@ func (agent *ecsAgent) initMetricsEngine() {
 	metrics.PublishMetrics()
 }
 
+// initTracing starts exporting the spans of the agent operations when an OTLP
+// endpoint is configured
+func (agent *ecsAgent) initTracing() {
+	if agent.cfg.OTelExporterEndpoint == "" {
+		return
+	}
+	seelog.Infof("Exporting traces of agent operations to %s", agent.cfg.OTelExporterEndpoint)
+	tracing.Init(agent.ctx, agent.cfg)
+}
+
 // setClusterInConfig sets the cluster name in the config object based on
 // previous state. It returns an error if there's a mismatch between the
 // the current cluster name with what's restored from the cluster state
@@ -463,6 +545,17 @@ func (agent *ecsAgent) setClusterInConfig(previousCluster string) error {
 	return nil
 }
 
+// vaultConfig returns the HashiCorp Vault server the vault secrets of the tasks are read from
+func (agent *ecsAgent) vaultConfig() vault.Config {
+	return vault.Config{
+		Address:     agent.cfg.VaultAddr,
+		Namespace:   agent.cfg.VaultNamespace,
+		AuthMount:   agent.cfg.VaultAWSAuthMount,
+		AuthRole:    agent.cfg.VaultAWSAuthRole,
+		IAMServerID: agent.cfg.VaultAWSIAMServerID,
+	}
+}
+
 // getEC2InstanceID gets the EC2 instance ID from the metadata service
 func (agent *ecsAgent) getEC2InstanceID() string {
 	var instanceID string
@@ -640,24 +733,47 @@ func (agent *ecsAgent) startAsyncRoutines(
 		go imageManager.StartImageCleanupProcess(agent.ctx)
 	}
 
-	// Start automatic spot instance draining poller routine
-	if agent.cfg.SpotInstanceDrainingEnabled.Enabled() {
-		go agent.startSpotInstanceDrainingPoller(agent.ctx, client)
+	// Start watching for the notices that the instance is going to be interrupted, to drain it
+	if agent.cfg.SpotInstanceDrainingEnabled.Enabled() || agent.cfg.SpotRebalanceDrainingEnabled.Enabled() ||
+		agent.cfg.ASGTerminationDrainingEnabled.Enabled() {
+		go newInterruptionWatcher(agent, client, state).watch(agent.ctx)
+	}
+
+	// Start refreshing the discovered attributes of the container instance
+	if agent.attributeDiscoverer != nil {
+		go agent.attributeDiscoverer.Refresh(agent.ctx, client, agent.containerInstanceARN,
+			agent.cfg.InstanceAttributeRefreshInterval)
 	}
 
 	go agent.terminationHandler(state, agent.dataClient, taskEngine, agent.cancel)
 
+	// Start of the periodic compaction of the data store
+	if agent.cfg.Checkpoint.Enabled() && agent.cfg.DataCompactionInterval > 0 {
+		go data.CompactPeriodically(agent.ctx, agent.dataClient, agent.cfg.DataCompactionInterval)
+	}
+
+	// Write diagnostics bundles to the data directory on SIGUSR2
+	if dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine); ok {
+		sighandlers.StartDiagnosticsBundleHandler(agent.ctx, diagnostics.NewBundler(dockerTaskEngine, logger.LogFile()),
+			filepath.Join(agent.cfg.DataDir, diagnosticsBundleDir))
+	}
+
+	// Reload part of the configuration on SIGHUP and when the config file changes
+	if agent.cfg.ReloadableConfigFile != "" {
+		newConfigReloader(agent.cfg, taskEngine).start(agent.ctx)
+	}
+
 	// Agent introspection api
-	go handlers.ServeIntrospectionHTTPEndpoint(agent.ctx, &agent.containerInstanceARN, taskEngine, agent.cfg)
+	go handlers.ServeIntrospectionHTTPEndpoint(agent.ctx, &agent.containerInstanceARN, taskEngine, taskHandler, agent.cfg)
 
 	statsEngine := stats.NewDockerStatsEngine(agent.cfg, agent.dockerClient, containerChangeEventStream)
 
 	// Start serving the endpoint to fetch IAM Role credentials and other task metadata
 	if agent.cfg.TaskMetadataAZDisabled {
 		// send empty availability zone
-		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, state, client, agent.containerInstanceARN, agent.cfg, statsEngine, "")
+		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, state, client, agent.containerInstanceARN, agent.cfg, statsEngine, "", agent.taskMetadataPipeServer)
 	} else {
-		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, state, client, agent.containerInstanceARN, agent.cfg, statsEngine, agent.availabilityZone)
+		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, state, client, agent.containerInstanceARN, agent.cfg, statsEngine, agent.availabilityZone, agent.taskMetadataPipeServer)
 	}
 
 	// Start sending events to the backend
@@ -678,53 +794,6 @@ func (agent *ecsAgent) startAsyncRoutines(
 	go tcshandler.StartMetricsSession(&telemetrySessionParams)
 }
 
-func (agent *ecsAgent) startSpotInstanceDrainingPoller(ctx context.Context, client api.ECSClient) {
-	for !agent.spotInstanceDrainingPoller(client) {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			time.Sleep(time.Second)
-		}
-	}
-}
-
-// spotInstanceDrainingPoller returns true if spot instance interruption has been
-// set AND the container instance state is successfully updated to DRAINING.
-func (agent *ecsAgent) spotInstanceDrainingPoller(client api.ECSClient) bool {
-	// this endpoint 404s unless a interruption has been set, so expect failure in most cases.
-	resp, err := agent.ec2MetadataClient.SpotInstanceAction()
-	if err == nil {
-		type InstanceAction struct {
-			Time   string
-			Action string
-		}
-		ia := InstanceAction{}
-
-		err := json.Unmarshal([]byte(resp), &ia)
-		if err != nil {
-			seelog.Errorf("Invalid response from /spot/instance-action endpoint: %s Error: %s", resp, err)
-			return false
-		}
-
-		switch ia.Action {
-		case "hibernate", "terminate", "stop":
-		default:
-			seelog.Errorf("Invalid response from /spot/instance-action endpoint: %s, Error: unrecognized action (%s)", resp, ia.Action)
-			return false
-		}
-
-		seelog.Infof("Received a spot interruption (%s) scheduled for %s, setting state to DRAINING", ia.Action, ia.Time)
-		err = client.UpdateContainerInstancesState(agent.containerInstanceARN, "DRAINING")
-		if err != nil {
-			seelog.Errorf("Error setting instance [ARN: %s] state to DRAINING: %s", agent.containerInstanceARN, err)
-		} else {
-			return true
-		}
-	}
-	return false
-}
-
 // startACSSession starts a session with ECS's Agent Communication service. This
 // is a blocking call and only returns when the handler returns
 func (agent *ecsAgent) startACSSession(
@@ -735,8 +804,12 @@ func (agent *ecsAgent) startACSSession(
 	state dockerstate.TaskEngineState,
 	taskHandler *eventhandler.TaskHandler) int {
 
+	// the session is stopped when the instance resumes from a hibernation as a
+	// different EC2 instance
+	ctx, cancel := context.WithCancel(agent.ctx)
+	defer cancel()
 	acsSession := acshandler.NewSession(
-		agent.ctx,
+		ctx,
 		agent.cfg,
 		deregisterInstanceEventStream,
 		agent.containerInstanceARN,
@@ -749,12 +822,21 @@ func (agent *ecsAgent) startACSSession(
 		taskHandler,
 		agent.latestSeqNumberTaskManifest,
 	)
+	go agent.watchForResume(ctx, func(suspended time.Duration) {
+		if !agent.handleResume(taskEngine, acsSession, suspended) {
+			cancel()
+		}
+	})
 	seelog.Info("Beginning Polling for updates")
 	err := acsSession.Start()
 	if err != nil {
 		seelog.Criticalf("Unretriable error starting communicating with ACS: %v", err)
 		return exitcodes.ExitTerminal
 	}
+	if ctx.Err() != nil && agent.ctx.Err() == nil {
+		seelog.Critical("Instance changed while it was suspended, exiting")
+		return exitcodes.ExitTerminal
+	}
 	return exitcodes.ExitSuccess
 }
 
@@ -14,14 +14,18 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/cihub/seelog"
@@ -36,6 +40,8 @@ const (
 	capabilityTaskIAMRoleNetHost                = "task-iam-role-network-host"
 	taskENIAttributeSuffix                      = "task-eni"
 	taskENIIPv6AttributeSuffix                  = "task-eni.ipv6"
+	taskENIEFAAttributeSuffix                   = "task-eni.efa"
+	taskENIDNSCacheAttributeSuffix              = "task-eni.dns-cache"
 	taskENIBlockInstanceMetadataAttributeSuffix = "task-eni-block-instance-metadata"
 	appMeshAttributeSuffix                      = "aws-appmesh"
 	cniPluginVersionSuffix                      = "cni-plugin-version"
@@ -57,6 +63,7 @@ const (
 	branchCNIPluginVersionSuffix                = "branch-cni-plugin-version"
 	capabilityFirelensFluentd                   = "firelens.fluentd"
 	capabilityFirelensFluentbit                 = "firelens.fluentbit"
+	capabilityFirelensOtelcol                   = "firelens.otelcol"
 	capabilityFirelensLoggingDriver             = "logging-driver.awsfirelens"
 	capabilityFirelensConfigFile                = "firelens.options.config.file"
 	capabilityFirelensConfigS3                  = "firelens.options.config.s3"
@@ -72,6 +79,11 @@ const (
 	capabilityExecConfigRelativePath            = "config"
 	capabilityExecCertsRelativePath             = "certs"
 	capabilityExternal                          = "external"
+	capabilityNUMAPlacement                     = "numa-placement"
+	capabilityNeuronCores                       = "neuron-cores"
+	capabilitySecurityProfiles                  = "security-profiles"
+	capabilityContainerRuntimeInfix             = "runtime."
+	capabilitySecretsVault                      = "secrets.vault"
 )
 
 var (
@@ -115,6 +127,8 @@ var (
 		attributePrefix + taskENIAttributeSuffix,
 		attributePrefix + cniPluginVersionSuffix,
 		attributePrefix + taskENIIPv6AttributeSuffix,
+		attributePrefix + taskENIEFAAttributeSuffix,
+		attributePrefix + taskENIDNSCacheAttributeSuffix,
 		attributePrefix + taskENIBlockInstanceMetadataAttributeSuffix,
 		attributePrefix + taskENITrunkingAttributeSuffix,
 		attributePrefix + appMeshAttributeSuffix,
@@ -149,6 +163,9 @@ var (
 //    com.amazonaws.ecs.capability.task-iam-role-network-host
 //    ecs.capability.docker-volume-driver.${driverName}
 //    ecs.capability.task-eni
+//    ecs.capability.task-eni.efa
+//    ecs.capability.task-eni.dns-cache
+//    ecs.capability.security-profiles
 //    ecs.capability.task-eni-block-instance-metadata
 //    ecs.capability.execution-role-ecr-pull
 //    ecs.capability.execution-role-awslogs
@@ -166,6 +183,7 @@ var (
 //    ecs.capability.task-eia.optimized-cpu
 //    ecs.capability.firelens.fluentd
 //    ecs.capability.firelens.fluentbit
+//    ecs.capability.firelens.otelcol
 //    ecs.capability.efs
 //    com.amazonaws.ecs.capability.logging-driver.awsfirelens
 //    ecs.capability.firelens.options.config.file
@@ -177,6 +195,10 @@ var (
 //    ecs.capability.fsxWindowsFileServer
 //    ecs.capability.execute-command
 //    ecs.capability.external
+//    ecs.capability.numa-placement
+//    ecs.capability.neuron-cores
+//    ecs.capability.runtime.${runtimeName}
+//    ecs.capability.secrets.vault
 func (agent *ecsAgent) capabilities() ([]*ecs.Attribute, error) {
 	var capabilities []*ecs.Attribute
 
@@ -228,6 +250,10 @@ func (agent *ecsAgent) capabilities() ([]*ecs.Attribute, error) {
 		capabilities = agent.appendNvidiaDriverVersionAttribute(capabilities)
 	}
 
+	if agent.cfg.InferentiaSupportEnabled {
+		capabilities = agent.appendNeuronCoresCapability(capabilities)
+	}
+
 	// ecs agent version 1.22.0 supports sharing PID namespaces and IPC resource namespaces
 	// with host EC2 instance and among containers within the task
 	capabilities = agent.appendPIDAndIPCNamespaceSharingCapabilities(capabilities)
@@ -244,6 +270,9 @@ func (agent *ecsAgent) capabilities() ([]*ecs.Attribute, error) {
 	// support aws router capabilities for fluentbit
 	capabilities = agent.appendFirelensFluentbitCapabilities(capabilities)
 
+	// support aws router capabilities for the OpenTelemetry Collector
+	capabilities = agent.appendFirelensOtelcolCapabilities(capabilities)
+
 	// support aws router capabilities for log driver router
 	capabilities = agent.appendFirelensLoggingDriverCapabilities(capabilities)
 
@@ -263,12 +292,29 @@ func (agent *ecsAgent) capabilities() ([]*ecs.Attribute, error) {
 
 	// support fsxWindowsFileServer on ecs capabilities
 	capabilities = agent.appendFSxWindowsFileServerCapabilities(capabilities)
+
+	// support seccomp and apparmor profiles referenced by name
+	capabilities = agent.appendSecurityProfilesCapability(capabilities)
+
+	// support the sandboxed runtimes installed in docker
+	capabilities = agent.appendContainerRuntimeCapabilities(capabilities)
+
+	// support vault secrets when the agent has a vault server to read them from
+	if agent.cfg.VaultAddr != "" {
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilitySecretsVault)
+	}
+
 	// add ecs-exec capabilities if applicable
 	capabilities, err = agent.appendExecCapabilities(capabilities)
 	if err != nil {
 		return nil, err
 	}
 
+	// register the attributes discovered on the instance
+	if agent.attributeDiscoverer != nil {
+		capabilities = append(capabilities, agent.attributeDiscoverer.Attributes()...)
+	}
+
 	if agent.cfg.External.Enabled() {
 		// Add external specific capability; remove external unsupported capabilities.
 		for _, cap := range externalSpecificCapabilities {
@@ -338,6 +384,10 @@ func (agent *ecsAgent) appendTaskCPUMemLimitCapabilities(capabilities []*ecs.Att
 	if agent.cfg.TaskCPUMemLimit.Enabled() {
 		if _, ok := supportedVersions[dockerclient.Version_1_22]; ok {
 			capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilityTaskCPUMemLimit)
+			// tasks are pinned to their NUMA node through the task cgroup
+			if agent.cfg.NUMAPlacementEnabled.Enabled() {
+				capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilityNUMAPlacement)
+			}
 		} else if agent.cfg.TaskCPUMemLimit.Value == config.ExplicitlyEnabled {
 			// explicitly enabled -- return an error because we cannot fulfil an explicit request
 			return nil, errors.New("engine: Task CPU + Mem limit cannot be enabled due to unsupported Docker version")
@@ -359,6 +409,8 @@ func (agent *ecsAgent) appendTaskENICapabilities(capabilities []*ecs.Attribute)
 			Name: aws.String(attributePrefix + taskENIAttributeSuffix),
 		})
 		capabilities = agent.appendIPv6Capability(capabilities)
+		capabilities = agent.appendEFACapability(capabilities)
+		capabilities = agent.appendDNSCacheCapability(capabilities)
 		taskENIVersionAttribute, err := agent.getTaskENIPluginVersionAttribute()
 		if err != nil {
 			return capabilities
@@ -518,3 +570,51 @@ func removeAttributesByNames(attributes []*ecs.Attribute, names []string) []*ecs
 	}
 	return ret
 }
+
+// appendSecurityProfilesCapability advertises support for named security profiles
+// when the agent has somewhere to look them up, either the profiles directory or S3
+func (agent *ecsAgent) appendSecurityProfilesCapability(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	if agent.cfg.SecurityProfilesS3ARN == "" {
+		if agent.cfg.SecurityProfilesDir == "" {
+			return capabilities
+		}
+		if exists, err := pathExists(agent.cfg.SecurityProfilesDir, true); err != nil || !exists {
+			return capabilities
+		}
+	}
+	return appendNameOnlyAttribute(capabilities, attributePrefix+capabilitySecurityProfiles)
+}
+
+// appendContainerRuntimeCapabilities advertises the sandboxed runtimes that tasks can request
+// and that docker has, so that the tasks requesting them are only placed where they can run
+func (agent *ecsAgent) appendContainerRuntimeCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	if agent.containerRuntimes == nil {
+		return capabilities
+	}
+	runtimes, err := agent.containerRuntimes()
+	if err != nil {
+		seelog.Warnf("Unable to list the runtimes of docker, sandboxed runtimes won't be advertised: %v", err)
+		return capabilities
+	}
+	for _, runtime := range runtimes {
+		if apitask.IsSupportedContainerRuntime(runtime) {
+			capabilities = appendNameOnlyAttribute(capabilities,
+				attributePrefix+capabilityContainerRuntimeInfix+runtime)
+		}
+	}
+	return capabilities
+}
+
+// dockerContainerRuntimes returns the names of the OCI runtimes docker has, in order
+func dockerContainerRuntimes(ctx context.Context, client dockerapi.DockerClient) ([]string, error) {
+	info, err := client.Info(ctx, dockerclient.InfoTimeout)
+	if err != nil {
+		return nil, err
+	}
+	var runtimes []string
+	for runtime := range info.Runtimes {
+		runtimes = append(runtimes, runtime)
+	}
+	sort.Strings(runtimes)
+	return runtimes, nil
+}
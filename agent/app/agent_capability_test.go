@@ -18,6 +18,7 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -35,6 +36,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	aws_credentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/docker/docker/api/types"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -642,6 +644,47 @@ func TestCapabilitiesTaskResourceLimit(t *testing.T) {
 	assert.True(t, agent.cfg.TaskCPUMemLimit.Enabled(), "TaskCPUMemLimit should remain true")
 }
 
+func TestCapabilitiesNUMAPlacement(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	conf := &config.Config{
+		TaskCPUMemLimit:      config.BooleanDefaultTrue{Value: config.ExplicitlyEnabled},
+		NUMAPlacementEnabled: config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+	}
+
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	versionList := []dockerclient.DockerVersion{dockerclient.Version_1_22}
+	mockMobyPlugins := mock_mobypkgwrapper.NewMockPlugins(ctrl)
+	mockPauseLoader := mock_pause.NewMockLoader(ctrl)
+	mockPauseLoader.EXPECT().IsLoaded(gomock.Any()).Return(false, nil).AnyTimes()
+	gomock.InOrder(
+		client.EXPECT().SupportedVersions().Return(versionList),
+		client.EXPECT().KnownVersions().Return(versionList),
+		mockMobyPlugins.EXPECT().Scan().AnyTimes().Return([]string{}, nil),
+		client.EXPECT().ListPluginsWithFilters(gomock.Any(), gomock.Any(), gomock.Any(),
+			gomock.Any()).AnyTimes().Return([]string{}, nil),
+	)
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:          ctx,
+		cfg:          conf,
+		dockerClient: client,
+		pauseLoader:  mockPauseLoader,
+		mobyPlugins:  mockMobyPlugins,
+	}
+
+	capabilities, err := agent.capabilities()
+	assert.NoError(t, err)
+
+	capMap := make(map[string]bool)
+	for _, capability := range capabilities {
+		capMap[aws.StringValue(capability.Name)] = true
+	}
+	assert.True(t, capMap[attributePrefix+capabilityNUMAPlacement])
+}
+
 func TestCapabilitesTaskResourceLimitDisabledByMissingDockerVersion(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -1107,3 +1150,127 @@ func TestAppendAndRemoveAttributes(t *testing.T) {
 		Name: aws.String("cap-2"),
 	})
 }
+
+func TestAppendSecurityProfilesCapability(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cfg       *config.Config
+		dirExists bool
+		expected  bool
+	}{
+		{
+			name:     "not configured",
+			cfg:      &config.Config{},
+			expected: false,
+		},
+		{
+			name:      "directory missing",
+			cfg:       &config.Config{SecurityProfilesDir: "/etc/ecs/security-profiles"},
+			dirExists: false,
+			expected:  false,
+		},
+		{
+			name:      "directory exists",
+			cfg:       &config.Config{SecurityProfilesDir: "/etc/ecs/security-profiles"},
+			dirExists: true,
+			expected:  true,
+		},
+		{
+			name:     "s3 configured",
+			cfg:      &config.Config{SecurityProfilesS3ARN: "arn:aws:s3:::bucket/profiles"},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPathExists(tc.dirExists)
+			defer mockPathExists(false)
+			agent := &ecsAgent{cfg: tc.cfg}
+			capabilities := agent.appendSecurityProfilesCapability([]*ecs.Attribute{})
+			attr := &ecs.Attribute{Name: aws.String(attributePrefix + capabilitySecurityProfiles)}
+			if tc.expected {
+				assert.Contains(t, capabilities, attr)
+			} else {
+				assert.NotContains(t, capabilities, attr)
+			}
+		})
+	}
+}
+
+func TestAppendContainerRuntimeCapabilities(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	client.EXPECT().Info(gomock.Any(), gomock.Any()).Return(types.Info{
+		Runtimes: map[string]types.Runtime{
+			"runc":         {},
+			"nvidia":       {},
+			"runsc":        {},
+			"kata-runtime": {},
+		},
+	}, nil)
+
+	agent := &ecsAgent{
+		containerRuntimes: func() ([]string, error) {
+			return dockerContainerRuntimes(context.TODO(), client)
+		},
+	}
+	assert.Equal(t, []*ecs.Attribute{
+		{Name: aws.String(attributePrefix + "runtime.kata-runtime")},
+		{Name: aws.String(attributePrefix + "runtime.runsc")},
+	}, agent.appendContainerRuntimeCapabilities(nil), "only the sandboxed runtimes should be advertised")
+}
+
+func TestAppendContainerRuntimeCapabilitiesError(t *testing.T) {
+	agent := &ecsAgent{
+		containerRuntimes: func() ([]string, error) {
+			return nil, errors.New("docker is unavailable")
+		},
+	}
+	assert.Empty(t, agent.appendContainerRuntimeCapabilities(nil))
+}
+
+func TestCapabilitiesVaultSecrets(t *testing.T) {
+	for _, vaultAddr := range []string{"", "https://vault.example.com:8200"} {
+		t.Run(fmt.Sprintf("vault address %q", vaultAddr), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := mock_dockerapi.NewMockDockerClient(ctrl)
+			mockMobyPlugins := mock_mobypkgwrapper.NewMockPlugins(ctrl)
+
+			client.EXPECT().SupportedVersions().Return([]dockerclient.DockerVersion{
+				dockerclient.Version_1_24,
+			})
+			client.EXPECT().KnownVersions().Return(nil)
+			mockMobyPlugins.EXPECT().Scan().AnyTimes().Return([]string{}, nil)
+			client.EXPECT().ListPluginsWithFilters(gomock.Any(), gomock.Any(), gomock.Any(),
+				gomock.Any()).AnyTimes().Return([]string{}, nil)
+
+			mockPauseLoader := mock_pause.NewMockLoader(ctrl)
+			mockPauseLoader.EXPECT().IsLoaded(gomock.Any()).Return(false, nil).AnyTimes()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			// Cancel the context to cancel async routines
+			defer cancel()
+			agent := &ecsAgent{
+				ctx:          ctx,
+				cfg:          &config.Config{VaultAddr: vaultAddr},
+				dockerClient: client,
+				pauseLoader:  mockPauseLoader,
+				mobyPlugins:  mockMobyPlugins,
+			}
+
+			capabilities, err := agent.capabilities()
+			require.NoError(t, err)
+
+			attr := &ecs.Attribute{Name: aws.String(attributePrefix + capabilitySecretsVault)}
+			if vaultAddr != "" {
+				assert.Contains(t, capabilities, attr)
+			} else {
+				assert.NotContains(t, capabilities, attr)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
+	"github.com/aws/amazon-ecs-agent/agent/efa"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/aws-sdk-go/aws"
@@ -37,6 +38,9 @@ const (
 	CpuInfoPath = "/proc/cpuinfo"
 )
 
+// efaSupported returns whether the instance can pass EFAs to containers, it's swappable for testing
+var efaSupported = efa.Supported
+
 func (agent *ecsAgent) appendVolumeDriverCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
 	// "local" is default docker driver
 	capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilityDockerPluginInfix+volume.DockerLocalVolumeDriver)
@@ -87,6 +91,14 @@ func (agent *ecsAgent) appendNvidiaDriverVersionAttribute(capabilities []*ecs.At
 	return capabilities
 }
 
+func (agent *ecsAgent) appendNeuronCoresCapability(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	if agent.resourceFields != nil && agent.resourceFields.NeuronManager != nil &&
+		len(agent.resourceFields.NeuronManager.GetDevices()) > 0 {
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilityNeuronCores)
+	}
+	return capabilities
+}
+
 func (agent *ecsAgent) appendENITrunkingCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
 	if !agent.cfg.ENITrunkingEnabled.Enabled() {
 		return capabilities
@@ -157,6 +169,10 @@ func (agent *ecsAgent) appendFirelensFluentbitCapabilities(capabilities []*ecs.A
 	return appendNameOnlyAttribute(capabilities, attributePrefix+capabilityFirelensFluentbit)
 }
 
+func (agent *ecsAgent) appendFirelensOtelcolCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	return appendNameOnlyAttribute(capabilities, attributePrefix+capabilityFirelensOtelcol)
+}
+
 func (agent *ecsAgent) appendEFSCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
 	return appendNameOnlyAttribute(capabilities, attributePrefix+capabilityEFS)
 }
@@ -182,6 +198,17 @@ func (agent *ecsAgent) appendIPv6Capability(capabilities []*ecs.Attribute) []*ec
 	return appendNameOnlyAttribute(capabilities, attributePrefix+taskENIIPv6AttributeSuffix)
 }
 
+func (agent *ecsAgent) appendEFACapability(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	if !efaSupported() {
+		return capabilities
+	}
+	return appendNameOnlyAttribute(capabilities, attributePrefix+taskENIEFAAttributeSuffix)
+}
+
+func (agent *ecsAgent) appendDNSCacheCapability(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	return appendNameOnlyAttribute(capabilities, attributePrefix+taskENIDNSCacheAttributeSuffix)
+}
+
 func (agent *ecsAgent) appendFSxWindowsFileServerCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
 	return capabilities
 }
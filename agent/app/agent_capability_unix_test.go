@@ -18,6 +18,7 @@ package app
 import (
 	"context"
 	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -30,8 +31,11 @@ import (
 	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
+	"github.com/aws/amazon-ecs-agent/agent/efa"
 	mock_ecscni "github.com/aws/amazon-ecs-agent/agent/ecscni/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/gpu"
+	"github.com/aws/amazon-ecs-agent/agent/instanceattributes"
+	mock_neuron "github.com/aws/amazon-ecs-agent/agent/neuron/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	mock_mobypkgwrapper "github.com/aws/amazon-ecs-agent/agent/utils/mobypkgwrapper/mocks"
@@ -277,9 +281,60 @@ func TestEmptyNvidiaDriverCapabilitiesUnix(t *testing.T) {
 	}
 }
 
+func TestNeuronCoresCapabilityUnix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockMobyPlugins := mock_mobypkgwrapper.NewMockPlugins(ctrl)
+	mockCredentialsProvider := app_mocks.NewMockProvider(ctrl)
+	mockPauseLoader := mock_pause.NewMockLoader(ctrl)
+	mockNeuronManager := mock_neuron.NewMockManager(ctrl)
+	conf := &config.Config{
+		PrivilegedDisabled:       config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+		InferentiaSupportEnabled: true,
+	}
+
+	mockPauseLoader.EXPECT().IsLoaded(gomock.Any()).Return(true, nil)
+	gomock.InOrder(
+		client.EXPECT().SupportedVersions().Return([]dockerclient.DockerVersion{
+			dockerclient.Version_1_17,
+		}),
+		client.EXPECT().KnownVersions().Return([]dockerclient.DockerVersion{
+			dockerclient.Version_1_17,
+		}),
+		mockMobyPlugins.EXPECT().Scan().AnyTimes().Return([]string{}, nil),
+		client.EXPECT().ListPluginsWithFilters(gomock.Any(), gomock.Any(), gomock.Any(),
+			gomock.Any()).AnyTimes().Return([]string{}, nil),
+	)
+	mockNeuronManager.EXPECT().GetDevices().Return([]*ecs.PlatformDevice{
+		{Id: aws.String("neuron-core-0"), Type: aws.String(ecs.PlatformDeviceTypeNeuronCore)},
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:                ctx,
+		cfg:                conf,
+		dockerClient:       client,
+		pauseLoader:        mockPauseLoader,
+		credentialProvider: aws_credentials.NewCredentials(mockCredentialsProvider),
+		mobyPlugins:        mockMobyPlugins,
+		resourceFields: &taskresource.ResourceFields{
+			NeuronManager: mockNeuronManager,
+		},
+	}
+	capabilities, err := agent.capabilities()
+	assert.NoError(t, err)
+	assert.Contains(t, capabilities, &ecs.Attribute{Name: aws.String(attributePrefix + capabilityNeuronCores)})
+}
+
 func TestENITrunkingCapabilitiesUnix(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
+	efaSupported = func() bool { return true }
+	defer func() { efaSupported = efa.Supported }()
 
 	client := mock_dockerapi.NewMockDockerClient(ctrl)
 	cniClient := mock_ecscni.NewMockCNIClient(ctrl)
@@ -312,6 +367,8 @@ func TestENITrunkingCapabilitiesUnix(t *testing.T) {
 		attributePrefix + "docker-plugin.local",
 		attributePrefix + taskENIAttributeSuffix,
 		attributePrefix + taskENIIPv6AttributeSuffix,
+		attributePrefix + taskENIEFAAttributeSuffix,
+		attributePrefix + taskENIDNSCacheAttributeSuffix,
 		attributePrefix + taskENITrunkingAttributeSuffix,
 		attributePrefix + taskENITrunkingAttributeSuffix,
 		attributePrefix + capabilityPrivateRegistryAuthASM,
@@ -364,6 +421,8 @@ func TestENITrunkingCapabilitiesUnix(t *testing.T) {
 func TestNoENITrunkingCapabilitiesUnix(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
+	efaSupported = func() bool { return true }
+	defer func() { efaSupported = efa.Supported }()
 
 	client := mock_dockerapi.NewMockDockerClient(ctrl)
 	cniClient := mock_ecscni.NewMockCNIClient(ctrl)
@@ -395,6 +454,8 @@ func TestNoENITrunkingCapabilitiesUnix(t *testing.T) {
 		attributePrefix + "docker-plugin.local",
 		attributePrefix + taskENIAttributeSuffix,
 		attributePrefix + taskENIIPv6AttributeSuffix,
+		attributePrefix + taskENIEFAAttributeSuffix,
+		attributePrefix + taskENIDNSCacheAttributeSuffix,
 		attributePrefix + capabilityPrivateRegistryAuthASM,
 		attributePrefix + capabilitySecretEnvSSM,
 		attributePrefix + capabilitySecretLogDriverSSM,
@@ -776,6 +837,7 @@ func TestCapabilitiesUnix(t *testing.T) {
 		attributePrefix + taskEIAAttributeSuffix,
 		attributePrefix + capabilityFirelensFluentd,
 		attributePrefix + capabilityFirelensFluentbit,
+		attributePrefix + capabilityFirelensOtelcol,
 		attributePrefix + capabilityEFS,
 		attributePrefix + capabilityEFSAuth,
 		capabilityPrefix + capabilityFirelensLoggingDriver,
@@ -870,3 +932,50 @@ func TestAppendFSxWindowsFileServerCapabilities(t *testing.T) {
 	assert.Equal(t, len(inputCapabilities), len(capabilities))
 	assert.EqualValues(t, capabilities, inputCapabilities)
 }
+
+func TestCapabilitiesDiscoveredAttributes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pluginsDir, err := ioutil.TempDir("", "attributes")
+	assert.NoError(t, err)
+	defer os.RemoveAll(pluginsDir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pluginsDir, "storage"),
+		[]byte("#!/bin/sh\necho host.local-storage=nvme\n"), 0755))
+
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockMobyPlugins := mock_mobypkgwrapper.NewMockPlugins(ctrl)
+	mockPauseLoader := mock_pause.NewMockLoader(ctrl)
+	client.EXPECT().SupportedVersions().Return(nil)
+	client.EXPECT().KnownVersions().Return(nil)
+	mockMobyPlugins.EXPECT().Scan().AnyTimes().Return([]string{}, nil)
+	client.EXPECT().ListPluginsWithFilters(gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Any()).AnyTimes().Return([]string{}, nil)
+	mockPauseLoader.EXPECT().IsLoaded(gomock.Any()).Return(false, nil).AnyTimes()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:                 ctx,
+		cfg:                 &config.Config{},
+		dockerClient:        client,
+		pauseLoader:         mockPauseLoader,
+		mobyPlugins:         mockMobyPlugins,
+		attributeDiscoverer: instanceattributes.NewDiscoverer(nil, pluginsDir),
+	}
+	capabilities, err := agent.capabilities()
+	assert.NoError(t, err)
+	assert.Contains(t, capabilities, &ecs.Attribute{
+		Name:  aws.String("host.local-storage"),
+		Value: aws.String("nvme"),
+	})
+}
+
+func TestEFACapabilityUnsupported(t *testing.T) {
+	efaSupported = func() bool { return false }
+	defer func() { efaSupported = efa.Supported }()
+
+	agent := &ecsAgent{}
+	assert.Empty(t, agent.appendEFACapability(nil))
+}
@@ -27,12 +27,15 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/eni/watcher"
 	"github.com/aws/amazon-ecs-agent/agent/gpu"
+	"github.com/aws/amazon-ecs-agent/agent/neuron"
 	ssmfactory "github.com/aws/amazon-ecs-agent/agent/ssm/factory"
 
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	cgroup "github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretcache"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
+	vaultfactory "github.com/aws/amazon-ecs-agent/agent/vault/factory"
 	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
 )
@@ -158,10 +161,13 @@ func (agent *ecsAgent) initializeResourceFields(credentialsManager credentials.M
 			SSMClientCreator:   ssmfactory.NewSSMClientCreator(),
 			CredentialsManager: credentialsManager,
 			EC2InstanceID:      agent.getEC2InstanceID(),
+			SecretCache:        secretcache.New(agent.cfg.SecretsCacheTTL, agent.cfg.SecretsFetchJitter),
+			VaultClientCreator: vaultfactory.NewClientCreator(agent.vaultConfig()),
 		},
 		Ctx:              agent.ctx,
 		DockerClient:     agent.dockerClient,
 		NvidiaGPUManager: gpu.NewNvidiaGPUManager(),
+		NeuronManager:    neuron.NewManager(),
 	}
 }
 
@@ -187,13 +193,26 @@ func (agent *ecsAgent) initializeGPUManager() error {
 	return nil
 }
 
+func (agent *ecsAgent) initializeNeuronManager() error {
+	if agent.resourceFields != nil && agent.resourceFields.NeuronManager != nil {
+		return agent.resourceFields.NeuronManager.Initialize()
+	}
+	return nil
+}
+
 func (agent *ecsAgent) getPlatformDevices() []*ecs.PlatformDevice {
+	var devices []*ecs.PlatformDevice
 	if agent.cfg.GPUSupportEnabled {
 		if agent.resourceFields != nil && agent.resourceFields.NvidiaGPUManager != nil {
-			return agent.resourceFields.NvidiaGPUManager.GetDevices()
+			devices = append(devices, agent.resourceFields.NvidiaGPUManager.GetDevices()...)
 		}
 	}
-	return nil
+	if agent.cfg.InferentiaSupportEnabled {
+		if agent.resourceFields != nil && agent.resourceFields.NeuronManager != nil {
+			devices = append(devices, agent.resourceFields.NeuronManager.GetDevices()...)
+		}
+	}
+	return devices
 }
 
 func (agent *ecsAgent) loadPauseContainer() error {
@@ -46,6 +46,10 @@ func (agent *ecsAgent) initializeGPUManager() error {
 	return nil
 }
 
+func (agent *ecsAgent) initializeNeuronManager() error {
+	return nil
+}
+
 func (agent *ecsAgent) getPlatformDevices() []*ecs.PlatformDevice {
 	return nil
 }
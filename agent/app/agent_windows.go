@@ -37,6 +37,8 @@ import (
 	ssmfactory "github.com/aws/amazon-ecs-agent/agent/ssm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretcache"
+	vaultfactory "github.com/aws/amazon-ecs-agent/agent/vault/factory"
 
 	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
@@ -307,6 +309,8 @@ func (agent *ecsAgent) initializeResourceFields(credentialsManager credentials.M
 			SSMClientCreator:   ssmfactory.NewSSMClientCreator(),
 			FSxClientCreator:   fsxfactory.NewFSxClientCreator(),
 			CredentialsManager: credentialsManager,
+			SecretCache:        secretcache.New(agent.cfg.SecretsCacheTTL, agent.cfg.SecretsFetchJitter),
+			VaultClientCreator: vaultfactory.NewClientCreator(agent.vaultConfig()),
 		},
 		Ctx:             agent.ctx,
 		DockerClient:    agent.dockerClient,
@@ -323,6 +327,10 @@ func (agent *ecsAgent) initializeGPUManager() error {
 	return nil
 }
 
+func (agent *ecsAgent) initializeNeuronManager() error {
+	return nil
+}
+
 func (agent *ecsAgent) getPlatformDevices() []*ecs.PlatformDevice {
 	return nil
 }
@@ -0,0 +1,150 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers"
+	"github.com/cihub/seelog"
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloader reads the reloadable part of the agent configuration again from the
+// config file when the agent receives SIGHUP or the file changes, and applies it
+type configReloader struct {
+	cfg        *config.Config
+	taskEngine engine.TaskEngine
+	// current is the configuration last applied
+	current config.ReloadableConfig
+	// requests receives the requests to reload the configuration
+	requests chan struct{}
+}
+
+func newConfigReloader(cfg *config.Config, taskEngine engine.TaskEngine) *configReloader {
+	current := cfg.Reloadable()
+	current.LogLevel = os.Getenv(logger.LOGLEVEL_ENV_VAR)
+	current.InstanceLogLevel = os.Getenv(logger.LOGLEVEL_ON_INSTANCE_ENV_VAR)
+	return &configReloader{
+		cfg:        cfg,
+		taskEngine: taskEngine,
+		current:    current,
+		requests:   make(chan struct{}, 1),
+	}
+}
+
+// start reloads the configuration on SIGHUP and when the config file changes, until the
+// context is done
+func (reloader *configReloader) start(ctx context.Context) {
+	sighandlers.StartConfigReloadHandler(ctx, reloader.request)
+	if err := reloader.watchFile(ctx); err != nil {
+		seelog.Warnf("Unable to watch the config file %s, the configuration is only reloaded on SIGHUP: %v",
+			reloader.cfg.ReloadableConfigFile, err)
+	}
+	go func() {
+		for {
+			select {
+			case <-reloader.requests:
+				reloader.reload()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// request requests a reload of the configuration, unless one is already pending
+func (reloader *configReloader) request() {
+	select {
+	case reloader.requests <- struct{}{}:
+	default:
+	}
+}
+
+// watchFile requests a reload when the config file changes. The directory of the file is
+// watched rather than the file, so that the file is still watched after it's replaced
+func (reloader *configReloader) watchFile(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Clean(reloader.cfg.ReloadableConfigFile)
+	if err := watcher.Add(filepath.Dir(fileName)); err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != fileName || event.Op == fsnotify.Chmod || event.Op == fsnotify.Remove {
+					continue
+				}
+				seelog.Debugf("Config file watcher: %s", event)
+				reloader.request()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				seelog.Warnf("Unable to watch the config file %s, the configuration is only reloaded on SIGHUP: %v",
+					fileName, err)
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// reload reads the reloadable configuration from the config file, and applies it if it
+// changed. Nothing is applied if the configuration is invalid
+func (reloader *configReloader) reload() {
+	fileName := reloader.cfg.ReloadableConfigFile
+	reloaded, err := reloader.cfg.ReadReloadableConfig(fileName, reloader.current)
+	if err != nil {
+		seelog.Errorf("Unable to reload the agent configuration from %s: %v", fileName, err)
+		return
+	}
+	if reloaded == reloader.current {
+		seelog.Debugf("The agent configuration in %s didn't change", fileName)
+		return
+	}
+
+	if reloaded.LogLevel != reloader.current.LogLevel || reloaded.InstanceLogLevel != reloader.current.InstanceLogLevel {
+		if err := logger.ReloadLevels(reloaded.LogLevel, reloaded.InstanceLogLevel); err != nil {
+			seelog.Errorf("Unable to reload the agent configuration from %s: %v", fileName, err)
+			return
+		}
+	}
+	if reloaded.ReservedMemory != reloader.current.ReservedMemory {
+		// the memory is only read when the container instance registers, when the agent
+		// starts. The configuration shared with the other goroutines is never modified, the
+		// reloaded values are only kept by the task engine
+		seelog.Warnf("Reserved memory changed to %d MiB, the memory registered for the container instance "+
+			"changes when the agent restarts", reloaded.ReservedMemory)
+	}
+	reloader.taskEngine.ReloadConfig(reloaded)
+	reloader.current = reloaded
+	seelog.Infof("Reloaded the agent configuration from %s", fileName)
+}
@@ -0,0 +1,88 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigReloaderReload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dir, err := ioutil.TempDir("", "reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-west-2"
+	cfg.ReloadableConfigFile = filepath.Join(dir, "ecs.config")
+
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	reloader := newConfigReloader(&cfg, taskEngine)
+
+	require.NoError(t, ioutil.WriteFile(cfg.ReloadableConfigFile,
+		[]byte("ECS_RESERVED_MEMORY=128\nECS_IMAGE_CLEANUP_INTERVAL=1h\n"), 0644))
+	expected := reloader.current
+	expected.ReservedMemory = 128
+	expected.ImageCleanupInterval = time.Hour
+	taskEngine.EXPECT().ReloadConfig(expected)
+	reloader.reload()
+	// the shared configuration isn't modified
+	assert.Zero(t, cfg.ReservedMemory)
+	assert.Equal(t, expected, reloader.current)
+
+	// the configuration isn't applied again when it didn't change
+	reloader.reload()
+
+	// nor when it's invalid
+	require.NoError(t, ioutil.WriteFile(cfg.ReloadableConfigFile,
+		[]byte("ECS_RESERVED_MEMORY=64\nECS_IMAGE_CLEANUP_INTERVAL=soon\n"), 0644))
+	reloader.reload()
+	assert.Equal(t, expected, reloader.current)
+}
+
+func TestConfigReloaderWatchesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfg := config.DefaultConfig()
+	cfg.ReloadableConfigFile = filepath.Join(dir, "ecs.config")
+
+	reloader := newConfigReloader(&cfg, nil)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	require.NoError(t, reloader.watchFile(ctx))
+
+	// changes to the other files of the directory are ignored
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other.config"), []byte("ECS_LOGLEVEL=debug\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(cfg.ReloadableConfigFile, []byte("ECS_LOGLEVEL=debug\n"), 0644))
+	select {
+	case <-reloader.requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a change of the config file should request a reload")
+	}
+}
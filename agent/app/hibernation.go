@@ -0,0 +1,143 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	acshandler "github.com/aws/amazon-ecs-agent/agent/acs/handler"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+
+	"github.com/cihub/seelog"
+)
+
+const (
+	// targetLifecycleStateInService is the target lifecycle state of the instances put
+	// in service by their auto scaling group
+	targetLifecycleStateInService = "InService"
+	// warmPoolsCheckMaxErrors is how many times the target lifecycle state can't be
+	// fetched before the instance is assumed not to be in an auto scaling group
+	warmPoolsCheckMaxErrors = 3
+	// minSuspendedDuration is how much the wall clock has to move ahead of the monotonic
+	// clock for the instance to be considered resumed from a hibernation, rather than
+	// the wall clock being adjusted
+	minSuspendedDuration = 30 * time.Second
+)
+
+var (
+	// warmPoolsCheckInterval is how often the target lifecycle state is checked while
+	// the instance is in a warm pool
+	warmPoolsCheckInterval = 10 * time.Second
+	// resumeCheckInterval is how often the clocks are compared to detect a resume
+	resumeCheckInterval = 5 * time.Second
+)
+
+// waitUntilInService waits for the auto scaling group of the instance to put it in
+// service, when it's in a warm pool. It returns false if the agent is stopped
+// while it's waiting.
+func (agent *ecsAgent) waitUntilInService() bool {
+	failures := 0
+	lastState := ""
+	for {
+		state, err := agent.ec2MetadataClient.TargetLifecycleState()
+		switch {
+		case err != nil:
+			failures++
+			if failures >= warmPoolsCheckMaxErrors {
+				seelog.Warnf("Unable to get the target lifecycle state of the instance, registering it: %v", err)
+				return true
+			}
+		case strings.TrimSpace(state) == targetLifecycleStateInService:
+			if lastState != "" {
+				seelog.Infof("Instance was put in service, registering it")
+			}
+			return true
+		case state != lastState:
+			seelog.Infof("Instance is in the warm pool of its auto scaling group (target lifecycle state: %s), waiting for it to be put in service",
+				state)
+			lastState = state
+		}
+
+		select {
+		case <-agent.ctx.Done():
+			return false
+		case <-time.After(warmPoolsCheckInterval):
+		}
+	}
+}
+
+// suspendedDuration returns how long the instance was suspended during an interval,
+// from the time elapsed on the wall clock and on the monotonic clock, which doesn't
+// advance while the instance is hibernated on Linux.
+func suspendedDuration(lastWall, nowWall time.Time, monotonicElapsed time.Duration) time.Duration {
+	return nowWall.Sub(lastWall) - monotonicElapsed
+}
+
+// watchForResume calls onResume every time the instance resumes from a hibernation,
+// with how long it was suspended
+func (agent *ecsAgent) watchForResume(ctx context.Context, onResume func(time.Duration)) {
+	ticker := time.NewTicker(resumeCheckInterval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			// Round strips the monotonic clock reading, so that the wall clocks are compared
+			suspended := suspendedDuration(last.Round(0), now.Round(0), now.Sub(last))
+			last = now
+			if suspended >= minSuspendedDuration {
+				onResume(suspended)
+			}
+		}
+	}
+}
+
+// handleResume refreshes the state of the agent that went stale while the instance
+// was hibernated: the instance identity is checked again, the instance credentials,
+// the ENIs and the containers are refreshed, and the agent reconnects to ACS right
+// away to get the credentials of the tasks. It returns false when the instance resumed
+// as a different EC2 instance, once the containers of the tasks of the previous one
+// are cleaned up, as the agent has to exit to register the new instance.
+func (agent *ecsAgent) handleResume(taskEngine engine.TaskEngine, acsSession acshandler.Session,
+	suspended time.Duration) bool {
+	seelog.Warnf("Instance resumed after being suspended for %s, refreshing the agent state", suspended)
+
+	if instanceID := agent.getEC2InstanceID(); instanceID != "" && agent.ec2InstanceID != "" &&
+		instanceID != agent.ec2InstanceID {
+		seelog.Criticalf("EC2 instance ID changed from %s to %s while the instance was suspended, exiting to register the new instance",
+			agent.ec2InstanceID, instanceID)
+		if dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine); ok {
+			dockerTaskEngine.CleanupAfterInstanceChange()
+		}
+		return false
+	}
+	if agent.credentialProvider != nil {
+		agent.credentialProvider.Expire()
+	}
+	if agent.eniWatcher != nil {
+		if err := agent.eniWatcher.Reconcile(); err != nil {
+			seelog.Warnf("Unable to reconcile the ENIs after the instance resumed: %v", err)
+		}
+	}
+	if dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine); ok {
+		dockerTaskEngine.ResumeAfterHibernation(suspended)
+	}
+	acsSession.Reconnect()
+	return true
+}
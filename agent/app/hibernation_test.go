@@ -0,0 +1,139 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mock_ec2 "github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeACSSession struct {
+	reconnects int
+}
+
+func (session *fakeACSSession) Start() error {
+	return nil
+}
+
+func (session *fakeACSSession) Reconnect() {
+	session.reconnects++
+}
+
+func setTestWarmPoolsCheckInterval() func() {
+	interval := warmPoolsCheckInterval
+	warmPoolsCheckInterval = time.Millisecond
+	return func() {
+		warmPoolsCheckInterval = interval
+	}
+}
+
+func TestWaitUntilInService(t *testing.T) {
+	defer setTestWarmPoolsCheckInterval()()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ctx:               context.TODO(),
+		ec2MetadataClient: ec2MetadataClient,
+	}
+	gomock.InOrder(
+		ec2MetadataClient.EXPECT().TargetLifecycleState().Return("Warmed:Hibernated", nil).Times(2),
+		ec2MetadataClient.EXPECT().TargetLifecycleState().Return("", errors.New("timeout")),
+		ec2MetadataClient.EXPECT().TargetLifecycleState().Return("InService", nil),
+	)
+	assert.True(t, agent.waitUntilInService())
+}
+
+func TestWaitUntilInServiceNotInAutoScalingGroup(t *testing.T) {
+	defer setTestWarmPoolsCheckInterval()()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ctx:               context.TODO(),
+		ec2MetadataClient: ec2MetadataClient,
+	}
+	ec2MetadataClient.EXPECT().TargetLifecycleState().Return("", errors.New("404 - Not Found")).
+		Times(warmPoolsCheckMaxErrors)
+	assert.True(t, agent.waitUntilInService())
+}
+
+func TestWaitUntilInServiceCanceled(t *testing.T) {
+	defer setTestWarmPoolsCheckInterval()()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ctx:               ctx,
+		ec2MetadataClient: ec2MetadataClient,
+	}
+	ec2MetadataClient.EXPECT().TargetLifecycleState().DoAndReturn(func() (string, error) {
+		cancel()
+		return "Warmed:Stopped", nil
+	})
+	assert.False(t, agent.waitUntilInService())
+}
+
+func TestSuspendedDuration(t *testing.T) {
+	last := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Duration(0), suspendedDuration(last, last.Add(5*time.Second), 5*time.Second))
+	assert.Equal(t, time.Hour, suspendedDuration(last, last.Add(time.Hour+5*time.Second), 5*time.Second))
+}
+
+func TestHandleResume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ec2MetadataClient: ec2MetadataClient,
+		ec2InstanceID:     "i-123",
+	}
+	session := &fakeACSSession{}
+	ec2MetadataClient.EXPECT().InstanceID().Return("i-123", nil)
+
+	assert.True(t, agent.handleResume(mock_engine.NewMockTaskEngine(ctrl), session, time.Hour))
+	assert.Equal(t, 1, session.reconnects)
+}
+
+func TestHandleResumeInstanceChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ec2MetadataClient: ec2MetadataClient,
+		ec2InstanceID:     "i-123",
+	}
+	session := &fakeACSSession{}
+	ec2MetadataClient.EXPECT().InstanceID().Return("i-456", nil)
+
+	assert.False(t, agent.handleResume(mock_engine.NewMockTaskEngine(ctrl), session, time.Hour),
+		"the agent has to exit to register the new instance")
+	assert.Equal(t, 0, session.reconnects)
+}
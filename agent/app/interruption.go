@@ -0,0 +1,264 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+
+	"github.com/cihub/seelog"
+)
+
+// Target lifecycle states of the instances the auto scaling group is terminating
+const (
+	targetLifecycleStateTerminated       = "Terminated"
+	targetLifecycleStateWarmedTerminated = "Warmed:Terminated"
+)
+
+// interruptionCheckInterval is how often the instance metadata is checked for
+// interruption notices
+var interruptionCheckInterval = time.Second
+
+// interruptionWatcher watches the instance metadata for the notices that the instance
+// is going to be interrupted. Once it receives one, it sets the state of the container
+// instance to DRAINING, records the notice on the tasks for them to read it from the
+// task metadata endpoint, and disables the scale-in protection of the protected tasks
+// when configured to.
+type interruptionWatcher struct {
+	agent  *ecsAgent
+	client api.ECSClient
+	state  dockerstate.TaskEngineState
+
+	interruption *apitask.Interruption
+	drained      bool
+	// notifiedTasks are the tasks the current notice is recorded on
+	notifiedTasks map[string]struct{}
+	// unprotectedTasks are the tasks whose protection was checked, and disabled if
+	// they were protected
+	unprotectedTasks map[string]bool
+}
+
+func newInterruptionWatcher(agent *ecsAgent, client api.ECSClient, state dockerstate.TaskEngineState) *interruptionWatcher {
+	return &interruptionWatcher{
+		agent:            agent,
+		client:           client,
+		state:            state,
+		notifiedTasks:    make(map[string]struct{}),
+		unprotectedTasks: make(map[string]bool),
+	}
+}
+
+// watch checks for interruption notices until the agent stops. It keeps running
+// after the first notice, to record it on the tasks started since then and to
+// replace a rebalance recommendation by a later interruption notice.
+func (watcher *interruptionWatcher) watch(ctx context.Context) {
+	ticker := time.NewTicker(interruptionCheckInterval)
+	defer ticker.Stop()
+	for {
+		watcher.check()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check polls the interruption notices the instance hasn't received yet, and handles
+// the current one
+func (watcher *interruptionWatcher) check() {
+	if watcher.interruption == nil || watcher.interruption.Source == apitask.InterruptionSourceRebalance {
+		if interruption := watcher.poll(watcher.interruption == nil); interruption != nil {
+			seelog.Infof("Received an interruption notice (%s)", interruption.Source)
+			watcher.interruption = interruption
+			watcher.notifiedTasks = make(map[string]struct{})
+		}
+	}
+	if watcher.interruption == nil {
+		return
+	}
+
+	if !watcher.drained {
+		seelog.Infof("Setting instance [ARN: %s] state to DRAINING", watcher.agent.containerInstanceARN)
+		err := watcher.client.UpdateContainerInstancesState(watcher.agent.containerInstanceARN, "DRAINING")
+		if err != nil {
+			seelog.Errorf("Error setting instance [ARN: %s] state to DRAINING: %s", watcher.agent.containerInstanceARN, err)
+			return
+		}
+		watcher.drained = true
+	}
+
+	var tasks []*apitask.Task
+	for _, task := range watcher.state.AllTasks() {
+		if _, ok := watcher.notifiedTasks[task.Arn]; !ok && !task.GetKnownStatus().Terminal() {
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	if watcher.agent.cfg.DisableTaskProtectionOnInterruption.Enabled() {
+		if err := watcher.disableTaskProtection(tasks); err != nil {
+			// The notice is recorded on the tasks once their protection is disabled
+			seelog.Errorf("Error disabling the scale-in protection of the tasks: %v", err)
+			return
+		}
+	}
+	for _, task := range tasks {
+		interruption := *watcher.interruption
+		interruption.TaskProtectionDisabled = watcher.unprotectedTasks[task.Arn]
+		task.SetInterruption(interruption)
+		watcher.notifiedTasks[task.Arn] = struct{}{}
+	}
+}
+
+// disableTaskProtection disables the scale-in protection of the tasks that are
+// protected, among the ones that weren't checked yet
+func (watcher *interruptionWatcher) disableTaskProtection(tasks []*apitask.Task) error {
+	var taskARNs []string
+	for _, task := range tasks {
+		if _, ok := watcher.unprotectedTasks[task.Arn]; !ok {
+			taskARNs = append(taskARNs, task.Arn)
+		}
+	}
+	if len(taskARNs) == 0 {
+		return nil
+	}
+	protectedTasks, err := watcher.client.GetProtectedTasks(taskARNs)
+	if err != nil {
+		return err
+	}
+	if len(protectedTasks) > 0 {
+		seelog.Infof("Disabling the scale-in protection of %d tasks", len(protectedTasks))
+		if err := watcher.client.DisableTaskProtection(protectedTasks); err != nil {
+			return err
+		}
+	}
+	for _, taskARN := range taskARNs {
+		watcher.unprotectedTasks[taskARN] = false
+	}
+	for _, taskARN := range protectedTasks {
+		watcher.unprotectedTasks[taskARN] = true
+	}
+	return nil
+}
+
+// poll returns the interruption notice of the instance, checking the sources enabled
+// in the configuration, or nil if there's none. The rebalance recommendation is only
+// checked until one is received.
+func (watcher *interruptionWatcher) poll(checkRebalance bool) *apitask.Interruption {
+	cfg := watcher.agent.cfg
+	if cfg.SpotInstanceDrainingEnabled.Enabled() {
+		if interruption := watcher.agent.spotInterruption(); interruption != nil {
+			return interruption
+		}
+	}
+	if cfg.ASGTerminationDrainingEnabled.Enabled() {
+		if interruption := watcher.agent.asgTermination(); interruption != nil {
+			return interruption
+		}
+	}
+	if checkRebalance && cfg.SpotRebalanceDrainingEnabled.Enabled() {
+		return watcher.agent.rebalanceRecommendation()
+	}
+	return nil
+}
+
+// spotInterruption returns the spot interruption notice of the instance, or nil if
+// there's none
+func (agent *ecsAgent) spotInterruption() *apitask.Interruption {
+	// this endpoint 404s unless a interruption has been set, so expect failure in most cases.
+	resp, err := agent.ec2MetadataClient.SpotInstanceAction()
+	if err != nil {
+		return nil
+	}
+	ia := struct {
+		Time   string
+		Action string
+	}{}
+	if err := json.Unmarshal([]byte(resp), &ia); err != nil {
+		seelog.Errorf("Invalid response from /spot/instance-action endpoint: %s Error: %s", resp, err)
+		return nil
+	}
+
+	switch ia.Action {
+	case "hibernate", "terminate", "stop":
+	default:
+		seelog.Errorf("Invalid response from /spot/instance-action endpoint: %s, Error: unrecognized action (%s)", resp, ia.Action)
+		return nil
+	}
+
+	seelog.Infof("Received a spot interruption (%s) scheduled for %s", ia.Action, ia.Time)
+	interruption := &apitask.Interruption{
+		Source:     apitask.InterruptionSourceSpot,
+		Action:     ia.Action,
+		NoticeTime: time.Now().UTC(),
+	}
+	if interruptionTime, err := time.Parse(time.RFC3339, ia.Time); err == nil {
+		interruption.Time = &interruptionTime
+	}
+	return interruption
+}
+
+// rebalanceRecommendation returns the rebalance recommendation of the spot instance,
+// or nil if there's none
+func (agent *ecsAgent) rebalanceRecommendation() *apitask.Interruption {
+	// like the spot instance action, this endpoint 404s until a recommendation is sent
+	resp, err := agent.ec2MetadataClient.RebalanceRecommendation()
+	if err != nil {
+		return nil
+	}
+	recommendation := struct {
+		NoticeTime string `json:"noticeTime"`
+	}{}
+	if err := json.Unmarshal([]byte(resp), &recommendation); err != nil {
+		seelog.Errorf("Invalid response from /events/recommendations/rebalance endpoint: %s Error: %s", resp, err)
+		return nil
+	}
+
+	seelog.Infof("Received a rebalance recommendation sent at %s", recommendation.NoticeTime)
+	noticeTime, err := time.Parse(time.RFC3339, recommendation.NoticeTime)
+	if err != nil {
+		noticeTime = time.Now().UTC()
+	}
+	return &apitask.Interruption{
+		Source:     apitask.InterruptionSourceRebalance,
+		NoticeTime: noticeTime,
+	}
+}
+
+// asgTermination returns the notice that the auto scaling group of the instance is
+// terminating it, or nil if it isn't
+func (agent *ecsAgent) asgTermination() *apitask.Interruption {
+	state, err := agent.ec2MetadataClient.TargetLifecycleState()
+	if err != nil {
+		return nil
+	}
+	switch state {
+	case targetLifecycleStateTerminated, targetLifecycleStateWarmedTerminated:
+	default:
+		return nil
+	}
+
+	seelog.Infof("The auto scaling group of the instance is terminating it (%s)", state)
+	return &apitask.Interruption{
+		Source:     apitask.InterruptionSourceASGTermination,
+		NoticeTime: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,229 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mock_api "github.com/aws/amazon-ecs-agent/agent/api/mocks"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	mock_ec2 "github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var enabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+
+func TestSpotInterruption(t *testing.T) {
+	tests := []struct {
+		jsonresp string
+		action   string
+	}{
+		{jsonresp: `{"action": "terminate", "time": "2017-09-18T08:22:00Z"}`, action: "terminate"},
+		{jsonresp: `{"action": "hibernate", "time": "2017-09-18T08:22:00Z"}`, action: "hibernate"},
+		{jsonresp: `{"action": "stop", "time": "2017-09-18T08:22:00Z"}`, action: "stop"},
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ec2MetadataClient: ec2MetadataClient,
+	}
+	for _, test := range tests {
+		ec2MetadataClient.EXPECT().SpotInstanceAction().Return(test.jsonresp, nil)
+
+		interruption := agent.spotInterruption()
+		require.NotNil(t, interruption)
+		assert.Equal(t, apitask.InterruptionSourceSpot, interruption.Source)
+		assert.Equal(t, test.action, interruption.Action)
+		require.NotNil(t, interruption.Time)
+		assert.Equal(t, time.Date(2017, time.September, 18, 8, 22, 0, 0, time.UTC), *interruption.Time)
+	}
+}
+
+func TestSpotInterruptionInvalid(t *testing.T) {
+	tests := []struct {
+		jsonresp string
+	}{
+		{jsonresp: `{"action": "terminate" "time": "2017-09-18T08:22:00Z"}`}, // invalid json
+		{jsonresp: ``}, // empty json
+		{jsonresp: `{"action": "flip!", "time": "2017-09-18T08:22:00Z"}`}, // invalid action
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ec2MetadataClient: ec2MetadataClient,
+	}
+	for _, test := range tests {
+		ec2MetadataClient.EXPECT().SpotInstanceAction().Return(test.jsonresp, nil)
+		assert.Nil(t, agent.spotInterruption())
+	}
+
+	// there's no interruption notice yet
+	ec2MetadataClient.EXPECT().SpotInstanceAction().Return("", fmt.Errorf("404"))
+	assert.Nil(t, agent.spotInterruption())
+}
+
+func TestRebalanceRecommendation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ec2MetadataClient: ec2MetadataClient,
+	}
+	ec2MetadataClient.EXPECT().RebalanceRecommendation().Return("", fmt.Errorf("404"))
+	assert.Nil(t, agent.rebalanceRecommendation())
+
+	ec2MetadataClient.EXPECT().RebalanceRecommendation().Return(`{"noticeTime": "2020-10-27T08:22:00Z"}`, nil)
+	interruption := agent.rebalanceRecommendation()
+	require.NotNil(t, interruption)
+	assert.Equal(t, apitask.InterruptionSourceRebalance, interruption.Source)
+	assert.Equal(t, time.Date(2020, time.October, 27, 8, 22, 0, 0, time.UTC), interruption.NoticeTime)
+}
+
+func TestASGTermination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	agent := &ecsAgent{
+		ec2MetadataClient: ec2MetadataClient,
+	}
+	ec2MetadataClient.EXPECT().TargetLifecycleState().Return("InService", nil)
+	assert.Nil(t, agent.asgTermination())
+
+	ec2MetadataClient.EXPECT().TargetLifecycleState().Return("Terminated", nil)
+	interruption := agent.asgTermination()
+	require.NotNil(t, interruption)
+	assert.Equal(t, apitask.InterruptionSourceASGTermination, interruption.Source)
+}
+
+func newTestInterruptionWatcher(t *testing.T, cfg *config.Config) (*interruptionWatcher,
+	*mock_ec2.MockEC2MetadataClient, *mock_api.MockECSClient, dockerstate.TaskEngineState, *gomock.Controller) {
+	ctrl := gomock.NewController(t)
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	state := dockerstate.NewTaskEngineState()
+	agent := &ecsAgent{
+		cfg:                  cfg,
+		ec2MetadataClient:    ec2MetadataClient,
+		containerInstanceARN: "myARN",
+	}
+	return newInterruptionWatcher(agent, ecsClient, state), ec2MetadataClient, ecsClient, state, ctrl
+}
+
+func TestInterruptionWatcherDrains(t *testing.T) {
+	watcher, ec2MetadataClient, ecsClient, state, ctrl := newTestInterruptionWatcher(t,
+		&config.Config{SpotInstanceDrainingEnabled: enabled})
+	defer ctrl.Finish()
+
+	running := &apitask.Task{Arn: "running", KnownStatusUnsafe: apitaskstatus.TaskRunning}
+	stopped := &apitask.Task{Arn: "stopped", KnownStatusUnsafe: apitaskstatus.TaskStopped}
+	state.AddTask(running)
+	state.AddTask(stopped)
+
+	ec2MetadataClient.EXPECT().SpotInstanceAction().Return("", fmt.Errorf("404"))
+	watcher.check()
+	assert.Nil(t, running.GetInterruption())
+
+	// The notice is only recorded on the tasks once the instance is draining.
+	ec2MetadataClient.EXPECT().SpotInstanceAction().Return(`{"action": "terminate", "time": "2017-09-18T08:22:00Z"}`, nil)
+	ecsClient.EXPECT().UpdateContainerInstancesState("myARN", "DRAINING").Return(errors.New("error"))
+	watcher.check()
+	assert.Nil(t, running.GetInterruption())
+
+	ecsClient.EXPECT().UpdateContainerInstancesState("myARN", "DRAINING").Return(nil)
+	watcher.check()
+	require.NotNil(t, running.GetInterruption())
+	assert.Equal(t, "terminate", running.GetInterruption().Action)
+	assert.Nil(t, stopped.GetInterruption())
+
+	// Once the instance is draining, the notice is recorded on the new tasks.
+	started := &apitask.Task{Arn: "started", KnownStatusUnsafe: apitaskstatus.TaskRunning}
+	state.AddTask(started)
+	watcher.check()
+	require.NotNil(t, started.GetInterruption())
+	assert.Equal(t, apitask.InterruptionSourceSpot, started.GetInterruption().Source)
+}
+
+func TestInterruptionWatcherDisablesTaskProtection(t *testing.T) {
+	watcher, ec2MetadataClient, ecsClient, state, ctrl := newTestInterruptionWatcher(t, &config.Config{
+		ASGTerminationDrainingEnabled:       enabled,
+		DisableTaskProtectionOnInterruption: enabled,
+	})
+	defer ctrl.Finish()
+
+	protected := &apitask.Task{Arn: "protected", KnownStatusUnsafe: apitaskstatus.TaskRunning}
+	unprotected := &apitask.Task{Arn: "unprotected", KnownStatusUnsafe: apitaskstatus.TaskRunning}
+	state.AddTask(protected)
+	state.AddTask(unprotected)
+
+	ec2MetadataClient.EXPECT().TargetLifecycleState().Return("Terminated", nil)
+	ecsClient.EXPECT().UpdateContainerInstancesState("myARN", "DRAINING").Return(nil)
+	ecsClient.EXPECT().GetProtectedTasks(gomock.Any()).DoAndReturn(func(taskARNs []string) ([]string, error) {
+		assert.ElementsMatch(t, []string{"protected", "unprotected"}, taskARNs)
+		return []string{"protected"}, nil
+	})
+	ecsClient.EXPECT().DisableTaskProtection([]string{"protected"}).Return(nil)
+	watcher.check()
+
+	require.NotNil(t, protected.GetInterruption())
+	assert.True(t, protected.GetInterruption().TaskProtectionDisabled)
+	require.NotNil(t, unprotected.GetInterruption())
+	assert.False(t, unprotected.GetInterruption().TaskProtectionDisabled)
+
+	// The protection of the tasks is only checked once.
+	watcher.check()
+}
+
+func TestInterruptionWatcherRebalanceThenSpotInterruption(t *testing.T) {
+	watcher, ec2MetadataClient, ecsClient, state, ctrl := newTestInterruptionWatcher(t, &config.Config{
+		SpotInstanceDrainingEnabled:  enabled,
+		SpotRebalanceDrainingEnabled: enabled,
+	})
+	defer ctrl.Finish()
+
+	task := &apitask.Task{Arn: "task", KnownStatusUnsafe: apitaskstatus.TaskRunning}
+	state.AddTask(task)
+
+	ec2MetadataClient.EXPECT().SpotInstanceAction().Return("", fmt.Errorf("404"))
+	ec2MetadataClient.EXPECT().RebalanceRecommendation().Return(`{"noticeTime": "2020-10-27T08:22:00Z"}`, nil)
+	ecsClient.EXPECT().UpdateContainerInstancesState("myARN", "DRAINING").Return(nil)
+	watcher.check()
+	require.NotNil(t, task.GetInterruption())
+	assert.Equal(t, apitask.InterruptionSourceRebalance, task.GetInterruption().Source)
+
+	// The rebalance recommendation isn't checked again, and the instance is already
+	// draining when the interruption notice comes.
+	ec2MetadataClient.EXPECT().SpotInstanceAction().Return(`{"action": "stop", "time": "2020-10-27T08:30:00Z"}`, nil)
+	watcher.check()
+	assert.Equal(t, apitask.InterruptionSourceSpot, task.GetInterruption().Source)
+	assert.Equal(t, "stop", task.GetInterruption().Action)
+
+	watcher.check()
+}
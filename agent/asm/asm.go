@@ -25,6 +25,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// currentVersionStage is the staging label of the current version of a secret
+const currentVersionStage = "AWSCURRENT"
+
 // AuthDataValue is the schema for
 // the SecretStringValue returned by ASM
 type AuthDataValue struct {
@@ -91,26 +94,43 @@ func extractASMValue(out *secretsmanager.GetSecretValueOutput) (types.AuthConfig
 
 func GetSecretFromASMWithInput(input *secretsmanager.GetSecretValueInput,
 	client secretsmanageriface.SecretsManagerAPI, jsonKey string) (string, error) {
+	secretString, err := GetSecretStringFromASMWithInput(input, client)
+	if err != nil {
+		return "", err
+	}
+
+	return ExtractJSONKeyFromSecretString(aws.StringValue(input.SecretId), secretString, jsonKey)
+}
+
+// GetSecretStringFromASMWithInput retrieves the whole string value of the secret, so that
+// the values of several of its JSON keys can be extracted from a single call
+func GetSecretStringFromASMWithInput(input *secretsmanager.GetSecretValueInput,
+	client secretsmanageriface.SecretsManagerAPI) (string, error) {
 	out, err := client.GetSecretValue(input)
 	if err != nil {
-		return "", errors.Wrapf(err, "secret %s", *input.SecretId)
+		return "", errors.Wrapf(err, "secret %s", aws.StringValue(input.SecretId))
 	}
 
+	return aws.StringValue(out.SecretString), nil
+}
+
+// ExtractJSONKeyFromSecretString returns the value of the JSON key of the string value of
+// the secret, or the whole string value when no key is given
+func ExtractJSONKeyFromSecretString(secretID, secretString, jsonKey string) (string, error) {
 	if jsonKey == "" {
-		return aws.StringValue(out.SecretString), nil
+		return secretString, nil
 	}
 
 	secretMap := make(map[string]interface{})
-	jsonErr := json.Unmarshal([]byte(*out.SecretString), &secretMap)
+	jsonErr := json.Unmarshal([]byte(secretString), &secretMap)
 	if jsonErr != nil {
-		seelog.Warnf("Error when treating retrieved secret value with secret id %s as JSON and calling unmarshal.", *input.SecretId)
+		seelog.Warnf("Error when treating retrieved secret value with secret id %s as JSON and calling unmarshal.", secretID)
 		return "", jsonErr
 	}
 
 	secretValue, ok := secretMap[jsonKey]
 	if !ok {
-		err = errors.New(fmt.Sprintf("retrieved secret from Secrets Manager did not contain json key %s", jsonKey))
-		return "", err
+		return "", errors.New(fmt.Sprintf("retrieved secret from Secrets Manager did not contain json key %s", jsonKey))
 	}
 
 	return fmt.Sprintf("%v", secretValue), nil
@@ -130,3 +150,39 @@ func GetSecretFromASM(secretID string, client secretsmanageriface.SecretsManager
 
 	return aws.StringValue(out.SecretString), nil
 }
+
+// GetSecretWithVersionFromASM makes the api call to the AWS Secrets Manager service to
+// retrieve the secret value along with the id of its version
+func GetSecretWithVersionFromASM(secretID string, client secretsmanageriface.SecretsManagerAPI) (string, string, error) {
+	in := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	}
+
+	out, err := client.GetSecretValue(in)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "secret %s", secretID)
+	}
+
+	return aws.StringValue(out.SecretString), aws.StringValue(out.VersionId), nil
+}
+
+// GetSecretVersionFromASM makes the api call to the AWS Secrets Manager service to
+// retrieve the id of the current version of the secret, without its value
+func GetSecretVersionFromASM(secretID string, client secretsmanageriface.SecretsManagerAPI) (string, error) {
+	in := &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretID),
+	}
+
+	out, err := client.DescribeSecret(in)
+	if err != nil {
+		return "", errors.Wrapf(err, "secret %s", secretID)
+	}
+	for versionID, stages := range out.VersionIdsToStages {
+		for _, stage := range stages {
+			if aws.StringValue(stage) == currentVersionStage {
+				return versionID, nil
+			}
+		}
+	}
+	return "", errors.Errorf("secret %s has no current version", secretID)
+}
@@ -166,6 +166,45 @@ func TestGetSecretFromASMWithVersionIDAndStage(t *testing.T) {
 	assert.Equal(t, secretValue, outSecretValue)
 }
 
+type mockDescribeSecret struct {
+	secretsmanageriface.SecretsManagerAPI
+	Resp secretsmanager.DescribeSecretOutput
+}
+
+func (m mockDescribeSecret) DescribeSecret(input *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+	return &m.Resp, nil
+}
+
+func TestGetSecretWithVersionFromASM(t *testing.T) {
+	asmClient := mockGetSecretValue{
+		Resp: secretsmanager.GetSecretValueOutput{
+			SecretString: aws.String(secretValue),
+			VersionId:    aws.String(versionID),
+		},
+	}
+	outSecretValue, outVersionID, err := GetSecretWithVersionFromASM("secretName", asmClient)
+	require.NoError(t, err)
+	assert.Equal(t, secretValue, outSecretValue)
+	assert.Equal(t, versionID, outVersionID)
+}
+
+func TestGetSecretVersionFromASM(t *testing.T) {
+	asmClient := mockDescribeSecret{
+		Resp: secretsmanager.DescribeSecretOutput{
+			VersionIdsToStages: map[string][]*string{
+				"previous": {aws.String("AWSPREVIOUS")},
+				versionID:  {aws.String(versionStage), aws.String(currentVersionStage)},
+			},
+		},
+	}
+	outVersionID, err := GetSecretVersionFromASM("secretName", asmClient)
+	require.NoError(t, err)
+	assert.Equal(t, versionID, outVersionID)
+
+	_, err = GetSecretVersionFromASM("secretName", mockDescribeSecret{})
+	assert.Error(t, err)
+}
+
 func toPtr(input string) *string {
 	if input == "" {
 		return nil
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"reflect"
 	"strings"
@@ -60,6 +61,22 @@ const (
 	// clean up task's containers.
 	DefaultTaskCleanupWaitDuration = 3 * time.Hour
 
+	// DefaultStoppedTaskHistoryRetention specifies how long the tasks that were cleaned up are
+	// still listed by the introspection API.
+	DefaultStoppedTaskHistoryRetention = 1 * time.Hour
+
+	// DefaultDataCompactionInterval specifies how often the checkpoint file is compacted.
+	DefaultDataCompactionInterval = 6 * time.Hour
+
+	// DataBackendBoltDB checkpoints the data of the agent to a boltdb file.
+	DataBackendBoltDB = "boltdb"
+
+	// DataBackendFile checkpoints the data of the agent to a JSON file per object.
+	DataBackendFile = "file"
+
+	// DefaultLifecycleHookTimeout specifies how long a lifecycle hook can run before it's killed.
+	DefaultLifecycleHookTimeout = 30 * time.Second
+
 	// DefaultPollingMetricsWaitDuration specifies the default value for polling metrics wait duration
 	// This is only used when PollMetrics is set to true
 	DefaultPollingMetricsWaitDuration = DefaultContainerMetricsPublishInterval / 2
@@ -71,6 +88,10 @@ const (
 	// remove the images pulled by agent.
 	DefaultImageCleanupTimeInterval = 30 * time.Minute
 
+	// DefaultImageCleanupDiskCheckInterval is the default time between two checks of the
+	// docker data root disk usage against the image cleanup high watermark
+	DefaultImageCleanupDiskCheckInterval = 1 * time.Minute
+
 	// DefaultNumImagesToDeletePerCycle specifies the default number of images to delete when agent performs
 	// image cleanup.
 	DefaultNumImagesToDeletePerCycle = 5
@@ -90,10 +111,105 @@ const (
 	//DefaultImagePullTimeout specifies the timeout for PullImage API.
 	DefaultImagePullTimeout = 2 * time.Hour
 
+	// DefaultImagePullMaxAttempts specifies the default number of times an image pull is attempted
+	DefaultImagePullMaxAttempts = 5
+
+	// DefaultImagePullRetryBaseDelay specifies the default delay before the first image pull retry
+	DefaultImagePullRetryBaseDelay = 1100 * time.Millisecond
+
+	// DefaultImagePullRetryMaxDelay specifies the default maximum delay between image pull retries
+	DefaultImagePullRetryMaxDelay = 5 * time.Second
+
+	// DefaultECRTokenRefreshWindow specifies how long before their expiry cached ECR
+	// auth tokens are refreshed in the background
+	DefaultECRTokenRefreshWindow = 2 * time.Hour
+
+	// DefaultTaskCredentialsRefreshWindow specifies how long before their expiry task
+	// IAM role credentials are proactively requested from ACS
+	DefaultTaskCredentialsRefreshWindow = 15 * time.Minute
+
+	// DefaultInstanceAttributeRefreshInterval specifies how often the discovered instance
+	// attributes are refreshed
+	DefaultInstanceAttributeRefreshInterval = 15 * time.Minute
+
+	// DefaultContainerCheckpointInterval specifies how often the running containers of
+	// tasks with checkpointing enabled are checkpointed
+	DefaultContainerCheckpointInterval = 15 * time.Minute
+
+	// DefaultAWSLogsRelayBufferSizeMB is the default maximum size, in MB, of the disk buffer
+	// of each container whose awslogs logs are relayed by the agent
+	DefaultAWSLogsRelayBufferSizeMB = 100
+
+	// DefaultEncryptedVolumeSizeMB is the default size, in MB, of the dm-crypt devices backing
+	// the encrypted ephemeral volumes
+	DefaultEncryptedVolumeSizeMB = 10240
+
+	// DefaultFirelensConfigReloadInterval specifies how often the config of the firelens
+	// containers of tasks with config reload enabled is checked for changes
+	DefaultFirelensConfigReloadInterval = 5 * time.Minute
+
+	// DefaultSecretsRefreshSignal is the signal sent to the containers whose file secrets
+	// were rotated, which most servers handle by reloading their config
+	DefaultSecretsRefreshSignal = "SIGHUP"
+
+	// DefaultMemoryPressureCheckInterval specifies how often the memory pressure of the
+	// tasks is checked when a memory pressure policy is set
+	DefaultMemoryPressureCheckInterval = 10 * time.Second
+
+	// DefaultMemoryPressureStallThreshold is the default share of time, in percent, the
+	// processes of a task can stall waiting for memory before it's under memory pressure
+	DefaultMemoryPressureStallThreshold = 40
+
+	// DefaultMemoryPressureUsageThreshold is the default memory usage of a task, in percent
+	// of its memory limit, above which it's under memory pressure
+	DefaultMemoryPressureUsageThreshold = 95
+
+	// DefaultTaskDNSCacheQueriesPerSecond is the default number of DNS queries per second
+	// the local DNS cache of an awsvpc task forwards to its resolvers, half of the packets
+	// per second the VPC resolver accepts from a network interface
+	DefaultTaskDNSCacheQueriesPerSecond = 512
+
+	// DefaultGMSACredentialSpecCacheTTL specifies how long the gMSA credential specs
+	// fetched from S3, SSM and Secrets Manager are cached
+	DefaultGMSACredentialSpecCacheTTL = 1 * time.Hour
+
+	// DefaultSecurityProfilesCacheTTL specifies how long the seccomp and AppArmor profiles
+	// are cached for the other containers that reference them
+	DefaultSecurityProfilesCacheTTL = 1 * time.Hour
+
+	// minimumContainerDiskUsagePollInterval specifies the minimum time between two
+	// measurements of the disk space used by a container, as walking its bind mounts
+	// can be expensive
+	minimumContainerDiskUsagePollInterval = 1 * time.Minute
+
+	// minimumEFSMountHealthCheckInterval specifies the minimum time between two checks of
+	// the EFS volumes mounted in a container
+	minimumEFSMountHealthCheckInterval = 30 * time.Second
+
+	// minimumMemoryPressureCheckInterval specifies the minimum time between two checks of
+	// the memory pressure of the tasks
+	minimumMemoryPressureCheckInterval = 1 * time.Second
+
+	// minimumContainerCheckpointInterval specifies the minimum time between two checkpoints
+	// of a container, as containers are paused while they're checkpointed
+	minimumContainerCheckpointInterval = 1 * time.Minute
+
+	// minimumFirelensConfigReloadInterval specifies the minimum time between two checks
+	// of the config of a firelens container, as external configs are downloaded from S3
+	minimumFirelensConfigReloadInterval = 1 * time.Minute
+
+	// minimumSecretsRefreshInterval specifies the minimum time between two refreshes of
+	// the secrets of a task, as each refresh fetches them all from SSM and Secrets Manager
+	minimumSecretsRefreshInterval = 1 * time.Minute
+
 	// minimumTaskCleanupWaitDuration specifies the minimum duration to wait before cleaning up
 	// a task's container. This is used to enforce sane values for the config.TaskCleanupWaitDuration field.
 	minimumTaskCleanupWaitDuration = 1 * time.Minute
 
+	// minimumDataCompactionInterval specifies the minimum time between two compactions of
+	// the checkpoint file, as reads and writes of the checkpoint wait for the compaction
+	minimumDataCompactionInterval = 10 * time.Minute
+
 	// minimumImagePullInactivityTimeout specifies the minimum amount of time for that an image can be
 	// 'stuck' in the pull / unpack step. Very small values are unsafe and lead to high failure rate.
 	minimumImagePullInactivityTimeout = 1 * time.Minute
@@ -106,6 +222,10 @@ const (
 	// from docker. This is only used when PollMetrics is set to true
 	maximumPollingMetricsWaitDuration = DefaultContainerMetricsPublishInterval
 
+	// minimumStatsCollectionInterval specifies the minimum duration between two collections
+	// of the stats of a container, when set with ECS_STATS_COLLECTION_INTERVAL
+	minimumStatsCollectionInterval = 1 * time.Second
+
 	// minimumDockerStopTimeout specifies the minimum value for docker StopContainer API
 	minimumDockerStopTimeout = 1 * time.Second
 
@@ -113,6 +233,24 @@ const (
 	// image cleanup.
 	minimumImageCleanupInterval = 10 * time.Minute
 
+	// minimumImageCleanupDiskCheckInterval specifies the minimum time between two checks of
+	// the docker data root disk usage
+	minimumImageCleanupDiskCheckInterval = 10 * time.Second
+
+	// minimumECRTokenRefreshWindow specifies the minimum time before expiry at which ECR
+	// auth tokens are refreshed. Cached tokens are already considered invalid up to an
+	// hour before they expire, so refreshing any later than that has no effect.
+	minimumECRTokenRefreshWindow = 1 * time.Hour
+
+	// minimumTaskCredentialsRefreshWindow specifies the minimum time before expiry at which
+	// task IAM role credentials are requested from ACS, so that there is time to reconnect
+	// to ACS and receive them before they expire.
+	minimumTaskCredentialsRefreshWindow = 1 * time.Minute
+
+	// minimumInstanceAttributeRefreshInterval specifies the minimum interval at which the
+	// discovered instance attributes are refreshed, to limit the PutAttributes calls.
+	minimumInstanceAttributeRefreshInterval = 1 * time.Minute
+
 	// minimumNumImagesToDeletePerCycle specifies the minimum number of images that to be deleted when
 	// performing image cleanup.
 	minimumNumImagesToDeletePerCycle = 1
@@ -134,6 +272,11 @@ const (
 	// DefaultTaskMetadataBurstRate is set to handle 60 burst requests at once
 	DefaultTaskMetadataBurstRate = 60
 
+	// DefaultPrometheusMetricsBindAddress is the address the Prometheus metrics
+	// endpoint listens on by default, so metrics can only be scraped from the
+	// instance itself
+	DefaultPrometheusMetricsBindAddress = "127.0.0.1"
+
 	//Known cached image names
 	CachedImageNameAgentContainer = "amazon/amazon-ecs-agent:latest"
 
@@ -169,6 +312,23 @@ const (
 	ImagePullPreferCachedBehavior
 )
 
+const (
+	// ImagePullErrorThrottling is the class of errors returned by registries that
+	// throttle the pulls, such as "toomanyrequests"
+	ImagePullErrorThrottling ImagePullErrorClass = "throttling"
+
+	// ImagePullErrorNotFound is the class of errors for images, tags and repositories
+	// that do not exist, such as "manifest unknown"
+	ImagePullErrorNotFound ImagePullErrorClass = "not-found"
+
+	// ImagePullErrorTimeout is the class of errors for pulls that timed out or made
+	// no progress
+	ImagePullErrorTimeout ImagePullErrorClass = "timeout"
+
+	// ImagePullErrorOther is the class of every other retriable image pull error
+	ImagePullErrorOther ImagePullErrorClass = "other"
+)
+
 const (
 	// When ContainerInstancePropagateTagsFromNoneType is specified, no DescribeTags
 	// API call will be made.
@@ -179,6 +339,21 @@ const (
 	ContainerInstancePropagateTagsFromEC2InstanceType
 )
 
+const (
+	// MemoryPressurePolicyNone specifies that the memory pressure of the tasks isn't
+	// checked
+	MemoryPressurePolicyNone MemoryPressurePolicyType = iota
+
+	// MemoryPressurePolicyReport specifies that the memory pressure of the tasks is
+	// reported in their task metadata
+	MemoryPressurePolicyReport
+
+	// MemoryPressurePolicyEvict specifies that the memory pressure of the tasks is
+	// reported, and that the non-essential container of a task under pressure with the
+	// lowest eviction priority is stopped
+	MemoryPressurePolicyEvict
+)
+
 var (
 	// DefaultPauseContainerImageName is the name of the pause container image. The linker's
 	// load flags are used to populate this value from the Makefile
@@ -332,34 +507,168 @@ func (cfg *Config) validateAndOverrideBounds() error {
 		return errors.New("Invalid logging drivers: " + strings.Join(badDrivers, ", "))
 	}
 
+	if cfg.DataBackend != DataBackendBoltDB && cfg.DataBackend != DataBackendFile {
+		return fmt.Errorf("config: invalid value for data backend: %s", cfg.DataBackend)
+	}
+
 	// If a value has been set for taskCleanupWaitDuration and the value is less than the minimum allowed cleanup duration,
 	// print a warning and override it
 	if cfg.TaskCleanupWaitDuration < minimumTaskCleanupWaitDuration {
-		seelog.Warnf("Invalid value for ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultTaskCleanupWaitDuration.String(), cfg.TaskCleanupWaitDuration, minimumTaskCleanupWaitDuration)
+		cfg.reportProblem([]string{"ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION"}, "Invalid value for ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultTaskCleanupWaitDuration.String(), cfg.TaskCleanupWaitDuration, minimumTaskCleanupWaitDuration)
 		cfg.TaskCleanupWaitDuration = DefaultTaskCleanupWaitDuration
 	}
 
 	if cfg.ImagePullInactivityTimeout < minimumImagePullInactivityTimeout {
-		seelog.Warnf("Invalid value for image pull inactivity timeout duration, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", defaultImagePullInactivityTimeout.String(), cfg.ImagePullInactivityTimeout, minimumImagePullInactivityTimeout)
+		cfg.reportProblem([]string{"ECS_IMAGE_PULL_INACTIVITY_TIMEOUT"}, "Invalid value for image pull inactivity timeout duration, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", defaultImagePullInactivityTimeout.String(), cfg.ImagePullInactivityTimeout, minimumImagePullInactivityTimeout)
 		cfg.ImagePullInactivityTimeout = defaultImagePullInactivityTimeout
 	}
 
+	if cfg.ContainerCheckpointInterval != 0 && cfg.ContainerCheckpointInterval < minimumContainerCheckpointInterval {
+		cfg.reportProblem([]string{"ECS_CONTAINER_CHECKPOINT_INTERVAL"}, "Invalid value for ECS_CONTAINER_CHECKPOINT_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultContainerCheckpointInterval.String(), cfg.ContainerCheckpointInterval, minimumContainerCheckpointInterval)
+		cfg.ContainerCheckpointInterval = DefaultContainerCheckpointInterval
+	}
+
+	if cfg.FirelensConfigReloadInterval != 0 && cfg.FirelensConfigReloadInterval < minimumFirelensConfigReloadInterval {
+		cfg.reportProblem([]string{"ECS_FIRELENS_CONFIG_RELOAD_INTERVAL"}, "Invalid value for ECS_FIRELENS_CONFIG_RELOAD_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultFirelensConfigReloadInterval.String(), cfg.FirelensConfigReloadInterval, minimumFirelensConfigReloadInterval)
+		cfg.FirelensConfigReloadInterval = DefaultFirelensConfigReloadInterval
+	}
+
+	if cfg.SecretsRefreshInterval != 0 && cfg.SecretsRefreshInterval < minimumSecretsRefreshInterval {
+		cfg.reportProblem([]string{"ECS_SECRETS_REFRESH_INTERVAL"}, "Invalid value for ECS_SECRETS_REFRESH_INTERVAL, will be overridden with the minimum value: %s. Parsed value: %v.", minimumSecretsRefreshInterval.String(), cfg.SecretsRefreshInterval)
+		cfg.SecretsRefreshInterval = minimumSecretsRefreshInterval
+	}
+
+	if cfg.LifecycleHookTimeout < 0 {
+		cfg.reportProblem([]string{"ECS_LIFECYCLE_HOOK_TIMEOUT"}, "Invalid value for ECS_LIFECYCLE_HOOK_TIMEOUT, will be overridden with the default value: %s. Parsed value: %v.", DefaultLifecycleHookTimeout.String(), cfg.LifecycleHookTimeout)
+		cfg.LifecycleHookTimeout = DefaultLifecycleHookTimeout
+	}
+
+	if cfg.DataCompactionInterval > 0 && cfg.DataCompactionInterval < minimumDataCompactionInterval {
+		cfg.reportProblem([]string{"ECS_DATA_COMPACTION_INTERVAL"}, "Invalid value for ECS_DATA_COMPACTION_INTERVAL, will be overridden with the minimum value: %s. Parsed value: %v.", minimumDataCompactionInterval.String(), cfg.DataCompactionInterval)
+		cfg.DataCompactionInterval = minimumDataCompactionInterval
+	}
+
 	if cfg.ImageCleanupInterval < minimumImageCleanupInterval {
-		seelog.Warnf("Invalid value for ECS_IMAGE_CLEANUP_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultImageCleanupTimeInterval.String(), cfg.ImageCleanupInterval, minimumImageCleanupInterval)
+		cfg.reportProblem([]string{"ECS_IMAGE_CLEANUP_INTERVAL"}, "Invalid value for ECS_IMAGE_CLEANUP_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultImageCleanupTimeInterval.String(), cfg.ImageCleanupInterval, minimumImageCleanupInterval)
 		cfg.ImageCleanupInterval = DefaultImageCleanupTimeInterval
 	}
 
+	if cfg.ImagePullMaxBandwidthMbps < 0 {
+		cfg.reportProblem([]string{"ECS_IMAGE_PULL_MAX_BANDWIDTH_MBPS"}, "Invalid value for ECS_IMAGE_PULL_MAX_BANDWIDTH_MBPS, will be overridden with the default value: 0 (no limit). Parsed value: %v.", cfg.ImagePullMaxBandwidthMbps)
+		cfg.ImagePullMaxBandwidthMbps = 0
+	}
+
+	if cfg.ContainerDiskUsagePollInterval != 0 && cfg.ContainerDiskUsagePollInterval < minimumContainerDiskUsagePollInterval {
+		cfg.reportProblem([]string{"ECS_CONTAINER_DISK_USAGE_POLL_INTERVAL"}, "Invalid value for ECS_CONTAINER_DISK_USAGE_POLL_INTERVAL, will be overridden with the minimum value: %s. Parsed value: %v.", minimumContainerDiskUsagePollInterval.String(), cfg.ContainerDiskUsagePollInterval)
+		cfg.ContainerDiskUsagePollInterval = minimumContainerDiskUsagePollInterval
+	}
+
+	if cfg.EFSMountHealthCheckInterval != 0 && cfg.EFSMountHealthCheckInterval < minimumEFSMountHealthCheckInterval {
+		cfg.reportProblem([]string{"ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL"}, "Invalid value for ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL, will be overridden with the minimum value: %s. Parsed value: %v.", minimumEFSMountHealthCheckInterval.String(), cfg.EFSMountHealthCheckInterval)
+		cfg.EFSMountHealthCheckInterval = minimumEFSMountHealthCheckInterval
+	}
+
+	if cfg.MemoryPressureCheckInterval < minimumMemoryPressureCheckInterval {
+		cfg.reportProblem([]string{"ECS_MEMORY_PRESSURE_CHECK_INTERVAL"}, "Invalid value for ECS_MEMORY_PRESSURE_CHECK_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultMemoryPressureCheckInterval.String(), cfg.MemoryPressureCheckInterval, minimumMemoryPressureCheckInterval)
+		cfg.MemoryPressureCheckInterval = DefaultMemoryPressureCheckInterval
+	}
+
+	if cfg.MemoryPressureStallThreshold <= 0 || cfg.MemoryPressureStallThreshold > 100 {
+		cfg.reportProblem([]string{"ECS_MEMORY_PRESSURE_STALL_THRESHOLD"}, "Invalid value for ECS_MEMORY_PRESSURE_STALL_THRESHOLD, will be overridden with the default value: %d. Parsed value: %v.", DefaultMemoryPressureStallThreshold, cfg.MemoryPressureStallThreshold)
+		cfg.MemoryPressureStallThreshold = DefaultMemoryPressureStallThreshold
+	}
+
+	if cfg.MemoryPressureUsageThreshold <= 0 || cfg.MemoryPressureUsageThreshold > 100 {
+		cfg.reportProblem([]string{"ECS_MEMORY_PRESSURE_USAGE_THRESHOLD"}, "Invalid value for ECS_MEMORY_PRESSURE_USAGE_THRESHOLD, will be overridden with the default value: %d. Parsed value: %v.", DefaultMemoryPressureUsageThreshold, cfg.MemoryPressureUsageThreshold)
+		cfg.MemoryPressureUsageThreshold = DefaultMemoryPressureUsageThreshold
+	}
+
+	if cfg.TaskDNSCacheQueriesPerSecond < 0 {
+		cfg.reportProblem([]string{"ECS_TASK_DNS_CACHE_QUERIES_PER_SECOND"}, "Invalid value for ECS_TASK_DNS_CACHE_QUERIES_PER_SECOND, will be overridden with the default value: %d. Parsed value: %d.", DefaultTaskDNSCacheQueriesPerSecond, cfg.TaskDNSCacheQueriesPerSecond)
+		cfg.TaskDNSCacheQueriesPerSecond = DefaultTaskDNSCacheQueriesPerSecond
+	}
+
+	if cfg.EncryptedVolumeSizeMB <= 0 {
+		cfg.reportProblem([]string{"ECS_ENCRYPTED_VOLUME_SIZE_MB"}, "Invalid value for ECS_ENCRYPTED_VOLUME_SIZE_MB, will be overridden with the default value: %d. Parsed value: %d.", DefaultEncryptedVolumeSizeMB, cfg.EncryptedVolumeSizeMB)
+		cfg.EncryptedVolumeSizeMB = DefaultEncryptedVolumeSizeMB
+	}
+
+	if cfg.TelemetryBufferSizeMB < 0 {
+		cfg.reportProblem([]string{"ECS_TELEMETRY_BUFFER_SIZE_MB"}, "Invalid value for ECS_TELEMETRY_BUFFER_SIZE_MB, will be overridden with the default value: 0 (disabled). Parsed value: %d.", cfg.TelemetryBufferSizeMB)
+		cfg.TelemetryBufferSizeMB = 0
+	}
+
+	if cfg.AWSLogsRelayBufferSizeMB < 0 {
+		cfg.reportProblem([]string{"ECS_AWSLOGS_RELAY_BUFFER_SIZE_MB"}, "Invalid value for ECS_AWSLOGS_RELAY_BUFFER_SIZE_MB, will be overridden with the default value: %d. Parsed value: %d.", DefaultAWSLogsRelayBufferSizeMB, cfg.AWSLogsRelayBufferSizeMB)
+		cfg.AWSLogsRelayBufferSizeMB = DefaultAWSLogsRelayBufferSizeMB
+	}
+
+	if cfg.TaskContainerStartConcurrency < 0 {
+		cfg.reportProblem([]string{"ECS_TASK_CONTAINER_START_CONCURRENCY"}, "Invalid value for ECS_TASK_CONTAINER_START_CONCURRENCY, will be overridden with the default value: 0 (no limit). Parsed value: %d.", cfg.TaskContainerStartConcurrency)
+		cfg.TaskContainerStartConcurrency = 0
+	}
+
+	if cfg.ContainerShutdownGracePeriod < 0 {
+		cfg.reportProblem([]string{"ECS_CONTAINER_SHUTDOWN_GRACE_PERIOD"}, "Invalid value for ECS_CONTAINER_SHUTDOWN_GRACE_PERIOD, will be overridden with the default value: 0s. Parsed value: %v.", cfg.ContainerShutdownGracePeriod)
+		cfg.ContainerShutdownGracePeriod = 0
+	}
+
+	if cfg.ImagePullMaxAttempts < 1 {
+		cfg.reportProblem([]string{"ECS_IMAGE_PULL_MAX_ATTEMPTS"}, "Invalid value for ECS_IMAGE_PULL_MAX_ATTEMPTS, will be overridden with the default value: %d. Parsed value: %d, minimum value: 1.", DefaultImagePullMaxAttempts, cfg.ImagePullMaxAttempts)
+		cfg.ImagePullMaxAttempts = DefaultImagePullMaxAttempts
+	}
+
+	if cfg.ImagePullRetryBaseDelay <= 0 || cfg.ImagePullRetryMaxDelay < cfg.ImagePullRetryBaseDelay {
+		cfg.reportProblem([]string{"ECS_IMAGE_PULL_RETRY_BASE_DELAY", "ECS_IMAGE_PULL_RETRY_MAX_DELAY"}, "Invalid values for image pull retry delays, will be overridden with the default values: %s,%s. Parsed values: %v,%v.", DefaultImagePullRetryBaseDelay.String(), DefaultImagePullRetryMaxDelay.String(), cfg.ImagePullRetryBaseDelay, cfg.ImagePullRetryMaxDelay)
+		cfg.ImagePullRetryBaseDelay = DefaultImagePullRetryBaseDelay
+		cfg.ImagePullRetryMaxDelay = DefaultImagePullRetryMaxDelay
+	}
+
+	if cfg.ECRTokenRefreshWindow < minimumECRTokenRefreshWindow {
+		cfg.reportProblem([]string{"ECS_ECR_TOKEN_REFRESH_WINDOW"}, "Invalid value for ECS_ECR_TOKEN_REFRESH_WINDOW, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultECRTokenRefreshWindow.String(), cfg.ECRTokenRefreshWindow, minimumECRTokenRefreshWindow)
+		cfg.ECRTokenRefreshWindow = DefaultECRTokenRefreshWindow
+	}
+
+	if cfg.TaskCredentialsRefreshWindow < minimumTaskCredentialsRefreshWindow {
+		cfg.reportProblem([]string{"ECS_TASK_CREDENTIALS_REFRESH_WINDOW"}, "Invalid value for ECS_TASK_CREDENTIALS_REFRESH_WINDOW, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultTaskCredentialsRefreshWindow.String(), cfg.TaskCredentialsRefreshWindow, minimumTaskCredentialsRefreshWindow)
+		cfg.TaskCredentialsRefreshWindow = DefaultTaskCredentialsRefreshWindow
+	}
+
+	if cfg.InstanceAttributeRefreshInterval < minimumInstanceAttributeRefreshInterval {
+		cfg.reportProblem([]string{"ECS_INSTANCE_ATTRIBUTE_REFRESH_INTERVAL"}, "Invalid value for ECS_INSTANCE_ATTRIBUTE_REFRESH_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultInstanceAttributeRefreshInterval.String(), cfg.InstanceAttributeRefreshInterval, minimumInstanceAttributeRefreshInterval)
+		cfg.InstanceAttributeRefreshInterval = DefaultInstanceAttributeRefreshInterval
+	}
+
+	if cfg.ImageCleanupDiskHighWatermark != 0 && (cfg.ImageCleanupDiskHighWatermark > 100 ||
+		cfg.ImageCleanupDiskLowWatermark <= 0 || cfg.ImageCleanupDiskLowWatermark >= cfg.ImageCleanupDiskHighWatermark) {
+		cfg.reportProblem([]string{"ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", "ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK"}, "Invalid values for image cleanup disk watermarks, image cleanup based on disk usage will be disabled. Parsed values: high %d%%, low %d%%.", cfg.ImageCleanupDiskHighWatermark, cfg.ImageCleanupDiskLowWatermark)
+		cfg.ImageCleanupDiskHighWatermark = 0
+		cfg.ImageCleanupDiskLowWatermark = 0
+	}
+
+	if cfg.ImageCleanupDiskCheckInterval < minimumImageCleanupDiskCheckInterval {
+		cfg.reportProblem([]string{"ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL"}, "Invalid value for ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultImageCleanupDiskCheckInterval.String(), cfg.ImageCleanupDiskCheckInterval, minimumImageCleanupDiskCheckInterval)
+		cfg.ImageCleanupDiskCheckInterval = DefaultImageCleanupDiskCheckInterval
+	}
+
 	if cfg.NumImagesToDeletePerCycle < minimumNumImagesToDeletePerCycle {
-		seelog.Warnf("Invalid value for number of images to delete for image cleanup, will be overridden with the default value: %d. Parsed value: %d, minimum value: %d.", DefaultImageDeletionAge, cfg.NumImagesToDeletePerCycle, minimumNumImagesToDeletePerCycle)
+		cfg.reportProblem([]string{"ECS_NUM_IMAGES_DELETE_PER_CYCLE"}, "Invalid value for number of images to delete for image cleanup, will be overridden with the default value: %d. Parsed value: %d, minimum value: %d.", DefaultImageDeletionAge, cfg.NumImagesToDeletePerCycle, minimumNumImagesToDeletePerCycle)
 		cfg.NumImagesToDeletePerCycle = DefaultNumImagesToDeletePerCycle
 	}
 
 	if cfg.TaskMetadataSteadyStateRate <= 0 || cfg.TaskMetadataBurstRate <= 0 {
-		seelog.Warnf("Invalid values for rate limits, will be overridden with default values: %d,%d.", DefaultTaskMetadataSteadyStateRate, DefaultTaskMetadataBurstRate)
+		cfg.reportProblem([]string{"ECS_TASK_METADATA_RPS_LIMIT"}, "Invalid values for rate limits, will be overridden with default values: %d,%d.", DefaultTaskMetadataSteadyStateRate, DefaultTaskMetadataBurstRate)
 		cfg.TaskMetadataSteadyStateRate = DefaultTaskMetadataSteadyStateRate
 		cfg.TaskMetadataBurstRate = DefaultTaskMetadataBurstRate
 	}
 
+	if net.ParseIP(cfg.PrometheusMetricsBindAddress) == nil {
+		cfg.reportProblem([]string{"ECS_PROMETHEUS_METRICS_BIND_ADDRESS"}, "Invalid value for ECS_PROMETHEUS_METRICS_BIND_ADDRESS, will be overridden with the default value: %s. Parsed value: %s.", DefaultPrometheusMetricsBindAddress, cfg.PrometheusMetricsBindAddress)
+		cfg.PrometheusMetricsBindAddress = DefaultPrometheusMetricsBindAddress
+	}
+
+	cfg.checkConflictingOptions()
+
 	// check the PollMetrics specific configurations
 	cfg.pollMetricsOverrides()
 
@@ -369,15 +678,33 @@ func (cfg *Config) validateAndOverrideBounds() error {
 }
 
 func (cfg *Config) pollMetricsOverrides() {
+	// a collection interval with ECS_POLL_METRICS set to false is reported by checkConflictingOptions
+	if cfg.StatsCollectionInterval != 0 && cfg.PollMetrics.Value != ExplicitlyDisabled {
+		if cfg.StatsCollectionInterval < minimumStatsCollectionInterval {
+			cfg.reportProblem([]string{"ECS_STATS_COLLECTION_INTERVAL"}, "ECS_STATS_COLLECTION_INTERVAL parsed value (%s) is less than the minimum of %s. Setting collection interval to minimum.",
+				cfg.StatsCollectionInterval, minimumStatsCollectionInterval)
+			cfg.StatsCollectionInterval = minimumStatsCollectionInterval
+		}
+		if cfg.StatsCollectionInterval > maximumPollingMetricsWaitDuration {
+			cfg.reportProblem([]string{"ECS_STATS_COLLECTION_INTERVAL"}, "ECS_STATS_COLLECTION_INTERVAL parsed value (%s) is greater than the maximum of %s. Setting collection interval to maximum.",
+				cfg.StatsCollectionInterval, maximumPollingMetricsWaitDuration)
+			cfg.StatsCollectionInterval = maximumPollingMetricsWaitDuration
+		}
+		// the collection interval supersedes the polling wait duration
+		cfg.PollMetrics = BooleanDefaultFalse{Value: ExplicitlyEnabled}
+		cfg.PollingMetricsWaitDuration = cfg.StatsCollectionInterval
+		return
+	}
+
 	if cfg.PollMetrics.Enabled() {
 		if cfg.PollingMetricsWaitDuration < minimumPollingMetricsWaitDuration {
-			seelog.Warnf("ECS_POLLING_METRICS_WAIT_DURATION parsed value (%s) is less than the minimum of %s. Setting polling interval to minimum.",
+			cfg.reportProblem([]string{"ECS_POLLING_METRICS_WAIT_DURATION"}, "ECS_POLLING_METRICS_WAIT_DURATION parsed value (%s) is less than the minimum of %s. Setting polling interval to minimum.",
 				cfg.PollingMetricsWaitDuration, minimumPollingMetricsWaitDuration)
 			cfg.PollingMetricsWaitDuration = minimumPollingMetricsWaitDuration
 		}
 
 		if cfg.PollingMetricsWaitDuration > maximumPollingMetricsWaitDuration {
-			seelog.Warnf("ECS_POLLING_METRICS_WAIT_DURATION parsed value (%s) is greater than the maximum of %s. Setting polling interval to maximum.",
+			cfg.reportProblem([]string{"ECS_POLLING_METRICS_WAIT_DURATION"}, "ECS_POLLING_METRICS_WAIT_DURATION parsed value (%s) is greater than the maximum of %s. Setting polling interval to maximum.",
 				cfg.PollingMetricsWaitDuration, maximumPollingMetricsWaitDuration)
 			cfg.PollingMetricsWaitDuration = maximumPollingMetricsWaitDuration
 		}
@@ -518,78 +845,62 @@ func environmentConfig() (Config, error) {
 
 	additionalLocalRoutes, errs := parseAdditionalLocalRoutes(errs)
 
+	dockerCredentialHelpers, errs := parseDockerCredentialHelpers(errs)
+	imagePullRetryPolicies, errs := parseImagePullRetryPolicies(errs)
+	containerStopSignalSequence, errs := parseContainerStopSignalSequence(errs)
+	containerDefaultUlimits, errs := parseContainerDefaultUlimits(errs)
+
 	var err error
 	if len(errs) > 0 {
 		err = apierrors.NewMultiError(errs...)
 	}
-	return Config{
-		Cluster:                             os.Getenv("ECS_CLUSTER"),
-		APIEndpoint:                         os.Getenv("ECS_BACKEND_HOST"),
-		AWSRegion:                           os.Getenv("AWS_DEFAULT_REGION"),
-		DockerEndpoint:                      os.Getenv("DOCKER_HOST"),
+	cfg := Config{
 		ReservedPorts:                       parseReservedPorts("ECS_RESERVED_PORTS"),
 		ReservedPortsUDP:                    parseReservedPorts("ECS_RESERVED_PORTS_UDP"),
 		DataDir:                             dataDir,
 		Checkpoint:                          parseCheckpoint(dataDir),
-		EngineAuthType:                      os.Getenv("ECS_ENGINE_AUTH_TYPE"),
-		EngineAuthData:                      NewSensitiveRawMessage([]byte(os.Getenv("ECS_ENGINE_AUTH_DATA"))),
-		UpdatesEnabled:                      parseBooleanDefaultFalseConfig("ECS_UPDATES_ENABLED"),
-		UpdateDownloadDir:                   os.Getenv("ECS_UPDATE_DOWNLOAD_DIR"),
-		DisableMetrics:                      parseBooleanDefaultFalseConfig("ECS_DISABLE_METRICS"),
-		ReservedMemory:                      parseEnvVariableUint16("ECS_RESERVED_MEMORY"),
+		DockerCredentialHelpers:             dockerCredentialHelpers,
 		AvailableLoggingDrivers:             parseAvailableLoggingDrivers(),
-		PrivilegedDisabled:                  parseBooleanDefaultFalseConfig("ECS_DISABLE_PRIVILEGED"),
-		SELinuxCapable:                      parseBooleanDefaultFalseConfig("ECS_SELINUX_CAPABLE"),
-		AppArmorCapable:                     parseBooleanDefaultFalseConfig("ECS_APPARMOR_CAPABLE"),
-		TaskCleanupWaitDuration:             parseEnvVariableDuration("ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION"),
-		TaskENIEnabled:                      parseBooleanDefaultFalseConfig("ECS_ENABLE_TASK_ENI"),
-		TaskIAMRoleEnabled:                  parseBooleanDefaultFalseConfig("ECS_ENABLE_TASK_IAM_ROLE"),
-		DeleteNonECSImagesEnabled:           parseBooleanDefaultFalseConfig("ECS_ENABLE_UNTRACKED_IMAGE_CLEANUP"),
-		TaskCPUMemLimit:                     parseBooleanDefaultTrueConfig("ECS_ENABLE_TASK_CPU_MEM_LIMIT"),
 		DockerStopTimeout:                   parseDockerStopTimeout(),
+		ContainerStopSignalSequence:         containerStopSignalSequence,
+		ContainerDefaultUlimits:             containerDefaultUlimits,
 		ContainerStartTimeout:               parseContainerStartTimeout(),
 		ContainerCreateTimeout:              parseContainerCreateTimeout(),
-		DependentContainersPullUpfront:      parseBooleanDefaultFalseConfig("ECS_PULL_DEPENDENT_CONTAINERS_UPFRONT"),
+		TaskContainerStartConcurrency:       parseTaskContainerStartConcurrency(),
 		ImagePullInactivityTimeout:          parseImagePullInactivityTimeout(),
-		ImagePullTimeout:                    parseEnvVariableDuration("ECS_IMAGE_PULL_TIMEOUT"),
-		CredentialsAuditLogFile:             os.Getenv("ECS_AUDIT_LOGFILE"),
-		CredentialsAuditLogDisabled:         utils.ParseBool(os.Getenv("ECS_AUDIT_LOGFILE_DISABLED"), false),
-		TaskIAMRoleEnabledForNetworkHost:    utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_IAM_ROLE_NETWORK_HOST"), false),
-		ImageCleanupDisabled:                parseBooleanDefaultFalseConfig("ECS_DISABLE_IMAGE_CLEANUP"),
-		MinimumImageDeletionAge:             parseEnvVariableDuration("ECS_IMAGE_MINIMUM_CLEANUP_AGE"),
-		NonECSMinimumImageDeletionAge:       parseEnvVariableDuration("NON_ECS_IMAGE_MINIMUM_CLEANUP_AGE"),
-		ImageCleanupInterval:                parseEnvVariableDuration("ECS_IMAGE_CLEANUP_INTERVAL"),
+		ImagePullMaxBandwidthMbps:           parseImagePullMaxBandwidthMbps(),
+		ImagePullMaxAttempts:                parseImagePullMaxAttempts(),
+		ImagePullRetryPolicies:              imagePullRetryPolicies,
+		ImageCleanupDiskHighWatermark:       parseImageCleanupDiskWatermark("ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK"),
+		ImageCleanupDiskLowWatermark:        parseImageCleanupDiskWatermark("ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK"),
 		NumImagesToDeletePerCycle:           parseNumImagesToDeletePerCycle(),
 		NumNonECSContainersToDeletePerCycle: parseNumNonECSContainersToDeletePerCycle(),
 		ImagePullBehavior:                   parseImagePullBehavior(),
 		ImageCleanupExclusionList:           parseImageCleanupExclusionList("ECS_EXCLUDE_UNTRACKED_IMAGE"),
 		InstanceAttributes:                  instanceAttributes,
-		CNIPluginsPath:                      os.Getenv("ECS_CNI_PLUGINS_PATH"),
-		AWSVPCBlockInstanceMetdata:          parseBooleanDefaultFalseConfig("ECS_AWSVPC_BLOCK_IMDS"),
+		InstanceAttributeProviders:          parseInstanceAttributeProviders(),
 		AWSVPCAdditionalLocalRoutes:         additionalLocalRoutes,
-		ContainerMetadataEnabled:            parseBooleanDefaultFalseConfig("ECS_ENABLE_CONTAINER_METADATA"),
-		DataDirOnHost:                       os.Getenv("ECS_HOST_DATA_DIR"),
-		OverrideAWSLogsExecutionRole:        parseBooleanDefaultFalseConfig("ECS_ENABLE_AWSLOGS_EXECUTIONROLE_OVERRIDE"),
-		CgroupPath:                          os.Getenv("ECS_CGROUP_PATH"),
 		TaskMetadataSteadyStateRate:         steadyStateRate,
 		TaskMetadataBurstRate:               burstRate,
-		SharedVolumeMatchFullConfig:         parseBooleanDefaultFalseConfig("ECS_SHARED_VOLUME_MATCH_FULL_CONFIG"),
+		EncryptedVolumeSizeMB:               parseEncryptedVolumeSizeMB(),
+		TaskDNSCacheQueriesPerSecond:        parseTaskDNSCacheQueriesPerSecond(),
 		ContainerInstanceTags:               containerInstanceTags,
 		ContainerInstancePropagateTagsFrom:  parseContainerInstancePropagateTagsFrom(),
-		PollMetrics:                         parseBooleanDefaultFalseConfig("ECS_POLL_METRICS"),
-		PollingMetricsWaitDuration:          parseEnvVariableDuration("ECS_POLLING_METRICS_WAIT_DURATION"),
-		DisableDockerHealthCheck:            parseBooleanDefaultFalseConfig("ECS_DISABLE_DOCKER_HEALTH_CHECK"),
-		GPUSupportEnabled:                   utils.ParseBool(os.Getenv("ECS_ENABLE_GPU_SUPPORT"), false),
-		InferentiaSupportEnabled:            utils.ParseBool(os.Getenv("ECS_ENABLE_INF_SUPPORT"), false),
-		NvidiaRuntime:                       os.Getenv("ECS_NVIDIA_RUNTIME"),
-		TaskMetadataAZDisabled:              utils.ParseBool(os.Getenv("ECS_DISABLE_TASK_METADATA_AZ"), false),
+		TelemetryBufferSizeMB:               parseTelemetryBufferSizeMB(),
+		AWSLogsRelayBufferSizeMB:            parseAWSLogsRelayBufferSizeMB(),
+		ExecMaxSessionsPerContainer:         parseExecSessionLimit("ECS_EXEC_MAX_SESSIONS_PER_CONTAINER"),
+		ExecMaxSessionsPerTask:              parseExecSessionLimit("ECS_EXEC_MAX_SESSIONS_PER_TASK"),
+		MemoryPressurePolicy:                parseMemoryPressurePolicy(),
+		MemoryPressureStallThreshold:        parseMemoryPressureThreshold("ECS_MEMORY_PRESSURE_STALL_THRESHOLD"),
+		MemoryPressureUsageThreshold:        parseMemoryPressureThreshold("ECS_MEMORY_PRESSURE_USAGE_THRESHOLD"),
+		TaskMetadataNamedPipeEnabled:        parseTaskMetadataNamedPipeEnabled(),
 		CgroupCPUPeriod:                     parseCgroupCPUPeriod(),
-		SpotInstanceDrainingEnabled:         parseBooleanDefaultFalseConfig("ECS_ENABLE_SPOT_INSTANCE_DRAINING"),
 		GMSACapable:                         parseGMSACapability(),
 		VolumePluginCapabilities:            parseVolumePluginCapabilities(),
 		FSxWindowsFileServerCapable:         parseFSxWindowsFileServerCapability(),
-		External:                            parseBooleanDefaultFalseConfig("ECS_EXTERNAL"),
-	}, err
+	}
+	cfg.loadEnvVariables()
+	return cfg, err
 }
 
 func ec2MetadataConfig(ec2client ec2.EC2MetadataClient) Config {
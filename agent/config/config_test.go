@@ -27,6 +27,7 @@ import (
 	mock_ec2 "github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
 
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/docker/go-units"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -232,11 +233,27 @@ func TestConfigBoolean(t *testing.T) {
 	defer setTestEnv("ECS_DISABLE_DOCKER_HEALTH_CHECK", "true")()
 	defer setTestEnv("ECS_DISABLE_METRICS", "true")()
 	defer setTestEnv("ECS_ENABLE_SPOT_INSTANCE_DRAINING", "true")()
+	defer setTestEnv("ECS_WARM_POOLS_CHECK", "true")()
+	defer setTestEnv("ECS_SKIP_CLEANUP_AFTER_HIBERNATION", "true")()
+	defer setTestEnv("ECS_ENABLE_SPOT_REBALANCE_DRAINING", "true")()
+	defer setTestEnv("ECS_ENABLE_ASG_TERMINATION_DRAINING", "true")()
+	defer setTestEnv("ECS_DISABLE_TASK_PROTECTION_ON_INTERRUPTION", "true")()
+	defer setTestEnv("ECS_ENABLE_NUMA_PLACEMENT", "true")()
+	defer setTestEnv("ECS_ENABLE_PPROF", "true")()
+	defer setTestEnv("ECS_ENABLE_ECR_PUBLIC_AUTH", "true")()
 	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
 	assert.NoError(t, err)
 	assert.True(t, cfg.DisableMetrics.Enabled())
 	assert.True(t, cfg.DisableDockerHealthCheck.Enabled())
 	assert.True(t, cfg.SpotInstanceDrainingEnabled.Enabled())
+	assert.True(t, cfg.WarmPoolsCheck.Enabled())
+	assert.True(t, cfg.SkipCleanupAfterHibernation.Enabled())
+	assert.True(t, cfg.SpotRebalanceDrainingEnabled.Enabled())
+	assert.True(t, cfg.ASGTerminationDrainingEnabled.Enabled())
+	assert.True(t, cfg.DisableTaskProtectionOnInterruption.Enabled())
+	assert.True(t, cfg.NUMAPlacementEnabled.Enabled())
+	assert.True(t, cfg.PprofEnabled.Enabled())
+	assert.True(t, cfg.ECRPublicAuthEnabled.Enabled())
 }
 
 func TestBadLoggingDriverSerialization(t *testing.T) {
@@ -261,6 +278,158 @@ func TestBadTagsSerialization(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDockerCredentialHelpers(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_DOCKER_CREDENTIAL_HELPERS", `{"gcr.io": "gcr", "registry.example.com": "ecr-login"}`)()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"gcr.io":               "gcr",
+		"registry.example.com": "ecr-login",
+	}, conf.DockerCredentialHelpers)
+}
+
+func TestBadDockerCredentialHelpers(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_DOCKER_CREDENTIAL_HELPERS", "This is not valid JSON")()
+	_, err := environmentConfig()
+	assert.Error(t, err)
+}
+
+func TestDockerCredentialHelpersInvalidName(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_DOCKER_CREDENTIAL_HELPERS", `{"gcr.io": "gcr", "registry.example.com": "../../bin/sh"}`)()
+	conf, err := environmentConfig()
+	assert.Error(t, err)
+	assert.Equal(t, map[string]string{"gcr.io": "gcr"}, conf.DockerCredentialHelpers)
+}
+
+func TestImagePullRetryPolicies(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_RETRY_POLICIES",
+		`{"throttling": {"baseDelay": "5s", "maxDelay": "1m"}, "not-found": {"retry": false}}`)()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, map[ImagePullErrorClass]ImagePullRetryPolicy{
+		ImagePullErrorThrottling: {BaseDelay: 5 * time.Second, MaxDelay: time.Minute},
+		ImagePullErrorNotFound:   {NoRetry: true},
+	}, conf.ImagePullRetryPolicies)
+}
+
+func TestInvalidImagePullRetryPolicies(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_RETRY_POLICIES",
+		`{"timeout": {"retry": false}, "unknown": {"retry": false}, "throttling": {"baseDelay": "1m", "maxDelay": "5s"}}`)()
+	conf, err := environmentConfig()
+	assert.Error(t, err)
+	assert.Equal(t, map[ImagePullErrorClass]ImagePullRetryPolicy{
+		ImagePullErrorTimeout: {NoRetry: true},
+	}, conf.ImagePullRetryPolicies)
+}
+
+func TestContainerDefaultUlimits(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_DEFAULT_ULIMITS", "nofile=1024:4096, nproc=512")()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []*units.Ulimit{
+		{Name: "nofile", Soft: 1024, Hard: 4096},
+		{Name: "nproc", Soft: 512, Hard: 512},
+	}, conf.ContainerDefaultUlimits)
+}
+
+func TestInvalidContainerDefaultUlimits(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_DEFAULT_ULIMITS", "nofile=4096:1024,unknown=1,core=0,core=1")()
+	conf, err := environmentConfig()
+	assert.Error(t, err)
+	assert.Equal(t, []*units.Ulimit{{Name: "core", Soft: 0, Hard: 0}}, conf.ContainerDefaultUlimits)
+}
+
+func TestContainerStopSignalSequence(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_STOP_SIGNAL_SEQUENCE", "SIGUSR1:10s, sigint:500ms,SIGQUIT")()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []StopSignal{
+		{Signal: "SIGUSR1", Wait: 10 * time.Second},
+		{Signal: "SIGINT", Wait: 500 * time.Millisecond},
+		{Signal: "SIGQUIT"},
+	}, conf.ContainerStopSignalSequence)
+}
+
+func TestInvalidContainerStopSignalSequence(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_STOP_SIGNAL_SEQUENCE", "SIGUSR1:ten")()
+	conf, err := environmentConfig()
+	assert.Error(t, err)
+	assert.Empty(t, conf.ContainerStopSignalSequence)
+}
+
+func TestGMSACredentialSpecCacheTTL(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_GMSA_CREDENTIAL_SPEC_CACHE_TTL", "-1s")()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, -time.Second, conf.GMSACredentialSpecCacheTTL)
+}
+
+func TestSecretsCache(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRETS_CACHE_TTL", "5m")()
+	defer setTestEnv("ECS_SECRETS_FETCH_JITTER", "2s")()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, conf.SecretsCacheTTL)
+	assert.Equal(t, 2*time.Second, conf.SecretsFetchJitter)
+}
+
+func TestVault(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_VAULT_ADDR", "https://vault.example.com:8200")()
+	defer setTestEnv("ECS_VAULT_NAMESPACE", "team")()
+	defer setTestEnv("ECS_VAULT_AWS_AUTH_MOUNT", "aws-ecs")()
+	defer setTestEnv("ECS_VAULT_AWS_AUTH_ROLE", "ecs-task")()
+	defer setTestEnv("ECS_VAULT_AWS_IAM_SERVER_ID", "vault.example.com")()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://vault.example.com:8200", conf.VaultAddr)
+	assert.Equal(t, "team", conf.VaultNamespace)
+	assert.Equal(t, "aws-ecs", conf.VaultAWSAuthMount)
+	assert.Equal(t, "ecs-task", conf.VaultAWSAuthRole)
+	assert.Equal(t, "vault.example.com", conf.VaultAWSIAMServerID)
+}
+
+func TestReloadableConfigFile(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_RELOADABLE_CONFIG_FILE", "/etc/ecs/agent.config")()
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/ecs/agent.config", conf.ReloadableConfigFile)
+}
+
+func TestInstanceAttributeProviders(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_INSTANCE_ATTRIBUTE_PROVIDERS", `["kernel-version","nsenter"]`)()
+	defer setTestEnv("ECS_INSTANCE_ATTRIBUTE_PLUGINS_DIR", "/etc/ecs/attributes.d")()
+	defer setTestEnv("ECS_INSTANCE_ATTRIBUTE_REFRESH_INTERVAL", "5m")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kernel-version", "nsenter"}, cfg.InstanceAttributeProviders)
+	assert.Equal(t, "/etc/ecs/attributes.d", cfg.InstanceAttributePluginsDir)
+	assert.Equal(t, 5*time.Minute, cfg.InstanceAttributeRefreshInterval)
+}
+
+func TestInvalidInstanceAttributeRefreshInterval(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_INSTANCE_ATTRIBUTE_PROVIDERS", "kernel-version")()
+	defer setTestEnv("ECS_INSTANCE_ATTRIBUTE_REFRESH_INTERVAL", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.InstanceAttributeProviders)
+	assert.Equal(t, DefaultInstanceAttributeRefreshInterval, cfg.InstanceAttributeRefreshInterval)
+}
+
 func TestInvalidLoggingDriver(t *testing.T) {
 	conf := DefaultConfig()
 	conf.AWSRegion = "us-west-2"
@@ -442,6 +611,34 @@ func TestInvalidValuePollingMetricsWaitDuration(t *testing.T) {
 	assert.Equal(t, DefaultPollingMetricsWaitDuration, conf.PollingMetricsWaitDuration, "Wrong value for PollingMetricsWaitDuration")
 }
 
+func TestStatsCollectionInterval(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_STATS_COLLECTION_INTERVAL", "2s")()
+	conf, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, conf.PollMetrics.Enabled(), "Setting the collection interval should enable polling metrics")
+	assert.Equal(t, 2*time.Second, conf.PollingMetricsWaitDuration, "Wrong value for PollingMetricsWaitDuration")
+}
+
+func TestStatsCollectionIntervalOverridesPollingMetricsWaitDuration(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_POLL_METRICS", "true")()
+	defer setTestEnv("ECS_POLLING_METRICS_WAIT_DURATION", "15s")()
+	defer setTestEnv("ECS_STATS_COLLECTION_INTERVAL", "100ms")()
+	conf, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumStatsCollectionInterval, conf.PollingMetricsWaitDuration, "Wrong value for PollingMetricsWaitDuration")
+}
+
+func TestStatsCollectionIntervalIgnoredWhenStreaming(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_POLL_METRICS", "false")()
+	defer setTestEnv("ECS_STATS_COLLECTION_INTERVAL", "2s")()
+	conf, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, conf.PollMetrics.Enabled(), "Wrong value for PollMetrics")
+}
+
 func TestInvalidFormatParseEnvVariableUint16(t *testing.T) {
 	defer setTestRegion()()
 	setTestEnv("FOO", "foo")
@@ -497,6 +694,18 @@ func TestTaskCleanupTimeout(t *testing.T) {
 	assert.Equal(t, 10*time.Minute, cfg.TaskCleanupWaitDuration, "Task cleanup wait duration set incorrectly")
 }
 
+func TestStoppedTaskHistoryRetention(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultStoppedTaskHistoryRetention, cfg.StoppedTaskHistoryRetention)
+
+	defer setTestEnv("ECS_STOPPED_TASK_HISTORY_RETENTION", "30m")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, cfg.StoppedTaskHistoryRetention)
+}
+
 func TestInvalidReservedMemoryOverridesToZero(t *testing.T) {
 	defer setTestRegion()()
 	defer setTestEnv("ECS_RESERVED_MEMORY", "-1")()
@@ -548,6 +757,27 @@ func TestCredentialsAuditLogFile(t *testing.T) {
 	assert.Equal(t, dummyLocation, cfg.CredentialsAuditLogFile, "Wrong value for CredentialsAuditLogFile")
 }
 
+func TestCredentialsAuditJSONLogFile(t *testing.T) {
+	defer setTestRegion()()
+	dummyLocation := "/foo/bar.jsonl"
+	defer setTestEnv("ECS_AUDIT_JSON_LOGFILE", dummyLocation)()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, dummyLocation, cfg.CredentialsAuditJSONLogFile, "Wrong value for CredentialsAuditJSONLogFile")
+}
+
+func TestOTelExporterEndpoint(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.OTelExporterEndpoint, "Tracing should be disabled by default")
+
+	defer setTestEnv("ECS_OTEL_EXPORTER_ENDPOINT", "http://localhost:4318")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:4318", cfg.OTelExporterEndpoint, "Wrong value for OTelExporterEndpoint")
+}
+
 func TestCredentialsAuditLogDisabled(t *testing.T) {
 	defer setTestRegion()()
 	defer setTestEnv("ECS_AUDIT_LOGFILE_DISABLED", "true")()
@@ -564,6 +794,330 @@ func TestImageCleanupMinimumInterval(t *testing.T) {
 	assert.Equal(t, DefaultImageCleanupTimeInterval, cfg.ImageCleanupInterval, "Wrong value for ImageCleanupInterval")
 }
 
+func TestECRTokenRefreshWindow(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ECR_TOKEN_REFRESH_WINDOW", "3h")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Hour, cfg.ECRTokenRefreshWindow, "Wrong value for ECRTokenRefreshWindow")
+}
+
+func TestECRTokenRefreshMinimumWindow(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ECR_TOKEN_REFRESH_WINDOW", "10m")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultECRTokenRefreshWindow, cfg.ECRTokenRefreshWindow, "Wrong value for ECRTokenRefreshWindow")
+}
+
+func TestTaskCredentialsRefreshWindow(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_CREDENTIALS_REFRESH_WINDOW", "30m")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, cfg.TaskCredentialsRefreshWindow, "Wrong value for TaskCredentialsRefreshWindow")
+}
+
+func TestTaskCredentialsRefreshMinimumWindow(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_CREDENTIALS_REFRESH_WINDOW", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultTaskCredentialsRefreshWindow, cfg.TaskCredentialsRefreshWindow, "Wrong value for TaskCredentialsRefreshWindow")
+}
+
+func TestImagePullRetrySettings(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MAX_ATTEMPTS", "3")()
+	defer setTestEnv("ECS_IMAGE_PULL_RETRY_BASE_DELAY", "2s")()
+	defer setTestEnv("ECS_IMAGE_PULL_RETRY_MAX_DELAY", "30s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cfg.ImagePullMaxAttempts, "Wrong value for ImagePullMaxAttempts")
+	assert.Equal(t, 2*time.Second, cfg.ImagePullRetryBaseDelay, "Wrong value for ImagePullRetryBaseDelay")
+	assert.Equal(t, 30*time.Second, cfg.ImagePullRetryMaxDelay, "Wrong value for ImagePullRetryMaxDelay")
+}
+
+func TestTaskContainerStartConcurrency(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.TaskContainerStartConcurrency, "Container starts should not be limited by default")
+
+	defer setTestEnv("ECS_TASK_CONTAINER_START_CONCURRENCY", "3")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cfg.TaskContainerStartConcurrency, "Wrong value for TaskContainerStartConcurrency")
+}
+
+func TestInvalidTaskContainerStartConcurrency(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_CONTAINER_START_CONCURRENCY", "-2")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.TaskContainerStartConcurrency, "Wrong value for TaskContainerStartConcurrency")
+}
+
+func TestDependencyOrderedShutdown(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.DependencyOrderedShutdown.Enabled(), "Dependency ordered shutdown should be disabled by default")
+	assert.Zero(t, cfg.ContainerShutdownGracePeriod, "There should be no shutdown grace period by default")
+
+	defer setTestEnv("ECS_ENABLE_DEPENDENCY_ORDERED_SHUTDOWN", "true")()
+	defer setTestEnv("ECS_CONTAINER_SHUTDOWN_GRACE_PERIOD", "10s")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.DependencyOrderedShutdown.Enabled(), "Wrong value for DependencyOrderedShutdown")
+	assert.Equal(t, 10*time.Second, cfg.ContainerShutdownGracePeriod, "Wrong value for ContainerShutdownGracePeriod")
+}
+
+func TestInvalidContainerShutdownGracePeriod(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_SHUTDOWN_GRACE_PERIOD", "-10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.ContainerShutdownGracePeriod, "Wrong value for ContainerShutdownGracePeriod")
+}
+
+func TestTelemetryBufferSize(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.TelemetryBufferSizeMB, "Telemetry should not be buffered by default")
+
+	defer setTestEnv("ECS_TELEMETRY_BUFFER_SIZE_MB", "20")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 20, cfg.TelemetryBufferSizeMB, "Wrong value for TelemetryBufferSizeMB")
+}
+
+func TestInvalidTelemetryBufferSize(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TELEMETRY_BUFFER_SIZE_MB", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.TelemetryBufferSizeMB, "Wrong value for TelemetryBufferSizeMB")
+}
+
+func TestAWSLogsRelay(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.AWSLogsRelayEnabled.Enabled(), "The awslogs relay should be disabled by default")
+	assert.Equal(t, DefaultAWSLogsRelayBufferSizeMB, cfg.AWSLogsRelayBufferSizeMB)
+
+	defer setTestEnv("ECS_ENABLE_AWSLOGS_RELAY", "true")()
+	defer setTestEnv("ECS_AWSLOGS_RELAY_BUFFER_SIZE_MB", "500")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.AWSLogsRelayEnabled.Enabled())
+	assert.Equal(t, 500, cfg.AWSLogsRelayBufferSizeMB, "Wrong value for AWSLogsRelayBufferSizeMB")
+}
+
+func TestInvalidAWSLogsRelayBufferSize(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_AWSLOGS_RELAY_BUFFER_SIZE_MB", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultAWSLogsRelayBufferSizeMB, cfg.AWSLogsRelayBufferSizeMB)
+}
+
+func TestEphemeralStorageQuota(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.EphemeralStorageQuotaEnabled.Enabled(), "The ephemeral storage quota should be disabled by default")
+
+	defer setTestEnv("ECS_ENABLE_EPHEMERAL_STORAGE_QUOTA", "true")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.EphemeralStorageQuotaEnabled.Enabled())
+}
+
+func TestEphemeralVolumeEncryption(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.EphemeralVolumeEncryption.Enabled(), "Ephemeral volumes should not be encrypted by default")
+	assert.Equal(t, DefaultEncryptedVolumeSizeMB, cfg.EncryptedVolumeSizeMB)
+
+	defer setTestEnv("ECS_ENCRYPT_EPHEMERAL_VOLUMES", "true")()
+	defer setTestEnv("ECS_ENCRYPTED_VOLUME_SIZE_MB", "2048")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.EphemeralVolumeEncryption.Enabled())
+	assert.Equal(t, 2048, cfg.EncryptedVolumeSizeMB, "Wrong value for EncryptedVolumeSizeMB")
+}
+
+func TestInvalidEncryptedVolumeSize(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENCRYPTED_VOLUME_SIZE_MB", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultEncryptedVolumeSizeMB, cfg.EncryptedVolumeSizeMB)
+}
+
+func TestTaskNetworkPolicyFile(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.TaskNetworkPolicyFile)
+
+	defer setTestEnv("ECS_TASK_NETWORK_POLICY_FILE", "/etc/ecs/network-policy.json")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/ecs/network-policy.json", cfg.TaskNetworkPolicyFile)
+}
+
+func TestExecSessionAudit(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.ExecSessionAuditS3Bucket, "Exec sessions should not be recorded by default")
+	assert.False(t, cfg.ExecSessionAuditTranscriptsEnabled.Enabled())
+
+	defer setTestEnv("ECS_EXEC_SESSION_AUDIT_S3_BUCKET", "bucket")()
+	defer setTestEnv("ECS_EXEC_SESSION_AUDIT_S3_KEY_PREFIX", "audit")()
+	defer setTestEnv("ECS_EXEC_SESSION_AUDIT_KMS_KEY_ID", "key-id")()
+	defer setTestEnv("ECS_EXEC_SESSION_AUDIT_TRANSCRIPTS", "true")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "bucket", cfg.ExecSessionAuditS3Bucket)
+	assert.Equal(t, "audit", cfg.ExecSessionAuditS3KeyPrefix)
+	assert.Equal(t, "key-id", cfg.ExecSessionAuditKMSKeyID)
+	assert.True(t, cfg.ExecSessionAuditTranscriptsEnabled.Enabled())
+}
+
+func TestExecSessionLimits(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.ExecMaxSessionsPerContainer)
+	assert.Zero(t, cfg.ExecMaxSessionsPerTask)
+	assert.Zero(t, cfg.ExecSessionIdleTimeout, "Idle exec sessions should not be terminated by default")
+
+	defer setTestEnv("ECS_EXEC_MAX_SESSIONS_PER_CONTAINER", "1")()
+	defer setTestEnv("ECS_EXEC_MAX_SESSIONS_PER_TASK", "3")()
+	defer setTestEnv("ECS_EXEC_SESSION_IDLE_TIMEOUT", "20m")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cfg.ExecMaxSessionsPerContainer)
+	assert.Equal(t, 3, cfg.ExecMaxSessionsPerTask)
+	assert.Equal(t, 20*time.Minute, cfg.ExecSessionIdleTimeout)
+}
+
+func TestInvalidExecSessionLimit(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_EXEC_MAX_SESSIONS_PER_TASK", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.ExecMaxSessionsPerTask)
+}
+
+func TestContainerDiskUsagePollInterval(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.ContainerDiskUsagePollInterval, "Disk usage should not be measured by default")
+
+	defer setTestEnv("ECS_CONTAINER_DISK_USAGE_POLL_INTERVAL", "5m")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.ContainerDiskUsagePollInterval, "Wrong value for ContainerDiskUsagePollInterval")
+}
+
+func TestInvalidContainerDiskUsagePollInterval(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_DISK_USAGE_POLL_INTERVAL", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumContainerDiskUsagePollInterval, cfg.ContainerDiskUsagePollInterval,
+		"Wrong value for ContainerDiskUsagePollInterval")
+}
+
+func TestEFSMountHealthCheckInterval(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.EFSMountHealthCheckInterval, "EFS mounts should not be checked by default")
+
+	defer setTestEnv("ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL", "10s")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumEFSMountHealthCheckInterval, cfg.EFSMountHealthCheckInterval,
+		"Wrong value for EFSMountHealthCheckInterval")
+}
+
+func TestMemoryPressurePolicy(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, MemoryPressurePolicyNone, cfg.MemoryPressurePolicy, "Memory pressure should not be checked by default")
+	assert.Equal(t, DefaultMemoryPressureCheckInterval, cfg.MemoryPressureCheckInterval)
+
+	defer setTestEnv("ECS_MEMORY_PRESSURE_POLICY", "evict")()
+	defer setTestEnv("ECS_MEMORY_PRESSURE_CHECK_INTERVAL", "100ms")()
+	defer setTestEnv("ECS_MEMORY_PRESSURE_STALL_THRESHOLD", "25.5")()
+	defer setTestEnv("ECS_MEMORY_PRESSURE_USAGE_THRESHOLD", "150")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, MemoryPressurePolicyEvict, cfg.MemoryPressurePolicy)
+	assert.Equal(t, DefaultMemoryPressureCheckInterval, cfg.MemoryPressureCheckInterval,
+		"Wrong value for MemoryPressureCheckInterval")
+	assert.Equal(t, 25.5, cfg.MemoryPressureStallThreshold)
+	assert.Equal(t, float64(DefaultMemoryPressureUsageThreshold), cfg.MemoryPressureUsageThreshold,
+		"Wrong value for MemoryPressureUsageThreshold")
+}
+
+func TestInvalidMemoryPressurePolicy(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_MEMORY_PRESSURE_POLICY", "kill")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, MemoryPressurePolicyNone, cfg.MemoryPressurePolicy)
+}
+
+func TestImagePullMaxBandwidth(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MAX_BANDWIDTH_MBPS", "250.5")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 250.5, cfg.ImagePullMaxBandwidthMbps, "Wrong value for ImagePullMaxBandwidthMbps")
+}
+
+func TestInvalidImagePullMaxBandwidth(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MAX_BANDWIDTH_MBPS", "-10")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.ImagePullMaxBandwidthMbps, "Wrong value for ImagePullMaxBandwidthMbps")
+}
+
+func TestImagePullSOCILazyLoading(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.ImagePullSOCILazyLoading.Enabled(), "SOCI lazy loading should be disabled by default")
+
+	defer setTestEnv("ECS_IMAGE_PULL_SOCI_LAZY_LOADING", "true")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.ImagePullSOCILazyLoading.Enabled(), "Wrong value for ImagePullSOCILazyLoading")
+}
+
+func TestInvalidImagePullRetrySettings(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MAX_ATTEMPTS", "-1")()
+	defer setTestEnv("ECS_IMAGE_PULL_RETRY_MAX_DELAY", "100ms")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultImagePullMaxAttempts, cfg.ImagePullMaxAttempts, "Wrong value for ImagePullMaxAttempts")
+	assert.Equal(t, DefaultImagePullRetryBaseDelay, cfg.ImagePullRetryBaseDelay, "Wrong value for ImagePullRetryBaseDelay")
+	assert.Equal(t, DefaultImagePullRetryMaxDelay, cfg.ImagePullRetryMaxDelay, "Wrong value for ImagePullRetryMaxDelay")
+}
+
 func TestImageCleanupMinimumNumImagesToDeletePerCycle(t *testing.T) {
 	defer setTestRegion()()
 	defer setTestEnv("ECS_NUM_IMAGES_DELETE_PER_CYCLE", "-1")()
@@ -572,6 +1126,70 @@ func TestImageCleanupMinimumNumImagesToDeletePerCycle(t *testing.T) {
 	assert.Equal(t, DefaultNumImagesToDeletePerCycle, cfg.NumImagesToDeletePerCycle, "Wrong value for NumImagesToDeletePerCycle")
 }
 
+func TestImageCleanupDiskWatermarks(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", "85")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK", "70")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 85, cfg.ImageCleanupDiskHighWatermark, "Wrong value for ImageCleanupDiskHighWatermark")
+	assert.Equal(t, 70, cfg.ImageCleanupDiskLowWatermark, "Wrong value for ImageCleanupDiskLowWatermark")
+	assert.Equal(t, DefaultImageCleanupDiskCheckInterval, cfg.ImageCleanupDiskCheckInterval, "Wrong value for ImageCleanupDiskCheckInterval")
+}
+
+func TestImageCleanupDiskCheckInterval(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL", "30s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.ImageCleanupDiskCheckInterval, "Wrong value for ImageCleanupDiskCheckInterval")
+}
+
+func TestInvalidImageCleanupDiskCheckInterval(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL", "1s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultImageCleanupDiskCheckInterval, cfg.ImageCleanupDiskCheckInterval, "Wrong value for ImageCleanupDiskCheckInterval")
+}
+
+func TestPrometheusMetricsBindAddress(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_PROMETHEUS_METRICS_BIND_ADDRESS", "0.0.0.0")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "0.0.0.0", cfg.PrometheusMetricsBindAddress, "Wrong value for PrometheusMetricsBindAddress")
+}
+
+func TestDefaultPrometheusMetricsBindAddress(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPrometheusMetricsBindAddress, cfg.PrometheusMetricsBindAddress, "Wrong value for PrometheusMetricsBindAddress")
+}
+
+func TestInvalidPrometheusMetricsBindAddress(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_PROMETHEUS_METRICS_BIND_ADDRESS", "localhost")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPrometheusMetricsBindAddress, cfg.PrometheusMetricsBindAddress, "Wrong value for PrometheusMetricsBindAddress")
+}
+
+func TestInvalidImageCleanupDiskWatermarks(t *testing.T) {
+	for _, watermarks := range [][2]string{{"85", ""}, {"70", "85"}, {"120", "70"}} {
+		t.Run(watermarks[0]+"-"+watermarks[1], func(t *testing.T) {
+			defer setTestRegion()()
+			defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", watermarks[0])()
+			defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK", watermarks[1])()
+			cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+			assert.NoError(t, err)
+			assert.Zero(t, cfg.ImageCleanupDiskHighWatermark, "Wrong value for ImageCleanupDiskHighWatermark")
+			assert.Zero(t, cfg.ImageCleanupDiskLowWatermark, "Wrong value for ImageCleanupDiskLowWatermark")
+		})
+	}
+}
+
 func TestInvalidImagePullBehavior(t *testing.T) {
 	defer setTestRegion()()
 	defer setTestEnv("ECS_IMAGE_PULL_BEHAVIOR", "invalid")()
@@ -647,6 +1265,22 @@ func TestAWSVPCBlockInstanceMetadata(t *testing.T) {
 	assert.True(t, cfg.AWSVPCBlockInstanceMetdata.Enabled())
 }
 
+func TestAWSVPCContainerNetworkStats(t *testing.T) {
+	defer setTestEnv("ECS_ENABLE_AWSVPC_CONTAINER_NETWORK_STATS", "true")()
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.AWSVPCContainerNetworkStats.Enabled())
+}
+
+func TestGPUMetricsEnabled(t *testing.T) {
+	defer setTestEnv("ECS_ENABLE_GPU_METRICS", "true")()
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.GPUMetricsEnabled.Enabled())
+}
+
 func TestInvalidAWSVPCAdditionalLocalRoutes(t *testing.T) {
 	os.Setenv("ECS_AWSVPC_ADDITIONAL_LOCAL_ROUTES", `["300.300.300.300/64"]`)
 	defer os.Unsetenv("ECS_AWSVPC_ADDITIONAL_LOCAL_ROUTES")
@@ -869,3 +1503,72 @@ func setTestEnv(k, v string) func() {
 		os.Unsetenv(k)
 	}
 }
+
+func TestDataCompactionInterval(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultDataCompactionInterval, cfg.DataCompactionInterval)
+
+	defer setTestEnv("ECS_DATA_COMPACTION_INTERVAL", "1m")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumDataCompactionInterval, cfg.DataCompactionInterval)
+}
+
+func TestDataCompactionIntervalDisabled(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_DATA_COMPACTION_INTERVAL", "-1s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, -time.Second, cfg.DataCompactionInterval)
+}
+
+func TestDataBackend(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DataBackendBoltDB, cfg.DataBackend)
+
+	defer setTestEnv("ECS_DATA_BACKEND", "file")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DataBackendFile, cfg.DataBackend)
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_LIFECYCLE_HOOKS_DIR", "/opt/hooks.d")()
+	defer setTestEnv("ECS_LIFECYCLE_HOOK_TIMEOUT", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/hooks.d", cfg.LifecycleHooksDir)
+	assert.Equal(t, 10*time.Second, cfg.LifecycleHookTimeout)
+}
+
+func TestSecurityProfiles(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECURITY_PROFILES_DIR", "/opt/profiles")()
+	defer setTestEnv("ECS_SECURITY_PROFILES_S3_ARN", "arn:aws:s3:::bucket/profiles")()
+	defer setTestEnv("ECS_SECURITY_PROFILES_CACHE_TTL", "-1s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/profiles", cfg.SecurityProfilesDir)
+	assert.Equal(t, "arn:aws:s3:::bucket/profiles", cfg.SecurityProfilesS3ARN)
+	assert.Equal(t, -time.Second, cfg.SecurityProfilesCacheTTL)
+}
+
+func TestInvalidLifecycleHookTimeout(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_LIFECYCLE_HOOK_TIMEOUT", "-10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultLifecycleHookTimeout, cfg.LifecycleHookTimeout)
+}
+
+func TestInvalidDataBackend(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_DATA_BACKEND", "mysql")()
+	_, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.Error(t, err)
+}
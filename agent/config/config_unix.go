@@ -58,18 +58,43 @@ func DefaultConfig() Config {
 		ReservedMemory:                      0,
 		AvailableLoggingDrivers:             []dockerclient.LoggingDriver{dockerclient.JSONFileDriver, dockerclient.NoneDriver},
 		TaskCleanupWaitDuration:             DefaultTaskCleanupWaitDuration,
+		StoppedTaskHistoryRetention:         DefaultStoppedTaskHistoryRetention,
+		DataCompactionInterval:              DefaultDataCompactionInterval,
+		DataBackend:                         DataBackendBoltDB,
+		SecurityProfilesDir:                 "/etc/ecs/security-profiles",
+		ReloadableConfigFile:                "/etc/ecs/ecs.config",
+		SecurityProfilesCacheTTL:            DefaultSecurityProfilesCacheTTL,
+		LifecycleHookTimeout:                DefaultLifecycleHookTimeout,
 		DockerStopTimeout:                   defaultDockerStopTimeout,
 		ContainerStartTimeout:               defaultContainerStartTimeout,
 		ContainerCreateTimeout:              defaultContainerCreateTimeout,
+		ContainerCheckpointInterval:         DefaultContainerCheckpointInterval,
+		FirelensConfigReloadInterval:        DefaultFirelensConfigReloadInterval,
+		SecretsRefreshSignal:                DefaultSecretsRefreshSignal,
+		MemoryPressureCheckInterval:         DefaultMemoryPressureCheckInterval,
+		MemoryPressureStallThreshold:        DefaultMemoryPressureStallThreshold,
+		MemoryPressureUsageThreshold:        DefaultMemoryPressureUsageThreshold,
 		DependentContainersPullUpfront:      BooleanDefaultFalse{Value: ExplicitlyDisabled},
+		AWSLogsRelayBufferSizeMB:            DefaultAWSLogsRelayBufferSizeMB,
+		EncryptedVolumeSizeMB:               DefaultEncryptedVolumeSizeMB,
+		TaskDNSCacheQueriesPerSecond:        DefaultTaskDNSCacheQueriesPerSecond,
 		CredentialsAuditLogFile:             defaultCredentialsAuditLogFile,
 		CredentialsAuditLogDisabled:         false,
 		ImageCleanupDisabled:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
 		MinimumImageDeletionAge:             DefaultImageDeletionAge,
 		NonECSMinimumImageDeletionAge:       DefaultNonECSImageDeletionAge,
 		ImageCleanupInterval:                DefaultImageCleanupTimeInterval,
+		ImageCleanupDiskCheckInterval:       DefaultImageCleanupDiskCheckInterval,
 		ImagePullInactivityTimeout:          defaultImagePullInactivityTimeout,
 		ImagePullTimeout:                    DefaultImagePullTimeout,
+		ImagePullMaxAttempts:                DefaultImagePullMaxAttempts,
+		ImagePullRetryBaseDelay:             DefaultImagePullRetryBaseDelay,
+		ImagePullRetryMaxDelay:              DefaultImagePullRetryMaxDelay,
+		ECRTokenRefreshWindow:               DefaultECRTokenRefreshWindow,
+		TaskCredentialsRefreshWindow:        DefaultTaskCredentialsRefreshWindow,
+		InstanceAttributeRefreshInterval:    DefaultInstanceAttributeRefreshInterval,
+		PersistECRTokenCache:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
+		ECRPublicAuthEnabled:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
 		NumImagesToDeletePerCycle:           DefaultNumImagesToDeletePerCycle,
 		NumNonECSContainersToDeletePerCycle: DefaultNumNonECSContainersToDeletePerCycle,
 		CNIPluginsPath:                      defaultCNIPluginsPath,
@@ -82,6 +107,7 @@ func DefaultConfig() Config {
 		CgroupPath:                          defaultCgroupPath,
 		TaskMetadataSteadyStateRate:         DefaultTaskMetadataSteadyStateRate,
 		TaskMetadataBurstRate:               DefaultTaskMetadataBurstRate,
+		PrometheusMetricsBindAddress:        DefaultPrometheusMetricsBindAddress,
 		SharedVolumeMatchFullConfig:         BooleanDefaultFalse{Value: ExplicitlyDisabled}, // only requiring shared volumes to match on name, which is default docker behavior
 		ContainerInstancePropagateTagsFrom:  ContainerInstancePropagateTagsFromNoneType,
 		PrometheusMetricsEnabled:            false,
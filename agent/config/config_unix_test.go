@@ -61,6 +61,7 @@ func TestConfigDefault(t *testing.T) {
 	assert.Equal(t, DefaultNumImagesToDeletePerCycle, cfg.NumImagesToDeletePerCycle, "NumImagesToDeletePerCycle default is set incorrectly")
 	assert.Equal(t, defaultCNIPluginsPath, cfg.CNIPluginsPath, "CNIPluginsPath default is set incorrectly")
 	assert.False(t, cfg.AWSVPCBlockInstanceMetdata.Enabled(), "AWSVPCBlockInstanceMetdata default is incorrectly set")
+	assert.False(t, cfg.AWSVPCContainerNetworkStats.Enabled(), "AWSVPCContainerNetworkStats default is incorrectly set")
 	assert.Equal(t, "/var/lib/ecs", cfg.DataDirOnHost, "Default DataDirOnHost set incorrectly")
 	assert.Equal(t, DefaultTaskMetadataSteadyStateRate, cfg.TaskMetadataSteadyStateRate,
 		"Default TaskMetadataSteadyStateRate is set incorrectly")
@@ -71,6 +72,9 @@ func TestConfigDefault(t *testing.T) {
 	assert.Equal(t, DefaultImagePullTimeout, cfg.ImagePullTimeout, "Default ImagePullTimeout set incorrectly")
 	assert.False(t, cfg.DependentContainersPullUpfront.Enabled(), "Default DependentContainersPullUpfront set incorrectly")
 	assert.False(t, cfg.PollMetrics.Enabled(), "ECS_POLL_METRICS default should be false")
+	assert.Equal(t, "/etc/ecs/security-profiles", cfg.SecurityProfilesDir, "Default SecurityProfilesDir set incorrectly")
+	assert.Equal(t, "/etc/ecs/ecs.config", cfg.ReloadableConfigFile, "Default ReloadableConfigFile set incorrectly")
+	assert.Equal(t, DefaultSecurityProfilesCacheTTL, cfg.SecurityProfilesCacheTTL, "Default SecurityProfilesCacheTTL set incorrectly")
 }
 
 // TestConfigFromFile tests the configuration can be read from file
@@ -239,6 +243,18 @@ func TestEmptyNvidiaRuntime(t *testing.T) {
 	assert.Equal(t, DefaultNvidiaRuntime, cfg.NvidiaRuntime, "Wrong value for NvidiaRuntime")
 }
 
+func TestTaskInitProcessEnabled(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	require.NoError(t, err)
+	assert.False(t, cfg.TaskInitProcessEnabled.Enabled())
+
+	defer setTestEnv("ECS_ENABLE_TASK_INIT_PROCESS", "true")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	require.NoError(t, err)
+	assert.True(t, cfg.TaskInitProcessEnabled.Enabled())
+}
+
 func TestCPUPeriodSettings(t *testing.T) {
 	cases := []struct {
 		Name     string
@@ -280,3 +296,89 @@ func TestCPUPeriodSettings(t *testing.T) {
 		})
 	}
 }
+
+func TestContainerCheckpointConfig(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultContainerCheckpointInterval, cfg.ContainerCheckpointInterval)
+	assert.Empty(t, cfg.ContainerCheckpointDir)
+
+	defer setTestEnv("ECS_CONTAINER_CHECKPOINT_INTERVAL", "30m")()
+	defer setTestEnv("ECS_CONTAINER_CHECKPOINT_DIR", "/mnt/checkpoints")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, cfg.ContainerCheckpointInterval)
+	assert.Equal(t, "/mnt/checkpoints", cfg.ContainerCheckpointDir)
+}
+
+func TestInvalidContainerCheckpointInterval(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_CHECKPOINT_INTERVAL", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultContainerCheckpointInterval, cfg.ContainerCheckpointInterval)
+}
+
+func TestFirelensConfigReloadInterval(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultFirelensConfigReloadInterval, cfg.FirelensConfigReloadInterval)
+
+	defer setTestEnv("ECS_FIRELENS_CONFIG_RELOAD_INTERVAL", "10m")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, cfg.FirelensConfigReloadInterval)
+}
+
+func TestInvalidFirelensConfigReloadInterval(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_FIRELENS_CONFIG_RELOAD_INTERVAL", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultFirelensConfigReloadInterval, cfg.FirelensConfigReloadInterval)
+}
+
+func TestSecretsRefresh(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.SecretsRefreshInterval)
+	assert.Equal(t, DefaultSecretsRefreshSignal, cfg.SecretsRefreshSignal)
+
+	defer setTestEnv("ECS_SECRETS_REFRESH_INTERVAL", "10s")()
+	defer setTestEnv("ECS_SECRETS_REFRESH_SIGNAL", "SIGUSR1")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumSecretsRefreshInterval, cfg.SecretsRefreshInterval)
+	assert.Equal(t, "SIGUSR1", cfg.SecretsRefreshSignal)
+}
+
+func TestTaskDNSCacheQueriesPerSecond(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultTaskDNSCacheQueriesPerSecond, cfg.TaskDNSCacheQueriesPerSecond)
+
+	defer setTestEnv("ECS_TASK_DNS_CACHE_QUERIES_PER_SECOND", "100")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 100, cfg.TaskDNSCacheQueriesPerSecond)
+}
+
+func TestInvalidTaskDNSCacheQueriesPerSecond(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_DNS_CACHE_QUERIES_PER_SECOND", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultTaskDNSCacheQueriesPerSecond, cfg.TaskDNSCacheQueriesPerSecond)
+}
+
+func TestTaskMetadataNamedPipeIgnored(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENABLE_TASK_METADATA_NAMED_PIPE", "true")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.TaskMetadataNamedPipeEnabled.Enabled(), "named pipes are only supported on Windows")
+}
@@ -103,18 +103,36 @@ func DefaultConfig() Config {
 		ReservedMemory:                      0,
 		AvailableLoggingDrivers:             []dockerclient.LoggingDriver{dockerclient.JSONFileDriver, dockerclient.NoneDriver, dockerclient.AWSLogsDriver},
 		TaskCleanupWaitDuration:             DefaultTaskCleanupWaitDuration,
+		StoppedTaskHistoryRetention:         DefaultStoppedTaskHistoryRetention,
+		DataCompactionInterval:              DefaultDataCompactionInterval,
+		DataBackend:                         DataBackendBoltDB,
+		LifecycleHookTimeout:                DefaultLifecycleHookTimeout,
 		DockerStopTimeout:                   defaultDockerStopTimeout,
 		ContainerStartTimeout:               defaultContainerStartTimeout,
 		ContainerCreateTimeout:              defaultContainerCreateTimeout,
 		DependentContainersPullUpfront:      BooleanDefaultFalse{Value: ExplicitlyDisabled},
+		AWSLogsRelayBufferSizeMB:            DefaultAWSLogsRelayBufferSizeMB,
+		EncryptedVolumeSizeMB:               DefaultEncryptedVolumeSizeMB,
+		MemoryPressureCheckInterval:         DefaultMemoryPressureCheckInterval,
+		MemoryPressureStallThreshold:        DefaultMemoryPressureStallThreshold,
+		MemoryPressureUsageThreshold:        DefaultMemoryPressureUsageThreshold,
 		ImagePullInactivityTimeout:          defaultImagePullInactivityTimeout,
 		ImagePullTimeout:                    DefaultImagePullTimeout,
+		ImagePullMaxAttempts:                DefaultImagePullMaxAttempts,
+		ImagePullRetryBaseDelay:             DefaultImagePullRetryBaseDelay,
+		ImagePullRetryMaxDelay:              DefaultImagePullRetryMaxDelay,
+		ECRTokenRefreshWindow:               DefaultECRTokenRefreshWindow,
+		TaskCredentialsRefreshWindow:        DefaultTaskCredentialsRefreshWindow,
+		InstanceAttributeRefreshInterval:    DefaultInstanceAttributeRefreshInterval,
+		PersistECRTokenCache:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
+		ECRPublicAuthEnabled:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
 		CredentialsAuditLogFile:             filepath.Join(ecsRoot, defaultCredentialsAuditLogFile),
 		CredentialsAuditLogDisabled:         false,
 		ImageCleanupDisabled:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
 		MinimumImageDeletionAge:             DefaultImageDeletionAge,
 		NonECSMinimumImageDeletionAge:       DefaultNonECSImageDeletionAge,
 		ImageCleanupInterval:                DefaultImageCleanupTimeInterval,
+		ImageCleanupDiskCheckInterval:       DefaultImageCleanupDiskCheckInterval,
 		NumImagesToDeletePerCycle:           DefaultNumImagesToDeletePerCycle,
 		NumNonECSContainersToDeletePerCycle: DefaultNumNonECSContainersToDeletePerCycle,
 		ContainerMetadataEnabled:            BooleanDefaultFalse{Value: ExplicitlyDisabled},
@@ -122,10 +140,12 @@ func DefaultConfig() Config {
 		PlatformVariables:                   platformVariables,
 		TaskMetadataSteadyStateRate:         DefaultTaskMetadataSteadyStateRate,
 		TaskMetadataBurstRate:               DefaultTaskMetadataBurstRate,
+		PrometheusMetricsBindAddress:        DefaultPrometheusMetricsBindAddress,
 		SharedVolumeMatchFullConfig:         BooleanDefaultFalse{Value: ExplicitlyDisabled}, //only requiring shared volumes to match on name, which is default docker behavior
 		PollMetrics:                         BooleanDefaultFalse{Value: NotSet},
 		PollingMetricsWaitDuration:          DefaultPollingMetricsWaitDuration,
 		GMSACapable:                         true,
+		GMSACredentialSpecCacheTTL:          DefaultGMSACredentialSpecCacheTTL,
 		FSxWindowsFileServerCapable:         true,
 		PauseContainerImageName:             DefaultPauseContainerImageName,
 		PauseContainerTag:                   DefaultPauseContainerTag,
@@ -146,6 +166,12 @@ func (cfg *Config) platformOverrides() {
 	// ensure TaskResourceLimit is disabled
 	cfg.TaskCPUMemLimit.Value = ExplicitlyDisabled
 
+	// docker doesn't support an init process in Windows containers
+	cfg.TaskInitProcessEnabled.Value = ExplicitlyDisabled
+
+	// ulimits don't apply to Windows containers
+	cfg.ContainerDefaultUlimits = nil
+
 	cpuUnbounded := parseBooleanDefaultFalseConfig("ECS_ENABLE_CPU_UNBOUNDED_WINDOWS_WORKAROUND")
 	memoryUnbounded := parseBooleanDefaultFalseConfig("ECS_ENABLE_MEMORY_UNBOUNDED_WINDOWS_WORKAROUND")
 
@@ -68,6 +68,7 @@ func TestConfigDefault(t *testing.T) {
 	assert.False(t, cfg.SharedVolumeMatchFullConfig.Enabled(), "Default SharedVolumeMatchFullConfig set incorrectly")
 	assert.Equal(t, DefaultImagePullTimeout, cfg.ImagePullTimeout, "Default ImagePullTimeout set incorrectly")
 	assert.False(t, cfg.DependentContainersPullUpfront.Enabled(), "Default DependentContainersPullUpfront set incorrectly")
+	assert.Equal(t, DefaultGMSACredentialSpecCacheTTL, cfg.GMSACredentialSpecCacheTTL, "Default GMSACredentialSpecCacheTTL set incorrectly")
 }
 
 func TestConfigIAMTaskRolesReserves80(t *testing.T) {
@@ -106,6 +107,14 @@ func TestTaskResourceLimitPlatformOverrideDisabled(t *testing.T) {
 	assert.False(t, cfg.TaskCPUMemLimit.Enabled())
 }
 
+func TestTaskInitProcessPlatformOverrideDisabled(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENABLE_TASK_INIT_PROCESS", "true")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.TaskInitProcessEnabled.Enabled())
+}
+
 func TestCPUUnboundedSet(t *testing.T) {
 	defer setTestRegion()()
 	defer setTestEnv("ECS_ENABLE_CPU_UNBOUNDED_WINDOWS_WORKAROUND", "true")()
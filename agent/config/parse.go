@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -25,8 +26,12 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	"github.com/cihub/seelog"
 	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/docker/go-units"
 )
 
+// credentialHelperNameRegex matches the names of docker credential helpers
+var credentialHelperNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
 func parseCheckpoint(dataDir string) BooleanDefaultFalse {
 	checkPoint := parseBooleanDefaultFalseConfig("ECS_CHECKPOINT")
 	if dataDir != "" {
@@ -101,6 +106,113 @@ func parseContainerCreateTimeout() time.Duration {
 	return containerCreateTimeout
 }
 
+func parseTaskContainerStartConcurrency() int {
+	concurrencyEnvVal := os.Getenv("ECS_TASK_CONTAINER_START_CONCURRENCY")
+	concurrency, err := strconv.Atoi(concurrencyEnvVal)
+	if concurrencyEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_TASK_CONTAINER_START_CONCURRENCY\", expected an integer. err %v", err)
+	}
+	return concurrency
+}
+
+func parseTelemetryBufferSizeMB() int {
+	bufferSizeEnvVal := os.Getenv("ECS_TELEMETRY_BUFFER_SIZE_MB")
+	bufferSize, err := strconv.Atoi(bufferSizeEnvVal)
+	if bufferSizeEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_TELEMETRY_BUFFER_SIZE_MB\", expected an integer. err %v", err)
+	}
+	return bufferSize
+}
+
+func parseAWSLogsRelayBufferSizeMB() int {
+	bufferSizeEnvVal := os.Getenv("ECS_AWSLOGS_RELAY_BUFFER_SIZE_MB")
+	bufferSize, err := strconv.Atoi(bufferSizeEnvVal)
+	if bufferSizeEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_AWSLOGS_RELAY_BUFFER_SIZE_MB\", expected an integer. err %v", err)
+	}
+	return bufferSize
+}
+
+func parseEncryptedVolumeSizeMB() int {
+	sizeEnvVal := os.Getenv("ECS_ENCRYPTED_VOLUME_SIZE_MB")
+	size, err := strconv.Atoi(sizeEnvVal)
+	if sizeEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_ENCRYPTED_VOLUME_SIZE_MB\", expected an integer. err %v", err)
+	}
+	return size
+}
+
+func parseTaskDNSCacheQueriesPerSecond() int {
+	qpsEnvVal := os.Getenv("ECS_TASK_DNS_CACHE_QUERIES_PER_SECOND")
+	qps, err := strconv.Atoi(qpsEnvVal)
+	if qpsEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_TASK_DNS_CACHE_QUERIES_PER_SECOND\", expected an integer. err %v", err)
+	}
+	return qps
+}
+
+func parseExecSessionLimit(envVar string) int {
+	limitEnvVal := os.Getenv(envVar)
+	limit, err := strconv.Atoi(limitEnvVal)
+	if limitEnvVal != "" && (err != nil || limit < 0) {
+		seelog.Warnf("Invalid format for \"%s\", expected a non-negative integer. err %v", envVar, err)
+		return 0
+	}
+	return limit
+}
+
+// parseContainerStopSignalSequence parses a comma separated list of signal:wait
+// steps, such as "SIGUSR1:10s,SIGINT:5s"
+func parseContainerStopSignalSequence(errs []error) ([]StopSignal, []error) {
+	sequenceEnv := os.Getenv("ECS_CONTAINER_STOP_SIGNAL_SEQUENCE")
+	if sequenceEnv == "" {
+		return nil, errs
+	}
+	var sequence []StopSignal
+	for _, step := range strings.Split(sequenceEnv, ",") {
+		parts := strings.SplitN(strings.TrimSpace(step), ":", 2)
+		signal := strings.ToUpper(strings.TrimSpace(parts[0]))
+		var wait time.Duration
+		var err error
+		if signal == "" {
+			err = fmt.Errorf("missing signal")
+		} else if len(parts) == 2 {
+			wait, err = parseOptionalDuration(strings.TrimSpace(parts[1]))
+		}
+		if err != nil {
+			wrappedErr := fmt.Errorf("Invalid step %q in ECS_CONTAINER_STOP_SIGNAL_SEQUENCE. Expected signal:wait: %v", step, err)
+			seelog.Error(wrappedErr)
+			return nil, append(errs, wrappedErr)
+		}
+		sequence = append(sequence, StopSignal{Signal: signal, Wait: wait})
+	}
+	return sequence, errs
+}
+
+func parseContainerDefaultUlimits(errs []error) ([]*units.Ulimit, []error) {
+	ulimitsEnv := os.Getenv("ECS_CONTAINER_DEFAULT_ULIMITS")
+	if ulimitsEnv == "" {
+		return nil, errs
+	}
+	var ulimits []*units.Ulimit
+	names := make(map[string]bool)
+	for _, value := range strings.Split(ulimitsEnv, ",") {
+		ulimit, err := units.ParseUlimit(strings.TrimSpace(value))
+		if err == nil && names[ulimit.Name] {
+			err = fmt.Errorf("duplicate ulimit %s", ulimit.Name)
+		}
+		if err != nil {
+			wrappedErr := fmt.Errorf("Invalid ulimit %q in ECS_CONTAINER_DEFAULT_ULIMITS. Expected name=soft[:hard]: %v", value, err)
+			seelog.Error(wrappedErr)
+			errs = append(errs, wrappedErr)
+			continue
+		}
+		names[ulimit.Name] = true
+		ulimits = append(ulimits, ulimit)
+	}
+	return ulimits, errs
+}
+
 func parseImagePullInactivityTimeout() time.Duration {
 	var imagePullInactivityTimeout time.Duration
 	parsedImagePullInactivityTimeout := parseEnvVariableDuration("ECS_IMAGE_PULL_INACTIVITY_TIMEOUT")
@@ -144,6 +256,19 @@ func parseVolumePluginCapabilities() []string {
 	return caps
 }
 
+func parseInstanceAttributeProviders() []string {
+	providersFromEnv := os.Getenv("ECS_INSTANCE_ATTRIBUTE_PROVIDERS")
+	if providersFromEnv == "" {
+		return nil
+	}
+	var providers []string
+	err := json.Unmarshal([]byte(providersFromEnv), &providers)
+	if err != nil {
+		seelog.Warnf("Invalid format for \"ECS_INSTANCE_ATTRIBUTE_PROVIDERS\", expected a json list of string. error: %v", err)
+	}
+	return providers
+}
+
 func parseNumImagesToDeletePerCycle() int {
 	numImagesToDeletePerCycleEnvVal := os.Getenv("ECS_NUM_IMAGES_DELETE_PER_CYCLE")
 	numImagesToDeletePerCycle, err := strconv.Atoi(numImagesToDeletePerCycleEnvVal)
@@ -154,6 +279,15 @@ func parseNumImagesToDeletePerCycle() int {
 	return numImagesToDeletePerCycle
 }
 
+func parseImageCleanupDiskWatermark(envVar string) int {
+	watermarkEnvVal := os.Getenv(envVar)
+	watermark, err := strconv.Atoi(watermarkEnvVal)
+	if watermarkEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"%s\", expected an integer percentage. err %v", envVar, err)
+	}
+	return watermark
+}
+
 func parseNumNonECSContainersToDeletePerCycle() int {
 	numNonEcsContainersToDeletePerCycleEnvVal := os.Getenv("NONECS_NUM_CONTAINERS_DELETE_PER_CYCLE")
 	numNonEcsContainersToDeletePerCycle, err := strconv.Atoi(numNonEcsContainersToDeletePerCycleEnvVal)
@@ -197,6 +331,120 @@ func parseInstanceAttributes(errs []error) (map[string]string, []error) {
 	return instanceAttributes, errs
 }
 
+func parseDockerCredentialHelpers(errs []error) (map[string]string, []error) {
+	var credentialHelpers map[string]string
+	credentialHelpersEnv := os.Getenv("ECS_DOCKER_CREDENTIAL_HELPERS")
+	if credentialHelpersEnv == "" {
+		return nil, errs
+	}
+	err := json.Unmarshal([]byte(credentialHelpersEnv), &credentialHelpers)
+	if err != nil {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_DOCKER_CREDENTIAL_HELPERS. Expected a json hash of registry to helper name: %v", err)
+		seelog.Error(wrappedErr)
+		return nil, append(errs, wrappedErr)
+	}
+	for registry, helper := range credentialHelpers {
+		// The helper name becomes part of the name of the binary that is run,
+		// so it must not be able to point outside of the PATH
+		if !credentialHelperNameRegex.MatchString(helper) {
+			wrappedErr := fmt.Errorf("Invalid docker credential helper name %q for registry %s in ECS_DOCKER_CREDENTIAL_HELPERS", helper, registry)
+			seelog.Error(wrappedErr)
+			errs = append(errs, wrappedErr)
+			delete(credentialHelpers, registry)
+		}
+	}
+
+	return credentialHelpers, errs
+}
+
+func parseImagePullMaxBandwidthMbps() float64 {
+	imagePullMaxBandwidthEnvVal := os.Getenv("ECS_IMAGE_PULL_MAX_BANDWIDTH_MBPS")
+	if imagePullMaxBandwidthEnvVal == "" {
+		return 0
+	}
+	imagePullMaxBandwidth, err := strconv.ParseFloat(imagePullMaxBandwidthEnvVal, 64)
+	if err != nil {
+		seelog.Warnf("Invalid format for \"ECS_IMAGE_PULL_MAX_BANDWIDTH_MBPS\", expected a number. err %v", err)
+		return 0
+	}
+	return imagePullMaxBandwidth
+}
+
+func parseImagePullMaxAttempts() int {
+	imagePullMaxAttemptsEnvVal := os.Getenv("ECS_IMAGE_PULL_MAX_ATTEMPTS")
+	imagePullMaxAttempts, err := strconv.Atoi(imagePullMaxAttemptsEnvVal)
+	if imagePullMaxAttemptsEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_IMAGE_PULL_MAX_ATTEMPTS\", expected an integer. err %v", err)
+	}
+	return imagePullMaxAttempts
+}
+
+// imagePullRetryPolicyJSON is the format of the retry policy of an image pull
+// error class in ECS_IMAGE_PULL_RETRY_POLICIES
+type imagePullRetryPolicyJSON struct {
+	Retry     *bool  `json:"retry"`
+	BaseDelay string `json:"baseDelay"`
+	MaxDelay  string `json:"maxDelay"`
+}
+
+func parseImagePullRetryPolicies(errs []error) (map[ImagePullErrorClass]ImagePullRetryPolicy, []error) {
+	imagePullRetryPoliciesEnv := os.Getenv("ECS_IMAGE_PULL_RETRY_POLICIES")
+	if imagePullRetryPoliciesEnv == "" {
+		return nil, errs
+	}
+	var policiesJSON map[ImagePullErrorClass]imagePullRetryPolicyJSON
+	err := json.Unmarshal([]byte(imagePullRetryPoliciesEnv), &policiesJSON)
+	if err != nil {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_IMAGE_PULL_RETRY_POLICIES. Expected a json hash of error class to retry policy: %v", err)
+		seelog.Error(wrappedErr)
+		return nil, append(errs, wrappedErr)
+	}
+
+	policies := make(map[ImagePullErrorClass]ImagePullRetryPolicy)
+	for class, policyJSON := range policiesJSON {
+		switch class {
+		case ImagePullErrorThrottling, ImagePullErrorNotFound, ImagePullErrorTimeout, ImagePullErrorOther:
+		default:
+			wrappedErr := fmt.Errorf("Invalid image pull error class %q in ECS_IMAGE_PULL_RETRY_POLICIES", class)
+			seelog.Error(wrappedErr)
+			errs = append(errs, wrappedErr)
+			continue
+		}
+		policy := ImagePullRetryPolicy{
+			NoRetry: policyJSON.Retry != nil && !*policyJSON.Retry,
+		}
+		if policy.BaseDelay, err = parseOptionalDuration(policyJSON.BaseDelay); err == nil {
+			policy.MaxDelay, err = parseOptionalDuration(policyJSON.MaxDelay)
+		}
+		if err == nil && policy.MaxDelay != 0 && policy.MaxDelay < policy.BaseDelay {
+			err = fmt.Errorf("maxDelay %v is lower than baseDelay %v", policy.MaxDelay, policy.BaseDelay)
+		}
+		if err != nil {
+			wrappedErr := fmt.Errorf("Invalid retry policy for image pull error class %q in ECS_IMAGE_PULL_RETRY_POLICIES: %v", class, err)
+			seelog.Error(wrappedErr)
+			errs = append(errs, wrappedErr)
+			continue
+		}
+		policies[class] = policy
+	}
+
+	return policies, errs
+}
+
+func parseOptionalDuration(duration string) (time.Duration, error) {
+	if duration == "" {
+		return 0, nil
+	}
+	parsed, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0, err
+	}
+	if parsed < 0 {
+		return 0, fmt.Errorf("negative duration %v", parsed)
+	}
+	return parsed, nil
+}
+
 func parseAdditionalLocalRoutes(errs []error) ([]cnitypes.IPNet, []error) {
 	var additionalLocalRoutes []cnitypes.IPNet
 	additionalLocalRoutesEnv := os.Getenv("ECS_AWSVPC_ADDITIONAL_LOCAL_ROUTES")
@@ -311,6 +559,34 @@ func parseContainerInstancePropagateTagsFrom() ContainerInstancePropagateTagsFro
 	}
 }
 
+func parseMemoryPressurePolicy() MemoryPressurePolicyType {
+	memoryPressurePolicyString := os.Getenv("ECS_MEMORY_PRESSURE_POLICY")
+	switch memoryPressurePolicyString {
+	case "report":
+		return MemoryPressurePolicyReport
+	case "evict":
+		return MemoryPressurePolicyEvict
+	case "", "none":
+		return MemoryPressurePolicyNone
+	default:
+		seelog.Warnf("Invalid value for \"ECS_MEMORY_PRESSURE_POLICY\", expected none, report or evict. Parsed value: %s", memoryPressurePolicyString)
+		return MemoryPressurePolicyNone
+	}
+}
+
+func parseMemoryPressureThreshold(envVar string) float64 {
+	thresholdEnvVal := os.Getenv(envVar)
+	if thresholdEnvVal == "" {
+		return 0
+	}
+	threshold, err := strconv.ParseFloat(thresholdEnvVal, 64)
+	if err != nil {
+		seelog.Warnf("Invalid format for \"%s\", expected a number. err %v", envVar, err)
+		return 0
+	}
+	return threshold
+}
+
 func parseEnvVariableUint16(envVar string) uint16 {
 	envVal := os.Getenv(envVar)
 	var var16 uint16
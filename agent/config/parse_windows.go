@@ -52,6 +52,12 @@ func parseFSxWindowsFileServerCapability() bool {
 	return checkDomainJoinWithEnvOverride(envStatus)
 }
 
+// parseTaskMetadataNamedPipeEnabled is used to determine if the task metadata endpoints should
+// also be served over named pipes
+func parseTaskMetadataNamedPipeEnabled() BooleanDefaultFalse {
+	return parseBooleanDefaultFalseConfig("ECS_ENABLE_TASK_METADATA_NAMED_PIPE")
+}
+
 func checkDomainJoinWithEnvOverride(envStatus bool) bool {
 	if envStatus {
 		// Check if domain join check override is present
@@ -29,6 +29,14 @@ func TestParseGMSACapability(t *testing.T) {
 	assert.False(t, parseGMSACapability())
 }
 
+func TestParseTaskMetadataNamedPipeEnabled(t *testing.T) {
+	assert.False(t, parseTaskMetadataNamedPipeEnabled().Enabled())
+
+	os.Setenv("ECS_ENABLE_TASK_METADATA_NAMED_PIPE", "true")
+	defer os.Unsetenv("ECS_ENABLE_TASK_METADATA_NAMED_PIPE")
+	assert.True(t, parseTaskMetadataNamedPipeEnabled().Enabled())
+}
+
 func TestParseBooleanEnvVar(t *testing.T) {
 	os.Setenv("EXAMPLE_SETTING", "True")
 	defer os.Unsetenv("EXAMPLE_SETTING")
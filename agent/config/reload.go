@@ -0,0 +1,190 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+)
+
+// ReloadableConfig is the part of the configuration of the agent that is read again from
+// the config file while the agent runs, when it receives SIGHUP or the file changes
+type ReloadableConfig struct {
+	// LogLevel is the level of the logs of the log driver output, from ECS_LOGLEVEL
+	LogLevel string
+	// InstanceLogLevel is the level of the logs written on the instance, from
+	// ECS_LOGLEVEL_ON_INSTANCE
+	InstanceLogLevel string
+	// ReservedMemory is the memory reserved for the processes outside of the tasks, in MiB.
+	// The memory registered for the container instance only changes when the agent
+	// restarts and registers it again
+	ReservedMemory uint16
+	// ImageCleanupInterval is the time between two image cleanups
+	ImageCleanupInterval time.Duration
+	// MinimumImageDeletionAge is how long the images used by tasks are kept after they
+	// were pulled
+	MinimumImageDeletionAge time.Duration
+	// NonECSMinimumImageDeletionAge is how long the other images are kept after they were
+	// created
+	NonECSMinimumImageDeletionAge time.Duration
+	// NumImagesToDeletePerCycle is the maximum number of images deleted by an image cleanup
+	NumImagesToDeletePerCycle int
+	// NumNonECSContainersToDeletePerCycle is the maximum number of stopped containers not
+	// started by the agent removed by an image cleanup
+	NumNonECSContainersToDeletePerCycle int
+	// EFSMountHealthCheckInterval is the time between two checks of the EFS mounts. The
+	// checks can't be enabled or disabled by a reload
+	EFSMountHealthCheckInterval time.Duration
+	// MemoryPressureCheckInterval is the time between two checks of the memory pressure
+	// of the tasks
+	MemoryPressureCheckInterval time.Duration
+}
+
+// Reloadable returns the part of the configuration that is reloaded while the agent runs.
+// The log levels are left empty, as they're kept by the logger
+func (cfg *Config) Reloadable() ReloadableConfig {
+	return ReloadableConfig{
+		ReservedMemory:                      cfg.ReservedMemory,
+		ImageCleanupInterval:                cfg.ImageCleanupInterval,
+		MinimumImageDeletionAge:             cfg.MinimumImageDeletionAge,
+		NonECSMinimumImageDeletionAge:       cfg.NonECSMinimumImageDeletionAge,
+		NumImagesToDeletePerCycle:           cfg.NumImagesToDeletePerCycle,
+		NumNonECSContainersToDeletePerCycle: cfg.NumNonECSContainersToDeletePerCycle,
+		EFSMountHealthCheckInterval:         cfg.EFSMountHealthCheckInterval,
+		MemoryPressureCheckInterval:         cfg.MemoryPressureCheckInterval,
+	}
+}
+
+// String returns the reloadable configuration, for logging
+func (reloadable ReloadableConfig) String() string {
+	return fmt.Sprintf("LogLevel: %s, InstanceLogLevel: %s, ReservedMemory: %d, ImageCleanupInterval: %v, "+
+		"MinimumImageDeletionAge: %v, NonECSMinimumImageDeletionAge: %v, NumImagesToDeletePerCycle: %d, "+
+		"NumNonECSContainersToDeletePerCycle: %d, EFSMountHealthCheckInterval: %v, MemoryPressureCheckInterval: %v",
+		reloadable.LogLevel, reloadable.InstanceLogLevel, reloadable.ReservedMemory, reloadable.ImageCleanupInterval,
+		reloadable.MinimumImageDeletionAge, reloadable.NonECSMinimumImageDeletionAge, reloadable.NumImagesToDeletePerCycle,
+		reloadable.NumNonECSContainersToDeletePerCycle, reloadable.EFSMountHealthCheckInterval,
+		reloadable.MemoryPressureCheckInterval)
+}
+
+// reloadableEnvVariables are the variables of the schema read again from the config file
+var reloadableEnvVariables = []string{
+	"ECS_RESERVED_MEMORY",
+	"ECS_IMAGE_CLEANUP_INTERVAL",
+	"ECS_IMAGE_MINIMUM_CLEANUP_AGE",
+	"NON_ECS_IMAGE_MINIMUM_CLEANUP_AGE",
+	"ECS_NUM_IMAGES_DELETE_PER_CYCLE",
+	"NONECS_NUM_CONTAINERS_DELETE_PER_CYCLE",
+	"ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL",
+	"ECS_MEMORY_PRESSURE_CHECK_INTERVAL",
+}
+
+// ReadReloadableConfig reads the reloadable configuration from a file in the format of
+// ecs.config, with one VARIABLE=value line per environment variable. The values are
+// converted according to the schema and the values out of bounds are overridden as they
+// are when the agent starts, on a copy of the configuration. The variables that aren't in
+// the file keep their current values. Nothing is read if any of the values can't be
+// converted, or if the whole configuration is invalid.
+func (cfg *Config) ReadReloadableConfig(fileName string, current ReloadableConfig) (ReloadableConfig, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return current, err
+	}
+	env := parseEnvFile(data)
+
+	// the configuration is shared with the rest of the agent, only a copy is modified
+	reloadedCfg := *cfg
+	reloadedCfg.ReservedPorts = append([]uint16(nil), cfg.ReservedPorts...)
+	reloadedCfg.ValidationProblems = nil
+	reloadedCfg.setReloadable(current)
+
+	var errs []error
+	cfgValue := reflect.ValueOf(&reloadedCfg).Elem()
+	for _, variable := range envVariables {
+		value, ok := env[variable.name]
+		if !ok || !isReloadableEnvVariable(variable.name) {
+			continue
+		}
+		if err := setEnvVariableField(cfgValue.FieldByName(variable.field), value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", variable.name, value, err))
+		}
+	}
+	if len(errs) > 0 {
+		return current, apierrors.NewMultiError(errs...)
+	}
+	if err := reloadedCfg.validateAndOverrideBounds(); err != nil {
+		return current, err
+	}
+	if (reloadedCfg.EFSMountHealthCheckInterval == 0) != (current.EFSMountHealthCheckInterval == 0) {
+		return current, fmt.Errorf("invalid ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL %q: "+
+			"the EFS mount checks are only enabled or disabled when the agent starts", env["ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL"])
+	}
+
+	reloadable := reloadedCfg.Reloadable()
+	reloadable.LogLevel = current.LogLevel
+	reloadable.InstanceLogLevel = current.InstanceLogLevel
+	if value, ok := env["ECS_LOGLEVEL"]; ok {
+		reloadable.LogLevel = value
+	}
+	if value, ok := env["ECS_LOGLEVEL_ON_INSTANCE"]; ok {
+		reloadable.InstanceLogLevel = value
+	}
+	return reloadable, nil
+}
+
+// setReloadable sets the fields of the reloadable configuration
+func (cfg *Config) setReloadable(reloadable ReloadableConfig) {
+	cfg.ReservedMemory = reloadable.ReservedMemory
+	cfg.ImageCleanupInterval = reloadable.ImageCleanupInterval
+	cfg.MinimumImageDeletionAge = reloadable.MinimumImageDeletionAge
+	cfg.NonECSMinimumImageDeletionAge = reloadable.NonECSMinimumImageDeletionAge
+	cfg.NumImagesToDeletePerCycle = reloadable.NumImagesToDeletePerCycle
+	cfg.NumNonECSContainersToDeletePerCycle = reloadable.NumNonECSContainersToDeletePerCycle
+	cfg.EFSMountHealthCheckInterval = reloadable.EFSMountHealthCheckInterval
+	cfg.MemoryPressureCheckInterval = reloadable.MemoryPressureCheckInterval
+}
+
+func isReloadableEnvVariable(name string) bool {
+	for _, reloadable := range reloadableEnvVariables {
+		if name == reloadable {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEnvFile returns the environment variables set by the lines of an environment file.
+// Empty lines and comments are ignored
+func parseEnvFile(data []byte) map[string]string {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		env[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return env
+}
@@ -0,0 +1,142 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeReloadableConfigFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "reload")
+	require.NoError(t, err)
+	fileName := filepath.Join(dir, "ecs.config")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte(content), 0644))
+	return fileName, func() { os.RemoveAll(dir) }
+}
+
+func TestReadReloadableConfig(t *testing.T) {
+	fileName, cleanup := writeReloadableConfigFile(t, `
+# comments and unrelated variables are ignored
+ECS_CLUSTER=default
+ECS_LOGLEVEL=debug
+ECS_RESERVED_MEMORY=256
+ECS_IMAGE_CLEANUP_INTERVAL=30m
+ECS_IMAGE_MINIMUM_CLEANUP_AGE=2h
+ECS_NUM_IMAGES_DELETE_PER_CYCLE = 10
+ECS_MEMORY_PRESSURE_CHECK_INTERVAL=5s
+`)
+	defer cleanup()
+
+	cfg := reloadTestConfig()
+	current := cfg.Reloadable()
+	current.InstanceLogLevel = "warn"
+	reloaded, err := cfg.ReadReloadableConfig(fileName, current)
+	require.NoError(t, err)
+	assert.Equal(t, "debug", reloaded.LogLevel)
+	assert.Equal(t, uint16(256), reloaded.ReservedMemory)
+	assert.Equal(t, 30*time.Minute, reloaded.ImageCleanupInterval)
+	assert.Equal(t, 2*time.Hour, reloaded.MinimumImageDeletionAge)
+	assert.Equal(t, 10, reloaded.NumImagesToDeletePerCycle)
+	assert.Equal(t, 5*time.Second, reloaded.MemoryPressureCheckInterval)
+
+	// the variables missing from the file keep their values
+	assert.Equal(t, "warn", reloaded.InstanceLogLevel)
+	assert.Equal(t, DefaultNonECSImageDeletionAge, reloaded.NonECSMinimumImageDeletionAge)
+	assert.Equal(t, DefaultNumNonECSContainersToDeletePerCycle, reloaded.NumNonECSContainersToDeletePerCycle)
+}
+
+func TestReadReloadableConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "unparsable value",
+			content: "ECS_LOGLEVEL=debug\nECS_RESERVED_MEMORY=lots\n",
+		},
+		{
+			name:    "unparsable integer",
+			content: "ECS_NUM_IMAGES_DELETE_PER_CYCLE=many\n",
+		},
+		{
+			name:    "EFS mount checks enabled",
+			content: "ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL=1m\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fileName, cleanup := writeReloadableConfigFile(t, tc.content)
+			defer cleanup()
+
+			cfg := reloadTestConfig()
+			current := cfg.Reloadable()
+			reloaded, err := cfg.ReadReloadableConfig(fileName, current)
+			assert.Error(t, err)
+			assert.Equal(t, current, reloaded, "nothing should be read from an invalid file")
+		})
+	}
+}
+
+func TestReadReloadableConfigEFSMountHealthCheckInterval(t *testing.T) {
+	fileName, cleanup := writeReloadableConfigFile(t, "ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL=10m\n")
+	defer cleanup()
+
+	cfg := reloadTestConfig()
+	cfg.EFSMountHealthCheckInterval = time.Minute
+	reloaded, err := cfg.ReadReloadableConfig(fileName, cfg.Reloadable())
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, reloaded.EFSMountHealthCheckInterval)
+}
+
+func TestReadReloadableConfigOverridesBounds(t *testing.T) {
+	fileName, cleanup := writeReloadableConfigFile(t, `
+ECS_IMAGE_CLEANUP_INTERVAL=1m
+ECS_NUM_IMAGES_DELETE_PER_CYCLE=0
+ECS_MEMORY_PRESSURE_CHECK_INTERVAL=1ms
+ECS_LOGLEVEL=debug
+`)
+	defer cleanup()
+
+	cfg := reloadTestConfig()
+	reloaded, err := cfg.ReadReloadableConfig(fileName, cfg.Reloadable())
+	require.NoError(t, err)
+	// the values out of bounds are overridden as they are when the agent starts
+	assert.Equal(t, DefaultImageCleanupTimeInterval, reloaded.ImageCleanupInterval)
+	assert.Equal(t, DefaultNumImagesToDeletePerCycle, reloaded.NumImagesToDeletePerCycle)
+	assert.Equal(t, DefaultMemoryPressureCheckInterval, reloaded.MemoryPressureCheckInterval)
+	assert.Equal(t, "debug", reloaded.LogLevel)
+	// the shared configuration isn't modified
+	assert.Empty(t, cfg.ValidationProblems)
+}
+
+func TestReadReloadableConfigMissingFile(t *testing.T) {
+	cfg := reloadTestConfig()
+	_, err := cfg.ReadReloadableConfig(filepath.Join(os.TempDir(), "missing", "ecs.config"), ReloadableConfig{})
+	assert.Error(t, err)
+}
+
+func reloadTestConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.AWSRegion = "us-west-2"
+	return &cfg
+}
@@ -0,0 +1,117 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const redactedValue = "[redacted]"
+
+// ValidationProblem is a configuration value that was rejected, overridden, or that has
+// no effect.
+type ValidationProblem struct {
+	Variables []string
+	Message   string
+}
+
+// ReportedVariable is the effective value of an environment variable of the agent,
+// whether it comes from the environment, the config file or the defaults.
+type ReportedVariable struct {
+	Name  string
+	Value string
+	// FromEnvironment is set when the variable is set in the environment of the agent.
+	FromEnvironment bool
+}
+
+// Report is the effective configuration of the agent, with the problems found while
+// loading it. Sensitive values and the credentials of URLs are redacted.
+type Report struct {
+	Variables []ReportedVariable
+	Problems  []ValidationProblem `json:",omitempty"`
+}
+
+// Report returns the effective configuration report of the config.
+func (cfg *Config) Report() Report {
+	cfgValue := reflect.ValueOf(cfg).Elem()
+	report := Report{Problems: cfg.ValidationProblems}
+	for _, variable := range envVariables {
+		var value string
+		if variable.value != nil {
+			value = variable.value(cfg)
+		} else {
+			value = reportedValue(cfgValue.FieldByName(variable.field))
+		}
+		report.Variables = append(report.Variables, ReportedVariable{
+			Name:            variable.name,
+			Value:           value,
+			FromEnvironment: os.Getenv(variable.name) != "",
+		})
+	}
+	return report
+}
+
+// String returns the report as a single line of json.
+func (report Report) String() string {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Sprintf("unable to marshal the configuration report: %v", err)
+	}
+	return string(data)
+}
+
+func reportedValue(field reflect.Value) string {
+	switch value := field.Interface().(type) {
+	case BooleanDefaultFalse:
+		return strconv.FormatBool(value.Enabled())
+	case BooleanDefaultTrue:
+		return strconv.FormatBool(value.Enabled())
+	case *SensitiveRawMessage:
+		if value == nil || len(value.Contents()) == 0 {
+			return ""
+		}
+		return redactedValue
+	case time.Duration:
+		return value.String()
+	}
+	if field.Kind() == reflect.String {
+		return redactURLCredentials(field.String())
+	}
+	data, err := json.Marshal(field.Interface())
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return string(data)
+}
+
+// redactURLCredentials hides the user information of values that are URLs, such as
+// the address of a Vault server.
+func redactURLCredentials(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.User == nil {
+		return value
+	}
+	parsed.User = url.User("redacted")
+	return parsed.String()
+}
+
+func reportTaskMetadataThrottles(cfg *Config) string {
+	return fmt.Sprintf("%d,%d", cfg.TaskMetadataSteadyStateRate, cfg.TaskMetadataBurstRate)
+}
@@ -0,0 +1,338 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// envVariable maps an environment variable onto the field of Config that it populates.
+type envVariable struct {
+	name  string
+	field string
+	// custom is set for variables that are parsed by their own function in
+	// environmentConfig. They are listed so that they are part of the configuration report.
+	custom bool
+	// value overrides how the effective value is reported, for variables that populate
+	// more than one field.
+	value func(cfg *Config) string
+}
+
+// envVariables is the schema of the environment variables read by environmentConfig.
+// The values of the variables that are not custom are converted according to the type
+// of their field by loadEnvVariables.
+var envVariables = []envVariable{
+	{name: "ECS_CLUSTER", field: "Cluster"},
+	{name: "ECS_BACKEND_HOST", field: "APIEndpoint"},
+	{name: "AWS_DEFAULT_REGION", field: "AWSRegion"},
+	{name: "DOCKER_HOST", field: "DockerEndpoint"},
+	{name: "ECS_RESERVED_PORTS", field: "ReservedPorts", custom: true},
+	{name: "ECS_RESERVED_PORTS_UDP", field: "ReservedPortsUDP", custom: true},
+	{name: "ECS_DATADIR", field: "DataDir", custom: true},
+	{name: "ECS_CHECKPOINT", field: "Checkpoint", custom: true},
+	{name: "ECS_ENGINE_AUTH_TYPE", field: "EngineAuthType"},
+	{name: "ECS_ENGINE_AUTH_DATA", field: "EngineAuthData"},
+	{name: "ECS_DOCKER_CREDENTIAL_HELPERS", field: "DockerCredentialHelpers", custom: true},
+	{name: "ECS_UPDATES_ENABLED", field: "UpdatesEnabled"},
+	{name: "ECS_UPDATE_DOWNLOAD_DIR", field: "UpdateDownloadDir"},
+	{name: "ECS_DISABLE_METRICS", field: "DisableMetrics"},
+	{name: "ECS_RESERVED_MEMORY", field: "ReservedMemory"},
+	{name: "ECS_AVAILABLE_LOGGING_DRIVERS", field: "AvailableLoggingDrivers", custom: true},
+	{name: "ECS_DISABLE_PRIVILEGED", field: "PrivilegedDisabled"},
+	{name: "ECS_SELINUX_CAPABLE", field: "SELinuxCapable"},
+	{name: "ECS_APPARMOR_CAPABLE", field: "AppArmorCapable"},
+	{name: "ECS_SECURITY_PROFILES_DIR", field: "SecurityProfilesDir"},
+	{name: "ECS_SECURITY_PROFILES_S3_ARN", field: "SecurityProfilesS3ARN"},
+	{name: "ECS_SECURITY_PROFILES_CACHE_TTL", field: "SecurityProfilesCacheTTL"},
+	{name: "ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION", field: "TaskCleanupWaitDuration"},
+	{name: "ECS_DATA_COMPACTION_INTERVAL", field: "DataCompactionInterval"},
+	{name: "ECS_DATA_BACKEND", field: "DataBackend"},
+	{name: "ECS_LIFECYCLE_HOOKS_DIR", field: "LifecycleHooksDir"},
+	{name: "ECS_LIFECYCLE_HOOK_TIMEOUT", field: "LifecycleHookTimeout"},
+	{name: "ECS_STOPPED_TASK_HISTORY_RETENTION", field: "StoppedTaskHistoryRetention"},
+	{name: "ECS_ENABLE_TASK_ENI", field: "TaskENIEnabled"},
+	{name: "ECS_ENABLE_TASK_IAM_ROLE", field: "TaskIAMRoleEnabled"},
+	{name: "ECS_ENABLE_UNTRACKED_IMAGE_CLEANUP", field: "DeleteNonECSImagesEnabled"},
+	{name: "ECS_ENABLE_TASK_CPU_MEM_LIMIT", field: "TaskCPUMemLimit"},
+	{name: "ECS_CONTAINER_STOP_TIMEOUT", field: "DockerStopTimeout", custom: true},
+	{name: "ECS_CONTAINER_STOP_SIGNAL_SEQUENCE", field: "ContainerStopSignalSequence", custom: true},
+	{name: "ECS_CONTAINER_DEFAULT_ULIMITS", field: "ContainerDefaultUlimits", custom: true},
+	{name: "ECS_CONTAINER_START_TIMEOUT", field: "ContainerStartTimeout", custom: true},
+	{name: "ECS_CONTAINER_CREATE_TIMEOUT", field: "ContainerCreateTimeout", custom: true},
+	{name: "ECS_TASK_CONTAINER_START_CONCURRENCY", field: "TaskContainerStartConcurrency", custom: true},
+	{name: "ECS_ENABLE_DEPENDENCY_ORDERED_SHUTDOWN", field: "DependencyOrderedShutdown"},
+	{name: "ECS_CONTAINER_SHUTDOWN_GRACE_PERIOD", field: "ContainerShutdownGracePeriod"},
+	{name: "ECS_CONTAINER_CHECKPOINT_INTERVAL", field: "ContainerCheckpointInterval"},
+	{name: "ECS_CONTAINER_CHECKPOINT_DIR", field: "ContainerCheckpointDir"},
+	{name: "ECS_FIRELENS_CONFIG_RELOAD_INTERVAL", field: "FirelensConfigReloadInterval"},
+	{name: "ECS_PULL_DEPENDENT_CONTAINERS_UPFRONT", field: "DependentContainersPullUpfront"},
+	{name: "ECS_IMAGE_PULL_INACTIVITY_TIMEOUT", field: "ImagePullInactivityTimeout", custom: true},
+	{name: "ECS_IMAGE_PULL_TIMEOUT", field: "ImagePullTimeout"},
+	{name: "ECS_IMAGE_PULL_MAX_BANDWIDTH_MBPS", field: "ImagePullMaxBandwidthMbps", custom: true},
+	{name: "ECS_IMAGE_PULL_SOCI_LAZY_LOADING", field: "ImagePullSOCILazyLoading"},
+	{name: "ECS_IMAGE_PULL_MAX_ATTEMPTS", field: "ImagePullMaxAttempts", custom: true},
+	{name: "ECS_IMAGE_PULL_RETRY_BASE_DELAY", field: "ImagePullRetryBaseDelay"},
+	{name: "ECS_IMAGE_PULL_RETRY_MAX_DELAY", field: "ImagePullRetryMaxDelay"},
+	{name: "ECS_IMAGE_PULL_RETRY_POLICIES", field: "ImagePullRetryPolicies", custom: true},
+	{name: "ECS_ECR_TOKEN_REFRESH_WINDOW", field: "ECRTokenRefreshWindow"},
+	{name: "ECS_TASK_CREDENTIALS_REFRESH_WINDOW", field: "TaskCredentialsRefreshWindow"},
+	{name: "ECS_PERSIST_ECR_TOKEN_CACHE", field: "PersistECRTokenCache"},
+	{name: "ECS_ENABLE_ECR_PUBLIC_AUTH", field: "ECRPublicAuthEnabled"},
+	{name: "ECS_AUDIT_LOGFILE", field: "CredentialsAuditLogFile"},
+	{name: "ECS_AUDIT_LOGFILE_DISABLED", field: "CredentialsAuditLogDisabled"},
+	{name: "ECS_AUDIT_JSON_LOGFILE", field: "CredentialsAuditJSONLogFile"},
+	{name: "ECS_OTEL_EXPORTER_ENDPOINT", field: "OTelExporterEndpoint"},
+	{name: "ECS_ENABLE_TASK_IAM_ROLE_NETWORK_HOST", field: "TaskIAMRoleEnabledForNetworkHost"},
+	{name: "ECS_DISABLE_IMAGE_CLEANUP", field: "ImageCleanupDisabled"},
+	{name: "ECS_IMAGE_MINIMUM_CLEANUP_AGE", field: "MinimumImageDeletionAge"},
+	{name: "NON_ECS_IMAGE_MINIMUM_CLEANUP_AGE", field: "NonECSMinimumImageDeletionAge"},
+	{name: "ECS_IMAGE_CLEANUP_INTERVAL", field: "ImageCleanupInterval"},
+	{name: "ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", field: "ImageCleanupDiskHighWatermark", custom: true},
+	{name: "ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK", field: "ImageCleanupDiskLowWatermark", custom: true},
+	{name: "ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL", field: "ImageCleanupDiskCheckInterval"},
+	{name: "ECS_NUM_IMAGES_DELETE_PER_CYCLE", field: "NumImagesToDeletePerCycle", custom: true},
+	{name: "NONECS_NUM_CONTAINERS_DELETE_PER_CYCLE", field: "NumNonECSContainersToDeletePerCycle", custom: true},
+	{name: "ECS_IMAGE_PULL_BEHAVIOR", field: "ImagePullBehavior", custom: true},
+	{name: "ECS_EXCLUDE_UNTRACKED_IMAGE", field: "ImageCleanupExclusionList", custom: true},
+	{name: "ECS_INSTANCE_ATTRIBUTES", field: "InstanceAttributes", custom: true},
+	{name: "ECS_INSTANCE_ATTRIBUTE_PROVIDERS", field: "InstanceAttributeProviders", custom: true},
+	{name: "ECS_INSTANCE_ATTRIBUTE_PLUGINS_DIR", field: "InstanceAttributePluginsDir"},
+	{name: "ECS_INSTANCE_ATTRIBUTE_REFRESH_INTERVAL", field: "InstanceAttributeRefreshInterval"},
+	{name: "ECS_ACS_CA_BUNDLE", field: "ACSCABundle"},
+	{name: "ECS_CNI_PLUGINS_PATH", field: "CNIPluginsPath"},
+	{name: "ECS_AWSVPC_BLOCK_IMDS", field: "AWSVPCBlockInstanceMetdata"},
+	{name: "ECS_ENABLE_AWSVPC_CONTAINER_NETWORK_STATS", field: "AWSVPCContainerNetworkStats"},
+	{name: "ECS_AWSVPC_ADDITIONAL_LOCAL_ROUTES", field: "AWSVPCAdditionalLocalRoutes", custom: true},
+	{name: "ECS_ENABLE_CONTAINER_METADATA", field: "ContainerMetadataEnabled"},
+	{name: "ECS_HOST_DATA_DIR", field: "DataDirOnHost"},
+	{name: "ECS_ENABLE_AWSLOGS_EXECUTIONROLE_OVERRIDE", field: "OverrideAWSLogsExecutionRole"},
+	{name: "ECS_CGROUP_PATH", field: "CgroupPath"},
+	{name: "ECS_TASK_METADATA_RPS_LIMIT", field: "TaskMetadataSteadyStateRate", custom: true, value: reportTaskMetadataThrottles},
+	{name: "ECS_SHARED_VOLUME_MATCH_FULL_CONFIG", field: "SharedVolumeMatchFullConfig"},
+	{name: "ECS_ENABLE_EPHEMERAL_STORAGE_QUOTA", field: "EphemeralStorageQuotaEnabled"},
+	{name: "ECS_ENCRYPT_EPHEMERAL_VOLUMES", field: "EphemeralVolumeEncryption"},
+	{name: "ECS_ENCRYPTED_VOLUME_SIZE_MB", field: "EncryptedVolumeSizeMB", custom: true},
+	{name: "ECS_TASK_NETWORK_POLICY_FILE", field: "TaskNetworkPolicyFile"},
+	{name: "ECS_TASK_DNS_CACHE_QUERIES_PER_SECOND", field: "TaskDNSCacheQueriesPerSecond", custom: true},
+	{name: "ECS_CONTAINER_INSTANCE_TAGS", field: "ContainerInstanceTags", custom: true},
+	{name: "ECS_CONTAINER_INSTANCE_PROPAGATE_TAGS_FROM", field: "ContainerInstancePropagateTagsFrom", custom: true},
+	{name: "ECS_POLL_METRICS", field: "PollMetrics"},
+	{name: "ECS_PROMETHEUS_METRICS_BIND_ADDRESS", field: "PrometheusMetricsBindAddress"},
+	{name: "ECS_POLLING_METRICS_WAIT_DURATION", field: "PollingMetricsWaitDuration"},
+	{name: "ECS_STATS_COLLECTION_INTERVAL", field: "StatsCollectionInterval"},
+	{name: "ECS_TELEMETRY_BUFFER_SIZE_MB", field: "TelemetryBufferSizeMB", custom: true},
+	{name: "ECS_ENABLE_AWSLOGS_RELAY", field: "AWSLogsRelayEnabled"},
+	{name: "ECS_AWSLOGS_RELAY_BUFFER_SIZE_MB", field: "AWSLogsRelayBufferSizeMB", custom: true},
+	{name: "ECS_EXEC_SESSION_AUDIT_S3_BUCKET", field: "ExecSessionAuditS3Bucket"},
+	{name: "ECS_EXEC_SESSION_AUDIT_S3_KEY_PREFIX", field: "ExecSessionAuditS3KeyPrefix"},
+	{name: "ECS_EXEC_SESSION_AUDIT_KMS_KEY_ID", field: "ExecSessionAuditKMSKeyID"},
+	{name: "ECS_EXEC_SESSION_AUDIT_TRANSCRIPTS", field: "ExecSessionAuditTranscriptsEnabled"},
+	{name: "ECS_EXEC_MAX_SESSIONS_PER_CONTAINER", field: "ExecMaxSessionsPerContainer", custom: true},
+	{name: "ECS_EXEC_MAX_SESSIONS_PER_TASK", field: "ExecMaxSessionsPerTask", custom: true},
+	{name: "ECS_EXEC_SESSION_IDLE_TIMEOUT", field: "ExecSessionIdleTimeout"},
+	{name: "ECS_CONTAINER_DISK_USAGE_POLL_INTERVAL", field: "ContainerDiskUsagePollInterval"},
+	{name: "ECS_EFS_MOUNT_HEALTH_CHECK_INTERVAL", field: "EFSMountHealthCheckInterval"},
+	{name: "ECS_MEMORY_PRESSURE_POLICY", field: "MemoryPressurePolicy", custom: true},
+	{name: "ECS_MEMORY_PRESSURE_CHECK_INTERVAL", field: "MemoryPressureCheckInterval"},
+	{name: "ECS_MEMORY_PRESSURE_STALL_THRESHOLD", field: "MemoryPressureStallThreshold", custom: true},
+	{name: "ECS_MEMORY_PRESSURE_USAGE_THRESHOLD", field: "MemoryPressureUsageThreshold", custom: true},
+	{name: "ECS_DISABLE_DOCKER_HEALTH_CHECK", field: "DisableDockerHealthCheck"},
+	{name: "ECS_ENABLE_GPU_SUPPORT", field: "GPUSupportEnabled"},
+	{name: "ECS_ENABLE_GPU_METRICS", field: "GPUMetricsEnabled"},
+	{name: "ECS_ENABLE_INF_SUPPORT", field: "InferentiaSupportEnabled"},
+	{name: "ECS_NVIDIA_RUNTIME", field: "NvidiaRuntime"},
+	{name: "ECS_DISABLE_TASK_METADATA_AZ", field: "TaskMetadataAZDisabled"},
+	{name: "ECS_ENABLE_TASK_METADATA_NAMED_PIPE", field: "TaskMetadataNamedPipeEnabled", custom: true},
+	{name: "ECS_CGROUP_CPU_PERIOD", field: "CgroupCPUPeriod", custom: true},
+	{name: "ECS_ENABLE_SPOT_INSTANCE_DRAINING", field: "SpotInstanceDrainingEnabled"},
+	{name: "ECS_ENABLE_SPOT_REBALANCE_DRAINING", field: "SpotRebalanceDrainingEnabled"},
+	{name: "ECS_ENABLE_ASG_TERMINATION_DRAINING", field: "ASGTerminationDrainingEnabled"},
+	{name: "ECS_DISABLE_TASK_PROTECTION_ON_INTERRUPTION", field: "DisableTaskProtectionOnInterruption"},
+	{name: "ECS_WARM_POOLS_CHECK", field: "WarmPoolsCheck"},
+	{name: "ECS_SKIP_CLEANUP_AFTER_HIBERNATION", field: "SkipCleanupAfterHibernation"},
+	{name: "ECS_ENABLE_NUMA_PLACEMENT", field: "NUMAPlacementEnabled"},
+	{name: "ECS_ENABLE_PPROF", field: "PprofEnabled"},
+	{name: "ECS_ENABLE_TASK_INIT_PROCESS", field: "TaskInitProcessEnabled"},
+	{name: "ECS_SECRETS_CACHE_TTL", field: "SecretsCacheTTL"},
+	{name: "ECS_SECRETS_FETCH_JITTER", field: "SecretsFetchJitter"},
+	{name: "ECS_SECRETS_REFRESH_INTERVAL", field: "SecretsRefreshInterval"},
+	{name: "ECS_SECRETS_REFRESH_SIGNAL", field: "SecretsRefreshSignal"},
+	{name: "ECS_VAULT_ADDR", field: "VaultAddr"},
+	{name: "ECS_VAULT_NAMESPACE", field: "VaultNamespace"},
+	{name: "ECS_VAULT_AWS_AUTH_MOUNT", field: "VaultAWSAuthMount"},
+	{name: "ECS_VAULT_AWS_AUTH_ROLE", field: "VaultAWSAuthRole"},
+	{name: "ECS_VAULT_AWS_IAM_SERVER_ID", field: "VaultAWSIAMServerID"},
+	{name: "ECS_RELOADABLE_CONFIG_FILE", field: "ReloadableConfigFile"},
+	{name: "ECS_GMSA_SUPPORTED", field: "GMSACapable", custom: true},
+	{name: "ECS_GMSA_CREDENTIAL_SPEC_CACHE_TTL", field: "GMSACredentialSpecCacheTTL"},
+	{name: "ECS_VOLUME_PLUGIN_CAPABILITIES", field: "VolumePluginCapabilities", custom: true},
+	{name: "ECS_FSX_WINDOWS_FILE_SERVER_SUPPORTED", field: "FSxWindowsFileServerCapable", custom: true},
+	{name: "ECS_EXTERNAL", field: "External"},
+}
+
+// conflictingOption is a combination of settings where one setting overrides or defeats
+// another one.
+type conflictingOption struct {
+	variables []string
+	conflicts func(cfg *Config) bool
+	message   string
+}
+
+// conflictingOptions do not prevent the agent from starting, they are reported so that
+// the configuration can be corrected.
+var conflictingOptions = []conflictingOption{
+	{
+		variables: []string{"ECS_STATS_COLLECTION_INTERVAL", "ECS_POLL_METRICS"},
+		conflicts: func(cfg *Config) bool {
+			return cfg.StatsCollectionInterval != 0 && cfg.PollMetrics.Value == ExplicitlyDisabled
+		},
+		message: "ECS_STATS_COLLECTION_INTERVAL is ignored as ECS_POLL_METRICS is set to false, stats will be streamed.",
+	},
+	{
+		variables: []string{"ECS_DISABLE_IMAGE_CLEANUP", "ECS_ENABLE_UNTRACKED_IMAGE_CLEANUP"},
+		conflicts: func(cfg *Config) bool {
+			return cfg.ImageCleanupDisabled.Enabled() && cfg.DeleteNonECSImagesEnabled.Enabled()
+		},
+		message: "ECS_ENABLE_UNTRACKED_IMAGE_CLEANUP is ignored as ECS_DISABLE_IMAGE_CLEANUP is set to true, no images will be deleted.",
+	},
+	{
+		variables: []string{"ECS_ENABLE_GPU_METRICS", "ECS_ENABLE_GPU_SUPPORT"},
+		conflicts: func(cfg *Config) bool {
+			return cfg.GPUMetricsEnabled.Enabled() && !cfg.GPUSupportEnabled
+		},
+		message: "ECS_ENABLE_GPU_METRICS is ignored as ECS_ENABLE_GPU_SUPPORT is not set to true.",
+	},
+	{
+		variables: []string{"ECS_EXEC_SESSION_AUDIT_TRANSCRIPTS", "ECS_EXEC_SESSION_AUDIT_S3_BUCKET"},
+		conflicts: func(cfg *Config) bool {
+			return cfg.ExecSessionAuditTranscriptsEnabled.Enabled() && cfg.ExecSessionAuditS3Bucket == ""
+		},
+		message: "ECS_EXEC_SESSION_AUDIT_TRANSCRIPTS is ignored as ECS_EXEC_SESSION_AUDIT_S3_BUCKET is not set, sessions will not be audited.",
+	},
+}
+
+// loadEnvVariables sets the fields of the variables in the schema that are not custom.
+// A value that cannot be converted to the type of its field leaves the field unset and
+// is reported as a problem.
+func (cfg *Config) loadEnvVariables() {
+	cfgValue := reflect.ValueOf(cfg).Elem()
+	for _, variable := range envVariables {
+		if variable.custom {
+			continue
+		}
+		value := os.Getenv(variable.name)
+		if err := setEnvVariableField(cfgValue.FieldByName(variable.field), value); err != nil {
+			cfg.reportProblem([]string{variable.name}, "Invalid format for \"%s\": %v", variable.name, err)
+		}
+	}
+}
+
+func setEnvVariableField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case BooleanDefaultFalse:
+		conditional, err := parseConditional(value)
+		field.Set(reflect.ValueOf(BooleanDefaultFalse{Value: conditional}))
+		return err
+	case BooleanDefaultTrue:
+		conditional, err := parseConditional(value)
+		field.Set(reflect.ValueOf(BooleanDefaultTrue{Value: conditional}))
+		return err
+	case *SensitiveRawMessage:
+		field.Set(reflect.ValueOf(NewSensitiveRawMessage([]byte(value))))
+	case time.Duration:
+		if value == "" {
+			return nil
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration: %v", err)
+		}
+		field.SetInt(int64(duration))
+	case bool:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected a boolean: %v", err)
+		}
+		field.SetBool(parsed)
+	case int:
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer: %v", err)
+		}
+		field.SetInt(int64(parsed))
+	case uint16:
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return fmt.Errorf("expected an unsigned integer: %v", err)
+		}
+		field.SetUint(parsed)
+	case string:
+		field.SetString(value)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+func parseConditional(value string) (Conditional, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return NotSet, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return NotSet, fmt.Errorf("expected a boolean: %v", err)
+	}
+	if parsed {
+		return ExplicitlyEnabled, nil
+	}
+	return ExplicitlyDisabled, nil
+}
+
+// checkConflictingOptions reports the conflicting options that are set.
+func (cfg *Config) checkConflictingOptions() {
+	for _, option := range conflictingOptions {
+		if option.conflicts(cfg) {
+			cfg.reportProblem(option.variables, "%s", option.message)
+		}
+	}
+}
+
+// reportProblem logs a problem with the configuration and records it so that it is
+// part of the configuration report.
+func (cfg *Config) reportProblem(variables []string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	seelog.Warn(message)
+	cfg.ValidationProblems = append(cfg.ValidationProblems, ValidationProblem{
+		Variables: variables,
+		Message:   message,
+	})
+}
@@ -0,0 +1,160 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvVariablesSchema(t *testing.T) {
+	cfgType := reflect.TypeOf(Config{})
+	names := make(map[string]bool)
+	for _, variable := range envVariables {
+		assert.False(t, names[variable.name], "duplicate variable %s", variable.name)
+		names[variable.name] = true
+
+		field, ok := cfgType.FieldByName(variable.field)
+		require.True(t, ok, "unknown field %s of variable %s", variable.field, variable.name)
+		if !variable.custom {
+			value := reflect.New(field.Type).Elem()
+			assert.NoError(t, setEnvVariableField(value, ""), "unsupported field %s of variable %s", variable.field, variable.name)
+		}
+	}
+}
+
+func TestLoadEnvVariables(t *testing.T) {
+	defer setTestEnv("ECS_CLUSTER", "my-cluster")()
+	defer setTestEnv("ECS_DISABLE_METRICS", "true")()
+	defer setTestEnv("ECS_ENABLE_TASK_CPU_MEM_LIMIT", "false")()
+	defer setTestEnv("ECS_RESERVED_MEMORY", "128")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_INTERVAL", "2h")()
+	defer setTestEnv("ECS_ENABLE_GPU_SUPPORT", " true ")()
+	defer setTestEnv("ECS_ENGINE_AUTH_DATA", `{"registry":{"auth":"secret"}}`)()
+
+	cfg, err := environmentConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "my-cluster", cfg.Cluster)
+	assert.Equal(t, ExplicitlyEnabled, cfg.DisableMetrics.Value)
+	assert.Equal(t, ExplicitlyDisabled, cfg.TaskCPUMemLimit.Value)
+	assert.Equal(t, NotSet, cfg.PrivilegedDisabled.Value)
+	assert.Equal(t, uint16(128), cfg.ReservedMemory)
+	assert.Equal(t, 2*time.Hour, cfg.ImageCleanupInterval)
+	assert.True(t, cfg.GPUSupportEnabled)
+	assert.Equal(t, `{"registry":{"auth":"secret"}}`, string(cfg.EngineAuthData.Contents()))
+	assert.Empty(t, cfg.ValidationProblems)
+}
+
+func TestLoadEnvVariablesInvalidValues(t *testing.T) {
+	defer setTestEnv("ECS_DISABLE_METRICS", "maybe")()
+	defer setTestEnv("ECS_RESERVED_MEMORY", "-1")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_INTERVAL", "2 hours")()
+	defer setTestEnv("ECS_ENABLE_GPU_SUPPORT", "yes")()
+
+	cfg, err := environmentConfig()
+	require.NoError(t, err)
+	assert.Equal(t, NotSet, cfg.DisableMetrics.Value)
+	assert.Zero(t, cfg.ReservedMemory)
+	assert.Zero(t, cfg.ImageCleanupInterval)
+	assert.False(t, cfg.GPUSupportEnabled)
+
+	var variables []string
+	for _, problem := range cfg.ValidationProblems {
+		require.Len(t, problem.Variables, 1)
+		assert.Contains(t, problem.Message, problem.Variables[0])
+		variables = append(variables, problem.Variables[0])
+	}
+	assert.ElementsMatch(t, []string{"ECS_DISABLE_METRICS", "ECS_RESERVED_MEMORY",
+		"ECS_IMAGE_CLEANUP_INTERVAL", "ECS_ENABLE_GPU_SUPPORT"}, variables)
+}
+
+func TestConflictingOptions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cfg       Config
+		variables []string
+	}{
+		{
+			name: "stats collection interval without polling",
+			cfg: Config{
+				StatsCollectionInterval: 10 * time.Second,
+				PollMetrics:             BooleanDefaultFalse{Value: ExplicitlyDisabled},
+			},
+			variables: []string{"ECS_STATS_COLLECTION_INTERVAL", "ECS_POLL_METRICS"},
+		},
+		{
+			name: "untracked image cleanup without image cleanup",
+			cfg: Config{
+				ImageCleanupDisabled:      BooleanDefaultFalse{Value: ExplicitlyEnabled},
+				DeleteNonECSImagesEnabled: BooleanDefaultFalse{Value: ExplicitlyEnabled},
+			},
+			variables: []string{"ECS_DISABLE_IMAGE_CLEANUP", "ECS_ENABLE_UNTRACKED_IMAGE_CLEANUP"},
+		},
+		{
+			name:      "gpu metrics without gpu support",
+			cfg:       Config{GPUMetricsEnabled: BooleanDefaultFalse{Value: ExplicitlyEnabled}},
+			variables: []string{"ECS_ENABLE_GPU_METRICS", "ECS_ENABLE_GPU_SUPPORT"},
+		},
+		{
+			name:      "session transcripts without audit bucket",
+			cfg:       Config{ExecSessionAuditTranscriptsEnabled: BooleanDefaultFalse{Value: ExplicitlyEnabled}},
+			variables: []string{"ECS_EXEC_SESSION_AUDIT_TRANSCRIPTS", "ECS_EXEC_SESSION_AUDIT_S3_BUCKET"},
+		},
+		{
+			name: "no conflict",
+			cfg: Config{
+				GPUSupportEnabled: true,
+				GPUMetricsEnabled: BooleanDefaultFalse{Value: ExplicitlyEnabled},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.checkConflictingOptions()
+			if tc.variables == nil {
+				assert.Empty(t, cfg.ValidationProblems)
+				return
+			}
+			require.Len(t, cfg.ValidationProblems, 1)
+			assert.Equal(t, tc.variables, cfg.ValidationProblems[0].Variables)
+		})
+	}
+}
+
+func TestValidationProblemsFromBounds(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION", "1s")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", "50")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK", "60")()
+
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTaskCleanupWaitDuration, cfg.TaskCleanupWaitDuration)
+
+	var variables [][]string
+	for _, problem := range cfg.ValidationProblems {
+		variables = append(variables, problem.Variables)
+	}
+	assert.Contains(t, variables, []string{"ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION"})
+	assert.Contains(t, variables, []string{"ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", "ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK"})
+}
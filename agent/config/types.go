@@ -18,16 +18,45 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/docker/go-units"
 )
 
 // ImagePullBehaviorType is an enum variable type corresponding to different agent pull
 // behaviors including default, always, never and once.
 type ImagePullBehaviorType int8
 
+// ImagePullErrorClass is a class of image pull errors, whose retries can be
+// configured independently of the other classes.
+type ImagePullErrorClass string
+
+// ImagePullRetryPolicy overrides how the image pull errors of a class are retried.
+// Zero delays fall back to the delays configured for every image pull error.
+type ImagePullRetryPolicy struct {
+	// NoRetry fails the image pull on the first error of the class
+	NoRetry bool
+	// BaseDelay is the delay before the first retry after an error of the class
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries after errors of the class
+	MaxDelay time.Duration
+}
+
+// StopSignal is a step of a container stop signal sequence. The signal is sent to
+// the container, which is then given Wait to exit before the next step.
+type StopSignal struct {
+	// Signal is the name or number of the signal, such as SIGUSR1
+	Signal string
+	// Wait is how long to wait for the container to exit after sending the signal
+	Wait time.Duration
+}
+
 // ContainerInstancePropagateTagsFromType is an enum variable type corresponding to different
 // ways to propagate tags, it includes none (default) and ec2_instance.
 type ContainerInstancePropagateTagsFromType int8
 
+// MemoryPressurePolicyType is an enum variable type corresponding to what the agent does
+// when a task is under memory pressure, including none (default), report and evict.
+type MemoryPressurePolicyType int8
+
 type Config struct {
 	// DEPRECATED
 	// ClusterArn is the Name or full ARN of a Cluster to register into. It has
@@ -70,6 +99,20 @@ type Config struct {
 	// as the same ContainerInstance. It defaults to false.
 	Checkpoint BooleanDefaultFalse
 
+	// DataCompactionInterval is how often the checkpoint file is compacted, to release
+	// the space of the deleted data. A negative value disables the compaction
+	DataCompactionInterval time.Duration
+
+	// DataBackend is how the checkpoint is stored in DataDir, DataBackendBoltDB or
+	// DataBackendFile
+	DataBackend string `trim:"true"`
+
+	// LifecycleHooksDir is the directory of the lifecycle hooks run for the containers of
+	// tasks, in a subdirectory per event: pre-pull, post-start, pre-stop and post-stop
+	LifecycleHooksDir string
+	// LifecycleHookTimeout is how long a lifecycle hook can run before it's killed
+	LifecycleHookTimeout time.Duration
+
 	// EngineAuthType configures what type of data is in EngineAuthData.
 	// Supported types, right now, can be found in the dockerauth package: https://godoc.org/github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerauth
 	EngineAuthType string `trim:"true"`
@@ -77,6 +120,12 @@ type Config struct {
 	// for EngineAuthType for more information.
 	EngineAuthData *SensitiveRawMessage
 
+	// DockerCredentialHelpers maps registry hostnames to the docker credential
+	// helper used to get credentials for them. The helper is named by the suffix
+	// of its binary, e.g. "ecr-login" for docker-credential-ecr-login, which is
+	// looked up in the agent's PATH.
+	DockerCredentialHelpers map[string]string
+
 	// UpdatesEnabled specifies whether updates should be applied to this agent.
 	// Default true
 	UpdatesEnabled BooleanDefaultFalse
@@ -97,6 +146,79 @@ type Config struct {
 	// again when PollMetrics is set to true
 	PollingMetricsWaitDuration time.Duration
 
+	// StatsCollectionInterval configures how often the stats of each container are collected.
+	// Setting it enables polling metrics, and it supersedes PollingMetricsWaitDuration
+	StatsCollectionInterval time.Duration
+
+	// TelemetryBufferSizeMB is the maximum size, in MB, of the disk buffer keeping the telemetry
+	// that can't be sent while the agent is disconnected from the telemetry endpoint. The buffer
+	// is disabled if it's 0.
+	TelemetryBufferSizeMB int
+
+	// AWSLogsRelayEnabled specifies whether containers using the awslogs log driver log to the
+	// local log driver instead, and have their logs shipped to CloudWatch Logs by the agent
+	AWSLogsRelayEnabled BooleanDefaultFalse
+
+	// AWSLogsRelayBufferSizeMB is the maximum size, in MB, of the disk buffer keeping the logs of
+	// a container that can't be shipped to CloudWatch Logs yet, when the awslogs relay is enabled
+	AWSLogsRelayBufferSizeMB int
+
+	// ExecSessionAuditS3Bucket is the S3 bucket the records of the ECS Exec sessions are
+	// uploaded to. The sessions aren't recorded when it's empty
+	ExecSessionAuditS3Bucket string
+
+	// ExecSessionAuditS3KeyPrefix is the prefix of the keys of the ECS Exec session records
+	ExecSessionAuditS3KeyPrefix string
+
+	// ExecSessionAuditKMSKeyID is the KMS key the ECS Exec session records are encrypted
+	// with. The default encryption of the bucket is used when it's empty
+	ExecSessionAuditKMSKeyID string
+
+	// ExecSessionAuditTranscriptsEnabled specifies whether the transcripts of the ECS Exec
+	// sessions are uploaded along with their records
+	ExecSessionAuditTranscriptsEnabled BooleanDefaultFalse
+
+	// ExecMaxSessionsPerContainer caps the number of concurrent ECS Exec sessions in each
+	// container, below the limit sent by ECS. Zero keeps the limit sent by ECS
+	ExecMaxSessionsPerContainer int
+
+	// ExecMaxSessionsPerTask is the maximum number of concurrent ECS Exec sessions across the
+	// containers of a task. The newest sessions over the limit are terminated. Zero disables
+	// the limit
+	ExecMaxSessionsPerTask int
+
+	// ExecSessionIdleTimeout is how long an ECS Exec session can stay without any input or
+	// output before it's terminated. Zero disables the timeout
+	ExecSessionIdleTimeout time.Duration
+
+	// ContainerDiskUsagePollInterval specifies how often the disk space used by the
+	// writable layer and the bind mounts of each container is measured. Zero disables
+	// the measurement
+	ContainerDiskUsagePollInterval time.Duration
+
+	// EFSMountHealthCheckInterval specifies how often the EFS volumes mounted in running
+	// containers are checked for stale or unresponsive mounts, which are remounted. Zero
+	// disables the checks
+	EFSMountHealthCheckInterval time.Duration
+
+	// MemoryPressurePolicy specifies what the agent does when the memory of a task is
+	// under pressure: nothing, reporting it in the task metadata, or also stopping the
+	// non-essential container of the task with the lowest eviction priority
+	MemoryPressurePolicy MemoryPressurePolicyType
+
+	// MemoryPressureCheckInterval specifies how often the memory pressure of the tasks
+	// is checked when a memory pressure policy is set
+	MemoryPressureCheckInterval time.Duration
+
+	// MemoryPressureStallThreshold is the share of time, in percent, the processes of a
+	// task can stall waiting for memory (the 10s average of the memory pressure stall
+	// information) before the task is under memory pressure. Only on cgroup v2 hosts
+	MemoryPressureStallThreshold float64
+
+	// MemoryPressureUsageThreshold is the memory usage of a task, in percent of its
+	// memory limit, above which the task is under memory pressure
+	MemoryPressureUsageThreshold float64
+
 	// DisableDockerHealthCheck configures whether container health feature was enabled
 	// on the instance
 	DisableDockerHealthCheck BooleanDefaultFalse
@@ -109,12 +231,50 @@ type Config struct {
 	// containers managed by ECS
 	DockerStopTimeout time.Duration
 
+	// ContainerStopSignalSequence lists the signals sent to containers that don't
+	// specify their own sequence, in order, before they are stopped with the regular
+	// stop signal and DockerStopTimeout
+	ContainerStopSignalSequence []StopSignal
+
+	// ContainerDefaultUlimits are the ulimits of the containers of the tasks whose task
+	// definitions don't set them, such as nofile=1024:4096. They are lowered to the hard
+	// limits of the host when higher.
+	ContainerDefaultUlimits []*units.Ulimit
+
 	// ContainerStartTimeout specifies the amount of time to wait to start a container
 	ContainerStartTimeout time.Duration
 
 	// ContainerCreateTimeout specifies the amount of time to wait to create a container
 	ContainerCreateTimeout time.Duration
 
+	// TaskContainerStartConcurrency is the maximum number of containers of a task
+	// that are created or started at the same time, for tasks that don't set their
+	// own limit. Zero means no limit
+	TaskContainerStartConcurrency int
+
+	// DependencyOrderedShutdown makes the containers of a stopping task wait for the
+	// containers that link to them or use their volumes to stop, in addition to the
+	// containers that declare a container ordering dependency on them
+	DependencyOrderedShutdown BooleanDefaultFalse
+
+	// ContainerShutdownGracePeriod is how long a container of a stopping task keeps
+	// running after the containers depending on it stopped, for containers that don't
+	// set their own grace period. Zero means no grace period
+	ContainerShutdownGracePeriod time.Duration
+
+	// ContainerCheckpointInterval specifies how often the running containers of tasks
+	// with checkpointing enabled are checkpointed. Zero disables checkpointing
+	ContainerCheckpointInterval time.Duration
+
+	// ContainerCheckpointDir is the directory container checkpoints are stored in, such
+	// as an EBS volume mount. Docker's default directory is used when it's empty
+	ContainerCheckpointDir string
+
+	// FirelensConfigReloadInterval specifies how often the config of the firelens containers
+	// of tasks with config reload enabled is regenerated, to signal the containers to reload
+	// it when it changed. Zero disables config reloads
+	FirelensConfigReloadInterval time.Duration
+
 	// DependentContainersPullUpfront specifies whether pulling images upfront should be applied to this agent.
 	// Default false
 	DependentContainersPullUpfront BooleanDefaultFalse
@@ -125,6 +285,47 @@ type Config struct {
 	//ImagePullTimeout is here to override the timeout for PullImage API
 	ImagePullTimeout time.Duration
 
+	// ImagePullMaxBandwidthMbps caps the estimated bandwidth, in megabits per second,
+	// used by the image pulls that run at the same time. Zero means no limit
+	ImagePullMaxBandwidthMbps float64
+
+	// ImagePullSOCILazyLoading enables the lazy loading of the ECR images that have a SOCI
+	// index, when docker uses the containerd image store with the SOCI snapshotter
+	ImagePullSOCILazyLoading BooleanDefaultFalse
+
+	// ImagePullMaxAttempts is the number of times an image pull is attempted before
+	// it is failed
+	ImagePullMaxAttempts int
+
+	// ImagePullRetryBaseDelay is the delay before the first retry of a failed image pull
+	ImagePullRetryBaseDelay time.Duration
+
+	// ImagePullRetryMaxDelay caps the exponential backoff between image pull retries
+	ImagePullRetryMaxDelay time.Duration
+
+	// ImagePullRetryPolicies overrides the retry behavior for classes of image pull
+	// errors, such as registry throttling or images that do not exist
+	ImagePullRetryPolicies map[ImagePullErrorClass]ImagePullRetryPolicy
+
+	// ECRTokenRefreshWindow specifies how long before their expiry cached ECR auth
+	// tokens are refreshed in the background, so that image pulls do not have to
+	// wait for a new token to be fetched
+	ECRTokenRefreshWindow time.Duration
+
+	// TaskCredentialsRefreshWindow specifies how long before their expiry task IAM role
+	// credentials are requested again from ACS, in case ACS did not refresh them yet
+	TaskCredentialsRefreshWindow time.Duration
+
+	// PersistECRTokenCache specifies whether ECR auth tokens are persisted, encrypted, in the
+	// agent's data directory, so that an agent restart does not require fetching them again.
+	// It only takes effect when checkpointing is enabled. Default false
+	PersistECRTokenCache BooleanDefaultFalse
+
+	// ECRPublicAuthEnabled specifies whether images are pulled from the ECR Public Gallery
+	// with an auth token fetched with the instance credentials, instead of anonymously.
+	// Default false
+	ECRPublicAuthEnabled BooleanDefaultFalse
+
 	// AvailableLoggingDrivers specifies the logging drivers available for use
 	// with Docker.  If not set, it defaults to ["json-file","none"].
 	AvailableLoggingDrivers []dockerclient.LoggingDriver
@@ -141,10 +342,27 @@ type Config struct {
 	// security options
 	AppArmorCapable BooleanDefaultFalse
 
+	// SecurityProfilesDir is the directory of the seccomp and AppArmor profiles the
+	// containers can reference by name, in a seccomp and an apparmor subdirectory
+	SecurityProfilesDir string
+
+	// SecurityProfilesS3ARN is the ARN of the S3 bucket, or of the folder in it, the
+	// profiles not found in SecurityProfilesDir are fetched from, with the same layout
+	SecurityProfilesS3ARN string
+
+	// SecurityProfilesCacheTTL is how long the security profiles are cached for the other
+	// containers that reference them. A negative value disables caching
+	SecurityProfilesCacheTTL time.Duration
+
 	// TaskCleanupWaitDuration specifies the time to wait after a task is stopped
 	// until cleanup of task resources is started.
 	TaskCleanupWaitDuration time.Duration
 
+	// StoppedTaskHistoryRetention is how long the tasks that were cleaned up are still
+	// listed, with their stop reason and the exit codes of their containers, by the
+	// introspection API. A negative value disables the history
+	StoppedTaskHistoryRetention time.Duration
+
 	// TaskIAMRoleEnabled specifies if the Agent is capable of launching
 	// tasks with IAM Roles.
 	TaskIAMRoleEnabled BooleanDefaultFalse
@@ -161,6 +379,10 @@ type Config struct {
 	// CredentialsAuditLogEnabled specifies whether audit logging is disabled.
 	CredentialsAuditLogDisabled bool
 
+	// CredentialsAuditJSONLogFile specifies the path/filename of the audit log of
+	// credentials requests with one JSON record per line. It is disabled when empty.
+	CredentialsAuditJSONLogFile string
+
 	// TaskIAMRoleEnabledForNetworkHost specifies if the Agent is capable of launching
 	// tasks with IAM Roles when networkMode is set to 'host'
 	TaskIAMRoleEnabledForNetworkHost bool
@@ -188,6 +410,19 @@ type Config struct {
 	// cleanup since last time it was executed
 	ImageCleanupInterval time.Duration
 
+	// ImageCleanupDiskHighWatermark is the usage, in percent, of the docker data root
+	// disk above which unused images are deleted until the usage is below
+	// ImageCleanupDiskLowWatermark. Zero disables cleanup based on disk usage
+	ImageCleanupDiskHighWatermark int
+
+	// ImageCleanupDiskLowWatermark is the disk usage, in percent, down to which unused
+	// images are deleted once the usage is above ImageCleanupDiskHighWatermark
+	ImageCleanupDiskLowWatermark int
+
+	// ImageCleanupDiskCheckInterval is how often the usage of the docker data root disk
+	// is checked against ImageCleanupDiskHighWatermark, in between image cleanups
+	ImageCleanupDiskCheckInterval time.Duration
+
 	// NumImagesToDeletePerCycle specifies the num of image to delete every time
 	// when Agent performs cleanup
 	NumImagesToDeletePerCycle int
@@ -206,9 +441,27 @@ type Config struct {
 	// placement.
 	InstanceAttributes map[string]string
 
+	// InstanceAttributeProviders are the names of the built-in providers whose discovered
+	// attributes are registered with the container instance, among kernel-version,
+	// cuda-version, local-nvme-size and nsenter
+	InstanceAttributeProviders []string
+
+	// InstanceAttributePluginsDir is the directory of the executables that discover
+	// attributes of the container instance, each printing one name=value attribute per line.
+	// On Linux they run in the container of the agent, so they must be static binaries
+	InstanceAttributePluginsDir string
+
+	// InstanceAttributeRefreshInterval is how often the attributes are discovered again,
+	// the ones that changed since they were registered are updated with PutAttributes
+	InstanceAttributeRefreshInterval time.Duration
+
 	// Set if clients validate ssl certificates. Used mainly for testing
 	AcceptInsecureCert bool `json:"-"`
 
+	// ACSCABundle is the path of a PEM bundle of CAs to trust, along with the system ones, when
+	// connecting to the ACS and TCS websocket endpoints, e.g. the CA of a TLS intercepting proxy
+	ACSCABundle string
+
 	// CNIPluginsPath is the path for the cni plugins
 	CNIPluginsPath string
 
@@ -230,10 +483,20 @@ type Config struct {
 	// default.
 	PrometheusMetricsEnabled bool
 
+	// PrometheusMetricsBindAddress is the IP address the Prometheus metrics
+	// endpoint listens on. It defaults to 127.0.0.1, and can be set to 0.0.0.0
+	// to allow scraping from outside the instance.
+	PrometheusMetricsBindAddress string
+
 	// AWSVPCBlockInstanceMetdata specifies if InstanceMetadata endpoint should be blocked
 	// for tasks that are launched with network mode "awsvpc" when ECS_AWSVPC_BLOCK_IMDS=true
 	AWSVPCBlockInstanceMetdata BooleanDefaultFalse
 
+	// AWSVPCContainerNetworkStats specifies if the network stats of tasks that are
+	// launched with network mode "awsvpc" should be attributed to each container of
+	// the task, instead of being split evenly between them
+	AWSVPCContainerNetworkStats BooleanDefaultFalse
+
 	// OverrideAWSVPCLocalIPv4Address overrides the local IPv4 address chosen
 	// for a task using the `awsvpc` networking mode. Using this configuration
 	// will limit you to running one `awsvpc` task at a time. IPv4 addresses
@@ -273,6 +536,27 @@ type Config struct {
 	// default behavior is to match name only, and does not propagate driver options and labels through volume drivers.
 	SharedVolumeMatchFullConfig BooleanDefaultFalse
 
+	// EphemeralStorageQuotaEnabled specifies whether the writable layers of the containers of
+	// tasks whose task definition sets an ephemeral storage size are limited to that size
+	EphemeralStorageQuotaEnabled BooleanDefaultFalse
+
+	// EphemeralVolumeEncryption specifies whether the task scoped volumes of the local driver
+	// without driver options, and the volumes without a host path, are backed by dm-crypt
+	// devices encrypted with ephemeral keys generated for each volume
+	EphemeralVolumeEncryption BooleanDefaultFalse
+
+	// EncryptedVolumeSizeMB is the size, in MB, of the dm-crypt devices backing the encrypted
+	// ephemeral volumes
+	EncryptedVolumeSizeMB int
+
+	// TaskNetworkPolicyFile is the path of the egress network policy document enforced in
+	// the network namespace of awsvpc tasks that aren't given a policy of their own
+	TaskNetworkPolicyFile string
+
+	// TaskDNSCacheQueriesPerSecond is the number of DNS queries per second the local DNS
+	// cache of an awsvpc task forwards to its resolvers, when the task doesn't set it
+	TaskDNSCacheQueriesPerSecond int
+
 	// NoIID when set to true, specifies that the agent should not register the instance
 	// with instance identity document. This is required in order to accomodate scenarios in
 	// which ECS agent tries to register the instance where the instance id document is
@@ -292,6 +576,10 @@ type Config struct {
 
 	// GPUSupportEnabled specifies if the Agent is capable of launching GPU tasks
 	GPUSupportEnabled bool
+	// GPUMetricsEnabled specifies if the utilization of the GPUs assigned to containers is sampled
+	// and reported in the container metrics and the task metadata stats. It only applies when GPU
+	// support is enabled.
+	GPUMetricsEnabled BooleanDefaultFalse
 	// InferentiaSupportEnabled specifies whether the built-in support for inferentia task is enabled.
 	InferentiaSupportEnabled bool
 
@@ -304,6 +592,10 @@ type Config struct {
 	// TaskMetadataAZDisabled specifies if availability zone should be disabled in Task Metadata endpoint
 	TaskMetadataAZDisabled bool
 
+	// TaskMetadataNamedPipeEnabled specifies if the task metadata and credentials endpoints should
+	// also be served over a named pipe mounted in the containers of each task. It's only supported on Windows.
+	TaskMetadataNamedPipeEnabled BooleanDefaultFalse
+
 	// ENIPauseContainerCleanupDelaySeconds specifies how long to wait before cleaning up the pause container after all
 	// other containers have stopped.
 	ENIPauseContainerCleanupDelaySeconds int
@@ -319,10 +611,92 @@ type Config struct {
 	// see https://docs.aws.amazon.com/AmazonECS/latest/developerguide/container-instance-draining.html
 	SpotInstanceDrainingEnabled BooleanDefaultFalse
 
+	// SpotRebalanceDrainingEnabled, if true, agent will also set the instance's state to
+	// DRAINING when EC2 sends a rebalance recommendation for the spot instance. Defaults
+	// to false.
+	SpotRebalanceDrainingEnabled BooleanDefaultFalse
+
+	// ASGTerminationDrainingEnabled, if true, agent will set the instance's state to
+	// DRAINING when its auto scaling group starts terminating it, which gives the tasks
+	// time to stop while a termination lifecycle hook holds the instance. Defaults to false.
+	ASGTerminationDrainingEnabled BooleanDefaultFalse
+
+	// DisableTaskProtectionOnInterruption, if true, agent will disable the scale-in
+	// protection of the tasks on the instance once it's draining because of an
+	// interruption notice, so that their services can replace them. Defaults to false.
+	DisableTaskProtectionOnInterruption BooleanDefaultFalse
+
+	// WarmPoolsCheck, if true, agent waits for the auto scaling group to move the instance
+	// out of its warm pool before registering it in the cluster. Defaults to false.
+	WarmPoolsCheck BooleanDefaultFalse
+
+	// SkipCleanupAfterHibernation, if true, the containers of the tasks that were known by
+	// the agent when the instance hibernated are kept when these tasks are cleaned up.
+	// Defaults to false.
+	SkipCleanupAfterHibernation BooleanDefaultFalse
+
+	// NUMAPlacementEnabled, if true, agent places the tasks whose resource controls
+	// request it on a single NUMA node, pinning their cpus and memory to it. It requires
+	// the task cpu and memory limits. Defaults to false.
+	NUMAPlacementEnabled BooleanDefaultFalse
+
+	// PprofEnabled, if true, serves the CPU, heap, goroutine, mutex and other runtime
+	// profiles of the agent on the introspection server, under /debug/pprof/, to requests
+	// from the instance itself. Defaults to false.
+	PprofEnabled BooleanDefaultFalse
+
+	// TaskInitProcessEnabled, if true, runs an init process as PID 1 of the containers
+	// of the tasks that don't choose themselves, to forward signals and reap zombie
+	// processes. It isn't supported on Windows. Defaults to false.
+	TaskInitProcessEnabled BooleanDefaultFalse
+
+	// SecretsCacheTTL is how long the secret values fetched from SSM and Secrets Manager
+	// are cached for the other tasks with the same execution role that use them. Caching
+	// is disabled unless it's set
+	SecretsCacheTTL time.Duration
+
+	// SecretsFetchJitter is the maximum random delay before the tasks fetch the secrets
+	// missing from the cache, so that the tasks started together read the values cached
+	// by the first one instead of all fetching them. It only applies with SecretsCacheTTL
+	SecretsFetchJitter time.Duration
+
+	// SecretsRefreshInterval specifies how often the secrets of the running tasks with file
+	// secrets are resolved again, to rewrite the files whose values were rotated. Zero
+	// disables periodic refreshes, secrets are then only refreshed on request from ACS
+	SecretsRefreshInterval time.Duration
+
+	// SecretsRefreshSignal is the signal sent to the containers whose file secrets were
+	// rotated, unless they set their own
+	SecretsRefreshSignal string
+
+	// VaultAddr is the address of the HashiCorp Vault server the "vault" container secrets
+	// are read from
+	VaultAddr string
+
+	// VaultNamespace is the Vault Enterprise namespace of the "vault" container secrets
+	VaultNamespace string
+
+	// VaultAWSAuthMount is the path the AWS auth method is mounted at in Vault. The tasks
+	// log in to Vault with it, using their task role credentials
+	VaultAWSAuthMount string
+
+	// VaultAWSAuthRole is the Vault role the tasks log in with. Vault uses the name of the
+	// IAM role of the task when it's empty
+	VaultAWSAuthRole string
+
+	// VaultAWSIAMServerID is the value of the X-Vault-AWS-IAM-Server-ID header of the login
+	// requests, for the AWS auth methods that require it
+	VaultAWSIAMServerID string
+
 	// GMSACapable is the config option to indicate if gMSA is supported.
 	// It should be enabled by default only if the container instance is part of a valid active directory domain.
 	GMSACapable bool
 
+	// GMSACredentialSpecCacheTTL is how long the gMSA credential specs fetched from S3,
+	// SSM and Secrets Manager are cached for the other tasks that use them. A negative
+	// value disables caching
+	GMSACredentialSpecCacheTTL time.Duration
+
 	// VolumePluginCapabilities specifies the capabilities of the ecs volume plugin.
 	VolumePluginCapabilities []string
 
@@ -333,7 +707,21 @@ type Config struct {
 	// External specifies whether agent is running on external compute capacity (i.e. outside of aws).
 	External BooleanDefaultFalse
 
+	// OTelExporterEndpoint is the OTLP/HTTP endpoint of the OpenTelemetry collector where
+	// the spans of the agent operations are exported. Tracing is disabled when empty.
+	OTelExporterEndpoint string
+
+	// ReloadableConfigFile is the config file, in the format of ecs.config, that the log
+	// levels, the reserved memory, the image cleanup settings and the poll intervals are
+	// read again from when the agent receives SIGHUP or the file changes. Reloading is
+	// disabled when empty
+	ReloadableConfigFile string
+
 	// InstanceENIDNSServerList stores the list of DNS servers for the primary instance ENI.
 	// Currently, this field is only populated for Windows and is used during task networking setup.
 	InstanceENIDNSServerList []string
+
+	// ValidationProblems are the problems found while loading the config, they are part of
+	// the configuration report
+	ValidationProblems []ValidationProblem `json:"-"`
 }
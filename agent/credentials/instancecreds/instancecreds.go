@@ -14,6 +14,7 @@
 package instancecreds
 
 import (
+	"context"
 	"sync"
 
 	"github.com/aws/amazon-ecs-agent/agent/credentials/providers"
@@ -28,17 +29,41 @@ var (
 )
 
 // GetCredentials returns the instance credentials chain. This is the default chain
-// credentials plus the "rotating shared credentials provider", so credentials will
-// be checked in this order:
+// credentials plus the "IAM Roles Anywhere credentials provider", the "shared config
+// credentials provider" and the "rotating shared credentials provider", so credentials
+// will be checked in this order:
 //    1. Env vars (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY).
-//    2. Shared credentials file (https://docs.aws.amazon.com/ses/latest/DeveloperGuide/create-shared-credentials-file.html) (file at ~/.aws/credentials containing access key id and secret access key).
-//    3. EC2 role credentials. This is an IAM role that the user specifies when they launch their EC2 container instance (ie ecsInstanceRole (https://docs.aws.amazon.com/AmazonECS/latest/developerguide/instance_IAM_role.html)).
-//    4. Rotating shared credentials file located at /rotatingcreds/credentials
+//    2. IAM Roles Anywhere, when configured with the ECS_ROLES_ANYWHERE_* env vars, using
+//       an X.509 certificate to create sessions that are renewed before they expire.
+//    3. Shared credentials file (https://docs.aws.amazon.com/ses/latest/DeveloperGuide/create-shared-credentials-file.html) (file at ~/.aws/credentials containing access key id and secret access key).
+//    4. Shared config file profile (file at ~/.aws/config) with a credential_process or
+//       AWS SSO role, with the SSO access token cached by `aws sso login`.
+//    5. EC2 role credentials. This is an IAM role that the user specifies when they launch their EC2 container instance (ie ecsInstanceRole (https://docs.aws.amazon.com/AmazonECS/latest/developerguide/instance_IAM_role.html)).
+//    6. Rotating shared credentials file located at /rotatingcreds/credentials, reloaded
+//       as soon as it changes
 func GetCredentials() *credentials.Credentials {
 	mu.Lock()
 	if credentialChain == nil {
 		credProviders := defaults.CredProviders(defaults.Config(), defaults.Handlers())
-		credProviders = append(credProviders, providers.NewRotatingSharedCredentialsProvider())
+		// the shared config credentials provider goes before the EC2 role credentials
+		// provider, which is the last of the default providers
+		remoteCredentialsProvider := credProviders[len(credProviders)-1]
+		rolesAnywhereCredentialsProvider, err := providers.NewRolesAnywhereCredentialsProvider()
+		if err != nil {
+			seelog.Errorf("Not using IAM Roles Anywhere for instance credentials: %v", err)
+		} else if rolesAnywhereCredentialsProvider != nil {
+			// the IAM Roles Anywhere credentials provider goes right after the env
+			// vars credentials provider, which is the first of the default providers
+			credProviders = append([]credentials.Provider{credProviders[0], rolesAnywhereCredentialsProvider},
+				credProviders[1:]...)
+		}
+		credProviders = append(credProviders[:len(credProviders)-1],
+			providers.NewSharedConfigCredentialsProvider(), remoteCredentialsProvider)
+		rotatingSharedCredentialsProvider := providers.NewRotatingSharedCredentialsProvider()
+		if err := rotatingSharedCredentialsProvider.WatchFile(context.Background()); err != nil {
+			seelog.Debugf("Not watching the rotating shared credentials file, credentials will be rotated at a fixed interval: %v", err)
+		}
+		credProviders = append(credProviders, rotatingSharedCredentialsProvider)
 		credentialChain = credentials.NewCredentials(&credentials.ChainProvider{
 			VerboseErrors: false,
 			Providers:     credProviders,
@@ -16,6 +16,7 @@ package credentials
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/aws-sdk-go/aws"
@@ -64,6 +65,10 @@ type IAMRoleCredentials struct {
 	RoleType string `json:"-"`
 }
 
+// credentialsExpirationLayouts are the layouts of the expiration of credentials sent by
+// ACS, with or without fractional seconds
+var credentialsExpirationLayouts = []string{time.RFC3339, "2006-01-02T15:04:05Z0700"}
+
 // TaskIAMRoleCredentials wraps the task arn and the credentials object for the same
 type TaskIAMRoleCredentials struct {
 	ARN                string
@@ -85,6 +90,26 @@ func (roleCredentials *IAMRoleCredentials) GenerateCredentialsEndpointRelativeUR
 	return fmt.Sprintf(credentialsEndpointRelativeURIFormat, CredentialsPath, roleCredentials.CredentialsID)
 }
 
+// ExpirationTime parses the expiration of the credentials
+func (roleCredentials *IAMRoleCredentials) ExpirationTime() (time.Time, error) {
+	var err error
+	for _, layout := range credentialsExpirationLayouts {
+		var expiration time.Time
+		if expiration, err = time.Parse(layout, roleCredentials.Expiration); err == nil {
+			return expiration, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse expiration of credentials %s: %v", roleCredentials.CredentialsID, err)
+}
+
+// CredentialsExpiry is the expiration of a set of task IAM role credentials
+type CredentialsExpiry struct {
+	TaskARN       string
+	CredentialsID string
+	RoleType      string
+	Expiration    time.Time
+}
+
 // credentialsManager implements the Manager interface. It is used to
 // save credentials sent from ACS and to retrieve credentials from
 // the credentials endpoint
@@ -162,3 +187,28 @@ func (manager *credentialsManager) RemoveCredentials(id string) {
 
 	delete(manager.idToTaskCredentials, id)
 }
+
+// GetCredentialsExpiry returns the expiration of all the credentials in the credentials
+// manager. Credentials with an expiration that can't be parsed are skipped.
+func (manager *credentialsManager) GetCredentialsExpiry() []CredentialsExpiry {
+	manager.taskCredentialsLock.RLock()
+	defer manager.taskCredentialsLock.RUnlock()
+
+	expiry := make([]CredentialsExpiry, 0, len(manager.idToTaskCredentials))
+	for id := range manager.idToTaskCredentials {
+		// credentials are only replaced under the manager lock, and the map entries
+		// are not addressable, so their fields are read directly
+		credentials := manager.idToTaskCredentials[id].IAMRoleCredentials
+		expiration, err := credentials.ExpirationTime()
+		if err != nil {
+			continue
+		}
+		expiry = append(expiry, CredentialsExpiry{
+			TaskARN:       manager.idToTaskCredentials[id].ARN,
+			CredentialsID: credentials.CredentialsID,
+			RoleType:      credentials.RoleType,
+			Expiration:    expiration,
+		})
+	}
+	return expiry
+}
@@ -18,6 +18,7 @@ package credentials
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/aws-sdk-go/aws"
@@ -171,3 +172,52 @@ func TestRemoveExistingCredentials(t *testing.T) {
 		t.Error("Expected GetTaskCredentials to return false for removed credentials")
 	}
 }
+
+// TestExpirationTime tests if the expiration of credentials sent from ACS is parsed
+// with or without fractional seconds
+func TestExpirationTime(t *testing.T) {
+	for _, expiration := range []string{"2016-03-25T06:17:19.318+0000", "2016-03-25T06:17:19Z"} {
+		t.Run(expiration, func(t *testing.T) {
+			credentials := IAMRoleCredentials{Expiration: expiration}
+			expirationTime, err := credentials.ExpirationTime()
+			assert.NoError(t, err)
+			assert.Equal(t, time.Date(2016, 3, 25, 6, 17, 19, 0, time.UTC), expirationTime.UTC().Truncate(time.Second))
+		})
+	}
+
+	credentials := IAMRoleCredentials{Expiration: "soon"}
+	_, err := credentials.ExpirationTime()
+	assert.Error(t, err)
+}
+
+// TestGetCredentialsExpiry tests if GetCredentialsExpiry returns the expiration of
+// all the credentials with a valid expiration
+func TestGetCredentialsExpiry(t *testing.T) {
+	manager := NewManager()
+	err := manager.SetTaskCredentials(&TaskIAMRoleCredentials{
+		ARN: "t1",
+		IAMRoleCredentials: IAMRoleCredentials{
+			CredentialsID: "cid1",
+			RoleType:      ApplicationRoleType,
+			Expiration:    "2016-03-25T06:17:19Z",
+		},
+	})
+	assert.NoError(t, err)
+	err = manager.SetTaskCredentials(&TaskIAMRoleCredentials{
+		ARN: "t2",
+		IAMRoleCredentials: IAMRoleCredentials{
+			CredentialsID: "cid2",
+			RoleType:      ExecutionRoleType,
+			Expiration:    "soon",
+		},
+	})
+	assert.NoError(t, err)
+
+	expiry := manager.GetCredentialsExpiry()
+	assert.Equal(t, []CredentialsExpiry{{
+		TaskARN:       "t1",
+		CredentialsID: "cid1",
+		RoleType:      ApplicationRoleType,
+		Expiration:    time.Date(2016, 3, 25, 6, 17, 19, 0, time.UTC),
+	}}, expiry)
+}
@@ -48,6 +48,20 @@ func (m *MockManager) EXPECT() *MockManagerMockRecorder {
 	return m.recorder
 }
 
+// GetCredentialsExpiry mocks base method
+func (m *MockManager) GetCredentialsExpiry() []credentials.CredentialsExpiry {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredentialsExpiry")
+	ret0, _ := ret[0].([]credentials.CredentialsExpiry)
+	return ret0
+}
+
+// GetCredentialsExpiry indicates an expected call of GetCredentialsExpiry
+func (mr *MockManagerMockRecorder) GetCredentialsExpiry() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredentialsExpiry", reflect.TypeOf((*MockManager)(nil).GetCredentialsExpiry))
+}
+
 // GetTaskCredentials mocks base method
 func (m *MockManager) GetTaskCredentials(arg0 string) (credentials.TaskIAMRoleCredentials, bool) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,91 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/cihub/seelog"
+	"github.com/fsnotify/fsnotify"
+)
+
+// credentialsFileWatcher watches the directory of the shared credentials file,
+// to invalidate the credentials retrieved from the file as soon as it changes.
+// The directory is watched rather than the file, so that the file is still
+// watched after it is replaced.
+type credentialsFileWatcher struct {
+	filename string
+	watching int32
+	changed  int32
+}
+
+// start starts watching the credentials file until the context is canceled,
+// or the watcher fails
+func (w *credentialsFileWatcher) start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(w.filename)); err != nil {
+		watcher.Close()
+		return err
+	}
+	atomic.StoreInt32(&w.watching, 1)
+	go w.watch(ctx, watcher)
+	return nil
+}
+
+func (w *credentialsFileWatcher) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+	defer atomic.StoreInt32(&w.watching, 0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			seelog.Debugf("Credentials file watcher: %s", event)
+			atomic.StoreInt32(&w.changed, 1)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			seelog.Warnf("Unable to watch credentials file %s, credentials will be rotated at a fixed interval: %v",
+				w.filename, err)
+			return
+		}
+	}
+}
+
+// isWatching returns true while the credentials file is watched
+func (w *credentialsFileWatcher) isWatching() bool {
+	return atomic.LoadInt32(&w.watching) == 1
+}
+
+// hasChanged returns true if the credentials file changed since the last reset
+func (w *credentialsFileWatcher) hasChanged() bool {
+	return atomic.LoadInt32(&w.changed) == 1
+}
+
+// reset is called before the credentials file is read
+func (w *credentialsFileWatcher) reset() {
+	atomic.StoreInt32(&w.changed, 0)
+}
@@ -0,0 +1,335 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// RolesAnywhereCredentialsProviderName is the name of this provider
+	RolesAnywhereCredentialsProviderName = "RolesAnywhereCredentialsProvider"
+
+	rolesAnywhereCertificateEnvVar    = "ECS_ROLES_ANYWHERE_CERTIFICATE"
+	rolesAnywherePrivateKeyEnvVar     = "ECS_ROLES_ANYWHERE_PRIVATE_KEY"
+	rolesAnywhereTrustAnchorARNEnvVar = "ECS_ROLES_ANYWHERE_TRUST_ANCHOR_ARN"
+	rolesAnywhereProfileARNEnvVar     = "ECS_ROLES_ANYWHERE_PROFILE_ARN"
+	rolesAnywhereRoleARNEnvVar        = "ECS_ROLES_ANYWHERE_ROLE_ARN"
+
+	// rolesAnywhereSessionDuration is the duration of the sessions created with IAM
+	// Roles Anywhere
+	rolesAnywhereSessionDuration = time.Hour
+	// rolesAnywhereExpiryWindow is how long before they expire sessions are renewed
+	rolesAnywhereExpiryWindow = 5 * time.Minute
+	// rolesAnywhereRequestTimeout is the timeout of the CreateSession requests
+	rolesAnywhereRequestTimeout = 30 * time.Second
+
+	rolesAnywhereService       = "rolesanywhere"
+	rolesAnywhereSessionsPath  = "/sessions"
+	rolesAnywhereDateFormat    = "20060102T150405Z"
+	rolesAnywhereRSAAlgorithm  = "AWS4-X509-RSA-SHA256"
+	rolesAnywhereECAlgorithm   = "AWS4-X509-ECDSA-SHA256"
+	rolesAnywhereX509Header    = "X-Amz-X509"
+	rolesAnywhereChainHeader   = "X-Amz-X509-Chain"
+	rolesAnywhereDateHeader    = "X-Amz-Date"
+	rolesAnywhereContentHeader = "Content-Type"
+)
+
+// rolesAnywhereCreateSessionRequest is the body of the IAM Roles Anywhere CreateSession request
+type rolesAnywhereCreateSessionRequest struct {
+	DurationSeconds int    `json:"durationSeconds"`
+	ProfileARN      string `json:"profileArn"`
+	RoleARN         string `json:"roleArn"`
+	TrustAnchorARN  string `json:"trustAnchorArn"`
+}
+
+// rolesAnywhereCreateSessionResponse is the response of the IAM Roles Anywhere CreateSession request
+type rolesAnywhereCreateSessionResponse struct {
+	CredentialSet []struct {
+		Credentials struct {
+			AccessKeyID     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      string `json:"expiration"`
+		} `json:"credentials"`
+	} `json:"credentialSet"`
+}
+
+// RolesAnywhereCredentialsProvider is a provider that retrieves temporary credentials
+// from IAM Roles Anywhere, with an X.509 certificate and its private key, instead of
+// long-lived access keys. The certificate and key are read every time a session is
+// created, so that renewed certificates are picked up.
+type RolesAnywhereCredentialsProvider struct {
+	credentials.Expiry
+
+	// CertificateFile is the PEM file of the certificate, optionally followed by the
+	// intermediate certificates of its chain
+	CertificateFile string
+	// PrivateKeyFile is the PEM file of the private key of the certificate
+	PrivateKeyFile string
+	TrustAnchorARN string
+	ProfileARN     string
+	RoleARN        string
+
+	region   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewRolesAnywhereCredentialsProvider returns an IAM Roles Anywhere credentials provider
+// configured by the ECS_ROLES_ANYWHERE_* environment variables, or nil if IAM Roles
+// Anywhere is not configured.
+func NewRolesAnywhereCredentialsProvider() (*RolesAnywhereCredentialsProvider, error) {
+	p := &RolesAnywhereCredentialsProvider{
+		CertificateFile: os.Getenv(rolesAnywhereCertificateEnvVar),
+		PrivateKeyFile:  os.Getenv(rolesAnywherePrivateKeyEnvVar),
+		TrustAnchorARN:  os.Getenv(rolesAnywhereTrustAnchorARNEnvVar),
+		ProfileARN:      os.Getenv(rolesAnywhereProfileARNEnvVar),
+		RoleARN:         os.Getenv(rolesAnywhereRoleARNEnvVar),
+		client:          &http.Client{Timeout: rolesAnywhereRequestTimeout},
+	}
+	if p.CertificateFile == "" && p.PrivateKeyFile == "" && p.TrustAnchorARN == "" &&
+		p.ProfileARN == "" && p.RoleARN == "" {
+		return nil, nil
+	}
+	for envVar, value := range map[string]string{
+		rolesAnywhereCertificateEnvVar:    p.CertificateFile,
+		rolesAnywherePrivateKeyEnvVar:     p.PrivateKeyFile,
+		rolesAnywhereTrustAnchorARNEnvVar: p.TrustAnchorARN,
+		rolesAnywhereProfileARNEnvVar:     p.ProfileARN,
+		rolesAnywhereRoleARNEnvVar:        p.RoleARN,
+	} {
+		if value == "" {
+			return nil, fmt.Errorf("IAM Roles Anywhere is partially configured, %s is not set", envVar)
+		}
+	}
+	trustAnchorARN, err := arn.Parse(p.TrustAnchorARN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IAM Roles Anywhere trust anchor ARN %s: %v", p.TrustAnchorARN, err)
+	}
+	p.region = trustAnchorARN.Region
+	p.endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", rolesAnywhereService, p.region)
+	return p, nil
+}
+
+// Retrieve creates an IAM Roles Anywhere session and returns its credentials.
+func (p *RolesAnywhereCredentialsProvider) Retrieve() (credentials.Value, error) {
+	certificates, signer, err := loadRolesAnywhereCertificate(p.CertificateFile, p.PrivateKeyFile)
+	if err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName}, err
+	}
+	body, err := json.Marshal(&rolesAnywhereCreateSessionRequest{
+		DurationSeconds: int(rolesAnywhereSessionDuration.Seconds()),
+		ProfileARN:      p.ProfileARN,
+		RoleARN:         p.RoleARN,
+		TrustAnchorARN:  p.TrustAnchorARN,
+	})
+	if err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName}, err
+	}
+	request, err := http.NewRequest(http.MethodPost, p.endpoint+rolesAnywhereSessionsPath, bytes.NewReader(body))
+	if err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName}, err
+	}
+	if err := signRolesAnywhereRequest(request, body, p.region, certificates, signer, time.Now()); err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName},
+			fmt.Errorf("unable to sign IAM Roles Anywhere request: %v", err)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName},
+			fmt.Errorf("unable to create IAM Roles Anywhere session: %v", err)
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName},
+			fmt.Errorf("unable to create IAM Roles Anywhere session: %v", err)
+	}
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName},
+			fmt.Errorf("unable to create IAM Roles Anywhere session: status %d: %s", response.StatusCode, responseBody)
+	}
+
+	var session rolesAnywhereCreateSessionResponse
+	if err := json.Unmarshal(responseBody, &session); err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName},
+			fmt.Errorf("unable to parse IAM Roles Anywhere session: %v", err)
+	}
+	if len(session.CredentialSet) == 0 {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName},
+			fmt.Errorf("IAM Roles Anywhere session has no credentials")
+	}
+	sessionCredentials := session.CredentialSet[0].Credentials
+	expiration, err := time.Parse(time.RFC3339, sessionCredentials.Expiration)
+	if err != nil {
+		return credentials.Value{ProviderName: RolesAnywhereCredentialsProviderName},
+			fmt.Errorf("unable to parse expiration of IAM Roles Anywhere session: %v", err)
+	}
+	p.SetExpiration(expiration, rolesAnywhereExpiryWindow)
+	seelog.Infof("Successfully created IAM Roles Anywhere session for role %s, expiring at %s",
+		p.RoleARN, expiration.Format(time.RFC3339))
+	return credentials.Value{
+		AccessKeyID:     sessionCredentials.AccessKeyID,
+		SecretAccessKey: sessionCredentials.SecretAccessKey,
+		SessionToken:    sessionCredentials.SessionToken,
+		ProviderName:    RolesAnywhereCredentialsProviderName,
+	}, nil
+}
+
+// loadRolesAnywhereCertificate returns the certificate chain, starting with the end
+// entity certificate, and the private key of the end entity certificate
+func loadRolesAnywhereCertificate(certificateFile string, privateKeyFile string) ([]*x509.Certificate, crypto.Signer, error) {
+	certificatePEM, err := ioutil.ReadFile(certificateFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read IAM Roles Anywhere certificate: %v", err)
+	}
+	var certificates []*x509.Certificate
+	for block, rest := pem.Decode(certificatePEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse IAM Roles Anywhere certificate: %v", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, nil, fmt.Errorf("no certificate found in %s", certificateFile)
+	}
+
+	privateKeyPEM, err := ioutil.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read IAM Roles Anywhere private key: %v", err)
+	}
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no private key found in %s", privateKeyFile)
+	}
+	var privateKey interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse IAM Roles Anywhere private key: %v", err)
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported IAM Roles Anywhere private key type %T", privateKey)
+	}
+	return certificates, signer, nil
+}
+
+// signRolesAnywhereRequest signs the request with the private key of the certificate,
+// following the IAM Roles Anywhere variant of Signature Version 4
+func signRolesAnywhereRequest(request *http.Request, body []byte, region string,
+	certificates []*x509.Certificate, signer crypto.Signer, now time.Time) error {
+	var algorithm string
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		algorithm = rolesAnywhereRSAAlgorithm
+	case *ecdsa.PublicKey:
+		algorithm = rolesAnywhereECAlgorithm
+	default:
+		return fmt.Errorf("unsupported private key type %T", signer.Public())
+	}
+
+	amzDate := now.UTC().Format(rolesAnywhereDateFormat)
+	request.Header.Set(rolesAnywhereContentHeader, "application/json")
+	request.Header.Set(rolesAnywhereDateHeader, amzDate)
+	request.Header.Set(rolesAnywhereX509Header, base64.StdEncoding.EncodeToString(certificates[0].Raw))
+	if len(certificates) > 1 {
+		var chain []string
+		for _, certificate := range certificates[1:] {
+			chain = append(chain, base64.StdEncoding.EncodeToString(certificate.Raw))
+		}
+		request.Header.Set(rolesAnywhereChainHeader, strings.Join(chain, ","))
+	}
+
+	canonicalRequest, signedHeaders := rolesAnywhereCanonicalRequest(request, body)
+	scope := strings.Join([]string{amzDate[:8], region, rolesAnywhereService, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, certificates[0].SerialNumber.String(), scope, signedHeaders, hex.EncodeToString(signature)))
+	return nil
+}
+
+// rolesAnywhereCanonicalRequest returns the canonical form of the request, and the list
+// of its signed headers
+func rolesAnywhereCanonicalRequest(request *http.Request, body []byte) (string, string) {
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		request.Method,
+		path,
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(body),
+	}, "\n"), signedHeaders
+}
+
+func hexSHA256(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
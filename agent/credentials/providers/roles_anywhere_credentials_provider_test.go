@@ -0,0 +1,209 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTrustAnchorARN = "arn:aws:rolesanywhere:us-west-2:123456789012:trust-anchor/anchor"
+	testProfileARN     = "arn:aws:rolesanywhere:us-west-2:123456789012:profile/profile"
+	testRoleARN        = "arn:aws:iam::123456789012:role/ecsExternalInstanceRole"
+)
+
+func writeTestRolesAnywhereCertificate(t *testing.T, dir string) *ecdsa.PrivateKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1234),
+		Subject:      pkix.Name{CommonName: "external-instance"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	require.NoError(t, err)
+	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "certificate.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "private-key.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER}), 0600))
+	return privateKey
+}
+
+func TestNewRolesAnywhereCredentialsProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roles-anywhere")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, err := NewRolesAnywhereCredentialsProvider()
+	require.NoError(t, err)
+	assert.Nil(t, p, "IAM Roles Anywhere should not be used when it is not configured")
+
+	os.Setenv(rolesAnywhereCertificateEnvVar, filepath.Join(dir, "certificate.pem"))
+	defer os.Unsetenv(rolesAnywhereCertificateEnvVar)
+	_, err = NewRolesAnywhereCredentialsProvider()
+	assert.Error(t, err, "partial IAM Roles Anywhere configuration should be rejected")
+
+	os.Setenv(rolesAnywherePrivateKeyEnvVar, filepath.Join(dir, "private-key.pem"))
+	defer os.Unsetenv(rolesAnywherePrivateKeyEnvVar)
+	os.Setenv(rolesAnywhereTrustAnchorARNEnvVar, testTrustAnchorARN)
+	defer os.Unsetenv(rolesAnywhereTrustAnchorARNEnvVar)
+	os.Setenv(rolesAnywhereProfileARNEnvVar, testProfileARN)
+	defer os.Unsetenv(rolesAnywhereProfileARNEnvVar)
+	os.Setenv(rolesAnywhereRoleARNEnvVar, testRoleARN)
+	defer os.Unsetenv(rolesAnywhereRoleARNEnvVar)
+	p, err = NewRolesAnywhereCredentialsProvider()
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, "us-west-2", p.region)
+	assert.Equal(t, "https://rolesanywhere.us-west-2.amazonaws.com", p.endpoint)
+}
+
+func TestRolesAnywhereCredentialsProviderRetrieve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roles-anywhere")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	privateKey := writeTestRolesAnywhereCertificate(t, dir)
+
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	authorizationRegex := regexp.MustCompile(`^AWS4-X509-ECDSA-SHA256 Credential=1234/(\d{8})/us-west-2/rolesanywhere/aws4_request, ` +
+		`SignedHeaders=content-type;host;x-amz-date;x-amz-x509, Signature=([0-9a-f]+)$`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var request rolesAnywhereCreateSessionRequest
+		require.NoError(t, json.Unmarshal(body, &request))
+		assert.Equal(t, rolesAnywhereCreateSessionRequest{
+			DurationSeconds: 3600,
+			ProfileARN:      testProfileARN,
+			RoleARN:         testRoleARN,
+			TrustAnchorARN:  testTrustAnchorARN,
+		}, request)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/sessions", r.URL.Path)
+
+		matches := authorizationRegex.FindStringSubmatch(r.Header.Get("Authorization"))
+		require.Len(t, matches, 3, "unexpected authorization header %s", r.Header.Get("Authorization"))
+		assert.True(t, strings.HasPrefix(r.Header.Get(rolesAnywhereDateHeader), matches[1]))
+		certificateDER, err := base64.StdEncoding.DecodeString(r.Header.Get(rolesAnywhereX509Header))
+		require.NoError(t, err)
+		certificate, err := x509.ParseCertificate(certificateDER)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1234), certificate.SerialNumber.Int64())
+
+		// verify the signature with the public key of the certificate, over the signed
+		// headers only since the client adds more headers after signing
+		for name := range r.Header {
+			if name != rolesAnywhereContentHeader && name != rolesAnywhereDateHeader && name != rolesAnywhereX509Header {
+				r.Header.Del(name)
+			}
+		}
+		r.URL.Host = r.Host
+		canonicalRequest, _ := rolesAnywhereCanonicalRequest(r, body)
+		stringToSign := strings.Join([]string{
+			rolesAnywhereECAlgorithm,
+			r.Header.Get(rolesAnywhereDateHeader),
+			fmt.Sprintf("%s/us-west-2/rolesanywhere/aws4_request", matches[1]),
+			hexSHA256([]byte(canonicalRequest)),
+		}, "\n")
+		digest := sha256.Sum256([]byte(stringToSign))
+		signature, err := hex.DecodeString(matches[2])
+		require.NoError(t, err)
+		var ecdsaSignature struct{ R, S *big.Int }
+		_, err = asn1.Unmarshal(signature, &ecdsaSignature)
+		require.NoError(t, err)
+		assert.True(t, ecdsa.Verify(&privateKey.PublicKey, digest[:], ecdsaSignature.R, ecdsaSignature.S))
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"credentialSet":[{"credentials":{"accessKeyId":"AKID","secretAccessKey":"SECRET",`+
+			`"sessionToken":"TOKEN","expiration":"%s"}}]}`, expiration.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	p := &RolesAnywhereCredentialsProvider{
+		CertificateFile: filepath.Join(dir, "certificate.pem"),
+		PrivateKeyFile:  filepath.Join(dir, "private-key.pem"),
+		TrustAnchorARN:  testTrustAnchorARN,
+		ProfileARN:      testProfileARN,
+		RoleARN:         testRoleARN,
+		region:          "us-west-2",
+		endpoint:        server.URL,
+		client:          server.Client(),
+	}
+	assert.True(t, p.IsExpired())
+	v, err := p.Retrieve()
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", v.AccessKeyID)
+	assert.Equal(t, "SECRET", v.SecretAccessKey)
+	assert.Equal(t, "TOKEN", v.SessionToken)
+	assert.Equal(t, RolesAnywhereCredentialsProviderName, v.ProviderName)
+	assert.False(t, p.IsExpired())
+	assert.Equal(t, expiration.Add(-rolesAnywhereExpiryWindow), p.ExpiresAt())
+}
+
+func TestRolesAnywhereCredentialsProviderRetrieveError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roles-anywhere")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeTestRolesAnywhereCertificate(t, dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"Untrusted certificate"}`)
+	}))
+	defer server.Close()
+
+	p := &RolesAnywhereCredentialsProvider{
+		CertificateFile: filepath.Join(dir, "certificate.pem"),
+		PrivateKeyFile:  filepath.Join(dir, "private-key.pem"),
+		TrustAnchorARN:  testTrustAnchorARN,
+		ProfileARN:      testProfileARN,
+		RoleARN:         testRoleARN,
+		region:          "us-west-2",
+		endpoint:        server.URL,
+		client:          server.Client(),
+	}
+	_, err = p.Retrieve()
+	assert.Error(t, err)
+	assert.True(t, p.IsExpired())
+}
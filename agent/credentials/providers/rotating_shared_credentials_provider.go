@@ -14,6 +14,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -32,12 +33,14 @@ const (
 
 // RotatingSharedCredentialsProvider is a provider that retrieves credentials via the
 // shared credentials provider, and adds the functionality of expiring and re-retrieving
-// those credentials from the file.
+// those credentials from the file. The credentials expire as soon as the file changes
+// while the file is watched, or every rotation interval otherwise.
 type RotatingSharedCredentialsProvider struct {
 	credentials.Expiry
 
 	RotationInterval          time.Duration
 	sharedCredentialsProvider *credentials.SharedCredentialsProvider
+	watcher                   *credentialsFileWatcher
 }
 
 // NewRotatingSharedCredentials returns a rotating shared credentials provider
@@ -52,8 +55,34 @@ func NewRotatingSharedCredentialsProvider() *RotatingSharedCredentialsProvider {
 	}
 }
 
+// WatchFile starts watching the credentials file until the context is canceled, so
+// that the credentials expire as soon as the file changes instead of every rotation
+// interval. If the file cannot be watched, for instance because its filesystem does
+// not support inotify, or once watching it fails, the credentials expire every rotation
+// interval. It must be called before the provider is used.
+func (p *RotatingSharedCredentialsProvider) WatchFile(ctx context.Context) error {
+	watcher := &credentialsFileWatcher{filename: p.sharedCredentialsProvider.Filename}
+	if err := watcher.start(ctx); err != nil {
+		return err
+	}
+	p.watcher = watcher
+	return nil
+}
+
+// IsExpired returns true if the credentials file changed while it is watched, or if
+// the rotation interval is over otherwise.
+func (p *RotatingSharedCredentialsProvider) IsExpired() bool {
+	if p.watcher != nil && p.watcher.isWatching() {
+		return p.watcher.hasChanged()
+	}
+	return p.Expiry.IsExpired()
+}
+
 // Retrieve will use the given filename and profile and retrieve AWS credentials.
 func (p *RotatingSharedCredentialsProvider) Retrieve() (credentials.Value, error) {
+	if p.watcher != nil {
+		p.watcher.reset()
+	}
 	v, err := p.sharedCredentialsProvider.Retrieve()
 	v.ProviderName = RotatingSharedCredentialsProviderName
 	if err != nil {
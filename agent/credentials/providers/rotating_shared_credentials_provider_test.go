@@ -16,8 +16,10 @@
 package providers
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -195,3 +197,60 @@ aws_secret_access_key = TESTFILESECRET2
 	require.Equal(t, "TESTFILEKEYID2", v.AccessKeyID)
 	require.Equal(t, "TESTFILESECRET2", v.SecretAccessKey)
 }
+
+func TestRotatingSharedCredentialsProvider_WatchFile(t *testing.T) {
+	// create tmp credentials file in its own directory and use that for this test
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "rotatingcreds")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	filename := filepath.Join(tmpDir, "credentials")
+	text := []byte(`[default]
+aws_access_key_id = TESTFILEKEYID1
+aws_secret_access_key = TESTFILESECRET1
+`)
+	require.NoError(t, ioutil.WriteFile(filename, text, 0600))
+
+	p := NewRotatingSharedCredentialsProvider()
+	p.sharedCredentialsProvider.Filename = filename
+	p.RotationInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	require.NoError(t, p.WatchFile(ctx))
+
+	v, err := p.Retrieve()
+	require.NoError(t, err)
+	require.Equal(t, "TESTFILEKEYID1", v.AccessKeyID)
+	time.Sleep(10 * time.Millisecond)
+	require.False(t, p.IsExpired(), "Credentials should not expire at an interval while the file is watched")
+
+	// replace the credentials file and expect the credentials to expire right away
+	text2 := []byte(`[default]
+aws_access_key_id = TESTFILEKEYID2
+aws_secret_access_key = TESTFILESECRET2
+`)
+	require.NoError(t, ioutil.WriteFile(filename+".tmp", text2, 0600))
+	require.NoError(t, os.Rename(filename+".tmp", filename))
+	for i := 0; i < 100 && !p.IsExpired(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, p.IsExpired(), "Credentials should expire when the file changes")
+	v, err = p.Retrieve()
+	require.NoError(t, err)
+	require.Equal(t, "TESTFILEKEYID2", v.AccessKeyID)
+	require.Equal(t, "TESTFILESECRET2", v.SecretAccessKey)
+	require.False(t, p.IsExpired())
+
+	// once the file is not watched anymore, credentials expire at an interval again
+	cancel()
+	for i := 0; i < 100 && p.watcher.isWatching(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, p.IsExpired(), "Credentials should expire at an interval once the file is not watched")
+}
+
+func TestRotatingSharedCredentialsProvider_WatchFileFail_BadPath(t *testing.T) {
+	p := NewRotatingSharedCredentialsProvider()
+	p.sharedCredentialsProvider.Filename = "/foo/bar/baz/bad/path"
+	require.Error(t, p.WatchFile(context.TODO()))
+	require.True(t, p.IsExpired(), "Credentials should expire at an interval when the file cannot be watched")
+}
@@ -0,0 +1,71 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"context"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/cihub/seelog"
+)
+
+// RotatingSharedCredentialsProviderV2 is the AWS SDK v2 counterpart of the
+// RotatingSharedCredentialsProvider. It retrieves credentials from the same
+// shared credentials file, and returns them with an expiration of one
+// rotation interval, so that an aws.CredentialsCache wrapping it re-retrieves
+// them from the file once the interval is over.
+type RotatingSharedCredentialsProviderV2 struct {
+	RotationInterval          time.Duration
+	sharedCredentialsProvider *credentials.SharedCredentialsProvider
+}
+
+// NewRotatingSharedCredentialsProviderV2 returns a rotating shared credentials
+// provider for the AWS SDK v2 with default values set.
+func NewRotatingSharedCredentialsProviderV2() *RotatingSharedCredentialsProviderV2 {
+	return &RotatingSharedCredentialsProviderV2{
+		RotationInterval: defaultRotationInterval,
+		sharedCredentialsProvider: &credentials.SharedCredentialsProvider{
+			Filename: defaultFilename,
+			Profile:  "default",
+		},
+	}
+}
+
+// NewRotatingSharedCredentialsCacheV2 returns the rotating shared credentials
+// provider for the AWS SDK v2, wrapped in a cache that rotates the credentials
+// every rotation interval.
+func NewRotatingSharedCredentialsCacheV2() *awsv2.CredentialsCache {
+	return awsv2.NewCredentialsCache(NewRotatingSharedCredentialsProviderV2())
+}
+
+// Retrieve will use the given filename and profile and retrieve AWS credentials.
+func (p *RotatingSharedCredentialsProviderV2) Retrieve(ctx context.Context) (awsv2.Credentials, error) {
+	v, err := p.sharedCredentialsProvider.Retrieve()
+	if err != nil {
+		return awsv2.Credentials{Source: RotatingSharedCredentialsProviderName}, err
+	}
+	v.ProviderName = RotatingSharedCredentialsProviderName
+	seelog.Infof("Successfully got instance credentials from file %s. %s",
+		p.sharedCredentialsProvider.Filename, credValueToString(v))
+	return awsv2.Credentials{
+		AccessKeyID:     v.AccessKeyID,
+		SecretAccessKey: v.SecretAccessKey,
+		SessionToken:    v.SessionToken,
+		Source:          RotatingSharedCredentialsProviderName,
+		CanExpire:       true,
+		Expires:         time.Now().Add(p.RotationInterval),
+	}, nil
+}
@@ -0,0 +1,115 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRotatingSharedCredentialsProviderV2(t *testing.T) {
+	p := NewRotatingSharedCredentialsProviderV2()
+	require.Equal(t, time.Minute, p.RotationInterval)
+	require.Equal(t, "default", p.sharedCredentialsProvider.Profile)
+	require.Equal(t, "/rotatingcreds/credentials", p.sharedCredentialsProvider.Filename)
+}
+
+func TestRotatingSharedCredentialsProviderV2_RetrieveFail_BadPath(t *testing.T) {
+	p := NewRotatingSharedCredentialsProviderV2()
+	p.sharedCredentialsProvider.Filename = "/foo/bar/baz/bad/path"
+	v, err := p.Retrieve(context.TODO())
+	require.Error(t, err)
+	require.Equal(t, RotatingSharedCredentialsProviderName, v.Source)
+	require.False(t, v.HasKeys())
+}
+
+func TestRotatingSharedCredentialsProviderV2_Retrieve(t *testing.T) {
+	// create tmp credentials file and use that for this test
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "credentials")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	text := []byte(`[default]
+aws_access_key_id = TESTFILEKEYID
+aws_secret_access_key = TESTFILESECRET
+aws_session_token = TESTFILETOKEN
+`)
+	_, err = tmpFile.Write(text)
+	require.NoError(t, err)
+
+	p := NewRotatingSharedCredentialsProviderV2()
+	p.sharedCredentialsProvider.Filename = tmpFile.Name()
+	v, err := p.Retrieve(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, RotatingSharedCredentialsProviderName, v.Source)
+	require.Equal(t, "TESTFILEKEYID", v.AccessKeyID)
+	require.Equal(t, "TESTFILESECRET", v.SecretAccessKey)
+	require.Equal(t, "TESTFILETOKEN", v.SessionToken)
+	require.True(t, v.CanExpire)
+	d := time.Until(v.Expires)
+	require.True(t, d > 50*time.Second && d <= time.Minute, "Expected credentials to expire after the rotation interval, was %s", d)
+}
+
+// TestRotatingSharedCredentialsProviderV2_CredentialsCaching tests that our
+// provider operates correctly within the aws.CredentialsCache, which does
+// caching on top of the CredentialsProvider interface
+func TestRotatingSharedCredentialsProviderV2_CredentialsCaching(t *testing.T) {
+	// create tmp credentials file and use that for this test
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "credentials")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	text := []byte(`[default]
+aws_access_key_id = TESTFILEKEYID1
+aws_secret_access_key = TESTFILESECRET1
+`)
+	_, err = tmpFile.Write(text)
+	require.NoError(t, err)
+
+	p := NewRotatingSharedCredentialsProviderV2()
+	p.sharedCredentialsProvider.Filename = tmpFile.Name()
+	p.RotationInterval = time.Second
+	creds := awsv2.NewCredentialsCache(p)
+	v, err := creds.Retrieve(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, "TESTFILEKEYID1", v.AccessKeyID)
+	require.Equal(t, "TESTFILESECRET1", v.SecretAccessKey)
+
+	// overwrite cred file and verify that old values still cached
+	text2 := []byte(`[default]
+aws_access_key_id = TESTFILEKEYID2
+aws_secret_access_key = TESTFILESECRET2
+`)
+	_, err = tmpFile.WriteAt(text2, 0)
+	require.NoError(t, err)
+	v, err = creds.Retrieve(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, "TESTFILEKEYID1", v.AccessKeyID)
+
+	// once the rotation interval is over, the new values should be retrieved
+	for i := 0; i < 10 && v.AccessKeyID == "TESTFILEKEYID1"; i++ {
+		time.Sleep(500 * time.Millisecond)
+		v, err = creds.Retrieve(context.TODO())
+		require.NoError(t, err)
+	}
+	require.Equal(t, RotatingSharedCredentialsProviderName, v.Source)
+	require.Equal(t, "TESTFILEKEYID2", v.AccessKeyID)
+	require.Equal(t, "TESTFILESECRET2", v.SecretAccessKey)
+}
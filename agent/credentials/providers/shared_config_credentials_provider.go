@@ -0,0 +1,178 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/processcreds"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// SharedConfigCredentialsProviderName is the name of this provider
+	SharedConfigCredentialsProviderName = "SharedConfigCredentialsProvider"
+
+	credentialProcessKey = "credential_process"
+	ssoStartURLKey       = "sso_start_url"
+	ssoRegionKey         = "sso_region"
+	ssoAccountIDKey      = "sso_account_id"
+	ssoRoleNameKey       = "sso_role_name"
+)
+
+// SharedConfigCredentialsProvider is a provider that retrieves short-lived credentials
+// from a profile of the shared config file, either by running the profile's
+// credential_process, or from the AWS SSO role of the profile, with the SSO access
+// token cached by the AWS CLI. Profiles with static keys are handled by the shared
+// credentials provider instead.
+type SharedConfigCredentialsProvider struct {
+	// Filename is the shared config file, ~/.aws/config by default
+	Filename string
+	// Profile is the profile of the shared config file, default by default
+	Profile string
+
+	lock sync.Mutex
+	// credentials are the credentials of the profile, once it is loaded
+	credentials *credentials.Credentials
+	// newSSOProvider creates the provider of the credentials of SSO profiles
+	newSSOProvider func(profile map[string]string) credentials.Provider
+}
+
+// NewSharedConfigCredentialsProvider returns a shared config credentials provider for
+// the file and profile set by the AWS_CONFIG_FILE and AWS_PROFILE environment variables,
+// like the AWS CLI.
+func NewSharedConfigCredentialsProvider() *SharedConfigCredentialsProvider {
+	filename := os.Getenv("AWS_CONFIG_FILE")
+	if filename == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			filename = filepath.Join(home, ".aws", "config")
+		}
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	return &SharedConfigCredentialsProvider{
+		Filename: filename,
+		Profile:  profile,
+		newSSOProvider: func(profile map[string]string) credentials.Provider {
+			return newSSOCredentialsProvider(profile[ssoStartURLKey], profile[ssoRegionKey],
+				profile[ssoAccountIDKey], profile[ssoRoleNameKey])
+		},
+	}
+}
+
+// Retrieve loads the profile from the shared config file, if not loaded yet, and
+// retrieves its credentials. The profile is loaded again after a failure.
+func (p *SharedConfigCredentialsProvider) Retrieve() (credentials.Value, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.credentials == nil {
+		creds, err := p.loadProfile()
+		if err != nil {
+			return credentials.Value{ProviderName: SharedConfigCredentialsProviderName}, err
+		}
+		p.credentials = creds
+	}
+	v, err := p.credentials.Get()
+	if err != nil {
+		p.credentials = nil
+		return credentials.Value{ProviderName: SharedConfigCredentialsProviderName}, err
+	}
+	seelog.Infof("Successfully got instance credentials from profile %s of file %s. %s",
+		p.Profile, p.Filename, credValueToString(v))
+	return v, nil
+}
+
+// IsExpired returns true if the credentials of the profile expired, or if the
+// profile is not loaded.
+func (p *SharedConfigCredentialsProvider) IsExpired() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.credentials == nil || p.credentials.IsExpired()
+}
+
+// loadProfile returns the credentials of the profile, depending on its keys
+func (p *SharedConfigCredentialsProvider) loadProfile() (*credentials.Credentials, error) {
+	profile, err := readSharedConfigProfile(p.Filename, p.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if command := profile[credentialProcessKey]; command != "" {
+		return processcreds.NewCredentials(command), nil
+	}
+	if profile[ssoStartURLKey] != "" {
+		for _, key := range []string{ssoRegionKey, ssoAccountIDKey, ssoRoleNameKey} {
+			if profile[key] == "" {
+				return nil, fmt.Errorf("profile %s of shared config file %s is missing %s", p.Profile, p.Filename, key)
+			}
+		}
+		return credentials.NewCredentials(p.newSSOProvider(profile)), nil
+	}
+	return nil, fmt.Errorf("profile %s of shared config file %s has neither %s nor %s",
+		p.Profile, p.Filename, credentialProcessKey, ssoStartURLKey)
+}
+
+// readSharedConfigProfile returns the keys of a profile of the shared config file. The
+// default profile is in the [default] section, and other profiles in [profile name]
+// sections.
+func readSharedConfigProfile(filename string, profile string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open shared config file: %v", err)
+	}
+	defer file.Close()
+
+	section := "profile " + profile
+	if profile == "default" {
+		section = "default"
+	}
+	var keys map[string]string
+	inSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.Join(strings.Fields(line[1:len(line)-1]), " ") == section
+			if inSection && keys == nil {
+				keys = make(map[string]string)
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if i := strings.Index(line, "="); i > 0 {
+			keys[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read shared config file: %v", err)
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("profile %s not found in shared config file %s", profile, filename)
+	}
+	return keys, nil
+}
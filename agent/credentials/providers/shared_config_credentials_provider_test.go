@@ -0,0 +1,138 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSharedConfigFile(t *testing.T, text string) string {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "config")
+	require.NoError(t, err)
+	_, err = tmpFile.Write([]byte(text))
+	require.NoError(t, err)
+	require.NoError(t, tmpFile.Close())
+	return tmpFile.Name()
+}
+
+func TestNewSharedConfigCredentialsProvider(t *testing.T) {
+	origFile := os.Getenv("AWS_CONFIG_FILE")
+	origProfile := os.Getenv("AWS_PROFILE")
+	os.Setenv("AWS_CONFIG_FILE", "/foo/config")
+	os.Setenv("AWS_PROFILE", "foo")
+	defer os.Setenv("AWS_CONFIG_FILE", origFile)
+	defer os.Setenv("AWS_PROFILE", origProfile)
+
+	p := NewSharedConfigCredentialsProvider()
+	require.Equal(t, "/foo/config", p.Filename)
+	require.Equal(t, "foo", p.Profile)
+}
+
+func TestSharedConfigCredentialsProvider_CredentialProcess(t *testing.T) {
+	filename := writeSharedConfigFile(t, `[default]
+region = us-west-2
+
+[profile onprem]
+credential_process = echo '{"Version": 1, "AccessKeyId": "TESTPROCESSKEYID", "SecretAccessKey": "TESTPROCESSSECRET", "SessionToken": "TESTPROCESSTOKEN", "Expiration": "2100-01-01T00:00:00Z"}'
+`)
+	defer os.Remove(filename)
+
+	p := NewSharedConfigCredentialsProvider()
+	p.Filename = filename
+	p.Profile = "onprem"
+	require.True(t, p.IsExpired())
+	v, err := p.Retrieve()
+	require.NoError(t, err)
+	require.Equal(t, "TESTPROCESSKEYID", v.AccessKeyID)
+	require.Equal(t, "TESTPROCESSSECRET", v.SecretAccessKey)
+	require.Equal(t, "TESTPROCESSTOKEN", v.SessionToken)
+	require.False(t, p.IsExpired())
+}
+
+func TestSharedConfigCredentialsProvider_SSO(t *testing.T) {
+	filename := writeSharedConfigFile(t, `[default]
+sso_start_url = https://example.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 123456789012
+sso_role_name = ecsAnywhereRole
+`)
+	defer os.Remove(filename)
+
+	p := NewSharedConfigCredentialsProvider()
+	p.Filename = filename
+	p.Profile = "default"
+	var ssoProfile map[string]string
+	p.newSSOProvider = func(profile map[string]string) credentials.Provider {
+		ssoProfile = profile
+		return &credentials.StaticProvider{Value: credentials.Value{
+			AccessKeyID:     "TESTSSOKEYID",
+			SecretAccessKey: "TESTSSOSECRET",
+		}}
+	}
+	v, err := p.Retrieve()
+	require.NoError(t, err)
+	require.Equal(t, "TESTSSOKEYID", v.AccessKeyID)
+	require.Equal(t, "https://example.awsapps.com/start", ssoProfile[ssoStartURLKey])
+	require.Equal(t, "ecsAnywhereRole", ssoProfile[ssoRoleNameKey])
+}
+
+func TestSharedConfigCredentialsProvider_RetrieveFail_IncompleteSSOProfile(t *testing.T) {
+	filename := writeSharedConfigFile(t, `[default]
+sso_start_url = https://example.awsapps.com/start
+sso_region = us-east-1
+`)
+	defer os.Remove(filename)
+
+	p := NewSharedConfigCredentialsProvider()
+	p.Filename = filename
+	p.Profile = "default"
+	v, err := p.Retrieve()
+	require.Error(t, err)
+	require.Equal(t, SharedConfigCredentialsProviderName, v.ProviderName)
+	require.True(t, p.IsExpired())
+}
+
+func TestSharedConfigCredentialsProvider_RetrieveFail_StaticKeysProfile(t *testing.T) {
+	filename := writeSharedConfigFile(t, `[default]
+aws_access_key_id = TESTFILEKEYID
+aws_secret_access_key = TESTFILESECRET
+`)
+	defer os.Remove(filename)
+
+	p := NewSharedConfigCredentialsProvider()
+	p.Filename = filename
+	p.Profile = "default"
+	_, err := p.Retrieve()
+	require.Error(t, err)
+}
+
+func TestSharedConfigCredentialsProvider_RetrieveFail_BadProfile(t *testing.T) {
+	filename := writeSharedConfigFile(t, `[profile onprem]
+credential_process = /bin/true
+`)
+	defer os.Remove(filename)
+
+	p := NewSharedConfigCredentialsProvider()
+	p.Filename = filename
+	p.Profile = "thisProfileDoesntExist"
+	_, err := p.Retrieve()
+	require.Error(t, err)
+}
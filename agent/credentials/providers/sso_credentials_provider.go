@@ -0,0 +1,155 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+const (
+	// SSOCredentialsProviderName is the name of the provider of SSO role credentials
+	SSOCredentialsProviderName = "SSOCredentialsProvider"
+
+	// ssoRequestTimeout is the timeout of the requests to get SSO role credentials
+	ssoRequestTimeout = 30 * time.Second
+	// ssoExpiryWindow is how long before they expire SSO role credentials are renewed
+	ssoExpiryWindow = 5 * time.Minute
+	// ssoBearerTokenHeader is the header of the requests to the SSO portal that holds
+	// the SSO access token
+	ssoBearerTokenHeader = "x-amz-sso_bearer_token"
+)
+
+// ssoCachedToken is the SSO access token cached by the AWS CLI after `aws sso login`
+type ssoCachedToken struct {
+	AccessToken string `json:"accessToken"`
+	ExpiresAt   string `json:"expiresAt"`
+}
+
+// ssoRoleCredentialsResponse is the response of the SSO portal GetRoleCredentials API
+type ssoRoleCredentialsResponse struct {
+	RoleCredentials struct {
+		AccessKeyID     string `json:"accessKeyId"`
+		SecretAccessKey string `json:"secretAccessKey"`
+		SessionToken    string `json:"sessionToken"`
+		Expiration      int64  `json:"expiration"`
+	} `json:"roleCredentials"`
+}
+
+// ssoCredentialsProvider retrieves the credentials of an AWS SSO role, with the SSO
+// access token cached by the AWS CLI. The token is read from the cache every time
+// credentials are retrieved, so that a new `aws sso login` is picked up.
+type ssoCredentialsProvider struct {
+	credentials.Expiry
+
+	startURL  string
+	region    string
+	accountID string
+	roleName  string
+	cacheDir  string
+	endpoint  string
+	client    *http.Client
+}
+
+func newSSOCredentialsProvider(startURL, region, accountID, roleName string) *ssoCredentialsProvider {
+	cacheDir := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		cacheDir = filepath.Join(home, ".aws", "sso", "cache")
+	}
+	return &ssoCredentialsProvider{
+		startURL:  startURL,
+		region:    region,
+		accountID: accountID,
+		roleName:  roleName,
+		cacheDir:  cacheDir,
+		endpoint:  fmt.Sprintf("https://portal.sso.%s.amazonaws.com", region),
+		client:    &http.Client{Timeout: ssoRequestTimeout},
+	}
+}
+
+// Retrieve gets the credentials of the SSO role from the SSO portal.
+func (p *ssoCredentialsProvider) Retrieve() (credentials.Value, error) {
+	token, err := p.cachedToken()
+	if err != nil {
+		return credentials.Value{ProviderName: SSOCredentialsProviderName}, err
+	}
+
+	query := url.Values{"account_id": []string{p.accountID}, "role_name": []string{p.roleName}}
+	request, err := http.NewRequest(http.MethodGet, p.endpoint+"/federation/credentials?"+query.Encode(), nil)
+	if err != nil {
+		return credentials.Value{ProviderName: SSOCredentialsProviderName}, err
+	}
+	request.Header.Set(ssoBearerTokenHeader, token)
+	response, err := p.client.Do(request)
+	if err != nil {
+		return credentials.Value{ProviderName: SSOCredentialsProviderName},
+			fmt.Errorf("unable to get SSO role credentials: %v", err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return credentials.Value{ProviderName: SSOCredentialsProviderName},
+			fmt.Errorf("unable to get SSO role credentials: %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return credentials.Value{ProviderName: SSOCredentialsProviderName},
+			fmt.Errorf("unable to get SSO role credentials: status %d: %s", response.StatusCode, body)
+	}
+
+	var roleCredentials ssoRoleCredentialsResponse
+	if err := json.Unmarshal(body, &roleCredentials); err != nil {
+		return credentials.Value{ProviderName: SSOCredentialsProviderName},
+			fmt.Errorf("unable to parse SSO role credentials: %v", err)
+	}
+	p.SetExpiration(time.Unix(0, roleCredentials.RoleCredentials.Expiration*int64(time.Millisecond)), ssoExpiryWindow)
+	return credentials.Value{
+		AccessKeyID:     roleCredentials.RoleCredentials.AccessKeyID,
+		SecretAccessKey: roleCredentials.RoleCredentials.SecretAccessKey,
+		SessionToken:    roleCredentials.RoleCredentials.SessionToken,
+		ProviderName:    SSOCredentialsProviderName,
+	}, nil
+}
+
+// cachedToken returns the SSO access token cached by the AWS CLI for the start URL,
+// if it did not expire
+func (p *ssoCredentialsProvider) cachedToken() (string, error) {
+	hash := sha1.Sum([]byte(p.startURL))
+	filename := filepath.Join(p.cacheDir, hex.EncodeToString(hash[:])+".json")
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("unable to read cached SSO token, run `aws sso login`: %v", err)
+	}
+	var token ssoCachedToken
+	if err := json.Unmarshal(contents, &token); err != nil {
+		return "", fmt.Errorf("unable to parse cached SSO token %s: %v", filename, err)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse expiration of cached SSO token %s: %v", filename, err)
+	}
+	if token.AccessToken == "" || !time.Now().Before(expiresAt) {
+		return "", fmt.Errorf("cached SSO token %s expired, run `aws sso login`", filename)
+	}
+	return token.AccessToken, nil
+}
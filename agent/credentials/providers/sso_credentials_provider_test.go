@@ -0,0 +1,96 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSSOStartURL = "https://example.awsapps.com/start"
+
+func newTestSSOCredentialsProvider(t *testing.T, tokenExpiresAt time.Time, endpoint string) (*ssoCredentialsProvider, func()) {
+	cacheDir, err := ioutil.TempDir(os.TempDir(), "sso")
+	require.NoError(t, err)
+	hash := sha1.Sum([]byte(testSSOStartURL))
+	token := fmt.Sprintf(`{"startUrl": "%s", "region": "us-east-1", "accessToken": "TESTSSOTOKEN", "expiresAt": "%s"}`,
+		testSSOStartURL, tokenExpiresAt.UTC().Format(time.RFC3339))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".json"), []byte(token), 0600))
+
+	p := newSSOCredentialsProvider(testSSOStartURL, "us-east-1", "123456789012", "ecsAnywhereRole")
+	p.cacheDir = cacheDir
+	p.endpoint = endpoint
+	return p, func() { os.RemoveAll(cacheDir) }
+}
+
+func TestSSOCredentialsProvider_Retrieve(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/federation/credentials", r.URL.Path)
+		require.Equal(t, "123456789012", r.URL.Query().Get("account_id"))
+		require.Equal(t, "ecsAnywhereRole", r.URL.Query().Get("role_name"))
+		require.Equal(t, "TESTSSOTOKEN", r.Header.Get(ssoBearerTokenHeader))
+		fmt.Fprintf(w, `{"roleCredentials": {"accessKeyId": "TESTSSOKEYID", "secretAccessKey": "TESTSSOSECRET", "sessionToken": "TESTSSOSESSIONTOKEN", "expiration": %d}}`,
+			expiration.UnixNano()/int64(time.Millisecond))
+	}))
+	defer server.Close()
+	p, cleanup := newTestSSOCredentialsProvider(t, time.Now().Add(time.Hour), server.URL)
+	defer cleanup()
+
+	v, err := p.Retrieve()
+	require.NoError(t, err)
+	require.Equal(t, SSOCredentialsProviderName, v.ProviderName)
+	require.Equal(t, "TESTSSOKEYID", v.AccessKeyID)
+	require.Equal(t, "TESTSSOSECRET", v.SecretAccessKey)
+	require.Equal(t, "TESTSSOSESSIONTOKEN", v.SessionToken)
+	require.False(t, p.IsExpired())
+	require.Equal(t, expiration.Add(-ssoExpiryWindow), p.ExpiresAt())
+}
+
+func TestSSOCredentialsProvider_RetrieveFail_ExpiredToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request with an expired SSO token")
+	}))
+	defer server.Close()
+	p, cleanup := newTestSSOCredentialsProvider(t, time.Now().Add(-time.Hour), server.URL)
+	defer cleanup()
+
+	_, err := p.Retrieve()
+	require.Error(t, err)
+}
+
+func TestSSOCredentialsProvider_RetrieveFail_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	p, cleanup := newTestSSOCredentialsProvider(t, time.Now().Add(time.Hour), server.URL)
+	defer cleanup()
+
+	_, err := p.Retrieve()
+	require.Error(t, err)
+	require.True(t, p.IsExpired())
+}
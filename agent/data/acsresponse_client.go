@@ -0,0 +1,50 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func (c *client) SaveACSResponse(key string, response []byte) error {
+	return c.batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(acsResponsesBucketName))
+		return putObject(b, key, response)
+	})
+}
+
+func (c *client) DeleteACSResponse(key string) error {
+	return c.batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(acsResponsesBucketName))
+		return b.Delete([]byte(key))
+	})
+}
+
+func (c *client) GetACSResponses() (map[string][]byte, error) {
+	responses := make(map[string][]byte)
+	err := c.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(acsResponsesBucketName))
+		return walk(bucket, func(key string, data []byte) error {
+			var response []byte
+			if err := json.Unmarshal(data, &response); err != nil {
+				return err
+			}
+			responses[key] = response
+			return nil
+		})
+	})
+	return responses, err
+}
@@ -0,0 +1,43 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManageACSResponses(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	require.NoError(t, testClient.SaveACSResponse("AckRequest/id1", []byte("response1")))
+	require.NoError(t, testClient.SaveACSResponse("NackRequest/id2", []byte("response2")))
+
+	responses, err := testClient.GetACSResponses()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"AckRequest/id1":  []byte("response1"),
+		"NackRequest/id2": []byte("response2"),
+	}, responses)
+
+	require.NoError(t, testClient.DeleteACSResponse("AckRequest/id1"))
+	responses, err = testClient.GetACSResponses()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"NackRequest/id2": []byte("response2")}, responses)
+}
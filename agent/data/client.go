@@ -20,8 +20,11 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/api/container"
 	"github.com/aws/amazon-ecs-agent/agent/api/eni"
 	"github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -34,6 +37,8 @@ const (
 	imagesBucketName         = "images"
 	eniAttachmentsBucketName = "eniattachments"
 	metadataBucketName       = "metadata"
+	ecrAuthTokensBucketName  = "ecrauthtokens"
+	acsResponsesBucketName   = "acsresponses"
 )
 
 var (
@@ -46,6 +51,8 @@ var (
 		tasksBucketName,
 		eniAttachmentsBucketName,
 		metadataBucketName,
+		ecrAuthTokensBucketName,
+		acsResponsesBucketName,
 	}
 )
 
@@ -89,13 +96,36 @@ type Client interface {
 	// GetMetadata gets the value of a certain kind of metadata.
 	GetMetadata(string) (string, error)
 
+	// SaveECRAuthToken saves an encrypted ECR auth token.
+	SaveECRAuthToken(string, []byte) error
+	// DeleteECRAuthToken deletes an ECR auth token.
+	DeleteECRAuthToken(string) error
+	// GetECRAuthTokens gets all the encrypted ECR auth tokens, keyed by their cache key.
+	GetECRAuthTokens() (map[string][]byte, error)
+
+	// SaveACSResponse saves a response to ACS that's waiting to be replayed.
+	SaveACSResponse(string, []byte) error
+	// DeleteACSResponse deletes a response to ACS.
+	DeleteACSResponse(string) error
+	// GetACSResponses gets all the responses to ACS waiting to be replayed, keyed by their queue key.
+	GetACSResponses() (map[string][]byte, error)
+
+	// Compact rewrites the database to release the space of the deleted data.
+	Compact() error
+	// Recovered returns whether the database was rebuilt when it was opened, because
+	// it was corrupted. Only the data that could be read is kept in that case.
+	Recovered() bool
+
 	// Close closes the connection to database.
 	Close() error
 }
 
 // client implements the Client interface using boltdb as the backing data store.
 type client struct {
-	db *bolt.DB
+	// lock protects db, which is replaced when the database is compacted
+	lock      sync.RWMutex
+	db        *bolt.DB
+	recovered bool
 }
 
 // New returns a data client that implements the Client interface with boltdb.
@@ -110,15 +140,74 @@ func New(dataDir string) (Client, error) {
 	return dbClient, nil
 }
 
+// NewWithBackend returns a data client that implements the Client interface with the
+// given backend, config.DataBackendBoltDB or config.DataBackendFile.
+func NewWithBackend(backend, dataDir string) (Client, error) {
+	var err error
+	once.Do(func() {
+		dbClient, err = setupBackend(backend, dataDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dbClient, nil
+}
+
 // NewWithSetup returns a data client that implements the Client interface with boltdb.
 // It always runs the db setup. Used for testing.
 func NewWithSetup(dataDir string) (Client, error) {
 	return setup(dataDir)
 }
 
+// setupBackend initiates the data client of a backend. The data saved with the other
+// backend is migrated to it when the backend was changed.
+func setupBackend(backend, dataDir string) (Client, error) {
+	switch backend {
+	case "", config.DataBackendBoltDB:
+		c, err := setup(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := migrateFromFileClient(c, dataDir); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	case config.DataBackendFile:
+		c, err := setupFileClient(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := migrateFromBoltDB(c, dataDir); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	default:
+		return nil, errors.Errorf("unknown data backend %q", backend)
+	}
+}
+
 // setup initiates the boltdb client and makes sure the buckets we use are created.
+// A corrupted database is moved aside and rebuilt with the data that can still be read.
 func setup(dataDir string) (*client, error) {
-	db, err := bolt.Open(filepath.Join(dataDir, dbName), dbMode, nil)
+	path := filepath.Join(dataDir, dbName)
+	if err := restoreInterruptedCompaction(path); err != nil {
+		return nil, errors.Wrap(err, "failed to restore the database moved aside by a compaction")
+	}
+	db, err := openAndCheck(path)
+	recovered := false
+	if err != nil {
+		if !isCorruption(err) {
+			return nil, err
+		}
+		seelog.Errorf("Data store %s is corrupted, rebuilding it: %v", path, err)
+		db, err = recoverDatabase(path, db)
+		if err != nil {
+			return nil, err
+		}
+		recovered = true
+	}
 	err = db.Update(func(tx *bolt.Tx) error {
 		for _, b := range buckets {
 			_, err = tx.CreateBucketIfNotExists([]byte(b))
@@ -130,14 +219,45 @@ func setup(dataDir string) (*client, error) {
 		return nil
 	})
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 	return &client{
-		db: db,
+		db:        db,
+		recovered: recovered,
 	}, nil
 }
 
+// Recovered returns whether the database was rebuilt when it was opened.
+func (c *client) Recovered() bool {
+	return c.recovered
+}
+
+// view runs a read-only transaction on the database.
+func (c *client) view(fn func(*bolt.Tx) error) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.db.View(fn)
+}
+
+// update runs a read-write transaction on the database.
+func (c *client) update(fn func(*bolt.Tx) error) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.db.Update(fn)
+}
+
+// batch runs a read-write transaction on the database, batched with the other
+// concurrent ones.
+func (c *client) batch(fn func(*bolt.Tx) error) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.db.Batch(fn)
+}
+
 // Close closes the boltdb connection.
 func (c *client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.db.Close()
 }
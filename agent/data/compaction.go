@@ -0,0 +1,156 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// compactingSuffix is the suffix of the database being written by a compaction
+	compactingSuffix = ".compacting"
+	// precompactionSuffix is the suffix of the database being replaced by a compaction,
+	// kept until the compacted one is opened in its place
+	precompactionSuffix = ".precompaction"
+	// minCompactionFreeRatio is the ratio of the database file that has to be free
+	// pages for a compaction to be worth it
+	minCompactionFreeRatio = 0.25
+	// compactionTxMaxSize is the maximum size of the transactions that copy the data
+	// to the compacted database
+	compactionTxMaxSize = 64 * 1024
+)
+
+// openDB opens a database. It's swappable for testing.
+var openDB = bolt.Open
+
+// Compact rewrites the database to a new file without its free pages, when at least
+// a quarter of the file is free pages. Boltdb never shrinks its file, which keeps the
+// size of the largest state the agent ever had otherwise. Reads and writes wait for
+// the compaction to complete. The client keeps the original database if the compacted
+// one can't replace it.
+func (c *client) Compact() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := c.db.Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the size of the database")
+	}
+	stats := c.db.Stats()
+	free := int64(stats.FreePageN+stats.PendingPageN) * int64(c.db.Info().PageSize)
+	if info.Size() == 0 || float64(free)/float64(info.Size()) < minCompactionFreeRatio {
+		seelog.Debugf("Skipping the compaction of data store %s: %d of %d bytes are free", path, free, info.Size())
+		return nil
+	}
+
+	compactingPath := path + compactingSuffix
+	os.Remove(compactingPath)
+	dst, err := bolt.Open(compactingPath, dbMode, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the compacted database")
+	}
+	if err := bolt.Compact(dst, c.db, compactionTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(compactingPath)
+		return errors.Wrap(err, "failed to compact the database")
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(compactingPath)
+		return errors.Wrap(err, "failed to close the compacted database")
+	}
+
+	// The database is moved aside until the compacted one is opened in its place, and
+	// put back and reopened if that fails, so that the client keeps working with it
+	backupPath := path + precompactionSuffix
+	if err := c.db.Close(); err != nil {
+		os.Remove(compactingPath)
+		return c.reopen(path, errors.Wrap(err, "failed to close the database"))
+	}
+	if err := os.Rename(path, backupPath); err != nil {
+		os.Remove(compactingPath)
+		return c.reopen(path, errors.Wrap(err, "failed to move the database aside"))
+	}
+	if err := os.Rename(compactingPath, path); err != nil {
+		os.Remove(compactingPath)
+		return c.restore(path, backupPath, errors.Wrap(err, "failed to replace the database with the compacted one"))
+	}
+	db, err := openDB(path, dbMode, nil)
+	if err != nil {
+		return c.restore(path, backupPath, errors.Wrap(err, "failed to open the compacted database"))
+	}
+	c.db = db
+	os.Remove(backupPath)
+	if compacted, err := os.Stat(path); err == nil {
+		seelog.Infof("Compacted data store %s from %d to %d bytes", path, info.Size(), compacted.Size())
+	}
+	return nil
+}
+
+// restore puts back the database moved aside by a compaction, and reopens it
+func (c *client) restore(path, backupPath string, cause error) error {
+	if err := os.Rename(backupPath, path); err != nil {
+		return errors.Wrapf(cause, "failed to put the database back (%v)", err)
+	}
+	return c.reopen(path, cause)
+}
+
+// reopen reopens the database closed by a compaction that failed, and returns the
+// cause of the failure
+func (c *client) reopen(path string, cause error) error {
+	db, err := openDB(path, dbMode, nil)
+	if err != nil {
+		return errors.Wrapf(cause, "failed to reopen the database (%v)", err)
+	}
+	c.db = db
+	return cause
+}
+
+// restoreInterruptedCompaction puts back the database moved aside by a compaction that
+// was interrupted before the compacted one replaced it
+func restoreInterruptedCompaction(path string) error {
+	backupPath := path + precompactionSuffix
+	if _, err := os.Stat(backupPath); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		// The compacted database replaced it, the agent stopped before removing it
+		return os.Remove(backupPath)
+	}
+	seelog.Warnf("Restoring data store %s moved aside by an interrupted compaction", path)
+	return os.Rename(backupPath, path)
+}
+
+// CompactPeriodically compacts the database at every interval, until the context
+// is canceled
+func CompactPeriodically(ctx context.Context, c Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Compact(); err != nil {
+				seelog.Warnf("Unable to compact the data store: %v", err)
+			}
+		}
+	}
+}
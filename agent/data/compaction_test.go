@@ -0,0 +1,135 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func dbSize(t *testing.T, testDir string) int64 {
+	info, err := os.Stat(filepath.Join(testDir, dbName))
+	require.NoError(t, err)
+	return info.Size()
+}
+
+// setupFreePages saves and deletes tasks so that most of the database is free pages,
+// keeping task0
+func setupFreePages(t *testing.T, c Client) {
+	family := strings.Repeat("x", 4096)
+	for i := 0; i < 200; i++ {
+		require.NoError(t, c.SaveTask(&apitask.Task{
+			Arn:    fmt.Sprintf("arn:aws:ecs:us-west-2:1234567890:task/test-cluster/task%d", i),
+			Family: family,
+		}))
+	}
+	for i := 1; i < 200; i++ {
+		require.NoError(t, c.DeleteTask(fmt.Sprintf("task%d", i)))
+	}
+}
+
+func TestCompact(t *testing.T) {
+	testDir := t.TempDir()
+	c, err := NewWithSetup(testDir)
+	require.NoError(t, err)
+	defer c.Close()
+
+	setupFreePages(t, c)
+	sizeBefore := dbSize(t, testDir)
+
+	require.NoError(t, c.Compact())
+	assert.True(t, dbSize(t, testDir) < sizeBefore)
+
+	// The client keeps working with the compacted database.
+	tasks, err := c.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "arn:aws:ecs:us-west-2:1234567890:task/test-cluster/task0", tasks[0].Arn)
+	require.NoError(t, c.SaveTask(&apitask.Task{Arn: testTaskArn}))
+
+	// Compacting a database without free pages does nothing.
+	sizeBefore = dbSize(t, testDir)
+	require.NoError(t, c.Compact())
+	assert.Equal(t, sizeBefore, dbSize(t, testDir))
+	_, err = os.Stat(filepath.Join(testDir, dbName+compactingSuffix))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(testDir, dbName+precompactionSuffix))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCompactOpenFailure(t *testing.T) {
+	defer func() {
+		openDB = bolt.Open
+	}()
+	testDir := t.TempDir()
+	c, err := NewWithSetup(testDir)
+	require.NoError(t, err)
+	defer c.Close()
+
+	setupFreePages(t, c)
+	sizeBefore := dbSize(t, testDir)
+
+	opened := 0
+	openDB = func(path string, mode os.FileMode, options *bolt.Options) (*bolt.DB, error) {
+		opened++
+		if opened == 1 {
+			return nil, errors.New("no space left on device")
+		}
+		return bolt.Open(path, mode, options)
+	}
+	assert.Error(t, c.Compact())
+	assert.Equal(t, 2, opened)
+
+	// The original database is put back, and the client keeps working with it.
+	assert.Equal(t, sizeBefore, dbSize(t, testDir))
+	tasks, err := c.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.NoError(t, c.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	_, err = os.Stat(filepath.Join(testDir, dbName+precompactionSuffix))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRestoreInterruptedCompaction(t *testing.T) {
+	testDir := t.TempDir()
+	c, err := NewWithSetup(testDir)
+	require.NoError(t, err)
+	require.NoError(t, c.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	require.NoError(t, c.Close())
+
+	// The agent stopped after moving the database aside.
+	path := filepath.Join(testDir, dbName)
+	require.NoError(t, os.Rename(path, path+precompactionSuffix))
+
+	c, err = NewWithSetup(testDir)
+	require.NoError(t, err)
+	defer c.Close()
+	tasks, err := c.GetTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	_, err = os.Stat(path + precompactionSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
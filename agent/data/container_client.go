@@ -29,7 +29,7 @@ func (c *client) SaveDockerContainer(container *apicontainer.DockerContainer) er
 	if err != nil {
 		return errors.Wrap(err, "failed to generate database id")
 	}
-	return c.db.Batch(func(tx *bolt.Tx) error {
+	return c.batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(containersBucketName))
 		return putObject(b, id, container)
 	})
@@ -49,7 +49,7 @@ func (c *client) SaveContainer(container *apicontainer.Container) error {
 		dockerContainer = &apicontainer.DockerContainer{}
 	}
 	dockerContainer.Container = container
-	return c.db.Batch(func(tx *bolt.Tx) error {
+	return c.batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(containersBucketName))
 		return putObject(b, id, dockerContainer)
 	})
@@ -57,7 +57,7 @@ func (c *client) SaveContainer(container *apicontainer.Container) error {
 
 func (c *client) getDockerContainer(id string) (*apicontainer.DockerContainer, error) {
 	container := &apicontainer.DockerContainer{}
-	err := c.db.View(func(tx *bolt.Tx) error {
+	err := c.view(func(tx *bolt.Tx) error {
 		return getObject(tx, containersBucketName, id, container)
 	})
 	return container, err
@@ -65,7 +65,7 @@ func (c *client) getDockerContainer(id string) (*apicontainer.DockerContainer, e
 
 // DeleteContainer deletes a container from the container bucket.
 func (c *client) DeleteContainer(id string) error {
-	return c.db.Batch(func(tx *bolt.Tx) error {
+	return c.batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(containersBucketName))
 		return b.Delete([]byte(id))
 	})
@@ -74,7 +74,7 @@ func (c *client) DeleteContainer(id string) error {
 // GetContainers returns all the containers in the container bucket.
 func (c *client) GetContainers() ([]*apicontainer.DockerContainer, error) {
 	var containers []*apicontainer.DockerContainer
-	err := c.db.View(func(tx *bolt.Tx) error {
+	err := c.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(containersBucketName))
 		return walk(bucket, func(id string, data []byte) error {
 			container := apicontainer.DockerContainer{}
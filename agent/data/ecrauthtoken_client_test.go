@@ -0,0 +1,43 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManageECRAuthTokens(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	require.NoError(t, testClient.SaveECRAuthToken("key1", []byte("token1")))
+	require.NoError(t, testClient.SaveECRAuthToken("key2", []byte("token2")))
+
+	tokens, err := testClient.GetECRAuthTokens()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"key1": []byte("token1"),
+		"key2": []byte("token2"),
+	}, tokens)
+
+	require.NoError(t, testClient.DeleteECRAuthToken("key1"))
+	tokens, err = testClient.GetECRAuthTokens()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"key2": []byte("token2")}, tokens)
+}
@@ -28,14 +28,14 @@ func (c *client) SaveENIAttachment(eni *apieni.ENIAttachment) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to generate database id")
 	}
-	return c.db.Batch(func(tx *bolt.Tx) error {
+	return c.batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(eniAttachmentsBucketName))
 		return putObject(b, id, eni)
 	})
 }
 
 func (c *client) DeleteENIAttachment(id string) error {
-	return c.db.Batch(func(tx *bolt.Tx) error {
+	return c.batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(eniAttachmentsBucketName))
 		return b.Delete([]byte(id))
 	})
@@ -43,7 +43,7 @@ func (c *client) DeleteENIAttachment(id string) error {
 
 func (c *client) GetENIAttachments() ([]*apieni.ENIAttachment, error) {
 	var eniAttachments []*apieni.ENIAttachment
-	err := c.db.View(func(tx *bolt.Tx) error {
+	err := c.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(eniAttachmentsBucketName))
 		return walk(bucket, func(id string, data []byte) error {
 			eniAttachment := apieni.ENIAttachment{}
@@ -0,0 +1,377 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fileStoreDirName is the directory, in the data directory, of the file data store
+	fileStoreDirName = "state"
+	// fileObjectSuffix is the suffix of the files of the objects
+	fileObjectSuffix = ".json"
+	// fileTempSuffix is the suffix of the files being written, before they replace the
+	// file of their object
+	fileTempSuffix = ".tmp"
+	// fileCorruptedSuffix is the suffix given to the files of the objects that can't
+	// be decoded
+	fileCorruptedSuffix = ".corrupted"
+)
+
+// fileClient implements the Client interface with a JSON file per object, in a
+// directory per bucket. Unlike boltdb, writes of different objects don't contend on
+// a lock, and deleted objects release their space right away, which suits instances
+// running a lot of short tasks.
+type fileClient struct {
+	dir string
+	// containerLock serializes SaveContainer, which updates the saved docker container
+	containerLock sync.Mutex
+	recovered     bool
+}
+
+// setupFileClient creates the directories of the buckets of the file data store.
+// The files left by interrupted writes are removed, and the files that can't be
+// decoded are moved aside.
+func setupFileClient(dataDir string) (*fileClient, error) {
+	c := &fileClient{
+		dir: filepath.Join(dataDir, fileStoreDirName),
+	}
+	for _, b := range buckets {
+		bucketDir := filepath.Join(c.dir, b)
+		if err := os.MkdirAll(bucketDir, 0700); err != nil {
+			return nil, errors.Wrapf(err, "failed to create directory of bucket %s", b)
+		}
+		files, err := ioutil.ReadDir(bucketDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list directory of bucket %s", b)
+		}
+		for _, file := range files {
+			path := filepath.Join(bucketDir, file.Name())
+			switch {
+			case strings.HasSuffix(file.Name(), fileTempSuffix):
+				os.Remove(path)
+			case strings.HasSuffix(file.Name(), fileObjectSuffix):
+				data, err := ioutil.ReadFile(path)
+				if err == nil && json.Valid(data) {
+					continue
+				}
+				seelog.Errorf("Data store file %s is corrupted, moving it aside", path)
+				if err := os.Rename(path, path+fileCorruptedSuffix); err != nil {
+					return nil, errors.Wrapf(err, "failed to move corrupted file %s aside", path)
+				}
+				c.recovered = true
+			}
+		}
+	}
+	return c, nil
+}
+
+// objectPath returns the path of the file of an object. The keys are encoded as they
+// may contain characters that aren't allowed in file names.
+func (c *fileClient) objectPath(bucket, key string) string {
+	return filepath.Join(c.dir, bucket, base64.RawURLEncoding.EncodeToString([]byte(key))+fileObjectSuffix)
+}
+
+// put writes the file of an object to a temporary file first, which then replaces the
+// previous file of the object, so that the file is never partially written.
+func (c *fileClient) put(bucket, key string, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal object with key %q", key)
+	}
+	return c.putRaw(bucket, key, data)
+}
+
+// putRaw writes the file of an object that's already marshaled
+func (c *fileClient) putRaw(bucket, key string, data []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Join(c.dir, bucket), "*"+fileTempSuffix)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file of object with key %q", key)
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to write file of object with key %q", key)
+	}
+	if err := os.Rename(tmpFile.Name(), c.objectPath(bucket, key)); err != nil {
+		return errors.Wrapf(err, "failed to insert object with key %q", key)
+	}
+	return nil
+}
+
+func (c *fileClient) get(bucket, key string, out interface{}) error {
+	data, err := ioutil.ReadFile(c.objectPath(bucket, key))
+	if os.IsNotExist(err) {
+		return errors.Errorf("object %s not found in bucket %s", key, bucket)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to read object with key %q", key)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal object with key %q", key)
+	}
+	return nil
+}
+
+func (c *fileClient) delete(bucket, key string) error {
+	err := os.Remove(c.objectPath(bucket, key))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to delete object with key %q", key)
+	}
+	return nil
+}
+
+func (c *fileClient) walk(bucket string, callback func(key string, data []byte) error) error {
+	files, err := ioutil.ReadDir(filepath.Join(c.dir, bucket))
+	if err != nil {
+		return errors.Wrapf(err, "failed to list objects of bucket %s", bucket)
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, fileObjectSuffix) {
+			continue
+		}
+		key, err := base64.RawURLEncoding.DecodeString(strings.TrimSuffix(name, fileObjectSuffix))
+		if err != nil {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(c.dir, bucket, name))
+		if os.IsNotExist(err) {
+			// The object was deleted after the directory was listed
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read object with key %q", string(key))
+		}
+		if err := callback(string(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveDockerContainer saves a docker container to the container bucket.
+func (c *fileClient) SaveDockerContainer(container *apicontainer.DockerContainer) error {
+	id, err := GetContainerID(container.Container)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate database id")
+	}
+	return c.put(containersBucketName, id, container)
+}
+
+// SaveContainer saves an apicontainer.Container to the container bucket, updating the
+// Container part of the corresponding apicontainer.DockerContainer if it exists.
+func (c *fileClient) SaveContainer(container *apicontainer.Container) error {
+	id, err := GetContainerID(container)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate database id")
+	}
+
+	c.containerLock.Lock()
+	defer c.containerLock.Unlock()
+	dockerContainer := &apicontainer.DockerContainer{}
+	if err := c.get(containersBucketName, id, dockerContainer); err != nil {
+		dockerContainer = &apicontainer.DockerContainer{}
+	}
+	dockerContainer.Container = container
+	return c.put(containersBucketName, id, dockerContainer)
+}
+
+// DeleteContainer deletes a container from the container bucket.
+func (c *fileClient) DeleteContainer(id string) error {
+	return c.delete(containersBucketName, id)
+}
+
+// GetContainers returns all the containers in the container bucket.
+func (c *fileClient) GetContainers() ([]*apicontainer.DockerContainer, error) {
+	var containers []*apicontainer.DockerContainer
+	err := c.walk(containersBucketName, func(id string, data []byte) error {
+		container := apicontainer.DockerContainer{}
+		if err := json.Unmarshal(data, &container); err != nil {
+			return err
+		}
+		containers = append(containers, &container)
+		return nil
+	})
+	return containers, err
+}
+
+// SaveTask saves a task to the task bucket.
+func (c *fileClient) SaveTask(task *apitask.Task) error {
+	id, err := utils.GetTaskID(task.Arn)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate database id")
+	}
+	return c.put(tasksBucketName, id, task)
+}
+
+// DeleteTask deletes a task from the task bucket.
+func (c *fileClient) DeleteTask(id string) error {
+	return c.delete(tasksBucketName, id)
+}
+
+// GetTasks returns all the tasks in the task bucket.
+func (c *fileClient) GetTasks() ([]*apitask.Task, error) {
+	var tasks []*apitask.Task
+	err := c.walk(tasksBucketName, func(id string, data []byte) error {
+		task := apitask.Task{}
+		if err := json.Unmarshal(data, &task); err != nil {
+			return err
+		}
+		tasks = append(tasks, &task)
+		return nil
+	})
+	return tasks, err
+}
+
+func (c *fileClient) SaveImageState(img *image.ImageState) error {
+	id := img.GetImageID()
+	if id == "" {
+		return errors.New("failed to generate database image id")
+	}
+	return c.put(imagesBucketName, id, img)
+}
+
+func (c *fileClient) DeleteImageState(id string) error {
+	return c.delete(imagesBucketName, id)
+}
+
+func (c *fileClient) GetImageStates() ([]*image.ImageState, error) {
+	var imageStates []*image.ImageState
+	err := c.walk(imagesBucketName, func(id string, data []byte) error {
+		imageState := image.ImageState{}
+		if err := json.Unmarshal(data, &imageState); err != nil {
+			return err
+		}
+		imageStates = append(imageStates, &imageState)
+		return nil
+	})
+	return imageStates, err
+}
+
+func (c *fileClient) SaveENIAttachment(eni *apieni.ENIAttachment) error {
+	id, err := utils.GetENIAttachmentId(eni.AttachmentARN)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate database id")
+	}
+	return c.put(eniAttachmentsBucketName, id, eni)
+}
+
+func (c *fileClient) DeleteENIAttachment(id string) error {
+	return c.delete(eniAttachmentsBucketName, id)
+}
+
+func (c *fileClient) GetENIAttachments() ([]*apieni.ENIAttachment, error) {
+	var eniAttachments []*apieni.ENIAttachment
+	err := c.walk(eniAttachmentsBucketName, func(id string, data []byte) error {
+		eniAttachment := apieni.ENIAttachment{}
+		if err := json.Unmarshal(data, &eniAttachment); err != nil {
+			return err
+		}
+		eniAttachments = append(eniAttachments, &eniAttachment)
+		return nil
+	})
+	return eniAttachments, err
+}
+
+func (c *fileClient) SaveMetadata(key, val string) error {
+	return c.put(metadataBucketName, key, val)
+}
+
+func (c *fileClient) GetMetadata(key string) (string, error) {
+	var val string
+	err := c.get(metadataBucketName, key, &val)
+	return val, err
+}
+
+func (c *fileClient) SaveECRAuthToken(key string, token []byte) error {
+	return c.put(ecrAuthTokensBucketName, key, token)
+}
+
+func (c *fileClient) DeleteECRAuthToken(key string) error {
+	return c.delete(ecrAuthTokensBucketName, key)
+}
+
+func (c *fileClient) GetECRAuthTokens() (map[string][]byte, error) {
+	tokens := make(map[string][]byte)
+	err := c.walk(ecrAuthTokensBucketName, func(key string, data []byte) error {
+		var token []byte
+		if err := json.Unmarshal(data, &token); err != nil {
+			return err
+		}
+		tokens[key] = token
+		return nil
+	})
+	return tokens, err
+}
+
+func (c *fileClient) SaveACSResponse(key string, response []byte) error {
+	return c.put(acsResponsesBucketName, key, response)
+}
+
+func (c *fileClient) DeleteACSResponse(key string) error {
+	return c.delete(acsResponsesBucketName, key)
+}
+
+func (c *fileClient) GetACSResponses() (map[string][]byte, error) {
+	responses := make(map[string][]byte)
+	err := c.walk(acsResponsesBucketName, func(key string, data []byte) error {
+		var response []byte
+		if err := json.Unmarshal(data, &response); err != nil {
+			return err
+		}
+		responses[key] = response
+		return nil
+	})
+	return responses, err
+}
+
+// Compact does nothing, the space of the deleted objects is released when their file
+// is removed.
+func (c *fileClient) Compact() error {
+	return nil
+}
+
+// Recovered returns whether files of objects were moved aside when the data store was
+// opened, because they couldn't be decoded.
+func (c *fileClient) Recovered() bool {
+	return c.recovered
+}
+
+// Close does nothing, the files are closed after every operation.
+func (c *fileClient) Close() error {
+	return nil
+}
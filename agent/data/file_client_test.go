@@ -0,0 +1,134 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileClientManageTask(t *testing.T) {
+	testClient, err := setupBackend(config.DataBackendFile, t.TempDir())
+	require.NoError(t, err)
+	defer testClient.Close()
+
+	require.NoError(t, testClient.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	res, err := testClient.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, testTaskArn, res[0].Arn)
+
+	require.NoError(t, testClient.DeleteTask("abc"))
+	res, err = testClient.GetTasks()
+	require.NoError(t, err)
+	assert.Len(t, res, 0)
+	// Deleting a task that isn't saved isn't an error, like with boltdb.
+	assert.NoError(t, testClient.DeleteTask("abc"))
+}
+
+func TestFileClientManageContainer(t *testing.T) {
+	testClient, err := setupBackend(config.DataBackendFile, t.TempDir())
+	require.NoError(t, err)
+	defer testClient.Close()
+
+	container := &apicontainer.Container{
+		Name:              "test-name",
+		TaskARNUnsafe:     testTaskArn,
+		KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+	}
+	require.NoError(t, testClient.SaveDockerContainer(&apicontainer.DockerContainer{
+		DockerID:  "docker-id",
+		Container: container,
+	}))
+	container.SetKnownStatus(apicontainerstatus.ContainerStopped)
+	require.NoError(t, testClient.SaveContainer(container))
+
+	res, err := testClient.GetContainers()
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "docker-id", res[0].DockerID)
+	assert.Equal(t, apicontainerstatus.ContainerStopped, res[0].Container.GetKnownStatus())
+
+	require.NoError(t, testClient.DeleteContainer("abc-test-name"))
+	res, err = testClient.GetContainers()
+	require.NoError(t, err)
+	assert.Len(t, res, 0)
+}
+
+func TestFileClientMetadataAndTokens(t *testing.T) {
+	testClient, err := setupBackend(config.DataBackendFile, t.TempDir())
+	require.NoError(t, err)
+	defer testClient.Close()
+
+	require.NoError(t, testClient.SaveMetadata(ClusterNameKey, "test-cluster"))
+	cluster, err := testClient.GetMetadata(ClusterNameKey)
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", cluster)
+	_, err = testClient.GetMetadata(AvailabilityZoneKey)
+	assert.Error(t, err)
+
+	// Keys with characters that aren't allowed in file names are encoded.
+	key := "https://1234567890.dkr.ecr.us-west-2.amazonaws.com/repo:tag"
+	require.NoError(t, testClient.SaveECRAuthToken(key, []byte("token")))
+	tokens, err := testClient.GetECRAuthTokens()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{key: []byte("token")}, tokens)
+	require.NoError(t, testClient.DeleteECRAuthToken(key))
+	tokens, err = testClient.GetECRAuthTokens()
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestFileClientSetupRecovers(t *testing.T) {
+	testDir := t.TempDir()
+	testClient, err := setupBackend(config.DataBackendFile, testDir)
+	require.NoError(t, err)
+	require.NoError(t, testClient.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	require.NoError(t, testClient.Close())
+
+	tasksDir := filepath.Join(testDir, fileStoreDirName, tasksBucketName)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tasksDir, "torn"+fileObjectSuffix), []byte(`{"Arn": "a`), dbMode))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tasksDir, "interrupted"+fileTempSuffix), []byte(`{`), dbMode))
+
+	testClient, err = setupBackend(config.DataBackendFile, testDir)
+	require.NoError(t, err)
+	defer testClient.Close()
+	assert.True(t, testClient.Recovered())
+	res, err := testClient.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, testTaskArn, res[0].Arn)
+
+	_, err = os.Stat(filepath.Join(tasksDir, "torn"+fileObjectSuffix+fileCorruptedSuffix))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tasksDir, "interrupted"+fileTempSuffix))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSetupUnknownBackend(t *testing.T) {
+	_, err := setupBackend("sqlite", t.TempDir())
+	assert.Error(t, err)
+}
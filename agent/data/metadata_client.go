@@ -27,7 +27,7 @@ const (
 )
 
 func (c *client) SaveMetadata(key, val string) error {
-	return c.db.Batch(func(tx *bolt.Tx) error {
+	return c.batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(metadataBucketName))
 		return putObject(b, key, val)
 	})
@@ -35,7 +35,7 @@ func (c *client) SaveMetadata(key, val string) error {
 
 func (c *client) GetMetadata(key string) (string, error) {
 	var val string
-	err := c.db.View(func(tx *bolt.Tx) error {
+	err := c.view(func(tx *bolt.Tx) error {
 		return getObject(tx, metadataBucketName, key, &val)
 	})
 	return val, err
@@ -0,0 +1,141 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// migratedSuffix is the suffix given to the data of a backend once it was migrated to the
+// other backend, so that it isn't migrated again
+const migratedSuffix = ".migrated"
+
+// rawStore reads and writes the marshaled objects of a backend, both backends keep the
+// same JSON documents by the same keys
+type rawStore interface {
+	walkBucket(bucket string, callback func(key string, data []byte) error) error
+	putRaw(bucket, key string, data []byte) error
+}
+
+func (c *client) walkBucket(bucket string, callback func(key string, data []byte) error) error {
+	return c.view(func(tx *bolt.Tx) error {
+		return walk(tx.Bucket([]byte(bucket)), callback)
+	})
+}
+
+func (c *client) putRaw(bucket, key string, data []byte) error {
+	return c.update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Put([]byte(key), data)
+	})
+}
+
+func (c *fileClient) walkBucket(bucket string, callback func(key string, data []byte) error) error {
+	return c.walk(bucket, callback)
+}
+
+// migrateFromBoltDB migrates the data of the boltdb database of the data directory, if
+// there is one, to the file data store
+func migrateFromBoltDB(to *fileClient, dataDir string) error {
+	path := filepath.Join(dataDir, dbName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	from, err := setup(dataDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the boltdb data store to migrate it")
+	}
+	err = migrate(from, to, path)
+	from.Close()
+	if err != nil {
+		return err
+	}
+	return moveAsideMigrated(path)
+}
+
+// migrateFromFileClient migrates the data of the file data store of the data directory,
+// if there is one, to the boltdb database
+func migrateFromFileClient(to *client, dataDir string) error {
+	path := filepath.Join(dataDir, fileStoreDirName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	from, err := setupFileClient(dataDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the file data store to migrate it")
+	}
+	if err := migrate(from, to, path); err != nil {
+		return err
+	}
+	return moveAsideMigrated(path)
+}
+
+// migrate copies the objects of every bucket of a backend to the other. It refuses to
+// when both backends have data, as it can't tell which of them is current.
+func migrate(from, to rawStore, path string) error {
+	empty, err := isEmpty(from)
+	if err != nil || empty {
+		return err
+	}
+	if empty, err = isEmpty(to); err != nil {
+		return err
+	}
+	if !empty {
+		return errors.Errorf("both data backends have data, remove %s or the data of the other backend "+
+			"to choose which one is kept", path)
+	}
+	for _, bucket := range buckets {
+		err := from.walkBucket(bucket, func(key string, data []byte) error {
+			return to.putRaw(bucket, key, data)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to migrate bucket %s of %s", bucket, path)
+		}
+	}
+	seelog.Infof("Migrated the data of %s to the configured data backend", path)
+	return nil
+}
+
+// isEmpty returns whether none of the buckets of a backend has any object
+func isEmpty(store rawStore) (bool, error) {
+	errNotEmpty := errors.New("not empty")
+	for _, bucket := range buckets {
+		err := store.walkBucket(bucket, func(string, []byte) error {
+			return errNotEmpty
+		})
+		if err == errNotEmpty {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// moveAsideMigrated renames the data of a backend once it was migrated, replacing the
+// data that was moved aside by a previous migration
+func moveAsideMigrated(path string) error {
+	if err := os.RemoveAll(path + migratedSuffix); err != nil {
+		return errors.Wrapf(err, "failed to remove previously migrated data %s", path+migratedSuffix)
+	}
+	if err := os.Rename(path, path+migratedSuffix); err != nil {
+		return errors.Wrapf(err, "failed to move migrated data %s aside", path)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupBackendMigratesBoltDBToFile(t *testing.T) {
+	testDir := t.TempDir()
+	boltClient, err := setupBackend(config.DataBackendBoltDB, testDir)
+	require.NoError(t, err)
+	require.NoError(t, boltClient.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	require.NoError(t, boltClient.SaveMetadata(ClusterNameKey, "test-cluster"))
+	require.NoError(t, boltClient.Close())
+
+	fileClient, err := setupBackend(config.DataBackendFile, testDir)
+	require.NoError(t, err)
+	defer fileClient.Close()
+	tasks, err := fileClient.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, testTaskArn, tasks[0].Arn)
+	cluster, err := fileClient.GetMetadata(ClusterNameKey)
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", cluster)
+
+	_, err = os.Stat(filepath.Join(testDir, dbName))
+	assert.True(t, os.IsNotExist(err), "the migrated database should be moved aside")
+	_, err = os.Stat(filepath.Join(testDir, dbName+migratedSuffix))
+	assert.NoError(t, err)
+}
+
+func TestSetupBackendMigratesFileToBoltDB(t *testing.T) {
+	testDir := t.TempDir()
+	fileClient, err := setupBackend(config.DataBackendFile, testDir)
+	require.NoError(t, err)
+	require.NoError(t, fileClient.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	require.NoError(t, fileClient.Close())
+
+	boltClient, err := setupBackend(config.DataBackendBoltDB, testDir)
+	require.NoError(t, err)
+	defer boltClient.Close()
+	tasks, err := boltClient.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, testTaskArn, tasks[0].Arn)
+
+	_, err = os.Stat(filepath.Join(testDir, fileStoreDirName))
+	assert.True(t, os.IsNotExist(err), "the migrated file data store should be moved aside")
+}
+
+func TestSetupBackendRefusesToMigrateWhenBothHaveData(t *testing.T) {
+	testDir := t.TempDir()
+	fileClient, err := setupFileClient(testDir)
+	require.NoError(t, err)
+	require.NoError(t, fileClient.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	boltClient, err := setup(testDir)
+	require.NoError(t, err)
+	require.NoError(t, boltClient.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	require.NoError(t, boltClient.Close())
+
+	_, err = setupBackend(config.DataBackendFile, testDir)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(testDir, dbName))
+	assert.NoError(t, err, "the data of the other backend should be left in place")
+}
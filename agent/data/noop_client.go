@@ -88,6 +88,38 @@ func (c *noopClient) GetMetadata(string) (string, error) {
 	return "", nil
 }
 
+func (c *noopClient) SaveECRAuthToken(string, []byte) error {
+	return nil
+}
+
+func (c *noopClient) DeleteECRAuthToken(string) error {
+	return nil
+}
+
+func (c *noopClient) GetECRAuthTokens() (map[string][]byte, error) {
+	return nil, nil
+}
+
+func (c *noopClient) SaveACSResponse(string, []byte) error {
+	return nil
+}
+
+func (c *noopClient) DeleteACSResponse(string) error {
+	return nil
+}
+
+func (c *noopClient) GetACSResponses() (map[string][]byte, error) {
+	return nil, nil
+}
+
+func (c *noopClient) Compact() error {
+	return nil
+}
+
+func (c *noopClient) Recovered() bool {
+	return false
+}
+
 func (c *noopClient) Close() error {
 	return nil
 }
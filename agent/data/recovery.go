@@ -0,0 +1,184 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// corruptedSuffixFormat is the suffix of the corrupted databases moved aside when
+// they are rebuilt, followed by the time of the recovery
+const corruptedSuffixFormat = ".corrupted-%d"
+
+// corruptionError is an error caused by a corrupted database
+type corruptionError struct {
+	err error
+}
+
+func (e corruptionError) Error() string {
+	return fmt.Sprintf("database is corrupted: %v", e.err)
+}
+
+// isCorruption returns whether the error opening a database is caused by the
+// database being corrupted, rather than by the database being unavailable
+func isCorruption(err error) bool {
+	if _, ok := err.(corruptionError); ok {
+		return true
+	}
+	return err == bolt.ErrInvalid || err == bolt.ErrChecksum || err == bolt.ErrVersionMismatch
+}
+
+// openAndCheck opens the database and checks its integrity. The database is
+// returned open along with the error when it's corrupted but could be opened, so
+// that its data can be salvaged.
+func openAndCheck(path string) (db *bolt.DB, err error) {
+	defer func() {
+		// Reading corrupted pages can panic
+		if r := recover(); r != nil {
+			err = corruptionError{errors.Errorf("panic while opening the database: %v", r)}
+		}
+	}()
+	db, err = bolt.Open(path, dbMode, nil)
+	if err != nil {
+		return nil, err
+	}
+	return db, checkIntegrity(db)
+}
+
+// checkIntegrity checks the consistency of the pages of the database, and that
+// every object it holds can be decoded
+func checkIntegrity(db *bolt.DB) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = corruptionError{errors.Errorf("panic while checking the database: %v", r)}
+		}
+	}()
+	return db.View(func(tx *bolt.Tx) error {
+		var checkErr error
+		// The errors are drained, the check goes on reading the transaction until
+		// the channel is closed
+		for err := range tx.Check() {
+			if checkErr == nil {
+				checkErr = corruptionError{err}
+			}
+		}
+		if checkErr != nil {
+			return checkErr
+		}
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return bucket.ForEach(func(key, value []byte) error {
+				if value != nil && !json.Valid(value) {
+					return corruptionError{errors.Errorf("object %s in bucket %s can't be decoded", key, name)}
+				}
+				return nil
+			})
+		})
+	})
+}
+
+// recoverDatabase moves the corrupted database aside, and creates a new database
+// at its path with the objects that can still be read from it. corrupted is nil
+// when the corrupted database couldn't be opened, in which case nothing is salvaged.
+func recoverDatabase(path string, corrupted *bolt.DB) (*bolt.DB, error) {
+	if corrupted != nil {
+		corrupted.Close()
+	}
+	corruptedPath := path + fmt.Sprintf(corruptedSuffixFormat, time.Now().Unix())
+	if err := os.Rename(path, corruptedPath); err != nil {
+		return nil, errors.Wrap(err, "failed to move the corrupted database aside")
+	}
+	db, err := bolt.Open(path, dbMode, nil)
+	if err != nil {
+		return nil, err
+	}
+	if corrupted == nil {
+		seelog.Warnf("Data store %s couldn't be opened, no data was salvaged from it", corruptedPath)
+		return db, nil
+	}
+	// The corrupted database is opened read-only, so that it's left as is for inspection
+	src, err := bolt.Open(corruptedPath, dbMode, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		seelog.Warnf("Unable to reopen data store %s, no data was salvaged from it: %v", corruptedPath, err)
+		return db, nil
+	}
+	defer src.Close()
+	salvaged, lost := salvage(src, db)
+	seelog.Warnf("Rebuilt data store %s: %d objects salvaged, %d objects lost. The corrupted data store was moved to %s",
+		path, salvaged, lost, corruptedPath)
+	return db, nil
+}
+
+// salvage copies the objects of the known buckets that can still be read and decoded
+// from the source database to the destination database, and returns the number of
+// objects copied and lost
+func salvage(src *bolt.DB, dst *bolt.DB) (salvaged int, lost int) {
+	for _, name := range buckets {
+		objects, unreadable := readBucket(src, name)
+		lost += unreadable
+		err := dst.Update(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+			for key, value := range objects {
+				if err := bucket.Put([]byte(key), value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			seelog.Warnf("Unable to salvage the objects of bucket %s: %v", name, err)
+			lost += len(objects)
+			continue
+		}
+		salvaged += len(objects)
+	}
+	return salvaged, lost
+}
+
+// readBucket reads the objects of a bucket that can be decoded, until the end of
+// the bucket or its first unreadable page. It returns the objects read, and the
+// number of objects skipped because they couldn't be decoded
+func readBucket(db *bolt.DB, name string) (objects map[string][]byte, unreadable int) {
+	objects = make(map[string][]byte)
+	defer func() {
+		if r := recover(); r != nil {
+			seelog.Warnf("Stopped reading bucket %s at a corrupted page: %v", name, r)
+		}
+	}()
+	db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(name))
+		if bucket == nil {
+			return nil
+		}
+		return walk(bucket, func(id string, data []byte) error {
+			if !json.Valid(data) {
+				unreadable++
+				return nil
+			}
+			// The data is only valid for the life of the transaction
+			objects[id] = append([]byte(nil), data...)
+			return nil
+		})
+	})
+	return objects, unreadable
+}
@@ -0,0 +1,95 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestSetupIntactDatabase(t *testing.T) {
+	testDir := t.TempDir()
+	c, err := NewWithSetup(testDir)
+	require.NoError(t, err)
+	require.NoError(t, c.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	require.NoError(t, c.Close())
+
+	c, err = NewWithSetup(testDir)
+	require.NoError(t, err)
+	defer c.Close()
+	assert.False(t, c.Recovered())
+	tasks, err := c.GetTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
+
+func TestSetupRecoversUndecodableObjects(t *testing.T) {
+	testDir := t.TempDir()
+	c, err := NewWithSetup(testDir)
+	require.NoError(t, err)
+	require.NoError(t, c.SaveTask(&apitask.Task{Arn: testTaskArn}))
+	require.NoError(t, c.SaveMetadata(ClusterNameKey, "test-cluster"))
+	require.NoError(t, c.Close())
+
+	// Simulate a torn write of a task.
+	db, err := bolt.Open(filepath.Join(testDir, dbName), dbMode, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(tasksBucketName)).Put([]byte("torn"), []byte(`{"Arn": "arn:aws:e`))
+	}))
+	require.NoError(t, db.Close())
+
+	c, err = NewWithSetup(testDir)
+	require.NoError(t, err)
+	defer c.Close()
+	assert.True(t, c.Recovered())
+	tasks, err := c.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, testTaskArn, tasks[0].Arn)
+	cluster, err := c.GetMetadata(ClusterNameKey)
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", cluster)
+
+	corrupted, err := filepath.Glob(filepath.Join(testDir, dbName+".corrupted-*"))
+	require.NoError(t, err)
+	assert.Len(t, corrupted, 1)
+}
+
+func TestSetupRecoversUnreadableDatabase(t *testing.T) {
+	testDir := t.TempDir()
+	garbage := make([]byte, 16*1024)
+	for i := range garbage {
+		garbage[i] = byte(i % 251)
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testDir, dbName), garbage, dbMode))
+
+	c, err := NewWithSetup(testDir)
+	require.NoError(t, err)
+	defer c.Close()
+	assert.True(t, c.Recovered())
+	tasks, err := c.GetTasks()
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+	require.NoError(t, c.SaveTask(&apitask.Task{Arn: testTaskArn}))
+}
@@ -0,0 +1,215 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package diagnostics builds the bundle of diagnostic information that can be
+// attached to support cases: goroutine dumps, recent logs, redacted agent state,
+// docker info and the network namespaces of the instance.
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BundleFilePrefix is the prefix of the names of the bundle files
+	BundleFilePrefix = "ecs-agent-diagnostics-"
+	// maxLogBytes is the number of bytes kept from the end of the log file
+	maxLogBytes = 10 * 1024 * 1024
+	// maxStackDumpBytes bounds the buffer of the goroutine dump
+	maxStackDumpBytes = 64 * 1024 * 1024
+	bundleFileMode    = 0600
+)
+
+// Source is the part of the task engine the bundle is collected from.
+type Source interface {
+	ListTasks() ([]*apitask.Task, error)
+	DockerInfo(context.Context) (types.Info, error)
+}
+
+// Bundler writes diagnostic bundles. Failing to collect a part of the bundle
+// doesn't fail the bundle; the error is written to errors.txt instead.
+type Bundler struct {
+	source  Source
+	logFile string
+	now     func() time.Time
+}
+
+// NewBundler returns a Bundler collecting the state from source and the logs
+// from logFile, which is skipped when empty.
+func NewBundler(source Source, logFile string) *Bundler {
+	return &Bundler{
+		source:  source,
+		logFile: logFile,
+		now:     time.Now,
+	}
+}
+
+// Write writes the bundle to w as a gzipped tarball.
+func (b *Bundler) Write(ctx context.Context, w io.Writer) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	bundle := &bundleWriter{tar: tarWriter, modTime: b.now()}
+
+	bundle.add("goroutines.txt", goroutineDump)
+	if b.logFile != "" {
+		bundle.add(filepath.Join("logs", filepath.Base(b.logFile)), func() ([]byte, error) {
+			return tailFile(b.logFile, maxLogBytes)
+		})
+	}
+	bundle.add("state.json", b.state)
+	bundle.add("docker-info.json", func() ([]byte, error) {
+		info, err := b.source.DockerInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(info, "", "  ")
+	})
+	bundle.add("netns.txt", networkNamespaces)
+	if len(bundle.errors) > 0 {
+		bundle.add("errors.txt", func() ([]byte, error) {
+			return bundle.errors, nil
+		})
+	}
+	if bundle.err != nil {
+		return bundle.err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return errors.Wrap(err, "diagnostics: unable to close bundle archive")
+	}
+	return errors.Wrap(gzipWriter.Close(), "diagnostics: unable to compress bundle")
+}
+
+// WriteFile writes the bundle to a new file in dir and returns its path.
+func (b *Bundler) WriteFile(ctx context.Context, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", errors.Wrapf(err, "diagnostics: unable to create directory %s", dir)
+	}
+	path := filepath.Join(dir, BundleFilePrefix+b.now().UTC().Format("20060102T150405Z")+".tar.gz")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, bundleFileMode)
+	if err != nil {
+		return "", errors.Wrapf(err, "diagnostics: unable to create bundle %s", path)
+	}
+	err = b.Write(ctx, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// state returns the tasks of the engine with their secrets redacted.
+func (b *Bundler) state() ([]byte, error) {
+	tasks, err := b.source.ListTasks()
+	if err != nil {
+		return nil, err
+	}
+	tasksJSON, err := json.Marshal(tasks)
+	if err != nil {
+		return nil, err
+	}
+	return redactState(tasksJSON)
+}
+
+// bundleWriter adds files to the bundle archive, recording the errors of
+// the collection separately from the errors of the archive itself.
+type bundleWriter struct {
+	tar     *tar.Writer
+	modTime time.Time
+	errors  []byte
+	err     error
+}
+
+func (bundle *bundleWriter) add(name string, collect func() ([]byte, error)) {
+	if bundle.err != nil {
+		return
+	}
+	contents, err := collect()
+	if err != nil {
+		seelog.Warnf("Diagnostics: unable to collect %s: %v", name, err)
+		bundle.errors = append(bundle.errors, fmt.Sprintf("%s: %v\n", name, err)...)
+		return
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    bundleFileMode,
+		Size:    int64(len(contents)),
+		ModTime: bundle.modTime,
+	}
+	if err := bundle.tar.WriteHeader(header); err != nil {
+		bundle.err = errors.Wrapf(err, "diagnostics: unable to add %s to bundle", name)
+		return
+	}
+	if _, err := bundle.tar.Write(contents); err != nil {
+		bundle.err = errors.Wrapf(err, "diagnostics: unable to add %s to bundle", name)
+	}
+}
+
+// goroutineDump returns the stacks of all the goroutines, growing the buffer
+// until the dump fits.
+func goroutineDump() ([]byte, error) {
+	for size := 1024 * 1024; ; size *= 2 {
+		buf := make([]byte, size)
+		n := runtime.Stack(buf, true)
+		if n < size || size >= maxStackDumpBytes {
+			return buf[:n], nil
+		}
+	}
+}
+
+// tailFile returns the last maxBytes bytes of the file at path, starting at
+// the first complete line.
+func tailFile(path string, maxBytes int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - maxBytes
+	if offset <= 0 {
+		return ioutil.ReadAll(file)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	contents, err := ioutil.ReadAll(io.LimitReader(file, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.IndexByte(contents, '\n'); i >= 0 {
+		contents = contents[i+1:]
+	}
+	return contents, nil
+}
@@ -0,0 +1,184 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	tasks   []*apitask.Task
+	info    types.Info
+	infoErr error
+}
+
+func (f *fakeSource) ListTasks() ([]*apitask.Task, error) {
+	return f.tasks, nil
+}
+
+func (f *fakeSource) DockerInfo(ctx context.Context) (types.Info, error) {
+	return f.info, f.infoErr
+}
+
+func readBundle(t *testing.T, bundle io.Reader) map[string][]byte {
+	gzipReader, err := gzip.NewReader(bundle)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	files := make(map[string][]byte)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = contents
+	}
+}
+
+func newTestSource() *fakeSource {
+	return &fakeSource{
+		tasks: []*apitask.Task{{
+			Arn: "task1",
+			Containers: []*apicontainer.Container{{
+				Name:        "app",
+				Environment: map[string]string{"DB_PASSWORD": "hunter2"},
+				DockerConfig: apicontainer.DockerConfig{
+					Config: aws.String("{\"Env\":[\"DB_PASSWORD=hunter2\"]}"),
+				},
+			}},
+		}},
+		info: types.Info{ID: "docker-id", ServerVersion: "20.10.7"},
+	}
+}
+
+func TestBundlerWrite(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "ecs-agent.log")
+	require.NoError(t, ioutil.WriteFile(logFile, []byte("level=info msg=\"started\"\n"), 0600))
+	bundler := NewBundler(newTestSource(), logFile)
+
+	var bundle bytes.Buffer
+	require.NoError(t, bundler.Write(context.Background(), &bundle))
+	files := readBundle(t, &bundle)
+
+	assert.Contains(t, string(files["goroutines.txt"]), "goroutine")
+	assert.Equal(t, "level=info msg=\"started\"\n", string(files["logs/ecs-agent.log"]))
+	assert.NotContains(t, string(files["state.json"]), "hunter2")
+	assert.Contains(t, string(files["state.json"]), "DB_PASSWORD")
+	var info types.Info
+	require.NoError(t, json.Unmarshal(files["docker-info.json"], &info))
+	assert.Equal(t, "docker-id", info.ID)
+	assert.Contains(t, files, "netns.txt")
+	assert.NotContains(t, files, "errors.txt")
+}
+
+func TestBundlerWriteCollectionError(t *testing.T) {
+	source := newTestSource()
+	source.infoErr = errors.New("docker is unavailable")
+	bundler := NewBundler(source, filepath.Join(t.TempDir(), "missing.log"))
+
+	var bundle bytes.Buffer
+	require.NoError(t, bundler.Write(context.Background(), &bundle))
+	files := readBundle(t, &bundle)
+
+	assert.NotContains(t, files, "docker-info.json")
+	assert.NotContains(t, files, "logs/missing.log")
+	assert.Contains(t, string(files["errors.txt"]), "docker-info.json: docker is unavailable")
+	assert.Contains(t, string(files["errors.txt"]), "logs/missing.log")
+	assert.Contains(t, files, "state.json")
+}
+
+func TestBundlerWriteFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "diagnostics")
+	bundler := NewBundler(newTestSource(), "")
+	bundler.now = func() time.Time {
+		return time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+	}
+
+	path, err := bundler.WriteFile(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "ecs-agent-diagnostics-20210601T123000Z.tar.gz"), path)
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	info, err := file.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(bundleFileMode), info.Mode().Perm())
+	assert.Contains(t, readBundle(t, file), "state.json")
+}
+
+func TestTailFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "ecs-agent.log")
+	require.NoError(t, ioutil.WriteFile(logFile, []byte("first line\nsecond line\nthird line\n"), 0600))
+
+	// The partial line at the start of the tail is dropped
+	contents, err := tailFile(logFile, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "third line\n", string(contents))
+
+	contents, err = tailFile(logFile, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(contents), "line"))
+}
+
+func TestRedactState(t *testing.T) {
+	state, err := redactState([]byte(`[{
+		"Arn": "task1",
+		"executionCredentialsID": "creds-id",
+		"Containers": [{
+			"name": "app",
+			"environment": {"DB_PASSWORD": "hunter2"},
+			"overrides": {"command": ["--token", "secret"]},
+			"dockerConfig": {"config": "{\"Env\":[\"DB_PASSWORD=hunter2\"]}", "hostConfig": "{}"},
+			"registryAuthentication": {"type": "ecr"},
+			"secrets": [{"name": "API_KEY", "valueFrom": "arn:aws:ssm:us-west-2:123456789012:parameter/key"}]
+		}]
+	}]`))
+	require.NoError(t, err)
+
+	var tasks []map[string]interface{}
+	require.NoError(t, json.Unmarshal(state, &tasks))
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "task1", tasks[0]["Arn"])
+	assert.Equal(t, redactedValue, tasks[0]["executionCredentialsID"])
+	container := tasks[0]["Containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"DB_PASSWORD": redactedValue}, container["environment"])
+	assert.Equal(t, redactedValue, container["overrides"])
+	assert.Equal(t, map[string]interface{}{"config": redactedValue, "hostConfig": "{}"}, container["dockerConfig"])
+	assert.Equal(t, redactedValue, container["registryAuthentication"])
+	// The secrets are only references to their values
+	assert.Len(t, container["secrets"], 1)
+}
@@ -0,0 +1,90 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+var (
+	// procRoots are the proc file systems that are looked up for network
+	// namespaces, the one of the host being mounted at /host/proc when the
+	// agent runs in a container
+	procRoots = []string{"/host/proc", "/proc"}
+	// namedNetnsDir is the directory of the network namespaces created by
+	// 'ip netns'
+	namedNetnsDir = "/var/run/netns"
+)
+
+// networkNamespaces lists the named network namespaces and the network
+// namespaces of the processes of the instance, with the processes in each.
+func networkNamespaces() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("Named network namespaces:\n")
+	if entries, err := ioutil.ReadDir(namedNetnsDir); err != nil {
+		fmt.Fprintf(&buf, "  unable to read %s: %v\n", namedNetnsDir, err)
+	} else {
+		for _, entry := range entries {
+			fmt.Fprintf(&buf, "  %s\n", entry.Name())
+		}
+	}
+
+	procRoot := ""
+	for _, root := range procRoots {
+		if _, err := os.Stat(root); err == nil {
+			procRoot = root
+			break
+		}
+	}
+	if procRoot == "" {
+		return buf.Bytes(), nil
+	}
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+	pidsByNamespace := make(map[string][]int)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		namespace, err := os.Readlink(filepath.Join(procRoot, entry.Name(), "ns", "net"))
+		if err != nil {
+			// The process exited or can't be inspected
+			continue
+		}
+		pidsByNamespace[namespace] = append(pidsByNamespace[namespace], pid)
+	}
+	namespaces := make([]string, 0, len(pidsByNamespace))
+	for namespace := range pidsByNamespace {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	fmt.Fprintf(&buf, "Network namespaces of the processes in %s:\n", procRoot)
+	for _, namespace := range namespaces {
+		pids := pidsByNamespace[namespace]
+		sort.Ints(pids)
+		fmt.Fprintf(&buf, "  %s pids=%v\n", namespace, pids)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"encoding/json"
+)
+
+// redactedValue replaces the values removed from the state, matching the way
+// config.SensitiveRawMessage is printed.
+const redactedValue = "[redacted]"
+
+// redactedFields are the fields of the tasks and containers whose values are
+// removed from the state: the environment of the containers, their overrides,
+// their docker config, which embeds the environment, and the credentials of
+// the tasks.
+var redactedFields = map[string]bool{
+	"config":                 true,
+	"environment":            true,
+	"executionCredentialsID": true,
+	"overrides":              true,
+	"registryAuthentication": true,
+}
+
+// redactState removes the secrets from the JSON of the tasks. The names of the
+// environment variables are kept, as they often matter when diagnosing
+// containers, but not their values.
+func redactState(tasksJSON []byte) ([]byte, error) {
+	var state interface{}
+	if err := json.Unmarshal(tasksJSON, &state); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(redact(state), "", "  ")
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if !redactedFields[key] || field == nil {
+				v[key] = redact(field)
+				continue
+			}
+			if environment, ok := field.(map[string]interface{}); ok && key == "environment" {
+				for name := range environment {
+					environment[name] = redactedValue
+				}
+				continue
+			}
+			v[key] = redactedValue
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return value
+}
@@ -0,0 +1,408 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package dnscache answers the DNS queries of awsvpc tasks from a cache in their network
+// namespace, forwarding the queries it can't answer to the resolvers of the task at a
+// limited rate, so that chatty tasks stay below the packet rate the VPC resolver allows
+package dnscache
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// ListenAddress is the address the cache answers queries on in the network namespace
+	// of the task, which its containers are configured to use as their nameserver
+	ListenAddress = "127.0.0.1"
+	// port is the port of the cache and of the upstream resolvers
+	port = "53"
+	// DefaultMaxEntries is the number of responses a cache holds when the task doesn't
+	// set its size
+	DefaultMaxEntries = 4096
+	// maxTTL caps the time a response is cached for
+	maxTTL = time.Hour
+	// upstreamTimeout is how long the upstream resolvers have to answer a query
+	upstreamTimeout = 2 * time.Second
+	// streamIdleTimeout is how long the TCP connections of the task are kept open while
+	// no query is sent over them
+	streamIdleTimeout = 10 * time.Second
+	// maxMessageSize is the size of the largest DNS message
+	maxMessageSize = 65535
+)
+
+// Config is the configuration of the cache of a task
+type Config struct {
+	// Upstreams are the addresses of the resolvers the queries the cache can't answer
+	// are forwarded to, in order
+	Upstreams []string
+	// MaxQueriesPerSecond is the number of queries per second forwarded to the upstream
+	// resolvers, beyond which queries are answered with a server failure. Zero means
+	// unlimited
+	MaxQueriesPerSecond int
+	// MaxEntries is the number of responses the cache holds
+	MaxEntries int
+}
+
+// Stats are the counters of a cache
+type Stats struct {
+	// Hits is the number of queries answered from the cache
+	Hits uint64 `json:"hits"`
+	// Misses is the number of queries the cache couldn't answer
+	Misses uint64 `json:"misses"`
+	// Throttled is the number of misses not forwarded to the upstream resolvers, because
+	// the task exceeded its rate of queries
+	Throttled uint64 `json:"throttled"`
+	// UpstreamErrors is the number of misses none of the upstream resolvers answered
+	UpstreamErrors uint64 `json:"upstream_errors"`
+	// Entries is the number of responses in the cache
+	Entries int `json:"entries"`
+}
+
+// exchangeFunc sends a query to an upstream resolver over the network, udp or tcp, and
+// returns its response
+type exchangeFunc func(network, upstream string, query []byte) ([]byte, error)
+
+// entry is a response in the cache
+type entry struct {
+	response   []byte
+	ttlOffsets []int
+	storedAt   time.Time
+	expiresAt  time.Time
+}
+
+// Cache is the DNS cache of a task
+type Cache struct {
+	upstreams  []string
+	maxEntries int
+	limiter    *rate.Limiter
+	exchange   exchangeFunc
+	now        func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*entry
+	stats   Stats
+	closers []io.Closer
+}
+
+// newCache returns a cache forwarding the queries it can't answer with the exchange
+// function
+func newCache(cfg Config, exchange exchangeFunc) *Cache {
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if cfg.MaxQueriesPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(cfg.MaxQueriesPerSecond), cfg.MaxQueriesPerSecond)
+	}
+	maxEntries := cfg.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		upstreams:  cfg.Upstreams,
+		maxEntries: maxEntries,
+		limiter:    limiter,
+		exchange:   exchange,
+		now:        time.Now,
+		entries:    make(map[string]*entry),
+	}
+}
+
+// Stats returns the counters of the cache
+func (cache *Cache) Stats() Stats {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	stats := cache.stats
+	stats.Entries = len(cache.entries)
+	return stats
+}
+
+// Close stops answering queries
+func (cache *Cache) Close() error {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	var closeErr error
+	for _, closer := range cache.closers {
+		if err := closer.Close(); err != nil {
+			closeErr = err
+		}
+	}
+	cache.closers = nil
+	return closeErr
+}
+
+// serve answers the queries received on the packet connection and on the connections
+// accepted by the listener, until the cache is closed
+func (cache *Cache) serve(packetConn net.PacketConn, listener net.Listener) {
+	cache.lock.Lock()
+	cache.closers = append(cache.closers, packetConn, listener)
+	cache.lock.Unlock()
+
+	go cache.servePackets(packetConn)
+	go cache.serveStreams(listener)
+}
+
+// servePackets answers the queries received over udp
+func (cache *Cache) servePackets(conn net.PacketConn) {
+	buffer := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		query := make([]byte, n)
+		copy(query, buffer[:n])
+		go func() {
+			response := cache.resolve("udp", query)
+			if response == nil {
+				return
+			}
+			if _, err := conn.WriteTo(response, addr); err != nil {
+				seelog.Debugf("DNS cache: unable to answer query of %s: %v", addr, err)
+			}
+		}()
+	}
+}
+
+// serveStreams answers the queries received over the tcp connections accepted by the
+// listener
+func (cache *Cache) serveStreams(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go cache.serveStream(conn)
+	}
+}
+
+// serveStream answers the queries received over a tcp connection, until it is idle
+func (cache *Cache) serveStream(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(streamIdleTimeout))
+		query, err := readStreamMessage(conn)
+		if err != nil {
+			return
+		}
+		response := cache.resolve("tcp", query)
+		if response == nil {
+			return
+		}
+		if err := writeStreamMessage(conn, response); err != nil {
+			return
+		}
+	}
+}
+
+// resolve returns the response to a query, from the cache or from the upstream resolvers.
+// Malformed queries aren't answered.
+func (cache *Cache) resolve(network string, query []byte) []byte {
+	msg, err := parseQuery(query)
+	if err != nil {
+		seelog.Debugf("DNS cache: dropping malformed query: %v", err)
+		return nil
+	}
+	// responses to TCP queries aren't limited in size, so they can't be replayed to UDP
+	// clients
+	msg.key = network + "/" + msg.key
+	if response := cache.lookup(msg); response != nil {
+		return response
+	}
+
+	cache.lock.Lock()
+	cache.stats.Misses++
+	if !cache.limiter.AllowN(cache.now(), 1) {
+		cache.stats.Throttled++
+		cache.lock.Unlock()
+		return serverFailure(query, msg)
+	}
+	cache.lock.Unlock()
+
+	response, err := cache.forward(network, query)
+	if err != nil {
+		seelog.Debugf("DNS cache: unable to forward query: %v", err)
+		cache.lock.Lock()
+		cache.stats.UpstreamErrors++
+		cache.lock.Unlock()
+		return serverFailure(query, msg)
+	}
+	cache.store(msg.key, response)
+	return response
+}
+
+// lookup returns the cached response to a query, with the id of the query and its TTLs
+// lowered by the time it spent in the cache, or nil if it isn't cached
+func (cache *Cache) lookup(msg *message) []byte {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cached, ok := cache.entries[msg.key]
+	if !ok {
+		return nil
+	}
+	now := cache.now()
+	if !now.Before(cached.expiresAt) {
+		delete(cache.entries, msg.key)
+		return nil
+	}
+	cache.stats.Hits++
+
+	response := make([]byte, len(cached.response))
+	copy(response, cached.response)
+	binary.BigEndian.PutUint16(response[0:2], msg.id)
+	elapsed := uint32(now.Sub(cached.storedAt) / time.Second)
+	for _, offset := range cached.ttlOffsets {
+		ttl := binary.BigEndian.Uint32(response[offset : offset+4])
+		if ttl > elapsed {
+			ttl -= elapsed
+		} else {
+			ttl = 0
+		}
+		binary.BigEndian.PutUint32(response[offset:offset+4], ttl)
+	}
+	return response
+}
+
+// forward sends a query to the upstream resolvers in order, until one of them answers
+func (cache *Cache) forward(network string, query []byte) ([]byte, error) {
+	if len(cache.upstreams) == 0 {
+		return nil, errors.New("no upstream resolver")
+	}
+	var lastErr error
+	for _, upstream := range cache.upstreams {
+		response, err := cache.exchange(network, upstream, query)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = errors.Wrapf(err, "upstream resolver %s", upstream)
+	}
+	return nil, lastErr
+}
+
+// store caches a response for the lowest TTL of its records. Only complete answers and
+// non existent domains are cached, as long as they carry a record.
+func (cache *Cache) store(key string, response []byte) {
+	msg, err := parseResponse(response)
+	if err != nil || msg.truncated() || len(msg.ttlOffsets) == 0 || msg.minTTL == 0 {
+		return
+	}
+	if msg.rcode() != rcodeSuccess && msg.rcode() != rcodeNameError {
+		return
+	}
+	ttl := time.Duration(msg.minTTL) * time.Second
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	now := cache.now()
+	if _, ok := cache.entries[key]; !ok && len(cache.entries) >= cache.maxEntries {
+		cache.evictUnsafe(now)
+	}
+	cache.entries[key] = &entry{
+		response:   response,
+		ttlOffsets: msg.ttlOffsets,
+		storedAt:   now,
+		expiresAt:  now.Add(ttl),
+	}
+}
+
+// evictUnsafe makes room for a response, dropping the expired responses, or the one
+// closest to expire if none has. It must be called with the lock of the cache held.
+func (cache *Cache) evictUnsafe(now time.Time) {
+	var oldestKey string
+	var oldest *entry
+	for key, cached := range cache.entries {
+		if !now.Before(cached.expiresAt) {
+			delete(cache.entries, key)
+			continue
+		}
+		if oldest == nil || cached.expiresAt.Before(oldest.expiresAt) {
+			oldestKey, oldest = key, cached
+		}
+	}
+	if len(cache.entries) >= cache.maxEntries && oldest != nil {
+		delete(cache.entries, oldestKey)
+	}
+}
+
+// exchangeOver returns the exchange function sending queries over the connections opened
+// with dial
+func exchangeOver(dial func(network, address string) (net.Conn, error)) exchangeFunc {
+	return func(network, upstream string, query []byte) ([]byte, error) {
+		conn, err := dial(network, net.JoinHostPort(upstream, port))
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		if err := conn.SetDeadline(time.Now().Add(upstreamTimeout)); err != nil {
+			return nil, err
+		}
+		if network == "tcp" {
+			if err := writeStreamMessage(conn, query); err != nil {
+				return nil, err
+			}
+			return readStreamMessage(conn)
+		}
+
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buffer := make([]byte, maxMessageSize)
+		for {
+			n, err := conn.Read(buffer)
+			if err != nil {
+				return nil, err
+			}
+			// responses to other queries are ignored, until the deadline
+			if n >= headerLength && binary.BigEndian.Uint16(buffer[0:2]) == binary.BigEndian.Uint16(query[0:2]) {
+				response := make([]byte, n)
+				copy(response, buffer[:n])
+				return response, nil
+			}
+		}
+	}
+}
+
+// readStreamMessage reads a message from a tcp connection, where it's prefixed with its
+// length
+func readStreamMessage(conn io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeStreamMessage writes a message to a tcp connection, prefixed with its length
+func writeStreamMessage(conn io.Writer, msg []byte) error {
+	data := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(data[0:2], uint16(len(msg)))
+	copy(data[2:], msg)
+	_, err := conn.Write(data)
+	return err
+}
@@ -0,0 +1,98 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dnscache
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecscni"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netns"
+)
+
+// Start starts a cache answering the queries sent to ListenAddress, over udp and tcp, in
+// the network namespace of the process. The sockets of the cache, including the ones its
+// queries are forwarded over, are opened in that namespace, so that the queries reach the
+// upstream resolvers through the network interface of the task.
+func Start(pid string, cfg Config) (*Cache, error) {
+	nsPath := fmt.Sprintf(ecscni.NetnsFormat, pid)
+	address := net.JoinHostPort(ListenAddress, port)
+
+	var packetConn net.PacketConn
+	var listener net.Listener
+	err := inNetNS(nsPath, func() error {
+		var err error
+		packetConn, err = net.ListenPacket("udp", address)
+		if err != nil {
+			return err
+		}
+		listener, err = net.Listen("tcp", address)
+		if err != nil {
+			packetConn.Close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to listen on %s in network namespace %s", address, nsPath)
+	}
+
+	cache := newCache(cfg, exchangeOver(func(network, address string) (net.Conn, error) {
+		var conn net.Conn
+		err := inNetNS(nsPath, func() error {
+			var err error
+			conn, err = net.DialTimeout(network, address, upstreamTimeout)
+			return err
+		})
+		return conn, err
+	}))
+	cache.serve(packetConn, listener)
+	return cache, nil
+}
+
+// inNetNS runs a function in a network namespace. The sockets the function opens stay in
+// that namespace once the thread it ran on is switched back to its own namespace.
+func inNetNS(nsPath string, fn func() error) error {
+	runtime.LockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return errors.Wrap(err, "unable to get the current network namespace")
+	}
+	defer origin.Close()
+	target, err := netns.GetFromPath(nsPath)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return errors.Wrap(err, "unable to open the network namespace")
+	}
+	defer target.Close()
+
+	if err := netns.Set(target); err != nil {
+		runtime.UnlockOSThread()
+		return errors.Wrap(err, "unable to enter the network namespace")
+	}
+	fnErr := fn()
+	if err := netns.Set(origin); err != nil {
+		// the thread is left locked, so that it exits with the goroutine instead of
+		// running other goroutines in the namespace of the task
+		return errors.Wrap(err, "unable to leave the network namespace")
+	}
+	runtime.UnlockOSThread()
+	return fnErr
+}
@@ -0,0 +1,303 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dnscache
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const typeA = 1
+
+// buildQuery returns a query for the A records of a name
+func buildQuery(id uint16, name string) []byte {
+	query := make([]byte, headerLength)
+	binary.BigEndian.PutUint16(query[0:2], id)
+	binary.BigEndian.PutUint16(query[2:4], 1<<8)
+	binary.BigEndian.PutUint16(query[4:6], 1)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, 0, typeA, 0, 1)
+	return query
+}
+
+// buildEDNSQuery returns a query for the A records of a name, with an EDNS record
+// advertising a UDP payload size
+func buildEDNSQuery(id uint16, name string, size uint16) []byte {
+	query := buildQuery(id, name)
+	binary.BigEndian.PutUint16(query[10:12], 1)
+	opt := []byte{0, 0, typeOPT, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(opt[3:5], size)
+	return append(query, opt...)
+}
+
+// buildResponse returns the response to a query with a single A record
+func buildResponse(query []byte, rcode uint16, ttl uint32) []byte {
+	response := make([]byte, len(query))
+	copy(response, query)
+	binary.BigEndian.PutUint16(response[2:4], flagResponse|1<<8|flagRecursionAvailable|rcode)
+	binary.BigEndian.PutUint16(response[6:8], 1)
+	record := []byte{pointerMask, headerLength, 0, typeA, 0, 1, 0, 0, 0, 0, 0, 4, 10, 0, 0, 2}
+	binary.BigEndian.PutUint32(record[6:10], ttl)
+	return append(response, record...)
+}
+
+func TestParseQuery(t *testing.T) {
+	query := buildQuery(42, "Example.COM")
+	msg, err := parseQuery(query)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(42), msg.id)
+	assert.Equal(t, len(query), msg.questionEnd)
+
+	other, err := parseQuery(buildQuery(43, "example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.key, other.key, "the case of names is ignored")
+
+	edns, err := parseQuery(buildEDNSQuery(44, "example.com", 4096))
+	require.NoError(t, err)
+	assert.NotEqual(t, msg.key, edns.key, "the EDNS payload size is part of the key")
+	larger, err := parseQuery(buildEDNSQuery(45, "example.com", 1232))
+	require.NoError(t, err)
+	assert.NotEqual(t, edns.key, larger.key, "the EDNS payload size is part of the key")
+
+	_, err = parseQuery(query[:headerLength-1])
+	assert.Error(t, err)
+	_, err = parseQuery(query[:len(query)-2])
+	assert.Error(t, err)
+}
+
+func TestParseResponse(t *testing.T) {
+	query := buildQuery(42, "example.com")
+	response := buildResponse(query, rcodeSuccess, 300)
+	msg, err := parseResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, rcodeSuccess, msg.rcode())
+	assert.False(t, msg.truncated())
+	assert.Equal(t, uint32(300), msg.minTTL)
+	assert.Equal(t, []int{len(query) + 6}, msg.ttlOffsets)
+
+	_, err = parseResponse(response[:len(response)-1])
+	assert.Error(t, err)
+}
+
+func TestServerFailure(t *testing.T) {
+	query := buildQuery(42, "example.com")
+	msg, err := parseQuery(query)
+	require.NoError(t, err)
+
+	failure, err := parseResponse(serverFailure(query, msg))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(42), failure.id)
+	assert.Equal(t, rcodeServerFailure, failure.rcode())
+	assert.Equal(t, msg.key, failure.key)
+	assert.Empty(t, failure.ttlOffsets)
+}
+
+// upstream answers the queries the cache forwards
+type upstream struct {
+	ttl     uint32
+	rcode   uint16
+	err     error
+	queries int
+}
+
+func (up *upstream) exchange(network, server string, query []byte) ([]byte, error) {
+	up.queries++
+	if up.err != nil {
+		return nil, up.err
+	}
+	return buildResponse(query, up.rcode, up.ttl), nil
+}
+
+func newTestCache(cfg Config, up *upstream) (*Cache, *time.Time) {
+	now := time.Now()
+	cfg.Upstreams = []string{"10.0.0.2"}
+	cache := newCache(cfg, up.exchange)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestResolveFromCache(t *testing.T) {
+	up := &upstream{ttl: 60}
+	cache, now := newTestCache(Config{}, up)
+
+	first := cache.resolve("udp", buildQuery(1, "example.com"))
+	require.NotNil(t, first)
+	*now = now.Add(20 * time.Second)
+	second := cache.resolve("udp", buildQuery(2, "EXAMPLE.com"))
+	require.NotNil(t, second)
+	assert.Equal(t, 1, up.queries)
+
+	msg, err := parseResponse(second)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), msg.id, "the id of the query is set on cached responses")
+	assert.Equal(t, uint32(40), msg.minTTL, "the TTLs are lowered by the time spent in the cache")
+	assert.Equal(t, Stats{Hits: 1, Misses: 1, Entries: 1}, cache.Stats())
+
+	*now = now.Add(40 * time.Second)
+	cache.resolve("udp", buildQuery(3, "example.com"))
+	assert.Equal(t, 2, up.queries, "expired responses are resolved again")
+}
+
+func TestResolveCachedPerTransport(t *testing.T) {
+	up := &upstream{ttl: 60}
+	cache, _ := newTestCache(Config{}, up)
+
+	require.NotNil(t, cache.resolve("tcp", buildQuery(1, "example.com")))
+	require.NotNil(t, cache.resolve("udp", buildQuery(2, "example.com")))
+	assert.Equal(t, 2, up.queries, "responses to TCP queries aren't served to UDP clients")
+	require.NotNil(t, cache.resolve("udp", buildEDNSQuery(3, "example.com", 4096)))
+	assert.Equal(t, 3, up.queries, "responses are cached per EDNS payload size")
+
+	require.NotNil(t, cache.resolve("udp", buildQuery(4, "example.com")))
+	assert.Equal(t, 3, up.queries)
+	assert.Equal(t, Stats{Hits: 1, Misses: 3, Entries: 3}, cache.Stats())
+}
+
+func TestResolveNotCached(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rcode uint16
+		ttl   uint32
+	}{
+		{name: "server failure", rcode: rcodeServerFailure, ttl: 60},
+		{name: "zero ttl", rcode: rcodeSuccess, ttl: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			up := &upstream{ttl: tc.ttl, rcode: tc.rcode}
+			cache, _ := newTestCache(Config{}, up)
+			cache.resolve("udp", buildQuery(1, "example.com"))
+			cache.resolve("udp", buildQuery(2, "example.com"))
+			assert.Equal(t, 2, up.queries)
+			assert.Equal(t, 0, cache.Stats().Entries)
+		})
+	}
+}
+
+func TestResolveThrottled(t *testing.T) {
+	up := &upstream{ttl: 60}
+	cache, now := newTestCache(Config{MaxQueriesPerSecond: 2}, up)
+
+	for i, name := range []string{"hosta.example.com", "hostb.example.com", "hostc.example.com"} {
+		cache.resolve("udp", buildQuery(uint16(i), name))
+	}
+	assert.Equal(t, 2, up.queries)
+	stats := cache.Stats()
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Throttled)
+
+	response := cache.resolve("udp", buildQuery(3, "hosta.example.com"))
+	msg, err := parseResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, rcodeSuccess, msg.rcode(), "cached responses aren't throttled")
+
+	*now = now.Add(time.Second)
+	cache.resolve("udp", buildQuery(4, "hostc.example.com"))
+	assert.Equal(t, 3, up.queries)
+}
+
+func TestResolveUpstreamError(t *testing.T) {
+	up := &upstream{err: errors.New("timeout")}
+	cache, _ := newTestCache(Config{}, up)
+
+	response := cache.resolve("udp", buildQuery(1, "example.com"))
+	msg, err := parseResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, rcodeServerFailure, msg.rcode())
+	assert.Equal(t, uint64(1), cache.Stats().UpstreamErrors)
+
+	assert.Nil(t, cache.resolve("udp", []byte{1, 2, 3}), "malformed queries aren't answered")
+}
+
+func TestStoreEvicts(t *testing.T) {
+	up := &upstream{ttl: 60}
+	cache, now := newTestCache(Config{MaxEntries: 2}, up)
+
+	cache.resolve("udp", buildQuery(1, "a.example.com"))
+	*now = now.Add(time.Second)
+	cache.resolve("udp", buildQuery(2, "b.example.com"))
+	cache.resolve("udp", buildQuery(3, "c.example.com"))
+	assert.Equal(t, 2, cache.Stats().Entries)
+
+	cache.resolve("udp", buildQuery(4, "a.example.com"))
+	assert.Equal(t, 4, up.queries, "the response closest to expire is evicted")
+}
+
+func TestServe(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+	go func() {
+		buffer := make([]byte, maxMessageSize)
+		for {
+			n, addr, err := server.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			server.WriteTo(buildResponse(buffer[:n], rcodeSuccess, 60), addr)
+		}
+	}()
+	serverAddr := server.LocalAddr().(*net.UDPAddr)
+
+	// the upstream only answers over UDP
+	cache := newCache(Config{Upstreams: []string{"127.0.0.1"}}, func(network, upstream string, query []byte) ([]byte, error) {
+		return exchangeOver(func(network, address string) (net.Conn, error) {
+			return net.Dial(network, serverAddr.String())
+		})("udp", upstream, query)
+	})
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	cache.serve(packetConn, listener)
+	defer cache.Close()
+
+	conn, err := net.Dial("udp", packetConn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write(buildQuery(7, "example.com"))
+	require.NoError(t, err)
+	buffer := make([]byte, maxMessageSize)
+	n, err := conn.Read(buffer)
+	require.NoError(t, err)
+	msg, err := parseResponse(buffer[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint16(7), msg.id)
+
+	stream, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(5 * time.Second))
+	require.NoError(t, writeStreamMessage(stream, buildQuery(8, "example.com")))
+	response, err := readStreamMessage(stream)
+	require.NoError(t, err)
+	msg, err = parseResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(8), msg.id)
+	assert.Equal(t, rcodeSuccess, msg.rcode())
+	assert.Equal(t, Stats{Misses: 2, Entries: 2}, cache.Stats(), "responses are cached per transport")
+}
@@ -0,0 +1,209 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dnscache
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// headerLength is the length of the header of DNS messages
+	headerLength = 12
+	// flagResponse, flagTruncated and flagRecursionAvailable are the bits of the header
+	// flags the cache reads or sets
+	flagResponse           = 1 << 15
+	flagTruncated          = 1 << 9
+	flagRecursionAvailable = 1 << 7
+	// rcodeMask masks the response code out of the header flags
+	rcodeMask = 0xf
+	// rcodeSuccess, rcodeNameError and rcodeServerFailure are the response codes of
+	// answers, non existent domains and failures
+	rcodeSuccess       = 0
+	rcodeNameError     = 3
+	rcodeServerFailure = 2
+	// typeOPT is the type of the EDNS pseudo record, whose TTL field isn't a TTL
+	typeOPT = 41
+	// pointerMask marks the compression pointers in domain names
+	pointerMask = 0xc0
+)
+
+// message is the part of a DNS message the cache needs to answer queries
+type message struct {
+	id    uint16
+	flags uint16
+	// key identifies the question of the message, with the case of its name ignored, and
+	// the UDP payload size of its EDNS record, which lets responses exceed 512 bytes
+	key string
+	// questionEnd is the offset of the end of the question section
+	questionEnd int
+	// ttlOffsets are the offsets of the TTLs of the records of the message
+	ttlOffsets []int
+	// minTTL is the lowest TTL of the records of the message
+	minTTL uint32
+}
+
+// rcode returns the response code of the message
+func (msg *message) rcode() int {
+	return int(msg.flags & rcodeMask)
+}
+
+// truncated returns true if the response didn't fit in the message
+func (msg *message) truncated() bool {
+	return msg.flags&flagTruncated != 0
+}
+
+// parseQuery parses the header and the question of a query. Only queries with a single
+// question can be cached, so they are the only ones accepted.
+func parseQuery(data []byte) (*message, error) {
+	if len(data) < headerLength {
+		return nil, errors.New("dns message shorter than its header")
+	}
+	msg := &message{
+		id:    binary.BigEndian.Uint16(data[0:2]),
+		flags: binary.BigEndian.Uint16(data[2:4]),
+	}
+	if questions := binary.BigEndian.Uint16(data[4:6]); questions != 1 {
+		return nil, errors.Errorf("dns message with %d questions", questions)
+	}
+	name, offset, err := readName(data, headerLength)
+	if err != nil {
+		return nil, err
+	}
+	if offset+4 > len(data) {
+		return nil, errors.New("dns question truncated")
+	}
+	msg.questionEnd = offset + 4
+	ednsSize := make([]byte, 2)
+	binary.BigEndian.PutUint16(ednsSize, ednsPayloadSize(data, msg.questionEnd))
+	msg.key = strings.ToLower(name) + string(data[offset:msg.questionEnd]) + string(ednsSize)
+	return msg, nil
+}
+
+// ednsPayloadSize returns the UDP payload size advertised by the EDNS record of a message,
+// which is carried in its class field, or 0 if the message has none
+func ednsPayloadSize(data []byte, offset int) uint16 {
+	records := int(binary.BigEndian.Uint16(data[6:8])) + int(binary.BigEndian.Uint16(data[8:10])) +
+		int(binary.BigEndian.Uint16(data[10:12]))
+	for i := 0; i < records; i++ {
+		var err error
+		offset, err = skipName(data, offset)
+		if err != nil || offset+10 > len(data) {
+			return 0
+		}
+		if binary.BigEndian.Uint16(data[offset:offset+2]) == typeOPT {
+			return binary.BigEndian.Uint16(data[offset+2 : offset+4])
+		}
+		offset += 10 + int(binary.BigEndian.Uint16(data[offset+8:offset+10]))
+	}
+	return 0
+}
+
+// parseResponse parses a response, locating the TTLs of its records
+func parseResponse(data []byte) (*message, error) {
+	msg, err := parseQuery(data)
+	if err != nil {
+		return nil, err
+	}
+	records := int(binary.BigEndian.Uint16(data[6:8])) + int(binary.BigEndian.Uint16(data[8:10])) +
+		int(binary.BigEndian.Uint16(data[10:12]))
+	offset := msg.questionEnd
+	for i := 0; i < records; i++ {
+		offset, err = skipName(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		// the name is followed by the type, the class, the ttl and the length of the data
+		if offset+10 > len(data) {
+			return nil, errors.New("dns record truncated")
+		}
+		recordType := binary.BigEndian.Uint16(data[offset : offset+2])
+		dataLength := int(binary.BigEndian.Uint16(data[offset+8 : offset+10]))
+		if recordType != typeOPT {
+			ttl := binary.BigEndian.Uint32(data[offset+4 : offset+8])
+			if len(msg.ttlOffsets) == 0 || ttl < msg.minTTL {
+				msg.minTTL = ttl
+			}
+			msg.ttlOffsets = append(msg.ttlOffsets, offset+4)
+		}
+		offset += 10 + dataLength
+		if offset > len(data) {
+			return nil, errors.New("dns record data truncated")
+		}
+	}
+	return msg, nil
+}
+
+// readName reads an uncompressed domain name, returning it in its dotted form along with
+// the offset following it
+func readName(data []byte, offset int) (string, int, error) {
+	var labels []string
+	for {
+		if offset >= len(data) {
+			return "", 0, errors.New("dns name truncated")
+		}
+		length := int(data[offset])
+		offset++
+		if length == 0 {
+			return strings.Join(labels, ".") + ".", offset, nil
+		}
+		if length&pointerMask != 0 {
+			return "", 0, errors.New("compressed dns name in question")
+		}
+		if offset+length > len(data) {
+			return "", 0, errors.New("dns label truncated")
+		}
+		labels = append(labels, string(data[offset:offset+length]))
+		offset += length
+	}
+}
+
+// skipName returns the offset following a domain name, which may end with a compression
+// pointer
+func skipName(data []byte, offset int) (int, error) {
+	for {
+		if offset >= len(data) {
+			return 0, errors.New("dns name truncated")
+		}
+		length := int(data[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&pointerMask == pointerMask:
+			if offset+2 > len(data) {
+				return 0, errors.New("dns name pointer truncated")
+			}
+			return offset + 2, nil
+		case length&pointerMask != 0:
+			return 0, errors.Errorf("unsupported dns label type %#x", length&pointerMask)
+		}
+		offset += 1 + length
+	}
+}
+
+// serverFailure returns the failure response to a query, which carries its question
+// without any record
+func serverFailure(query []byte, msg *message) []byte {
+	response := make([]byte, msg.questionEnd)
+	copy(response, query[:msg.questionEnd])
+	flags := msg.flags | flagResponse | flagRecursionAvailable
+	flags = flags&^rcodeMask | rcodeServerFailure
+	binary.BigEndian.PutUint16(response[2:4], flags)
+	binary.BigEndian.PutUint16(response[6:8], 0)
+	binary.BigEndian.PutUint16(response[8:10], 0)
+	binary.BigEndian.PutUint16(response[10:12], 0)
+	return response
+}
@@ -0,0 +1,93 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerapi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+const (
+	// nanoCPUsCPUPeriod is the cpu period, in microseconds, docker enforces the cpu
+	// limit of containers created with nano cpus with
+	nanoCPUsCPUPeriod = 100000
+	// unifiedCgroupPrefix prefixes the cgroup of a process in the unified (v2) hierarchy
+	// in /proc/<pid>/cgroup
+	unifiedCgroupPrefix = "0::"
+)
+
+// hostProcFSPath is where the proc filesystem of the host is mounted in the agent container
+var hostProcFSPath = "/host/proc"
+
+// effectiveResourcesFromHostConfig returns the cpu and memory limits docker runs a
+// container with, and the path of its cgroup in the unified hierarchy when the container
+// is running. The path is empty on instances that don't use cgroup v2
+func effectiveResourcesFromHostConfig(hostConfig *dockercontainer.HostConfig, pid int) *apicontainer.EffectiveResources {
+	resources := &apicontainer.EffectiveResources{
+		CPUShares:         hostConfig.CPUShares,
+		CPUQuota:          hostConfig.CPUQuota,
+		CPUPeriod:         hostConfig.CPUPeriod,
+		CpusetCpus:        hostConfig.CpusetCpus,
+		Memory:            hostConfig.Memory,
+		MemoryReservation: hostConfig.MemoryReservation,
+		MemorySwap:        hostConfig.MemorySwap,
+		CgroupParent:      hostConfig.CgroupParent,
+	}
+	// docker converts nano cpus into a cpu quota, so report it as such
+	if hostConfig.NanoCPUs > 0 && resources.CPUQuota == 0 {
+		resources.CPUPeriod = nanoCPUsCPUPeriod
+		resources.CPUQuota = hostConfig.NanoCPUs * nanoCPUsCPUPeriod / 1e9
+	}
+	if pid > 0 {
+		resources.CgroupPath = unifiedCgroupPath(pid)
+	}
+	return resources
+}
+
+// unifiedCgroupPath returns the path of the cgroup of a process in the unified (v2)
+// hierarchy, or an empty string if it isn't in one
+func unifiedCgroupPath(pid int) string {
+	data, err := ioutil.ReadFile(filepath.Join(hostProcFSPath, fmt.Sprint(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	return parseUnifiedCgroupPath(data)
+}
+
+// parseUnifiedCgroupPath returns the path of the unified hierarchy in the content of a
+// /proc/<pid>/cgroup file. Hosts using cgroup v1 only list the v1 hierarchies, and hosts
+// using both list the unified one along with them, so the path is only returned when
+// it's the only hierarchy
+func parseUnifiedCgroupPath(data []byte) string {
+	var path string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, unifiedCgroupPrefix) || path != "" {
+			return ""
+		}
+		path = strings.TrimPrefix(line, unifiedCgroupPrefix)
+	}
+	return path
+}
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -38,6 +40,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/sdkclient"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/sdkclientfactory"
 	"github.com/aws/amazon-ecs-agent/agent/ecr"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
 	"github.com/aws/amazon-ecs-agent/agent/metrics"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
@@ -72,18 +75,14 @@ const (
 	tokenCacheSize = 100
 	// tokenCacheTTL is the default ttl of the docker auth for ECR
 	tokenCacheTTL = 12 * time.Hour
+	// ecrTokenCacheKeyFile is the name of the file in the data directory that holds
+	// the key used to encrypt the persisted ECR auth tokens
+	ecrTokenCacheKeyFile = "ecr_token_cache.key"
 
 	// pullStatusSuppressDelay controls the time where pull status progress bar
 	// output will be suppressed in debug mode
 	pullStatusSuppressDelay = 2 * time.Second
 
-	// retry settings for pulling images
-	maximumPullRetries        = 5
-	minimumPullRetryDelay     = 1100 * time.Millisecond
-	maximumPullRetryDelay     = 5 * time.Second
-	pullRetryDelayMultiplier  = 2
-	pullRetryJitterMultiplier = 0.2
-
 	// pollStatsTimeout is the timeout for polling Docker Stats API;
 	// keeping it same as streaming stats inactivity timeout
 	pollStatsTimeout = 18 * time.Second
@@ -117,6 +116,10 @@ type DockerClient interface {
 	// PullImage pulls an image. authData should contain authentication data provided by the ECS backend.
 	PullImage(context.Context, string, *apicontainer.RegistryAuthenticationData, time.Duration) DockerContainerMetadata
 
+	// ForgetPullCredentials stops using the given task credentials to refresh the ECR auth tokens that were
+	// fetched with them. It should be called once the task that the credentials belong to has stopped.
+	ForgetPullCredentials(credentialsID string)
+
 	// CreateContainer creates a container with the provided Config, HostConfig, and name. A timeout value
 	// and a context should be provided for the request.
 	CreateContainer(context.Context, *dockercontainer.Config, *dockercontainer.HostConfig, string, time.Duration) DockerContainerMetadata
@@ -125,10 +128,32 @@ type DockerClient interface {
 	// provided for the request.
 	StartContainer(context.Context, string, time.Duration) DockerContainerMetadata
 
+	// RestoreContainer starts the container identified by the name provided from one of its
+	// checkpoints, identified by its ID and the directory it was created in. A timeout value and
+	// a context should be provided for the request.
+	RestoreContainer(context.Context, string, string, string, time.Duration) DockerContainerMetadata
+
+	// CheckpointContainer creates a checkpoint of the running container identified by the name
+	// provided, with the given ID, in the given directory. The container keeps running. A timeout
+	// value and a context should be provided for the request.
+	CheckpointContainer(context.Context, string, string, string, time.Duration) error
+
+	// RemoveCheckpoint removes a checkpoint of the container identified by the name provided. A
+	// timeout value and a context should be provided for the request.
+	RemoveCheckpoint(context.Context, string, string, string, time.Duration) error
+
 	// StopContainer stops the container identified by the name provided. A timeout value and a context should be provided
 	// for the request.
 	StopContainer(context.Context, string, time.Duration) DockerContainerMetadata
 
+	// KillContainer sends the signal to the main process of the container identified by the name provided.
+	// A timeout value and a context should be provided for the request.
+	KillContainer(context.Context, string, string, time.Duration) error
+
+	// ContainerLogs returns the stream of the logs of the container identified by the name provided, read
+	// from the log driver of the container. The stream ends when the context is done.
+	ContainerLogs(context.Context, string, types.ContainerLogsOptions) (io.ReadCloser, error)
+
 	// DescribeContainer returns status information about the specified container. A context should be provided
 	// for the request
 	DescribeContainer(context.Context, string) (apicontainerstatus.ContainerStatus, DockerContainerMetadata)
@@ -141,6 +166,11 @@ type DockerClient interface {
 	// provided for the request.
 	InspectContainer(context.Context, string, time.Duration) (*types.ContainerJSON, error)
 
+	// InspectContainerSize returns information about the specified container, along with the size of its
+	// writable layer and root filesystem. Computing the sizes can be expensive, so this should only be used
+	// when the sizes are needed. A timeout value and a context should be provided for the request.
+	InspectContainerSize(context.Context, string, time.Duration) (*types.ContainerJSON, error)
+
 	// TopContainer returns information about the top processes running in the specified container.  A timeout value and a context
 	// should be provided for the request. The last argument is an optional parameter for passing in 'ps' arguments
 	// as part of the top command.
@@ -190,6 +220,10 @@ type DockerClient interface {
 	// be canceled.
 	Stats(context.Context, string, time.Duration) (<-chan *types.StatsJSON, <-chan error)
 
+	// StatsSnapshot returns a single, freshly collected, stat data sample for the specified container. A timeout
+	// value and a context should be provided for the request.
+	StatsSnapshot(context.Context, string, time.Duration) (*types.StatsJSON, error)
+
 	// Version returns the version of the Docker daemon.
 	Version(context.Context, time.Duration) (string, error)
 
@@ -208,6 +242,14 @@ type DockerClient interface {
 
 	// Info returns the information of the Docker server.
 	Info(context.Context, time.Duration) (types.Info, error)
+
+	// LazyLoadsImage returns true if the Docker daemon lazily loads the image, because it uses the SOCI
+	// snapshotter and the image has a SOCI index. authData should contain authentication data provided by the ECS backend.
+	LazyLoadsImage(context.Context, string, *apicontainer.RegistryAuthenticationData) bool
+
+	// PersistECRTokenCache loads the ECR auth tokens persisted in the store into the token cache,
+	// and persists the tokens fetched from then on, so that they survive agent restarts.
+	PersistECRTokenCache(dockerauth.ECRTokenStore) error
 }
 
 // DockerGoClient wraps the underlying go-dockerclient and docker/docker library.
@@ -233,17 +275,22 @@ type dockerGoClient struct {
 	version                  dockerclient.DockerVersion
 	ecrClientFactory         ecr.ECRFactory
 	auth                     dockerauth.DockerAuthProvider
-	ecrTokenCache            async.Cache
+	ecrTokenCache            *dockerauth.PersistentTokenCache
+	ecrTokenRefresher        *dockerauth.ECRTokenRefresher
+	ecrPublicClientFactory   ecr.ECRPublicFactory
 	config                   *config.Config
 	context                  context.Context
-	imagePullBackoff         retry.Backoff
+	pullRetryPolicy          *pullRetryPolicy
 	inactivityTimeoutHandler inactivityTimeoutHandlerFunc
+	// registryClient sends requests to the registry API, to find the SOCI index of images
+	registryClient *http.Client
 
 	_time     ttime.Time
 	_timeOnce sync.Once
 
-	daemonVersionUnsafe string
-	lock                sync.Mutex
+	daemonVersionUnsafe   string
+	sociSnapshotterUnsafe *bool
+	lock                  sync.Mutex
 }
 
 type ImagePullResponse struct {
@@ -264,6 +311,7 @@ func (dg *dockerGoClient) WithVersion(version dockerclient.DockerVersion) Docker
 		auth:             dg.auth,
 		config:           dg.config,
 		context:          dg.context,
+		pullRetryPolicy:  dg.pullRetryPolicy,
 	}
 }
 
@@ -293,19 +341,40 @@ func NewDockerGoClient(sdkclientFactory sdkclientfactory.Factory,
 	if cfg.EngineAuthData != nil {
 		dockerAuthData = cfg.EngineAuthData.Contents()
 	}
+	auth := dockerauth.NewDockerAuthProvider(cfg.EngineAuthType, dockerAuthData)
+	if len(cfg.DockerCredentialHelpers) > 0 {
+		auth = dockerauth.NewCredentialHelperAuthProvider(cfg.DockerCredentialHelpers, auth)
+	}
+
+	ecrClientFactory := ecr.NewECRFactory(cfg.AcceptInsecureCert)
+	ecrTokenCache := dockerauth.NewPersistentTokenCache(async.NewLRUCache(tokenCacheSize, tokenCacheTTL))
+	ecrTokenRefresher := dockerauth.NewECRTokenRefresher(ecrClientFactory, ecrTokenCache, cfg.ECRTokenRefreshWindow)
+	go ecrTokenRefresher.Start(ctx)
+
 	return &dockerGoClient{
-		sdkClientFactory: sdkclientFactory,
-		auth:             dockerauth.NewDockerAuthProvider(cfg.EngineAuthType, dockerAuthData),
-		ecrClientFactory: ecr.NewECRFactory(cfg.AcceptInsecureCert),
-		ecrTokenCache:    async.NewLRUCache(tokenCacheSize, tokenCacheTTL),
-		config:           cfg,
-		context:          ctx,
-		imagePullBackoff: retry.NewExponentialBackoff(minimumPullRetryDelay, maximumPullRetryDelay,
-			pullRetryJitterMultiplier, pullRetryDelayMultiplier),
+		sdkClientFactory:         sdkclientFactory,
+		auth:                     auth,
+		ecrClientFactory:         ecrClientFactory,
+		ecrTokenCache:            ecrTokenCache,
+		ecrTokenRefresher:        ecrTokenRefresher,
+		ecrPublicClientFactory:   ecr.NewECRPublicFactory(cfg.AcceptInsecureCert),
+		config:                   cfg,
+		context:                  ctx,
+		pullRetryPolicy:          newPullRetryPolicy(cfg),
 		inactivityTimeoutHandler: handleInactivityTimeout,
+		registryClient:           httpclient.New(sociIndexLookupTimeout, cfg.AcceptInsecureCert),
 	}, nil
 }
 
+// PersistECRTokenCache loads the ECR auth tokens persisted in the store into the token cache,
+// and persists the tokens fetched from then on
+func (dg *dockerGoClient) PersistECRTokenCache(store dockerauth.ECRTokenStore) error {
+	if dg.ecrTokenCache == nil {
+		return errors.New("DockerGoClient: no ECR token cache to persist")
+	}
+	return dg.ecrTokenCache.Persist(store, filepath.Join(dg.config.DataDir, ecrTokenCacheKeyFile))
+}
+
 // Returns the Docker SDK Client
 func (dg *dockerGoClient) sdkDockerClient() (sdkclient.Client, error) {
 	if dg.version == "" {
@@ -330,14 +399,7 @@ func (dg *dockerGoClient) PullImage(ctx context.Context, image string,
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("PULL_IMAGE")()
 	response := make(chan DockerContainerMetadata, 1)
 	go func() {
-		err := retry.RetryNWithBackoffCtx(ctx, dg.imagePullBackoff, maximumPullRetries,
-			func() error {
-				err := dg.pullImage(ctx, image, authData)
-				if err != nil {
-					seelog.Errorf("DockerGoClient: failed to pull image %s: [%s] %s", image, err.ErrorName(), err.Error())
-				}
-				return err
-			})
+		err := dg.pullImageWithRetries(ctx, image, authData)
 		response <- DockerContainerMetadata{Error: wrapPullErrorAsNamedError(err)}
 	}()
 
@@ -357,6 +419,29 @@ func (dg *dockerGoClient) PullImage(ctx context.Context, image string,
 	}
 }
 
+// pullImageWithRetries pulls the image, retrying failed pulls as configured by the
+// pull retry policy
+func (dg *dockerGoClient) pullImageWithRetries(ctx context.Context, image string,
+	authData *apicontainer.RegistryAuthenticationData) error {
+	backoff := dg.pullRetryPolicy.newBackoff()
+	for {
+		err := dg.pullImage(ctx, image, authData)
+		if err == nil {
+			return nil
+		}
+		seelog.Errorf("DockerGoClient: failed to pull image %s: [%s] %s", image, err.ErrorName(), err.Error())
+		delay, ok := backoff.next(err)
+		if !ok {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
 func wrapPullErrorAsNamedError(err error) apierrors.NamedError {
 	var retErr apierrors.NamedError
 	if err != nil {
@@ -508,15 +593,25 @@ func (dg *dockerGoClient) InspectImage(image string) (*types.ImageInspect, error
 	return &imageData, err
 }
 
+// ForgetPullCredentials stops refreshing the ECR auth tokens fetched with the given task credentials
+func (dg *dockerGoClient) ForgetPullCredentials(credentialsID string) {
+	if dg.ecrTokenRefresher != nil {
+		dg.ecrTokenRefresher.ForgetCredentials(credentialsID)
+	}
+}
+
 func (dg *dockerGoClient) getAuthdata(image string, authData *apicontainer.RegistryAuthenticationData) (types.AuthConfig, error) {
 
 	if authData == nil {
+		if dg.config.ECRPublicAuthEnabled.Enabled() && dockerauth.IsECRPublicImage(image) {
+			return dg.getECRPublicAuthdata(image)
+		}
 		return dg.auth.GetAuthconfig(image, nil)
 	}
 
 	switch authData.Type {
 	case apicontainer.AuthTypeECR:
-		provider := dockerauth.NewECRAuthProvider(dg.ecrClientFactory, dg.ecrTokenCache)
+		provider := dockerauth.NewECRAuthProviderWithRefresher(dg.ecrClientFactory, dg.ecrTokenCache, dg.ecrTokenRefresher)
 		authConfig, err := provider.GetAuthconfig(image, authData)
 		if err != nil {
 			return authConfig, CannotPullECRContainerError{err}
@@ -531,6 +626,24 @@ func (dg *dockerGoClient) getAuthdata(image string, authData *apicontainer.Regis
 	}
 }
 
+// getECRPublicAuthdata returns the auth for pulling from the ECR Public Gallery.
+// Auth configured through ECS_ENGINE_AUTH_DATA takes precedence. Public images
+// can always be pulled anonymously, so failing to get a token is not an error
+func (dg *dockerGoClient) getECRPublicAuthdata(image string) (types.AuthConfig, error) {
+	authConfig, err := dg.auth.GetAuthconfig(image, nil)
+	if err != nil || authConfig != (types.AuthConfig{}) || dg.ecrPublicClientFactory == nil {
+		return authConfig, err
+	}
+
+	provider := dockerauth.NewECRPublicAuthProvider(dg.ecrPublicClientFactory, dg.ecrTokenCache)
+	authConfig, err = provider.GetAuthconfig(image, nil)
+	if err != nil {
+		seelog.Warnf("DockerGoClient: unable to get ECR Public auth token, pulling %s anonymously: %v", image, err)
+		return types.AuthConfig{}, nil
+	}
+	return authConfig, nil
+}
+
 func (dg *dockerGoClient) CreateContainer(ctx context.Context,
 	config *dockercontainer.Config,
 	hostConfig *dockercontainer.HostConfig,
@@ -586,7 +699,7 @@ func (dg *dockerGoClient) StartContainer(ctx context.Context, id string, timeout
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan DockerContainerMetadata, 1)
-	go func() { response <- dg.startContainer(ctx, id) }()
+	go func() { response <- dg.startContainer(ctx, id, types.ContainerStartOptions{}) }()
 	select {
 	case resp := <-response:
 		return resp
@@ -601,13 +714,39 @@ func (dg *dockerGoClient) StartContainer(ctx context.Context, id string, timeout
 	}
 }
 
-func (dg *dockerGoClient) startContainer(ctx context.Context, id string) DockerContainerMetadata {
+func (dg *dockerGoClient) RestoreContainer(ctx context.Context, id string, checkpointID string, checkpointDir string,
+	timeout time.Duration) DockerContainerMetadata {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("RESTORE_CONTAINER")()
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan DockerContainerMetadata, 1)
+	go func() {
+		response <- dg.startContainer(ctx, id, types.ContainerStartOptions{
+			CheckpointID:  checkpointID,
+			CheckpointDir: checkpointDir,
+		})
+	}()
+	select {
+	case resp := <-response:
+		return resp
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			return DockerContainerMetadata{Error: &DockerTimeoutError{timeout, "restored"}}
+		}
+		return DockerContainerMetadata{Error: CannotStartContainerError{err}}
+	}
+}
+
+func (dg *dockerGoClient) startContainer(ctx context.Context, id string, options types.ContainerStartOptions) DockerContainerMetadata {
 	client, err := dg.sdkDockerClient()
 	if err != nil {
 		return DockerContainerMetadata{Error: CannotGetDockerClientError{version: dg.version, err: err}}
 	}
 
-	err = client.ContainerStart(ctx, id, types.ContainerStartOptions{})
+	err = client.ContainerStart(ctx, id, options)
 	metadata := dg.containerMetadata(ctx, id)
 	if err != nil {
 		metadata.Error = CannotStartContainerError{err}
@@ -644,6 +783,15 @@ func (dg *dockerGoClient) DescribeContainer(ctx context.Context, dockerID string
 }
 
 func (dg *dockerGoClient) InspectContainer(ctx context.Context, dockerID string, timeout time.Duration) (*types.ContainerJSON, error) {
+	return dg.inspectContainerWithTimeout(ctx, dockerID, timeout, false)
+}
+
+func (dg *dockerGoClient) InspectContainerSize(ctx context.Context, dockerID string, timeout time.Duration) (*types.ContainerJSON, error) {
+	return dg.inspectContainerWithTimeout(ctx, dockerID, timeout, true)
+}
+
+func (dg *dockerGoClient) inspectContainerWithTimeout(ctx context.Context, dockerID string, timeout time.Duration,
+	getSize bool) (*types.ContainerJSON, error) {
 	type inspectResponse struct {
 		container *types.ContainerJSON
 		err       error
@@ -655,7 +803,7 @@ func (dg *dockerGoClient) InspectContainer(ctx context.Context, dockerID string,
 	// read, and can still be GC'd
 	response := make(chan inspectResponse, 1)
 	go func() {
-		container, err := dg.inspectContainer(ctx, dockerID)
+		container, err := dg.inspectContainer(ctx, dockerID, getSize)
 		response <- inspectResponse{container, err}
 	}()
 
@@ -673,11 +821,15 @@ func (dg *dockerGoClient) InspectContainer(ctx context.Context, dockerID string,
 	}
 }
 
-func (dg *dockerGoClient) inspectContainer(ctx context.Context, dockerID string) (*types.ContainerJSON, error) {
+func (dg *dockerGoClient) inspectContainer(ctx context.Context, dockerID string, getSize bool) (*types.ContainerJSON, error) {
 	client, err := dg.sdkDockerClient()
 	if err != nil {
 		return nil, err
 	}
+	if getSize {
+		containerData, _, err := client.ContainerInspectWithRaw(ctx, dockerID, true)
+		return &containerData, err
+	}
 	containerData, err := client.ContainerInspect(ctx, dockerID)
 	return &containerData, err
 }
@@ -767,6 +919,131 @@ func (dg *dockerGoClient) stopContainer(ctx context.Context, dockerID string, ti
 	return metadata
 }
 
+func (dg *dockerGoClient) KillContainer(ctx context.Context, dockerID string, signal string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("KILL_CONTAINER")()
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan error, 1)
+	go func() { response <- dg.killContainer(ctx, dockerID, signal) }()
+	select {
+	case resp := <-response:
+		return resp
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			return &DockerTimeoutError{timeout, "signaled"}
+		}
+		return CannotKillContainerError{err}
+	}
+}
+
+func (dg *dockerGoClient) ContainerLogs(ctx context.Context, dockerID string,
+	options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		return nil, CannotGetDockerClientError{version: dg.version, err: err}
+	}
+	logs, err := client.ContainerLogs(ctx, dockerID, options)
+	if err != nil {
+		if strings.Contains(err.Error(), "No such container") {
+			return nil, NoSuchContainerError{dockerID}
+		}
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (dg *dockerGoClient) killContainer(ctx context.Context, dockerID string, signal string) error {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		return CannotGetDockerClientError{version: dg.version, err: err}
+	}
+	err = client.ContainerKill(ctx, dockerID, signal)
+	if err != nil {
+		if strings.Contains(err.Error(), "No such container") {
+			err = NoSuchContainerError{dockerID}
+		}
+		return CannotKillContainerError{err}
+	}
+	return nil
+}
+
+func (dg *dockerGoClient) CheckpointContainer(ctx context.Context, dockerID string, checkpointID string,
+	checkpointDir string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("CHECKPOINT_CONTAINER")()
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan error, 1)
+	go func() {
+		response <- dg.checkpointContainer(ctx, dockerID, types.CheckpointCreateOptions{
+			CheckpointID:  checkpointID,
+			CheckpointDir: checkpointDir,
+		})
+	}()
+	select {
+	case resp := <-response:
+		return resp
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			return &DockerTimeoutError{timeout, "checkpointed"}
+		}
+		return CannotCheckpointContainerError{err}
+	}
+}
+
+func (dg *dockerGoClient) checkpointContainer(ctx context.Context, dockerID string, options types.CheckpointCreateOptions) error {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		return CannotGetDockerClientError{version: dg.version, err: err}
+	}
+	if err := client.CheckpointCreate(ctx, dockerID, options); err != nil {
+		return CannotCheckpointContainerError{err}
+	}
+	return nil
+}
+
+func (dg *dockerGoClient) RemoveCheckpoint(ctx context.Context, dockerID string, checkpointID string,
+	checkpointDir string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("REMOVE_CHECKPOINT")()
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan error, 1)
+	go func() {
+		response <- dg.removeCheckpoint(ctx, dockerID, types.CheckpointDeleteOptions{
+			CheckpointID:  checkpointID,
+			CheckpointDir: checkpointDir,
+		})
+	}()
+	select {
+	case resp := <-response:
+		return resp
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			return &DockerTimeoutError{timeout, "checkpoint removed"}
+		}
+		return CannotRemoveCheckpointError{err}
+	}
+}
+
+func (dg *dockerGoClient) removeCheckpoint(ctx context.Context, dockerID string, options types.CheckpointDeleteOptions) error {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		return CannotGetDockerClientError{version: dg.version, err: err}
+	}
+	if err := client.CheckpointDelete(ctx, dockerID, options); err != nil {
+		return CannotRemoveCheckpointError{err}
+	}
+	return nil
+}
+
 func (dg *dockerGoClient) RemoveContainer(ctx context.Context, dockerID string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -850,6 +1127,13 @@ func MetadataFromContainer(dockerContainer *types.ContainerJSON) DockerContainer
 
 	if dockerContainer.HostConfig != nil {
 		metadata.NetworkMode = string(dockerContainer.HostConfig.NetworkMode)
+		metadata.Runtime = dockerContainer.HostConfig.Runtime
+		metadata.Ulimits = dockerContainer.HostConfig.Ulimits
+		var pid int
+		if dockerContainer.State != nil && dockerContainer.State.Running {
+			pid = dockerContainer.State.Pid
+		}
+		metadata.EffectiveResources = effectiveResourcesFromHostConfig(dockerContainer.HostConfig, pid)
 	}
 
 	if dockerContainer.Config != nil {
@@ -875,9 +1159,26 @@ func MetadataFromContainer(dockerContainer *types.ContainerJSON) DockerContainer
 	}
 	// Record the health check information if exists
 	metadata.Health = getMetadataHealthCheck(dockerContainer)
+	metadata.HealthLog = getMetadataHealthLog(dockerContainer)
 	return metadata
 }
 
+// getMetadataHealthLog converts the health check results kept by docker, oldest first
+func getMetadataHealthLog(dockerContainer *types.ContainerJSON) []apicontainer.HealthCheckResult {
+	var healthLog []apicontainer.HealthCheckResult
+	for _, result := range dockerContainer.State.Health.Log {
+		if result == nil {
+			continue
+		}
+		healthLog = append(healthLog, apicontainer.HealthCheckResult{
+			Timestamp: result.End,
+			ExitCode:  result.ExitCode,
+			Output:    result.Output,
+		})
+	}
+	return healthLog
+}
+
 func getMetadataHealthCheck(dockerContainer *types.ContainerJSON) apicontainer.HealthStatus {
 	health := apicontainer.HealthStatus{}
 	if dockerContainer.State == nil || dockerContainer.State.Health == nil {
@@ -919,6 +1220,8 @@ func (dg *dockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 
 	events := make(chan *events.Message)
 	buffer := NewInfiniteBuffer()
+	checkpoint := newEventCheckpoint(dg.time().Now())
+	changedContainers := make(chan DockerContainerChangeEvent)
 
 	derivedCtx, cancel := context.WithCancel(ctx)
 	dockerEvents, eventErr := client.Events(derivedCtx, types.EventsOptions{})
@@ -942,9 +1245,15 @@ func (dg *dockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 					seelog.Errorf("DockerGoClient: Docker events stream closed with error: %v", err)
 				}
 
-				// Reopen a new event stream to continue listening.
+				// Reopen a new event stream to continue listening, replaying the events
+				// since the last one processed. The events replayed that were already
+				// processed are skipped by handleContainerEvents.
+				since := checkpoint.time()
 				nextCtx, nextCancel := context.WithCancel(ctx)
-				dockerEvents, eventErr = client.Events(nextCtx, types.EventsOptions{})
+				dockerEvents, eventErr = client.Events(nextCtx, types.EventsOptions{Since: sinceOption(since)})
+				// The events of the daemon are lost if it restarted, so its containers
+				// that stopped in the meantime are looked up as well
+				go dg.reconcileStoppedContainers(ctx, since, changedContainers)
 				// Cache the event from docker client.
 				go buffer.StartListening(nextCtx, dockerEvents)
 				// Close previous stream after starting to listen on new one
@@ -959,17 +1268,21 @@ func (dg *dockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 
 	// Read the buffered events and send to task engine
 	go buffer.Consume(events)
-	changedContainers := make(chan DockerContainerChangeEvent)
-	go dg.handleContainerEvents(ctx, events, changedContainers)
+	go dg.handleContainerEvents(ctx, events, changedContainers, checkpoint)
 
 	return changedContainers, nil
 }
 
 func (dg *dockerGoClient) handleContainerEvents(ctx context.Context,
 	events <-chan *events.Message,
-	changedContainers chan<- DockerContainerChangeEvent) {
+	changedContainers chan<- DockerContainerChangeEvent,
+	checkpoint *eventCheckpoint) {
 	for event := range events {
 		containerID := event.ID
+		if !checkpoint.record(event) {
+			seelog.Debugf("DockerGoClient: skipping event replayed by docker daemon: %v", event)
+			continue
+		}
 		seelog.Debugf("DockerGoClient: got event from docker daemon: %v", event)
 
 		var status apicontainerstatus.ContainerStatus
@@ -1024,6 +1337,47 @@ func (dg *dockerGoClient) handleContainerEvents(ctx context.Context,
 	}
 }
 
+// reconcileStoppedContainers sends the stop events of the containers that exited
+// since the given time, whose events are lost if the daemon restarted while the
+// event stream was closed. The stop events of the containers the engine already
+// knows to be stopped, or doesn't know, are ignored by the engine.
+func (dg *dockerGoClient) reconcileStoppedContainers(ctx context.Context,
+	since time.Time,
+	changedContainers chan<- DockerContainerChangeEvent) {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		seelog.Warnf("DockerGoClient: unable to look up the containers stopped since %s: %v", since, err)
+		return
+	}
+	listCtx, cancel := context.WithTimeout(ctx, dockerclient.ListContainersTimeout)
+	defer cancel()
+	containers, err := client.ContainerList(listCtx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("status", "exited"), filters.Arg("status", "dead")),
+	})
+	if err != nil {
+		seelog.Warnf("DockerGoClient: unable to look up the containers stopped since %s: %v", since, err)
+		return
+	}
+	for _, container := range containers {
+		metadata := dg.containerMetadata(ctx, container.ID)
+		if metadata.Error != nil || metadata.FinishedAt.Before(since) {
+			continue
+		}
+		seelog.Infof("DockerGoClient: container %s stopped at %s while the docker events stream was closed",
+			container.ID, metadata.FinishedAt)
+		select {
+		case changedContainers <- DockerContainerChangeEvent{
+			Status:                  apicontainerstatus.ContainerStopped,
+			Type:                    apicontainer.ContainerStatusEvent,
+			DockerContainerMetadata: metadata,
+		}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // ListContainers returns a slice of container IDs.
 func (dg *dockerGoClient) ListContainers(ctx context.Context, all bool, timeout time.Duration) ListContainersResponse {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
@@ -1499,6 +1853,14 @@ func (dg *dockerGoClient) Stats(ctx context.Context, id string, inactivityTimeou
 	return statsC, errC
 }
 
+func (dg *dockerGoClient) StatsSnapshot(ctx context.Context, id string, timeout time.Duration) (*types.StatsJSON, error) {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	return getContainerStatsNotStreamed(client, ctx, id, timeout)
+}
+
 func getContainerStatsNotStreamed(client sdkclient.Client, ctx context.Context, id string, timeout time.Duration) (*types.StatsJSON, error) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
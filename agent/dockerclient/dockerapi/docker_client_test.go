@@ -21,6 +21,8 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -40,10 +42,10 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/ec2"
 	mock_ecr "github.com/aws/amazon-ecs-agent/agent/ecr/mocks"
 	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
-	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	mock_ttime "github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecrpublic"
 	"github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
@@ -51,6 +53,7 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -69,12 +72,10 @@ const xImageShortTimeout = 1 * time.Millisecond
 
 const (
 	// retry settings for pulling images mock backoff
-	xMaximumPullRetries        = 5
-	xMinimumPullRetryDelay     = 25 * time.Millisecond
-	xMaximumPullRetryDelay     = 100 * time.Microsecond
-	xPullRetryDelayMultiplier  = 2
-	xPullRetryJitterMultiplier = 0.2
-	dockerEventBufferSize      = 100
+	xMaximumPullRetries    = 5
+	xMinimumPullRetryDelay = 25 * time.Millisecond
+	xMaximumPullRetryDelay = 100 * time.Microsecond
+	dockerEventBufferSize  = 100
 )
 
 func defaultTestConfig() *config.Config {
@@ -116,8 +117,10 @@ func dockerClientSetupWithConfig(t *testing.T, conf config.Config) (
 	ecrClientFactory := mock_ecr.NewMockECRFactory(ctrl)
 	goClient.ecrClientFactory = ecrClientFactory
 	goClient._time = mockTime
-	goClient.imagePullBackoff = retry.NewExponentialBackoff(xMinimumPullRetryDelay, xMaximumPullRetryDelay,
-		xPullRetryJitterMultiplier, xPullRetryDelayMultiplier)
+	retryConf := conf
+	retryConf.ImagePullRetryBaseDelay = xMinimumPullRetryDelay
+	retryConf.ImagePullRetryMaxDelay = xMaximumPullRetryDelay
+	goClient.pullRetryPolicy = newPullRetryPolicy(&retryConf)
 	return mockDockerSDK, goClient, mockTime, ctrl, ecrClientFactory, ctrl.Finish
 }
 
@@ -137,7 +140,7 @@ func TestPullImageOutputTimeout(t *testing.T) {
 				reader: strings.NewReader(`{"status":"pull in progress"}`),
 			}
 			return reader, nil
-		}).Times(maximumPullRetries) // expected number of retries
+		}).Times(config.DefaultImagePullMaxAttempts) // expected number of retries
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
@@ -182,7 +185,7 @@ func TestPullImageInactivityTimeout(t *testing.T) {
 				delay:  300 * time.Millisecond,
 			}
 			return reader, nil
-		}).Times(maximumPullRetries) // expected number of retries
+		}).Times(config.DefaultImagePullMaxAttempts) // expected number of retries
 
 	client.inactivityTimeoutHandler = func(reader io.ReadCloser, timeout time.Duration, cancelRequest func(), canceled *uint32) (io.ReadCloser, chan<- struct{}) {
 		assert.Equal(t, client.config.ImagePullInactivityTimeout, timeout)
@@ -339,6 +342,57 @@ func TestPullImageECRAuthFail(t *testing.T) {
 	assert.Error(t, metadata.Error, "expected pull to fail")
 }
 
+func TestGetAuthdataECRPublic(t *testing.T) {
+	_, client, _, ctrl, _, done := dockerClientSetup(t)
+	defer done()
+
+	ecrPublicFactory := mock_ecr.NewMockECRPublicFactory(ctrl)
+	ecrPublicClient := mock_ecr.NewMockECRPublicClient(ctrl)
+	client.ecrPublicClientFactory = ecrPublicFactory
+	client.config.ECRPublicAuthEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	image := "public.ecr.aws/amazonlinux/amazonlinux:latest"
+
+	ecrPublicFactory.EXPECT().GetClient().Return(ecrPublicClient)
+	ecrPublicClient.EXPECT().GetAuthorizationToken().Return(&ecrpublic.AuthorizationData{
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:password"))),
+		ExpiresAt:          aws.Time(time.Now().Add(12 * time.Hour)),
+	}, nil)
+
+	authConfig, err := client.getAuthdata(image, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "AWS", authConfig.Username)
+	assert.Equal(t, "password", authConfig.Password)
+}
+
+func TestGetAuthdataECRPublicFallsBackToAnonymous(t *testing.T) {
+	_, client, _, ctrl, _, done := dockerClientSetup(t)
+	defer done()
+
+	ecrPublicFactory := mock_ecr.NewMockECRPublicFactory(ctrl)
+	ecrPublicClient := mock_ecr.NewMockECRPublicClient(ctrl)
+	client.ecrPublicClientFactory = ecrPublicFactory
+	client.config.ECRPublicAuthEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+
+	ecrPublicFactory.EXPECT().GetClient().Return(ecrPublicClient)
+	ecrPublicClient.EXPECT().GetAuthorizationToken().Return(nil, errors.New("no credentials"))
+
+	authConfig, err := client.getAuthdata("public.ecr.aws/amazonlinux/amazonlinux:latest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, types.AuthConfig{}, authConfig)
+}
+
+func TestGetAuthdataECRPublicDisabled(t *testing.T) {
+	_, client, _, ctrl, _, done := dockerClientSetup(t)
+	defer done()
+
+	// The ECR Public token is not requested unless enabled
+	client.ecrPublicClientFactory = mock_ecr.NewMockECRPublicFactory(ctrl)
+
+	authConfig, err := client.getAuthdata("public.ecr.aws/amazonlinux/amazonlinux:latest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, types.AuthConfig{}, authConfig)
+}
+
 func TestPullImageError(t *testing.T) {
 	mockDockerSDK, client, testTime, _, _, _ := dockerClientSetup(t)
 
@@ -351,7 +405,7 @@ func TestPullImageError(t *testing.T) {
 				delay:  300 * time.Millisecond,
 			}
 			return reader, nil
-		}).Times(maximumPullRetries) // expected number of retries
+		}).Times(config.DefaultImagePullMaxAttempts) // expected number of retries
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
@@ -360,6 +414,22 @@ func TestPullImageError(t *testing.T) {
 	assert.Equal(t, "CannotPullContainerError", metadata.Error.(apierrors.NamedError).ErrorName(), "Wrong error type")
 }
 
+func TestPullImageNotFoundNoRetry(t *testing.T) {
+	mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+	defer done()
+	client.pullRetryPolicy.classes[config.ImagePullErrorNotFound] = pullErrorClassPolicy{retry: false}
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "image:latest", gomock.Any()).Return(
+		mockReadCloser{reader: strings.NewReader(`{"error":"manifest for image:latest not found: manifest unknown"}`)}, nil).Times(1)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.PullImage(ctx, "image", nil, defaultTestConfig().ImagePullTimeout)
+	assert.Error(t, metadata.Error)
+	assert.Equal(t, "CannotPullContainerError", metadata.Error.(apierrors.NamedError).ErrorName(), "Wrong error type")
+}
+
 type mockReadCloser struct {
 	reader io.Reader
 	delay  time.Duration
@@ -586,6 +656,27 @@ func TestInspectContainerExec(t *testing.T) {
 	assert.Equal(t, 25537, resp.Pid)
 }
 
+func TestInspectContainerSize(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	sizeRw := int64(4096)
+	inspectContainerResponse := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:     "id",
+			SizeRw: &sizeRw,
+		},
+	}
+	mockDockerSDK.EXPECT().ContainerInspectWithRaw(gomock.Any(), "id", true).Return(inspectContainerResponse, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	resp, err := client.InspectContainerSize(ctx, "id", dockerclient.InspectContainerTimeout)
+	assert.NoError(t, err)
+	assert.Equal(t, "id", resp.ID)
+	assert.Equal(t, sizeRw, *resp.SizeRw)
+}
+
 func TestStartContainerTimeout(t *testing.T) {
 	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
 	defer done()
@@ -675,6 +766,69 @@ func TestStopContainer(t *testing.T) {
 	assert.Equal(t, "id", metadata.DockerID)
 }
 
+func TestKillContainer(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().ContainerKill(gomock.Any(), "id", "SIGUSR1").Return(nil),
+		mockDockerSDK.EXPECT().ContainerKill(gomock.Any(), "id", "SIGINT").Return(errors.New("No such container: id")),
+	)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	assert.NoError(t, client.KillContainer(ctx, "id", "SIGUSR1", dockerclient.KillContainerTimeout))
+	err := client.KillContainer(ctx, "id", "SIGINT", dockerclient.KillContainerTimeout)
+	assert.Error(t, err)
+	assert.Equal(t, "CannotKillContainerError", err.(apierrors.NamedError).ErrorName())
+	assert.IsType(t, NoSuchContainerError{}, err.(CannotKillContainerError).FromError)
+}
+
+func TestCheckpointContainer(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().CheckpointCreate(gomock.Any(), "id", types.CheckpointCreateOptions{
+			CheckpointID:  "ecs-2",
+			CheckpointDir: "/checkpoints",
+		}).Return(nil),
+		mockDockerSDK.EXPECT().CheckpointDelete(gomock.Any(), "id", types.CheckpointDeleteOptions{
+			CheckpointID:  "ecs-1",
+			CheckpointDir: "/checkpoints",
+		}).Return(errors.New("test error")),
+	)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	assert.NoError(t, client.CheckpointContainer(ctx, "id", "ecs-2", "/checkpoints", dockerclient.CheckpointContainerTimeout))
+	err := client.RemoveCheckpoint(ctx, "id", "ecs-1", "/checkpoints", dockerclient.RemoveCheckpointTimeout)
+	assert.Error(t, err)
+	assert.Equal(t, "CannotRemoveCheckpointError", err.(apierrors.NamedError).ErrorName())
+}
+
+func TestRestoreContainer(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().ContainerStart(gomock.Any(), "id", types.ContainerStartOptions{
+			CheckpointID:  "ecs-1",
+			CheckpointDir: "/checkpoints",
+		}).Return(nil),
+		mockDockerSDK.EXPECT().ContainerInspect(gomock.Any(), "id").Return(types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:    "id",
+				State: &types.ContainerState{Running: true},
+			},
+			Config: &dockercontainer.Config{},
+		}, nil),
+	)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.RestoreContainer(ctx, "id", "ecs-1", "/checkpoints", defaultTestConfig().ContainerStartTimeout)
+	assert.NoError(t, metadata.Error)
+	assert.Equal(t, "id", metadata.DockerID)
+}
+
 func TestRemoveContainerTimeout(t *testing.T) {
 	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
 	defer done()
@@ -796,9 +950,11 @@ func TestTopContainer(t *testing.T) {
 }
 
 func TestContainerEvents(t *testing.T) {
-	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	mockDockerSDK, client, mockTime, _, _, done := dockerClientSetup(t)
 	defer done()
 
+	mockTime.EXPECT().Now().Return(time.Now())
+
 	eventsChan := make(chan events.Message, dockerEventBufferSize)
 	errChan := make(chan error)
 	mockDockerSDK.EXPECT().Events(gomock.Any(), gomock.Any()).Return(eventsChan, errChan)
@@ -974,12 +1130,14 @@ func TestContainerEventsError(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+			mockDockerSDK, client, mockTime, _, _, done := dockerClientSetup(t)
 			defer done()
 
+			mockTime.EXPECT().Now().Return(time.Now())
 			eventsChan := make(chan events.Message, dockerEventBufferSize)
 			errChan := make(chan error)
 			mockDockerSDK.EXPECT().Events(gomock.Any(), gomock.Any()).Return(eventsChan, errChan).MinTimes(1)
+			mockDockerSDK.EXPECT().ContainerList(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 
 			dockerEvents, err := client.ContainerEvents(context.TODO())
 			require.NoError(t, err, "Could not get container events")
@@ -995,6 +1153,69 @@ func TestContainerEventsError(t *testing.T) {
 	}
 }
 
+func TestContainerEventsReplay(t *testing.T) {
+	mockDockerSDK, client, mockTime, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	eventTime := time.Now().Add(time.Minute)
+	mockTime.EXPECT().Now().Return(time.Now())
+	eventsChan := make(chan events.Message, dockerEventBufferSize)
+	errChan := make(chan error)
+	replayEventsChan := make(chan events.Message, dockerEventBufferSize)
+	stopped := func(id string, finishedAt time.Time) types.ContainerJSON {
+		return types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID: id,
+				State: &types.ContainerState{
+					FinishedAt: finishedAt.Format(time.RFC3339Nano),
+					ExitCode:   1,
+				},
+			},
+		}
+	}
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().Events(gomock.Any(), types.EventsOptions{}).Return(eventsChan, errChan),
+		// The stream is reopened from the last event processed
+		mockDockerSDK.EXPECT().Events(gomock.Any(), types.EventsOptions{Since: sinceOption(eventTime)}).
+			Return(replayEventsChan, make(chan error)),
+	)
+	mockDockerSDK.EXPECT().ContainerList(gomock.Any(), gomock.Any()).Return([]types.Container{
+		{ID: "stopped-before"}, {ID: "stopped-while-closed"},
+	}, nil)
+	mockDockerSDK.EXPECT().ContainerInspect(gomock.Any(), "stopped-before").
+		Return(stopped("stopped-before", eventTime.Add(-time.Second)), nil)
+	mockDockerSDK.EXPECT().ContainerInspect(gomock.Any(), "stopped-while-closed").
+		Return(stopped("stopped-while-closed", eventTime.Add(time.Second)), nil)
+
+	dockerEvents, err := client.ContainerEvents(context.TODO())
+	require.NoError(t, err, "Could not get container events")
+	created := events.Message{Type: "container", ID: "containerId", Status: "create", TimeNano: eventTime.UnixNano()}
+	eventsChan <- created
+	event := <-dockerEvents
+	assert.Equal(t, "containerId", event.DockerID)
+
+	errChan <- io.EOF
+	// The event processed before the stream closed is replayed, and skipped
+	replayEventsChan <- created
+	replayEventsChan <- events.Message{Type: "container", ID: "containerId2", Status: "create",
+		TimeNano: eventTime.Add(2 * time.Second).UnixNano()}
+
+	received := make(map[string]apicontainerstatus.ContainerStatus)
+	for i := 0; i < 2; i++ {
+		event := <-dockerEvents
+		received[event.DockerID] = event.Status
+	}
+	assert.Equal(t, map[string]apicontainerstatus.ContainerStatus{
+		"containerId2":         apicontainerstatus.ContainerCreated,
+		"stopped-while-closed": apicontainerstatus.ContainerStopped,
+	}, received)
+	select {
+	case event := <-dockerEvents:
+		t.Errorf("Unexpected event: %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestDockerVersion(t *testing.T) {
 	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
 	defer done()
@@ -1409,6 +1630,20 @@ func TestPollStatsError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestStatsSnapshot(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+	mockDockerSDK.EXPECT().ContainerStats(gomock.Any(), "foo", false).Return(types.ContainerStats{
+		Body: ioutil.NopCloser(strings.NewReader(`{"memory_stats":{"Usage":50},"cpu_stats":{"system_cpu_usage":100}}`)),
+	}, nil)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	stats, err := client.StatsSnapshot(ctx, "foo", dockerclient.StatsInactivityTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(50), stats.MemoryStats.Usage)
+	assert.Equal(t, uint64(100), stats.CPUStats.SystemUsage)
+}
+
 func TestStatsInactivityTimeoutNoHit(t *testing.T) {
 	longInactivityTimeout := 500 * time.Millisecond
 	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
@@ -1762,6 +1997,13 @@ func TestMetadataFromContainer(t *testing.T) {
 			},
 			HostConfig: &dockercontainer.HostConfig{
 				NetworkMode: dockercontainer.NetworkMode("bridge"),
+				Runtime:     "runsc",
+				Resources: dockercontainer.Resources{
+					CPUShares: 512,
+					Memory:    512 * 1024 * 1024,
+					NanoCPUs:  1500000000,
+					Ulimits:   []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+				},
 			},
 		},
 		Config: &dockercontainer.Config{
@@ -1776,6 +2018,13 @@ func TestMetadataFromContainer(t *testing.T) {
 	assert.Equal(t, labels, metadata.Labels)
 	assert.Len(t, metadata.PortBindings, 1)
 	assert.Equal(t, "bridge", metadata.NetworkMode)
+	assert.Equal(t, "runsc", metadata.Runtime)
+	assert.Equal(t, []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}}, metadata.Ulimits)
+	require.NotNil(t, metadata.EffectiveResources)
+	assert.Equal(t, int64(512), metadata.EffectiveResources.CPUShares)
+	assert.Equal(t, int64(150000), metadata.EffectiveResources.CPUQuota)
+	assert.Equal(t, int64(100000), metadata.EffectiveResources.CPUPeriod)
+	assert.Equal(t, int64(512*1024*1024), metadata.EffectiveResources.Memory)
 	assert.NotNil(t, metadata.NetworkSettings)
 	assert.Equal(t, "17.0.0.3", metadata.NetworkSettings.IPAddress)
 
@@ -1793,6 +2042,53 @@ func TestMetadataFromContainer(t *testing.T) {
 	assert.True(t, finishedTime.Equal(finishedTimeSDK))
 }
 
+func TestParseUnifiedCgroupPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{
+			name:     "unified hierarchy",
+			data:     "0::/ecs/task-id/container-id\n",
+			expected: "/ecs/task-id/container-id",
+		},
+		{
+			name: "v1 hierarchies",
+			data: "12:memory:/ecs/task-id/container-id\n11:cpu,cpuacct:/ecs/task-id/container-id\n",
+		},
+		{
+			name: "hybrid hierarchies",
+			data: "12:memory:/ecs/task-id/container-id\n0::/ecs/task-id/container-id\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseUnifiedCgroupPath([]byte(tc.data)))
+		})
+	}
+}
+
+func TestUnifiedCgroupPath(t *testing.T) {
+	defer func(original string) {
+		hostProcFSPath = original
+	}(hostProcFSPath)
+	procDir, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procDir)
+	hostProcFSPath = procDir
+
+	assert.Empty(t, unifiedCgroupPath(42))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(hostProcFSPath, "42"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hostProcFSPath, "42", "cgroup"),
+		[]byte("0::/ecs/task-id/container-id\n"), 0644))
+	assert.Equal(t, "/ecs/task-id/container-id", unifiedCgroupPath(42))
+}
+
 func TestMetadataFromContainerHealthCheckWithNoLogs(t *testing.T) {
 
 	dockerContainer := &types.ContainerJSON{
@@ -1807,6 +2103,37 @@ func TestMetadataFromContainerHealthCheckWithNoLogs(t *testing.T) {
 	assert.Equal(t, apicontainerstatus.ContainerUnhealthy, metadata.Health.Status)
 }
 
+func TestMetadataFromContainerHealthCheckLog(t *testing.T) {
+	end := time.Now()
+	dockerContainer := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State: &types.ContainerState{
+				Health: &types.Health{
+					Status: "healthy",
+					Log: []*types.HealthcheckResult{
+						{
+							End:      end.Add(-time.Second),
+							ExitCode: 1,
+							Output:   "failed",
+						},
+						{
+							End:      end,
+							ExitCode: 0,
+							Output:   "ok",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	metadata := MetadataFromContainer(dockerContainer)
+	assert.Equal(t, []apicontainer.HealthCheckResult{
+		{Timestamp: end.Add(-time.Second), ExitCode: 1, Output: "failed"},
+		{Timestamp: end, ExitCode: 0, Output: "ok"},
+	}, metadata.HealthLog)
+}
+
 func TestCreateVolumeTimeout(t *testing.T) {
 	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
 	defer done()
@@ -2060,3 +2387,28 @@ func TestListPluginsWithFilter(t *testing.T) {
 	assert.Equal(t, 1, len(pluginNames))
 	assert.Equal(t, "name2", pluginNames[0])
 }
+
+func TestContainerLogs(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	options := types.ContainerLogsOptions{ShowStdout: true, Follow: true, Timestamps: true}
+	mockDockerSDK.EXPECT().ContainerLogs(gomock.Any(), "id", options).Return(
+		ioutil.NopCloser(strings.NewReader("logs")), nil)
+	logs, err := client.ContainerLogs(context.TODO(), "id", options)
+	require.NoError(t, err)
+	defer logs.Close()
+	content, err := ioutil.ReadAll(logs)
+	require.NoError(t, err)
+	assert.Equal(t, "logs", string(content))
+}
+
+func TestContainerLogsNoSuchContainer(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDockerSDK.EXPECT().ContainerLogs(gomock.Any(), "id", gomock.Any()).Return(nil,
+		errors.New("Error: No such container: id"))
+	_, err := client.ContainerLogs(context.TODO(), "id", types.ContainerLogsOptions{})
+	assert.IsType(t, NoSuchContainerError{}, err)
+}
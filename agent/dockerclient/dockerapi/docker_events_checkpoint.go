@@ -0,0 +1,91 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerapi
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+)
+
+// recentEventsSize is the number of the processed events remembered to skip
+// their copies replayed by docker when the event stream is reopened
+const recentEventsSize = 1024
+
+// eventCheckpoint tracks the time of the last docker event processed, so that
+// the event stream can be reopened from it to replay the events missed while
+// it was closed, and remembers the recent events, so that the events processed
+// both before and after the stream was reopened are only processed once.
+type eventCheckpoint struct {
+	timeNano int64
+	// recent is a ring of the keys of the recent events, indexed by recentKeys
+	recent     []string
+	recentKeys map[string]struct{}
+	next       int
+	lock       sync.Mutex
+}
+
+// newEventCheckpoint returns a checkpoint starting at the time the event stream
+// is first opened.
+func newEventCheckpoint(start time.Time) *eventCheckpoint {
+	return &eventCheckpoint{
+		timeNano:   start.UnixNano(),
+		recent:     make([]string, recentEventsSize),
+		recentKeys: make(map[string]struct{}, recentEventsSize),
+	}
+}
+
+// record records that the event is processed, and returns false if it already was.
+// Events without a timestamp are always processed, as they can't be told apart.
+func (checkpoint *eventCheckpoint) record(event *events.Message) bool {
+	if event.TimeNano == 0 {
+		return true
+	}
+	key := fmt.Sprintf("%s/%s/%d", event.ID, event.Status, event.TimeNano)
+
+	checkpoint.lock.Lock()
+	defer checkpoint.lock.Unlock()
+
+	if _, ok := checkpoint.recentKeys[key]; ok {
+		return false
+	}
+	if evicted := checkpoint.recent[checkpoint.next]; evicted != "" {
+		delete(checkpoint.recentKeys, evicted)
+	}
+	checkpoint.recent[checkpoint.next] = key
+	checkpoint.recentKeys[key] = struct{}{}
+	checkpoint.next = (checkpoint.next + 1) % len(checkpoint.recent)
+	// The buffer of the events doesn't keep their order, so the checkpoint only
+	// moves forward
+	if event.TimeNano > checkpoint.timeNano {
+		checkpoint.timeNano = event.TimeNano
+	}
+	return true
+}
+
+// time returns the time of the last event processed.
+func (checkpoint *eventCheckpoint) time() time.Time {
+	checkpoint.lock.Lock()
+	defer checkpoint.lock.Unlock()
+
+	return time.Unix(0, checkpoint.timeNano)
+}
+
+// sinceOption formats the time for the 'since' option of the docker events API,
+// as seconds and nanoseconds since the epoch.
+func sinceOption(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
@@ -0,0 +1,63 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventCheckpointRecord(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	checkpoint := newEventCheckpoint(start)
+	assert.Equal(t, start, checkpoint.time())
+
+	event := &events.Message{ID: "c1", Status: "die", TimeNano: start.Add(time.Second).UnixNano()}
+	assert.True(t, checkpoint.record(event))
+	assert.False(t, checkpoint.record(event), "replayed event should be skipped")
+	assert.Equal(t, start.Add(time.Second), checkpoint.time())
+
+	// Older events are processed without moving the checkpoint back
+	older := &events.Message{ID: "c2", Status: "start", TimeNano: start.Add(time.Millisecond).UnixNano()}
+	assert.True(t, checkpoint.record(older))
+	assert.Equal(t, start.Add(time.Second), checkpoint.time())
+
+	// Events without a timestamp can't be told apart
+	untimed := &events.Message{ID: "c3", Status: "create"}
+	assert.True(t, checkpoint.record(untimed))
+	assert.True(t, checkpoint.record(untimed))
+}
+
+func TestEventCheckpointForgetsOldEvents(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	checkpoint := newEventCheckpoint(start)
+	first := &events.Message{ID: "c0", Status: "start", TimeNano: start.UnixNano() + 1}
+	assert.True(t, checkpoint.record(first))
+	for i := 2; i <= recentEventsSize; i++ {
+		assert.True(t, checkpoint.record(&events.Message{ID: "c", Status: "start", TimeNano: start.UnixNano() + int64(i)}))
+	}
+	assert.False(t, checkpoint.record(first))
+	assert.True(t, checkpoint.record(&events.Message{ID: "c", Status: "start", TimeNano: start.UnixNano() + recentEventsSize + 1}))
+	assert.True(t, checkpoint.record(first), "the oldest event should be forgotten")
+	assert.Len(t, checkpoint.recentKeys, recentEventsSize)
+}
+
+func TestSinceOption(t *testing.T) {
+	assert.Equal(t, "1600000000.000000042", sinceOption(time.Unix(1600000000, 42)))
+}
@@ -245,6 +245,48 @@ func (err CannotGetContainerTopError) ErrorName() string {
 	return CannotGetContainerTopErrorName
 }
 
+// CannotCheckpointContainerError indicates any error when trying to checkpoint a container
+type CannotCheckpointContainerError struct {
+	FromError error
+}
+
+func (err CannotCheckpointContainerError) Error() string {
+	return err.FromError.Error()
+}
+
+// ErrorName returns name of the CannotCheckpointContainerError
+func (err CannotCheckpointContainerError) ErrorName() string {
+	return "CannotCheckpointContainerError"
+}
+
+// CannotRemoveCheckpointError indicates any error when trying to remove a checkpoint of a container
+type CannotRemoveCheckpointError struct {
+	FromError error
+}
+
+func (err CannotRemoveCheckpointError) Error() string {
+	return err.FromError.Error()
+}
+
+// ErrorName returns name of the CannotRemoveCheckpointError
+func (err CannotRemoveCheckpointError) ErrorName() string {
+	return "CannotRemoveCheckpointError"
+}
+
+// CannotKillContainerError indicates any error when trying to send a signal to a container
+type CannotKillContainerError struct {
+	FromError error
+}
+
+func (err CannotKillContainerError) Error() string {
+	return err.FromError.Error()
+}
+
+// ErrorName returns name of the CannotKillContainerError
+func (err CannotKillContainerError) ErrorName() string {
+	return "CannotKillContainerError"
+}
+
 // CannotRemoveContainerError indicates any error when trying to remove a container
 type CannotRemoveContainerError struct {
 	FromError error
@@ -28,6 +28,7 @@ import (
 	status "github.com/aws/amazon-ecs-agent/agent/api/container/status"
 	dockerclient "github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	dockerauth "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerauth"
 	types "github.com/docker/docker/api/types"
 	container0 "github.com/docker/docker/api/types/container"
 	filters "github.com/docker/docker/api/types/filters"
@@ -72,6 +73,20 @@ func (mr *MockDockerClientMockRecorder) APIVersion() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIVersion", reflect.TypeOf((*MockDockerClient)(nil).APIVersion))
 }
 
+// CheckpointContainer mocks base method
+func (m *MockDockerClient) CheckpointContainer(arg0 context.Context, arg1, arg2, arg3 string, arg4 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckpointContainer", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckpointContainer indicates an expected call of CheckpointContainer
+func (mr *MockDockerClientMockRecorder) CheckpointContainer(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckpointContainer", reflect.TypeOf((*MockDockerClient)(nil).CheckpointContainer), arg0, arg1, arg2, arg3, arg4)
+}
+
 // ContainerEvents mocks base method
 func (m *MockDockerClient) ContainerEvents(arg0 context.Context) (<-chan dockerapi.DockerContainerChangeEvent, error) {
 	m.ctrl.T.Helper()
@@ -87,6 +102,21 @@ func (mr *MockDockerClientMockRecorder) ContainerEvents(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerEvents", reflect.TypeOf((*MockDockerClient)(nil).ContainerEvents), arg0)
 }
 
+// ContainerLogs mocks base method
+func (m *MockDockerClient) ContainerLogs(arg0 context.Context, arg1 string, arg2 types.ContainerLogsOptions) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerLogs", arg0, arg1, arg2)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerLogs indicates an expected call of ContainerLogs
+func (mr *MockDockerClientMockRecorder) ContainerLogs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerLogs", reflect.TypeOf((*MockDockerClient)(nil).ContainerLogs), arg0, arg1, arg2)
+}
+
 // CreateContainer mocks base method
 func (m *MockDockerClient) CreateContainer(arg0 context.Context, arg1 *container0.Config, arg2 *container0.HostConfig, arg3 string, arg4 time.Duration) dockerapi.DockerContainerMetadata {
 	m.ctrl.T.Helper()
@@ -145,6 +175,18 @@ func (mr *MockDockerClientMockRecorder) DescribeContainer(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeContainer", reflect.TypeOf((*MockDockerClient)(nil).DescribeContainer), arg0, arg1)
 }
 
+// ForgetPullCredentials mocks base method
+func (m *MockDockerClient) ForgetPullCredentials(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ForgetPullCredentials", arg0)
+}
+
+// ForgetPullCredentials indicates an expected call of ForgetPullCredentials
+func (mr *MockDockerClientMockRecorder) ForgetPullCredentials(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgetPullCredentials", reflect.TypeOf((*MockDockerClient)(nil).ForgetPullCredentials), arg0)
+}
+
 // Info mocks base method
 func (m *MockDockerClient) Info(arg0 context.Context, arg1 time.Duration) (types.Info, error) {
 	m.ctrl.T.Helper()
@@ -190,6 +232,21 @@ func (mr *MockDockerClientMockRecorder) InspectContainerExec(arg0, arg1, arg2 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectContainerExec", reflect.TypeOf((*MockDockerClient)(nil).InspectContainerExec), arg0, arg1, arg2)
 }
 
+// InspectContainerSize mocks base method
+func (m *MockDockerClient) InspectContainerSize(arg0 context.Context, arg1 string, arg2 time.Duration) (*types.ContainerJSON, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InspectContainerSize", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.ContainerJSON)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectContainerSize indicates an expected call of InspectContainerSize
+func (mr *MockDockerClientMockRecorder) InspectContainerSize(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectContainerSize", reflect.TypeOf((*MockDockerClient)(nil).InspectContainerSize), arg0, arg1, arg2)
+}
+
 // InspectImage mocks base method
 func (m *MockDockerClient) InspectImage(arg0 string) (*types.ImageInspect, error) {
 	m.ctrl.T.Helper()
@@ -219,6 +276,20 @@ func (mr *MockDockerClientMockRecorder) InspectVolume(arg0, arg1, arg2 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectVolume", reflect.TypeOf((*MockDockerClient)(nil).InspectVolume), arg0, arg1, arg2)
 }
 
+// KillContainer mocks base method
+func (m *MockDockerClient) KillContainer(arg0 context.Context, arg1, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillContainer", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// KillContainer indicates an expected call of KillContainer
+func (mr *MockDockerClientMockRecorder) KillContainer(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillContainer", reflect.TypeOf((*MockDockerClient)(nil).KillContainer), arg0, arg1, arg2, arg3)
+}
+
 // KnownVersions mocks base method
 func (m *MockDockerClient) KnownVersions() []dockerclient.DockerVersion {
 	m.ctrl.T.Helper()
@@ -233,6 +304,20 @@ func (mr *MockDockerClientMockRecorder) KnownVersions() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KnownVersions", reflect.TypeOf((*MockDockerClient)(nil).KnownVersions))
 }
 
+// LazyLoadsImage mocks base method
+func (m *MockDockerClient) LazyLoadsImage(arg0 context.Context, arg1 string, arg2 *container.RegistryAuthenticationData) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LazyLoadsImage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// LazyLoadsImage indicates an expected call of LazyLoadsImage
+func (mr *MockDockerClientMockRecorder) LazyLoadsImage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LazyLoadsImage", reflect.TypeOf((*MockDockerClient)(nil).LazyLoadsImage), arg0, arg1, arg2)
+}
+
 // ListContainers mocks base method
 func (m *MockDockerClient) ListContainers(arg0 context.Context, arg1 bool, arg2 time.Duration) dockerapi.ListContainersResponse {
 	m.ctrl.T.Helper()
@@ -304,6 +389,20 @@ func (mr *MockDockerClientMockRecorder) LoadImage(arg0, arg1, arg2 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadImage", reflect.TypeOf((*MockDockerClient)(nil).LoadImage), arg0, arg1, arg2)
 }
 
+// PersistECRTokenCache mocks base method
+func (m *MockDockerClient) PersistECRTokenCache(arg0 dockerauth.ECRTokenStore) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PersistECRTokenCache", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PersistECRTokenCache indicates an expected call of PersistECRTokenCache
+func (mr *MockDockerClientMockRecorder) PersistECRTokenCache(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PersistECRTokenCache", reflect.TypeOf((*MockDockerClient)(nil).PersistECRTokenCache), arg0)
+}
+
 // PullImage mocks base method
 func (m *MockDockerClient) PullImage(arg0 context.Context, arg1 string, arg2 *container.RegistryAuthenticationData, arg3 time.Duration) dockerapi.DockerContainerMetadata {
 	m.ctrl.T.Helper()
@@ -318,6 +417,20 @@ func (mr *MockDockerClientMockRecorder) PullImage(arg0, arg1, arg2, arg3 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullImage", reflect.TypeOf((*MockDockerClient)(nil).PullImage), arg0, arg1, arg2, arg3)
 }
 
+// RemoveCheckpoint mocks base method
+func (m *MockDockerClient) RemoveCheckpoint(arg0 context.Context, arg1, arg2, arg3 string, arg4 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCheckpoint", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCheckpoint indicates an expected call of RemoveCheckpoint
+func (mr *MockDockerClientMockRecorder) RemoveCheckpoint(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCheckpoint", reflect.TypeOf((*MockDockerClient)(nil).RemoveCheckpoint), arg0, arg1, arg2, arg3, arg4)
+}
+
 // RemoveContainer mocks base method
 func (m *MockDockerClient) RemoveContainer(arg0 context.Context, arg1 string, arg2 time.Duration) error {
 	m.ctrl.T.Helper()
@@ -360,6 +473,20 @@ func (mr *MockDockerClientMockRecorder) RemoveVolume(arg0, arg1, arg2 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveVolume", reflect.TypeOf((*MockDockerClient)(nil).RemoveVolume), arg0, arg1, arg2)
 }
 
+// RestoreContainer mocks base method
+func (m *MockDockerClient) RestoreContainer(arg0 context.Context, arg1, arg2, arg3 string, arg4 time.Duration) dockerapi.DockerContainerMetadata {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreContainer", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(dockerapi.DockerContainerMetadata)
+	return ret0
+}
+
+// RestoreContainer indicates an expected call of RestoreContainer
+func (mr *MockDockerClientMockRecorder) RestoreContainer(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreContainer", reflect.TypeOf((*MockDockerClient)(nil).RestoreContainer), arg0, arg1, arg2, arg3, arg4)
+}
+
 // StartContainer mocks base method
 func (m *MockDockerClient) StartContainer(arg0 context.Context, arg1 string, arg2 time.Duration) dockerapi.DockerContainerMetadata {
 	m.ctrl.T.Helper()
@@ -403,6 +530,21 @@ func (mr *MockDockerClientMockRecorder) Stats(arg0, arg1, arg2 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockDockerClient)(nil).Stats), arg0, arg1, arg2)
 }
 
+// StatsSnapshot mocks base method
+func (m *MockDockerClient) StatsSnapshot(arg0 context.Context, arg1 string, arg2 time.Duration) (*types.StatsJSON, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StatsSnapshot", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.StatsJSON)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StatsSnapshot indicates an expected call of StatsSnapshot
+func (mr *MockDockerClientMockRecorder) StatsSnapshot(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatsSnapshot", reflect.TypeOf((*MockDockerClient)(nil).StatsSnapshot), arg0, arg1, arg2)
+}
+
 // StopContainer mocks base method
 func (m *MockDockerClient) StopContainer(arg0 context.Context, arg1 string, arg2 time.Duration) dockerapi.DockerContainerMetadata {
 	m.ctrl.T.Helper()
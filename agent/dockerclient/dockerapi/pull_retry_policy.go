@@ -0,0 +1,148 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerapi
+
+import (
+	"strings"
+	"time"
+
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+)
+
+const (
+	pullRetryDelayMultiplier  = 2
+	pullRetryJitterMultiplier = 0.2
+)
+
+var (
+	// pullThrottlingErrors are the messages of registries that throttle pulls
+	pullThrottlingErrors = []string{"toomanyrequests", "too many requests", "rate exceeded", "throttl"}
+	// pullNotFoundErrors are the messages of registries for images that do not exist
+	pullNotFoundErrors = []string{"manifest unknown", "not found", "does not exist", "name unknown"}
+	// pullTimeoutErrors are the messages of pulls that timed out
+	pullTimeoutErrors = []string{"timeout", "timed out", "deadline exceeded"}
+)
+
+// pullErrorClassPolicy is how the image pull errors of a class are retried
+type pullErrorClassPolicy struct {
+	retry     bool
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// pullRetryPolicy decides whether a failed image pull is retried, and how long
+// to wait before retrying it, based on the class of the pull error
+type pullRetryPolicy struct {
+	maxAttempts int
+	classes     map[config.ImagePullErrorClass]pullErrorClassPolicy
+}
+
+// newPullRetryPolicy creates the image pull retry policy from the agent config
+func newPullRetryPolicy(cfg *config.Config) *pullRetryPolicy {
+	policy := &pullRetryPolicy{
+		maxAttempts: cfg.ImagePullMaxAttempts,
+		classes:     make(map[config.ImagePullErrorClass]pullErrorClassPolicy),
+	}
+	for _, class := range []config.ImagePullErrorClass{config.ImagePullErrorThrottling,
+		config.ImagePullErrorNotFound, config.ImagePullErrorTimeout, config.ImagePullErrorOther} {
+		classPolicy := pullErrorClassPolicy{
+			retry:     true,
+			baseDelay: cfg.ImagePullRetryBaseDelay,
+			maxDelay:  cfg.ImagePullRetryMaxDelay,
+		}
+		if override, ok := cfg.ImagePullRetryPolicies[class]; ok {
+			classPolicy.retry = !override.NoRetry
+			if override.BaseDelay != 0 {
+				classPolicy.baseDelay = override.BaseDelay
+			}
+			if override.MaxDelay != 0 {
+				classPolicy.maxDelay = override.MaxDelay
+			}
+			if classPolicy.maxDelay < classPolicy.baseDelay {
+				classPolicy.maxDelay = classPolicy.baseDelay
+			}
+		}
+		policy.classes[class] = classPolicy
+	}
+	return policy
+}
+
+// newBackoff returns the backoff for the retries of a single image pull
+func (policy *pullRetryPolicy) newBackoff() *pullBackoff {
+	return &pullBackoff{
+		policy:   policy,
+		backoffs: make(map[config.ImagePullErrorClass]retry.Backoff),
+	}
+}
+
+// pullBackoff tracks the retries of a single image pull. Errors of each class
+// back off independently, so that a transient error does not inherit the
+// delay built up by registry throttling and vice versa
+type pullBackoff struct {
+	policy   *pullRetryPolicy
+	attempts int
+	backoffs map[config.ImagePullErrorClass]retry.Backoff
+}
+
+// next records a failed pull attempt, and returns how long to wait before the
+// next attempt, or false if the pull should not be retried
+func (b *pullBackoff) next(err apierrors.NamedError) (time.Duration, bool) {
+	b.attempts++
+	if b.attempts >= b.policy.maxAttempts {
+		return 0, false
+	}
+	if retriable, ok := err.(apierrors.Retriable); ok && !retriable.Retry() {
+		return 0, false
+	}
+	class := classifyPullError(err)
+	classPolicy := b.policy.classes[class]
+	if !classPolicy.retry {
+		return 0, false
+	}
+	backoff, ok := b.backoffs[class]
+	if !ok {
+		backoff = retry.NewExponentialBackoff(classPolicy.baseDelay, classPolicy.maxDelay,
+			pullRetryJitterMultiplier, pullRetryDelayMultiplier)
+		b.backoffs[class] = backoff
+	}
+	return backoff.Duration(), true
+}
+
+// classifyPullError returns the class of an image pull error
+func classifyPullError(err apierrors.NamedError) config.ImagePullErrorClass {
+	if _, ok := err.(*DockerTimeoutError); ok {
+		return config.ImagePullErrorTimeout
+	}
+	message := strings.ToLower(err.Error())
+	switch {
+	case containsAny(message, pullThrottlingErrors):
+		return config.ImagePullErrorThrottling
+	case containsAny(message, pullNotFoundErrors):
+		return config.ImagePullErrorNotFound
+	case containsAny(message, pullTimeoutErrors):
+		return config.ImagePullErrorTimeout
+	}
+	return config.ImagePullErrorOther
+}
+
+func containsAny(message string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(message, substring) {
+			return true
+		}
+	}
+	return false
+}
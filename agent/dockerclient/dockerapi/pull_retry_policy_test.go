@@ -0,0 +1,96 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPullRetryConfig() *config.Config {
+	return &config.Config{
+		ImagePullMaxAttempts:    3,
+		ImagePullRetryBaseDelay: time.Second,
+		ImagePullRetryMaxDelay:  10 * time.Second,
+	}
+}
+
+func TestClassifyPullError(t *testing.T) {
+	testCases := []struct {
+		err   apierrors.NamedError
+		class config.ImagePullErrorClass
+	}{
+		{CannotPullContainerError{errors.New("toomanyrequests: Rate exceeded")}, config.ImagePullErrorThrottling},
+		{CannotPullContainerError{errors.New("manifest for image:tag not found: manifest unknown")}, config.ImagePullErrorNotFound},
+		{CannotPullContainerError{errors.New("repository image does not exist or may require 'docker login'")}, config.ImagePullErrorNotFound},
+		{CannotPullContainerError{errors.New("inactivity time exceeded timeout while pulling image")}, config.ImagePullErrorTimeout},
+		{&DockerTimeoutError{dockerclient.DockerPullBeginTimeout, "pullBegin"}, config.ImagePullErrorTimeout},
+		{CannotPullContainerError{errors.New("unexpected EOF")}, config.ImagePullErrorOther},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.err.Error(), func(t *testing.T) {
+			assert.Equal(t, testCase.class, classifyPullError(testCase.err))
+		})
+	}
+}
+
+func TestPullBackoffMaxAttempts(t *testing.T) {
+	backoff := newPullRetryPolicy(testPullRetryConfig()).newBackoff()
+	err := CannotPullContainerError{errors.New("unexpected EOF")}
+
+	for attempt := 1; attempt < 3; attempt++ {
+		_, ok := backoff.next(err)
+		assert.True(t, ok, "attempt %d should be retried", attempt)
+	}
+	_, ok := backoff.next(err)
+	assert.False(t, ok, "last attempt should not be retried")
+}
+
+func TestPullBackoffNonRetriableError(t *testing.T) {
+	backoff := newPullRetryPolicy(testPullRetryConfig()).newBackoff()
+
+	_, ok := backoff.next(CannotPullECRContainerError{errors.New("no token")})
+	assert.False(t, ok)
+}
+
+func TestPullBackoffErrorClassPolicies(t *testing.T) {
+	cfg := testPullRetryConfig()
+	cfg.ImagePullMaxAttempts = 10
+	cfg.ImagePullRetryPolicies = map[config.ImagePullErrorClass]config.ImagePullRetryPolicy{
+		config.ImagePullErrorNotFound:   {NoRetry: true},
+		config.ImagePullErrorThrottling: {BaseDelay: time.Minute, MaxDelay: 2 * time.Minute},
+	}
+	backoff := newPullRetryPolicy(cfg).newBackoff()
+
+	delay, ok := backoff.next(CannotPullContainerError{errors.New("toomanyrequests: Rate exceeded")})
+	assert.True(t, ok)
+	assert.True(t, delay >= time.Minute && delay <= 72*time.Second, "unexpected throttling delay %v", delay)
+
+	// Other errors back off independently of the throttling errors
+	delay, ok = backoff.next(CannotPullContainerError{errors.New("unexpected EOF")})
+	assert.True(t, ok)
+	assert.True(t, delay >= time.Second && delay <= 1200*time.Millisecond, "unexpected delay %v", delay)
+
+	_, ok = backoff.next(CannotPullContainerError{errors.New("manifest unknown")})
+	assert.False(t, ok, "not found errors should not be retried")
+}
@@ -0,0 +1,239 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"runtime"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/cihub/seelog"
+	"github.com/docker/distribution/reference"
+)
+
+const (
+	// sociSnapshotterDriver is the storage driver reported by docker when it
+	// uses the SOCI snapshotter
+	sociSnapshotterDriver = "soci"
+	// containerdSnapshotterDriverType is the driver type reported by docker
+	// when it uses the containerd image store
+	containerdSnapshotterDriverType = "io.containerd.snapshotter.v1"
+
+	// sociIndexArtifactType is the artifact type of the SOCI indexes that
+	// refer to an image manifest
+	sociIndexArtifactType = "application/vnd.amazon.soci.index.v1+json"
+	// sociIndexDigestAnnotation annotates the image manifests that embed a
+	// SOCI index, in the image indexes converted for SOCI
+	sociIndexDigestAnnotation = "com.amazon.soci.index-digest"
+
+	ociImageIndexMediaType      = "application/vnd.oci.image.index.v1+json"
+	ociImageManifestMediaType   = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerManifestMediaType     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// sociIndexLookupTimeout is the timeout to find the SOCI index of an image
+	sociIndexLookupTimeout = 10 * time.Second
+	// maxRegistryResponseSize is the maximum size of the registry responses read
+	// to find the SOCI index of an image
+	maxRegistryResponseSize = 4 << 20
+)
+
+// registryDescriptor is the subset of an OCI content descriptor used to find
+// the SOCI index of an image
+type registryDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	ArtifactType string            `json:"artifactType"`
+	Annotations  map[string]string `json:"annotations"`
+	Platform     *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform"`
+}
+
+// registryIndex is the subset of an OCI image index, or of a docker manifest
+// list, used to find the SOCI index of an image
+type registryIndex struct {
+	Manifests []registryDescriptor `json:"manifests"`
+}
+
+// LazyLoadsImage returns true if docker loads the image lazily, because it
+// uses the SOCI snapshotter and the image has a SOCI index in ECR. Any error
+// is logged and reported as false, so that the image is pulled as usual
+func (dg *dockerGoClient) LazyLoadsImage(ctx context.Context, image string,
+	authData *apicontainer.RegistryAuthenticationData) bool {
+	if authData == nil || authData.Type != apicontainer.AuthTypeECR {
+		return false
+	}
+	if !dg.usesSOCISnapshotter(ctx) {
+		return false
+	}
+	authConfig, err := dg.getAuthdata(image, authData)
+	if err != nil {
+		seelog.Warnf("DockerGoClient: unable to get the credentials to find the SOCI index of image %s: %v", image, err)
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, sociIndexLookupTimeout)
+	defer cancel()
+	found, err := dg.hasSOCIIndex(ctx, image, authConfig.Username, authConfig.Password)
+	if err != nil {
+		seelog.Warnf("DockerGoClient: unable to find the SOCI index of image %s, pulling it without lazy loading: %v", image, err)
+		return false
+	}
+	return found
+}
+
+// usesSOCISnapshotter returns true if docker uses the containerd image store
+// with the SOCI snapshotter. The storage driver cannot change without
+// restarting docker, so it is only looked up once successfully
+func (dg *dockerGoClient) usesSOCISnapshotter(ctx context.Context) bool {
+	dg.lock.Lock()
+	defer dg.lock.Unlock()
+
+	if dg.sociSnapshotterUnsafe != nil {
+		return *dg.sociSnapshotterUnsafe
+	}
+	info, err := dg.Info(ctx, dockerclient.InfoTimeout)
+	if err != nil {
+		seelog.Warnf("DockerGoClient: unable to get the docker storage driver: %v", err)
+		return false
+	}
+	usesSOCI := false
+	if info.Driver == sociSnapshotterDriver {
+		for _, status := range info.DriverStatus {
+			if status[0] == "driver-type" && status[1] == containerdSnapshotterDriverType {
+				usesSOCI = true
+			}
+		}
+	}
+	seelog.Infof("DockerGoClient: docker storage driver is %s, SOCI lazy loading supported: %t", info.Driver, usesSOCI)
+	dg.sociSnapshotterUnsafe = &usesSOCI
+	return usesSOCI
+}
+
+// hasSOCIIndex returns true if the image manifest for the platform of the
+// instance is referred to by a SOCI index, or embeds one
+func (dg *dockerGoClient) hasSOCIIndex(ctx context.Context, image string, username string, password string) (bool, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false, err
+	}
+	named = reference.TagNameOnly(named)
+	manifestRef := ""
+	if digested, ok := named.(reference.Digested); ok {
+		manifestRef = digested.Digest().String()
+	} else if tagged, ok := named.(reference.Tagged); ok {
+		manifestRef = tagged.Tag()
+	}
+	registryURL := &url.URL{Scheme: "https", Host: reference.Domain(named)}
+	repository := reference.Path(named)
+
+	body, header, err := dg.getRegistry(ctx, registryURL, "/v2/"+repository+"/manifests/"+manifestRef,
+		username, password, ociImageIndexMediaType, dockerManifestListMediaType, ociImageManifestMediaType, dockerManifestMediaType)
+	if err != nil {
+		return false, err
+	}
+	manifestDigest := header.Get("Docker-Content-Digest")
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType == ociImageIndexMediaType || mediaType == dockerManifestListMediaType {
+		var index registryIndex
+		if err := json.Unmarshal(body, &index); err != nil {
+			return false, fmt.Errorf("unable to parse image index: %v", err)
+		}
+		manifest, ok := platformManifest(index)
+		if !ok {
+			return false, fmt.Errorf("no image manifest for platform %s/%s", runtime.GOOS, runtime.GOARCH)
+		}
+		if manifest.Annotations[sociIndexDigestAnnotation] != "" {
+			return true, nil
+		}
+		manifestDigest = manifest.Digest
+	}
+	if manifestDigest == "" {
+		return false, errors.New("registry did not return the digest of the image manifest")
+	}
+
+	referrersURL := *registryURL
+	referrersURL.RawQuery = url.Values{"artifactType": []string{sociIndexArtifactType}}.Encode()
+	body, _, err = dg.getRegistry(ctx, &referrersURL, "/v2/"+repository+"/referrers/"+manifestDigest,
+		username, password, ociImageIndexMediaType)
+	if err != nil {
+		return false, err
+	}
+	var referrers registryIndex
+	if err := json.Unmarshal(body, &referrers); err != nil {
+		return false, fmt.Errorf("unable to parse image referrers: %v", err)
+	}
+	for _, referrer := range referrers.Manifests {
+		if referrer.ArtifactType == sociIndexArtifactType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// platformManifest returns the manifest for the platform of the instance in
+// the image index
+func platformManifest(index registryIndex) (registryDescriptor, bool) {
+	for _, manifest := range index.Manifests {
+		if manifest.Platform != nil && manifest.Platform.OS == runtime.GOOS &&
+			manifest.Platform.Architecture == runtime.GOARCH {
+			return manifest, true
+		}
+	}
+	return registryDescriptor{}, false
+}
+
+// getRegistry sends a GET request to the registry API, and returns the body
+// and headers of the response
+func (dg *dockerGoClient) getRegistry(ctx context.Context, registryURL *url.URL, path string,
+	username string, password string, accept ...string) ([]byte, http.Header, error) {
+	requestURL := *registryURL
+	requestURL.Path = path
+	request, err := http.NewRequest(http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	request = request.WithContext(ctx)
+	for _, mediaType := range accept {
+		request.Header.Add("Accept", mediaType)
+	}
+	if username != "" {
+		request.SetBasicAuth(username, password)
+	}
+	response, err := dg.registryClient.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, io.LimitReader(response.Body, maxRegistryResponseSize))
+		return nil, nil, fmt.Errorf("registry returned status %d for %s", response.StatusCode, path)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxRegistryResponseSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, response.Header, nil
+}
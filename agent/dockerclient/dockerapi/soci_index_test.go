@@ -0,0 +1,232 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	mock_ecr "github.com/aws/amazon-ecs-agent/agent/ecr/mocks"
+	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSOCIManifestDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// newTestRegistry returns a registry serving the manifest of repo:tag, and
+// the given SOCI referrers of the manifest with the digest testSOCIManifestDigest
+func newTestRegistry(t *testing.T, manifestType string, manifest string, referrers string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "AWS" || password != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/repo/manifests/tag":
+			assert.Contains(t, r.Header["Accept"], manifestType)
+			w.Header().Set("Content-Type", manifestType)
+			w.Header().Set("Docker-Content-Digest", "sha256:fromheader")
+			fmt.Fprint(w, manifest)
+		case "/v2/repo/referrers/" + testSOCIManifestDigest:
+			if referrers == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			assert.Equal(t, sociIndexArtifactType, r.URL.Query().Get("artifactType"))
+			w.Header().Set("Content-Type", ociImageIndexMediaType)
+			fmt.Fprint(w, referrers)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func testImageIndex(annotations string) string {
+	return fmt.Sprintf(`{"manifests":[
+		{"mediaType":%q,"digest":"sha256:other","platform":{"os":"other","architecture":%q}},
+		{"mediaType":%q,"digest":%q,"platform":{"os":%q,"architecture":%q},"annotations":{%s}}]}`,
+		ociImageManifestMediaType, runtime.GOARCH,
+		ociImageManifestMediaType, testSOCIManifestDigest, runtime.GOOS, runtime.GOARCH, annotations)
+}
+
+const testSOCIReferrers = `{"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json",` +
+	`"digest":"sha256:index","artifactType":"application/vnd.amazon.soci.index.v1+json"}]}`
+
+func TestHasSOCIIndex(t *testing.T) {
+	testCases := []struct {
+		name         string
+		manifestType string
+		manifest     string
+		referrers    string
+		expected     bool
+		expectError  bool
+	}{
+		{
+			name:         "image index with SOCI referrer",
+			manifestType: ociImageIndexMediaType,
+			manifest:     testImageIndex(""),
+			referrers:    testSOCIReferrers,
+			expected:     true,
+		},
+		{
+			name:         "image index without SOCI referrer",
+			manifestType: dockerManifestListMediaType,
+			manifest:     testImageIndex(""),
+			referrers:    `{"manifests":[]}`,
+			expected:     false,
+		},
+		{
+			name:         "image index with embedded SOCI index",
+			manifestType: ociImageIndexMediaType,
+			manifest:     testImageIndex(`"com.amazon.soci.index-digest":"sha256:index"`),
+			expected:     true,
+		},
+		{
+			name:         "registry without referrers API",
+			manifestType: ociImageIndexMediaType,
+			manifest:     testImageIndex(""),
+			expectError:  true,
+		},
+		{
+			name:         "image index without manifest for the platform",
+			manifestType: ociImageIndexMediaType,
+			manifest:     `{"manifests":[]}`,
+			expectError:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := newTestRegistry(t, tc.manifestType, tc.manifest, tc.referrers)
+			defer registry.Close()
+			client := &dockerGoClient{registryClient: registry.Client()}
+
+			image := strings.TrimPrefix(registry.URL, "https://") + "/repo:tag"
+			found, err := client.hasSOCIIndex(context.TODO(), image, "AWS", "token")
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, found)
+		})
+	}
+}
+
+func TestUsesSOCISnapshotter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		info     types.Info
+		expected bool
+	}{
+		{
+			name: "SOCI snapshotter",
+			info: types.Info{Driver: "soci", DriverStatus: [][2]string{
+				{"driver-type", "io.containerd.snapshotter.v1"}}},
+			expected: true,
+		},
+		{
+			name: "overlayfs snapshotter",
+			info: types.Info{Driver: "overlayfs", DriverStatus: [][2]string{
+				{"driver-type", "io.containerd.snapshotter.v1"}}},
+			expected: false,
+		},
+		{
+			name:     "graph driver",
+			info:     types.Info{Driver: "overlay2", DriverStatus: [][2]string{{"Backing Filesystem", "xfs"}}},
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+			defer done()
+
+			mockDockerSDK.EXPECT().Info(gomock.Any()).Return(tc.info, nil)
+			assert.Equal(t, tc.expected, client.usesSOCISnapshotter(context.TODO()))
+			// The storage driver is only looked up once
+			assert.Equal(t, tc.expected, client.usesSOCISnapshotter(context.TODO()))
+		})
+	}
+}
+
+func TestUsesSOCISnapshotterInfoError(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().Info(gomock.Any()).Return(types.Info{}, errors.New("docker unavailable")),
+		mockDockerSDK.EXPECT().Info(gomock.Any()).Return(types.Info{Driver: "soci", DriverStatus: [][2]string{
+			{"driver-type", "io.containerd.snapshotter.v1"}}}, nil),
+	)
+	assert.False(t, client.usesSOCISnapshotter(context.TODO()))
+	assert.True(t, client.usesSOCISnapshotter(context.TODO()), "the storage driver should be looked up again after an error")
+}
+
+func TestLazyLoadsImageECR(t *testing.T) {
+	mockDockerSDK, client, _, ctrl, ecrClientFactory, done := dockerClientSetup(t)
+	defer done()
+
+	registry := newTestRegistry(t, ociImageIndexMediaType, testImageIndex(""), testSOCIReferrers)
+	defer registry.Close()
+	client.registryClient = registry.Client()
+
+	registryEndpoint := strings.TrimPrefix(registry.URL, "https://")
+	authData := &apicontainer.RegistryAuthenticationData{
+		Type: apicontainer.AuthTypeECR,
+		ECRAuthData: &apicontainer.ECRAuthData{
+			RegistryID: "123456789012",
+			Region:     "us-west-2",
+		},
+	}
+	ecrClient := mock_ecr.NewMockECRClient(ctrl)
+	ecrClientFactory.EXPECT().GetClient(authData.ECRAuthData).Return(ecrClient, nil)
+	ecrClient.EXPECT().GetAuthorizationToken("123456789012").Return(&ecrapi.AuthorizationData{
+		ProxyEndpoint:      aws.String(registry.URL),
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:token"))),
+	}, nil)
+	mockDockerSDK.EXPECT().Info(gomock.Any()).Return(types.Info{Driver: "soci", DriverStatus: [][2]string{
+		{"driver-type", "io.containerd.snapshotter.v1"}}}, nil)
+
+	assert.True(t, client.LazyLoadsImage(context.TODO(), registryEndpoint+"/repo:tag", authData))
+	assert.False(t, client.LazyLoadsImage(context.TODO(), "busybox:latest", nil),
+		"images that are not pulled from ECR should not be lazily loaded")
+}
+
+func TestLazyLoadsImageWithoutSOCISnapshotter(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	authData := &apicontainer.RegistryAuthenticationData{
+		Type:        apicontainer.AuthTypeECR,
+		ECRAuthData: &apicontainer.ECRAuthData{RegistryID: "123456789012", Region: "us-west-2"},
+	}
+	mockDockerSDK.EXPECT().Info(gomock.Any()).Return(types.Info{Driver: "overlay2"}, nil)
+	assert.False(t, client.LazyLoadsImage(context.TODO(),
+		"123456789012.dkr.ecr.us-west-2.amazonaws.com/repo:tag", authData))
+}
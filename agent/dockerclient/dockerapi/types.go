@@ -22,6 +22,7 @@ import (
 	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/go-units"
 )
 
 // ContainerNotFound is a type for a missing container
@@ -71,8 +72,17 @@ type DockerContainerMetadata struct {
 	FinishedAt time.Time
 	// Health contains the result of a container health check
 	Health apicontainer.HealthStatus
+	// HealthLog contains the results of the last container health checks, oldest first
+	HealthLog []apicontainer.HealthCheckResult
 	// NetworkMode denotes the network mode in which the container is started
 	NetworkMode string
+	// Runtime is the OCI runtime docker runs the container with
+	Runtime string
+	// Ulimits are the ulimits docker runs the container with
+	Ulimits []*units.Ulimit
+	// EffectiveResources are the cpu and memory limits docker runs the container with,
+	// and its cgroup
+	EffectiveResources *apicontainer.EffectiveResources
 	// NetworksUnsafe denotes the Docker Network Settings in the container
 	NetworkSettings *types.NetworkSettings
 }
@@ -0,0 +1,151 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	// credentialHelperPrefix is the prefix of the binary name of every docker
+	// credential helper
+	credentialHelperPrefix = "docker-credential-"
+	// credentialHelperTimeout is how long a credential helper is given to
+	// return credentials
+	credentialHelperTimeout = 30 * time.Second
+	// credentialHelperTokenUsername is the username returned by credential
+	// helpers when the secret is an identity token rather than a password
+	credentialHelperTokenUsername = "<token>"
+	// credentialHelperNotFound is the output of credential helpers that do not
+	// have credentials for the server
+	credentialHelperNotFound = "credentials not found in native keychain"
+)
+
+// credentialHelperOutput is the response of the credential helper "get"
+// command, see https://github.com/docker/docker-credential-helpers
+type credentialHelperOutput struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// runCredentialHelperFunc runs the "get" command of a credential helper for
+// the server and returns its output
+type runCredentialHelperFunc func(helper string, serverURL string) ([]byte, error)
+
+type credentialHelperAuthProvider struct {
+	// helpers maps registry hostnames to credential helper names
+	helpers  map[string]string
+	fallback DockerAuthProvider
+	run      runCredentialHelperFunc
+}
+
+// NewCredentialHelperAuthProvider returns a DockerAuthProvider that gets the
+// credentials for the registries in helpers from docker credential helpers,
+// and the credentials for every other registry from the fallback provider
+func NewCredentialHelperAuthProvider(helpers map[string]string, fallback DockerAuthProvider) DockerAuthProvider {
+	normalizedHelpers := make(map[string]string)
+	for registry, helper := range helpers {
+		registry = stripRegistrySchema(registry)
+		if isDockerhubHostname(strings.TrimSuffix(registry, "/v1/")) {
+			registry = dockerRegistryKey
+		}
+		normalizedHelpers[registry] = helper
+	}
+	return &credentialHelperAuthProvider{
+		helpers:  normalizedHelpers,
+		fallback: fallback,
+		run:      runCredentialHelper,
+	}
+}
+
+// GetAuthconfig retrieves the auth configuration for the image's registry
+func (authProvider *credentialHelperAuthProvider) GetAuthconfig(image string,
+	registryAuthData *apicontainer.RegistryAuthenticationData) (types.AuthConfig, error) {
+
+	repository, _ := utils.ParseRepositoryTag(image)
+	indexName, _ := splitReposName(repository)
+	registry, serverURL := indexName, indexName
+	if isDockerhubHostname(indexName) {
+		registry, serverURL = dockerRegistryKey, "https://"+dockerRegistryKey
+	}
+
+	helper, ok := authProvider.helpers[registry]
+	if !ok {
+		return authProvider.fallback.GetAuthconfig(image, registryAuthData)
+	}
+
+	seelog.Debugf("Getting credentials for registry %s from docker credential helper %s", registry, helper)
+	output, err := authProvider.run(helper, serverURL)
+	if err != nil {
+		if strings.Contains(string(output), credentialHelperNotFound) {
+			seelog.Infof("Docker credential helper %s has no credentials for registry %s, pulling without credentials",
+				helper, registry)
+			return types.AuthConfig{}, nil
+		}
+		return types.AuthConfig{}, fmt.Errorf("dockerauth: docker credential helper %s failed for registry %s: %v",
+			helper, registry, err)
+	}
+
+	var credentials credentialHelperOutput
+	if err := json.Unmarshal(output, &credentials); err != nil {
+		return types.AuthConfig{}, fmt.Errorf("dockerauth: unable to parse output of docker credential helper %s: %v",
+			helper, err)
+	}
+
+	if credentials.Username == credentialHelperTokenUsername {
+		return types.AuthConfig{
+			IdentityToken: credentials.Secret,
+			ServerAddress: serverURL,
+		}, nil
+	}
+	return types.AuthConfig{
+		Username:      credentials.Username,
+		Password:      credentials.Secret,
+		ServerAddress: serverURL,
+	}, nil
+}
+
+// runCredentialHelper runs docker-credential-<helper> get, which reads the
+// server url from stdin and writes the credentials to stdout. On failure, the
+// output holds the error message of the helper
+func runCredentialHelper(helper string, serverURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialHelperTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, credentialHelperPrefix+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stdout.String() + " " + stderr.String())
+		if message != "" {
+			err = fmt.Errorf("%v: %s", err, message)
+		}
+		return []byte(message), err
+	}
+	return stdout.Bytes(), nil
+}
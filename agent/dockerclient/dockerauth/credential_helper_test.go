@@ -0,0 +1,139 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCredentialHelperProvider(helpers map[string]string, run runCredentialHelperFunc) DockerAuthProvider {
+	fallback := NewDockerAuthProvider("docker", []byte(`{"fallback.example.com":{"username":"static","password":"pass"}}`))
+	provider := NewCredentialHelperAuthProvider(helpers, fallback)
+	provider.(*credentialHelperAuthProvider).run = run
+	return provider
+}
+
+func TestCredentialHelperGetAuthconfig(t *testing.T) {
+	var helperName, helperServerURL string
+	provider := newTestCredentialHelperProvider(map[string]string{
+		"https://gcr.io": "gcr",
+	}, func(helper string, serverURL string) ([]byte, error) {
+		helperName, helperServerURL = helper, serverURL
+		return []byte(`{"ServerURL":"gcr.io","Username":"user","Secret":"secret"}`), nil
+	})
+
+	authConfig, err := provider.GetAuthconfig("gcr.io/project/image:tag", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "gcr", helperName)
+	assert.Equal(t, "gcr.io", helperServerURL)
+	assert.Equal(t, types.AuthConfig{
+		Username:      "user",
+		Password:      "secret",
+		ServerAddress: "gcr.io",
+	}, authConfig)
+}
+
+func TestCredentialHelperGetAuthconfigIdentityToken(t *testing.T) {
+	provider := newTestCredentialHelperProvider(map[string]string{
+		"registry.example.com": "example",
+	}, func(helper string, serverURL string) ([]byte, error) {
+		return []byte(`{"ServerURL":"registry.example.com","Username":"<token>","Secret":"identity"}`), nil
+	})
+
+	authConfig, err := provider.GetAuthconfig("registry.example.com/image", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "identity", authConfig.IdentityToken)
+	assert.Empty(t, authConfig.Username)
+	assert.Empty(t, authConfig.Password)
+}
+
+func TestCredentialHelperGetAuthconfigDockerHub(t *testing.T) {
+	var helperServerURL string
+	provider := newTestCredentialHelperProvider(map[string]string{
+		"docker.io": "desktop",
+	}, func(helper string, serverURL string) ([]byte, error) {
+		helperServerURL = serverURL
+		return []byte(`{"Username":"user","Secret":"secret"}`), nil
+	})
+
+	authConfig, err := provider.GetAuthconfig("library/busybox:latest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://index.docker.io/v1/", helperServerURL)
+	assert.Equal(t, "user", authConfig.Username)
+}
+
+func TestCredentialHelperGetAuthconfigFallback(t *testing.T) {
+	provider := newTestCredentialHelperProvider(map[string]string{
+		"gcr.io": "gcr",
+	}, func(helper string, serverURL string) ([]byte, error) {
+		t.Fatal("credential helper should not be run for registries without a helper")
+		return nil, nil
+	})
+
+	authConfig, err := provider.GetAuthconfig("fallback.example.com/image", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "static", authConfig.Username)
+}
+
+func TestCredentialHelperGetAuthconfigErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		output      string
+		err         error
+		expectError bool
+	}{
+		{
+			name:        "helper fails",
+			output:      "some failure",
+			err:         errors.New("exit status 1"),
+			expectError: true,
+		},
+		{
+			name:        "credentials not found",
+			output:      "credentials not found in native keychain",
+			err:         errors.New("exit status 1"),
+			expectError: false,
+		},
+		{
+			name:        "malformed output",
+			output:      "not json",
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			provider := newTestCredentialHelperProvider(map[string]string{
+				"gcr.io": "gcr",
+			}, func(helper string, serverURL string) ([]byte, error) {
+				return []byte(testCase.output), testCase.err
+			})
+
+			authConfig, err := provider.GetAuthconfig("gcr.io/project/image", nil)
+			if testCase.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, types.AuthConfig{}, authConfig)
+		})
+	}
+}
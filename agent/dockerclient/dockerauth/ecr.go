@@ -16,6 +16,7 @@ package dockerauth
 import (
 	"encoding/base64"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -24,8 +25,10 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/ecr"
 	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	log "github.com/cihub/seelog"
 	"github.com/docker/docker/api/types"
 )
@@ -35,11 +38,15 @@ type cacheKey struct {
 	roleARN          string
 	registryID       string
 	endpointOverride string
+	// repository is only set for pulls whose tokens must not be shared across
+	// repositories, see isRepositoryScoped
+	repository string
 }
 
 type ecrAuthProvider struct {
 	tokenCache async.Cache
 	factory    ecr.ECRFactory
+	refresher  *ECRTokenRefresher
 }
 
 const (
@@ -52,6 +59,9 @@ const (
 
 // String formats the cachKey as a string
 func (key *cacheKey) String() string {
+	if key.repository != "" {
+		return fmt.Sprintf("%s-%s-%s-%s-%s", key.roleARN, key.region, key.registryID, key.endpointOverride, key.repository)
+	}
 	return fmt.Sprintf("%s-%s-%s-%s", key.roleARN, key.region, key.registryID, key.endpointOverride)
 }
 
@@ -64,6 +74,18 @@ func NewECRAuthProvider(ecrFactory ecr.ECRFactory, cache async.Cache) DockerAuth
 	}
 }
 
+// NewECRAuthProviderWithRefresher returns an ECR DockerAuthProvider that hands
+// the tokens it fetches to the refresher, so that they are renewed in the
+// background before they expire
+func NewECRAuthProviderWithRefresher(ecrFactory ecr.ECRFactory, cache async.Cache,
+	refresher *ECRTokenRefresher) DockerAuthProvider {
+	return &ecrAuthProvider{
+		tokenCache: cache,
+		factory:    ecrFactory,
+		refresher:  refresher,
+	}
+}
+
 // GetAuthconfig retrieves the correct auth configuration for the given repository
 func (authProvider *ecrAuthProvider) GetAuthconfig(image string,
 	registryAuthData *apicontainer.RegistryAuthenticationData) (types.AuthConfig, error) {
@@ -93,6 +115,17 @@ func (authProvider *ecrAuthProvider) GetAuthconfig(image string,
 		key.roleARN = authData.GetPullCredentials().RoleArn
 	}
 
+	// ECR tokens are scoped to the registry, and a cross account pull is
+	// authorized by the policy of the repository when the token is used.
+	// The cache entries of cross account pulls are kept per repository
+	// anyway, so that a repository's token is only ever fetched with the
+	// credentials of a pull from that repository, and so that expiring or
+	// refreshing it doesn't affect the pulls of other repositories in the
+	// same foreign registry
+	if isRepositoryScoped(key) {
+		key.repository = repositoryName(image)
+	}
+
 	// Try to get the auth config from cache
 	auth := authProvider.getAuthConfigFromCache(key)
 	if auth != nil {
@@ -156,11 +189,42 @@ func (authProvider *ecrAuthProvider) getAuthConfigFromECR(image string, key cach
 
 		// Cache the new token
 		authProvider.tokenCache.Set(key.String(), ecrAuthData)
+		if authProvider.refresher != nil {
+			authProvider.refresher.track(image, key, authData)
+		}
 		return extractToken(ecrAuthData)
 	}
 	return types.AuthConfig{}, fmt.Errorf("ecr auth: AuthorizationData is malformed for %s", image)
 }
 
+// accountIDRegex matches the ids of ECR registries, which are the ids of the
+// accounts that own them
+var accountIDRegex = regexp.MustCompile(`^[0-9]{12}$`)
+
+// isRepositoryScoped returns true if the pull is made with a role from an account
+// other than the one that owns the registry
+func isRepositoryScoped(key cacheKey) bool {
+	if key.roleARN == "" || !accountIDRegex.MatchString(key.registryID) {
+		return false
+	}
+	roleARN, err := arn.Parse(key.roleARN)
+	if err != nil {
+		return false
+	}
+	return roleARN.AccountID != key.registryID
+}
+
+// repositoryName returns the name of the image's repository, without the
+// registry, tag or digest
+func repositoryName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	repository, _ := utils.ParseRepositoryTag(image)
+	_, remoteName := splitReposName(repository)
+	return remoteName
+}
+
 func extractToken(authData *ecrapi.AuthorizationData) (types.AuthConfig, error) {
 	decodedToken, err := base64.StdEncoding.DecodeString(aws.StringValue(authData.AuthorizationToken))
 	if err != nil {
@@ -177,11 +241,20 @@ func extractToken(authData *ecrapi.AuthorizationData) (types.AuthConfig, error)
 // IsTokenValid checks the token is still within it's expiration window. We early expire to allow
 // for timing in calls and add jitter to avoid refreshing all of the tokens at once.
 func (authProvider *ecrAuthProvider) IsTokenValid(authData *ecrapi.AuthorizationData) bool {
-	if authData == nil || authData.ExpiresAt == nil {
+	if authData == nil {
+		return false
+	}
+	return isExpiryValid(authData.ExpiresAt)
+}
+
+// isExpiryValid checks that a token expiring at expiresAt is still within its
+// expiration window, with the same early, jittered expiry as IsTokenValid
+func isExpiryValid(expiresAt *time.Time) bool {
+	if expiresAt == nil {
 		return false
 	}
 
-	refreshTime := aws.TimeValue(authData.ExpiresAt).
+	refreshTime := aws.TimeValue(expiresAt).
 		Add(-1 * retry.AddJitter(MinimumJitterDuration, MinimumJitterDuration))
 
 	return time.Now().Before(refreshTime)
@@ -0,0 +1,171 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/async"
+	"github.com/aws/amazon-ecs-agent/agent/ecr"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecrpublic"
+	log "github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	// ecrPublicCacheKey is the key of the ECR Public token in the token cache.
+	// There is a single public registry and the token is always fetched with
+	// the instance credentials, so one token serves every pull
+	ecrPublicCacheKey = "ecr-public"
+	// ecrPublicFailureCacheKey is the key of the last failure to get the ECR
+	// Public token in the token cache
+	ecrPublicFailureCacheKey = "ecr-public-failure"
+	// ecrPublicFailureTTL is how long a failure to get the ECR Public token is
+	// cached, so that the pulls started meanwhile don't call the API again
+	ecrPublicFailureTTL = time.Minute
+)
+
+// ecrPublicTokenFailure is the error of the last call to get the ECR Public
+// token, which is returned until retryAfter
+type ecrPublicTokenFailure struct {
+	err        error
+	retryAfter time.Time
+}
+
+type ecrPublicAuthProvider struct {
+	tokenCache async.Cache
+	factory    ecr.ECRPublicFactory
+}
+
+// NewECRPublicAuthProvider returns a DockerAuthProvider that can retrieve
+// credentials for pulling from the Amazon ECR Public Gallery. Authenticated
+// pulls are not subject to the rate limits of anonymous pulls
+func NewECRPublicAuthProvider(ecrPublicFactory ecr.ECRPublicFactory, cache async.Cache) DockerAuthProvider {
+	return &ecrPublicAuthProvider{
+		tokenCache: cache,
+		factory:    ecrPublicFactory,
+	}
+}
+
+// IsECRPublicImage returns true if the image is hosted in the ECR Public Gallery
+func IsECRPublicImage(image string) bool {
+	repository, _ := utils.ParseRepositoryTag(image)
+	indexName, _ := splitReposName(repository)
+	return indexName == ecr.ECRPublicRegistry
+}
+
+// GetAuthconfig retrieves the auth configuration for the public registry. The
+// registry auth data is unused, as public images are not pulled with task
+// specific credentials
+func (authProvider *ecrPublicAuthProvider) GetAuthconfig(image string,
+	registryAuthData *apicontainer.RegistryAuthenticationData) (types.AuthConfig, error) {
+
+	if !IsECRPublicImage(image) {
+		return types.AuthConfig{}, fmt.Errorf("dockerauth: %s is not an ECR Public image", image)
+	}
+
+	if auth := authProvider.getAuthConfigFromCache(); auth != nil {
+		return *auth, nil
+	}
+	if err := authProvider.getFailureFromCache(); err != nil {
+		return types.AuthConfig{}, err
+	}
+
+	auth, err := authProvider.getAuthConfigFromECRPublic(image)
+	if err != nil {
+		authProvider.tokenCache.Set(ecrPublicFailureCacheKey, &ecrPublicTokenFailure{
+			err:        err,
+			retryAfter: time.Now().Add(ecrPublicFailureTTL),
+		})
+	}
+	return auth, err
+}
+
+// getFailureFromCache returns the error of the last call to get the token if
+// it failed less than ecrPublicFailureTTL ago
+func (authProvider *ecrPublicAuthProvider) getFailureFromCache() error {
+	value, ok := authProvider.tokenCache.Get(ecrPublicFailureCacheKey)
+	if !ok {
+		return nil
+	}
+	failure, ok := value.(*ecrPublicTokenFailure)
+	if !ok || !time.Now().Before(failure.retryAfter) {
+		authProvider.tokenCache.Delete(ecrPublicFailureCacheKey)
+		return nil
+	}
+	return fmt.Errorf("ecr public auth: not retrying before %s: %v", failure.retryAfter.Format(time.RFC3339), failure.err)
+}
+
+// getAuthConfigFromCache retrieves the token from cache
+func (authProvider *ecrPublicAuthProvider) getAuthConfigFromCache() *types.AuthConfig {
+	token, ok := authProvider.tokenCache.Get(ecrPublicCacheKey)
+	if !ok {
+		return nil
+	}
+
+	cachedToken, ok := token.(*ecrpublic.AuthorizationData)
+	if !ok || !isExpiryValid(cachedToken.ExpiresAt) {
+		// Remove invalid token from cache
+		authProvider.tokenCache.Delete(ecrPublicCacheKey)
+		return nil
+	}
+
+	auth, err := extractECRPublicToken(cachedToken)
+	if err != nil {
+		log.Errorf("Extract docker auth for ECR Public from cache failed, err: %v", err)
+		authProvider.tokenCache.Delete(ecrPublicCacheKey)
+		return nil
+	}
+	return &auth
+}
+
+// getAuthConfigFromECRPublic calls the ECR Public API to get docker auth config
+func (authProvider *ecrPublicAuthProvider) getAuthConfigFromECRPublic(image string) (types.AuthConfig, error) {
+	client := authProvider.factory.GetClient()
+
+	log.Debugf("Calling ECR Public GetAuthorizationToken for %s", image)
+	authData, err := client.GetAuthorizationToken()
+	if err != nil {
+		return types.AuthConfig{}, err
+	}
+
+	auth, err := extractECRPublicToken(authData)
+	if err != nil {
+		return types.AuthConfig{}, fmt.Errorf("ecr public auth: AuthorizationData is malformed for %s: %v", image, err)
+	}
+	authProvider.tokenCache.Set(ecrPublicCacheKey, authData)
+	return auth, nil
+}
+
+func extractECRPublicToken(authData *ecrpublic.AuthorizationData) (types.AuthConfig, error) {
+	decodedToken, err := base64.StdEncoding.DecodeString(aws.StringValue(authData.AuthorizationToken))
+	if err != nil {
+		return types.AuthConfig{}, err
+	}
+	parts := strings.SplitN(string(decodedToken), ":", 2)
+	if len(parts) != 2 {
+		return types.AuthConfig{}, fmt.Errorf("unexpected token format")
+	}
+	return types.AuthConfig{
+		Username:      parts[0],
+		Password:      parts[1],
+		ServerAddress: ecr.ECRPublicRegistry,
+	}, nil
+}
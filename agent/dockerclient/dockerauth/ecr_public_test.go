@@ -0,0 +1,166 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/async"
+	mock_ecr "github.com/aws/amazon-ecs-agent/agent/ecr/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecrpublic"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testECRPublicImage = "public.ecr.aws/amazonlinux/amazonlinux:latest"
+
+func TestIsECRPublicImage(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected bool
+	}{
+		{testECRPublicImage, true},
+		{"public.ecr.aws/nginx/nginx", true},
+		{"public.ecr.aws.example.com/nginx", false},
+		{"123456789012.dkr.ecr.us-west-2.amazonaws.com/myimage:latest", false},
+		{"busybox", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.image, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, IsECRPublicImage(testCase.image))
+		})
+	}
+}
+
+func TestECRPublicGetAuthConfigSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_ecr.NewMockECRPublicClient(ctrl)
+	factory := mock_ecr.NewMockECRPublicFactory(ctrl)
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+
+	provider := NewECRPublicAuthProvider(factory, cache)
+
+	factory.EXPECT().GetClient().Return(client)
+	client.EXPECT().GetAuthorizationToken().Return(&ecrpublic.AuthorizationData{
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:password"))),
+		ExpiresAt:          aws.Time(time.Now().Add(12 * time.Hour)),
+	}, nil)
+
+	authConfig, err := provider.GetAuthconfig(testECRPublicImage, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "AWS", authConfig.Username)
+	assert.Equal(t, "password", authConfig.Password)
+	assert.Equal(t, "public.ecr.aws", authConfig.ServerAddress)
+
+	// The second call is served from the cache
+	authConfig, err = provider.GetAuthconfig(testECRPublicImage, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "password", authConfig.Password)
+}
+
+func TestECRPublicGetAuthConfigExpiredCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_ecr.NewMockECRPublicClient(ctrl)
+	factory := mock_ecr.NewMockECRPublicFactory(ctrl)
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+	cache.Set(ecrPublicCacheKey, &ecrpublic.AuthorizationData{
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:expired"))),
+		ExpiresAt:          aws.Time(time.Now().Add(time.Minute)),
+	})
+
+	provider := NewECRPublicAuthProvider(factory, cache)
+
+	factory.EXPECT().GetClient().Return(client)
+	client.EXPECT().GetAuthorizationToken().Return(&ecrpublic.AuthorizationData{
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:fresh"))),
+		ExpiresAt:          aws.Time(time.Now().Add(12 * time.Hour)),
+	}, nil)
+
+	authConfig, err := provider.GetAuthconfig(testECRPublicImage, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", authConfig.Password)
+}
+
+func TestECRPublicGetAuthConfigErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_ecr.NewMockECRPublicClient(ctrl)
+	factory := mock_ecr.NewMockECRPublicFactory(ctrl)
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+
+	provider := NewECRPublicAuthProvider(factory, cache)
+
+	_, err := provider.GetAuthconfig("busybox", nil)
+	assert.Error(t, err, "expected error for an image outside of the public registry")
+
+	factory.EXPECT().GetClient().Return(client).Times(2)
+	client.EXPECT().GetAuthorizationToken().Return(nil, errors.New("some error"))
+	_, err = provider.GetAuthconfig(testECRPublicImage, nil)
+	assert.Error(t, err)
+	cache.Delete(ecrPublicFailureCacheKey)
+
+	client.EXPECT().GetAuthorizationToken().Return(&ecrpublic.AuthorizationData{
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("malformed"))),
+	}, nil)
+	_, err = provider.GetAuthconfig(testECRPublicImage, nil)
+	assert.Error(t, err)
+	_, ok := cache.Get(ecrPublicCacheKey)
+	assert.False(t, ok, "malformed token should not be cached")
+}
+
+func TestECRPublicGetAuthConfigCachesFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_ecr.NewMockECRPublicClient(ctrl)
+	factory := mock_ecr.NewMockECRPublicFactory(ctrl)
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+
+	provider := NewECRPublicAuthProvider(factory, cache)
+
+	factory.EXPECT().GetClient().Return(client)
+	client.EXPECT().GetAuthorizationToken().Return(nil, errors.New("some error"))
+	_, err := provider.GetAuthconfig(testECRPublicImage, nil)
+	assert.Error(t, err)
+
+	// The failure is returned without calling the API again
+	_, err = provider.GetAuthconfig(testECRPublicImage, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "some error")
+
+	// The API is called again once the failure expired
+	cache.Set(ecrPublicFailureCacheKey, &ecrPublicTokenFailure{
+		err:        errors.New("some error"),
+		retryAfter: time.Now().Add(-time.Second),
+	})
+	factory.EXPECT().GetClient().Return(client)
+	client.EXPECT().GetAuthorizationToken().Return(&ecrpublic.AuthorizationData{
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:password"))),
+		ExpiresAt:          aws.Time(time.Now().Add(12 * time.Hour)),
+	}, nil)
+	authConfig, err := provider.GetAuthconfig(testECRPublicImage, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "password", authConfig.Password)
+	_, ok := cache.Get(ecrPublicFailureCacheKey)
+	assert.False(t, ok, "expired failure should be removed from the cache")
+}
@@ -517,3 +517,117 @@ func TestExtractECRTokenError(t *testing.T) {
 	assert.Equal(t, username, authconfig.Username)
 	assert.Equal(t, password, authconfig.Password)
 }
+
+func TestAuthorizationTokenCacheCrossAccountScopedByRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+	ecrClient := mock_ecr.NewMockECRClient(ctrl)
+	mockCache := mock_async.NewMockCache(ctrl)
+
+	provider := ecrAuthProvider{
+		factory:    factory,
+		tokenCache: mockCache,
+	}
+
+	proxyEndpoint := "210987654321.dkr.ecr.us-west-2.amazonaws.com"
+	authData := &apicontainer.ECRAuthData{
+		Region:     "us-west-2",
+		RegistryID: "210987654321",
+	}
+	authData.SetPullCredentials(credentials.IAMRoleCredentials{
+		RoleArn: "arn:aws:iam::123456789012:role/test",
+	})
+	registryAuthData := &apicontainer.RegistryAuthenticationData{
+		ECRAuthData: authData,
+	}
+	testAuthData := &ecrapi.AuthorizationData{
+		ProxyEndpoint:      aws.String(proxyEndpointScheme + proxyEndpoint),
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("user:pass"))),
+		ExpiresAt:          aws.Time(time.Now().Add(12 * time.Hour)),
+	}
+
+	key := cacheKey{
+		roleARN:    authData.GetPullCredentials().RoleArn,
+		region:     authData.Region,
+		registryID: authData.RegistryID,
+		repository: "team/myimage",
+	}
+
+	mockCache.EXPECT().Get(key.String()).Return(nil, false)
+	factory.EXPECT().GetClient(authData).Return(ecrClient, nil)
+	ecrClient.EXPECT().GetAuthorizationToken(authData.RegistryID).Return(testAuthData, nil)
+	mockCache.EXPECT().Set(key.String(), testAuthData)
+
+	_, err := provider.GetAuthconfig(proxyEndpoint+"/team/myimage@sha256:abcdef", registryAuthData)
+	assert.NoError(t, err)
+}
+
+func TestAuthorizationTokenCacheSameAccountSharedAcrossRepositories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+	mockCache := mock_async.NewMockCache(ctrl)
+
+	provider := ecrAuthProvider{
+		factory:    factory,
+		tokenCache: mockCache,
+	}
+
+	proxyEndpoint := "123456789012.dkr.ecr.us-west-2.amazonaws.com"
+	authData := &apicontainer.ECRAuthData{
+		Region:     "us-west-2",
+		RegistryID: "123456789012",
+	}
+	authData.SetPullCredentials(credentials.IAMRoleCredentials{
+		RoleArn: "arn:aws:iam::123456789012:role/test",
+	})
+	registryAuthData := &apicontainer.RegistryAuthenticationData{
+		ECRAuthData: authData,
+	}
+	testAuthData := &ecrapi.AuthorizationData{
+		ProxyEndpoint:      aws.String(proxyEndpointScheme + proxyEndpoint),
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("user:pass"))),
+		ExpiresAt:          aws.Time(time.Now().Add(12 * time.Hour)),
+	}
+
+	key := cacheKey{
+		roleARN:    authData.GetPullCredentials().RoleArn,
+		region:     authData.Region,
+		registryID: authData.RegistryID,
+	}
+
+	// both repositories of the registry are served by the same cache entry
+	mockCache.EXPECT().Get(key.String()).Return(testAuthData, true).Times(2)
+
+	_, err := provider.GetAuthconfig(proxyEndpoint+"/team/myimage", registryAuthData)
+	assert.NoError(t, err)
+	_, err = provider.GetAuthconfig(proxyEndpoint+"/team/otherimage", registryAuthData)
+	assert.NoError(t, err)
+}
+
+func TestIsRepositoryScoped(t *testing.T) {
+	testCases := []struct {
+		name     string
+		key      cacheKey
+		expected bool
+	}{
+		{"instance credentials", cacheKey{registryID: "123456789012"}, false},
+		{"same account", cacheKey{registryID: "123456789012", roleARN: "arn:aws:iam::123456789012:role/test"}, false},
+		{"cross account", cacheKey{registryID: "210987654321", roleARN: "arn:aws:iam::123456789012:role/test"}, true},
+		{"malformed role arn", cacheKey{registryID: "210987654321", roleARN: "not-an-arn"}, false},
+		{"malformed registry id", cacheKey{registryID: "0123456789012", roleARN: "arn:aws:iam::123456789012:role/test"}, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, isRepositoryScoped(testCase.key))
+		})
+	}
+}
+
+func TestRepositoryName(t *testing.T) {
+	assert.Equal(t, "myimage", repositoryName("123456789012.dkr.ecr.us-west-2.amazonaws.com/myimage"))
+	assert.Equal(t, "team/myimage", repositoryName("123456789012.dkr.ecr.us-west-2.amazonaws.com/team/myimage:latest"))
+	assert.Equal(t, "team/myimage", repositoryName("123456789012.dkr.ecr.us-west-2.amazonaws.com/team/myimage@sha256:abcdef"))
+}
@@ -0,0 +1,185 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/async"
+	"github.com/aws/amazon-ecs-agent/agent/ecr"
+	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	"github.com/aws/aws-sdk-go/aws"
+	log "github.com/cihub/seelog"
+)
+
+const (
+	// ecrTokenRefreshInterval is how often the refresher looks for cached
+	// tokens that are about to expire
+	ecrTokenRefreshInterval = 5 * time.Minute
+)
+
+// ECRTokenRefresher renews the ECR auth tokens held in the token cache before
+// they expire, so that image pulls are not delayed by, or fail because of,
+// calls to ECR.GetAuthorizationToken
+type ECRTokenRefresher struct {
+	provider *ecrAuthProvider
+	window   time.Duration
+	interval time.Duration
+	// tokens holds the data needed to fetch each cached token again, keyed
+	// by the token's cache key
+	tokens map[string]refreshableToken
+	lock   sync.Mutex
+}
+
+// refreshableToken is the data a token was originally fetched with. The pull
+// credentials of the task are only kept until they expire, or until the task
+// that the token was fetched for stops
+type refreshableToken struct {
+	image    string
+	key      cacheKey
+	authData *apicontainer.ECRAuthData
+}
+
+// NewECRTokenRefresher returns a refresher for the tokens in the cache, which
+// renews them once they are within window of expiring
+func NewECRTokenRefresher(ecrFactory ecr.ECRFactory, cache async.Cache, window time.Duration) *ECRTokenRefresher {
+	return &ECRTokenRefresher{
+		provider: &ecrAuthProvider{
+			tokenCache: cache,
+			factory:    ecrFactory,
+		},
+		window:   window,
+		interval: ecrTokenRefreshInterval,
+		tokens:   make(map[string]refreshableToken),
+	}
+}
+
+// Start refreshes the tracked tokens periodically until the context is canceled
+func (refresher *ECRTokenRefresher) Start(ctx context.Context) {
+	ticker := time.NewTicker(refresher.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresher.refreshExpiringTokens()
+		}
+	}
+}
+
+// ForgetCredentials stops refreshing the tokens that were fetched with the
+// given task credentials. It's called once the task that the credentials
+// belong to has stopped
+func (refresher *ECRTokenRefresher) ForgetCredentials(credentialsID string) {
+	if credentialsID == "" {
+		return
+	}
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	for tokenKey, token := range refresher.tokens {
+		if token.authData.GetPullCredentials().CredentialsID == credentialsID {
+			delete(refresher.tokens, tokenKey)
+		}
+	}
+}
+
+// track records the data a token was fetched with, so that it can be
+// fetched again in the background. The auth data is copied, as the engine
+// clears the pull credentials of a container once its image is pulled
+func (refresher *ECRTokenRefresher) track(image string, key cacheKey, authData *apicontainer.ECRAuthData) {
+	trackedAuthData := &apicontainer.ECRAuthData{
+		EndpointOverride: authData.EndpointOverride,
+		Region:           authData.Region,
+		RegistryID:       authData.RegistryID,
+		UseExecutionRole: authData.UseExecutionRole,
+	}
+	trackedAuthData.SetPullCredentials(authData.GetPullCredentials())
+
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	refresher.tokens[key.String()] = refreshableToken{
+		image:    image,
+		key:      key,
+		authData: trackedAuthData,
+	}
+}
+
+func (refresher *ECRTokenRefresher) untrack(key cacheKey) {
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	delete(refresher.tokens, key.String())
+}
+
+// refreshExpiringTokens fetches a new token for every tracked token that
+// expires within the refresh window. Tokens that are no longer cached, whose
+// pull credentials have expired, or that cannot be refreshed, stop being
+// tracked; the next pull that needs them fetches and tracks them again
+func (refresher *ECRTokenRefresher) refreshExpiringTokens() {
+	refresher.lock.Lock()
+	tokens := make([]refreshableToken, 0, len(refresher.tokens))
+	for _, token := range refresher.tokens {
+		tokens = append(tokens, token)
+	}
+	refresher.lock.Unlock()
+
+	for _, token := range tokens {
+		if token.authData.UseExecutionRole && pullCredentialsExpired(token.authData) {
+			refresher.untrack(token.key)
+			continue
+		}
+		cached, ok := refresher.provider.tokenCache.Get(token.key.String())
+		if !ok {
+			refresher.untrack(token.key)
+			continue
+		}
+		cachedToken, ok := cached.(*ecrapi.AuthorizationData)
+		if !ok || !refresher.isExpiring(cachedToken) {
+			continue
+		}
+
+		log.Debugf("Refreshing ECR auth token for registry %s in region %s ahead of its expiry",
+			token.key.registryID, token.key.region)
+		if _, err := refresher.provider.getAuthConfigFromECR(token.image, token.key, token.authData); err != nil {
+			log.Warnf("Unable to refresh ECR auth token for registry %s in region %s: %v",
+				token.key.registryID, token.key.region, err)
+			refresher.untrack(token.key)
+		}
+	}
+}
+
+// isExpiring returns true if the token expires within the refresh window
+func (refresher *ECRTokenRefresher) isExpiring(authData *ecrapi.AuthorizationData) bool {
+	if authData.ExpiresAt == nil {
+		return false
+	}
+	return time.Now().Add(refresher.window).After(aws.TimeValue(authData.ExpiresAt))
+}
+
+// pullCredentialsExpired returns true if the task credentials a token was
+// fetched with have expired, or if their expiration is unknown
+func pullCredentialsExpired(authData *apicontainer.ECRAuthData) bool {
+	pullCredentials := authData.GetPullCredentials()
+	expiration, err := pullCredentials.ExpirationTime()
+	if err != nil {
+		return true
+	}
+	return !time.Now().Before(expiration)
+}
@@ -0,0 +1,202 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/async"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	mock_ecr "github.com/aws/amazon-ecs-agent/agent/ecr/mocks"
+	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRefreshWindow = 2 * time.Hour
+
+func testECRAuthorizationData(expiresAt time.Time) *ecrapi.AuthorizationData {
+	return &ecrapi.AuthorizationData{
+		ProxyEndpoint:      aws.String(proxyEndpointScheme + "proxy"),
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("user:pass"))),
+		ExpiresAt:          aws.Time(expiresAt),
+	}
+}
+
+func TestECRTokenRefresherTracksFetchedTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_ecr.NewMockECRClient(ctrl)
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+	refresher := NewECRTokenRefresher(factory, cache, testRefreshWindow)
+	provider := NewECRAuthProviderWithRefresher(factory, cache, refresher)
+
+	authData := &apicontainer.ECRAuthData{
+		Region:     "us-west-2",
+		RegistryID: "0123456789012",
+	}
+	factory.EXPECT().GetClient(authData).Return(client, nil)
+	client.EXPECT().GetAuthorizationToken(authData.RegistryID).Return(
+		testECRAuthorizationData(time.Now().Add(12*time.Hour)), nil)
+
+	_, err := provider.GetAuthconfig("proxy/myimage", &apicontainer.RegistryAuthenticationData{
+		ECRAuthData: authData,
+	})
+	require.NoError(t, err)
+
+	key := cacheKey{region: authData.Region, registryID: authData.RegistryID}
+	token, ok := refresher.tokens[key.String()]
+	require.True(t, ok, "fetched token should be tracked")
+	assert.Equal(t, "proxy/myimage", token.image)
+	assert.Equal(t, authData, token.authData)
+}
+
+func TestECRTokenRefresherRefreshesExpiringTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_ecr.NewMockECRClient(ctrl)
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+	refresher := NewECRTokenRefresher(factory, cache, testRefreshWindow)
+
+	expiringAuthData := &apicontainer.ECRAuthData{Region: "us-west-2", RegistryID: "expiring"}
+	expiringKey := cacheKey{region: "us-west-2", registryID: "expiring"}
+	cache.Set(expiringKey.String(), testECRAuthorizationData(time.Now().Add(time.Hour)))
+	refresher.track("proxy/expiring", expiringKey, expiringAuthData)
+
+	freshAuthData := &apicontainer.ECRAuthData{Region: "us-west-2", RegistryID: "fresh"}
+	freshKey := cacheKey{region: "us-west-2", registryID: "fresh"}
+	cache.Set(freshKey.String(), testECRAuthorizationData(time.Now().Add(10*time.Hour)))
+	refresher.track("proxy/fresh", freshKey, freshAuthData)
+
+	refreshed := testECRAuthorizationData(time.Now().Add(12 * time.Hour))
+	factory.EXPECT().GetClient(expiringAuthData).Return(client, nil)
+	client.EXPECT().GetAuthorizationToken("expiring").Return(refreshed, nil)
+
+	refresher.refreshExpiringTokens()
+
+	cached, ok := cache.Get(expiringKey.String())
+	require.True(t, ok)
+	assert.Equal(t, refreshed, cached, "expiring token should have been replaced")
+	assert.Len(t, refresher.tokens, 2)
+}
+
+func TestECRTokenRefresherUntracksFailedAndEvictedTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_ecr.NewMockECRClient(ctrl)
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+	refresher := NewECRTokenRefresher(factory, cache, testRefreshWindow)
+
+	failingAuthData := &apicontainer.ECRAuthData{Region: "us-west-2", RegistryID: "failing"}
+	failingKey := cacheKey{region: "us-west-2", registryID: "failing"}
+	cache.Set(failingKey.String(), testECRAuthorizationData(time.Now().Add(time.Hour)))
+	refresher.track("proxy/failing", failingKey, failingAuthData)
+
+	evictedKey := cacheKey{region: "us-west-2", registryID: "evicted"}
+	refresher.track("proxy/evicted", evictedKey, &apicontainer.ECRAuthData{})
+
+	factory.EXPECT().GetClient(failingAuthData).Return(client, nil)
+	client.EXPECT().GetAuthorizationToken("failing").Return(nil, errors.New("some error"))
+
+	refresher.refreshExpiringTokens()
+
+	assert.Empty(t, refresher.tokens)
+}
+
+func TestECRTokenRefresherStopsOnContextCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+
+	refresher := NewECRTokenRefresher(factory, async.NewLRUCache(tokenCacheSize, tokenCacheTTL), testRefreshWindow)
+	refresher.interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		refresher.Start(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("refresher did not stop after the context was canceled")
+	}
+}
+
+func TestECRTokenRefresherKeepsPullCredentialsUntilTheyExpire(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+
+	cache := async.NewLRUCache(tokenCacheSize, tokenCacheTTL)
+	refresher := NewECRTokenRefresher(factory, cache, testRefreshWindow)
+
+	authData := &apicontainer.ECRAuthData{Region: "us-west-2", RegistryID: "expired", UseExecutionRole: true}
+	authData.SetPullCredentials(credentials.IAMRoleCredentials{
+		CredentialsID: "credentials-id",
+		Expiration:    time.Now().Add(-time.Minute).Format(time.RFC3339),
+	})
+	key := cacheKey{region: "us-west-2", roleARN: "role", registryID: "expired"}
+	cache.Set(key.String(), testECRAuthorizationData(time.Now().Add(time.Hour)))
+	refresher.track("proxy/expired", key, authData)
+
+	// The engine clears the pull credentials of the container once the image is pulled
+	authData.SetPullCredentials(credentials.IAMRoleCredentials{})
+	assert.Equal(t, "credentials-id", refresher.tokens[key.String()].authData.GetPullCredentials().CredentialsID)
+
+	// No token is fetched with the expired credentials
+	refresher.refreshExpiringTokens()
+	assert.Empty(t, refresher.tokens)
+}
+
+func TestECRTokenRefresherForgetCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	factory := mock_ecr.NewMockECRFactory(ctrl)
+
+	refresher := NewECRTokenRefresher(factory, async.NewLRUCache(tokenCacheSize, tokenCacheTTL), testRefreshWindow)
+
+	for _, id := range []string{"stopped", "running"} {
+		authData := &apicontainer.ECRAuthData{Region: "us-west-2", RegistryID: id, UseExecutionRole: true}
+		authData.SetPullCredentials(credentials.IAMRoleCredentials{
+			CredentialsID: id,
+			Expiration:    time.Now().Add(time.Hour).Format(time.RFC3339),
+		})
+		refresher.track("proxy/"+id, cacheKey{region: "us-west-2", roleARN: id, registryID: id}, authData)
+	}
+
+	refresher.ForgetCredentials("stopped")
+	require.Len(t, refresher.tokens, 1)
+	for _, token := range refresher.tokens {
+		assert.Equal(t, "running", token.authData.GetPullCredentials().CredentialsID)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/async"
+	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// tokenCacheKeySize is the size of the AES-256 key used to encrypt the
+	// persisted tokens
+	tokenCacheKeySize = 32
+	tokenCacheKeyMode = 0600
+)
+
+// ECRTokenStore persists ECR auth tokens, so that they survive agent restarts.
+// It is implemented by the agent data client
+type ECRTokenStore interface {
+	// SaveECRAuthToken saves an encrypted ECR auth token.
+	SaveECRAuthToken(string, []byte) error
+	// DeleteECRAuthToken deletes an ECR auth token.
+	DeleteECRAuthToken(string) error
+	// GetECRAuthTokens gets all the encrypted ECR auth tokens, keyed by their cache key.
+	GetECRAuthTokens() (map[string][]byte, error)
+}
+
+// PersistentTokenCache is a token cache that can write the ECR tokens it holds
+// through to a token store, encrypted with a key that is kept outside of the
+// store. Until Persist is called, it only holds the tokens in memory
+type PersistentTokenCache struct {
+	cache async.Cache
+	store ECRTokenStore
+	aead  cipher.AEAD
+	lock  sync.RWMutex
+}
+
+// NewPersistentTokenCache returns a token cache backed by cache, which does not
+// persist tokens until Persist is called
+func NewPersistentTokenCache(cache async.Cache) *PersistentTokenCache {
+	return &PersistentTokenCache{
+		cache: cache,
+	}
+}
+
+// Persist loads the tokens in the store that are still valid into the cache, and
+// persists every ECR token set in the cache from then on. The encryption key is
+// read from keyFile, and created if it does not exist yet
+func (tokenCache *PersistentTokenCache) Persist(store ECRTokenStore, keyFile string) error {
+	key, err := loadOrCreateTokenCacheKey(keyFile)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return errors.Wrap(err, "failed to create token cache cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return errors.Wrap(err, "failed to create token cache cipher")
+	}
+
+	tokenCache.lock.Lock()
+	defer tokenCache.lock.Unlock()
+
+	tokenCache.store = store
+	tokenCache.aead = aead
+	if err := tokenCache.loadUnsafe(); err != nil {
+		tokenCache.store = nil
+		tokenCache.aead = nil
+		return err
+	}
+	return nil
+}
+
+// Get fetches a value from the cache
+func (tokenCache *PersistentTokenCache) Get(key string) (async.Value, bool) {
+	return tokenCache.cache.Get(key)
+}
+
+// Set sets the value in the cache, and persists it if it's an ECR token
+func (tokenCache *PersistentTokenCache) Set(key string, value async.Value) {
+	tokenCache.cache.Set(key, value)
+
+	tokenCache.lock.RLock()
+	defer tokenCache.lock.RUnlock()
+
+	token, ok := value.(*ecrapi.AuthorizationData)
+	if tokenCache.store == nil || !ok {
+		return
+	}
+	encrypted, err := tokenCache.encrypt(token)
+	if err != nil {
+		log.Warnf("Unable to encrypt ECR auth token for persistence: %v", err)
+		return
+	}
+	if err := tokenCache.store.SaveECRAuthToken(key, encrypted); err != nil {
+		log.Warnf("Unable to persist ECR auth token: %v", err)
+	}
+}
+
+// Delete deletes the value from the cache and from the store
+func (tokenCache *PersistentTokenCache) Delete(key string) {
+	tokenCache.cache.Delete(key)
+
+	tokenCache.lock.RLock()
+	defer tokenCache.lock.RUnlock()
+
+	if tokenCache.store == nil {
+		return
+	}
+	if err := tokenCache.store.DeleteECRAuthToken(key); err != nil {
+		log.Warnf("Unable to delete persisted ECR auth token: %v", err)
+	}
+}
+
+// loadUnsafe populates the cache with the persisted tokens that are still
+// valid, and removes the rest from the store
+func (tokenCache *PersistentTokenCache) loadUnsafe() error {
+	tokens, err := tokenCache.store.GetECRAuthTokens()
+	if err != nil {
+		return errors.Wrap(err, "failed to load persisted ECR auth tokens")
+	}
+
+	loaded := 0
+	for key, encrypted := range tokens {
+		token, err := tokenCache.decrypt(encrypted)
+		if err != nil || !isExpiryValid(token.ExpiresAt) {
+			if err != nil {
+				log.Warnf("Discarding persisted ECR auth token that could not be decrypted: %v", err)
+			}
+			if err := tokenCache.store.DeleteECRAuthToken(key); err != nil {
+				log.Warnf("Unable to delete persisted ECR auth token: %v", err)
+			}
+			continue
+		}
+		tokenCache.cache.Set(key, token)
+		loaded++
+	}
+	log.Infof("Loaded %d persisted ECR auth tokens", loaded)
+	return nil
+}
+
+func (tokenCache *PersistentTokenCache) encrypt(token *ecrapi.AuthorizationData) ([]byte, error) {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, tokenCache.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	// The nonce is stored as the prefix of the ciphertext
+	return tokenCache.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (tokenCache *PersistentTokenCache) decrypt(encrypted []byte) (*ecrapi.AuthorizationData, error) {
+	nonceSize := tokenCache.aead.NonceSize()
+	if len(encrypted) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+	plaintext, err := tokenCache.aead.Open(nil, encrypted[:nonceSize], encrypted[nonceSize:], nil)
+	if err != nil {
+		return nil, err
+	}
+	token := &ecrapi.AuthorizationData{}
+	if err := json.Unmarshal(plaintext, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// loadOrCreateTokenCacheKey reads the encryption key from keyFile, or creates
+// a random key and writes it to keyFile if it does not exist yet
+func loadOrCreateTokenCacheKey(keyFile string) ([]byte, error) {
+	key, err := ioutil.ReadFile(keyFile)
+	if err == nil {
+		if len(key) != tokenCacheKeySize {
+			return nil, errors.Errorf("token cache key in %s has an invalid size", keyFile)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read token cache key from %s", keyFile)
+	}
+
+	key = make([]byte, tokenCacheKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, errors.Wrap(err, "failed to generate token cache key")
+	}
+	if err := ioutil.WriteFile(keyFile, key, tokenCacheKeyMode); err != nil {
+		return nil, errors.Wrapf(err, "failed to write token cache key to %s", keyFile)
+	}
+	return key, nil
+}
@@ -0,0 +1,136 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerauth
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/async"
+	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTokenStore is an in memory ECRTokenStore
+type memoryTokenStore map[string][]byte
+
+func (store memoryTokenStore) SaveECRAuthToken(key string, token []byte) error {
+	store[key] = token
+	return nil
+}
+
+func (store memoryTokenStore) DeleteECRAuthToken(key string) error {
+	delete(store, key)
+	return nil
+}
+
+func (store memoryTokenStore) GetECRAuthTokens() (map[string][]byte, error) {
+	tokens := make(map[string][]byte)
+	for key, token := range store {
+		tokens[key] = token
+	}
+	return tokens, nil
+}
+
+func testPersistedToken(expiresIn time.Duration) *ecrapi.AuthorizationData {
+	return &ecrapi.AuthorizationData{
+		ProxyEndpoint:      aws.String(proxyEndpointScheme + "proxy"),
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("user:secret-password"))),
+		ExpiresAt:          aws.Time(time.Now().Add(expiresIn).Round(time.Second)),
+	}
+}
+
+func TestPersistentTokenCacheSurvivesRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecr-token-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "key")
+	store := make(memoryTokenStore)
+
+	tokenCache := NewPersistentTokenCache(async.NewLRUCache(tokenCacheSize, tokenCacheTTL))
+	require.NoError(t, tokenCache.Persist(store, keyFile))
+
+	token := testPersistedToken(12 * time.Hour)
+	tokenCache.Set("valid", token)
+	tokenCache.Set("expiring", testPersistedToken(time.Minute))
+	tokenCache.Set("deleted", testPersistedToken(12*time.Hour))
+	tokenCache.Delete("deleted")
+	require.Len(t, store, 2)
+	for _, encrypted := range store {
+		assert.False(t, strings.Contains(string(encrypted), aws.StringValue(token.AuthorizationToken)),
+			"persisted token should be encrypted")
+	}
+
+	// Simulate an agent restart, with a new in memory cache
+	restarted := NewPersistentTokenCache(async.NewLRUCache(tokenCacheSize, tokenCacheTTL))
+	require.NoError(t, restarted.Persist(store, keyFile))
+
+	cached, ok := restarted.Get("valid")
+	require.True(t, ok, "valid token should be loaded")
+	cachedToken := cached.(*ecrapi.AuthorizationData)
+	assert.Equal(t, aws.StringValue(token.AuthorizationToken), aws.StringValue(cachedToken.AuthorizationToken))
+	assert.True(t, aws.TimeValue(token.ExpiresAt).Equal(aws.TimeValue(cachedToken.ExpiresAt)))
+
+	_, ok = restarted.Get("expiring")
+	assert.False(t, ok, "token about to expire should not be loaded")
+	assert.Len(t, store, 1, "token about to expire should be removed from the store")
+}
+
+func TestPersistentTokenCacheDiscardsTokensWithDifferentKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecr-token-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := make(memoryTokenStore)
+
+	tokenCache := NewPersistentTokenCache(async.NewLRUCache(tokenCacheSize, tokenCacheTTL))
+	require.NoError(t, tokenCache.Persist(store, filepath.Join(dir, "key1")))
+	tokenCache.Set("valid", testPersistedToken(12*time.Hour))
+
+	restarted := NewPersistentTokenCache(async.NewLRUCache(tokenCacheSize, tokenCacheTTL))
+	require.NoError(t, restarted.Persist(store, filepath.Join(dir, "key2")))
+
+	_, ok := restarted.Get("valid")
+	assert.False(t, ok, "token encrypted with another key should not be loaded")
+	assert.Empty(t, store)
+}
+
+func TestPersistentTokenCacheNotPersisted(t *testing.T) {
+	store := make(memoryTokenStore)
+	tokenCache := NewPersistentTokenCache(async.NewLRUCache(tokenCacheSize, tokenCacheTTL))
+
+	tokenCache.Set("valid", testPersistedToken(12*time.Hour))
+	_, ok := tokenCache.Get("valid")
+	assert.True(t, ok)
+	assert.Empty(t, store)
+}
+
+func TestPersistentTokenCacheInvalidKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecr-token-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("short"), 0600))
+
+	tokenCache := NewPersistentTokenCache(async.NewLRUCache(tokenCacheSize, tokenCacheTTL))
+	assert.Error(t, tokenCache.Persist(make(memoryTokenStore), keyFile))
+}
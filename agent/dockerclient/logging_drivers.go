@@ -27,6 +27,7 @@ const (
 	SumoLogicDriver   LoggingDriver = "sumologic"
 	NoneDriver        LoggingDriver = "none"
 	AWSFirelensDriver LoggingDriver = "awsfirelens"
+	LocalDriver       LoggingDriver = "local"
 )
 
 var LoggingDriverMinimumVersion = map[LoggingDriver]DockerVersion{
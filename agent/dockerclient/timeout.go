@@ -45,6 +45,13 @@ const (
 	ContainerExecInspectTimeout = 1 * time.Minute
 	// StopContainerTimeout is the timeout for the StopContainer API.
 	StopContainerTimeout = 30 * time.Second
+	// KillContainerTimeout is the timeout for the KillContainer API.
+	KillContainerTimeout = 30 * time.Second
+	// CheckpointContainerTimeout is the timeout for the CheckpointContainer API. The state of
+	// the container, including its memory, is written to disk.
+	CheckpointContainerTimeout = 5 * time.Minute
+	// RemoveCheckpointTimeout is the timeout for the RemoveCheckpoint API.
+	RemoveCheckpointTimeout = 1 * time.Minute
 	// RemoveContainerTimeout is the timeout for the RemoveContainer API.
 	RemoveContainerTimeout = 5 * time.Minute
 
@@ -84,3 +84,11 @@ func (blackholeMetadataClient) SpotInstanceAction() (string, error) {
 func (blackholeMetadataClient) OutpostARN() (string, error) {
 	return "", errors.New("blackholed")
 }
+
+func (blackholeMetadataClient) TargetLifecycleState() (string, error) {
+	return "", errors.New("blackholed")
+}
+
+func (blackholeMetadataClient) RebalanceRecommendation() (string, error) {
+	return "", errors.New("blackholed")
+}
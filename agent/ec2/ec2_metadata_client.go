@@ -39,6 +39,8 @@ const (
 	PrivateIPv4Resource                       = "local-ipv4"
 	PublicIPv4Resource                        = "public-ipv4"
 	OutpostARN                                = "outpost-arn"
+	TargetLifecycleStateResource              = "autoscaling/target-lifecycle-state"
+	RebalanceRecommendationResource           = "events/recommendations/rebalance"
 	PrimaryIPV4VPCCIDRResourceFormat          = "network/interfaces/macs/%s/vpc-ipv4-cidr-block"
 )
 
@@ -82,6 +84,8 @@ type EC2MetadataClient interface {
 	PublicIPv4Address() (string, error)
 	SpotInstanceAction() (string, error)
 	OutpostARN() (string, error)
+	TargetLifecycleState() (string, error)
+	RebalanceRecommendation() (string, error)
 }
 
 type ec2MetadataClientImpl struct {
@@ -203,3 +207,18 @@ func (c *ec2MetadataClientImpl) SpotInstanceAction() (string, error) {
 func (c *ec2MetadataClientImpl) OutpostARN() (string, error) {
 	return c.client.GetMetadata(OutpostARN)
 }
+
+// TargetLifecycleState returns the lifecycle state the auto scaling group is moving
+// the instance to, such as Warmed:Hibernated or InService. It returns an error when
+// the instance isn't in an auto scaling group.
+// see https://docs.aws.amazon.com/autoscaling/ec2/userguide/retrieving-target-lifecycle-state-through-imds.html
+func (c *ec2MetadataClientImpl) TargetLifecycleState() (string, error) {
+	return c.client.GetMetadata(TargetLifecycleStateResource)
+}
+
+// RebalanceRecommendation returns the rebalance recommendation of the spot instance,
+// if it has been set.
+// see https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/rebalance-recommendations.html
+func (c *ec2MetadataClientImpl) RebalanceRecommendation() (string, error) {
+	return c.client.GetMetadata(RebalanceRecommendationResource)
+}
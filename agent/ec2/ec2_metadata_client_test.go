@@ -239,6 +239,32 @@ func TestSpotInstanceAction(t *testing.T) {
 	assert.Equal(t, "{\"action\": \"terminate\", \"time\": \"2017-09-18T08:22:00Z\"}", resp)
 }
 
+func TestTargetLifecycleState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGetter := mock_ec2.NewMockHttpClient(ctrl)
+	testClient := ec2.NewEC2MetadataClient(mockGetter)
+
+	mockGetter.EXPECT().GetMetadata(ec2.TargetLifecycleStateResource).Return("Warmed:Hibernated", nil)
+	resp, err := testClient.TargetLifecycleState()
+	assert.NoError(t, err)
+	assert.Equal(t, "Warmed:Hibernated", resp)
+}
+
+func TestRebalanceRecommendation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGetter := mock_ec2.NewMockHttpClient(ctrl)
+	testClient := ec2.NewEC2MetadataClient(mockGetter)
+
+	mockGetter.EXPECT().GetMetadata(ec2.RebalanceRecommendationResource).Return("{\"noticeTime\": \"2020-10-27T08:22:00Z\"}", nil)
+	resp, err := testClient.RebalanceRecommendation()
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"noticeTime\": \"2020-10-27T08:22:00Z\"}", resp)
+}
+
 func TestSpotInstanceActionError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -216,6 +216,21 @@ func (mr *MockEC2MetadataClientMockRecorder) PublicIPv4Address() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublicIPv4Address", reflect.TypeOf((*MockEC2MetadataClient)(nil).PublicIPv4Address))
 }
 
+// RebalanceRecommendation mocks base method
+func (m *MockEC2MetadataClient) RebalanceRecommendation() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RebalanceRecommendation")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RebalanceRecommendation indicates an expected call of RebalanceRecommendation
+func (mr *MockEC2MetadataClientMockRecorder) RebalanceRecommendation() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RebalanceRecommendation", reflect.TypeOf((*MockEC2MetadataClient)(nil).RebalanceRecommendation))
+}
+
 // Region mocks base method
 func (m *MockEC2MetadataClient) Region() (string, error) {
 	m.ctrl.T.Helper()
@@ -261,6 +276,21 @@ func (mr *MockEC2MetadataClientMockRecorder) SubnetID(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubnetID", reflect.TypeOf((*MockEC2MetadataClient)(nil).SubnetID), arg0)
 }
 
+// TargetLifecycleState mocks base method
+func (m *MockEC2MetadataClient) TargetLifecycleState() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TargetLifecycleState")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TargetLifecycleState indicates an expected call of TargetLifecycleState
+func (mr *MockEC2MetadataClientMockRecorder) TargetLifecycleState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TargetLifecycleState", reflect.TypeOf((*MockEC2MetadataClient)(nil).TargetLifecycleState))
+}
+
 // VPCID mocks base method
 func (m *MockEC2MetadataClient) VPCID(arg0 string) (string, error) {
 	m.ctrl.T.Helper()
@@ -13,7 +13,7 @@
 //
 
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/aws/amazon-ecs-agent/agent/ecr (interfaces: ECRSDK,ECRFactory,ECRClient)
+// Source: github.com/aws/amazon-ecs-agent/agent/ecr (interfaces: ECRSDK,ECRFactory,ECRClient,ECRPublicSDK,ECRPublicFactory,ECRPublicClient)
 
 // Package mock_ecr is a generated GoMock package.
 package mock_ecr
@@ -24,6 +24,7 @@ import (
 	container "github.com/aws/amazon-ecs-agent/agent/api/container"
 	ecr "github.com/aws/amazon-ecs-agent/agent/ecr"
 	ecr0 "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	ecrpublic "github.com/aws/aws-sdk-go/service/ecrpublic"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -140,3 +141,116 @@ func (mr *MockECRClientMockRecorder) GetAuthorizationToken(arg0 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthorizationToken", reflect.TypeOf((*MockECRClient)(nil).GetAuthorizationToken), arg0)
 }
+
+// MockECRPublicSDK is a mock of ECRPublicSDK interface
+type MockECRPublicSDK struct {
+	ctrl     *gomock.Controller
+	recorder *MockECRPublicSDKMockRecorder
+}
+
+// MockECRPublicSDKMockRecorder is the mock recorder for MockECRPublicSDK
+type MockECRPublicSDKMockRecorder struct {
+	mock *MockECRPublicSDK
+}
+
+// NewMockECRPublicSDK creates a new mock instance
+func NewMockECRPublicSDK(ctrl *gomock.Controller) *MockECRPublicSDK {
+	mock := &MockECRPublicSDK{ctrl: ctrl}
+	mock.recorder = &MockECRPublicSDKMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockECRPublicSDK) EXPECT() *MockECRPublicSDKMockRecorder {
+	return m.recorder
+}
+
+// GetAuthorizationToken mocks base method
+func (m *MockECRPublicSDK) GetAuthorizationToken(arg0 *ecrpublic.GetAuthorizationTokenInput) (*ecrpublic.GetAuthorizationTokenOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthorizationToken", arg0)
+	ret0, _ := ret[0].(*ecrpublic.GetAuthorizationTokenOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthorizationToken indicates an expected call of GetAuthorizationToken
+func (mr *MockECRPublicSDKMockRecorder) GetAuthorizationToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthorizationToken", reflect.TypeOf((*MockECRPublicSDK)(nil).GetAuthorizationToken), arg0)
+}
+
+// MockECRPublicFactory is a mock of ECRPublicFactory interface
+type MockECRPublicFactory struct {
+	ctrl     *gomock.Controller
+	recorder *MockECRPublicFactoryMockRecorder
+}
+
+// MockECRPublicFactoryMockRecorder is the mock recorder for MockECRPublicFactory
+type MockECRPublicFactoryMockRecorder struct {
+	mock *MockECRPublicFactory
+}
+
+// NewMockECRPublicFactory creates a new mock instance
+func NewMockECRPublicFactory(ctrl *gomock.Controller) *MockECRPublicFactory {
+	mock := &MockECRPublicFactory{ctrl: ctrl}
+	mock.recorder = &MockECRPublicFactoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockECRPublicFactory) EXPECT() *MockECRPublicFactoryMockRecorder {
+	return m.recorder
+}
+
+// GetClient mocks base method
+func (m *MockECRPublicFactory) GetClient() ecr.ECRPublicClient {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClient")
+	ret0, _ := ret[0].(ecr.ECRPublicClient)
+	return ret0
+}
+
+// GetClient indicates an expected call of GetClient
+func (mr *MockECRPublicFactoryMockRecorder) GetClient() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockECRPublicFactory)(nil).GetClient))
+}
+
+// MockECRPublicClient is a mock of ECRPublicClient interface
+type MockECRPublicClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockECRPublicClientMockRecorder
+}
+
+// MockECRPublicClientMockRecorder is the mock recorder for MockECRPublicClient
+type MockECRPublicClientMockRecorder struct {
+	mock *MockECRPublicClient
+}
+
+// NewMockECRPublicClient creates a new mock instance
+func NewMockECRPublicClient(ctrl *gomock.Controller) *MockECRPublicClient {
+	mock := &MockECRPublicClient{ctrl: ctrl}
+	mock.recorder = &MockECRPublicClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockECRPublicClient) EXPECT() *MockECRPublicClientMockRecorder {
+	return m.recorder
+}
+
+// GetAuthorizationToken mocks base method
+func (m *MockECRPublicClient) GetAuthorizationToken() (*ecrpublic.AuthorizationData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthorizationToken")
+	ret0, _ := ret[0].(*ecrpublic.AuthorizationData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthorizationToken indicates an expected call of GetAuthorizationToken
+func (mr *MockECRPublicClientMockRecorder) GetAuthorizationToken() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthorizationToken", reflect.TypeOf((*MockECRPublicClient)(nil).GetAuthorizationToken))
+}
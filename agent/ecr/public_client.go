@@ -0,0 +1,101 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ecr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials/instancecreds"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecrpublic"
+	log "github.com/cihub/seelog"
+)
+
+const (
+	// ECRPublicRegistry is the hostname of the ECR Public Gallery registry
+	ECRPublicRegistry = "public.ecr.aws"
+	// ecrPublicRegion is the only region the ECR Public API is available in
+	ecrPublicRegion = "us-east-1"
+)
+
+// ECRPublicFactory defines the interface to produce an ECR Public SDK client
+type ECRPublicFactory interface {
+	GetClient() ECRPublicClient
+}
+
+// ECRPublicClient wrapper interface for mocking
+type ECRPublicClient interface {
+	GetAuthorizationToken() (*ecrpublic.AuthorizationData, error)
+}
+
+// ECRPublicSDK is an interface that specifies the subset of the AWS Go SDK's
+// ECR Public client that the Agent uses. This interface is meant to allow
+// injecting a mock for testing.
+type ECRPublicSDK interface {
+	GetAuthorizationToken(*ecrpublic.GetAuthorizationTokenInput) (*ecrpublic.GetAuthorizationTokenOutput, error)
+}
+
+type ecrPublicFactory struct {
+	httpClient *http.Client
+}
+
+type ecrPublicClient struct {
+	sdkClient ECRPublicSDK
+}
+
+// NewECRPublicFactory returns an ECRPublicFactory capable of producing ECR
+// Public SDK clients
+func NewECRPublicFactory(acceptInsecureCert bool) ECRPublicFactory {
+	return &ecrPublicFactory{
+		httpClient: httpclient.New(roundtripTimeout, acceptInsecureCert),
+	}
+}
+
+// GetClient creates an ECR Public SDK client that uses the instance credentials.
+// Pulls from the public registry are not authorized per task, so there are no
+// task credentials to use instead
+func (factory *ecrPublicFactory) GetClient() ECRPublicClient {
+	cfg := aws.NewConfig().
+		WithRegion(ecrPublicRegion).
+		WithHTTPClient(factory.httpClient).
+		WithCredentials(instancecreds.GetCredentials())
+	return NewECRPublicClient(ecrpublic.New(session.New(cfg)))
+}
+
+// NewECRPublicClient creates an ECR Public client used to get docker auth
+// for the ECR Public Gallery
+func NewECRPublicClient(sdkClient ECRPublicSDK) ECRPublicClient {
+	return &ecrPublicClient{
+		sdkClient: sdkClient,
+	}
+}
+
+// GetAuthorizationToken calls the ecr public api to get the docker auth for
+// the public registry
+func (client *ecrPublicClient) GetAuthorizationToken() (*ecrpublic.AuthorizationData, error) {
+	log.Debugf("Calling ECR Public GetAuthorizationToken")
+
+	output, err := client.sdkClient.GetAuthorizationToken(&ecrpublic.GetAuthorizationTokenInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	if output.AuthorizationData == nil {
+		return nil, errors.New("missing AuthorizationData in ECR Public response")
+	}
+	return output.AuthorizationData, nil
+}
@@ -0,0 +1,71 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// ecr_test package to avoid test dependency cycle on ecr/mocks
+package ecr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecr"
+	mock_ecr "github.com/aws/amazon-ecs-agent/agent/ecr/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecrpublic"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECRPublicGetAuthorizationToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSDK := mock_ecr.NewMockECRPublicSDK(ctrl)
+	client := ecr.NewECRPublicClient(mockSDK)
+
+	authData := &ecrpublic.AuthorizationData{AuthorizationToken: aws.String("token")}
+	mockSDK.EXPECT().GetAuthorizationToken(&ecrpublic.GetAuthorizationTokenInput{}).Return(
+		&ecrpublic.GetAuthorizationTokenOutput{AuthorizationData: authData}, nil)
+
+	result, err := client.GetAuthorizationToken()
+	require.NoError(t, err)
+	assert.Equal(t, authData, result)
+}
+
+func TestECRPublicGetAuthorizationTokenMissingAuthData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSDK := mock_ecr.NewMockECRPublicSDK(ctrl)
+	client := ecr.NewECRPublicClient(mockSDK)
+
+	mockSDK.EXPECT().GetAuthorizationToken(gomock.Any()).Return(&ecrpublic.GetAuthorizationTokenOutput{}, nil)
+
+	result, err := client.GetAuthorizationToken()
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestECRPublicGetAuthorizationTokenError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSDK := mock_ecr.NewMockECRPublicSDK(ctrl)
+	client := ecr.NewECRPublicClient(mockSDK)
+
+	mockSDK.EXPECT().GetAuthorizationToken(gomock.Any()).Return(nil, errors.New("Nope Nope Nope"))
+
+	result, err := client.GetAuthorizationToken()
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
@@ -116,7 +116,7 @@ func NewENINetworkConfig(eni *eni.ENI, cfg *Config) (string, *libcni.NetworkConf
 		ENIID:                 eni.ID,
 		MACAddress:            eni.MacAddress,
 		IPAddresses:           eni.GetIPAddressesWithPrefixLength(),
-		GatewayIPAddresses:    []string{eni.GetSubnetGatewayIPv4Address()},
+		GatewayIPAddresses:    eni.GetSubnetGatewayIPAddresses(),
 		BlockInstanceMetadata: cfg.BlockInstanceMetadata,
 	}
 
@@ -136,7 +136,7 @@ func NewBranchENINetworkConfig(eni *eni.ENI, cfg *Config) (string, *libcni.Netwo
 		BranchVlanID:          eni.InterfaceVlanProperties.VlanID,
 		BranchMACAddress:      eni.MacAddress,
 		IPAddresses:           eni.GetIPAddressesWithPrefixLength(),
-		GatewayIPAddresses:    []string{eni.GetSubnetGatewayIPv4Address()},
+		GatewayIPAddresses:    eni.GetSubnetGatewayIPAddresses(),
 		BlockInstanceMetadata: cfg.BlockInstanceMetadata,
 		InterfaceType:         vpcCNIPluginInterfaceType,
 	}
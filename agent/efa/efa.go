@@ -0,0 +1,29 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package efa looks up the devices of the Elastic Fabric Adapters attached to the instance,
+// so they can be passed to the containers of the tasks they are attached for
+package efa
+
+import "fmt"
+
+// NotAttachedError is returned when no EFA device backs the network interface with a MAC
+// address, either because the interface isn't attached as an EFA, or because the EFA driver
+// isn't loaded
+type NotAttachedError struct {
+	MACAddress string
+}
+
+func (err *NotAttachedError) Error() string {
+	return fmt.Sprintf("no EFA device found for the network interface %s", err.MACAddress)
+}
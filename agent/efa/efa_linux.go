@@ -0,0 +1,83 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package efa
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// infinibandPath is where the kernel exposes the RDMA devices, EFAs included
+	infinibandPath = "/sys/class/infiniband"
+	// devPath is where the device files of the RDMA devices are
+	devPath = "/dev/infiniband"
+	// libfabricPath is where the EFA installer puts libfabric and its EFA provider on the host
+	libfabricPath = "/opt/amazon/efa"
+	// hostRootPath is the root file system of the host, seen through its init process, as
+	// libfabric isn't installed in the container the agent runs in
+	hostRootPath = "/host/proc/1/root"
+)
+
+// Devices returns the paths of the verbs device files of the EFA of the network interface
+// with the MAC address. The interface must still be in the host network namespace, as the
+// kernel hides the interfaces of the other namespaces from the RDMA devices
+func Devices(macAddress string) ([]string, error) {
+	addresses, err := filepath.Glob(filepath.Join(infinibandPath, "*", "device", "net", "*", "address"))
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addresses {
+		content, err := ioutil.ReadFile(address)
+		if err != nil || !strings.EqualFold(strings.TrimSpace(string(content)), macAddress) {
+			continue
+		}
+		// <infinibandPath>/<device>/device/net/<interface>/address
+		device := filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(address))))
+		verbs, err := filepath.Glob(filepath.Join(device, "device", "infiniband_verbs", "uverbs*"))
+		if err != nil {
+			return nil, err
+		}
+		var devices []string
+		for _, verb := range verbs {
+			devices = append(devices, filepath.Join(devPath, filepath.Base(verb)))
+		}
+		if len(devices) > 0 {
+			return devices, nil
+		}
+	}
+	return nil, &NotAttachedError{MACAddress: macAddress}
+}
+
+// LibfabricPath returns the directory libfabric is installed in on the host, if it is
+func LibfabricPath() string {
+	if _, err := os.Stat(filepath.Join(hostRootPath, libfabricPath)); err != nil {
+		return ""
+	}
+	return libfabricPath
+}
+
+// Supported returns whether the instance has RDMA devices, which EFAs are, and libfabric
+// installed for the containers to use them
+func Supported() bool {
+	devices, err := ioutil.ReadDir(infinibandPath)
+	if err != nil || len(devices) == 0 {
+		return false
+	}
+	return LibfabricPath() != ""
+}
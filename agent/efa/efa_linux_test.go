@@ -0,0 +1,84 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package efa
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupInfiniband(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "efa")
+	require.NoError(t, err)
+	infinibandPath = filepath.Join(dir, "infiniband")
+	hostRootPath = filepath.Join(dir, "host")
+
+	for device, iface := range map[string]string{"rdmap0s6": "eth1", "rdmap0s7": "eth2"} {
+		net := filepath.Join(infinibandPath, device, "device", "net", iface)
+		require.NoError(t, os.MkdirAll(net, 0755))
+		mac := map[string]string{"eth1": "0a:1b:2c:3d:4e:5f\n", "eth2": "0a:1b:2c:3d:4e:60\n"}[iface]
+		require.NoError(t, ioutil.WriteFile(filepath.Join(net, "address"), []byte(mac), 0644))
+	}
+	// eth2 isn't backed by verbs devices
+	require.NoError(t, os.MkdirAll(filepath.Join(infinibandPath, "rdmap0s6", "device", "infiniband_verbs", "uverbs0"), 0755))
+
+	return func() {
+		os.RemoveAll(dir)
+		infinibandPath = "/sys/class/infiniband"
+		hostRootPath = "/host/proc/1/root"
+	}
+}
+
+func TestDevices(t *testing.T) {
+	defer setupInfiniband(t)()
+
+	devices, err := Devices("0A:1B:2C:3D:4E:5F")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/infiniband/uverbs0"}, devices)
+}
+
+func TestDevicesNotAttached(t *testing.T) {
+	defer setupInfiniband(t)()
+
+	for _, mac := range []string{"0a:1b:2c:3d:4e:60", "0a:1b:2c:3d:4e:61"} {
+		_, err := Devices(mac)
+		assert.Equal(t, &NotAttachedError{MACAddress: mac}, err)
+	}
+}
+
+func TestLibfabricPath(t *testing.T) {
+	defer setupInfiniband(t)()
+
+	assert.Empty(t, LibfabricPath())
+	require.NoError(t, os.MkdirAll(filepath.Join(hostRootPath, libfabricPath), 0755))
+	assert.Equal(t, "/opt/amazon/efa", LibfabricPath(), "the path of libfabric on the host should be returned")
+}
+
+func TestSupported(t *testing.T) {
+	defer setupInfiniband(t)()
+
+	assert.False(t, Supported(), "libfabric isn't installed")
+	require.NoError(t, os.MkdirAll(filepath.Join(hostRootPath, libfabricPath), 0755))
+	assert.True(t, Supported())
+
+	require.NoError(t, os.RemoveAll(infinibandPath))
+	assert.False(t, Supported(), "the instance has no RDMA devices")
+}
@@ -0,0 +1,31 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package efa
+
+// Devices returns an error, as EFAs are only supported on Linux
+func Devices(macAddress string) ([]string, error) {
+	return nil, &NotAttachedError{MACAddress: macAddress}
+}
+
+// LibfabricPath returns an empty path, as EFAs are only supported on Linux
+func LibfabricPath() string {
+	return ""
+}
+
+// Supported returns false, as EFAs are only supported on Linux
+func Supported() bool {
+	return false
+}
@@ -0,0 +1,71 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/cihub/seelog"
+)
+
+// ReloadConfig applies the part of the agent configuration that is reloaded while the
+// agent runs to the engine and to its image manager
+func (engine *DockerTaskEngine) ReloadConfig(cfg config.ReloadableConfig) {
+	if engine.imageManager != nil {
+		engine.imageManager.SetCleanupConfig(cfg)
+	}
+
+	engine.reloadedConfigLock.Lock()
+	defer engine.reloadedConfigLock.Unlock()
+	engine.reloadedConfig = &cfg
+	if engine.configReloaded != nil {
+		close(engine.configReloaded)
+	}
+	engine.configReloaded = make(chan struct{})
+	seelog.Infof("Task engine: reloaded configuration: %s", cfg.String())
+}
+
+// configReloadedNotification returns a channel closed the next time the configuration is
+// reloaded. The loops get it before they read the configuration, so that they don't miss
+// a reload
+func (engine *DockerTaskEngine) configReloadedNotification() <-chan struct{} {
+	engine.reloadedConfigLock.Lock()
+	defer engine.reloadedConfigLock.Unlock()
+	if engine.configReloaded == nil {
+		engine.configReloaded = make(chan struct{})
+	}
+	return engine.configReloaded
+}
+
+// efsMountHealthCheckInterval returns the time between two checks of the EFS mounts
+func (engine *DockerTaskEngine) efsMountHealthCheckInterval() time.Duration {
+	engine.reloadedConfigLock.RLock()
+	defer engine.reloadedConfigLock.RUnlock()
+	if engine.reloadedConfig != nil {
+		return engine.reloadedConfig.EFSMountHealthCheckInterval
+	}
+	return engine.cfg.EFSMountHealthCheckInterval
+}
+
+// memoryPressureCheckInterval returns the time between two checks of the memory pressure
+// of the tasks
+func (engine *DockerTaskEngine) memoryPressureCheckInterval() time.Duration {
+	engine.reloadedConfigLock.RLock()
+	defer engine.reloadedConfigLock.RUnlock()
+	if engine.reloadedConfig != nil {
+		return engine.reloadedConfig.MemoryPressureCheckInterval
+	}
+	return engine.cfg.MemoryPressureCheckInterval
+}
@@ -0,0 +1,59 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadConfig(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	imageManager := mock_engine.NewMockImageManager(ctrl)
+	cfg := &config.Config{
+		EFSMountHealthCheckInterval: time.Minute,
+		MemoryPressureCheckInterval: 10 * time.Second,
+	}
+	taskEngine := &DockerTaskEngine{
+		cfg:          cfg,
+		imageManager: imageManager,
+	}
+	assert.Equal(t, time.Minute, taskEngine.efsMountHealthCheckInterval())
+	assert.Equal(t, 10*time.Second, taskEngine.memoryPressureCheckInterval())
+
+	reloaded := cfg.Reloadable()
+	reloaded.EFSMountHealthCheckInterval = 5 * time.Minute
+	reloaded.MemoryPressureCheckInterval = 2 * time.Second
+	imageManager.EXPECT().SetCleanupConfig(reloaded)
+	notification := taskEngine.configReloadedNotification()
+	taskEngine.ReloadConfig(reloaded)
+
+	select {
+	case <-notification:
+	default:
+		t.Fatal("the reload should be notified")
+	}
+	assert.NotEqual(t, notification, taskEngine.configReloadedNotification())
+	assert.Equal(t, 5*time.Minute, taskEngine.efsMountHealthCheckInterval())
+	assert.Equal(t, 2*time.Second, taskEngine.memoryPressureCheckInterval())
+}
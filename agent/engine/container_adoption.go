@@ -0,0 +1,106 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// adoptableContainer is a docker container created for a task that was lost with
+// the checkpoint of the state
+type adoptableContainer struct {
+	dockerID   string
+	dockerName string
+}
+
+// RecoverContainersFromLabels looks for the docker containers created by the agent,
+// for tasks that aren't in the state. This is the case when the checkpoint of the
+// state was corrupted and only partially recovered. The containers are adopted by
+// their task when it's sent again by ACS, instead of being created again.
+func (engine *DockerTaskEngine) RecoverContainersFromLabels(ctx context.Context) error {
+	listResponse := engine.client.ListContainers(ctx, true, dockerclient.ListContainersTimeout)
+	if listResponse.Error != nil {
+		return errors.Wrap(listResponse.Error, "unable to list docker containers")
+	}
+
+	adoptable := make(map[string]map[string]adoptableContainer)
+	for _, dockerID := range listResponse.DockerIDs {
+		dockerContainer, err := engine.client.InspectContainer(ctx, dockerID, dockerclient.InspectContainerTimeout)
+		if err != nil {
+			seelog.Warnf("Task engine: unable to inspect docker container %s to recover it: %v", dockerID, err)
+			continue
+		}
+		if dockerContainer.Config == nil {
+			continue
+		}
+		labels := dockerContainer.Config.Labels
+		taskARN, containerName := labels[labelTaskARN], labels[labelContainerName]
+		if taskARN == "" || containerName == "" {
+			continue
+		}
+		if _, ok := engine.state.TaskByArn(taskARN); ok {
+			continue
+		}
+		if adoptable[taskARN] == nil {
+			adoptable[taskARN] = make(map[string]adoptableContainer)
+		}
+		adoptable[taskARN][containerName] = adoptableContainer{
+			dockerID:   dockerID,
+			dockerName: strings.TrimPrefix(dockerContainer.Name, "/"),
+		}
+	}
+
+	engine.adoptableContainersLock.Lock()
+	engine.adoptableContainers = adoptable
+	engine.adoptableContainersLock.Unlock()
+	seelog.Infof("Task engine: found the containers of %d tasks missing from the state, to adopt once their task is sent again",
+		len(adoptable))
+	return nil
+}
+
+// adoptRecoveredContainers adds the docker containers found for a new task by
+// RecoverContainersFromLabels to the state, and synchronizes the status of the
+// task containers with them, so that they are managed rather than created again
+func (engine *DockerTaskEngine) adoptRecoveredContainers(task *apitask.Task) {
+	engine.adoptableContainersLock.Lock()
+	adoptable, ok := engine.adoptableContainers[task.Arn]
+	delete(engine.adoptableContainers, task.Arn)
+	engine.adoptableContainersLock.Unlock()
+	if !ok {
+		return
+	}
+
+	for _, container := range task.Containers {
+		recovered, ok := adoptable[container.Name]
+		if !ok {
+			continue
+		}
+		seelog.Infof("Task engine [%s]: adopting docker container %s for container %s",
+			task.Arn, recovered.dockerID, container.Name)
+		dockerContainer := &apicontainer.DockerContainer{
+			DockerID:   recovered.dockerID,
+			DockerName: recovered.dockerName,
+			Container:  container,
+		}
+		engine.state.AddContainer(dockerContainer, task)
+		engine.synchronizeContainerStatus(dockerContainer, task)
+	}
+}
@@ -0,0 +1,84 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverContainersFromLabels(t *testing.T) {
+	ctrl, client, _, taskEngine, _, imageManager, _ := mocks(t, context.TODO(), &defaultConfig)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	known := &apitask.Task{Arn: "known"}
+	engine.state.AddTask(known)
+
+	client.EXPECT().ListContainers(gomock.Any(), true, gomock.Any()).Return(dockerapi.ListContainersResponse{
+		DockerIDs: []string{"app", "sidecar", "known", "unlabeled"},
+	})
+	lostContainer := dockerContainerJSON("app", true, map[string]string{labelTaskARN: "lost", labelContainerName: "app"})
+	lostContainer.Name = "/ecs-lost-app"
+	client.EXPECT().InspectContainer(gomock.Any(), "app", gomock.Any()).Return(lostContainer, nil)
+	client.EXPECT().InspectContainer(gomock.Any(), "sidecar", gomock.Any()).Return(nil, errors.New("no such container"))
+	client.EXPECT().InspectContainer(gomock.Any(), "known", gomock.Any()).Return(
+		dockerContainerJSON("known", true, map[string]string{labelTaskARN: "known", labelContainerName: "app"}), nil)
+	client.EXPECT().InspectContainer(gomock.Any(), "unlabeled", gomock.Any()).Return(
+		dockerContainerJSON("unlabeled", true, nil), nil)
+	require.NoError(t, engine.RecoverContainersFromLabels(context.TODO()))
+	assert.Equal(t, map[string]map[string]adoptableContainer{
+		"lost": {"app": {dockerID: "app", dockerName: "ecs-lost-app"}},
+	}, engine.adoptableContainers)
+
+	// The recovered containers are adopted by their task once it's sent again.
+	container := &apicontainer.Container{Name: "app", DesiredStatusUnsafe: apicontainerstatus.ContainerRunning}
+	task := &apitask.Task{Arn: "lost", Containers: []*apicontainer.Container{container}}
+	engine.state.AddTask(task)
+	client.EXPECT().DescribeContainer(gomock.Any(), "app").Return(apicontainerstatus.ContainerRunning,
+		dockerapi.DockerContainerMetadata{DockerID: "app"})
+	imageManager.EXPECT().RecordContainerReference(container).Return(nil)
+	engine.adoptRecoveredContainers(task)
+
+	assert.Equal(t, apicontainerstatus.ContainerRunning, container.GetKnownStatus())
+	dockerContainer, ok := engine.state.ContainerByID("app")
+	require.True(t, ok)
+	assert.Equal(t, container, dockerContainer.Container)
+	assert.Empty(t, engine.adoptableContainers)
+
+	// Tasks without recovered containers are left as is.
+	engine.adoptRecoveredContainers(&apitask.Task{Arn: "new"})
+}
+
+func TestRecoverContainersFromLabelsListError(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, context.TODO(), &defaultConfig)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	client.EXPECT().ListContainers(gomock.Any(), true, gomock.Any()).Return(dockerapi.ListContainersResponse{
+		Error: errors.New("docker is unavailable"),
+	})
+	assert.Error(t, engine.RecoverContainersFromLabels(context.TODO()))
+}
@@ -0,0 +1,183 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+// checkpointIDPrefix is the prefix of the IDs of the checkpoints created by the agent
+const checkpointIDPrefix = "ecs-"
+
+// startPeriodicContainerCheckpoints checkpoints the running containers of the tasks
+// with checkpointing enabled at the configured interval, until the context is done
+func (engine *DockerTaskEngine) startPeriodicContainerCheckpoints(ctx context.Context) {
+	if engine.cfg.ContainerCheckpointInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(engine.cfg.ContainerCheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			engine.checkpointContainers(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkpointContainers checkpoints the running containers of the running tasks with
+// checkpointing enabled, one at a time
+func (engine *DockerTaskEngine) checkpointContainers(ctx context.Context) {
+	var tasks []*apitask.Task
+	engine.tasksLock.RLock()
+	for _, mTask := range engine.managedTasks {
+		if mTask.CheckpointEnabled && mTask.GetKnownStatus() == apitaskstatus.TaskRunning &&
+			!mTask.GetDesiredStatus().Terminal() {
+			tasks = append(tasks, mTask.Task)
+		}
+	}
+	engine.tasksLock.RUnlock()
+
+	for _, task := range tasks {
+		for _, container := range task.Containers {
+			if container.IsInternal() || container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+				continue
+			}
+			engine.checkpointContainer(ctx, task, container)
+		}
+	}
+}
+
+// checkpointContainer creates a new checkpoint of the container, and removes its
+// previous one once it's replaced
+func (engine *DockerTaskEngine) checkpointContainer(ctx context.Context, task *apitask.Task, container *apicontainer.Container) {
+	dockerID, err := engine.getDockerID(task, container)
+	if err != nil {
+		logger.Error("Unable to checkpoint container", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.Error:     err,
+		})
+		return
+	}
+
+	checkpointID := fmt.Sprintf("%s%d", checkpointIDPrefix, engine.time().Now().UnixNano())
+	err = engine.client.CheckpointContainer(ctx, dockerID, checkpointID, engine.cfg.ContainerCheckpointDir,
+		dockerclient.CheckpointContainerTimeout)
+	if err != nil {
+		logger.Warn("Error checkpointing container", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: dockerID,
+			field.Error:     err,
+		})
+		return
+	}
+	logger.Info("Checkpointed container", logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: container.Name,
+		field.RuntimeID: dockerID,
+		"checkpointID":  checkpointID,
+	})
+
+	previousCheckpointID := container.GetCheckpointID()
+	container.SetCheckpointID(checkpointID)
+	engine.saveContainerData(container)
+	if previousCheckpointID == "" {
+		return
+	}
+	err = engine.client.RemoveCheckpoint(ctx, dockerID, previousCheckpointID, engine.cfg.ContainerCheckpointDir,
+		dockerclient.RemoveCheckpointTimeout)
+	if err != nil {
+		logger.Warn("Error removing previous checkpoint of container", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: dockerID,
+			"checkpointID":  previousCheckpointID,
+			field.Error:     err,
+		})
+	}
+}
+
+// removeContainerCheckpoint removes the latest checkpoint of a container when its task
+// is cleaned up, as the checkpoints kept in a checkpoint directory outlive the container
+func (engine *DockerTaskEngine) removeContainerCheckpoint(task *apitask.Task, container *apicontainer.Container) {
+	checkpointID := container.GetCheckpointID()
+	if checkpointID == "" {
+		return
+	}
+	dockerID, err := engine.getDockerID(task, container)
+	if err != nil {
+		return
+	}
+	err = engine.client.RemoveCheckpoint(engine.ctx, dockerID, checkpointID, engine.cfg.ContainerCheckpointDir,
+		dockerclient.RemoveCheckpointTimeout)
+	if err != nil {
+		logger.Warn("Error removing the latest checkpoint of container", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: dockerID,
+			"checkpointID":  checkpointID,
+			field.Error:     err,
+		})
+		return
+	}
+	container.SetCheckpointID("")
+}
+
+// startOrRestoreContainer starts the container, restoring it from its latest checkpoint
+// if its task has checkpointing enabled and it has one. A container only has a checkpoint
+// once it ran, so only the restarts of containers per their restart policy are restored.
+// Containers that can't be restored are started from scratch.
+func (engine *DockerTaskEngine) startOrRestoreContainer(client dockerapi.DockerClient, task *apitask.Task,
+	container *apicontainer.Container, dockerID string) dockerapi.DockerContainerMetadata {
+	checkpointID := container.GetCheckpointID()
+	if !task.CheckpointEnabled || checkpointID == "" {
+		return client.StartContainer(engine.ctx, dockerID, engine.cfg.ContainerStartTimeout)
+	}
+
+	metadata := client.RestoreContainer(engine.ctx, dockerID, checkpointID, engine.cfg.ContainerCheckpointDir,
+		engine.cfg.ContainerStartTimeout)
+	if metadata.Error == nil {
+		logger.Info("Restored container from its latest checkpoint", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: dockerID,
+			"checkpointID":  checkpointID,
+		})
+		return metadata
+	}
+	logger.Warn("Error restoring container from its latest checkpoint; starting it from scratch", logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: container.Name,
+		field.RuntimeID: dockerID,
+		"checkpointID":  checkpointID,
+		field.Error:     metadata.Error,
+	})
+	container.SetCheckpointID("")
+	return client.StartContainer(engine.ctx, dockerID, engine.cfg.ContainerStartTimeout)
+}
@@ -0,0 +1,156 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCheckpointedTask(arn string, checkpointEnabled bool) *managedTask {
+	container := &apicontainer.Container{
+		Name:                "c1",
+		KnownStatusUnsafe:   apicontainerstatus.ContainerRunning,
+		DesiredStatusUnsafe: apicontainerstatus.ContainerRunning,
+	}
+	container.SetRuntimeID(containerID)
+	container.SetCheckpointID("ecs-1")
+	return &managedTask{
+		Task: &apitask.Task{
+			Arn:                 arn,
+			Containers:          []*apicontainer.Container{container},
+			KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+			CheckpointEnabled:   checkpointEnabled,
+		},
+	}
+}
+
+func TestCheckpointContainers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.ContainerCheckpointDir = "/checkpoints"
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	checkpointedTask := newCheckpointedTask("checkpointed", true)
+	dockerTaskEngine.managedTasks[checkpointedTask.Arn] = checkpointedTask
+	otherTask := newCheckpointedTask("other", false)
+	dockerTaskEngine.managedTasks[otherTask.Arn] = otherTask
+
+	now := time.Now()
+	checkpointID := fmt.Sprintf("ecs-%d", now.UnixNano())
+	mockTime.EXPECT().Now().Return(now)
+	gomock.InOrder(
+		client.EXPECT().CheckpointContainer(gomock.Any(), containerID, checkpointID, "/checkpoints",
+			dockerclient.CheckpointContainerTimeout).Return(nil),
+		client.EXPECT().RemoveCheckpoint(gomock.Any(), containerID, "ecs-1", "/checkpoints",
+			dockerclient.RemoveCheckpointTimeout).Return(nil),
+	)
+
+	dockerTaskEngine.checkpointContainers(ctx)
+	assert.Equal(t, checkpointID, checkpointedTask.Containers[0].GetCheckpointID())
+	assert.Equal(t, "ecs-1", otherTask.Containers[0].GetCheckpointID())
+}
+
+func TestCheckpointContainerError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	task := newCheckpointedTask("checkpointed", true)
+
+	mockTime.EXPECT().Now().Return(time.Now())
+	client.EXPECT().CheckpointContainer(gomock.Any(), containerID, gomock.Any(), gomock.Any(), gomock.Any()).Return(
+		dockerapi.CannotCheckpointContainerError{FromError: errors.New("criu not found")})
+
+	dockerTaskEngine.checkpointContainer(ctx, task.Task, task.Containers[0])
+	assert.Equal(t, "ecs-1", task.Containers[0].GetCheckpointID(), "The previous checkpoint should be kept")
+}
+
+func TestStartOrRestoreContainer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	t.Run("checkpointing disabled", func(t *testing.T) {
+		task := newCheckpointedTask("task", false)
+		client.EXPECT().StartContainer(gomock.Any(), containerID, defaultConfig.ContainerStartTimeout).Return(
+			dockerapi.DockerContainerMetadata{DockerID: containerID})
+		md := dockerTaskEngine.startOrRestoreContainer(client, task.Task, task.Containers[0], containerID)
+		assert.NoError(t, md.Error)
+	})
+
+	t.Run("restored from checkpoint", func(t *testing.T) {
+		task := newCheckpointedTask("task", true)
+		client.EXPECT().RestoreContainer(gomock.Any(), containerID, "ecs-1", "", defaultConfig.ContainerStartTimeout).Return(
+			dockerapi.DockerContainerMetadata{DockerID: containerID})
+		md := dockerTaskEngine.startOrRestoreContainer(client, task.Task, task.Containers[0], containerID)
+		assert.NoError(t, md.Error)
+		assert.Equal(t, "ecs-1", task.Containers[0].GetCheckpointID())
+	})
+
+	t.Run("restore error", func(t *testing.T) {
+		task := newCheckpointedTask("task", true)
+		gomock.InOrder(
+			client.EXPECT().RestoreContainer(gomock.Any(), containerID, "ecs-1", "", gomock.Any()).Return(
+				dockerapi.DockerContainerMetadata{Error: dockerapi.CannotStartContainerError{FromError: errors.New("error")}}),
+			client.EXPECT().StartContainer(gomock.Any(), containerID, defaultConfig.ContainerStartTimeout).Return(
+				dockerapi.DockerContainerMetadata{DockerID: containerID}),
+		)
+		md := dockerTaskEngine.startOrRestoreContainer(client, task.Task, task.Containers[0], containerID)
+		assert.NoError(t, md.Error)
+		assert.Empty(t, task.Containers[0].GetCheckpointID(), "A checkpoint that can't be restored should be dropped")
+	})
+}
+
+func TestSweepTaskRemovesContainerCheckpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.ContainerCheckpointDir = "/checkpoints"
+	ctrl, client, _, taskEngine, _, imageManager, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	task := newCheckpointedTask("checkpointed", true).Task
+	gomock.InOrder(
+		client.EXPECT().RemoveCheckpoint(gomock.Any(), containerID, "ecs-1", "/checkpoints",
+			dockerclient.RemoveCheckpointTimeout).Return(nil),
+		client.EXPECT().RemoveContainer(gomock.Any(), containerID, gomock.Any()).Return(nil),
+	)
+	imageManager.EXPECT().RemoveContainerReferenceFromImageState(task.Containers[0]).Return(nil)
+
+	dockerTaskEngine.sweepTask(task)
+	assert.Empty(t, task.Containers[0].GetCheckpointID())
+}
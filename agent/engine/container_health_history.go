@@ -0,0 +1,97 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/aws-sdk-go/aws"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+// defaultDockerHealthCheckInterval is the interval of the docker health checks that
+// don't specify one
+const defaultDockerHealthCheckInterval = 30 * time.Second
+
+// startHealthHistoryPolling starts reading the health check results kept by docker for
+// a running container with a docker health check, unless they're already read. Docker
+// only emits an event when the health status changes, so the results of the health
+// checks in between are read from the health log of the container.
+func (mtask *managedTask) startHealthHistoryPolling(container *apicontainer.Container) {
+	if container.HealthCheckType != apicontainer.DockerHealthCheckType || container.HasHealthCheckProbes() ||
+		container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+		return
+	}
+	if !mtask.setContainerProbed(container.Name) {
+		return
+	}
+	go mtask.pollHealthHistory(container, dockerHealthCheckInterval(container))
+}
+
+// pollHealthHistory adds the health check results kept by docker to the health history
+// of the container on every health check interval, until the task is done
+func (mtask *managedTask) pollHealthHistory(container *apicontainer.Container, interval time.Duration) {
+	for {
+		select {
+		case <-mtask.time().After(interval):
+		case <-mtask.ctx.Done():
+			return
+		}
+		// the container may be restarted per its restart policy
+		if container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+			continue
+		}
+
+		dockerContainer, err := mtask.engine.client.InspectContainer(mtask.ctx, container.GetRuntimeID(),
+			dockerclient.InspectContainerTimeout)
+		if err != nil {
+			logger.Debug("Unable to read the health check results of the container", logger.Fields{
+				field.TaskARN:   mtask.Arn,
+				field.Container: container.Name,
+				field.Error:     err,
+			})
+			continue
+		}
+		healthLog := dockerapi.MetadataFromContainer(dockerContainer).HealthLog
+		if len(healthLog) == 0 {
+			continue
+		}
+		previous := container.GetHealthHistory()
+		container.AddHealthCheckResults(healthLog...)
+		if history := container.GetHealthHistory(); len(previous) == 0 ||
+			history[len(history)-1].Timestamp.After(previous[len(previous)-1].Timestamp) {
+			mtask.engine.saveContainerData(container)
+		}
+	}
+}
+
+// dockerHealthCheckInterval returns the interval of the docker health check of the container
+func dockerHealthCheckInterval(container *apicontainer.Container) time.Duration {
+	if container.DockerConfig.Config == nil {
+		return defaultDockerHealthCheckInterval
+	}
+	var config dockercontainer.Config
+	if err := json.Unmarshal([]byte(aws.StringValue(container.DockerConfig.Config)), &config); err != nil ||
+		config.Healthcheck == nil || config.Healthcheck.Interval <= 0 {
+		return defaultDockerHealthCheckInterval
+	}
+	return config.Healthcheck.Interval
+}
@@ -0,0 +1,122 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartHealthHistoryPolling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+
+	container := &apicontainer.Container{
+		Name:              "c1",
+		RuntimeID:         "id1",
+		KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+		HealthCheckType:   apicontainer.DockerHealthCheckType,
+		DockerConfig: apicontainer.DockerConfig{
+			Config: aws.String(`{"Healthcheck":{"Test":["CMD","true"],"Interval":10000000000}}`),
+		},
+	}
+	mtask := &managedTask{
+		Task: &apitask.Task{
+			Arn:        "myArn",
+			Containers: []*apicontainer.Container{container},
+		},
+		ctx:    ctx,
+		engine: taskEngine.(*DockerTaskEngine),
+	}
+	mtask._time = mockTime
+
+	checkedAt := time.Now()
+	inspected := make(chan struct{}, 10)
+	intervalElapsed := make(chan time.Time)
+	mockTime.EXPECT().After(10 * time.Second).Return(intervalElapsed).AnyTimes()
+	client.EXPECT().InspectContainer(gomock.Any(), "id1", dockerclient.InspectContainerTimeout).DoAndReturn(
+		func(ctx context.Context, dockerID string, timeout time.Duration) (*types.ContainerJSON, error) {
+			defer func() { inspected <- struct{}{} }()
+			return &types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					State: &types.ContainerState{
+						Running: true,
+						Health: &types.Health{
+							Status: "healthy",
+							Log: []*types.HealthcheckResult{
+								{End: checkedAt.Add(-10 * time.Second), ExitCode: 1, Output: "connection refused"},
+								{End: checkedAt, ExitCode: 0},
+							},
+						},
+					},
+				},
+			}, nil
+		}).AnyTimes()
+
+	mtask.startHealthHistoryPolling(container)
+	mtask.startHealthHistoryPolling(container)
+	intervalElapsed <- time.Now()
+	<-inspected
+	intervalElapsed <- time.Now()
+	<-inspected
+	// the history is updated before the next interval is awaited
+	intervalElapsed <- time.Now()
+	history := container.GetHealthHistory()
+	assert.Len(t, history, 2, "Results already in the history shouldn't be added again")
+	assert.Equal(t, 1, history[0].ExitCode)
+	assert.Equal(t, "connection refused", history[0].Output)
+	assert.Equal(t, 0, history[1].ExitCode)
+}
+
+func TestStartHealthHistoryPollingWithoutDockerHealthCheck(t *testing.T) {
+	mtask := &managedTask{
+		Task: &apitask.Task{Arn: "myArn"},
+	}
+	container := &apicontainer.Container{
+		Name:              "c1",
+		KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+	}
+	mtask.startHealthHistoryPolling(container)
+	assert.Empty(t, mtask.probedContainers)
+
+	container.HealthCheckType = apicontainer.DockerHealthCheckType
+	container.SetKnownStatus(apicontainerstatus.ContainerCreated)
+	mtask.startHealthHistoryPolling(container)
+	assert.Empty(t, mtask.probedContainers)
+}
+
+func TestDockerHealthCheckInterval(t *testing.T) {
+	container := &apicontainer.Container{}
+	assert.Equal(t, defaultDockerHealthCheckInterval, dockerHealthCheckInterval(container))
+
+	container.DockerConfig.Config = aws.String(`{"Healthcheck":{"Test":["CMD","true"]}}`)
+	assert.Equal(t, defaultDockerHealthCheckInterval, dockerHealthCheckInterval(container))
+
+	container.DockerConfig.Config = aws.String(`{"Healthcheck":{"Test":["CMD","true"],"Interval":5000000000}}`)
+	assert.Equal(t, 5*time.Second, dockerHealthCheckInterval(container))
+}
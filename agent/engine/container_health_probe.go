@@ -0,0 +1,263 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultHealthCheckProbeInterval is the time between two probes of probes that
+	// don't specify one
+	defaultHealthCheckProbeInterval = 30 * time.Second
+	// defaultHealthCheckProbeTimeout is the timeout of probes that don't specify one
+	defaultHealthCheckProbeTimeout = 5 * time.Second
+	// defaultHealthCheckProbeRetries is the number of consecutive failures after which
+	// the container is unhealthy, for probes that don't specify one
+	defaultHealthCheckProbeRetries = 3
+	// localhostIPv4 is the address probed for containers using the host network
+	localhostIPv4 = "127.0.0.1"
+)
+
+// executeHealthCheckProbe runs a single probe against the container address
+var executeHealthCheckProbe = runHealthCheckProbe
+
+// healthCheckProbesState holds the results of the probes of a container
+type healthCheckProbesState struct {
+	lock   sync.Mutex
+	probes []healthCheckProbeState
+}
+
+type healthCheckProbeState struct {
+	status              apicontainerstatus.ContainerHealthStatus
+	consecutiveFailures int
+	output              string
+}
+
+// startHealthCheckProbes starts probing a running container with its health check
+// probes, unless it's already being probed
+func (mtask *managedTask) startHealthCheckProbes(container *apicontainer.Container) {
+	if !container.HasHealthCheckProbes() || container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+		return
+	}
+	if !mtask.setContainerProbed(container.Name) {
+		return
+	}
+	logger.Info("Starting container health check probes", logger.Fields{
+		field.TaskARN:   mtask.Arn,
+		field.Container: container.Name,
+		"probes":        len(container.HealthCheckProbes),
+	})
+	state := &healthCheckProbesState{
+		probes: make([]healthCheckProbeState, len(container.HealthCheckProbes)),
+	}
+	for i, probe := range container.HealthCheckProbes {
+		go mtask.runHealthCheckProbes(container, i, probe, state)
+	}
+}
+
+// runHealthCheckProbes probes the container on every interval of the probe and updates
+// its health status, until the container stops
+func (mtask *managedTask) runHealthCheckProbes(container *apicontainer.Container, index int,
+	probe apicontainer.HealthCheckProbe, state *healthCheckProbesState) {
+	interval := defaultHealthCheckProbeInterval
+	if probe.IntervalSeconds > 0 {
+		interval = time.Duration(probe.IntervalSeconds) * time.Second
+	}
+	timeout := defaultHealthCheckProbeTimeout
+	if probe.TimeoutSeconds > 0 {
+		timeout = time.Duration(probe.TimeoutSeconds) * time.Second
+	}
+	startPeriod := time.Duration(probe.StartPeriodSeconds) * time.Second
+
+	for {
+		select {
+		case <-mtask.time().After(interval):
+		case <-mtask.ctx.Done():
+			return
+		}
+		if container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+			return
+		}
+
+		address, err := healthCheckProbeAddress(mtask.Task, container)
+		if err == nil {
+			err = executeHealthCheckProbe(probe, address, timeout)
+		}
+		now := mtask.time().Now()
+		inStartPeriod := now.Sub(container.GetStartedAt()) < startPeriod
+		health := state.record(index, probe, err, inStartPeriod)
+		if health.Status != container.GetHealthStatus().Status {
+			logger.Info("Container health status changed", logger.Fields{
+				field.TaskARN:   mtask.Arn,
+				field.Container: container.Name,
+				field.Status:    health.Status.String(),
+				"output":        health.Output,
+			})
+		}
+		container.SetHealthStatus(health)
+		container.AddHealthCheckResults(healthCheckProbeResult(now, err))
+		mtask.engine.saveContainerData(container)
+	}
+}
+
+// healthCheckProbeResult returns the health history entry of a probe run
+func healthCheckProbeResult(timestamp time.Time, err error) apicontainer.HealthCheckResult {
+	if err != nil {
+		return apicontainer.HealthCheckResult{Timestamp: timestamp, ExitCode: 1, Output: err.Error()}
+	}
+	return apicontainer.HealthCheckResult{Timestamp: timestamp}
+}
+
+// record records the result of a probe and returns the resulting health of the
+// container: unhealthy if any probe failed as many consecutive times as its retries,
+// healthy if all the probes succeeded since, and unknown otherwise. Failures during
+// the start period of the probe aren't counted.
+func (state *healthCheckProbesState) record(index int, probe apicontainer.HealthCheckProbe, err error,
+	inStartPeriod bool) apicontainer.HealthStatus {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	probeState := &state.probes[index]
+	if err == nil {
+		probeState.status = apicontainerstatus.ContainerHealthy
+		probeState.consecutiveFailures = 0
+		probeState.output = ""
+	} else if !inStartPeriod {
+		retries := defaultHealthCheckProbeRetries
+		if probe.Retries > 0 {
+			retries = probe.Retries
+		}
+		probeState.consecutiveFailures++
+		probeState.output = err.Error()
+		if probeState.consecutiveFailures >= retries {
+			probeState.status = apicontainerstatus.ContainerUnhealthy
+		}
+	}
+
+	health := apicontainer.HealthStatus{Status: apicontainerstatus.ContainerHealthy}
+	for _, p := range state.probes {
+		switch p.status {
+		case apicontainerstatus.ContainerUnhealthy:
+			// exit code 1 matches the one reported by docker health checks that fail
+			return apicontainer.HealthStatus{
+				Status:   apicontainerstatus.ContainerUnhealthy,
+				Output:   p.output,
+				ExitCode: 1,
+			}
+		case apicontainerstatus.ContainerHealthy:
+		default:
+			health.Status = apicontainerstatus.ContainerHealthUnknown
+		}
+	}
+	return health
+}
+
+// healthCheckProbeAddress returns the IP address at which the container is probed
+func healthCheckProbeAddress(task *apitask.Task, container *apicontainer.Container) (string, error) {
+	if task.IsNetworkModeAWSVPC() {
+		eni := task.GetPrimaryENI()
+		if eni == nil {
+			return "", errors.Errorf("no ENI address found for task %s", task.Arn)
+		}
+		if eni.GetPrimaryIPv4Address() != "" {
+			return eni.GetPrimaryIPv4Address(), nil
+		}
+		if ipv6Addresses := eni.GetIPV6Addresses(); len(ipv6Addresses) > 0 {
+			return ipv6Addresses[0], nil
+		}
+		return "", errors.Errorf("no ENI address found for task %s", task.Arn)
+	}
+	if container.GetNetworkMode() == hostNetworkMode {
+		return localhostIPv4, nil
+	}
+	settings := container.GetNetworkSettings()
+	if settings != nil {
+		if settings.IPAddress != "" {
+			return settings.IPAddress, nil
+		}
+		for _, network := range settings.Networks {
+			if network != nil && network.IPAddress != "" {
+				return network.IPAddress, nil
+			}
+		}
+	}
+	return "", errors.Errorf("no IP address found for container %s", container.Name)
+}
+
+// runHealthCheckProbe opens a TCP connection to, or sends a HTTP GET request to, the probed
+// port of the container. HTTP probes succeed on 2xx and 3xx responses.
+func runHealthCheckProbe(probe apicontainer.HealthCheckProbe, address string, timeout time.Duration) error {
+	target := net.JoinHostPort(address, strconv.Itoa(int(probe.Port)))
+	switch probe.Type {
+	case apicontainer.HealthCheckProbeTCP:
+		conn, err := net.DialTimeout("tcp", target, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case apicontainer.HealthCheckProbeHTTP:
+		path := probe.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		client := &http.Client{
+			// the zero transport doesn't go through the proxy configured for the agent
+			Transport: &http.Transport{},
+			Timeout:   timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		resp, err := client.Get("http://" + target + path)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+			return errors.Errorf("health check request to %s returned status %d", path, resp.StatusCode)
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported health check probe type %q", probe.Type)
+	}
+}
+
+// setContainerProbed marks the container as being probed. It returns false if it
+// already was.
+func (mtask *managedTask) setContainerProbed(containerName string) bool {
+	mtask.probedContainersLock.Lock()
+	defer mtask.probedContainersLock.Unlock()
+
+	if _, ok := mtask.probedContainers[containerName]; ok {
+		return false
+	}
+	if mtask.probedContainers == nil {
+		mtask.probedContainers = make(map[string]struct{})
+	}
+	mtask.probedContainers[containerName] = struct{}{}
+	return true
+}
@@ -0,0 +1,205 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckProbesStateRecord(t *testing.T) {
+	probes := []apicontainer.HealthCheckProbe{
+		{Type: apicontainer.HealthCheckProbeHTTP, Retries: 2},
+		{Type: apicontainer.HealthCheckProbeTCP},
+	}
+	state := &healthCheckProbesState{probes: make([]healthCheckProbeState, len(probes))}
+	probeErr := errors.New("connection refused")
+
+	health := state.record(0, probes[0], probeErr, true)
+	assert.Equal(t, apicontainerstatus.ContainerHealthUnknown, health.Status,
+		"Failures during the start period shouldn't count")
+	health = state.record(0, probes[0], nil, false)
+	assert.Equal(t, apicontainerstatus.ContainerHealthUnknown, health.Status,
+		"The container shouldn't be healthy until all of its probes succeed")
+	health = state.record(1, probes[1], nil, false)
+	assert.Equal(t, apicontainerstatus.ContainerHealthy, health.Status)
+
+	health = state.record(0, probes[0], probeErr, false)
+	assert.Equal(t, apicontainerstatus.ContainerHealthy, health.Status,
+		"The container should stay healthy until the probe fails as many times as its retries")
+	health = state.record(0, probes[0], probeErr, false)
+	assert.Equal(t, apicontainerstatus.ContainerUnhealthy, health.Status)
+	assert.Equal(t, "connection refused", health.Output)
+	assert.Equal(t, 1, health.ExitCode)
+
+	health = state.record(0, probes[0], nil, false)
+	assert.Equal(t, apicontainerstatus.ContainerHealthy, health.Status)
+}
+
+func TestHealthCheckProbeAddress(t *testing.T) {
+	container := &apicontainer.Container{Name: "c1"}
+	task := &apitask.Task{Arn: "myArn", Containers: []*apicontainer.Container{container}}
+	_, err := healthCheckProbeAddress(task, container)
+	assert.Error(t, err, "Containers without network settings have no address")
+
+	container.SetNetworkSettings(&types.NetworkSettings{
+		Networks: map[string]*network.EndpointSettings{
+			"bridge": {IPAddress: "172.17.0.2"},
+		},
+	})
+	address, err := healthCheckProbeAddress(task, container)
+	require.NoError(t, err)
+	assert.Equal(t, "172.17.0.2", address)
+
+	container.SetNetworkMode(hostNetworkMode)
+	address, err = healthCheckProbeAddress(task, container)
+	require.NoError(t, err)
+	assert.Equal(t, localhostIPv4, address)
+
+	task.AddTaskENI(&apieni.ENI{
+		IPV4Addresses: []*apieni.ENIIPV4Address{{Primary: true, Address: "10.0.0.5"}},
+	})
+	address, err = healthCheckProbeAddress(task, container)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", address)
+
+	task.ENIs[0].IPV4Addresses = nil
+	task.ENIs[0].IPV6Addresses = []*apieni.ENIIPV6Address{{Address: "2001:db8::5"}}
+	address, err = healthCheckProbeAddress(task, container)
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::5", address)
+}
+
+func TestRunHealthCheckProbeHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	probe := apicontainer.HealthCheckProbe{Type: apicontainer.HealthCheckProbeHTTP, Port: uint16(port), Path: "health"}
+	assert.NoError(t, runHealthCheckProbe(probe, serverURL.Hostname(), time.Second))
+	probe.Path = "/ready"
+	assert.Error(t, runHealthCheckProbe(probe, serverURL.Hostname(), time.Second))
+}
+
+func TestRunHealthCheckProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", localhostIPv4+":0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	probe := apicontainer.HealthCheckProbe{Type: apicontainer.HealthCheckProbeTCP, Port: uint16(port)}
+	assert.NoError(t, runHealthCheckProbe(probe, localhostIPv4, time.Second))
+	listener.Close()
+	assert.Error(t, runHealthCheckProbe(probe, localhostIPv4, time.Second))
+
+	probe.Type = "UDP"
+	assert.Error(t, runHealthCheckProbe(probe, localhostIPv4, time.Second))
+}
+
+func TestStartHealthCheckProbes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+
+	container := &apicontainer.Container{
+		Name:              "c1",
+		KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+		HealthCheckProbes: []apicontainer.HealthCheckProbe{
+			{Type: apicontainer.HealthCheckProbeTCP, Port: 80, IntervalSeconds: 10},
+		},
+	}
+	container.SetNetworkMode(hostNetworkMode)
+	mtask := &managedTask{
+		Task: &apitask.Task{
+			Arn:        "myArn",
+			Containers: []*apicontainer.Container{container},
+		},
+		ctx:    ctx,
+		engine: taskEngine.(*DockerTaskEngine),
+	}
+	mtask._time = mockTime
+
+	probed := make(chan string)
+	executeHealthCheckProbe = func(probe apicontainer.HealthCheckProbe, address string, timeout time.Duration) error {
+		probed <- address
+		return nil
+	}
+	defer func() {
+		executeHealthCheckProbe = runHealthCheckProbe
+	}()
+	intervalElapsed := make(chan time.Time)
+	mockTime.EXPECT().After(10 * time.Second).Return(intervalElapsed).AnyTimes()
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+
+	mtask.startHealthCheckProbes(container)
+	mtask.startHealthCheckProbes(container)
+	intervalElapsed <- time.Now()
+	assert.Equal(t, localhostIPv4, <-probed)
+	// the status is updated before the next interval is awaited
+	intervalElapsed <- time.Now()
+	assert.Equal(t, apicontainerstatus.ContainerHealthy, container.GetHealthStatus().Status)
+	history := container.GetHealthHistory()
+	assert.Len(t, history, 1)
+	assert.Equal(t, 0, history[0].ExitCode)
+	select {
+	case intervalElapsed <- time.Now():
+		t.Fatal("Container should be probed by a single probe loop")
+	case <-probed:
+	}
+}
+
+func TestStartHealthCheckProbesNotRunning(t *testing.T) {
+	mtask := &managedTask{
+		Task: &apitask.Task{Arn: "myArn"},
+	}
+	container := &apicontainer.Container{
+		Name:              "c1",
+		KnownStatusUnsafe: apicontainerstatus.ContainerCreated,
+		HealthCheckProbes: []apicontainer.HealthCheckProbe{{Type: apicontainer.HealthCheckProbeTCP, Port: 80}},
+	}
+	mtask.startHealthCheckProbes(container)
+	assert.Empty(t, mtask.probedContainers)
+
+	container.HealthCheckProbes = nil
+	container.SetKnownStatus(apicontainerstatus.ContainerRunning)
+	mtask.startHealthCheckProbes(container)
+	assert.Empty(t, mtask.probedContainers)
+}
@@ -0,0 +1,159 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+const (
+	// defaultContainerRestartBackoff is the delay before the first restart of
+	// containers whose restart policy doesn't specify one
+	defaultContainerRestartBackoff = 10 * time.Second
+	// maxContainerRestartBackoff caps the delay between restarts of a container
+	maxContainerRestartBackoff = 5 * time.Minute
+)
+
+// handleContainerExit restarts a running container that exited while its task is
+// running, if its restart policy allows it. It returns true if the container change
+// was handled, in which case the exit must not be applied to the container.
+func (mtask *managedTask) handleContainerExit(container *apicontainer.Container, event dockerapi.DockerContainerChangeEvent) bool {
+	policy := container.GetRestartPolicy()
+	if policy == nil || container.IsInternal() {
+		return false
+	}
+	if event.Status != apicontainerstatus.ContainerStopped || event.Error != nil ||
+		!container.GetKnownStatus().IsRunning() || container.GetDesiredStatus().Terminal() ||
+		mtask.GetDesiredStatus().Terminal() {
+		return false
+	}
+	if !mtask.setContainerRestarting(container.Name) {
+		// A restart is already scheduled, this is a duplicate event of the same exit
+		return true
+	}
+
+	restartCount := container.GetRestartCount()
+	resetWindow := time.Duration(policy.ResetWindowSeconds) * time.Second
+	if resetWindow > 0 && !event.StartedAt.IsZero() && event.FinishedAt.Sub(event.StartedAt) >= resetWindow {
+		restartCount = 0
+	}
+	if policy.MaxAttempts > 0 && restartCount >= policy.MaxAttempts {
+		logger.Warn("Container exited and has no restart attempts left", logger.Fields{
+			field.TaskARN:   mtask.Arn,
+			field.Container: container.Name,
+			"restartCount":  restartCount,
+		})
+		mtask.unsetContainerRestarting(container.Name)
+		return false
+	}
+
+	backoff := containerRestartBackoff(policy, restartCount)
+	container.SetRestartCount(restartCount + 1)
+	if event.ExitCode != nil {
+		container.SetKnownExitCode(event.ExitCode)
+	}
+	mtask.engine.saveContainerData(container)
+	logger.Info("Container exited; restarting it per its restart policy", logger.Fields{
+		field.TaskARN:   mtask.Arn,
+		field.Container: container.Name,
+		field.RuntimeID: container.GetRuntimeID(),
+		"restartCount":  restartCount + 1,
+		"backoff":       backoff.String(),
+	})
+	go mtask.restartContainer(container, backoff)
+	return true
+}
+
+// restartContainer starts the exited container again once the backoff elapses. If the
+// container can't be started, its exit is reported again, so that it's retried with
+// the remaining restart attempts or stopped.
+func (mtask *managedTask) restartContainer(container *apicontainer.Container, backoff time.Duration) {
+	select {
+	case <-mtask.time().After(backoff):
+	case <-mtask.ctx.Done():
+		return
+	}
+	if mtask.GetDesiredStatus().Terminal() || container.GetDesiredStatus().Terminal() {
+		// The exited container is stopped along with the rest of the task
+		mtask.unsetContainerRestarting(container.Name)
+		return
+	}
+
+	metadata := mtask.engine.startContainer(mtask.Task, container)
+	mtask.unsetContainerRestarting(container.Name)
+	if metadata.Error == nil {
+		return
+	}
+	logger.Warn("Error restarting container", logger.Fields{
+		field.TaskARN:   mtask.Arn,
+		field.Container: container.Name,
+		field.RuntimeID: container.GetRuntimeID(),
+		field.Error:     metadata.Error,
+	})
+	mtask.emitDockerContainerChange(dockerContainerChange{
+		container: container,
+		event: dockerapi.DockerContainerChangeEvent{
+			Status: apicontainerstatus.ContainerStopped,
+			DockerContainerMetadata: dockerapi.DockerContainerMetadata{
+				DockerID: container.GetRuntimeID(),
+				ExitCode: container.GetKnownExitCode(),
+			},
+		},
+	})
+}
+
+// containerRestartBackoff returns the delay before restarting a container that was
+// already restarted restartCount times
+func containerRestartBackoff(policy *apicontainer.RestartPolicy, restartCount int) time.Duration {
+	backoff := defaultContainerRestartBackoff
+	if policy.BackoffSeconds > 0 {
+		backoff = time.Duration(policy.BackoffSeconds) * time.Second
+	}
+	for i := 0; i < restartCount && backoff < maxContainerRestartBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxContainerRestartBackoff {
+		backoff = maxContainerRestartBackoff
+	}
+	return backoff
+}
+
+// setContainerRestarting marks the container as waiting to be restarted. It returns
+// false if it already was.
+func (mtask *managedTask) setContainerRestarting(containerName string) bool {
+	mtask.restartingContainersLock.Lock()
+	defer mtask.restartingContainersLock.Unlock()
+
+	if _, ok := mtask.restartingContainers[containerName]; ok {
+		return false
+	}
+	if mtask.restartingContainers == nil {
+		mtask.restartingContainers = make(map[string]struct{})
+	}
+	mtask.restartingContainers[containerName] = struct{}{}
+	return true
+}
+
+func (mtask *managedTask) unsetContainerRestarting(containerName string) {
+	mtask.restartingContainersLock.Lock()
+	defer mtask.restartingContainersLock.Unlock()
+
+	delete(mtask.restartingContainers, containerName)
+}
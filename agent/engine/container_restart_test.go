@@ -0,0 +1,180 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRestartableTask(ctx context.Context, engine *DockerTaskEngine, policy *apicontainer.RestartPolicy) *managedTask {
+	container := &apicontainer.Container{
+		Name:                "c1",
+		KnownStatusUnsafe:   apicontainerstatus.ContainerRunning,
+		DesiredStatusUnsafe: apicontainerstatus.ContainerRunning,
+		RestartPolicy:       policy,
+	}
+	container.SetRuntimeID(containerID)
+	return &managedTask{
+		Task: &apitask.Task{
+			Arn:                 "myArn",
+			Containers:          []*apicontainer.Container{container},
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		},
+		ctx:            ctx,
+		engine:         engine,
+		dockerMessages: make(chan dockerContainerChange),
+	}
+}
+
+func exitEvent(exitCode int) dockerapi.DockerContainerChangeEvent {
+	return dockerapi.DockerContainerChangeEvent{
+		Status: apicontainerstatus.ContainerStopped,
+		DockerContainerMetadata: dockerapi.DockerContainerMetadata{
+			DockerID: containerID,
+			ExitCode: aws.Int(exitCode),
+		},
+	}
+}
+
+func TestHandleContainerExitRestartsContainer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	mtask := newRestartableTask(ctx, taskEngine.(*DockerTaskEngine), &apicontainer.RestartPolicy{BackoffSeconds: 2})
+	mtask._time = mockTime
+	container := mtask.Containers[0]
+
+	backoffElapsed := make(chan time.Time)
+	restarted := make(chan struct{})
+	mockTime.EXPECT().After(2 * time.Second).Return(backoffElapsed)
+	client.EXPECT().StartContainer(gomock.Any(), containerID, defaultConfig.ContainerStartTimeout).Do(
+		func(ctx context.Context, id string, timeout time.Duration) {
+			close(restarted)
+		}).Return(dockerapi.DockerContainerMetadata{DockerID: containerID})
+
+	assert.True(t, mtask.handleContainerExit(container, exitEvent(1)))
+	assert.True(t, mtask.handleContainerExit(container, exitEvent(1)),
+		"Duplicate exit events should be ignored while the restart is pending")
+	backoffElapsed <- time.Now()
+	<-restarted
+
+	assert.Equal(t, 1, container.GetRestartCount())
+	assert.Equal(t, aws.Int(1), container.GetKnownExitCode())
+	assert.Equal(t, apicontainerstatus.ContainerRunning, container.GetKnownStatus())
+}
+
+func TestHandleContainerExitRestartError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	mtask := newRestartableTask(ctx, taskEngine.(*DockerTaskEngine), &apicontainer.RestartPolicy{MaxAttempts: 1})
+	mtask._time = mockTime
+	container := mtask.Containers[0]
+
+	backoffElapsed := make(chan time.Time, 1)
+	backoffElapsed <- time.Now()
+	mockTime.EXPECT().After(defaultContainerRestartBackoff).Return(backoffElapsed)
+	client.EXPECT().StartContainer(gomock.Any(), containerID, gomock.Any()).Return(dockerapi.DockerContainerMetadata{
+		Error: dockerapi.CannotStartContainerError{FromError: errors.New("error")},
+	})
+
+	require.True(t, mtask.handleContainerExit(container, exitEvent(1)))
+	change := <-mtask.dockerMessages
+	assert.Equal(t, container, change.container)
+	assert.Equal(t, apicontainerstatus.ContainerStopped, change.event.Status)
+	assert.Equal(t, aws.Int(1), change.event.ExitCode)
+	assert.False(t, mtask.handleContainerExit(container, change.event),
+		"The exit should be applied once the container has no restart attempts left")
+}
+
+func TestHandleContainerExitNotRestarted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	mtask := newRestartableTask(ctx, engine, nil)
+	assert.False(t, mtask.handleContainerExit(mtask.Containers[0], exitEvent(1)), "No restart policy")
+
+	mtask = newRestartableTask(ctx, engine, &apicontainer.RestartPolicy{})
+	mtask.SetDesiredStatus(apitaskstatus.TaskStopped)
+	assert.False(t, mtask.handleContainerExit(mtask.Containers[0], exitEvent(1)), "Task is stopping")
+
+	mtask = newRestartableTask(ctx, engine, &apicontainer.RestartPolicy{})
+	event := exitEvent(1)
+	event.Error = dockerapi.CannotStopContainerError{FromError: errors.New("error")}
+	assert.False(t, mtask.handleContainerExit(mtask.Containers[0], event), "Container failed to stop")
+
+	mtask = newRestartableTask(ctx, engine, &apicontainer.RestartPolicy{MaxAttempts: 2})
+	mtask.Containers[0].SetRestartCount(2)
+	assert.False(t, mtask.handleContainerExit(mtask.Containers[0], exitEvent(1)), "No restart attempts left")
+}
+
+func TestHandleContainerExitResetsRestartAttempts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	mtask := newRestartableTask(ctx, taskEngine.(*DockerTaskEngine),
+		&apicontainer.RestartPolicy{MaxAttempts: 2, ResetWindowSeconds: 60})
+	mtask._time = mockTime
+	container := mtask.Containers[0]
+	container.SetRestartCount(2)
+
+	// The restart is never triggered, the task context is canceled first
+	restartScheduled := make(chan struct{})
+	mockTime.EXPECT().After(defaultContainerRestartBackoff).Do(func(d time.Duration) {
+		close(restartScheduled)
+	})
+	event := exitEvent(1)
+	event.StartedAt = time.Now().Add(-time.Hour)
+	event.FinishedAt = time.Now()
+	assert.True(t, mtask.handleContainerExit(container, event))
+	<-restartScheduled
+	assert.Equal(t, 1, container.GetRestartCount())
+}
+
+func TestContainerRestartBackoff(t *testing.T) {
+	testCases := []struct {
+		policy          apicontainer.RestartPolicy
+		restartCount    int
+		expectedBackoff time.Duration
+	}{
+		{restartCount: 0, expectedBackoff: defaultContainerRestartBackoff},
+		{restartCount: 2, expectedBackoff: 4 * defaultContainerRestartBackoff},
+		{policy: apicontainer.RestartPolicy{BackoffSeconds: 1}, restartCount: 3, expectedBackoff: 8 * time.Second},
+		{policy: apicontainer.RestartPolicy{BackoffSeconds: 1}, restartCount: 100, expectedBackoff: maxContainerRestartBackoff},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedBackoff, containerRestartBackoff(&tc.policy, tc.restartCount))
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+// checkContainerRuntime verifies that docker has the container runtime the task
+// requests, so that the containers fail with the reason rather than with the error
+// of docker, when the runtime isn't installed or isn't declared in the config of
+// the docker daemon. The runtimes are looked up for every container, as the docker
+// daemon can be reloaded with new runtimes while the agent runs.
+func (engine *DockerTaskEngine) checkContainerRuntime(task *apitask.Task,
+	hostConfig *dockercontainer.HostConfig) apierrors.NamedError {
+	if task.ContainerRuntime == "" || hostConfig.Runtime != task.ContainerRuntime {
+		return nil
+	}
+	info, err := engine.client.Info(engine.ctx, dockerclient.InfoTimeout)
+	if err != nil {
+		return ContainerRuntimeError{errors.Wrap(err, "unable to list the runtimes of docker")}
+	}
+	if _, ok := info.Runtimes[task.ContainerRuntime]; !ok {
+		return ContainerRuntimeError{errors.Errorf("container runtime %s is not installed", task.ContainerRuntime)}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContainerRuntimeTestEngine(t *testing.T) (*gomock.Controller, *mock_dockerapi.MockDockerClient, *DockerTaskEngine) {
+	ctrl := gomock.NewController(t)
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	engine := &DockerTaskEngine{
+		ctx:    context.TODO(),
+		client: client,
+	}
+	return ctrl, client, engine
+}
+
+func TestCheckContainerRuntime(t *testing.T) {
+	ctrl, client, engine := newContainerRuntimeTestEngine(t)
+	defer ctrl.Finish()
+	client.EXPECT().Info(gomock.Any(), gomock.Any()).Return(types.Info{
+		Runtimes: map[string]types.Runtime{
+			"runc":  {Path: "runc"},
+			"runsc": {Path: "/usr/local/bin/runsc"},
+		},
+	}, nil)
+
+	task := &apitask.Task{Arn: "task1", ContainerRuntime: apitask.ContainerRuntimeGVisor}
+	assert.Nil(t, engine.checkContainerRuntime(task, &dockercontainer.HostConfig{Runtime: apitask.ContainerRuntimeGVisor}))
+}
+
+func TestCheckContainerRuntimeNotInstalled(t *testing.T) {
+	ctrl, client, engine := newContainerRuntimeTestEngine(t)
+	defer ctrl.Finish()
+	client.EXPECT().Info(gomock.Any(), gomock.Any()).Return(types.Info{
+		Runtimes: map[string]types.Runtime{"runc": {Path: "runc"}},
+	}, nil)
+
+	task := &apitask.Task{Arn: "task1", ContainerRuntime: apitask.ContainerRuntimeKata}
+	err := engine.checkContainerRuntime(task, &dockercontainer.HostConfig{Runtime: apitask.ContainerRuntimeKata})
+	assert.IsType(t, ContainerRuntimeError{}, err)
+	assert.Contains(t, err.Error(), "kata-runtime is not installed")
+}
+
+func TestCheckContainerRuntimeInfoError(t *testing.T) {
+	ctrl, client, engine := newContainerRuntimeTestEngine(t)
+	defer ctrl.Finish()
+	client.EXPECT().Info(gomock.Any(), gomock.Any()).Return(types.Info{}, errors.New("docker is unavailable"))
+
+	task := &apitask.Task{Arn: "task1", ContainerRuntime: apitask.ContainerRuntimeGVisor}
+	err := engine.checkContainerRuntime(task, &dockercontainer.HostConfig{Runtime: apitask.ContainerRuntimeGVisor})
+	assert.IsType(t, ContainerRuntimeError{}, err)
+}
+
+func TestCheckContainerRuntimeSkipped(t *testing.T) {
+	ctrl, _, engine := newContainerRuntimeTestEngine(t)
+	defer ctrl.Finish()
+
+	// Docker isn't asked for the runtimes of the tasks without one, nor for the
+	// internal containers, which keep the default runtime
+	assert.Nil(t, engine.checkContainerRuntime(&apitask.Task{Arn: "task1"}, &dockercontainer.HostConfig{}))
+	task := &apitask.Task{Arn: "task2", ContainerRuntime: apitask.ContainerRuntimeGVisor}
+	assert.Nil(t, engine.checkContainerRuntime(task, &dockercontainer.HostConfig{}))
+}
@@ -40,6 +40,14 @@ const (
 	completeCondition = "COMPLETE"
 	// HealthyCondition ensures that a container progresses to next state only when dependency container is healthy
 	healthyCondition = "HEALTHY"
+	// OnFailureCondition ensures that a container progresses to next state only when
+	// dependency container has completed with a non-zero exit code. The container is
+	// not started at all if the dependency container exits successfully
+	onFailureCondition = "ON_FAILURE"
+	// CompleteWithinCondition ensures that a container progresses to next state only when
+	// dependency container has completed, and fails the dependency if it has not completed
+	// within the timeout of the dependency
+	completeWithinCondition = "COMPLETE_WITHIN"
 	// 0 is the standard exit code for success.
 	successExitCode = 0
 )
@@ -63,6 +71,9 @@ var (
 	// ErrContainerDependencyNotResolvedForResource is when the resource's dependencies
 	// on other containers are not resolved
 	ErrContainerDependencyNotResolvedForResource = errors.New("dependency graph: resource's dependency on containers not resolved")
+	// ErrOnFailureDependencySucceeded is when the container depends on another container
+	// failing, but that container exited successfully. The container should not be started
+	ErrOnFailureDependencySucceeded = errors.New("dependency graph: ON_FAILURE dependency exited successfully")
 )
 
 // ValidDependencies takes a task and verifies that it is possible to allow all
@@ -157,8 +168,8 @@ func DependenciesAreResolved(target *apicontainer.Container,
 	// If the target is desired terminal and isn't stopped, we should validate that it doesn't have any containers
 	// that are dependent on it that need to shut down first.
 	if target.DesiredTerminal() && !target.KnownTerminal() {
-		if err := verifyShutdownOrder(target, nameMap); err != nil {
-			return nil, err
+		if blocked, err := verifyShutdownOrder(target, nameMap, cfg); err != nil {
+			return blocked, err
 		}
 	}
 
@@ -270,6 +281,16 @@ func verifyContainerOrderingStatusResolvable(target *apicontainer.Container, exi
 			if hasDependencyTimedOut(dependencyContainer, dependency.Condition) {
 				return nil, fmt.Errorf("dependency graph: container ordering dependency [%v] for target [%v] has timed out.", dependencyContainer, target)
 			}
+			if hasDependencyCompletionTimedOut(dependencyContainer, dependency) {
+				return nil, fmt.Errorf("dependency graph: container ordering dependency [%v] for target [%v] did not complete within %ds.", dependencyContainer, target, dependency.Timeout)
+			}
+		}
+
+		// A container that depends on another container failing can never start once that container
+		// has exited successfully
+		if dependency.Condition == onFailureCondition && dependencyContainer.GetKnownStatus() == apicontainerstatus.ContainerStopped &&
+			hasDependencyStoppedSuccessfully(dependencyContainer) {
+			return nil, ErrOnFailureDependencySucceeded
 		}
 
 		// We want to fail fast if the dependency container has stopped but did not exit successfully because target container
@@ -374,7 +395,7 @@ func containerOrderingDependenciesCanResolve(target *apicontainer.Container,
 		}
 		return verifyContainerOrderingStatus(dependsOnContainer) || dependencyStoppedSuccessfully
 
-	case completeCondition:
+	case completeCondition, completeWithinCondition, onFailureCondition:
 		return verifyContainerOrderingStatus(dependsOnContainer)
 
 	case healthyCondition:
@@ -430,11 +451,20 @@ func containerOrderingDependenciesIsResolved(target *apicontainer.Container,
 		}
 		return false
 
-	case completeCondition:
+	case completeCondition, completeWithinCondition:
 		// The 'target' container desires to be moved to 'Created' or the 'steady' state.
 		// Allow this only if the known status of the dependency container state is stopped with any exit code
 		return dependsOnContainerKnownStatus == apicontainerstatus.ContainerStopped && dependsOnContainer.GetKnownExitCode() != nil
 
+	case onFailureCondition:
+		// The 'target' container desires to be moved to 'Created' or the 'steady' state.
+		// Allow this only if the known status of the dependency container state is stopped with a non-zero exit code
+		if dependsOnContainer.GetKnownExitCode() != nil {
+			return dependsOnContainerKnownStatus == apicontainerstatus.ContainerStopped &&
+				*dependsOnContainer.GetKnownExitCode() != successExitCode
+		}
+		return false
+
 	case healthyCondition:
 		return dependsOnContainer.HealthStatusShouldBeReported() &&
 			dependsOnContainer.GetHealthStatus().Status == apicontainerstatus.ContainerHealthy
@@ -456,6 +486,22 @@ func hasDependencyTimedOut(dependOnContainer *apicontainer.Container, dependency
 	}
 }
 
+// hasDependencyCompletionTimedOut returns true if a COMPLETE_WITHIN dependency has not
+// completed within the timeout of the dependency, counted from when the dependency started.
+// Without a timeout the start timeout of the dependency container applies, as for COMPLETE
+func hasDependencyCompletionTimedOut(dependOnContainer *apicontainer.Container, dependency apicontainer.DependsOn) bool {
+	if dependency.Condition != completeWithinCondition {
+		return false
+	}
+	if dependency.Timeout <= 0 {
+		return hasDependencyTimedOut(dependOnContainer, completeCondition)
+	}
+	if dependOnContainer.GetStartedAt().IsZero() {
+		return false
+	}
+	return time.Now().After(dependOnContainer.GetStartedAt().Add(time.Duration(dependency.Timeout) * time.Second))
+}
+
 func hasDependencyStoppedSuccessfully(dependency *apicontainer.Container) bool {
 	p := dependency.GetKnownExitCode()
 	return p != nil && *p == 0
@@ -471,30 +517,77 @@ func verifyContainerOrderingStatus(dependsOnContainer *apicontainer.Container) b
 		dependsOnContainerDesiredStatus == dependsOnContainer.GetSteadyStateStatus()
 }
 
-func verifyShutdownOrder(target *apicontainer.Container, existingContainers map[string]*apicontainer.Container) error {
+// verifyShutdownOrder returns an error if containers depending on the target container
+// haven't stopped yet, or if the target container is still in its shutdown grace period
+// after they stopped. In the latter case the container the target is waiting on is
+// returned as well, since the target will be stopped without any other event happening.
+func verifyShutdownOrder(target *apicontainer.Container, existingContainers map[string]*apicontainer.Container,
+	cfg *config.Config) (*apicontainer.DependsOn, error) {
 	// We considered adding this to the task state, but this will be at most 45 loops,
 	// so we err'd on the side of having less state.
 	missingShutdownDependencies := []string{}
+	var gracePeriodDependency *apicontainer.Container
+	var gracePeriodEnd time.Time
+
+	gracePeriod := target.GetShutdownGracePeriod()
+	if gracePeriod == 0 && cfg != nil {
+		gracePeriod = cfg.ContainerShutdownGracePeriod
+	}
+	orderAllDependencies := cfg != nil && cfg.DependencyOrderedShutdown.Enabled()
 
 	for _, existingContainer := range existingContainers {
-		dependencies := existingContainer.GetDependsOn()
-		for _, dependency := range dependencies {
-			// If another container declares a dependency on our target, we will want to verify that the container is
-			// stopped.
-			if dependency.ContainerName == target.Name {
-				if !existingContainer.KnownTerminal() {
-					missingShutdownDependencies = append(missingShutdownDependencies, existingContainer.Name)
-				}
-			}
+		// If another container declares a dependency on our target, we will want to verify that the container is
+		// stopped.
+		if !isShutdownDependency(existingContainer, target, orderAllDependencies) {
+			continue
+		}
+		if !existingContainer.KnownTerminal() {
+			missingShutdownDependencies = append(missingShutdownDependencies, existingContainer.Name)
+			continue
+		}
+		finishedAt := existingContainer.GetFinishedAt()
+		if gracePeriod > 0 && !finishedAt.IsZero() && finishedAt.Add(gracePeriod).After(gracePeriodEnd) {
+			gracePeriodDependency = existingContainer
+			gracePeriodEnd = finishedAt.Add(gracePeriod)
 		}
 	}
 
-	if len(missingShutdownDependencies) == 0 {
-		return nil
+	if len(missingShutdownDependencies) != 0 {
+		return nil, fmt.Errorf("dependency graph: target %s needs other containers stopped before it can stop: [%s]",
+			target.Name, strings.Join(missingShutdownDependencies, "], ["))
+	}
+	if gracePeriodDependency != nil && time.Now().Before(gracePeriodEnd) {
+		return &apicontainer.DependsOn{ContainerName: gracePeriodDependency.Name},
+			fmt.Errorf("dependency graph: target %s is in its shutdown grace period until %s, after %s stopped",
+				target.Name, gracePeriodEnd.Format(time.RFC3339), gracePeriodDependency.Name)
 	}
+	return nil, nil
+}
 
-	return fmt.Errorf("dependency graph: target %s needs other containers stopped before it can stop: [%s]",
-		target.Name, strings.Join(missingShutdownDependencies, "], ["))
+// isShutdownDependency returns true if the container has to stop before the target
+// container is stopped: if it declares a container ordering dependency on the target
+// and, when all dependencies are ordered, if it links to the target or uses its volumes
+func isShutdownDependency(container *apicontainer.Container, target *apicontainer.Container,
+	orderAllDependencies bool) bool {
+	for _, dependency := range container.GetDependsOn() {
+		if dependency.ContainerName == target.Name {
+			return true
+		}
+	}
+	if !orderAllDependencies {
+		return false
+	}
+	for _, link := range container.Links {
+		if strings.SplitN(link, ":", 2)[0] == target.Name {
+			return true
+		}
+	}
+	for _, volume := range container.VolumesFrom {
+		if volume.SourceContainer == target.Name {
+			return true
+		}
+	}
+	return false
 }
 
 func onSteadyStateCanResolve(target *apicontainer.Container, run *apicontainer.Container) bool {
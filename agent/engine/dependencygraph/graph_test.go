@@ -30,6 +30,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func volumeStrToVol(vols []string) []apicontainer.VolumeFrom {
@@ -964,6 +965,39 @@ func TestContainerOrderingIsResolved(t *testing.T) {
 			DependencyCondition: completeCondition,
 			Resolved:            false,
 		},
+		{
+			TargetDesired:       apicontainerstatus.ContainerRunning,
+			DependencyKnown:     apicontainerstatus.ContainerStopped,
+			DependencyCondition: completeWithinCondition,
+			ExitCode:            1,
+			Resolved:            true,
+		},
+		{
+			TargetDesired:       apicontainerstatus.ContainerRunning,
+			DependencyKnown:     apicontainerstatus.ContainerRunning,
+			DependencyCondition: completeWithinCondition,
+			Resolved:            false,
+		},
+		{
+			TargetDesired:       apicontainerstatus.ContainerRunning,
+			DependencyKnown:     apicontainerstatus.ContainerStopped,
+			DependencyCondition: onFailureCondition,
+			ExitCode:            1,
+			Resolved:            true,
+		},
+		{
+			TargetDesired:       apicontainerstatus.ContainerRunning,
+			DependencyKnown:     apicontainerstatus.ContainerStopped,
+			DependencyCondition: onFailureCondition,
+			ExitCode:            0,
+			Resolved:            false,
+		},
+		{
+			TargetDesired:       apicontainerstatus.ContainerRunning,
+			DependencyKnown:     apicontainerstatus.ContainerRunning,
+			DependencyCondition: onFailureCondition,
+			Resolved:            false,
+		},
 	}
 	cfg := config.Config{}
 	for _, tc := range testcases {
@@ -1310,15 +1344,73 @@ func TestVerifyShutdownOrder(t *testing.T) {
 			}
 
 			// Validation
+			_, err := verifyShutdownOrder(target, others, &config.Config{})
 			if tc.ShouldResolve {
-				assert.NoError(t, verifyShutdownOrder(target, others))
+				assert.NoError(t, err)
 			} else {
-				assert.Error(t, verifyShutdownOrder(target, others))
+				assert.Error(t, err)
 			}
 		})
 	}
 }
 
+func TestVerifyShutdownOrderAllDependencies(t *testing.T) {
+	others := map[string]*apicontainer.Container{
+		"app": {
+			Name:              "app",
+			Links:             []string{"db:database"},
+			KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+		},
+		"sidecar": {
+			Name:              "sidecar",
+			VolumesFrom:       []apicontainer.VolumeFrom{{SourceContainer: "data"}},
+			KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+		},
+	}
+	for _, targetName := range []string{"db", "data"} {
+		t.Run(targetName, func(t *testing.T) {
+			target := &apicontainer.Container{
+				Name:                targetName,
+				KnownStatusUnsafe:   apicontainerstatus.ContainerRunning,
+				DesiredStatusUnsafe: apicontainerstatus.ContainerStopped,
+			}
+			_, err := verifyShutdownOrder(target, others, &config.Config{})
+			assert.NoError(t, err, "Links and volumes should only be ordered when enabled")
+
+			cfg := &config.Config{DependencyOrderedShutdown: config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}}
+			_, err = verifyShutdownOrder(target, others, cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestVerifyShutdownOrderGracePeriod(t *testing.T) {
+	app := &apicontainer.Container{
+		Name:              "app",
+		DependsOnUnsafe:   dependsOn("log-router"),
+		KnownStatusUnsafe: apicontainerstatus.ContainerStopped,
+	}
+	app.SetFinishedAt(time.Now())
+	others := map[string]*apicontainer.Container{"app": app}
+	target := &apicontainer.Container{
+		Name:                "log-router",
+		KnownStatusUnsafe:   apicontainerstatus.ContainerRunning,
+		DesiredStatusUnsafe: apicontainerstatus.ContainerStopped,
+	}
+
+	blocked, err := verifyShutdownOrder(target, others, &config.Config{ContainerShutdownGracePeriod: time.Minute})
+	assert.Error(t, err)
+	require.NotNil(t, blocked, "Container in its grace period should be reported as blocked")
+	assert.Equal(t, "app", blocked.ContainerName)
+
+	// the grace period of the container takes precedence
+	target.ShutdownGracePeriod = 1
+	app.SetFinishedAt(time.Now().Add(-2 * time.Second))
+	blocked, err = verifyShutdownOrder(target, others, &config.Config{ContainerShutdownGracePeriod: time.Minute})
+	assert.NoError(t, err)
+	assert.Nil(t, blocked)
+}
+
 func TestStartTimeoutForContainerOrdering(t *testing.T) {
 	testcases := []struct {
 		DependencyStartedAt    time.Time
@@ -1395,3 +1487,84 @@ func TestVerifyContainerOrderingStatusResolvableFailOnDependencyWontStart(t *tes
 	_, err := verifyContainerOrderingStatusResolvable(target, contMap, &config.Config{}, dummyResolves)
 	assert.Error(t, err)
 }
+
+func TestCompletionTimeoutForContainerOrdering(t *testing.T) {
+	testcases := []struct {
+		name                   string
+		dependencyStartedAt    time.Time
+		dependencyStartTimeout uint
+		dependency             apicontainer.DependsOn
+		expectedTimedOut       bool
+	}{
+		{
+			name:                "timed out",
+			dependencyStartedAt: time.Now().Add(-time.Minute),
+			dependency:          apicontainer.DependsOn{Condition: completeWithinCondition, Timeout: 30},
+			expectedTimedOut:    true,
+		},
+		{
+			name:                "within timeout",
+			dependencyStartedAt: time.Now().Add(-time.Minute),
+			dependency:          apicontainer.DependsOn{Condition: completeWithinCondition, Timeout: 300},
+			expectedTimedOut:    false,
+		},
+		{
+			name:                "not started",
+			dependencyStartedAt: time.Time{},
+			dependency:          apicontainer.DependsOn{Condition: completeWithinCondition, Timeout: 30},
+			expectedTimedOut:    false,
+		},
+		{
+			name:                   "start timeout without timeout",
+			dependencyStartedAt:    time.Now().Add(-time.Minute),
+			dependencyStartTimeout: 10,
+			dependency:             apicontainer.DependsOn{Condition: completeWithinCondition},
+			expectedTimedOut:       true,
+		},
+		{
+			name:                "other condition",
+			dependencyStartedAt: time.Now().Add(-time.Minute),
+			dependency:          apicontainer.DependsOn{Condition: completeCondition, Timeout: 30},
+			expectedTimedOut:    false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := &apicontainer.Container{
+				Name:         "dep",
+				StartTimeout: tc.dependencyStartTimeout,
+			}
+			dep.SetStartedAt(tc.dependencyStartedAt)
+			assert.Equal(t, tc.expectedTimedOut, hasDependencyCompletionTimedOut(dep, tc.dependency))
+		})
+	}
+}
+
+func TestVerifyContainerOrderingStatusResolvableOnFailureDependencySucceeded(t *testing.T) {
+	targetName := "target"
+	dependencyName := "dependency"
+	target := &apicontainer.Container{
+		Name:                targetName,
+		KnownStatusUnsafe:   apicontainerstatus.ContainerPulled,
+		DesiredStatusUnsafe: apicontainerstatus.ContainerRunning,
+		DependsOnUnsafe: []apicontainer.DependsOn{
+			{
+				ContainerName: dependencyName,
+				Condition:     onFailureCondition,
+			},
+		},
+	}
+	dep := &apicontainer.Container{
+		Name:                dependencyName,
+		KnownStatusUnsafe:   apicontainerstatus.ContainerStopped,
+		DesiredStatusUnsafe: apicontainerstatus.ContainerStopped,
+	}
+	dep.SetKnownExitCode(aws.Int(0))
+	contMap := map[string]*apicontainer.Container{
+		targetName:     target,
+		dependencyName: dep,
+	}
+	_, err := verifyContainerOrderingStatusResolvable(target, contMap, &config.Config{}, containerOrderingDependenciesIsResolved)
+	assert.Equal(t, ErrOnFailureDependencySucceeded, err)
+}
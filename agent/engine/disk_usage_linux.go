@@ -0,0 +1,41 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// diskUsagePercent returns the usage, in percent, of the host filesystem that
+// holds the path. Host paths such as the docker data root aren't mounted in
+// the agent container, so the path is looked up under the root of the host's
+// init process. Like df, the blocks reserved for the root user are counted as
+// unavailable
+func diskUsagePercent(path string) (float64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(filepath.Join(hostProcFSPath, "1", "root", path), &stat); err != nil {
+		return 0, errors.Wrapf(err, "unable to get the disk usage of %s", path)
+	}
+	used := stat.Blocks - stat.Bfree
+	total := used + stat.Bavail
+	if total == 0 {
+		return 0, errors.Errorf("unable to get the disk usage of %s: filesystem has no blocks", path)
+	}
+	return float64(used) * 100 / float64(total), nil
+}
@@ -0,0 +1,45 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskUsagePercent(t *testing.T) {
+	// the root of the host's init process is the root of the test's filesystem
+	procFS, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procFS)
+	require.NoError(t, os.Mkdir(filepath.Join(procFS, "1"), 0755))
+	require.NoError(t, os.Symlink("/", filepath.Join(procFS, "1", "root")))
+	original := hostProcFSPath
+	defer func() { hostProcFSPath = original }()
+	hostProcFSPath = procFS
+
+	usage, err := diskUsagePercent("/tmp")
+	assert.NoError(t, err)
+	assert.True(t, usage >= 0 && usage <= 100, "disk usage should be a percentage, got %f", usage)
+
+	_, err = diskUsagePercent("/does/not/exist")
+	assert.Error(t, err)
+}
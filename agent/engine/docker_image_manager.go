@@ -30,6 +30,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
 	"github.com/cihub/seelog"
 )
 
@@ -41,11 +42,15 @@ const (
 // adding and removing container references to ImageStates
 type ImageManager interface {
 	RecordContainerReference(container *apicontainer.Container) error
+	// RecordImage adds an image pulled without a container to the image states
+	RecordImage(imageName string) (*image.ImageState, error)
 	RemoveContainerReferenceFromImageState(container *apicontainer.Container) error
 	AddAllImageStates(imageStates []*image.ImageState)
 	GetImageStateFromImageName(containerImageName string) (*image.ImageState, bool)
 	StartImageCleanupProcess(ctx context.Context)
 	SetDataClient(dataClient data.Client)
+	// SetCleanupConfig applies the reloaded image cleanup settings
+	SetCleanupConfig(cfg config.ReloadableConfig)
 }
 
 // dockerImageManager accounts all the images and their states in the instance.
@@ -67,6 +72,16 @@ type dockerImageManager struct {
 	nonECSContainerCleanupWaitDuration time.Duration
 	numNonECSContainersToDelete        int
 	nonECSMinimumAgeBeforeDeletion     time.Duration
+	diskHighWatermark                  int
+	diskLowWatermark                   int
+	diskCheckInterval                  time.Duration
+	// diskUsage returns the usage, in percent, of the disk that holds a path
+	diskUsage func(path string) (float64, error)
+	// dockerDataRoot is the docker data root, found the first time the disk
+	// usage is measured
+	dockerDataRoot string
+	// cleanupIntervalUpdates receives the image cleanup interval when it's reloaded
+	cleanupIntervalUpdates chan time.Duration
 }
 
 // ImageStatesForDeletion is used for implementing the sort interface
@@ -80,12 +95,17 @@ func NewImageManager(cfg *config.Config, client dockerapi.DockerClient, state do
 		minimumAgeBeforeDeletion:           cfg.MinimumImageDeletionAge,
 		numImagesToDelete:                  cfg.NumImagesToDeletePerCycle,
 		imageCleanupTimeInterval:           cfg.ImageCleanupInterval,
+		cleanupIntervalUpdates:             make(chan time.Duration, 1),
 		imagePullBehavior:                  cfg.ImagePullBehavior,
 		imageCleanupExclusionList:          buildImageCleanupExclusionList(cfg),
 		deleteNonECSImagesEnabled:          cfg.DeleteNonECSImagesEnabled,
 		nonECSContainerCleanupWaitDuration: cfg.TaskCleanupWaitDuration,
 		numNonECSContainersToDelete:        cfg.NumNonECSContainersToDeletePerCycle,
 		nonECSMinimumAgeBeforeDeletion:     cfg.NonECSMinimumImageDeletionAge,
+		diskHighWatermark:                  cfg.ImageCleanupDiskHighWatermark,
+		diskLowWatermark:                   cfg.ImageCleanupDiskLowWatermark,
+		diskCheckInterval:                  cfg.ImageCleanupDiskCheckInterval,
+		diskUsage:                          diskUsagePercent,
 	}
 }
 
@@ -94,6 +114,31 @@ func (imageManager *dockerImageManager) SetDataClient(dataClient data.Client) {
 	imageManager.dataClient = dataClient
 }
 
+// SetCleanupConfig applies the reloaded image cleanup settings. The settings apply from
+// the next image cleanup, and a new cleanup interval restarts the wait for it
+func (imageManager *dockerImageManager) SetCleanupConfig(cfg config.ReloadableConfig) {
+	imageManager.updateLock.Lock()
+	defer imageManager.updateLock.Unlock()
+
+	imageManager.minimumAgeBeforeDeletion = cfg.MinimumImageDeletionAge
+	imageManager.nonECSMinimumAgeBeforeDeletion = cfg.NonECSMinimumImageDeletionAge
+	imageManager.numImagesToDelete = cfg.NumImagesToDeletePerCycle
+	imageManager.numNonECSContainersToDelete = cfg.NumNonECSContainersToDeletePerCycle
+	if cfg.ImageCleanupInterval == imageManager.imageCleanupTimeInterval {
+		return
+	}
+	imageManager.imageCleanupTimeInterval = cfg.ImageCleanupInterval
+	// replace the interval the cleanup loop didn't pick up yet, if any
+	select {
+	case <-imageManager.cleanupIntervalUpdates:
+	default:
+	}
+	select {
+	case imageManager.cleanupIntervalUpdates <- cfg.ImageCleanupInterval:
+	default:
+	}
+}
+
 func buildImageCleanupExclusionList(cfg *config.Config) []string {
 	// append known cached internal images to imageCleanupExclusionList
 	excludedImages := append(cfg.ImageCleanupExclusionList,
@@ -152,6 +197,40 @@ func (imageManager *dockerImageManager) RecordContainerReference(container *apic
 	return nil
 }
 
+// RecordImage adds an image pulled without a container, such as a preloaded
+// image, to the image states, so that the image cleanup removes it once it's
+// old enough and unused
+func (imageManager *dockerImageManager) RecordImage(imageName string) (*image.ImageState, error) {
+	if imageName == "" {
+		return nil, fmt.Errorf("Invalid image reference: Empty image name")
+	}
+	imageInspected, err := imageManager.client.InspectImage(imageName)
+	if err != nil {
+		seelog.Errorf("Error inspecting image %v: %v", imageName, err)
+		return nil, err
+	}
+
+	imageManager.updateLock.Lock()
+	defer imageManager.updateLock.Unlock()
+	imageManager.removeExistingImageNameOfDifferentID(imageName, imageInspected.ID)
+	imageState, ok := imageManager.getImageState(imageInspected.ID)
+	if !ok {
+		imageState = &image.ImageState{
+			Image: &image.Image{
+				ImageID: imageInspected.ID,
+				Size:    imageInspected.Size,
+			},
+			PulledAt:   time.Now(),
+			LastUsedAt: time.Now(),
+		}
+		imageManager.imageStates = append(imageManager.imageStates, imageState)
+	}
+	imageState.AddImageName(imageName)
+	imageState.SetPullSucceeded(true)
+	imageManager.saveImageStateData(imageState)
+	return imageState, nil
+}
+
 // check whether image pull from ECR
 func (imageManager *dockerImageManager) isImagePullFromECR(container *apicontainer.Container) bool {
 	return container.RegistryAuthentication != nil && container.RegistryAuthentication.ECRAuthData != nil && container.RegistryAuthentication.Type == apicontainer.AuthTypeECR
@@ -343,16 +422,51 @@ func (imageManager *dockerImageManager) StartImageCleanupProcess(ctx context.Con
 		seelog.Info("Pull behavior is set to always use cache. Disabling cleanup")
 		return
 	}
+	if imageManager.diskHighWatermark != 0 {
+		go imageManager.performPeriodicDiskPressureCheck(ctx, imageManager.diskCheckInterval)
+	}
 	// passing the cleanup interval as argument which would help during testing
 	imageManager.performPeriodicImageCleanup(ctx, imageManager.imageCleanupTimeInterval)
 }
 
+// performPeriodicDiskPressureCheck checks the usage of the docker data root disk more often than
+// the image cleanup runs, so that unused images are removed soon after the high watermark is crossed
+func (imageManager *dockerImageManager) performPeriodicDiskPressureCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			imageManager.checkDiskPressure(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkDiskPressure removes unused images if the usage of the docker data root disk is above the
+// high watermark
+func (imageManager *dockerImageManager) checkDiskPressure(ctx context.Context) {
+	ImagePullDeleteLock.Lock()
+	defer ImagePullDeleteLock.Unlock()
+
+	imageManager.updateLock.Lock()
+	defer imageManager.updateLock.Unlock()
+
+	imageManager.imageStatesConsideredForDeletion = imageManager.imagesConsiderForDeletion(imageManager.getAllImageStates())
+	imageManager.removeImagesForDiskPressure(ctx)
+}
+
 func (imageManager *dockerImageManager) performPeriodicImageCleanup(ctx context.Context, imageCleanupInterval time.Duration) {
 	imageManager.imageCleanupTicker = time.NewTicker(imageCleanupInterval)
 	for {
 		select {
 		case <-imageManager.imageCleanupTicker.C:
 			go imageManager.removeUnusedImages(ctx)
+		case interval := <-imageManager.cleanupIntervalUpdates:
+			seelog.Infof("Image cleanup interval changed to %v", interval)
+			imageManager.imageCleanupTicker.Stop()
+			imageManager.imageCleanupTicker = time.NewTicker(interval)
 		case <-ctx.Done():
 			imageManager.imageCleanupTicker.Stop()
 			return
@@ -381,6 +495,7 @@ func (imageManager *dockerImageManager) removeUnusedImages(ctx context.Context)
 			break
 		}
 	}
+	imageManager.removeImagesForDiskPressure(ctx)
 	if imageManager.deleteNonECSImagesEnabled.Enabled() {
 		// remove nonecs containers
 		imageManager.removeNonECSContainers(ctx)
@@ -390,6 +505,77 @@ func (imageManager *dockerImageManager) removeUnusedImages(ctx context.Context)
 	}
 }
 
+// removeImagesForDiskPressure deletes the unused images, least recently used
+// first and regardless of their age, while the usage of the docker data root
+// disk is above the low watermark, once it crossed the high watermark
+func (imageManager *dockerImageManager) removeImagesForDiskPressure(ctx context.Context) {
+	if imageManager.diskHighWatermark == 0 {
+		return
+	}
+	usage, err := imageManager.getDockerDataRootDiskUsage(ctx)
+	if err != nil {
+		seelog.Warnf("Image Manager: unable to check the disk usage for image cleanup: %v", err)
+		return
+	}
+	if usage < float64(imageManager.diskHighWatermark) {
+		return
+	}
+	seelog.Infof("Image Manager: disk usage of %s is %.1f%%, above the high watermark of %d%%; removing unused images",
+		imageManager.dockerDataRoot, usage, imageManager.diskHighWatermark)
+	for usage >= float64(imageManager.diskLowWatermark) {
+		leastRecentlyUsedImage := imageManager.getUnusedImageForDiskPressure()
+		if leastRecentlyUsedImage == nil {
+			seelog.Warnf("Image Manager: no more unused images to remove, disk usage of %s is still %.1f%%",
+				imageManager.dockerDataRoot, usage)
+			return
+		}
+		imageManager.removeImage(ctx, leastRecentlyUsedImage, metrics.ImageCleanupTriggerDiskPressure)
+		usage, err = imageManager.getDockerDataRootDiskUsage(ctx)
+		if err != nil {
+			seelog.Warnf("Image Manager: unable to check the disk usage for image cleanup: %v", err)
+			return
+		}
+	}
+	seelog.Infof("Image Manager: disk usage of %s is %.1f%%, below the low watermark of %d%%",
+		imageManager.dockerDataRoot, usage, imageManager.diskLowWatermark)
+}
+
+// getDockerDataRootDiskUsage returns the usage, in percent, of the disk that
+// holds the docker data root
+func (imageManager *dockerImageManager) getDockerDataRootDiskUsage(ctx context.Context) (float64, error) {
+	if imageManager.dockerDataRoot == "" {
+		info, err := imageManager.client.Info(ctx, dockerclient.InfoTimeout)
+		if err != nil {
+			return 0, fmt.Errorf("unable to get the docker data root: %v", err)
+		}
+		if info.DockerRootDir == "" {
+			return 0, fmt.Errorf("docker did not report its data root")
+		}
+		imageManager.dockerDataRoot = info.DockerRootDir
+	}
+	usage, err := imageManager.diskUsage(imageManager.dockerDataRoot)
+	if err != nil {
+		return 0, err
+	}
+	metrics.MetricsEngineGlobal.SetImageCleanupDiskUsage(imageManager.dockerDataRoot, usage)
+	return usage, nil
+}
+
+// getUnusedImageForDiskPressure returns the least recently used image that is
+// not used by any container, whatever its age
+func (imageManager *dockerImageManager) getUnusedImageForDiskPressure() *image.ImageState {
+	var candidateImages []*image.ImageState
+	for _, imageState := range imageManager.imageStatesConsideredForDeletion {
+		if imageState.HasNoAssociatedContainers() {
+			candidateImages = append(candidateImages, imageState)
+		}
+	}
+	if len(candidateImages) == 0 {
+		return nil
+	}
+	return imageManager.getLeastRecentlyUsedImage(candidateImages)
+}
+
 func (imageManager *dockerImageManager) removeNonECSContainers(ctx context.Context) {
 	nonECSContainersIDs, err := imageManager.getNonECSContainerIDs(ctx)
 	if err != nil {
@@ -611,7 +797,7 @@ func (imageManager *dockerImageManager) removeLeastRecentlyUsedImage(ctx context
 	if leastRecentlyUsedImage == nil {
 		return fmt.Errorf("No more eligible images for deletion")
 	}
-	imageManager.removeImage(ctx, leastRecentlyUsedImage)
+	imageManager.removeImage(ctx, leastRecentlyUsedImage, metrics.ImageCleanupTriggerAge)
 	return nil
 }
 
@@ -625,25 +811,33 @@ func (imageManager *dockerImageManager) getUnusedImageForDeletion() *image.Image
 	return imageManager.getLeastRecentlyUsedImage(candidateImageStatesForDeletion)
 }
 
-func (imageManager *dockerImageManager) removeImage(ctx context.Context, leastRecentlyUsedImage *image.ImageState) {
+// removeImage removes every name of the image, and records the deletion of
+// the image for the trigger of the cleanup once all of them are removed
+func (imageManager *dockerImageManager) removeImage(ctx context.Context, leastRecentlyUsedImage *image.ImageState, trigger string) {
 	// Handling deleting while traversing a slice
 	imageNames := make([]string, len(leastRecentlyUsedImage.Image.Names))
 	copy(imageNames, leastRecentlyUsedImage.Image.Names)
+	removed := false
 	if len(imageNames) == 0 {
 		// potentially untagged image of format <none>:<none>; remove by ID
-		imageManager.deleteImage(ctx, leastRecentlyUsedImage.Image.ImageID, leastRecentlyUsedImage)
+		removed = imageManager.deleteImage(ctx, leastRecentlyUsedImage.Image.ImageID, leastRecentlyUsedImage)
 	} else {
 		// Image has multiple tags/repos. Untag each name and delete the final reference to image
 		for _, imageName := range imageNames {
-			imageManager.deleteImage(ctx, imageName, leastRecentlyUsedImage)
+			removed = imageManager.deleteImage(ctx, imageName, leastRecentlyUsedImage) || removed
 		}
 	}
+	if removed {
+		metrics.MetricsEngineGlobal.RecordImageCleanupDeletion(trigger, leastRecentlyUsedImage.Image.Size)
+	}
 }
 
-func (imageManager *dockerImageManager) deleteImage(ctx context.Context, imageID string, imageState *image.ImageState) {
+// deleteImage removes the image name from the instance, and returns true if
+// it was the last reference to the image
+func (imageManager *dockerImageManager) deleteImage(ctx context.Context, imageID string, imageState *image.ImageState) bool {
 	if imageID == "" {
 		seelog.Errorf("Image ID to be deleted is null")
-		return
+		return false
 	}
 	seelog.Infof("Removing Image: %s", imageID)
 	err := imageManager.client.RemoveImage(ctx, imageID, dockerclient.RemoveImageTimeout)
@@ -653,7 +847,7 @@ func (imageManager *dockerImageManager) deleteImage(ctx context.Context, imageID
 		} else {
 			seelog.Errorf("Error removing Image %v - %v", imageID, err)
 			delete(imageManager.imageStatesConsideredForDeletion, imageState.Image.ImageID)
-			return
+			return false
 		}
 	}
 	seelog.Infof("Image removed: %v", imageID)
@@ -663,7 +857,9 @@ func (imageManager *dockerImageManager) deleteImage(ctx context.Context, imageID
 		delete(imageManager.imageStatesConsideredForDeletion, imageState.Image.ImageID)
 		imageManager.removeImageState(imageState)
 		imageManager.state.RemoveImageState(imageState)
+		return true
 	}
+	return false
 }
 
 func (imageManager *dockerImageManager) GetImageStateFromImageName(containerImageName string) (*image.ImageState, bool) {
@@ -237,6 +237,63 @@ func TestRecordContainerReferenceWithNoImageName(t *testing.T) {
 	}
 }
 
+func TestRecordImage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	imageManager := &dockerImageManager{
+		client:                   client,
+		dataClient:               data.NewNoopClient(),
+		state:                    dockerstate.NewTaskEngineState(),
+		minimumAgeBeforeDeletion: config.DefaultImageDeletionAge,
+		numImagesToDelete:        config.DefaultNumImagesToDeletePerCycle,
+		imageCleanupTimeInterval: config.DefaultImageCleanupTimeInterval,
+	}
+	// The image name used to refer to an older image
+	oldImageState := &image.ImageState{
+		Image:    &image.Image{ImageID: "sha256:old"},
+		PulledAt: time.Now(),
+	}
+	oldImageState.AddImageName("preloaded:latest")
+	imageManager.addImageState(oldImageState)
+
+	client.EXPECT().InspectImage("preloaded:latest").Return(&types.ImageInspect{ID: "sha256:new", Size: 1024}, nil).Times(2)
+	imageState, err := imageManager.RecordImage("preloaded:latest")
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:new", imageState.Image.ImageID)
+	assert.Equal(t, int64(1024), imageState.Image.Size)
+	assert.Equal(t, []string{"preloaded:latest"}, imageState.Image.Names)
+	assert.True(t, imageState.GetPullSucceeded())
+	assert.True(t, imageState.HasNoAssociatedContainers(), "a preloaded image should be a cleanup candidate")
+	assert.Empty(t, oldImageState.Image.Names)
+
+	// Preloading the image again doesn't add another image state
+	_, err = imageManager.RecordImage("preloaded:latest")
+	require.NoError(t, err)
+	assert.Equal(t, 2, imageManager.GetImageStatesCount())
+	recordedImageState, ok := imageManager.GetImageStateFromImageName("preloaded:latest")
+	require.True(t, ok)
+	assert.Equal(t, imageState, recordedImageState)
+	assert.Equal(t, []string{"preloaded:latest"}, imageState.Image.Names)
+}
+
+func TestRecordImageInspectError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	imageManager := &dockerImageManager{
+		client:     client,
+		dataClient: data.NewNoopClient(),
+		state:      dockerstate.NewTaskEngineState(),
+	}
+	client.EXPECT().InspectImage("preloaded:latest").Return(nil, errors.New("error inspecting"))
+	_, err := imageManager.RecordImage("preloaded:latest")
+	assert.Error(t, err)
+	assert.Equal(t, 0, imageManager.GetImageStatesCount())
+}
+
 func TestAddInvalidContainerReferenceToImageState(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -990,6 +1047,146 @@ func TestImageCleanupHappyPath(t *testing.T) {
 	}
 }
 
+func TestSetCleanupConfig(t *testing.T) {
+	cfg := defaultTestConfig()
+	imageManager := NewImageManager(cfg, nil, dockerstate.NewTaskEngineState()).(*dockerImageManager)
+
+	reloaded := cfg.Reloadable()
+	reloaded.MinimumImageDeletionAge = 2 * time.Hour
+	reloaded.NonECSMinimumImageDeletionAge = 3 * time.Hour
+	reloaded.NumImagesToDeletePerCycle = 10
+	reloaded.NumNonECSContainersToDeletePerCycle = 20
+	imageManager.SetCleanupConfig(reloaded)
+	assert.Equal(t, 2*time.Hour, imageManager.minimumAgeBeforeDeletion)
+	assert.Equal(t, 3*time.Hour, imageManager.nonECSMinimumAgeBeforeDeletion)
+	assert.Equal(t, 10, imageManager.numImagesToDelete)
+	assert.Equal(t, 20, imageManager.numNonECSContainersToDelete)
+	assert.Len(t, imageManager.cleanupIntervalUpdates, 0, "the cleanup interval didn't change")
+
+	reloaded.ImageCleanupInterval = time.Hour
+	imageManager.SetCleanupConfig(reloaded)
+	reloaded.ImageCleanupInterval = 2 * time.Hour
+	imageManager.SetCleanupConfig(reloaded)
+	assert.Equal(t, 2*time.Hour, imageManager.imageCleanupTimeInterval)
+	// only the last interval is picked up by the cleanup loop
+	assert.Equal(t, 2*time.Hour, <-imageManager.cleanupIntervalUpdates)
+	assert.Len(t, imageManager.cleanupIntervalUpdates, 0)
+}
+
+// newDiskPressureImageManager returns an image manager with disk watermarks of
+// 80% and 70%, and the images that were used most recently last
+func newDiskPressureImageManager(client dockerapi.DockerClient, diskUsages []float64,
+	images ...*image.ImageState) *dockerImageManager {
+	imageManager := &dockerImageManager{
+		client:                   client,
+		state:                    dockerstate.NewTaskEngineState(),
+		minimumAgeBeforeDeletion: config.DefaultImageDeletionAge,
+		numImagesToDelete:        config.DefaultNumImagesToDeletePerCycle,
+		imageCleanupTimeInterval: config.DefaultImageCleanupTimeInterval,
+		diskHighWatermark:        80,
+		diskLowWatermark:         70,
+		diskUsage: func(path string) (float64, error) {
+			if path != "/var/lib/docker" {
+				return 0, errors.New("unexpected path")
+			}
+			usage := diskUsages[0]
+			if len(diskUsages) > 1 {
+				diskUsages = diskUsages[1:]
+			}
+			return usage, nil
+		},
+	}
+	imageManager.SetDataClient(data.NewNoopClient())
+	for i, imageState := range images {
+		imageState.PulledAt = time.Now()
+		imageState.LastUsedAt = time.Now().Add(time.Duration(i-len(images)) * time.Minute)
+	}
+	imageManager.AddAllImageStates(images)
+	return imageManager
+}
+
+func TestImageCleanupDiskPressure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	inUse := &image.ImageState{
+		Image:      &image.Image{ImageID: "sha256:inuse", Names: []string{"inuse"}, Size: 100},
+		Containers: []*apicontainer.Container{{Name: "container"}},
+	}
+	oldest := &image.ImageState{Image: &image.Image{ImageID: "sha256:oldest", Names: []string{"oldest"}, Size: 200}}
+	older := &image.ImageState{Image: &image.Image{ImageID: "sha256:older", Names: []string{"older"}, Size: 300}}
+	newest := &image.ImageState{Image: &image.Image{ImageID: "sha256:newest", Names: []string{"newest"}, Size: 400}}
+	imageManager := newDiskPressureImageManager(client, []float64{90, 75, 69}, inUse, oldest, older, newest)
+
+	client.EXPECT().Info(gomock.Any(), dockerclient.InfoTimeout).Return(types.Info{DockerRootDir: "/var/lib/docker"}, nil)
+	gomock.InOrder(
+		client.EXPECT().RemoveImage(gomock.Any(), "oldest", dockerclient.RemoveImageTimeout).Return(nil),
+		client.EXPECT().RemoveImage(gomock.Any(), "older", dockerclient.RemoveImageTimeout).Return(nil),
+	)
+	imageManager.removeUnusedImages(context.TODO())
+
+	remaining := imageManager.getAllImageStates()
+	require.Len(t, remaining, 2, "images should be removed until the disk usage is below the low watermark")
+	assert.Equal(t, "sha256:inuse", remaining[0].Image.ImageID)
+	assert.Equal(t, "sha256:newest", remaining[1].Image.ImageID)
+}
+
+func TestCheckDiskPressure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	oldest := &image.ImageState{Image: &image.Image{ImageID: "sha256:oldest", Names: []string{"oldest"}}}
+	newest := &image.ImageState{Image: &image.Image{ImageID: "sha256:newest", Names: []string{"newest"}}}
+	imageManager := newDiskPressureImageManager(client, []float64{79, 85, 60}, oldest, newest)
+
+	client.EXPECT().Info(gomock.Any(), dockerclient.InfoTimeout).Return(types.Info{DockerRootDir: "/var/lib/docker"}, nil)
+	imageManager.checkDiskPressure(context.TODO())
+	assert.Len(t, imageManager.getAllImageStates(), 2, "images should not be removed below the high watermark")
+
+	// the disk usage crosses the high watermark before the next image cleanup
+	client.EXPECT().RemoveImage(gomock.Any(), "oldest", dockerclient.RemoveImageTimeout).Return(nil)
+	imageManager.checkDiskPressure(context.TODO())
+	remaining := imageManager.getAllImageStates()
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "sha256:newest", remaining[0].Image.ImageID)
+}
+
+func TestImageCleanupDiskPressureBelowHighWatermark(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	unused := &image.ImageState{Image: &image.Image{ImageID: "sha256:unused", Names: []string{"unused"}}}
+	imageManager := newDiskPressureImageManager(client, []float64{79}, unused)
+
+	client.EXPECT().Info(gomock.Any(), dockerclient.InfoTimeout).Return(types.Info{DockerRootDir: "/var/lib/docker"}, nil)
+	imageManager.removeUnusedImages(context.TODO())
+	imageManager.removeUnusedImages(context.TODO())
+
+	assert.Len(t, imageManager.getAllImageStates(), 1, "images should not be removed below the high watermark")
+}
+
+func TestImageCleanupDiskPressureNoUnusedImages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	unused := &image.ImageState{Image: &image.Image{ImageID: "sha256:unused", Names: []string{"unused"}}}
+	failing := &image.ImageState{Image: &image.Image{ImageID: "sha256:failing", Names: []string{"failing"}}}
+	imageManager := newDiskPressureImageManager(client, []float64{95}, failing, unused)
+
+	client.EXPECT().Info(gomock.Any(), dockerclient.InfoTimeout).Return(types.Info{DockerRootDir: "/var/lib/docker"}, nil)
+	client.EXPECT().RemoveImage(gomock.Any(), "failing", dockerclient.RemoveImageTimeout).Return(errors.New("conflict"))
+	client.EXPECT().RemoveImage(gomock.Any(), "unused", dockerclient.RemoveImageTimeout).Return(nil)
+	imageManager.removeUnusedImages(context.TODO())
+
+	remaining := imageManager.getAllImageStates()
+	require.Len(t, remaining, 1, "cleanup should stop once there are no more unused images")
+	assert.Equal(t, "sha256:failing", remaining[0].Image.ImageID)
+}
+
 func TestImageCleanupCannotRemoveImage(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
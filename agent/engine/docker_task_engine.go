@@ -27,6 +27,7 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/logrelay"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
@@ -46,10 +47,14 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/execcmd"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/aws/amazon-ecs-agent/agent/networkpolicy"
+	s3factory "github.com/aws/amazon-ecs-agent/agent/s3/factory"
+	"github.com/aws/amazon-ecs-agent/agent/securityprofile"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/credentialspec"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	utilsync "github.com/aws/amazon-ecs-agent/agent/utils/sync"
@@ -108,6 +113,8 @@ const (
 	stopContainerBackoffJitter     = 0.2
 	stopContainerBackoffMultiplier = 1.3
 	stopContainerMaxRetryCount     = 5
+
+	defaultStopSignalPollInterval = time.Second
 )
 
 var newExponentialBackoff = retry.NewExponentialBackoff
@@ -157,6 +164,15 @@ type DockerTaskEngine struct {
 	_time                               ttime.Time
 	_timeOnce                           sync.Once
 	imageManager                        ImageManager
+	imagePullScheduler                  *imagePullScheduler
+	imagePreloader                      *imagePreloader
+	taskDryRunner                       *taskDryRunner
+	taskMetadataPipeServer              TaskMetadataPipeServer
+	taskDrainer                         *taskDrainer
+	numaPlacer                          *numaPlacer
+	neuronAssigner                      *neuronAssigner
+	taskHistory                         *taskHistory
+	lifecycleHooks                      *lifecycleHooks
 	containerStatusToTransitionFunction map[apicontainerstatus.ContainerStatus]transitionApplyFunc
 	metadataManager                     containermetadata.Manager
 
@@ -179,7 +195,41 @@ type DockerTaskEngine struct {
 	monitorExecAgentsInterval time.Duration
 	stopContainerBackoffMin   time.Duration
 	stopContainerBackoffMax   time.Duration
+	stopSignalPollInterval    time.Duration
 	namespaceHelper           ecscni.NamespaceHelper
+	logRelay                  *logrelay.Relay
+	execSessionAuditor        *execcmd.SessionAuditor
+	efsMountWatcher           *efsMountWatcher
+	memoryPressureMonitor     *memoryPressureMonitor
+	securityProfileManager    securityprofile.Manager
+
+	// storageQuotaSupportedUnsafe caches whether the docker storage driver supports
+	// limiting the size of the writable layers of containers
+	storageQuotaSupportedUnsafe *bool
+	storageQuotaLock            sync.Mutex
+
+	// instanceNetworkPolicy is the network policy enforced for the tasks not sent with
+	// a policy of their own, loaded once from the configured policy document
+	instanceNetworkPolicy     *networkpolicy.Policy
+	instanceNetworkPolicyErr  error
+	instanceNetworkPolicyOnce sync.Once
+
+	// adoptableContainers are the docker containers of the tasks lost with the checkpoint
+	// of the state, by task ARN and container name
+	adoptableContainers     map[string]map[string]adoptableContainer
+	adoptableContainersLock sync.Mutex
+
+	// hibernatedTasks are the ARNs of the tasks known when the instance hibernated, whose
+	// containers are kept when they're cleaned up
+	hibernatedTasks     map[string]struct{}
+	hibernatedTasksLock sync.Mutex
+
+	// reloadedConfig is the part of the configuration last reloaded while the agent runs,
+	// nil until it's first reloaded. configReloaded is closed when it's reloaded, to
+	// wake the loops that depend on it
+	reloadedConfig     *config.ReloadableConfig
+	configReloaded     chan struct{}
+	reloadedConfigLock sync.RWMutex
 }
 
 // NewDockerTaskEngine returns a created, but uninitialized, DockerTaskEngine.
@@ -209,6 +259,7 @@ func NewDockerTaskEngine(cfg *config.Config,
 
 		containerChangeEventStream: containerChangeEventStream,
 		imageManager:               imageManager,
+		imagePullScheduler:         newImagePullScheduler(cfg.ImagePullMaxBandwidthMbps, client),
 		cniClient:                  ecscni.NewClient(cfg.CNIPluginsPath),
 
 		metadataManager:                   metadataManager,
@@ -218,11 +269,22 @@ func NewDockerTaskEngine(cfg *config.Config,
 		handleDelay:                       time.Sleep,
 		execCmdMgr:                        execCmdMgr,
 		monitorExecAgentsInterval:         defaultMonitorExecAgentsInterval,
+		efsMountWatcher:                   newEFSMountWatcher(),
+		memoryPressureMonitor:             newMemoryPressureMonitor(),
+		securityProfileManager:            securityprofile.NewManager(cfg, s3factory.NewS3ClientCreator()),
 		stopContainerBackoffMin:           defaultStopContainerBackoffMin,
 		stopContainerBackoffMax:           defaultStopContainerBackoffMax,
+		stopSignalPollInterval:            defaultStopSignalPollInterval,
 		namespaceHelper:                   ecscni.NewNamespaceHelper(client),
 	}
 
+	dockerTaskEngine.imagePreloader = newImagePreloader(dockerTaskEngine)
+	dockerTaskEngine.taskDryRunner = newTaskDryRunner(dockerTaskEngine)
+	dockerTaskEngine.taskDrainer = newTaskDrainer(dockerTaskEngine)
+	dockerTaskEngine.numaPlacer = newNUMAPlacer(dockerTaskEngine)
+	dockerTaskEngine.neuronAssigner = newNeuronAssigner(dockerTaskEngine)
+	dockerTaskEngine.taskHistory = newTaskHistory(dockerTaskEngine)
+	dockerTaskEngine.lifecycleHooks = newLifecycleHooks(dockerTaskEngine)
 	dockerTaskEngine.initializeContainerStatusToTransitionFunction()
 
 	return dockerTaskEngine
@@ -269,11 +331,20 @@ func (engine *DockerTaskEngine) Init(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	engine.logRelay = logrelay.NewRelay(derivedCtx, engine.cfg, engine.client, engine.credentialsManager)
+	engine.logRelay.Resume()
+	engine.execSessionAuditor = execcmd.NewSessionAuditor(engine.cfg, s3factory.NewS3ClientCreator())
 	engine.synchronizeState()
 	// Now catch up and start processing new events per normal
 	go engine.handleDockerEvents(derivedCtx)
 	engine.initialized = true
 	go engine.startPeriodicExecAgentsMonitoring(derivedCtx)
+	go engine.startPeriodicContainerCheckpoints(derivedCtx)
+	go engine.startPeriodicFirelensConfigReloads(derivedCtx)
+	go engine.startPeriodicExecSessionAudits(derivedCtx)
+	go engine.startPeriodicEFSMountChecks(derivedCtx)
+	go engine.startPeriodicMemoryPressureChecks(derivedCtx)
+	go engine.startPeriodicSecretRefreshes(derivedCtx)
 	return nil
 }
 
@@ -307,6 +378,23 @@ func (engine *DockerTaskEngine) monitorExecAgentProcesses(ctx context.Context) {
 				}
 			}
 		}
+		if engine.enforcesExecSessionLimits() && execcmd.IsExecEnabledTask(task) {
+			go engine.enforceExecSessionLimits(ctx, task)
+		}
+	}
+}
+
+// enforcesExecSessionLimits returns whether the agent terminates exec sessions on its own,
+// beyond the limit of concurrent sessions per container enforced by the ExecAgents
+func (engine *DockerTaskEngine) enforcesExecSessionLimits() bool {
+	return engine.cfg.ExecMaxSessionsPerTask > 0 || engine.cfg.ExecSessionIdleTimeout > 0
+}
+
+// enforceExecSessionLimits terminates the exec sessions of a task that are idle or over the
+// limit of concurrent sessions per task
+func (engine *DockerTaskEngine) enforceExecSessionLimits(ctx context.Context, task *apitask.Task) {
+	if err := engine.execCmdMgr.EnforceSessionLimits(ctx, engine.client, task); err != nil {
+		seelog.Warnf("Task engine [%s]: Failed to enforce exec session limits: %v", task.Arn, err)
 	}
 }
 
@@ -424,6 +512,8 @@ func (engine *DockerTaskEngine) synchronizeState() {
 	}
 
 	tasks := engine.state.AllTasks()
+	engine.numaPlacer.restore(tasks)
+	engine.neuronAssigner.restore(tasks)
 	tasksToStart := engine.filterTasksToStartUnsafe(tasks)
 	for _, task := range tasks {
 		task.InitializeResources(engine.resourceFields)
@@ -431,6 +521,8 @@ func (engine *DockerTaskEngine) synchronizeState() {
 	}
 
 	for _, task := range tasksToStart {
+		engine.restoreTaskMetadataPipe(task)
+		engine.restoreTaskDNSCache(task)
 		engine.startTask(task)
 	}
 }
@@ -493,9 +585,19 @@ func updateContainerMetadata(metadata *dockerapi.DockerContainerMetadata, contai
 	if len(metadata.PortBindings) != 0 && len(container.GetKnownPortBindings()) == 0 {
 		container.SetKnownPortBindings(metadata.PortBindings)
 	}
-	// update the container health information
-	if container.HealthStatusShouldBeReported() {
+	// update the container health information, unless it's checked by the agent
+	if container.HealthStatusShouldBeReported() && !container.HasHealthCheckProbes() {
 		container.SetHealthStatus(metadata.Health)
+		container.AddHealthCheckResults(metadata.HealthLog...)
+	}
+	if metadata.Runtime != "" {
+		container.SetOCIRuntime(metadata.Runtime)
+	}
+	if len(metadata.Ulimits) > 0 {
+		container.SetUlimits(metadata.Ulimits)
+	}
+	if metadata.EffectiveResources != nil {
+		container.SetEffectiveResources(metadata.EffectiveResources)
 	}
 	container.SetNetworkMode(metadata.NetworkMode)
 	container.SetNetworkSettings(metadata.NetworkSettings)
@@ -599,11 +701,16 @@ func (engine *DockerTaskEngine) checkTaskState(task *apitask.Task) {
 // sweepTask deletes all the containers associated with a task
 func (engine *DockerTaskEngine) sweepTask(task *apitask.Task) {
 	for _, cont := range task.Containers {
+		// the checkpoint is removed while docker still knows the container
+		engine.removeContainerCheckpoint(task, cont)
 		err := engine.removeContainer(task, cont)
 		if err != nil {
 			seelog.Infof("Task engine [%s]: unable to remove old container [%s]: %v",
 				task.Arn, cont.Name, err)
 		}
+		if cont.GetAWSLogsRelayOptions() != nil {
+			engine.logRelay.Remove(cont.GetRuntimeID())
+		}
 		// Internal container(created by ecs-agent) state isn't recorded
 		if cont.IsInternal() {
 			continue
@@ -624,6 +731,17 @@ func (engine *DockerTaskEngine) sweepTask(task *apitask.Task) {
 	}
 }
 
+// taskPullsWithExecutionRole returns true if any container of the task pulls its image from ECR
+// with the task execution role
+func taskPullsWithExecutionRole(task *apitask.Task) bool {
+	for _, container := range task.Containers {
+		if container.ShouldPullWithExecutionRole() {
+			return true
+		}
+	}
+	return false
+}
+
 var removeAll = os.RemoveAll
 
 func (engine *DockerTaskEngine) deleteTask(task *apitask.Task) {
@@ -649,9 +767,31 @@ func (engine *DockerTaskEngine) deleteTask(task *apitask.Task) {
 		}
 	}
 
+	if taskHasSecretFiles(task) {
+		engine.removeSecretFiles(task)
+	}
+
+	if taskPullsWithExecutionRole(task) {
+		// stop refreshing ECR auth tokens with the pull credentials of the stopped task
+		engine.client.ForgetPullCredentials(task.GetExecutionCredentialsID())
+	}
+
+	if engine.taskMetadataPipeServer != nil {
+		engine.taskMetadataPipeServer.StopServingTask(task.Arn)
+	}
+
+	// The DNS cache of the task is stopped along with its network namespace, unless the
+	// namespace couldn't be cleaned up
+	engine.stopTaskDNSCache(task)
+
 	// Now remove ourselves from the global state and cleanup channels
 	engine.tasksLock.Lock()
+	// The containers are looked up before the task is removed from the state, so that
+	// the task can still be inspected with its containers once it's gone
+	containerMap, _ := engine.state.ContainerMapByArn(task.Arn)
 	engine.state.RemoveTask(task)
+	engine.taskHistory.add(task, containerMap)
+	engine.forgetHibernatedTask(task.Arn)
 
 	taskENIs := task.GetTaskENIs()
 	for _, taskENI := range taskENIs {
@@ -758,10 +898,12 @@ func (engine *DockerTaskEngine) handleDockerEvent(event dockerapi.DockerContaine
 	// Container health status change does not affect the container status
 	// no need to process this in task manager
 	if event.Type == apicontainer.ContainerHealthEvent {
-		if cont.Container.HealthStatusShouldBeReported() {
+		if cont.Container.HealthStatusShouldBeReported() && !cont.Container.HasHealthCheckProbes() {
 			seelog.Debugf("Task engine: updating container [%s(%s)] health status: %v",
 				cont.Container.Name, cont.DockerID, event.DockerContainerMetadata.Health)
 			cont.Container.SetHealthStatus(event.DockerContainerMetadata.Health)
+			cont.Container.AddHealthCheckResults(event.DockerContainerMetadata.HealthLog...)
+			engine.saveContainerData(cont.Container)
 		}
 		return
 	}
@@ -791,10 +933,25 @@ func (engine *DockerTaskEngine) StateChangeEvents() chan statechange.Event {
 // AddTask starts tracking a task
 func (engine *DockerTaskEngine) AddTask(task *apitask.Task) {
 	defer metrics.MetricsEngineGlobal.RecordTaskEngineMetric("ADD_TASK")()
+	if _, exists := engine.state.TaskByArn(task.Arn); !exists {
+		// New tasks are placed on their NUMA node and assigned their devices before their
+		// cgroup spec is built
+		if err := engine.reserveInstanceResources(task); err == errNUMATaskQueued {
+			seelog.Infof("Task engine [%s]: %v", task.Arn, err)
+			return
+		} else if err != nil {
+			seelog.Errorf("Task engine [%s]: unable to reserve the instance resources of the task: %v", task.Arn, err)
+			task.SetKnownStatus(apitaskstatus.TaskStopped)
+			task.SetDesiredStatus(apitaskstatus.TaskStopped)
+			engine.emitTaskEvent(task, err.Error())
+			return
+		}
+	}
 	err := task.PostUnmarshalTask(engine.cfg, engine.credentialsManager,
 		engine.resourceFields, engine.client, engine.ctx)
 	if err != nil {
 		seelog.Errorf("Task engine [%s]: unable to add task to the engine: %v", task.Arn, err)
+		engine.releaseInstanceResources(task)
 		task.SetKnownStatus(apitaskstatus.TaskStopped)
 		task.SetDesiredStatus(apitaskstatus.TaskStopped)
 		engine.emitTaskEvent(task, err.Error())
@@ -813,10 +970,20 @@ func (engine *DockerTaskEngine) AddTask(task *apitask.Task) {
 		engine.updateTaskENIDependencies(task)
 
 		engine.state.AddTask(task)
+		engine.adoptRecoveredContainers(task)
+		if engine.taskDrainer.isDraining() && !task.GetDesiredStatus().Terminal() {
+			seelog.Warnf("Task engine [%s]: not starting task, the agent is draining", task.Arn)
+			engine.releaseInstanceResources(task)
+			task.SetKnownStatus(apitaskstatus.TaskStopped)
+			task.SetDesiredStatus(apitaskstatus.TaskStopped)
+			engine.emitTaskEvent(task, drainingStopReason)
+			return
+		}
 		if dependencygraph.ValidDependencies(task, engine.cfg) {
 			engine.startTask(task)
 		} else {
 			seelog.Errorf("Task engine [%s]: unable to progress task with circular dependencies", task.Arn)
+			engine.releaseInstanceResources(task)
 			task.SetKnownStatus(apitaskstatus.TaskStopped)
 			task.SetDesiredStatus(apitaskstatus.TaskStopped)
 			err := TaskDependencyError{task.Arn}
@@ -829,6 +996,32 @@ func (engine *DockerTaskEngine) AddTask(task *apitask.Task) {
 	engine.updateTaskUnsafe(existingTask, task)
 }
 
+// reserveInstanceResources places a new task on its NUMA node and assigns the neuron cores
+// its containers require
+func (engine *DockerTaskEngine) reserveInstanceResources(task *apitask.Task) error {
+	if err := engine.numaPlacer.place(task); err != nil {
+		return err
+	}
+	if err := engine.neuronAssigner.assign(task); err != nil {
+		engine.numaPlacer.release(task)
+		return err
+	}
+	return nil
+}
+
+// releaseInstanceResources frees the NUMA node resources and the neuron cores of a task.
+// The tasks queued until a NUMA node has room for them are added again once a placed
+// task frees its node resources.
+func (engine *DockerTaskEngine) releaseInstanceResources(task *apitask.Task) {
+	engine.numaPlacer.release(task)
+	engine.neuronAssigner.release(task)
+	if _, placed := task.GetNUMANode(); placed {
+		for _, queued := range engine.numaPlacer.takeQueued() {
+			go engine.AddTask(queued)
+		}
+	}
+}
+
 // ListTasks returns the tasks currently managed by the DockerTaskEngine
 func (engine *DockerTaskEngine) ListTasks() ([]*apitask.Task, error) {
 	return engine.state.AllTasks(), nil
@@ -845,6 +1038,7 @@ func (engine *DockerTaskEngine) pullContainer(task *apitask.Task, container *api
 		// pause images are managed at startup
 		return dockerapi.DockerContainerMetadata{}
 	}
+	engine.lifecycleHooks.runBefore(hookEventPrePull, task, container, 0)
 
 	if engine.imagePullRequired(engine.cfg.ImagePullBehavior, container, task.Arn) {
 		// Record the pullStoppedAt timestamp
@@ -976,7 +1170,19 @@ func (engine *DockerTaskEngine) pullAndUpdateContainerReference(task *apitask.Ta
 		defer container.SetASMDockerAuthConfig(types.AuthConfig{})
 	}
 
-	metadata := engine.client.PullImage(engine.ctx, container.Image, container.RegistryAuthentication, engine.cfg.ImagePullTimeout)
+	pullImage := func() dockerapi.DockerContainerMetadata {
+		return engine.client.PullImage(engine.ctx, container.Image, container.RegistryAuthentication,
+			engine.cfg.ImagePullTimeout)
+	}
+	var metadata dockerapi.DockerContainerMetadata
+	if engine.cfg.ImagePullSOCILazyLoading.Enabled() &&
+		engine.client.LazyLoadsImage(engine.ctx, container.Image, container.RegistryAuthentication) {
+		seelog.Infof("Task engine [%s]: image %s for container %s has a SOCI index, it will be lazily loaded",
+			task.Arn, container.Image, container.Name)
+		metadata = engine.imagePullScheduler.pullLazily(engine.ctx, container.Image, container.RegistryAuthentication, pullImage)
+	} else {
+		metadata = engine.imagePullScheduler.pull(engine.ctx, container.Image, container.RegistryAuthentication, pullImage)
+	}
 
 	// Don't add internal images(created by ecs-agent) into imagemanger state
 	if container.IsInternal() {
@@ -1138,6 +1344,21 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 		}
 	}
 
+	if container.HasSecretFiles() {
+		if err := engine.addSecretFiles(task, container, hostConfig); err != nil {
+			return dockerapi.DockerContainerMetadata{Error: err}
+		}
+	}
+
+	engine.applyAWSLogsRelay(container, hostConfig)
+	engine.applyEphemeralStorageQuota(engine.ctx, task, container, hostConfig)
+	if err := engine.applySecurityProfiles(task, container, hostConfig); err != nil {
+		return dockerapi.DockerContainerMetadata{Error: err}
+	}
+	if err := engine.checkContainerRuntime(task, hostConfig); err != nil {
+		return dockerapi.DockerContainerMetadata{Error: err}
+	}
+
 	// Populate credentialspec resource
 	if container.RequiresCredentialSpec() {
 		seelog.Debugf("Obtained container %s with credentialspec resource requirement for task %s.", container.Name, task.Arn)
@@ -1206,6 +1427,8 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 		}
 	}
 
+	engine.injectTaskMetadataPipe(task, container, hostConfig)
+
 	config, err := task.DockerConfig(container, dockerClientVersion)
 	if err != nil {
 		return dockerapi.DockerContainerMetadata{Error: apierrors.NamedError(err)}
@@ -1306,7 +1529,7 @@ func (engine *DockerTaskEngine) startContainer(task *apitask.Task, container *ap
 	}
 
 	startContainerBegin := time.Now()
-	dockerContainerMD := client.StartContainer(engine.ctx, dockerID, engine.cfg.ContainerStartTimeout)
+	dockerContainerMD := engine.startOrRestoreContainer(client, task, container, dockerID)
 	if dockerContainerMD.Error != nil {
 		return dockerContainerMD
 	}
@@ -1427,6 +1650,16 @@ func (engine *DockerTaskEngine) provisionContainerResources(task *apitask.Task,
 		}
 	}
 
+	// The EFA devices of the task are looked up while its ENI is still in the host namespace
+	if err := engine.attachTaskEFA(task); err != nil {
+		seelog.Errorf("Task engine [%s]: unable to attach the EFA of the task: %v", task.Arn, err)
+		return dockerapi.DockerContainerMetadata{
+			DockerID: cniConfig.ContainerID,
+			Error: ContainerNetworkingError{errors.Wrap(err,
+				"container resource provisioning: failed to attach the EFA")},
+		}
+	}
+
 	// Invoke the libcni to config the network namespace for the container
 	result, err := engine.cniClient.SetupNS(engine.ctx, cniConfig, cniSetupTimeout)
 	if err != nil {
@@ -1458,6 +1691,31 @@ func (engine *DockerTaskEngine) provisionContainerResources(task *apitask.Task,
 		}
 	}
 
+	// Enforce the network policy of the task before its other containers start.
+	err = engine.applyTaskNetworkPolicy(task, cniConfig.ContainerPID)
+	if err != nil {
+		seelog.Errorf("Task engine [%s]: unable to apply network policy: %v", task.Arn, err)
+		return dockerapi.DockerContainerMetadata{
+			DockerID: cniConfig.ContainerID,
+			Error: ContainerNetworkingError{errors.Wrap(err,
+				"container resource provisioning: failed to apply network policy")},
+		}
+	}
+	if cniConfig.BlockInstanceMetadata {
+		engine.countBlockedInstanceMetadataAttempts(task, cniConfig.ContainerPID)
+	}
+
+	// The containers of the task send their queries to its DNS cache, if it uses one.
+	err = engine.startTaskDNSCache(task, cniConfig.ContainerPID)
+	if err != nil {
+		seelog.Errorf("Task engine [%s]: unable to start the dns cache: %v", task.Arn, err)
+		return dockerapi.DockerContainerMetadata{
+			DockerID: cniConfig.ContainerID,
+			Error: ContainerNetworkingError{errors.Wrap(err,
+				"container resource provisioning: failed to start the dns cache")},
+		}
+	}
+
 	return dockerapi.DockerContainerMetadata{
 		DockerID: cniConfig.ContainerID,
 	}
@@ -1501,6 +1759,7 @@ func (engine *DockerTaskEngine) cleanupPauseContainerNetwork(task *apitask.Task,
 	}
 
 	seelog.Infof("Task engine [%s]: cleaning up the network namespace", task.Arn)
+	engine.stopTaskDNSCache(task)
 	cniConfig, err := engine.buildCNIConfigFromTaskContainer(task, containerInspectOutput, false)
 	if err != nil {
 		return errors.Wrapf(err,
@@ -1523,7 +1782,7 @@ func (engine *DockerTaskEngine) buildCNIConfigFromTaskContainer(
 	containerInspectOutput *types.ContainerJSON,
 	includeIPAMConfig bool) (*ecscni.Config, error) {
 	cniConfig := &ecscni.Config{
-		BlockInstanceMetadata:    engine.cfg.AWSVPCBlockInstanceMetdata.Enabled(),
+		BlockInstanceMetadata:    engine.cfg.AWSVPCBlockInstanceMetdata.Enabled() || task.BlockInstanceMetadata,
 		MinSupportedCNIVersion:   config.DefaultMinSupportedCNIVersion,
 		InstanceENIDNSServerList: engine.cfg.InstanceENIDNSServerList,
 	}
@@ -1593,9 +1852,82 @@ func (engine *DockerTaskEngine) stopContainer(task *apitask.Task, container *api
 		apiTimeoutStopContainer = engine.cfg.DockerStopTimeout
 	}
 
+	// The pre-stop hooks and command use up the stop timeout of the container, so that
+	// stopping it doesn't take longer than the timeout in total
+	preStopBegin := time.Now()
+	engine.lifecycleHooks.runBefore(hookEventPreStop, task, container, apiTimeoutStopContainer)
+	engine.runPreStopExec(dockerID, container, apiTimeoutStopContainer)
+	apiTimeoutStopContainer = remainingStopTimeout(apiTimeoutStopContainer, time.Since(preStopBegin))
+	engine.sendStopSignals(dockerID, container.Name, engine.stopSignalSequence(container))
 	return engine.stopDockerContainer(dockerID, container.Name, apiTimeoutStopContainer)
 }
 
+// stopSignalSequence returns the signals to send to the container before it is stopped:
+// the sequence of the container if it has one, otherwise the one configured for the agent.
+func (engine *DockerTaskEngine) stopSignalSequence(container *apicontainer.Container) []config.StopSignal {
+	if container.IsInternal() {
+		return nil
+	}
+	containerSequence := container.GetStopSignalSequence()
+	if len(containerSequence) == 0 {
+		return engine.cfg.ContainerStopSignalSequence
+	}
+	sequence := make([]config.StopSignal, 0, len(containerSequence))
+	for _, step := range containerSequence {
+		sequence = append(sequence, config.StopSignal{
+			Signal: step.Signal,
+			Wait:   time.Duration(step.WaitSeconds) * time.Second,
+		})
+	}
+	return sequence
+}
+
+// sendStopSignals sends the signals of the sequence to the container in order, moving on
+// to the next one once the wait of the current one elapses. It returns as soon as the
+// container exits, or if a signal can't be sent; the container is then stopped as usual.
+func (engine *DockerTaskEngine) sendStopSignals(dockerID, containerName string, sequence []config.StopSignal) {
+	for _, step := range sequence {
+		logger.Info(fmt.Sprintf("Sending %s to container, waiting %v for it to exit", step.Signal, step.Wait), logger.Fields{
+			field.Container: containerName,
+			field.RuntimeID: dockerID,
+		})
+		err := engine.client.KillContainer(engine.ctx, dockerID, step.Signal, dockerclient.KillContainerTimeout)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Error sending %s to container", step.Signal), logger.Fields{
+				field.Container: containerName,
+				field.RuntimeID: dockerID,
+				field.Error:     err,
+			})
+			return
+		}
+		if engine.waitForContainerExit(dockerID, step.Wait) {
+			return
+		}
+	}
+}
+
+// waitForContainerExit polls the status of the container until it stops or the wait
+// elapses, and returns whether the container stopped.
+func (engine *DockerTaskEngine) waitForContainerExit(dockerID string, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(engine.stopSignalPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			status, _ := engine.client.DescribeContainer(engine.ctx, dockerID)
+			if status.Terminal() {
+				return true
+			}
+		case <-timer.C:
+			return false
+		case <-engine.ctx.Done():
+			return false
+		}
+	}
+}
+
 // stopDockerContainer attempts to stop the container, retrying only in case of time out errors.
 // If the maximum number of retries is reached, the container is marked as stopped. This is because docker sometimes
 // deadlocks when trying to stop a container but the actual container process is stopped.
@@ -1694,7 +2026,9 @@ func (engine *DockerTaskEngine) applyContainerState(task *apitask.Task, containe
 			task.Arn, container.Name, nextState.String())
 		return dockerapi.DockerContainerMetadata{Error: &impossibleTransitionError{nextState}}
 	}
+	span := startContainerTransitionSpan(task, container, nextState)
 	metadata := transitionFunction(task, container)
+	span.End(metadata.Error)
 	if metadata.Error != nil {
 		seelog.Infof("Task engine [%s]: error transitioning container [%s (Runtime ID: %s)] to [%s]: %v",
 			task.Arn, container.Name, container.GetRuntimeID(), nextState.String(), metadata.Error)
@@ -1705,6 +2039,23 @@ func (engine *DockerTaskEngine) applyContainerState(task *apitask.Task, containe
 	return metadata
 }
 
+// startContainerTransitionSpan starts the span of the image pull, container creation,
+// start or stop, or returns nil for the other transitions, which aren't traced
+func startContainerTransitionSpan(task *apitask.Task, container *apicontainer.Container, nextState apicontainerstatus.ContainerStatus) *tracing.Span {
+	spanName, ok := map[apicontainerstatus.ContainerStatus]string{
+		apicontainerstatus.ContainerPulled:  "PullImage",
+		apicontainerstatus.ContainerCreated: "CreateContainer",
+		apicontainerstatus.ContainerRunning: "StartContainer",
+		apicontainerstatus.ContainerStopped: "StopContainer",
+	}[nextState]
+	if !ok {
+		return nil
+	}
+	return tracing.StartTaskSpan(task.Arn, spanName,
+		tracing.String(tracing.ContainerNameKey, container.Name),
+		tracing.String(tracing.ImageKey, container.Image))
+}
+
 // transitionFunctionMap provides the logic for the simple state machine of the
 // DockerTaskEngine. Each desired state maps to a function that can be called
 // to try and move the task to that desired state.
@@ -1726,6 +2077,11 @@ func (engine *DockerTaskEngine) Version() (string, error) {
 	return engine.client.Version(engine.ctx, dockerclient.VersionTimeout)
 }
 
+// DockerInfo returns the system-wide information of docker.
+func (engine *DockerTaskEngine) DockerInfo(ctx context.Context) (types.Info, error) {
+	return engine.client.Info(ctx, dockerclient.InfoTimeout)
+}
+
 func (engine *DockerTaskEngine) updateMetadataFile(task *apitask.Task, cont *apicontainer.DockerContainer) {
 	err := engine.metadataManager.Update(engine.ctx, cont.DockerID, task, cont.Container.Name)
 	if err != nil {
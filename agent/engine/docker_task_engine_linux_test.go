@@ -102,6 +102,7 @@ func TestResourceContainerProgression(t *testing.T) {
 		// Ensure that the resource is created first
 		mockControl.EXPECT().Exists(gomock.Any()).Return(false),
 		mockControl.EXPECT().Create(gomock.Any()).Return(nil, nil),
+		mockControl.EXPECT().Unified().Return(false),
 		mockIO.EXPECT().WriteFile(cgroupMemoryPath, gomock.Any(), gomock.Any()).Return(nil),
 		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes(),
 		client.EXPECT().PullImage(gomock.Any(), sleepContainer.Image, nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{}),
@@ -185,6 +186,7 @@ func TestDeleteTask(t *testing.T) {
 
 	gomock.InOrder(
 		mockControl.EXPECT().Remove("cgroupRoot").Return(nil),
+		mockState.EXPECT().ContainerMapByArn(testTaskARN).Return(nil, false),
 		mockState.EXPECT().RemoveTask(task),
 		mockState.EXPECT().ENIByMac(gomock.Any()).Return(attachment, true),
 		mockState.EXPECT().RemoveENIAttachment(mac),
@@ -233,6 +235,7 @@ func TestDeleteTaskBranchENIEnabled(t *testing.T) {
 
 	gomock.InOrder(
 		mockControl.EXPECT().Remove("cgroupRoot").Return(nil),
+		mockState.EXPECT().ContainerMapByArn(testTaskARN).Return(nil, false),
 		mockState.EXPECT().RemoveTask(task),
 	)
 
@@ -349,6 +352,7 @@ func TestTaskCPULimitHappyPath(t *testing.T) {
 				}
 				mockControl.EXPECT().Exists(gomock.Any()).Return(false)
 				mockControl.EXPECT().Create(gomock.Any()).Return(nil, nil)
+				mockControl.EXPECT().Unified().Return(false)
 				mockIO.EXPECT().WriteFile(cgroupMemoryPath, gomock.Any(), gomock.Any()).Return(nil)
 			}
 
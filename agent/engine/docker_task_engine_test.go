@@ -25,6 +25,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -177,6 +178,7 @@ func mocks(t *testing.T, ctx context.Context, cfg *config.Config) (*gomock.Contr
 	taskEngine.(*DockerTaskEngine).ctx = ctx
 	taskEngine.(*DockerTaskEngine).stopContainerBackoffMin = time.Millisecond
 	taskEngine.(*DockerTaskEngine).stopContainerBackoffMax = time.Millisecond * 2
+	taskEngine.(*DockerTaskEngine).stopSignalPollInterval = time.Millisecond
 	return ctrl, client, mockTime, taskEngine, credentialsManager, imageManager, metadataManager
 }
 
@@ -1051,6 +1053,21 @@ func TestProvisionContainerResourcesInspectError(t *testing.T) {
 	assert.NotNil(t, taskEngine.(*DockerTaskEngine).provisionContainerResources(testTask, pauseContainer).Error)
 }
 
+func TestPreStopExecTimeout(t *testing.T) {
+	stopTimeout := 30 * time.Second
+	assert.Equal(t, stopTimeout, preStopExecTimeout(&apicontainer.PreStopExec{}, stopTimeout))
+	assert.Equal(t, 10*time.Second, preStopExecTimeout(&apicontainer.PreStopExec{TimeoutSeconds: 10}, stopTimeout))
+	assert.Equal(t, stopTimeout, preStopExecTimeout(&apicontainer.PreStopExec{TimeoutSeconds: 600}, stopTimeout),
+		"The timeout of the pre-stop command should be capped by the stop timeout")
+}
+
+func TestRemainingStopTimeout(t *testing.T) {
+	stopTimeout := 30 * time.Second
+	assert.Equal(t, stopTimeout, remainingStopTimeout(stopTimeout, 300*time.Millisecond))
+	assert.Equal(t, 18*time.Second, remainingStopTimeout(stopTimeout, 12500*time.Millisecond))
+	assert.Equal(t, time.Duration(0), remainingStopTimeout(stopTimeout, time.Minute))
+}
+
 // TestStopPauseContainerCleanupCalled tests when stopping the pause container
 // its network namespace should be cleaned up first
 func TestStopPauseContainerCleanupCalled(t *testing.T) {
@@ -1108,6 +1125,182 @@ func TestStopPauseContainerCleanupCalled(t *testing.T) {
 	require.True(t, pauseContainer.IsContainerTornDown())
 }
 
+// TestStopContainerSendsStopSignalSequence tests that the configured signals are sent
+// to the container in order until it exits, before it is stopped
+func TestStopContainerSendsStopSignalSequence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.ContainerStopSignalSequence = []config.StopSignal{
+		{Signal: "SIGUSR1", Wait: 10 * time.Millisecond},
+		{Signal: "SIGINT", Wait: time.Hour},
+		{Signal: "SIGQUIT", Wait: time.Hour},
+	}
+	ctrl, dockerClient, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+
+	testTask := &apitask.Task{Arn: "myArn"}
+	testContainer := &apicontainer.Container{Name: "c1"}
+	testContainer.SetRuntimeID(containerID)
+	testTask.Containers = append(testTask.Containers, testContainer)
+
+	var exited int32
+	dockerClient.EXPECT().DescribeContainer(gomock.Any(), containerID).DoAndReturn(
+		func(ctx context.Context, dockerID string) (apicontainerstatus.ContainerStatus, dockerapi.DockerContainerMetadata) {
+			if atomic.LoadInt32(&exited) == 1 {
+				return apicontainerstatus.ContainerStopped, dockerapi.DockerContainerMetadata{}
+			}
+			return apicontainerstatus.ContainerRunning, dockerapi.DockerContainerMetadata{}
+		}).AnyTimes()
+	gomock.InOrder(
+		dockerClient.EXPECT().KillContainer(gomock.Any(), containerID, "SIGUSR1", gomock.Any()).Return(nil),
+		dockerClient.EXPECT().KillContainer(gomock.Any(), containerID, "SIGINT", gomock.Any()).Do(
+			func(ctx context.Context, dockerID, signal string, timeout time.Duration) {
+				atomic.StoreInt32(&exited, 1)
+			}).Return(nil),
+		dockerClient.EXPECT().StopContainer(gomock.Any(), containerID, cfg.DockerStopTimeout).Return(
+			dockerapi.DockerContainerMetadata{}),
+	)
+
+	md := taskEngine.(*DockerTaskEngine).stopContainer(testTask, testContainer)
+	assert.NoError(t, md.Error)
+}
+
+// TestStopContainerStopSignalError tests that the container is stopped as usual when a
+// signal of its sequence can't be sent
+func TestStopContainerStopSignalError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, dockerClient, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := &apitask.Task{Arn: "myArn"}
+	testContainer := &apicontainer.Container{
+		Name: "c1",
+		StopSignalSequence: []apicontainer.StopSignal{
+			{Signal: "SIGUSR1", WaitSeconds: 10},
+			{Signal: "SIGINT", WaitSeconds: 10},
+		},
+	}
+	testContainer.SetRuntimeID(containerID)
+	testTask.Containers = append(testTask.Containers, testContainer)
+
+	gomock.InOrder(
+		dockerClient.EXPECT().KillContainer(gomock.Any(), containerID, "SIGUSR1", gomock.Any()).Return(
+			dockerapi.CannotKillContainerError{FromError: errors.New("container is not running")}),
+		dockerClient.EXPECT().StopContainer(gomock.Any(), containerID, defaultConfig.DockerStopTimeout).Return(
+			dockerapi.DockerContainerMetadata{}),
+	)
+
+	md := taskEngine.(*DockerTaskEngine).stopContainer(testTask, testContainer)
+	assert.NoError(t, md.Error)
+}
+
+func TestStopSignalSequence(t *testing.T) {
+	cfg := defaultConfig
+	cfg.ContainerStopSignalSequence = []config.StopSignal{{Signal: "SIGINT", Wait: time.Second}}
+	engine := &DockerTaskEngine{cfg: &cfg}
+
+	assert.Equal(t, cfg.ContainerStopSignalSequence, engine.stopSignalSequence(&apicontainer.Container{}),
+		"Containers without a sequence should use the configured one")
+	assert.Equal(t, []config.StopSignal{{Signal: "SIGUSR1", Wait: 10 * time.Second}, {Signal: "SIGTERM"}},
+		engine.stopSignalSequence(&apicontainer.Container{
+			StopSignalSequence: []apicontainer.StopSignal{{Signal: "SIGUSR1", WaitSeconds: 10}, {Signal: "SIGTERM"}},
+		}), "The sequence of the container should override the configured one")
+	assert.Empty(t, engine.stopSignalSequence(&apicontainer.Container{Type: apicontainer.ContainerCNIPause}),
+		"Internal containers should not be signaled")
+}
+
+// TestStopContainerRunsPreStopExec tests that the pre-stop command of the container is
+// run inside it, and waited for, before it is stopped
+func TestStopContainerRunsPreStopExec(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, dockerClient, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := &apitask.Task{Arn: "myArn"}
+	testContainer := &apicontainer.Container{
+		Name: "c1",
+		PreStopExec: &apicontainer.PreStopExec{
+			Command:        []string{"/bin/drain", "--graceful"},
+			TimeoutSeconds: 10,
+		},
+	}
+	testContainer.SetRuntimeID(containerID)
+	testContainer.SetKnownStatus(apicontainerstatus.ContainerRunning)
+	testTask.Containers = append(testTask.Containers, testContainer)
+
+	gomock.InOrder(
+		dockerClient.EXPECT().CreateContainerExec(gomock.Any(), containerID, types.ExecConfig{
+			Cmd:    []string{"/bin/drain", "--graceful"},
+			Detach: true,
+		}, gomock.Any()).Return(&types.IDResponse{ID: "execID"}, nil),
+		dockerClient.EXPECT().StartContainerExec(gomock.Any(), "execID", gomock.Any(), gomock.Any()).Return(nil),
+		dockerClient.EXPECT().InspectContainerExec(gomock.Any(), "execID", gomock.Any()).Return(
+			&types.ContainerExecInspect{Running: true}, nil),
+		dockerClient.EXPECT().InspectContainerExec(gomock.Any(), "execID", gomock.Any()).Return(
+			&types.ContainerExecInspect{Running: false, ExitCode: 0}, nil),
+		dockerClient.EXPECT().StopContainer(gomock.Any(), containerID, defaultConfig.DockerStopTimeout).Return(
+			dockerapi.DockerContainerMetadata{}),
+	)
+
+	md := taskEngine.(*DockerTaskEngine).stopContainer(testTask, testContainer)
+	assert.NoError(t, md.Error)
+}
+
+// TestStopContainerPreStopExecError tests that the container is stopped as usual when
+// its pre-stop command can't be run
+func TestStopContainerPreStopExecError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, dockerClient, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := &apitask.Task{Arn: "myArn"}
+	testContainer := &apicontainer.Container{
+		Name:        "c1",
+		PreStopExec: &apicontainer.PreStopExec{Command: []string{"/bin/drain"}},
+	}
+	testContainer.SetRuntimeID(containerID)
+	testContainer.SetKnownStatus(apicontainerstatus.ContainerRunning)
+	testTask.Containers = append(testTask.Containers, testContainer)
+
+	gomock.InOrder(
+		dockerClient.EXPECT().CreateContainerExec(gomock.Any(), containerID, gomock.Any(), gomock.Any()).Return(
+			nil, errors.New("container is not running")),
+		dockerClient.EXPECT().StopContainer(gomock.Any(), containerID, defaultConfig.DockerStopTimeout).Return(
+			dockerapi.DockerContainerMetadata{}),
+	)
+
+	md := taskEngine.(*DockerTaskEngine).stopContainer(testTask, testContainer)
+	assert.NoError(t, md.Error)
+}
+
+// TestStopContainerSkipsPreStopExecOfStoppedContainer tests that the pre-stop command
+// isn't run in containers that aren't running
+func TestStopContainerSkipsPreStopExecOfStoppedContainer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, dockerClient, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := &apitask.Task{Arn: "myArn"}
+	testContainer := &apicontainer.Container{
+		Name:        "c1",
+		PreStopExec: &apicontainer.PreStopExec{Command: []string{"/bin/drain"}},
+	}
+	testContainer.SetRuntimeID(containerID)
+	testContainer.SetKnownStatus(apicontainerstatus.ContainerStopped)
+	testTask.Containers = append(testTask.Containers, testContainer)
+
+	dockerClient.EXPECT().StopContainer(gomock.Any(), containerID, defaultConfig.DockerStopTimeout).Return(
+		dockerapi.DockerContainerMetadata{})
+
+	md := taskEngine.(*DockerTaskEngine).stopContainer(testTask, testContainer)
+	assert.NoError(t, md.Error)
+}
+
 // TestStopPauseContainerCleanupCalled tests when stopping the pause container
 // its network namespace should be cleaned up first
 func TestStopPauseContainerCleanupDelay(t *testing.T) {
@@ -1569,6 +1762,47 @@ func TestPullAndUpdateContainerReference(t *testing.T) {
 	}
 }
 
+func TestPullAndUpdateContainerReferenceSOCILazyLoading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := &config.Config{
+		ImagePullSOCILazyLoading:  config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+		ImagePullMaxBandwidthMbps: 100,
+	}
+	ctrl, client, _, privateTaskEngine, _, imageManager, _ := mocks(t, ctx, cfg)
+	defer ctrl.Finish()
+
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	taskEngine._time = nil
+	// Lazily loaded images should not wait for the bandwidth of running pulls
+	require.NoError(t, taskEngine.imagePullScheduler.acquire(ctx, 100))
+	defer taskEngine.imagePullScheduler.release(100)
+
+	authData := &apicontainer.RegistryAuthenticationData{
+		Type:        apicontainer.AuthTypeECR,
+		ECRAuthData: &apicontainer.ECRAuthData{RegistryID: "123456789012", Region: "us-west-2"},
+	}
+	container := &apicontainer.Container{
+		Type:                   apicontainer.ContainerNormal,
+		Image:                  testPreloadECRImage,
+		Essential:              true,
+		RegistryAuthentication: authData,
+	}
+	task := &apitask.Task{
+		Arn:        "taskArn",
+		Containers: []*apicontainer.Container{container},
+	}
+
+	client.EXPECT().LazyLoadsImage(gomock.Any(), testPreloadECRImage, authData).Return(true)
+	client.EXPECT().PullImage(gomock.Any(), testPreloadECRImage, authData, gomock.Any()).
+		Return(dockerapi.DockerContainerMetadata{})
+	imageManager.EXPECT().RecordContainerReference(container)
+	imageManager.EXPECT().GetImageStateFromImageName(testPreloadECRImage).Return(nil, false)
+
+	metadata := taskEngine.pullAndUpdateContainerReference(task, container)
+	assert.NoError(t, metadata.Error)
+}
+
 // TestMetadataFileUpdatedAgentRestart checks whether metadataManager.Update(...) is
 // invoked in the path DockerTaskEngine.Init() -> .synchronizeState() -> .updateMetadataFile(...)
 // for the following case:
@@ -2578,7 +2812,8 @@ func TestTaskSecretsEnvironmentVariables(t *testing.T) {
 				ssmRequirements,
 				credentialsID,
 				credentialsManager,
-				ssmClientCreator)
+				ssmClientCreator,
+				nil)
 
 			// required for validating asm workflows
 			asmClientCreator := mock_asm_factory.NewMockClientCreator(ctrl)
@@ -2593,7 +2828,8 @@ func TestTaskSecretsEnvironmentVariables(t *testing.T) {
 				asmRequirements,
 				credentialsID,
 				credentialsManager,
-				asmClientCreator)
+				asmClientCreator,
+				nil)
 
 			testTask.ResourcesMapUnsafe = map[string][]taskresource.TaskResource{
 				ssmsecret.ResourceName: {ssmSecretRes},
@@ -3457,6 +3693,49 @@ func TestMonitorExecAgentsMultipleContainers(t *testing.T) {
 
 }
 
+func TestMonitorExecAgentsEnforcesSessionLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.ExecSessionIdleTimeout = time.Minute
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	execCmdMgr := mock_execcmdagent.NewMockManager(ctrl)
+	dockerTaskEngine.execCmdMgr = execCmdMgr
+	defer ctrl.Finish()
+
+	testTask := &apitask.Task{
+		Arn: "arn:aws:ecs:region:account-id:task/test-task-arn",
+		Containers: []*apicontainer.Container{
+			{
+				Name:              "test-container",
+				RuntimeID:         "runtime-ID",
+				KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+			},
+		},
+		KnownStatusUnsafe: apitaskstatus.TaskRunning,
+	}
+	// the agent failed to initialize, so it's not restarted, but its sessions are still checked
+	enableExecCommandAgentForContainer(testTask.Containers[0], apicontainer.ManagedAgentState{
+		InitFailed: true,
+	})
+	dockerTaskEngine.state.AddTask(testTask)
+	dockerTaskEngine.managedTasks[testTask.Arn] = &managedTask{Task: testTask}
+
+	done := make(chan struct{})
+	execCmdMgr.EXPECT().EnforceSessionLimits(ctx, dockerTaskEngine.client, testTask).DoAndReturn(
+		func(ctx context.Context, client dockerapi.DockerClient, task *apitask.Task) error {
+			close(done)
+			return nil
+		})
+	dockerTaskEngine.monitorExecAgentProcesses(ctx)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("exec session limits were not enforced")
+	}
+}
+
 func TestPeriodicExecAgentsMonitoring(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
@@ -3577,3 +3856,25 @@ func TestCreateContainerWithExecAgent(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteTaskForgetsPullCredentials(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	mockTime.EXPECT().Now().AnyTimes()
+
+	testTask := testdata.LoadTask("sleep5")
+	testTask.SetExecutionRoleCredentialsID(credentialsID)
+	testTask.Containers[0].RegistryAuthentication = &apicontainer.RegistryAuthenticationData{
+		Type: "ecr",
+		ECRAuthData: &apicontainer.ECRAuthData{
+			UseExecutionRole: true,
+		},
+	}
+
+	taskEngine.(*DockerTaskEngine).State().AddTask(testTask)
+
+	client.EXPECT().ForgetPullCredentials(credentialsID)
+	taskEngine.(*DockerTaskEngine).deleteTask(testTask)
+}
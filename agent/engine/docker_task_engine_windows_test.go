@@ -73,6 +73,7 @@ func TestDeleteTask(t *testing.T) {
 	}
 
 	gomock.InOrder(
+		mockState.EXPECT().ContainerMapByArn(testTaskARN).Return(nil, false),
 		mockState.EXPECT().RemoveTask(task),
 	)
 
@@ -142,7 +143,9 @@ func TestCredentialSpecResourceTaskFile(t *testing.T) {
 		credentialsID,
 		credentialsManager,
 		ssmClientCreator,
-		s3ClientCreator)
+		s3ClientCreator,
+		nil,
+		0)
 	assert.NoError(t, cerr)
 
 	credSpecdata := map[string]string{
@@ -223,7 +226,9 @@ func TestCredentialSpecResourceTaskFileErr(t *testing.T) {
 		credentialsID,
 		credentialsManager,
 		ssmClientCreator,
-		s3ClientCreator)
+		s3ClientCreator,
+		nil,
+		0)
 	assert.NoError(t, cerr)
 
 	credSpecdata := map[string]string{
@@ -0,0 +1,200 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// nfsNoShareCacheOption makes the kernel create a new superblock for a remounted NFS
+// volume, instead of reusing the one of the stale mount
+const nfsNoShareCacheOption = "nosharecache"
+
+// hostProcFSPath is the path at which the host procfs is mounted in the agent container
+var hostProcFSPath = "/host/proc"
+
+// mountFlags maps the per-mount options listed in mountinfo to their mount flags
+var mountFlags = map[string]uintptr{
+	"ro":         unix.MS_RDONLY,
+	"nosuid":     unix.MS_NOSUID,
+	"nodev":      unix.MS_NODEV,
+	"noexec":     unix.MS_NOEXEC,
+	"noatime":    unix.MS_NOATIME,
+	"nodiratime": unix.MS_NODIRATIME,
+	"relatime":   unix.MS_RELATIME,
+}
+
+// mountInfo is a mount as listed in mountinfo
+type mountInfo struct {
+	root         string
+	mountPoint   string
+	options      string
+	fsType       string
+	source       string
+	superOptions string
+}
+
+// probeMount stats the mount point at the path in the mount namespace of the process,
+// through the root of the process in the host procfs. Only the errors of stale or
+// unreachable mounts are returned, so that a missing host procfs isn't mistaken for an
+// unhealthy mount.
+func probeMount(pid int, mountPath string) error {
+	var stat unix.Stat_t
+	err := unix.Stat(filepath.Join(hostProcFSPath, strconv.Itoa(pid), "root", mountPath), &stat)
+	switch err {
+	case nil:
+		return nil
+	case unix.ESTALE, unix.EIO, unix.ENOTCONN, unix.ETIMEDOUT:
+		return errors.Wrapf(err, "unable to stat mount %s", mountPath)
+	}
+	logger.Debug("Unable to probe mount", logger.Fields{
+		"pid":       pid,
+		"path":      mountPath,
+		field.Error: err,
+	})
+	return nil
+}
+
+// remountMount lazily unmounts the NFS mount at the path in the mount namespace of the
+// process, and mounts it again with the same source and options. The mount namespace
+// is joined from a dedicated thread, which is never unlocked so that it exits along
+// with the goroutine instead of being reused in the namespace of the container.
+func remountMount(pid int, mountPath string) error {
+	info, err := findMount(filepath.Join(hostProcFSPath, strconv.Itoa(pid), "mountinfo"), mountPath)
+	if err != nil {
+		return err
+	}
+	if info.fsType != "nfs" && info.fsType != "nfs4" {
+		return errors.Errorf("unable to remount %s: unsupported filesystem type %s", mountPath, info.fsType)
+	}
+	source, flags, data := info.mountArgs()
+
+	result := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		result <- remountInNamespace(pid, mountPath, source, info.fsType, flags, data)
+	}()
+	return <-result
+}
+
+func remountInNamespace(pid int, mountPath, source, fsType string, flags uintptr, data string) error {
+	ns, err := os.Open(filepath.Join(hostProcFSPath, strconv.Itoa(pid), "ns", "mnt"))
+	if err != nil {
+		return errors.Wrap(err, "unable to open the mount namespace of the container")
+	}
+	defer ns.Close()
+	// the filesystem attributes are shared with the other threads until unshared, which
+	// is required to join another mount namespace
+	if err := unix.Unshare(unix.CLONE_FS); err != nil {
+		return errors.Wrap(err, "unable to unshare the filesystem attributes")
+	}
+	if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNS); err != nil {
+		return errors.Wrap(err, "unable to join the mount namespace of the container")
+	}
+	if err := unix.Unmount(mountPath, unix.MNT_DETACH); err != nil {
+		return errors.Wrapf(err, "unable to unmount %s", mountPath)
+	}
+	if err := unix.Mount(source, mountPath, fsType, flags, data); err != nil {
+		return errors.Wrapf(err, "unable to mount %s at %s", source, mountPath)
+	}
+	return nil
+}
+
+// findMount returns the last mount at the mount point listed in a mountinfo file, which
+// is the one visible at the mount point
+func findMount(mountInfoPath, mountPoint string) (*mountInfo, error) {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the mounts of the container")
+	}
+	defer file.Close()
+
+	var found *mountInfo
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		info, ok := parseMountInfo(scanner.Text())
+		if ok && info.mountPoint == path.Clean(mountPoint) {
+			found = info
+		}
+	}
+	if found == nil {
+		return nil, errors.Errorf("no mount found at %s", mountPoint)
+	}
+	return found, nil
+}
+
+// parseMountInfo parses a line of mountinfo, whose optional fields are terminated by
+// a single hyphen:
+// 36 35 0:45 / /data rw,relatime shared:1 - nfs4 fs-id.efs.us-west-2.amazonaws.com:/ rw,vers=4.1
+func parseMountInfo(line string) (*mountInfo, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 10 {
+		return nil, false
+	}
+	for i := 6; i < len(fields)-3; i++ {
+		if fields[i] == "-" {
+			return &mountInfo{
+				root:         unescapeMountInfo(fields[3]),
+				mountPoint:   unescapeMountInfo(fields[4]),
+				options:      fields[5],
+				fsType:       fields[i+1],
+				source:       unescapeMountInfo(fields[i+2]),
+				superOptions: fields[i+3],
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// unescapeMountInfo unescapes the octal escapes of the spaces, tabs, newlines and
+// backslashes in the paths of mountinfo
+func unescapeMountInfo(field string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(field)
+}
+
+// mountArgs returns the arguments of the mount syscall recreating the mount. The source
+// includes the directory of the filesystem mounted at the mount point, and the read-only
+// flag comes from the per-mount options rather than from the filesystem options.
+func (info *mountInfo) mountArgs() (string, uintptr, string) {
+	source := info.source
+	if info.root != "/" {
+		source = strings.TrimSuffix(source, "/") + info.root
+	}
+
+	var flags uintptr
+	for _, option := range strings.Split(info.options, ",") {
+		flags |= mountFlags[option]
+	}
+	var data []string
+	for _, option := range strings.Split(info.superOptions, ",") {
+		if option != "rw" && option != "ro" && option != nfsNoShareCacheOption {
+			data = append(data, option)
+		}
+	}
+	data = append(data, nfsNoShareCacheOption)
+	return source, flags, strings.Join(data, ",")
+}
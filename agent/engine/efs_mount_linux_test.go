@@ -0,0 +1,72 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+const testMountInfo = `22 1 259:1 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p1 rw
+36 22 0:45 / /mnt/efs rw,relatime shared:2 - nfs4 fs-12345678.efs.us-west-2.amazonaws.com:/ rw,vers=4.1,rsize=1048576,hard,timeo=600
+37 22 0:46 /data /mnt/efs\040data ro,nosuid,nodev - nfs4 127.0.0.1:/ rw,vers=4.1,port=20049
+38 36 0:47 / /mnt/efs rw,noatime - nfs4 fs-12345678.efs.us-west-2.amazonaws.com:/ rw,vers=4.1,nosharecache
+`
+
+func TestFindMount(t *testing.T) {
+	mountInfoPath := filepath.Join(t.TempDir(), "mountinfo")
+	require.NoError(t, ioutil.WriteFile(mountInfoPath, []byte(testMountInfo), 0644))
+
+	// the last mount at the mount point is the visible one
+	info, err := findMount(mountInfoPath, "/mnt/efs/")
+	require.NoError(t, err)
+	assert.Equal(t, "rw,noatime", info.options)
+
+	info, err = findMount(mountInfoPath, "/mnt/efs data")
+	require.NoError(t, err)
+	assert.Equal(t, "/data", info.root)
+	assert.Equal(t, "nfs4", info.fsType)
+	assert.Equal(t, "127.0.0.1:/", info.source)
+
+	_, err = findMount(mountInfoPath, "/mnt/other")
+	assert.Error(t, err)
+}
+
+func TestParseMountInfoInvalidLine(t *testing.T) {
+	_, ok := parseMountInfo("36 22 0:45 / /mnt/efs rw,relatime shared:2 nfs4")
+	assert.False(t, ok)
+}
+
+func TestMountArgs(t *testing.T) {
+	info, ok := parseMountInfo(`37 22 0:46 /data /mnt/efs\040data ro,nosuid,nodev - nfs4 127.0.0.1:/ rw,vers=4.1,port=20049`)
+	require.True(t, ok)
+	source, flags, data := info.mountArgs()
+	assert.Equal(t, "127.0.0.1:/data", source)
+	assert.Equal(t, uintptr(unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV), flags)
+	assert.Equal(t, "vers=4.1,port=20049,nosharecache", data)
+
+	info, ok = parseMountInfo(`38 36 0:47 / /mnt/efs rw,noatime - nfs4 fs-12345678.efs.us-west-2.amazonaws.com:/ rw,vers=4.1,nosharecache`)
+	require.True(t, ok)
+	source, flags, data = info.mountArgs()
+	assert.Equal(t, "fs-12345678.efs.us-west-2.amazonaws.com:/", source)
+	assert.Equal(t, uintptr(unix.MS_NOATIME), flags)
+	assert.Equal(t, "vers=4.1,nosharecache", data)
+}
@@ -0,0 +1,397 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/pkg/errors"
+)
+
+const (
+	// efsMountProbeTimeout is how long a probe of an EFS mount can take before the mount
+	// is considered unresponsive
+	efsMountProbeTimeout = 10 * time.Second
+	// efsMountRemountAttempts is the number of times an unhealthy EFS mount is remounted
+	// before the containers using it are marked unhealthy or stopped
+	efsMountRemountAttempts      = 3
+	efsMountRemountMinDelay      = 5 * time.Second
+	efsMountRemountMaxDelay      = 30 * time.Second
+	efsMountRemountJitter        = 0.2
+	efsMountRemountDelayMultiple = 2
+
+	// EFSMountUnhealthyReason is the reason in the health status, or the stopped reason, of
+	// the containers whose EFS mounts couldn't be recovered
+	EFSMountUnhealthyReason = "EFSMountUnhealthy"
+)
+
+var (
+	// probeEFSMount checks that the EFS mount at the path in the mount namespace of the
+	// process responds
+	probeEFSMount = probeMount
+	// remountEFSMount remounts the EFS mount at the path in the mount namespace of the
+	// process
+	remountEFSMount = remountMount
+
+	errEFSMountProbePending = errors.New("a previous probe of the mount hasn't returned yet")
+)
+
+// efsMountWatcher keeps track of the EFS mounts being probed and of the ones that
+// couldn't be recovered
+type efsMountWatcher struct {
+	lock sync.Mutex
+	// pending holds the mounts whose last probe hasn't returned yet, which happens when
+	// the mount hangs, so that probes don't pile up on an unresponsive mount
+	pending map[string]bool
+	// failed holds the mounts that couldn't be recovered, which aren't remounted again
+	// until they respond
+	failed map[string]bool
+	// remounting holds the mounts being remounted, so that the checks that overlap with
+	// the remounts leave them alone
+	remounting map[string]bool
+	// swappable for testing
+	remountMinDelay time.Duration
+	remountMaxDelay time.Duration
+}
+
+func newEFSMountWatcher() *efsMountWatcher {
+	return &efsMountWatcher{
+		pending:         make(map[string]bool),
+		failed:          make(map[string]bool),
+		remounting:      make(map[string]bool),
+		remountMinDelay: efsMountRemountMinDelay,
+		remountMaxDelay: efsMountRemountMaxDelay,
+	}
+}
+
+// efsMount is an EFS volume mounted in a container
+type efsMount struct {
+	task          *apitask.Task
+	container     *apicontainer.Container
+	volumeName    string
+	containerPath string
+	dockerID      string
+	pid           int
+}
+
+func (mount efsMount) key() string {
+	return mount.dockerID + ":" + mount.containerPath
+}
+
+// startPeriodicEFSMountChecks checks the EFS mounts of the running containers at the
+// configured interval, until the context is done
+func (engine *DockerTaskEngine) startPeriodicEFSMountChecks(ctx context.Context) {
+	if engine.cfg.EFSMountHealthCheckInterval <= 0 {
+		return
+	}
+	reloaded := engine.configReloadedNotification()
+	interval := engine.efsMountHealthCheckInterval()
+	ticker := time.NewTicker(interval)
+	defer func() { ticker.Stop() }()
+	for {
+		select {
+		case <-ticker.C:
+			engine.checkEFSMounts(ctx)
+		case <-reloaded:
+			reloaded = engine.configReloadedNotification()
+			if next := engine.efsMountHealthCheckInterval(); next > 0 && next != interval {
+				interval = next
+				ticker.Stop()
+				ticker = time.NewTicker(interval)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkEFSMounts checks the EFS mounts of the running containers of the running tasks.
+// Each mount is checked on its own, so that an unresponsive mount doesn't hold up the
+// others.
+func (engine *DockerTaskEngine) checkEFSMounts(ctx context.Context) {
+	var tasks []*apitask.Task
+	engine.tasksLock.RLock()
+	for _, mTask := range engine.managedTasks {
+		if mTask.GetKnownStatus() == apitaskstatus.TaskRunning && !mTask.GetDesiredStatus().Terminal() {
+			tasks = append(tasks, mTask.Task)
+		}
+	}
+	engine.tasksLock.RUnlock()
+
+	for _, task := range tasks {
+		for _, mount := range engine.efsMounts(ctx, task) {
+			go engine.checkEFSMount(ctx, mount)
+		}
+	}
+}
+
+// efsMounts returns the EFS volumes mounted in the running containers of the task
+func (engine *DockerTaskEngine) efsMounts(ctx context.Context, task *apitask.Task) []efsMount {
+	efsVolumes := make(map[string]bool)
+	for _, volume := range task.Volumes {
+		if volume.Type == apitask.EFSVolumeType {
+			efsVolumes[volume.Name] = true
+		}
+	}
+	if len(efsVolumes) == 0 {
+		return nil
+	}
+
+	var mounts []efsMount
+	for _, container := range task.Containers {
+		if container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+			continue
+		}
+		var containerMounts []efsMount
+		for _, mountPoint := range container.MountPoints {
+			if efsVolumes[mountPoint.SourceVolume] {
+				containerMounts = append(containerMounts, efsMount{
+					task:          task,
+					container:     container,
+					volumeName:    mountPoint.SourceVolume,
+					containerPath: mountPoint.ContainerPath,
+				})
+			}
+		}
+		if len(containerMounts) == 0 {
+			continue
+		}
+		dockerID, pid, err := engine.containerPID(ctx, task, container)
+		if err != nil {
+			logger.Warn("Unable to check the EFS mounts of container", logger.Fields{
+				field.TaskARN:   task.Arn,
+				field.Container: container.Name,
+				field.Error:     err,
+			})
+			continue
+		}
+		for _, mount := range containerMounts {
+			mount.dockerID = dockerID
+			mount.pid = pid
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
+
+// containerPID returns the docker ID and the pid on the host of the container
+func (engine *DockerTaskEngine) containerPID(ctx context.Context, task *apitask.Task,
+	container *apicontainer.Container) (string, int, error) {
+	dockerID, err := engine.getDockerID(task, container)
+	if err != nil {
+		return "", 0, err
+	}
+	inspect, err := engine.client.InspectContainer(ctx, dockerID, dockerclient.InspectContainerTimeout)
+	if err != nil {
+		return "", 0, err
+	}
+	if inspect.State == nil || inspect.State.Pid == 0 {
+		return "", 0, errors.Errorf("container %s isn't running", dockerID)
+	}
+	return dockerID, inspect.State.Pid, nil
+}
+
+// checkEFSMount probes an EFS mount, and remounts it with backoff when it's stale or
+// unresponsive. The containers using the mount are marked unhealthy, or stopped, when it
+// can't be recovered.
+func (engine *DockerTaskEngine) checkEFSMount(ctx context.Context, mount efsMount) {
+	watcher := engine.efsMountWatcher
+	if watcher.isRemounting(mount) {
+		return
+	}
+	err := watcher.probe(mount.key(), mount)
+	if err == errEFSMountProbePending {
+		return
+	}
+	if err == nil {
+		watcher.setFailed(mount, false)
+		return
+	}
+	if watcher.isFailed(mount) || !watcher.startRemount(mount) {
+		return
+	}
+	defer watcher.finishRemount(mount)
+
+	fields := logger.Fields{
+		field.TaskARN:   mount.task.Arn,
+		field.Container: mount.container.Name,
+		"volume":        mount.volumeName,
+		"path":          mount.containerPath,
+		field.Error:     err,
+	}
+	logger.Warn("EFS mount is unhealthy, remounting", fields)
+
+	backoff := retry.NewExponentialBackoff(watcher.remountMinDelay, watcher.remountMaxDelay,
+		efsMountRemountJitter, efsMountRemountDelayMultiple)
+	for attempt := 0; attempt < efsMountRemountAttempts; attempt++ {
+		select {
+		case <-time.After(backoff.Duration()):
+		case <-ctx.Done():
+			return
+		}
+		err = remountEFSMount(mount.pid, mount.containerPath)
+		if err == nil {
+			// the previous probes may still hang on the detached mount
+			err = watcher.probe(fmt.Sprintf("%s/remount-%d", mount.key(), attempt), mount)
+		}
+		if err == nil {
+			delete(fields, field.Error)
+			logger.Info("Remounted EFS mount", fields)
+			return
+		}
+		fields[field.Error] = err
+		logger.Warn("Unable to recover EFS mount", fields)
+	}
+
+	watcher.setFailed(mount, true)
+	engine.setEFSMountUnhealthy(mount, err)
+}
+
+// setEFSMountUnhealthy marks the containers using an EFS mount that couldn't be
+// recovered as unhealthy. The health status of the containers without a health check
+// isn't reported, so their managed task stops them instead, with the reason recorded on
+// the container, and on the task when they're essential.
+func (engine *DockerTaskEngine) setEFSMountUnhealthy(mount efsMount, err error) {
+	unhealthyErr := EFSMountUnhealthyError{errors.Errorf("volume %s mounted at %s is unresponsive: %v",
+		mount.volumeName, mount.containerPath, err)}
+	for _, container := range mount.task.Containers {
+		if container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+			continue
+		}
+		uses := false
+		for _, mountPoint := range container.MountPoints {
+			uses = uses || mountPoint.SourceVolume == mount.volumeName
+		}
+		if !uses {
+			continue
+		}
+		if !container.HealthStatusShouldBeReported() {
+			engine.stopEFSMountUnhealthyContainer(mount.task, container, unhealthyErr)
+			continue
+		}
+		logger.Error("Marking container unhealthy, as its EFS mount couldn't be recovered", logger.Fields{
+			field.TaskARN:   mount.task.Arn,
+			field.Container: container.Name,
+			"volume":        mount.volumeName,
+		})
+		container.SetHealthStatus(apicontainer.HealthStatus{
+			Status:   apicontainerstatus.ContainerUnhealthy,
+			Output:   unhealthyErr.Error(),
+			ExitCode: 1,
+		})
+		container.AddHealthCheckResults(apicontainer.HealthCheckResult{
+			Timestamp: time.Now(),
+			ExitCode:  1,
+			Output:    unhealthyErr.Error(),
+		})
+		engine.saveContainerData(container)
+	}
+}
+
+// stopEFSMountUnhealthyContainer asks the managed task of a container whose EFS mount
+// couldn't be recovered to stop it, so that the reason is sent along with its stopped
+// state change
+func (engine *DockerTaskEngine) stopEFSMountUnhealthyContainer(task *apitask.Task, container *apicontainer.Container,
+	unhealthyErr EFSMountUnhealthyError) {
+	engine.tasksLock.RLock()
+	managedTask, ok := engine.managedTasks[task.Arn]
+	engine.tasksLock.RUnlock()
+	if !ok {
+		return
+	}
+	logger.Error("Stopping container, as its EFS mount couldn't be recovered", logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: container.Name,
+		field.Error:     unhealthyErr,
+	})
+	managedTask.emitContainerStopRequest(containerStopRequest{container: container, err: unhealthyErr})
+}
+
+// probe probes the mount with a timeout, unless the previous probe with the same key
+// hasn't returned yet
+func (watcher *efsMountWatcher) probe(key string, mount efsMount) error {
+	watcher.lock.Lock()
+	if watcher.pending[key] {
+		watcher.lock.Unlock()
+		return errEFSMountProbePending
+	}
+	watcher.pending[key] = true
+	watcher.lock.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		err := probeEFSMount(mount.pid, mount.containerPath)
+		watcher.lock.Lock()
+		delete(watcher.pending, key)
+		watcher.lock.Unlock()
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(efsMountProbeTimeout):
+		return errors.Errorf("mount didn't respond within %s", efsMountProbeTimeout)
+	}
+}
+
+func (watcher *efsMountWatcher) isFailed(mount efsMount) bool {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+	return watcher.failed[mount.key()]
+}
+
+func (watcher *efsMountWatcher) setFailed(mount efsMount, failed bool) {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+	if failed {
+		watcher.failed[mount.key()] = true
+	} else {
+		delete(watcher.failed, mount.key())
+	}
+}
+
+func (watcher *efsMountWatcher) isRemounting(mount efsMount) bool {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+	return watcher.remounting[mount.key()]
+}
+
+// startRemount records that the mount is being remounted, and returns false if it
+// already was
+func (watcher *efsMountWatcher) startRemount(mount efsMount) bool {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+	if watcher.remounting[mount.key()] {
+		return false
+	}
+	watcher.remounting[mount.key()] = true
+	return true
+}
+
+func (watcher *efsMountWatcher) finishRemount(mount efsMount) {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+	delete(watcher.remounting, mount.key())
+}
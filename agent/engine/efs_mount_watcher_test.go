@@ -0,0 +1,234 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEFSMountPath = "/mnt/efs"
+
+func newEFSMountTestTask() *apitask.Task {
+	appContainer := &apicontainer.Container{
+		Name:              "app",
+		KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+		MountPoints:       []apicontainer.MountPoint{{SourceVolume: "efs-vol", ContainerPath: testEFSMountPath}},
+	}
+	appContainer.SetRuntimeID(containerID)
+	sidecarContainer := &apicontainer.Container{
+		Name:              "sidecar",
+		KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+	}
+	sidecarContainer.SetRuntimeID("sidecar-id")
+	return &apitask.Task{
+		Arn:                 "arn:aws:ecs:us-west-2:1234567890:task/mycluster/task1",
+		Containers:          []*apicontainer.Container{appContainer, sidecarContainer},
+		KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		Volumes: []apitask.TaskVolume{
+			{Name: "efs-vol", Type: apitask.EFSVolumeType},
+			{Name: "docker-vol", Type: apitask.DockerVolumeType},
+		},
+	}
+}
+
+// setupEFSMountTest stubs the probes and the remounts of the EFS mounts, and returns the
+// number of remounts
+func setupEFSMountTest(t *testing.T, engine *DockerTaskEngine, probeErrs []error, remountErr error) (*int, func()) {
+	engine.efsMountWatcher.remountMinDelay = time.Millisecond
+	engine.efsMountWatcher.remountMaxDelay = 2 * time.Millisecond
+	probes, remounts := 0, 0
+	probeEFSMount = func(pid int, mountPath string) error {
+		assert.Equal(t, 1234, pid)
+		assert.Equal(t, testEFSMountPath, mountPath)
+		require.True(t, probes < len(probeErrs), "unexpected probe")
+		err := probeErrs[probes]
+		probes++
+		return err
+	}
+	remountEFSMount = func(pid int, mountPath string) error {
+		remounts++
+		return remountErr
+	}
+	return &remounts, func() {
+		probeEFSMount = probeMount
+		remountEFSMount = remountMount
+	}
+}
+
+func TestEFSMounts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	client.EXPECT().InspectContainer(gomock.Any(), containerID, gomock.Any()).Return(&types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Pid: 1234}},
+	}, nil)
+
+	mounts := dockerTaskEngine.efsMounts(ctx, newEFSMountTestTask())
+	require.Len(t, mounts, 1)
+	assert.Equal(t, "app", mounts[0].container.Name)
+	assert.Equal(t, "efs-vol", mounts[0].volumeName)
+	assert.Equal(t, testEFSMountPath, mounts[0].containerPath)
+	assert.Equal(t, 1234, mounts[0].pid)
+}
+
+func TestCheckEFSMountRemountsUnhealthyMount(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	remounts, cleanup := setupEFSMountTest(t, dockerTaskEngine,
+		[]error{errors.New("stale file handle"), nil}, nil)
+	defer cleanup()
+
+	task := newEFSMountTestTask()
+	dockerTaskEngine.checkEFSMount(ctx, efsMount{
+		task:          task,
+		container:     task.Containers[0],
+		volumeName:    "efs-vol",
+		containerPath: testEFSMountPath,
+		dockerID:      containerID,
+		pid:           1234,
+	})
+	assert.Equal(t, 1, *remounts)
+	assert.Equal(t, apicontainerstatus.ContainerHealthUnknown, task.Containers[0].GetHealthStatus().Status)
+}
+
+func TestCheckEFSMountMarksContainersUnhealthy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	probeErr := errors.New("stale file handle")
+	remounts, cleanup := setupEFSMountTest(t, dockerTaskEngine,
+		[]error{probeErr, probeErr, probeErr, probeErr, probeErr}, nil)
+	defer cleanup()
+
+	task := newEFSMountTestTask()
+	task.Containers[0].HealthCheckType = apicontainer.DockerHealthCheckType
+	mount := efsMount{
+		task:          task,
+		container:     task.Containers[0],
+		volumeName:    "efs-vol",
+		containerPath: testEFSMountPath,
+		dockerID:      containerID,
+		pid:           1234,
+	}
+	dockerTaskEngine.checkEFSMount(ctx, mount)
+	assert.Equal(t, efsMountRemountAttempts, *remounts)
+	assert.False(t, task.Containers[0].GetDesiredStatus().Terminal())
+	health := task.Containers[0].GetHealthStatus()
+	assert.Equal(t, apicontainerstatus.ContainerUnhealthy, health.Status)
+	assert.Contains(t, health.Output, EFSMountUnhealthyReason)
+	assert.Equal(t, apicontainerstatus.ContainerHealthUnknown, task.Containers[1].GetHealthStatus().Status)
+
+	// the mount isn't remounted again until it responds
+	dockerTaskEngine.checkEFSMount(ctx, mount)
+	assert.Equal(t, efsMountRemountAttempts, *remounts)
+	assert.True(t, dockerTaskEngine.efsMountWatcher.isFailed(mount))
+}
+
+func TestCheckEFSMountStopsContainersWithoutHealthCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	probeErr := errors.New("stale file handle")
+	_, cleanup := setupEFSMountTest(t, dockerTaskEngine,
+		[]error{probeErr, probeErr, probeErr, probeErr}, nil)
+	defer cleanup()
+
+	stopped := make(chan struct{})
+	client.EXPECT().
+		StopContainer(gomock.Any(), containerID, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, dockerID string, timeout time.Duration) dockerapi.DockerContainerMetadata {
+			close(stopped)
+			return dockerapi.DockerContainerMetadata{}
+		})
+
+	task := newEFSMountTestTask()
+	task.Containers[0].Essential = true
+	mTask := dockerTaskEngine.newManagedTask(task)
+	go dockerTaskEngine.checkEFSMount(ctx, efsMount{
+		task:          task,
+		container:     task.Containers[0],
+		volumeName:    "efs-vol",
+		containerPath: testEFSMountPath,
+		dockerID:      containerID,
+		pid:           1234,
+	})
+	// the container is stopped by its managed task
+	mTask.waitEvent(ctx.Done())
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the container wasn't stopped")
+	}
+	container := task.Containers[0]
+	assert.Equal(t, apicontainerstatus.ContainerStopped, container.GetDesiredStatus())
+	require.NotNil(t, container.ApplyingError)
+	assert.Equal(t, EFSMountUnhealthyReason, container.ApplyingError.ErrorName())
+	assert.Contains(t, task.GetTerminalReason(), EFSMountUnhealthyReason)
+	assert.Equal(t, apicontainerstatus.ContainerHealthUnknown, container.GetHealthStatus().Status)
+	assert.False(t, task.Containers[1].GetDesiredStatus().Terminal())
+}
+
+func TestCheckEFSMountSkipsMountBeingRemounted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	// the mount isn't probed nor remounted while it's being remounted
+	remounts, cleanup := setupEFSMountTest(t, dockerTaskEngine, nil, nil)
+	defer cleanup()
+
+	task := newEFSMountTestTask()
+	mount := efsMount{
+		task:          task,
+		container:     task.Containers[0],
+		volumeName:    "efs-vol",
+		containerPath: testEFSMountPath,
+		dockerID:      containerID,
+		pid:           1234,
+	}
+	require.True(t, dockerTaskEngine.efsMountWatcher.startRemount(mount))
+	assert.False(t, dockerTaskEngine.efsMountWatcher.startRemount(mount))
+	dockerTaskEngine.checkEFSMount(ctx, mount)
+	assert.Equal(t, 0, *remounts)
+
+	dockerTaskEngine.efsMountWatcher.finishRemount(mount)
+	assert.False(t, dockerTaskEngine.efsMountWatcher.isRemounting(mount))
+}
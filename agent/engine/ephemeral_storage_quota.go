@@ -0,0 +1,130 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+const (
+	// storageOptSize is the docker storage option limiting the size of the writable layer
+	// of a container
+	storageOptSize = "size"
+	// ec2LaunchType is the launch type of the tasks whose ephemeral storage is enforced
+	ec2LaunchType = "EC2"
+
+	bytesPerMiB = 1024 * 1024
+
+	overlay2StorageDriver         = "overlay2"
+	backingFilesystemDriverStatus = "Backing Filesystem"
+	xfsBackingFilesystem          = "xfs"
+)
+
+// storageQuotaDrivers are the docker storage drivers supporting the size storage option.
+// overlay2 only supports it on xfs, which must be mounted with the pquota option.
+var storageQuotaDrivers = map[string]bool{
+	overlay2StorageDriver: true,
+	"devicemapper":        true,
+	"btrfs":               true,
+	"zfs":                 true,
+}
+
+// applyEphemeralStorageQuota limits the size of the writable layer of a container to its
+// share of the ephemeral storage of its task, when the quota is enabled and the docker
+// storage driver supports it. The size storage option limits each container on its own,
+// so the ephemeral storage is split evenly between the containers of the task, which keeps
+// the task as a whole within it. The writable layers of the containers managed by the agent
+// aren't limited.
+func (engine *DockerTaskEngine) applyEphemeralStorageQuota(ctx context.Context, task *apitask.Task,
+	container *apicontainer.Container, hostConfig *dockercontainer.HostConfig) {
+	if !engine.cfg.EphemeralStorageQuotaEnabled.Enabled() || task.EphemeralStorage.SizeInBytes() == 0 ||
+		(task.LaunchType != "" && task.LaunchType != ec2LaunchType) || container.IsInternal() {
+		return
+	}
+	if !engine.supportsStorageQuota(ctx) {
+		return
+	}
+	containers := 0
+	for _, taskContainer := range task.Containers {
+		if !taskContainer.IsInternal() {
+			containers++
+		}
+	}
+	if containers == 0 {
+		containers = 1
+	}
+	sizeInMiB := task.EphemeralStorage.SizeInBytes() / bytesPerMiB / uint64(containers)
+	if hostConfig.StorageOpt == nil {
+		hostConfig.StorageOpt = make(map[string]string)
+	}
+	hostConfig.StorageOpt[storageOptSize] = fmt.Sprintf("%dM", sizeInMiB)
+	task.SetEphemeralStorageQuotaEnforced()
+	logger.Debug("Limiting the writable layer of container to its share of the ephemeral storage of the task", logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: container.Name,
+		"sizeInMiB":     sizeInMiB,
+	})
+}
+
+// supportsStorageQuota returns true if the docker storage driver supports limiting the size
+// of the writable layers. The storage driver can't change without restarting docker, so it
+// is only looked up once successfully.
+func (engine *DockerTaskEngine) supportsStorageQuota(ctx context.Context) bool {
+	engine.storageQuotaLock.Lock()
+	defer engine.storageQuotaLock.Unlock()
+
+	if engine.storageQuotaSupportedUnsafe != nil {
+		return *engine.storageQuotaSupportedUnsafe
+	}
+	info, err := engine.client.Info(ctx, dockerclient.InfoTimeout)
+	if err != nil {
+		logger.Warn("Unable to get the docker storage driver, not enforcing ephemeral storage", logger.Fields{
+			field.Error: err,
+		})
+		return false
+	}
+	supported := storageQuotaDrivers[info.Driver]
+	if info.Driver == overlay2StorageDriver {
+		supported = false
+		for _, status := range info.DriverStatus {
+			if status[0] == backingFilesystemDriverStatus && status[1] == xfsBackingFilesystem {
+				supported = true
+			}
+		}
+		// docker only reports the backing filesystem, not whether it has project quotas
+		if supported {
+			supported, err = hasProjectQuota(info.DockerRootDir)
+			if err != nil {
+				logger.Warn("Unable to find the mount options of the docker root directory, not enforcing ephemeral storage", logger.Fields{
+					field.Error: err,
+				})
+			}
+		}
+	}
+	if !supported {
+		logger.Warn("The docker storage driver doesn't support limiting the size of writable layers, not enforcing ephemeral storage", logger.Fields{
+			"driver": info.Driver,
+		})
+	}
+	engine.storageQuotaSupportedUnsafe = &supported
+	return supported
+}
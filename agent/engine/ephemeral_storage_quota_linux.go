@@ -0,0 +1,73 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// xfsProjectQuotaOption is the option of the xfs filesystems mounted with project quotas,
+// which are listed as prjquota whether they were mounted with pquota or prjquota
+const xfsProjectQuotaOption = "prjquota"
+
+// hasProjectQuota returns true if the filesystem holding the docker root directory, as
+// mounted on the host, is an xfs filesystem with project quotas, which overlay2 requires
+// to limit the size of writable layers
+func hasProjectQuota(dockerRootDir string) (bool, error) {
+	mountInfoPath := filepath.Join(hostProcFSPath, "1", "mountinfo")
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to read the mounts of the host")
+	}
+	defer file.Close()
+
+	dockerRootDir = path.Clean(dockerRootDir)
+	var found *mountInfo
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		info, ok := parseMountInfo(scanner.Text())
+		if !ok || !isPathUnder(dockerRootDir, info.mountPoint) {
+			continue
+		}
+		// the last of the closest mounts is the one visible at the docker root directory
+		if found == nil || len(info.mountPoint) >= len(found.mountPoint) {
+			found = info
+		}
+	}
+	if found == nil {
+		return false, errors.Errorf("no mount found for %s in %s", dockerRootDir, mountInfoPath)
+	}
+	if found.fsType != xfsBackingFilesystem {
+		return false, nil
+	}
+	for _, option := range strings.Split(found.superOptions, ",") {
+		if option == xfsProjectQuotaOption {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isPathUnder returns true if the path is the mount point or under it
+func isPathUnder(filePath, mountPoint string) bool {
+	return mountPoint == "/" || filePath == mountPoint || strings.HasPrefix(filePath, mountPoint+"/")
+}
@@ -0,0 +1,124 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testMountInfoRoot            = "22 1 259:1 / / rw,noatime shared:1 - ext4 /dev/nvme0n1p1 rw\n"
+	testMountInfoXFS             = "30 22 259:2 / /var/lib/docker rw,noatime shared:2 - xfs /dev/nvme1n1 rw,attr2,inode64,logbufs=8,logbsize=32k,noquota\n"
+	testMountInfoXFSProjectQuota = "30 22 259:2 / /var/lib/docker rw,noatime shared:2 - xfs /dev/nvme1n1 rw,attr2,inode64,logbufs=8,logbsize=32k,prjquota\n"
+)
+
+// setupHostMountInfo writes the mountinfo of the host, and returns a function restoring the
+// path of the host procfs
+func setupHostMountInfo(t *testing.T, mountInfo string) func() {
+	hostProcFSPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(hostProcFSPath, "1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hostProcFSPath, "1", "mountinfo"), []byte(mountInfo), 0644))
+	return func() {
+		hostProcFSPath = "/host/proc"
+	}
+}
+
+func TestApplyEphemeralStorageQuotaOverlay2(t *testing.T) {
+	info := types.Info{
+		Driver:        "overlay2",
+		DriverStatus:  [][2]string{{"Backing Filesystem", "xfs"}, {"Supports d_type", "true"}},
+		DockerRootDir: "/var/lib/docker",
+	}
+	testCases := []struct {
+		name          string
+		mountInfo     string
+		expectedQuota string
+	}{
+		{
+			name:          "xfs with project quotas",
+			mountInfo:     testMountInfoRoot + testMountInfoXFSProjectQuota,
+			expectedQuota: "10240M",
+		},
+		{
+			name:      "xfs without project quotas",
+			mountInfo: testMountInfoRoot + testMountInfoXFS,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cleanup := setupHostMountInfo(t, tc.mountInfo)
+			defer cleanup()
+			testApplyEphemeralStorageQuota(t, info, "EC2", tc.expectedQuota)
+		})
+	}
+}
+
+func TestHasProjectQuota(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mountInfo     string
+		dockerRootDir string
+		expected      bool
+	}{
+		{
+			name:          "docker root directory on xfs with project quotas",
+			mountInfo:     testMountInfoRoot + testMountInfoXFSProjectQuota,
+			dockerRootDir: "/var/lib/docker",
+			expected:      true,
+		},
+		{
+			name:          "docker root directory under xfs mount with project quotas",
+			mountInfo:     testMountInfoRoot + testMountInfoXFSProjectQuota,
+			dockerRootDir: "/var/lib/docker/custom",
+			expected:      true,
+		},
+		{
+			name:          "docker root directory on xfs without project quotas",
+			mountInfo:     testMountInfoRoot + testMountInfoXFS,
+			dockerRootDir: "/var/lib/docker",
+		},
+		{
+			name:          "docker root directory on the root filesystem",
+			mountInfo:     testMountInfoRoot + testMountInfoXFSProjectQuota,
+			dockerRootDir: "/var/lib/docker2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cleanup := setupHostMountInfo(t, tc.mountInfo)
+			defer cleanup()
+			supported, err := hasProjectQuota(tc.dockerRootDir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, supported)
+		})
+	}
+}
+
+func TestHasProjectQuotaWithoutHostProcFS(t *testing.T) {
+	hostProcFSPath = filepath.Join(t.TempDir(), "missing")
+	defer func() {
+		hostProcFSPath = "/host/proc"
+	}()
+	_, err := hasProjectQuota("/var/lib/docker")
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEphemeralStorageQuota(t *testing.T) {
+	testCases := []struct {
+		name          string
+		info          types.Info
+		launchType    string
+		expectedQuota string
+	}{
+		{
+			name:          "devicemapper",
+			info:          types.Info{Driver: "devicemapper"},
+			expectedQuota: "10240M",
+		},
+		{
+			name: "overlay2 on extfs",
+			info: types.Info{
+				Driver:       "overlay2",
+				DriverStatus: [][2]string{{"Backing Filesystem", "extfs"}},
+			},
+			launchType: "EC2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testApplyEphemeralStorageQuota(t, tc.info, tc.launchType, tc.expectedQuota)
+		})
+	}
+}
+
+// testApplyEphemeralStorageQuota checks the quota of the containers of a task with 30GiB
+// of ephemeral storage, split between its three containers that aren't internal
+func testApplyEphemeralStorageQuota(t *testing.T, info types.Info, launchType, expectedQuota string) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := config.DefaultConfig()
+	cfg.EphemeralStorageQuotaEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	// the storage driver is only looked up once
+	client.EXPECT().Info(gomock.Any(), gomock.Any()).Return(info, nil)
+	for i := 0; i < 2; i++ {
+		task := &apitask.Task{
+			Arn:              "arn:aws:ecs:us-west-2:1234567890:task/mycluster/task1",
+			LaunchType:       launchType,
+			EphemeralStorage: &apitask.EphemeralStorage{SizeInGiB: 30},
+			Containers: []*apicontainer.Container{
+				{Name: "app"},
+				{Name: "sidecar"},
+				{Name: "log"},
+				{Name: "pause", Type: apicontainer.ContainerCNIPause},
+			},
+		}
+		for _, container := range task.Containers {
+			hostConfig := &dockercontainer.HostConfig{}
+			dockerTaskEngine.applyEphemeralStorageQuota(ctx, task, container, hostConfig)
+			if container.IsInternal() {
+				assert.Empty(t, hostConfig.StorageOpt)
+				continue
+			}
+			assert.Equal(t, expectedQuota, hostConfig.StorageOpt[storageOptSize])
+		}
+		assert.Equal(t, expectedQuota != "", task.IsEphemeralStorageQuotaEnforced())
+	}
+}
+
+func TestApplyEphemeralStorageQuotaNotApplicable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := config.DefaultConfig()
+	cfg.EphemeralStorageQuotaEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	// absence of info expect call indicates the storage driver shouldn't have been looked up
+	for _, task := range []*apitask.Task{
+		{Arn: "no-ephemeral-storage"},
+		{Arn: "external", LaunchType: "EXTERNAL", EphemeralStorage: &apitask.EphemeralStorage{SizeInGiB: 30}},
+	} {
+		hostConfig := &dockercontainer.HostConfig{}
+		dockerTaskEngine.applyEphemeralStorageQuota(ctx, task, &apicontainer.Container{Name: "c"}, hostConfig)
+		assert.Empty(t, hostConfig.StorageOpt)
+		assert.False(t, task.IsEphemeralStorageQuotaEnforced())
+	}
+
+	dockerTaskEngine.cfg.EphemeralStorageQuotaEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyDisabled}
+	task := &apitask.Task{Arn: "disabled", EphemeralStorage: &apitask.EphemeralStorage{SizeInGiB: 30}}
+	hostConfig := &dockercontainer.HostConfig{}
+	dockerTaskEngine.applyEphemeralStorageQuota(ctx, task, &apicontainer.Container{Name: "c"}, hostConfig)
+	assert.Empty(t, hostConfig.StorageOpt)
+}
@@ -97,3 +97,92 @@ func (err CannotGetDockerClientVersionError) ErrorName() string {
 func (err CannotGetDockerClientVersionError) Error() string {
 	return err.fromError.Error()
 }
+
+// NUMAPlacementError is the error for tasks that can't be placed on a NUMA node
+type NUMAPlacementError struct {
+	fromError error
+}
+
+func (err NUMAPlacementError) Error() string {
+	return "NUMAPlacementError: " + err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err NUMAPlacementError) ErrorName() string {
+	return "NUMAPlacementError"
+}
+
+// NeuronAssignmentError is the error for tasks whose containers can't be assigned the
+// neuron cores they require
+type NeuronAssignmentError struct {
+	fromError error
+}
+
+func (err NeuronAssignmentError) Error() string {
+	return "NeuronAssignmentError: " + err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err NeuronAssignmentError) ErrorName() string {
+	return "NeuronAssignmentError"
+}
+
+// MemoryPressureEvictionError is the error for the containers stopped to relieve the
+// memory pressure of their task
+type MemoryPressureEvictionError struct {
+	fromError error
+}
+
+func (err MemoryPressureEvictionError) Error() string {
+	return "MemoryPressureEvictionError: " + err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err MemoryPressureEvictionError) ErrorName() string {
+	return "MemoryPressureEvictionError"
+}
+
+// EFSMountUnhealthyError is the error for the containers stopped because one of their EFS
+// mounts couldn't be recovered
+type EFSMountUnhealthyError struct {
+	fromError error
+}
+
+func (err EFSMountUnhealthyError) Error() string {
+	return EFSMountUnhealthyReason + ": " + err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err EFSMountUnhealthyError) ErrorName() string {
+	return EFSMountUnhealthyReason
+}
+
+// SecurityProfileError is the error for the containers whose seccomp or AppArmor profiles
+// can't be applied, such as when a profile is missing
+type SecurityProfileError struct {
+	fromError error
+}
+
+func (err SecurityProfileError) Error() string {
+	return "SecurityProfileError: " + err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err SecurityProfileError) ErrorName() string {
+	return "SecurityProfileError"
+}
+
+// ContainerRuntimeError is the error for the containers of the tasks requesting a
+// container runtime that docker doesn't have
+type ContainerRuntimeError struct {
+	fromError error
+}
+
+func (err ContainerRuntimeError) Error() string {
+	return "ContainerRuntimeError: " + err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err ContainerRuntimeError) ErrorName() string {
+	return "ContainerRuntimeError"
+}
@@ -0,0 +1,64 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine/execcmd"
+)
+
+// execSessionAuditInterval is how often the records of the ECS Exec sessions that ended
+// are uploaded
+const execSessionAuditInterval = time.Minute
+
+// startPeriodicExecSessionAudits uploads the records of the ECS Exec sessions that ended
+// at every interval, until the context is done. When the sessions aren't audited, but
+// their data stores are kept to track their activity, the data stores of the tasks that
+// were removed from the state are removed instead.
+func (engine *DockerTaskEngine) startPeriodicExecSessionAudits(ctx context.Context) {
+	if engine.execSessionAuditor == nil && engine.cfg.ExecSessionIdleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(execSessionAuditInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			engine.auditExecSessions()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// auditExecSessions uploads the records of the ECS Exec sessions that ended. The sessions
+// of the tasks that were removed from the state are audited one last time.
+func (engine *DockerTaskEngine) auditExecSessions() {
+	activeTaskIDs := make(map[string]bool)
+	for _, task := range engine.state.AllTasks() {
+		if !execcmd.IsExecEnabledTask(task) {
+			continue
+		}
+		if taskID, err := task.GetID(); err == nil {
+			activeTaskIDs[taskID] = true
+		}
+	}
+	if engine.execSessionAuditor == nil {
+		execcmd.RemoveSessionData(activeTaskIDs)
+		return
+	}
+	engine.execSessionAuditor.Audit(activeTaskIDs)
+}
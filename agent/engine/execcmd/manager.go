@@ -20,6 +20,7 @@ import (
 
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 
@@ -72,6 +73,7 @@ type Manager interface {
 	InitializeContainer(taskId string, container *apicontainer.Container, hostConfig *dockercontainer.HostConfig) error
 	StartAgent(ctx context.Context, client dockerapi.DockerClient, task *apitask.Task, container *apicontainer.Container, containerId string) error
 	RestartAgentIfStopped(ctx context.Context, client dockerapi.DockerClient, task *apitask.Task, container *apicontainer.Container, containerId string) (RestartStatus, error)
+	EnforceSessionLimits(ctx context.Context, client dockerapi.DockerClient, task *apitask.Task) error
 }
 
 type manager struct {
@@ -80,6 +82,12 @@ type manager struct {
 	retryMinDelay       time.Duration
 	startRetryTimeout   time.Duration
 	inspectRetryTimeout time.Duration
+	sessionAuditEnabled bool
+	// maxSessionsPerContainer caps the session limit sent by ACS, when positive
+	maxSessionsPerContainer int
+	// maxSessionsPerTask and sessionIdleTimeout are enforced by EnforceSessionLimits, when positive
+	maxSessionsPerTask int
+	sessionIdleTimeout time.Duration
 }
 
 func NewManager() *manager {
@@ -98,6 +106,33 @@ func NewManagerWithBinDir(hostBinDir string) *manager {
 	return m
 }
 
+// NewManagerWithSessionAudit returns a manager that keeps the data stores of the ExecAgents on the host,
+// so that their sessions are audited once they end
+func NewManagerWithSessionAudit() *manager {
+	m := NewManager()
+	m.sessionAuditEnabled = true
+	return m
+}
+
+// NewManagerWithConfig returns a manager enforcing the session limits of the agent config, which also
+// audits the sessions when an audit bucket is configured
+func NewManagerWithConfig(cfg *config.Config) *manager {
+	m := NewManager()
+	if cfg.ExecSessionAuditS3Bucket != "" {
+		m = NewManagerWithSessionAudit()
+	}
+	m.maxSessionsPerContainer = cfg.ExecMaxSessionsPerContainer
+	m.maxSessionsPerTask = cfg.ExecMaxSessionsPerTask
+	m.sessionIdleTimeout = cfg.ExecSessionIdleTimeout
+	return m
+}
+
+// keepsSessionData returns whether the data stores of the ExecAgents are kept on the host, from which
+// the sessions are audited and their activity is tracked
+func (m *manager) keepsSessionData() bool {
+	return m.sessionAuditEnabled || m.sessionIdleTimeout > 0
+}
+
 func (m *manager) isAgentStarted(ma apicontainer.ManagedAgent) bool {
 	return !ma.LastStartedAt.IsZero()
 }
@@ -55,6 +55,14 @@ const (
 	ContainerLogDir    = "/var/log/amazon/ssm"
 	ECSAgentExecLogDir = "/log/exec"
 
+	// HostSessionDataDir and ECSAgentSessionDataDir are the directories, on the host and in the ECS Agent
+	// container, keeping the data stores of the ExecAgents when their sessions are audited
+	HostSessionDataDir     = hostExecDepsDir + "/sessions"
+	ECSAgentSessionDataDir = ecsAgentExecDepsDir + "/sessions"
+	// ContainerDataDir is the data store of the ExecAgent, where it saves the state and the transcripts of
+	// its sessions
+	ContainerDataDir = "/var/lib/amazon/ssm"
+
 	HostCertFile            = "/var/lib/ecs/deps/execute-command/certs/tls-ca-bundle.pem"
 	ContainerCertFileSuffix = "certs/amazon-ssm-agent.crt"
 
@@ -128,7 +136,7 @@ func (m *manager) InitializeContainer(taskId string, container *apicontainer.Con
 	if !ok {
 		return errExecCommandManagedAgentNotFound
 	}
-	configFile, rErr := GetExecAgentConfigFileName(getSessionWorkersLimit(ma))
+	configFile, rErr := GetExecAgentConfigFileName(m.sessionWorkersLimit(ma))
 	if rErr != nil {
 		rErr = fmt.Errorf("could not generate ExecAgent Config File: %v", rErr)
 		return rErr
@@ -151,6 +159,14 @@ func (m *manager) InitializeContainer(taskId string, container *apicontainer.Con
 		return rErr
 	}
 
+	cn := fileSystemSafeContainerName(container)
+	if m.keepsSessionData() {
+		if rErr = mkdirAll(filepath.Join(ECSAgentSessionDataDir, taskId, cn), sessionDataDirPerm); rErr != nil {
+			rErr = fmt.Errorf("could not create ExecAgent session data dir: %v", rErr)
+			return rErr
+		}
+	}
+
 	// Add ssm binary mounts
 	hostConfig.Binds = append(hostConfig.Binds, getReadOnlyBindMountMapping(
 		filepath.Join(latestBinVersionDir, SSMAgentBinName),
@@ -180,11 +196,17 @@ func (m *manager) InitializeContainer(taskId string, container *apicontainer.Con
 		filepath.Join(containerDepsFolder, ContainerCertFileSuffix)))
 
 	// Add ssm log bind mount
-	cn := fileSystemSafeContainerName(container)
 	hostConfig.Binds = append(hostConfig.Binds, getBindMountMapping(
 		filepath.Join(HostLogDir, taskId, cn),
 		ContainerLogDir))
 
+	// Add ssm data store bind mount, from which the sessions are audited once they end and their activity is tracked
+	if m.keepsSessionData() {
+		hostConfig.Binds = append(hostConfig.Binds, getBindMountMapping(
+			filepath.Join(HostSessionDataDir, taskId, cn),
+			ContainerDataDir))
+	}
+
 	container.UpdateManagedAgentByName(ExecuteCommandAgentName, apicontainer.ManagedAgentState{
 		ID: uuid,
 	})
@@ -262,6 +284,16 @@ func getSessionWorkersLimit(ma apicontainer.ManagedAgent) int {
 	return limit
 }
 
+// sessionWorkersLimit returns the session limit sent by ACS, capped by the limit of sessions per container
+// of the agent
+func (m *manager) sessionWorkersLimit(ma apicontainer.ManagedAgent) int {
+	limit := getSessionWorkersLimit(ma)
+	if m.maxSessionsPerContainer > 0 && m.maxSessionsPerContainer < limit {
+		limit = m.maxSessionsPerContainer
+	}
+	return limit
+}
+
 var GetExecAgentLogConfigFile = getAgentLogConfigFile
 
 func getAgentLogConfigFile() (string, error) {
@@ -287,6 +319,8 @@ func getAgentLogConfigFile() (string, error) {
 
 var removeAll = os.RemoveAll
 
+var mkdirAll = os.MkdirAll
+
 func validConfigExists(configFilePath, expectedHash string) bool {
 	config, err := getFileContent(configFilePath)
 	if err != nil {
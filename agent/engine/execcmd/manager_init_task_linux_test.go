@@ -25,6 +25,7 @@ import (
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/pborman/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
@@ -218,6 +219,51 @@ func TestInitializeContainer(t *testing.T) {
 	}
 }
 
+func TestInitializeContainerWithSessionAudit(t *testing.T) {
+	defer func() {
+		GetExecAgentConfigFileName = getAgentConfigFileName
+		newUUID = uuid.New
+		ioUtilReadDir = ioutil.ReadDir
+		osStat = os.Stat
+		GetExecAgentLogConfigFile = getAgentLogConfigFile
+		mkdirAll = os.MkdirAll
+	}()
+	newUUID = func() string {
+		return "test-UUID"
+	}
+	GetExecAgentConfigFileName = func(s int) (string, error) {
+		return "amazon-ssm-agent.json", nil
+	}
+	GetExecAgentLogConfigFile = func() (string, error) {
+		return "seelog.xml", nil
+	}
+	ioUtilReadDir = func(dirname string) ([]os.FileInfo, error) {
+		return []os.FileInfo{&mockFileInfo{name: "3.0.236.0", isDir: true}}, nil
+	}
+	osStat = func(name string) (os.FileInfo, error) {
+		return &mockFileInfo{name: "", isDir: false}, nil
+	}
+	var createdDir string
+	mkdirAll = func(path string, perm os.FileMode) error {
+		createdDir = path
+		return nil
+	}
+
+	execCmdMgr := NewManagerWithSessionAudit()
+	container := &apicontainer.Container{
+		Name:                "container-name",
+		ManagedAgentsUnsafe: []apicontainer.ManagedAgent{{Name: ExecuteCommandAgentName}},
+	}
+	hc := &dockercontainer.HostConfig{}
+	err := execCmdMgr.InitializeContainer("task-id", container, hc)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/managed-agents/execute-command/sessions/task-id/container-name", createdDir)
+	assert.Len(t, hc.Binds, 8)
+	assert.Subset(t, hc.Binds, []string{
+		"/var/lib/ecs/deps/execute-command/sessions/task-id/container-name:/var/lib/amazon/ssm"})
+}
+
 func TestGetExecAgentConfigFileName(t *testing.T) {
 	execAgentConfig := `{
 	"Mgs": {
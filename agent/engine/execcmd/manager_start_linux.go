@@ -187,5 +187,12 @@ func (m *manager) doStartAgent(ctx context.Context, client dockerapi.DockerClien
 	newMD.PID = strconv.Itoa(inspect.Pid)
 	newMD.DockerExecID = execRes.ID
 	newMD.CMD = execAgentCmd
+	newMD.SessionLimit = strconv.Itoa(m.sessionWorkersLimit(ma))
+	if m.maxSessionsPerTask > 0 {
+		newMD.TaskSessionLimit = strconv.Itoa(m.maxSessionsPerTask)
+	}
+	if m.sessionIdleTimeout > 0 {
+		newMD.IdleSessionTimeout = m.sessionIdleTimeout.String()
+	}
 	return newMD, nil
 }
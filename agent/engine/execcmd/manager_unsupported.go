@@ -27,6 +27,8 @@ const (
 	// ECSAgentExecLogDir here is used used while cleaning up exec logs when task exits.
 	// When this path is empty, nothing is cleaned up for unsupported platforms.
 	ECSAgentExecLogDir = ""
+	// ECSAgentSessionDataDir is empty as exec sessions aren't audited on unsupported platforms.
+	ECSAgentSessionDataDir = ""
 )
 
 // Note: exec cmd agent is a linux-only feature, thus implemented here as a no-op.
@@ -44,3 +46,8 @@ func (m *manager) StartAgent(ctx context.Context, client dockerapi.DockerClient,
 func (m *manager) InitializeContainer(taskId string, container *apicontainer.Container, hostConfig *dockercontainer.HostConfig) error {
 	return nil
 }
+
+// Note: exec cmd agent is a linux-only feature, thus implemented here as a no-op.
+func (m *manager) EnforceSessionLimits(ctx context.Context, client dockerapi.DockerClient, task *apitask.Task) error {
+	return nil
+}
@@ -9,6 +9,12 @@ type AgentMetadata struct {
 	PID          string `json:"PID"`
 	DockerExecID string `json:"DockerExecID"`
 	CMD          string `json:"CMD"`
+	// SessionLimit is the maximum number of concurrent sessions in the container
+	SessionLimit string `json:"SessionLimit,omitempty"`
+	// TaskSessionLimit is the maximum number of concurrent sessions across the containers of the task
+	TaskSessionLimit string `json:"TaskSessionLimit,omitempty"`
+	// IdleSessionTimeout is how long a session can be idle before it's terminated
+	IdleSessionTimeout string `json:"IdleSessionTimeout,omitempty"`
 }
 
 func (md *AgentMetadata) String() string {
@@ -16,11 +22,21 @@ func (md *AgentMetadata) String() string {
 }
 
 func (md *AgentMetadata) ToMap() map[string]interface{} {
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"PID":          md.PID,
 		"DockerExecID": md.DockerExecID,
 		"CMD":          md.CMD,
 	}
+	if md.SessionLimit != "" {
+		m["SessionLimit"] = md.SessionLimit
+	}
+	if md.TaskSessionLimit != "" {
+		m["TaskSessionLimit"] = md.TaskSessionLimit
+	}
+	if md.IdleSessionTimeout != "" {
+		m["IdleSessionTimeout"] = md.IdleSessionTimeout
+	}
+	return m
 }
 
 func MapToAgentMetadata(md map[string]interface{}) AgentMetadata {
@@ -31,5 +47,8 @@ func MapToAgentMetadata(md map[string]interface{}) AgentMetadata {
 	execMD.PID, _ = md["PID"].(string)
 	execMD.DockerExecID, _ = md["DockerExecID"].(string)
 	execMD.CMD, _ = md["CMD"].(string)
+	execMD.SessionLimit, _ = md["SessionLimit"].(string)
+	execMD.TaskSessionLimit, _ = md["TaskSessionLimit"].(string)
+	execMD.IdleSessionTimeout, _ = md["IdleSessionTimeout"].(string)
 	return execMD
 }
@@ -53,6 +53,20 @@ func (m *MockManager) EXPECT() *MockManagerMockRecorder {
 	return m.recorder
 }
 
+// EnforceSessionLimits mocks base method
+func (m *MockManager) EnforceSessionLimits(arg0 context.Context, arg1 dockerapi.DockerClient, arg2 *task.Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnforceSessionLimits", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnforceSessionLimits indicates an expected call of EnforceSessionLimits
+func (mr *MockManagerMockRecorder) EnforceSessionLimits(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnforceSessionLimits", reflect.TypeOf((*MockManager)(nil).EnforceSessionLimits), arg0, arg1, arg2)
+}
+
 // InitializeContainer mocks base method
 func (m *MockManager) InitializeContainer(arg0 string, arg1 *container.Container, arg2 *container0.HostConfig) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,350 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package execcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials/instancecreds"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	s3client "github.com/aws/amazon-ecs-agent/agent/s3"
+	"github.com/aws/amazon-ecs-agent/agent/s3/factory"
+	"github.com/pkg/errors"
+)
+
+const (
+	// auditedDirName is the directory of a task session data dir keeping a marker for each
+	// session that was audited
+	auditedDirName       = ".audited"
+	sessionDataDirPerm   = 0700
+	auditedMarkerPerm    = 0600
+	sessionUploadTimeout = 5 * time.Minute
+	transcriptFileName   = "ipcTempFile.log"
+
+	// SessionStatusInterrupted is the status of the sessions that didn't end before their
+	// container stopped
+	SessionStatusInterrupted = "Interrupted"
+)
+
+// SessionRecord is the record of an ECS Exec session uploaded to S3
+type SessionRecord struct {
+	Cluster       string     `json:"cluster"`
+	TaskID        string     `json:"taskId"`
+	ContainerName string     `json:"containerName"`
+	SessionID     string     `json:"sessionId"`
+	UserARN       string     `json:"userArn,omitempty"`
+	RunAsUser     string     `json:"runAsUser,omitempty"`
+	Command       string     `json:"command,omitempty"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	StoppedAt     *time.Time `json:"stoppedAt,omitempty"`
+	Status        string     `json:"status"`
+	TranscriptKey string     `json:"transcriptKey,omitempty"`
+}
+
+// sessionState is the part of the document state the ExecAgent saves in its data store for
+// each session that's recorded
+type sessionState struct {
+	DocumentInformation struct {
+		DocumentID     string
+		DocumentStatus string
+		SessionOwner   string
+		RunAsUser      string
+	}
+	InstancePluginsInformation []struct {
+		Configuration struct {
+			Properties interface{}
+		}
+		Result struct {
+			StartDateTime time.Time
+			EndDateTime   time.Time
+		}
+	}
+}
+
+// SessionAuditor uploads the records of the ECS Exec sessions, and optionally their
+// transcripts, to the S3 bucket configured for the instance once the sessions end. The
+// sessions are read from the data stores of the ExecAgents, which are kept on the host
+// by a manager created with NewManagerWithSessionAudit.
+type SessionAuditor struct {
+	cfg             *config.Config
+	s3ClientCreator factory.S3ClientCreator
+	uploader        s3client.S3Uploader
+	dataDir         string
+}
+
+// NewSessionAuditor returns an auditor of the ECS Exec sessions, or nil when no audit
+// bucket is configured or exec isn't supported on the platform
+func NewSessionAuditor(cfg *config.Config, s3ClientCreator factory.S3ClientCreator) *SessionAuditor {
+	if cfg.ExecSessionAuditS3Bucket == "" || ECSAgentSessionDataDir == "" {
+		return nil
+	}
+	return &SessionAuditor{
+		cfg:             cfg,
+		s3ClientCreator: s3ClientCreator,
+		dataDir:         ECSAgentSessionDataDir,
+	}
+}
+
+// Audit uploads the records of the sessions that ended since the last audit. The tasks
+// that aren't active anymore are audited one last time, including the sessions that
+// were interrupted when their container stopped, and their data stores are removed once
+// all their sessions are uploaded.
+func (auditor *SessionAuditor) Audit(activeTaskIDs map[string]bool) {
+	if auditor == nil {
+		return
+	}
+	taskDirs, err := ioutil.ReadDir(auditor.dataDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Unable to list exec session data dirs", logger.Fields{
+				field.Error: err,
+			})
+		}
+		return
+	}
+	for _, taskDir := range taskDirs {
+		if !taskDir.IsDir() {
+			continue
+		}
+		taskID := taskDir.Name()
+		final := !activeTaskIDs[taskID]
+		if !auditor.auditTask(taskID, final) || !final {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(auditor.dataDir, taskID)); err != nil {
+			logger.Warn("Unable to remove exec session data dir", logger.Fields{
+				"taskID":    taskID,
+				field.Error: err,
+			})
+		}
+	}
+}
+
+// RemoveSessionData removes the data stores of the ExecAgents of the tasks that aren't active anymore,
+// when they're kept on the host only to track the activity of the sessions
+func RemoveSessionData(activeTaskIDs map[string]bool) {
+	if ECSAgentSessionDataDir == "" {
+		return
+	}
+	taskDirs, err := ioutil.ReadDir(ECSAgentSessionDataDir)
+	if err != nil {
+		return
+	}
+	for _, taskDir := range taskDirs {
+		if !taskDir.IsDir() || activeTaskIDs[taskDir.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(ECSAgentSessionDataDir, taskDir.Name())); err != nil {
+			logger.Warn("Unable to remove exec session data dir", logger.Fields{
+				"taskID":    taskDir.Name(),
+				field.Error: err,
+			})
+		}
+	}
+}
+
+// auditTask uploads the sessions of the containers of a task, and returns whether all
+// of them were uploaded
+func (auditor *SessionAuditor) auditTask(taskID string, final bool) bool {
+	containerDirs, err := ioutil.ReadDir(filepath.Join(auditor.dataDir, taskID))
+	if err != nil {
+		return false
+	}
+	uploaded := true
+	for _, containerDir := range containerDirs {
+		if !containerDir.IsDir() || strings.HasPrefix(containerDir.Name(), ".") {
+			continue
+		}
+		states := []string{"completed"}
+		if final {
+			states = append(states, "current", "pending")
+		}
+		for _, state := range states {
+			stateFiles, _ := filepath.Glob(filepath.Join(auditor.dataDir, taskID, containerDir.Name(),
+				"*", "document", "state", state, "*"))
+			for _, stateFile := range stateFiles {
+				if err := auditor.auditSession(taskID, containerDir.Name(), stateFile, state != "completed"); err != nil {
+					logger.Warn("Unable to upload exec session record", logger.Fields{
+						"taskID":        taskID,
+						field.Container: containerDir.Name(),
+						"sessionID":     filepath.Base(stateFile),
+						field.Error:     err,
+					})
+					uploaded = false
+				}
+			}
+		}
+	}
+	return uploaded
+}
+
+// auditSession uploads the record of a session, and its transcript when enabled, unless
+// it was already uploaded
+func (auditor *SessionAuditor) auditSession(taskID, containerName, stateFile string, interrupted bool) error {
+	sessionID := filepath.Base(stateFile)
+	marker := filepath.Join(auditor.dataDir, taskID, auditedDirName, containerName, sessionID)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+
+	record, err := readSessionRecord(stateFile)
+	if err != nil {
+		return err
+	}
+	record.Cluster = auditor.cluster()
+	record.TaskID = taskID
+	record.ContainerName = containerName
+	record.SessionID = sessionID
+	if interrupted {
+		record.Status = SessionStatusInterrupted
+		record.StoppedAt = nil
+	}
+
+	uploader, err := auditor.getUploader()
+	if err != nil {
+		return err
+	}
+	keyPrefix := path.Join(auditor.cfg.ExecSessionAuditS3KeyPrefix, record.Cluster, taskID, containerName, sessionID)
+	if auditor.cfg.ExecSessionAuditTranscriptsEnabled.Enabled() {
+		transcripts, _ := filepath.Glob(filepath.Join(auditor.dataDir, taskID, containerName,
+			"*", "session", "orchestration", sessionID, "*", transcriptFileName))
+		if len(transcripts) > 0 {
+			record.TranscriptKey = keyPrefix + ".log"
+			if err := auditor.uploadFile(record.TranscriptKey, transcripts[0], uploader); err != nil {
+				return err
+			}
+		}
+	}
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = s3client.UploadFile(auditor.cfg.ExecSessionAuditS3Bucket, keyPrefix+".json",
+		auditor.cfg.ExecSessionAuditKMSKeyID, sessionUploadTimeout, bytes.NewReader(content), uploader)
+	if err != nil {
+		return errors.Wrap(err, "unable to upload session record")
+	}
+
+	err = os.MkdirAll(filepath.Dir(marker), sessionDataDirPerm)
+	if err == nil {
+		err = ioutil.WriteFile(marker, nil, auditedMarkerPerm)
+	}
+	if err != nil {
+		// the record is uploaded again by the next audit
+		logger.Warn("Unable to mark exec session as audited", logger.Fields{
+			"sessionID": sessionID,
+			field.Error: err,
+		})
+	}
+	logger.Info("Uploaded exec session record", logger.Fields{
+		"taskID":        taskID,
+		field.Container: containerName,
+		"sessionID":     sessionID,
+		"key":           keyPrefix + ".json",
+	})
+	return nil
+}
+
+func (auditor *SessionAuditor) uploadFile(key, filePath string, uploader s3client.S3Uploader) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrap(err, "unable to open session transcript")
+	}
+	defer file.Close()
+	err = s3client.UploadFile(auditor.cfg.ExecSessionAuditS3Bucket, key, auditor.cfg.ExecSessionAuditKMSKeyID,
+		sessionUploadTimeout, file, uploader)
+	return errors.Wrap(err, "unable to upload session transcript")
+}
+
+// getUploader returns the uploader to the audit bucket, which uses the instance credentials
+func (auditor *SessionAuditor) getUploader() (s3client.S3Uploader, error) {
+	if auditor.uploader != nil {
+		return auditor.uploader, nil
+	}
+	uploader, err := auditor.s3ClientCreator.NewS3UploaderForBucket(auditor.cfg.ExecSessionAuditS3Bucket,
+		auditor.cfg.AWSRegion, instancecreds.GetCredentials())
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create uploader for bucket %s", auditor.cfg.ExecSessionAuditS3Bucket)
+	}
+	auditor.uploader = uploader
+	return uploader, nil
+}
+
+func (auditor *SessionAuditor) cluster() string {
+	if auditor.cfg.Cluster == "" {
+		return config.DefaultClusterName
+	}
+	return auditor.cfg.Cluster
+}
+
+// readSessionRecord reads the record of a session from the document state saved by the
+// ExecAgent
+func readSessionRecord(stateFile string) (*SessionRecord, error) {
+	content, err := ioutil.ReadFile(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	var state sessionState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, errors.Wrap(err, "unable to parse session state")
+	}
+
+	record := &SessionRecord{
+		UserARN:   state.DocumentInformation.SessionOwner,
+		RunAsUser: state.DocumentInformation.RunAsUser,
+		Status:    state.DocumentInformation.DocumentStatus,
+	}
+	for _, plugin := range state.InstancePluginsInformation {
+		if record.Command == "" {
+			record.Command = sessionCommand(plugin.Configuration.Properties)
+		}
+		if start := plugin.Result.StartDateTime; !start.IsZero() && (record.StartedAt == nil || start.Before(*record.StartedAt)) {
+			record.StartedAt = &start
+		}
+		if end := plugin.Result.EndDateTime; !end.IsZero() && (record.StoppedAt == nil || end.After(*record.StoppedAt)) {
+			record.StoppedAt = &end
+		}
+	}
+	return record, nil
+}
+
+// sessionCommand returns the command of a session from the properties of its plugin,
+// which are either a single command or a list of commands
+func sessionCommand(properties interface{}) string {
+	props, ok := properties.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch commands := props["commands"].(type) {
+	case string:
+		return commands
+	case []interface{}:
+		var parts []string
+		for _, command := range commands {
+			parts = append(parts, fmt.Sprint(command))
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package execcmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	mock_factory "github.com/aws/amazon-ecs-agent/agent/s3/factory/mocks"
+	mock_s3 "github.com/aws/amazon-ecs-agent/agent/s3/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAuditTaskID    = "task-id"
+	testAuditContainer = "container-name"
+	testSessionID      = "user-0123456789abcdef0"
+	testSessionState   = `{
+	"DocumentInformation": {
+		"DocumentID": "user-0123456789abcdef0",
+		"DocumentStatus": "Success",
+		"SessionOwner": "arn:aws:iam::123456789012:user/user",
+		"RunAsUser": ""
+	},
+	"InstancePluginsInformation": [{
+		"Configuration": {
+			"Properties": {"commands": "/bin/sh"}
+		},
+		"Result": {
+			"StartDateTime": "2023-01-02T03:04:05Z",
+			"EndDateTime": "2023-01-02T03:14:05Z"
+		}
+	}]
+}`
+)
+
+func newTestSessionAuditor(t *testing.T, ctrl *gomock.Controller) (*SessionAuditor, *mock_s3.MockS3Uploader) {
+	s3ClientCreator := mock_factory.NewMockS3ClientCreator(ctrl)
+	uploader := mock_s3.NewMockS3Uploader(ctrl)
+	s3ClientCreator.EXPECT().NewS3UploaderForBucket("bucket", "us-west-2", gomock.Any()).Return(uploader, nil).AnyTimes()
+	return &SessionAuditor{
+		cfg: &config.Config{
+			Cluster:                            "cluster",
+			AWSRegion:                          "us-west-2",
+			ExecSessionAuditS3Bucket:           "bucket",
+			ExecSessionAuditS3KeyPrefix:        "audit",
+			ExecSessionAuditKMSKeyID:           "key-id",
+			ExecSessionAuditTranscriptsEnabled: config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+		},
+		s3ClientCreator: s3ClientCreator,
+		dataDir:         t.TempDir(),
+	}, uploader
+}
+
+// writeTestSession writes the state of a session with the given status, and its transcript,
+// the way the ExecAgent saves them in its data store
+func writeTestSession(t *testing.T, auditor *SessionAuditor, status string) {
+	dataStore := filepath.Join(auditor.dataDir, testAuditTaskID, testAuditContainer, "mi-0123456789abcdef0")
+	stateDir := filepath.Join(dataStore, "document", "state", status)
+	transcriptDir := filepath.Join(dataStore, "session", "orchestration", testSessionID, "Standard_Stream")
+	require.NoError(t, os.MkdirAll(stateDir, 0700))
+	require.NoError(t, os.MkdirAll(transcriptDir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(stateDir, testSessionID), []byte(testSessionState), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(transcriptDir, transcriptFileName), []byte("$ ls\n"), 0600))
+}
+
+func TestAuditCompletedSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	auditor, uploader := newTestSessionAuditor(t, ctrl)
+	writeTestSession(t, auditor, "completed")
+
+	keyPrefix := "audit/cluster/" + testAuditTaskID + "/" + testAuditContainer + "/" + testSessionID
+	var record SessionRecord
+	gomock.InOrder(
+		uploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).Do(func(ctx aws.Context,
+			input *s3manager.UploadInput) {
+			assert.Equal(t, "bucket", aws.StringValue(input.Bucket))
+			assert.Equal(t, keyPrefix+".log", aws.StringValue(input.Key))
+			assert.Equal(t, "key-id", aws.StringValue(input.SSEKMSKeyId))
+			transcript, err := ioutil.ReadAll(input.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "$ ls\n", string(transcript))
+		}),
+		uploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).Do(func(ctx aws.Context,
+			input *s3manager.UploadInput) {
+			assert.Equal(t, keyPrefix+".json", aws.StringValue(input.Key))
+			require.NoError(t, json.NewDecoder(input.Body).Decode(&record))
+		}),
+	)
+
+	auditor.Audit(map[string]bool{testAuditTaskID: true})
+	assert.Equal(t, "cluster", record.Cluster)
+	assert.Equal(t, testAuditTaskID, record.TaskID)
+	assert.Equal(t, testAuditContainer, record.ContainerName)
+	assert.Equal(t, testSessionID, record.SessionID)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/user", record.UserARN)
+	assert.Equal(t, "/bin/sh", record.Command)
+	assert.Equal(t, "Success", record.Status)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), *record.StartedAt)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 14, 5, 0, time.UTC), *record.StoppedAt)
+	assert.Equal(t, keyPrefix+".log", record.TranscriptKey)
+
+	// the session was already uploaded
+	auditor.Audit(map[string]bool{testAuditTaskID: true})
+}
+
+func TestAuditInterruptedSessionOfStoppedTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	auditor, uploader := newTestSessionAuditor(t, ctrl)
+	auditor.cfg.ExecSessionAuditTranscriptsEnabled = config.BooleanDefaultFalse{}
+	writeTestSession(t, auditor, "current")
+
+	// the session is still running while the task is active
+	auditor.Audit(map[string]bool{testAuditTaskID: true})
+
+	var record SessionRecord
+	uploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).Do(func(ctx aws.Context,
+		input *s3manager.UploadInput) {
+		require.NoError(t, json.NewDecoder(input.Body).Decode(&record))
+	})
+	auditor.Audit(map[string]bool{})
+	assert.Equal(t, SessionStatusInterrupted, record.Status)
+	assert.Nil(t, record.StoppedAt)
+	assert.Empty(t, record.TranscriptKey)
+	_, err := os.Stat(filepath.Join(auditor.dataDir, testAuditTaskID))
+	assert.True(t, os.IsNotExist(err), "the data stores of the task should be removed")
+}
+
+func TestAuditKeepsSessionsThatFailedToUpload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	auditor, uploader := newTestSessionAuditor(t, ctrl)
+	auditor.cfg.ExecSessionAuditTranscriptsEnabled = config.BooleanDefaultFalse{}
+	writeTestSession(t, auditor, "completed")
+
+	uploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+	auditor.Audit(map[string]bool{})
+	_, err := os.Stat(filepath.Join(auditor.dataDir, testAuditTaskID))
+	assert.NoError(t, err, "the data stores of the task should be kept until its sessions are uploaded")
+
+	uploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).Return(&s3manager.UploadOutput{}, nil)
+	auditor.Audit(map[string]bool{})
+	_, err = os.Stat(filepath.Join(auditor.dataDir, testAuditTaskID))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNilSessionAuditor(t *testing.T) {
+	auditor := NewSessionAuditor(&config.Config{}, nil)
+	assert.Nil(t, auditor)
+	auditor.Audit(map[string]bool{})
+}
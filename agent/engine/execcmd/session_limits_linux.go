@@ -0,0 +1,226 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package execcmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+const (
+	// sessionWorkerPSArgs are the ps arguments listing the pid, the elapsed seconds and the command line
+	// of the processes of a container
+	sessionWorkerPSArgs = "-eo pid,etimes,args"
+	// nsPIDPrefix is the line of the status of a process listing its pid in each pid namespace it's in
+	nsPIDPrefix = "NSpid:"
+)
+
+var (
+	// hostProcFSPath is the path at which the host procfs is mounted in the agent container
+	hostProcFSPath = "/host/proc"
+	// sessionDataDir is the directory keeping the data stores of the ExecAgents in the agent container
+	sessionDataDir = ECSAgentSessionDataDir
+)
+
+// execSession is an ECS Exec session, served by a session worker in a container
+type execSession struct {
+	id          string
+	container   *apicontainer.Container
+	containerID string
+	hostPID     string
+	elapsed     time.Duration
+}
+
+// EnforceSessionLimits terminates the ECS Exec sessions of a task that have been idle for longer than the
+// idle timeout, and then its newest sessions over the limit of concurrent sessions per task.
+func (m *manager) EnforceSessionLimits(ctx context.Context, client dockerapi.DockerClient, task *apitask.Task) error {
+	if m.maxSessionsPerTask <= 0 && m.sessionIdleTimeout <= 0 {
+		return nil
+	}
+	taskID, err := task.GetID()
+	if err != nil {
+		return err
+	}
+	var sessions []execSession
+	for _, c := range task.Containers {
+		if !IsExecEnabledContainer(c) || !c.IsRunning() || c.GetRuntimeID() == "" {
+			continue
+		}
+		if ma, _ := c.GetManagedAgentByName(ExecuteCommandAgentName); !m.isAgentStarted(ma) {
+			continue
+		}
+		containerSessions, err := listSessions(ctx, client, c)
+		if err != nil {
+			return err
+		}
+		sessions = append(sessions, containerSessions...)
+	}
+
+	var active []execSession
+	for _, session := range sessions {
+		if m.sessionIdleTimeout > 0 {
+			if lastActivity, ok := sessionLastActivity(taskID, session); ok && time.Since(lastActivity) > m.sessionIdleTimeout {
+				m.terminateSession(ctx, client, task, session, "idle timeout")
+				continue
+			}
+		}
+		active = append(active, session)
+	}
+	if m.maxSessionsPerTask <= 0 || len(active) <= m.maxSessionsPerTask {
+		return nil
+	}
+	// the sessions started last are terminated, so that the existing ones aren't interrupted
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].elapsed > active[j].elapsed
+	})
+	for _, session := range active[m.maxSessionsPerTask:] {
+		m.terminateSession(ctx, client, task, session, "task session limit")
+	}
+	return nil
+}
+
+// listSessions lists the sessions of a container from its session worker processes, whose first argument
+// is the ID of the session they serve
+func listSessions(ctx context.Context, client dockerapi.DockerClient, c *apicontainer.Container) ([]execSession, error) {
+	top, err := client.TopContainer(ctx, c.GetRuntimeID(), dockerclient.TopContainerTimeout,
+		strings.Split(sessionWorkerPSArgs, " ")...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list the processes of container %s", c.Name)
+	}
+	pidIndex, elapsedIndex, argsIndex := -1, -1, -1
+	for i, title := range top.Titles {
+		switch title {
+		case "PID":
+			pidIndex = i
+		case "ELAPSED":
+			elapsedIndex = i
+		case "COMMAND":
+			argsIndex = i
+		}
+	}
+	if pidIndex < 0 || elapsedIndex < 0 || argsIndex < 0 {
+		return nil, fmt.Errorf("unexpected process list titles for container %s: %v", c.Name, top.Titles)
+	}
+
+	var sessions []execSession
+	for _, process := range top.Processes {
+		if len(process) <= argsIndex {
+			continue
+		}
+		args := strings.Fields(process[argsIndex])
+		if len(args) < 2 || filepath.Base(args[0]) != SessionWorkerBinName {
+			continue
+		}
+		elapsed, _ := strconv.Atoi(process[elapsedIndex])
+		sessions = append(sessions, execSession{
+			id:          args[1],
+			container:   c,
+			containerID: c.GetRuntimeID(),
+			hostPID:     process[pidIndex],
+			elapsed:     time.Duration(elapsed) * time.Second,
+		})
+	}
+	return sessions, nil
+}
+
+// sessionLastActivity returns the last time a session had any input or output, from the last modification
+// of its transcript in the data store of the ExecAgent, when it's kept on the host
+func sessionLastActivity(taskID string, session execSession) (time.Time, bool) {
+	sessionDir := filepath.Join(sessionDataDir, taskID, fileSystemSafeContainerName(session.container),
+		"*", "session", "orchestration", session.id)
+	paths, _ := filepath.Glob(filepath.Join(sessionDir, "*", transcriptFileName))
+	if len(paths) == 0 {
+		// the session hasn't had any output yet
+		paths, _ = filepath.Glob(sessionDir)
+	}
+	var lastActivity time.Time
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil && fi.ModTime().After(lastActivity) {
+			lastActivity = fi.ModTime()
+		}
+	}
+	return lastActivity, !lastActivity.IsZero()
+}
+
+// terminateSession stops the session worker serving a session, which ends the session
+func (m *manager) terminateSession(ctx context.Context, client dockerapi.DockerClient, task *apitask.Task,
+	session execSession, reason string) {
+	fields := logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: session.container.Name,
+		"sessionID":     session.id,
+		"reason":        reason,
+	}
+	pid, err := containerPID(session.hostPID)
+	if err == nil {
+		err = execInContainer(ctx, client, session.containerID, []string{"/bin/sh", "-c", "kill -TERM " + pid})
+	}
+	if err != nil {
+		fields[field.Error] = err
+		logger.Warn("Unable to terminate exec session", fields)
+		return
+	}
+	logger.Info("Terminated exec session", fields)
+}
+
+// containerPID returns the pid of a process in the pid namespace of its container, from its pid on the host
+func containerPID(hostPID string) (string, error) {
+	file, err := os.Open(filepath.Join(hostProcFSPath, hostPID, "status"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, nsPIDPrefix) {
+			pids := strings.Fields(strings.TrimPrefix(line, nsPIDPrefix))
+			if len(pids) > 0 {
+				return pids[len(pids)-1], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no pid namespace found for process %s", hostPID)
+}
+
+func execInContainer(ctx context.Context, client dockerapi.DockerClient, containerID string, cmd []string) error {
+	execRes, err := client.CreateContainerExec(ctx, containerID, types.ExecConfig{
+		User:   "0",
+		Detach: true,
+		Cmd:    cmd,
+	}, dockerclient.ContainerExecCreateTimeout)
+	if err != nil {
+		return err
+	}
+	return client.StartContainerExec(ctx, execRes.ID, types.ExecStartCheck{Detach: true, Tty: false},
+		dockerclient.ContainerExecStartTimeout)
+}
@@ -0,0 +1,165 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package execcmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLimitsTaskARN = "arn:aws:ecs:us-west-2:123456789012:task/cluster/" + testAuditTaskID
+
+func newTestLimitsContainer(name, runtimeID string) *apicontainer.Container {
+	return &apicontainer.Container{
+		Name:              name,
+		RuntimeID:         runtimeID,
+		KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+		ManagedAgentsUnsafe: []apicontainer.ManagedAgent{{
+			Name: ExecuteCommandAgentName,
+			ManagedAgentState: apicontainer.ManagedAgentState{
+				LastStartedAt: time.Now(),
+			},
+		}},
+	}
+}
+
+func sessionWorkers(processes ...[]string) *dockercontainer.ContainerTopOKBody {
+	return &dockercontainer.ContainerTopOKBody{
+		Titles:    []string{"PID", "ELAPSED", "COMMAND"},
+		Processes: append([][]string{{"100", "3600", "/ecs-execute-command-id/amazon-ssm-agent"}}, processes...),
+	}
+}
+
+// setupTestSessionLimits points the procfs and the session data dir to temporary dirs, and writes the
+// status of the processes with the given host pids, which are all in the container pid namespace as pid 42
+func setupTestSessionLimits(t *testing.T, hostPIDs ...string) func() {
+	procFS := t.TempDir()
+	for _, pid := range hostPIDs {
+		require.NoError(t, os.MkdirAll(filepath.Join(procFS, pid), 0700))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(procFS, pid, "status"),
+			[]byte("Name:\tssm-session-worker\nNSpid:\t"+pid+"\t42\n"), 0600))
+	}
+	hostProcFSPath = procFS
+	sessionDataDir = t.TempDir()
+	return func() {
+		hostProcFSPath = "/host/proc"
+		sessionDataDir = ECSAgentSessionDataDir
+	}
+}
+
+// writeTestTranscript writes the transcript of a session, last modified at the given time
+func writeTestTranscript(t *testing.T, containerName, sessionID string, modTime time.Time) {
+	dir := filepath.Join(sessionDataDir, testAuditTaskID, containerName, "mi-0123456789abcdef0",
+		"session", "orchestration", sessionID, "Standard_Stream")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	transcript := filepath.Join(dir, transcriptFileName)
+	require.NoError(t, ioutil.WriteFile(transcript, []byte("$ "), 0600))
+	require.NoError(t, os.Chtimes(transcript, modTime, modTime))
+}
+
+func expectKill(t *testing.T, client *mock_dockerapi.MockDockerClient, containerID string) {
+	client.EXPECT().CreateContainerExec(gomock.Any(), containerID, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, containerID string, execConfig types.ExecConfig,
+			timeout time.Duration) (*types.IDResponse, error) {
+			assert.Equal(t, []string{"/bin/sh", "-c", "kill -TERM 42"}, execConfig.Cmd)
+			return &types.IDResponse{ID: "exec-id"}, nil
+		})
+	client.EXPECT().StartContainerExec(gomock.Any(), "exec-id", gomock.Any(), gomock.Any()).Return(nil)
+}
+
+func TestEnforceSessionLimitsTerminatesIdleSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	defer setupTestSessionLimits(t, "200")()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	task := &apitask.Task{
+		Arn:        testLimitsTaskARN,
+		Containers: []*apicontainer.Container{newTestLimitsContainer(testAuditContainer, "runtime-id")},
+	}
+	writeTestTranscript(t, testAuditContainer, "idle-session", time.Now().Add(-time.Hour))
+	writeTestTranscript(t, testAuditContainer, "active-session", time.Now())
+
+	client.EXPECT().TopContainer(gomock.Any(), "runtime-id", gomock.Any(), "-eo", "pid,etimes,args").Return(
+		sessionWorkers(
+			[]string{"200", "3000", "/ecs-execute-command-id/ssm-session-worker idle-session mi-0123456789abcdef0"},
+			[]string{"201", "60", "/ecs-execute-command-id/ssm-session-worker active-session mi-0123456789abcdef0"},
+			// the activity of sessions without a data store is unknown
+			[]string{"202", "3000", "/ecs-execute-command-id/ssm-session-worker unknown-session mi-0123456789abcdef0"},
+		), nil)
+	expectKill(t, client, "runtime-id")
+
+	m := NewManagerWithConfig(&config.Config{ExecSessionIdleTimeout: 20 * time.Minute})
+	assert.NoError(t, m.EnforceSessionLimits(context.TODO(), client, task))
+}
+
+func TestEnforceSessionLimitsTerminatesNewestSessionsOverTaskLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	defer setupTestSessionLimits(t, "300")()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	task := &apitask.Task{
+		Arn: testLimitsTaskARN,
+		Containers: []*apicontainer.Container{
+			newTestLimitsContainer("container1", "runtime-id1"),
+			newTestLimitsContainer("container2", "runtime-id2"),
+		},
+	}
+
+	client.EXPECT().TopContainer(gomock.Any(), "runtime-id1", gomock.Any(), gomock.Any()).Return(
+		sessionWorkers([]string{"200", "600", "ssm-session-worker old-session mi-0123456789abcdef0"}), nil)
+	client.EXPECT().TopContainer(gomock.Any(), "runtime-id2", gomock.Any(), gomock.Any()).Return(
+		sessionWorkers([]string{"300", "5", "ssm-session-worker new-session mi-0123456789abcdef0"}), nil)
+	expectKill(t, client, "runtime-id2")
+
+	m := NewManagerWithConfig(&config.Config{ExecMaxSessionsPerTask: 1})
+	assert.NoError(t, m.EnforceSessionLimits(context.TODO(), client, task))
+}
+
+func TestEnforceSessionLimitsDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	task := &apitask.Task{
+		Arn:        testLimitsTaskARN,
+		Containers: []*apicontainer.Container{newTestLimitsContainer(testAuditContainer, "runtime-id")},
+	}
+	// absence of top container expect call indicates it shouldn't have been called
+	assert.NoError(t, NewManager().EnforceSessionLimits(context.TODO(), client, task))
+}
+
+func TestSessionWorkersLimitCappedByConfig(t *testing.T) {
+	ma := apicontainer.ManagedAgent{
+		Properties: map[string]string{"sessionLimit": "5"},
+	}
+	assert.Equal(t, 5, NewManager().sessionWorkersLimit(ma))
+	assert.Equal(t, 1, NewManagerWithConfig(&config.Config{ExecMaxSessionsPerContainer: 1}).sessionWorkersLimit(ma))
+	assert.Equal(t, 5, NewManagerWithConfig(&config.Config{ExecMaxSessionsPerContainer: 10}).sessionWorkersLimit(ma))
+}
@@ -0,0 +1,125 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+// firelensConfigReloadSignal is the signal sent to firelens containers to reload their config
+const firelensConfigReloadSignal = "SIGHUP"
+
+// startPeriodicFirelensConfigReloads reloads the config of the firelens containers of the
+// tasks with config reload enabled at the configured interval, until the context is done
+func (engine *DockerTaskEngine) startPeriodicFirelensConfigReloads(ctx context.Context) {
+	if engine.cfg.FirelensConfigReloadInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(engine.cfg.FirelensConfigReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			engine.reloadFirelensConfigs(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadFirelensConfigs regenerates the config of the running firelens containers of the
+// running tasks with config reload enabled, and signals the ones whose config changed to
+// reload it. The other containers of the tasks keep running.
+func (engine *DockerTaskEngine) reloadFirelensConfigs(ctx context.Context) {
+	var tasks []*apitask.Task
+	engine.tasksLock.RLock()
+	for _, mTask := range engine.managedTasks {
+		if mTask.GetKnownStatus() == apitaskstatus.TaskRunning && !mTask.GetDesiredStatus().Terminal() {
+			tasks = append(tasks, mTask.Task)
+		}
+	}
+	engine.tasksLock.RUnlock()
+
+	for _, task := range tasks {
+		engine.reloadFirelensConfig(ctx, task)
+	}
+}
+
+// reloadFirelensConfig regenerates the config of the firelens container of the task from its
+// current log options and secrets, and sends it the reload signal when the config changed
+func (engine *DockerTaskEngine) reloadFirelensConfig(ctx context.Context, task *apitask.Task) {
+	firelensResource, ok := task.GetFirelensResource()
+	if !ok || !firelensResource.GetConfigReloadEnabled() {
+		return
+	}
+	container := task.GetFirelensContainer()
+	if container == nil || container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+		return
+	}
+
+	containerToLogOptions, hasSecretValues, err := task.GetFirelensLogOptions(container)
+	if err != nil {
+		logger.Warn("Error collecting log options to reload firelens config", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.Error:     err,
+		})
+		return
+	}
+	changed, err := firelensResource.ReloadConfig(containerToLogOptions, hasSecretValues)
+	if err != nil {
+		logger.Warn("Error reloading firelens config", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.Error:     err,
+		})
+		return
+	}
+	if !changed {
+		return
+	}
+
+	dockerID, err := engine.getDockerID(task, container)
+	if err != nil {
+		logger.Error("Unable to signal firelens container to reload its config", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.Error:     err,
+		})
+		return
+	}
+	err = engine.client.KillContainer(ctx, dockerID, firelensConfigReloadSignal, dockerclient.KillContainerTimeout)
+	if err != nil {
+		logger.Warn("Error signaling firelens container to reload its config", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: dockerID,
+			field.Error:     err,
+		})
+		return
+	}
+	logger.Info("Signaled firelens container to reload its config", logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: container.Name,
+		field.RuntimeID: dockerID,
+	})
+}
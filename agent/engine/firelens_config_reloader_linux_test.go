@@ -0,0 +1,121 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
+	"github.com/aws/aws-sdk-go/aws"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFirelensConfigReloadTask(t *testing.T, reloadEnabled string) (*managedTask, string) {
+	firelensContainer := &apicontainer.Container{
+		Name:                "firelens",
+		KnownStatusUnsafe:   apicontainerstatus.ContainerRunning,
+		DesiredStatusUnsafe: apicontainerstatus.ContainerRunning,
+		FirelensConfig:      &apicontainer.FirelensConfig{Type: firelens.FirelensConfigTypeFluentbit},
+	}
+	firelensContainer.SetRuntimeID(containerID)
+	appHostConfig, err := json.Marshal(&dockercontainer.HostConfig{
+		LogConfig: dockercontainer.LogConfig{
+			Type:   "awsfirelens",
+			Config: map[string]string{"Name": "cloudwatch"},
+		},
+	})
+	require.NoError(t, err)
+	appContainer := &apicontainer.Container{
+		Name: "app",
+		DockerConfig: apicontainer.DockerConfig{
+			HostConfig: aws.String(string(appHostConfig)),
+		},
+	}
+	task := &apitask.Task{
+		Arn:                 "arn:aws:ecs:us-west-2:1234567890:task/mycluster/task1",
+		Containers:          []*apicontainer.Container{appContainer, firelensContainer},
+		KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		ResourcesMapUnsafe:  make(map[string][]taskresource.TaskResource),
+	}
+
+	dataDir := t.TempDir()
+	firelensResource, err := firelens.NewFirelensResource("mycluster", task.Arn, "taskdef:1", "i-123", dataDir,
+		firelens.FirelensConfigTypeFluentbit, "us-west-2", "bridge",
+		map[string]string{"enable-config-reload": reloadEnabled},
+		map[string]map[string]string{"app": {"Name": "cloudwatch"}}, nil, "", "")
+	require.NoError(t, err)
+	require.NoError(t, firelensResource.Create())
+	task.AddResource(firelens.ResourceName, firelensResource)
+	return &managedTask{Task: task}, filepath.Join(firelensResource.GetResourceDir(), "config", "fluent.conf")
+}
+
+func TestReloadFirelensConfigs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	mTask, configFile := newFirelensConfigReloadTask(t, "true")
+	dockerTaskEngine.managedTasks[mTask.Arn] = mTask
+	config, err := ioutil.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(config), "Hot_Reload On")
+
+	// the config didn't change, so the container isn't signaled
+	dockerTaskEngine.reloadFirelensConfigs(ctx)
+
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("stale"), 0644))
+	client.EXPECT().KillContainer(gomock.Any(), containerID, "SIGHUP", dockerclient.KillContainerTimeout).Return(nil)
+	dockerTaskEngine.reloadFirelensConfigs(ctx)
+	reloaded, err := ioutil.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, config, reloaded)
+}
+
+func TestReloadFirelensConfigsDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	mTask, configFile := newFirelensConfigReloadTask(t, "false")
+	dockerTaskEngine.managedTasks[mTask.Arn] = mTask
+	config, err := ioutil.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(config), "Hot_Reload")
+
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("stale"), 0644))
+	dockerTaskEngine.reloadFirelensConfigs(ctx)
+	reloaded, err := ioutil.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "stale", string(reloaded))
+}
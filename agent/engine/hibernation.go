@@ -0,0 +1,91 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+// ResumeAfterHibernation checks the containers of the tasks that are still running
+// once the instance resumed from a hibernation, so that the containers that didn't
+// survive it are known as stopped right away rather than at the next steady state
+// check. With SkipCleanupAfterHibernation, the containers of all the tasks known
+// at that point are kept when their task is cleaned up.
+func (engine *DockerTaskEngine) ResumeAfterHibernation(suspended time.Duration) {
+	tasks := engine.state.AllTasks()
+	logger.Info("Checking the containers of tasks after a hibernation", logger.Fields{
+		"tasks":     len(tasks),
+		"suspended": suspended.String(),
+	})
+
+	if engine.cfg.SkipCleanupAfterHibernation.Enabled() {
+		engine.hibernatedTasksLock.Lock()
+		if engine.hibernatedTasks == nil {
+			engine.hibernatedTasks = make(map[string]struct{})
+		}
+		for _, task := range tasks {
+			engine.hibernatedTasks[task.Arn] = struct{}{}
+		}
+		engine.hibernatedTasksLock.Unlock()
+	}
+
+	for _, task := range tasks {
+		if task.GetKnownStatus().Terminal() {
+			continue
+		}
+		engine.checkTaskState(task)
+	}
+}
+
+// CleanupAfterInstanceChange stops and removes the containers of all the tasks once
+// the instance resumed as a different EC2 instance. Their container instance is gone,
+// and the agent doesn't know about them once it registers the new instance, so they
+// are removed even with SkipCleanupAfterHibernation.
+func (engine *DockerTaskEngine) CleanupAfterInstanceChange() {
+	engine.hibernatedTasksLock.Lock()
+	engine.hibernatedTasks = nil
+	engine.hibernatedTasksLock.Unlock()
+
+	for _, task := range engine.state.AllTasks() {
+		logger.Info("Cleaning up the containers of task of the previous instance", logger.Fields{
+			field.TaskARN: task.Arn,
+		})
+		for _, cont := range task.Containers {
+			dockerID, err := engine.getDockerID(task, cont)
+			if err != nil {
+				continue
+			}
+			engine.client.StopContainer(engine.ctx, dockerID, engine.cfg.DockerStopTimeout)
+		}
+		engine.sweepTask(task)
+	}
+}
+
+// survivedHibernation returns whether the task was known when the instance hibernated,
+// and its containers have to be kept when it's cleaned up
+func (engine *DockerTaskEngine) survivedHibernation(taskARN string) bool {
+	engine.hibernatedTasksLock.Lock()
+	defer engine.hibernatedTasksLock.Unlock()
+	_, ok := engine.hibernatedTasks[taskARN]
+	return ok
+}
+
+func (engine *DockerTaskEngine) forgetHibernatedTask(taskARN string) {
+	engine.hibernatedTasksLock.Lock()
+	defer engine.hibernatedTasksLock.Unlock()
+	delete(engine.hibernatedTasks, taskARN)
+}
@@ -0,0 +1,102 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func addHibernationTestTask(engine *DockerTaskEngine, arn, dockerID string, status apitaskstatus.TaskStatus) {
+	container := &apicontainer.Container{Name: "app"}
+	task := &apitask.Task{
+		Arn:               arn,
+		KnownStatusUnsafe: status,
+		Containers:        []*apicontainer.Container{container},
+	}
+	engine.state.AddTask(task)
+	engine.state.AddContainer(&apicontainer.DockerContainer{DockerID: dockerID, DockerName: "ecs-" + dockerID, Container: container}, task)
+}
+
+func TestResumeAfterHibernation(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SkipCleanupAfterHibernation = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, context.TODO(), &cfg)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	addHibernationTestTask(engine, "running", "running-id", apitaskstatus.TaskRunning)
+	addHibernationTestTask(engine, "stopped", "stopped-id", apitaskstatus.TaskStopped)
+
+	// Only the containers of the tasks that aren't stopped are checked.
+	client.EXPECT().DescribeContainer(gomock.Any(), "running-id").Return(apicontainerstatus.ContainerRunning,
+		dockerapi.DockerContainerMetadata{DockerID: "running-id"})
+	engine.ResumeAfterHibernation(time.Hour)
+
+	assert.True(t, engine.survivedHibernation("running"))
+	assert.True(t, engine.survivedHibernation("stopped"))
+	assert.False(t, engine.survivedHibernation("new"))
+	engine.forgetHibernatedTask("stopped")
+	assert.False(t, engine.survivedHibernation("stopped"))
+}
+
+func TestResumeAfterHibernationCleansUp(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, context.TODO(), &defaultConfig)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	addHibernationTestTask(engine, "running", "running-id", apitaskstatus.TaskRunning)
+	client.EXPECT().DescribeContainer(gomock.Any(), "running-id").Return(apicontainerstatus.ContainerRunning,
+		dockerapi.DockerContainerMetadata{DockerID: "running-id"})
+	engine.ResumeAfterHibernation(time.Hour)
+
+	assert.False(t, engine.survivedHibernation("running"))
+}
+
+func TestCleanupAfterInstanceChange(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SkipCleanupAfterHibernation = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	ctrl, client, _, taskEngine, _, imageManager, _ := mocks(t, context.TODO(), &cfg)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	addHibernationTestTask(engine, "running", "running-id", apitaskstatus.TaskRunning)
+	client.EXPECT().DescribeContainer(gomock.Any(), "running-id").Return(apicontainerstatus.ContainerRunning,
+		dockerapi.DockerContainerMetadata{DockerID: "running-id"})
+	engine.ResumeAfterHibernation(time.Hour)
+	require.True(t, engine.survivedHibernation("running"))
+
+	// The containers of the previous instance are removed even though they survived the hibernation.
+	gomock.InOrder(
+		client.EXPECT().StopContainer(gomock.Any(), "running-id", cfg.DockerStopTimeout),
+		client.EXPECT().RemoveContainer(gomock.Any(), "running-id", gomock.Any()),
+	)
+	imageManager.EXPECT().RemoveContainerReferenceFromImageState(gomock.Any())
+	engine.CleanupAfterInstanceChange()
+	assert.False(t, engine.survivedHibernation("running"))
+}
@@ -0,0 +1,308 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/credentials/instancecreds"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ImagePreloadPulling is the status of an image that is being preloaded
+	ImagePreloadPulling = "PULLING"
+	// ImagePreloadPulled is the status of an image that was preloaded
+	ImagePreloadPulled = "PULLED"
+	// ImagePreloadFailed is the status of an image that could not be preloaded
+	ImagePreloadFailed = "FAILED"
+
+	// imagePreloadRoleSessionName is the session name of the roles assumed to
+	// preload ECR images
+	imagePreloadRoleSessionName = "ecs-agent-image-preload"
+
+	// imagePreloadStatusRetention is how long the status of a finished preload
+	// is kept
+	imagePreloadStatusRetention = time.Hour
+	// maxImagePreloadStatuses is the maximum number of preload statuses that
+	// are kept. The statuses of the preloads that finished first are evicted
+	// to make room for new preloads
+	maxImagePreloadStatuses = 1000
+)
+
+// ecrImageRegex matches the images in private ECR repositories, and captures
+// their registry ID and region
+var ecrImageRegex = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/`)
+
+// ImagePreload is a request to pull an image before the tasks that use it are
+// placed on the instance
+type ImagePreload struct {
+	// Image is the image to pull
+	Image string
+	// RoleArn is the role assumed to pull the image from ECR. If empty, the
+	// image is pulled with the instance credentials
+	RoleArn string
+}
+
+// ImagePreloadStatus is the status of an image preload
+type ImagePreloadStatus struct {
+	Image       string
+	RoleArn     string `json:",omitempty"`
+	Status      string
+	Error       string `json:",omitempty"`
+	RequestedAt time.Time
+	FinishedAt  *time.Time `json:",omitempty"`
+}
+
+// assumeRoleFunc returns credentials for the role, to pull images from ECR
+// in the region
+type assumeRoleFunc func(roleARN string, region string) (credentials.IAMRoleCredentials, error)
+
+// imagePreloader pulls images in the background on request, and keeps track
+// of the status of the latest preload of every image
+type imagePreloader struct {
+	engine     *DockerTaskEngine
+	assumeRole assumeRoleFunc
+	lock       sync.RWMutex
+	statuses   map[ImagePreload]*ImagePreloadStatus
+}
+
+func newImagePreloader(engine *DockerTaskEngine) *imagePreloader {
+	return &imagePreloader{
+		engine:     engine,
+		assumeRole: assumeECRPullRole,
+		statuses:   make(map[ImagePreload]*ImagePreloadStatus),
+	}
+}
+
+// PreloadImages starts pulling the images in the background, so that the tasks
+// placed later on the instance do not have to wait for them. If any of the
+// requests is invalid, none of the images are pulled
+func (engine *DockerTaskEngine) PreloadImages(preloads []ImagePreload) error {
+	return engine.imagePreloader.preload(preloads)
+}
+
+// ImagePreloadStatuses returns the status of the latest preload of every image
+func (engine *DockerTaskEngine) ImagePreloadStatuses() []ImagePreloadStatus {
+	return engine.imagePreloader.getStatuses()
+}
+
+func (preloader *imagePreloader) preload(preloads []ImagePreload) error {
+	for _, preload := range preloads {
+		if err := validateImagePreload(preload); err != nil {
+			return err
+		}
+	}
+
+	preloader.lock.Lock()
+	defer preloader.lock.Unlock()
+
+	newPreloads := 0
+	for _, preload := range preloads {
+		if _, ok := preloader.statuses[preload]; !ok {
+			newPreloads++
+		}
+	}
+	preloader.evictStatuses(newPreloads)
+	if len(preloader.statuses)+newPreloads > maxImagePreloadStatuses {
+		return fmt.Errorf("image preload: too many images are being preloaded, at most %d preloads are tracked",
+			maxImagePreloadStatuses)
+	}
+
+	for _, preload := range preloads {
+		if status, ok := preloader.statuses[preload]; ok && status.Status == ImagePreloadPulling {
+			continue
+		}
+		preloader.statuses[preload] = &ImagePreloadStatus{
+			Image:       preload.Image,
+			RoleArn:     preload.RoleArn,
+			Status:      ImagePreloadPulling,
+			RequestedAt: preloader.engine.time().Now(),
+		}
+		go preloader.pull(preload)
+	}
+	return nil
+}
+
+// evictStatuses removes the statuses of the preloads that finished more than
+// imagePreloadStatusRetention ago. If there's still no room for the new
+// preloads, it removes the statuses of the preloads that finished first.
+// Statuses of the preloads that are still pulling are never removed
+func (preloader *imagePreloader) evictStatuses(newPreloads int) {
+	now := preloader.engine.time().Now()
+	var finished []ImagePreload
+	for preload, status := range preloader.statuses {
+		if status.FinishedAt == nil {
+			continue
+		}
+		if now.Sub(*status.FinishedAt) > imagePreloadStatusRetention {
+			delete(preloader.statuses, preload)
+			continue
+		}
+		finished = append(finished, preload)
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return preloader.statuses[finished[i]].FinishedAt.Before(*preloader.statuses[finished[j]].FinishedAt)
+	})
+	for _, preload := range finished {
+		if len(preloader.statuses)+newPreloads <= maxImagePreloadStatuses {
+			return
+		}
+		delete(preloader.statuses, preload)
+	}
+}
+
+func (preloader *imagePreloader) getStatuses() []ImagePreloadStatus {
+	preloader.lock.RLock()
+	defer preloader.lock.RUnlock()
+
+	statuses := make([]ImagePreloadStatus, 0, len(preloader.statuses))
+	for _, status := range preloader.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Image != statuses[j].Image {
+			return statuses[i].Image < statuses[j].Image
+		}
+		return statuses[i].RoleArn < statuses[j].RoleArn
+	})
+	return statuses
+}
+
+// pull pulls the image like the images of tasks, sharing the pull with the
+// containers that request the image at the same time
+func (preloader *imagePreloader) pull(preload ImagePreload) {
+	engine := preloader.engine
+	seelog.Infof("Task engine: preloading image %s", preload.Image)
+
+	metadata := preloader.pullImage(preload)
+	if metadata.Error == nil {
+		// Let the image cleanup remove the image once it's unused, like the
+		// images pulled for tasks
+		imageState, err := engine.imageManager.RecordImage(preload.Image)
+		if err != nil {
+			seelog.Warnf("Task engine: unable to add preloaded image %s to the image states: %v", preload.Image, err)
+		} else {
+			engine.state.AddImageState(imageState)
+		}
+	}
+
+	preloader.lock.Lock()
+	defer preloader.lock.Unlock()
+
+	status := preloader.statuses[preload]
+	finishedAt := engine.time().Now()
+	status.FinishedAt = &finishedAt
+	if metadata.Error != nil {
+		seelog.Errorf("Task engine: failed to preload image %s: %v", preload.Image, metadata.Error)
+		status.Status = ImagePreloadFailed
+		status.Error = metadata.Error.Error()
+		return
+	}
+	seelog.Infof("Task engine: finished preloading image %s", preload.Image)
+	status.Status = ImagePreloadPulled
+}
+
+// pullImage pulls the image with the role of the preload request, if any, and
+// waits for the pull to finish
+func (preloader *imagePreloader) pullImage(preload ImagePreload) dockerapi.DockerContainerMetadata {
+	engine := preloader.engine
+	authData, err := preloader.registryAuthData(preload)
+	if err != nil {
+		return dockerapi.DockerContainerMetadata{Error: dockerapi.CannotPullECRContainerError{FromError: err}}
+	}
+
+	ImagePullDeleteLock.RLock()
+	defer ImagePullDeleteLock.RUnlock()
+	return engine.imagePullScheduler.pull(engine.ctx, preload.Image, authData,
+		func() dockerapi.DockerContainerMetadata {
+			return engine.client.PullImage(engine.ctx, preload.Image, authData, engine.cfg.ImagePullTimeout)
+		})
+}
+
+// registryAuthData returns the registry authentication data to pull the image
+// with the role of the preload request, if any
+func (preloader *imagePreloader) registryAuthData(preload ImagePreload) (*apicontainer.RegistryAuthenticationData, error) {
+	if preload.RoleArn == "" {
+		return nil, nil
+	}
+	match := ecrImageRegex.FindStringSubmatch(preload.Image)
+	registryID, region := match[1], match[2]
+	pullCredentials, err := preloader.assumeRole(preload.RoleArn, region)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to assume role %s", preload.RoleArn)
+	}
+	authData := &apicontainer.RegistryAuthenticationData{
+		Type: apicontainer.AuthTypeECR,
+		ECRAuthData: &apicontainer.ECRAuthData{
+			Region:           region,
+			RegistryID:       registryID,
+			UseExecutionRole: true,
+		},
+	}
+	authData.ECRAuthData.SetPullCredentials(pullCredentials)
+	return authData, nil
+}
+
+func validateImagePreload(preload ImagePreload) error {
+	if preload.Image == "" {
+		return errors.New("image preload: image name is required")
+	}
+	if preload.RoleArn == "" {
+		return nil
+	}
+	if _, err := arn.Parse(preload.RoleArn); err != nil {
+		return fmt.Errorf("image preload: invalid role arn %s: %v", preload.RoleArn, err)
+	}
+	if !ecrImageRegex.MatchString(preload.Image) {
+		return fmt.Errorf("image preload: a role can only be used to pull ECR images, not %s", preload.Image)
+	}
+	return nil
+}
+
+// assumeECRPullRole assumes the role with the instance credentials
+func assumeECRPullRole(roleARN string, region string) (credentials.IAMRoleCredentials, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: instancecreds.GetCredentials(),
+		Region:      aws.String(region),
+	})
+	if err != nil {
+		return credentials.IAMRoleCredentials{}, err
+	}
+	creds, err := stscreds.NewCredentials(sess, roleARN, func(provider *stscreds.AssumeRoleProvider) {
+		provider.RoleSessionName = imagePreloadRoleSessionName
+	}).Get()
+	if err != nil {
+		return credentials.IAMRoleCredentials{}, err
+	}
+	return credentials.IAMRoleCredentials{
+		RoleArn:         roleARN,
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, nil
+}
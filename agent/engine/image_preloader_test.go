@@ -0,0 +1,188 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPreloadECRImage = "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:1"
+	testPreloadRoleArn  = "arn:aws:iam::123456789012:role/pull"
+)
+
+// waitForImagePreloads waits for every image preload to finish
+func waitForImagePreloads(t *testing.T, taskEngine *DockerTaskEngine) []ImagePreloadStatus {
+	for i := 0; i < 1000; i++ {
+		statuses := taskEngine.ImagePreloadStatuses()
+		finished := true
+		for _, status := range statuses {
+			if status.Status == ImagePreloadPulling {
+				finished = false
+			}
+		}
+		if finished {
+			return statuses
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out waiting for image preloads")
+	return nil
+}
+
+func TestPreloadImages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, imageManager, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	dockerTaskEngine.imagePreloader.assumeRole = func(roleARN string, region string) (credentials.IAMRoleCredentials, error) {
+		assert.Equal(t, testPreloadRoleArn, roleARN)
+		assert.Equal(t, "us-west-2", region)
+		return credentials.IAMRoleCredentials{RoleArn: roleARN, AccessKeyID: "id"}, nil
+	}
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().PullImage(gomock.Any(), "busybox:latest", nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{})
+	busyboxImageState := &image.ImageState{Image: &image.Image{ImageID: "sha256:busybox"}}
+	imageManager.EXPECT().RecordImage("busybox:latest").Return(busyboxImageState, nil)
+	client.EXPECT().PullImage(gomock.Any(), testPreloadECRImage, gomock.Any(), gomock.Any()).Do(
+		func(ctx context.Context, image string, authData *apicontainer.RegistryAuthenticationData, timeout time.Duration) {
+			require.NotNil(t, authData.ECRAuthData)
+			assert.Equal(t, "123456789012", authData.ECRAuthData.RegistryID)
+			assert.Equal(t, "us-west-2", authData.ECRAuthData.Region)
+			assert.Equal(t, "id", authData.ECRAuthData.GetPullCredentials().AccessKeyID)
+		}).Return(dockerapi.DockerContainerMetadata{
+		Error: dockerapi.CannotPullContainerError{FromError: errors.New("manifest unknown")},
+	})
+
+	require.NoError(t, dockerTaskEngine.PreloadImages([]ImagePreload{
+		{Image: "busybox:latest"},
+		{Image: testPreloadECRImage, RoleArn: testPreloadRoleArn},
+	}))
+
+	statuses := waitForImagePreloads(t, dockerTaskEngine)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, testPreloadECRImage, statuses[0].Image)
+	assert.Equal(t, ImagePreloadFailed, statuses[0].Status)
+	assert.Contains(t, statuses[0].Error, "manifest unknown")
+	assert.Equal(t, "busybox:latest", statuses[1].Image)
+	assert.Equal(t, ImagePreloadPulled, statuses[1].Status)
+	assert.NotNil(t, statuses[1].FinishedAt)
+	assert.Contains(t, dockerTaskEngine.state.AllImageStates(), busyboxImageState,
+		"the preloaded image should be added to the image states")
+}
+
+func TestPreloadImagesAssumeRoleFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	dockerTaskEngine.imagePreloader.assumeRole = func(roleARN string, region string) (credentials.IAMRoleCredentials, error) {
+		return credentials.IAMRoleCredentials{}, errors.New("access denied")
+	}
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	require.NoError(t, dockerTaskEngine.PreloadImages([]ImagePreload{
+		{Image: testPreloadECRImage, RoleArn: testPreloadRoleArn},
+	}))
+
+	statuses := waitForImagePreloads(t, dockerTaskEngine)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, ImagePreloadFailed, statuses[0].Status)
+	assert.Contains(t, statuses[0].Error, "access denied")
+}
+
+func TestPreloadImagesInvalidRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	for _, preloads := range [][]ImagePreload{
+		{{Image: ""}},
+		{{Image: "busybox", RoleArn: testPreloadRoleArn}},
+		{{Image: testPreloadECRImage, RoleArn: "not-an-arn"}},
+		{{Image: "busybox"}, {Image: ""}},
+	} {
+		assert.Error(t, dockerTaskEngine.PreloadImages(preloads))
+	}
+	assert.Empty(t, dockerTaskEngine.ImagePreloadStatuses(), "no image should be pulled for invalid requests")
+}
+
+func TestPreloadImagesEvictsStatuses(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	preloader := dockerTaskEngine.imagePreloader
+
+	now := time.Now()
+	mockTime.EXPECT().Now().Return(now).AnyTimes()
+	expired := now.Add(-imagePreloadStatusRetention - time.Minute)
+	preloader.statuses[ImagePreload{Image: "expired"}] = &ImagePreloadStatus{
+		Image: "expired", Status: ImagePreloadPulled, FinishedAt: &expired,
+	}
+	for i := 0; i < maxImagePreloadStatuses-1; i++ {
+		finishedAt := now.Add(-time.Duration(i) * time.Second)
+		imageName := fmt.Sprintf("image-%d", i)
+		preloader.statuses[ImagePreload{Image: imageName}] = &ImagePreloadStatus{
+			Image: imageName, Status: ImagePreloadPulled, FinishedAt: &finishedAt,
+		}
+	}
+
+	// Evicting the expired status is not enough for two new preloads, so the
+	// status of the preload that finished first is evicted too
+	preloader.evictStatuses(2)
+	assert.Len(t, preloader.statuses, maxImagePreloadStatuses-2)
+	assert.NotContains(t, preloader.statuses, ImagePreload{Image: "expired"})
+	assert.NotContains(t, preloader.statuses, ImagePreload{Image: fmt.Sprintf("image-%d", maxImagePreloadStatuses-2)})
+	assert.Contains(t, preloader.statuses, ImagePreload{Image: "image-0"})
+}
+
+func TestPreloadImagesTooManyPulling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	preloader := dockerTaskEngine.imagePreloader
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	for i := 0; i < maxImagePreloadStatuses; i++ {
+		imageName := fmt.Sprintf("image-%d", i)
+		preloader.statuses[ImagePreload{Image: imageName}] = &ImagePreloadStatus{Image: imageName, Status: ImagePreloadPulling}
+	}
+
+	assert.Error(t, dockerTaskEngine.PreloadImages([]ImagePreload{{Image: "busybox"}}),
+		"the statuses of the preloads that are still pulling should not be evicted")
+	assert.Len(t, preloader.statuses, maxImagePreloadStatuses)
+}
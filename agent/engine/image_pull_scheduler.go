@@ -0,0 +1,304 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// dockerMaxConcurrentDownloads is the default number of layers the docker
+	// daemon downloads in parallel for a single image pull
+	dockerMaxConcurrentDownloads = 3
+	// defaultPullBandwidthShares is the number of pulls that are allowed to run
+	// at the same time before any pull bandwidth has been observed
+	defaultPullBandwidthShares = 4
+	// minimumObservedPullDuration is the minimum duration of a pull for its
+	// bandwidth to be recorded. Shorter pulls are mostly of layers that were
+	// already present, and say little about the bandwidth of the pull
+	minimumObservedPullDuration = time.Second
+	// layerBandwidthSmoothing is the weight of the latest observation in the
+	// moving average of the bandwidth of a layer download
+	layerBandwidthSmoothing = 0.3
+	bitsPerMegabit          = 1000 * 1000
+)
+
+// sharedPull is an image pull whose result is shared by every container that
+// requested the same image with the same credentials while it was running
+type sharedPull struct {
+	done     chan struct{}
+	metadata dockerapi.DockerContainerMetadata
+}
+
+// pullWaiter is an image pull waiting for bandwidth to run
+type pullWaiter struct {
+	bandwidth float64
+	ready     chan struct{}
+}
+
+// imagePullScheduler deduplicates concurrent pulls of the same image, and
+// limits the pulls running at the same time by their estimated bandwidth.
+//
+// The bandwidth of a pull is estimated from the previous pull of the image.
+// For images that have not been pulled yet, it is estimated from the average
+// bandwidth of a layer download, times the number of layers the docker daemon
+// downloads in parallel
+type imagePullScheduler struct {
+	// maxBandwidth is the bandwidth cap in Mbps, zero means no limit
+	maxBandwidth float64
+	client       dockerapi.DockerClient
+
+	lock     sync.Mutex
+	pulls    map[string]*sharedPull
+	reserved float64
+	running  int
+	waiters  []*pullWaiter
+	// imageBandwidth is the observed bandwidth of the last pull of each image
+	imageBandwidth map[string]float64
+	// layerBandwidth is the moving average of the observed bandwidth of a
+	// layer download
+	layerBandwidth float64
+}
+
+// newImagePullScheduler creates an image pull scheduler that caps the
+// bandwidth of concurrent pulls to maxBandwidth Mbps, if not zero
+func newImagePullScheduler(maxBandwidth float64, client dockerapi.DockerClient) *imagePullScheduler {
+	return &imagePullScheduler{
+		maxBandwidth:   maxBandwidth,
+		client:         client,
+		pulls:          make(map[string]*sharedPull),
+		imageBandwidth: make(map[string]float64),
+	}
+}
+
+// pull runs pullFunc to pull the image once there is enough bandwidth for it.
+// If the image is already being pulled with the same credentials, it waits
+// for that pull instead, and returns its result
+func (scheduler *imagePullScheduler) pull(ctx context.Context, image string,
+	authData *apicontainer.RegistryAuthenticationData,
+	pullFunc func() dockerapi.DockerContainerMetadata) dockerapi.DockerContainerMetadata {
+	return scheduler.schedule(ctx, image, authData, false, pullFunc)
+}
+
+// pullLazily runs pullFunc to pull an image that is lazily loaded, right
+// away. Lazily loaded pulls only download the image metadata, so they do not
+// reserve bandwidth, and their bandwidth is not recorded. They are still
+// shared like the other pulls
+func (scheduler *imagePullScheduler) pullLazily(ctx context.Context, image string,
+	authData *apicontainer.RegistryAuthenticationData,
+	pullFunc func() dockerapi.DockerContainerMetadata) dockerapi.DockerContainerMetadata {
+	return scheduler.schedule(ctx, image, authData, true, pullFunc)
+}
+
+func (scheduler *imagePullScheduler) schedule(ctx context.Context, image string,
+	authData *apicontainer.RegistryAuthenticationData, lazy bool,
+	pullFunc func() dockerapi.DockerContainerMetadata) dockerapi.DockerContainerMetadata {
+	key := image + "|" + pullAuthKey(authData)
+
+	scheduler.lock.Lock()
+	if pull, ok := scheduler.pulls[key]; ok {
+		scheduler.lock.Unlock()
+		seelog.Infof("Image pull scheduler: waiting for the pull of image %s already in progress", image)
+		<-pull.done
+		return pull.metadata
+	}
+	pull := &sharedPull{done: make(chan struct{})}
+	scheduler.pulls[key] = pull
+	scheduler.lock.Unlock()
+
+	defer func() {
+		scheduler.lock.Lock()
+		delete(scheduler.pulls, key)
+		scheduler.lock.Unlock()
+		close(pull.done)
+	}()
+
+	if lazy {
+		pull.metadata = pullFunc()
+		return pull.metadata
+	}
+	bandwidth := scheduler.estimateBandwidth(image)
+	if err := scheduler.acquire(ctx, bandwidth); err != nil {
+		pull.metadata = dockerapi.DockerContainerMetadata{Error: dockerapi.CannotPullContainerError{FromError: err}}
+		return pull.metadata
+	}
+	start := time.Now()
+	pull.metadata = pullFunc()
+	scheduler.release(bandwidth)
+
+	if pull.metadata.Error == nil {
+		scheduler.recordBandwidth(image, time.Since(start))
+	}
+	return pull.metadata
+}
+
+// acquire reserves the bandwidth for a pull, waiting for running pulls to
+// finish if the pull would exceed the bandwidth cap. Pulls are admitted in
+// the order they are requested, and a pull is always admitted when no other
+// pull is running, whatever its bandwidth
+func (scheduler *imagePullScheduler) acquire(ctx context.Context, bandwidth float64) error {
+	scheduler.lock.Lock()
+	if len(scheduler.waiters) == 0 && scheduler.fitsUnsafe(bandwidth) {
+		scheduler.reserveUnsafe(bandwidth)
+		scheduler.lock.Unlock()
+		return nil
+	}
+	waiter := &pullWaiter{bandwidth: bandwidth, ready: make(chan struct{})}
+	scheduler.waiters = append(scheduler.waiters, waiter)
+	scheduler.lock.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		scheduler.lock.Lock()
+		defer scheduler.lock.Unlock()
+		for i, queued := range scheduler.waiters {
+			if queued == waiter {
+				scheduler.waiters = append(scheduler.waiters[:i], scheduler.waiters[i+1:]...)
+				scheduler.admitUnsafe()
+				return ctx.Err()
+			}
+		}
+		// The pull was admitted concurrently with the cancellation
+		scheduler.running--
+		scheduler.reserved -= bandwidth
+		scheduler.admitUnsafe()
+		return ctx.Err()
+	}
+}
+
+// release frees the bandwidth reserved for a pull, and admits the waiting
+// pulls that fit in the freed bandwidth
+func (scheduler *imagePullScheduler) release(bandwidth float64) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	scheduler.running--
+	scheduler.reserved -= bandwidth
+	scheduler.admitUnsafe()
+}
+
+func (scheduler *imagePullScheduler) admitUnsafe() {
+	for len(scheduler.waiters) > 0 {
+		waiter := scheduler.waiters[0]
+		if !scheduler.fitsUnsafe(waiter.bandwidth) {
+			return
+		}
+		scheduler.waiters = scheduler.waiters[1:]
+		scheduler.reserveUnsafe(waiter.bandwidth)
+		close(waiter.ready)
+	}
+}
+
+func (scheduler *imagePullScheduler) fitsUnsafe(bandwidth float64) bool {
+	return scheduler.maxBandwidth == 0 || scheduler.running == 0 ||
+		scheduler.reserved+bandwidth <= scheduler.maxBandwidth
+}
+
+func (scheduler *imagePullScheduler) reserveUnsafe(bandwidth float64) {
+	scheduler.running++
+	scheduler.reserved += bandwidth
+}
+
+// estimateBandwidth returns the bandwidth, in Mbps, that a pull of the image
+// is expected to use. It never exceeds the bandwidth cap
+func (scheduler *imagePullScheduler) estimateBandwidth(image string) float64 {
+	if scheduler.maxBandwidth == 0 {
+		return 0
+	}
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	bandwidth, ok := scheduler.imageBandwidth[image]
+	if !ok {
+		if scheduler.layerBandwidth > 0 {
+			bandwidth = scheduler.layerBandwidth * dockerMaxConcurrentDownloads
+		} else {
+			bandwidth = scheduler.maxBandwidth / defaultPullBandwidthShares
+		}
+	}
+	if bandwidth > scheduler.maxBandwidth {
+		bandwidth = scheduler.maxBandwidth
+	}
+	return bandwidth
+}
+
+// recordBandwidth records the bandwidth observed for a successful pull of the
+// image, from the size and the number of layers of the pulled image
+func (scheduler *imagePullScheduler) recordBandwidth(image string, duration time.Duration) {
+	if scheduler.maxBandwidth == 0 || duration < minimumObservedPullDuration {
+		return
+	}
+	inspected, err := scheduler.client.InspectImage(image)
+	if err != nil || inspected.Size <= 0 {
+		seelog.Debugf("Image pull scheduler: unable to get the size of image %s: %v", image, err)
+		return
+	}
+	bandwidth := float64(inspected.Size) * 8 / bitsPerMegabit / duration.Seconds()
+	parallelLayers := len(inspected.RootFS.Layers)
+	if parallelLayers > dockerMaxConcurrentDownloads {
+		parallelLayers = dockerMaxConcurrentDownloads
+	}
+	if parallelLayers < 1 {
+		parallelLayers = 1
+	}
+
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	scheduler.imageBandwidth[image] = bandwidth
+	layerBandwidth := bandwidth / float64(parallelLayers)
+	if scheduler.layerBandwidth == 0 {
+		scheduler.layerBandwidth = layerBandwidth
+	} else {
+		scheduler.layerBandwidth += layerBandwidthSmoothing * (layerBandwidth - scheduler.layerBandwidth)
+	}
+	seelog.Debugf("Image pull scheduler: observed %.1f Mbps pulling image %s", bandwidth, image)
+}
+
+// pullAuthKey identifies the credentials used to pull an image, so that pulls
+// are only shared between containers that use the same credentials
+func pullAuthKey(authData *apicontainer.RegistryAuthenticationData) string {
+	if authData == nil {
+		return ""
+	}
+	switch authData.Type {
+	case apicontainer.AuthTypeECR:
+		if authData.ECRAuthData == nil {
+			return authData.Type
+		}
+		ecrAuthData := authData.ECRAuthData
+		roleARN := ""
+		if ecrAuthData.UseExecutionRole {
+			roleARN = ecrAuthData.GetPullCredentials().RoleArn
+		}
+		return strings.Join([]string{authData.Type, ecrAuthData.Region, ecrAuthData.RegistryID,
+			ecrAuthData.EndpointOverride, roleARN}, "|")
+	case apicontainer.AuthTypeASM:
+		if authData.ASMAuthData == nil {
+			return authData.Type
+		}
+		return strings.Join([]string{authData.Type, authData.ASMAuthData.Region,
+			authData.ASMAuthData.CredentialsParameter}, "|")
+	}
+	return authData.Type
+}
@@ -0,0 +1,181 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImagePullSchedulerDeduplicatesPulls(t *testing.T) {
+	scheduler := newImagePullScheduler(0, nil)
+	release := make(chan struct{})
+	var pulls int32
+	pullFunc := func() dockerapi.DockerContainerMetadata {
+		atomic.AddInt32(&pulls, 1)
+		<-release
+		return dockerapi.DockerContainerMetadata{}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			metadata := scheduler.pull(context.TODO(), "image", nil, pullFunc)
+			assert.NoError(t, metadata.Error)
+		}()
+	}
+	// Wait for every pull to be requested before letting the first one finish
+	for atomic.LoadInt32(&pulls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pulls))
+	assert.Empty(t, scheduler.pulls)
+}
+
+func TestImagePullSchedulerDoesNotShareCredentials(t *testing.T) {
+	newAuthData := func(roleARN string) *apicontainer.RegistryAuthenticationData {
+		authData := &apicontainer.RegistryAuthenticationData{
+			Type: apicontainer.AuthTypeECR,
+			ECRAuthData: &apicontainer.ECRAuthData{
+				Region:           "us-west-2",
+				RegistryID:       "123456789012",
+				UseExecutionRole: true,
+			},
+		}
+		authData.ECRAuthData.SetPullCredentials(credentials.IAMRoleCredentials{RoleArn: roleARN})
+		return authData
+	}
+	assert.Equal(t, pullAuthKey(newAuthData("role1")), pullAuthKey(newAuthData("role1")))
+	assert.NotEqual(t, pullAuthKey(newAuthData("role1")), pullAuthKey(newAuthData("role2")))
+	assert.NotEqual(t, pullAuthKey(nil), pullAuthKey(newAuthData("role1")))
+}
+
+func TestImagePullSchedulerBandwidthCap(t *testing.T) {
+	scheduler := newImagePullScheduler(100, nil)
+	scheduler.imageBandwidth["image1"] = 60
+	scheduler.imageBandwidth["image2"] = 60
+
+	release := make(chan struct{})
+	started := make(chan string, 2)
+	pullFunc := func(image string) func() dockerapi.DockerContainerMetadata {
+		return func() dockerapi.DockerContainerMetadata {
+			started <- image
+			<-release
+			return dockerapi.DockerContainerMetadata{}
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.pull(context.TODO(), "image1", nil, pullFunc("image1"))
+	}()
+	assert.Equal(t, "image1", <-started)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.pull(context.TODO(), "image2", nil, pullFunc("image2"))
+	}()
+	select {
+	case <-started:
+		t.Fatal("pull exceeding the bandwidth cap should wait for the running pull")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	assert.Equal(t, "image2", <-started)
+	close(release)
+	wg.Wait()
+	assert.Zero(t, scheduler.running)
+}
+
+func TestImagePullSchedulerCanceledWhileWaiting(t *testing.T) {
+	scheduler := newImagePullScheduler(100, nil)
+	scheduler.imageBandwidth["image2"] = 100
+	require.NoError(t, scheduler.acquire(context.TODO(), 100))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	metadata := scheduler.pull(ctx, "image2", nil, func() dockerapi.DockerContainerMetadata {
+		t.Fatal("canceled pull should not run")
+		return dockerapi.DockerContainerMetadata{}
+	})
+	assert.Error(t, metadata.Error)
+	assert.Empty(t, scheduler.waiters)
+
+	scheduler.release(100)
+	assert.Zero(t, scheduler.running)
+}
+
+func TestImagePullSchedulerRecordBandwidth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	scheduler := newImagePullScheduler(1000, client)
+
+	assert.Equal(t, 250.0, scheduler.estimateBandwidth("image1"),
+		"pulls should get a default share of the bandwidth before any pull is observed")
+
+	inspected := &types.ImageInspect{Size: 100 * 1000 * 1000}
+	inspected.RootFS.Layers = []string{"layer1", "layer2", "layer3", "layer4"}
+	client.EXPECT().InspectImage("image1").Return(inspected, nil)
+	scheduler.recordBandwidth("image1", 10*time.Second)
+
+	// 100MB in 10s is 80Mbps, downloaded over 3 layers in parallel
+	assert.InDelta(t, 80, scheduler.estimateBandwidth("image1"), 0.001)
+	assert.InDelta(t, 80, scheduler.estimateBandwidth("image2"), 0.001)
+
+	// Pulls that are too short to be meaningful are not recorded
+	scheduler.recordBandwidth("image2", time.Millisecond)
+	assert.NotContains(t, scheduler.imageBandwidth, "image2")
+}
+
+func TestImagePullSchedulerLazyPull(t *testing.T) {
+	scheduler := newImagePullScheduler(100, nil)
+	require.NoError(t, scheduler.acquire(context.TODO(), 100))
+
+	pulled := false
+	metadata := scheduler.pullLazily(context.TODO(), "image", nil, func() dockerapi.DockerContainerMetadata {
+		pulled = true
+		return dockerapi.DockerContainerMetadata{}
+	})
+	assert.NoError(t, metadata.Error)
+	assert.True(t, pulled, "lazy pulls should not wait for bandwidth")
+	assert.Equal(t, 1, scheduler.running)
+	assert.NotContains(t, scheduler.imageBandwidth, "image")
+
+	scheduler.release(100)
+}
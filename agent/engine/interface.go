@@ -19,6 +19,7 @@ import (
 	"context"
 
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/data"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 )
@@ -55,6 +56,9 @@ type TaskEngine interface {
 	LoadState() error
 	// SaveState saves all the data in task engine state to db.
 	SaveState() error
+	// ReloadConfig applies the part of the agent configuration that is reloaded while
+	// the agent runs.
+	ReloadConfig(config.ReloadableConfig)
 
 	json.Marshaler
 	json.Unmarshaler
@@ -0,0 +1,276 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+// Events the lifecycle hooks are run at. The hooks of an event are the executables
+// in the subdirectory of the lifecycle hooks directory named after the event.
+const (
+	hookEventPrePull   = "pre-pull"
+	hookEventPostStart = "post-start"
+	hookEventPreStop   = "pre-stop"
+	hookEventPostStop  = "post-stop"
+
+	// maxHookOutputSize is how much of the output of a failed hook is logged
+	maxHookOutputSize = 4 * 1024
+	// hookWaitDelay is how long a hook that was killed is waited for. A process it started
+	// in another process group can keep its output open after it's killed, it isn't waited
+	// for any longer.
+	hookWaitDelay = 5 * time.Second
+)
+
+// hookEnvAllowlist is the environment of the agent passed to the hooks, SYSTEMROOT
+// being needed by most Windows executables. The rest of it, such as the credentials
+// and the docker auth data of the agent, isn't passed.
+var hookEnvAllowlist = []string{"PATH", "TZ", "LANG", "SYSTEMROOT"}
+
+// hookDocument is the description of the task and the container a hook is run for,
+// written to the stdin of the hook
+type hookDocument struct {
+	Event         string `json:"Event"`
+	Cluster       string `json:"Cluster,omitempty"`
+	TaskARN       string `json:"TaskARN"`
+	Family        string `json:"Family"`
+	Revision      string `json:"Revision"`
+	ContainerName string `json:"ContainerName"`
+	Image         string `json:"Image"`
+	DockerID      string `json:"DockerId,omitempty"`
+	ExitCode      *int   `json:"ExitCode,omitempty"`
+}
+
+// lifecycleHooks runs the executables of the instance that integrate with the
+// lifecycle of the containers of tasks, such as registering them in DNS. The
+// hooks run before a transition delay it, those run after it don't. The hooks are
+// run by the agent process, in the agent container on Linux, which has no shell:
+// they have to be static binaries, in a directory mounted in the agent container.
+type lifecycleHooks struct {
+	engine *DockerTaskEngine
+}
+
+func newLifecycleHooks(engine *DockerTaskEngine) *lifecycleHooks {
+	return &lifecycleHooks{
+		engine: engine,
+	}
+}
+
+// runBefore runs the hooks of an event before the transition of the container, and
+// returns once they're done or killed. The hooks that are still running once the timeout,
+// when it's positive, expires are killed, so that they delay the transition by the timeout
+// at most.
+func (hooks *lifecycleHooks) runBefore(event string, task *apitask.Task, container *apicontainer.Container,
+	timeout time.Duration) {
+	if hooks == nil || container.IsInternal() {
+		return
+	}
+	ctx := hooks.engine.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	hooks.run(ctx, event, task, container)
+}
+
+// runAfterTransition runs in the background the hooks of the container transition to
+// a known status, if it has any
+func (hooks *lifecycleHooks) runAfterTransition(task *apitask.Task, container *apicontainer.Container) {
+	if hooks == nil || container.IsInternal() || container.GetRuntimeID() == "" {
+		return
+	}
+	switch container.GetKnownStatus() {
+	case apicontainerstatus.ContainerRunning:
+		go hooks.run(hooks.engine.ctx, hookEventPostStart, task, container)
+	case apicontainerstatus.ContainerStopped:
+		go hooks.run(hooks.engine.ctx, hookEventPostStop, task, container)
+	}
+}
+
+// list returns the paths of the hooks of an event, in lexical order
+func (hooks *lifecycleHooks) list(event string) []string {
+	if hooks.engine.cfg.LifecycleHooksDir == "" {
+		return nil
+	}
+	eventDir := filepath.Join(hooks.engine.cfg.LifecycleHooksDir, event)
+	files, err := ioutil.ReadDir(eventDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Unable to list lifecycle hooks", logger.Fields{
+				field.Event: event,
+				field.Error: err,
+			})
+		}
+		return nil
+	}
+	var paths []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		// Windows has no executable permission, the hooks are run based on their extension
+		if runtime.GOOS != "windows" && file.Mode()&0111 == 0 {
+			continue
+		}
+		paths = append(paths, filepath.Join(eventDir, file.Name()))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (hooks *lifecycleHooks) run(ctx context.Context, event string, task *apitask.Task, container *apicontainer.Container) {
+	paths := hooks.list(event)
+	if len(paths) == 0 {
+		return
+	}
+
+	document := hookDocument{
+		Event:         event,
+		Cluster:       hooks.engine.cfg.Cluster,
+		TaskARN:       task.Arn,
+		Family:        task.Family,
+		Revision:      task.Version,
+		ContainerName: container.Name,
+		Image:         container.Image,
+		DockerID:      container.GetRuntimeID(),
+		ExitCode:      container.GetKnownExitCode(),
+	}
+	input, err := json.Marshal(document)
+	if err != nil {
+		return
+	}
+	env := append(hookBaseEnv(),
+		"ECS_HOOK_EVENT="+event,
+		"ECS_CLUSTER="+hooks.engine.cfg.Cluster,
+		"ECS_TASK_ARN="+task.Arn,
+		"ECS_TASK_FAMILY="+task.Family,
+		"ECS_TASK_REVISION="+task.Version,
+		"ECS_CONTAINER_NAME="+container.Name,
+		"ECS_CONTAINER_IMAGE="+container.Image,
+		"ECS_CONTAINER_DOCKER_ID="+document.DockerID,
+	)
+	if document.ExitCode != nil {
+		env = append(env, "ECS_CONTAINER_EXIT_CODE="+strconv.Itoa(*document.ExitCode))
+	}
+
+	for i, path := range paths {
+		if ctx.Err() != nil {
+			logger.Warn("Lifecycle hooks timed out, skipping the remaining hooks", logger.Fields{
+				field.TaskARN:   task.Arn,
+				field.Container: container.Name,
+				field.Event:     event,
+				"skipped":       strings.Join(paths[i:], ","),
+			})
+			return
+		}
+		hooks.runHook(ctx, path, event, task, container, env, input)
+	}
+}
+
+// hookBaseEnv returns the variables of the environment of the agent in the allowlist
+func hookBaseEnv() []string {
+	var env []string
+	for _, name := range hookEnvAllowlist {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// runHook runs a hook, killing it along with the processes it started once the hook
+// timeout expires or the context is done. A failed hook is logged, it doesn't fail the
+// transition of the container.
+func (hooks *lifecycleHooks) runHook(ctx context.Context, path, event string, task *apitask.Task,
+	container *apicontainer.Container, env []string, input []byte) {
+	ctx, cancel := context.WithTimeout(ctx, hooks.engine.cfg.LifecycleHookTimeout)
+	defer cancel()
+
+	cmd := exec.Command(path)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.SysProcAttr = hookSysProcAttr()
+	begin := time.Now()
+	waited, err := waitHook(ctx, cmd)
+	fields := logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: container.Name,
+		field.Event:     event,
+		"hook":          path,
+		"duration":      time.Since(begin).String(),
+	}
+	if err == nil {
+		logger.Info("Ran lifecycle hook", fields)
+		return
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	fields[field.Error] = err
+	if waited {
+		// the output is only complete, and safe to read, once the hook was waited for
+		out := output.String()
+		if len(out) > maxHookOutputSize {
+			out = out[:maxHookOutputSize]
+		}
+		fields["output"] = strings.TrimSpace(out)
+	}
+	logger.Warn("Lifecycle hook failed", fields)
+}
+
+// waitHook starts a hook and waits for it to exit, killing it once the context is done.
+// It returns false when the hook was killed but its output remained open for longer
+// than hookWaitDelay, in which case it isn't waited for, and the error of the hook.
+func waitHook(ctx context.Context, cmd *exec.Cmd) (bool, error) {
+	if err := cmd.Start(); err != nil {
+		return true, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return true, err
+	case <-ctx.Done():
+	}
+	killHook(cmd)
+	select {
+	case err := <-done:
+		return true, err
+	case <-time.After(hookWaitDelay):
+		return false, ctx.Err()
+	}
+}
@@ -0,0 +1,198 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLifecycleHooks(t *testing.T, timeout time.Duration) (*lifecycleHooks, string) {
+	hooksDir := t.TempDir()
+	cfg := &config.Config{
+		Cluster:              "test-cluster",
+		LifecycleHooksDir:    hooksDir,
+		LifecycleHookTimeout: timeout,
+	}
+	return newLifecycleHooks(&DockerTaskEngine{ctx: context.TODO(), cfg: cfg}), hooksDir
+}
+
+func writeHook(t *testing.T, hooksDir, event, name, script string, mode os.FileMode) {
+	require.NoError(t, os.MkdirAll(filepath.Join(hooksDir, event), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hooksDir, event, name), []byte("#!/bin/sh\n"+script), mode))
+}
+
+func testHookTask() (*apitask.Task, *apicontainer.Container) {
+	container := &apicontainer.Container{
+		Name:  "web",
+		Image: "nginx:latest",
+	}
+	return &apitask.Task{
+		Arn:        "arn:aws:ecs:us-west-2:1234567890:task/test-cluster/abc",
+		Family:     "web",
+		Version:    "3",
+		Containers: []*apicontainer.Container{container},
+	}, container
+}
+
+func TestLifecycleHooksRunBefore(t *testing.T) {
+	hooks, hooksDir := newTestLifecycleHooks(t, 10*time.Second)
+	outDir := t.TempDir()
+	writeHook(t, hooksDir, hookEventPrePull, "20-document", "cat > "+outDir+"/document\n", 0755)
+	writeHook(t, hooksDir, hookEventPrePull, "10-env",
+		"echo \"$ECS_HOOK_EVENT $ECS_TASK_FAMILY:$ECS_TASK_REVISION $ECS_CONTAINER_NAME\" > "+outDir+"/env\n", 0755)
+	writeHook(t, hooksDir, hookEventPrePull, "30-not-executable", "touch "+outDir+"/not-executable\n", 0644)
+	writeHook(t, hooksDir, hookEventPrePull, "40-failing", "echo failing; exit 1\n", 0755)
+	writeHook(t, hooksDir, hookEventPreStop, "10-pre-stop", "touch "+outDir+"/pre-stop\n", 0755)
+
+	task, container := testHookTask()
+	hooks.runBefore(hookEventPrePull, task, container, 0)
+
+	env, err := ioutil.ReadFile(filepath.Join(outDir, "env"))
+	require.NoError(t, err)
+	assert.Equal(t, "pre-pull web:3 web", strings.TrimSpace(string(env)))
+
+	data, err := ioutil.ReadFile(filepath.Join(outDir, "document"))
+	require.NoError(t, err)
+	var document hookDocument
+	require.NoError(t, json.Unmarshal(data, &document))
+	assert.Equal(t, hookDocument{
+		Event:         hookEventPrePull,
+		Cluster:       "test-cluster",
+		TaskARN:       task.Arn,
+		Family:        "web",
+		Revision:      "3",
+		ContainerName: "web",
+		Image:         "nginx:latest",
+	}, document)
+
+	_, err = os.Stat(filepath.Join(outDir, "not-executable"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(outDir, "pre-stop"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLifecycleHooksEnvAllowlist(t *testing.T) {
+	defer setTestEnv(t, "AWS_SECRET_ACCESS_KEY", "secret")()
+	defer setTestEnv(t, "TZ", "UTC")()
+	hooks, hooksDir := newTestLifecycleHooks(t, 10*time.Second)
+	outDir := t.TempDir()
+	writeHook(t, hooksDir, hookEventPrePull, "10-env", "env > "+outDir+"/env\n", 0755)
+
+	task, container := testHookTask()
+	hooks.runBefore(hookEventPrePull, task, container, 0)
+
+	env, err := ioutil.ReadFile(filepath.Join(outDir, "env"))
+	require.NoError(t, err)
+	assert.Contains(t, string(env), "TZ=UTC\n")
+	assert.Contains(t, string(env), "ECS_TASK_ARN="+task.Arn+"\n")
+	assert.NotContains(t, string(env), "AWS_SECRET_ACCESS_KEY")
+}
+
+func setTestEnv(t *testing.T, name, value string) func() {
+	previous, ok := os.LookupEnv(name)
+	require.NoError(t, os.Setenv(name, value))
+	return func() {
+		if ok {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestLifecycleHooksTimeout(t *testing.T) {
+	hooks, hooksDir := newTestLifecycleHooks(t, 100*time.Millisecond)
+	writeHook(t, hooksDir, hookEventPreStop, "10-slow", "exec sleep 10\n", 0755)
+
+	task, container := testHookTask()
+	begin := time.Now()
+	hooks.runBefore(hookEventPreStop, task, container, 0)
+	assert.True(t, time.Since(begin) < 5*time.Second)
+}
+
+func TestLifecycleHooksTimeoutKillsProcessGroup(t *testing.T) {
+	hooks, hooksDir := newTestLifecycleHooks(t, 100*time.Millisecond)
+	// the background process keeps the output of the hook open unless it's killed too
+	writeHook(t, hooksDir, hookEventPreStop, "10-background", "sleep 10 &\nexec sleep 10\n", 0755)
+
+	task, container := testHookTask()
+	begin := time.Now()
+	hooks.runBefore(hookEventPreStop, task, container, 0)
+	assert.True(t, time.Since(begin) < hookWaitDelay)
+}
+
+func TestLifecycleHooksRunBeforeTimeout(t *testing.T) {
+	hooks, hooksDir := newTestLifecycleHooks(t, 10*time.Second)
+	outDir := t.TempDir()
+	writeHook(t, hooksDir, hookEventPreStop, "10-slow", "exec sleep 10\n", 0755)
+	writeHook(t, hooksDir, hookEventPreStop, "20-next", "touch "+outDir+"/next\n", 0755)
+
+	task, container := testHookTask()
+	begin := time.Now()
+	hooks.runBefore(hookEventPreStop, task, container, 100*time.Millisecond)
+	assert.True(t, time.Since(begin) < 5*time.Second, "the hooks should be bounded by the timeout of the transition")
+	_, err := os.Stat(filepath.Join(outDir, "next"))
+	assert.True(t, os.IsNotExist(err), "the hooks after the timeout expired shouldn't run")
+}
+
+func TestLifecycleHooksRunAfterTransition(t *testing.T) {
+	hooks, hooksDir := newTestLifecycleHooks(t, 10*time.Second)
+	outDir := t.TempDir()
+	writeHook(t, hooksDir, hookEventPostStop, "10-exit-code",
+		"echo \"$ECS_CONTAINER_DOCKER_ID $ECS_CONTAINER_EXIT_CODE\" > "+outDir+"/tmp && mv "+outDir+"/tmp "+outDir+"/post-stop\n", 0755)
+
+	task, container := testHookTask()
+	// Containers that were never created don't run the hooks.
+	container.SetKnownStatus(apicontainerstatus.ContainerStopped)
+	hooks.runAfterTransition(task, container)
+
+	container.SetRuntimeID("docker-id")
+	exitCode := 137
+	container.SetKnownExitCode(&exitCode)
+	hooks.runAfterTransition(task, container)
+
+	var output []byte
+	for i := 0; i < 100; i++ {
+		var err error
+		if output, err = ioutil.ReadFile(filepath.Join(outDir, "post-stop")); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, "docker-id 137", strings.TrimSpace(string(output)))
+}
+
+func TestLifecycleHooksNil(t *testing.T) {
+	var hooks *lifecycleHooks
+	task, container := testHookTask()
+	hooks.runBefore(hookEventPrePull, task, container, 0)
+	hooks.runAfterTransition(task, container)
+}
@@ -0,0 +1,32 @@
+// +build windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hookSysProcAttr returns no attributes, Windows has no process groups to kill the
+// processes started by a hook with
+func hookSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// killHook kills the process of a hook
+func killHook(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
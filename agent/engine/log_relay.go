@@ -0,0 +1,61 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/logrelay"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+// applyAWSLogsRelay switches a container using the awslogs log driver to the local log
+// driver when the awslogs relay is enabled, so that its logs are relayed to CloudWatch
+// Logs by the agent once it's running. Containers using awslogs options the relay doesn't
+// support keep the awslogs log driver.
+func (engine *DockerTaskEngine) applyAWSLogsRelay(container *apicontainer.Container,
+	hostConfig *dockercontainer.HostConfig) {
+	if !engine.cfg.AWSLogsRelayEnabled.Enabled() ||
+		hostConfig.LogConfig.Type != string(dockerclient.AWSLogsDriver) {
+		return
+	}
+	if option, ok := logrelay.UnsupportedOption(hostConfig.LogConfig.Config); ok {
+		logger.Info("Not relaying awslogs logs of container, the relay doesn't support one of its options", logger.Fields{
+			field.Container: container.Name,
+			"option":        option,
+		})
+		return
+	}
+	container.SetAWSLogsRelayOptions(logrelay.RelayOptions(hostConfig.LogConfig.Config))
+	hostConfig.LogConfig = dockercontainer.LogConfig{
+		Type:   string(dockerclient.LocalDriver),
+		Config: logrelay.LocalDriverOptions(hostConfig.LogConfig.Config),
+	}
+	logger.Debug("Relaying awslogs logs of container through the local log driver", logger.Fields{
+		field.Container: container.Name,
+	})
+}
+
+// startLogRelay starts relaying the logs of a running container whose awslogs logs are
+// relayed by the agent, unless they're already relayed
+func (mtask *managedTask) startLogRelay(container *apicontainer.Container) {
+	options := container.GetAWSLogsRelayOptions()
+	if options == nil || container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+		return
+	}
+	mtask.engine.logRelay.Start(container.GetRuntimeID(), options, mtask.GetExecutionCredentialsID())
+}
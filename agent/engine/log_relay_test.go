@@ -0,0 +1,86 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyAWSLogsRelay(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSLogsRelayEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	engine := &DockerTaskEngine{cfg: &cfg}
+	container := &apicontainer.Container{Name: "app"}
+	hostConfig := &dockercontainer.HostConfig{
+		LogConfig: dockercontainer.LogConfig{
+			Type: "awslogs",
+			Config: map[string]string{
+				"awslogs-group":  "group",
+				"awslogs-region": "us-west-2",
+				"mode":           "non-blocking",
+			},
+		},
+	}
+
+	engine.applyAWSLogsRelay(container, hostConfig)
+	assert.Equal(t, "local", hostConfig.LogConfig.Type)
+	assert.Equal(t, map[string]string{"mode": "non-blocking"}, hostConfig.LogConfig.Config)
+	assert.Equal(t, map[string]string{
+		"awslogs-group":  "group",
+		"awslogs-region": "us-west-2",
+	}, container.GetAWSLogsRelayOptions())
+}
+
+func TestApplyAWSLogsRelayDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	engine := &DockerTaskEngine{cfg: &cfg}
+	container := &apicontainer.Container{Name: "app"}
+	hostConfig := &dockercontainer.HostConfig{
+		LogConfig: dockercontainer.LogConfig{
+			Type:   "awslogs",
+			Config: map[string]string{"awslogs-group": "group"},
+		},
+	}
+
+	engine.applyAWSLogsRelay(container, hostConfig)
+	assert.Equal(t, "awslogs", hostConfig.LogConfig.Type)
+	assert.Nil(t, container.GetAWSLogsRelayOptions())
+}
+
+func TestApplyAWSLogsRelayUnsupportedOption(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSLogsRelayEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	engine := &DockerTaskEngine{cfg: &cfg}
+	container := &apicontainer.Container{Name: "app"}
+	hostConfig := &dockercontainer.HostConfig{
+		LogConfig: dockercontainer.LogConfig{
+			Type: "awslogs",
+			Config: map[string]string{
+				"awslogs-group":             "group",
+				"awslogs-multiline-pattern": "^INFO",
+			},
+		},
+	}
+
+	engine.applyAWSLogsRelay(container, hostConfig)
+	assert.Equal(t, "awslogs", hostConfig.LogConfig.Type, "containers grouping lines into events keep the awslogs driver")
+	assert.Nil(t, container.GetAWSLogsRelayOptions())
+}
@@ -0,0 +1,210 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/mempressure"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+)
+
+// memoryPressureEvictionCooldown is how long after stopping a container of a task under
+// memory pressure the agent waits before stopping another one, as the memory of the
+// stopped container takes time to be freed, and the stall average to decay
+const memoryPressureEvictionCooldown = 30 * time.Second
+
+// readMemoryPressure returns the memory pressure of the cgroup at the path. It's
+// swappable for testing
+var readMemoryPressure = mempressure.Read
+
+// memoryPressureMonitor keeps track of the last container evicted from each task under
+// memory pressure
+type memoryPressureMonitor struct {
+	lock         sync.Mutex
+	lastEviction map[string]time.Time
+	// swappable for testing
+	evictionCooldown time.Duration
+}
+
+func newMemoryPressureMonitor() *memoryPressureMonitor {
+	return &memoryPressureMonitor{
+		lastEviction:     make(map[string]time.Time),
+		evictionCooldown: memoryPressureEvictionCooldown,
+	}
+}
+
+// checkMemoryPressure checks the memory pressure of the running tasks that have a task
+// cgroup, and acts on the tasks under pressure according to the memory pressure policy
+func (engine *DockerTaskEngine) checkMemoryPressure(ctx context.Context) {
+	var tasks []*apitask.Task
+	engine.tasksLock.RLock()
+	for _, mTask := range engine.managedTasks {
+		if mTask.MemoryCPULimitsEnabled && mTask.GetKnownStatus() == apitaskstatus.TaskRunning &&
+			!mTask.GetDesiredStatus().Terminal() {
+			tasks = append(tasks, mTask.Task)
+		}
+	}
+	engine.tasksLock.RUnlock()
+
+	active := make(map[string]bool)
+	for _, task := range tasks {
+		active[task.Arn] = true
+		reading, err := taskMemoryPressure(task)
+		if err != nil {
+			logger.Debug("Unable to read the memory pressure of task", logger.Fields{
+				field.TaskARN: task.Arn,
+				field.Error:   err,
+			})
+			continue
+		}
+		engine.handleMemoryPressure(ctx, task, reading)
+	}
+	engine.memoryPressureMonitor.forget(active)
+}
+
+// handleMemoryPressure records whether the memory of the task is under pressure, and
+// evicts one of its containers if the policy says so
+func (engine *DockerTaskEngine) handleMemoryPressure(ctx context.Context, task *apitask.Task, reading mempressure.Reading) {
+	thresholds := mempressure.Thresholds{
+		StallPercent: engine.cfg.MemoryPressureStallThreshold,
+		UsagePercent: engine.cfg.MemoryPressureUsageThreshold,
+	}
+	pressure := task.GetMemoryPressure()
+	fields := logger.Fields{
+		field.TaskARN:  task.Arn,
+		"usageBytes":   reading.UsageBytes,
+		"limitBytes":   reading.LimitBytes,
+		"stallPercent": reading.StallPercent,
+	}
+	if !reading.Exceeds(thresholds) {
+		if pressure != nil && !pressure.Relieved() {
+			logger.Info("Task memory is no longer under pressure", fields)
+			pressure.RelievedAt = aws.Time(time.Now())
+			task.SetMemoryPressure(*pressure)
+			engine.saveTaskData(task)
+		}
+		return
+	}
+
+	if pressure == nil || pressure.Relieved() {
+		logger.Warn("Task memory is under pressure", fields)
+		pressure = &apitask.MemoryPressure{DetectedAt: time.Now()}
+	}
+	pressure.StallPercent = nil
+	if reading.StallKnown {
+		pressure.StallPercent = aws.Float64(reading.StallPercent)
+	}
+	pressure.UsageBytes = reading.UsageBytes
+	pressure.LimitBytes = reading.LimitBytes
+
+	if engine.cfg.MemoryPressurePolicy == config.MemoryPressurePolicyEvict &&
+		engine.memoryPressureMonitor.canEvict(task.Arn) {
+		if container := memoryPressureEvictionCandidate(task); container != nil {
+			engine.memoryPressureMonitor.recordEviction(task.Arn)
+			pressure.EvictedContainers = append(pressure.EvictedContainers, container.Name)
+			engine.evictContainer(ctx, task, container, reading)
+		}
+	}
+	task.SetMemoryPressure(*pressure)
+	engine.saveTaskData(task)
+}
+
+// memoryPressureEvictionCandidate returns the running non-essential container of the task
+// with the lowest eviction priority, the last one in the task definition on ties, or nil
+// if the task has none
+func memoryPressureEvictionCandidate(task *apitask.Task) *apicontainer.Container {
+	var candidate *apicontainer.Container
+	for _, container := range task.Containers {
+		if container.IsEssential() || container.IsInternal() ||
+			container.GetKnownStatus() != apicontainerstatus.ContainerRunning ||
+			container.GetDesiredStatus().Terminal() {
+			continue
+		}
+		if candidate == nil || container.EvictionPriority <= candidate.EvictionPriority {
+			candidate = container
+		}
+	}
+	return candidate
+}
+
+// evictContainer stops a container of a task under memory pressure. The reason is
+// recorded on the container, so that its exit isn't mistaken for an OOM kill
+func (engine *DockerTaskEngine) evictContainer(ctx context.Context, task *apitask.Task,
+	container *apicontainer.Container, reading mempressure.Reading) {
+	msg := fmt.Sprintf("stopped to relieve the memory pressure of the task: %d bytes used", reading.UsageBytes)
+	if reading.LimitBytes > 0 {
+		msg += fmt.Sprintf(" of %d", reading.LimitBytes)
+	}
+	if reading.StallKnown {
+		msg += fmt.Sprintf(", stalled on memory %.2f%% of the time", reading.StallPercent)
+	}
+	logger.Warn("Stopping container to relieve the memory pressure of its task", logger.Fields{
+		field.TaskARN:      task.Arn,
+		field.Container:    container.Name,
+		"evictionPriority": container.EvictionPriority,
+	})
+	container.ApplyingError = apierrors.NewNamedError(MemoryPressureEvictionError{errors.New(msg)})
+	// the desired status keeps the container from being restarted per its restart policy
+	container.SetDesiredStatus(apicontainerstatus.ContainerStopped)
+	engine.saveContainerData(container)
+
+	go func() {
+		metadata := engine.stopContainer(task, container)
+		if metadata.Error != nil {
+			logger.Error("Unable to stop container to relieve the memory pressure of its task", logger.Fields{
+				field.TaskARN:   task.Arn,
+				field.Container: container.Name,
+				field.Error:     metadata.Error,
+			})
+		}
+	}()
+}
+
+// canEvict returns true if no container of the task was evicted within the cooldown
+func (monitor *memoryPressureMonitor) canEvict(taskARN string) bool {
+	monitor.lock.Lock()
+	defer monitor.lock.Unlock()
+	last, ok := monitor.lastEviction[taskARN]
+	return !ok || time.Since(last) >= monitor.evictionCooldown
+}
+
+func (monitor *memoryPressureMonitor) recordEviction(taskARN string) {
+	monitor.lock.Lock()
+	defer monitor.lock.Unlock()
+	monitor.lastEviction[taskARN] = time.Now()
+}
+
+// forget drops the evictions of the tasks no longer checked
+func (monitor *memoryPressureMonitor) forget(active map[string]bool) {
+	monitor.lock.Lock()
+	defer monitor.lock.Unlock()
+	for taskARN := range monitor.lastEviction {
+		if !active[taskARN] {
+			delete(monitor.lastEviction, taskARN)
+		}
+	}
+}
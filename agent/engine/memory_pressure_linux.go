@@ -0,0 +1,61 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/mempressure"
+)
+
+// startPeriodicMemoryPressureChecks checks the memory pressure of the tasks at the
+// configured interval when a memory pressure policy is set, until the context is done
+func (engine *DockerTaskEngine) startPeriodicMemoryPressureChecks(ctx context.Context) {
+	if engine.cfg.MemoryPressurePolicy == config.MemoryPressurePolicyNone {
+		return
+	}
+	reloaded := engine.configReloadedNotification()
+	interval := engine.memoryPressureCheckInterval()
+	ticker := time.NewTicker(interval)
+	defer func() { ticker.Stop() }()
+	for {
+		select {
+		case <-ticker.C:
+			engine.checkMemoryPressure(ctx)
+		case <-reloaded:
+			reloaded = engine.configReloadedNotification()
+			if next := engine.memoryPressureCheckInterval(); next > 0 && next != interval {
+				interval = next
+				ticker.Stop()
+				ticker = time.NewTicker(interval)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// taskMemoryPressure returns the memory pressure of the cgroup of the task
+func taskMemoryPressure(task *apitask.Task) (mempressure.Reading, error) {
+	cgroupRoot, err := task.BuildCgroupRoot()
+	if err != nil {
+		return mempressure.Reading{}, err
+	}
+	return readMemoryPressure(cgroupRoot)
+}
@@ -0,0 +1,166 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/mempressure"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const memoryPressureTestTaskARN = "arn:aws:ecs:us-west-2:1234567890:task/mycluster/task1"
+
+func newMemoryPressureTestTask() *apitask.Task {
+	newContainer := func(name string, essential bool, priority int64) *apicontainer.Container {
+		container := &apicontainer.Container{
+			Name:                name,
+			Essential:           essential,
+			EvictionPriority:    priority,
+			KnownStatusUnsafe:   apicontainerstatus.ContainerRunning,
+			DesiredStatusUnsafe: apicontainerstatus.ContainerRunning,
+		}
+		container.SetRuntimeID(name + "-id")
+		return container
+	}
+	return &apitask.Task{
+		Arn: memoryPressureTestTaskARN,
+		Containers: []*apicontainer.Container{
+			newContainer("app", true, 0),
+			newContainer("cache", false, 1),
+			newContainer("logs", false, 10),
+			newContainer("debug", false, 1),
+		},
+		KnownStatusUnsafe:      apitaskstatus.TaskRunning,
+		DesiredStatusUnsafe:    apitaskstatus.TaskRunning,
+		MemoryCPULimitsEnabled: true,
+	}
+}
+
+// setupMemoryPressureTest returns a task engine with the memory pressure policy checking
+// a running task, whose memory pressure readings are returned in order
+func setupMemoryPressureTest(t *testing.T, ctx context.Context, policy config.MemoryPressurePolicyType,
+	readings []mempressure.Reading) (*gomock.Controller, *mock_dockerapi.MockDockerClient, *DockerTaskEngine, *apitask.Task, func()) {
+	cfg := config.DefaultConfig()
+	cfg.MemoryPressurePolicy = policy
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	task := newMemoryPressureTestTask()
+	dockerTaskEngine.managedTasks[task.Arn] = &managedTask{Task: task}
+	readMemoryPressure = func(cgroupPath string) (mempressure.Reading, error) {
+		assert.Equal(t, "/ecs/task1", cgroupPath)
+		require.NotEmpty(t, readings, "unexpected memory pressure reading")
+		reading := readings[0]
+		readings = readings[1:]
+		return reading, nil
+	}
+	return ctrl, client, dockerTaskEngine, task, func() {
+		readMemoryPressure = mempressure.Read
+	}
+}
+
+var (
+	stalledMemoryReading  = mempressure.Reading{StallKnown: true, StallPercent: 75, UsageBytes: 900, LimitBytes: 1000}
+	relievedMemoryReading = mempressure.Reading{StallKnown: true, StallPercent: 1.5, UsageBytes: 500, LimitBytes: 1000}
+)
+
+func TestCheckMemoryPressureReports(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, taskEngine, task, cleanup := setupMemoryPressureTest(t, ctx, config.MemoryPressurePolicyReport,
+		[]mempressure.Reading{stalledMemoryReading, relievedMemoryReading})
+	defer ctrl.Finish()
+	defer cleanup()
+
+	taskEngine.checkMemoryPressure(ctx)
+	pressure := task.GetMemoryPressure()
+	require.NotNil(t, pressure)
+	assert.False(t, pressure.Relieved())
+	require.NotNil(t, pressure.StallPercent)
+	assert.Equal(t, 75.0, *pressure.StallPercent)
+	assert.Equal(t, uint64(900), pressure.UsageBytes)
+	assert.Equal(t, uint64(1000), pressure.LimitBytes)
+	assert.Empty(t, pressure.EvictedContainers, "Containers should only be evicted by the evict policy")
+
+	taskEngine.checkMemoryPressure(ctx)
+	relieved := task.GetMemoryPressure()
+	require.NotNil(t, relieved)
+	assert.True(t, relieved.Relieved())
+	assert.Equal(t, pressure.DetectedAt, relieved.DetectedAt)
+	for _, container := range task.Containers {
+		assert.Equal(t, apicontainerstatus.ContainerRunning, container.GetDesiredStatus())
+	}
+}
+
+func TestCheckMemoryPressureEvictsLowestPriorityContainer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, taskEngine, task, cleanup := setupMemoryPressureTest(t, ctx, config.MemoryPressurePolicyEvict,
+		[]mempressure.Reading{stalledMemoryReading, stalledMemoryReading})
+	defer ctrl.Finish()
+	defer cleanup()
+
+	stopped := make(chan struct{})
+	client.EXPECT().
+		StopContainer(gomock.Any(), "debug-id", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, dockerID string, timeout time.Duration) dockerapi.DockerContainerMetadata {
+			close(stopped)
+			return dockerapi.DockerContainerMetadata{}
+		})
+
+	taskEngine.checkMemoryPressure(ctx)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the container wasn't stopped")
+	}
+	evicted := task.Containers[3]
+	assert.Equal(t, apicontainerstatus.ContainerStopped, evicted.GetDesiredStatus())
+	require.NotNil(t, evicted.ApplyingError)
+	assert.Equal(t, "MemoryPressureEvictionError", evicted.ApplyingError.ErrorName())
+	assert.Equal(t, []string{"debug"}, task.GetMemoryPressure().EvictedContainers)
+
+	// no other container is evicted until the memory of the stopped one is freed
+	taskEngine.checkMemoryPressure(ctx)
+	assert.Equal(t, []string{"debug"}, task.GetMemoryPressure().EvictedContainers)
+	assert.Equal(t, apicontainerstatus.ContainerRunning, task.Containers[1].GetDesiredStatus())
+}
+
+func TestMemoryPressureEvictionCandidate(t *testing.T) {
+	task := newMemoryPressureTestTask()
+	assert.Equal(t, "debug", memoryPressureEvictionCandidate(task).Name)
+
+	task.Containers[3].SetKnownStatus(apicontainerstatus.ContainerStopped)
+	assert.Equal(t, "cache", memoryPressureEvictionCandidate(task).Name)
+
+	task.Containers[1].SetDesiredStatus(apicontainerstatus.ContainerStopped)
+	assert.Equal(t, "logs", memoryPressureEvictionCandidate(task).Name)
+
+	task.Containers[2].Essential = true
+	assert.Nil(t, memoryPressureEvictionCandidate(task), "Essential containers should never be evicted")
+}
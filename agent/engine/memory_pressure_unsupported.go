@@ -0,0 +1,33 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/mempressure"
+	"github.com/pkg/errors"
+)
+
+// startPeriodicMemoryPressureChecks is a no-op, as the tasks only have a cgroup on Linux
+func (engine *DockerTaskEngine) startPeriodicMemoryPressureChecks(ctx context.Context) {
+}
+
+// taskMemoryPressure is only supported on Linux
+func taskMemoryPressure(task *apitask.Task) (mempressure.Reading, error) {
+	return mempressure.Reading{}, errors.New("memory pressure of the tasks is not supported on this platform")
+}
@@ -24,6 +24,7 @@ import (
 
 	container "github.com/aws/amazon-ecs-agent/agent/api/container"
 	task "github.com/aws/amazon-ecs-agent/agent/api/task"
+	config "github.com/aws/amazon-ecs-agent/agent/config"
 	data "github.com/aws/amazon-ecs-agent/agent/data"
 	image "github.com/aws/amazon-ecs-agent/agent/engine/image"
 	statechange "github.com/aws/amazon-ecs-agent/agent/statechange"
@@ -162,6 +163,18 @@ func (mr *MockTaskEngineMockRecorder) MustInit(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MustInit", reflect.TypeOf((*MockTaskEngine)(nil).MustInit), arg0)
 }
 
+// ReloadConfig mocks base method
+func (m *MockTaskEngine) ReloadConfig(arg0 config.ReloadableConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReloadConfig", arg0)
+}
+
+// ReloadConfig indicates an expected call of ReloadConfig
+func (mr *MockTaskEngineMockRecorder) ReloadConfig(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadConfig", reflect.TypeOf((*MockTaskEngine)(nil).ReloadConfig), arg0)
+}
+
 // SaveState mocks base method
 func (m *MockTaskEngine) SaveState() error {
 	m.ctrl.T.Helper()
@@ -295,6 +308,21 @@ func (mr *MockImageManagerMockRecorder) RecordContainerReference(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordContainerReference", reflect.TypeOf((*MockImageManager)(nil).RecordContainerReference), arg0)
 }
 
+// RecordImage mocks base method
+func (m *MockImageManager) RecordImage(arg0 string) (*image.ImageState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordImage", arg0)
+	ret0, _ := ret[0].(*image.ImageState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordImage indicates an expected call of RecordImage
+func (mr *MockImageManagerMockRecorder) RecordImage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordImage", reflect.TypeOf((*MockImageManager)(nil).RecordImage), arg0)
+}
+
 // RemoveContainerReferenceFromImageState mocks base method
 func (m *MockImageManager) RemoveContainerReferenceFromImageState(arg0 *container.Container) error {
 	m.ctrl.T.Helper()
@@ -309,6 +337,18 @@ func (mr *MockImageManagerMockRecorder) RemoveContainerReferenceFromImageState(a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveContainerReferenceFromImageState", reflect.TypeOf((*MockImageManager)(nil).RemoveContainerReferenceFromImageState), arg0)
 }
 
+// SetCleanupConfig mocks base method
+func (m *MockImageManager) SetCleanupConfig(arg0 config.ReloadableConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCleanupConfig", arg0)
+}
+
+// SetCleanupConfig indicates an expected call of SetCleanupConfig
+func (mr *MockImageManagerMockRecorder) SetCleanupConfig(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCleanupConfig", reflect.TypeOf((*MockImageManager)(nil).SetCleanupConfig), arg0)
+}
+
 // SetDataClient mocks base method
 func (m *MockImageManager) SetDataClient(arg0 data.Client) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,103 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/neuron"
+	"github.com/cihub/seelog"
+)
+
+// neuronAssigner assigns the neuron cores the containers of a task require, on Inferentia
+// and Trainium instances. The cores of each container are recorded on it, so the cores
+// assigned to the tasks in the state are restored when the agent restarts
+type neuronAssigner struct {
+	engine  *DockerTaskEngine
+	manager neuron.Manager
+}
+
+func newNeuronAssigner(engine *DockerTaskEngine) *neuronAssigner {
+	return &neuronAssigner{
+		engine:  engine,
+		manager: neuronManager(engine.resourceFields),
+	}
+}
+
+// assign assigns free cores to the containers of a new task that require them
+func (assigner *neuronAssigner) assign(task *apitask.Task) error {
+	if assigner == nil || !requiresNeuronCores(task) || task.GetDesiredStatus().Terminal() {
+		return nil
+	}
+	if !assigner.engine.cfg.InferentiaSupportEnabled || assigner.manager == nil {
+		return NeuronAssignmentError{errors.New("neuron devices are not enabled on the container instance")}
+	}
+
+	for _, container := range task.Containers {
+		if container.NeuronCores <= 0 || len(container.GetNeuronCoreIDs()) > 0 {
+			continue
+		}
+		assignment, err := assigner.manager.Assign(task.Arn, container.NeuronCores)
+		if err != nil {
+			assigner.manager.Release(task.Arn)
+			return NeuronAssignmentError{err}
+		}
+		seelog.Infof("Task engine [%s]: assigned neuron cores %v to container [%s]",
+			task.Arn, assignment.CoreIDs, container.Name)
+		container.SetNeuronCores(assignment.CoreIDs, assignment.Devices, assignment.VisibleCores)
+	}
+	return nil
+}
+
+// restore reserves the cores assigned to the tasks before the agent restarted, that
+// haven't stopped yet
+func (assigner *neuronAssigner) restore(tasks []*apitask.Task) {
+	if assigner == nil || assigner.manager == nil {
+		return
+	}
+	for _, task := range tasks {
+		if task.GetKnownStatus().Terminal() {
+			continue
+		}
+		for _, container := range task.Containers {
+			coreIDs := container.GetNeuronCoreIDs()
+			if len(coreIDs) == 0 {
+				continue
+			}
+			if err := assigner.manager.Reserve(task.Arn, coreIDs); err != nil {
+				seelog.Errorf("Task engine [%s]: unable to restore the neuron cores of container [%s]: %v",
+					task.Arn, container.Name, err)
+			}
+		}
+	}
+}
+
+// release frees the cores assigned to the task, once it stopped
+func (assigner *neuronAssigner) release(task *apitask.Task) {
+	if assigner == nil || assigner.manager == nil {
+		return
+	}
+	assigner.manager.Release(task.Arn)
+}
+
+// requiresNeuronCores returns whether a container of the task requires neuron cores
+func requiresNeuronCores(task *apitask.Task) bool {
+	for _, container := range task.Containers {
+		if container.NeuronCores > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/neuron"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+)
+
+// neuronManager returns the manager of the Neuron devices of the instance
+func neuronManager(resourceFields *taskresource.ResourceFields) neuron.Manager {
+	if resourceFields == nil {
+		return nil
+	}
+	return resourceFields.NeuronManager
+}
@@ -0,0 +1,129 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/neuron"
+	mock_neuron "github.com/aws/amazon-ecs-agent/agent/neuron/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNeuronAssigner(manager neuron.Manager, enabled bool) *neuronAssigner {
+	cfg := config.DefaultConfig()
+	cfg.InferentiaSupportEnabled = enabled
+	return &neuronAssigner{
+		engine:  &DockerTaskEngine{cfg: &cfg},
+		manager: manager,
+	}
+}
+
+func neuronTask() *apitask.Task {
+	return &apitask.Task{
+		Arn: "task",
+		Containers: []*apicontainer.Container{
+			{Name: "c1", NeuronCores: 2},
+			{Name: "c2"},
+			{Name: "c3", NeuronCores: 1},
+		},
+	}
+}
+
+func TestNeuronAssignerAssign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	manager := mock_neuron.NewMockManager(ctrl)
+	assigner := newTestNeuronAssigner(manager, true)
+
+	gomock.InOrder(
+		manager.EXPECT().Assign("task", 2).Return(neuron.Assignment{
+			CoreIDs:      []int{2, 3},
+			Devices:      []string{"/dev/neuron1"},
+			VisibleCores: []int{0, 1},
+		}, nil),
+		manager.EXPECT().Assign("task", 1).Return(neuron.Assignment{
+			CoreIDs:      []int{4},
+			Devices:      []string{"/dev/neuron2"},
+			VisibleCores: []int{0},
+		}, nil),
+	)
+
+	task := neuronTask()
+	require.NoError(t, assigner.assign(task))
+	assert.Equal(t, []int{2, 3}, task.Containers[0].GetNeuronCoreIDs())
+	assert.Equal(t, []string{"/dev/neuron1"}, task.Containers[0].GetNeuronDevices())
+	assert.Equal(t, "0,1", task.Containers[0].Environment["NEURON_RT_VISIBLE_CORES"])
+	assert.Empty(t, task.Containers[1].GetNeuronCoreIDs())
+	assert.Equal(t, []int{4}, task.Containers[2].GetNeuronCoreIDs())
+}
+
+func TestNeuronAssignerAssignExhausted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	manager := mock_neuron.NewMockManager(ctrl)
+	assigner := newTestNeuronAssigner(manager, true)
+
+	gomock.InOrder(
+		manager.EXPECT().Assign("task", 2).Return(neuron.Assignment{CoreIDs: []int{0, 1}}, nil),
+		manager.EXPECT().Assign("task", 1).Return(neuron.Assignment{}, errors.New("no free core")),
+		// the cores assigned to the first container are freed
+		manager.EXPECT().Release("task"),
+	)
+
+	err := assigner.assign(neuronTask())
+	assert.IsType(t, NeuronAssignmentError{}, err)
+}
+
+func TestNeuronAssignerRejectsWhenDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	assigner := newTestNeuronAssigner(mock_neuron.NewMockManager(ctrl), false)
+
+	assert.IsType(t, NeuronAssignmentError{}, assigner.assign(neuronTask()))
+	// tasks without neuron cores aren't affected
+	assert.NoError(t, assigner.assign(&apitask.Task{Arn: "task", Containers: []*apicontainer.Container{{Name: "c"}}}))
+}
+
+func TestNeuronAssignerRestore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	manager := mock_neuron.NewMockManager(ctrl)
+	assigner := newTestNeuronAssigner(manager, true)
+
+	running := neuronTask()
+	running.SetKnownStatus(apitaskstatus.TaskRunning)
+	running.Containers[0].SetNeuronCores([]int{0, 1}, []string{"/dev/neuron0"}, []int{0, 1})
+	stopped := &apitask.Task{
+		Arn:        "stopped",
+		Containers: []*apicontainer.Container{{Name: "c", NeuronCores: 1}},
+	}
+	stopped.SetKnownStatus(apitaskstatus.TaskStopped)
+	stopped.Containers[0].SetNeuronCores([]int{2}, []string{"/dev/neuron1"}, []int{0})
+
+	manager.EXPECT().Reserve("task", []int{0, 1}).Return(nil)
+	assigner.restore([]*apitask.Task{running, stopped})
+
+	manager.EXPECT().Release("task")
+	assigner.release(running)
+}
@@ -0,0 +1,204 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"sync"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/numa"
+	"github.com/cihub/seelog"
+)
+
+// cpuUnitsPerVCPU is the number of cpu units of a vCPU
+const cpuUnitsPerVCPU = 1024
+
+// numaTopology returns the NUMA nodes of the instance. It's swappable for testing
+var numaTopology = numa.Topology
+
+// errNUMATaskQueued is returned for the tasks that wait for a NUMA node to have room
+// for them
+var errNUMATaskQueued = errors.New("no NUMA node has room for the task, it's queued until one does")
+
+// numaPlacer places the tasks that request it on a single NUMA node of the instance.
+// The node of a task is recorded on it, so the cpus and memory each node has available
+// are restored from the tasks in the state when the agent restarts. The tasks that no
+// node has room for are queued, rather than stopped, until a placed task stops
+type numaPlacer struct {
+	engine *DockerTaskEngine
+
+	allocator    *numa.Allocator
+	allocatorErr error
+	once         sync.Once
+
+	// queued are the tasks waiting for a node to have room for them, in order
+	queued     []*apitask.Task
+	queuedLock sync.Mutex
+}
+
+func newNUMAPlacer(engine *DockerTaskEngine) *numaPlacer {
+	return &numaPlacer{
+		engine: engine,
+	}
+}
+
+// getAllocator returns the allocator of the NUMA nodes, reading the topology of the
+// instance the first time
+func (placer *numaPlacer) getAllocator() (*numa.Allocator, error) {
+	placer.once.Do(func() {
+		nodes, err := numaTopology()
+		if err != nil {
+			placer.allocatorErr = err
+			return
+		}
+		placer.allocator = numa.NewAllocator(nodes)
+	})
+	return placer.allocator, placer.allocatorErr
+}
+
+// place places a new task on the NUMA node with room for it, if it requests it, and pins
+// its cpus and memory to it. A task that no node has room for is queued, and
+// errNUMATaskQueued is returned.
+func (placer *numaPlacer) place(task *apitask.Task) error {
+	if placer == nil || !task.RequiresNUMAPlacement() {
+		return nil
+	}
+	if task.GetDesiredStatus().Terminal() {
+		// the task is stopped before it got room on a node
+		placer.dequeue(task.Arn)
+		return nil
+	}
+	if _, placed := task.GetNUMANode(); placed {
+		return nil
+	}
+	cfg := placer.engine.cfg
+	if !cfg.NUMAPlacementEnabled.Enabled() || !cfg.TaskCPUMemLimit.Enabled() {
+		return NUMAPlacementError{errors.New("NUMA placement is not enabled on the container instance")}
+	}
+	if task.ResourceControls.CpusetCpus != "" || task.ResourceControls.CpusetMems != "" {
+		return NUMAPlacementError{errors.New("the cpusets of NUMA aligned tasks are chosen by the agent")}
+	}
+	allocator, err := placer.getAllocator()
+	if err != nil {
+		return NUMAPlacementError{err}
+	}
+
+	cpu, memoryMiB := numaRequirements(task)
+	node, err := allocator.Allocate(task.Arn, cpu, memoryMiB)
+	if _, exhausted := err.(*numa.ExhaustedError); exhausted {
+		placer.enqueue(task)
+		return errNUMATaskQueued
+	}
+	if err != nil {
+		return NUMAPlacementError{err}
+	}
+	seelog.Infof("Task engine [%s]: placed task on NUMA node %d (cpus %s)", task.Arn, node.ID, node.CPUs)
+	task.SetNUMANode(node.ID, node.CPUs)
+	return nil
+}
+
+// restore reserves the cpus and memory of the tasks placed on NUMA nodes before the agent
+// restarted, that haven't stopped yet
+func (placer *numaPlacer) restore(tasks []*apitask.Task) {
+	if placer == nil {
+		return
+	}
+	for _, task := range tasks {
+		nodeID, placed := task.GetNUMANode()
+		if !placed || task.GetKnownStatus().Terminal() {
+			continue
+		}
+		allocator, err := placer.getAllocator()
+		if err != nil {
+			seelog.Errorf("Task engine [%s]: unable to restore the NUMA placement of the task: %v", task.Arn, err)
+			return
+		}
+		cpu, memoryMiB := numaRequirements(task)
+		if err := allocator.Reserve(task.Arn, nodeID, cpu, memoryMiB); err != nil {
+			seelog.Errorf("Task engine [%s]: unable to restore the NUMA placement of the task: %v", task.Arn, err)
+		}
+	}
+}
+
+// release frees the cpus and memory of the NUMA node the task is placed on, once it
+// stopped
+func (placer *numaPlacer) release(task *apitask.Task) {
+	if placer == nil {
+		return
+	}
+	if _, placed := task.GetNUMANode(); !placed {
+		return
+	}
+	if allocator, err := placer.getAllocator(); err == nil {
+		allocator.Release(task.Arn)
+	}
+}
+
+// enqueue queues the task until a node has room for it. A task that is already queued
+// is replaced by its latest version
+func (placer *numaPlacer) enqueue(task *apitask.Task) {
+	placer.queuedLock.Lock()
+	defer placer.queuedLock.Unlock()
+	for i, queued := range placer.queued {
+		if queued.Arn == task.Arn {
+			placer.queued[i] = task
+			return
+		}
+	}
+	placer.queued = append(placer.queued, task)
+}
+
+func (placer *numaPlacer) dequeue(taskARN string) {
+	placer.queuedLock.Lock()
+	defer placer.queuedLock.Unlock()
+	for i, queued := range placer.queued {
+		if queued.Arn == taskARN {
+			placer.queued = append(placer.queued[:i], placer.queued[i+1:]...)
+			return
+		}
+	}
+}
+
+// takeQueued returns the queued tasks, in order, and empties the queue. The tasks that
+// still don't fit on a node are queued again when they are placed
+func (placer *numaPlacer) takeQueued() []*apitask.Task {
+	if placer == nil {
+		return nil
+	}
+	placer.queuedLock.Lock()
+	defer placer.queuedLock.Unlock()
+	queued := placer.queued
+	placer.queued = nil
+	return queued
+}
+
+// numaRequirements returns the vCPUs and the memory, in MiB, a task reserves on its NUMA
+// node: its task level limits, or else the sum of the limits of its containers
+func numaRequirements(task *apitask.Task) (float64, int64) {
+	cpu := task.CPU
+	memoryMiB := task.Memory
+	var containersCPU, containersMemory uint
+	for _, container := range task.Containers {
+		containersCPU += container.CPU
+		containersMemory += container.Memory
+	}
+	if cpu <= 0 {
+		cpu = float64(containersCPU) / cpuUnitsPerVCPU
+	}
+	if memoryMiB <= 0 {
+		memoryMiB = int64(containersMemory)
+	}
+	return cpu, memoryMiB
+}
@@ -0,0 +1,165 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/numa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNUMAPlacer(t *testing.T, enabled bool) *numaPlacer {
+	numaTopology = func() ([]numa.Node, error) {
+		return []numa.Node{
+			{ID: 0, CPUs: "0-3", CPUCount: 4, MemoryMiB: 4096},
+			{ID: 1, CPUs: "4-7", CPUCount: 4, MemoryMiB: 4096},
+		}, nil
+	}
+	t.Cleanup(func() { numaTopology = numa.Topology })
+
+	cfg := config.DefaultConfig()
+	cfg.TaskCPUMemLimit = config.BooleanDefaultTrue{Value: config.ExplicitlyEnabled}
+	if enabled {
+		cfg.NUMAPlacementEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	}
+	return newNUMAPlacer(&DockerTaskEngine{cfg: &cfg})
+}
+
+func numaAlignedTask(arn string, cpu float64, memoryMiB int64) *apitask.Task {
+	return &apitask.Task{
+		Arn:              arn,
+		CPU:              cpu,
+		Memory:           memoryMiB,
+		ResourceControls: &apitask.ResourceControls{NUMAAligned: true},
+	}
+}
+
+func TestNUMAPlacerPlace(t *testing.T) {
+	placer := newTestNUMAPlacer(t, true)
+
+	task1 := numaAlignedTask("task1", 3, 2048)
+	require.NoError(t, placer.place(task1))
+	node, placed := task1.GetNUMANode()
+	assert.True(t, placed)
+	assert.Equal(t, 0, node)
+	assert.Equal(t, "0-3", task1.ResourceControls.CpusetCpus)
+	assert.Equal(t, "0", task1.ResourceControls.CpusetMems)
+
+	task2 := numaAlignedTask("task2", 2, 2048)
+	require.NoError(t, placer.place(task2))
+	node, _ = task2.GetNUMANode()
+	assert.Equal(t, 1, node)
+
+	// no node has 3 vCPUs left
+	task3 := numaAlignedTask("task3", 3, 1024)
+	assert.Equal(t, errNUMATaskQueued, placer.place(task3))
+
+	placer.release(task1)
+	assert.Equal(t, []*apitask.Task{task3}, placer.takeQueued())
+	assert.Empty(t, placer.takeQueued())
+	require.NoError(t, placer.place(task3))
+}
+
+func TestNUMAPlacerQueue(t *testing.T) {
+	placer := newTestNUMAPlacer(t, true)
+	require.NoError(t, placer.place(numaAlignedTask("task1", 4, 1024)))
+	require.NoError(t, placer.place(numaAlignedTask("task2", 4, 1024)))
+
+	queued1 := numaAlignedTask("queued1", 1, 1024)
+	queued2 := numaAlignedTask("queued2", 1, 1024)
+	assert.Equal(t, errNUMATaskQueued, placer.place(queued1))
+	assert.Equal(t, errNUMATaskQueued, placer.place(queued2))
+	// the latest version of a queued task replaces it
+	queued1Update := numaAlignedTask("queued1", 1, 1024)
+	assert.Equal(t, errNUMATaskQueued, placer.place(queued1Update))
+
+	// a queued task that is stopped leaves the queue
+	stopped := numaAlignedTask("queued2", 1, 1024)
+	stopped.SetDesiredStatus(apitaskstatus.TaskStopped)
+	require.NoError(t, placer.place(stopped))
+
+	assert.Equal(t, []*apitask.Task{queued1Update}, placer.takeQueued())
+}
+
+func TestNUMAPlacerPlaceFromContainerLimits(t *testing.T) {
+	placer := newTestNUMAPlacer(t, true)
+
+	task := numaAlignedTask("task", 0, 0)
+	task.Containers = []*apicontainer.Container{
+		{Name: "c1", CPU: 2048, Memory: 1024},
+		{Name: "c2", CPU: 1024, Memory: 1024},
+	}
+	require.NoError(t, placer.place(task))
+
+	allocator, err := placer.getAllocator()
+	require.NoError(t, err)
+	availability := allocator.Availability()
+	assert.Equal(t, 1.0, availability[0].AvailableCPU)
+	assert.Equal(t, int64(2048), availability[0].AvailableMemoryMiB)
+}
+
+func TestNUMAPlacerRejects(t *testing.T) {
+	t.Run("placement disabled", func(t *testing.T) {
+		placer := newTestNUMAPlacer(t, false)
+		err := placer.place(numaAlignedTask("task", 1, 512))
+		assert.IsType(t, NUMAPlacementError{}, err)
+	})
+	t.Run("explicit cpusets", func(t *testing.T) {
+		placer := newTestNUMAPlacer(t, true)
+		task := numaAlignedTask("task", 1, 512)
+		task.ResourceControls.CpusetCpus = "0-1"
+		assert.IsType(t, NUMAPlacementError{}, placer.place(task))
+	})
+	t.Run("unknown topology", func(t *testing.T) {
+		placer := newTestNUMAPlacer(t, true)
+		numaTopology = func() ([]numa.Node, error) { return nil, errors.New("no topology") }
+		assert.IsType(t, NUMAPlacementError{}, placer.place(numaAlignedTask("task", 1, 512)))
+	})
+}
+
+func TestNUMAPlacerSkipsTasksNotAligned(t *testing.T) {
+	placer := newTestNUMAPlacer(t, false)
+
+	task := &apitask.Task{Arn: "task", CPU: 1, Memory: 512}
+	assert.NoError(t, placer.place(task))
+	_, placed := task.GetNUMANode()
+	assert.False(t, placed)
+}
+
+func TestNUMAPlacerRestore(t *testing.T) {
+	placer := newTestNUMAPlacer(t, true)
+
+	running := numaAlignedTask("running", 4, 1024)
+	running.SetNUMANode(1, "4-7")
+	running.SetKnownStatus(apitaskstatus.TaskRunning)
+	stopped := numaAlignedTask("stopped", 4, 1024)
+	stopped.SetNUMANode(0, "0-3")
+	stopped.SetKnownStatus(apitaskstatus.TaskStopped)
+	placer.restore([]*apitask.Task{running, stopped})
+
+	// only node 0 has room left
+	task := numaAlignedTask("task", 4, 1024)
+	require.NoError(t, placer.place(task))
+	node, _ := task.GetNUMANode()
+	assert.Equal(t, 0, node)
+}
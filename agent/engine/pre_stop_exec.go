@@ -0,0 +1,113 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/docker/docker/api/types"
+)
+
+// runPreStopExec runs the pre-stop command of the container inside it, like the preStop
+// exec hooks of Kubernetes, and waits for it to exit before the container is sent its
+// stop signals. The command is given its own timeout, capped by the stop timeout of
+// the container, or the stop timeout. A failed command is logged, the container is
+// stopped anyway.
+func (engine *DockerTaskEngine) runPreStopExec(dockerID string, container *apicontainer.Container,
+	stopTimeout time.Duration) {
+	preStopExec := container.GetPreStopExec()
+	if preStopExec == nil || len(preStopExec.Command) == 0 || container.IsInternal() || !container.IsRunning() {
+		return
+	}
+	timeout := preStopExecTimeout(preStopExec, stopTimeout)
+	fields := logger.Fields{
+		field.Container: container.Name,
+		field.RuntimeID: dockerID,
+	}
+
+	logger.Info(fmt.Sprintf("Running pre-stop command in container, waiting %v for it to exit", timeout), fields)
+	execRes, err := engine.client.CreateContainerExec(engine.ctx, dockerID, types.ExecConfig{
+		Cmd:    preStopExec.Command,
+		Detach: true,
+	}, dockerclient.ContainerExecCreateTimeout)
+	if err == nil {
+		err = engine.client.StartContainerExec(engine.ctx, execRes.ID, types.ExecStartCheck{Detach: true, Tty: false},
+			dockerclient.ContainerExecStartTimeout)
+	}
+	if err != nil {
+		fields[field.Error] = err
+		logger.Warn("Unable to run the pre-stop command in container", fields)
+		return
+	}
+
+	exitCode, exited := engine.waitForExecExit(execRes.ID, timeout)
+	switch {
+	case !exited:
+		logger.Warn("Pre-stop command of container did not exit in time", fields)
+	case exitCode != 0:
+		fields["exitCode"] = exitCode
+		logger.Warn("Pre-stop command of container failed", fields)
+	default:
+		logger.Info("Pre-stop command of container exited", fields)
+	}
+}
+
+// preStopExecTimeout returns how long the pre-stop command is waited for: its own timeout
+// when it has one, capped by the stop timeout, as the command uses up the time the
+// container is given to stop
+func preStopExecTimeout(preStopExec *apicontainer.PreStopExec, stopTimeout time.Duration) time.Duration {
+	timeout := time.Duration(preStopExec.TimeoutSeconds) * time.Second
+	if timeout <= 0 || timeout > stopTimeout {
+		return stopTimeout
+	}
+	return timeout
+}
+
+// remainingStopTimeout returns the part of the stop timeout left once the pre-stop hooks
+// and command ran, in whole seconds as docker stops containers with a timeout in seconds
+func remainingStopTimeout(stopTimeout, preStopElapsed time.Duration) time.Duration {
+	remaining := stopTimeout - preStopElapsed.Truncate(time.Second)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// waitForExecExit polls the status of the exec process until it exits or the wait
+// elapses, and returns its exit code and whether it exited.
+func (engine *DockerTaskEngine) waitForExecExit(execID string, wait time.Duration) (int, bool) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(engine.stopSignalPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			inspect, err := engine.client.InspectContainerExec(engine.ctx, execID,
+				dockerclient.ContainerExecInspectTimeout)
+			if err == nil && !inspect.Running {
+				return inspect.ExitCode, true
+			}
+		case <-timer.C:
+			return 0, false
+		case <-engine.ctx.Done():
+			return 0, false
+		}
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/aws-sdk-go/aws"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+const (
+	// secretFilesParentDirPerm keeps the directories of the tasks and containers private
+	// to the agent
+	secretFilesParentDirPerm = 0700
+	// secretFilesDirPerm and secretFilePerm let the user of the container, which owns
+	// the mounted directories and their files, and only that user, read the secrets
+	secretFilesDirPerm = 0500
+	secretFilePerm     = 0400
+	// secretFilesSharedDirPerm and secretFileSharedPerm let all the users of the
+	// container read the secrets, when its user is a name the agent can't resolve
+	secretFilesSharedDirPerm = 0555
+	secretFileSharedPerm     = 0444
+	secretTempFilePrefix     = ".tmp-"
+	// legacySecretFilesDir is the directory of the data directory the file secrets were
+	// written to by previous versions of the agent
+	legacySecretFilesDir = "secrets"
+)
+
+var (
+	// chown is swappable for testing
+	chown = os.Chown
+)
+
+// secretFilesOwner is the owner of the secret files of a container, and their modes
+type secretFilesOwner struct {
+	uid      int
+	gid      int
+	dirMode  os.FileMode
+	fileMode os.FileMode
+}
+
+// parseSecretFilesOwner returns the owner of the secret files of a container running as
+// the user, a uid or uid:gid. The files of users given by name are readable by all the
+// users of the container, as their uid is only known inside it.
+func parseSecretFilesOwner(user string) (secretFilesOwner, bool) {
+	owner := secretFilesOwner{dirMode: secretFilesDirPerm, fileMode: secretFilePerm}
+	if user == "" {
+		return owner, true
+	}
+	parts := strings.SplitN(user, ":", 2)
+	uid, err := strconv.Atoi(parts[0])
+	if err == nil && uid >= 0 {
+		owner.uid = uid
+		if len(parts) == 1 {
+			return owner, true
+		}
+		gid, err := strconv.Atoi(parts[1])
+		if err == nil && gid >= 0 {
+			owner.gid = gid
+			return owner, true
+		}
+	}
+	if parts[0] == "root" && (len(parts) == 1 || parts[1] == "root") {
+		return owner, true
+	}
+	return secretFilesOwner{dirMode: secretFilesSharedDirPerm, fileMode: secretFileSharedPerm}, false
+}
+
+// secretFilesOwner returns the owner of the secret files of the container, the user set
+// in its docker config or else in its image
+func (engine *DockerTaskEngine) secretFilesOwner(container *apicontainer.Container) secretFilesOwner {
+	var user string
+	if container.DockerConfig.Config != nil {
+		config := &dockercontainer.Config{}
+		if err := json.Unmarshal([]byte(aws.StringValue(container.DockerConfig.Config)), config); err == nil {
+			user = config.User
+		}
+	}
+	if user == "" {
+		if image, err := engine.client.InspectImage(container.Image); err == nil && image.Config != nil {
+			user = image.Config.User
+		}
+	}
+	owner, ok := parseSecretFilesOwner(user)
+	if !ok {
+		logger.Warn("The user of the container isn't numeric, its secret files are readable by all its users", logger.Fields{
+			field.Container: container.Name,
+			"user":          user,
+		})
+	}
+	return owner
+}
+
+// addSecretFiles writes the file secrets of the container to a tmpfs, and bind mounts
+// their directories read-only in the container. The directories are mounted rather than
+// the files, so that the container sees the files rewritten when the secrets are rotated.
+func (engine *DockerTaskEngine) addSecretFiles(task *apitask.Task, container *apicontainer.Container,
+	hostConfig *dockercontainer.HostConfig) apierrors.NamedError {
+	if _, err := engine.writeSecretFiles(task, container); err != nil {
+		return &apierrors.DockerClientConfigError{Msg: "unable to write secret files: " + err.Error()}
+	}
+	containerDir, err := secretFilesContainerDir(task, container)
+	if err != nil {
+		return &apierrors.DockerClientConfigError{Msg: err.Error()}
+	}
+	for i, dir := range secretFileDirs(container) {
+		hostConfig.Binds = append(hostConfig.Binds, filepath.Join(containerDir, strconv.Itoa(i))+":"+dir+":ro")
+	}
+	return nil
+}
+
+// secretFilesContainerDir returns the directory of the secret files of the container, on
+// the tmpfs shared by the host and the agent container
+func secretFilesContainerDir(task *apitask.Task, container *apicontainer.Container) (string, error) {
+	taskID, err := task.GetID()
+	if err != nil {
+		return "", err
+	}
+	root, err := secretFilesRootDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, taskID, container.Name), nil
+}
+
+// writeSecretFiles writes the values of the file secrets of the container from the
+// secrets resources of the task, and returns whether any of the files changed
+func (engine *DockerTaskEngine) writeSecretFiles(task *apitask.Task, container *apicontainer.Container) (bool, error) {
+	containerDir, err := secretFilesContainerDir(task, container)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(containerDir, secretFilesParentDirPerm); err != nil {
+		return false, err
+	}
+	owner := engine.secretFilesOwner(container)
+	dirIndexes := make(map[string]int)
+	for i, dir := range secretFileDirs(container) {
+		dirIndexes[dir] = i
+	}
+
+	changed := false
+	for _, secret := range container.Secrets {
+		if secret.Type != apicontainer.SecretTypeFile {
+			continue
+		}
+		if secret.Name == "" || secret.Name == "." || secret.Name == ".." || strings.ContainsAny(secret.Name, `/\`) ||
+			strings.HasPrefix(secret.Name, secretTempFilePrefix) {
+			return changed, errors.Errorf("secret name %q is not a valid file name", secret.Name)
+		}
+		value, ok := task.GetSecretValue(secret)
+		if !ok {
+			return changed, errors.Errorf("value of secret %s not found", secret.Name)
+		}
+		dir := filepath.Join(containerDir, strconv.Itoa(dirIndexes[secret.GetFileContainerPath()]))
+		written, err := writeSecretFile(dir, secret.Name, value, owner)
+		if err != nil {
+			return changed, errors.Wrapf(err, "secret %s", secret.Name)
+		}
+		changed = changed || written
+	}
+	return changed, nil
+}
+
+// secretFileDirs returns the directories of the container its file secrets are written
+// to, in order. The files of the n-th directory are written to the n-th directory of the
+// secret files directory of the container.
+func secretFileDirs(container *apicontainer.Container) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, secret := range container.Secrets {
+		dir := secret.GetFileContainerPath()
+		if secret.Type != apicontainer.SecretTypeFile || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// writeSecretFile replaces the file with the value of the secret, unless it already holds
+// it, and returns whether it was replaced. The file is renamed into place, so that the
+// container never reads a partially written secret.
+func writeSecretFile(dir, name, value string, owner secretFilesOwner) (bool, error) {
+	path := filepath.Join(dir, name)
+	if current, err := ioutil.ReadFile(path); err == nil && string(current) == value {
+		return false, nil
+	}
+	if err := os.Mkdir(dir, owner.dirMode); err != nil && !os.IsExist(err) {
+		return false, err
+	}
+	if err := chown(dir, owner.uid, owner.gid); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(dir, owner.dirMode); err != nil {
+		return false, err
+	}
+	temp, err := ioutil.TempFile(dir, secretTempFilePrefix)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	if _, err := temp.WriteString(value); err != nil {
+		return false, err
+	}
+	if err := temp.Chmod(owner.fileMode); err != nil {
+		return false, err
+	}
+	if err := chown(temp.Name(), owner.uid, owner.gid); err != nil {
+		return false, err
+	}
+	if err := temp.Sync(); err != nil {
+		return false, err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeSecretFiles removes the file secrets of the containers of the task
+func (engine *DockerTaskEngine) removeSecretFiles(task *apitask.Task) {
+	taskID, err := task.GetID()
+	if err != nil {
+		return
+	}
+	dirs := []string{filepath.Join(engine.cfg.DataDir, legacySecretFilesDir, taskID)}
+	if root, err := secretFilesRootDir(); err == nil {
+		dirs = append(dirs, filepath.Join(root, taskID))
+	}
+	for _, dir := range dirs {
+		if err := removeAll(dir); err != nil {
+			logger.Warn("Unable to remove the secret files of the task", logger.Fields{
+				field.TaskARN: task.Arn,
+				field.Error:   err,
+			})
+		}
+	}
+}
+
+// startPeriodicSecretRefreshes refreshes the secrets of the tasks with file secrets at
+// the configured interval, until the context is done
+func (engine *DockerTaskEngine) startPeriodicSecretRefreshes(ctx context.Context) {
+	if engine.cfg.SecretsRefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(engine.cfg.SecretsRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			engine.refreshSecrets()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refreshSecrets resolves the secrets of the running tasks with file secrets again, so
+// that the rotated secrets are written to their files
+func (engine *DockerTaskEngine) refreshSecrets() {
+	var taskARNs []string
+	engine.tasksLock.RLock()
+	for arn, mTask := range engine.managedTasks {
+		if mTask.GetKnownStatus() == apitaskstatus.TaskRunning && !mTask.GetDesiredStatus().Terminal() &&
+			taskHasSecretFiles(mTask.Task) {
+			taskARNs = append(taskARNs, arn)
+		}
+	}
+	engine.tasksLock.RUnlock()
+
+	for _, taskARN := range taskARNs {
+		if err := engine.UpdateTask(TaskUpdate{TaskARN: taskARN, RefreshSecrets: true}); err != nil {
+			logger.Warn("Unable to refresh the secrets of the task", logger.Fields{
+				field.TaskARN: taskARN,
+				field.Error:   err,
+			})
+		}
+	}
+}
+
+func taskHasSecretFiles(task *apitask.Task) bool {
+	for _, container := range task.Containers {
+		if container.HasSecretFiles() {
+			return true
+		}
+	}
+	return false
+}
+
+// rotateSecretFiles rewrites the file secrets of the created containers of the task after
+// its secrets were refreshed, and signals the running containers whose files changed. The
+// containers that aren't created yet write their files when they're created.
+func (engine *DockerTaskEngine) rotateSecretFiles(task *apitask.Task) {
+	for _, container := range task.Containers {
+		status := container.GetKnownStatus()
+		if !container.HasSecretFiles() || status < apicontainerstatus.ContainerCreated || status.Terminal() {
+			continue
+		}
+		changed, err := engine.writeSecretFiles(task, container)
+		if err != nil {
+			logger.Error("Unable to rewrite the secret files of the container", logger.Fields{
+				field.TaskARN:   task.Arn,
+				field.Container: container.Name,
+				field.Error:     err,
+			})
+			continue
+		}
+		if changed && status == apicontainerstatus.ContainerRunning {
+			engine.signalSecretsRotated(task, container)
+		}
+	}
+}
+
+// signalSecretsRotated sends the secrets refresh signal to the container, so that it reads
+// its rotated secret files
+func (engine *DockerTaskEngine) signalSecretsRotated(task *apitask.Task, container *apicontainer.Container) {
+	signal := container.GetSecretsRefreshSignal()
+	if signal == "" {
+		signal = engine.cfg.SecretsRefreshSignal
+	}
+	if signal == "" {
+		return
+	}
+	dockerID, err := engine.getDockerID(task, container)
+	if err != nil {
+		logger.Error("Unable to signal container of its rotated secrets", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.Error:     err,
+		})
+		return
+	}
+	err = engine.client.KillContainer(engine.ctx, dockerID, signal, dockerclient.KillContainerTimeout)
+	if err != nil {
+		logger.Warn("Error signaling container of its rotated secrets", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: dockerID,
+			field.Error:     err,
+		})
+		return
+	}
+	logger.Info("Signaled container of its rotated secrets", logger.Fields{
+		field.TaskARN:   task.Arn,
+		field.Container: container.Name,
+		field.RuntimeID: dockerID,
+	})
+}
@@ -0,0 +1,52 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// tmpfsMagic is the filesystem type of tmpfs, from include/uapi/linux/magic.h
+const tmpfsMagic = 0x01021994
+
+var (
+	// secretFilesRoot is the directory the file secrets of the containers are written to,
+	// in a directory per task and container. It's on the /run tmpfs of the host, which is
+	// mounted at the same path in the agent container, so that the plaintext secrets are
+	// never written to disk.
+	secretFilesRoot = "/var/run/ecs/secrets"
+	// statfs is swappable for testing
+	statfs = syscall.Statfs
+)
+
+// secretFilesRootDir returns the directory the file secrets are written to, once it
+// checked it's on a tmpfs
+func secretFilesRootDir() (string, error) {
+	if err := os.MkdirAll(secretFilesRoot, secretFilesParentDirPerm); err != nil {
+		return "", err
+	}
+	var stat syscall.Statfs_t
+	if err := statfs(secretFilesRoot, &stat); err != nil {
+		return "", errors.Wrapf(err, "unable to get the filesystem of %s", secretFilesRoot)
+	}
+	if stat.Type != tmpfsMagic {
+		return "", errors.Errorf("%s isn't on a tmpfs, which file secrets require", secretFilesRoot)
+	}
+	return secretFilesRoot, nil
+}
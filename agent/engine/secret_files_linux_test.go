@@ -0,0 +1,217 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSecretFilesTask(signal string) (*apitask.Task, *ssmsecret.SSMSecretResource) {
+	secret := apicontainer.Secret{
+		Name:      "db-password",
+		ValueFrom: "db-password",
+		Region:    "us-west-2",
+		Provider:  apicontainer.SecretProviderSSM,
+		Type:      apicontainer.SecretTypeFile,
+	}
+	container := &apicontainer.Container{
+		Name:                 "app",
+		Secrets:              []apicontainer.Secret{secret},
+		SecretsRefreshSignal: signal,
+		KnownStatusUnsafe:    apicontainerstatus.ContainerRunning,
+		DesiredStatusUnsafe:  apicontainerstatus.ContainerRunning,
+	}
+	container.SetRuntimeID(containerID)
+	task := &apitask.Task{
+		Arn:                 "arn:aws:ecs:us-west-2:1234567890:task/mycluster/task1",
+		Containers:          []*apicontainer.Container{container},
+		KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		ResourcesMapUnsafe:  make(map[string][]taskresource.TaskResource),
+	}
+	ssmRes := ssmsecret.NewSSMSecretResource(task.Arn, nil, "", nil, nil, nil)
+	ssmRes.SetCachedSecretValue(secret.GetSecretResourceCacheKey(), "password1")
+	task.AddResource(ssmsecret.ResourceName, ssmRes)
+	return task, ssmRes
+}
+
+// setupSecretFilesRoot writes the secret files to a temporary directory, considered a
+// tmpfs, and records their owners
+func setupSecretFilesRoot(t *testing.T) (string, map[string]string, func()) {
+	owners := make(map[string]string)
+	secretFilesRoot = filepath.Join(t.TempDir(), "secrets")
+	statfs = func(path string, stat *syscall.Statfs_t) error {
+		stat.Type = tmpfsMagic
+		return nil
+	}
+	chown = func(path string, uid, gid int) error {
+		owners[filepath.Base(path)] = fmt.Sprintf("%d:%d", uid, gid)
+		return nil
+	}
+	return secretFilesRoot, owners, func() {
+		secretFilesRoot = "/var/run/ecs/secrets"
+		statfs = syscall.Statfs
+		chown = os.Chown
+	}
+}
+
+func TestSecretFilesRotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.DataDir = t.TempDir()
+	root, owners, cleanup := setupSecretFilesRoot(t)
+	defer cleanup()
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	task, ssmRes := newSecretFilesTask("")
+	container := task.Containers[0]
+	container.Image = "app:latest"
+	client.EXPECT().InspectImage("app:latest").Return(&types.ImageInspect{
+		Config: &dockercontainer.Config{User: "1000:1001"},
+	}, nil).AnyTimes()
+	hostConfig := &dockercontainer.HostConfig{}
+	require.Nil(t, dockerTaskEngine.addSecretFiles(task, container, hostConfig))
+	assert.Equal(t, []string{root + "/task1/app/0:/run/secrets:ro"}, hostConfig.Binds)
+	secretFile := filepath.Join(root, "task1", "app", "0", "db-password")
+	value, err := ioutil.ReadFile(secretFile)
+	require.NoError(t, err)
+	assert.Equal(t, "password1", string(value))
+	info, err := os.Stat(secretFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(secretFilePerm), info.Mode().Perm())
+	info, err = os.Stat(filepath.Dir(secretFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(secretFilesDirPerm), info.Mode().Perm())
+	// the directory and the file are owned by the user of the image
+	require.Len(t, owners, 2)
+	for _, owner := range owners {
+		assert.Equal(t, "1000:1001", owner)
+	}
+
+	// the value didn't change, so the container isn't signaled
+	dockerTaskEngine.rotateSecretFiles(task)
+
+	ssmRes.SetCachedSecretValue("db-password_us-west-2", "password2")
+	client.EXPECT().KillContainer(gomock.Any(), containerID, "SIGHUP", dockerclient.KillContainerTimeout).Return(nil)
+	dockerTaskEngine.rotateSecretFiles(task)
+	value, err = ioutil.ReadFile(secretFile)
+	require.NoError(t, err)
+	assert.Equal(t, "password2", string(value))
+
+	dockerTaskEngine.removeSecretFiles(task)
+	_, err = os.Stat(filepath.Join(root, "task1"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSecretFilesRequireTmpfs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	_, _, cleanup := setupSecretFilesRoot(t)
+	defer cleanup()
+	statfs = func(path string, stat *syscall.Statfs_t) error {
+		stat.Type = 0xef53
+		return nil
+	}
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+
+	task, _ := newSecretFilesTask("")
+	err := taskEngine.(*DockerTaskEngine).addSecretFiles(task, task.Containers[0], &dockercontainer.HostConfig{})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "tmpfs")
+}
+
+func TestParseSecretFilesOwner(t *testing.T) {
+	testCases := []struct {
+		user     string
+		expected secretFilesOwner
+		ok       bool
+	}{
+		{"", secretFilesOwner{dirMode: secretFilesDirPerm, fileMode: secretFilePerm}, true},
+		{"root", secretFilesOwner{dirMode: secretFilesDirPerm, fileMode: secretFilePerm}, true},
+		{"1000", secretFilesOwner{uid: 1000, dirMode: secretFilesDirPerm, fileMode: secretFilePerm}, true},
+		{"1000:1001", secretFilesOwner{uid: 1000, gid: 1001, dirMode: secretFilesDirPerm, fileMode: secretFilePerm}, true},
+		{"nginx", secretFilesOwner{dirMode: secretFilesSharedDirPerm, fileMode: secretFileSharedPerm}, false},
+		{"1000:staff", secretFilesOwner{dirMode: secretFilesSharedDirPerm, fileMode: secretFileSharedPerm}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.user, func(t *testing.T) {
+			owner, ok := parseSecretFilesOwner(tc.user)
+			assert.Equal(t, tc.expected, owner)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestSecretFilesRotationContainerSignal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	_, _, cleanup := setupSecretFilesRoot(t)
+	defer cleanup()
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	client.EXPECT().InspectImage(gomock.Any()).Return(&types.ImageInspect{}, nil).AnyTimes()
+
+	task, ssmRes := newSecretFilesTask("SIGUSR1")
+	_, err := dockerTaskEngine.writeSecretFiles(task, task.Containers[0])
+	require.NoError(t, err)
+
+	ssmRes.SetCachedSecretValue("db-password_us-west-2", "password2")
+	client.EXPECT().KillContainer(gomock.Any(), containerID, "SIGUSR1", dockerclient.KillContainerTimeout).Return(nil)
+	dockerTaskEngine.rotateSecretFiles(task)
+}
+
+func TestSecretFilesInvalidName(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	_, _, cleanup := setupSecretFilesRoot(t)
+	defer cleanup()
+	ctrl, client, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	client.EXPECT().InspectImage(gomock.Any()).Return(&types.ImageInspect{}, nil).AnyTimes()
+
+	task, _ := newSecretFilesTask("")
+	task.Containers[0].Secrets[0].Name = "../db-password"
+	_, err := dockerTaskEngine.writeSecretFiles(task, task.Containers[0])
+	assert.Error(t, err)
+}
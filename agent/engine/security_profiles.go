@@ -0,0 +1,93 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"strings"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/securityprofile"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+// applySecurityProfiles fetches the seccomp and AppArmor profiles the container references
+// by name, and confines the container with them. The managed profiles replace the seccomp
+// and AppArmor security options of the container, if any. The status of each profile is
+// recorded on the container, along with why it couldn't be applied.
+func (engine *DockerTaskEngine) applySecurityProfiles(task *apitask.Task, container *apicontainer.Container,
+	hostConfig *dockercontainer.HostConfig) apierrors.NamedError {
+	profiles := container.GetSecurityProfiles()
+	if len(profiles) == 0 {
+		return nil
+	}
+	defer func() {
+		container.SetSecurityProfiles(profiles)
+		engine.saveContainerData(container)
+	}()
+
+	securityOpts := make(map[string]string)
+	for i := range profiles {
+		profile, err := engine.securityProfileManager.Get(profiles[i].Type, profiles[i].Name)
+		if err != nil {
+			profiles[i].Status = apicontainer.SecurityProfileFailed
+			profiles[i].Reason = err.Error()
+			logger.Error("Unable to apply security profile to container", logger.Fields{
+				field.TaskARN:   task.Arn,
+				field.Container: container.Name,
+				"profileType":   profiles[i].Type,
+				"profile":       profiles[i].Name,
+				field.Error:     err,
+			})
+			return SecurityProfileError{errors.Wrapf(err, "container %s", container.Name)}
+		}
+		profiles[i].Status = apicontainer.SecurityProfileApplied
+		profiles[i].Source = profile.Source
+		profiles[i].Reason = ""
+		switch profile.Type {
+		case securityprofile.TypeSeccomp:
+			// docker takes the seccomp profile itself rather than its path
+			securityOpts[profile.Type] = string(profile.Content)
+		case securityprofile.TypeAppArmor:
+			securityOpts[profile.Type] = profile.Name
+		}
+	}
+
+	var securityOpt []string
+	for _, opt := range hostConfig.SecurityOpt {
+		if _, managed := securityOpts[securityOptKey(opt)]; !managed {
+			securityOpt = append(securityOpt, opt)
+		}
+	}
+	for _, profileType := range []string{securityprofile.TypeSeccomp, securityprofile.TypeAppArmor} {
+		if value, ok := securityOpts[profileType]; ok {
+			securityOpt = append(securityOpt, profileType+"="+value)
+		}
+	}
+	hostConfig.SecurityOpt = securityOpt
+	return nil
+}
+
+// securityOptKey returns the key of a docker security option, such as seccomp for
+// "seccomp=unconfined" or "seccomp:unconfined"
+func securityOptKey(opt string) string {
+	if i := strings.IndexAny(opt, "=:"); i >= 0 {
+		return opt[:i]
+	}
+	return opt
+}
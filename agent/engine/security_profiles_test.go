@@ -0,0 +1,116 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/securityprofile"
+	mock_securityprofile "github.com/aws/amazon-ecs-agent/agent/securityprofile/mocks"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSeccompProfile = `{"defaultAction":"SCMP_ACT_ERRNO"}`
+
+func newSecurityProfilesTestEngine(t *testing.T) (*gomock.Controller, *mock_securityprofile.MockManager, *DockerTaskEngine) {
+	ctrl := gomock.NewController(t)
+	manager := mock_securityprofile.NewMockManager(ctrl)
+	engine := &DockerTaskEngine{
+		dataClient:             data.NewNoopClient(),
+		securityProfileManager: manager,
+	}
+	return ctrl, manager, engine
+}
+
+func TestApplySecurityProfiles(t *testing.T) {
+	ctrl, manager, engine := newSecurityProfilesTestEngine(t)
+	defer ctrl.Finish()
+	manager.EXPECT().Get(securityprofile.TypeSeccomp, "strict").Return(&securityprofile.Profile{
+		Type:    securityprofile.TypeSeccomp,
+		Name:    "strict",
+		Source:  "/etc/ecs/security-profiles/seccomp/strict.json",
+		Content: []byte(testSeccompProfile),
+	}, nil)
+	manager.EXPECT().Get(securityprofile.TypeAppArmor, "web").Return(&securityprofile.Profile{
+		Type:   securityprofile.TypeAppArmor,
+		Name:   "web",
+		Source: "/etc/ecs/security-profiles/apparmor/web",
+	}, nil)
+
+	container := &apicontainer.Container{
+		Name: "app",
+		SecurityProfilesUnsafe: []apicontainer.SecurityProfile{
+			{Type: securityprofile.TypeSeccomp, Name: "strict"},
+			{Type: securityprofile.TypeAppArmor, Name: "web"},
+		},
+	}
+	hostConfig := &dockercontainer.HostConfig{
+		SecurityOpt: []string{"no-new-privileges", "apparmor:docker-default"},
+	}
+	require.Nil(t, engine.applySecurityProfiles(&apitask.Task{Arn: "task1"}, container, hostConfig))
+	assert.Equal(t, []string{
+		"no-new-privileges",
+		"seccomp=" + testSeccompProfile,
+		"apparmor=web",
+	}, hostConfig.SecurityOpt)
+
+	profiles := container.GetSecurityProfiles()
+	require.Len(t, profiles, 2)
+	for _, profile := range profiles {
+		assert.Equal(t, apicontainer.SecurityProfileApplied, profile.Status)
+		assert.NotEmpty(t, profile.Source)
+	}
+}
+
+func TestApplySecurityProfilesMissingProfile(t *testing.T) {
+	ctrl, manager, engine := newSecurityProfilesTestEngine(t)
+	defer ctrl.Finish()
+	manager.EXPECT().Get(securityprofile.TypeSeccomp, "strict").Return(nil,
+		&securityprofile.NotFoundError{Type: securityprofile.TypeSeccomp, Name: "strict"})
+
+	container := &apicontainer.Container{
+		Name: "app",
+		SecurityProfilesUnsafe: []apicontainer.SecurityProfile{
+			{Type: securityprofile.TypeSeccomp, Name: "strict"},
+		},
+	}
+	hostConfig := &dockercontainer.HostConfig{}
+	err := engine.applySecurityProfiles(&apitask.Task{Arn: "task1"}, container, hostConfig)
+	require.NotNil(t, err)
+	assert.Equal(t, "SecurityProfileError", err.ErrorName())
+	assert.Contains(t, err.Error(), "seccomp profile strict not found")
+	assert.Empty(t, hostConfig.SecurityOpt)
+
+	profiles := container.GetSecurityProfiles()
+	require.Len(t, profiles, 1)
+	assert.Equal(t, apicontainer.SecurityProfileFailed, profiles[0].Status)
+	assert.Equal(t, "seccomp profile strict not found", profiles[0].Reason)
+}
+
+func TestApplySecurityProfilesWithoutProfiles(t *testing.T) {
+	ctrl, _, engine := newSecurityProfilesTestEngine(t)
+	defer ctrl.Finish()
+
+	hostConfig := &dockercontainer.HostConfig{SecurityOpt: []string{"seccomp=unconfined"}}
+	assert.Nil(t, engine.applySecurityProfiles(&apitask.Task{}, &apicontainer.Container{}, hostConfig))
+	assert.Equal(t, []string{"seccomp=unconfined"}, hostConfig.SecurityOpt)
+}
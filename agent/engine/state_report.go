@@ -0,0 +1,258 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	// CheckpointKindTask is the kind of the checkpoints of tasks
+	CheckpointKindTask = "task"
+	// CheckpointKindContainer is the kind of the checkpoints of containers
+	CheckpointKindContainer = "container"
+)
+
+// StateReport is the divergence between the state of the engine, the checkpoint
+// of the state in the data store and the containers known to docker. Building it
+// doesn't change any of them
+type StateReport struct {
+	GeneratedAt time.Time
+	// Consistent is true when no divergence was found and all the sources could
+	// be read
+	Consistent bool
+	// OrphanedContainers are the docker containers labeled with the ARN of a task
+	// that isn't in the state
+	OrphanedContainers []OrphanedContainer
+	// UnknownStateContainers are the containers in the state whose status doesn't
+	// match the status of their docker container
+	UnknownStateContainers []UnknownStateContainer
+	// MissingCheckpoints are the tasks and containers in the state that aren't
+	// checkpointed, and would be lost by a restart of the agent
+	MissingCheckpoints []Checkpoint
+	// StaleCheckpoints are the checkpointed tasks and containers that aren't in
+	// the state anymore, and would be restored by a restart of the agent
+	StaleCheckpoints []Checkpoint
+	// Errors are the sources that couldn't be read, the report only covers the
+	// other sources when set
+	Errors []string `json:",omitempty"`
+}
+
+// OrphanedContainer is a docker container created for a task that isn't in the
+// state of the engine
+type OrphanedContainer struct {
+	DockerID      string
+	DockerName    string
+	TaskARN       string
+	ContainerName string
+	DockerStatus  string
+}
+
+// UnknownStateContainer is a container in the state of the engine whose status
+// doesn't match the status of its docker container
+type UnknownStateContainer struct {
+	TaskARN       string
+	ContainerName string
+	DockerID      string
+	KnownStatus   string
+	// DockerStatus is empty when the docker container doesn't exist
+	DockerStatus string `json:",omitempty"`
+	Reason       string
+}
+
+// Checkpoint identifies a task or container checkpoint in the data store
+type Checkpoint struct {
+	Kind          string
+	TaskARN       string
+	ContainerName string `json:",omitempty"`
+}
+
+// StateReport compares the state of the engine with its checkpoint in the data
+// store and with the containers known to docker, and reports the divergences
+func (engine *DockerTaskEngine) StateReport(ctx context.Context) StateReport {
+	report := StateReport{
+		GeneratedAt:            engine.time().Now(),
+		OrphanedContainers:     []OrphanedContainer{},
+		UnknownStateContainers: []UnknownStateContainer{},
+		MissingCheckpoints:     []Checkpoint{},
+		StaleCheckpoints:       []Checkpoint{},
+	}
+	engine.reportDockerDivergence(ctx, &report)
+	if engine.cfg.Checkpoint.Enabled() {
+		engine.reportCheckpointDivergence(&report)
+	}
+	report.Consistent = len(report.OrphanedContainers) == 0 && len(report.UnknownStateContainers) == 0 &&
+		len(report.MissingCheckpoints) == 0 && len(report.StaleCheckpoints) == 0 && len(report.Errors) == 0
+	return report
+}
+
+// reportDockerDivergence inspects every container known to docker, to find the
+// containers of tasks that aren't in the state and the containers of the state
+// whose docker container has a different status or is gone
+func (engine *DockerTaskEngine) reportDockerDivergence(ctx context.Context, report *StateReport) {
+	listResponse := engine.client.ListContainers(ctx, true, dockerclient.ListContainersTimeout)
+	if listResponse.Error != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("unable to list docker containers: %v", listResponse.Error))
+		return
+	}
+	dockerContainers := make(map[string]*types.ContainerJSON)
+	for _, dockerID := range listResponse.DockerIDs {
+		dockerContainer, err := engine.client.InspectContainer(ctx, dockerID, dockerclient.InspectContainerTimeout)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("unable to inspect docker container %s: %v", dockerID, err))
+			continue
+		}
+		dockerContainers[dockerID] = dockerContainer
+	}
+
+	for dockerID, dockerContainer := range dockerContainers {
+		if dockerContainer.Config == nil {
+			continue
+		}
+		taskARN, ok := dockerContainer.Config.Labels[labelTaskARN]
+		if !ok {
+			continue
+		}
+		if _, ok := engine.state.TaskByArn(taskARN); ok {
+			continue
+		}
+		report.OrphanedContainers = append(report.OrphanedContainers, OrphanedContainer{
+			DockerID:      dockerID,
+			DockerName:    dockerContainer.Name,
+			TaskARN:       taskARN,
+			ContainerName: dockerContainer.Config.Labels[labelContainerName],
+			DockerStatus:  dockerContainerStatus(dockerContainer),
+		})
+	}
+	sort.Slice(report.OrphanedContainers, func(i, j int) bool {
+		return report.OrphanedContainers[i].DockerID < report.OrphanedContainers[j].DockerID
+	})
+
+	for _, task := range engine.state.AllTasks() {
+		containerMap, _ := engine.state.ContainerMapByArn(task.Arn)
+		for _, container := range task.Containers {
+			knownStatus := container.GetKnownStatus()
+			dockerContainer, ok := containerMap[container.Name]
+			if !ok || dockerContainer.DockerID == "" {
+				// The container wasn't created yet
+				continue
+			}
+			divergence := UnknownStateContainer{
+				TaskARN:       task.Arn,
+				ContainerName: container.Name,
+				DockerID:      dockerContainer.DockerID,
+				KnownStatus:   knownStatus.String(),
+			}
+			inspected, exists := dockerContainers[dockerContainer.DockerID]
+			switch {
+			case !exists && !knownStatus.Terminal():
+				divergence.Reason = "the docker container doesn't exist"
+			case !exists:
+				continue
+			case inspected.State != nil && inspected.State.Running && knownStatus.Terminal():
+				divergence.DockerStatus = dockerContainerStatus(inspected)
+				divergence.Reason = "the docker container is running but the container is known to be stopped"
+			case (inspected.State == nil || !inspected.State.Running) && knownStatus == apicontainerstatus.ContainerRunning:
+				divergence.DockerStatus = dockerContainerStatus(inspected)
+				divergence.Reason = "the docker container is not running but the container is known to be running"
+			default:
+				continue
+			}
+			report.UnknownStateContainers = append(report.UnknownStateContainers, divergence)
+		}
+	}
+	sort.Slice(report.UnknownStateContainers, func(i, j int) bool {
+		a, b := report.UnknownStateContainers[i], report.UnknownStateContainers[j]
+		return a.TaskARN < b.TaskARN || (a.TaskARN == b.TaskARN && a.ContainerName < b.ContainerName)
+	})
+}
+
+// reportCheckpointDivergence compares the tasks and containers of the state with
+// the ones checkpointed in the data store
+func (engine *DockerTaskEngine) reportCheckpointDivergence(report *StateReport) {
+	checkpointedTasks, err := engine.dataClient.GetTasks()
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("unable to read the task checkpoints: %v", err))
+		return
+	}
+	checkpointedContainers, err := engine.dataClient.GetContainers()
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("unable to read the container checkpoints: %v", err))
+		return
+	}
+
+	checkpoints := make(map[Checkpoint]bool)
+	for _, task := range checkpointedTasks {
+		checkpoints[Checkpoint{Kind: CheckpointKindTask, TaskARN: task.Arn}] = true
+	}
+	for _, container := range checkpointedContainers {
+		if container.Container == nil {
+			continue
+		}
+		checkpoints[Checkpoint{
+			Kind:          CheckpointKindContainer,
+			TaskARN:       container.Container.GetTaskARN(),
+			ContainerName: container.Container.Name,
+		}] = true
+	}
+
+	inState := make(map[Checkpoint]bool)
+	for _, task := range engine.state.AllTasks() {
+		inState[Checkpoint{Kind: CheckpointKindTask, TaskARN: task.Arn}] = true
+		containerMap, _ := engine.state.ContainerMapByArn(task.Arn)
+		for name := range containerMap {
+			inState[Checkpoint{Kind: CheckpointKindContainer, TaskARN: task.Arn, ContainerName: name}] = true
+		}
+	}
+
+	for checkpoint := range inState {
+		if !checkpoints[checkpoint] {
+			report.MissingCheckpoints = append(report.MissingCheckpoints, checkpoint)
+		}
+	}
+	for checkpoint := range checkpoints {
+		if !inState[checkpoint] {
+			report.StaleCheckpoints = append(report.StaleCheckpoints, checkpoint)
+		}
+	}
+	sortCheckpoints(report.MissingCheckpoints)
+	sortCheckpoints(report.StaleCheckpoints)
+}
+
+func sortCheckpoints(checkpoints []Checkpoint) {
+	sort.Slice(checkpoints, func(i, j int) bool {
+		a, b := checkpoints[i], checkpoints[j]
+		if a.TaskARN != b.TaskARN {
+			return a.TaskARN < b.TaskARN
+		}
+		if a.Kind != b.Kind {
+			return a.Kind == CheckpointKindTask
+		}
+		return a.ContainerName < b.ContainerName
+	})
+}
+
+func dockerContainerStatus(container *types.ContainerJSON) string {
+	if container.State == nil {
+		return ""
+	}
+	return container.State.Status
+}
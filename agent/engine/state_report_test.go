@@ -0,0 +1,205 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	mock_ttime "github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	stateReportTaskARN        = "arn:aws:ecs:us-west-2:123456789012:task/cluster/task1"
+	stateReportStaleTaskARN   = "arn:aws:ecs:us-west-2:123456789012:task/cluster/stale"
+	stateReportOrphanTaskARN  = "arn:aws:ecs:us-west-2:123456789012:task/cluster/orphan"
+	stateReportStoppedTaskARN = "arn:aws:ecs:us-west-2:123456789012:task/cluster/stopped"
+)
+
+func dockerContainerJSON(id string, running bool, labels map[string]string) *types.ContainerJSON {
+	status := "exited"
+	if running {
+		status = "running"
+	}
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    id,
+			Name:  "/" + id,
+			State: &types.ContainerState{Running: running, Status: status},
+		},
+		Config: &dockercontainer.Config{Labels: labels},
+	}
+}
+
+func TestStateReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	dataClient, cleanup := newTestDataClient(t)
+	defer cleanup()
+
+	cfg := config.DefaultConfig()
+	cfg.Checkpoint = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	state := dockerstate.NewTaskEngineState()
+	engine := &DockerTaskEngine{
+		cfg:        &cfg,
+		client:     client,
+		state:      state,
+		dataClient: dataClient,
+		_time:      mockTime,
+	}
+
+	task := &apitask.Task{Arn: stateReportTaskARN}
+	for _, name := range []string{"running", "exited", "removed", "pending"} {
+		container := &apicontainer.Container{Name: name, KnownStatusUnsafe: apicontainerstatus.ContainerRunning}
+		container.SetTaskARN(task.Arn)
+		task.Containers = append(task.Containers, container)
+	}
+	task.Containers[3].SetKnownStatus(apicontainerstatus.ContainerStatusNone)
+	state.AddTask(task)
+	for _, container := range task.Containers[:3] {
+		state.AddContainer(&apicontainer.DockerContainer{DockerID: container.Name, Container: container}, task)
+	}
+	state.AddContainer(&apicontainer.DockerContainer{Container: task.Containers[3]}, task)
+
+	require.NoError(t, dataClient.SaveTask(task))
+	require.NoError(t, dataClient.SaveContainer(task.Containers[0]))
+	require.NoError(t, dataClient.SaveContainer(task.Containers[1]))
+	require.NoError(t, dataClient.SaveContainer(task.Containers[3]))
+	require.NoError(t, dataClient.SaveTask(&apitask.Task{Arn: stateReportStaleTaskARN}))
+
+	now := time.Now()
+	mockTime.EXPECT().Now().Return(now)
+	client.EXPECT().ListContainers(gomock.Any(), true, gomock.Any()).Return(dockerapi.ListContainersResponse{
+		DockerIDs: []string{"running", "exited", "orphan", "other"},
+	})
+	client.EXPECT().InspectContainer(gomock.Any(), "running", gomock.Any()).Return(
+		dockerContainerJSON("running", true, map[string]string{labelTaskARN: stateReportTaskARN}), nil)
+	client.EXPECT().InspectContainer(gomock.Any(), "exited", gomock.Any()).Return(
+		dockerContainerJSON("exited", false, map[string]string{labelTaskARN: stateReportTaskARN}), nil)
+	client.EXPECT().InspectContainer(gomock.Any(), "orphan", gomock.Any()).Return(
+		dockerContainerJSON("orphan", true, map[string]string{
+			labelTaskARN:       stateReportOrphanTaskARN,
+			labelContainerName: "app",
+		}), nil)
+	client.EXPECT().InspectContainer(gomock.Any(), "other", gomock.Any()).Return(
+		dockerContainerJSON("other", true, nil), nil)
+
+	report := engine.StateReport(context.TODO())
+	assert.Equal(t, now, report.GeneratedAt)
+	assert.False(t, report.Consistent)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, []OrphanedContainer{{
+		DockerID:      "orphan",
+		DockerName:    "/orphan",
+		TaskARN:       stateReportOrphanTaskARN,
+		ContainerName: "app",
+		DockerStatus:  "running",
+	}}, report.OrphanedContainers)
+	assert.Equal(t, []UnknownStateContainer{
+		{
+			TaskARN:       stateReportTaskARN,
+			ContainerName: "exited",
+			DockerID:      "exited",
+			KnownStatus:   "RUNNING",
+			DockerStatus:  "exited",
+			Reason:        "the docker container is not running but the container is known to be running",
+		},
+		{
+			TaskARN:       stateReportTaskARN,
+			ContainerName: "removed",
+			DockerID:      "removed",
+			KnownStatus:   "RUNNING",
+			Reason:        "the docker container doesn't exist",
+		},
+	}, report.UnknownStateContainers)
+	assert.Equal(t, []Checkpoint{
+		{Kind: CheckpointKindContainer, TaskARN: stateReportTaskARN, ContainerName: "removed"},
+	}, report.MissingCheckpoints)
+	assert.Equal(t, []Checkpoint{
+		{Kind: CheckpointKindTask, TaskARN: stateReportStaleTaskARN},
+	}, report.StaleCheckpoints)
+}
+
+func TestStateReportConsistent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+
+	// The checkpoint isn't compared when checkpointing is disabled.
+	cfg := config.DefaultConfig()
+	state := dockerstate.NewTaskEngineState()
+	engine := &DockerTaskEngine{
+		cfg:    &cfg,
+		client: client,
+		state:  state,
+		_time:  mockTime,
+	}
+	task := &apitask.Task{Arn: stateReportStoppedTaskARN, Containers: []*apicontainer.Container{
+		{Name: "app", KnownStatusUnsafe: apicontainerstatus.ContainerStopped},
+	}}
+	state.AddTask(task)
+	state.AddContainer(&apicontainer.DockerContainer{DockerID: "app", Container: task.Containers[0]}, task)
+
+	mockTime.EXPECT().Now().Return(time.Now())
+	// Stopped containers whose docker container was removed are consistent.
+	client.EXPECT().ListContainers(gomock.Any(), true, gomock.Any()).Return(dockerapi.ListContainersResponse{})
+
+	report := engine.StateReport(context.TODO())
+	assert.True(t, report.Consistent)
+	assert.Empty(t, report.OrphanedContainers)
+	assert.Empty(t, report.UnknownStateContainers)
+}
+
+func TestStateReportDockerError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+
+	cfg := config.DefaultConfig()
+	engine := &DockerTaskEngine{
+		cfg:    &cfg,
+		client: client,
+		state:  dockerstate.NewTaskEngineState(),
+		_time:  mockTime,
+	}
+	mockTime.EXPECT().Now().Return(time.Now())
+	client.EXPECT().ListContainers(gomock.Any(), true, gomock.Any()).Return(dockerapi.ListContainersResponse{
+		Error: errors.New("docker is unavailable"),
+	})
+
+	report := engine.StateReport(context.TODO())
+	assert.False(t, report.Consistent)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], "docker is unavailable")
+}
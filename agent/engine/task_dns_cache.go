@@ -0,0 +1,114 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+// startDNSCache starts a DNS cache in the network namespace of a process. It's swappable
+// for testing.
+var startDNSCache = dnscache.Start
+
+// taskDNSCacheConfig returns the configuration of the DNS cache of the task, which forwards
+// the queries it can't answer to the nameservers of the ENI of the task, or to the Amazon
+// provided DNS server when the ENI has none
+func (engine *DockerTaskEngine) taskDNSCacheConfig(task *apitask.Task) dnscache.Config {
+	cfg := dnscache.Config{
+		MaxQueriesPerSecond: engine.cfg.TaskDNSCacheQueriesPerSecond,
+		MaxEntries:          task.DNSConfiguration.CacheSize,
+	}
+	if task.DNSConfiguration.MaxQueriesPerSecond > 0 {
+		cfg.MaxQueriesPerSecond = task.DNSConfiguration.MaxQueriesPerSecond
+	}
+	if eni := task.GetPrimaryENI(); eni != nil {
+		cfg.Upstreams = eni.GetDomainNameServers()
+	}
+	if len(cfg.Upstreams) == 0 {
+		cfg.Upstreams = []string{apieni.AmazonIPv4DNSServer}
+	}
+	return cfg
+}
+
+// startTaskDNSCache starts the DNS cache of the task in the network namespace of its pause
+// container, before any of its other containers start. Its containers are configured to
+// send their queries to the cache, so a task whose cache can't start fails to start.
+func (engine *DockerTaskEngine) startTaskDNSCache(task *apitask.Task, pausePID string) error {
+	if !task.UsesLocalDNSCache() {
+		return nil
+	}
+	cfg := engine.taskDNSCacheConfig(task)
+	cache, err := startDNSCache(pausePID, cfg)
+	if err != nil {
+		return err
+	}
+	logger.Info("Started DNS cache of task", logger.Fields{
+		field.TaskARN:         task.Arn,
+		"upstreams":           cfg.Upstreams,
+		"maxQueriesPerSecond": cfg.MaxQueriesPerSecond,
+	})
+	task.SetDNSCache(cache, pausePID)
+	engine.saveTaskData(task)
+	return nil
+}
+
+// restoreTaskDNSCache starts the DNS cache of a task again after the agent restarted, as
+// long as the network namespace of the task is still set up
+func (engine *DockerTaskEngine) restoreTaskDNSCache(task *apitask.Task) {
+	cache, pausePID := task.GetDNSCache()
+	if cache != nil || pausePID == "" {
+		return
+	}
+	for _, container := range task.Containers {
+		if container.Type != apicontainer.ContainerCNIPause {
+			continue
+		}
+		if container.KnownTerminal() || container.IsContainerTornDown() {
+			return
+		}
+	}
+	cache, err := startDNSCache(pausePID, engine.taskDNSCacheConfig(task))
+	if err != nil {
+		logger.Error("Unable to restart DNS cache of task", logger.Fields{
+			field.TaskARN: task.Arn,
+			field.Error:   err,
+		})
+		return
+	}
+	task.SetDNSCache(cache, pausePID)
+}
+
+// stopTaskDNSCache stops the DNS cache of the task, once its network namespace is being
+// torn down
+func (engine *DockerTaskEngine) stopTaskDNSCache(task *apitask.Task) {
+	cache, pausePID := task.GetDNSCache()
+	if pausePID == "" {
+		return
+	}
+	if cache != nil {
+		if err := cache.Close(); err != nil {
+			logger.Warn("Unable to stop DNS cache of task", logger.Fields{
+				field.TaskARN: task.Arn,
+				field.Error:   err,
+			})
+		}
+	}
+	task.SetDNSCache(nil, "")
+	engine.saveTaskData(task)
+}
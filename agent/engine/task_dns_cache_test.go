@@ -0,0 +1,137 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupStartDNSCache records the configurations of the caches started, by pid
+func setupStartDNSCache(startErr error) (map[string]dnscache.Config, func()) {
+	started := make(map[string]dnscache.Config)
+	startDNSCache = func(pid string, cfg dnscache.Config) (*dnscache.Cache, error) {
+		if startErr != nil {
+			return nil, startErr
+		}
+		started[pid] = cfg
+		return &dnscache.Cache{}, nil
+	}
+	return started, func() {
+		startDNSCache = dnscache.Start
+	}
+}
+
+func newDNSCacheTestTask(dnsConfig *apitask.DNSConfiguration, nameservers ...string) *apitask.Task {
+	return &apitask.Task{
+		Arn:              "task",
+		DNSConfiguration: dnsConfig,
+		ENIs:             []*apieni.ENI{{ID: "eni", DomainNameServers: nameservers}},
+		Containers: []*apicontainer.Container{
+			{Name: "pause", Type: apicontainer.ContainerCNIPause},
+		},
+	}
+}
+
+func newDNSCacheTestEngine() *DockerTaskEngine {
+	cfg := config.DefaultConfig()
+	cfg.TaskDNSCacheQueriesPerSecond = 100
+	return &DockerTaskEngine{cfg: &cfg, dataClient: data.NewNoopClient()}
+}
+
+func TestStartTaskDNSCache(t *testing.T) {
+	started, cleanup := setupStartDNSCache(nil)
+	defer cleanup()
+	engine := newDNSCacheTestEngine()
+
+	task := newDNSCacheTestTask(&apitask.DNSConfiguration{LocalCache: true, CacheSize: 10}, "10.0.0.2")
+	require.NoError(t, engine.startTaskDNSCache(task, "1"))
+	assert.Equal(t, dnscache.Config{
+		Upstreams:           []string{"10.0.0.2"},
+		MaxQueriesPerSecond: 100,
+		MaxEntries:          10,
+	}, started["1"])
+	cache, pid := task.GetDNSCache()
+	assert.NotNil(t, cache)
+	assert.Equal(t, "1", pid)
+
+	// The limit of the task takes precedence, and the Amazon provided DNS server is used
+	// when the ENI has no nameserver.
+	task = newDNSCacheTestTask(&apitask.DNSConfiguration{LocalCache: true, MaxQueriesPerSecond: 20})
+	require.NoError(t, engine.startTaskDNSCache(task, "2"))
+	assert.Equal(t, []string{apieni.AmazonIPv4DNSServer}, started["2"].Upstreams)
+	assert.Equal(t, 20, started["2"].MaxQueriesPerSecond)
+
+	// Tasks without a local cache don't get one.
+	task = newDNSCacheTestTask(nil, "10.0.0.2")
+	require.NoError(t, engine.startTaskDNSCache(task, "3"))
+	assert.NotContains(t, started, "3")
+}
+
+func TestStartTaskDNSCacheError(t *testing.T) {
+	_, cleanup := setupStartDNSCache(errors.New("setns failed"))
+	defer cleanup()
+	engine := newDNSCacheTestEngine()
+
+	task := newDNSCacheTestTask(&apitask.DNSConfiguration{LocalCache: true}, "10.0.0.2")
+	assert.Error(t, engine.startTaskDNSCache(task, "1"))
+	cache, pid := task.GetDNSCache()
+	assert.Nil(t, cache)
+	assert.Empty(t, pid)
+}
+
+func TestRestoreTaskDNSCache(t *testing.T) {
+	started, cleanup := setupStartDNSCache(nil)
+	defer cleanup()
+	engine := newDNSCacheTestEngine()
+
+	task := newDNSCacheTestTask(&apitask.DNSConfiguration{LocalCache: true}, "10.0.0.2")
+	task.SetDNSCache(nil, "1")
+	engine.restoreTaskDNSCache(task)
+	assert.Contains(t, started, "1")
+	cache, _ := task.GetDNSCache()
+	assert.NotNil(t, cache)
+
+	// The cache isn't restarted once the pause container stopped.
+	task = newDNSCacheTestTask(&apitask.DNSConfiguration{LocalCache: true}, "10.0.0.2")
+	task.Containers[0].SetKnownStatus(apicontainerstatus.ContainerStopped)
+	task.SetDNSCache(nil, "2")
+	engine.restoreTaskDNSCache(task)
+	assert.NotContains(t, started, "2")
+}
+
+func TestStopTaskDNSCache(t *testing.T) {
+	_, cleanup := setupStartDNSCache(nil)
+	defer cleanup()
+	engine := newDNSCacheTestEngine()
+
+	task := newDNSCacheTestTask(&apitask.DNSConfiguration{LocalCache: true}, "10.0.0.2")
+	require.NoError(t, engine.startTaskDNSCache(task, "1"))
+	engine.stopTaskDNSCache(task)
+	cache, pid := task.GetDNSCache()
+	assert.Nil(t, cache)
+	assert.Empty(t, pid)
+}
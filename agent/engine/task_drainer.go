@@ -0,0 +1,159 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/cihub/seelog"
+)
+
+// drainingStopReason is the reason reported for tasks that are not started
+// because the agent is draining
+const drainingStopReason = "TaskNotStarted: the container instance is draining"
+
+// DrainStatus is the progress of a local drain of the agent
+type DrainStatus struct {
+	// Draining is true once a drain has been requested
+	Draining bool
+	// Complete is true once all of the tasks running when the drain was
+	// requested have stopped
+	Complete  bool
+	StartedAt *time.Time `json:",omitempty"`
+	Tasks     []TaskDrainStatus
+}
+
+// TaskDrainStatus is the progress of the drain of a task
+type TaskDrainStatus struct {
+	TaskARN       string
+	KnownStatus   string
+	DesiredStatus string
+	// RunningContainers is the number of containers of the task that have not
+	// stopped yet
+	RunningContainers int
+	Stopped           bool
+}
+
+// taskDrainer stops the tasks managed by the engine on request, and keeps
+// track of the tasks that were running when the drain was requested
+type taskDrainer struct {
+	engine    *DockerTaskEngine
+	lock      sync.RWMutex
+	startedAt time.Time
+	taskARNs  []string
+}
+
+func newTaskDrainer(engine *DockerTaskEngine) *taskDrainer {
+	return &taskDrainer{
+		engine: engine,
+	}
+}
+
+// Drain puts the agent in a local draining state: new tasks are not started
+// anymore and the tasks that are running are stopped, respecting the stop
+// timeout of their containers. Draining an agent that is already draining
+// does nothing
+func (engine *DockerTaskEngine) Drain() {
+	engine.taskDrainer.drain()
+}
+
+// DrainStatus returns the progress of the local drain of the agent
+func (engine *DockerTaskEngine) DrainStatus() DrainStatus {
+	return engine.taskDrainer.status()
+}
+
+func (drainer *taskDrainer) drain() {
+	engine := drainer.engine
+	// The tasks lock is taken before the drain lock, as in AddTask, so that no
+	// task can be added between listing the tasks and starting the drain
+	engine.tasksLock.Lock()
+	drainer.lock.Lock()
+	if !drainer.startedAt.IsZero() {
+		drainer.lock.Unlock()
+		engine.tasksLock.Unlock()
+		return
+	}
+	drainer.startedAt = engine.time().Now()
+	var tasksToStop []*managedTask
+	for taskARN, mtask := range engine.managedTasks {
+		drainer.taskARNs = append(drainer.taskARNs, taskARN)
+		if !mtask.GetDesiredStatus().Terminal() {
+			tasksToStop = append(tasksToStop, mtask)
+		}
+	}
+	sort.Strings(drainer.taskARNs)
+	drainer.lock.Unlock()
+	engine.tasksLock.Unlock()
+
+	seelog.Infof("Task engine: draining, stopping %d tasks", len(tasksToStop))
+	for _, mtask := range tasksToStop {
+		// Stopping a task is handled like a stop from ACS, without a sequence
+		// number as the stop doesn't come from ACS
+		go mtask.emitACSTransition(acsTransition{
+			desiredStatus: apitaskstatus.TaskStopped,
+		})
+	}
+}
+
+func (drainer *taskDrainer) isDraining() bool {
+	if drainer == nil {
+		return false
+	}
+	drainer.lock.RLock()
+	defer drainer.lock.RUnlock()
+	return !drainer.startedAt.IsZero()
+}
+
+func (drainer *taskDrainer) status() DrainStatus {
+	drainer.lock.RLock()
+	startedAt := drainer.startedAt
+	taskARNs := drainer.taskARNs
+	drainer.lock.RUnlock()
+
+	if startedAt.IsZero() {
+		return DrainStatus{}
+	}
+	status := DrainStatus{
+		Draining:  true,
+		Complete:  true,
+		StartedAt: &startedAt,
+		Tasks:     make([]TaskDrainStatus, 0, len(taskARNs)),
+	}
+	for _, taskARN := range taskARNs {
+		taskStatus := TaskDrainStatus{
+			TaskARN:       taskARN,
+			KnownStatus:   apitaskstatus.TaskStopped.String(),
+			DesiredStatus: apitaskstatus.TaskStopped.String(),
+			Stopped:       true,
+		}
+		// Tasks that are not in the state anymore have been cleaned up
+		if task, ok := drainer.engine.state.TaskByArn(taskARN); ok {
+			taskStatus.KnownStatus = task.GetKnownStatus().String()
+			taskStatus.DesiredStatus = task.GetDesiredStatus().String()
+			taskStatus.Stopped = task.GetKnownStatus().Terminal()
+			for _, container := range task.Containers {
+				if container.GetKnownStatus() != apicontainerstatus.ContainerStopped {
+					taskStatus.RunningContainers++
+				}
+			}
+		}
+		status.Complete = status.Complete && taskStatus.Stopped
+		status.Tasks = append(status.Tasks, taskStatus)
+	}
+	return status
+}
@@ -0,0 +1,107 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainStopsRunningTasks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+
+	assert.False(t, dockerTaskEngine.DrainStatus().Draining)
+
+	runningTask := testdata.LoadTask("sleep5")
+	runningTask.SetKnownStatus(apitaskstatus.TaskRunning)
+	runningTask.Containers[0].SetKnownStatus(apicontainerstatus.ContainerRunning)
+	stoppedTask := testdata.LoadTask("sleep5")
+	stoppedTask.Arn = "stoppedTask"
+	stoppedTask.SetKnownStatus(apitaskstatus.TaskStopped)
+	stoppedTask.SetDesiredStatus(apitaskstatus.TaskStopped)
+	stoppedTask.Containers[0].SetKnownStatus(apicontainerstatus.ContainerStopped)
+
+	runningManagedTask := &managedTask{
+		Task:        runningTask,
+		ctx:         ctx,
+		acsMessages: make(chan acsTransition),
+	}
+	for _, task := range []*managedTask{runningManagedTask, {Task: stoppedTask, ctx: ctx}} {
+		dockerTaskEngine.state.AddTask(task.Task)
+		dockerTaskEngine.managedTasks[task.Arn] = task
+	}
+
+	dockerTaskEngine.Drain()
+	transition := <-runningManagedTask.acsMessages
+	assert.Equal(t, apitaskstatus.TaskStopped, transition.desiredStatus)
+	// Draining again doesn't stop the tasks again
+	dockerTaskEngine.Drain()
+
+	status := dockerTaskEngine.DrainStatus()
+	assert.True(t, status.Draining)
+	assert.False(t, status.Complete)
+	require.NotNil(t, status.StartedAt)
+	require.Len(t, status.Tasks, 2)
+	assert.Equal(t, TaskDrainStatus{
+		TaskARN:           runningTask.Arn,
+		KnownStatus:       "RUNNING",
+		DesiredStatus:     "RUNNING",
+		RunningContainers: 1,
+	}, status.Tasks[0])
+	assert.True(t, status.Tasks[1].Stopped)
+
+	runningTask.SetKnownStatus(apitaskstatus.TaskStopped)
+	runningTask.Containers[0].SetKnownStatus(apicontainerstatus.ContainerStopped)
+	dockerTaskEngine.state.RemoveTask(runningTask)
+	assert.True(t, dockerTaskEngine.DrainStatus().Complete)
+}
+
+func TestAddTaskWhileDraining(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	require.NoError(t, taskEngine.Init(ctx))
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	dockerTaskEngine.Drain()
+
+	task := testdata.LoadTask("sleep5")
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+	event := <-events
+	assert.Equal(t, apitaskstatus.TaskStopped, event.(api.TaskStateChange).Status, "Expected task to move to stopped directly")
+	assert.Equal(t, drainingStopReason, event.(api.TaskStateChange).Reason)
+
+	_, ok := dockerTaskEngine.managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
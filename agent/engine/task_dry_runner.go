@@ -0,0 +1,425 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/cihub/seelog"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+const (
+	// TaskDryRunRunning is the status of a task dry run that is in progress
+	TaskDryRunRunning = "RUNNING"
+	// TaskDryRunSucceeded is the status of a task dry run, or of one of its steps,
+	// that succeeded
+	TaskDryRunSucceeded = "SUCCEEDED"
+	// TaskDryRunFailed is the status of a task dry run, or of one of its steps,
+	// that failed
+	TaskDryRunFailed = "FAILED"
+	// TaskDryRunSkipped is the status of a task dry run step that doesn't apply
+	// to the task or the instance
+	TaskDryRunSkipped = "SKIPPED"
+
+	// maxTaskDryRunResults is the number of latest task dry runs whose results
+	// are kept
+	maxTaskDryRunResults = 20
+	// dryRunContainerNamePrefix is the prefix of the names of the containers
+	// created by task dry runs
+	dryRunContainerNamePrefix = "ecs-dryrun-"
+	// labelDryRun is the label of the containers created by task dry runs
+	labelDryRun = labelPrefix + "dry-run"
+	// hostNetworkMode is the network mode of the tasks that use the host network
+	hostNetworkMode = "host"
+	// noneNetworkMode is the network mode of the tasks without external networking
+	noneNetworkMode = "none"
+)
+
+// DryRunTaskDefinition is the subset of an ECS task definition that is needed to
+// dry run it on the instance. Other fields of task definitions are ignored.
+type DryRunTaskDefinition struct {
+	Family               string
+	ExecutionRoleArn     string
+	NetworkMode          string
+	Cpu                  string
+	Memory               string
+	ContainerDefinitions []DryRunContainerDefinition
+}
+
+// DryRunContainerDefinition is the subset of an ECS container definition that is
+// needed to dry run it on the instance
+type DryRunContainerDefinition struct {
+	Name   string
+	Image  string
+	Cpu    int64
+	Memory int64
+}
+
+// TaskDryRunStep is the result of a step of a task dry run
+type TaskDryRunStep struct {
+	Name       string
+	Target     string `json:",omitempty"`
+	Status     string
+	Error      string `json:",omitempty"`
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// TaskDryRunResult is the progress of a task dry run
+type TaskDryRunResult struct {
+	ID         string
+	Family     string `json:",omitempty"`
+	Status     string
+	StartedAt  time.Time
+	FinishedAt *time.Time `json:",omitempty"`
+	Steps      []TaskDryRunStep
+}
+
+// taskDryRunner runs task dry runs in the background, and keeps the results of
+// the latest ones
+type taskDryRunner struct {
+	engine *DockerTaskEngine
+	lock   sync.RWMutex
+	// results are the results of the latest dry runs, oldest first
+	results []*TaskDryRunResult
+}
+
+func newTaskDryRunner(engine *DockerTaskEngine) *taskDryRunner {
+	return &taskDryRunner{engine: engine}
+}
+
+// taskDryRun is a dry run of a task definition
+type taskDryRun struct {
+	runner *taskDryRunner
+	engine *DockerTaskEngine
+	def    DryRunTaskDefinition
+	// id identifies the dry run, and the containers and cgroups it creates
+	id     string
+	result *TaskDryRunResult
+}
+
+// StartTaskDryRun starts running the task definition in the background through
+// the steps the agent takes to start a task, without registering the task with
+// ECS or starting any of its containers: the images are pulled, and the
+// containers and the task cgroup are created in a sandbox and removed right
+// away. The network of awsvpc tasks isn't set up; the CNI plugins it needs are only
+// probed for their version, which doesn't validate their configuration. The dry run
+// stops at the first failed step. An error is returned if the task definition is
+// invalid.
+func (engine *DockerTaskEngine) StartTaskDryRun(def DryRunTaskDefinition) (TaskDryRunResult, error) {
+	return engine.taskDryRunner.start(def)
+}
+
+// TaskDryRunResults returns the progress of the latest task dry runs, oldest first
+func (engine *DockerTaskEngine) TaskDryRunResults() []TaskDryRunResult {
+	return engine.taskDryRunner.getResults()
+}
+
+func (runner *taskDryRunner) start(def DryRunTaskDefinition) (TaskDryRunResult, error) {
+	engine := runner.engine
+	if err := validateDryRunTaskDefinition(def, engine.cfg.TaskENIEnabled.Enabled()); err != nil {
+		return TaskDryRunResult{}, err
+	}
+
+	dryRun := &taskDryRun{
+		runner: runner,
+		engine: engine,
+		def:    def,
+		id:     utils.RandHex(),
+	}
+	dryRun.result = &TaskDryRunResult{
+		ID:        dryRun.id,
+		Family:    def.Family,
+		Status:    TaskDryRunRunning,
+		StartedAt: engine.time().Now(),
+	}
+
+	runner.lock.Lock()
+	runner.results = append(runner.results, dryRun.result)
+	if len(runner.results) > maxTaskDryRunResults {
+		runner.results = runner.results[len(runner.results)-maxTaskDryRunResults:]
+	}
+	result := dryRun.result.copy()
+	runner.lock.Unlock()
+
+	seelog.Infof("Task engine: dry running task definition %s [%s]", def.Family, dryRun.id)
+	go dryRun.finish()
+	return result, nil
+}
+
+func (runner *taskDryRunner) getResults() []TaskDryRunResult {
+	runner.lock.RLock()
+	defer runner.lock.RUnlock()
+
+	results := make([]TaskDryRunResult, 0, len(runner.results))
+	for _, result := range runner.results {
+		results = append(results, result.copy())
+	}
+	return results
+}
+
+func (result *TaskDryRunResult) copy() TaskDryRunResult {
+	resultCopy := *result
+	resultCopy.Steps = append([]TaskDryRunStep(nil), result.Steps...)
+	return resultCopy
+}
+
+// finish runs the steps of the dry run and records its outcome
+func (dryRun *taskDryRun) finish() {
+	status := TaskDryRunFailed
+	if dryRun.run() {
+		status = TaskDryRunSucceeded
+	}
+	seelog.Infof("Task engine: finished dry running task definition %s [%s]: %s",
+		dryRun.def.Family, dryRun.id, status)
+
+	dryRun.runner.lock.Lock()
+	defer dryRun.runner.lock.Unlock()
+	finishedAt := dryRun.engine.time().Now()
+	dryRun.result.FinishedAt = &finishedAt
+	dryRun.result.Status = status
+}
+
+func (dryRun *taskDryRun) run() bool {
+	pulled := make(map[string]struct{})
+	for _, containerDef := range dryRun.def.ContainerDefinitions {
+		image := containerDef.Image
+		if _, ok := pulled[image]; ok {
+			continue
+		}
+		pulled[image] = struct{}{}
+		if !dryRun.step("PullImage", image, func() error { return dryRun.pullImage(image) }) {
+			return false
+		}
+	}
+
+	if !dryRun.step("ProbeCNIPluginVersions", dryRun.def.NetworkMode, dryRun.probeCNIPluginVersions) {
+		return false
+	}
+	if !dryRun.step("CreateTaskCgroup", "", dryRun.createTaskCgroup) {
+		return false
+	}
+
+	var dockerIDs []string
+	defer func() {
+		for _, dockerID := range dockerIDs {
+			dryRun.removeContainer(dockerID)
+		}
+	}()
+	for _, containerDef := range dryRun.def.ContainerDefinitions {
+		containerDef := containerDef
+		succeeded := dryRun.step("CreateContainer", containerDef.Name, func() error {
+			dockerID, err := dryRun.createContainer(containerDef)
+			if dockerID != "" {
+				dockerIDs = append(dockerIDs, dockerID)
+			}
+			return err
+		})
+		if !succeeded {
+			return false
+		}
+	}
+	return true
+}
+
+// step runs the step of the dry run and records its result. Steps that return a
+// skippedStepError are recorded as skipped.
+func (dryRun *taskDryRun) step(name string, target string, run func() error) bool {
+	step := TaskDryRunStep{
+		Name:      name,
+		Target:    target,
+		StartedAt: dryRun.engine.time().Now(),
+	}
+	err := run()
+	step.FinishedAt = dryRun.engine.time().Now()
+	switch err := err.(type) {
+	case nil:
+		step.Status = TaskDryRunSucceeded
+	case skippedStepError:
+		step.Status = TaskDryRunSkipped
+		step.Error = err.Error()
+	default:
+		step.Status = TaskDryRunFailed
+		step.Error = err.Error()
+	}
+
+	dryRun.runner.lock.Lock()
+	defer dryRun.runner.lock.Unlock()
+	dryRun.result.Steps = append(dryRun.result.Steps, step)
+	return step.Status != TaskDryRunFailed
+}
+
+// skippedStepError is returned by the steps of a dry run that don't apply to the
+// task or the instance, with the reason why
+type skippedStepError struct {
+	reason string
+}
+
+func (err skippedStepError) Error() string {
+	return err.reason
+}
+
+// validateDryRunTaskDefinition validates the task definition before it's dry run
+func validateDryRunTaskDefinition(def DryRunTaskDefinition, taskENIEnabled bool) error {
+	if len(def.ContainerDefinitions) == 0 {
+		return fmt.Errorf("task definition has no container definitions")
+	}
+	names := make(map[string]struct{})
+	for _, containerDef := range def.ContainerDefinitions {
+		if containerDef.Name == "" {
+			return fmt.Errorf("container definition name is required")
+		}
+		if _, ok := names[containerDef.Name]; ok {
+			return fmt.Errorf("duplicate container definition name %s", containerDef.Name)
+		}
+		names[containerDef.Name] = struct{}{}
+		if containerDef.Image == "" {
+			return fmt.Errorf("container definition %s: image is required", containerDef.Name)
+		}
+		if containerDef.Cpu < 0 || containerDef.Memory < 0 {
+			return fmt.Errorf("container definition %s: cpu and memory can't be negative", containerDef.Name)
+		}
+		if err := validateImagePreload(dryRunImagePreload(def, containerDef.Image)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := parseDryRunTaskCPU(def.Cpu); err != nil {
+		return err
+	}
+	if _, err := parseDryRunTaskMemory(def.Memory); err != nil {
+		return err
+	}
+
+	switch def.NetworkMode {
+	case "", apitask.BridgeNetworkMode, hostNetworkMode, noneNetworkMode:
+	case apitask.AWSVPCNetworkMode:
+		if !taskENIEnabled {
+			return fmt.Errorf("network mode %s is not enabled on the instance", def.NetworkMode)
+		}
+	default:
+		return fmt.Errorf("unsupported network mode %s", def.NetworkMode)
+	}
+	return nil
+}
+
+// dryRunImagePreload returns the request to pull the image, with the execution
+// role of the task for ECR images
+func dryRunImagePreload(def DryRunTaskDefinition, image string) ImagePreload {
+	preload := ImagePreload{Image: image}
+	if ecrImageRegex.MatchString(image) {
+		preload.RoleArn = def.ExecutionRoleArn
+	}
+	return preload
+}
+
+func (dryRun *taskDryRun) pullImage(image string) error {
+	metadata := dryRun.engine.imagePreloader.pullImage(dryRunImagePreload(dryRun.def, image))
+	if metadata.Error != nil {
+		return metadata.Error
+	}
+	return nil
+}
+
+// createContainer creates the container of the container definition without
+// starting it, in the network mode of the task. The containers of awsvpc tasks
+// are created without networking, as their network interface is only attached by
+// ECS when the task is placed on the instance.
+func (dryRun *taskDryRun) createContainer(containerDef DryRunContainerDefinition) (string, error) {
+	engine := dryRun.engine
+	config := &dockercontainer.Config{
+		Image: containerDef.Image,
+		Labels: map[string]string{
+			labelDryRun:               dryRun.id,
+			labelContainerName:        containerDef.Name,
+			labelTaskDefinitionFamily: dryRun.def.Family,
+		},
+	}
+	hostConfig := &dockercontainer.HostConfig{
+		Resources: dockercontainer.Resources{
+			CPUShares: containerDef.Cpu,
+			Memory:    containerDef.Memory * 1024 * 1024,
+		},
+	}
+	switch dryRun.def.NetworkMode {
+	case "":
+	case apitask.AWSVPCNetworkMode:
+		hostConfig.NetworkMode = noneNetworkMode
+	default:
+		hostConfig.NetworkMode = dockercontainer.NetworkMode(dryRun.def.NetworkMode)
+	}
+
+	// only alphanumeric and hyphen characters are allowed
+	reInvalidChars := regexp.MustCompile("[^A-Za-z0-9-]+")
+	name := dryRunContainerNamePrefix + reInvalidChars.ReplaceAllString(containerDef.Name, "") + "-" + dryRun.id
+	metadata := engine.client.CreateContainer(engine.ctx, config, hostConfig, name, engine.cfg.ContainerCreateTimeout)
+	if metadata.Error != nil {
+		return metadata.DockerID, metadata.Error
+	}
+	return metadata.DockerID, nil
+}
+
+func (dryRun *taskDryRun) removeContainer(dockerID string) {
+	engine := dryRun.engine
+	if err := engine.client.RemoveContainer(engine.ctx, dockerID, dockerclient.RemoveContainerTimeout); err != nil {
+		seelog.Warnf("Task engine: unable to remove container %s of task dry run [%s]: %v", dockerID, dryRun.id, err)
+	}
+}
+
+// parseDryRunTaskCPU returns the number of vCPUs of the task level CPU of a task
+// definition, given in CPU units ("1024") or in vCPUs ("1 vCPU")
+func parseDryRunTaskCPU(cpu string) (float64, error) {
+	value := strings.ToLower(strings.TrimSpace(cpu))
+	if value == "" {
+		return 0, nil
+	}
+	divisor := float64(1024)
+	if strings.HasSuffix(value, "vcpu") {
+		value = strings.TrimSpace(strings.TrimSuffix(value, "vcpu"))
+		divisor = 1
+	}
+	vcpus, err := strconv.ParseFloat(value, 64)
+	if err != nil || vcpus <= 0 {
+		return 0, fmt.Errorf("invalid task cpu %s", cpu)
+	}
+	return vcpus / divisor, nil
+}
+
+// parseDryRunTaskMemory returns the MiB of the task level memory of a task
+// definition, given in MiB ("512") or in GB ("1 GB")
+func parseDryRunTaskMemory(memory string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(memory))
+	if value == "" {
+		return 0, nil
+	}
+	multiplier := float64(1)
+	if strings.HasSuffix(value, "gb") {
+		value = strings.TrimSpace(strings.TrimSuffix(value, "gb"))
+		multiplier = 1024
+	}
+	mib, err := strconv.ParseFloat(value, 64)
+	if err != nil || mib <= 0 {
+		return 0, fmt.Errorf("invalid task memory %s", memory)
+	}
+	return int64(mib * multiplier), nil
+}
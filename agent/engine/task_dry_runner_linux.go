@@ -0,0 +1,88 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/ecscni"
+	cgroup "github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
+	"github.com/cihub/seelog"
+)
+
+// dryRunCgroupPrefix is the prefix of the names of the cgroups created by task dry runs
+const dryRunCgroupPrefix = "dryrun-"
+
+// probeCNIPluginVersions checks that the CNI plugins used to set up the network of
+// awsvpc tasks are installed and answer a version query. It's only a probe: no
+// network namespace is created and no network is set up or torn down, as that needs
+// the ENI of the task, so a plugin that is installed but misconfigured passes it.
+// The network of the tasks in the other network modes is set up by docker when
+// their containers are created.
+func (dryRun *taskDryRun) probeCNIPluginVersions() error {
+	if dryRun.def.NetworkMode != apitask.AWSVPCNetworkMode {
+		return skippedStepError{reason: "the task network is set up by docker"}
+	}
+	for _, plugin := range []string{ecscni.ECSENIPluginName, ecscni.ECSBridgePluginName, ecscni.ECSIPAMPluginName} {
+		if _, err := dryRun.engine.cniClient.Version(plugin); err != nil {
+			return fmt.Errorf("unable to get the version of CNI plugin %s: %v", plugin, err)
+		}
+	}
+	return nil
+}
+
+// createTaskCgroup creates the cgroup of the task with its CPU and memory limits,
+// and removes it right away
+func (dryRun *taskDryRun) createTaskCgroup() error {
+	engine := dryRun.engine
+	if !engine.cfg.TaskCPUMemLimit.Enabled() || engine.resourceFields == nil {
+		return skippedStepError{reason: "task cpu and memory limits are disabled"}
+	}
+
+	cpu, err := parseDryRunTaskCPU(dryRun.def.Cpu)
+	if err != nil {
+		return err
+	}
+	memory, err := parseDryRunTaskMemory(dryRun.def.Memory)
+	if err != nil {
+		return err
+	}
+	task := &apitask.Task{CPU: cpu, Memory: memory}
+	for _, containerDef := range dryRun.def.ContainerDefinitions {
+		task.Containers = append(task.Containers, &apicontainer.Container{
+			CPU:    uint(containerDef.Cpu),
+			Memory: uint(containerDef.Memory),
+		})
+	}
+	resourceSpec, err := task.BuildLinuxResourceSpec(engine.cfg.CgroupCPUPeriod)
+	if err != nil {
+		return err
+	}
+
+	control := engine.resourceFields.Control
+	cgroupRoot := filepath.Join(config.DefaultTaskCgroupPrefix, dryRunCgroupPrefix+dryRun.id)
+	if _, err := control.Create(&cgroup.Spec{Root: cgroupRoot, Specs: &resourceSpec}); err != nil {
+		return err
+	}
+	if err := control.Remove(cgroupRoot); err != nil {
+		seelog.Warnf("Task engine: unable to remove cgroup %s of task dry run [%s]: %v", cgroupRoot, dryRun.id, err)
+	}
+	return nil
+}
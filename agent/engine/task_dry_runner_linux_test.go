@@ -0,0 +1,117 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package engine
+
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/ecscni"
+	mock_ecscni "github.com/aws/amazon-ecs-agent/agent/ecscni/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	cgroup "github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control/mock_control"
+	"github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartTaskDryRunAWSVPCTask(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.TaskENIEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	cfg.TaskCPUMemLimit = config.BooleanDefaultTrue{Value: config.ExplicitlyEnabled}
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	mockCNIClient := mock_ecscni.NewMockCNIClient(ctrl)
+	dockerTaskEngine.cniClient = mockCNIClient
+	mockControl := mock_control.NewMockControl(ctrl)
+	dockerTaskEngine.resourceFields = &taskresource.ResourceFields{Control: mockControl}
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().PullImage(gomock.Any(), "busybox:latest", nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{})
+	for _, plugin := range []string{ecscni.ECSENIPluginName, ecscni.ECSBridgePluginName, ecscni.ECSIPAMPluginName} {
+		mockCNIClient.EXPECT().Version(plugin).Return("2020.09.0", nil)
+	}
+	var cgroupRoot string
+	mockControl.EXPECT().Create(gomock.Any()).Do(func(spec *cgroup.Spec) {
+		cgroupRoot = spec.Root
+		require.NotNil(t, spec.Specs.CPU)
+		assert.Equal(t, int64(50000), *spec.Specs.CPU.Quota)
+		require.NotNil(t, spec.Specs.Memory)
+		assert.Equal(t, int64(512*1024*1024), *spec.Specs.Memory.Limit)
+	}).Return(nil, nil)
+	mockControl.EXPECT().Remove(gomock.Any()).Do(func(root string) {
+		assert.Equal(t, cgroupRoot, root)
+	}).Return(nil)
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, name string, timeout time.Duration) {
+			assert.Equal(t, container.NetworkMode("none"), hostConfig.NetworkMode)
+		}).Return(dockerapi.DockerContainerMetadata{DockerID: "app"})
+	client.EXPECT().RemoveContainer(gomock.Any(), "app", gomock.Any()).Return(nil)
+
+	_, err := dockerTaskEngine.StartTaskDryRun(DryRunTaskDefinition{
+		NetworkMode:          "awsvpc",
+		Cpu:                  "512",
+		Memory:               "512",
+		ContainerDefinitions: []DryRunContainerDefinition{{Name: "app", Image: "busybox:latest"}},
+	})
+	require.NoError(t, err)
+
+	results := waitForTaskDryRuns(t, dockerTaskEngine)
+	require.Len(t, results, 1)
+	assert.Equal(t, TaskDryRunSucceeded, results[0].Status)
+	for _, step := range results[0].Steps {
+		assert.Equal(t, TaskDryRunSucceeded, step.Status, step.Name)
+	}
+	assert.Contains(t, cgroupRoot, dryRunCgroupPrefix+results[0].ID)
+}
+
+func TestStartTaskDryRunMissingCNIPlugin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.TaskENIEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	mockCNIClient := mock_ecscni.NewMockCNIClient(ctrl)
+	dockerTaskEngine.cniClient = mockCNIClient
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().PullImage(gomock.Any(), "busybox:latest", nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{})
+	mockCNIClient.EXPECT().Version(ecscni.ECSENIPluginName).Return("", errors.New("no such file or directory"))
+
+	_, err := dockerTaskEngine.StartTaskDryRun(DryRunTaskDefinition{
+		NetworkMode:          "awsvpc",
+		ContainerDefinitions: []DryRunContainerDefinition{{Name: "app", Image: "busybox:latest"}},
+	})
+	require.NoError(t, err)
+
+	results := waitForTaskDryRuns(t, dockerTaskEngine)
+	require.Len(t, results, 1)
+	assert.Equal(t, TaskDryRunFailed, results[0].Status)
+	lastStep := results[0].Steps[len(results[0].Steps)-1]
+	assert.Equal(t, "ProbeCNIPluginVersions", lastStep.Name)
+	assert.Contains(t, lastStep.Error, ecscni.ECSENIPluginName)
+}
@@ -0,0 +1,310 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForTaskDryRuns waits for every task dry run to finish
+func waitForTaskDryRuns(t *testing.T, taskEngine *DockerTaskEngine) []TaskDryRunResult {
+	for i := 0; i < 1000; i++ {
+		results := taskEngine.TaskDryRunResults()
+		finished := true
+		for _, result := range results {
+			if result.Status == TaskDryRunRunning {
+				finished = false
+			}
+		}
+		if finished {
+			return results
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out waiting for task dry runs")
+	return nil
+}
+
+func TestStartTaskDryRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().PullImage(gomock.Any(), "busybox:latest", nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{})
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, name string, timeout time.Duration) {
+			assert.True(t, strings.HasPrefix(name, dryRunContainerNamePrefix+"appcontainer-"))
+			assert.Equal(t, "busybox:latest", config.Image)
+			assert.NotEmpty(t, config.Labels[labelDryRun])
+			assert.Equal(t, "web", config.Labels[labelTaskDefinitionFamily])
+			assert.Equal(t, container.NetworkMode("bridge"), hostConfig.NetworkMode)
+			assert.Equal(t, int64(128*1024*1024), hostConfig.Memory)
+		}).Return(dockerapi.DockerContainerMetadata{DockerID: "app"})
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+		dockerapi.DockerContainerMetadata{DockerID: "sidecar"})
+	client.EXPECT().RemoveContainer(gomock.Any(), "app", gomock.Any()).Return(nil)
+	client.EXPECT().RemoveContainer(gomock.Any(), "sidecar", gomock.Any()).Return(nil)
+
+	result, err := dockerTaskEngine.StartTaskDryRun(DryRunTaskDefinition{
+		Family:      "web",
+		NetworkMode: "bridge",
+		ContainerDefinitions: []DryRunContainerDefinition{
+			{Name: "app_container", Image: "busybox:latest", Memory: 128},
+			{Name: "sidecar", Image: "busybox:latest"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TaskDryRunRunning, result.Status)
+	assert.NotEmpty(t, result.ID)
+
+	results := waitForTaskDryRuns(t, dockerTaskEngine)
+	require.Len(t, results, 1)
+	assert.Equal(t, result.ID, results[0].ID)
+	assert.Equal(t, TaskDryRunSucceeded, results[0].Status)
+	require.NotNil(t, results[0].FinishedAt)
+
+	var stepNames []string
+	for _, step := range results[0].Steps {
+		stepNames = append(stepNames, step.Name)
+		assert.NotEqual(t, TaskDryRunFailed, step.Status)
+	}
+	// The image is only pulled once
+	assert.Equal(t, []string{"PullImage", "ProbeCNIPluginVersions", "CreateTaskCgroup", "CreateContainer", "CreateContainer"},
+		stepNames)
+}
+
+func TestStartTaskDryRunStopsAtFailedStep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().PullImage(gomock.Any(), "busybox:latest", nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{
+		Error: dockerapi.CannotPullContainerError{FromError: errors.New("manifest unknown")},
+	})
+
+	_, err := dockerTaskEngine.StartTaskDryRun(DryRunTaskDefinition{
+		Family: "web",
+		ContainerDefinitions: []DryRunContainerDefinition{
+			{Name: "app", Image: "busybox:latest"},
+			{Name: "sidecar", Image: "amazon/amazon-ecs-pause:0.1.0"},
+		},
+	})
+	require.NoError(t, err)
+
+	results := waitForTaskDryRuns(t, dockerTaskEngine)
+	require.Len(t, results, 1)
+	assert.Equal(t, TaskDryRunFailed, results[0].Status)
+	require.Len(t, results[0].Steps, 1)
+	assert.Equal(t, "busybox:latest", results[0].Steps[0].Target)
+	assert.Contains(t, results[0].Steps[0].Error, "manifest unknown")
+}
+
+func TestStartTaskDryRunRemovesCreatedContainersOnFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{}).Times(2)
+	gomock.InOrder(
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			dockerapi.DockerContainerMetadata{DockerID: "app"}),
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			dockerapi.DockerContainerMetadata{Error: dockerapi.CannotCreateContainerError{FromError: errors.New("invalid mount config")}}),
+		client.EXPECT().RemoveContainer(gomock.Any(), "app", gomock.Any()).Return(nil),
+	)
+
+	_, err := dockerTaskEngine.StartTaskDryRun(DryRunTaskDefinition{
+		ContainerDefinitions: []DryRunContainerDefinition{
+			{Name: "app", Image: "busybox:latest"},
+			{Name: "sidecar", Image: "amazon/amazon-ecs-pause:0.1.0"},
+		},
+	})
+	require.NoError(t, err)
+
+	results := waitForTaskDryRuns(t, dockerTaskEngine)
+	require.Len(t, results, 1)
+	assert.Equal(t, TaskDryRunFailed, results[0].Status)
+	lastStep := results[0].Steps[len(results[0].Steps)-1]
+	assert.Equal(t, "sidecar", lastStep.Target)
+	assert.Equal(t, TaskDryRunFailed, lastStep.Status)
+}
+
+func TestTaskDryRunResultsAreBounded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, mockTime, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(dockerapi.DockerContainerMetadata{
+		Error: dockerapi.CannotPullContainerError{FromError: errors.New("manifest unknown")},
+	}).AnyTimes()
+
+	for i := 0; i < maxTaskDryRunResults+5; i++ {
+		_, err := dockerTaskEngine.StartTaskDryRun(DryRunTaskDefinition{
+			ContainerDefinitions: []DryRunContainerDefinition{{Name: "app", Image: "busybox:latest"}},
+		})
+		require.NoError(t, err)
+	}
+	assert.Len(t, waitForTaskDryRuns(t, dockerTaskEngine), maxTaskDryRunResults)
+}
+
+func TestValidateDryRunTaskDefinition(t *testing.T) {
+	testCases := []struct {
+		name           string
+		def            DryRunTaskDefinition
+		taskENIEnabled bool
+		expectedErr    string
+	}{
+		{
+			name:        "no containers",
+			def:         DryRunTaskDefinition{},
+			expectedErr: "no container definitions",
+		},
+		{
+			name: "no container name",
+			def: DryRunTaskDefinition{ContainerDefinitions: []DryRunContainerDefinition{
+				{Image: "busybox"},
+			}},
+			expectedErr: "name is required",
+		},
+		{
+			name: "duplicate container names",
+			def: DryRunTaskDefinition{ContainerDefinitions: []DryRunContainerDefinition{
+				{Name: "app", Image: "busybox"},
+				{Name: "app", Image: "busybox"},
+			}},
+			expectedErr: "duplicate container definition name app",
+		},
+		{
+			name: "no image",
+			def: DryRunTaskDefinition{ContainerDefinitions: []DryRunContainerDefinition{
+				{Name: "app"},
+			}},
+			expectedErr: "image is required",
+		},
+		{
+			name: "invalid execution role",
+			def: DryRunTaskDefinition{
+				ExecutionRoleArn:     "not-an-arn",
+				ContainerDefinitions: []DryRunContainerDefinition{{Name: "app", Image: testPreloadECRImage}},
+			},
+			expectedErr: "invalid role arn",
+		},
+		{
+			name: "invalid task cpu",
+			def: DryRunTaskDefinition{
+				Cpu:                  "lots",
+				ContainerDefinitions: []DryRunContainerDefinition{{Name: "app", Image: "busybox"}},
+			},
+			expectedErr: "invalid task cpu",
+		},
+		{
+			name: "unsupported network mode",
+			def: DryRunTaskDefinition{
+				NetworkMode:          "overlay",
+				ContainerDefinitions: []DryRunContainerDefinition{{Name: "app", Image: "busybox"}},
+			},
+			expectedErr: "unsupported network mode",
+		},
+		{
+			name: "awsvpc without task eni",
+			def: DryRunTaskDefinition{
+				NetworkMode:          "awsvpc",
+				ContainerDefinitions: []DryRunContainerDefinition{{Name: "app", Image: "busybox"}},
+			},
+			expectedErr: "not enabled",
+		},
+		{
+			name: "awsvpc with task eni",
+			def: DryRunTaskDefinition{
+				NetworkMode:      "awsvpc",
+				ExecutionRoleArn: testPreloadRoleArn,
+				Cpu:              "1 vCPU",
+				Memory:           "2 GB",
+				ContainerDefinitions: []DryRunContainerDefinition{
+					{Name: "app", Image: testPreloadECRImage},
+					{Name: "sidecar", Image: "busybox"},
+				},
+			},
+			taskENIEnabled: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := validateDryRunTaskDefinition(testCase.def, testCase.taskENIEnabled)
+			if testCase.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.expectedErr)
+		})
+	}
+}
+
+func TestParseDryRunTaskCPUAndMemory(t *testing.T) {
+	for cpu, expected := range map[string]float64{"": 0, "512": 0.5, "2 vCPU": 2, "0.25vcpu": 0.25} {
+		vcpus, err := parseDryRunTaskCPU(cpu)
+		assert.NoError(t, err, cpu)
+		assert.Equal(t, expected, vcpus, cpu)
+	}
+	for memory, expected := range map[string]int64{"": 0, "512": 512, "1 GB": 1024, "0.5gb": 512} {
+		mib, err := parseDryRunTaskMemory(memory)
+		assert.NoError(t, err, memory)
+		assert.Equal(t, expected, mib, memory)
+	}
+
+	_, err := parseDryRunTaskCPU("-1")
+	assert.Error(t, err)
+	_, err = parseDryRunTaskMemory("1 TB")
+	assert.Error(t, err)
+}
+
+func TestStartTaskDryRunInvalidTaskDefinition(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := config.DefaultConfig()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	_, err := dockerTaskEngine.StartTaskDryRun(DryRunTaskDefinition{})
+	assert.Error(t, err)
+	assert.Empty(t, dockerTaskEngine.TaskDryRunResults())
+}
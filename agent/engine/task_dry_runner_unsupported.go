@@ -0,0 +1,26 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+// probeCNIPluginVersions is not supported on this platform
+func (dryRun *taskDryRun) probeCNIPluginVersions() error {
+	return skippedStepError{reason: "CNI plugin checks are not supported on this platform"}
+}
+
+// createTaskCgroup is not supported on this platform
+func (dryRun *taskDryRun) createTaskCgroup() error {
+	return skippedStepError{reason: "task cgroups are not supported on this platform"}
+}
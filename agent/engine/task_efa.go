@@ -0,0 +1,56 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/efa"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+var (
+	// efaDevices looks up the devices of the EFA of a network interface. It's swappable
+	// for testing.
+	efaDevices = efa.Devices
+	// efaLibfabricPath returns where libfabric is installed on the instance. It's swappable
+	// for testing.
+	efaLibfabricPath = efa.LibfabricPath
+)
+
+// attachTaskEFA looks up the devices of the EFA of the task, for its containers that use
+// it. It must be done before the ENI of the task is moved to the task network namespace,
+// which hides the interface from the EFA device. The devices are recorded on the task, so
+// they're known after the agent restarts
+func (engine *DockerTaskEngine) attachTaskEFA(task *apitask.Task) error {
+	if !task.RequiresEFA() || task.GetEFA() != nil {
+		return nil
+	}
+	eni := task.GetPrimaryENI()
+	devices, err := efaDevices(eni.MacAddress)
+	if err != nil {
+		return err
+	}
+	taskEFA := &apitask.EFA{
+		Devices:       devices,
+		LibfabricPath: efaLibfabricPath(),
+	}
+	logger.Info("Attaching EFA to task", logger.Fields{
+		field.TaskARN: task.Arn,
+		"devices":     devices,
+		"libfabric":   taskEFA.LibfabricPath,
+	})
+	task.SetEFA(taskEFA)
+	return nil
+}
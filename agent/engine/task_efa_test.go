@@ -0,0 +1,89 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/efa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func efaTestTask() *apitask.Task {
+	return &apitask.Task{
+		Arn: "arn:aws:ecs:us-west-2:1234567890:task/mycluster/task1",
+		ENIs: []*apieni.ENI{
+			{ID: "eni-1", MacAddress: "0a:1b:2c:3d:4e:5f", InterfaceType: apieni.EFAInterfaceType},
+		},
+		Containers: []*apicontainer.Container{{Name: "mpi", EFAEnabled: true}},
+	}
+}
+
+func TestAttachTaskEFA(t *testing.T) {
+	defer func() {
+		efaDevices = efa.Devices
+		efaLibfabricPath = efa.LibfabricPath
+	}()
+	lookups := 0
+	efaDevices = func(macAddress string) ([]string, error) {
+		lookups++
+		assert.Equal(t, "0a:1b:2c:3d:4e:5f", macAddress)
+		return []string{"/dev/infiniband/uverbs0"}, nil
+	}
+	efaLibfabricPath = func() string { return "/opt/amazon/efa" }
+
+	engine := &DockerTaskEngine{ctx: context.TODO()}
+	task := efaTestTask()
+	require.NoError(t, engine.attachTaskEFA(task))
+	assert.Equal(t, &apitask.EFA{
+		Devices:       []string{"/dev/infiniband/uverbs0"},
+		LibfabricPath: "/opt/amazon/efa",
+	}, task.GetEFA())
+
+	// the devices of an attached EFA aren't looked up again
+	require.NoError(t, engine.attachTaskEFA(task))
+	assert.Equal(t, 1, lookups)
+}
+
+func TestAttachTaskEFANotAttached(t *testing.T) {
+	defer func() { efaDevices = efa.Devices }()
+	efaDevices = func(macAddress string) ([]string, error) {
+		return nil, &efa.NotAttachedError{MACAddress: macAddress}
+	}
+
+	engine := &DockerTaskEngine{ctx: context.TODO()}
+	task := efaTestTask()
+	assert.Error(t, engine.attachTaskEFA(task))
+	assert.Nil(t, task.GetEFA())
+}
+
+func TestAttachTaskEFANotRequired(t *testing.T) {
+	defer func() { efaDevices = efa.Devices }()
+	efaDevices = func(macAddress string) ([]string, error) {
+		t.Fatal("the devices of tasks whose containers don't use the EFA shouldn't be looked up")
+		return nil, nil
+	}
+
+	engine := &DockerTaskEngine{ctx: context.TODO()}
+	task := efaTestTask()
+	task.Containers[0].EFAEnabled = false
+	assert.NoError(t, engine.attachTaskEFA(task))
+}
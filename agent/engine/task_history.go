@@ -0,0 +1,101 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+)
+
+// maxStoppedTaskHistorySize bounds the number of cleaned up tasks kept in the
+// history, whatever the retention
+const maxStoppedTaskHistorySize = 1000
+
+// StoppedTask is a task that was cleaned up and removed from the state of the
+// engine, with the containers it had when it was removed
+type StoppedTask struct {
+	Task        *apitask.Task
+	Containers  map[string]*apicontainer.DockerContainer
+	CleanedUpAt time.Time
+}
+
+// taskHistory keeps the tasks cleaned up by the engine for the configured
+// retention, so that they can still be inspected once they are gone from the
+// state
+type taskHistory struct {
+	engine *DockerTaskEngine
+	lock   sync.Mutex
+	// tasks are the cleaned up tasks, oldest first
+	tasks []StoppedTask
+}
+
+func newTaskHistory(engine *DockerTaskEngine) *taskHistory {
+	return &taskHistory{
+		engine: engine,
+	}
+}
+
+// StoppedTasks returns the tasks cleaned up within the retention of the
+// stopped task history, oldest first
+func (engine *DockerTaskEngine) StoppedTasks() []StoppedTask {
+	return engine.taskHistory.list()
+}
+
+func (history *taskHistory) add(task *apitask.Task, containers map[string]*apicontainer.DockerContainer) {
+	if history == nil || history.engine.cfg.StoppedTaskHistoryRetention < 0 {
+		return
+	}
+	history.lock.Lock()
+	defer history.lock.Unlock()
+
+	now := history.engine.time().Now()
+	history.tasks = append(history.tasks, StoppedTask{
+		Task:        task,
+		Containers:  containers,
+		CleanedUpAt: now,
+	})
+	if len(history.tasks) > maxStoppedTaskHistorySize {
+		history.tasks = history.tasks[len(history.tasks)-maxStoppedTaskHistorySize:]
+	}
+	history.prune(now)
+}
+
+func (history *taskHistory) list() []StoppedTask {
+	if history == nil {
+		return nil
+	}
+	history.lock.Lock()
+	defer history.lock.Unlock()
+
+	history.prune(history.engine.time().Now())
+	tasks := make([]StoppedTask, len(history.tasks))
+	copy(tasks, history.tasks)
+	return tasks
+}
+
+// prune drops the tasks cleaned up before the retention window. It must be
+// called with the lock held
+func (history *taskHistory) prune(now time.Time) {
+	cutoff := now.Add(-history.engine.cfg.StoppedTaskHistoryRetention)
+	expired := 0
+	for expired < len(history.tasks) && history.tasks[expired].CleanedUpAt.Before(cutoff) {
+		expired++
+	}
+	if expired > 0 {
+		history.tasks = append([]StoppedTask(nil), history.tasks[expired:]...)
+	}
+}
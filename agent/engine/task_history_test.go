@@ -0,0 +1,84 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	mock_ttime "github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTaskHistoryTestEngine(t *testing.T, retention time.Duration) (*DockerTaskEngine, *mock_ttime.MockTime, func()) {
+	ctrl := gomock.NewController(t)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	cfg := config.DefaultConfig()
+	cfg.StoppedTaskHistoryRetention = retention
+	engine := &DockerTaskEngine{cfg: &cfg, _time: mockTime}
+	engine.taskHistory = newTaskHistory(engine)
+	return engine, mockTime, ctrl.Finish
+}
+
+func TestStoppedTasks(t *testing.T) {
+	engine, mockTime, done := newTaskHistoryTestEngine(t, time.Hour)
+	defer done()
+
+	now := time.Now()
+	mockTime.EXPECT().Now().Return(now)
+	engine.taskHistory.add(&apitask.Task{Arn: "task1"}, nil)
+	mockTime.EXPECT().Now().Return(now.Add(30 * time.Minute))
+	engine.taskHistory.add(&apitask.Task{Arn: "task2"}, nil)
+
+	mockTime.EXPECT().Now().Return(now.Add(45 * time.Minute))
+	stopped := engine.StoppedTasks()
+	require.Len(t, stopped, 2)
+	assert.Equal(t, "task1", stopped[0].Task.Arn)
+	assert.Equal(t, now, stopped[0].CleanedUpAt)
+	assert.Equal(t, "task2", stopped[1].Task.Arn)
+
+	// Tasks cleaned up before the retention window are dropped.
+	mockTime.EXPECT().Now().Return(now.Add(75 * time.Minute))
+	stopped = engine.StoppedTasks()
+	require.Len(t, stopped, 1)
+	assert.Equal(t, "task2", stopped[0].Task.Arn)
+}
+
+func TestStoppedTasksMaxSize(t *testing.T) {
+	engine, mockTime, done := newTaskHistoryTestEngine(t, time.Hour)
+	defer done()
+
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	for i := 0; i < maxStoppedTaskHistorySize+1; i++ {
+		engine.taskHistory.add(&apitask.Task{Arn: fmt.Sprintf("task%d", i)}, nil)
+	}
+	stopped := engine.StoppedTasks()
+	require.Len(t, stopped, maxStoppedTaskHistorySize)
+	assert.Equal(t, "task1", stopped[0].Task.Arn)
+}
+
+func TestStoppedTasksDisabled(t *testing.T) {
+	engine, _, done := newTaskHistoryTestEngine(t, -1)
+	defer done()
+
+	engine.taskHistory.add(&apitask.Task{Arn: "task1"}, nil)
+	assert.Empty(t, engine.taskHistory.tasks)
+}
@@ -82,6 +82,13 @@ type acsTransition struct {
 	desiredStatus apitaskstatus.TaskStatus
 }
 
+// containerStopRequest asks the managed task to stop one of its running containers, with
+// the error recorded as the reason it stopped
+type containerStopRequest struct {
+	container *apicontainer.Container
+	err       apierrors.NamedError
+}
+
 // containerTransition defines the struct for a container to transition
 type containerTransition struct {
 	nextState      apicontainerstatus.ContainerStatus
@@ -139,6 +146,7 @@ type managedTask struct {
 	acsMessages                chan acsTransition
 	dockerMessages             chan dockerContainerChange
 	resourceStateChangeEvent   chan resourceStateChange
+	containerStopRequests      chan containerStopRequest
 	stateChangeEvents          chan statechange.Event
 	containerChangeEventStream *eventstream.EventStream
 
@@ -151,6 +159,17 @@ type managedTask struct {
 	// thing managing the container.
 	unexpectedStart sync.Once
 
+	// restartingContainers holds the names of the containers that exited and are
+	// waiting to be restarted per their restart policy
+	restartingContainers     map[string]struct{}
+	restartingContainersLock sync.Mutex
+
+	// probedContainers holds the names of the containers whose health is being
+	// checked by the agent with their health check probes, or whose docker health
+	// check results are being read
+	probedContainers     map[string]struct{}
+	probedContainersLock sync.Mutex
+
 	_time     ttime.Time
 	_timeOnce sync.Once
 
@@ -176,6 +195,7 @@ func (engine *DockerTaskEngine) newManagedTask(task *apitask.Task) *managedTask
 		acsMessages:                   make(chan acsTransition),
 		dockerMessages:                make(chan dockerContainerChange),
 		resourceStateChangeEvent:      make(chan resourceStateChange),
+		containerStopRequests:         make(chan containerStopRequest),
 		engine:                        engine,
 		cfg:                           engine.cfg,
 		stateChangeEvents:             engine.stateChangeEvents,
@@ -240,6 +260,7 @@ func (mtask *managedTask) overseeTask() {
 		field.TaskARN: mtask.Arn,
 	})
 	mtask.engine.checkTearDownPauseContainer(mtask.Task)
+	mtask.engine.releaseInstanceResources(mtask.Task)
 	mtask.cleanupCredentials()
 	if mtask.StopSequenceNumber != 0 {
 		logger.Debug("Marking done for this sequence", logger.Fields{
@@ -385,6 +406,9 @@ func (mtask *managedTask) waitEvent(stopWaiting <-chan struct{}) bool {
 		})
 		mtask.handleResourceStateChange(resChange)
 		return false
+	case stopRequest := <-mtask.containerStopRequests:
+		mtask.handleContainerStopRequest(stopRequest)
+		return false
 	case <-stopWaiting:
 		return true
 	}
@@ -454,6 +478,11 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 		return
 	}
 
+	// Running containers that exit may be restarted instead of being known as stopped
+	if mtask.handleContainerExit(container, event) {
+		return
+	}
+
 	// If this is a backwards transition stopped->running, the first time set it
 	// to be known running so it will be stopped. Subsequently ignore these backward transitions
 	mtask.handleStoppedToRunningContainerTransition(event.Status, container)
@@ -469,6 +498,10 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 		// Only update container metadata when status stays RUNNING
 		if event.Status == containerKnownStatus && event.Status == apicontainerstatus.ContainerRunning {
 			updateContainerMetadata(&event.DockerContainerMetadata, container, mtask.Task)
+			// The container may already be running when the agent starts
+			mtask.startHealthCheckProbes(container)
+			mtask.startHealthHistoryPolling(container)
+			mtask.startLogRelay(container)
 		}
 		return
 	}
@@ -493,7 +526,11 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 		mtask.handleManagedAgentStoppedTransition(container, execcmd.ExecuteCommandAgentName)
 	}
 
+	mtask.startHealthCheckProbes(container)
+	mtask.startHealthHistoryPolling(container)
+	mtask.startLogRelay(container)
 	mtask.RecordExecutionStoppedAt(container)
+	mtask.engine.lifecycleHooks.runAfterTransition(mtask.Task, container)
 	logger.Debug("Sending container change event to tcs", logger.Fields{
 		field.TaskARN:   mtask.Arn,
 		field.Container: container.Name,
@@ -713,6 +750,39 @@ func (mtask *managedTask) emitDockerContainerChange(change dockerContainerChange
 	}
 }
 
+func (mtask *managedTask) emitContainerStopRequest(request containerStopRequest) {
+	select {
+	case <-mtask.ctx.Done():
+		logger.Info("Unable to emit container stop request due to exit", logger.Fields{
+			field.TaskARN: mtask.Arn,
+		})
+	case mtask.containerStopRequests <- request:
+	}
+}
+
+// handleContainerStopRequest stops a running container of the task on behalf of the
+// engine, recording the reason on the container, and on the task when the container is
+// essential. The desired status keeps the container from being restarted per its
+// restart policy.
+func (mtask *managedTask) handleContainerStopRequest(request containerStopRequest) {
+	container := request.container
+	if container.GetKnownStatus() != apicontainerstatus.ContainerRunning || container.GetDesiredStatus().Terminal() {
+		return
+	}
+	logger.Info("Stopping container on request of the engine", logger.Fields{
+		field.TaskARN:   mtask.Arn,
+		field.Container: container.Name,
+		field.Error:     request.err,
+	})
+	container.ApplyingError = apierrors.NewNamedError(request.err)
+	if container.IsEssential() {
+		mtask.SetTerminalReason(request.err.Error())
+	}
+	container.SetDesiredStatus(apicontainerstatus.ContainerStopped)
+	mtask.engine.saveContainerData(container)
+	go mtask.engine.transitionContainer(mtask.Task, container, apicontainerstatus.ContainerStopped)
+}
+
 func (mtask *managedTask) emitACSTransition(transition acsTransition) {
 	select {
 	case <-mtask.ctx.Done():
@@ -1084,6 +1154,8 @@ func (mtask *managedTask) startContainerTransitions(transitionFunc containerTran
 	var reasons []error
 	blocked := make(map[string]apicontainer.DependsOn)
 	transitions := make(map[string]apicontainerstatus.ContainerStatus)
+	startLimit := mtask.containerStartConcurrency()
+	startsInProgress := mtask.containerStartsInProgress()
 	for _, cont := range mtask.Containers {
 		transition := mtask.containerNextState(cont)
 		if transition.reason != nil {
@@ -1095,6 +1167,22 @@ func (mtask *managedTask) startContainerTransitions(transitionFunc containerTran
 			continue
 		}
 
+		if startLimit > 0 && transition.actionRequired && isContainerStartTransition(transition.nextState) &&
+			cont.GetAppliedStatus() == apicontainerstatus.ContainerStatusNone {
+			if startsInProgress >= startLimit {
+				// The container will be transitioned once one of the containers being
+				// created or started finishes, so we're not deadlocked
+				logger.Debug("Delaying container transition, too many containers of the task are starting", logger.Fields{
+					field.TaskARN:   mtask.Arn,
+					field.Container: cont.Name,
+					"limit":         startLimit,
+				})
+				anyCanTransition = true
+				continue
+			}
+			startsInProgress++
+		}
+
 		// If the container is already in a transition, skip
 		if transition.actionRequired && !cont.SetAppliedStatus(transition.nextState) {
 			// At least one container is able to be moved forwards, so we're not deadlocked
@@ -1127,6 +1215,37 @@ func (mtask *managedTask) startContainerTransitions(transitionFunc containerTran
 	return anyCanTransition, blocked, transitions, reasons
 }
 
+// containerStartConcurrency returns the maximum number of containers of the task
+// that may be created or started at the same time. The task's own limit takes
+// precedence over the one in the agent configuration, zero means no limit
+func (mtask *managedTask) containerStartConcurrency() int {
+	if mtask.ContainerStartConcurrency > 0 {
+		return int(mtask.ContainerStartConcurrency)
+	}
+	if mtask.cfg == nil {
+		return 0
+	}
+	return mtask.cfg.TaskContainerStartConcurrency
+}
+
+// containerStartsInProgress returns the number of containers of the task that
+// are being created or started
+func (mtask *managedTask) containerStartsInProgress() int {
+	inProgress := 0
+	for _, cont := range mtask.Containers {
+		if isContainerStartTransition(cont.GetAppliedStatus()) {
+			inProgress++
+		}
+	}
+	return inProgress
+}
+
+// isContainerStartTransition returns true if transitioning a container to the
+// status creates or starts it
+func isContainerStartTransition(status apicontainerstatus.ContainerStatus) bool {
+	return status == apicontainerstatus.ContainerCreated || status == apicontainerstatus.ContainerRunning
+}
+
 // startResourceTransitions steps through each resource in the task and calls
 // the passed transition function when a transition should occur
 func (mtask *managedTask) startResourceTransitions(transitionFunc resourceTransitionFunc) (bool, map[string]string) {
@@ -1260,6 +1379,19 @@ func (mtask *managedTask) containerNextState(container *apicontainer.Container)
 	}
 	if blocked, err := dependencygraph.DependenciesAreResolved(container, mtask.Containers,
 		mtask.Task.GetExecutionCredentialsID(), mtask.credentialsManager, mtask.GetResources(), mtask.cfg); err != nil {
+		if err == dependencygraph.ErrOnFailureDependencySucceeded {
+			// The container only runs when its dependency fails, mark it as stopped
+			// without ever starting it
+			logger.Info("Skipping container, the container it depends on exited successfully", logger.Fields{
+				field.TaskARN:   mtask.Arn,
+				field.Container: container.Name,
+			})
+			container.SetDesiredStatus(apicontainerstatus.ContainerStopped)
+			return &containerTransition{
+				nextState:      apicontainerstatus.ContainerStopped,
+				actionRequired: false,
+			}
+		}
 		logger.Debug("Can't apply state to container yet due to unresolved dependencies", logger.Fields{
 			field.TaskARN:   mtask.Arn,
 			field.Container: container.Name,
@@ -1461,7 +1593,13 @@ func (mtask *managedTask) cleanupTask(taskStoppedDuration time.Duration) {
 	// speedy processing of other events for other tasks
 	// discard events while the task is being removed from engine state
 	go mtask.discardEvents()
-	mtask.engine.sweepTask(mtask.Task)
+	if mtask.engine.survivedHibernation(mtask.Arn) {
+		logger.Info("Keeping the containers of task that survived a hibernation", logger.Fields{
+			field.TaskARN: mtask.Arn,
+		})
+	} else {
+		mtask.engine.sweepTask(mtask.Task)
+	}
 	mtask.engine.deleteTask(mtask.Task)
 
 	// The last thing to do here is to cancel the context, which should cancel
@@ -358,6 +358,35 @@ func TestContainerNextState(t *testing.T) {
 	}
 }
 
+func TestContainerNextStateSkipsContainerWhenOnFailureDependencySucceeded(t *testing.T) {
+	dependency := apicontainer.NewContainerWithSteadyState(apicontainerstatus.ContainerRunning)
+	dependency.Name = "dependency"
+	dependency.KnownStatusUnsafe = apicontainerstatus.ContainerStopped
+	dependency.DesiredStatusUnsafe = apicontainerstatus.ContainerStopped
+	exitCode := 0
+	dependency.SetKnownExitCode(&exitCode)
+
+	container := apicontainer.NewContainerWithSteadyState(apicontainerstatus.ContainerRunning)
+	container.Name = "onfailure"
+	container.KnownStatusUnsafe = apicontainerstatus.ContainerPulled
+	container.DesiredStatusUnsafe = apicontainerstatus.ContainerRunning
+	container.SetDependsOn([]apicontainer.DependsOn{{ContainerName: dependency.Name, Condition: "ON_FAILURE"}})
+
+	task := &managedTask{
+		Task: &apitask.Task{
+			Containers:          []*apicontainer.Container{dependency, container},
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		},
+		engine: &DockerTaskEngine{},
+		cfg:    &config.Config{},
+	}
+	transition := task.containerNextState(container)
+	assert.Equal(t, apicontainerstatus.ContainerStopped, transition.nextState, "Mismatch for expected next state")
+	assert.False(t, transition.actionRequired, "Mismatch for expected actionable flag")
+	assert.NoError(t, transition.reason)
+	assert.Equal(t, apicontainerstatus.ContainerStopped, container.GetDesiredStatus())
+}
+
 func TestContainerNextStateWithTransitionDependencies(t *testing.T) {
 	testCases := []struct {
 		name                         string
@@ -689,6 +718,58 @@ func TestStartContainerTransitionsWhenForwardTransitionPossible(t *testing.T) {
 	}
 }
 
+func TestStartContainerTransitionsLimitsContainerStarts(t *testing.T) {
+	testCases := []struct {
+		name                string
+		taskConcurrency     int64
+		agentConcurrency    int
+		expectedTransitions int
+	}{
+		{name: "no limit", expectedTransitions: 3},
+		{name: "agent limit", agentConcurrency: 2, expectedTransitions: 1},
+		{name: "task limit overrides agent limit", taskConcurrency: 3, agentConcurrency: 1, expectedTransitions: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The first container is already being started and counts toward the limit
+			startingContainer := apicontainer.NewContainerWithSteadyState(apicontainerstatus.ContainerRunning)
+			startingContainer.Name = "starting"
+			startingContainer.KnownStatusUnsafe = apicontainerstatus.ContainerCreated
+			startingContainer.DesiredStatusUnsafe = apicontainerstatus.ContainerRunning
+			startingContainer.SetAppliedStatus(apicontainerstatus.ContainerRunning)
+			containers := []*apicontainer.Container{startingContainer}
+			for i := 0; i < 3; i++ {
+				cont := apicontainer.NewContainerWithSteadyState(apicontainerstatus.ContainerRunning)
+				cont.Name = fmt.Sprintf("container%d", i)
+				cont.KnownStatusUnsafe = apicontainerstatus.ContainerPulled
+				cont.DesiredStatusUnsafe = apicontainerstatus.ContainerRunning
+				containers = append(containers, cont)
+			}
+
+			task := &managedTask{
+				Task: &apitask.Task{
+					Containers:                containers,
+					DesiredStatusUnsafe:       apitaskstatus.TaskRunning,
+					ContainerStartConcurrency: tc.taskConcurrency,
+				},
+				engine: &DockerTaskEngine{},
+				cfg:    &config.Config{TaskContainerStartConcurrency: tc.agentConcurrency},
+			}
+
+			waitForTransitions := sync.WaitGroup{}
+			waitForTransitions.Add(tc.expectedTransitions)
+			canTransition, _, transitions, _ := task.startContainerTransitions(
+				func(cont *apicontainer.Container, nextStatus apicontainerstatus.ContainerStatus) {
+					assert.Equal(t, apicontainerstatus.ContainerCreated, nextStatus)
+					waitForTransitions.Done()
+				})
+			waitForTransitions.Wait()
+			assert.True(t, canTransition, "Mismatch for canTransition")
+			assert.Len(t, transitions, tc.expectedTransitions)
+		})
+	}
+}
+
 func TestStartContainerTransitionsWhenForwardTransitionIsNotPossible(t *testing.T) {
 	firstContainerName := "container1"
 	firstContainer := &apicontainer.Container{
@@ -1073,7 +1154,7 @@ func TestCleanupTask(t *testing.T) {
 	}()
 
 	// Expectations to verify that the task gets removed
-	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
+	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true).Times(2)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
 	mockState.EXPECT().RemoveTask(mTask.Task)
@@ -1143,7 +1224,7 @@ func TestCleanupTaskWaitsForStoppedSent(t *testing.T) {
 
 	// Expectations to verify that the task gets removed
 	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(
-		map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
+		map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true).Times(2)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
 	mockState.EXPECT().RemoveTask(mTask.Task)
@@ -1269,7 +1350,7 @@ func TestCleanupTaskENIs(t *testing.T) {
 	}()
 
 	// Expectations to verify that the task gets removed
-	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
+	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true).Times(2)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
 	mockState.EXPECT().RemoveTask(mTask.Task)
@@ -1384,7 +1465,7 @@ func TestCleanupTaskWithInvalidInterval(t *testing.T) {
 	}()
 
 	// Expectations to verify that the task gets removed
-	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
+	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true).Times(2)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
 	mockState.EXPECT().RemoveTask(mTask.Task)
@@ -1443,7 +1524,7 @@ func TestCleanupTaskWithResourceHappyPath(t *testing.T) {
 	}()
 
 	// Expectations to verify that the task gets removed
-	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
+	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true).Times(2)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
 	mockState.EXPECT().RemoveTask(mTask.Task)
@@ -1504,7 +1585,7 @@ func TestCleanupTaskWithResourceErrorPath(t *testing.T) {
 	}()
 
 	// Expectations to verify that the task gets removed
-	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
+	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true).Times(2)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
 	mockState.EXPECT().RemoveTask(mTask.Task)
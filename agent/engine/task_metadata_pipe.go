@@ -0,0 +1,79 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/cihub/seelog"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+// TaskMetadataPipeEnvVar is the environment variable set in the containers to the path of
+// the named pipe over which they can reach the task metadata and credentials endpoints
+const TaskMetadataPipeEnvVar = "ECS_CONTAINER_METADATA_PIPE"
+
+// TaskMetadataPipeServer serves the task metadata and credentials endpoints of a task over
+// a named pipe that's mounted in its containers
+type TaskMetadataPipeServer interface {
+	// ServeTask starts serving the endpoints of the task, if they are not served yet, and
+	// returns the path of its pipe
+	ServeTask(task *apitask.Task) (string, error)
+	// StopServingTask closes the pipe of the task
+	StopServingTask(taskARN string)
+}
+
+// SetTaskMetadataPipeServer sets the server used to serve the task metadata endpoints over
+// named pipes. It must be called before the engine is initialized.
+func (engine *DockerTaskEngine) SetTaskMetadataPipeServer(server TaskMetadataPipeServer) {
+	engine.taskMetadataPipeServer = server
+}
+
+// injectTaskMetadataPipe mounts the pipe of the task in the container and sets its path in
+// the environment of the container. The container is still created without the pipe if it
+// can't be served, as the endpoints remain reachable over the network in most setups.
+func (engine *DockerTaskEngine) injectTaskMetadataPipe(task *apitask.Task,
+	container *apicontainer.Container,
+	hostConfig *dockercontainer.HostConfig) {
+	if engine.taskMetadataPipeServer == nil || container.IsInternal() {
+		return
+	}
+	pipePath, err := engine.taskMetadataPipeServer.ServeTask(task)
+	if err != nil {
+		seelog.Warnf("Task engine [%s]: unable to serve task metadata pipe for container %s: %v",
+			task.Arn, container.Name, err)
+		return
+	}
+	hostConfig.Binds = append(hostConfig.Binds, pipePath+":"+pipePath)
+	container.MergeEnvironmentVariables(map[string]string{
+		TaskMetadataPipeEnvVar: pipePath,
+	})
+}
+
+// restoreTaskMetadataPipe serves the pipe of a task again after the agent is restarted, if
+// any of its containers was created with the pipe mounted
+func (engine *DockerTaskEngine) restoreTaskMetadataPipe(task *apitask.Task) {
+	if engine.taskMetadataPipeServer == nil {
+		return
+	}
+	for _, container := range task.Containers {
+		if _, ok := container.Environment[TaskMetadataPipeEnvVar]; !ok {
+			continue
+		}
+		if _, err := engine.taskMetadataPipeServer.ServeTask(task); err != nil {
+			seelog.Warnf("Task engine [%s]: unable to restore task metadata pipe: %v", task.Arn, err)
+		}
+		return
+	}
+}
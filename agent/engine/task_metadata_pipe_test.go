@@ -0,0 +1,98 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTaskMetadataPipePath = `\\.\pipe\ecs-task-metadata-taskid`
+
+// fakeTaskMetadataPipeServer records the tasks it serves
+type fakeTaskMetadataPipeServer struct {
+	served  []string
+	stopped []string
+	err     error
+}
+
+func (s *fakeTaskMetadataPipeServer) ServeTask(task *apitask.Task) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.served = append(s.served, task.Arn)
+	return testTaskMetadataPipePath, nil
+}
+
+func (s *fakeTaskMetadataPipeServer) StopServingTask(taskARN string) {
+	s.stopped = append(s.stopped, taskARN)
+}
+
+func TestInjectTaskMetadataPipe(t *testing.T) {
+	pipeServer := &fakeTaskMetadataPipeServer{}
+	engine := &DockerTaskEngine{}
+	task := &apitask.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/default/taskid"}
+	container := &apicontainer.Container{Name: "app"}
+	hostConfig := &dockercontainer.HostConfig{Binds: []string{`C:\data:C:\data`}}
+
+	engine.injectTaskMetadataPipe(task, container, hostConfig)
+	assert.Empty(t, container.Environment, "no pipe should be injected when pipes are disabled")
+
+	engine.SetTaskMetadataPipeServer(pipeServer)
+	engine.injectTaskMetadataPipe(task, container, hostConfig)
+	assert.Equal(t, []string{task.Arn}, pipeServer.served)
+	assert.Equal(t, testTaskMetadataPipePath, container.Environment[TaskMetadataPipeEnvVar])
+	assert.Equal(t, []string{`C:\data:C:\data`, testTaskMetadataPipePath + ":" + testTaskMetadataPipePath}, hostConfig.Binds)
+
+	internalContainer := &apicontainer.Container{Name: "pause", Type: apicontainer.ContainerCNIPause}
+	engine.injectTaskMetadataPipe(task, internalContainer, &dockercontainer.HostConfig{})
+	assert.Empty(t, internalContainer.Environment, "no pipe should be injected in internal containers")
+}
+
+func TestInjectTaskMetadataPipeServeError(t *testing.T) {
+	engine := &DockerTaskEngine{}
+	engine.SetTaskMetadataPipeServer(&fakeTaskMetadataPipeServer{err: errors.New("access denied")})
+	container := &apicontainer.Container{Name: "app"}
+	hostConfig := &dockercontainer.HostConfig{}
+
+	engine.injectTaskMetadataPipe(&apitask.Task{Arn: "arn"}, container, hostConfig)
+	assert.Empty(t, container.Environment)
+	assert.Empty(t, hostConfig.Binds)
+}
+
+func TestRestoreTaskMetadataPipe(t *testing.T) {
+	pipeServer := &fakeTaskMetadataPipeServer{}
+	engine := &DockerTaskEngine{}
+	engine.SetTaskMetadataPipeServer(pipeServer)
+
+	engine.restoreTaskMetadataPipe(&apitask.Task{
+		Arn:        "without-pipe",
+		Containers: []*apicontainer.Container{{Name: "app"}},
+	})
+	engine.restoreTaskMetadataPipe(&apitask.Task{
+		Arn: "with-pipe",
+		Containers: []*apicontainer.Container{
+			{Name: "app", Environment: map[string]string{TaskMetadataPipeEnvVar: testTaskMetadataPipePath}},
+			{Name: "sidecar", Environment: map[string]string{TaskMetadataPipeEnvVar: testTaskMetadataPipePath}},
+		},
+	})
+	assert.Equal(t, []string{"with-pipe"}, pipeServer.served)
+}
@@ -0,0 +1,87 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/networkpolicy"
+	"github.com/pkg/errors"
+)
+
+var (
+	// applyNetworkPolicy programs a network policy in the network namespace of a process.
+	// It's swappable for testing.
+	applyNetworkPolicy = networkpolicy.Apply
+	// blockInstanceMetadata blocks the access to the instance metadata service in the
+	// network namespace of a process, counting the attempts. It's swappable for testing.
+	blockInstanceMetadata = networkpolicy.BlockInstanceMetadata
+)
+
+// taskNetworkPolicy returns the network policy enforced for the task: the policy sent
+// with the task if any, the instance level policy otherwise. The instance level policy
+// is loaded once, the first time it's needed.
+func (engine *DockerTaskEngine) taskNetworkPolicy(task *apitask.Task) (*networkpolicy.Policy, error) {
+	if task.NetworkPolicy != nil {
+		if err := task.NetworkPolicy.Validate(); err != nil {
+			return nil, errors.Wrap(err, "invalid task network policy")
+		}
+		return task.NetworkPolicy, nil
+	}
+	if engine.cfg.TaskNetworkPolicyFile == "" {
+		return nil, nil
+	}
+	engine.instanceNetworkPolicyOnce.Do(func() {
+		engine.instanceNetworkPolicy, engine.instanceNetworkPolicyErr = networkpolicy.Load(
+			engine.cfg.TaskNetworkPolicyFile)
+	})
+	return engine.instanceNetworkPolicy, engine.instanceNetworkPolicyErr
+}
+
+// applyTaskNetworkPolicy enforces the network policy of the task in the network namespace
+// of its pause container, before any of its other containers start
+func (engine *DockerTaskEngine) applyTaskNetworkPolicy(task *apitask.Task, pausePID string) error {
+	policy, err := engine.taskNetworkPolicy(task)
+	if err != nil {
+		return err
+	}
+	if policy == nil || len(policy.Rules) == 0 {
+		return nil
+	}
+	logger.Info("Applying network policy to task", logger.Fields{
+		field.TaskARN: task.Arn,
+		"rules":       len(policy.Rules),
+	})
+	return applyNetworkPolicy(engine.ctx, pausePID, policy)
+}
+
+// countBlockedInstanceMetadataAttempts blocks the access of the task to the instance
+// metadata service in a chain of its own, counting the blocked attempts reported in the
+// task metadata. The access is already blocked by the CNI plugins, so failing to
+// count the attempts doesn't fail the task.
+func (engine *DockerTaskEngine) countBlockedInstanceMetadataAttempts(task *apitask.Task, pausePID string) {
+	counterPID := pausePID
+	counterNetNS, err := blockInstanceMetadata(engine.ctx, pausePID)
+	if err != nil {
+		logger.Warn("Unable to count the blocked instance metadata attempts of task", logger.Fields{
+			field.TaskARN: task.Arn,
+			field.Error:   err,
+		})
+		counterPID = ""
+		counterNetNS = ""
+	}
+	task.SetInstanceMetadataBlocked(counterPID, counterNetNS)
+	engine.saveTaskData(task)
+}
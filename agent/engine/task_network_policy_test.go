@@ -0,0 +1,128 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/networkpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupApplyNetworkPolicy records the policies applied, by pid
+func setupApplyNetworkPolicy() (map[string]*networkpolicy.Policy, func()) {
+	applied := make(map[string]*networkpolicy.Policy)
+	applyNetworkPolicy = func(ctx context.Context, pid string, policy *networkpolicy.Policy) error {
+		applied[pid] = policy
+		return nil
+	}
+	return applied, func() {
+		applyNetworkPolicy = networkpolicy.Apply
+	}
+}
+
+func TestApplyTaskNetworkPolicy(t *testing.T) {
+	applied, cleanup := setupApplyNetworkPolicy()
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, ioutil.WriteFile(path,
+		[]byte(`{"rules": [{"action": "deny", "cidr": "169.254.169.254/32"}]}`), 0600))
+	cfg := config.DefaultConfig()
+	cfg.TaskNetworkPolicyFile = path
+	engine := &DockerTaskEngine{cfg: &cfg, ctx: context.TODO()}
+
+	// The instance level policy is applied to tasks without a policy.
+	require.NoError(t, engine.applyTaskNetworkPolicy(&apitask.Task{Arn: "task1"}, "1"))
+	require.Contains(t, applied, "1")
+	assert.Equal(t, "169.254.169.254/32", applied["1"].Rules[0].CIDR)
+
+	// The policy of the task takes precedence.
+	taskPolicy := &networkpolicy.Policy{Rules: []networkpolicy.Rule{
+		{Action: networkpolicy.ActionDeny, CIDR: "10.0.0.0/8"},
+	}}
+	require.NoError(t, engine.applyTaskNetworkPolicy(&apitask.Task{Arn: "task2", NetworkPolicy: taskPolicy}, "2"))
+	assert.Equal(t, taskPolicy, applied["2"])
+
+	// Invalid task policies fail the task.
+	invalidPolicy := &networkpolicy.Policy{Rules: []networkpolicy.Rule{
+		{Action: "drop", CIDR: "10.0.0.0/8"},
+	}}
+	assert.Error(t, engine.applyTaskNetworkPolicy(&apitask.Task{Arn: "task3", NetworkPolicy: invalidPolicy}, "3"))
+	assert.NotContains(t, applied, "3")
+}
+
+func TestApplyTaskNetworkPolicyNotConfigured(t *testing.T) {
+	applied, cleanup := setupApplyNetworkPolicy()
+	defer cleanup()
+
+	cfg := config.DefaultConfig()
+	engine := &DockerTaskEngine{cfg: &cfg, ctx: context.TODO()}
+	require.NoError(t, engine.applyTaskNetworkPolicy(&apitask.Task{Arn: "task1"}, "1"))
+	assert.Empty(t, applied)
+}
+
+func TestApplyTaskNetworkPolicyInvalidFile(t *testing.T) {
+	applied, cleanup := setupApplyNetworkPolicy()
+	defer cleanup()
+
+	cfg := config.DefaultConfig()
+	cfg.TaskNetworkPolicyFile = filepath.Join(t.TempDir(), "missing.json")
+	engine := &DockerTaskEngine{cfg: &cfg, ctx: context.TODO()}
+	assert.Error(t, engine.applyTaskNetworkPolicy(&apitask.Task{Arn: "task1"}, "1"))
+	assert.Error(t, engine.applyTaskNetworkPolicy(&apitask.Task{Arn: "task2"}, "2"))
+	assert.Empty(t, applied)
+}
+
+func TestCountBlockedInstanceMetadataAttempts(t *testing.T) {
+	defer func() {
+		blockInstanceMetadata = networkpolicy.BlockInstanceMetadata
+	}()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, context.TODO(), &defaultConfig)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	var blockedPID string
+	blockInstanceMetadata = func(ctx context.Context, pid string) (string, error) {
+		blockedPID = pid
+		return "NS(1:2)", nil
+	}
+	task := &apitask.Task{Arn: "task1"}
+	engine.countBlockedInstanceMetadataAttempts(task, "1")
+	assert.Equal(t, "1", blockedPID)
+	blocked, counterPID, counterNetNS := task.GetInstanceMetadataBlock()
+	assert.True(t, blocked)
+	assert.Equal(t, "1", counterPID)
+	assert.Equal(t, "NS(1:2)", counterNetNS)
+
+	// The access is still reported as blocked when the attempts can't be counted.
+	blockInstanceMetadata = func(ctx context.Context, pid string) (string, error) {
+		return "", errors.New("iptables-restore failed")
+	}
+	task = &apitask.Task{Arn: "task2"}
+	engine.countBlockedInstanceMetadataAttempts(task, "2")
+	blocked, counterPID, counterNetNS = task.GetInstanceMetadataBlock()
+	assert.True(t, blocked)
+	assert.Empty(t, counterPID)
+	assert.Empty(t, counterNetNS)
+}
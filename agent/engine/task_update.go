@@ -0,0 +1,141 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
+	"github.com/pkg/errors"
+)
+
+// TaskUpdate is an update of the mutable fields of a running task
+type TaskUpdate struct {
+	TaskARN string
+	// RefreshEnvironmentFiles downloads the environment files of the task again
+	RefreshEnvironmentFiles bool
+	// RefreshSecrets resolves the SSM, Secrets Manager and Vault secrets of the task again
+	RefreshSecrets bool
+	// StopContainers are the names of the non essential containers of the task to
+	// stop, without stopping the task
+	StopContainers []string
+}
+
+// UpdateTask applies an update to a running task without stopping it. The resources
+// of the task are refreshed as a whole: if any of them can't be refreshed, the ones
+// that already were are rolled back and the task is left unchanged. The refreshed
+// environment files and secrets are used by the containers created afterwards, the
+// containers that were already created keep the values they were created with, except
+// for their file secrets, which are rewritten.
+func (engine *DockerTaskEngine) UpdateTask(update TaskUpdate) error {
+	engine.tasksLock.RLock()
+	mtask, ok := engine.managedTasks[update.TaskARN]
+	engine.tasksLock.RUnlock()
+	if !ok {
+		return errors.Errorf("task engine: task %s is not managed by the agent", update.TaskARN)
+	}
+	if mtask.GetDesiredStatus().Terminal() {
+		return errors.Errorf("task engine: task %s is stopping and can't be updated", update.TaskARN)
+	}
+
+	task := mtask.Task
+	var containersToStop []string
+	for _, name := range update.StopContainers {
+		container, ok := task.ContainerByName(name)
+		if !ok {
+			return errors.Errorf("task engine: container %s not found in task %s", name, update.TaskARN)
+		}
+		if container.IsInternal() || container.IsEssential() {
+			return errors.Errorf("task engine: container %s of task %s is essential or managed by the agent and can't be stopped on its own",
+				name, update.TaskARN)
+		}
+		if !container.GetDesiredStatus().Terminal() {
+			containersToStop = append(containersToStop, name)
+		}
+	}
+
+	if err := refreshTaskResources(task.GetResources(), update); err != nil {
+		return errors.Wrapf(err, "task engine: unable to update task %s", update.TaskARN)
+	}
+	if update.RefreshSecrets {
+		engine.rotateSecretFiles(task)
+	}
+
+	for _, name := range containersToStop {
+		container, _ := task.ContainerByName(name)
+		logger.Info("Stopping container on task update", logger.Fields{
+			field.TaskARN:   update.TaskARN,
+			field.Container: name,
+		})
+		container.SetDesiredStatus(apicontainerstatus.ContainerStopped)
+		engine.saveContainerData(container)
+		if container.GetKnownStatus() < apicontainerstatus.ContainerCreated {
+			// The container was not created yet, the task manager moves it to stopped
+			// along with its desired status
+			continue
+		}
+		go engine.transitionContainer(task, container, apicontainerstatus.ContainerStopped)
+	}
+	return nil
+}
+
+// refreshTaskResources refreshes the resources selected by the update. The refreshes
+// already applied are rolled back in reverse order if one of them fails, and they are
+// all committed otherwise.
+func refreshTaskResources(resources []taskresource.TaskResource, update TaskUpdate) error {
+	var rollbacks []func() error
+	var commits []func()
+	for _, resource := range resources {
+		if !shouldRefreshResource(resource, update) {
+			continue
+		}
+		refreshable, ok := resource.(taskresource.RefreshableResource)
+		if !ok {
+			continue
+		}
+		rollback, commit, err := refreshable.Refresh()
+		if err != nil {
+			for i := len(rollbacks) - 1; i >= 0; i-- {
+				if rollbackErr := rollbacks[i](); rollbackErr != nil {
+					logger.Error("Unable to roll back task resource refresh", logger.Fields{
+						field.TaskARN: update.TaskARN,
+						field.Error:   rollbackErr,
+					})
+				}
+			}
+			return errors.Wrapf(err, "unable to refresh resource %s", resource.GetName())
+		}
+		rollbacks = append(rollbacks, rollback)
+		commits = append(commits, commit)
+	}
+	for _, commit := range commits {
+		commit()
+	}
+	return nil
+}
+
+func shouldRefreshResource(resource taskresource.TaskResource, update TaskUpdate) bool {
+	switch resource.GetName() {
+	case envFiles.ResourceName:
+		return update.RefreshEnvironmentFiles
+	case ssmsecret.ResourceName, asmsecret.ResourceName, vaultsecret.ResourceName:
+		return update.RefreshSecrets
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	mock_taskresource "github.com/aws/amazon-ecs-agent/agent/taskresource/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// refreshableTaskResource records the refreshes, rollbacks and commits of a resource
+type refreshableTaskResource struct {
+	*mock_taskresource.MockTaskResource
+	refreshErr error
+	events     *[]string
+}
+
+func (resource *refreshableTaskResource) Refresh() (func() error, func(), error) {
+	if resource.refreshErr != nil {
+		return nil, nil, resource.refreshErr
+	}
+	id := resource.GetName()
+	*resource.events = append(*resource.events, "refresh "+id)
+	rollback := func() error {
+		*resource.events = append(*resource.events, "rollback "+id)
+		return nil
+	}
+	commit := func() {
+		*resource.events = append(*resource.events, "commit "+id)
+	}
+	return rollback, commit, nil
+}
+
+func newRefreshableTaskResource(ctrl *gomock.Controller, name string, refreshErr error,
+	events *[]string) *refreshableTaskResource {
+	mockResource := mock_taskresource.NewMockTaskResource(ctrl)
+	mockResource.EXPECT().GetName().Return(name).AnyTimes()
+	return &refreshableTaskResource{
+		MockTaskResource: mockResource,
+		refreshErr:       refreshErr,
+		events:           events,
+	}
+}
+
+func TestRefreshTaskResourcesCommitsAllRefreshes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var events []string
+	resources := []taskresource.TaskResource{
+		newRefreshableTaskResource(ctrl, ssmsecret.ResourceName, nil, &events),
+		newRefreshableTaskResource(ctrl, "envfile", nil, &events),
+	}
+	err := refreshTaskResources(resources, TaskUpdate{RefreshSecrets: true})
+	assert.NoError(t, err)
+	// The env files are not refreshed as they were not part of the update
+	assert.Equal(t, []string{"refresh ssmsecret", "commit ssmsecret"}, events)
+}
+
+func TestRefreshTaskResourcesRollsBackOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var events []string
+	resources := []taskresource.TaskResource{
+		newRefreshableTaskResource(ctrl, "envfile", nil, &events),
+		newRefreshableTaskResource(ctrl, ssmsecret.ResourceName, nil, &events),
+		newRefreshableTaskResource(ctrl, "asmsecret", errors.New("access denied"), &events),
+	}
+	err := refreshTaskResources(resources, TaskUpdate{RefreshEnvironmentFiles: true, RefreshSecrets: true})
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"refresh envfile",
+		"refresh ssmsecret",
+		"rollback ssmsecret",
+		"rollback envfile",
+	}, events)
+}
+
+func TestUpdateTaskValidatesContainersToStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	assert.Error(t, dockerTaskEngine.UpdateTask(TaskUpdate{TaskARN: "unknown"}))
+
+	task := testdata.LoadTask("sleep5")
+	task.Containers[0].Essential = true
+	task.Containers[0].SetDesiredStatus(apicontainerstatus.ContainerRunning)
+	dockerTaskEngine.managedTasks[task.Arn] = &managedTask{Task: task, ctx: ctx}
+
+	assert.Error(t, dockerTaskEngine.UpdateTask(TaskUpdate{
+		TaskARN:        task.Arn,
+		StopContainers: []string{"unknown"},
+	}))
+	assert.Error(t, dockerTaskEngine.UpdateTask(TaskUpdate{
+		TaskARN:        task.Arn,
+		StopContainers: []string{task.Containers[0].Name},
+	}))
+	assert.Equal(t, apicontainerstatus.ContainerRunning, task.Containers[0].GetDesiredStatus())
+
+	task.SetDesiredStatus(apitaskstatus.TaskStopped)
+	assert.Error(t, dockerTaskEngine.UpdateTask(TaskUpdate{TaskARN: task.Arn}))
+}
+
+func TestUpdateTaskStopsNonEssentialContainer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	task := testdata.LoadTask("sleep5")
+	container := task.Containers[0]
+	container.Essential = false
+	dockerTaskEngine.managedTasks[task.Arn] = &managedTask{Task: task, ctx: ctx}
+
+	assert.NoError(t, dockerTaskEngine.UpdateTask(TaskUpdate{
+		TaskARN:        task.Arn,
+		StopContainers: []string{container.Name},
+	}))
+	assert.Equal(t, apicontainerstatus.ContainerStopped, container.GetDesiredStatus())
+}
@@ -94,6 +94,12 @@ func (eniWatcher *ENIWatcher) Start() {
 	eniWatcher.performPeriodicReconciliation(defaultReconciliationInterval)
 }
 
+// Reconcile updates the state of the ENIs connected to the system right away, rather
+// than at the next periodic reconciliation
+func (eniWatcher *ENIWatcher) Reconcile() error {
+	return eniWatcher.reconcileOnce(false)
+}
+
 // Stop is used to invoke the cancellation routine
 func (eniWatcher *ENIWatcher) Stop() {
 	eniWatcher.cancel()
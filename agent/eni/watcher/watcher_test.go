@@ -220,7 +220,9 @@ func TestSendENIStateChangeWithAttachmentTypeInstanceENI(t *testing.T) {
 	eniChangeEvent := <-eventChannel
 	attachmentStateChange, ok := eniChangeEvent.(api.AttachmentStateChange)
 	require.True(t, ok)
-	assert.Equal(t, apieni.ENIAttached, attachmentStateChange.Attachment.Status)
+	eniAttachment, ok := attachmentStateChange.Attachment.(*apieni.ENIAttachment)
+	require.True(t, ok)
+	assert.Equal(t, apieni.ENIAttached, eniAttachment.Status)
 }
 
 // TestSendENIStateChangeWithAttachmentTypeTaskENI tests that we send the attachment state change
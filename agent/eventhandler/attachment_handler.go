@@ -18,46 +18,51 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
+	apiattachment "github.com/aws/amazon-ecs-agent/agent/api/attachment"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
 	"github.com/aws/amazon-ecs-agent/agent/data"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/cihub/seelog"
 )
 
+const (
+	// attachmentBatchWindow is how long the handler waits after receiving an
+	// attachment state change for more changes to submit in the same batch
+	attachmentBatchWindow = 100 * time.Millisecond
+)
+
 // AttachmentEventHandler is a handler that is responsible for submitting attachment state change events
-// to backend
+// to backend. It handles any type of attachment that implements the attachment.Attachment interface.
+// Attachments own their ack timers; the handler stops the timer of an attachment once its state change
+// has been submitted. State changes received within a short window of each other are submitted together
+// in a single SubmitAttachmentStateChanges call. If the batch fails, each of its state changes is retried
+// on its own, so that an attachment that keeps failing doesn't hold back the rest of the batch.
 type AttachmentEventHandler struct {
-	// backoff is the backoff object used in submitting attachment state change
-	backoff retry.Backoff
+	// newBackoff returns the backoff object used in retrying an attachment state change
+	newBackoff func() retry.Backoff
 
 	// dataClient is used to save any changes to an attachment's SentStatus
 	dataClient data.Client
 
-	// attachmentARNToHandler is a map from attachment ARN to the attachmentHandler that is
-	// responsible for handling the attachment
-	attachmentARNToHandler map[string]*attachmentHandler
-
-	// lock is used to safely access the attachmentARNToHandler map
-	lock sync.Mutex
-
-	client api.ECSClient
-	ctx    context.Context
-}
+	// pendingChanges is a map from attachment ARN to the latest state change received for the
+	// attachment that hasn't been submitted yet
+	pendingChanges map[string]*api.AttachmentStateChange
 
-// attachmentHandler is responsible for handling a certain attachment
-type attachmentHandler struct {
-	// attachmentARN is the arn of the attachment that the attachmentHandler is handling
-	attachmentARN string
+	// submitting is set when a goroutine is submitting the pending changes
+	submitting bool
 
-	// dataClient is used to save any changes to an attachment's SentStatus
-	dataClient data.Client
+	// inFlight is the set of the ARNs of the attachments whose state change is being submitted or
+	// retried, so that the same state change is never submitted twice at once
+	inFlight map[string]struct{}
 
-	// backoff is the backoff object used in submitting attachment state change
-	backoff retry.Backoff
+	// batchWindow is how long to wait for more state changes before submitting a batch
+	batchWindow time.Duration
 
-	// lock is used to ensure that the attached status of an attachment won't be sent multiple times
+	// lock is used to safely access pendingChanges, submitting and inFlight
 	lock sync.Mutex
 
 	client api.ECSClient
@@ -69,12 +74,16 @@ func NewAttachmentEventHandler(ctx context.Context,
 	dataClient data.Client,
 	client api.ECSClient) *AttachmentEventHandler {
 	return &AttachmentEventHandler{
-		ctx:                    ctx,
-		client:                 client,
-		dataClient:             dataClient,
-		attachmentARNToHandler: make(map[string]*attachmentHandler),
-		backoff: retry.NewExponentialBackoff(submitStateBackoffMin, submitStateBackoffMax,
-			submitStateBackoffJitterMultiple, submitStateBackoffMultiple),
+		ctx:            ctx,
+		client:         client,
+		dataClient:     dataClient,
+		pendingChanges: make(map[string]*api.AttachmentStateChange),
+		inFlight:       make(map[string]struct{}),
+		batchWindow:    attachmentBatchWindow,
+		newBackoff: func() retry.Backoff {
+			return retry.NewExponentialBackoff(submitStateBackoffMin, submitStateBackoffMax,
+				submitStateBackoffJitterMultiple, submitStateBackoffMultiple)
+		},
 	}
 }
 
@@ -92,64 +101,175 @@ func (eventHandler *AttachmentEventHandler) AddStateChangeEvent(change statechan
 		return fmt.Errorf("eventhandler: received malformed attachment state change event: %v", event)
 	}
 
-	attachmentARN := event.Attachment.AttachmentARN
 	eventHandler.lock.Lock()
-	if _, ok := eventHandler.attachmentARNToHandler[attachmentARN]; !ok {
-		eventHandler.attachmentARNToHandler[attachmentARN] = &attachmentHandler{
-			attachmentARN: attachmentARN,
-			dataClient:    eventHandler.dataClient,
-			client:        eventHandler.client,
-			ctx:           eventHandler.ctx,
-			backoff:       eventHandler.backoff,
+	defer eventHandler.lock.Unlock()
+
+	// A later state change for the same attachment replaces the pending one. This also dedupes the
+	// attached events that the udev watcher can send multiple times for an attachment, for example
+	// one from udev event and one from reconciliation loop
+	eventHandler.pendingChanges[event.Attachment.GetAttachmentARN()] = &event
+	if !eventHandler.submitting {
+		eventHandler.submitting = true
+		go eventHandler.submitPendingChanges()
+	}
+
+	return nil
+}
+
+// submitPendingChanges submits the pending attachment state changes in batches until there are none left
+func (eventHandler *AttachmentEventHandler) submitPendingChanges() {
+	for {
+		select {
+		case <-eventHandler.ctx.Done():
+			return
+		case <-time.After(eventHandler.batchWindow):
+		}
+
+		changes, ok := eventHandler.takePendingChanges()
+		if !ok {
+			return
 		}
+		eventHandler.submitAttachmentEvents(changes)
 	}
-	eventHandler.lock.Unlock()
+}
 
-	attachmentHandler := eventHandler.attachmentARNToHandler[attachmentARN]
-	go attachmentHandler.submitAttachmentEvent(&event)
+// takePendingChanges removes the pending state changes from the handler and returns them. If there
+// are none, it marks the handler as not submitting and returns false
+func (eventHandler *AttachmentEventHandler) takePendingChanges() ([]*api.AttachmentStateChange, bool) {
+	eventHandler.lock.Lock()
+	defer eventHandler.lock.Unlock()
 
-	return nil
+	if len(eventHandler.pendingChanges) == 0 {
+		eventHandler.submitting = false
+		return nil, false
+	}
+	changes := make([]*api.AttachmentStateChange, 0, len(eventHandler.pendingChanges))
+	for attachmentARN, change := range eventHandler.pendingChanges {
+		changes = append(changes, change)
+		delete(eventHandler.pendingChanges, attachmentARN)
+	}
+	return changes, true
 }
 
-// submitAttachmentEvent submits an attachment event to backend
-func (handler *attachmentHandler) submitAttachmentEvent(attachmentChange *api.AttachmentStateChange) {
-	// we need to lock the attachment handler to avoid sending an attachment state change for an attachment
-	// multiple times (this can happen when udev watcher sends multiple attached events for a certain attachment,
-	// for example one from udev event and one from reconciliation loop)
-	seelog.Debugf("AttachmentHandler: acquiring attachment lock before sending attachment state change for attachment %s", handler.attachmentARN)
-	handler.lock.Lock()
-	seelog.Debugf("AttachmentHandler: acquired attachment lock for attachment %s", handler.attachmentARN)
-	defer handler.lock.Unlock()
-
-	retry.RetryWithBackoffCtx(handler.ctx, handler.backoff, func() error {
-		return handler.submitAttachmentEventOnce(attachmentChange)
-	})
+// submitAttachmentEvents submits a batch of attachment state changes to backend. If the batch can't be
+// submitted, each of the state changes is retried individually in the background until it succeeds or
+// shouldn't be sent anymore
+func (eventHandler *AttachmentEventHandler) submitAttachmentEvents(changes []*api.AttachmentStateChange) {
+	toSend := eventHandler.startSubmitting(attachmentChangesToSend(changes))
+	if len(toSend) == 0 {
+		return
+	}
+	if err := eventHandler.submitAttachmentEventsOnce(toSend); err == nil {
+		eventHandler.doneSubmitting(toSend...)
+		return
+	}
+
+	for _, change := range toSend {
+		go eventHandler.retryAttachmentEvent(change)
+	}
 }
 
-func (handler *attachmentHandler) submitAttachmentEventOnce(attachmentChange *api.AttachmentStateChange) error {
-	if !attachmentChangeShouldBeSent(attachmentChange) {
-		seelog.Debugf("AttachmentHandler: not sending attachment state change [%s] as it should not be sent", attachmentChange.String())
-		// if the attachment state change should not be sent, we don't need to retry anymore so return nil here
-		return nil
+// startSubmitting marks the attachments of the state changes as in flight, and returns the state
+// changes whose attachment wasn't already in flight
+func (eventHandler *AttachmentEventHandler) startSubmitting(changes []*api.AttachmentStateChange) []*api.AttachmentStateChange {
+	eventHandler.lock.Lock()
+	defer eventHandler.lock.Unlock()
+
+	var toSend []*api.AttachmentStateChange
+	for _, change := range changes {
+		attachmentARN := change.Attachment.GetAttachmentARN()
+		if _, ok := eventHandler.inFlight[attachmentARN]; ok {
+			seelog.Debugf("AttachmentHandler: not sending attachment state change [%s] as it is already being sent",
+				change.String())
+			continue
+		}
+		eventHandler.inFlight[attachmentARN] = struct{}{}
+		toSend = append(toSend, change)
 	}
+	return toSend
+}
 
-	seelog.Infof("AttachmentHandler: sending attachment state change: %s", attachmentChange.String())
-	if err := handler.client.SubmitAttachmentStateChange(*attachmentChange); err != nil {
-		seelog.Errorf("AttachmentHandler: error submitting attachment state change [%s]: %v", attachmentChange.String(), err)
-		return err
+// doneSubmitting marks the attachments of the state changes as no longer in flight
+func (eventHandler *AttachmentEventHandler) doneSubmitting(changes ...*api.AttachmentStateChange) {
+	eventHandler.lock.Lock()
+	defer eventHandler.lock.Unlock()
+
+	for _, change := range changes {
+		delete(eventHandler.inFlight, change.Attachment.GetAttachmentARN())
 	}
-	seelog.Debugf("AttachmentHandler: submitted attachment state change: %s", attachmentChange.String())
+}
 
-	attachmentChange.Attachment.SetSentStatus()
-	attachmentChange.Attachment.StopAckTimer()
+// retryAttachmentEvent retries submitting a single attachment state change with its own backoff, until
+// it succeeds or the attachment state change shouldn't be sent anymore
+func (eventHandler *AttachmentEventHandler) retryAttachmentEvent(change *api.AttachmentStateChange) {
+	defer eventHandler.doneSubmitting(change)
 
-	err := handler.dataClient.SaveENIAttachment(attachmentChange.Attachment)
-	if err != nil {
-		seelog.Errorf("AttachmentHandler: error saving state after submitted attachment state change [%s]: %v", attachmentChange.String(), err)
+	backoff := eventHandler.newBackoff()
+	select {
+	case <-eventHandler.ctx.Done():
+		return
+	case <-time.After(backoff.Duration()):
+	}
+
+	retry.RetryWithBackoffCtx(eventHandler.ctx, backoff, func() error {
+		// Attachments may expire, or be sent along with a task state change, while we are retrying
+		toSend := attachmentChangesToSend([]*api.AttachmentStateChange{change})
+		if len(toSend) == 0 {
+			// if the attachment state change shouldn't be sent, we don't need to retry anymore so return nil here
+			return nil
+		}
+		return eventHandler.submitAttachmentEventsOnce(toSend)
+	})
+}
+
+// submitAttachmentEventsOnce makes a single SubmitAttachmentStateChanges call for the given state changes,
+// and marks their attachments as sent if it succeeds
+func (eventHandler *AttachmentEventHandler) submitAttachmentEventsOnce(toSend []*api.AttachmentStateChange) error {
+	changes := make([]api.AttachmentStateChange, 0, len(toSend))
+	for _, change := range toSend {
+		seelog.Infof("AttachmentHandler: sending attachment state change: %s", change.String())
+		changes = append(changes, *change)
+	}
+	if err := eventHandler.client.SubmitAttachmentStateChanges(changes); err != nil {
+		seelog.Errorf("AttachmentHandler: error submitting %d attachment state changes: %v", len(changes), err)
+		return err
+	}
+
+	for _, change := range toSend {
+		seelog.Debugf("AttachmentHandler: submitted attachment state change: %s", change.String())
+		change.Attachment.SetSentStatus()
+		change.Attachment.StopAckTimer()
+		if err := saveAttachment(eventHandler.dataClient, change.Attachment); err != nil {
+			seelog.Errorf("AttachmentHandler: error saving state after submitted attachment state change [%s]: %v",
+				change.String(), err)
+		}
 	}
 	return nil
 }
 
+// attachmentChangesToSend returns the attachment state changes that should still be sent to backend
+func attachmentChangesToSend(changes []*api.AttachmentStateChange) []*api.AttachmentStateChange {
+	var toSend []*api.AttachmentStateChange
+	for _, change := range changes {
+		if !attachmentChangeShouldBeSent(change) {
+			seelog.Debugf("AttachmentHandler: not sending attachment state change [%s] as it should not be sent", change.String())
+			continue
+		}
+		toSend = append(toSend, change)
+	}
+	return toSend
+}
+
+// saveAttachment saves the attachment using the data client method for its type
+func saveAttachment(dataClient data.Client, attachment apiattachment.Attachment) error {
+	switch typedAttachment := attachment.(type) {
+	case *apieni.ENIAttachment:
+		return dataClient.SaveENIAttachment(typedAttachment)
+	default:
+		return fmt.Errorf("unable to save attachment of type %s: not supported", attachment.GetAttachmentType())
+	}
+}
+
 // attachmentChangeShouldBeSent checks whether an attachment state change should be sent to backend
 func attachmentChangeShouldBeSent(attachmentChange *api.AttachmentStateChange) bool {
 	return !attachmentChange.Attachment.HasExpired() && !attachmentChange.Attachment.IsSent()
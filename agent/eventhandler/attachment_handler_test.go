@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
+	apiattachment "github.com/aws/amazon-ecs-agent/agent/api/attachment"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
 	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
 	mock_api "github.com/aws/amazon-ecs-agent/agent/api/mocks"
@@ -53,7 +54,7 @@ func TestSendAttachmentEvent(t *testing.T) {
 	timeoutFunc := func() {
 		t.Error("Timeout sending ENI attach status")
 	}
-	assert.NoError(t, attachmentEvent.Attachment.StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent).StartTimer(timeoutFunc))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
@@ -62,9 +63,10 @@ func TestSendAttachmentEvent(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	client.EXPECT().SubmitAttachmentStateChange(gomock.Any()).Return(nil).Do(func(change api.AttachmentStateChange) {
-		assert.NotNil(t, change.Attachment)
-		assert.Equal(t, attachmentARN, change.Attachment.AttachmentARN)
+	client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(nil).Do(func(changes []api.AttachmentStateChange) {
+		require.Len(t, changes, 1)
+		assert.NotNil(t, changes[0].Attachment)
+		assert.Equal(t, attachmentARN, changes[0].Attachment.GetAttachmentARN())
 		wg.Done()
 	})
 
@@ -83,15 +85,14 @@ func TestSendAttachmentEventRetries(t *testing.T) {
 	timeoutFunc := func() {
 		t.Error("Timeout sending ENI attach status")
 	}
-	assert.NoError(t, attachmentEvent.Attachment.StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent).StartTimer(timeoutFunc))
 
 	dataClient, cleanup := newTestDataClient(t)
 	defer cleanup()
 	ctx, cancel := context.WithCancel(context.Background())
 	handler := NewAttachmentEventHandler(ctx, dataClient, client)
 	// use smaller backoff value for unit test
-	handler.backoff = retry.NewExponentialBackoff(xSubmitStateBackoffMin, xSubmitStateBackoffMax,
-		xSubmitStateBackoffJitterMultiple, xSubmitStateBackoffMultiple)
+	handler.newBackoff = newTestBackoff
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -100,10 +101,10 @@ func TestSendAttachmentEventRetries(t *testing.T) {
 	retriable := apierrors.NewRetriableError(apierrors.NewRetriable(true), errors.New("test"))
 
 	gomock.InOrder(
-		client.EXPECT().SubmitAttachmentStateChange(gomock.Any()).Return(retriable).Do(func(interface{}) { wg.Done() }),
-		client.EXPECT().SubmitAttachmentStateChange(gomock.Any()).Return(nil).Do(func(change api.AttachmentStateChange) {
-			assert.NotNil(t, change.Attachment)
-			assert.Equal(t, attachmentARN, change.Attachment.AttachmentARN)
+		client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(retriable).Do(func(interface{}) { wg.Done() }),
+		client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(nil).Do(func(changes []api.AttachmentStateChange) {
+			require.Len(t, changes, 1)
+			assert.Equal(t, attachmentARN, changes[0].Attachment.GetAttachmentARN())
 			wg.Done()
 		}),
 	)
@@ -125,30 +126,36 @@ func TestSendMultipleAttachmentEventsDifferentAttachments(t *testing.T) {
 	timeoutFunc := func() {
 		t.Error("Timeout sending ENI attach status")
 	}
-	assert.NoError(t, attachmentEvent1.Attachment.StartTimer(timeoutFunc))
-	assert.NoError(t, attachmentEvent2.Attachment.StartTimer(timeoutFunc))
-	assert.NoError(t, attachmentEvent3.Attachment.StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent1).StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent2).StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent3).StartTimer(timeoutFunc))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
+	handler.batchWindow = time.Hour
 	defer cancel()
 
 	var wg sync.WaitGroup
-	wg.Add(3)
+	wg.Add(1)
 
 	submittedAttachments := make(map[string]bool) // note down submitted attachments
-	mapLock := sync.Mutex{}                       // lock to protect the above map
-	client.EXPECT().SubmitAttachmentStateChange(gomock.Any()).Times(3).Return(nil).Do(func(change api.AttachmentStateChange) {
-		mapLock.Lock()
-		defer mapLock.Unlock()
-
-		submittedAttachments[change.Attachment.AttachmentARN] = true
+	client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(nil).Do(func(changes []api.AttachmentStateChange) {
+		for _, change := range changes {
+			submittedAttachments[change.Attachment.GetAttachmentARN()] = true
+		}
 		wg.Done()
 	})
 
 	require.NoError(t, handler.AddStateChangeEvent(attachmentEvent1))
 	require.NoError(t, handler.AddStateChangeEvent(attachmentEvent2))
 	require.NoError(t, handler.AddStateChangeEvent(attachmentEvent3))
+	// The same attachment reported twice is only submitted once
+	require.NoError(t, handler.AddStateChangeEvent(attachmentEvent3))
+
+	// All of the events are submitted in a single batch
+	changes, ok := handler.takePendingChanges()
+	require.True(t, ok)
+	handler.submitAttachmentEvents(changes)
 
 	wg.Wait()
 	assert.Equal(t, 3, len(submittedAttachments))
@@ -157,6 +164,128 @@ func TestSendMultipleAttachmentEventsDifferentAttachments(t *testing.T) {
 	assert.Contains(t, submittedAttachments, "attachmentARN3")
 }
 
+func TestSubmitAttachmentEventsRetriesEachAttachment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+
+	failingEvent := attachmentEvent("attachmentARN1")
+	succeedingEvent := attachmentEvent("attachmentARN2")
+
+	timeoutFunc := func() {
+		t.Error("Timeout sending ENI attach status")
+	}
+	assert.NoError(t, eniAttachment(succeedingEvent).StartTimer(timeoutFunc))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
+	handler.newBackoff = newTestBackoff
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var retriedOnce sync.Once
+
+	retriable := apierrors.NewRetriableError(apierrors.NewRetriable(true), errors.New("test"))
+	gomock.InOrder(
+		client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(retriable).Do(func(changes []api.AttachmentStateChange) {
+			assert.Len(t, changes, 2)
+		}),
+		client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).DoAndReturn(func(changes []api.AttachmentStateChange) error {
+			require.Len(t, changes, 1)
+			if changes[0].Attachment.GetAttachmentARN() == "attachmentARN1" {
+				retriedOnce.Do(wg.Done)
+				return retriable
+			}
+			wg.Done()
+			return nil
+		}).MinTimes(2),
+	)
+
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&failingEvent, &succeedingEvent})
+
+	// The attachment that keeps failing doesn't prevent the other one from being submitted
+	wg.Wait()
+	assert.True(t, succeedingEvent.Attachment.IsSent())
+	assert.False(t, failingEvent.Attachment.IsSent())
+}
+
+func TestSubmitAttachmentEventsConcurrently(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+
+	attachmentEvent := attachmentEvent(attachmentARN)
+	duplicateEvent := attachmentEvent
+	assert.NoError(t, eniAttachment(attachmentEvent).StartTimer(func() {
+		t.Error("Timeout sending ENI attach status")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
+	defer cancel()
+
+	submitting := make(chan struct{})
+	release := make(chan struct{})
+	// the state change is submitted once, although it's submitted again while in flight
+	client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).DoAndReturn(func(changes []api.AttachmentStateChange) error {
+		close(submitting)
+		<-release
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		handler.submitAttachmentEvents([]*api.AttachmentStateChange{&attachmentEvent})
+		close(done)
+	}()
+	<-submitting
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&duplicateEvent})
+	close(release)
+	<-done
+
+	assert.True(t, attachmentEvent.Attachment.IsSent())
+	assert.Empty(t, handler.inFlight)
+}
+
+func TestSubmitAttachmentEventWhileRetrying(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+
+	attachmentEvent := attachmentEvent(attachmentARN)
+	duplicateEvent := attachmentEvent
+	assert.NoError(t, eniAttachment(attachmentEvent).StartTimer(func() {
+		t.Error("Timeout sending ENI attach status")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
+	retrying := make(chan struct{})
+	handler.newBackoff = func() retry.Backoff {
+		<-retrying
+		return newTestBackoff()
+	}
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	retriable := apierrors.NewRetriableError(apierrors.NewRetriable(true), errors.New("test"))
+	gomock.InOrder(
+		client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(retriable),
+		// only the retry submits the state change again
+		client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).DoAndReturn(func(changes []api.AttachmentStateChange) error {
+			wg.Done()
+			return nil
+		}),
+	)
+
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&attachmentEvent})
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&duplicateEvent})
+	close(retrying)
+	wg.Wait()
+}
+
 func TestSubmitAttachmentEventSucceeds(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -170,51 +299,71 @@ func TestSubmitAttachmentEventSucceeds(t *testing.T) {
 	timeoutFunc := func() {
 		t.Error("Timeout sending ENI attach status")
 	}
-	assert.NoError(t, attachmentEvent.Attachment.StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent).StartTimer(timeoutFunc))
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := &attachmentHandler{
-		client:     client,
-		dataClient: dataClient,
-		ctx:        ctx,
-	}
+	handler := NewAttachmentEventHandler(ctx, dataClient, client)
 	defer cancel()
 
-	client.EXPECT().SubmitAttachmentStateChange(gomock.Any()).Return(nil).Do(func(change api.AttachmentStateChange) {
-		assert.NotNil(t, change.Attachment)
-		assert.Equal(t, attachmentARN, change.Attachment.AttachmentARN)
+	client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(nil).Do(func(changes []api.AttachmentStateChange) {
+		require.Len(t, changes, 1)
+		assert.Equal(t, attachmentARN, changes[0].Attachment.GetAttachmentARN())
 	})
 
-	handler.submitAttachmentEvent(&attachmentEvent)
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&attachmentEvent})
 
-	assert.True(t, attachmentEvent.Attachment.AttachStatusSent)
+	assert.True(t, eniAttachment(attachmentEvent).AttachStatusSent)
 	res, err := dataClient.GetENIAttachments()
 	assert.NoError(t, err)
 	assert.Len(t, res, 1)
 }
 
+func TestSubmitAttachmentEventOnlySendsUnsentAttachments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+
+	sentEvent := attachmentEvent("attachmentARN1")
+	sentEvent.Attachment.SetSentStatus()
+	unsentEvent := attachmentEvent("attachmentARN2")
+
+	timeoutFunc := func() {
+		t.Error("Timeout sending ENI attach status")
+	}
+	assert.NoError(t, eniAttachment(unsentEvent).StartTimer(timeoutFunc))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
+	defer cancel()
+
+	client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Return(nil).Do(func(changes []api.AttachmentStateChange) {
+		require.Len(t, changes, 1)
+		assert.Equal(t, "attachmentARN2", changes[0].Attachment.GetAttachmentARN())
+	})
+
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&sentEvent, &unsentEvent})
+	assert.True(t, unsentEvent.Attachment.IsSent())
+}
+
 func TestSubmitAttachmentEventAttachmentExpired(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	client := mock_api.NewMockECSClient(ctrl)
 
 	attachmentEvent := attachmentEvent(attachmentARN)
-	attachmentEvent.Attachment.ExpiresAt = time.Now().Add(100 * time.Millisecond)
+	eniAttachment(attachmentEvent).ExpiresAt = time.Now().Add(100 * time.Millisecond)
 
 	// wait until eni attachment expires
 	time.Sleep(200 * time.Millisecond)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := &attachmentHandler{
-		client: client,
-		ctx:    ctx,
-	}
+	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
 	defer cancel()
 
-	handler.submitAttachmentEvent(&attachmentEvent)
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&attachmentEvent})
 
-	// no SubmitAttachmentStateChange should happen and attach status should not be sent
-	assert.False(t, attachmentEvent.Attachment.AttachStatusSent)
+	// no SubmitAttachmentStateChanges should happen and attach status should not be sent
+	assert.False(t, eniAttachment(attachmentEvent).AttachStatusSent)
 }
 
 func TestSubmitAttachmentEventAttachmentIsSent(t *testing.T) {
@@ -228,21 +377,27 @@ func TestSubmitAttachmentEventAttachmentIsSent(t *testing.T) {
 	timeoutFunc := func() {
 		t.Error("Timeout sending ENI attach status")
 	}
-	assert.NoError(t, attachmentEvent.Attachment.StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent).StartTimer(timeoutFunc))
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := &attachmentHandler{
-		client: client,
-		ctx:    ctx,
-	}
+	handler := NewAttachmentEventHandler(ctx, data.NewNoopClient(), client)
 	defer cancel()
 
-	handler.submitAttachmentEvent(&attachmentEvent)
+	handler.submitAttachmentEvents([]*api.AttachmentStateChange{&attachmentEvent})
 
-	// no SubmitAttachmentStateChange should happen
+	// no SubmitAttachmentStateChanges should happen
 	attachmentEvent.Attachment.StopAckTimer()
 }
 
+func newTestBackoff() retry.Backoff {
+	return retry.NewExponentialBackoff(xSubmitStateBackoffMin, xSubmitStateBackoffMax,
+		xSubmitStateBackoffJitterMultiple, xSubmitStateBackoffMultiple)
+}
+
+func TestSaveAttachmentUnsupportedType(t *testing.T) {
+	assert.Error(t, saveAttachment(data.NewNoopClient(), &testAttachment{}))
+}
+
 func TestAttachmentChangeShouldBeSent(t *testing.T) {
 	attachmentEvent := attachmentEvent(attachmentARN)
 	assert.True(t, attachmentChangeShouldBeSent(&attachmentEvent))
@@ -250,7 +405,7 @@ func TestAttachmentChangeShouldBeSent(t *testing.T) {
 
 func TestAttachmentChangeShouldBeSentAttachmentExpired(t *testing.T) {
 	attachmentEvent := attachmentEvent(attachmentARN)
-	attachmentEvent.Attachment.ExpiresAt = time.Now()
+	eniAttachment(attachmentEvent).ExpiresAt = time.Now()
 	time.Sleep(10 * time.Millisecond)
 
 	assert.False(t, attachmentChangeShouldBeSent(&attachmentEvent))
@@ -272,3 +427,16 @@ func attachmentEvent(attachmentARN string) api.AttachmentStateChange {
 		},
 	}
 }
+
+func eniAttachment(change api.AttachmentStateChange) *apieni.ENIAttachment {
+	return change.Attachment.(*apieni.ENIAttachment)
+}
+
+// testAttachment is an attachment type that the handler doesn't know how to persist
+type testAttachment struct {
+	apiattachment.Attachment
+}
+
+func (*testAttachment) GetAttachmentType() string {
+	return "test"
+}
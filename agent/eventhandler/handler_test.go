@@ -50,7 +50,7 @@ func TestHandleEngineEvent(t *testing.T) {
 	timeoutFunc := func() {
 		t.Error("Timeout sending ENI attach status")
 	}
-	assert.NoError(t, attachmentEvent.Attachment.StartTimer(timeoutFunc))
+	assert.NoError(t, eniAttachment(attachmentEvent).StartTimer(timeoutFunc))
 
 	client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
 		assert.Equal(t, 2, len(change.Containers))
@@ -59,9 +59,9 @@ func TestHandleEngineEvent(t *testing.T) {
 		wg.Done()
 	})
 
-	client.EXPECT().SubmitAttachmentStateChange(gomock.Any()).Do(func(change api.AttachmentStateChange) {
-		assert.NotNil(t, change.Attachment)
-		assert.Equal(t, "attachmentARN", change.Attachment.AttachmentARN)
+	client.EXPECT().SubmitAttachmentStateChanges(gomock.Any()).Do(func(changes []api.AttachmentStateChange) {
+		assert.Len(t, changes, 1)
+		assert.Equal(t, "attachmentARN", changes[0].Attachment.GetAttachmentARN())
 		wg.Done()
 	})
 
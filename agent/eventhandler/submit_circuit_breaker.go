@@ -0,0 +1,196 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventhandler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// submitCircuitBreakerThreshold is the number of consecutive server errors
+	// from ECS after which all state change submissions are paused
+	submitCircuitBreakerThreshold = 5
+
+	// submitCircuitBreakerOpen* configure how long submissions are paused for
+	// once the circuit breaker trips. The pause grows every time the probe
+	// submission made after a pause fails
+	submitCircuitBreakerOpenMin            = 10 * time.Second
+	submitCircuitBreakerOpenMax            = 5 * time.Minute
+	submitCircuitBreakerOpenJitterMultiple = 0.20
+	submitCircuitBreakerOpenMultiple       = 2
+)
+
+// circuitState is the state of the submitCircuitBreaker
+type circuitState int
+
+const (
+	// circuitClosed lets all submissions through
+	circuitClosed circuitState = iota
+	// circuitOpen pauses all submissions until the open timer fires
+	circuitOpen
+	// circuitHalfOpen lets a single probe submission through. The result of
+	// the probe decides whether the circuit closes or opens again
+	circuitHalfOpen
+)
+
+func (state circuitState) String() string {
+	switch state {
+	case circuitClosed:
+		return "CLOSED"
+	case circuitOpen:
+		return "OPEN"
+	case circuitHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// submitCircuitBreaker is shared by all the task event goroutines of the
+// TaskHandler. When ECS keeps returning server errors, it stops every
+// goroutine from retrying on its own schedule and instead pauses all of them
+// behind a single timer, after which one probe submission is let through
+type submitCircuitBreaker struct {
+	threshold           int
+	openBackoff         retry.Backoff
+	state               circuitState
+	consecutiveFailures int
+	// probing is set when the probe submission for the half open state is
+	// in flight
+	probing bool
+	// stateChange is closed and replaced every time the state changes, so
+	// that waiters can block on it
+	stateChange chan struct{}
+	lock        sync.Mutex
+}
+
+func newSubmitCircuitBreaker(threshold int, openBackoff retry.Backoff) *submitCircuitBreaker {
+	return &submitCircuitBreaker{
+		threshold:   threshold,
+		openBackoff: openBackoff,
+		state:       circuitClosed,
+		stateChange: make(chan struct{}),
+	}
+}
+
+// wait blocks until a submission is allowed. It returns false if the context
+// is canceled before that happens
+func (breaker *submitCircuitBreaker) wait(ctx context.Context) bool {
+	for {
+		breaker.lock.Lock()
+		switch {
+		case breaker.state == circuitClosed:
+			breaker.lock.Unlock()
+			return true
+		case breaker.state == circuitHalfOpen && !breaker.probing:
+			breaker.probing = true
+			breaker.lock.Unlock()
+			return true
+		}
+		stateChange := breaker.stateChange
+		breaker.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-stateChange:
+		}
+	}
+}
+
+// recordResult updates the state of the circuit breaker based on the result
+// of a submission that was allowed by wait
+func (breaker *submitCircuitBreaker) recordResult(err error) {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	if !isServerError(err) {
+		breaker.consecutiveFailures = 0
+		if breaker.state != circuitClosed {
+			seelog.Infof("TaskHandler: State change submission succeeded, resuming submissions")
+			breaker.openBackoff.Reset()
+			breaker.setStateUnsafe(circuitClosed)
+		}
+		return
+	}
+
+	breaker.consecutiveFailures++
+	switch breaker.state {
+	case circuitClosed:
+		if breaker.consecutiveFailures >= breaker.threshold {
+			breaker.openUnsafe(err)
+		}
+	case circuitHalfOpen:
+		breaker.openUnsafe(err)
+	}
+}
+
+// openUnsafe pauses all submissions and starts the timer that half opens
+// the circuit
+func (breaker *submitCircuitBreaker) openUnsafe(err error) {
+	duration := breaker.openBackoff.Duration()
+	seelog.Warnf("TaskHandler: %d consecutive server errors submitting state changes, last error: %v; pausing submissions for %s",
+		breaker.consecutiveFailures, err, duration.String())
+	breaker.setStateUnsafe(circuitOpen)
+	time.AfterFunc(duration, breaker.halfOpen)
+}
+
+func (breaker *submitCircuitBreaker) halfOpen() {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	if breaker.state != circuitOpen {
+		return
+	}
+	seelog.Infof("TaskHandler: Probing ECS with a single state change submission")
+	breaker.setStateUnsafe(circuitHalfOpen)
+}
+
+func (breaker *submitCircuitBreaker) setStateUnsafe(state circuitState) {
+	breaker.state = state
+	breaker.probing = false
+	close(breaker.stateChange)
+	breaker.stateChange = make(chan struct{})
+}
+
+func (breaker *submitCircuitBreaker) getState() circuitState {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	return breaker.state
+}
+
+// isServerError returns true if the error was caused by ECS failing to
+// process the request, as opposed to the request being invalid
+func isServerError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if utils.IsAWSErrorCodeEqual(err, ecs.ErrCodeServerException) {
+		return true
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		return reqErr.StatusCode() >= http.StatusInternalServerError
+	}
+	return false
+}
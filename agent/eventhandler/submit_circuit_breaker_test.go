@@ -0,0 +1,101 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventhandler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	mock_retry "github.com/aws/amazon-ecs-agent/agent/utils/retry/mock"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func serverError() error {
+	return awserr.NewRequestFailure(awserr.New(ecs.ErrCodeServerException, "", nil), 500, "")
+}
+
+func TestIsServerError(t *testing.T) {
+	assert.False(t, isServerError(nil))
+	assert.False(t, isServerError(errors.New("error")))
+	assert.False(t, isServerError(awserr.New(ecs.ErrCodeInvalidParameterException, "", nil)))
+	assert.False(t, isServerError(awserr.NewRequestFailure(awserr.New("ThrottlingException", "", nil), 400, "")))
+	assert.True(t, isServerError(awserr.New(ecs.ErrCodeServerException, "", nil)))
+	assert.True(t, isServerError(awserr.NewRequestFailure(awserr.New("InternalFailure", "", nil), 503, "")))
+}
+
+func TestSubmitCircuitBreakerTripsAfterThreshold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	backoff := mock_retry.NewMockBackoff(ctrl)
+	backoff.EXPECT().Duration().Return(time.Hour)
+	breaker := newSubmitCircuitBreaker(3, backoff)
+
+	breaker.recordResult(serverError())
+	breaker.recordResult(serverError())
+	// A non server error resets the count of consecutive failures
+	breaker.recordResult(awserr.New(ecs.ErrCodeInvalidParameterException, "", nil))
+	breaker.recordResult(serverError())
+	breaker.recordResult(serverError())
+	assert.Equal(t, circuitClosed, breaker.getState())
+	breaker.recordResult(serverError())
+	assert.Equal(t, circuitOpen, breaker.getState())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, breaker.wait(ctx), "submissions should be paused while the circuit is open")
+}
+
+func TestSubmitCircuitBreakerHalfOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	backoff := mock_retry.NewMockBackoff(ctrl)
+	gomock.InOrder(
+		backoff.EXPECT().Duration().Return(time.Millisecond),
+		backoff.EXPECT().Duration().Return(time.Millisecond),
+		backoff.EXPECT().Reset(),
+	)
+	breaker := newSubmitCircuitBreaker(1, backoff)
+	breaker.recordResult(serverError())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// The first waiter is let through as the probe once the timer fires
+	assert.True(t, breaker.wait(ctx))
+	assert.Equal(t, circuitHalfOpen, breaker.getState())
+
+	// Other waiters are held back until the probe completes
+	probeCtx, probeCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer probeCancel()
+	assert.False(t, breaker.wait(probeCtx))
+
+	// A failed probe opens the circuit again
+	breaker.recordResult(serverError())
+	assert.Equal(t, circuitOpen, breaker.getState())
+	assert.True(t, breaker.wait(ctx))
+
+	// A successful probe closes it, letting everything through
+	breaker.recordResult(nil)
+	assert.Equal(t, circuitClosed, breaker.getState())
+	assert.True(t, breaker.wait(ctx))
+	assert.True(t, breaker.wait(ctx))
+}
@@ -77,6 +77,10 @@ type TaskHandler struct {
 	minDrainEventsFrequency time.Duration
 	maxDrainEventsFrequency time.Duration
 
+	// submitBreaker pauses the submission of events for all tasks when ECS
+	// keeps failing with server errors
+	submitBreaker *submitCircuitBreaker
+
 	state  dockerstate.TaskEngineState
 	client api.ECSClient
 	ctx    context.Context
@@ -97,6 +101,13 @@ type taskSendableEvents struct {
 	createdAt time.Time
 	// taskARN is the task arn that the event list is associated with
 	taskARN string
+	// submitRetries is the number of failed submissions for events in the
+	// list that have been retried
+	submitRetries int
+	// backoff is the duration the handler is waiting for before retrying
+	// the submission of the first event in the list. It's zero when the
+	// last submission attempt succeeded
+	backoff time.Duration
 }
 
 // NewTaskHandler returns a pointer to TaskHandler
@@ -116,6 +127,9 @@ func NewTaskHandler(ctx context.Context,
 		client:                    client,
 		minDrainEventsFrequency:   minDrainEventsFrequency,
 		maxDrainEventsFrequency:   maxDrainEventsFrequency,
+		submitBreaker: newSubmitCircuitBreaker(submitCircuitBreakerThreshold,
+			retry.NewExponentialBackoff(submitCircuitBreakerOpenMin, submitCircuitBreakerOpenMax,
+				submitCircuitBreakerOpenJitterMultiple, submitCircuitBreakerOpenMultiple)),
 	}
 	go taskHandler.startDrainEventsTicker()
 
@@ -265,12 +279,52 @@ func (handler *TaskHandler) batchContainerEventUnsafe(event api.ContainerStateCh
 	handler.tasksToContainerStates[event.TaskArn] = append(handler.tasksToContainerStates[event.TaskArn], event)
 }
 
-// batchManagedAgentEventUnsafe collects managed agent state change events for a given task arn
+// batchManagedAgentEventUnsafe collects managed agent state change events for a given task arn.
+// Events for a status that has already been sent to ECS, or that is already batched for the
+// same managed agent, are dropped
 func (handler *TaskHandler) batchManagedAgentEventUnsafe(event api.ManagedAgentStateChange) {
+	if managedAgentStatusSent(event) {
+		seelog.Debugf("TaskHandler: not batching managed agent event as the status has already been sent: %s",
+			event.String())
+		return
+	}
+	for _, batched := range handler.tasksToManagedAgentStates[event.TaskArn] {
+		if isDuplicateManagedAgentEvent(batched, event) {
+			seelog.Debugf("TaskHandler: not batching duplicate managed agent event: %s", event.String())
+			return
+		}
+	}
 	seelog.Debugf("TaskHandler: batching managed agent event: %s", event.String())
 	handler.tasksToManagedAgentStates[event.TaskArn] = append(handler.tasksToManagedAgentStates[event.TaskArn], event)
 }
 
+// managedAgentEventsToSend filters out the managed agent events whose status has been
+// sent to ECS since they were batched
+func managedAgentEventsToSend(events []api.ManagedAgentStateChange) []api.ManagedAgentStateChange {
+	var toSend []api.ManagedAgentStateChange
+	for _, event := range events {
+		if managedAgentStatusSent(event) {
+			seelog.Debugf("TaskHandler: dropping managed agent event as the status has already been sent: %s",
+				event.String())
+			continue
+		}
+		toSend = append(toSend, event)
+	}
+	return toSend
+}
+
+// managedAgentStatusSent returns true if the managed agent's sent status, tracked on its
+// container, is the same as the status in the event
+func managedAgentStatusSent(event api.ManagedAgentStateChange) bool {
+	return event.Container != nil && event.Container.GetManagedAgentSentStatus(event.Name) == event.Status
+}
+
+// isDuplicateManagedAgentEvent returns true if both events report the same status for the
+// same managed agent
+func isDuplicateManagedAgentEvent(event, other api.ManagedAgentStateChange) bool {
+	return event.Container == other.Container && event.Name == other.Name && event.Status == other.Status
+}
+
 // flushBatchUnsafe attaches the task arn's container events to TaskStateChange event
 // by creating the sendable event list. It then submits this event to ECS asynchronously
 func (handler *TaskHandler) flushBatchUnsafe(taskStateChange *api.TaskStateChange, client api.ECSClient) {
@@ -280,7 +334,7 @@ func (handler *TaskHandler) flushBatchUnsafe(taskStateChange *api.TaskStateChang
 	// task state change object. Remove them from the map
 	delete(handler.tasksToContainerStates, taskStateChange.TaskARN)
 	taskStateChange.ManagedAgents = append(taskStateChange.ManagedAgents,
-		handler.tasksToManagedAgentStates[taskStateChange.TaskARN]...)
+		managedAgentEventsToSend(handler.tasksToManagedAgentStates[taskStateChange.TaskARN])...)
 	// All managed agent events for the task have now been copied to the
 	// task state change object. Remove them from the map
 	delete(handler.tasksToManagedAgentStates, taskStateChange.TaskARN)
@@ -323,8 +377,11 @@ func (handler *TaskHandler) submitTaskEvents(taskEvents *taskSendableEvents, cli
 	defer metrics.MetricsEngineGlobal.RecordECSClientMetric("SUBMIT_TASK_EVENTS")()
 	defer handler.removeTaskEvents(taskARN)
 
-	backoff := retry.NewExponentialBackoff(submitStateBackoffMin, submitStateBackoffMax,
-		submitStateBackoffJitterMultiple, submitStateBackoffMultiple)
+	backoff := &taskEventsBackoff{
+		Backoff: retry.NewExponentialBackoff(submitStateBackoffMin, submitStateBackoffMax,
+			submitStateBackoffJitterMultiple, submitStateBackoffMultiple),
+		taskEvents: taskEvents,
+	}
 
 	// Mirror events.sending, but without the need to lock since this is local
 	// to our goroutine
@@ -335,6 +392,14 @@ func (handler *TaskHandler) submitTaskEvents(taskEvents *taskSendableEvents, cli
 		// we haven't emptied the list so we should keep submitting
 		backoff.Reset()
 		retry.RetryWithBackoff(backoff, func() error {
+			// Wait for ECS to recover if submissions are paused because of
+			// server errors, instead of retrying on this task's own backoff
+			if !handler.submitBreaker.wait(handler.ctx) {
+				seelog.Infof("TaskHandler: Stopping submission of events for task %s", taskARN)
+				done = true
+				return nil
+			}
+
 			// Lock and unlock within this function, allowing the list to be added
 			// to while we're not actively sending an event
 			seelog.Debug("TaskHandler: Waiting on semaphore to send events...")
@@ -343,6 +408,8 @@ func (handler *TaskHandler) submitTaskEvents(taskEvents *taskSendableEvents, cli
 
 			var err error
 			done, err = taskEvents.submitFirstEvent(handler, backoff)
+			handler.submitBreaker.recordResult(err)
+			taskEvents.recordSubmitResult(err)
 			return err
 		})
 	}
@@ -353,6 +420,7 @@ func (handler *TaskHandler) removeTaskEvents(taskARN string) {
 	defer handler.lock.Unlock()
 
 	delete(handler.tasksToEvents, taskARN)
+	metrics.MetricsEngineGlobal.RemoveEventHandlerTaskMetrics(taskARN)
 }
 
 // sendChange adds the change to the sendable events queue. It triggers
@@ -367,7 +435,9 @@ func (taskEvents *taskSendableEvents) sendChange(change *sendableEvent,
 
 	// Add event to the queue
 	seelog.Debugf("TaskHandler: Adding event: %s", change.toString())
+	change.enqueuedAt = time.Now()
 	taskEvents.events.PushBack(change)
+	metrics.MetricsEngineGlobal.SetEventHandlerQueueLength(taskEvents.taskARN, taskEvents.events.Len())
 
 	if !taskEvents.sending {
 		// If a send event is not already in progress, trigger the
@@ -390,6 +460,9 @@ func (taskEvents *taskSendableEvents) submitFirstEvent(handler *TaskHandler, bac
 	seelog.Debug("TaskHandler: Acquiring lock for sending event...")
 	taskEvents.lock.Lock()
 	defer taskEvents.lock.Unlock()
+	defer func() {
+		metrics.MetricsEngineGlobal.SetEventHandlerQueueLength(taskEvents.taskARN, taskEvents.events.Len())
+	}()
 
 	seelog.Debugf("TaskHandler: Acquired lock, processing event list: : %s", taskEvents.toStringUnsafe())
 
@@ -435,11 +508,50 @@ func (taskEvents *taskSendableEvents) submitFirstEvent(handler *TaskHandler, bac
 	return false, nil
 }
 
+// recordSubmitResult updates the retry and backoff state of the event list
+// based on the result of the last submission attempt
+func (taskEvents *taskSendableEvents) recordSubmitResult(err error) {
+	taskEvents.lock.Lock()
+	defer taskEvents.lock.Unlock()
+
+	if err != nil {
+		taskEvents.submitRetries++
+		metrics.MetricsEngineGlobal.IncrementEventHandlerSubmitRetries(taskEvents.taskARN)
+		return
+	}
+	taskEvents.backoff = 0
+	metrics.MetricsEngineGlobal.SetEventHandlerBackoff(taskEvents.taskARN, 0)
+}
+
+// setBackoff records the duration the handler is going to wait for before
+// retrying the submission of the first event in the list
+func (taskEvents *taskSendableEvents) setBackoff(duration time.Duration) {
+	taskEvents.lock.Lock()
+	defer taskEvents.lock.Unlock()
+
+	taskEvents.backoff = duration
+	metrics.MetricsEngineGlobal.SetEventHandlerBackoff(taskEvents.taskARN, duration)
+}
+
 func (taskEvents *taskSendableEvents) toStringUnsafe() string {
 	return fmt.Sprintf("Task event list [taskARN: %s, sending: %t, createdAt: %s]",
 		taskEvents.taskARN, taskEvents.sending, taskEvents.createdAt.String())
 }
 
+// taskEventsBackoff wraps the backoff used to retry event submissions for a
+// task so that the current backoff duration is visible to the task's event list
+type taskEventsBackoff struct {
+	retry.Backoff
+	taskEvents *taskSendableEvents
+}
+
+// Duration returns the next backoff duration and records it in the event list
+func (backoff *taskEventsBackoff) Duration() time.Duration {
+	duration := backoff.Backoff.Duration()
+	backoff.taskEvents.setBackoff(duration)
+	return duration
+}
+
 // handleInvalidParamException removes the event from event queue when its parameters are
 // invalid to reduce redundant API call
 func handleInvalidParamException(err error, events *list.List, eventToSubmit *list.Element) {
@@ -0,0 +1,110 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventhandler
+
+import (
+	"sort"
+	"time"
+)
+
+// QueueStats is a point in time snapshot of the state change events that the
+// TaskHandler has yet to submit to ECS
+type QueueStats struct {
+	// QueueLength is the total number of events queued across all tasks
+	QueueLength int `json:"QueueLength"`
+	// BatchedContainerEvents is the number of container events waiting for
+	// a task event to be sent with
+	BatchedContainerEvents int `json:"BatchedContainerEvents"`
+	// BatchedManagedAgentEvents is the number of managed agent events waiting
+	// for a task event to be sent with
+	BatchedManagedAgentEvents int `json:"BatchedManagedAgentEvents"`
+	// SubmitRetries is the total number of retried submissions for the
+	// queued events
+	SubmitRetries int `json:"SubmitRetries"`
+	// TasksInBackoff is the number of tasks whose event submission is
+	// currently backing off after an error
+	TasksInBackoff int `json:"TasksInBackoff"`
+	// OldestEventAgeSeconds is the time the oldest queued event has been
+	// waiting for
+	OldestEventAgeSeconds float64 `json:"OldestEventAgeSeconds"`
+	// SubmitCircuitBreakerState is the state of the circuit breaker that
+	// pauses submissions when ECS keeps failing with server errors
+	SubmitCircuitBreakerState string `json:"SubmitCircuitBreakerState,omitempty"`
+	// Tasks holds the per task breakdown, sorted by task arn
+	Tasks []TaskQueueStats `json:"Tasks"`
+}
+
+// TaskQueueStats is a snapshot of the state change events queued for a task
+type TaskQueueStats struct {
+	TaskARN               string  `json:"TaskARN"`
+	QueueLength           int     `json:"QueueLength"`
+	Sending               bool    `json:"Sending"`
+	SubmitRetries         int     `json:"SubmitRetries"`
+	BackoffSeconds        float64 `json:"BackoffSeconds"`
+	OldestEventAgeSeconds float64 `json:"OldestEventAgeSeconds"`
+}
+
+// QueueStats returns a snapshot of the handler's event queues
+func (handler *TaskHandler) QueueStats() QueueStats {
+	handler.lock.RLock()
+	defer handler.lock.RUnlock()
+
+	now := time.Now()
+	stats := QueueStats{
+		Tasks: []TaskQueueStats{},
+	}
+	if handler.submitBreaker != nil {
+		stats.SubmitCircuitBreakerState = handler.submitBreaker.getState().String()
+	}
+	for _, events := range handler.tasksToContainerStates {
+		stats.BatchedContainerEvents += len(events)
+	}
+	for _, events := range handler.tasksToManagedAgentStates {
+		stats.BatchedManagedAgentEvents += len(events)
+	}
+	for _, taskEvents := range handler.tasksToEvents {
+		taskStats := taskEvents.queueStats(now)
+		stats.QueueLength += taskStats.QueueLength
+		stats.SubmitRetries += taskStats.SubmitRetries
+		if taskStats.BackoffSeconds > 0 {
+			stats.TasksInBackoff++
+		}
+		if taskStats.OldestEventAgeSeconds > stats.OldestEventAgeSeconds {
+			stats.OldestEventAgeSeconds = taskStats.OldestEventAgeSeconds
+		}
+		stats.Tasks = append(stats.Tasks, taskStats)
+	}
+	sort.Slice(stats.Tasks, func(i, j int) bool {
+		return stats.Tasks[i].TaskARN < stats.Tasks[j].TaskARN
+	})
+	return stats
+}
+
+// queueStats returns a snapshot of the event list
+func (taskEvents *taskSendableEvents) queueStats(now time.Time) TaskQueueStats {
+	taskEvents.lock.Lock()
+	defer taskEvents.lock.Unlock()
+
+	stats := TaskQueueStats{
+		TaskARN:        taskEvents.taskARN,
+		QueueLength:    taskEvents.events.Len(),
+		Sending:        taskEvents.sending,
+		SubmitRetries:  taskEvents.submitRetries,
+		BackoffSeconds: taskEvents.backoff.Seconds(),
+	}
+	if front := taskEvents.events.Front(); front != nil {
+		stats.OldestEventAgeSeconds = now.Sub(front.Value.(*sendableEvent).enqueuedAt).Seconds()
+	}
+	return stats
+}
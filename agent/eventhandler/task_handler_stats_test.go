@@ -0,0 +1,94 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventhandler
+
+import (
+	"container/list"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	mock_retry "github.com/aws/amazon-ecs-agent/agent/utils/retry/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	events := list.New()
+	events.PushBack(&sendableEvent{
+		taskChange: taskEvent("t1").(api.TaskStateChange),
+		enqueuedAt: time.Now().Add(-time.Minute),
+	})
+	events.PushBack(&sendableEvent{
+		taskChange: taskEvent("t1").(api.TaskStateChange),
+		enqueuedAt: time.Now(),
+	})
+	t1Events := &taskSendableEvents{
+		events:  events,
+		sending: true,
+		taskARN: "t1",
+	}
+	t2Events := &taskSendableEvents{
+		events:  list.New(),
+		taskARN: "t2",
+	}
+	handler := &TaskHandler{
+		tasksToEvents: map[string]*taskSendableEvents{
+			"t1": t1Events,
+			"t2": t2Events,
+		},
+		tasksToContainerStates: map[string][]api.ContainerStateChange{
+			"t3": {containerEvent("t3").(api.ContainerStateChange)},
+		},
+		tasksToManagedAgentStates: map[string][]api.ManagedAgentStateChange{},
+	}
+
+	// Two failed submissions followed by a backoff for t1
+	t1Events.recordSubmitResult(errors.New("error"))
+	t1Events.recordSubmitResult(errors.New("error"))
+	mockBackoff := mock_retry.NewMockBackoff(ctrl)
+	mockBackoff.EXPECT().Duration().Return(2 * time.Second)
+	backoff := &taskEventsBackoff{
+		Backoff:    mockBackoff,
+		taskEvents: t1Events,
+	}
+	assert.Equal(t, 2*time.Second, backoff.Duration())
+
+	stats := handler.QueueStats()
+	assert.Equal(t, 2, stats.QueueLength)
+	assert.Equal(t, 1, stats.BatchedContainerEvents)
+	assert.Equal(t, 0, stats.BatchedManagedAgentEvents)
+	assert.Equal(t, 2, stats.SubmitRetries)
+	assert.Equal(t, 1, stats.TasksInBackoff)
+	assert.True(t, stats.OldestEventAgeSeconds >= time.Minute.Seconds())
+	require.Len(t, stats.Tasks, 2)
+	assert.Equal(t, "t1", stats.Tasks[0].TaskARN)
+	assert.True(t, stats.Tasks[0].Sending)
+	assert.Equal(t, 2.0, stats.Tasks[0].BackoffSeconds)
+	assert.Equal(t, "t2", stats.Tasks[1].TaskARN)
+	assert.Equal(t, 0, stats.Tasks[1].QueueLength)
+
+	// A successful submission clears the backoff but not the retry count
+	t1Events.recordSubmitResult(nil)
+	stats = handler.QueueStats()
+	assert.Equal(t, 0, stats.TasksInBackoff)
+	assert.Equal(t, 2, stats.SubmitRetries)
+}
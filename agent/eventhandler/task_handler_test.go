@@ -665,6 +665,87 @@ func TestSendManagedAgentEventsTaskDifferences(t *testing.T) {
 	wg.Wait()
 }
 
+func TestSendManagedAgentEventsDedupe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := NewTaskHandler(ctx, data.NewNoopClient(), dockerstate.NewTaskEngineState(), client)
+	defer cancel()
+
+	runningContainer := &apicontainer.Container{
+		Name: "c1",
+		ManagedAgentsUnsafe: []apicontainer.ManagedAgent{
+			{
+				Name: "ExecAgent",
+			},
+		},
+	}
+	sentContainer := &apicontainer.Container{
+		Name: "c2",
+		ManagedAgentsUnsafe: []apicontainer.ManagedAgent{
+			{
+				Name: "ExecAgent",
+				ManagedAgentState: apicontainer.ManagedAgentState{
+					Status:     apicontainerstatus.ManagedAgentRunning,
+					SentStatus: apicontainerstatus.ManagedAgentRunning,
+				},
+			},
+		},
+	}
+	maEvent := api.ManagedAgentStateChange{
+		TaskArn:   taskARN,
+		Name:      "ExecAgent",
+		Container: runningContainer,
+		Status:    apicontainerstatus.ManagedAgentRunning,
+	}
+	sentMAEvent := api.ManagedAgentStateChange{
+		TaskArn:   taskARN,
+		Name:      "ExecAgent",
+		Container: sentContainer,
+		Status:    apicontainerstatus.ManagedAgentRunning,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
+		assert.Len(t, change.ManagedAgents, 1)
+		assert.Equal(t, runningContainer, change.ManagedAgents[0].Container)
+		wg.Done()
+	})
+
+	// The same transition reported twice, and a transition that has already been sent
+	handler.AddStateChangeEvent(maEvent, client)
+	handler.AddStateChangeEvent(maEvent, client)
+	handler.AddStateChangeEvent(sentMAEvent, client)
+	assert.Len(t, handler.tasksToManagedAgentStates[taskARN], 1)
+
+	handler.AddStateChangeEvent(taskEvent(taskARN), client)
+	wg.Wait()
+}
+
+func TestManagedAgentEventsToSend(t *testing.T) {
+	container := &apicontainer.Container{
+		ManagedAgentsUnsafe: []apicontainer.ManagedAgent{
+			{
+				Name: "ExecAgent",
+			},
+		},
+	}
+	maEvent := api.ManagedAgentStateChange{
+		TaskArn:   taskARN,
+		Name:      "ExecAgent",
+		Container: container,
+		Status:    apicontainerstatus.ManagedAgentRunning,
+	}
+	assert.Len(t, managedAgentEventsToSend([]api.ManagedAgentStateChange{maEvent}), 1)
+
+	// The status got sent with another task event after the event was batched
+	container.UpdateManagedAgentSentStatus("ExecAgent", apicontainerstatus.ManagedAgentRunning)
+	assert.Len(t, managedAgentEventsToSend([]api.ManagedAgentStateChange{maEvent}), 0)
+}
+
 func TestGetBatchedManagedAgentEvents(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
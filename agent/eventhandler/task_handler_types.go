@@ -17,6 +17,7 @@ import (
 	"container/list"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
@@ -24,6 +25,8 @@ import (
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/cihub/seelog"
 )
@@ -40,6 +43,10 @@ type sendableEvent struct {
 	taskSent   bool
 	taskChange api.TaskStateChange
 
+	// enqueuedAt is the time at which the event was added to the task's
+	// sendable events queue
+	enqueuedAt time.Time
+
 	lock sync.RWMutex
 }
 
@@ -150,12 +157,16 @@ func (event *sendableEvent) send(
 	taskEvents *taskSendableEvents) error {
 
 	seelog.Infof("TaskHandler: Sending %s change: %s", eventType, event.toString())
+	span := tracing.StartTaskSpan(event.taskArn(), "SubmitStateChange", tracing.String(tracing.EventTypeKey, eventType))
 	// Try submitting the change to ECS
-	if err := sendStatusToECS(client, event); err != nil {
+	err := sendStatusToECS(client, event)
+	span.End(err)
+	if err != nil {
 		seelog.Errorf("TaskHandler: Unretriable error submitting %s state change [%s]: %v",
 			eventType, event.toString(), err)
 		return err
 	}
+	metrics.MetricsEngineGlobal.RecordEventHandlerTimeInQueue(eventType, time.Since(event.enqueuedAt))
 	// submitted; ensure we don't retry it
 	event.setSent()
 	// Mark event as sent
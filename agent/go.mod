@@ -4,7 +4,10 @@ go 1.12
 
 require (
 	github.com/Azure/go-ansiterm v0.0.0-20170929234023-d6e3b3328b78 // indirect
+	github.com/Microsoft/go-winio v0.4.11
 	github.com/aws/aws-sdk-go v1.36.0
+	github.com/aws/aws-sdk-go-v2 v1.7.1
+	github.com/aws/smithy-go v1.6.0 // indirect
 	github.com/awslabs/go-config-generator-for-fluentd-and-fluentbit v0.0.0-20190829210224-55d4fd2e6f35
 	github.com/beorn7/perks v0.0.0-20180321164747-3a771d992973 // indirect
 	github.com/cihub/seelog v0.0.0-20170130134532-f561c5e57575
@@ -15,10 +18,11 @@ require (
 	github.com/containernetworking/plugins v0.8.6
 	github.com/deniswernert/udev v0.0.0-20140626150257-82fe5be8ca5f
 	github.com/didip/tollbooth v3.0.2+incompatible
-	github.com/docker/distribution v0.0.0-20181002220433-1cb4180b1a5b // indirect
+	github.com/docker/distribution v0.0.0-20181002220433-1cb4180b1a5b
 	github.com/docker/docker v0.0.0-20200531234253-77e06fda0c94
 	github.com/docker/go-connections v0.3.0
 	github.com/docker/go-units v0.3.2
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/godbus/dbus v4.1.0+incompatible // indirect
 	github.com/gogo/protobuf v1.1.1 // indirect
 	github.com/golang/mock v1.1.1
@@ -43,11 +47,12 @@ require (
 	github.com/sirupsen/logrus v1.1.1 // indirect
 	github.com/stretchr/testify v1.5.1
 	github.com/vishvananda/netlink v0.0.0-20181108222139-023a6dafdcdf
+	github.com/vishvananda/netns v0.0.0-20180720170159-13995c7128cc
 	go.etcd.io/bbolt v1.3.6
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	golang.org/x/sys v0.0.0-20200923182605-d9f96fdee20d
 	golang.org/x/text v0.3.6 // indirect
-	golang.org/x/time v0.0.0-20170927054726-6dc17368e09b // indirect
+	golang.org/x/time v0.0.0-20170927054726-6dc17368e09b
 	golang.org/x/tools v0.0.0-20190524140312-2c0ae7006135
 	google.golang.org/grpc v1.38.0 // indirect
 	gotest.tools v2.2.0+incompatible // indirect
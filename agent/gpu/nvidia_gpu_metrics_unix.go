@@ -0,0 +1,167 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gpu
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// nvidiaSMIBinary is the NVML command line utility shipped with the nvidia driver
+	nvidiaSMIBinary = "nvidia-smi"
+	// nvidiaSMIQueryFields are the device fields sampled from NVML, in the order they are reported
+	nvidiaSMIQueryFields = "uuid,utilization.gpu,memory.used,memory.total,temperature.gpu"
+	// nvidiaSMINotSupported is reported by nvidia-smi for fields that the device doesn't support
+	nvidiaSMINotSupported = "[Not Supported]"
+	bytesInMiB            = 1024 * 1024
+	// nvidiaSMITimeout is how long nvidia-smi can take to query the driver
+	nvidiaSMITimeout = 10 * time.Second
+)
+
+// ErrNvidiaSMINotFound is returned when nvidia-smi isn't installed on the host
+var ErrNvidiaSMINotFound = errors.New("nvidia-smi not found on the host")
+
+var (
+	// hostRootPath is the root file system of the host, seen through its init process. The
+	// agent image doesn't include nvidia-smi, so the one of the host is run with the root file
+	// system of the host, where it finds the NVIDIA driver libraries it links against.
+	hostRootPath = "/host/proc/1/root"
+	// hostBinDirs are the directories nvidia-smi is looked up in on the host
+	hostBinDirs = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+)
+
+// GPUMetrics is a sample of the utilization of a GPU
+type GPUMetrics struct {
+	// GPUID is the UUID of the GPU, as used in the GPU info file and the task's GPU assignments
+	GPUID              string
+	UtilizationPercent float64
+	MemoryUsedBytes    uint64
+	MemoryTotalBytes   uint64
+	TemperatureCelsius float64
+}
+
+// NVMLClient samples the metrics of the GPUs on the instance through NVML
+type NVMLClient interface {
+	DeviceMetrics() ([]*GPUMetrics, error)
+}
+
+// nvidiaSMIClient queries NVML through nvidia-smi, as the agent is built without cgo and can't
+// load the NVML library itself
+type nvidiaSMIClient struct{}
+
+// NewNVMLClient is used to obtain a NVMLClient handle
+func NewNVMLClient() NVMLClient {
+	return &nvidiaSMIClient{}
+}
+
+var RunNvidiaSMI = runNvidiaSMI
+
+// runNvidiaSMI runs the nvidia-smi of the host with the root file system of the host
+func runNvidiaSMI(args ...string) ([]byte, error) {
+	path, err := lookHostNvidiaSMI()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), nvidiaSMITimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = "/"
+	cmd.SysProcAttr = &syscall.SysProcAttr{Chroot: hostRootPath}
+	return cmd.Output()
+}
+
+// lookHostNvidiaSMI returns the path, on the host, of nvidia-smi. Symbolic links aren't followed
+// here, as the absolute ones are relative to the root file system of the host, they are resolved
+// when nvidia-smi is run with that root file system.
+func lookHostNvidiaSMI() (string, error) {
+	for _, dir := range hostBinDirs {
+		path := filepath.Join(dir, nvidiaSMIBinary)
+		if _, err := os.Lstat(filepath.Join(hostRootPath, path)); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNvidiaSMINotFound
+}
+
+// DeviceMetrics returns a sample of the metrics of each GPU on the instance
+func (c *nvidiaSMIClient) DeviceMetrics() ([]*GPUMetrics, error) {
+	output, err := RunNvidiaSMI("--query-gpu="+nvidiaSMIQueryFields, "--format=csv,noheader,nounits")
+	if err == ErrNvidiaSMINotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to query GPU metrics")
+	}
+	return parseNvidiaSMIOutput(output)
+}
+
+func parseNvidiaSMIOutput(output []byte) ([]*GPUMetrics, error) {
+	reader := csv.NewReader(bytes.NewReader(output))
+	reader.FieldsPerRecord = len(strings.Split(nvidiaSMIQueryFields, ","))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse GPU metrics")
+	}
+
+	metrics := make([]*GPUMetrics, 0, len(records))
+	for _, record := range records {
+		utilization, err := parseNvidiaSMIValue(record[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse utilization of GPU %s", record[0])
+		}
+		memoryUsed, err := parseNvidiaSMIValue(record[2])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse memory used by GPU %s", record[0])
+		}
+		memoryTotal, err := parseNvidiaSMIValue(record[3])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse memory of GPU %s", record[0])
+		}
+		temperature, err := parseNvidiaSMIValue(record[4])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse temperature of GPU %s", record[0])
+		}
+		metrics = append(metrics, &GPUMetrics{
+			GPUID:              record[0],
+			UtilizationPercent: utilization,
+			MemoryUsedBytes:    uint64(memoryUsed) * bytesInMiB,
+			MemoryTotalBytes:   uint64(memoryTotal) * bytesInMiB,
+			TemperatureCelsius: temperature,
+		})
+	}
+	return metrics, nil
+}
+
+// parseNvidiaSMIValue parses a numeric field, reporting fields that aren't supported by the device as 0
+func parseNvidiaSMIValue(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == nvidiaSMINotSupported {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
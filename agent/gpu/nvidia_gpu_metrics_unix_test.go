@@ -0,0 +1,101 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gpu
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNVMLClientDeviceMetrics(t *testing.T) {
+	RunNvidiaSMI = func(args ...string) ([]byte, error) {
+		return []byte("GPU-a1, 45, 1024, 15360, 60\nGPU-b2, 0, 0, 15360, [Not Supported]\n"), nil
+	}
+	defer func() {
+		RunNvidiaSMI = runNvidiaSMI
+	}()
+
+	metrics, err := NewNVMLClient().DeviceMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, []*GPUMetrics{
+		{
+			GPUID:              "GPU-a1",
+			UtilizationPercent: 45,
+			MemoryUsedBytes:    1024 * bytesInMiB,
+			MemoryTotalBytes:   15360 * bytesInMiB,
+			TemperatureCelsius: 60,
+		},
+		{
+			GPUID:            "GPU-b2",
+			MemoryTotalBytes: 15360 * bytesInMiB,
+		},
+	}, metrics)
+}
+
+func TestNVMLClientDeviceMetricsError(t *testing.T) {
+	RunNvidiaSMI = func(args ...string) ([]byte, error) {
+		return nil, errors.New("nvidia-smi not found")
+	}
+	defer func() {
+		RunNvidiaSMI = runNvidiaSMI
+	}()
+
+	_, err := NewNVMLClient().DeviceMetrics()
+	assert.Error(t, err)
+}
+
+func TestNVMLClientDeviceMetricsNotFound(t *testing.T) {
+	RunNvidiaSMI = func(args ...string) ([]byte, error) {
+		return nil, ErrNvidiaSMINotFound
+	}
+	defer func() {
+		RunNvidiaSMI = runNvidiaSMI
+	}()
+
+	_, err := NewNVMLClient().DeviceMetrics()
+	assert.Equal(t, ErrNvidiaSMINotFound, err)
+}
+
+func TestParseNvidiaSMIOutputInvalid(t *testing.T) {
+	_, err := parseNvidiaSMIOutput([]byte("GPU-a1, 45, 1024\n"))
+	assert.Error(t, err)
+
+	_, err = parseNvidiaSMIOutput([]byte("GPU-a1, abc, 1024, 15360, 60\n"))
+	assert.Error(t, err)
+}
+
+func TestLookHostNvidiaSMI(t *testing.T) {
+	testRoot := t.TempDir()
+	hostRootPath = testRoot
+	defer func() {
+		hostRootPath = "/host/proc/1/root"
+	}()
+
+	_, err := lookHostNvidiaSMI()
+	assert.Equal(t, ErrNvidiaSMINotFound, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(testRoot, "usr", "bin"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testRoot, "usr", "bin", nvidiaSMIBinary), nil, 0755))
+	path, err := lookHostNvidiaSMI()
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/nvidia-smi", path, "nvidia-smi should be run by its path on the host")
+}
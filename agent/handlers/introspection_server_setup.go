@@ -18,13 +18,17 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"runtime"
 	"strconv"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/diagnostics"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	handlersutils "github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
+	v2 "github.com/aws/amazon-ecs-agent/agent/handlers/v2"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/cihub/seelog"
 )
@@ -33,8 +37,22 @@ type rootResponse struct {
 	AvailableCommands []string
 }
 
-func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersutils.DockerStateResolver, cfg *config.Config) *http.Server {
-	paths := []string{v1.AgentMetadataPath, v1.TaskContainerMetadataPath, v1.LicensePath}
+func introspectionServerSetup(containerInstanceArn *string,
+	taskEngine handlersutils.DockerStateResolver,
+	eventHandlerStats v1.EventHandlerStatsResolver,
+	imagePreloader v1.ImagePreloader,
+	drainer v1.Drainer,
+	dryRunner v1.TaskDryRunner,
+	stateReporter v1.StateReporter,
+	bundler v1.DiagnosticsBundler,
+	tasksResolver v2.IntrospectionTasksResolver,
+	cfg *config.Config) *http.Server {
+	paths := []string{v1.AgentMetadataPath, v1.TaskContainerMetadataPath, v1.LicensePath, v1.EventHandlerStatsPath,
+		v1.ImagePreloadPath, v1.LogLevelPath, v1.DrainPath, v1.DrainStatusPath,
+		v1.TaskDryRunPath, v1.StateReportPath, v1.DiagnosticsBundlePath, v1.ConfigReportPath, v2.IntrospectionTasksPath}
+	if cfg.PprofEnabled.Enabled() {
+		paths = append(paths, v1.PprofPath)
+	}
 	availableCommands := &rootResponse{paths}
 	// Autogenerated list of the above serverFunctions paths
 	availableCommandResponse, err := json.Marshal(&availableCommands)
@@ -49,7 +67,16 @@ func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersu
 	serverMux := http.NewServeMux()
 	serverMux.HandleFunc("/", defaultHandler)
 
-	v1HandlersSetup(serverMux, containerInstanceArn, taskEngine, cfg)
+	v1HandlersSetup(serverMux, containerInstanceArn, taskEngine, eventHandlerStats, imagePreloader, drainer, dryRunner,
+		stateReporter, bundler, cfg)
+	serverMux.HandleFunc(v2.IntrospectionTasksPath, v2.IntrospectionTasksHandler(tasksResolver))
+	serverWriteTimeout := writeTimeout
+	if cfg.PprofEnabled.Enabled() {
+		runtime.SetMutexProfileFraction(v1.MutexProfileFraction)
+		serverMux.HandleFunc(v1.PprofPath, v1.PprofHandler())
+		// CPU profiles and execution traces are written for as long as they are recorded
+		serverWriteTimeout = v1.MaxProfileDuration + writeTimeout
+	}
 
 	// Log all requests and then pass through to serverMux
 	loggingServeMux := http.NewServeMux()
@@ -59,7 +86,7 @@ func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersu
 		Addr:         ":" + strconv.Itoa(config.AgentIntrospectionPort),
 		Handler:      loggingServeMux,
 		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
+		WriteTimeout: serverWriteTimeout,
 	}
 
 	return server
@@ -69,21 +96,45 @@ func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersu
 func v1HandlersSetup(serverMux *http.ServeMux,
 	containerInstanceArn *string,
 	taskEngine handlersutils.DockerStateResolver,
+	eventHandlerStats v1.EventHandlerStatsResolver,
+	imagePreloader v1.ImagePreloader,
+	drainer v1.Drainer,
+	dryRunner v1.TaskDryRunner,
+	stateReporter v1.StateReporter,
+	bundler v1.DiagnosticsBundler,
 	cfg *config.Config) {
 	serverMux.HandleFunc(v1.AgentMetadataPath, v1.AgentMetadataHandler(containerInstanceArn, cfg))
 	serverMux.HandleFunc(v1.TaskContainerMetadataPath, v1.TaskContainerMetadataHandler(taskEngine))
 	serverMux.HandleFunc(v1.LicensePath, v1.LicenseHandler)
+	serverMux.HandleFunc(v1.EventHandlerStatsPath, v1.EventHandlerStatsHandler(eventHandlerStats))
+	serverMux.HandleFunc(v1.ImagePreloadPath, v1.ImagePreloadHandler(imagePreloader, cfg.DataDir))
+	serverMux.HandleFunc(v1.LogLevelPath, v1.LogLevelHandler)
+	serverMux.HandleFunc(v1.DrainPath, v1.DrainHandler(drainer))
+	serverMux.HandleFunc(v1.DrainStatusPath, v1.DrainStatusHandler(drainer))
+	serverMux.HandleFunc(v1.TaskDryRunPath, v1.TaskDryRunHandler(dryRunner))
+	serverMux.HandleFunc(v1.StateReportPath, v1.StateReportHandler(stateReporter))
+	serverMux.HandleFunc(v1.DiagnosticsBundlePath, v1.DiagnosticsBundleHandler(bundler))
+	serverMux.HandleFunc(v1.ConfigReportPath, v1.ConfigReportHandler(cfg))
 }
 
 // ServeIntrospectionHTTPEndpoint serves information about this agent/containerInstance and tasks
 // running on it. "V1" here indicates the hostname version of this server instead
 // of the handler versions, i.e. "V1" server can include "V1" and "V2" handlers.
-func ServeIntrospectionHTTPEndpoint(ctx context.Context, containerInstanceArn *string, taskEngine engine.TaskEngine, cfg *config.Config) {
+func ServeIntrospectionHTTPEndpoint(ctx context.Context,
+	containerInstanceArn *string,
+	taskEngine engine.TaskEngine,
+	eventHandlerStats v1.EventHandlerStatsResolver,
+	cfg *config.Config) {
 	// Is this the right level to type assert, assuming we'd abstract multiple taskengines here?
 	// Revisit if we ever add another type..
 	dockerTaskEngine := taskEngine.(*engine.DockerTaskEngine)
+	bundler := diagnostics.NewBundler(dockerTaskEngine, logger.LogFile())
+	if err := v1.WriteImagePreloadToken(cfg.DataDir); err != nil {
+		seelog.Warnf("Unable to write the image preload token, image preload requests will be rejected: %v", err)
+	}
 
-	server := introspectionServerSetup(containerInstanceArn, dockerTaskEngine, cfg)
+	server := introspectionServerSetup(containerInstanceArn, dockerTaskEngine, eventHandlerStats, dockerTaskEngine,
+		dockerTaskEngine, dockerTaskEngine, dockerTaskEngine, bundler, dockerTaskEngine, cfg)
 
 	go func() {
 		<-ctx.Done()
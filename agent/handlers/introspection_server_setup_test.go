@@ -16,20 +16,32 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
 	mock_utils "github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
+	v2 "github.com/aws/amazon-ecs-agent/agent/handlers/v2"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -60,6 +72,872 @@ func TestMetadataHandler(t *testing.T) {
 	}
 }
 
+type fakeEventHandlerStats struct {
+	stats eventhandler.QueueStats
+}
+
+func (f fakeEventHandlerStats) QueueStats() eventhandler.QueueStats {
+	return f.stats
+}
+
+func TestEventHandlerStatsHandler(t *testing.T) {
+	stats := eventhandler.QueueStats{
+		QueueLength:           3,
+		SubmitRetries:         2,
+		TasksInBackoff:        1,
+		OldestEventAgeSeconds: 12.5,
+		Tasks: []eventhandler.TaskQueueStats{
+			{
+				TaskARN:               "task1",
+				QueueLength:           3,
+				Sending:               true,
+				SubmitRetries:         2,
+				BackoffSeconds:        1.3,
+				OldestEventAgeSeconds: 12.5,
+			},
+		},
+	}
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil,
+		fakeEventHandlerStats{stats: stats}, nil, nil, nil, nil, nil, nil, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v1.EventHandlerStatsPath, nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp eventhandler.QueueStats
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, stats, resp)
+}
+
+type fakeImagePreloader struct {
+	preloads []engine.ImagePreload
+	err      error
+}
+
+func (f *fakeImagePreloader) PreloadImages(preloads []engine.ImagePreload) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.preloads = append(f.preloads, preloads...)
+	return nil
+}
+
+func (f *fakeImagePreloader) ImagePreloadStatuses() []engine.ImagePreloadStatus {
+	var statuses []engine.ImagePreloadStatus
+	for _, preload := range f.preloads {
+		statuses = append(statuses, engine.ImagePreloadStatus{
+			Image:   preload.Image,
+			RoleArn: preload.RoleArn,
+			Status:  engine.ImagePreloadPulling,
+		})
+	}
+	return statuses
+}
+
+func performImagePreloadRequest(t *testing.T, preloader v1.ImagePreloader, method string, body string,
+	remoteAddr string, sendToken bool) *httptest.ResponseRecorder {
+	dataDir, err := ioutil.TempDir("", "image-preload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	require.NoError(t, v1.WriteImagePreloadToken(dataDir))
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		preloader, nil, nil, nil, nil, nil, &config.Config{Cluster: testClusterArn, DataDir: dataDir})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, v1.ImagePreloadPath, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	if sendToken {
+		token, err := ioutil.ReadFile(filepath.Join(dataDir, v1.ImagePreloadTokenFile))
+		require.NoError(t, err)
+		req.Header.Set(v1.ImagePreloadTokenHeader, string(token))
+	}
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestImagePreloadHandler(t *testing.T) {
+	preloader := &fakeImagePreloader{}
+	body := `{"Images": [{"Image": "busybox:latest"}, {"Image": "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:1", "RoleArn": "arn:aws:iam::123456789012:role/pull"}]}`
+
+	recorder := performImagePreloadRequest(t, preloader, http.MethodPost, body, "127.0.0.1:43210", true)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, []engine.ImagePreload{
+		{Image: "busybox:latest"},
+		{Image: "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:1", RoleArn: "arn:aws:iam::123456789012:role/pull"},
+	}, preloader.preloads)
+
+	recorder = performImagePreloadRequest(t, preloader, http.MethodGet, "", "[::1]:43210", true)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp v1.ImagePreloadResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Len(t, resp.Images, 2)
+	assert.Equal(t, engine.ImagePreloadPulling, resp.Images[0].Status)
+}
+
+func TestImagePreloadHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		remoteAddr     string
+		noToken        bool
+		preloadErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "remote request",
+			method:         http.MethodPost,
+			body:           `{"Images": [{"Image": "busybox"}]}`,
+			remoteAddr:     "10.0.0.5:43210",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "missing token",
+			method:         http.MethodPost,
+			body:           `{"Images": [{"Image": "busybox"}]}`,
+			remoteAddr:     "127.0.0.1:43210",
+			noToken:        true,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid json",
+			method:         http.MethodPost,
+			body:           `not json`,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no images",
+			method:         http.MethodPost,
+			body:           `{"Images": []}`,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid preload",
+			method:         http.MethodPost,
+			body:           `{"Images": [{"Image": "busybox", "RoleArn": "arn:aws:iam::123456789012:role/pull"}]}`,
+			remoteAddr:     "127.0.0.1:43210",
+			preloadErr:     errors.New("a role can only be used to pull ECR images"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported method",
+			method:         http.MethodDelete,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			preloader := &fakeImagePreloader{err: testCase.preloadErr}
+			recorder := performImagePreloadRequest(t, preloader, testCase.method, testCase.body, testCase.remoteAddr,
+				!testCase.noToken)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+			assert.Empty(t, preloader.preloads)
+		})
+	}
+}
+
+func performLogLevelRequest(method string, body string, remoteAddr string) *httptest.ResponseRecorder {
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, nil, nil, nil, nil, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, v1.LogLevelPath, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	level, instanceLevel, moduleLevels := logger.Levels()
+	defer logger.SetLevels(level, instanceLevel, moduleLevels)
+
+	recorder := performLogLevelRequest(http.MethodPut,
+		`{"Level": "debug", "ModuleLevels": {"dockerapi": "debug", "acs": "warn"}}`, "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp v1.LogLevelResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, "debug", resp.Level)
+	assert.Equal(t, instanceLevel, resp.InstanceLevel)
+	assert.Equal(t, map[string]string{"dockerapi": "debug", "acs": "warn"}, resp.ModuleLevels)
+
+	recorder = performLogLevelRequest(http.MethodGet, "", "[::1]:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var getResp v1.LogLevelResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &getResp))
+	assert.Equal(t, resp, getResp)
+}
+
+func TestLogLevelHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{
+			name:           "remote request",
+			method:         http.MethodPut,
+			body:           `{"Level": "debug"}`,
+			remoteAddr:     "10.0.0.5:43210",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid json",
+			method:         http.MethodPut,
+			body:           `not json`,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid level",
+			method:         http.MethodPut,
+			body:           `{"Level": "verbose"}`,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid module level",
+			method:         http.MethodPut,
+			body:           `{"ModuleLevels": {"acs": "verbose"}}`,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported method",
+			method:         http.MethodPost,
+			body:           `{"Level": "debug"}`,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			level, instanceLevel, moduleLevels := logger.Levels()
+			recorder := performLogLevelRequest(testCase.method, testCase.body, testCase.remoteAddr)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+			newLevel, newInstanceLevel, newModuleLevels := logger.Levels()
+			assert.Equal(t, level, newLevel)
+			assert.Equal(t, instanceLevel, newInstanceLevel)
+			assert.Equal(t, moduleLevels, newModuleLevels)
+		})
+	}
+}
+
+type fakeDrainer struct {
+	draining bool
+}
+
+func (f *fakeDrainer) Drain() {
+	f.draining = true
+}
+
+func (f *fakeDrainer) DrainStatus() engine.DrainStatus {
+	if !f.draining {
+		return engine.DrainStatus{}
+	}
+	return engine.DrainStatus{
+		Draining: true,
+		Tasks: []engine.TaskDrainStatus{
+			{
+				TaskARN:           "task1",
+				KnownStatus:       "RUNNING",
+				DesiredStatus:     "STOPPED",
+				RunningContainers: 2,
+			},
+		},
+	}
+}
+
+func performDrainRequest(drainer v1.Drainer, method string, path string, remoteAddr string) *httptest.ResponseRecorder {
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, drainer, nil, nil, nil, nil, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestDrainHandler(t *testing.T) {
+	drainer := &fakeDrainer{}
+
+	recorder := performDrainRequest(drainer, http.MethodGet, v1.DrainStatusPath, "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp engine.DrainStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.False(t, resp.Draining)
+
+	recorder = performDrainRequest(drainer, http.MethodPost, v1.DrainPath, "127.0.0.1:43210")
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.True(t, drainer.draining)
+
+	recorder = performDrainRequest(drainer, http.MethodGet, v1.DrainStatusPath, "[::1]:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.True(t, resp.Draining)
+	require.Len(t, resp.Tasks, 1)
+	assert.Equal(t, 2, resp.Tasks[0].RunningContainers)
+}
+
+func TestDrainHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{
+			name:           "remote drain request",
+			method:         http.MethodPost,
+			path:           v1.DrainPath,
+			remoteAddr:     "10.0.0.5:43210",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "remote drain status request",
+			method:         http.MethodGet,
+			path:           v1.DrainStatusPath,
+			remoteAddr:     "10.0.0.5:43210",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "drain with unsupported method",
+			method:         http.MethodGet,
+			path:           v1.DrainPath,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "drain status with unsupported method",
+			method:         http.MethodPost,
+			path:           v1.DrainStatusPath,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			drainer := &fakeDrainer{}
+			recorder := performDrainRequest(drainer, testCase.method, testCase.path, testCase.remoteAddr)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+			assert.False(t, drainer.draining)
+		})
+	}
+}
+
+type fakeTaskDryRunner struct {
+	defs []engine.DryRunTaskDefinition
+	err  error
+}
+
+func (f *fakeTaskDryRunner) StartTaskDryRun(def engine.DryRunTaskDefinition) (engine.TaskDryRunResult, error) {
+	if f.err != nil {
+		return engine.TaskDryRunResult{}, f.err
+	}
+	f.defs = append(f.defs, def)
+	return engine.TaskDryRunResult{ID: "dryrun1", Family: def.Family, Status: engine.TaskDryRunRunning}, nil
+}
+
+func (f *fakeTaskDryRunner) TaskDryRunResults() []engine.TaskDryRunResult {
+	var results []engine.TaskDryRunResult
+	for _, def := range f.defs {
+		results = append(results, engine.TaskDryRunResult{
+			ID:     "dryrun1",
+			Family: def.Family,
+			Status: engine.TaskDryRunSucceeded,
+		})
+	}
+	return results
+}
+
+func performTaskDryRunRequest(dryRunner v1.TaskDryRunner, method string, body string, remoteAddr string) *httptest.ResponseRecorder {
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, dryRunner, nil, nil, nil, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, v1.TaskDryRunPath, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestTaskDryRunHandler(t *testing.T) {
+	dryRunner := &fakeTaskDryRunner{}
+	body := `{"family": "web", "networkMode": "bridge", "requiresCompatibilities": ["EC2"],
+		"containerDefinitions": [{"name": "app", "image": "busybox", "memory": 128, "essential": true}]}`
+
+	recorder := performTaskDryRunRequest(dryRunner, http.MethodPost, body, "127.0.0.1:43210")
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	var result engine.TaskDryRunResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Equal(t, engine.TaskDryRunRunning, result.Status)
+	assert.Equal(t, []engine.DryRunTaskDefinition{{
+		Family:      "web",
+		NetworkMode: "bridge",
+		ContainerDefinitions: []engine.DryRunContainerDefinition{
+			{Name: "app", Image: "busybox", Memory: 128},
+		},
+	}}, dryRunner.defs)
+
+	recorder = performTaskDryRunRequest(dryRunner, http.MethodGet, "", "[::1]:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp v1.TaskDryRunResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Len(t, resp.DryRuns, 1)
+	assert.Equal(t, engine.TaskDryRunSucceeded, resp.DryRuns[0].Status)
+}
+
+func TestTaskDryRunHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		remoteAddr     string
+		dryRunErr      error
+		expectedStatus int
+	}{
+		{
+			name:           "remote request",
+			method:         http.MethodPost,
+			body:           `{"containerDefinitions": [{"name": "app", "image": "busybox"}]}`,
+			remoteAddr:     "10.0.0.5:43210",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid json",
+			method:         http.MethodPost,
+			body:           `not json`,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid task definition",
+			method:         http.MethodPost,
+			body:           `{"containerDefinitions": []}`,
+			remoteAddr:     "127.0.0.1:43210",
+			dryRunErr:      errors.New("task definition has no container definitions"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported method",
+			method:         http.MethodDelete,
+			remoteAddr:     "127.0.0.1:43210",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dryRunner := &fakeTaskDryRunner{err: testCase.dryRunErr}
+			recorder := performTaskDryRunRequest(dryRunner, testCase.method, testCase.body, testCase.remoteAddr)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+			assert.Empty(t, dryRunner.defs)
+		})
+	}
+}
+
+type fakeStateReporter struct {
+	report engine.StateReport
+	calls  int
+}
+
+func (f *fakeStateReporter) StateReport(ctx context.Context) engine.StateReport {
+	f.calls++
+	return f.report
+}
+
+func performStateReportRequest(reporter v1.StateReporter, method string, remoteAddr string) *httptest.ResponseRecorder {
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, nil, reporter, nil, nil, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, v1.StateReportPath, nil)
+	req.RemoteAddr = remoteAddr
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestStateReportHandler(t *testing.T) {
+	reporter := &fakeStateReporter{report: engine.StateReport{
+		OrphanedContainers: []engine.OrphanedContainer{
+			{DockerID: "abc", TaskARN: "task1", ContainerName: "app", DockerStatus: "running"},
+		},
+		StaleCheckpoints: []engine.Checkpoint{
+			{Kind: engine.CheckpointKindTask, TaskARN: "task2"},
+		},
+	}}
+
+	recorder := performStateReportRequest(reporter, http.MethodGet, "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp engine.StateReport
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, reporter.report, resp)
+}
+
+func TestStateReportHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{"remote request", http.MethodGet, "10.0.0.5:43210", http.StatusForbidden},
+		{"unsupported method", http.MethodPost, "127.0.0.1:43210", http.StatusMethodNotAllowed},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reporter := &fakeStateReporter{}
+			recorder := performStateReportRequest(reporter, testCase.method, testCase.remoteAddr)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+			assert.Zero(t, reporter.calls)
+		})
+	}
+}
+
+type fakeDiagnosticsBundler struct {
+	bundle []byte
+	err    error
+	calls  int
+}
+
+func (f *fakeDiagnosticsBundler) Write(ctx context.Context, w io.Writer) error {
+	f.calls++
+	if f.err != nil {
+		return f.err
+	}
+	_, err := w.Write(f.bundle)
+	return err
+}
+
+func performDiagnosticsBundleRequest(bundler v1.DiagnosticsBundler, method string, remoteAddr string) *httptest.ResponseRecorder {
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, nil, nil, bundler, nil, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, v1.DiagnosticsBundlePath, nil)
+	req.RemoteAddr = remoteAddr
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestDiagnosticsBundleHandler(t *testing.T) {
+	bundler := &fakeDiagnosticsBundler{bundle: []byte("bundle")}
+
+	recorder := performDiagnosticsBundleRequest(bundler, http.MethodGet, "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/gzip", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Header().Get("Content-Disposition"), "ecs-agent-diagnostics-")
+	assert.Equal(t, "bundle", recorder.Body.String())
+}
+
+func TestDiagnosticsBundleHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		remoteAddr     string
+		bundleErr      error
+		expectedStatus int
+		expectedCalls  int
+	}{
+		{"remote request", http.MethodGet, "10.0.0.5:43210", nil, http.StatusForbidden, 0},
+		{"unsupported method", http.MethodPost, "127.0.0.1:43210", nil, http.StatusMethodNotAllowed, 0},
+		{"bundle error", http.MethodGet, "127.0.0.1:43210", errors.New("disk full"), http.StatusInternalServerError, 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			bundler := &fakeDiagnosticsBundler{err: testCase.bundleErr}
+			recorder := performDiagnosticsBundleRequest(bundler, testCase.method, testCase.remoteAddr)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, testCase.expectedCalls, bundler.calls)
+		})
+	}
+}
+
+func TestConfigReportHandler(t *testing.T) {
+	cfg := &config.Config{
+		Cluster:        testClusterArn,
+		EngineAuthData: config.NewSensitiveRawMessage([]byte(`{"registry":{"auth":"secret"}}`)),
+		ValidationProblems: []config.ValidationProblem{
+			{Variables: []string{"ECS_ENABLE_GPU_METRICS", "ECS_ENABLE_GPU_SUPPORT"}, Message: "problem"},
+		},
+	}
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, nil, nil, nil, nil, cfg)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, v1.ConfigReportPath, nil)
+	req.RemoteAddr = "127.0.0.1:43210"
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "secret")
+
+	var report config.Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, cfg.ValidationProblems, report.Problems)
+	variables := make(map[string]string)
+	for _, variable := range report.Variables {
+		variables[variable.Name] = variable.Value
+	}
+	assert.Equal(t, testClusterArn, variables["ECS_CLUSTER"])
+	assert.Equal(t, "[redacted]", variables["ECS_ENGINE_AUTH_DATA"])
+}
+
+func TestConfigReportHandlerRemoteRequest(t *testing.T) {
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, nil, nil, nil, nil, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, v1.ConfigReportPath, nil)
+	req.RemoteAddr = "10.0.0.5:43210"
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), testClusterArn)
+}
+
+func performPprofRequest(pprofEnabled bool, path string, remoteAddr string) *httptest.ResponseRecorder {
+	cfg := &config.Config{Cluster: testClusterArn}
+	if pprofEnabled {
+		cfg.PprofEnabled = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	}
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, nil, nil, nil, nil, cfg)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestPprofHandler(t *testing.T) {
+	recorder := performPprofRequest(true, v1.PprofPath, "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "/debug/pprof/heap\n")
+	assert.Contains(t, recorder.Body.String(), "/debug/pprof/profile\n")
+
+	recorder = performPprofRequest(true, v1.PprofPath+"heap?gc=1", "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/octet-stream", recorder.Header().Get("Content-Type"))
+	assert.NotEmpty(t, recorder.Body.Bytes())
+
+	recorder = performPprofRequest(true, v1.PprofPath+"goroutine?debug=1", "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "goroutine profile:")
+
+	recorder = performPprofRequest(true, v1.PprofPath+"profile?seconds=0.1", "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotEmpty(t, recorder.Body.Bytes())
+}
+
+func TestPprofHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{"remote request", v1.PprofPath + "heap", "10.0.0.5:43210", http.StatusForbidden},
+		{"unknown profile", v1.PprofPath + "unknown", "127.0.0.1:43210", http.StatusNotFound},
+		{"invalid seconds", v1.PprofPath + "profile?seconds=abc", "127.0.0.1:43210", http.StatusBadRequest},
+		{"too long profile", v1.PprofPath + "trace?seconds=120", "127.0.0.1:43210", http.StatusBadRequest},
+		{"invalid debug level", v1.PprofPath + "heap?debug=-1", "127.0.0.1:43210", http.StatusBadRequest},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := performPprofRequest(true, testCase.path, testCase.remoteAddr)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestPprofHandlerDisabled(t *testing.T) {
+	// The request falls through to the list of the available commands
+	recorder := performPprofRequest(false, v1.PprofPath+"heap", "127.0.0.1:43210")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp rootResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.NotContains(t, resp.AvailableCommands, v1.PprofPath)
+	assert.Contains(t, resp.AvailableCommands, v1.DiagnosticsBundlePath)
+	assert.Contains(t, resp.AvailableCommands, v1.ConfigReportPath)
+}
+
+type fakeIntrospectionTasksResolver struct {
+	state   dockerstate.TaskEngineState
+	stopped []engine.StoppedTask
+}
+
+func (f *fakeIntrospectionTasksResolver) State() dockerstate.TaskEngineState {
+	return f.state
+}
+
+func (f *fakeIntrospectionTasksResolver) StoppedTasks() []engine.StoppedTask {
+	return f.stopped
+}
+
+func newFakeIntrospectionTasksResolver() *fakeIntrospectionTasksResolver {
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*apitask.Task{
+		{
+			Arn:                 "task-running",
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+			KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+			Family:              "web",
+			Version:             "1",
+			Containers:          []*apicontainer.Container{{Name: "nginx"}},
+		},
+		{
+			Arn:                 "task-batch",
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+			KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+			Family:              "batch",
+			Version:             "3",
+			Containers:          []*apicontainer.Container{{Name: "worker"}},
+		},
+	})
+
+	exitCode := 137
+	stoppedTask := &apitask.Task{
+		Arn:                 "task-cleaned-up",
+		DesiredStatusUnsafe: apitaskstatus.TaskStopped,
+		KnownStatusUnsafe:   apitaskstatus.TaskStopped,
+		Family:              "web",
+		Version:             "1",
+	}
+	stoppedTask.SetTerminalReason("Essential container in task exited")
+	container := &apicontainer.Container{Name: "nginx", KnownExitCodeUnsafe: &exitCode}
+	return &fakeIntrospectionTasksResolver{
+		state: state,
+		stopped: []engine.StoppedTask{
+			{
+				Task: stoppedTask,
+				Containers: map[string]*apicontainer.DockerContainer{
+					"nginx": {Container: container, DockerID: "dockerid-task-cleaned-up-nginx"},
+				},
+				CleanedUpAt: time.Now(),
+			},
+		},
+	}
+}
+
+func performIntrospectionTasksRequest(t *testing.T, resolver *fakeIntrospectionTasksResolver,
+	method string, query string) (*httptest.ResponseRecorder, v2.IntrospectionTasksResponse) {
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), nil, nil,
+		nil, nil, nil, nil, nil, resolver, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, v2.IntrospectionTasksPath+query, nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+
+	var resp v2.IntrospectionTasksResponse
+	if recorder.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	}
+	return recorder, resp
+}
+
+func introspectionTaskARNs(resp v2.IntrospectionTasksResponse) []string {
+	var arns []string
+	for _, task := range resp.Tasks {
+		arns = append(arns, task.Arn)
+	}
+	return arns
+}
+
+func TestIntrospectionTasksHandler(t *testing.T) {
+	resolver := newFakeIntrospectionTasksResolver()
+
+	recorder, resp := performIntrospectionTasksRequest(t, resolver, http.MethodGet, "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"task-batch", "task-cleaned-up", "task-running"}, introspectionTaskARNs(resp))
+	assert.Empty(t, resp.NextToken)
+
+	stopped := resp.Tasks[1]
+	assert.Equal(t, "STOPPED", stopped.KnownStatus)
+	assert.Equal(t, "Essential container in task exited", stopped.StoppedReason)
+	assert.NotNil(t, stopped.CleanedUpAt)
+	require.Len(t, stopped.Containers, 1)
+	assert.Equal(t, "nginx", stopped.Containers[0].Name)
+	require.NotNil(t, stopped.Containers[0].ExitCode)
+	assert.Equal(t, 137, *stopped.Containers[0].ExitCode)
+
+	running := resp.Tasks[2]
+	assert.Equal(t, "RUNNING", running.KnownStatus)
+	assert.Nil(t, running.CleanedUpAt)
+	require.Len(t, running.Containers, 1)
+	assert.Equal(t, "dockerid-task-running-nginx", running.Containers[0].DockerID)
+}
+
+func TestIntrospectionTasksHandlerFilters(t *testing.T) {
+	testCases := []struct {
+		query        string
+		expectedARNs []string
+	}{
+		{"?status=stopped", []string{"task-cleaned-up"}},
+		{"?status=RUNNING", []string{"task-batch", "task-running"}},
+		{"?family=web", []string{"task-cleaned-up", "task-running"}},
+		{"?family=web&status=RUNNING", []string{"task-running"}},
+		{"?taskarn=task-batch", []string{"task-batch"}},
+		{"?taskarn=unknown", nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.query, func(t *testing.T) {
+			recorder, resp := performIntrospectionTasksRequest(t, newFakeIntrospectionTasksResolver(),
+				http.MethodGet, testCase.query)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, testCase.expectedARNs, introspectionTaskARNs(resp))
+		})
+	}
+}
+
+func TestIntrospectionTasksHandlerPagination(t *testing.T) {
+	resolver := newFakeIntrospectionTasksResolver()
+
+	recorder, resp := performIntrospectionTasksRequest(t, resolver, http.MethodGet, "?maxresults=2")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"task-batch", "task-cleaned-up"}, introspectionTaskARNs(resp))
+	require.NotEmpty(t, resp.NextToken)
+
+	recorder, resp = performIntrospectionTasksRequest(t, resolver, http.MethodGet,
+		"?maxresults=2&nexttoken="+resp.NextToken)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"task-running"}, introspectionTaskARNs(resp))
+	assert.Empty(t, resp.NextToken)
+}
+
+func TestIntrospectionTasksHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		query          string
+		expectedStatus int
+	}{
+		{"max results too small", http.MethodGet, "?maxresults=0", http.StatusBadRequest},
+		{"max results too large", http.MethodGet, "?maxresults=101", http.StatusBadRequest},
+		{"max results not a number", http.MethodGet, "?maxresults=ten", http.StatusBadRequest},
+		{"invalid next token", http.MethodGet, "?nexttoken=%25%25", http.StatusBadRequest},
+		{"unsupported method", http.MethodPost, "", http.StatusMethodNotAllowed},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder, _ := performIntrospectionTasksRequest(t, newFakeIntrospectionTasksResolver(),
+				testCase.method, testCase.query)
+			assert.Equal(t, testCase.expectedStatus, recorder.Code)
+		})
+	}
+}
+
 func TestListMultipleTasks(t *testing.T) {
 	recorder := performMockRequest(t, "/v1/tasks")
 
@@ -410,7 +1288,7 @@ func performMockRequest(t *testing.T, path string) *httptest.ResponseRecorder {
 	stateSetupHelper(state, testTasks)
 
 	mockStateResolver.EXPECT().State().Return(state)
-	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), mockStateResolver, &config.Config{Cluster: testClusterArn})
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), mockStateResolver, nil, nil, nil, nil, nil, nil, nil, &config.Config{Cluster: testClusterArn})
 
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", path, nil)
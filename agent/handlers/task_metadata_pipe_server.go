@@ -0,0 +1,150 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/cihub/seelog"
+)
+
+// taskMetadataPipePathPrefix is the prefix of the named pipe created for each task
+const taskMetadataPipePathPrefix = `\\.\pipe\ecs-task-metadata-`
+
+// TaskMetadataPipeServer serves the task metadata and credentials endpoints over a
+// per-task named pipe, so that containers can reach them without going through the
+// network stack of the task. Each pipe only answers requests for the task it was
+// created for.
+type TaskMetadataPipeServer struct {
+	state   dockerstate.TaskEngineState
+	lock    sync.RWMutex
+	handler http.Handler
+	servers map[string]*http.Server
+	// listen creates the listener of a pipe, it's a platform specific function
+	listen func(pipePath string) (net.Listener, error)
+}
+
+// NewTaskMetadataPipeServer creates a new TaskMetadataPipeServer
+func NewTaskMetadataPipeServer(state dockerstate.TaskEngineState) *TaskMetadataPipeServer {
+	return &TaskMetadataPipeServer{
+		state:   state,
+		servers: make(map[string]*http.Server),
+		listen:  listenTaskMetadataPipe,
+	}
+}
+
+// setHandler sets the handler of the task server, that requests received on the
+// pipes are forwarded to once they have been checked against the task of the pipe
+func (s *TaskMetadataPipeServer) setHandler(handler http.Handler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handler = handler
+}
+
+// ServeTask starts serving the endpoints of the task over its pipe if it's not served
+// yet, and returns the path of the pipe
+func (s *TaskMetadataPipeServer) ServeTask(task *apitask.Task) (string, error) {
+	taskID, err := task.GetID()
+	if err != nil {
+		return "", err
+	}
+	pipePath := taskMetadataPipePathPrefix + taskID
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.servers[task.Arn]; ok {
+		return pipePath, nil
+	}
+	listener, err := s.listen(pipePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to create task metadata pipe %s: %v", pipePath, err)
+	}
+	server := &http.Server{
+		Handler:      s.taskHandler(task.Arn),
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}
+	s.servers[task.Arn] = server
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			seelog.Errorf("Error serving task metadata pipe %s: %v", pipePath, err)
+		}
+	}()
+	seelog.Infof("Serving task metadata over pipe %s for task %s", pipePath, task.Arn)
+	return pipePath, nil
+}
+
+// StopServingTask closes the pipe of the task
+func (s *TaskMetadataPipeServer) StopServingTask(taskARN string) {
+	s.lock.Lock()
+	server, ok := s.servers[taskARN]
+	delete(s.servers, taskARN)
+	s.lock.Unlock()
+	if !ok {
+		return
+	}
+	if err := server.Close(); err != nil {
+		seelog.Warnf("Error closing task metadata pipe of task %s: %v", taskARN, err)
+	}
+}
+
+// taskHandler returns the handler of the pipe of a task. It rejects the requests
+// for the metadata or the credentials of any other task.
+func (s *TaskMetadataPipeServer) taskHandler(taskARN string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.RLock()
+		handler := s.handler
+		s.lock.RUnlock()
+		if handler == nil {
+			http.Error(w, "task metadata server is not ready", http.StatusServiceUnavailable)
+			return
+		}
+		if !s.isTaskRequest(taskARN, r) {
+			http.Error(w, "resource is not available over the pipe of this task", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// isTaskRequest returns true if the request is for the metadata, stats or credentials
+// of the given task
+func (s *TaskMetadataPipeServer) isTaskRequest(taskARN string, r *http.Request) bool {
+	segments := strings.Split(strings.TrimPrefix(path.Clean(r.URL.Path), "/"), "/")
+	switch {
+	case len(segments) >= 2 && (segments[0] == "v3" || segments[0] == "v4"):
+		endpointTaskARN, ok := s.state.TaskARNByV3EndpointID(segments[1])
+		return ok && endpointTaskARN == taskARN
+	case path.Clean(r.URL.Path) == credentials.V1CredentialsPath:
+		return s.isTaskCredentialsID(taskARN, r.URL.Query().Get(credentials.CredentialsIDQueryParameterName))
+	case len(segments) == 3 && "/"+segments[0]+"/"+segments[1] == credentials.V2CredentialsPath:
+		return s.isTaskCredentialsID(taskARN, segments[2])
+	}
+	return false
+}
+
+// isTaskCredentialsID returns true if the credentials ID is the one of the task role
+// of the given task
+func (s *TaskMetadataPipeServer) isTaskCredentialsID(taskARN string, credentialsID string) bool {
+	task, ok := s.state.TaskByArn(taskARN)
+	return ok && credentialsID != "" && task.GetCredentialsID() == credentialsID
+}
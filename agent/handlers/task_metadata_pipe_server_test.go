@@ -0,0 +1,134 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	pipeTaskARN       = "arn:aws:ecs:us-west-2:123456789012:task/default/pipetask"
+	otherPipeTaskARN  = "arn:aws:ecs:us-west-2:123456789012:task/default/othertask"
+	pipeEndpointID    = "pipe-endpoint-id"
+	otherEndpointID   = "other-endpoint-id"
+	pipeCredentialsID = "pipe-credentials-id"
+)
+
+// newTestTaskMetadataPipeServer creates a pipe server backed by a state with two tasks,
+// and listening on the loopback interface instead of named pipes
+func newTestTaskMetadataPipeServer(t *testing.T) (*TaskMetadataPipeServer, *[]string) {
+	state := dockerstate.NewTaskEngineState()
+	for arn, endpointID := range map[string]string{
+		pipeTaskARN:      pipeEndpointID,
+		otherPipeTaskARN: otherEndpointID,
+	} {
+		container := &apicontainer.Container{Name: "app", V3EndpointID: endpointID}
+		task := &apitask.Task{Arn: arn, Containers: []*apicontainer.Container{container}}
+		state.AddTask(task)
+		state.AddContainer(&apicontainer.DockerContainer{DockerID: endpointID, Container: container}, task)
+	}
+	task, _ := state.TaskByArn(pipeTaskARN)
+	task.SetCredentialsID(pipeCredentialsID)
+
+	server := NewTaskMetadataPipeServer(state)
+	var pipePaths []string
+	server.listen = func(pipePath string) (net.Listener, error) {
+		pipePaths = append(pipePaths, pipePath)
+		return net.Listen("tcp", "127.0.0.1:0")
+	}
+	return server, &pipePaths
+}
+
+func TestTaskMetadataPipeServerServeTask(t *testing.T) {
+	server, pipePaths := newTestTaskMetadataPipeServer(t)
+	task, _ := server.state.TaskByArn(pipeTaskARN)
+
+	pipePath, err := server.ServeTask(task)
+	require.NoError(t, err)
+	assert.Equal(t, `\\.\pipe\ecs-task-metadata-pipetask`, pipePath)
+
+	pipePath, err = server.ServeTask(task)
+	require.NoError(t, err)
+	assert.Equal(t, `\\.\pipe\ecs-task-metadata-pipetask`, pipePath)
+	assert.Len(t, *pipePaths, 1, "the pipe of a task should only be created once")
+
+	server.StopServingTask(pipeTaskARN)
+	server.StopServingTask(pipeTaskARN)
+	assert.Empty(t, server.servers)
+
+	_, err = server.ServeTask(task)
+	require.NoError(t, err)
+	assert.Len(t, *pipePaths, 2, "the pipe should be created again once it has been stopped")
+	server.StopServingTask(pipeTaskARN)
+}
+
+func TestTaskMetadataPipeServerListenError(t *testing.T) {
+	server, _ := newTestTaskMetadataPipeServer(t)
+	server.listen = func(pipePath string) (net.Listener, error) {
+		return nil, errors.New("access denied")
+	}
+	task, _ := server.state.TaskByArn(pipeTaskARN)
+
+	_, err := server.ServeTask(task)
+	assert.Error(t, err)
+	assert.Empty(t, server.servers)
+}
+
+func TestTaskMetadataPipeServerTaskHandler(t *testing.T) {
+	server, _ := newTestTaskMetadataPipeServer(t)
+	handler := server.taskHandler(pipeTaskARN)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v4/"+pipeEndpointID, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code, "requests should fail until the task server is set up")
+
+	server.setHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tc := range []struct {
+		path         string
+		expectedCode int
+	}{
+		{"/v3/" + pipeEndpointID, http.StatusOK},
+		{"/v4/" + pipeEndpointID + "/task", http.StatusOK},
+		{"//v4//" + pipeEndpointID + "/stats", http.StatusOK},
+		{"/v4/" + otherEndpointID + "/task", http.StatusForbidden},
+		{"/v4/unknown-endpoint-id", http.StatusForbidden},
+		{"/v2/credentials/" + pipeCredentialsID, http.StatusOK},
+		{"/v2/credentials/other-credentials-id", http.StatusForbidden},
+		{"/v2/credentials/", http.StatusForbidden},
+		{"/v1/credentials?id=" + pipeCredentialsID, http.StatusOK},
+		{"/v1/credentials?id=other-credentials-id", http.StatusForbidden},
+		{"/v2/metadata", http.StatusForbidden},
+		{"/v1/metadata", http.StatusForbidden},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", tc.path, nil))
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// +build windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// taskMetadataPipeSecurityDescriptor allows SYSTEM and administrators full access to the
+// pipe, and every other user to read and write to it, so that the processes running as
+// container users can connect to it
+const taskMetadataPipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GRGW;;;WD)"
+
+// listenTaskMetadataPipe creates the named pipe of a task
+func listenTaskMetadataPipe(pipePath string) (net.Listener, error) {
+	return winio.ListenPipe(pipePath, &winio.PipeConfig{
+		SecurityDescriptor: taskMetadataPipeSecurityDescriptor,
+	})
+}
@@ -63,7 +63,7 @@ func taskServerSetup(credentialsManager credentials.Manager,
 	muxRouter.SkipClean(false)
 
 	muxRouter.HandleFunc(v1.CredentialsPath,
-		v1.CredentialsHandler(credentialsManager, auditLogger))
+		v1.CredentialsHandler(credentialsManager, state, auditLogger))
 
 	v2HandlersSetup(muxRouter, state, ecsClient, statsEngine, cluster, credentialsManager, auditLogger, availabilityZone, containerInstanceArn)
 
@@ -71,6 +71,9 @@ func taskServerSetup(credentialsManager credentials.Manager,
 
 	v4HandlersSetup(muxRouter, state, ecsClient, statsEngine, cluster, availabilityZone, containerInstanceArn)
 
+	// Record the status and latency of all requests served by the handlers above.
+	muxRouter.Use(requestMetricsMiddleware)
+
 	limiter := tollbooth.NewLimiter(int64(steadyStateRate), nil)
 	limiter.SetOnLimitReached(handlersutils.LimitReachedHandler(auditLogger))
 	limiter.SetBurst(burstRate)
@@ -105,7 +108,7 @@ func v2HandlersSetup(muxRouter *mux.Router,
 	auditLogger audit.AuditLogger,
 	availabilityZone string,
 	containerInstanceArn string) {
-	muxRouter.HandleFunc(v2.CredentialsPath, v2.CredentialsHandler(credentialsManager, auditLogger))
+	muxRouter.HandleFunc(v2.CredentialsPath, v2.CredentialsHandler(credentialsManager, state, auditLogger))
 	muxRouter.HandleFunc(v2.ContainerMetadataPath, v2.TaskContainerMetadataHandler(state, ecsClient, cluster, availabilityZone, containerInstanceArn, false))
 	muxRouter.HandleFunc(v2.TaskMetadataPath, v2.TaskContainerMetadataHandler(state, ecsClient, cluster, availabilityZone, containerInstanceArn, false))
 	muxRouter.HandleFunc(v2.TaskWithTagsMetadataPath, v2.TaskContainerMetadataHandler(state, ecsClient, cluster, availabilityZone, containerInstanceArn, true))
@@ -143,17 +146,20 @@ func v4HandlersSetup(muxRouter *mux.Router,
 	availabilityZone string,
 	containerInstanceArn string) {
 	muxRouter.HandleFunc(v4.ContainerMetadataPath, v4.ContainerMetadataHandler(state))
-	muxRouter.HandleFunc(v4.TaskMetadataPath, v4.TaskMetadataHandler(state, ecsClient, cluster, availabilityZone, containerInstanceArn, false))
-	muxRouter.HandleFunc(v4.TaskWithTagsMetadataPath, v4.TaskMetadataHandler(state, ecsClient, cluster, availabilityZone, containerInstanceArn, true))
+	muxRouter.HandleFunc(v4.TaskMetadataPath, v4.TaskMetadataHandler(state, ecsClient, statsEngine, cluster, availabilityZone, containerInstanceArn, false))
+	muxRouter.HandleFunc(v4.TaskWithTagsMetadataPath, v4.TaskMetadataHandler(state, ecsClient, statsEngine, cluster, availabilityZone, containerInstanceArn, true))
 	muxRouter.HandleFunc(v4.ContainerStatsPath, v4.ContainerStatsHandler(state, statsEngine))
+	muxRouter.HandleFunc(v4.ContainerHealthHistoryPath, v4.ContainerHealthHistoryHandler(state))
 	muxRouter.HandleFunc(v4.TaskStatsPath, v4.TaskStatsHandler(state, statsEngine))
+	muxRouter.HandleFunc(v4.TaskStatsSnapshotPath, v4.TaskStatsSnapshotHandler(state, statsEngine))
 	muxRouter.HandleFunc(v4.ContainerAssociationsPath, v4.ContainerAssociationsHandler(state))
 	muxRouter.HandleFunc(v4.ContainerAssociationPathWithSlash, v4.ContainerAssociationHandler(state))
 	muxRouter.HandleFunc(v4.ContainerAssociationPath, v4.ContainerAssociationHandler(state))
 }
 
 // ServeTaskHTTPEndpoint serves task/container metadata, task/container stats, and IAM Role Credentials
-// for tasks being managed by the agent.
+// for tasks being managed by the agent. If pipeServer is not nil, the same endpoints are also served
+// over the named pipes of the tasks.
 func ServeTaskHTTPEndpoint(
 	ctx context.Context,
 	credentialsManager credentials.Manager,
@@ -162,7 +168,8 @@ func ServeTaskHTTPEndpoint(
 	containerInstanceArn string,
 	cfg *config.Config,
 	statsEngine stats.Engine,
-	availabilityZone string) {
+	availabilityZone string,
+	pipeServer *TaskMetadataPipeServer) {
 	// Create and initialize the audit log
 	logger, err := seelog.LoggerFromConfigAsString(audit.AuditLoggerConfig(cfg))
 	if err != nil {
@@ -171,10 +178,21 @@ func ServeTaskHTTPEndpoint(
 		logger = seelog.Disabled
 	}
 
-	auditLogger := audit.NewAuditLog(containerInstanceArn, cfg, logger)
+	var jsonLogger audit.InfoLogger
+	if cfg.CredentialsAuditJSONLogFile != "" {
+		if jsonLogger, err = seelog.LoggerFromConfigAsString(audit.JSONAuditLoggerConfig(cfg)); err != nil {
+			seelog.Errorf("Error initializing the JSON audit log: %v", err)
+			jsonLogger = nil
+		}
+	}
+
+	auditLogger := audit.NewAuditLogWithJSONRecords(containerInstanceArn, cfg, logger, jsonLogger)
 
 	server := taskServerSetup(credentialsManager, auditLogger, state, ecsClient, cfg.Cluster, statsEngine,
 		cfg.TaskMetadataSteadyStateRate, cfg.TaskMetadataBurstRate, availabilityZone, containerInstanceArn)
+	if pipeServer != nil {
+		pipeServer.setHandler(server.Handler)
+	}
 
 	go func() {
 		<-ctx.Done()
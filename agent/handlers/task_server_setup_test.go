@@ -36,6 +36,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/containermetadata"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
@@ -44,11 +45,14 @@ import (
 	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
 	v4 "github.com/aws/amazon-ecs-agent/agent/handlers/v4"
 	mock_audit "github.com/aws/amazon-ecs-agent/agent/logger/audit/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/logger/audit/request"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	mock_stats "github.com/aws/amazon-ecs-agent/agent/stats/mock"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/docker/api/types"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -446,6 +450,8 @@ var (
 			LaunchType:         "EC2",
 		},
 		Containers: []v4.ContainerResponse{expectedV4ContainerResponse},
+
+		InstanceMetadataAccess: &v4.InstanceMetadataAccess{},
 	}
 	expectedV4PulledTaskResponse = v4.TaskResponse{
 		TaskResponse: &v2.TaskResponse{
@@ -467,6 +473,8 @@ var (
 			LaunchType:         "EC2",
 		},
 		Containers: []v4.ContainerResponse{expectedV4ContainerResponse, expectedV4PulledContainerResponse},
+
+		InstanceMetadataAccess: &v4.InstanceMetadataAccess{},
 	}
 	expectedV4BridgeContainerResponse = v4.ContainerResponse{
 		ContainerResponse: &expectedBridgeContainerResponse,
@@ -643,6 +651,51 @@ func TestCredentialsV2RequestWhenCredentialsFound(t *testing.T) {
 	assert.Equal(t, secretAccessKey, credentials.SecretAccessKey, "Incorrect credentials received: secret access key")
 }
 
+// TestCredentialsRequestAudit tests that the audit record of a credentials request has
+// the task and role ARNs and the calling container, and that the fetch is counted for the task
+func TestCredentialsRequestAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	server := taskServerSetup(credentialsManager, auditLog, state, ecsClient, "", nil, config.DefaultTaskMetadataSteadyStateRate,
+		config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+
+	task := &apitask.Task{Arn: taskARN}
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).DoAndReturn(
+		func(id string) (credentials.TaskIAMRoleCredentials, bool) {
+			return credentials.TaskIAMRoleCredentials{
+				ARN: taskARN,
+				IAMRoleCredentials: credentials.IAMRoleCredentials{
+					RoleArn:         roleArn,
+					AccessKeyID:     accessKeyID,
+					SecretAccessKey: secretAccessKey,
+					RoleType:        credentials.ApplicationRoleType,
+				},
+			}, true
+		})
+	state.EXPECT().TaskByArn(taskARN).Return(task, true)
+	state.EXPECT().ContainerMapByArn(taskARN).Return(map[string]*apicontainer.DockerContainer{
+		containerName: bridgeContainer,
+	}, true)
+	auditLog.EXPECT().Log(gomock.Any(), http.StatusOK, gomock.Any()).Do(
+		func(logRequest request.LogRequest, httpResponseCode int, eventType string) {
+			assert.Equal(t, taskARN, logRequest.ARN)
+			assert.Equal(t, roleArn, logRequest.RoleARN)
+			assert.Equal(t, containerName, logRequest.Container)
+		})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", credentials.V2CredentialsPath+"/"+credentialsID, nil)
+	req.RemoteAddr = bridgeIPAddr + ":32768"
+	server.Handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, map[string]int{credentials.ApplicationRoleType: 1}, task.GetCredentialsFetchCount())
+}
+
 func testErrorResponsesFromServer(t *testing.T, path string, expectedErrorMessage *utils.ErrorMessage) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -684,11 +737,13 @@ func getResponseForCredentialsRequest(t *testing.T, expectedStatus int,
 	defer ctrl.Finish()
 	credentialsManager := mock_credentials.NewMockManager(ctrl)
 	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
 	ecsClient := mock_api.NewMockECSClient(ctrl)
-	server := taskServerSetup(credentialsManager, auditLog, nil, ecsClient, "", nil, config.DefaultTaskMetadataSteadyStateRate,
+	server := taskServerSetup(credentialsManager, auditLog, state, ecsClient, "", nil, config.DefaultTaskMetadataSteadyStateRate,
 		config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
 	recorder := httptest.NewRecorder()
 
+	state.EXPECT().TaskByArn(gomock.Any()).Return(nil, false).AnyTimes()
 	creds, ok := getCredentials()
 	credentialsManager.EXPECT().GetTaskCredentials(gomock.Any()).Return(creds, ok)
 	auditLog.EXPECT().Log(gomock.Any(), gomock.Any(), gomock.Any())
@@ -1573,7 +1628,10 @@ func TestV4TaskStats(t *testing.T) {
 	gomock.InOrder(
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().ContainerMapByArn(taskARN).Return(containerMap, true),
+		state.EXPECT().TaskByArn(taskARN).Return(nil, false),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, &stats.NetworkStatsPerSec{}, nil),
+		statsEngine.EXPECT().ContainerDiskUsage(taskARN, containerID).Return(nil, nil),
+		statsEngine.EXPECT().ContainerGPUStats(taskARN, containerID).Return(nil, nil),
 	)
 	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
 		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
@@ -1591,6 +1649,90 @@ func TestV4TaskStats(t *testing.T) {
 	assert.Equal(t, dockerStats.NumProcs, containerStats.NumProcs)
 }
 
+func TestV4TaskStatsSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+
+	dockerStats := &types.StatsJSON{}
+	dockerStats.NumProcs = 2
+
+	containerMap := map[string]*apicontainer.DockerContainer{
+		containerName: {
+			DockerID: containerID,
+		},
+	}
+
+	gomock.InOrder(
+		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
+		state.EXPECT().ContainerMapByArn(taskARN).Return(containerMap, true),
+		state.EXPECT().TaskByArn(taskARN).Return(nil, false),
+		statsEngine.EXPECT().ContainerDockerStatsSnapshot(taskARN, containerID).Return(dockerStats, &stats.NetworkStatsPerSec{}, nil),
+		statsEngine.EXPECT().ContainerDiskUsage(taskARN, containerID).Return(nil, nil),
+		statsEngine.EXPECT().ContainerGPUStats(taskARN, containerID).Return(nil, nil),
+	)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/task/stats/snapshot", nil)
+	server.Handler.ServeHTTP(recorder, req)
+	res, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var statsFromResult map[string]*types.StatsJSON
+	err = json.Unmarshal(res, &statsFromResult)
+	assert.NoError(t, err)
+	containerStats, ok := statsFromResult[containerID]
+	assert.True(t, ok)
+	assert.Equal(t, dockerStats.NumProcs, containerStats.NumProcs)
+}
+
+func TestV4ContainerHealthHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+
+	checkedAt := time.Now().UTC()
+	healthContainer := &apicontainer.Container{
+		Name:            containerName,
+		HealthCheckType: apicontainer.DockerHealthCheckType,
+		Health:          apicontainer.HealthStatus{Status: apicontainerstatus.ContainerHealthy},
+	}
+	healthContainer.AddHealthCheckResults(apicontainer.HealthCheckResult{Timestamp: checkedAt, Output: "ok"})
+
+	gomock.InOrder(
+		state.EXPECT().DockerIDByV3EndpointID(v3EndpointID).Return(containerID, true),
+		state.EXPECT().ContainerByID(containerID).Return(&apicontainer.DockerContainer{
+			DockerID:  containerID,
+			Container: healthContainer,
+		}, true),
+	)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/health/history", nil)
+	server.Handler.ServeHTTP(recorder, req)
+	res, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var historyResponse v4.ContainerHealthHistoryResponse
+	assert.NoError(t, json.Unmarshal(res, &historyResponse))
+	assert.Equal(t, containerID, historyResponse.DockerID)
+	assert.Equal(t, apicontainerstatus.ContainerHealthy, historyResponse.Health.Status)
+	require.Len(t, historyResponse.History, 1)
+	assert.True(t, checkedAt.Equal(historyResponse.History[0].Timestamp))
+	assert.Equal(t, "ok", historyResponse.History[0].Output)
+}
+
 func TestV4ContainerStats(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -1602,11 +1744,24 @@ func TestV4ContainerStats(t *testing.T) {
 
 	dockerStats := &types.StatsJSON{}
 	dockerStats.NumProcs = 2
+	dnsCacheTask := &apitask.Task{Arn: taskARN}
+	dnsCacheTask.SetDNSCache(&dnscache.Cache{}, "1")
 
 	gomock.InOrder(
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().DockerIDByV3EndpointID(v3EndpointID).Return(containerID, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, &stats.NetworkStatsPerSec{}, nil),
+		statsEngine.EXPECT().ContainerDiskUsage(taskARN, containerID).Return(&stats.DiskUsage{
+			WritableLayerSizeBytes: 1024,
+			BindMountsSizeBytes:    2048,
+		}, nil),
+		statsEngine.EXPECT().ContainerGPUStats(taskARN, containerID).Return([]*stats.GPUStats{
+			{
+				GPUID:              "GPU-a1",
+				UtilizationPercent: 45,
+			},
+		}, nil),
+		state.EXPECT().TaskByArn(taskARN).Return(dnsCacheTask, true),
 	)
 	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
 		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
@@ -1616,10 +1771,17 @@ func TestV4ContainerStats(t *testing.T) {
 	res, err := ioutil.ReadAll(recorder.Body)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, recorder.Code)
-	var statsFromResult *types.StatsJSON
+	var statsFromResult *v4.StatsResponse
 	err = json.Unmarshal(res, &statsFromResult)
 	assert.NoError(t, err)
 	assert.Equal(t, dockerStats.NumProcs, statsFromResult.NumProcs)
+	require.NotNil(t, statsFromResult.Disk_usage_stats)
+	assert.EqualValues(t, 1024, statsFromResult.Disk_usage_stats.WritableLayerSizeBytes)
+	assert.EqualValues(t, 2048, statsFromResult.Disk_usage_stats.BindMountsSizeBytes)
+	require.Len(t, statsFromResult.Gpu_stats, 1)
+	assert.Equal(t, "GPU-a1", statsFromResult.Gpu_stats[0].GPUID)
+	assert.Equal(t, float64(45), statsFromResult.Gpu_stats[0].UtilizationPercent)
+	assert.NotNil(t, statsFromResult.Dns_cache_stats)
 }
 
 func TestV4ContainerAssociations(t *testing.T) {
@@ -1831,3 +1993,43 @@ func TestTaskHTTPEndpointErrorCode500(t *testing.T) {
 		})
 	}
 }
+
+// TestTaskServerRequestMetrics tests that requests served by the task server are
+// recorded with the route's path template and the response status code.
+func TestTaskServerRequestMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func() {
+		metrics.MetricsEngineGlobal = &metrics.MetricsEngine{}
+	}()
+	cfg := config.DefaultConfig()
+	cfg.PrometheusMetricsEnabled = true
+	metrics.MustInit(&cfg, prometheus.NewRegistry())
+
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	auditLog.EXPECT().Log(gomock.Any(), gomock.Any(), gomock.Any())
+	server := taskServerSetup(mock_credentials.NewMockManager(ctrl), auditLog, nil, mock_api.NewMockECSClient(ctrl), "", nil,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", credentials.V1CredentialsPath, nil)
+	server.Handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	metricFamilies, err := metrics.MetricsEngineGlobal.Registry.Gather()
+	require.NoError(t, err)
+	labels := make(map[string]string)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "AgentMetrics_TaskMetadata_requests" {
+			continue
+		}
+		require.Len(t, metricFamily.GetMetric(), 1)
+		metric := metricFamily.GetMetric()[0]
+		assert.Equal(t, 1.0, metric.GetCounter().GetValue())
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+	}
+	assert.Equal(t, map[string]string{"Path": v1.CredentialsPath, "StatusCode": "400"}, labels)
+}
@@ -14,7 +14,9 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
@@ -39,18 +41,54 @@ const (
 	// RequestTypeTaskStats specifies the task stats request type of StatsHandler.
 	RequestTypeTaskStats = "task stats"
 
+	// RequestTypeTaskStatsSnapshot specifies the task stats snapshot request type of TaskStatsSnapshotHandler.
+	RequestTypeTaskStatsSnapshot = "task stats snapshot"
+
 	// RequestTypeContainerStats specifies the container stats request type of StatsHandler.
 	RequestTypeContainerStats = "container stats"
 
+	// RequestTypeContainerHealthHistory specifies the container health history request type of ContainerHealthHistoryHandler.
+	RequestTypeContainerHealthHistory = "container health history"
+
 	// RequestTypeAgentMetadata specifies the Agent metadata request type of AgentMetadataHandler.
 	RequestTypeAgentMetadata = "agent metadata"
 
+	// RequestTypeEventHandlerStats specifies the event handler stats request type of EventHandlerStatsHandler.
+	RequestTypeEventHandlerStats = "event handler stats"
+
+	// RequestTypeImagePreload specifies the image preload request type of ImagePreloadHandler.
+	RequestTypeImagePreload = "image preload"
+
+	// RequestTypeLogLevel specifies the log level request type of LogLevelHandler.
+	RequestTypeLogLevel = "log level"
+
+	// RequestTypeDrain specifies the drain request type of DrainHandler and DrainStatusHandler.
+	RequestTypeDrain = "drain"
+
+	// RequestTypeTaskDryRun specifies the task dry run request type of TaskDryRunHandler.
+	RequestTypeTaskDryRun = "task dry run"
+
+	// RequestTypeStateReport specifies the state report request type of StateReportHandler.
+	RequestTypeStateReport = "state report"
+
+	// RequestTypeDiagnosticsBundle specifies the diagnostics bundle request type of DiagnosticsBundleHandler.
+	RequestTypeDiagnosticsBundle = "diagnostics bundle"
+
+	// RequestTypePprof specifies the profiling request type of PprofHandler.
+	RequestTypePprof = "pprof"
+
+	// RequestTypeIntrospectionTasks specifies the tasks request type of IntrospectionTasksHandler.
+	RequestTypeIntrospectionTasks = "introspection tasks"
+
 	// RequestTypeContainerAssociations specifies the container associations request type of ContainerAssociationsHandler.
 	RequestTypeContainerAssociations = "container associations"
 
 	// RequestTypeContainerAssociation specifies the container association request type of ContainerAssociationHandler.
 	RequestTypeContainerAssociation = "container association"
 
+	// RequestTypeConfigReport specifies the configuration report request type of ConfigReportHandler.
+	RequestTypeConfigReport = "config report"
+
 	// AnythingButSlashRegEx is a regex pattern that matches any string without slash.
 	AnythingButSlashRegEx = "[^/]*"
 
@@ -98,6 +136,41 @@ func WriteResponseIfMarshalError(w http.ResponseWriter, err error) error {
 	return nil
 }
 
+// WriteJSONError writes an ErrorMessage with the given code and message as the JSON
+// response of a request of the given type.
+func WriteJSONError(w http.ResponseWriter, httpStatusCode int, code string, message string, requestType string) {
+	responseJSON, err := json.Marshal(ErrorMessage{
+		Code:          code,
+		Message:       message,
+		HTTPErrorCode: httpStatusCode,
+	})
+	if e := WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	WriteJSONToResponse(w, httpStatusCode, responseJSON, requestType)
+}
+
+// IsLoopbackRequest returns true if the request was sent from the loopback interface.
+func IsLoopbackRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// LoopbackOnly returns true if the request was sent from the loopback interface.
+// Otherwise it writes an AccessDenied response, and the calling function should return.
+func LoopbackOnly(w http.ResponseWriter, r *http.Request, requestType string) bool {
+	if IsLoopbackRequest(r) {
+		return true
+	}
+	WriteJSONError(w, http.StatusForbidden, "AccessDenied",
+		fmt.Sprintf("%s requests are only accepted from localhost", requestType), requestType)
+	return false
+}
+
 // ValueFromRequest returns the value of a field in the http request. The boolean value is
 // set to true if the field exists in the query.
 func ValueFromRequest(r *http.Request, field string) (string, bool) {
@@ -85,3 +85,50 @@ func TestValueFromRequest(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "credid", val)
 }
+
+func TestWriteJSONError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	WriteJSONError(recorder, http.StatusBadRequest, "InvalidRequest", "bad request", RequestTypeDrain)
+
+	var errorMessage ErrorMessage
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &errorMessage))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, ErrorMessage{
+		Code:          "InvalidRequest",
+		Message:       "bad request",
+		HTTPErrorCode: http.StatusBadRequest,
+	}, errorMessage)
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	testCases := []struct {
+		remoteAddr string
+		allowed    bool
+	}{
+		{remoteAddr: "127.0.0.1:51678", allowed: true},
+		{remoteAddr: "[::1]:51678", allowed: true},
+		{remoteAddr: "10.0.0.1:51678", allowed: false},
+		{remoteAddr: "127.0.0.1", allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.remoteAddr, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/v1/drain/status", nil)
+			r.RemoteAddr = tc.remoteAddr
+			recorder := httptest.NewRecorder()
+
+			assert.Equal(t, tc.allowed, IsLoopbackRequest(r))
+			assert.Equal(t, tc.allowed, LoopbackOnly(recorder, r, RequestTypeDrain))
+			if tc.allowed {
+				assert.Equal(t, 0, recorder.Body.Len())
+				return
+			}
+			var errorMessage ErrorMessage
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &errorMessage))
+			assert.Equal(t, http.StatusForbidden, recorder.Code)
+			assert.Equal(t, "AccessDenied", errorMessage.Code)
+			assert.Equal(t, "drain requests are only accepted from localhost", errorMessage.Message)
+		})
+	}
+}
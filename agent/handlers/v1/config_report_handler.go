@@ -0,0 +1,42 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+)
+
+// ConfigReportPath is the path to get the effective configuration of the agent.
+const ConfigReportPath = "/v1/config"
+
+// ConfigReportHandler creates response for the '/v1/config' API, which returns the
+// effective value of every environment variable of the agent and the problems found
+// while loading the configuration. Sensitive values are redacted. Only requests from the
+// instance itself are allowed, as the configuration describes the whole instance.
+func ConfigReportHandler(cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypeConfigReport) {
+			return
+		}
+		responseJSON, err := json.Marshal(cfg.Report())
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeConfigReport)
+	}
+}
@@ -17,9 +17,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	handlersutils "github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit/request"
@@ -57,32 +59,66 @@ const (
 
 // CredentialsHandler creates response for the 'v1/credentials' API. It returns a JSON response
 // containing credentials when found. The HTTP status code of 400 is returned otherwise.
-func CredentialsHandler(credentialsManager credentials.Manager, auditLogger audit.AuditLogger) func(http.ResponseWriter, *http.Request) {
+func CredentialsHandler(credentialsManager credentials.Manager, state dockerstate.TaskEngineState, auditLogger audit.AuditLogger) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		credentialsID := getCredentialsID(r)
 		errPrefix := fmt.Sprintf("CredentialsV%dRequest: ", apiVersion)
-		CredentialsHandlerImpl(w, r, auditLogger, credentialsManager, credentialsID, errPrefix)
+		CredentialsHandlerImpl(w, r, auditLogger, credentialsManager, state, credentialsID, errPrefix)
 	}
 }
 
 // CredentialsHandlerImpl is the major logic in CredentialsHandler, abstract this out
 // because v2.CredentialsHandler also uses the same logic.
-func CredentialsHandlerImpl(w http.ResponseWriter, r *http.Request, auditLogger audit.AuditLogger, credentialsManager credentials.Manager, credentialsID string, errPrefix string) {
-	responseJSON, arn, roleType, errorMessage, err := processCredentialsRequest(credentialsManager, r, credentialsID, errPrefix)
+func CredentialsHandlerImpl(w http.ResponseWriter, r *http.Request, auditLogger audit.AuditLogger, credentialsManager credentials.Manager, state dockerstate.TaskEngineState, credentialsID string, errPrefix string) {
+	responseJSON, arn, roleType, roleARN, errorMessage, err := processCredentialsRequest(credentialsManager, r, credentialsID, errPrefix)
+	logRequest := request.LogRequest{Request: r, ARN: arn, RoleARN: roleARN}
 	if err != nil {
 		errResponseJSON, err := json.Marshal(errorMessage)
 		if e := handlersutils.WriteResponseIfMarshalError(w, err); e != nil {
 			return
 		}
-		writeCredentialsRequestResponse(w, r, errorMessage.HTTPErrorCode, audit.GetCredentialsEventType(roleType), arn, auditLogger, errResponseJSON)
+		writeCredentialsRequestResponse(w, errorMessage.HTTPErrorCode, audit.GetCredentialsEventType(roleType), logRequest, auditLogger, errResponseJSON)
 		return
 	}
 
-	writeCredentialsRequestResponse(w, r, http.StatusOK, audit.GetCredentialsEventType(roleType), arn, auditLogger, responseJSON)
+	if task, ok := state.TaskByArn(arn); ok {
+		task.RecordCredentialsFetch(roleType)
+		logRequest.Container = callerContainerName(state, arn, r.RemoteAddr)
+	}
+	writeCredentialsRequestResponse(w, http.StatusOK, audit.GetCredentialsEventType(roleType), logRequest, auditLogger, responseJSON)
+}
+
+// callerContainerName returns the name of the container of the task that has the IP
+// address the request comes from. Containers can only be told apart when they have
+// their own IP address, as in the bridge network mode, the name is empty otherwise.
+func callerContainerName(state dockerstate.TaskEngineState, taskARN string, remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return ""
+	}
+	containers, ok := state.ContainerMapByArn(taskARN)
+	if !ok {
+		return ""
+	}
+	for name, dockerContainer := range containers {
+		networkSettings := dockerContainer.Container.GetNetworkSettings()
+		if networkSettings == nil {
+			continue
+		}
+		if networkSettings.IPAddress == host {
+			return name
+		}
+		for _, endpoint := range networkSettings.Networks {
+			if endpoint != nil && endpoint.IPAddress == host {
+				return name
+			}
+		}
+	}
+	return ""
 }
 
 // processCredentialsRequest returns the response json containing credentials for the credentials id in the request
-func processCredentialsRequest(credentialsManager credentials.Manager, r *http.Request, credentialsID string, errPrefix string) ([]byte, string, string, *handlersutils.ErrorMessage, error) {
+func processCredentialsRequest(credentialsManager credentials.Manager, r *http.Request, credentialsID string, errPrefix string) ([]byte, string, string, string, *handlersutils.ErrorMessage, error) {
 	if credentialsID == "" {
 		errText := errPrefix + "No Credential ID in the request"
 		seelog.Errorf("Error processing credential request: %s", errText)
@@ -91,7 +127,7 @@ func processCredentialsRequest(credentialsManager credentials.Manager, r *http.R
 			Message:       errText,
 			HTTPErrorCode: http.StatusBadRequest,
 		}
-		return nil, "", "", msg, errors.New(errText)
+		return nil, "", "", "", msg, errors.New(errText)
 	}
 
 	credentials, ok := credentialsManager.GetTaskCredentials(credentialsID)
@@ -103,7 +139,7 @@ func processCredentialsRequest(credentialsManager credentials.Manager, r *http.R
 			Message:       errText,
 			HTTPErrorCode: http.StatusBadRequest,
 		}
-		return nil, "", "", msg, errors.New(errText)
+		return nil, "", "", "", msg, errors.New(errText)
 	}
 
 	seelog.Infof("Processing credential request, credentialType=%s taskARN=%s",
@@ -119,7 +155,7 @@ func processCredentialsRequest(credentialsManager credentials.Manager, r *http.R
 			Message:       errText,
 			HTTPErrorCode: http.StatusServiceUnavailable,
 		}
-		return nil, "", "", msg, errors.New(errText)
+		return nil, "", "", "", msg, errors.New(errText)
 	}
 
 	credentialsJSON, err := json.Marshal(credentials.IAMRoleCredentials)
@@ -132,15 +168,15 @@ func processCredentialsRequest(credentialsManager credentials.Manager, r *http.R
 			Message:       "Internal server error",
 			HTTPErrorCode: http.StatusInternalServerError,
 		}
-		return nil, "", "", msg, errors.New(errText)
+		return nil, "", "", "", msg, errors.New(errText)
 	}
 
 	// Success
-	return credentialsJSON, credentials.ARN, credentials.IAMRoleCredentials.RoleType, nil, nil
+	return credentialsJSON, credentials.ARN, credentials.IAMRoleCredentials.RoleType, credentials.IAMRoleCredentials.RoleArn, nil, nil
 }
 
-func writeCredentialsRequestResponse(w http.ResponseWriter, r *http.Request, httpStatusCode int, eventType string, arn string, auditLogger audit.AuditLogger, message []byte) {
-	auditLogger.Log(request.LogRequest{Request: r, ARN: arn}, httpStatusCode, eventType)
+func writeCredentialsRequestResponse(w http.ResponseWriter, httpStatusCode int, eventType string, logRequest request.LogRequest, auditLogger audit.AuditLogger, message []byte) {
+	auditLogger.Log(logRequest, httpStatusCode, eventType)
 
 	handlersutils.WriteJSONToResponse(w, httpStatusCode, message, handlersutils.RequestTypeCreds)
 }
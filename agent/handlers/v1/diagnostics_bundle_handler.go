@@ -0,0 +1,76 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/diagnostics"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// DiagnosticsBundlePath is the path to download a diagnostics bundle of the agent.
+	DiagnosticsBundlePath = "/v1/diagnostics/bundle"
+	// diagnosticsBundleTimeout bounds the collection of the bundle, so that the
+	// bundle is written before the write timeout of the introspection server
+	diagnosticsBundleTimeout = 4 * time.Second
+)
+
+// DiagnosticsBundler writes diagnostics bundles, such as diagnostics.Bundler
+type DiagnosticsBundler interface {
+	Write(context.Context, io.Writer) error
+}
+
+// DiagnosticsBundleHandler creates response for the '/v1/diagnostics/bundle' API,
+// which returns a gzipped tarball with the goroutines, the recent logs, the redacted
+// state of the agent, docker info and the network namespaces of the instance.
+// Only requests from the instance itself are allowed.
+func DiagnosticsBundleHandler(bundler DiagnosticsBundler) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypeDiagnosticsBundle) {
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeDiagnosticsBundle)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), diagnosticsBundleTimeout)
+		defer cancel()
+		// The bundle is buffered so that its errors can still be reported as an
+		// error response
+		var bundle bytes.Buffer
+		if err := bundler.Write(ctx, &bundle); err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "InternalServerError",
+				fmt.Sprintf("unable to create diagnostics bundle: %v", err), utils.RequestTypeDiagnosticsBundle)
+			return
+		}
+		fileName := diagnostics.BundleFilePrefix + time.Now().UTC().Format("20060102T150405Z") + ".tar.gz"
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		w.WriteHeader(http.StatusOK)
+		if _, err := bundle.WriteTo(w); err != nil {
+			seelog.Errorf("Unable to write %s response to ResponseWriter: %v",
+				utils.RequestTypeDiagnosticsBundle, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+)
+
+const (
+	// DrainPath is the path to put the agent in a local draining state.
+	DrainPath = "/v1/drain"
+	// DrainStatusPath is the path to get the progress of the drain.
+	DrainStatusPath = "/v1/drain/status"
+)
+
+// Drainer is a sub-interface of engine.DockerTaskEngine to make it easy to
+// test code in this package
+type Drainer interface {
+	Drain()
+	DrainStatus() engine.DrainStatus
+}
+
+// DrainHandler creates response for the '/v1/drain' API. A POST request stops
+// the tasks running on the instance and prevents new tasks from being started,
+// and returns the progress of the drain. Only requests from the instance itself
+// are allowed.
+func DrainHandler(drainer Drainer) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypeDrain) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeDrain)
+			return
+		}
+		drainer.Drain()
+		writeDrainStatus(w, http.StatusAccepted, drainer)
+	}
+}
+
+// DrainStatusHandler creates response for the '/v1/drain/status' API, which
+// returns the progress of the drain of every task that was running when the
+// drain was requested.
+func DrainStatusHandler(drainer Drainer) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypeDrain) {
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeDrain)
+			return
+		}
+		writeDrainStatus(w, http.StatusOK, drainer)
+	}
+}
+
+func writeDrainStatus(w http.ResponseWriter, status int, drainer Drainer) {
+	responseJSON, err := json.Marshal(drainer.DrainStatus())
+	if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	utils.WriteJSONToResponse(w, status, responseJSON, utils.RequestTypeDrain)
+}
@@ -0,0 +1,42 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+)
+
+// EventHandlerStatsPath is the path for the state change event handler stats.
+const EventHandlerStatsPath = "/v1/eventhandler/stats"
+
+// EventHandlerStatsResolver is a sub-interface of eventhandler.TaskHandler to
+// make it easy to test code in this package
+type EventHandlerStatsResolver interface {
+	QueueStats() eventhandler.QueueStats
+}
+
+// EventHandlerStatsHandler creates response for '/v1/eventhandler/stats' API.
+func EventHandlerStatsHandler(statsResolver EventHandlerStatsResolver) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, err := json.Marshal(statsResolver.QueueStats())
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeEventHandlerStats)
+	}
+}
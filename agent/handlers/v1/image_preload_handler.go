@@ -0,0 +1,153 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+)
+
+const (
+	// ImagePreloadPath is the path to request and list image preloads.
+	ImagePreloadPath = "/v1/images/preload"
+
+	// maxImagePreloadRequestSize is the maximum size of the body of an image preload request
+	maxImagePreloadRequestSize = 1 << 20
+	// maxImagePreloadsPerRequest is the maximum number of images in an image preload request
+	maxImagePreloadsPerRequest = 100
+
+	// ImagePreloadTokenFile is the file, in the data directory of the agent,
+	// with the token that image preload requests must send
+	ImagePreloadTokenFile = "image_preload_token"
+	// ImagePreloadTokenHeader is the header image preload requests send the token in
+	ImagePreloadTokenHeader = "X-Ecs-Image-Preload-Token"
+	// imagePreloadTokenSize is the number of random bytes in the token
+	imagePreloadTokenSize = 32
+	// imagePreloadTokenFilePerm only lets root read the token, so that the
+	// containers in the host network namespace cannot preload images unless
+	// they are given the token
+	imagePreloadTokenFilePerm = 0600
+)
+
+// ImagePreloader is a sub-interface of engine.DockerTaskEngine to make it
+// easy to test code in this package
+type ImagePreloader interface {
+	PreloadImages([]engine.ImagePreload) error
+	ImagePreloadStatuses() []engine.ImagePreloadStatus
+}
+
+// ImagePreloadRequest is the body of a request to preload images
+type ImagePreloadRequest struct {
+	Images []engine.ImagePreload
+}
+
+// ImagePreloadResponse lists the status of the image preloads
+type ImagePreloadResponse struct {
+	Images []engine.ImagePreloadStatus
+}
+
+// WriteImagePreloadToken writes a new random token to the image preload token
+// file in the data directory. The token changes every time the agent starts.
+func WriteImagePreloadToken(dataDir string) error {
+	token := make([]byte, imagePreloadTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	tokenFile := filepath.Join(dataDir, ImagePreloadTokenFile)
+	if err := ioutil.WriteFile(tokenFile, []byte(hex.EncodeToString(token)), imagePreloadTokenFilePerm); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of a file that already exists
+	return os.Chmod(tokenFile, imagePreloadTokenFilePerm)
+}
+
+// ImagePreloadHandler creates response for the '/v1/images/preload' API. A POST
+// request starts pulling the images in the body in the background, and a GET
+// request lists the status of the image preloads. Only requests from the
+// instance itself that send the token of the image preload token file are
+// allowed, as preloads can pull images with any role the instance role can
+// assume, and containers in the host network namespace share the loopback
+// interface of the instance.
+func ImagePreloadHandler(preloader ImagePreloader, dataDir string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypeImagePreload) {
+			return
+		}
+		if !validImagePreloadToken(r, dataDir) {
+			utils.WriteJSONError(w, http.StatusForbidden, "AccessDenied",
+				fmt.Sprintf("image preload requests must send the token of the %s file of the agent data directory in the %s header",
+					ImagePreloadTokenFile, ImagePreloadTokenHeader), utils.RequestTypeImagePreload)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeImagePreloadStatuses(w, http.StatusOK, preloader)
+		case http.MethodPost:
+			var request ImagePreloadRequest
+			decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImagePreloadRequestSize))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&request); err != nil {
+				utils.WriteJSONError(w, http.StatusBadRequest, "InvalidRequest",
+					fmt.Sprintf("unable to parse image preload request: %v", err), utils.RequestTypeImagePreload)
+				return
+			}
+			if len(request.Images) == 0 || len(request.Images) > maxImagePreloadsPerRequest {
+				utils.WriteJSONError(w, http.StatusBadRequest, "InvalidRequest",
+					fmt.Sprintf("an image preload request must have between 1 and %d images", maxImagePreloadsPerRequest),
+					utils.RequestTypeImagePreload)
+				return
+			}
+			if err := preloader.PreloadImages(request.Images); err != nil {
+				utils.WriteJSONError(w, http.StatusBadRequest, "InvalidRequest", err.Error(), utils.RequestTypeImagePreload)
+				return
+			}
+			writeImagePreloadStatuses(w, http.StatusAccepted, preloader)
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeImagePreload)
+		}
+	}
+}
+
+// validImagePreloadToken returns true if the request sends the token of the
+// image preload token file
+func validImagePreloadToken(r *http.Request, dataDir string) bool {
+	token, err := ioutil.ReadFile(filepath.Join(dataDir, ImagePreloadTokenFile))
+	if err != nil || len(token) == 0 {
+		return false
+	}
+	requestToken := strings.TrimSpace(r.Header.Get(ImagePreloadTokenHeader))
+	return subtle.ConstantTimeCompare([]byte(requestToken), token) == 1
+}
+
+func writeImagePreloadStatuses(w http.ResponseWriter, status int, preloader ImagePreloader) {
+	responseJSON, err := json.Marshal(ImagePreloadResponse{Images: preloader.ImagePreloadStatuses()})
+	if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	utils.WriteJSONToResponse(w, status, responseJSON, utils.RequestTypeImagePreload)
+}
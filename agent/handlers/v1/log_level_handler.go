@@ -0,0 +1,96 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// LogLevelPath is the path to get and change the log levels of the agent.
+	LogLevelPath = "/v1/loglevel"
+
+	// maxLogLevelRequestSize is the maximum size of the body of a log level request
+	maxLogLevelRequestSize = 64 << 10
+)
+
+// LogLevelRequest is the body of a request to change the log levels. Empty levels
+// are left unchanged, and ModuleLevels replaces the per-module levels when present.
+type LogLevelRequest struct {
+	Level         string
+	InstanceLevel string
+	ModuleLevels  map[string]string
+}
+
+// LogLevelResponse lists the current log levels
+type LogLevelResponse struct {
+	Level         string
+	InstanceLevel string
+	ModuleLevels  map[string]string
+}
+
+// LogLevelHandler creates response for the '/v1/loglevel' API. A PUT request
+// changes the log levels of the agent without restarting it, and a GET request
+// returns the current log levels. Only requests from the instance itself are
+// allowed.
+func LogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.LoopbackOnly(w, r, utils.RequestTypeLogLevel) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeLogLevels(w)
+	case http.MethodPut:
+		var request LogLevelRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLogLevelRequestSize))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "InvalidRequest",
+				fmt.Sprintf("unable to parse log level request: %v", err), utils.RequestTypeLogLevel)
+			return
+		}
+		if err := logger.SetLevels(request.Level, request.InstanceLevel, request.ModuleLevels); err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "InvalidRequest", err.Error(), utils.RequestTypeLogLevel)
+			return
+		}
+		level, instanceLevel, moduleLevels := logger.Levels()
+		seelog.Infof("Log levels changed: level=%s, instance level=%s, module levels=%v",
+			level, instanceLevel, moduleLevels)
+		writeLogLevels(w)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPut)
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+			fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeLogLevel)
+	}
+}
+
+func writeLogLevels(w http.ResponseWriter) {
+	level, instanceLevel, moduleLevels := logger.Levels()
+	responseJSON, err := json.Marshal(LogLevelResponse{
+		Level:         level,
+		InstanceLevel: instanceLevel,
+		ModuleLevels:  moduleLevels,
+	})
+	if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeLogLevel)
+}
@@ -0,0 +1,163 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// PprofPath is the path prefix of the profiles of the agent, matching the one of
+	// net/http/pprof so that 'go tool pprof' can download them.
+	PprofPath = "/debug/pprof/"
+	// MaxProfileDuration is the longest CPU profile or execution trace that can be requested.
+	MaxProfileDuration = time.Minute
+	// MutexProfileFraction is the fraction of the mutex contention events reported in the
+	// mutex profile while the profiles are enabled.
+	MutexProfileFraction = 5
+
+	cpuProfileName          = "profile"
+	traceProfileName        = "trace"
+	defaultCPUProfileLength = 30 * time.Second
+	defaultTraceLength      = time.Second
+)
+
+// PprofHandler creates response for the '/debug/pprof/' APIs. '/debug/pprof/profile' and
+// '/debug/pprof/trace' record a CPU profile and an execution trace for the number of seconds
+// of the 'seconds' query parameter, '/debug/pprof/<name>' returns the named profiles of the
+// runtime, such as heap, goroutine or mutex, and '/debug/pprof/' lists them. The profiles are
+// written in the formats of net/http/pprof, which isn't used directly as importing it exposes
+// the profiles on every server using the default mux. Only requests from the instance itself
+// are allowed.
+func PprofHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypePprof) {
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypePprof)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, PprofPath)
+		switch name {
+		case "":
+			writePprofIndex(w)
+		case cpuProfileName, traceProfileName:
+			writeTimedProfile(w, r, name)
+		default:
+			writeNamedProfile(w, r, name)
+		}
+	}
+}
+
+func writePprofIndex(w http.ResponseWriter) {
+	names := []string{cpuProfileName, traceProfileName}
+	for _, profile := range pprof.Profiles() {
+		names = append(names, profile.Name())
+	}
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s%s\n", PprofPath, name)
+	}
+}
+
+// writeTimedProfile records a CPU profile or an execution trace for the requested
+// duration, stopping early if the client goes away.
+func writeTimedProfile(w http.ResponseWriter, r *http.Request, name string) {
+	duration := defaultCPUProfileLength
+	if name == traceProfileName {
+		duration = defaultTraceLength
+	}
+	if value, ok := utils.ValueFromRequest(r, "seconds"); ok {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds <= 0 {
+			utils.WriteJSONError(w, http.StatusBadRequest, "InvalidParameter",
+				fmt.Sprintf("invalid number of seconds: %q", value), utils.RequestTypePprof)
+			return
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+	if duration > MaxProfileDuration {
+		utils.WriteJSONError(w, http.StatusBadRequest, "InvalidParameter",
+			fmt.Sprintf("profiles are limited to %s", MaxProfileDuration), utils.RequestTypePprof)
+		return
+	}
+
+	setProfileDownloadHeaders(w, name)
+	start, stop := pprof.StartCPUProfile, pprof.StopCPUProfile
+	if name == traceProfileName {
+		start, stop = trace.Start, trace.Stop
+	}
+	if err := start(w); err != nil {
+		// Only one CPU profile or trace can be recorded at a time
+		w.Header().Del("Content-Disposition")
+		utils.WriteJSONError(w, http.StatusConflict, "ProfilingInProgress",
+			fmt.Sprintf("unable to start %s: %v", name, err), utils.RequestTypePprof)
+		return
+	}
+	timer := time.NewTimer(duration)
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+		timer.Stop()
+	}
+	stop()
+}
+
+func writeNamedProfile(w http.ResponseWriter, r *http.Request, name string) {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("unknown profile: %s", name), utils.RequestTypePprof)
+		return
+	}
+	debug := 0
+	if value, ok := utils.ValueFromRequest(r, "debug"); ok {
+		var err error
+		if debug, err = strconv.Atoi(value); err != nil || debug < 0 {
+			utils.WriteJSONError(w, http.StatusBadRequest, "InvalidParameter",
+				fmt.Sprintf("invalid debug level: %q", value), utils.RequestTypePprof)
+			return
+		}
+	}
+	if value, _ := utils.ValueFromRequest(r, "gc"); name == "heap" && value != "" && value != "0" {
+		runtime.GC()
+	}
+	if debug > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		setProfileDownloadHeaders(w, name)
+	}
+	if err := profile.WriteTo(w, debug); err != nil {
+		seelog.Errorf("Unable to write %s profile to ResponseWriter: %v", name, err)
+	}
+}
+
+func setProfileDownloadHeaders(w http.ResponseWriter, name string) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+}
@@ -0,0 +1,57 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+)
+
+// StateReportPath is the path to get the divergence between the state of the agent,
+// its checkpoint and docker.
+const StateReportPath = "/v1/state/report"
+
+// StateReporter is a sub-interface of engine.DockerTaskEngine to make it easy to
+// test code in this package
+type StateReporter interface {
+	StateReport(context.Context) engine.StateReport
+}
+
+// StateReportHandler creates response for the '/v1/state/report' API, which compares
+// the state of the agent with its checkpoint and with the containers known to docker,
+// and returns the divergences without changing any of them. Only requests from the
+// instance itself are allowed, as every docker container is inspected.
+func StateReportHandler(reporter StateReporter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypeStateReport) {
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeStateReport)
+			return
+		}
+		responseJSON, err := json.Marshal(reporter.StateReport(r.Context()))
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeStateReport)
+	}
+}
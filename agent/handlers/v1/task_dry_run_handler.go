@@ -0,0 +1,90 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+)
+
+const (
+	// TaskDryRunPath is the path to request and list task dry runs.
+	TaskDryRunPath = "/v1/tasks/dryrun"
+
+	// maxTaskDryRunRequestSize is the maximum size of the body of a task dry run request
+	maxTaskDryRunRequestSize = 1 << 20
+)
+
+// TaskDryRunner is a sub-interface of engine.DockerTaskEngine to make it easy
+// to test code in this package
+type TaskDryRunner interface {
+	StartTaskDryRun(engine.DryRunTaskDefinition) (engine.TaskDryRunResult, error)
+	TaskDryRunResults() []engine.TaskDryRunResult
+}
+
+// TaskDryRunResponse lists the progress of the latest task dry runs
+type TaskDryRunResponse struct {
+	DryRuns []engine.TaskDryRunResult
+}
+
+// TaskDryRunHandler creates response for the '/v1/tasks/dryrun' API. A POST
+// request with an ECS task definition in the body starts running it through
+// the steps the agent takes to start a task, without registering it with ECS,
+// and returns the progress of the dry run. The network of awsvpc tasks isn't
+// set up, the CNI plugins are only probed for their version. A GET request lists
+// the progress of the latest dry runs. Only requests from the instance itself are
+// allowed, as dry runs can pull images with any role the instance role can assume.
+func TaskDryRunHandler(dryRunner TaskDryRunner) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.LoopbackOnly(w, r, utils.RequestTypeTaskDryRun) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeTaskDryRunResponse(w, http.StatusOK, TaskDryRunResponse{DryRuns: dryRunner.TaskDryRunResults()})
+		case http.MethodPost:
+			// Unknown fields are allowed, so that complete task definitions can be dry run
+			var def engine.DryRunTaskDefinition
+			decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTaskDryRunRequestSize))
+			if err := decoder.Decode(&def); err != nil {
+				utils.WriteJSONError(w, http.StatusBadRequest, "InvalidRequest",
+					fmt.Sprintf("unable to parse task definition: %v", err), utils.RequestTypeTaskDryRun)
+				return
+			}
+			result, err := dryRunner.StartTaskDryRun(def)
+			if err != nil {
+				utils.WriteJSONError(w, http.StatusBadRequest, "InvalidRequest", err.Error(), utils.RequestTypeTaskDryRun)
+				return
+			}
+			writeTaskDryRunResponse(w, http.StatusAccepted, result)
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeTaskDryRun)
+		}
+	}
+}
+
+func writeTaskDryRunResponse(w http.ResponseWriter, status int, response interface{}) {
+	responseJSON, err := json.Marshal(response)
+	if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	utils.WriteJSONToResponse(w, status, responseJSON, utils.RequestTypeTaskDryRun)
+}
@@ -18,6 +18,7 @@ import (
 	"net/http"
 
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
@@ -40,11 +41,11 @@ const (
 var CredentialsPath = credentials.V2CredentialsPath + "/" + utils.ConstructMuxVar(credentialsIDMuxName, utils.AnythingRegEx)
 
 // CredentialsHandler creates response for the 'v2/credentials' API.
-func CredentialsHandler(credentialsManager credentials.Manager, auditLogger audit.AuditLogger) func(http.ResponseWriter, *http.Request) {
+func CredentialsHandler(credentialsManager credentials.Manager, state dockerstate.TaskEngineState, auditLogger audit.AuditLogger) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		credentialsID := getCredentialsID(r)
 		errPrefix := fmt.Sprintf("CredentialsV%dRequest: ", apiVersion)
-		v1.CredentialsHandlerImpl(w, r, auditLogger, credentialsManager, credentialsID, errPrefix)
+		v1.CredentialsHandlerImpl(w, r, auditLogger, credentialsManager, state, credentialsID, errPrefix)
 	}
 }
 
@@ -0,0 +1,217 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
+)
+
+const (
+	// IntrospectionTasksPath is the path of the tasks API of the introspection server.
+	IntrospectionTasksPath = "/v2/tasks"
+
+	statusQueryField     = "status"
+	familyQueryField     = "family"
+	taskARNQueryField    = "taskarn"
+	maxResultsQueryField = "maxresults"
+	nextTokenQueryField  = "nexttoken"
+
+	// defaultMaxResults is the number of tasks returned per page when the request
+	// doesn't specify it, and the maximum that can be requested.
+	defaultMaxResults = 100
+)
+
+// IntrospectionTasksResolver is a sub-interface of engine.DockerTaskEngine to make it
+// easy to test code in this package
+type IntrospectionTasksResolver interface {
+	State() dockerstate.TaskEngineState
+	StoppedTasks() []engine.StoppedTask
+}
+
+// IntrospectionTasksResponse is the schema for a page of the '/v2/tasks' API response
+type IntrospectionTasksResponse struct {
+	Tasks []*IntrospectionTaskResponse `json:"Tasks"`
+	// NextToken is set when there are more tasks to list, and is passed back in the
+	// 'nexttoken' field of the request for the next page
+	NextToken string `json:"NextToken,omitempty"`
+}
+
+// IntrospectionTaskResponse is the schema for a task of the '/v2/tasks' API response
+type IntrospectionTaskResponse struct {
+	Arn                string                           `json:"Arn"`
+	DesiredStatus      string                           `json:"DesiredStatus,omitempty"`
+	KnownStatus        string                           `json:"KnownStatus"`
+	Family             string                           `json:"Family"`
+	Version            string                           `json:"Version"`
+	StoppedReason      string                           `json:"StoppedReason,omitempty"`
+	ExecutionStoppedAt *time.Time                       `json:"ExecutionStoppedAt,omitempty"`
+	CleanedUpAt        *time.Time                       `json:"CleanedUpAt,omitempty"`
+	Containers         []IntrospectionContainerResponse `json:"Containers"`
+}
+
+// IntrospectionContainerResponse is the schema for a container of the '/v2/tasks' API
+// response
+type IntrospectionContainerResponse struct {
+	v1.ContainerResponse
+	KnownStatus string `json:"KnownStatus"`
+	ExitCode    *int   `json:"ExitCode,omitempty"`
+}
+
+// introspectionTasksFilter is the filter of a '/v2/tasks' request. Empty fields
+// match any task
+type introspectionTasksFilter struct {
+	status  string
+	family  string
+	taskARN string
+}
+
+func (filter introspectionTasksFilter) matches(task *IntrospectionTaskResponse) bool {
+	return (filter.status == "" || strings.EqualFold(filter.status, task.KnownStatus)) &&
+		(filter.family == "" || filter.family == task.Family) &&
+		(filter.taskARN == "" || filter.taskARN == task.Arn)
+}
+
+// IntrospectionTasksHandler creates response for the '/v2/tasks' API, which lists the
+// tasks managed by the agent along with the tasks cleaned up within the retention of
+// the stopped task history, ordered by ARN. The tasks can be filtered by known status,
+// family and ARN, and are returned a page at a time.
+func IntrospectionTasksHandler(resolver IntrospectionTasksResolver) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			utils.WriteJSONError(w, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				fmt.Sprintf("method %s is not allowed", r.Method), utils.RequestTypeIntrospectionTasks)
+			return
+		}
+		filter := introspectionTasksFilter{}
+		filter.status, _ = utils.ValueFromRequest(r, statusQueryField)
+		filter.family, _ = utils.ValueFromRequest(r, familyQueryField)
+		filter.taskARN, _ = utils.ValueFromRequest(r, taskARNQueryField)
+
+		maxResults := defaultMaxResults
+		if value, ok := utils.ValueFromRequest(r, maxResultsQueryField); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > defaultMaxResults {
+				utils.WriteJSONError(w, http.StatusBadRequest, "InvalidParameter",
+					fmt.Sprintf("%s must be between 1 and %d", maxResultsQueryField, defaultMaxResults), utils.RequestTypeIntrospectionTasks)
+				return
+			}
+			maxResults = parsed
+		}
+		var startAfter string
+		if token, ok := utils.ValueFromRequest(r, nextTokenQueryField); ok {
+			decoded, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil || len(decoded) == 0 {
+				utils.WriteJSONError(w, http.StatusBadRequest, "InvalidParameter",
+					fmt.Sprintf("invalid %s", nextTokenQueryField), utils.RequestTypeIntrospectionTasks)
+				return
+			}
+			startAfter = string(decoded)
+		}
+
+		response := newIntrospectionTasksResponse(resolver, filter, startAfter, maxResults)
+		responseJSON, err := json.Marshal(response)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeIntrospectionTasks)
+	}
+}
+
+// newIntrospectionTasksResponse lists the page of the tasks matching the filter that
+// starts after the task ARN. The tasks still in the state take precedence over the
+// tasks of the history, in case of a task ARN in both
+func newIntrospectionTasksResponse(resolver IntrospectionTasksResolver,
+	filter introspectionTasksFilter,
+	startAfter string,
+	maxResults int) *IntrospectionTasksResponse {
+	state := resolver.State()
+	tasks := make(map[string]*IntrospectionTaskResponse)
+	for _, stopped := range resolver.StoppedTasks() {
+		cleanedUpAt := stopped.CleanedUpAt
+		task := newIntrospectionTaskResponse(stopped.Task, stopped.Containers)
+		task.CleanedUpAt = &cleanedUpAt
+		tasks[task.Arn] = task
+	}
+	for _, task := range state.AllTasks() {
+		containerMap, _ := state.ContainerMapByArn(task.Arn)
+		tasks[task.Arn] = newIntrospectionTaskResponse(task, containerMap)
+	}
+
+	var arns []string
+	for arn, task := range tasks {
+		if arn > startAfter && filter.matches(task) {
+			arns = append(arns, arn)
+		}
+	}
+	sort.Strings(arns)
+
+	response := &IntrospectionTasksResponse{Tasks: []*IntrospectionTaskResponse{}}
+	if len(arns) > maxResults {
+		arns = arns[:maxResults]
+		response.NextToken = base64.RawURLEncoding.EncodeToString([]byte(arns[maxResults-1]))
+	}
+	for _, arn := range arns {
+		response.Tasks = append(response.Tasks, tasks[arn])
+	}
+	return response
+}
+
+// newIntrospectionTaskResponse creates an IntrospectionTaskResponse for a task, with
+// its containers ordered by name
+func newIntrospectionTaskResponse(task *apitask.Task,
+	containerMap map[string]*apicontainer.DockerContainer) *IntrospectionTaskResponse {
+	taskResponse := v1.NewTaskResponse(task, nil)
+	response := &IntrospectionTaskResponse{
+		Arn:           taskResponse.Arn,
+		DesiredStatus: taskResponse.DesiredStatus,
+		KnownStatus:   taskResponse.KnownStatus,
+		Family:        taskResponse.Family,
+		Version:       taskResponse.Version,
+		StoppedReason: task.GetTerminalReason(),
+		Containers:    []IntrospectionContainerResponse{},
+	}
+	if stoppedAt := task.GetExecutionStoppedAt(); !stoppedAt.IsZero() {
+		response.ExecutionStoppedAt = &stoppedAt
+	}
+	for _, dockerContainer := range containerMap {
+		container := dockerContainer.Container
+		if container.IsInternal() {
+			continue
+		}
+		response.Containers = append(response.Containers, IntrospectionContainerResponse{
+			ContainerResponse: v1.NewContainerResponse(dockerContainer, task.GetPrimaryENI()),
+			KnownStatus:       container.GetKnownStatus().String(),
+			ExitCode:          container.GetKnownExitCode(),
+		})
+	}
+	sort.Slice(response.Containers, func(i, j int) bool {
+		return response.Containers[i].Name < response.Containers[j].Name
+	})
+	return response
+}
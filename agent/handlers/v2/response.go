@@ -21,12 +21,14 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	"github.com/aws/amazon-ecs-agent/agent/containermetadata"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/cihub/seelog"
+	"github.com/docker/go-units"
 	"github.com/pkg/errors"
 )
 
@@ -53,27 +55,36 @@ type TaskResponse struct {
 // ContainerResponse defines the schema for the container response
 // JSON object
 type ContainerResponse struct {
-	ID            string                      `json:"DockerId"`
-	Name          string                      `json:"Name"`
-	DockerName    string                      `json:"DockerName"`
-	Image         string                      `json:"Image"`
-	ImageID       string                      `json:"ImageID"`
-	Ports         []v1.PortResponse           `json:"Ports,omitempty"`
-	Labels        map[string]string           `json:"Labels,omitempty"`
-	DesiredStatus string                      `json:"DesiredStatus"`
-	KnownStatus   string                      `json:"KnownStatus"`
-	ExitCode      *int                        `json:"ExitCode,omitempty"`
-	Limits        LimitsResponse              `json:"Limits"`
-	CreatedAt     *time.Time                  `json:"CreatedAt,omitempty"`
-	StartedAt     *time.Time                  `json:"StartedAt,omitempty"`
-	FinishedAt    *time.Time                  `json:"FinishedAt,omitempty"`
-	Type          string                      `json:"Type"`
-	Networks      []containermetadata.Network `json:"Networks,omitempty"`
-	Health        *apicontainer.HealthStatus  `json:"Health,omitempty"`
-	Volumes       []v1.VolumeResponse         `json:"Volumes,omitempty"`
-	LogDriver     string                      `json:"LogDriver,omitempty"`
-	LogOptions    map[string]string           `json:"LogOptions,omitempty"`
-	ContainerARN  string                      `json:"ContainerARN,omitempty"`
+	ID               string                         `json:"DockerId"`
+	Name             string                         `json:"Name"`
+	DockerName       string                         `json:"DockerName"`
+	Image            string                         `json:"Image"`
+	ImageID          string                         `json:"ImageID"`
+	Ports            []v1.PortResponse              `json:"Ports,omitempty"`
+	Labels           map[string]string              `json:"Labels,omitempty"`
+	DesiredStatus    string                         `json:"DesiredStatus"`
+	KnownStatus      string                         `json:"KnownStatus"`
+	ExitCode         *int                           `json:"ExitCode,omitempty"`
+	RestartCount     *int                           `json:"RestartCount,omitempty"`
+	Limits           LimitsResponse                 `json:"Limits"`
+	CreatedAt        *time.Time                     `json:"CreatedAt,omitempty"`
+	StartedAt        *time.Time                     `json:"StartedAt,omitempty"`
+	FinishedAt       *time.Time                     `json:"FinishedAt,omitempty"`
+	Type             string                         `json:"Type"`
+	Networks         []containermetadata.Network    `json:"Networks,omitempty"`
+	Health           *apicontainer.HealthStatus     `json:"Health,omitempty"`
+	Volumes          []v1.VolumeResponse            `json:"Volumes,omitempty"`
+	LogDriver        string                         `json:"LogDriver,omitempty"`
+	LogOptions       map[string]string              `json:"LogOptions,omitempty"`
+	ContainerARN     string                         `json:"ContainerARN,omitempty"`
+	NeuronCoreIDs    []int                          `json:"NeuronCoreIDs,omitempty"`
+	NeuronDevices    []string                       `json:"NeuronDevices,omitempty"`
+	SecurityProfiles []apicontainer.SecurityProfile `json:"SecurityProfiles,omitempty"`
+	Runtime          string                         `json:"Runtime,omitempty"`
+	Ulimits          []*units.Ulimit                `json:"Ulimits,omitempty"`
+	// EffectiveResources are the cpu and memory limits docker runs the container with,
+	// and its cgroup
+	EffectiveResources *apicontainer.EffectiveResources `json:"EffectiveResources,omitempty"`
 }
 
 // LimitsResponse defines the schema for task/cpu limits response
@@ -113,7 +124,22 @@ func NewTaskResponse(
 	if !ok {
 		return nil, errors.Errorf("v2 task response: unable to find task '%s'", taskARN)
 	}
+	return NewTaskResponseFromTask(task, state, ecsClient, cluster, az, containerInstanceArn, propagateTags,
+		includeV4Metadata), nil
+}
 
+// NewTaskResponseFromTask creates a new response object for a task already looked up
+// in the state
+func NewTaskResponseFromTask(
+	task *apitask.Task,
+	state dockerstate.TaskEngineState,
+	ecsClient api.ECSClient,
+	cluster string,
+	az string,
+	containerInstanceArn string,
+	propagateTags bool,
+	includeV4Metadata bool,
+) *TaskResponse {
 	resp := &TaskResponse{
 		Cluster:          cluster,
 		TaskARN:          task.Arn,
@@ -153,7 +179,7 @@ func NewTaskResponse(
 	if !ok {
 		seelog.Warnf("V2 task response: unable to get container name mapping for task '%s'",
 			task.Arn)
-		return resp, nil
+		return resp
 	}
 
 	for _, dockerContainer := range containerNameToDockerContainer {
@@ -162,10 +188,10 @@ func NewTaskResponse(
 	}
 
 	if propagateTags {
-		propagateTagsToMetadata(ecsClient, containerInstanceArn, taskARN, resp, includeV4Metadata)
+		propagateTagsToMetadata(ecsClient, containerInstanceArn, task.Arn, resp, includeV4Metadata)
 	}
 
-	return resp, nil
+	return resp
 }
 
 // propagateTagsToMetadata retrieves container instance and task tags from ECS
@@ -239,6 +265,12 @@ func NewContainerResponse(
 		Labels:   container.GetLabels(),
 	}
 
+	// The restart count is only meaningful for containers the agent restarts
+	if container.GetRestartPolicy() != nil {
+		restartCount := container.GetRestartCount()
+		resp.RestartCount = &restartCount
+	}
+
 	if container.CPU < minimumCPUUnit {
 		defaultCPU := func(val float64) *float64 { return &val }(minimumCPUUnit)
 		resp.Limits.CPU = defaultCPU
@@ -250,6 +282,12 @@ func NewContainerResponse(
 		resp.LogDriver = container.GetLogDriver()
 		resp.LogOptions = container.GetLogOptions()
 		resp.ContainerARN = container.ContainerArn
+		resp.NeuronCoreIDs = container.GetNeuronCoreIDs()
+		resp.NeuronDevices = container.GetNeuronDevices()
+		resp.SecurityProfiles = container.GetSecurityProfiles()
+		resp.Runtime = container.GetOCIRuntime()
+		resp.Ulimits = container.GetUlimits()
+		resp.EffectiveResources = container.GetEffectiveResources()
 	}
 
 	// Write the container health status inside the container
@@ -32,6 +32,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/go-units"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -322,6 +323,122 @@ func TestContainerResponse(t *testing.T) {
 	}
 }
 
+func TestContainerResponseRestartCount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	container := &apicontainer.Container{
+		Name:          containerName,
+		Type:          apicontainer.ContainerNormal,
+		RestartPolicy: &apicontainer.RestartPolicy{MaxAttempts: 3},
+	}
+	container.SetRestartCount(2)
+	dockerContainer := &apicontainer.DockerContainer{
+		DockerID:   containerID,
+		DockerName: containerName,
+		Container:  container,
+	}
+	gomock.InOrder(
+		state.EXPECT().ContainerByID(containerID).Return(dockerContainer, true),
+		state.EXPECT().TaskByID(containerID).Return(&apitask.Task{}, true),
+	)
+
+	containerResponse, err := NewContainerResponseFromState(containerID, state, false)
+	assert.NoError(t, err)
+	assert.Equal(t, aws.Int(2), containerResponse.RestartCount)
+}
+
+func TestContainerResponseNeuronCores(t *testing.T) {
+	container := &apicontainer.Container{
+		Name:        containerName,
+		Type:        apicontainer.ContainerNormal,
+		NeuronCores: 2,
+	}
+	container.SetNeuronCores([]int{2, 3}, []string{"/dev/neuron1"}, []int{0, 1})
+	dockerContainer := &apicontainer.DockerContainer{
+		DockerID:   containerID,
+		DockerName: containerName,
+		Container:  container,
+	}
+
+	containerResponse := NewContainerResponse(dockerContainer, nil, true)
+	assert.Equal(t, []int{2, 3}, containerResponse.NeuronCoreIDs)
+	assert.Equal(t, []string{"/dev/neuron1"}, containerResponse.NeuronDevices)
+
+	// the assignments are only exposed by the v4 metadata endpoint
+	containerResponse = NewContainerResponse(dockerContainer, nil, false)
+	assert.Empty(t, containerResponse.NeuronCoreIDs)
+}
+
+func TestContainerResponseRuntime(t *testing.T) {
+	container := &apicontainer.Container{
+		Name: containerName,
+		Type: apicontainer.ContainerNormal,
+	}
+	container.SetOCIRuntime("runsc")
+	dockerContainer := &apicontainer.DockerContainer{
+		DockerID:   containerID,
+		DockerName: containerName,
+		Container:  container,
+	}
+
+	containerResponse := NewContainerResponse(dockerContainer, nil, true)
+	assert.Equal(t, "runsc", containerResponse.Runtime)
+
+	// the runtime is only exposed by the v4 metadata endpoint
+	containerResponse = NewContainerResponse(dockerContainer, nil, false)
+	assert.Empty(t, containerResponse.Runtime)
+}
+
+func TestContainerResponseUlimits(t *testing.T) {
+	container := &apicontainer.Container{
+		Name: containerName,
+		Type: apicontainer.ContainerNormal,
+	}
+	ulimits := []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}}
+	container.SetUlimits(ulimits)
+	dockerContainer := &apicontainer.DockerContainer{
+		DockerID:   containerID,
+		DockerName: containerName,
+		Container:  container,
+	}
+
+	containerResponse := NewContainerResponse(dockerContainer, nil, true)
+	assert.Equal(t, ulimits, containerResponse.Ulimits)
+
+	// the ulimits are only exposed by the v4 metadata endpoint
+	containerResponse = NewContainerResponse(dockerContainer, nil, false)
+	assert.Empty(t, containerResponse.Ulimits)
+}
+
+func TestContainerResponseEffectiveResources(t *testing.T) {
+	container := &apicontainer.Container{
+		Name: containerName,
+		Type: apicontainer.ContainerNormal,
+	}
+	resources := &apicontainer.EffectiveResources{
+		CPUShares:  512,
+		CPUQuota:   50000,
+		CPUPeriod:  100000,
+		Memory:     512 * 1024 * 1024,
+		CgroupPath: "/ecs/task-id/container-id",
+	}
+	container.SetEffectiveResources(resources)
+	dockerContainer := &apicontainer.DockerContainer{
+		DockerID:   containerID,
+		DockerName: containerName,
+		Container:  container,
+	}
+
+	containerResponse := NewContainerResponse(dockerContainer, nil, true)
+	assert.Equal(t, resources, containerResponse.EffectiveResources)
+
+	// the effective resources are only exposed by the v4 metadata endpoint
+	containerResponse = NewContainerResponse(dockerContainer, nil, false)
+	assert.Nil(t, containerResponse.EffectiveResources)
+}
+
 func TestTaskResponseMarshal(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -0,0 +1,84 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v4
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
+	"github.com/cihub/seelog"
+)
+
+// ContainerHealthHistoryPath specifies the relative URI path for serving the results of the
+// last health checks of a container.
+var ContainerHealthHistoryPath = "/v4/" + utils.ConstructMuxVar(v3.V3EndpointIDMuxName, utils.AnythingButSlashRegEx) + "/health/history"
+
+// ContainerHealthHistoryResponse is the schema for the container health history response JSON object
+type ContainerHealthHistoryResponse struct {
+	DockerID string                           `json:"DockerId"`
+	Name     string                           `json:"Name"`
+	Health   *apicontainer.HealthStatus       `json:"Health,omitempty"`
+	History  []apicontainer.HealthCheckResult `json:"History"`
+}
+
+// ContainerHealthHistoryHandler returns the handler method for handling container health history requests.
+func ContainerHealthHistoryHandler(state dockerstate.TaskEngineState) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		containerID, err := v3.GetContainerIDByRequest(r, state)
+		if err != nil {
+			responseJSON, err := json.Marshal(
+				fmt.Sprintf("V4 container health history handler: unable to get container ID from request: %s", err.Error()))
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusInternalServerError, responseJSON, utils.RequestTypeContainerHealthHistory)
+			return
+		}
+		dockerContainer, ok := state.ContainerByID(containerID)
+		if !ok {
+			errResponseJSON, err := json.Marshal(fmt.Sprintf("unable to find container '%s'", containerID))
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusInternalServerError, errResponseJSON, utils.RequestTypeContainerHealthHistory)
+			return
+		}
+		seelog.Infof("V4 container health history handler: writing response for container '%s'", containerID)
+
+		container := dockerContainer.Container
+		response := ContainerHealthHistoryResponse{
+			DockerID: containerID,
+			Name:     container.Name,
+			History:  container.GetHealthHistory(),
+		}
+		if container.HealthStatusShouldBeReported() {
+			health := container.GetHealthStatus()
+			response.Health = &health
+		}
+		if response.History == nil {
+			response.History = []apicontainer.HealthCheckResult{}
+		}
+
+		responseJSON, err := json.Marshal(response)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeContainerHealthHistory)
+	}
+}
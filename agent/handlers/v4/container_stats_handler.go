@@ -53,7 +53,7 @@ func ContainerStatsHandler(state dockerstate.TaskEngineState, statsEngine stats.
 
 		seelog.Infof("V4 container stats handler: writing response for container '%s'", containerID)
 		// v4 handler shares the same container states response format with v2 handler.
-		WriteV4ContainerStatsResponse(w, taskArn, containerID, statsEngine)
+		WriteV4ContainerStatsResponse(w, taskArn, containerID, state, statsEngine)
 	}
 }
 
@@ -61,6 +61,7 @@ func ContainerStatsHandler(state dockerstate.TaskEngineState, statsEngine stats.
 func WriteV4ContainerStatsResponse(w http.ResponseWriter,
 	taskARN string,
 	containerID string,
+	state dockerstate.TaskEngineState,
 	statsEngine stats.Engine) {
 	dockerStats, network_rate_stats, err := statsEngine.ContainerDockerStats(taskARN, containerID)
 	if err != nil {
@@ -75,6 +76,9 @@ func WriteV4ContainerStatsResponse(w http.ResponseWriter,
 	containerStatsResponse := StatsResponse{
 		StatsJSON:          dockerStats,
 		Network_rate_stats: network_rate_stats,
+		Disk_usage_stats:   containerDiskUsage(taskARN, containerID, statsEngine),
+		Gpu_stats:          containerGPUStats(taskARN, containerID, statsEngine),
+		Dns_cache_stats:    taskDNSCacheStats(taskARN, state),
 	}
 
 	responseJSON, err := json.Marshal(containerStatsResponse)
@@ -0,0 +1,106 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v4
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/networkpolicy"
+	"github.com/cihub/seelog"
+)
+
+// instanceMetadataAttemptsTTL is how long the blocked attempts of a task are reused, so
+// that frequent task metadata requests don't each read the counter of its namespace
+const instanceMetadataAttemptsTTL = 5 * time.Second
+
+var (
+	// instanceMetadataBlockedAttempts returns the number of attempts to reach the instance
+	// metadata service blocked in the network namespace of a process. It's swappable for
+	// testing.
+	instanceMetadataBlockedAttempts = networkpolicy.InstanceMetadataBlockedAttempts
+	// now is swappable for testing
+	now = time.Now
+
+	attemptsCache = &instanceMetadataAttemptsCache{entries: make(map[string]cachedAttempts)}
+)
+
+// instanceMetadataAttemptsCache holds the blocked attempts last read in each network
+// namespace
+type instanceMetadataAttemptsCache struct {
+	lock    sync.Mutex
+	entries map[string]cachedAttempts
+}
+
+type cachedAttempts struct {
+	attempts uint64
+	readAt   time.Time
+}
+
+// get returns the blocked attempts of the network namespace, reading them again once the
+// cached ones are older than instanceMetadataAttemptsTTL. Expired entries, such as the
+// ones of stopped tasks, are dropped whenever the attempts are read.
+func (cache *instanceMetadataAttemptsCache) get(ctx context.Context, counterPID string, counterNetNS string) (uint64, error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	current := now()
+	if entry, ok := cache.entries[counterNetNS]; ok && current.Sub(entry.readAt) < instanceMetadataAttemptsTTL {
+		return entry.attempts, nil
+	}
+	attempts, err := instanceMetadataBlockedAttempts(ctx, counterPID, counterNetNS)
+	if err != nil {
+		return 0, err
+	}
+	for netns, entry := range cache.entries {
+		if current.Sub(entry.readAt) >= instanceMetadataAttemptsTTL {
+			delete(cache.entries, netns)
+		}
+	}
+	cache.entries[counterNetNS] = cachedAttempts{attempts: attempts, readAt: current}
+	return attempts, nil
+}
+
+// InstanceMetadataAccess reports whether the access of a task to the instance metadata
+// service is blocked, and how many attempts to reach it were blocked
+type InstanceMetadataAccess struct {
+	// Blocked is true when the access to the instance metadata service is blocked
+	Blocked bool `json:"Blocked"`
+	// BlockedAttempts is the number of packets sent to the instance metadata service that
+	// were rejected. It's only set when the attempts are counted
+	BlockedAttempts *uint64 `json:"BlockedAttempts,omitempty"`
+}
+
+// NewInstanceMetadataAccess returns the instance metadata service access of a task, or nil
+// if the task doesn't use the awsvpc network mode
+func NewInstanceMetadataAccess(ctx context.Context, task *apitask.Task) *InstanceMetadataAccess {
+	if !task.IsNetworkModeAWSVPC() {
+		return nil
+	}
+	blocked, counterPID, counterNetNS := task.GetInstanceMetadataBlock()
+	access := &InstanceMetadataAccess{Blocked: blocked}
+	if !blocked || counterPID == "" || counterNetNS == "" {
+		return access
+	}
+	attempts, err := attemptsCache.get(ctx, counterPID, counterNetNS)
+	if err != nil {
+		seelog.Warnf("V4 task metadata handler: unable to count the blocked instance metadata attempts of task '%s': %v",
+			task.Arn, err)
+		return access
+	}
+	access.BlockedAttempts = &attempts
+	return access
+}
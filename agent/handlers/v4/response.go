@@ -30,6 +30,39 @@ import (
 type TaskResponse struct {
 	*v2.TaskResponse
 	Containers []ContainerResponse `json:"Containers,omitempty"`
+	// CgroupPath is the path of the cgroup of the task, relative to the root of the
+	// cgroup hierarchies, once it's created
+	CgroupPath string `json:"CgroupPath,omitempty"`
+	// CredentialsFetches is the number of times the credentials endpoint served
+	// credentials for the task, by role type
+	CredentialsFetches map[string]int `json:"CredentialsFetches,omitempty"`
+	// EphemeralStorageMetrics is the ephemeral storage of the task, and how much of it
+	// the writable layers of its containers use
+	EphemeralStorageMetrics *EphemeralStorageMetrics `json:"EphemeralStorageMetrics,omitempty"`
+	// InstanceMetadataAccess reports whether the access of the task to the instance
+	// metadata service is blocked, for tasks using the awsvpc network mode
+	InstanceMetadataAccess *InstanceMetadataAccess `json:"InstanceMetadataAccess,omitempty"`
+	// Interruption is the notice that the instance the task runs on is going to be
+	// interrupted, once the agent received one
+	Interruption *apitask.Interruption `json:"Interruption,omitempty"`
+	// MemoryPressure is the last time the memory of the task went under pressure, and the
+	// containers the agent stopped to relieve it
+	MemoryPressure *apitask.MemoryPressure `json:"MemoryPressure,omitempty"`
+	// ResourceControls are the cpuset pinning and the io limits applied to the cgroup of
+	// the task, once it's created
+	ResourceControls *apitask.ResourceControls `json:"ResourceControls,omitempty"`
+}
+
+// EphemeralStorageMetrics is the ephemeral storage of a task, in MiB
+type EphemeralStorageMetrics struct {
+	// Utilized is the size of the writable layers of the containers of the task, as last
+	// measured. It's only set when the disk usage of containers is measured
+	Utilized *uint64 `json:"Utilized,omitempty"`
+	// Reserved is the ephemeral storage size from the task definition
+	Reserved uint64 `json:"Reserved"`
+	// QuotaEnforced is true when the writable layers of the containers of the task are
+	// limited to the ephemeral storage size
+	QuotaEnforced bool `json:"QuotaEnforced"`
 }
 
 // ContainerResponse is the v4 Container response. It augments the v4 Network response
@@ -72,6 +105,8 @@ type NetworkInterfaceProperties struct {
 	PrivateDNSName string `json:"PrivateDNSName,omitempty"`
 	// SubnetGatewayIPV4Address is the IPv4 gateway address for the network interface.
 	SubnetGatewayIPV4Address string `json:"SubnetGatewayIpv4Address,omitempty"`
+	// SubnetGatewayIPV6Address is the IPv6 gateway address for the network interface.
+	SubnetGatewayIPV6Address string `json:"SubnetGatewayIpv6Address,omitempty"`
 }
 
 // NewTaskResponse creates a new v4 response object for the task. It augments v2 task response
@@ -85,12 +120,13 @@ func NewTaskResponse(
 	containerInstanceARN string,
 	propagateTags bool,
 ) (*TaskResponse, error) {
+	task, ok := state.TaskByArn(taskARN)
+	if !ok {
+		return nil, errors.Errorf("v4 task response: unable to find task '%s'", taskARN)
+	}
 	// Construct the v2 response first.
-	v2Resp, err := v2.NewTaskResponse(taskARN, state, ecsClient, cluster, az,
+	v2Resp := v2.NewTaskResponseFromTask(task, state, ecsClient, cluster, az,
 		containerInstanceARN, propagateTags, true)
-	if err != nil {
-		return nil, err
-	}
 	var containers []ContainerResponse
 	// Convert each container response into v4 container response.
 	for i, container := range v2Resp.Containers {
@@ -107,8 +143,13 @@ func NewTaskResponse(
 	}
 
 	return &TaskResponse{
-		TaskResponse: v2Resp,
-		Containers:   containers,
+		TaskResponse:       v2Resp,
+		Containers:         containers,
+		CgroupPath:         task.GetCgroupPath(),
+		CredentialsFetches: task.GetCredentialsFetchCount(),
+		Interruption:       task.GetInterruption(),
+		MemoryPressure:     task.GetMemoryPressure(),
+		ResourceControls:   task.GetAppliedResourceControls(),
 	}, nil
 }
 
@@ -183,10 +224,11 @@ func newNetworkInterfaceProperties(task *apitask.Task) (NetworkInterfaceProperti
 		IPV4SubnetCIDRBlock:      eni.GetIPv4SubnetCIDRBlock(),
 		IPv6SubnetCIDRBlock:      eni.GetIPv6SubnetCIDRBlock(),
 		MACAddress:               eni.MacAddress,
-		DomainNameServers:        eni.DomainNameServers,
+		DomainNameServers:        eni.GetDomainNameServers(),
 		DomainNameSearchList:     eni.DomainNameSearchList,
 		PrivateDNSName:           eni.PrivateDNSName,
 		SubnetGatewayIPV4Address: eni.SubnetGatewayIPV4Address,
+		SubnetGatewayIPV6Address: eni.GetSubnetGatewayIPv6Address(),
 	}, nil
 }
 
@@ -16,7 +16,9 @@
 package v4
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -27,6 +29,9 @@ import (
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/networkpolicy"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	mock_stats "github.com/aws/amazon-ecs-agent/agent/stats/mock"
 	"github.com/docker/docker/api/types"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -155,3 +160,140 @@ func TestNewTaskContainerResponses(t *testing.T) {
 	assert.Equal(t, "192.168.0.0/24", containerResponse.Networks[0].IPV4SubnetCIDRBlock)
 	assert.Equal(t, subnetGatewayIPV4Address, containerResponse.Networks[0].SubnetGatewayIPV4Address)
 }
+
+func TestNewTaskResponseTaskFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	now := time.Now()
+	task := &apitask.Task{
+		Arn:                 taskARN,
+		Family:              family,
+		Version:             version,
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+	}
+	task.RecordCredentialsFetch("TaskApplication")
+	task.SetInterruption(apitask.Interruption{
+		Source:     apitask.InterruptionSourceSpot,
+		Action:     "terminate",
+		NoticeTime: now,
+	})
+	task.SetMemoryPressure(apitask.MemoryPressure{
+		DetectedAt:        now,
+		UsageBytes:        1 << 30,
+		LimitBytes:        1 << 30,
+		EvictedContainers: []string{"sidecar"},
+	})
+	// The task is only looked up once
+	gomock.InOrder(
+		state.EXPECT().TaskByArn(taskARN).Return(task, true),
+		state.EXPECT().ContainerMapByArn(taskARN).Return(nil, false),
+	)
+
+	taskResponse, err := NewTaskResponse(taskARN, state, ecsClient, cluster, availabilityZone, containerInstanceArn, false)
+	require.NoError(t, err)
+	assert.Equal(t, taskARN, taskResponse.TaskARN)
+	assert.Equal(t, map[string]int{"TaskApplication": 1}, taskResponse.CredentialsFetches)
+	require.NotNil(t, taskResponse.Interruption)
+	assert.Equal(t, apitask.InterruptionSourceSpot, taskResponse.Interruption.Source)
+	assert.Equal(t, "terminate", taskResponse.Interruption.Action)
+	require.NotNil(t, taskResponse.MemoryPressure)
+	assert.Equal(t, []string{"sidecar"}, taskResponse.MemoryPressure.EvictedContainers)
+}
+
+func TestNewTaskResponseTaskNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	state.EXPECT().TaskByArn(taskARN).Return(nil, false)
+
+	_, err := NewTaskResponse(taskARN, state, ecsClient, cluster, availabilityZone, containerInstanceArn, false)
+	assert.Error(t, err)
+}
+
+func TestNewEphemeralStorageMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+
+	container1 := &apicontainer.Container{Name: "c1"}
+	container1.SetRuntimeID("cid1")
+	container2 := &apicontainer.Container{Name: "c2"}
+	container2.SetRuntimeID("cid2")
+	task := &apitask.Task{
+		Arn:              taskARN,
+		Containers:       []*apicontainer.Container{container1, container2, {Name: "not-created"}},
+		EphemeralStorage: &apitask.EphemeralStorage{SizeInGiB: 20},
+	}
+	task.SetEphemeralStorageQuotaEnforced()
+
+	statsEngine.EXPECT().ContainerDiskUsage(taskARN, "cid1").Return(&stats.DiskUsage{WritableLayerSizeBytes: 100 * stats.BytesInMiB}, nil)
+	statsEngine.EXPECT().ContainerDiskUsage(taskARN, "cid2").Return(&stats.DiskUsage{WritableLayerSizeBytes: 28 * stats.BytesInMiB}, nil)
+	metrics := NewEphemeralStorageMetrics(task, statsEngine)
+	require.NotNil(t, metrics)
+	assert.Equal(t, uint64(20480), metrics.Reserved)
+	require.NotNil(t, metrics.Utilized)
+	assert.Equal(t, uint64(128), *metrics.Utilized)
+	assert.True(t, metrics.QuotaEnforced)
+
+	// the usage is omitted when the disk usage of the containers isn't measured
+	statsEngine.EXPECT().ContainerDiskUsage(taskARN, gomock.Any()).Return(nil, errors.New("not measured")).Times(2)
+	metrics = NewEphemeralStorageMetrics(task, statsEngine)
+	require.NotNil(t, metrics)
+	assert.Nil(t, metrics.Utilized)
+
+	assert.Nil(t, NewEphemeralStorageMetrics(&apitask.Task{Arn: taskARN}, statsEngine))
+}
+
+func TestNewInstanceMetadataAccess(t *testing.T) {
+	defer func() {
+		instanceMetadataBlockedAttempts = networkpolicy.InstanceMetadataBlockedAttempts
+		now = time.Now
+	}()
+	reads := 0
+	instanceMetadataBlockedAttempts = func(ctx context.Context, pid string, netnsID string) (uint64, error) {
+		if pid != "1234" || netnsID != "NS(1:2)" {
+			return 0, errors.New("no such process")
+		}
+		reads++
+		return uint64(7 * reads), nil
+	}
+	current := time.Now()
+	now = func() time.Time {
+		return current
+	}
+
+	task := &apitask.Task{Arn: taskARN, ENIs: []*apieni.ENI{{ID: "eni-1"}}}
+	assert.Equal(t, &InstanceMetadataAccess{}, NewInstanceMetadataAccess(context.TODO(), task))
+
+	task.SetInstanceMetadataBlocked("1234", "NS(1:2)")
+	access := NewInstanceMetadataAccess(context.TODO(), task)
+	require.NotNil(t, access)
+	assert.True(t, access.Blocked)
+	require.NotNil(t, access.BlockedAttempts)
+	assert.Equal(t, uint64(7), *access.BlockedAttempts)
+
+	// The attempts are read again only once the cached ones expire.
+	access = NewInstanceMetadataAccess(context.TODO(), task)
+	require.NotNil(t, access.BlockedAttempts)
+	assert.Equal(t, uint64(7), *access.BlockedAttempts)
+	current = current.Add(instanceMetadataAttemptsTTL)
+	access = NewInstanceMetadataAccess(context.TODO(), task)
+	require.NotNil(t, access.BlockedAttempts)
+	assert.Equal(t, uint64(14), *access.BlockedAttempts)
+	assert.Equal(t, 2, reads)
+
+	task.SetInstanceMetadataBlocked("4321", "NS(1:3)")
+	assert.Equal(t, &InstanceMetadataAccess{Blocked: true}, NewInstanceMetadataAccess(context.TODO(), task))
+
+	// Tasks blocked without a namespace identity aren't counted, as their pid may be reused.
+	task.SetInstanceMetadataBlocked("1234", "")
+	assert.Equal(t, &InstanceMetadataAccess{Blocked: true}, NewInstanceMetadataAccess(context.TODO(), task))
+
+	assert.Nil(t, NewInstanceMetadataAccess(context.TODO(), &apitask.Task{Arn: taskARN}))
+}
@@ -14,6 +14,10 @@
 package v4
 
 import (
+	"sync"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/cihub/seelog"
@@ -26,6 +30,9 @@ import (
 type StatsResponse struct {
 	*types.StatsJSON
 	Network_rate_stats *stats.NetworkStatsPerSec `json:"network_rate_stats,omitempty"`
+	Disk_usage_stats   *stats.DiskUsage          `json:"disk_usage_stats,omitempty"`
+	Gpu_stats          []*stats.GPUStats         `json:"gpu_stats,omitempty"`
+	Dns_cache_stats    *dnscache.Stats           `json:"dns_cache_stats,omitempty"`
 }
 
 // NewV4TaskStatsResponse returns a new v4 task stats response object
@@ -40,6 +47,7 @@ func NewV4TaskStatsResponse(taskARN string,
 			taskARN)
 	}
 
+	dnsCacheStats := taskDNSCacheStats(taskARN, state)
 	resp := make(map[string]StatsResponse)
 	for _, dockerContainer := range containerMap {
 		containerID := dockerContainer.DockerID
@@ -54,6 +62,9 @@ func NewV4TaskStatsResponse(taskARN string,
 		statsResponse := StatsResponse{
 			StatsJSON:          dockerStats,
 			Network_rate_stats: network_rate_stats,
+			Disk_usage_stats:   containerDiskUsage(taskARN, containerID, statsEngine),
+			Gpu_stats:          containerGPUStats(taskARN, containerID, statsEngine),
+			Dns_cache_stats:    dnsCacheStats,
 		}
 
 		resp[containerID] = statsResponse
@@ -61,3 +72,119 @@ func NewV4TaskStatsResponse(taskARN string,
 
 	return resp, nil
 }
+
+// NewV4TaskStatsSnapshotResponse returns a new v4 task stats response object, built from stats
+// samples collected for each container of the task at the time of the request.
+func NewV4TaskStatsSnapshotResponse(taskARN string,
+	state dockerstate.TaskEngineState,
+	statsEngine stats.Engine) (map[string]StatsResponse, error) {
+
+	containerMap, ok := state.ContainerMapByArn(taskARN)
+	if !ok {
+		return nil, errors.Errorf(
+			"v4 task stats snapshot response: unable to lookup containers for task %s",
+			taskARN)
+	}
+
+	dnsCacheStats := taskDNSCacheStats(taskARN, state)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	resp := make(map[string]StatsResponse)
+	for _, dockerContainer := range containerMap {
+		containerID := dockerContainer.DockerID
+		wg.Add(1)
+		// collecting a sample takes a couple of seconds, so the containers are sampled concurrently
+		go func() {
+			defer wg.Done()
+			statsResponse := StatsResponse{}
+			dockerStats, network_rate_stats, err := statsEngine.ContainerDockerStatsSnapshot(taskARN, containerID)
+			if err != nil {
+				seelog.Warnf("V4 task stats snapshot response: Unable to get stats for container '%s' for task '%s': %v",
+					containerID, taskARN, err)
+			} else {
+				statsResponse = StatsResponse{
+					StatsJSON:          dockerStats,
+					Network_rate_stats: network_rate_stats,
+					Disk_usage_stats:   containerDiskUsage(taskARN, containerID, statsEngine),
+					Gpu_stats:          containerGPUStats(taskARN, containerID, statsEngine),
+					Dns_cache_stats:    dnsCacheStats,
+				}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			resp[containerID] = statsResponse
+		}()
+	}
+	wg.Wait()
+
+	return resp, nil
+}
+
+// containerDiskUsage returns the last measured disk usage of a container, or nil if
+// it hasn't been measured.
+func containerDiskUsage(taskARN string, containerID string, statsEngine stats.Engine) *stats.DiskUsage {
+	diskUsage, err := statsEngine.ContainerDiskUsage(taskARN, containerID)
+	if err != nil {
+		seelog.Debugf("V4 stats response: Unable to get disk usage for container '%s' for task '%s': %v",
+			containerID, taskARN, err)
+		return nil
+	}
+	return diskUsage
+}
+
+// NewEphemeralStorageMetrics returns the ephemeral storage metrics of a task, or nil if
+// its task definition doesn't set an ephemeral storage size
+func NewEphemeralStorageMetrics(task *apitask.Task, statsEngine stats.Engine) *EphemeralStorageMetrics {
+	reserved := task.EphemeralStorage.SizeInBytes()
+	if reserved == 0 {
+		return nil
+	}
+	metrics := &EphemeralStorageMetrics{
+		Reserved:      reserved / stats.BytesInMiB,
+		QuotaEnforced: task.IsEphemeralStorageQuotaEnforced(),
+	}
+	measured := false
+	var utilized uint64
+	for _, container := range task.Containers {
+		containerID := container.GetRuntimeID()
+		if containerID == "" {
+			continue
+		}
+		if diskUsage := containerDiskUsage(task.Arn, containerID, statsEngine); diskUsage != nil {
+			measured = true
+			utilized += diskUsage.WritableLayerSizeBytes
+		}
+	}
+	if measured {
+		utilized /= stats.BytesInMiB
+		metrics.Utilized = &utilized
+	}
+	return metrics
+}
+
+// containerGPUStats returns the last sample of the GPUs assigned to a container, or nil if
+// GPU metrics aren't collected.
+func containerGPUStats(taskARN string, containerID string, statsEngine stats.Engine) []*stats.GPUStats {
+	gpuStats, err := statsEngine.ContainerGPUStats(taskARN, containerID)
+	if err != nil {
+		seelog.Debugf("V4 stats response: Unable to get GPU stats for container '%s' for task '%s': %v",
+			containerID, taskARN, err)
+		return nil
+	}
+	return gpuStats
+}
+
+// taskDNSCacheStats returns the counters of the DNS cache of a task, which all of its
+// containers share, or nil if the task doesn't use one.
+func taskDNSCacheStats(taskARN string, state dockerstate.TaskEngineState) *dnscache.Stats {
+	task, ok := state.TaskByArn(taskARN)
+	if !ok {
+		return nil
+	}
+	cache, _ := task.GetDNSCache()
+	if cache == nil {
+		return nil
+	}
+	cacheStats := cache.Stats()
+	return &cacheStats
+}
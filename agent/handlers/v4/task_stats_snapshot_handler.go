@@ -0,0 +1,63 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v4
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/cihub/seelog"
+)
+
+// TaskStatsSnapshotPath specifies the relative URI path for serving task stats collected at
+// the time of the request, rather than the last sample of the regular collection cycle.
+var TaskStatsSnapshotPath = TaskStatsPath + "/snapshot"
+
+// TaskStatsSnapshotHandler returns the handler method for serving a fresh stats sample of the task.
+func TaskStatsSnapshotHandler(state dockerstate.TaskEngineState, statsEngine stats.Engine) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskArn, err := v3.GetTaskARNByRequest(r, state)
+		if err != nil {
+			errResponseJSON, err := json.Marshal(fmt.Sprintf("V4 task stats snapshot handler: unable to get task arn from request: %s", err.Error()))
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusBadRequest, errResponseJSON, utils.RequestTypeTaskStatsSnapshot)
+			return
+		}
+		seelog.Infof("V4 task stats snapshot handler: writing response for task '%s'", taskArn)
+
+		taskStatsResponse, err := NewV4TaskStatsSnapshotResponse(taskArn, state, statsEngine)
+		if err != nil {
+			seelog.Warnf("Unable to get task stats snapshot for task '%s': %v", taskArn, err)
+			errResponseJSON, err := json.Marshal("Unable to get task stats snapshot for: " + taskArn)
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusBadRequest, errResponseJSON, utils.RequestTypeTaskStatsSnapshot)
+			return
+		}
+
+		responseJSON, err := json.Marshal(taskStatsResponse)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeTaskStatsSnapshot)
+	}
+}
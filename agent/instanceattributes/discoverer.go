@@ -0,0 +1,226 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package instanceattributes discovers attributes of the container instance, such as
+// its kernel version or the size of its local NVMe storage, which are registered with
+// ECS so that services can constrain the placement of their tasks on the capabilities
+// of the instances
+package instanceattributes
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cihub/seelog"
+)
+
+var (
+	// attributeNameRegex and attributeValueRegex are the characters ECS accepts in the
+	// names and the values of attributes
+	attributeNameRegex  = regexp.MustCompile(`^[a-zA-Z0-9_./-]{1,128}$`)
+	attributeValueRegex = regexp.MustCompile(`^[a-zA-Z0-9_.@/: -]{0,128}$`)
+	// reservedAttributePrefixes are the prefixes of the attributes and capabilities
+	// registered by ECS and the agent, which discovered attributes can't override
+	reservedAttributePrefixes = []string{"ecs.", "com.amazonaws.ecs."}
+)
+
+// Provider discovers attributes of the container instance
+type Provider interface {
+	// Name identifies the provider in the logs
+	Name() string
+	// Attributes returns the discovered attributes by name. An attribute with an empty
+	// value is registered without a value. A provider returns no attribute when what it
+	// discovers isn't available on the instance.
+	Attributes() (map[string]string, error)
+}
+
+// Client is the part of api.ECSClient that updates the attributes of the container
+// instance
+type Client interface {
+	PutAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error
+	DeleteAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error
+}
+
+// Discoverer discovers the attributes of its providers, and refreshes the registered
+// attributes that changed. Attributes that are no longer discovered are deleted, unless
+// a provider failed, as its attributes may still be there.
+type Discoverer struct {
+	providers []Provider
+
+	lock sync.Mutex
+	// registered are the attributes of the container instance as last registered
+	registered map[string]string
+}
+
+// NewDiscoverer returns a discoverer of the attributes of the given built-in providers
+// and of the executables of the plugins directory. It returns nil when there is neither
+// a provider nor a plugins directory. Unknown providers are ignored with a warning.
+func NewDiscoverer(providerNames []string, pluginsDir string) *Discoverer {
+	var providers []Provider
+	for _, name := range providerNames {
+		provider, ok := builtinProviders[name]
+		if !ok {
+			seelog.Warnf("Unknown instance attribute provider %q, it is ignored", name)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	if pluginsDir != "" {
+		providers = append(providers, &pluginsProvider{dir: pluginsDir})
+	}
+	if len(providers) == 0 {
+		return nil
+	}
+	return newDiscoverer(providers)
+}
+
+func newDiscoverer(providers []Provider) *Discoverer {
+	return &Discoverer{
+		providers:  providers,
+		registered: make(map[string]string),
+	}
+}
+
+// Attributes discovers the attributes to register the container instance with. They
+// are remembered as registered.
+func (discoverer *Discoverer) Attributes() []*ecs.Attribute {
+	attributes, _ := discoverer.discover()
+	discoverer.lock.Lock()
+	defer discoverer.lock.Unlock()
+	discoverer.registered = attributes
+	return toECSAttributes(attributes)
+}
+
+// Refresh discovers the attributes again every interval until the context is done, puts
+// the ones that changed since they were registered and deletes the ones that are gone
+func (discoverer *Discoverer) Refresh(ctx context.Context, client Client, containerInstanceArn string,
+	interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := discoverer.refresh(client, containerInstanceArn); err != nil {
+				seelog.Warnf("Unable to update the attributes of container instance %s: %v", containerInstanceArn, err)
+			}
+		}
+	}
+}
+
+func (discoverer *Discoverer) refresh(client Client, containerInstanceArn string) error {
+	attributes, complete := discoverer.discover()
+	discoverer.lock.Lock()
+	defer discoverer.lock.Unlock()
+	changed := make(map[string]string)
+	for name, value := range attributes {
+		if registered, ok := discoverer.registered[name]; !ok || registered != value {
+			changed[name] = value
+		}
+	}
+	if len(changed) > 0 {
+		seelog.Infof("Updating %d attributes of container instance %s", len(changed), containerInstanceArn)
+		if err := client.PutAttributes(containerInstanceArn, toECSAttributes(changed)); err != nil {
+			return err
+		}
+		for name, value := range changed {
+			discoverer.registered[name] = value
+		}
+	}
+
+	if !complete {
+		return nil
+	}
+	removed := make(map[string]string)
+	for name := range discoverer.registered {
+		if _, ok := attributes[name]; !ok {
+			removed[name] = ""
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	seelog.Infof("Deleting %d attributes of container instance %s that are no longer discovered",
+		len(removed), containerInstanceArn)
+	if err := client.DeleteAttributes(containerInstanceArn, toECSAttributes(removed)); err != nil {
+		return err
+	}
+	for name := range removed {
+		delete(discoverer.registered, name)
+	}
+	return nil
+}
+
+// discover returns the valid attributes of all the providers, and whether none of them
+// failed. A provider that fails is logged and skipped, so that it doesn't prevent the
+// registration of the instance.
+func (discoverer *Discoverer) discover() (map[string]string, bool) {
+	attributes := make(map[string]string)
+	complete := true
+	for _, provider := range discoverer.providers {
+		discovered, err := provider.Attributes()
+		if err != nil {
+			seelog.Warnf("Unable to discover the instance attributes of provider %s: %v", provider.Name(), err)
+			complete = false
+			continue
+		}
+		for name, value := range discovered {
+			if !attributeNameRegex.MatchString(name) || !attributeValueRegex.MatchString(value) {
+				seelog.Warnf("Invalid instance attribute %q=%q of provider %s, it is ignored", name, value, provider.Name())
+				continue
+			}
+			if isReservedAttribute(name) {
+				seelog.Warnf("Instance attribute %q of provider %s has a reserved prefix, it is ignored", name, provider.Name())
+				continue
+			}
+			attributes[name] = value
+		}
+	}
+	return attributes, complete
+}
+
+// isReservedAttribute returns whether the attribute has the prefix of the attributes of
+// ECS or of the capabilities of the agent
+func isReservedAttribute(name string) bool {
+	for _, prefix := range reservedAttributePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// toECSAttributes returns the attributes sorted by name
+func toECSAttributes(attributes map[string]string) []*ecs.Attribute {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ecsAttributes := make([]*ecs.Attribute, 0, len(names))
+	for _, name := range names {
+		attribute := &ecs.Attribute{Name: aws.String(name)}
+		if value := attributes[name]; value != "" {
+			attribute.Value = aws.String(value)
+		}
+		ecsAttributes = append(ecsAttributes, attribute)
+	}
+	return ecsAttributes
+}
@@ -0,0 +1,167 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContainerInstanceArn = "arn:aws:ecs:us-west-2:123456789012:container-instance/test/0123456789"
+
+type fakeProvider struct {
+	attributes map[string]string
+	err        error
+}
+
+func (provider *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (provider *fakeProvider) Attributes() (map[string]string, error) {
+	return provider.attributes, provider.err
+}
+
+type fakeClient struct {
+	puts    [][]*ecs.Attribute
+	deletes [][]*ecs.Attribute
+	err     error
+}
+
+func (client *fakeClient) PutAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error {
+	if containerInstanceArn != testContainerInstanceArn {
+		return errors.New("unexpected container instance")
+	}
+	client.puts = append(client.puts, attributes)
+	return client.err
+}
+
+func (client *fakeClient) DeleteAttributes(containerInstanceArn string, attributes []*ecs.Attribute) error {
+	if containerInstanceArn != testContainerInstanceArn {
+		return errors.New("unexpected container instance")
+	}
+	client.deletes = append(client.deletes, attributes)
+	return client.err
+}
+
+func TestNewDiscoverer(t *testing.T) {
+	assert.Nil(t, NewDiscoverer(nil, ""))
+	assert.Nil(t, NewDiscoverer([]string{"unknown"}, ""))
+
+	discoverer := NewDiscoverer([]string{"kernel-version", "unknown", "nsenter"}, "/etc/ecs/attributes.d")
+	require.NotNil(t, discoverer)
+	var names []string
+	for _, provider := range discoverer.providers {
+		names = append(names, provider.Name())
+	}
+	assert.Equal(t, []string{"kernel-version", "nsenter", "plugins"}, names)
+}
+
+func TestDiscovererAttributes(t *testing.T) {
+	discoverer := newDiscoverer([]Provider{
+		&fakeProvider{attributes: map[string]string{
+			"host.kernel-version": "5.10.205-195.807.amzn2.x86_64",
+			"host.nsenter":        "",
+			"invalid name":        "value",
+			"host.invalid-value":  "a;b",
+			"ecs.capability.efs":  "",
+			"ecs.os-type":         "windows",
+			"com.amazonaws.ecs.capability.privileged-container": "",
+		}},
+		&fakeProvider{err: errors.New("discovery failed")},
+		&fakeProvider{attributes: map[string]string{"host.cuda-version": "12.2"}},
+	})
+
+	assert.Equal(t, []*ecs.Attribute{
+		{Name: aws.String("host.cuda-version"), Value: aws.String("12.2")},
+		{Name: aws.String("host.kernel-version"), Value: aws.String("5.10.205-195.807.amzn2.x86_64")},
+		{Name: aws.String("host.nsenter")},
+	}, discoverer.Attributes())
+}
+
+func TestDiscovererRefresh(t *testing.T) {
+	provider := &fakeProvider{attributes: map[string]string{
+		"host.kernel-version": "5.10.205",
+		"host.cuda-version":   "12.2",
+	}}
+	discoverer := newDiscoverer([]Provider{provider})
+	discoverer.Attributes()
+	client := &fakeClient{}
+
+	// nothing changed since the registration
+	require.NoError(t, discoverer.refresh(client, testContainerInstanceArn))
+	assert.Empty(t, client.puts)
+
+	provider.attributes = map[string]string{
+		"host.kernel-version": "6.1.0",
+		"host.nsenter":        "",
+	}
+	require.NoError(t, discoverer.refresh(client, testContainerInstanceArn))
+	require.Len(t, client.puts, 1)
+	assert.Equal(t, []*ecs.Attribute{
+		{Name: aws.String("host.kernel-version"), Value: aws.String("6.1.0")},
+		{Name: aws.String("host.nsenter")},
+	}, client.puts[0])
+
+	// the attribute that is no longer discovered is deleted
+	require.Len(t, client.deletes, 1)
+	assert.Equal(t, []*ecs.Attribute{{Name: aws.String("host.cuda-version")}}, client.deletes[0])
+
+	// the attributes that were put are registered, and the deleted ones aren't
+	require.NoError(t, discoverer.refresh(client, testContainerInstanceArn))
+	assert.Len(t, client.puts, 1)
+	assert.Len(t, client.deletes, 1)
+}
+
+func TestDiscovererRefreshKeepsAttributesOfFailedProviders(t *testing.T) {
+	nvme := &fakeProvider{attributes: map[string]string{"host.local-nvme-size": "1900"}}
+	plugin := &fakeProvider{attributes: map[string]string{"custom.rack": "r1"}}
+	discoverer := newDiscoverer([]Provider{nvme, plugin})
+	discoverer.Attributes()
+	client := &fakeClient{}
+
+	// the attributes of a provider that fails may still be there
+	plugin.attributes, plugin.err = nil, errors.New("plugin timed out")
+	nvme.attributes = nil
+	require.NoError(t, discoverer.refresh(client, testContainerInstanceArn))
+	assert.Empty(t, client.deletes)
+
+	plugin.attributes, plugin.err = map[string]string{"custom.rack": "r1"}, nil
+	require.NoError(t, discoverer.refresh(client, testContainerInstanceArn))
+	assert.Empty(t, client.puts)
+	require.Len(t, client.deletes, 1)
+	assert.Equal(t, []*ecs.Attribute{{Name: aws.String("host.local-nvme-size")}}, client.deletes[0])
+}
+
+func TestDiscovererRefreshError(t *testing.T) {
+	provider := &fakeProvider{attributes: map[string]string{"host.kernel-version": "5.10.205"}}
+	discoverer := newDiscoverer([]Provider{provider})
+	discoverer.Attributes()
+	client := &fakeClient{err: errors.New("throttled")}
+
+	provider.attributes = map[string]string{"host.kernel-version": "6.1.0"}
+	assert.Error(t, discoverer.refresh(client, testContainerInstanceArn))
+
+	// the attribute is put again on the next refresh
+	client.err = nil
+	require.NoError(t, discoverer.refresh(client, testContainerInstanceArn))
+	assert.Len(t, client.puts, 2)
+}
@@ -0,0 +1,117 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// pluginTimeout is how long a plugin can run before it is killed
+var pluginTimeout = 10 * time.Second
+
+// pluginsProvider runs the executables of a directory, in lexical order, and returns the
+// attributes they print on their standard output, one per line as name=value, or as a
+// name alone for an attribute without value. Empty lines and lines starting with # are
+// ignored. A plugin that fails is logged and skipped. On Linux the plugins run in the
+// container of the agent, which has no shell nor shared libraries, so they must be static
+// binaries and can only see the host through the mounts of the agent.
+type pluginsProvider struct {
+	dir string
+}
+
+func (provider *pluginsProvider) Name() string {
+	return "plugins"
+}
+
+func (provider *pluginsProvider) Attributes() (map[string]string, error) {
+	paths, err := provider.list()
+	if err != nil {
+		return nil, err
+	}
+	attributes := make(map[string]string)
+	for _, path := range paths {
+		output, err := runPlugin(path)
+		if err != nil {
+			seelog.Warnf("Instance attribute plugin %s failed: %v", path, err)
+			continue
+		}
+		for name, value := range parsePluginOutput(output) {
+			attributes[name] = value
+		}
+	}
+	return attributes, nil
+}
+
+// list returns the paths of the plugins. There are none when the directory doesn't exist.
+func (provider *pluginsProvider) list() ([]string, error) {
+	files, err := ioutil.ReadDir(provider.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var paths []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		// Windows has no executable permission, the plugins are run based on their extension
+		if runtime.GOOS != "windows" && file.Mode()&0111 == 0 {
+			continue
+		}
+		paths = append(paths, filepath.Join(provider.dir, file.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func runPlugin(path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path).Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, ctx.Err()
+	}
+	return output, err
+}
+
+func parsePluginOutput(output []byte) map[string]string {
+	attributes := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value := line, ""
+		if index := strings.Index(line, "="); index >= 0 {
+			name, value = strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+1:])
+		}
+		attributes[name] = value
+	}
+	return attributes
+}
@@ -0,0 +1,74 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) {
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), mode))
+}
+
+func TestPluginsProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writePlugin(t, dir, "10-storage", "echo '# local storage'\necho 'host.storage = nvme'\necho\necho host.raid", 0755)
+	writePlugin(t, dir, "20-override", "echo host.storage=ssd", 0755)
+	writePlugin(t, dir, "30-failing", "echo host.failing=true\nexit 1", 0755)
+	writePlugin(t, dir, "40-not-executable", "echo host.not-executable=true", 0644)
+	writePlugin(t, dir, ".hidden", "echo host.hidden=true", 0755)
+
+	provider := &pluginsProvider{dir: dir}
+	attributes, err := provider.Attributes()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"host.storage": "ssd",
+		"host.raid":    "",
+	}, attributes)
+}
+
+func TestPluginsProviderTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	original := pluginTimeout
+	defer func() { pluginTimeout = original }()
+	pluginTimeout = 100 * time.Millisecond
+
+	writePlugin(t, dir, "slow", "exec sleep 5", 0755)
+
+	provider := &pluginsProvider{dir: dir}
+	attributes, err := provider.Attributes()
+	require.NoError(t, err)
+	assert.Empty(t, attributes)
+}
+
+func TestPluginsProviderMissingDir(t *testing.T) {
+	provider := &pluginsProvider{dir: "/nonexistent/attributes.d"}
+	attributes, err := provider.Attributes()
+	assert.NoError(t, err)
+	assert.Empty(t, attributes)
+}
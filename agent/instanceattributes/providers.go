@@ -0,0 +1,91 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// Names of the attributes of the built-in providers
+const (
+	kernelVersionAttribute = "host.kernel-version"
+	cudaVersionAttribute   = "host.cuda-version"
+	localNVMeSizeAttribute = "host.local-nvme-size-gib"
+	nsenterAttribute       = "host.nsenter"
+)
+
+// builtinProviders are the built-in providers by the names they are enabled with in
+// ECS_INSTANCE_ATTRIBUTE_PROVIDERS
+var builtinProviders = map[string]Provider{
+	"kernel-version":  &providerFunc{name: "kernel-version", attributes: kernelVersion},
+	"cuda-version":    &providerFunc{name: "cuda-version", attributes: cudaVersion},
+	"local-nvme-size": &providerFunc{name: "local-nvme-size", attributes: localNVMeSize},
+	"nsenter":         &providerFunc{name: "nsenter", attributes: nsenter},
+}
+
+var (
+	// lookPath and runNvidiaSMI look up and run the binaries of the host, rather than the
+	// ones of the container the agent runs in
+	lookPath = lookHostPath
+	// nvidiaSMITimeout is how long nvidia-smi can take to query the driver
+	nvidiaSMITimeout = 10 * time.Second
+	runNvidiaSMI     = func(ctx context.Context, path string) ([]byte, error) {
+		return hostCommandOutput(ctx, path, "-q")
+	}
+	cudaVersionRegex = regexp.MustCompile(`(?m)^CUDA Version\s*:\s*(\S+)`)
+)
+
+// providerFunc is a provider whose attributes are discovered by a function
+type providerFunc struct {
+	name       string
+	attributes func() (map[string]string, error)
+}
+
+func (provider *providerFunc) Name() string {
+	return provider.name
+}
+
+func (provider *providerFunc) Attributes() (map[string]string, error) {
+	return provider.attributes()
+}
+
+// cudaVersion returns the highest CUDA version the NVIDIA driver supports, as reported
+// by nvidia-smi. There is no attribute when nvidia-smi isn't installed on the host.
+func cudaVersion() (map[string]string, error) {
+	path, err := lookPath("nvidia-smi")
+	if err != nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), nvidiaSMITimeout)
+	defer cancel()
+	output, err := runNvidiaSMI(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	match := cudaVersionRegex.FindSubmatch(output)
+	if match == nil {
+		return nil, nil
+	}
+	return map[string]string{cudaVersionAttribute: string(match[1])}, nil
+}
+
+// nsenter returns an attribute without value when nsenter is installed on the host
+func nsenter() (map[string]string, error) {
+	if _, err := lookPath("nsenter"); err != nil {
+		return nil, nil
+	}
+	return map[string]string{nsenterAttribute: ""}, nil
+}
@@ -0,0 +1,130 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// instanceStorageModel is the model of the NVMe instance store volumes
+	instanceStorageModel = "Amazon EC2 NVMe Instance Storage"
+	sectorSize           = 512
+	bytesPerGiB          = 1 << 30
+	// maxHostSymlinks is how many symbolic links are followed to find a binary of the host
+	maxHostSymlinks = 16
+)
+
+var (
+	// osReleasePath is where the kernel exposes its release
+	osReleasePath = "/proc/sys/kernel/osrelease"
+	// sysBlockPath is where the kernel exposes the block devices
+	sysBlockPath = "/sys/block"
+	// hostRootPath is the root file system of the host, seen through its init process, as
+	// the container the agent runs in has none of the binaries of the host
+	hostRootPath = "/host/proc/1/root"
+	// hostBinDirs are the directories the binaries of the host are looked up in
+	hostBinDirs = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+)
+
+// lookHostPath returns the path, on the host, of an executable of the host
+func lookHostPath(file string) (string, error) {
+	for _, dir := range hostBinDirs {
+		path := filepath.Join(dir, file)
+		info, err := statHostPath(path)
+		if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0 {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("executable file %s not found on the host", file)
+}
+
+// statHostPath returns the file info of a path of the host. The symbolic links are
+// followed from the root file system of the host, which the absolute links of the host
+// are relative to, rather than from the one of the agent.
+func statHostPath(path string) (os.FileInfo, error) {
+	for i := 0; i < maxHostSymlinks; i++ {
+		info, err := os.Lstat(filepath.Join(hostRootPath, path))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return info, err
+		}
+		target, err := os.Readlink(filepath.Join(hostRootPath, path))
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return nil, fmt.Errorf("too many levels of symbolic links in %s", path)
+}
+
+// hostCommandOutput runs an executable of the host with the root file system of the host,
+// so that it finds the libraries and devices of the host, and returns its output
+func hostCommandOutput(ctx context.Context, path string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = "/"
+	cmd.SysProcAttr = &syscall.SysProcAttr{Chroot: hostRootPath}
+	return cmd.Output()
+}
+
+// kernelVersion returns the release of the kernel, such as 5.10.205-195.807.amzn2.x86_64
+func kernelVersion() (map[string]string, error) {
+	release, err := ioutil.ReadFile(osReleasePath)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{kernelVersionAttribute: strings.TrimSpace(string(release))}, nil
+}
+
+// localNVMeSize returns the total size, in GiB, of the NVMe instance store volumes.
+// There is no attribute when the instance has none.
+func localNVMeSize() (map[string]string, error) {
+	devices, err := filepath.Glob(filepath.Join(sysBlockPath, "nvme*n*"))
+	if err != nil {
+		return nil, err
+	}
+	var totalBytes uint64
+	for _, device := range devices {
+		model, err := ioutil.ReadFile(filepath.Join(device, "device", "model"))
+		if err != nil || strings.TrimSpace(string(model)) != instanceStorageModel {
+			// partitions have no device directory, and EBS volumes are NVMe devices too
+			continue
+		}
+		size, err := ioutil.ReadFile(filepath.Join(device, "size"))
+		if err != nil {
+			return nil, err
+		}
+		sectors, err := strconv.ParseUint(strings.TrimSpace(string(size)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the size of %s: %v", filepath.Base(device), err)
+		}
+		totalBytes += sectors * sectorSize
+	}
+	if totalBytes == 0 {
+		return nil, nil
+	}
+	return map[string]string{localNVMeSizeAttribute: strconv.FormatUint(totalBytes/bytesPerGiB, 10)}, nil
+}
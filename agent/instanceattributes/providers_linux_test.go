@@ -0,0 +1,112 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKernelVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "osrelease")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	original := osReleasePath
+	defer func() { osReleasePath = original }()
+	osReleasePath = filepath.Join(dir, "osrelease")
+	require.NoError(t, ioutil.WriteFile(osReleasePath, []byte("5.10.205-195.807.amzn2.x86_64\n"), 0644))
+
+	attributes, err := kernelVersion()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{kernelVersionAttribute: "5.10.205-195.807.amzn2.x86_64"}, attributes)
+}
+
+func writeBlockDevice(t *testing.T, dir, name, model, size string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, name, "device"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name, "device", "model"), []byte(model+"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name, "size"), []byte(size+"\n"), 0644))
+}
+
+func TestLocalNVMeSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "block")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	original := sysBlockPath
+	defer func() { sysBlockPath = original }()
+	sysBlockPath = dir
+
+	attributes, err := localNVMeSize()
+	require.NoError(t, err)
+	assert.Empty(t, attributes)
+
+	// 2 instance store volumes of 220 GiB and an EBS volume
+	writeBlockDevice(t, dir, "nvme0n1", "Amazon Elastic Block Store", "16777216")
+	writeBlockDevice(t, dir, "nvme1n1", instanceStorageModel, "461373440")
+	writeBlockDevice(t, dir, "nvme2n1", instanceStorageModel, "461373440")
+
+	attributes, err = localNVMeSize()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{localNVMeSizeAttribute: "440"}, attributes)
+}
+
+func TestLocalNVMeSizeInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "block")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	original := sysBlockPath
+	defer func() { sysBlockPath = original }()
+	sysBlockPath = dir
+	writeBlockDevice(t, dir, "nvme1n1", instanceStorageModel, "invalid")
+
+	_, err = localNVMeSize()
+	assert.Error(t, err)
+}
+
+func TestLookHostPath(t *testing.T) {
+	root, err := ioutil.TempDir("", "hostroot")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	original := hostRootPath
+	defer func() { hostRootPath = original }()
+	hostRootPath = root
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "bin"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "opt", "nvidia", "bin"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "usr", "bin", "nsenter"), nil, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "usr", "bin", "notexec"), nil, 0644))
+	// absolute links are relative to the root file system of the host
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "opt", "nvidia", "bin", "nvidia-smi"), nil, 0755))
+	require.NoError(t, os.Symlink("/opt/nvidia/bin/nvidia-smi", filepath.Join(root, "usr", "bin", "nvidia-smi")))
+	require.NoError(t, os.Symlink("/opt/missing", filepath.Join(root, "usr", "bin", "dangling")))
+
+	path, err := lookHostPath("nsenter")
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/nsenter", path)
+
+	path, err = lookHostPath("nvidia-smi")
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/nvidia-smi", path)
+
+	for _, file := range []string{"notexec", "dangling", "missing"} {
+		_, err = lookHostPath(file)
+		assert.Error(t, err, file)
+	}
+}
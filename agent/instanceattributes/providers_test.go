@@ -0,0 +1,103 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nvidiaSMIOutput = `
+==============NVSMI LOG==============
+
+Timestamp                                 : Mon Jan 15 10:00:00 2024
+Driver Version                            : 535.104.05
+CUDA Version                              : 12.2
+
+Attached GPUs                             : 1
+`
+
+func mockLookPath(found bool) func() {
+	original := lookPath
+	lookPath = func(file string) (string, error) {
+		if !found {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+	return func() {
+		lookPath = original
+	}
+}
+
+func mockNvidiaSMI(output string, err error) func() {
+	original := runNvidiaSMI
+	runNvidiaSMI = func(ctx context.Context, path string) ([]byte, error) {
+		return []byte(output), err
+	}
+	return func() {
+		runNvidiaSMI = original
+	}
+}
+
+func TestCUDAVersion(t *testing.T) {
+	defer mockLookPath(true)()
+	defer mockNvidiaSMI(nvidiaSMIOutput, nil)()
+
+	attributes, err := cudaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{cudaVersionAttribute: "12.2"}, attributes)
+}
+
+func TestCUDAVersionUnavailable(t *testing.T) {
+	defer mockNvidiaSMI("Driver Version : 535.104.05", nil)()
+
+	restore := mockLookPath(false)
+	attributes, err := cudaVersion()
+	restore()
+	assert.NoError(t, err)
+	assert.Empty(t, attributes)
+
+	defer mockLookPath(true)()
+	attributes, err = cudaVersion()
+	assert.NoError(t, err)
+	assert.Empty(t, attributes)
+}
+
+func TestCUDAVersionError(t *testing.T) {
+	defer mockLookPath(true)()
+	defer mockNvidiaSMI("", errors.New("driver not loaded"))()
+
+	_, err := cudaVersion()
+	assert.Error(t, err)
+}
+
+func TestNsenter(t *testing.T) {
+	restore := mockLookPath(true)
+	attributes, err := nsenter()
+	restore()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{nsenterAttribute: ""}, attributes)
+
+	defer mockLookPath(false)()
+	attributes, err = nsenter()
+	assert.NoError(t, err)
+	assert.Empty(t, attributes)
+}
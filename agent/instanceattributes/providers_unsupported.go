@@ -0,0 +1,43 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instanceattributes
+
+import (
+	"context"
+	"os/exec"
+)
+
+// lookHostPath looks up an executable in the directories of PATH, as the agent runs on
+// the host
+func lookHostPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+// hostCommandOutput runs an executable and returns its output
+func hostCommandOutput(ctx context.Context, path string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, path, args...).Output()
+}
+
+// kernelVersion returns no attribute, the kernel version is only discovered on Linux
+func kernelVersion() (map[string]string, error) {
+	return nil, nil
+}
+
+// localNVMeSize returns no attribute, the NVMe instance store volumes are only
+// discovered on Linux
+func localNVMeSize() (map[string]string, error) {
+	return nil, nil
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit/request"
+	log "github.com/cihub/seelog"
 )
 
 type AuditLogger interface {
@@ -36,14 +37,23 @@ type auditLog struct {
 	containerInstanceArn string
 	cluster              string
 	logger               InfoLogger
-	cfg                  *config.Config
+	// jsonLogger writes the JSON records of credentials requests, if enabled
+	jsonLogger InfoLogger
+	cfg        *config.Config
 }
 
 func NewAuditLog(containerInstanceArn string, cfg *config.Config, logger InfoLogger) AuditLogger {
+	return NewAuditLogWithJSONRecords(containerInstanceArn, cfg, logger, nil)
+}
+
+// NewAuditLogWithJSONRecords creates an audit log that also writes a JSON record of
+// every credentials request to the jsonLogger, unless it is nil.
+func NewAuditLogWithJSONRecords(containerInstanceArn string, cfg *config.Config, logger InfoLogger, jsonLogger InfoLogger) AuditLogger {
 	return &auditLog{
 		cluster:              cfg.Cluster,
 		containerInstanceArn: containerInstanceArn,
 		logger:               logger,
+		jsonLogger:           jsonLogger,
 		cfg:                  cfg,
 	}
 }
@@ -57,6 +67,15 @@ func (a *auditLog) Log(r request.LogRequest, httpResponseCode int, eventType str
 
 		a.logger.Info(auditLogEntry)
 	}
+	if a.jsonLogger != nil && isGetCredentialsEventType(eventType) {
+		record, err := constructCredentialsAuditRecord(r, httpResponseCode, eventType, a.GetCluster(),
+			a.GetContainerInstanceArn())
+		if err != nil {
+			log.Warnf("Unable to construct the JSON audit record of credentials request: %v", err)
+			return
+		}
+		a.jsonLogger.Info(record)
+	}
 }
 
 func constructAuditLogEntry(r request.LogRequest, httpResponseCode int, eventType string,
@@ -100,3 +119,28 @@ func AuditLoggerConfig(cfg *config.Config) string {
 `
 	return config
 }
+
+// JSONAuditLoggerConfig returns the seelog config of the audit log of credentials
+// requests with JSON records, which is rotated like the agent log.
+func JSONAuditLoggerConfig(cfg *config.Config) string {
+	config := `
+<seelog type="asyncloop" minlevel="info">
+	<outputs formatid="main">`
+	if logger.Config.RolloverType == "size" {
+		config += `
+		<rollingfile filename="` + cfg.CredentialsAuditJSONLogFile + `" type="size"
+		 maxsize="` + strconv.Itoa(int(logger.Config.MaxFileSizeMB*1000000)) + `" archivetype="none" maxrolls="` + strconv.Itoa(logger.Config.MaxRollCount) + `" />`
+	} else {
+		config += `
+		<rollingfile filename="` + cfg.CredentialsAuditJSONLogFile + `" type="date"
+		 datepattern="2006-01-02-15" archivetype="none" maxrolls="` + strconv.Itoa(logger.Config.MaxRollCount) + `" />`
+	}
+	config += `
+	</outputs>
+	<formats>
+		<format id="main" format="%Msg%n" />
+	</formats>
+</seelog>
+`
+	return config
+}
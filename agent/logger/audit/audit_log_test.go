@@ -16,6 +16,7 @@
 package audit
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -164,6 +165,45 @@ func TestWritingToAuditLogWhenDisabled(t *testing.T) {
 	auditLogger.Log(request.LogRequest{Request: req, ARN: taskARN}, dummyResponseCode, GetCredentialsEventType(dummyRoleType))
 }
 
+func TestWritingJSONRecordsToAuditLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockInfoLogger := mock_infologger.NewMockInfoLogger(ctrl)
+	mockJSONLogger := mock_infologger.NewMockInfoLogger(ctrl)
+
+	req, _ := http.NewRequest("GET", dummyURLV2, nil)
+	req.RemoteAddr = dummyRemoteAddress
+
+	cfg := &config.Config{
+		Cluster:                     dummyCluster,
+		CredentialsAuditLogDisabled: true,
+		CredentialsAuditJSONLogFile: "foo.jsonl",
+	}
+
+	auditLogger := NewAuditLogWithJSONRecords(dummyContainerInstanceArn, cfg, mockInfoLogger, mockJSONLogger)
+
+	mockInfoLogger.EXPECT().Info(gomock.Any()).Times(0)
+	mockJSONLogger.EXPECT().Info(gomock.Any()).Do(func(record string) {
+		var parsed credentialsAuditRecord
+		assert.NoError(t, json.Unmarshal([]byte(record), &parsed))
+		assert.NotEmpty(t, parsed.EventTime)
+		assert.Equal(t, GetCredentialsEventType(dummyRoleType), parsed.EventType)
+		assert.Equal(t, http.StatusOK, parsed.ResponseCode)
+		assert.Equal(t, dummyRemoteAddress, parsed.SourceAddress)
+		assert.Equal(t, taskARN, parsed.TaskARN)
+		assert.Equal(t, "role-arn-1", parsed.RoleARN)
+		assert.Equal(t, "app", parsed.CallerContainer)
+		assert.Equal(t, dummyCluster, parsed.Cluster)
+		assert.Equal(t, dummyContainerInstanceArn, parsed.ContainerInstanceARN)
+	})
+
+	auditLogger.Log(request.LogRequest{Request: req, ARN: taskARN, RoleARN: "role-arn-1", Container: "app"},
+		http.StatusOK, GetCredentialsEventType(dummyRoleType))
+	// requests other than credentials requests, such as throttled ones, have no JSON record
+	auditLogger.Log(request.LogRequest{Request: req}, http.StatusTooManyRequests, "")
+}
+
 func TestConstructCommonAuditLogEntryFields(t *testing.T) {
 	req, _ := http.NewRequest("GET", "foo", nil)
 	req.RemoteAddr = dummyRemoteAddress
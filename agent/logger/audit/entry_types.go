@@ -14,6 +14,7 @@
 package audit
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -47,6 +48,19 @@ const (
 	getCredentialsAuditLogVersion = 2
 )
 
+// credentialsAuditRecord is the JSON record of a credentials request
+type credentialsAuditRecord struct {
+	EventTime            string `json:"EventTime"`
+	EventType            string `json:"EventType"`
+	ResponseCode         int    `json:"ResponseCode"`
+	SourceAddress        string `json:"SourceAddress"`
+	TaskARN              string `json:"TaskARN,omitempty"`
+	RoleARN              string `json:"RoleARN,omitempty"`
+	CallerContainer      string `json:"CallerContainer,omitempty"`
+	Cluster              string `json:"Cluster"`
+	ContainerInstanceARN string `json:"ContainerInstanceARN"`
+}
+
 type commonAuditLogEntryFields struct {
 	eventTime    string
 	responseCode int
@@ -101,6 +115,33 @@ func constructCommonAuditLogEntryFields(r request.LogRequest, httpResponseCode i
 	return fields.string()
 }
 
+// isGetCredentialsEventType returns true if the event type is the type of a
+// GetCredentials request
+func isGetCredentialsEventType(eventType string) bool {
+	switch eventType {
+	case getCredentialsEventType, getCredentialsTaskExecutionEventType, getCredentialsInvalidRoleTypeEventType:
+		return true
+	default:
+		return false
+	}
+}
+
+func constructCredentialsAuditRecord(r request.LogRequest, httpResponseCode int, eventType string,
+	cluster string, containerInstanceArn string) (string, error) {
+	record, err := json.Marshal(&credentialsAuditRecord{
+		EventTime:            time.Now().UTC().Format(time.RFC3339Nano),
+		EventType:            eventType,
+		ResponseCode:         httpResponseCode,
+		SourceAddress:        r.Request.RemoteAddr,
+		TaskARN:              r.ARN,
+		RoleARN:              r.RoleARN,
+		CallerContainer:      r.Container,
+		Cluster:              cluster,
+		ContainerInstanceARN: containerInstanceArn,
+	})
+	return string(record), err
+}
+
 func constructAuditLogEntryByType(eventType string, cluster string, containerInstanceArn string) string {
 	switch eventType {
 	case getCredentialsEventType:
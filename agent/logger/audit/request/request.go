@@ -18,4 +18,8 @@ import "net/http"
 type LogRequest struct {
 	Request *http.Request
 	ARN     string
+	// RoleARN is the ARN of the role of the credentials requested, if any
+	RoleARN string
+	// Container is the name of the task container that made the request, if known
+	Container string
 }
@@ -67,8 +67,15 @@ func platformLogConfig() string {
 			<custom name="wineventlog" formatid="windows" />`
 }
 
+// nativeLogDriverAvailable returns false, there is no journald or syslog on Windows
+func nativeLogDriverAvailable(driver string) bool { return false }
+
 // ReceiveMessage receives a log line from seelog and emits it to the Windows event log
 func (r *eventLogReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if message == "" {
+		// dropped by the formatter because of the module levels
+		return nil
+	}
 	switch level {
 	case seelog.DebugLvl, seelog.InfoLvl:
 		return eventLog.Info(eventLogID, message)
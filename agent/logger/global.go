@@ -33,8 +33,24 @@ func init() {
 func setGlobalLogger(logger seelog.LoggerInterface, logFormat string) {
 	loggerMux.Lock()
 	defer loggerMux.Unlock()
+	structuredLogger := newStructuredLogger(logFormat)
+	seelogger, err := newStructuredSeelogger(logger)
+	if err != nil {
+		seelog.Warnf("Structured log messages won't be attributed to their caller: %v", err)
+	}
+	structuredLogger.seelogger = seelogger
+
+	// the previous clone shares the outputs of the previous seelog logger, which are
+	// closed when that logger is replaced, so its messages are flushed first
+	previous := globalStructuredLogger
+	if previous != nil && previous.seelogger != nil {
+		previous.seelogger.Flush()
+	}
 	seelog.ReplaceLogger(logger)
-	globalStructuredLogger = newStructuredLogger(logFormat)
+	if previous != nil && previous.seelogger != nil {
+		previous.seelogger.Close()
+	}
+	globalStructuredLogger = structuredLogger
 }
 
 func getGlobalStructuredLogger() *structuredLogger {
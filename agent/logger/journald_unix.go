@@ -0,0 +1,121 @@
+// +build !windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cihub/seelog"
+)
+
+const (
+	// journaldIdentifier is the SYSLOG_IDENTIFIER of the agent's journal entries
+	journaldIdentifier = "ecs-agent"
+)
+
+// journaldSocket is the socket of the native journal protocol
+var journaldSocket = "/run/systemd/journal/socket"
+
+// journaldReceiver fulfills the seelog.CustomReceiver interface, and sends log messages
+// to journald with the native journal protocol, so that they are recorded with their
+// priority and source location
+type journaldReceiver struct {
+	conn      *net.UnixConn
+	closeOnce sync.Once
+}
+
+// journaldPriority maps the seelog levels to the syslog priorities used by journald
+func journaldPriority(level seelog.LogLevel) int {
+	switch level {
+	case seelog.TraceLvl, seelog.DebugLvl:
+		return 7
+	case seelog.InfoLvl:
+		return 6
+	case seelog.WarnLvl:
+		return 4
+	case seelog.ErrorLvl:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// ReceiveMessage receives a log line from seelog and sends it to journald
+func (r *journaldReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if message == "" {
+		// dropped by the formatter because of the module levels
+		return nil
+	}
+	buf := bufferPool.Get()
+	defer bufferPool.Put(buf)
+	appendJournaldField(buf, "MESSAGE", message)
+	appendJournaldField(buf, "PRIORITY", strconv.Itoa(journaldPriority(level)))
+	appendJournaldField(buf, "SYSLOG_IDENTIFIER", journaldIdentifier)
+	if context != nil && context.IsValid() {
+		appendJournaldField(buf, "CODE_FILE", context.FullPath())
+		appendJournaldField(buf, "CODE_LINE", strconv.Itoa(context.Line()))
+		appendJournaldField(buf, "CODE_FUNC", context.Func())
+	}
+	_, err := r.conn.Write(buf.Bytes())
+	return err
+}
+
+// appendJournaldField appends a field to a journal entry, with the binary encoding
+// of the native journal protocol for the values spanning several lines
+func appendJournaldField(buf *bytes.Buffer, name string, value string) {
+	if !strings.ContainsRune(value, '\n') {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func (r *journaldReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	return nil
+}
+
+func (r *journaldReceiver) Flush() {}
+
+// Close closes the connection to journald. It's called for both the global seelog
+// logger and the clone of it that the structured logger writes to, which share their
+// outputs, so only the first call closes the connection.
+func (r *journaldReceiver) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	var err error
+	r.closeOnce.Do(func() {
+		err = r.conn.Close()
+	})
+	return err
+}
@@ -0,0 +1,70 @@
+// +build !windows,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournaldReceiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "socket")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	defer func(original string) { journaldSocket = original }(journaldSocket)
+	journaldSocket = socket
+	receiver := &journaldReceiver{}
+	require.NoError(t, receiver.AfterParse(seelog.CustomReceiverInitArgs{}))
+	defer receiver.Close()
+
+	require.NoError(t, receiver.ReceiveMessage("This is my log message", seelog.WarnLvl, &LogContextMock{}))
+	entry := make([]byte, 4096)
+	n, err := listener.Read(entry)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE=This is my log message\nPRIORITY=4\nSYSLOG_IDENTIFIER=ecs-agent\n"+
+		"CODE_FILE=\nCODE_LINE=0\nCODE_FUNC=\n", string(entry[:n]))
+
+	require.NoError(t, receiver.ReceiveMessage("two\nlines", seelog.DebugLvl, nil))
+	n, err = listener.Read(entry)
+	require.NoError(t, err)
+	expected := bytes.NewBufferString("MESSAGE\n")
+	binary.Write(expected, binary.LittleEndian, uint64(len("two\nlines")))
+	expected.WriteString("two\nlines\nPRIORITY=7\nSYSLOG_IDENTIFIER=ecs-agent\n")
+	assert.Equal(t, expected.String(), string(entry[:n]))
+}
+
+func TestJournaldPriority(t *testing.T) {
+	assert.Equal(t, 7, journaldPriority(seelog.TraceLvl))
+	assert.Equal(t, 7, journaldPriority(seelog.DebugLvl))
+	assert.Equal(t, 6, journaldPriority(seelog.InfoLvl))
+	assert.Equal(t, 4, journaldPriority(seelog.WarnLvl))
+	assert.Equal(t, 3, journaldPriority(seelog.ErrorLvl))
+	assert.Equal(t, 2, journaldPriority(seelog.CriticalLvl))
+}
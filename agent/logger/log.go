@@ -14,6 +14,7 @@
 package logger
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"strconv"
@@ -25,41 +26,56 @@ import (
 )
 
 const (
-	LOGLEVEL_ENV_VAR             = "ECS_LOGLEVEL"
-	LOGLEVEL_ON_INSTANCE_ENV_VAR = "ECS_LOGLEVEL_ON_INSTANCE"
-	LOGFILE_ENV_VAR              = "ECS_LOGFILE"
-	LOG_DRIVER_ENV_VAR           = "ECS_LOG_DRIVER"
-	LOG_ROLLOVER_TYPE_ENV_VAR    = "ECS_LOG_ROLLOVER_TYPE"
-	LOG_OUTPUT_FORMAT_ENV_VAR    = "ECS_LOG_OUTPUT_FORMAT"
-	LOG_MAX_FILE_SIZE_ENV_VAR    = "ECS_LOG_MAX_FILE_SIZE_MB"
-	LOG_MAX_ROLL_COUNT_ENV_VAR   = "ECS_LOG_MAX_ROLL_COUNT"
+	LOGLEVEL_ENV_VAR               = "ECS_LOGLEVEL"
+	LOGLEVEL_ON_INSTANCE_ENV_VAR   = "ECS_LOGLEVEL_ON_INSTANCE"
+	LOGFILE_ENV_VAR                = "ECS_LOGFILE"
+	LOG_DRIVER_ENV_VAR             = "ECS_LOG_DRIVER"
+	LOG_ROLLOVER_TYPE_ENV_VAR      = "ECS_LOG_ROLLOVER_TYPE"
+	LOG_OUTPUT_FORMAT_ENV_VAR      = "ECS_LOG_OUTPUT_FORMAT"
+	LOG_MAX_FILE_SIZE_ENV_VAR      = "ECS_LOG_MAX_FILE_SIZE_MB"
+	LOG_MAX_ROLL_COUNT_ENV_VAR     = "ECS_LOG_MAX_ROLL_COUNT"
+	LOGLEVEL_PER_MODULE_ENV_VAR    = "ECS_LOGLEVEL_PER_MODULE"
+	LOG_SAMPLING_THRESHOLD_ENV_VAR = "ECS_LOG_SAMPLING_THRESHOLD"
+	LOG_SAMPLING_INTERVAL_ENV_VAR  = "ECS_LOG_SAMPLING_INTERVAL"
 
 	logFmt  = "logfmt"
 	jsonFmt = "json"
 
+	logDriverJournald = "journald"
+	logDriverSyslog   = "syslog"
+	logDriverFile     = "file"
+
 	DEFAULT_LOGLEVEL                         = "info"
 	DEFAULT_LOGLEVEL_WHEN_DRIVER_SET         = "off"
 	DEFAULT_ROLLOVER_TYPE                    = "date"
 	DEFAULT_OUTPUT_FORMAT                    = logFmt
 	DEFAULT_MAX_FILE_SIZE            float64 = 10
 	DEFAULT_MAX_ROLL_COUNT           int     = 24
+	DEFAULT_SAMPLING_THRESHOLD       int     = 0
+	DEFAULT_SAMPLING_INTERVAL                = time.Minute
 )
 
 type logConfig struct {
-	RolloverType  string
-	MaxRollCount  int
-	MaxFileSizeMB float64
-	logfile       string
-	driverLevel   string
-	instanceLevel string
-	outputFormat  string
-	lock          sync.Mutex
+	RolloverType      string
+	MaxRollCount      int
+	MaxFileSizeMB     float64
+	SamplingThreshold int
+	SamplingInterval  time.Duration
+	logfile           string
+	driver            string
+	driverLevel       string
+	instanceLevel     string
+	outputFormat      string
+	lock              sync.Mutex
 }
 
 var Config *logConfig
 
 func ecsMsgFormatter(params string) seelog.FormatterFunc {
 	return func(message string, level seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+		if !moduleLevelEnabled(params, level, context) {
+			return ""
+		}
 		buf := bufferPool.Get()
 		defer bufferPool.Put(buf)
 		// temporary measure to make this change backwards compatible as we update to structured logs
@@ -80,58 +96,88 @@ func ecsMsgFormatter(params string) seelog.FormatterFunc {
 }
 
 func logfmtFormatter(params string) seelog.FormatterFunc {
+	sampler := newLogSampler(params)
 	return func(message string, level seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+		if !moduleLevelEnabled(params, level, context) {
+			return ""
+		}
+		write, summaries := sampler.sample(message, level, context)
+		if !write {
+			return ""
+		}
 		buf := bufferPool.Get()
 		defer bufferPool.Put(buf)
-		buf.WriteString("level=")
-		buf.WriteString(level.String())
-		buf.WriteByte(' ')
-		buf.WriteString("time=")
-		buf.WriteString(context.CallTime().UTC().Format(time.RFC3339))
-		buf.WriteByte(' ')
-		// temporary measure to make this change backwards compatible as we update to structured logs
-		if strings.HasPrefix(message, structuredTxtFormatPrefix) {
-			message = strings.TrimPrefix(message, structuredTxtFormatPrefix)
-			buf.WriteString(message)
-		} else {
-			buf.WriteString("msg=")
-			buf.WriteString(fmt.Sprintf("%q", message))
-			buf.WriteByte(' ')
-			buf.WriteString("module=")
-			buf.WriteString(context.FileName())
+		for _, summary := range summaries {
+			writeLogfmt(buf, summary.message(), summary.level, summary.context)
 		}
-		buf.WriteByte('\n')
+		writeLogfmt(buf, message, level, context)
 		return buf.String()
 	}
 }
 
+func writeLogfmt(buf *bytes.Buffer, message string, level seelog.LogLevel, context seelog.LogContextInterface) {
+	buf.WriteString("level=")
+	buf.WriteString(level.String())
+	buf.WriteByte(' ')
+	buf.WriteString("time=")
+	buf.WriteString(context.CallTime().UTC().Format(time.RFC3339))
+	buf.WriteByte(' ')
+	// temporary measure to make this change backwards compatible as we update to structured logs
+	if strings.HasPrefix(message, structuredTxtFormatPrefix) {
+		message = strings.TrimPrefix(message, structuredTxtFormatPrefix)
+		buf.WriteString(message)
+	} else {
+		buf.WriteString("msg=")
+		buf.WriteString(fmt.Sprintf("%q", message))
+		buf.WriteByte(' ')
+		buf.WriteString("module=")
+		buf.WriteString(context.FileName())
+	}
+	buf.WriteByte('\n')
+}
+
 func jsonFormatter(params string) seelog.FormatterFunc {
+	sampler := newLogSampler(params)
 	return func(message string, level seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+		if !moduleLevelEnabled(params, level, context) {
+			return ""
+		}
+		write, summaries := sampler.sample(message, level, context)
+		if !write {
+			return ""
+		}
 		buf := bufferPool.Get()
 		defer bufferPool.Put(buf)
-		buf.WriteString(`{"level":"`)
-		buf.WriteString(level.String())
-		buf.WriteString(`","time":"`)
-		buf.WriteString(context.CallTime().UTC().Format(time.RFC3339))
-		buf.WriteString(`",`)
-		// temporary measure to make this change backwards compatible as we update to structured logs
-		if strings.HasPrefix(message, structuredJsonFormatPrefix) {
-			message = strings.TrimPrefix(message, structuredJsonFormatPrefix)
-			message = strings.TrimRight(message, ",")
-			buf.WriteString(message)
-			buf.WriteByte('}')
-		} else {
-			buf.WriteString(`"msg":`)
-			buf.WriteString(fmt.Sprintf("%q", message))
-			buf.WriteString(`,"module":"`)
-			buf.WriteString(context.FileName())
-			buf.WriteString(`"}`)
+		for _, summary := range summaries {
+			writeJSON(buf, summary.message(), summary.level, summary.context)
 		}
-		buf.WriteByte('\n')
+		writeJSON(buf, message, level, context)
 		return buf.String()
 	}
 }
 
+func writeJSON(buf *bytes.Buffer, message string, level seelog.LogLevel, context seelog.LogContextInterface) {
+	buf.WriteString(`{"level":"`)
+	buf.WriteString(level.String())
+	buf.WriteString(`","time":"`)
+	buf.WriteString(context.CallTime().UTC().Format(time.RFC3339))
+	buf.WriteString(`",`)
+	// temporary measure to make this change backwards compatible as we update to structured logs
+	if strings.HasPrefix(message, structuredJsonFormatPrefix) {
+		message = strings.TrimPrefix(message, structuredJsonFormatPrefix)
+		message = strings.TrimRight(message, ",")
+		buf.WriteString(message)
+		buf.WriteByte('}')
+	} else {
+		buf.WriteString(`"msg":`)
+		buf.WriteString(fmt.Sprintf("%q", message))
+		buf.WriteString(`,"module":"`)
+		buf.WriteString(context.FileName())
+		buf.WriteString(`"}`)
+	}
+	buf.WriteByte('\n')
+}
+
 func reloadConfig() {
 	logger, err := seelog.LoggerFromConfigAsString(seelogConfig())
 	if err != nil {
@@ -145,14 +191,14 @@ func seelogConfig() string {
 	c := `
 <seelog type="asyncloop">
 	<outputs formatid="` + Config.outputFormat + `">
-		<filter levels="` + getLevelList(Config.driverLevel) + `">
-			<console />`
+		<filter levels="` + getLevelList(outputFilterLevel(Config.driverLevel)) + `"` + outputFormatID("driver") + `>`
+	c += driverOutputConfig()
 	c += platformLogConfig()
 	c += `
 		</filter>`
 	if Config.logfile != "" {
 		c += `
-		<filter levels="` + getLevelList(Config.instanceLevel) + `">`
+		<filter levels="` + getLevelList(outputFilterLevel(Config.instanceLevel)) + `"` + outputFormatID("instance") + `>`
 		if Config.RolloverType == "size" {
 			c += `
 			<rollingfile filename="` + Config.logfile + `" type="size"
@@ -170,13 +216,70 @@ func seelogConfig() string {
 	<formats>
 		<format id="` + logFmt + `" format="%EcsAgentLogfmt" />
 		<format id="` + jsonFmt + `" format="%EcsAgentJson" />
-		<format id="windows" format="%EcsMsg" />
+		<format id="windows" format="%EcsMsg` + moduleLevelsFormatParams(Config.driverLevel) + `" />`
+	if usesNativeLogDriver() {
+		// journald and syslog record the level and time themselves
+		c += `
+		<format id="native" format="%EcsMsg` + moduleLevelsFormatParams(Config.driverLevel) + `" />`
+	}
+	if usesOutputFormats() {
+		// each output gets its own formatter, with the level of the output, so that
+		// messages are dropped below the level of their module, or of their output,
+		// and are sampled separately for each output
+		formatters := map[string]string{logFmt: "%EcsAgentLogfmt", jsonFmt: "%EcsAgentJson"}
+		c += `
+		<format id="` + Config.outputFormat + `-driver" format="` + formatters[Config.outputFormat] + `(` + Config.driverLevel + `)" />
+		<format id="` + Config.outputFormat + `-instance" format="` + formatters[Config.outputFormat] + `(` + Config.instanceLevel + `)" />`
+	}
+	c += `
 	</formats>
 </seelog>`
 
 	return c
 }
 
+// usesNativeLogDriver returns whether the agent writes its logs directly to journald
+// or syslog, instead of the console, for its log driver
+func usesNativeLogDriver() bool {
+	return (Config.driver == logDriverJournald || Config.driver == logDriverSyslog) &&
+		nativeLogDriverAvailable(Config.driver)
+}
+
+// driverOutputConfig returns the receiver of the log driver output, which is the
+// console unless the agent can write to journald or syslog directly
+func driverOutputConfig() string {
+	if usesNativeLogDriver() {
+		return `
+			<custom name="` + Config.driver + `" formatid="native" />`
+	}
+	return `
+			<console />`
+}
+
+// usesOutputFormats returns whether each output needs its own formatter, for module
+// levels or log sampling
+func usesOutputFormats() bool {
+	return hasModuleLevels() || Config.SamplingThreshold > 0
+}
+
+// outputFormatID returns the formatid attribute of the filter of an output when it
+// has its own formatter
+func outputFormatID(output string) string {
+	if !usesOutputFormats() {
+		return ""
+	}
+	return ` formatid="` + Config.outputFormat + `-` + output + `"`
+}
+
+// moduleLevelsFormatParams returns the parameters of the formatter of an output when
+// module levels are in use
+func moduleLevelsFormatParams(outputLevel string) string {
+	if !hasModuleLevels() {
+		return ""
+	}
+	return `(` + outputLevel + `)`
+}
+
 func getLevelList(fileLevel string) string {
 	levelLists := map[string]string{
 		"debug":    "debug,info,warn,error,critical",
@@ -189,8 +292,8 @@ func getLevelList(fileLevel string) string {
 	return levelLists[fileLevel]
 }
 
-// SetLevel sets the log levels for logging
-func SetLevel(driverLogLevel, instanceLogLevel string) {
+// parseLevel converts a log level of the agent configuration to a seelog level
+func parseLevel(level string) (string, bool) {
 	levels := map[string]string{
 		"debug": "debug",
 		"info":  "info",
@@ -199,9 +302,25 @@ func SetLevel(driverLogLevel, instanceLogLevel string) {
 		"crit":  "critical",
 		"none":  "off",
 	}
+	parsedLevel, ok := levels[strings.ToLower(strings.TrimSpace(level))]
+	return parsedLevel, ok
+}
+
+// levelName converts a seelog level back to a log level of the agent configuration
+func levelName(level string) string {
+	switch level {
+	case "critical":
+		return "crit"
+	case "off":
+		return "none"
+	}
+	return level
+}
 
-	parsedDriverLevel, driverOk := levels[strings.ToLower(driverLogLevel)]
-	parsedInstanceLevel, instanceOk := levels[strings.ToLower(instanceLogLevel)]
+// SetLevel sets the log levels for logging
+func SetLevel(driverLogLevel, instanceLogLevel string) {
+	parsedDriverLevel, driverOk := parseLevel(driverLogLevel)
+	parsedInstanceLevel, instanceOk := parseLevel(instanceLogLevel)
 
 	if instanceOk || driverOk {
 		Config.lock.Lock()
@@ -224,8 +343,99 @@ func GetLevel() string {
 	return Config.driverLevel
 }
 
+// LogFile returns the path of the on-instance log file, which is empty when the
+// logs aren't written to a file
+func LogFile() string {
+	Config.lock.Lock()
+	defer Config.lock.Unlock()
+
+	return Config.logfile
+}
+
+// Levels returns the log levels of the log driver output, of the on-instance log file
+// and of the modules, with the level names of ECS_LOGLEVEL
+func Levels() (string, string, map[string]string) {
+	Config.lock.Lock()
+	driverLevel, instanceLevel := Config.driverLevel, Config.instanceLevel
+	Config.lock.Unlock()
+
+	moduleLevelsLock.RLock()
+	defer moduleLevelsLock.RUnlock()
+	modules := make(map[string]string, len(moduleLevels))
+	for module, level := range moduleLevels {
+		modules[module] = levelName(level)
+	}
+	return levelName(driverLevel), levelName(instanceLevel), modules
+}
+
+// SetLevels changes the log levels at runtime. Empty output levels are left unchanged,
+// and nil module levels leave the module levels unchanged, while empty module levels
+// remove them. Nothing is changed if any of the levels is invalid.
+func SetLevels(driverLogLevel, instanceLogLevel string, modules map[string]string) error {
+	var parsedDriverLevel, parsedInstanceLevel string
+	var ok bool
+	if driverLogLevel != "" {
+		if parsedDriverLevel, ok = parseLevel(driverLogLevel); !ok {
+			return fmt.Errorf("invalid log level %q", driverLogLevel)
+		}
+	}
+	if instanceLogLevel != "" {
+		if parsedInstanceLevel, ok = parseLevel(instanceLogLevel); !ok {
+			return fmt.Errorf("invalid log level %q", instanceLogLevel)
+		}
+	}
+	var parsedModuleLevels map[string]string
+	if modules != nil {
+		parsedModuleLevels = make(map[string]string, len(modules))
+		for module, level := range modules {
+			parsedLevel, ok := parseLevel(level)
+			if module == "" || !ok {
+				return fmt.Errorf("invalid log level %q for module %q", level, module)
+			}
+			parsedModuleLevels[module] = parsedLevel
+		}
+	}
+
+	Config.lock.Lock()
+	defer Config.lock.Unlock()
+	if parsedDriverLevel != "" {
+		Config.driverLevel = parsedDriverLevel
+	}
+	if parsedInstanceLevel != "" {
+		Config.instanceLevel = parsedInstanceLevel
+	}
+	if parsedModuleLevels != nil {
+		moduleLevelsLock.Lock()
+		moduleLevels = parsedModuleLevels
+		moduleLevelsLock.Unlock()
+	}
+	reloadConfig()
+	return nil
+}
+
+// ReloadLevels changes the log levels at runtime to new values of ECS_LOGLEVEL and
+// ECS_LOGLEVEL_ON_INSTANCE. Empty levels default as they do when the agent starts: the
+// level on the instance follows the log level, unless the logs go to another log driver.
+// Nothing is changed if any of the levels is invalid.
+func ReloadLevels(logLevel, instanceLogLevel string) error {
+	if logLevel == "" {
+		logLevel = DEFAULT_LOGLEVEL
+	}
+	if instanceLogLevel == "" {
+		Config.lock.Lock()
+		driver := Config.driver
+		Config.lock.Unlock()
+		if driver != "" && driver != logDriverFile {
+			instanceLogLevel = levelName(DEFAULT_LOGLEVEL_WHEN_DRIVER_SET)
+		} else {
+			instanceLogLevel = logLevel
+		}
+	}
+	return SetLevels(logLevel, instanceLogLevel, nil)
+}
+
 func setInstanceLevelDefault() string {
-	if logDriver := os.Getenv(LOG_DRIVER_ENV_VAR); logDriver != "" {
+	if logDriver := os.Getenv(LOG_DRIVER_ENV_VAR); logDriver != "" && logDriver != logDriverFile {
 		return DEFAULT_LOGLEVEL_WHEN_DRIVER_SET
 	}
 	if loglevel := os.Getenv(LOGLEVEL_ENV_VAR); loglevel != "" {
@@ -236,13 +446,16 @@ func setInstanceLevelDefault() string {
 
 func init() {
 	Config = &logConfig{
-		logfile:       os.Getenv(LOGFILE_ENV_VAR),
-		driverLevel:   DEFAULT_LOGLEVEL,
-		instanceLevel: setInstanceLevelDefault(),
-		RolloverType:  DEFAULT_ROLLOVER_TYPE,
-		outputFormat:  DEFAULT_OUTPUT_FORMAT,
-		MaxFileSizeMB: DEFAULT_MAX_FILE_SIZE,
-		MaxRollCount:  DEFAULT_MAX_ROLL_COUNT,
+		logfile:           os.Getenv(LOGFILE_ENV_VAR),
+		driver:            os.Getenv(LOG_DRIVER_ENV_VAR),
+		driverLevel:       DEFAULT_LOGLEVEL,
+		instanceLevel:     setInstanceLevelDefault(),
+		RolloverType:      DEFAULT_ROLLOVER_TYPE,
+		outputFormat:      DEFAULT_OUTPUT_FORMAT,
+		MaxFileSizeMB:     DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:      DEFAULT_MAX_ROLL_COUNT,
+		SamplingThreshold: DEFAULT_SAMPLING_THRESHOLD,
+		SamplingInterval:  DEFAULT_SAMPLING_INTERVAL,
 	}
 }
 
@@ -282,6 +495,33 @@ func InitSeelog() {
 		}
 	}
 
+	if SamplingThreshold := os.Getenv(LOG_SAMPLING_THRESHOLD_ENV_VAR); SamplingThreshold != "" {
+		i, err := strconv.Atoi(SamplingThreshold)
+		if err == nil {
+			Config.SamplingThreshold = i
+		} else {
+			seelog.Error("Invalid value for "+LOG_SAMPLING_THRESHOLD_ENV_VAR, err)
+		}
+	}
+	if SamplingInterval := os.Getenv(LOG_SAMPLING_INTERVAL_ENV_VAR); SamplingInterval != "" {
+		d, err := time.ParseDuration(SamplingInterval)
+		if err == nil && d > 0 {
+			Config.SamplingInterval = d
+		} else {
+			seelog.Error("Invalid value for "+LOG_SAMPLING_INTERVAL_ENV_VAR, err)
+		}
+	}
+	if moduleLevelsValue := os.Getenv(LOGLEVEL_PER_MODULE_ENV_VAR); moduleLevelsValue != "" {
+		levels, err := parseModuleLevels(moduleLevelsValue)
+		if err == nil {
+			moduleLevelsLock.Lock()
+			moduleLevels = levels
+			moduleLevelsLock.Unlock()
+		} else {
+			seelog.Error("Invalid value for "+LOGLEVEL_PER_MODULE_ENV_VAR, err)
+		}
+	}
+
 	registerPlatformLogger()
 	reloadConfig()
 }
@@ -0,0 +1,126 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// logSampler collapses the identical messages written to an output more than a
+// threshold number of times per interval. The repetitions above the threshold are
+// dropped, and counted in a summary written before the first message of the next
+// interval.
+type logSampler struct {
+	threshold   int
+	interval    time.Duration
+	windowStart time.Time
+	messages    map[sampledMessageKey]*sampledMessage
+	lock        sync.Mutex
+}
+
+type sampledMessageKey struct {
+	level   seelog.LogLevel
+	message string
+}
+
+type sampledMessage struct {
+	count int
+	// context is the context of the last dropped repetition of the message
+	context seelog.LogContextInterface
+}
+
+// sampledSummary summarizes the repetitions of a message dropped during an interval
+type sampledSummary struct {
+	level      seelog.LogLevel
+	original   string
+	suppressed int
+	interval   time.Duration
+	context    seelog.LogContextInterface
+}
+
+// newLogSampler returns the sampler of the formatter of an output, or nil if log
+// sampling is disabled or the formatter is shared by several outputs, in which case
+// the formatter has no parameters
+func newLogSampler(params string) *logSampler {
+	if params == "" || Config.SamplingThreshold <= 0 {
+		return nil
+	}
+	interval := Config.SamplingInterval
+	if interval <= 0 {
+		interval = DEFAULT_SAMPLING_INTERVAL
+	}
+	return &logSampler{
+		threshold: Config.SamplingThreshold,
+		interval:  interval,
+		messages:  make(map[sampledMessageKey]*sampledMessage),
+	}
+}
+
+// sample returns whether a message should be written, and the summaries of the
+// messages dropped during the previous interval when a new interval starts
+func (s *logSampler) sample(message string, level seelog.LogLevel, context seelog.LogContextInterface) (bool, []sampledSummary) {
+	if s == nil {
+		return true, nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var summaries []sampledSummary
+	now := context.CallTime()
+	if now.Sub(s.windowStart) >= s.interval || now.Before(s.windowStart) {
+		for key, m := range s.messages {
+			if m.count > s.threshold {
+				summaries = append(summaries, sampledSummary{
+					level:      key.level,
+					original:   key.message,
+					suppressed: m.count - s.threshold,
+					interval:   s.interval,
+					context:    m.context,
+				})
+			}
+		}
+		sort.Slice(summaries, func(i, j int) bool {
+			return summaries[i].context.CallTime().Before(summaries[j].context.CallTime())
+		})
+		s.messages = make(map[sampledMessageKey]*sampledMessage)
+		s.windowStart = now
+	}
+
+	key := sampledMessageKey{level: level, message: message}
+	m, ok := s.messages[key]
+	if !ok {
+		m = &sampledMessage{}
+		s.messages[key] = m
+	}
+	m.count++
+	if m.count > s.threshold {
+		m.context = context
+		return false, summaries
+	}
+	return true, summaries
+}
+
+// message returns the summary line of the dropped repetitions of a message
+func (summary sampledSummary) message() string {
+	original := strings.TrimPrefix(summary.original, structuredTxtFormatPrefix)
+	original = strings.TrimPrefix(original, structuredJsonFormatPrefix)
+	return fmt.Sprintf("Suppressed %d repetitions in %s of message: %s",
+		summary.suppressed, summary.interval, original)
+}
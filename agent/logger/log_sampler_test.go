@@ -0,0 +1,125 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timedLogContextMock struct {
+	LogContextMock
+	callTime time.Time
+}
+
+func (l *timedLogContextMock) CallTime() time.Time {
+	return l.callTime
+}
+
+func setSamplingConfig(threshold int, interval time.Duration) {
+	Config = &logConfig{
+		driverLevel:       DEFAULT_LOGLEVEL,
+		instanceLevel:     DEFAULT_LOGLEVEL,
+		RolloverType:      DEFAULT_ROLLOVER_TYPE,
+		outputFormat:      DEFAULT_OUTPUT_FORMAT,
+		MaxFileSizeMB:     DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:      DEFAULT_MAX_ROLL_COUNT,
+		SamplingThreshold: threshold,
+		SamplingInterval:  interval,
+	}
+}
+
+func TestLogSampler(t *testing.T) {
+	setSamplingConfig(2, time.Minute)
+	sampler := newLogSampler("info")
+	require.NotNil(t, sampler)
+	start := time.Date(2018, time.October, 1, 1, 2, 3, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		write, summaries := sampler.sample("container exited", seelog.WarnLvl,
+			&timedLogContextMock{callTime: start.Add(time.Duration(i) * time.Second)})
+		assert.Equal(t, i < 2, write, "repetition %d", i)
+		assert.Empty(t, summaries)
+	}
+	write, _ := sampler.sample("another message", seelog.WarnLvl, &timedLogContextMock{callTime: start.Add(10 * time.Second)})
+	assert.True(t, write, "other messages should not be sampled with the repeated one")
+
+	write, summaries := sampler.sample("container exited", seelog.WarnLvl, &timedLogContextMock{callTime: start.Add(time.Minute)})
+	assert.True(t, write, "the message should be written again in the next interval")
+	require.Len(t, summaries, 1)
+	assert.Equal(t, seelog.LogLevel(seelog.WarnLvl), summaries[0].level)
+	assert.Equal(t, 3, summaries[0].suppressed)
+	assert.Equal(t, start.Add(4*time.Second), summaries[0].context.CallTime())
+	assert.Equal(t, "Suppressed 3 repetitions in 1m0s of message: container exited", summaries[0].message())
+}
+
+func TestLogSamplerDisabled(t *testing.T) {
+	setSamplingConfig(0, time.Minute)
+	assert.Nil(t, newLogSampler("info"))
+
+	setSamplingConfig(2, time.Minute)
+	assert.Nil(t, newLogSampler(""), "shared formatters should not sample")
+
+	var sampler *logSampler
+	write, summaries := sampler.sample("message", seelog.InfoLvl, &LogContextMock{})
+	assert.True(t, write)
+	assert.Empty(t, summaries)
+}
+
+func TestLogfmtFormat_Sampling(t *testing.T) {
+	setSamplingConfig(1, time.Minute)
+	logfmt := logfmtFormatter("info")
+	start := time.Date(2018, time.October, 1, 1, 2, 3, 0, time.UTC)
+
+	out := logfmt("This is my log message", seelog.WarnLvl, &timedLogContextMock{callTime: start})
+	assert.Equal(t, `level=warn time=2018-10-01T01:02:03Z msg="This is my log message" module=mytestmodule.go
+`, out)
+	out = logfmt("This is my log message", seelog.WarnLvl, &timedLogContextMock{callTime: start.Add(time.Second)})
+	assert.Equal(t, "", out)
+
+	fm := defaultStructuredTextFormatter.Format("This is my log message", Fields{"task": "t1"})
+	out = logfmt(fm, seelog.WarnLvl, &timedLogContextMock{callTime: start.Add(2 * time.Second)})
+	assert.Equal(t, "level=warn time=2018-10-01T01:02:05Z msg=\"This is my log message\" task=\"t1\"\n", out)
+	out = logfmt(fm, seelog.WarnLvl, &timedLogContextMock{callTime: start.Add(3 * time.Second)})
+	assert.Equal(t, "", out)
+
+	out = logfmt("This is my log message", seelog.WarnLvl, &timedLogContextMock{callTime: start.Add(time.Minute)})
+	lines := strings.Split(strings.TrimSuffix(out.(string), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, `level=warn time=2018-10-01T01:02:04Z msg="Suppressed 1 repetitions in 1m0s of message: This is my log message" module=mytestmodule.go`, lines[0])
+	assert.Equal(t, `level=warn time=2018-10-01T01:02:06Z msg="Suppressed 1 repetitions in 1m0s of message: msg=\"This is my log message\" task=\"t1\"" module=mytestmodule.go`, lines[1])
+	assert.Equal(t, `level=warn time=2018-10-01T01:03:03Z msg="This is my log message" module=mytestmodule.go`, lines[2])
+}
+
+func TestJSONFormat_Sampling(t *testing.T) {
+	setSamplingConfig(1, time.Minute)
+	jsonF := jsonFormatter("info")
+	start := time.Date(2018, time.October, 1, 1, 2, 3, 0, time.UTC)
+
+	jsonF("This is my log message", seelog.ErrorLvl, &timedLogContextMock{callTime: start})
+	assert.Equal(t, "", jsonF("This is my log message", seelog.ErrorLvl, &timedLogContextMock{callTime: start.Add(time.Second)}))
+
+	out := jsonF("This is my log message", seelog.ErrorLvl, &timedLogContextMock{callTime: start.Add(time.Minute)})
+	lines := strings.Split(strings.TrimSuffix(out.(string), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"level": "error", "time": "2018-10-01T01:02:04Z", "msg": "Suppressed 1 repetitions in 1m0s of message: This is my log message", "module": "mytestmodule.go"}`, lines[0])
+	assert.JSONEq(t, `{"level": "error", "time": "2018-10-01T01:03:03Z", "msg": "This is my log message", "module": "mytestmodule.go"}`, lines[1])
+}
@@ -267,3 +267,73 @@ func (l *LogContextMock) CallTime() time.Time {
 func (l *LogContextMock) CustomContext() interface{} {
 	return map[string]string{}
 }
+
+func TestSetLevels(t *testing.T) {
+	Config = &logConfig{
+		driverLevel:   DEFAULT_LOGLEVEL,
+		instanceLevel: "off",
+		RolloverType:  DEFAULT_ROLLOVER_TYPE,
+		outputFormat:  DEFAULT_OUTPUT_FORMAT,
+		MaxFileSizeMB: DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:  DEFAULT_MAX_ROLL_COUNT,
+	}
+	defer func() { moduleLevels = nil }()
+
+	require.NoError(t, SetLevels("debug", "", map[string]string{"dockerapi": "crit"}))
+	driverLevel, instanceLevel, modules := Levels()
+	require.Equal(t, "debug", driverLevel)
+	require.Equal(t, "none", instanceLevel)
+	require.Equal(t, map[string]string{"dockerapi": "crit"}, modules)
+
+	require.NoError(t, SetLevels("", "warn", nil))
+	driverLevel, instanceLevel, modules = Levels()
+	require.Equal(t, "debug", driverLevel)
+	require.Equal(t, "warn", instanceLevel)
+	require.Equal(t, map[string]string{"dockerapi": "crit"}, modules)
+
+	require.Error(t, SetLevels("info", "verbose", map[string]string{}))
+	require.Error(t, SetLevels("info", "", map[string]string{"acs": "verbose"}))
+	driverLevel, _, modules = Levels()
+	require.Equal(t, "debug", driverLevel, "levels should not change when a level is invalid")
+	require.Len(t, modules, 1)
+
+	require.NoError(t, SetLevels("", "", map[string]string{}))
+	_, _, modules = Levels()
+	require.Empty(t, modules)
+}
+
+func TestReloadLevels(t *testing.T) {
+	Config = &logConfig{
+		driverLevel:   DEFAULT_LOGLEVEL,
+		instanceLevel: DEFAULT_LOGLEVEL,
+		RolloverType:  DEFAULT_ROLLOVER_TYPE,
+		outputFormat:  DEFAULT_OUTPUT_FORMAT,
+		MaxFileSizeMB: DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:  DEFAULT_MAX_ROLL_COUNT,
+	}
+
+	// the level on the instance follows the log level
+	require.NoError(t, ReloadLevels("debug", ""))
+	driverLevel, instanceLevel, _ := Levels()
+	require.Equal(t, "debug", driverLevel)
+	require.Equal(t, "debug", instanceLevel)
+
+	require.NoError(t, ReloadLevels("warn", "error"))
+	driverLevel, instanceLevel, _ = Levels()
+	require.Equal(t, "warn", driverLevel)
+	require.Equal(t, "error", instanceLevel)
+
+	require.NoError(t, ReloadLevels("", ""))
+	driverLevel, instanceLevel, _ = Levels()
+	require.Equal(t, "info", driverLevel)
+	require.Equal(t, "info", instanceLevel)
+
+	// unless the logs go to another log driver
+	Config.driver = logDriverJournald
+	require.NoError(t, ReloadLevels("debug", ""))
+	driverLevel, instanceLevel, _ = Levels()
+	require.Equal(t, "debug", driverLevel)
+	require.Equal(t, "none", instanceLevel)
+
+	require.Error(t, ReloadLevels("verbose", ""))
+}
@@ -16,7 +16,10 @@
 package logger
 
 import (
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -319,3 +322,127 @@ func TestSeelogConfig_FileLevelDefault(t *testing.T) {
 	</formats>
 </seelog>`, c)
 }
+
+func TestSeelogConfig_ModuleLevels(t *testing.T) {
+	Config = &logConfig{
+		logfile:       "foo.log",
+		driverLevel:   "warn",
+		instanceLevel: DEFAULT_LOGLEVEL,
+		RolloverType:  DEFAULT_ROLLOVER_TYPE,
+		outputFormat:  DEFAULT_OUTPUT_FORMAT,
+		MaxFileSizeMB: DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:  DEFAULT_MAX_ROLL_COUNT,
+	}
+	moduleLevels = map[string]string{"dockerclient/dockerapi": "debug", "acs": "error"}
+	defer func() { moduleLevels = nil }()
+	c := seelogConfig()
+	require.Equal(t, `
+<seelog type="asyncloop">
+	<outputs formatid="logfmt">
+		<filter levels="debug,info,warn,error,critical" formatid="logfmt-driver">
+			<console />
+		</filter>
+		<filter levels="debug,info,warn,error,critical" formatid="logfmt-instance">
+			<rollingfile filename="foo.log" type="date"
+			 datepattern="2006-01-02-15" archivetype="none" maxrolls="24" />
+		</filter>
+	</outputs>
+	<formats>
+		<format id="logfmt" format="%EcsAgentLogfmt" />
+		<format id="json" format="%EcsAgentJson" />
+		<format id="windows" format="%EcsMsg(warn)" />
+		<format id="logfmt-driver" format="%EcsAgentLogfmt(warn)" />
+		<format id="logfmt-instance" format="%EcsAgentLogfmt(info)" />
+	</formats>
+</seelog>`, c)
+}
+
+func TestSeelogConfig_JournaldDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func(original string) { journaldSocket = original }(journaldSocket)
+	journaldSocket = filepath.Join(dir, "socket")
+
+	Config = &logConfig{
+		logfile:       "foo.log",
+		driver:        "journald",
+		driverLevel:   DEFAULT_LOGLEVEL,
+		instanceLevel: "off",
+		RolloverType:  DEFAULT_ROLLOVER_TYPE,
+		outputFormat:  DEFAULT_OUTPUT_FORMAT,
+		MaxFileSizeMB: DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:  DEFAULT_MAX_ROLL_COUNT,
+	}
+	// falls back to the console when the journald socket does not exist
+	c := seelogConfig()
+	require.Contains(t, c, `
+		<filter levels="info,warn,error,critical">
+			<console />
+		</filter>`)
+	require.NotContains(t, c, `<format id="native"`)
+
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer listener.Close()
+	c = seelogConfig()
+	require.Equal(t, `
+<seelog type="asyncloop">
+	<outputs formatid="logfmt">
+		<filter levels="info,warn,error,critical">
+			<custom name="journald" formatid="native" />
+		</filter>
+		<filter levels="off">
+			<rollingfile filename="foo.log" type="date"
+			 datepattern="2006-01-02-15" archivetype="none" maxrolls="24" />
+		</filter>
+	</outputs>
+	<formats>
+		<format id="logfmt" format="%EcsAgentLogfmt" />
+		<format id="json" format="%EcsAgentJson" />
+		<format id="windows" format="%EcsMsg" />
+		<format id="native" format="%EcsMsg" />
+	</formats>
+</seelog>`, c)
+}
+
+func TestSeelogConfig_FileDriverLevelDefault(t *testing.T) {
+	os.Setenv(LOG_DRIVER_ENV_VAR, "file")
+	defer os.Unsetenv(LOG_DRIVER_ENV_VAR)
+
+	require.Equal(t, DEFAULT_LOGLEVEL, setInstanceLevelDefault())
+}
+
+func TestSeelogConfig_Sampling(t *testing.T) {
+	Config = &logConfig{
+		logfile:           "foo.log",
+		driverLevel:       DEFAULT_LOGLEVEL,
+		instanceLevel:     "debug",
+		RolloverType:      DEFAULT_ROLLOVER_TYPE,
+		outputFormat:      jsonFmt,
+		MaxFileSizeMB:     DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:      DEFAULT_MAX_ROLL_COUNT,
+		SamplingThreshold: 10,
+		SamplingInterval:  DEFAULT_SAMPLING_INTERVAL,
+	}
+	c := seelogConfig()
+	require.Equal(t, `
+<seelog type="asyncloop">
+	<outputs formatid="json">
+		<filter levels="info,warn,error,critical" formatid="json-driver">
+			<console />
+		</filter>
+		<filter levels="debug,info,warn,error,critical" formatid="json-instance">
+			<rollingfile filename="foo.log" type="date"
+			 datepattern="2006-01-02-15" archivetype="none" maxrolls="24" />
+		</filter>
+	</outputs>
+	<formats>
+		<format id="logfmt" format="%EcsAgentLogfmt" />
+		<format id="json" format="%EcsAgentJson" />
+		<format id="windows" format="%EcsMsg" />
+		<format id="json-driver" format="%EcsAgentJson(info)" />
+		<format id="json-instance" format="%EcsAgentJson(debug)" />
+	</formats>
+</seelog>`, c)
+}
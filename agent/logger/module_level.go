@@ -0,0 +1,132 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/cihub/seelog"
+)
+
+var (
+	// moduleLevels maps a module, which is the path of a package directory relative to
+	// the agent source tree (e.g. "dockerclient/dockerapi" or "acs"), to the log level
+	// of the messages logged from the module and from the packages below it
+	moduleLevels     map[string]string
+	moduleLevelsLock sync.RWMutex
+
+	// loggerDir is the directory of this package, and agentRoot the root of the agent
+	// source tree that module paths are relative to
+	loggerDir string
+	agentRoot string
+)
+
+func init() {
+	_, file, _, _ := runtime.Caller(0)
+	loggerDir = path.Dir(file)
+	agentRoot = path.Dir(loggerDir)
+}
+
+// parseModuleLevels parses a comma separated list of module=level pairs, with the
+// same level names as ECS_LOGLEVEL
+func parseModuleLevels(value string) (map[string]string, error) {
+	levels := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid module log level %q, expected module=level", pair)
+		}
+		level, ok := parseLevel(parts[1])
+		if !ok {
+			return nil, fmt.Errorf("invalid log level %q for module %s", parts[1], parts[0])
+		}
+		levels[strings.TrimSpace(parts[0])] = level
+	}
+	return levels, nil
+}
+
+func hasModuleLevels() bool {
+	moduleLevelsLock.RLock()
+	defer moduleLevelsLock.RUnlock()
+	return len(moduleLevels) > 0
+}
+
+// outputFilterLevel returns the lowest level that must go through the filter of an
+// output for the module levels to be honored
+func outputFilterLevel(outputLevel string) string {
+	if outputLevel == "off" {
+		return outputLevel
+	}
+	moduleLevelsLock.RLock()
+	defer moduleLevelsLock.RUnlock()
+	lowest := outputLevel
+	for _, level := range moduleLevels {
+		if logLevel(level) < logLevel(lowest) {
+			lowest = level
+		}
+	}
+	return lowest
+}
+
+// moduleLevelEnabled returns whether a message should be written to an output, given
+// the level of the output and the module the message was logged from. An empty output
+// level means that module levels are not in use and every message is written.
+func moduleLevelEnabled(outputLevel string, level seelog.LogLevel, context seelog.LogContextInterface) bool {
+	if outputLevel == "" {
+		return true
+	}
+	if outputLevel == "off" {
+		return false
+	}
+	minLevel := outputLevel
+	module := modulePath(context.FullPath())
+	moduleLevelsLock.RLock()
+	// the longest module that the caller's package is in wins, so that e.g.
+	// "dockerclient/dockerapi" takes precedence over "dockerclient"
+	longest := -1
+	for name, moduleLevel := range moduleLevels {
+		if (module == name || strings.HasPrefix(module, name+"/")) && len(name) > longest {
+			minLevel = moduleLevel
+			longest = len(name)
+		}
+	}
+	moduleLevelsLock.RUnlock()
+	return level >= logLevel(minLevel)
+}
+
+// modulePath returns the directory of a source file relative to the agent source tree,
+// or an empty string for the files outside of it
+func modulePath(file string) string {
+	dir := path.Dir(file)
+	if !strings.HasPrefix(dir, agentRoot+"/") {
+		return ""
+	}
+	return strings.TrimPrefix(dir, agentRoot+"/")
+}
+
+func logLevel(level string) seelog.LogLevel {
+	logLevel, ok := seelog.LogLevelFromString(level)
+	if !ok {
+		return seelog.Off
+	}
+	return logLevel
+}
@@ -0,0 +1,166 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"testing"
+
+	mock_seelog "github.com/aws/amazon-ecs-agent/agent/logger/mocks"
+	"github.com/cihub/seelog"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type moduleLogContextMock struct {
+	LogContextMock
+	fullPath string
+}
+
+func (l *moduleLogContextMock) FullPath() string {
+	return l.fullPath
+}
+
+func TestParseModuleLevels(t *testing.T) {
+	levels, err := parseModuleLevels("dockerapi=debug, acs=WARN,engine=none,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"dockerapi": "debug", "acs": "warn", "engine": "off"}, levels)
+
+	_, err = parseModuleLevels("dockerapi")
+	assert.Error(t, err)
+	_, err = parseModuleLevels("=debug")
+	assert.Error(t, err)
+	_, err = parseModuleLevels("dockerapi=verbose")
+	assert.Error(t, err)
+}
+
+// setTestAgentRoot sets the root of the agent source tree to the one of the test contexts
+func setTestAgentRoot() func() {
+	original := agentRoot
+	agentRoot = "/go/src/agent"
+	return func() {
+		agentRoot = original
+	}
+}
+
+func TestModuleLevelEnabled(t *testing.T) {
+	moduleLevels = map[string]string{"dockerclient": "error", "dockerclient/dockerapi": "debug", "acs": "error",
+		"handler": "error", "agent": "error"}
+	defer func() { moduleLevels = nil }()
+	defer setTestAgentRoot()()
+
+	dockerAPI := &moduleLogContextMock{fullPath: "/go/src/agent/dockerclient/dockerapi/docker_client.go"}
+	dockerClient := &moduleLogContextMock{fullPath: "/go/src/agent/dockerclient/versions.go"}
+	acsHandler := &moduleLogContextMock{fullPath: "/go/src/agent/acs/handler/acs_handler.go"}
+	engine := &moduleLogContextMock{fullPath: "/go/src/agent/engine/docker_task_engine.go"}
+	engineHandler := &moduleLogContextMock{fullPath: "/go/src/agent/engine/handler/handler.go"}
+
+	testCases := []struct {
+		name        string
+		outputLevel string
+		level       seelog.LogLevel
+		context     seelog.LogContextInterface
+		enabled     bool
+	}{
+		{"no module levels", "", seelog.TraceLvl, engine, true},
+		{"module without level below output level", "info", seelog.DebugLvl, engine, false},
+		{"module without level at output level", "info", seelog.InfoLvl, engine, true},
+		{"module with lower level", "info", seelog.DebugLvl, dockerAPI, true},
+		{"deepest module wins", "info", seelog.InfoLvl, dockerAPI, true},
+		{"parent module level", "info", seelog.WarnLvl, dockerClient, false},
+		{"module with higher level", "info", seelog.WarnLvl, acsHandler, false},
+		{"module with higher level above its level", "info", seelog.ErrorLvl, acsHandler, true},
+		{"nested directory name is not a module", "info", seelog.InfoLvl, engineHandler, true},
+		{"directory above the source tree is not a module", "info", seelog.InfoLvl, engine, true},
+		{"output turned off", "off", seelog.CriticalLvl, dockerAPI, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.enabled, moduleLevelEnabled(tc.outputLevel, tc.level, tc.context))
+		})
+	}
+}
+
+func TestLogfmtFormat_ModuleLevels(t *testing.T) {
+	moduleLevels = map[string]string{"dockerclient/dockerapi": "debug"}
+	defer func() { moduleLevels = nil }()
+	defer setTestAgentRoot()()
+
+	logfmt := logfmtFormatter("info")
+	out := logfmt("This is my log message", seelog.DebugLvl,
+		&moduleLogContextMock{fullPath: "/go/src/agent/engine/docker_task_engine.go"})
+	assert.Equal(t, "", out)
+	out = logfmt("This is my log message", seelog.DebugLvl,
+		&moduleLogContextMock{fullPath: "/go/src/agent/dockerclient/dockerapi/docker_client.go"})
+	assert.Equal(t, `level=debug time=2018-10-01T01:02:03Z msg="This is my log message" module=mytestmodule.go
+`, out)
+}
+
+func TestOutputFilterLevel(t *testing.T) {
+	assert.Equal(t, "info", outputFilterLevel("info"))
+
+	moduleLevels = map[string]string{"dockerapi": "debug", "acs": "error"}
+	defer func() { moduleLevels = nil }()
+	assert.Equal(t, "debug", outputFilterLevel("info"))
+	assert.Equal(t, "off", outputFilterLevel("off"))
+}
+
+func TestStructuredLoggerCaller(t *testing.T) {
+	defer globalLoggerBackup()()
+	moduleLevels = map[string]string{"logger": "debug"}
+	defer func() { moduleLevels = nil }()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockReceiver := mock_seelog.NewMockCustomReceiver(ctrl)
+	seeLog, err := seelog.LoggerFromCustomReceiver(mockReceiver)
+	require.NoError(t, err)
+	setGlobalLogger(seeLog, logFmt)
+
+	mockReceiver.EXPECT().ReceiveMessage(gomock.Any(), seelog.LogLevel(seelog.DebugLvl), gomock.Any()).Do(
+		func(message string, level seelog.LogLevel, context seelog.LogContextInterface) {
+			assert.Equal(t, `logger=structured msg="message"`, message)
+			// the message is attributed to the code that called the structured logger
+			assert.Equal(t, "module_level_test.go", context.FileName())
+			assert.Equal(t, "logger", modulePath(context.FullPath()))
+			assert.True(t, moduleLevelEnabled("info", level, context))
+		})
+	mockReceiver.EXPECT().Flush().AnyTimes()
+	mockReceiver.EXPECT().Close().AnyTimes()
+
+	Debug("message")
+}
+
+func TestSeelogCallerWithStructuredLogger(t *testing.T) {
+	defer globalLoggerBackup()()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockReceiver := mock_seelog.NewMockCustomReceiver(ctrl)
+	seeLog, err := seelog.LoggerFromCustomReceiver(mockReceiver)
+	require.NoError(t, err)
+	setGlobalLogger(seeLog, logFmt)
+
+	// the messages logged with seelog directly keep the caller seelog resolves for them
+	mockReceiver.EXPECT().ReceiveMessage("message", seelog.LogLevel(seelog.InfoLvl), gomock.Any()).Do(
+		func(message string, level seelog.LogLevel, context seelog.LogContextInterface) {
+			assert.Equal(t, "module_level_test.go", context.FileName())
+		})
+	mockReceiver.EXPECT().Flush().AnyTimes()
+	mockReceiver.EXPECT().Close().AnyTimes()
+
+	seelog.Info("message")
+}
@@ -15,8 +15,35 @@
 
 package logger
 
-// registerPlatformLogger does nothing on Linux
-func registerPlatformLogger() {}
+import (
+	"os"
+
+	"github.com/cihub/seelog"
+)
+
+// syslogSockets are the sockets where the local syslog daemon may listen
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// registerPlatformLogger registers the journald and syslog receivers
+func registerPlatformLogger() {
+	seelog.RegisterReceiver(logDriverJournald, &journaldReceiver{})
+	seelog.RegisterReceiver(logDriverSyslog, &syslogReceiver{})
+}
 
 // platformLogConfig does nothing on Linux
 func platformLogConfig() string { return "" }
+
+// nativeLogDriverAvailable returns whether the agent can write its logs to the journald
+// or syslog socket of the host
+func nativeLogDriverAvailable(driver string) bool {
+	sockets := syslogSockets
+	if driver == logDriverJournald {
+		sockets = []string{journaldSocket}
+	}
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -49,28 +49,77 @@ func newStructuredLogger(logFormat string) *structuredLogger {
 
 type structuredLogger struct {
 	formatter seelogMessageFormatter
+	// seelogger writes the messages to the outputs of the global seelog logger. It's a
+	// clone of that logger with an additional stack depth, so that seelog attributes the
+	// messages to the code that called the global structured logger functions instead of
+	// to the structured logger itself. Without it, the global seelog logger is used.
+	seelogger seelog.LoggerInterface
+}
+
+// structuredLoggerStackDepth is the number of frames between the code that logs a
+// structured message and the seelog logger: the global function and the method of the
+// structured logger
+const structuredLoggerStackDepth = 2
+
+// newStructuredSeelogger returns a clone of a seelog logger that writes to the same
+// outputs, with the additional stack depth of the structured logger
+func newStructuredSeelogger(logger seelog.LoggerInterface) (seelog.LoggerInterface, error) {
+	clone, err := seelog.CloneLogger(logger)
+	if err != nil {
+		return nil, err
+	}
+	err = clone.SetAdditionalStackDepth(structuredLoggerStackDepth)
+	if err != nil {
+		clone.Close()
+		return nil, err
+	}
+	return clone, nil
 }
 
 func (l *structuredLogger) Trace(message string, fields ...Fields) {
-	seelog.Trace(l.formatter.Format(message, fields...))
+	if l.seelogger == nil {
+		seelog.Trace(l.formatter.Format(message, fields...))
+		return
+	}
+	l.seelogger.Trace(l.formatter.Format(message, fields...))
 }
 
 func (l *structuredLogger) Debug(message string, fields ...Fields) {
-	seelog.Debug(l.formatter.Format(message, fields...))
+	if l.seelogger == nil {
+		seelog.Debug(l.formatter.Format(message, fields...))
+		return
+	}
+	l.seelogger.Debug(l.formatter.Format(message, fields...))
 }
 
 func (l *structuredLogger) Info(message string, fields ...Fields) {
-	seelog.Info(l.formatter.Format(message, fields...))
+	if l.seelogger == nil {
+		seelog.Info(l.formatter.Format(message, fields...))
+		return
+	}
+	l.seelogger.Info(l.formatter.Format(message, fields...))
 }
 
 func (l *structuredLogger) Warn(message string, fields ...Fields) {
-	seelog.Warn(l.formatter.Format(message, fields...))
+	if l.seelogger == nil {
+		seelog.Warn(l.formatter.Format(message, fields...))
+		return
+	}
+	l.seelogger.Warn(l.formatter.Format(message, fields...))
 }
 
 func (l *structuredLogger) Error(message string, fields ...Fields) {
-	seelog.Error(l.formatter.Format(message, fields...))
+	if l.seelogger == nil {
+		seelog.Error(l.formatter.Format(message, fields...))
+		return
+	}
+	l.seelogger.Error(l.formatter.Format(message, fields...))
 }
 
 func (l *structuredLogger) Critical(message string, fields ...Fields) {
-	seelog.Critical(l.formatter.Format(message, fields...))
+	if l.seelogger == nil {
+		seelog.Critical(l.formatter.Format(message, fields...))
+		return
+	}
+	l.seelogger.Critical(l.formatter.Format(message, fields...))
 }
@@ -0,0 +1,67 @@
+// +build !windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"log/syslog"
+
+	"github.com/cihub/seelog"
+)
+
+// syslogReceiver fulfills the seelog.CustomReceiver interface, and sends log messages
+// to the local syslog daemon with the priority of their level
+type syslogReceiver struct {
+	writer *syslog.Writer
+}
+
+// ReceiveMessage receives a log line from seelog and sends it to syslog
+func (r *syslogReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if message == "" {
+		// dropped by the formatter because of the module levels
+		return nil
+	}
+	switch level {
+	case seelog.TraceLvl, seelog.DebugLvl:
+		return r.writer.Debug(message)
+	case seelog.InfoLvl:
+		return r.writer.Info(message)
+	case seelog.WarnLvl:
+		return r.writer.Warning(message)
+	case seelog.ErrorLvl:
+		return r.writer.Err(message)
+	case seelog.CriticalLvl:
+		return r.writer.Crit(message)
+	}
+	return nil
+}
+
+func (r *syslogReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) error {
+	writer, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, journaldIdentifier)
+	if err != nil {
+		return err
+	}
+	r.writer = writer
+	return nil
+}
+
+func (r *syslogReceiver) Flush() {}
+
+func (r *syslogReceiver) Close() error {
+	if r.writer == nil {
+		return nil
+	}
+	return r.writer.Close()
+}
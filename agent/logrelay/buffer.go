@@ -0,0 +1,371 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logrelay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	bufferEntryExtension  = ".json.gz"
+	bufferEntryNameFormat = "%020d-%d" + bufferEntryExtension
+	cursorFile            = "cursor"
+	bufferDirPerm         = 0700
+	bufferFilePerm        = 0600
+	// maxMemoryBatches is the number of batches kept in memory before the next ones
+	// overflow to disk
+	maxMemoryBatches = 4
+	memoryBuffer     = "memory"
+	diskBuffer       = "disk"
+)
+
+// logEvent is a line logged by a container
+type logEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+}
+
+// size returns the size of the event as counted by CloudWatch Logs
+func (event logEvent) size() int {
+	return len(event.Message) + eventOverhead
+}
+
+// logBatch is a batch of log events shipped to CloudWatch Logs in a single request
+type logBatch struct {
+	Events []logEvent `json:"events"`
+	// entry is the disk buffer entry of the batch, which is nil for batches kept in memory
+	entry *bufferEntry
+}
+
+func (batch *logBatch) size() int64 {
+	var size int64
+	for _, event := range batch.Events {
+		size += int64(event.size())
+	}
+	return size
+}
+
+func (batch *logBatch) lastTimestamp() time.Time {
+	return batch.Events[len(batch.Events)-1].Timestamp
+}
+
+type bufferEntry struct {
+	seq    uint64
+	events int
+	size   int64
+}
+
+// logBuffer keeps the batches of log events of a container that haven't been shipped
+// yet, in the order they were added. Batches are kept in memory while the relay keeps
+// up with the container; once the memory buffer is full, for example while CloudWatch
+// Logs is unreachable, the next batches overflow to disk. The batches on disk are
+// gzip-compressed, and the oldest ones are dropped once the disk buffer reaches its
+// maximum size.
+//
+// The buffer also keeps a cursor, the timestamp of the last event up to which all the
+// events were either shipped or written to disk, from which the logs of the container
+// are read again when the agent restarts.
+type logBuffer struct {
+	dir        string
+	maxSize    int64
+	lock       sync.Mutex
+	memory     []*logBatch
+	memorySize int64
+	entries    []bufferEntry
+	size       int64
+	nextSeq    uint64
+	lastAdded  time.Time
+	cursor     time.Time
+	// added is notified when a batch is added to the buffer
+	added chan struct{}
+}
+
+// newLogBuffer returns a buffer whose disk buffer of at most maxSize bytes is stored
+// in dir. The batches and the cursor already in dir, e.g. before the agent restarted,
+// are kept.
+func newLogBuffer(dir string, maxSize int64) (*logBuffer, error) {
+	if err := os.MkdirAll(dir, bufferDirPerm); err != nil {
+		return nil, errors.Wrap(err, "log relay: unable to create buffer directory")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "log relay: unable to read buffer directory")
+	}
+
+	buffer := &logBuffer{
+		dir:     dir,
+		maxSize: maxSize,
+		added:   make(chan struct{}, 1),
+	}
+	for _, file := range files {
+		entry, ok := parseBufferEntry(file.Name())
+		if !ok {
+			continue
+		}
+		entry.size = file.Size()
+		buffer.entries = append(buffer.entries, entry)
+		buffer.size += entry.size
+		if entry.seq >= buffer.nextSeq {
+			buffer.nextSeq = entry.seq + 1
+		}
+	}
+	sort.Slice(buffer.entries, func(i, j int) bool {
+		return buffer.entries[i].seq < buffer.entries[j].seq
+	})
+	metrics.MetricsEngineGlobal.AddLogRelayBufferedBytes(diskBuffer, buffer.size)
+
+	cursor, err := ioutil.ReadFile(filepath.Join(dir, cursorFile))
+	if err == nil {
+		buffer.cursor, err = time.Parse(time.RFC3339Nano, strings.TrimSpace(string(cursor)))
+		if err != nil {
+			seelog.Warnf("Log relay: ignoring invalid cursor in %s: %v", dir, err)
+		}
+	}
+	buffer.lastAdded = buffer.cursor
+
+	buffer.lock.Lock()
+	buffer.trimUnsafe(0)
+	buffer.lock.Unlock()
+	return buffer, nil
+}
+
+// Cursor returns the timestamp of the last event up to which all the events were
+// either shipped or written to disk
+func (buffer *logBuffer) Cursor() time.Time {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	return buffer.cursor
+}
+
+// Len returns the number of buffered batches
+func (buffer *logBuffer) Len() int {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	return len(buffer.memory) + len(buffer.entries)
+}
+
+// Add buffers a batch, in memory if there is room left and no batch overflowed to disk,
+// and on disk otherwise
+func (buffer *logBuffer) Add(batch *logBatch) error {
+	if len(batch.Events) == 0 {
+		return nil
+	}
+	defer buffer.notify()
+
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	buffer.lastAdded = batch.lastTimestamp()
+	if len(buffer.entries) == 0 && len(buffer.memory) < maxMemoryBatches {
+		size := batch.size()
+		buffer.memory = append(buffer.memory, batch)
+		buffer.memorySize += size
+		metrics.MetricsEngineGlobal.AddLogRelayBufferedBytes(memoryBuffer, size)
+		return nil
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if err := json.NewEncoder(writer).Encode(batch); err != nil {
+		return errors.Wrap(err, "log relay: unable to encode batch")
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "log relay: unable to compress batch")
+	}
+	size := int64(compressed.Len())
+	if size > buffer.maxSize {
+		metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsDropped, len(batch.Events))
+		return errors.Errorf("log relay: batch of %d bytes exceeds the buffer size of %d bytes", size, buffer.maxSize)
+	}
+
+	buffer.trimUnsafe(size)
+	entry := bufferEntry{
+		seq:    buffer.nextSeq,
+		events: len(batch.Events),
+		size:   size,
+	}
+	if err := ioutil.WriteFile(buffer.entryPath(entry), compressed.Bytes(), bufferFilePerm); err != nil {
+		metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsDropped, len(batch.Events))
+		return errors.Wrap(err, "log relay: unable to write batch")
+	}
+	buffer.nextSeq++
+	buffer.entries = append(buffer.entries, entry)
+	buffer.size += size
+	metrics.MetricsEngineGlobal.AddLogRelayBufferedBytes(diskBuffer, size)
+	buffer.updateCursorUnsafe()
+	return nil
+}
+
+func (buffer *logBuffer) notify() {
+	select {
+	case buffer.added <- struct{}{}:
+	default:
+	}
+}
+
+// Peek returns the oldest buffered batch, if any. Batches on disk that can't be read
+// are dropped.
+func (buffer *logBuffer) Peek() (*logBatch, bool) {
+	for {
+		buffer.lock.Lock()
+		if len(buffer.memory) > 0 {
+			batch := buffer.memory[0]
+			buffer.lock.Unlock()
+			return batch, true
+		}
+		if len(buffer.entries) == 0 {
+			buffer.lock.Unlock()
+			return nil, false
+		}
+		entry := buffer.entries[0]
+		buffer.lock.Unlock()
+
+		batch, err := buffer.read(entry)
+		if err != nil {
+			seelog.Warnf("Log relay: dropping buffered batch that can't be read: %v", err)
+			metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsDropped, entry.events)
+			buffer.Remove(&logBatch{entry: &entry})
+			continue
+		}
+		return batch, true
+	}
+}
+
+// read decodes the batch of a disk buffer entry
+func (buffer *logBuffer) read(entry bufferEntry) (*logBatch, error) {
+	file, err := os.Open(buffer.entryPath(entry))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	batch := &logBatch{}
+	if err := json.NewDecoder(reader).Decode(batch); err != nil {
+		return nil, err
+	}
+	if len(batch.Events) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	batch.entry = &entry
+	return batch, nil
+}
+
+// Remove removes a batch returned by Peek once it's shipped, unless it was already
+// dropped while it was being shipped
+func (buffer *logBuffer) Remove(batch *logBatch) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	if batch.entry == nil {
+		if len(buffer.memory) == 0 || buffer.memory[0] != batch {
+			return
+		}
+		size := batch.size()
+		buffer.memory = buffer.memory[1:]
+		buffer.memorySize -= size
+		metrics.MetricsEngineGlobal.AddLogRelayBufferedBytes(memoryBuffer, -size)
+	} else {
+		if len(buffer.entries) == 0 || buffer.entries[0].seq != batch.entry.seq {
+			return
+		}
+		buffer.removeOldestUnsafe()
+	}
+	buffer.updateCursorUnsafe()
+}
+
+// Close releases the memory buffer, whose batches are read again from the container
+// when the relay is restarted
+func (buffer *logBuffer) Close() {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	metrics.MetricsEngineGlobal.AddLogRelayBufferedBytes(memoryBuffer, -buffer.memorySize)
+	metrics.MetricsEngineGlobal.AddLogRelayBufferedBytes(diskBuffer, -buffer.size)
+	buffer.memory = nil
+	buffer.memorySize = 0
+	buffer.entries = nil
+	buffer.size = 0
+}
+
+// updateCursorUnsafe persists the timestamp of the last added event as the cursor once
+// no batch is left in memory, as all the events up to it were then either shipped or
+// written to disk
+func (buffer *logBuffer) updateCursorUnsafe() {
+	if len(buffer.memory) > 0 || !buffer.lastAdded.After(buffer.cursor) {
+		return
+	}
+	cursor := buffer.lastAdded.Format(time.RFC3339Nano)
+	if err := ioutil.WriteFile(filepath.Join(buffer.dir, cursorFile), []byte(cursor), bufferFilePerm); err != nil {
+		seelog.Warnf("Log relay: unable to save cursor in %s: %v", buffer.dir, err)
+		return
+	}
+	buffer.cursor = buffer.lastAdded
+}
+
+// trimUnsafe drops the oldest entries on disk until there's room for an entry of the
+// given size
+func (buffer *logBuffer) trimUnsafe(size int64) {
+	dropped := 0
+	for len(buffer.entries) > 0 && buffer.size+size > buffer.maxSize {
+		dropped += buffer.entries[0].events
+		buffer.removeOldestUnsafe()
+	}
+	if dropped > 0 {
+		seelog.Warnf("Log relay buffer in %s is full, dropped the %d oldest buffered log events", buffer.dir, dropped)
+		metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsDropped, dropped)
+	}
+}
+
+func (buffer *logBuffer) removeOldestUnsafe() {
+	entry := buffer.entries[0]
+	if err := os.Remove(buffer.entryPath(entry)); err != nil && !os.IsNotExist(err) {
+		seelog.Warnf("Log relay: unable to remove buffered batch: %v", err)
+	}
+	buffer.entries = buffer.entries[1:]
+	buffer.size -= entry.size
+	metrics.MetricsEngineGlobal.AddLogRelayBufferedBytes(diskBuffer, -entry.size)
+}
+
+func (buffer *logBuffer) entryPath(entry bufferEntry) string {
+	return filepath.Join(buffer.dir, fmt.Sprintf(bufferEntryNameFormat, entry.seq, entry.events))
+}
+
+// parseBufferEntry parses the sequence number and the number of events of an entry
+// from the name of its file
+func parseBufferEntry(name string) (bufferEntry, bool) {
+	if !strings.HasSuffix(name, bufferEntryExtension) {
+		return bufferEntry{}, false
+	}
+	var entry bufferEntry
+	if _, err := fmt.Sscanf(strings.TrimSuffix(name, bufferEntryExtension), "%d-%d", &entry.seq, &entry.events); err != nil {
+		return bufferEntry{}, false
+	}
+	return entry, true
+}
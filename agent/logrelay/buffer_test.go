@@ -0,0 +1,130 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logrelay
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestBatch(first, events int) *logBatch {
+	batch := &logBatch{}
+	for i := first; i < first+events; i++ {
+		batch.Events = append(batch.Events, logEvent{
+			Timestamp: testTime.Add(time.Duration(i) * time.Second),
+			Message:   fmt.Sprintf("message %d", i),
+		})
+	}
+	return batch
+}
+
+func TestLogBufferOverflowsToDisk(t *testing.T) {
+	dir := t.TempDir()
+	buffer, err := newLogBuffer(dir, 1024*1024)
+	require.NoError(t, err)
+
+	for i := 0; i < maxMemoryBatches+2; i++ {
+		require.NoError(t, buffer.Add(newTestBatch(i, 1)))
+	}
+	assert.Equal(t, maxMemoryBatches+2, buffer.Len())
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	// the overflowing batches
+	assert.Len(t, files, 2)
+	// the cursor is only saved once the batches in memory are shipped
+	assert.True(t, buffer.Cursor().IsZero())
+
+	for i := 0; i < maxMemoryBatches+2; i++ {
+		batch, ok := buffer.Peek()
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("message %d", i), batch.Events[0].Message)
+		buffer.Remove(batch)
+	}
+	_, ok := buffer.Peek()
+	assert.False(t, ok)
+	assert.Equal(t, testTime.Add(time.Duration(maxMemoryBatches+1)*time.Second), buffer.Cursor())
+}
+
+func TestLogBufferKeepsOrderWhileOverflowing(t *testing.T) {
+	buffer, err := newLogBuffer(t.TempDir(), 1024*1024)
+	require.NoError(t, err)
+
+	for i := 0; i < maxMemoryBatches+1; i++ {
+		require.NoError(t, buffer.Add(newTestBatch(i, 1)))
+	}
+	batch, ok := buffer.Peek()
+	require.True(t, ok)
+	buffer.Remove(batch)
+	// there's room left in memory, but the new batch must be shipped after the one on disk
+	require.NoError(t, buffer.Add(newTestBatch(maxMemoryBatches+1, 1)))
+
+	for i := 1; i < maxMemoryBatches+2; i++ {
+		batch, ok := buffer.Peek()
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("message %d", i), batch.Events[0].Message)
+		buffer.Remove(batch)
+	}
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestLogBufferDropsOldestBatchesWhenFull(t *testing.T) {
+	buffer, err := newLogBuffer(t.TempDir(), 200)
+	require.NoError(t, err)
+
+	for i := 0; i < maxMemoryBatches+10; i++ {
+		require.NoError(t, buffer.Add(newTestBatch(i, 1)))
+	}
+	assert.True(t, buffer.size <= 200)
+	for i := 0; i < maxMemoryBatches; i++ {
+		batch, ok := buffer.Peek()
+		require.True(t, ok)
+		buffer.Remove(batch)
+	}
+	batch, ok := buffer.Peek()
+	require.True(t, ok)
+	assert.NotEqual(t, fmt.Sprintf("message %d", maxMemoryBatches), batch.Events[0].Message)
+}
+
+func TestLogBufferResumesFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	buffer, err := newLogBuffer(dir, 1024*1024)
+	require.NoError(t, err)
+	for i := 0; i < maxMemoryBatches+2; i++ {
+		require.NoError(t, buffer.Add(newTestBatch(2*i, 2)))
+	}
+	for i := 0; i < maxMemoryBatches; i++ {
+		batch, ok := buffer.Peek()
+		require.True(t, ok)
+		buffer.Remove(batch)
+	}
+	buffer.Close()
+
+	buffer, err = newLogBuffer(dir, 1024*1024)
+	require.NoError(t, err)
+	assert.Equal(t, 2, buffer.Len())
+	assert.Equal(t, testTime.Add(time.Duration(2*maxMemoryBatches+3)*time.Second), buffer.Cursor())
+	batch, ok := buffer.Peek()
+	require.True(t, ok)
+	assert.Len(t, batch.Events, 2)
+	assert.Equal(t, fmt.Sprintf("message %d", 2*maxMemoryBatches), batch.Events[0].Message)
+}
@@ -0,0 +1,83 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logrelay
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+	"github.com/aws/aws-sdk-go/aws"
+	awscreds "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/pkg/errors"
+)
+
+const (
+	roundtripTimeout = 30 * time.Second
+	// taskCredentialsProviderName is the name of the provider of task execution role credentials
+	taskCredentialsProviderName = "TaskExecutionRoleCredentialsProvider"
+)
+
+// NewCloudWatchLogsClientCreator returns a creator of CloudWatch Logs clients
+func NewCloudWatchLogsClientCreator() CloudWatchLogsClientCreator {
+	return &cloudWatchLogsClientCreator{}
+}
+
+type cloudWatchLogsClientCreator struct{}
+
+func (*cloudWatchLogsClientCreator) NewCloudWatchLogsClient(region, endpoint string,
+	creds *awscreds.Credentials) CloudWatchLogsClient {
+	cfg := aws.NewConfig().
+		WithHTTPClient(httpclient.New(roundtripTimeout, false)).
+		WithRegion(region).
+		WithCredentials(creds)
+	if endpoint != "" {
+		cfg = cfg.WithEndpoint(endpoint)
+	}
+	sess := session.Must(session.NewSession(cfg))
+	return cloudwatchlogs.New(sess)
+}
+
+// taskCredentialsProvider provides the task execution role credentials held by the
+// credentials manager, which are refreshed by ACS, so that the relay of a container
+// ships its logs with the same credentials the awslogs driver would have used
+type taskCredentialsProvider struct {
+	credentialsManager credentials.Manager
+	credentialsID      string
+	expiration         time.Time
+}
+
+func (provider *taskCredentialsProvider) Retrieve() (awscreds.Value, error) {
+	taskCredentials, ok := provider.credentialsManager.GetTaskCredentials(provider.credentialsID)
+	if !ok {
+		return awscreds.Value{}, errors.Errorf("log relay: task execution role credentials %s not found",
+			provider.credentialsID)
+	}
+	creds := taskCredentials.GetIAMRoleCredentials()
+	provider.expiration, _ = creds.ExpirationTime()
+	return awscreds.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    taskCredentialsProviderName,
+	}, nil
+}
+
+// IsExpired returns true when the credentials expired, or when their expiration is
+// unknown so that they're read again from the credentials manager
+func (provider *taskCredentialsProvider) IsExpired() bool {
+	return !time.Now().Before(provider.expiration)
+}
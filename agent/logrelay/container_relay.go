@@ -0,0 +1,479 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logrelay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// eventOverhead is the number of bytes CloudWatch Logs adds to the size of each event
+	eventOverhead = 26
+	// maxEventSize is the maximum size of an event, longer messages are truncated
+	maxEventSize = 256*1024 - eventOverhead
+	// maxBatchEvents, maxBatchSize and maxBatchSpan are the limits of a PutLogEvents request
+	maxBatchEvents = 10000
+	maxBatchSize   = 1024 * 1024
+	maxBatchSpan   = 24 * time.Hour
+	// flushInterval is how often the events read from a container are buffered when
+	// they don't fill a batch
+	flushInterval = 5 * time.Second
+
+	readBackoffMin      = time.Second
+	readBackoffMax      = 30 * time.Second
+	sendBackoffMin      = time.Second
+	sendBackoffMax      = 5 * time.Minute
+	backoffJitter       = 0.2
+	backoffMultiple     = 2
+	eventsSent          = "sent"
+	eventsRejected      = "rejected"
+	eventsDropped       = "dropped"
+	batchesSent         = "sent"
+	batchesFailed       = "failed"
+	maxReadErrorRetries = 10
+	// maxSendErrorRetries is how many times a batch that CloudWatch Logs rejects is retried
+	// before it's dropped. The batches that fail to be shipped because CloudWatch Logs is
+	// unreachable or throttling are retried until the buffer drops them when it's full.
+	maxSendErrorRetries = 10
+	// throttlingErrorCode is the error code of the requests that CloudWatch Logs throttles
+	throttlingErrorCode = "ThrottlingException"
+)
+
+// containerRelay ships the logs of a container to a CloudWatch Logs stream. The reader
+// follows the logs of the container from the local log driver and adds them to the
+// buffer in batches, while the sender ships the buffered batches in order. Both stop
+// once the container stopped and all its logs were shipped.
+type containerRelay struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	dockerID    string
+	group       string
+	stream      string
+	createGroup bool
+	client      CloudWatchLogsClient
+	streamer    ContainerLogsStreamer
+	buffer      *logBuffer
+	// lastRead is the timestamp of the last event read from the container
+	lastRead      time.Time
+	streamCreated bool
+	// readDone is closed once all the logs of the container were read
+	readDone chan struct{}
+	// removed is set, under the lock of the Relay, once the container was removed
+	removed bool
+}
+
+func newContainerRelay(ctx context.Context, dockerID string, options map[string]string,
+	client CloudWatchLogsClient, streamer ContainerLogsStreamer, buffer *logBuffer) *containerRelay {
+	ctx, cancel := context.WithCancel(ctx)
+	return &containerRelay{
+		ctx:         ctx,
+		cancel:      cancel,
+		dockerID:    dockerID,
+		group:       options[groupOption],
+		stream:      streamName(dockerID, options),
+		createGroup: options[createGroupOption] == "true",
+		client:      client,
+		streamer:    streamer,
+		buffer:      buffer,
+		lastRead:    buffer.Cursor(),
+		readDone:    make(chan struct{}),
+	}
+}
+
+// streamName returns the name of the log stream of the container, as named by the
+// awslogs log driver
+func streamName(dockerID string, options map[string]string) string {
+	if stream := options[streamOption]; stream != "" {
+		return stream
+	}
+	if prefix := options[streamPrefixOption]; prefix != "" {
+		return prefix + "/" + dockerID
+	}
+	return dockerID
+}
+
+// run reads and ships the logs of the container, and returns whether all of them were
+// shipped, or false when the context is done first
+func (relay *containerRelay) run() bool {
+	defer relay.cancel()
+	go relay.read()
+	return relay.send()
+}
+
+// read reads the logs of the container until they end, reopening the logs from the
+// last event read when reading them fails
+func (relay *containerRelay) read() {
+	defer close(relay.readDone)
+	backoff := retry.NewExponentialBackoff(readBackoffMin, readBackoffMax, backoffJitter, backoffMultiple)
+	for retries := 0; ; retries++ {
+		err := relay.readLogs()
+		if err == nil || relay.ctx.Err() != nil {
+			return
+		}
+		if _, ok := err.(dockerapi.NoSuchContainerError); ok || retries >= maxReadErrorRetries {
+			logger.Error("Log relay: unable to read container logs, giving up", logger.Fields{
+				field.RuntimeID: relay.dockerID,
+				field.Error:     err,
+			})
+			return
+		}
+		logger.Warn("Log relay: error reading container logs", logger.Fields{
+			field.RuntimeID: relay.dockerID,
+			field.Error:     err,
+		})
+		select {
+		case <-time.After(backoff.Duration()):
+		case <-relay.ctx.Done():
+			return
+		}
+	}
+}
+
+// readLogs follows the logs of the container from the last event read and adds them to
+// the buffer in batches, until the logs end
+func (relay *containerRelay) readLogs() error {
+	ctx, cancel := context.WithCancel(relay.ctx)
+	defer cancel()
+	logs, err := relay.streamer.ContainerLogs(ctx, relay.dockerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Timestamps: true,
+		Since:      since(relay.lastRead),
+	})
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	events := make(chan logEvent)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanErr <- scanLogs(logs, func(event logEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := &logBatch{}
+	var batchSize int
+	flush := func() {
+		if len(batch.Events) == 0 {
+			return
+		}
+		if err := relay.buffer.Add(batch); err != nil {
+			logger.Warn("Log relay: unable to buffer container logs", logger.Fields{
+				field.RuntimeID: relay.dockerID,
+				field.Error:     err,
+			})
+		}
+		batch = &logBatch{}
+		batchSize = 0
+	}
+	for {
+		select {
+		case event := <-events:
+			if !event.Timestamp.After(relay.lastRead) {
+				continue
+			}
+			relay.lastRead = event.Timestamp
+			if len(batch.Events) == maxBatchEvents || batchSize+event.size() > maxBatchSize ||
+				(len(batch.Events) > 0 && event.Timestamp.Sub(batch.Events[0].Timestamp) >= maxBatchSpan) {
+				flush()
+			}
+			batch.Events = append(batch.Events, event)
+			batchSize += event.size()
+		case <-ticker.C:
+			flush()
+		case err := <-scanErr:
+			flush()
+			return err
+		}
+	}
+}
+
+// since formats a timestamp as the since option of the logs of a container
+func since(timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%09d", timestamp.Unix(), timestamp.Nanosecond())
+}
+
+// scanLogs parses the timestamped lines of the logs of a container, which are
+// multiplexed unless the container has a TTY, and passes them as events to handle
+// until the logs end or handle returns false
+func scanLogs(logs io.Reader, handle func(logEvent) bool) error {
+	reader := bufio.NewReader(logs)
+	header, err := reader.Peek(1)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if header[0] <= byte(stdcopy.Stderr) {
+		multiplexed := reader
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			_, err := stdcopy.StdCopy(pipeWriter, pipeWriter, multiplexed)
+			pipeWriter.CloseWithError(err)
+		}()
+		defer pipeReader.Close()
+		reader = bufio.NewReader(pipeReader)
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if event, ok := parseLogLine(line); ok && !handle(event) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseLogLine parses a log line prefixed with its RFC3339Nano timestamp, and returns
+// false for empty lines
+func parseLogLine(line string) (logEvent, bool) {
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	event := logEvent{Message: line}
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if timestamp, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			event.Timestamp = timestamp
+			event.Message = line[i+1:]
+		}
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.Message == "" {
+		return event, false
+	}
+	if len(event.Message) > maxEventSize {
+		event.Message = event.Message[:maxEventSize]
+	}
+	return event, true
+}
+
+// send ships the buffered batches in order, retrying the ones that fail, and returns
+// true once all the logs of the container were read and shipped. The batches that
+// CloudWatch Logs rejects as invalid, or keeps rejecting, are dropped.
+func (relay *containerRelay) send() bool {
+	backoff := retry.NewExponentialBackoff(sendBackoffMin, sendBackoffMax, backoffJitter, backoffMultiple)
+	retries := 0
+	for {
+		batch, ok := relay.buffer.Peek()
+		if !ok {
+			select {
+			case <-relay.buffer.added:
+			case <-relay.readDone:
+				if relay.buffer.Len() == 0 {
+					return true
+				}
+			case <-relay.ctx.Done():
+				return false
+			}
+			continue
+		}
+
+		if err := relay.putLogEvents(batch); err != nil {
+			if isRejectedError(err) {
+				retries++
+			}
+			if isPermanentError(err) || retries > maxSendErrorRetries {
+				logger.Error("Log relay: CloudWatch Logs rejected container logs, dropping them", logger.Fields{
+					field.RuntimeID: relay.dockerID,
+					"logGroup":      relay.group,
+					"logStream":     relay.stream,
+					"events":        len(batch.Events),
+					field.Error:     err,
+				})
+				metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsDropped, len(batch.Events))
+				relay.buffer.Remove(batch)
+				retries = 0
+				backoff.Reset()
+				continue
+			}
+			logger.Warn("Log relay: error shipping container logs to CloudWatch Logs", logger.Fields{
+				field.RuntimeID: relay.dockerID,
+				"logGroup":      relay.group,
+				"logStream":     relay.stream,
+				field.Error:     err,
+			})
+			select {
+			case <-time.After(backoff.Duration()):
+			case <-relay.ctx.Done():
+				return false
+			}
+			continue
+		}
+		retries = 0
+		backoff.Reset()
+		relay.buffer.Remove(batch)
+	}
+}
+
+// putLogEvents ships a batch, creating the log stream first if needed
+func (relay *containerRelay) putLogEvents(batch *logBatch) error {
+	if err := relay.createStream(); err != nil {
+		metrics.MetricsEngineGlobal.RecordLogRelayBatch(batchesFailed)
+		return err
+	}
+
+	events := make([]*cloudwatchlogs.InputLogEvent, len(batch.Events))
+	for i, event := range batch.Events {
+		events[i] = &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(event.Message),
+			Timestamp: aws.Int64(event.Timestamp.UnixNano() / int64(time.Millisecond)),
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return aws.Int64Value(events[i].Timestamp) < aws.Int64Value(events[j].Timestamp)
+	})
+	output, err := relay.client.PutLogEventsWithContext(relay.ctx, &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(relay.group),
+		LogStreamName: aws.String(relay.stream),
+		LogEvents:     events,
+	})
+	if isAWSErrorCode(err, cloudwatchlogs.ErrCodeDataAlreadyAcceptedException) {
+		// the batch was shipped by a request whose response was lost
+		metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsSent, len(events))
+		metrics.MetricsEngineGlobal.RecordLogRelayBatch(batchesSent)
+		return nil
+	}
+	if err != nil {
+		if isAWSErrorCode(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+			relay.streamCreated = false
+		}
+		metrics.MetricsEngineGlobal.RecordLogRelayBatch(batchesFailed)
+		return err
+	}
+
+	rejected := 0
+	if output != nil {
+		rejected = rejectedEvents(output.RejectedLogEventsInfo, len(events))
+	}
+	if rejected > 0 {
+		logger.Warn("Log relay: CloudWatch Logs rejected container log events", logger.Fields{
+			field.RuntimeID: relay.dockerID,
+			"rejected":      rejected,
+		})
+	}
+	metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsSent, len(events)-rejected)
+	metrics.MetricsEngineGlobal.RecordLogRelayEvents(eventsRejected, rejected)
+	metrics.MetricsEngineGlobal.RecordLogRelayBatch(batchesSent)
+	return nil
+}
+
+// createStream creates the log stream of the container, and its log group when the
+// awslogs-create-group option is set, unless they already exist
+func (relay *containerRelay) createStream() error {
+	if relay.streamCreated {
+		return nil
+	}
+	if relay.createGroup {
+		_, err := relay.client.CreateLogGroupWithContext(relay.ctx, &cloudwatchlogs.CreateLogGroupInput{
+			LogGroupName: aws.String(relay.group),
+		})
+		if err != nil && !isAWSErrorCode(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+			return err
+		}
+	}
+	_, err := relay.client.CreateLogStreamWithContext(relay.ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(relay.group),
+		LogStreamName: aws.String(relay.stream),
+	})
+	if err != nil && !isAWSErrorCode(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+		return err
+	}
+	relay.streamCreated = true
+	return nil
+}
+
+// rejectedEvents returns the number of events of a batch of the given size that
+// CloudWatch Logs rejected
+func rejectedEvents(info *cloudwatchlogs.RejectedLogEventsInfo, events int) int {
+	if info == nil {
+		return 0
+	}
+	// the too old and expired end indexes are exclusive, the too new start index inclusive
+	tooOld := int(aws.Int64Value(info.TooOldLogEventEndIndex))
+	if expired := int(aws.Int64Value(info.ExpiredLogEventEndIndex)); expired > tooOld {
+		tooOld = expired
+	}
+	if tooOld > events {
+		tooOld = events
+	}
+	tooNew := events
+	if info.TooNewLogEventStartIndex != nil {
+		tooNew = int(aws.Int64Value(info.TooNewLogEventStartIndex))
+	}
+	if tooNew < tooOld {
+		tooNew = tooOld
+	}
+	return tooOld + events - tooNew
+}
+
+// isPermanentError returns whether CloudWatch Logs rejected a batch as invalid, so that
+// retrying it can't succeed
+func isPermanentError(err error) bool {
+	return isAWSErrorCode(err, cloudwatchlogs.ErrCodeInvalidParameterException)
+}
+
+// isRejectedError returns whether CloudWatch Logs was reached and rejected a request, rather
+// than being unreachable, failing or throttling the request
+func isRejectedError(err error) bool {
+	reqErr, ok := err.(awserr.RequestFailure)
+	if !ok || reqErr.Code() == throttlingErrorCode {
+		return false
+	}
+	return reqErr.StatusCode() >= http.StatusBadRequest && reqErr.StatusCode() < http.StatusInternalServerError &&
+		reqErr.StatusCode() != http.StatusTooManyRequests
+}
+
+func isAWSErrorCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
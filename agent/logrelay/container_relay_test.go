@@ -0,0 +1,177 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logrelay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	mock_logrelay "github.com/aws/amazon-ecs-agent/agent/logrelay/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDockerID = "dockerid"
+	testGroup    = "group"
+)
+
+func scanTestLogs(t *testing.T, logs []byte) []logEvent {
+	var events []logEvent
+	err := scanLogs(bytes.NewReader(logs), func(event logEvent) bool {
+		events = append(events, event)
+		return true
+	})
+	require.NoError(t, err)
+	return events
+}
+
+func TestScanLogsMultiplexed(t *testing.T) {
+	var logs bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&logs, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(&logs, stdcopy.Stderr)
+	stdout.Write([]byte("2023-01-02T03:04:05.000000001Z out\n"))
+	stderr.Write([]byte("2023-01-02T03:04:05.000000002Z err\n"))
+	stdout.Write([]byte("2023-01-02T03:04:05.000000003Z \n"))
+
+	events := scanTestLogs(t, logs.Bytes())
+	require.Len(t, events, 2)
+	assert.Equal(t, "out", events[0].Message)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 1, time.UTC), events[0].Timestamp)
+	assert.Equal(t, "err", events[1].Message)
+}
+
+func TestScanLogsTTY(t *testing.T) {
+	events := scanTestLogs(t, []byte("2023-01-02T03:04:05Z first\r\n2023-01-02T03:04:06Z "+
+		strings.Repeat("a", maxEventSize+1)))
+	require.Len(t, events, 2)
+	assert.Equal(t, "first", events[0].Message)
+	assert.Len(t, events[1].Message, maxEventSize)
+}
+
+func TestStreamName(t *testing.T) {
+	assert.Equal(t, testDockerID, streamName(testDockerID, map[string]string{}))
+	assert.Equal(t, "prefix/"+testDockerID, streamName(testDockerID, map[string]string{
+		streamPrefixOption: "prefix",
+	}))
+	assert.Equal(t, "stream", streamName(testDockerID, map[string]string{
+		streamPrefixOption: "prefix",
+		streamOption:       "stream",
+	}))
+}
+
+func TestRejectedEvents(t *testing.T) {
+	assert.Equal(t, 0, rejectedEvents(nil, 10))
+	assert.Equal(t, 5, rejectedEvents(&cloudwatchlogs.RejectedLogEventsInfo{
+		TooOldLogEventEndIndex:   aws.Int64(2),
+		ExpiredLogEventEndIndex:  aws.Int64(3),
+		TooNewLogEventStartIndex: aws.Int64(8),
+	}, 10))
+}
+
+func TestContainerRelayShipsLogs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_logrelay.NewMockCloudWatchLogsClient(ctrl)
+	streamer := mock_logrelay.NewMockContainerLogsStreamer(ctrl)
+	buffer, err := newLogBuffer(t.TempDir(), 1024*1024)
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&logs, stdcopy.Stdout)
+	stdout.Write([]byte("2023-01-02T03:04:05Z first\n"))
+	stdout.Write([]byte("2023-01-02T03:04:06Z second\n"))
+	streamer.EXPECT().ContainerLogs(gomock.Any(), testDockerID, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, dockerID string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+			assert.True(t, options.Follow)
+			assert.True(t, options.Timestamps)
+			assert.Empty(t, options.Since)
+			return ioutil.NopCloser(&logs), nil
+		})
+	gomock.InOrder(
+		client.EXPECT().CreateLogGroupWithContext(gomock.Any(), gomock.Any()).Return(nil,
+			awserr.New(cloudwatchlogs.ErrCodeResourceAlreadyExistsException, "exists", nil)),
+		client.EXPECT().CreateLogStreamWithContext(gomock.Any(), &cloudwatchlogs.CreateLogStreamInput{
+			LogGroupName:  aws.String(testGroup),
+			LogStreamName: aws.String("prefix/" + testDockerID),
+		}).Return(&cloudwatchlogs.CreateLogStreamOutput{}, nil),
+		client.EXPECT().PutLogEventsWithContext(gomock.Any(), gomock.Any()).Return(nil, errors.New("unreachable")),
+		client.EXPECT().PutLogEventsWithContext(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx aws.Context, input *cloudwatchlogs.PutLogEventsInput,
+				opts ...request.Option) (*cloudwatchlogs.PutLogEventsOutput, error) {
+				require.Len(t, input.LogEvents, 2)
+				assert.Equal(t, "first", aws.StringValue(input.LogEvents[0].Message))
+				assert.Equal(t, "second", aws.StringValue(input.LogEvents[1].Message))
+				return &cloudwatchlogs.PutLogEventsOutput{}, nil
+			}),
+	)
+
+	relay := newContainerRelay(context.TODO(), testDockerID, map[string]string{
+		groupOption:        testGroup,
+		streamPrefixOption: "prefix",
+		createGroupOption:  "true",
+	}, client, streamer, buffer)
+	assert.True(t, relay.run())
+	assert.Equal(t, 0, buffer.Len())
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC), buffer.Cursor())
+}
+
+func TestContainerRelayDropsInvalidBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_logrelay.NewMockCloudWatchLogsClient(ctrl)
+	streamer := mock_logrelay.NewMockContainerLogsStreamer(ctrl)
+	buffer, err := newLogBuffer(t.TempDir(), 1024*1024)
+	require.NoError(t, err)
+
+	streamer.EXPECT().ContainerLogs(gomock.Any(), testDockerID, gomock.Any()).Return(
+		ioutil.NopCloser(strings.NewReader("2023-01-02T03:04:05Z invalid\n")), nil)
+	client.EXPECT().CreateLogStreamWithContext(gomock.Any(), gomock.Any()).Return(
+		&cloudwatchlogs.CreateLogStreamOutput{}, nil)
+	// the batch is dropped without retrying it
+	client.EXPECT().PutLogEventsWithContext(gomock.Any(), gomock.Any()).Return(nil,
+		awserr.NewRequestFailure(awserr.New(cloudwatchlogs.ErrCodeInvalidParameterException, "invalid", nil),
+			400, "request-id"))
+
+	relay := newContainerRelay(context.TODO(), testDockerID, map[string]string{
+		groupOption: testGroup,
+	}, client, streamer, buffer)
+	assert.True(t, relay.run())
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestIsRejectedError(t *testing.T) {
+	assert.False(t, isRejectedError(errors.New("unreachable")))
+	assert.False(t, isRejectedError(awserr.NewRequestFailure(
+		awserr.New("ServiceUnavailableException", "unavailable", nil), 503, "request-id")))
+	assert.False(t, isRejectedError(awserr.NewRequestFailure(
+		awserr.New(throttlingErrorCode, "throttled", nil), 400, "request-id")))
+	assert.True(t, isRejectedError(awserr.NewRequestFailure(
+		awserr.New("AccessDeniedException", "denied", nil), 400, "request-id")))
+}
@@ -0,0 +1,45 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logrelay
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awscreds "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/docker/docker/api/types"
+)
+
+// CloudWatchLogsClient wraps the CloudWatch Logs API used by the relay.
+type CloudWatchLogsClient interface {
+	CreateLogGroupWithContext(ctx aws.Context, input *cloudwatchlogs.CreateLogGroupInput,
+		opts ...request.Option) (*cloudwatchlogs.CreateLogGroupOutput, error)
+	CreateLogStreamWithContext(ctx aws.Context, input *cloudwatchlogs.CreateLogStreamInput,
+		opts ...request.Option) (*cloudwatchlogs.CreateLogStreamOutput, error)
+	PutLogEventsWithContext(ctx aws.Context, input *cloudwatchlogs.PutLogEventsInput,
+		opts ...request.Option) (*cloudwatchlogs.PutLogEventsOutput, error)
+}
+
+// CloudWatchLogsClientCreator creates CloudWatch Logs clients.
+type CloudWatchLogsClientCreator interface {
+	NewCloudWatchLogsClient(region, endpoint string, creds *awscreds.Credentials) CloudWatchLogsClient
+}
+
+// ContainerLogsStreamer streams the logs of containers from their log driver.
+type ContainerLogsStreamer interface {
+	ContainerLogs(ctx context.Context, dockerID string, options types.ContainerLogsOptions) (io.ReadCloser, error)
+}
@@ -0,0 +1,327 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package logrelay ships the logs of the containers using the awslogs log driver to
+// CloudWatch Logs when the awslogs relay is enabled. These containers log to the
+// non-blocking local log driver instead, and the agent buffers their logs on disk while
+// CloudWatch Logs is unreachable, so that containers neither block on logging nor have
+// their logs dropped.
+package logrelay
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/credentials/instancecreds"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	awscreds "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/pkg/errors"
+)
+
+const (
+	// relayDir is the directory of ECS_DATADIR keeping the buffers of the relays
+	relayDir       = "logrelay"
+	relayStateFile = "relay.json"
+	bytesPerMB     = 1024 * 1024
+
+	groupOption               = "awslogs-group"
+	streamOption              = "awslogs-stream"
+	streamPrefixOption        = "awslogs-stream-prefix"
+	regionOption              = "awslogs-region"
+	createGroupOption         = "awslogs-create-group"
+	endpointOption            = "awslogs-endpoint"
+	credentialsEndpointOption = "awslogs-credentials-endpoint"
+	multilinePatternOption    = "awslogs-multiline-pattern"
+	datetimeFormatOption      = "awslogs-datetime-format"
+	modeOption                = "mode"
+	maxBufferSizeOption       = "max-buffer-size"
+)
+
+// relayedOptions are the awslogs log driver options used by the relay
+var relayedOptions = []string{
+	groupOption,
+	streamOption,
+	streamPrefixOption,
+	regionOption,
+	createGroupOption,
+	endpointOption,
+	credentialsEndpointOption,
+}
+
+// unsupportedOptions are the awslogs log driver options that group several log lines into
+// one event, which the relay doesn't do. The containers using them keep logging with the
+// awslogs log driver.
+var unsupportedOptions = []string{
+	multilinePatternOption,
+	datetimeFormatOption,
+}
+
+// localDriverOptions are the generic log options, such as the blocking mode, that are passed
+// through to the local log driver
+var localDriverOptions = []string{
+	modeOption,
+	maxBufferSizeOption,
+}
+
+// UnsupportedOption returns the first awslogs log driver option of a container that the
+// relay doesn't support, if any
+func UnsupportedOption(logOptions map[string]string) (string, bool) {
+	for _, option := range unsupportedOptions {
+		if _, ok := logOptions[option]; ok {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+// LocalDriverOptions returns the options of the local log driver that a container using the
+// awslogs log driver logs to while its logs are relayed
+func LocalDriverOptions(logOptions map[string]string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range localDriverOptions {
+		if value, ok := logOptions[option]; ok {
+			options[option] = value
+		}
+	}
+	return options
+}
+
+// RelayOptions returns the awslogs log driver options of a container used to relay its
+// logs, leaving out the other options such as secrets
+func RelayOptions(logOptions map[string]string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range relayedOptions {
+		if value, ok := logOptions[option]; ok {
+			options[option] = value
+		}
+	}
+	return options
+}
+
+// relayState is saved in the directory of each relay so that the relay is resumed when
+// the agent restarts
+type relayState struct {
+	Options                map[string]string `json:"options"`
+	ExecutionCredentialsID string            `json:"executionCredentialsID,omitempty"`
+}
+
+// Relay ships the logs of containers to CloudWatch Logs. A nil Relay, returned when the
+// awslogs relay is disabled, doesn't relay any logs.
+type Relay struct {
+	ctx                context.Context
+	cfg                *config.Config
+	streamer           ContainerLogsStreamer
+	clientCreator      CloudWatchLogsClientCreator
+	credentialsManager credentials.Manager
+	lock               sync.Mutex
+	relays             map[string]*containerRelay
+}
+
+// NewRelay returns the relay of the logs of containers, or nil when the awslogs relay is
+// disabled. The relays of the containers stop when the context is done.
+func NewRelay(ctx context.Context, cfg *config.Config, streamer ContainerLogsStreamer,
+	credentialsManager credentials.Manager) *Relay {
+	if !cfg.AWSLogsRelayEnabled.Enabled() {
+		return nil
+	}
+	return &Relay{
+		ctx:                ctx,
+		cfg:                cfg,
+		streamer:           streamer,
+		clientCreator:      NewCloudWatchLogsClientCreator(),
+		credentialsManager: credentialsManager,
+		relays:             make(map[string]*containerRelay),
+	}
+}
+
+// Start starts relaying the logs of a container with the given awslogs options, unless
+// they're already relayed. The execution credentials are used when the options specify
+// the credentials endpoint, and the instance credentials otherwise.
+func (relay *Relay) Start(dockerID string, options map[string]string, executionCredentialsID string) {
+	if relay == nil || dockerID == "" {
+		return
+	}
+	relay.lock.Lock()
+	defer relay.lock.Unlock()
+	if _, ok := relay.relays[dockerID]; ok {
+		return
+	}
+
+	state := relayState{
+		Options:                options,
+		ExecutionCredentialsID: executionCredentialsID,
+	}
+	dir := relay.containerDir(dockerID)
+	if err := saveRelayState(dir, state); err != nil {
+		logger.Error("Log relay: unable to start relaying container logs", logger.Fields{
+			field.RuntimeID: dockerID,
+			field.Error:     err,
+		})
+		return
+	}
+	relay.startUnsafe(dockerID, state)
+}
+
+// Resume resumes relaying the logs of the containers that were relayed before the agent
+// restarted, reading them again from the last event buffered on disk. The relays of the
+// containers that stopped since then ship their remaining logs and stop.
+func (relay *Relay) Resume() {
+	if relay == nil {
+		return
+	}
+	dirs, err := ioutil.ReadDir(filepath.Join(relay.cfg.DataDir, relayDir))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Log relay: unable to list relayed containers", logger.Fields{
+				field.Error: err,
+			})
+		}
+		return
+	}
+
+	relay.lock.Lock()
+	defer relay.lock.Unlock()
+	for _, dir := range dirs {
+		dockerID := dir.Name()
+		if _, ok := relay.relays[dockerID]; ok || !dir.IsDir() {
+			continue
+		}
+		state, err := loadRelayState(relay.containerDir(dockerID))
+		if err != nil {
+			logger.Warn("Log relay: unable to resume relaying container logs", logger.Fields{
+				field.RuntimeID: dockerID,
+				field.Error:     err,
+			})
+			continue
+		}
+		relay.startUnsafe(dockerID, state)
+	}
+}
+
+// Remove stops relaying the logs of a container that was removed, and removes the buffer of
+// its logs. The logs that weren't shipped yet are dropped, as the container was removed long
+// enough after it stopped for its logs to have been shipped unless CloudWatch Logs is unreachable.
+func (relay *Relay) Remove(dockerID string) {
+	if relay == nil || dockerID == "" {
+		return
+	}
+	relay.lock.Lock()
+	defer relay.lock.Unlock()
+	if containerRelay, ok := relay.relays[dockerID]; ok {
+		// the buffer is removed once the relay stopped
+		containerRelay.removed = true
+		containerRelay.cancel()
+		return
+	}
+	relay.removeContainerDir(dockerID)
+}
+
+func (relay *Relay) startUnsafe(dockerID string, state relayState) {
+	dir := relay.containerDir(dockerID)
+	buffer, err := newLogBuffer(dir, int64(relay.cfg.AWSLogsRelayBufferSizeMB)*bytesPerMB)
+	if err != nil {
+		logger.Error("Log relay: unable to start relaying container logs", logger.Fields{
+			field.RuntimeID: dockerID,
+			field.Error:     err,
+		})
+		return
+	}
+	region := state.Options[regionOption]
+	if region == "" {
+		region = relay.cfg.AWSRegion
+	}
+	client := relay.clientCreator.NewCloudWatchLogsClient(region, state.Options[endpointOption],
+		relay.credentials(state))
+	containerRelay := newContainerRelay(relay.ctx, dockerID, state.Options, client, relay.streamer, buffer)
+	relay.relays[dockerID] = containerRelay
+	logger.Info("Log relay: relaying container logs to CloudWatch Logs", logger.Fields{
+		field.RuntimeID: dockerID,
+		"logGroup":      containerRelay.group,
+		"logStream":     containerRelay.stream,
+	})
+
+	go func() {
+		done := containerRelay.run()
+		relay.lock.Lock()
+		defer relay.lock.Unlock()
+		delete(relay.relays, dockerID)
+		if containerRelay.removed && buffer.Len() > 0 {
+			logger.Warn("Log relay: container was removed, dropping its logs that weren't shipped", logger.Fields{
+				field.RuntimeID: dockerID,
+				"batches":       buffer.Len(),
+			})
+		}
+		buffer.Close()
+		if !done && !containerRelay.removed {
+			return
+		}
+		relay.removeContainerDir(dockerID)
+		if done {
+			logger.Info("Log relay: relayed all container logs", logger.Fields{
+				field.RuntimeID: dockerID,
+			})
+		}
+	}()
+}
+
+func (relay *Relay) removeContainerDir(dockerID string) {
+	if err := os.RemoveAll(relay.containerDir(dockerID)); err != nil {
+		logger.Warn("Log relay: unable to remove buffer of container logs", logger.Fields{
+			field.RuntimeID: dockerID,
+			field.Error:     err,
+		})
+	}
+}
+
+// credentials returns the credentials used to ship the logs of a container
+func (relay *Relay) credentials(state relayState) *awscreds.Credentials {
+	if _, ok := state.Options[credentialsEndpointOption]; ok && state.ExecutionCredentialsID != "" {
+		return awscreds.NewCredentials(&taskCredentialsProvider{
+			credentialsManager: relay.credentialsManager,
+			credentialsID:      state.ExecutionCredentialsID,
+		})
+	}
+	return instancecreds.GetCredentials()
+}
+
+func (relay *Relay) containerDir(dockerID string) string {
+	return filepath.Join(relay.cfg.DataDir, relayDir, dockerID)
+}
+
+func saveRelayState(dir string, state relayState) error {
+	if err := os.MkdirAll(dir, bufferDirPerm); err != nil {
+		return errors.Wrap(err, "log relay: unable to create relay directory")
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, relayStateFile), data, bufferFilePerm)
+}
+
+func loadRelayState(dir string) (relayState, error) {
+	var state relayState
+	data, err := ioutil.ReadFile(filepath.Join(dir, relayStateFile))
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
@@ -0,0 +1,172 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logrelay
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_logrelay "github.com/aws/amazon-ecs-agent/agent/logrelay/mocks"
+	awscreds "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClientCreator struct {
+	client  CloudWatchLogsClient
+	regions []string
+}
+
+func (creator *testClientCreator) NewCloudWatchLogsClient(region, endpoint string,
+	creds *awscreds.Credentials) CloudWatchLogsClient {
+	creator.regions = append(creator.regions, region)
+	return creator.client
+}
+
+func TestRelayOptions(t *testing.T) {
+	options := RelayOptions(map[string]string{
+		"awslogs-group":                "group",
+		"awslogs-region":               "us-west-2",
+		"awslogs-credentials-endpoint": "/v2/credentials/id",
+		"mode":                         "non-blocking",
+	})
+	assert.Equal(t, map[string]string{
+		"awslogs-group":                "group",
+		"awslogs-region":               "us-west-2",
+		"awslogs-credentials-endpoint": "/v2/credentials/id",
+	}, options)
+}
+
+func TestUnsupportedOption(t *testing.T) {
+	_, ok := UnsupportedOption(map[string]string{"awslogs-group": "group"})
+	assert.False(t, ok)
+	option, ok := UnsupportedOption(map[string]string{
+		"awslogs-group":           "group",
+		"awslogs-datetime-format": "%Y-%m-%d",
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "awslogs-datetime-format", option)
+}
+
+func TestLocalDriverOptions(t *testing.T) {
+	options := LocalDriverOptions(map[string]string{
+		"awslogs-group":   "group",
+		"mode":            "non-blocking",
+		"max-buffer-size": "4m",
+	})
+	assert.Equal(t, map[string]string{
+		"mode":            "non-blocking",
+		"max-buffer-size": "4m",
+	}, options)
+}
+
+func TestNewRelayDisabled(t *testing.T) {
+	relay := NewRelay(context.TODO(), &config.Config{}, nil, nil)
+	assert.Nil(t, relay)
+	// a nil relay doesn't relay anything
+	relay.Start(testDockerID, map[string]string{groupOption: testGroup}, "")
+	relay.Resume()
+	relay.Remove(testDockerID)
+}
+
+func TestRelayResumesStoppedContainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	streamer := mock_logrelay.NewMockContainerLogsStreamer(ctrl)
+	client := mock_logrelay.NewMockCloudWatchLogsClient(ctrl)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	cfg := &config.Config{
+		DataDir:                  t.TempDir(),
+		AWSRegion:                "us-east-1",
+		AWSLogsRelayEnabled:      config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+		AWSLogsRelayBufferSizeMB: 1,
+	}
+	relay := NewRelay(ctx, cfg, streamer, nil)
+	require.NotNil(t, relay)
+	creator := &testClientCreator{client: client}
+	relay.clientCreator = creator
+
+	dir := relay.containerDir(testDockerID)
+	require.NoError(t, saveRelayState(dir, relayState{
+		Options: map[string]string{groupOption: testGroup},
+	}))
+	// the container was removed while the agent was stopped
+	streamer.EXPECT().ContainerLogs(gomock.Any(), testDockerID, gomock.Any()).Return(nil,
+		dockerapi.NoSuchContainerError{ID: testDockerID})
+
+	relay.Resume()
+	for i := 0; i < 500; i++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err), "the buffer of the relay should be removed")
+	assert.Equal(t, []string{"us-east-1"}, creator.regions)
+	_, err = os.Stat(filepath.Join(cfg.DataDir, relayDir))
+	assert.NoError(t, err)
+}
+
+func TestRelayRemovesContainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	streamer := mock_logrelay.NewMockContainerLogsStreamer(ctrl)
+	client := mock_logrelay.NewMockCloudWatchLogsClient(ctrl)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	cfg := &config.Config{
+		DataDir:                  t.TempDir(),
+		AWSRegion:                "us-east-1",
+		AWSLogsRelayEnabled:      config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+		AWSLogsRelayBufferSizeMB: 1,
+	}
+	relay := NewRelay(ctx, cfg, streamer, nil)
+	require.NotNil(t, relay)
+	relay.clientCreator = &testClientCreator{client: client}
+
+	// the logs of the container are followed until the relay is stopped
+	streamer.EXPECT().ContainerLogs(gomock.Any(), testDockerID, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, dockerID string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	relay.Start(testDockerID, map[string]string{groupOption: testGroup}, "")
+	dir := relay.containerDir(testDockerID)
+	_, err := os.Stat(dir)
+	require.NoError(t, err)
+
+	relay.Remove(testDockerID)
+	for i := 0; i < 500; i++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err), "the buffer of a removed container should be removed")
+}
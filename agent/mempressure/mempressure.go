@@ -0,0 +1,58 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package mempressure measures the memory pressure of the task cgroups, from the memory
+// pressure stall information of the kernel and from the memory the tasks use
+package mempressure
+
+// Reading is the memory pressure of a cgroup at a point in time
+type Reading struct {
+	// StallKnown is true when the kernel reports the memory pressure stall information
+	// of the cgroup, which it only does on cgroup v2 hosts
+	StallKnown bool
+	// StallPercent is the share of the last 10 seconds, in percent, in which some
+	// process of the cgroup stalled waiting for memory
+	StallPercent float64
+	// UsageBytes is the memory the cgroup uses, without the inactive page cache the
+	// kernel can reclaim
+	UsageBytes uint64
+	// LimitBytes is the memory limit of the cgroup, or zero if it isn't limited
+	LimitBytes uint64
+}
+
+// Thresholds are the memory pressure above which a cgroup is under pressure
+type Thresholds struct {
+	// StallPercent is the share of time, in percent, the processes of the cgroup can
+	// stall waiting for memory
+	StallPercent float64
+	// UsagePercent is the memory usage, in percent of the memory limit
+	UsagePercent float64
+}
+
+// UsagePercent returns the memory usage of the cgroup in percent of its memory limit, or
+// zero if it isn't limited
+func (reading Reading) UsagePercent() float64 {
+	if reading.LimitBytes == 0 {
+		return 0
+	}
+	return float64(reading.UsageBytes) * 100 / float64(reading.LimitBytes)
+}
+
+// Exceeds returns true if the cgroup is under memory pressure, that is if its processes
+// stall waiting for memory or its usage nears its limit beyond the thresholds
+func (reading Reading) Exceeds(thresholds Thresholds) bool {
+	if reading.StallKnown && thresholds.StallPercent > 0 && reading.StallPercent >= thresholds.StallPercent {
+		return true
+	}
+	return reading.LimitBytes > 0 && thresholds.UsagePercent > 0 && reading.UsagePercent() >= thresholds.UsagePercent
+}
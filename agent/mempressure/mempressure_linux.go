@@ -0,0 +1,167 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mempressure
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupControllersFile = "cgroup.controllers"
+	memoryController      = "memory"
+
+	// interface files of the unified (cgroup v2) hierarchy
+	memoryPressureFile = "memory.pressure"
+	memoryCurrentFile  = "memory.current"
+	memoryMaxFile      = "memory.max"
+	memoryStatFile     = "memory.stat"
+	inactiveFileStat   = "inactive_file"
+
+	// interface files of the cgroup v1 memory controller
+	memoryUsageFile       = "memory.usage_in_bytes"
+	memoryLimitFile       = "memory.limit_in_bytes"
+	totalInactiveFileStat = "total_inactive_file"
+)
+
+// unlimitedV1Memory is the smallest memory limit of the cgroup v1 memory controller that
+// means the cgroup isn't limited
+const unlimitedV1Memory = uint64(1) << 62
+
+// cgroupMountPath is where the cgroup hierarchies are mounted. It's swappable for testing
+var cgroupMountPath = "/sys/fs/cgroup"
+
+// Read returns the memory pressure of the cgroup at the path, relative to the root of
+// the cgroup hierarchy, such as /ecs/<task id>
+func Read(cgroupPath string) (Reading, error) {
+	if _, err := os.Stat(filepath.Join(cgroupMountPath, cgroupControllersFile)); err == nil {
+		return readUnified(filepath.Join(cgroupMountPath, cgroupPath))
+	}
+	return readV1(filepath.Join(cgroupMountPath, memoryController, cgroupPath))
+}
+
+// readUnified reads the memory pressure of a cgroup of the unified hierarchy
+func readUnified(dir string) (Reading, error) {
+	var reading Reading
+	pressure, err := ioutil.ReadFile(filepath.Join(dir, memoryPressureFile))
+	switch {
+	case err == nil:
+		reading.StallPercent, err = parseStallAverage(string(pressure))
+		if err != nil {
+			return Reading{}, fmt.Errorf("unable to parse %s of cgroup %s: %w", memoryPressureFile, dir, err)
+		}
+		reading.StallKnown = true
+	case !os.IsNotExist(err):
+		// the file is missing when the kernel doesn't track the pressure stall information
+		return Reading{}, err
+	}
+
+	usage, err := readUint(filepath.Join(dir, memoryCurrentFile))
+	if err != nil {
+		return Reading{}, err
+	}
+	reading.UsageBytes = subtractInactiveFile(usage, filepath.Join(dir, memoryStatFile), inactiveFileStat)
+	limit, err := ioutil.ReadFile(filepath.Join(dir, memoryMaxFile))
+	if err != nil {
+		return Reading{}, err
+	}
+	if value := strings.TrimSpace(string(limit)); value != "max" {
+		reading.LimitBytes, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return Reading{}, fmt.Errorf("unable to parse %s of cgroup %s: %w", memoryMaxFile, dir, err)
+		}
+	}
+	return reading, nil
+}
+
+// readV1 reads the memory usage of a cgroup of the cgroup v1 memory controller, which
+// doesn't report the pressure stall information
+func readV1(dir string) (Reading, error) {
+	usage, err := readUint(filepath.Join(dir, memoryUsageFile))
+	if err != nil {
+		return Reading{}, err
+	}
+	limit, err := readUint(filepath.Join(dir, memoryLimitFile))
+	if err != nil {
+		return Reading{}, err
+	}
+	if limit >= unlimitedV1Memory {
+		// the limit of unlimited cgroups is the largest page aligned int64
+		limit = 0
+	}
+	return Reading{
+		UsageBytes: subtractInactiveFile(usage, filepath.Join(dir, memoryStatFile), totalInactiveFileStat),
+		LimitBytes: limit,
+	}, nil
+}
+
+// parseStallAverage returns the 10s average of the "some" line of a pressure stall
+// information file, such as "some avg10=1.53 avg60=0.87 avg300=0.22 total=1220417"
+func parseStallAverage(pressure string) (float64, error) {
+	for _, line := range strings.Split(pressure, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "avg10=") {
+				return strconv.ParseFloat(strings.TrimPrefix(field, "avg10="), 64)
+			}
+		}
+	}
+	return 0, fmt.Errorf("no avg10 of some in %q", pressure)
+}
+
+// subtractInactiveFile returns the memory usage without the inactive page cache, which
+// the kernel reclaims before running out of memory. The usage is returned as is if the
+// stat can't be read
+func subtractInactiveFile(usage uint64, statPath, stat string) uint64 {
+	file, err := os.Open(statPath)
+	if err != nil {
+		return usage
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != stat {
+			continue
+		}
+		inactive, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil || inactive > usage {
+			return usage
+		}
+		return usage - inactive
+	}
+	return usage
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	return value, nil
+}
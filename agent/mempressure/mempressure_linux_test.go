@@ -0,0 +1,110 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mempressure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCgroupPath = "/ecs/task-id"
+
+func setupCgroupMount(t *testing.T, unified bool, files map[string]string) func() {
+	mountPath, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	dir := filepath.Join(mountPath, memoryController, testCgroupPath)
+	if unified {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(mountPath, cgroupControllersFile), []byte("cpu io memory"), 0644))
+		dir = filepath.Join(mountPath, testCgroupPath)
+	}
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	cgroupMountPath = mountPath
+	return func() {
+		cgroupMountPath = "/sys/fs/cgroup"
+		os.RemoveAll(mountPath)
+	}
+}
+
+func TestReadUnified(t *testing.T) {
+	defer setupCgroupMount(t, true, map[string]string{
+		memoryPressureFile: "some avg10=42.50 avg60=10.00 avg300=2.00 total=1220417\nfull avg10=20.00 avg60=5.00 avg300=1.00 total=620417\n",
+		memoryCurrentFile:  "1073741824\n",
+		memoryMaxFile:      "2147483648\n",
+		memoryStatFile:     "anon 805306368\nfile 268435456\ninactive_file 268435456\n",
+	})()
+
+	reading, err := Read(testCgroupPath)
+	require.NoError(t, err)
+	assert.Equal(t, Reading{
+		StallKnown:   true,
+		StallPercent: 42.5,
+		UsageBytes:   805306368,
+		LimitBytes:   2147483648,
+	}, reading)
+	assert.Equal(t, 37.5, reading.UsagePercent())
+}
+
+func TestReadUnifiedUnlimitedWithoutPressure(t *testing.T) {
+	defer setupCgroupMount(t, true, map[string]string{
+		memoryCurrentFile: "1073741824\n",
+		memoryMaxFile:     "max\n",
+	})()
+
+	reading, err := Read(testCgroupPath)
+	require.NoError(t, err)
+	assert.Equal(t, Reading{UsageBytes: 1073741824}, reading)
+	assert.Zero(t, reading.UsagePercent())
+}
+
+func TestReadV1(t *testing.T) {
+	defer setupCgroupMount(t, false, map[string]string{
+		memoryUsageFile: "1073741824\n",
+		memoryLimitFile: "1073741824\n",
+		memoryStatFile:  "cache 134217728\ninactive_file 4096\ntotal_inactive_file 134217728\n",
+	})()
+
+	reading, err := Read(testCgroupPath)
+	require.NoError(t, err)
+	assert.Equal(t, Reading{UsageBytes: 939524096, LimitBytes: 1073741824}, reading)
+	assert.Equal(t, 87.5, reading.UsagePercent())
+}
+
+func TestReadV1Unlimited(t *testing.T) {
+	defer setupCgroupMount(t, false, map[string]string{
+		memoryUsageFile: "1073741824\n",
+		memoryLimitFile: "9223372036854771712\n",
+	})()
+
+	reading, err := Read(testCgroupPath)
+	require.NoError(t, err)
+	assert.Equal(t, Reading{UsageBytes: 1073741824}, reading)
+}
+
+func TestReadMissingCgroup(t *testing.T) {
+	defer setupCgroupMount(t, true, nil)()
+
+	_, err := Read("/ecs/other-task-id")
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mempressure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExceeds(t *testing.T) {
+	thresholds := Thresholds{StallPercent: 40, UsagePercent: 95}
+	for _, tc := range []struct {
+		name     string
+		reading  Reading
+		exceeded bool
+	}{
+		{"stalled", Reading{StallKnown: true, StallPercent: 40}, true},
+		{"not stalled", Reading{StallKnown: true, StallPercent: 39.9, UsageBytes: 90, LimitBytes: 100}, false},
+		{"near its limit", Reading{StallKnown: true, UsageBytes: 95, LimitBytes: 100}, true},
+		{"near its limit without stall information", Reading{UsageBytes: 99, LimitBytes: 100}, true},
+		{"unlimited", Reading{UsageBytes: 1 << 40}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exceeded, tc.reading.Exceeds(thresholds))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	EventHandlerSubsystem = "EventHandler"
+)
+
+// EventHandlerMetrics holds the collectors used to instrument the state change
+// event handler. Unlike GenericMetrics, these are not call based; they track
+// the depth of the per task event queues and how long events sit in them
+// before being accepted by ECS.
+type EventHandlerMetrics struct {
+	queueLength   *prometheus.GaugeVec
+	timeInQueue   *prometheus.SummaryVec
+	submitRetries *prometheus.CounterVec
+	backoff       *prometheus.GaugeVec
+}
+
+// NewEventHandlerMetrics creates the event handler collectors and registers
+// them with the registry
+func NewEventHandlerMetrics(registry *prometheus.Registry) *EventHandlerMetrics {
+	queueLength := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: AgentNamespace,
+		Subsystem: EventHandlerSubsystem,
+		Name:      "queue_length",
+		Help:      "Number of state change events waiting to be submitted for a task",
+	}, []string{"Task"})
+	registry.MustRegister(queueLength)
+
+	timeInQueue := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:  AgentNamespace,
+		Subsystem:  EventHandlerSubsystem,
+		Name:       "time_in_queue_seconds",
+		Help:       "Time between a state change event being queued and being accepted by ECS",
+		Objectives: make(map[float64]float64),
+	}, []string{"EventType"})
+	registry.MustRegister(timeInQueue)
+
+	submitRetries := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: EventHandlerSubsystem,
+		Name:      "submit_retries",
+		Help:      "Number of failed state change submissions that were retried for a task",
+	}, []string{"Task"})
+	registry.MustRegister(submitRetries)
+
+	backoff := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: AgentNamespace,
+		Subsystem: EventHandlerSubsystem,
+		Name:      "backoff_seconds",
+		Help:      "Current backoff duration before the next submission attempt for a task, 0 if not backing off",
+	}, []string{"Task"})
+	registry.MustRegister(backoff)
+
+	return &EventHandlerMetrics{
+		queueLength:   queueLength,
+		timeInQueue:   timeInQueue,
+		submitRetries: submitRetries,
+		backoff:       backoff,
+	}
+}
+
+// SetEventHandlerQueueLength records the number of events queued for a task
+func (engine *MetricsEngine) SetEventHandlerQueueLength(taskARN string, length int) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.eventHandlerMetrics.queueLength.WithLabelValues(taskARN).Set(float64(length))
+}
+
+// RecordEventHandlerTimeInQueue records how long an event of the given type
+// was queued before it was submitted successfully
+func (engine *MetricsEngine) RecordEventHandlerTimeInQueue(eventType string, duration time.Duration) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.eventHandlerMetrics.timeInQueue.WithLabelValues(eventType).Observe(duration.Seconds())
+}
+
+// IncrementEventHandlerSubmitRetries increments the submission retry count for a task
+func (engine *MetricsEngine) IncrementEventHandlerSubmitRetries(taskARN string) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.eventHandlerMetrics.submitRetries.WithLabelValues(taskARN).Inc()
+}
+
+// SetEventHandlerBackoff records the current submission backoff for a task
+func (engine *MetricsEngine) SetEventHandlerBackoff(taskARN string, duration time.Duration) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.eventHandlerMetrics.backoff.WithLabelValues(taskARN).Set(duration.Seconds())
+}
+
+// RemoveEventHandlerTaskMetrics drops the per task series once the handler
+// stops tracking events for the task, so that the label cardinality does not
+// grow with the number of tasks ever run on the instance
+func (engine *MetricsEngine) RemoveEventHandlerTaskMetrics(taskARN string) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.eventHandlerMetrics.queueLength.DeleteLabelValues(taskARN)
+	engine.eventHandlerMetrics.submitRetries.DeleteLabelValues(taskARN)
+	engine.eventHandlerMetrics.backoff.DeleteLabelValues(taskARN)
+}
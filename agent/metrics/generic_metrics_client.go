@@ -30,15 +30,18 @@ const (
 	callTimeout = 2 * time.Minute
 )
 
-// A GenericMetricsClient records 3 metrics:
+// A GenericMetricsClient records 4 metrics:
 // 1) A Prometheus summary vector representing call durations for different API calls
 // 2) A durations guage vector that updates the last recorded duration for the API call
 // 	  allowing for a time series view in the Prometheus browser
 // 3) A counter vector that increments call counts for each API call
+// 4) A histogram vector bucketing call durations, so that latency quantiles can
+//    be aggregated by the scraper
 // The outstandingCalls map allows Fired CallStarts to be matched with Fired CallEnds
 type GenericMetrics struct {
 	durationVec      *prometheus.SummaryVec
 	durations        *prometheus.GaugeVec
+	latencyVec       *prometheus.HistogramVec
 	counterVec       *prometheus.CounterVec
 	lock             sync.RWMutex
 	outstandingCalls map[string]time.Time
@@ -109,6 +112,7 @@ func (gm *GenericMetrics) FireCallEnd(callHash, callName string, timestamp time.
 		seconds := timestamp.Sub(timeStart)
 		gm.durationVec.WithLabelValues(callName).Observe(seconds.Seconds())
 		gm.durations.WithLabelValues(callName).Set(seconds.Seconds())
+		gm.latencyVec.WithLabelValues(callName).Observe(seconds.Seconds())
 		delete(gm.outstandingCalls, callHash)
 	} else {
 		seelog.Errorf("Call is not outstanding: %s", callName)
@@ -0,0 +1,92 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	ImageCleanupSubsystem = "ImageCleanup"
+
+	// ImageCleanupTriggerAge labels the images deleted because they were
+	// unused for long enough
+	ImageCleanupTriggerAge = "age"
+	// ImageCleanupTriggerDiskPressure labels the images deleted because the
+	// docker data root disk usage crossed the high watermark
+	ImageCleanupTriggerDiskPressure = "disk_pressure"
+)
+
+// ImageCleanupMetrics holds the collectors used to instrument the image
+// cleanup, to track how much disk space it reclaims and why
+type ImageCleanupMetrics struct {
+	imagesDeleted  *prometheus.CounterVec
+	bytesReclaimed *prometheus.CounterVec
+	diskUsage      *prometheus.GaugeVec
+}
+
+// NewImageCleanupMetrics creates the image cleanup collectors and registers
+// them with the registry
+func NewImageCleanupMetrics(registry *prometheus.Registry) *ImageCleanupMetrics {
+	imagesDeleted := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: ImageCleanupSubsystem,
+		Name:      "images_deleted",
+		Help:      "Number of images deleted by the image cleanup",
+	}, []string{"Trigger"})
+	registry.MustRegister(imagesDeleted)
+
+	bytesReclaimed := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: ImageCleanupSubsystem,
+		Name:      "bytes_reclaimed",
+		Help:      "Size in bytes of the images deleted by the image cleanup",
+	}, []string{"Trigger"})
+	registry.MustRegister(bytesReclaimed)
+
+	diskUsage := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: AgentNamespace,
+		Subsystem: ImageCleanupSubsystem,
+		Name:      "disk_usage_percent",
+		Help:      "Usage in percent of the docker data root disk when it was last measured by the image cleanup",
+	}, []string{"Path"})
+	registry.MustRegister(diskUsage)
+
+	return &ImageCleanupMetrics{
+		imagesDeleted:  imagesDeleted,
+		bytesReclaimed: bytesReclaimed,
+		diskUsage:      diskUsage,
+	}
+}
+
+// RecordImageCleanupDeletion records an image deleted by the image cleanup
+// for the trigger, and the bytes it reclaimed
+func (engine *MetricsEngine) RecordImageCleanupDeletion(trigger string, size int64) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.imageCleanupMetrics.imagesDeleted.WithLabelValues(trigger).Inc()
+	if size > 0 {
+		engine.imageCleanupMetrics.bytesReclaimed.WithLabelValues(trigger).Add(float64(size))
+	}
+}
+
+// SetImageCleanupDiskUsage records the last measured usage, in percent, of
+// the disk that holds the docker data root
+func (engine *MetricsEngine) SetImageCleanupDiskUsage(dataRoot string, usage float64) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.imageCleanupMetrics.diskUsage.WithLabelValues(dataRoot).Set(usage)
+}
@@ -0,0 +1,91 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	LogRelaySubsystem = "LogRelay"
+)
+
+// LogRelayMetrics holds the collectors used to track the backpressure of the
+// awslogs relay shipping container logs to CloudWatch Logs
+type LogRelayMetrics struct {
+	bufferedBytes *prometheus.GaugeVec
+	events        *prometheus.CounterVec
+	batches       *prometheus.CounterVec
+}
+
+// NewLogRelayMetrics creates the log relay collectors and registers them with
+// the registry
+func NewLogRelayMetrics(registry *prometheus.Registry) *LogRelayMetrics {
+	bufferedBytes := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: AgentNamespace,
+		Subsystem: LogRelaySubsystem,
+		Name:      "buffered_bytes",
+		Help:      "Size in bytes of the container logs waiting to be shipped to CloudWatch Logs",
+	}, []string{"Buffer"})
+	registry.MustRegister(bufferedBytes)
+
+	events := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: LogRelaySubsystem,
+		Name:      "events",
+		Help:      "Number of container log events shipped to CloudWatch Logs, rejected by it, or dropped because the buffer was full",
+	}, []string{"Outcome"})
+	registry.MustRegister(events)
+
+	batches := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: LogRelaySubsystem,
+		Name:      "batches",
+		Help:      "Number of batches of container log events sent to CloudWatch Logs, by outcome",
+	}, []string{"Outcome"})
+	registry.MustRegister(batches)
+
+	return &LogRelayMetrics{
+		bufferedBytes: bufferedBytes,
+		events:        events,
+		batches:       batches,
+	}
+}
+
+// AddLogRelayBufferedBytes records a change in the size of the container logs
+// kept in a buffer ("memory" or "disk") of the log relay
+func (engine *MetricsEngine) AddLogRelayBufferedBytes(buffer string, delta int64) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.logRelayMetrics.bufferedBytes.WithLabelValues(buffer).Add(float64(delta))
+}
+
+// RecordLogRelayEvents records the outcome ("sent", "rejected" or "dropped") of
+// container log events handled by the log relay
+func (engine *MetricsEngine) RecordLogRelayEvents(outcome string, count int) {
+	if engine == nil || !engine.collection || count == 0 {
+		return
+	}
+	engine.logRelayMetrics.events.WithLabelValues(outcome).Add(float64(count))
+}
+
+// RecordLogRelayBatch records the outcome ("sent" or "failed") of a batch of
+// container log events sent to CloudWatch Logs by the log relay
+func (engine *MetricsEngine) RecordLogRelayBatch(outcome string) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.logRelayMetrics.batches.WithLabelValues(outcome).Inc()
+}
@@ -14,8 +14,9 @@
 package metrics
 
 import (
-	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/config"
@@ -30,6 +31,18 @@ type MetricsEngine struct {
 	cfg            *config.Config
 	Registry       *prometheus.Registry
 	managedMetrics map[APIType]MetricsClient
+	// eventHandlerMetrics tracks the state change event queues, which are
+	// not modelled as API calls and so are kept outside of managedMetrics
+	eventHandlerMetrics *EventHandlerMetrics
+	// imageCleanupMetrics tracks the images deleted by the image manager
+	imageCleanupMetrics *ImageCleanupMetrics
+	// taskCredentialsMetrics tracks the expiry of task IAM role credentials
+	taskCredentialsMetrics *TaskCredentialsMetrics
+	// taskMetadataMetrics tracks the requests served by the task metadata
+	// endpoints
+	taskMetadataMetrics *TaskMetadataMetrics
+	// logRelayMetrics tracks the backpressure of the awslogs relay
+	logRelayMetrics *LogRelayMetrics
 }
 
 const (
@@ -91,6 +104,11 @@ func NewMetricsEngine(cfg *config.Config, registry *prometheus.Registry) *Metric
 		aClient := NewMetricsClient(managedAPI, metricsEngine.Registry)
 		metricsEngine.managedMetrics[managedAPI] = aClient
 	}
+	metricsEngine.eventHandlerMetrics = NewEventHandlerMetrics(metricsEngine.Registry)
+	metricsEngine.imageCleanupMetrics = NewImageCleanupMetrics(metricsEngine.Registry)
+	metricsEngine.taskCredentialsMetrics = NewTaskCredentialsMetrics(metricsEngine.Registry)
+	metricsEngine.taskMetadataMetrics = NewTaskMetadataMetrics(metricsEngine.Registry)
+	metricsEngine.logRelayMetrics = NewLogRelayMetrics(metricsEngine.Registry)
 	return metricsEngine
 }
 
@@ -140,14 +158,17 @@ func (engine *MetricsEngine) recordMetric(apiType APIType, callName, callID stri
 	return engine.managedMetrics[apiType].RecordCall(callID, callName, time.Now(), callStarted)
 }
 
-// Function that exposes all Agent Metrics on a given port.
+// Function that exposes all Agent Metrics on a given port. The endpoint
+// listens on ECS_PROMETHEUS_METRICS_BIND_ADDRESS, which defaults to 127.0.0.1
+// so metrics can only be scraped from the instance itself.
 func (engine *MetricsEngine) publishMetrics() {
 	go func() {
 		// Because we are using the DefaultRegisterer in Prometheus, we can use
 		// the promhttp.Handler() function. In future cases for custom registers,
 		// we can use promhttp.HandlerFor(customRegisterer, promhttp.HandlerOpts{})
 		http.Handle("/metrics", promhttp.Handler())
-		err := http.ListenAndServe(fmt.Sprintf(":%d", config.AgentPrometheusExpositionPort), nil)
+		err := http.ListenAndServe(net.JoinHostPort(engine.cfg.PrometheusMetricsBindAddress,
+			strconv.Itoa(config.AgentPrometheusExpositionPort)), nil)
 		if err != nil {
 			seelog.Errorf("Error publishing metrics: %s", err.Error())
 		}
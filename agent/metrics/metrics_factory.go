@@ -72,10 +72,20 @@ func NewGenericMetricsClient(subsystem string, registry *prometheus.Registry) *G
 		[]string{"Call"})
 	registry.MustRegister(aGaugeVec)
 
+	aHistogramVec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: AgentNamespace,
+		Subsystem: subsystem,
+		Name:      "latency_seconds",
+		Help:      subsystem + " call latency in seconds",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"Call"})
+	registry.MustRegister(aHistogramVec)
+
 	genericMetrics := &GenericMetrics{
 		durationVec:      aDurationVec,
 		counterVec:       aCounterVec,
 		durations:        aGaugeVec,
+		latencyVec:       aHistogramVec,
 		outstandingCalls: make(map[string]time.Time),
 	}
 	return genericMetrics
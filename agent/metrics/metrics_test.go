@@ -153,6 +153,15 @@ func TestMetricCollection(t *testing.T) {
 		"SUMMARY",
 		1.5,
 	}
+	expected["AgentMetrics_DockerAPI_latency_seconds"] = make(map[string][]interface{})
+	expected["AgentMetrics_DockerAPI_latency_seconds"]["CallSTART"] = []interface{}{
+		"HISTOGRAM",
+		1.5,
+	}
+	expected["AgentMetrics_DockerAPI_latency_seconds"]["CallSTOP"] = []interface{}{
+		"HISTOGRAM",
+		1.5,
+	}
 	// We will do a simple tree search to verify all metrics in metricsFamilies
 	// are as expected
 	assert.True(t, verifyStats(metricFamilies, expected), "Metrics are not accurate")
@@ -190,6 +199,11 @@ func verifyStats(metricsReceived []*dto.MetricFamily, expectedMetrics metricMap)
 							fmt.Printf("Does not match SUMMARY. Expected: %f, Received: %f\n", metricValExpected, metric.GetSummary().GetSampleSum()/float64(metric.GetSummary().GetSampleCount()))
 							return false
 						}
+					case "HISTOGRAM":
+						if !compareDiff(metricValExpected, metric.GetHistogram().GetSampleSum()/float64(metric.GetHistogram().GetSampleCount()), threshhold) {
+							fmt.Printf("Does not match HISTOGRAM. Expected: %f, Received: %f\n", metricValExpected, metric.GetHistogram().GetSampleSum()/float64(metric.GetHistogram().GetSampleCount()))
+							return false
+						}
 					default:
 						fmt.Println("Metric Type not recognized")
 						return false
@@ -215,3 +229,159 @@ func compareDiff(a, b, deltaMin float64) bool {
 	}
 	return diff <= (a * deltaMin)
 }
+
+func TestEventHandlerMetrics(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	cfg := getTestConfig()
+	MustInit(&cfg, prometheus.NewRegistry())
+
+	MetricsEngineGlobal.SetEventHandlerQueueLength("task1", 3)
+	MetricsEngineGlobal.IncrementEventHandlerSubmitRetries("task1")
+	MetricsEngineGlobal.IncrementEventHandlerSubmitRetries("task1")
+	MetricsEngineGlobal.SetEventHandlerBackoff("task1", 2*time.Second)
+	MetricsEngineGlobal.RecordEventHandlerTimeInQueue("task", 4*time.Second)
+
+	metricFamilies, err := MetricsEngineGlobal.Registry.Gather()
+	assert.NoError(t, err)
+	expected := make(metricMap)
+	expected["AgentMetrics_EventHandler_submit_retries"] = map[string][]interface{}{
+		"Tasktask1": {"COUNTER", 2.0},
+	}
+	expected["AgentMetrics_EventHandler_time_in_queue_seconds"] = map[string][]interface{}{
+		"EventTypetask": {"SUMMARY", 4.0},
+	}
+	expected["AgentMetrics_EventHandler_queue_length"] = map[string][]interface{}{
+		"Tasktask1": {"GUAGE", 3.0},
+	}
+	expected["AgentMetrics_EventHandler_backoff_seconds"] = map[string][]interface{}{
+		"Tasktask1": {"GUAGE", 2.0},
+	}
+	assert.True(t, verifyStats(metricFamilies, expected), "Metrics are not accurate")
+
+	MetricsEngineGlobal.RemoveEventHandlerTaskMetrics("task1")
+	metricFamilies, err = MetricsEngineGlobal.Registry.Gather()
+	assert.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		assert.NotEqual(t, "AgentMetrics_EventHandler_queue_length", metricFamily.GetName())
+	}
+}
+
+func TestImageCleanupMetrics(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	cfg := getTestConfig()
+	MustInit(&cfg, prometheus.NewRegistry())
+
+	MetricsEngineGlobal.RecordImageCleanupDeletion(ImageCleanupTriggerAge, 100)
+	MetricsEngineGlobal.RecordImageCleanupDeletion(ImageCleanupTriggerDiskPressure, 200)
+	MetricsEngineGlobal.RecordImageCleanupDeletion(ImageCleanupTriggerDiskPressure, 300)
+	MetricsEngineGlobal.SetImageCleanupDiskUsage("/var/lib/docker", 72.5)
+
+	metricFamilies, err := MetricsEngineGlobal.Registry.Gather()
+	assert.NoError(t, err)
+	expected := make(metricMap)
+	expected["AgentMetrics_ImageCleanup_images_deleted"] = map[string][]interface{}{
+		"Triggerage":           {"COUNTER", 1.0},
+		"Triggerdisk_pressure": {"COUNTER", 2.0},
+	}
+	expected["AgentMetrics_ImageCleanup_bytes_reclaimed"] = map[string][]interface{}{
+		"Triggerage":           {"COUNTER", 100.0},
+		"Triggerdisk_pressure": {"COUNTER", 500.0},
+	}
+	expected["AgentMetrics_ImageCleanup_disk_usage_percent"] = map[string][]interface{}{
+		"Path/var/lib/docker": {"GUAGE", 72.5},
+	}
+	assert.True(t, verifyStats(metricFamilies, expected), "Metrics are not accurate")
+}
+
+func TestTaskCredentialsMetrics(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	cfg := getTestConfig()
+	MustInit(&cfg, prometheus.NewRegistry())
+
+	MetricsEngineGlobal.SetTaskCredentialsExpiry("TaskApplication", 1, -30)
+	MetricsEngineGlobal.SetTaskCredentialsExpiry("TaskExecution", 0, 3600)
+	MetricsEngineGlobal.RecordTaskCredentialsRefreshRequest("TaskApplication")
+
+	metricFamilies, err := MetricsEngineGlobal.Registry.Gather()
+	assert.NoError(t, err)
+	expected := make(metricMap)
+	expected["AgentMetrics_TaskCredentials_expired_credentials"] = map[string][]interface{}{
+		"RoleTypeTaskApplication": {"GUAGE", 1.0},
+		"RoleTypeTaskExecution":   {"GUAGE", 0.0},
+	}
+	expected["AgentMetrics_TaskCredentials_min_seconds_to_expiry"] = map[string][]interface{}{
+		"RoleTypeTaskApplication": {"GUAGE", -30.0},
+		"RoleTypeTaskExecution":   {"GUAGE", 3600.0},
+	}
+	expected["AgentMetrics_TaskCredentials_refresh_requests"] = map[string][]interface{}{
+		"RoleTypeTaskApplication": {"COUNTER", 1.0},
+	}
+	assert.True(t, verifyStats(metricFamilies, expected), "Metrics are not accurate")
+}
+
+func TestTaskMetadataMetrics(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	cfg := getTestConfig()
+	MustInit(&cfg, prometheus.NewRegistry())
+
+	MetricsEngineGlobal.RecordTaskMetadataRequest("/v4/{id}/task", 200, 10*time.Millisecond)
+	MetricsEngineGlobal.RecordTaskMetadataRequest("/v4/{id}/task", 200, 30*time.Millisecond)
+
+	metricFamilies, err := MetricsEngineGlobal.Registry.Gather()
+	assert.NoError(t, err)
+	expected := make(metricMap)
+	expected["AgentMetrics_TaskMetadata_requests"] = map[string][]interface{}{
+		"Path/v4/{id}/task": {"COUNTER", 2.0},
+	}
+	expected["AgentMetrics_TaskMetadata_request_duration_seconds"] = map[string][]interface{}{
+		"Path/v4/{id}/task": {"HISTOGRAM", 0.02},
+	}
+	assert.True(t, verifyStats(metricFamilies, expected), "Metrics are not accurate")
+}
+
+func TestLogRelayMetrics(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	cfg := getTestConfig()
+	MustInit(&cfg, prometheus.NewRegistry())
+
+	MetricsEngineGlobal.AddLogRelayBufferedBytes("disk", 2048)
+	MetricsEngineGlobal.AddLogRelayBufferedBytes("disk", -1024)
+	MetricsEngineGlobal.RecordLogRelayEvents("sent", 10)
+	MetricsEngineGlobal.RecordLogRelayEvents("dropped", 3)
+	MetricsEngineGlobal.RecordLogRelayBatch("failed")
+
+	metricFamilies, err := MetricsEngineGlobal.Registry.Gather()
+	assert.NoError(t, err)
+	expected := make(metricMap)
+	expected["AgentMetrics_LogRelay_buffered_bytes"] = map[string][]interface{}{
+		"Bufferdisk": {"GUAGE", 1024.0},
+	}
+	expected["AgentMetrics_LogRelay_events"] = map[string][]interface{}{
+		"Outcomesent":    {"COUNTER", 10.0},
+		"Outcomedropped": {"COUNTER", 3.0},
+	}
+	expected["AgentMetrics_LogRelay_batches"] = map[string][]interface{}{
+		"Outcomefailed": {"COUNTER", 1.0},
+	}
+	assert.True(t, verifyStats(metricFamilies, expected), "Metrics are not accurate")
+}
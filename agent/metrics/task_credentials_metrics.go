@@ -0,0 +1,83 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	TaskCredentialsSubsystem = "TaskCredentials"
+)
+
+// TaskCredentialsMetrics holds the collectors used to track the expiry of the
+// IAM role credentials of running tasks
+type TaskCredentialsMetrics struct {
+	expiredCredentials *prometheus.GaugeVec
+	secondsToExpiry    *prometheus.GaugeVec
+	refreshRequests    *prometheus.CounterVec
+}
+
+// NewTaskCredentialsMetrics creates the task credentials collectors and
+// registers them with the registry
+func NewTaskCredentialsMetrics(registry *prometheus.Registry) *TaskCredentialsMetrics {
+	expiredCredentials := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: AgentNamespace,
+		Subsystem: TaskCredentialsSubsystem,
+		Name:      "expired_credentials",
+		Help:      "Number of running tasks with expired IAM role credentials",
+	}, []string{"RoleType"})
+	registry.MustRegister(expiredCredentials)
+
+	secondsToExpiry := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: AgentNamespace,
+		Subsystem: TaskCredentialsSubsystem,
+		Name:      "min_seconds_to_expiry",
+		Help:      "Time in seconds until the first IAM role credentials of running tasks expire, negative once expired",
+	}, []string{"RoleType"})
+	registry.MustRegister(secondsToExpiry)
+
+	refreshRequests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: TaskCredentialsSubsystem,
+		Name:      "refresh_requests",
+		Help:      "Number of times credentials were requested from ACS because IAM role credentials of running tasks were about to expire",
+	}, []string{"RoleType"})
+	registry.MustRegister(refreshRequests)
+
+	return &TaskCredentialsMetrics{
+		expiredCredentials: expiredCredentials,
+		secondsToExpiry:    secondsToExpiry,
+		refreshRequests:    refreshRequests,
+	}
+}
+
+// SetTaskCredentialsExpiry records, for a role type, the number of running tasks
+// with expired credentials and the time until the first credentials expire
+func (engine *MetricsEngine) SetTaskCredentialsExpiry(roleType string, expired int, secondsToExpiry float64) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.taskCredentialsMetrics.expiredCredentials.WithLabelValues(roleType).Set(float64(expired))
+	engine.taskCredentialsMetrics.secondsToExpiry.WithLabelValues(roleType).Set(secondsToExpiry)
+}
+
+// RecordTaskCredentialsRefreshRequest records that credentials were requested from
+// ACS because credentials of the role type were about to expire
+func (engine *MetricsEngine) RecordTaskCredentialsRefreshRequest(roleType string) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.taskCredentialsMetrics.refreshRequests.WithLabelValues(roleType).Inc()
+}
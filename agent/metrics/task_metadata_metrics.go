@@ -0,0 +1,69 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	TaskMetadataSubsystem = "TaskMetadata"
+)
+
+// TaskMetadataMetrics holds the collectors used to track the requests served
+// by the task metadata and credentials endpoints
+type TaskMetadataMetrics struct {
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+// NewTaskMetadataMetrics creates the task metadata endpoint collectors and
+// registers them with the registry
+func NewTaskMetadataMetrics(registry *prometheus.Registry) *TaskMetadataMetrics {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: TaskMetadataSubsystem,
+		Name:      "requests",
+		Help:      "Number of requests served by the task metadata endpoints",
+	}, []string{"Path", "StatusCode"})
+	registry.MustRegister(requests)
+
+	requestDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: AgentNamespace,
+		Subsystem: TaskMetadataSubsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Time in seconds taken to serve requests to the task metadata endpoints",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"Path"})
+	registry.MustRegister(requestDuration)
+
+	return &TaskMetadataMetrics{
+		requests:        requests,
+		requestDuration: requestDuration,
+	}
+}
+
+// RecordTaskMetadataRequest records a request served by the task metadata
+// endpoints. The path is expected to be the route template rather than the
+// request path, so that the number of label values stays bounded
+func (engine *MetricsEngine) RecordTaskMetadataRequest(path string, statusCode int, duration time.Duration) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.taskMetadataMetrics.requests.WithLabelValues(path, strconv.Itoa(statusCode)).Inc()
+	engine.taskMetadataMetrics.requestDuration.WithLabelValues(path).Observe(duration.Seconds())
+}
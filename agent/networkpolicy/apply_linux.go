@@ -0,0 +1,126 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecscni"
+	"github.com/aws/amazon-ecs-agent/agent/utils/nswrapper"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netns"
+)
+
+const (
+	iptablesRestoreBinary = "iptables-restore"
+	// ip6tablesRestoreBinary programs the rules of IPv6 CIDR blocks
+	ip6tablesRestoreBinary = "ip6tables-restore"
+)
+
+var (
+	// execCommand, nsWrapper, openNetNS, createNfacct and readNfacctPackets are
+	// swappable for testing
+	execCommand       = exec.CommandContext
+	nsWrapper         = nswrapper.NewNS()
+	openNetNS         = netns.GetFromPath
+	createNfacct      = newNfacct
+	readNfacctPackets = nfacctPackets
+)
+
+// Apply programs the rules of the policy in the network namespace of the process. The
+// rules are added to a dedicated chain, without flushing the rules already set up in
+// the namespace by the CNI plugins.
+func Apply(ctx context.Context, pid string, policy *Policy) error {
+	if policy == nil {
+		return nil
+	}
+	netns := fmt.Sprintf(ecscni.NetnsFormat, pid)
+	for _, family := range []struct {
+		ipv6   bool
+		binary string
+	}{
+		{false, iptablesRestoreBinary},
+		{true, ip6tablesRestoreBinary},
+	} {
+		rules := policy.rulesFor(family.ipv6)
+		if rules == "" {
+			continue
+		}
+		if err := restore(ctx, netns, family.binary, rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BlockInstanceMetadata blocks the access to the instance metadata service, over IPv4
+// and IPv6, in the network namespace of the process, and counts the blocked attempts in
+// an accounting object of the namespace. It returns the identity of the namespace, which
+// InstanceMetadataBlockedAttempts checks before reading the counter, as the pid may be
+// reused once the process exits.
+func BlockInstanceMetadata(ctx context.Context, pid string) (string, error) {
+	netnsPath := fmt.Sprintf(ecscni.NetnsFormat, pid)
+	handle, err := openNetNS(netnsPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to open the network namespace %s", netnsPath)
+	}
+	defer handle.Close()
+	if err := createNfacct(handle, instanceMetadataNfacctName); err != nil {
+		return "", err
+	}
+	if err := restore(ctx, netnsPath, iptablesRestoreBinary, instanceMetadataRules(false)); err != nil {
+		return "", err
+	}
+	if err := restore(ctx, netnsPath, ip6tablesRestoreBinary, instanceMetadataRules(true)); err != nil {
+		return "", err
+	}
+	return handle.UniqueId(), nil
+}
+
+// InstanceMetadataBlockedAttempts returns the number of packets to the instance metadata
+// service rejected in the network namespace of the process, read over netlink from the
+// accounting object of the namespace. It fails if the namespace of the process isn't the
+// one the access was blocked in.
+func InstanceMetadataBlockedAttempts(ctx context.Context, pid string, netnsID string) (uint64, error) {
+	netnsPath := fmt.Sprintf(ecscni.NetnsFormat, pid)
+	handle, err := openNetNS(netnsPath)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to open the network namespace %s", netnsPath)
+	}
+	defer handle.Close()
+	if id := handle.UniqueId(); id != netnsID {
+		return 0, errors.Errorf("the network namespace of process %s is %s, not %s", pid, id, netnsID)
+	}
+	return readNfacctPackets(handle, instanceMetadataNfacctName)
+}
+
+// restore runs the iptables-restore binary on the rules in the network namespace,
+// without flushing the existing rules. The binary is started from the thread locked in
+// the network namespace, which the process inherits.
+func restore(ctx context.Context, netns string, binary string, rules string) error {
+	return nsWrapper.WithNetNSPath(netns, func(ns.NetNS) error {
+		cmd := execCommand(ctx, binary, "--noflush")
+		cmd.Stdin = strings.NewReader(rules)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "%s failed: %s", binary, strings.TrimSpace(string(out)))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkpolicy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/nswrapper"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netns"
+)
+
+// fakeNS records the network namespaces entered, and runs the functions in the current one
+type fakeNS struct {
+	nswrapper.NS
+	commands *[]string
+}
+
+func (fake fakeNS) WithNetNSPath(nspath string, toRun func(ns.NetNS) error) error {
+	*fake.commands = append(*fake.commands, "netns "+nspath)
+	return toRun(nil)
+}
+
+// setupFakeIPTables records the commands run, and makes them exit with the status
+func setupFakeIPTables(exitStatus string) (*[]string, func()) {
+	var commands []string
+	nsWrapper = fakeNS{commands: &commands}
+	execCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		cs := []string{"-test.run=TestHelperProcess", "--", name}
+		cmd := exec.CommandContext(ctx, os.Args[0], append(cs, args...)...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "EXIT_STATUS=" + exitStatus}
+		return cmd
+	}
+	return &commands, func() {
+		execCommand = exec.CommandContext
+		nsWrapper = nswrapper.NewNS()
+	}
+}
+
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	if os.Getenv("EXIT_STATUS") != "0" {
+		os.Stderr.WriteString("iptables-restore: line 3 failed")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestApply(t *testing.T) {
+	commands, cleanup := setupFakeIPTables("0")
+	defer cleanup()
+
+	assert.NoError(t, Apply(context.TODO(), "1234", &Policy{Rules: []Rule{
+		{Action: ActionDeny, CIDR: "169.254.169.254/32"},
+	}}))
+	assert.Equal(t, []string{"netns /host/proc/1234/ns/net", "iptables-restore --noflush"}, *commands)
+
+	*commands = nil
+	assert.NoError(t, Apply(context.TODO(), "1234", &Policy{Rules: []Rule{
+		{Action: ActionDeny, CIDR: "10.0.0.0/8"},
+		{Action: ActionDeny, CIDR: "fd00::/8"},
+	}}))
+	assert.Equal(t, []string{
+		"netns /host/proc/1234/ns/net",
+		"iptables-restore --noflush",
+		"netns /host/proc/1234/ns/net",
+		"ip6tables-restore --noflush",
+	}, *commands)
+
+	*commands = nil
+	assert.NoError(t, Apply(context.TODO(), "1234", nil))
+	assert.Empty(t, *commands)
+}
+
+func TestApplyFailure(t *testing.T) {
+	_, cleanup := setupFakeIPTables("1")
+	defer cleanup()
+
+	err := Apply(context.TODO(), "1234", &Policy{Rules: []Rule{
+		{Action: ActionDeny, CIDR: "169.254.169.254/32"},
+	}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3 failed")
+}
+
+func fakeNetNSFile(path string) string {
+	return strings.Replace(path, "/", "_", -1)
+}
+
+// setupFakeNetNS makes the network namespaces regular files, whose identities are the
+// ones of the files, and records the accounting objects created
+func setupFakeNetNS(dir string, packets uint64) (*[]string, func()) {
+	var created []string
+	openNetNS = func(path string) (netns.NsHandle, error) {
+		file := filepath.Join(dir, fakeNetNSFile(path))
+		fd, err := syscall.Open(file, syscall.O_RDONLY|syscall.O_CREAT, 0600)
+		return netns.NsHandle(fd), err
+	}
+	createNfacct = func(handle netns.NsHandle, name string) error {
+		created = append(created, name)
+		return nil
+	}
+	readNfacctPackets = func(handle netns.NsHandle, name string) (uint64, error) {
+		return packets, nil
+	}
+	return &created, func() {
+		openNetNS = netns.GetFromPath
+		createNfacct = newNfacct
+		readNfacctPackets = nfacctPackets
+	}
+}
+
+func TestBlockInstanceMetadata(t *testing.T) {
+	commands, cleanup := setupFakeIPTables("0")
+	defer cleanup()
+	created, cleanupNetNS := setupFakeNetNS(t.TempDir(), 0)
+	defer cleanupNetNS()
+
+	netnsID, err := BlockInstanceMetadata(context.TODO(), "1234")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, netnsID)
+	assert.Equal(t, []string{"ecs-task-imds"}, *created)
+	assert.Equal(t, []string{
+		"netns /host/proc/1234/ns/net",
+		"iptables-restore --noflush",
+		"netns /host/proc/1234/ns/net",
+		"ip6tables-restore --noflush",
+	}, *commands)
+}
+
+func TestBlockInstanceMetadataFailure(t *testing.T) {
+	_, cleanup := setupFakeIPTables("1")
+	defer cleanup()
+	_, cleanupNetNS := setupFakeNetNS(t.TempDir(), 0)
+	defer cleanupNetNS()
+
+	_, err := BlockInstanceMetadata(context.TODO(), "1234")
+	assert.Error(t, err)
+}
+
+func TestInstanceMetadataBlockedAttempts(t *testing.T) {
+	commands, cleanup := setupFakeIPTables("0")
+	defer cleanup()
+	_, cleanupNetNS := setupFakeNetNS(t.TempDir(), 3)
+	defer cleanupNetNS()
+
+	netnsID, err := BlockInstanceMetadata(context.TODO(), "1234")
+	require.NoError(t, err)
+	*commands = nil
+
+	attempts, err := InstanceMetadataBlockedAttempts(context.TODO(), "1234", netnsID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), attempts)
+	// The counter is read over netlink, without running any command.
+	assert.Empty(t, *commands)
+}
+
+func TestInstanceMetadataBlockedAttemptsOtherNetNS(t *testing.T) {
+	_, cleanup := setupFakeIPTables("0")
+	defer cleanup()
+	dir := t.TempDir()
+	_, cleanupNetNS := setupFakeNetNS(dir, 3)
+	defer cleanupNetNS()
+
+	netnsID, err := BlockInstanceMetadata(context.TODO(), "1234")
+	require.NoError(t, err)
+
+	// The pid was reused by a process in another network namespace.
+	other := filepath.Join(dir, "other")
+	require.NoError(t, ioutil.WriteFile(other, nil, 0600))
+	require.NoError(t, os.Rename(other, filepath.Join(dir, fakeNetNSFile("/host/proc/1234/ns/net"))))
+	_, err = InstanceMetadataBlockedAttempts(context.TODO(), "1234", netnsID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkpolicy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Apply is not supported on this platform, and fails for any policy with rules
+func Apply(ctx context.Context, pid string, policy *Policy) error {
+	if policy == nil || len(policy.Rules) == 0 {
+		return nil
+	}
+	return errors.New("task network policies are not supported on this platform")
+}
+
+// BlockInstanceMetadata is not supported on this platform
+func BlockInstanceMetadata(ctx context.Context, pid string) (string, error) {
+	return "", errors.New("blocking the instance metadata service is not supported on this platform")
+}
+
+// InstanceMetadataBlockedAttempts is not supported on this platform
+func InstanceMetadataBlockedAttempts(ctx context.Context, pid string, netnsID string) (uint64, error) {
+	return 0, errors.New("blocking the instance metadata service is not supported on this platform")
+}
@@ -0,0 +1,212 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package networkpolicy implements the egress network policy of tasks, and the blocking
+// of their access to the instance metadata service, which are enforced with iptables
+// rules inside the network namespace of awsvpc tasks.
+package networkpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ActionAllow accepts the traffic matched by a rule
+	ActionAllow = "allow"
+	// ActionDeny rejects the traffic matched by a rule
+	ActionDeny = "deny"
+
+	// ProtocolTCP, ProtocolUDP and ProtocolAll are the protocols a rule can match.
+	// Rules without a protocol match all protocols.
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+	ProtocolAll = "all"
+
+	// chainName is the iptables chain holding the rules of the policy
+	chainName = "ECS-TASK-POLICY"
+	// credentialsEndpointCIDR is the task credentials and metadata endpoint, which is
+	// always allowed so that the policy can't break the agent's own endpoints
+	credentialsEndpointCIDR = "169.254.170.2/32"
+	maxPort                 = 65535
+
+	// instanceMetadataChainName is the iptables chain blocking the access to the instance
+	// metadata service, and instanceMetadataNfacctName the accounting object counting the
+	// packets it rejects, which are the blocked attempts
+	instanceMetadataChainName  = "ECS-TASK-IMDS"
+	instanceMetadataNfacctName = "ecs-task-imds"
+	instanceMetadataIPv4CIDR   = "169.254.169.254/32"
+	instanceMetadataIPv6CIDR   = "fd00:ec2::254/128"
+)
+
+// Policy is an ordered list of egress rules. The first rule matching a packet decides
+// whether it's allowed; packets that match no rule are allowed.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule allows or denies the egress traffic to a CIDR block, optionally restricted to a
+// protocol and a range of destination ports
+type Rule struct {
+	Action   string `json:"action"`
+	CIDR     string `json:"cidr"`
+	Protocol string `json:"protocol,omitempty"`
+	FromPort int    `json:"fromPort,omitempty"`
+	ToPort   int    `json:"toPort,omitempty"`
+}
+
+// Load reads and validates the policy document at the path
+func Load(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read network policy %s", path)
+	}
+	policy := &Policy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse network policy %s", path)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid network policy %s", path)
+	}
+	return policy, nil
+}
+
+// Validate returns an error if any rule of the policy is invalid
+func (policy *Policy) Validate() error {
+	for i, rule := range policy.Rules {
+		if err := rule.validate(); err != nil {
+			return errors.Wrapf(err, "rule %d", i)
+		}
+	}
+	return nil
+}
+
+func (rule Rule) validate() error {
+	switch strings.ToLower(rule.Action) {
+	case ActionAllow, ActionDeny:
+	default:
+		return errors.Errorf("invalid action %q", rule.Action)
+	}
+	if _, err := rule.network(); err != nil {
+		return err
+	}
+	switch strings.ToLower(rule.Protocol) {
+	case "", ProtocolAll:
+		if rule.FromPort != 0 || rule.ToPort != 0 {
+			return errors.New("ports require the tcp or udp protocol")
+		}
+	case ProtocolTCP, ProtocolUDP:
+	default:
+		return errors.Errorf("invalid protocol %q", rule.Protocol)
+	}
+	if rule.FromPort < 0 || rule.FromPort > maxPort || rule.ToPort < 0 || rule.ToPort > maxPort {
+		return errors.Errorf("invalid port range %d-%d", rule.FromPort, rule.ToPort)
+	}
+	if rule.ToPort != 0 && rule.ToPort < rule.FromPort {
+		return errors.Errorf("invalid port range %d-%d", rule.FromPort, rule.ToPort)
+	}
+	return nil
+}
+
+// network returns the CIDR block of the rule. A bare IP address is a single host block.
+func (rule Rule) network() (*net.IPNet, error) {
+	if ip := net.ParseIP(rule.CIDR); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipNet, err := net.ParseCIDR(rule.CIDR)
+	if err != nil {
+		return nil, errors.Errorf("invalid cidr %q", rule.CIDR)
+	}
+	return ipNet, nil
+}
+
+// rulesFor returns the iptables-restore input enforcing the rules of the policy that
+// apply to the IP family, or an empty string when there are none
+func (policy *Policy) rulesFor(ipv6 bool) string {
+	var rules []string
+	for _, rule := range policy.Rules {
+		ipNet, err := rule.network()
+		if err != nil || (ipNet.IP.To4() == nil) != ipv6 {
+			continue
+		}
+		rules = append(rules, rule.iptablesRule(ipNet))
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "*filter")
+	fmt.Fprintf(&buf, ":%s - [0:0]\n", chainName)
+	fmt.Fprintf(&buf, "-A OUTPUT -j %s\n", chainName)
+	fmt.Fprintf(&buf, "-A %s -o lo -j ACCEPT\n", chainName)
+	fmt.Fprintf(&buf, "-A %s -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT\n", chainName)
+	if !ipv6 {
+		fmt.Fprintf(&buf, "-A %s -d %s -j ACCEPT\n", chainName, credentialsEndpointCIDR)
+	}
+	for _, rule := range rules {
+		fmt.Fprintln(&buf, rule)
+	}
+	fmt.Fprintln(&buf, "COMMIT")
+	return buf.String()
+}
+
+func (rule Rule) iptablesRule(ipNet *net.IPNet) string {
+	args := []string{"-A", chainName, "-d", ipNet.String()}
+	protocol := strings.ToLower(rule.Protocol)
+	if protocol != "" && protocol != ProtocolAll {
+		args = append(args, "-p", protocol)
+		if rule.FromPort != 0 || rule.ToPort != 0 {
+			ports := fmt.Sprint(rule.FromPort)
+			if rule.ToPort > rule.FromPort {
+				ports = fmt.Sprintf("%d:%d", rule.FromPort, rule.ToPort)
+			}
+			args = append(args, "--dport", ports)
+		}
+	}
+	target := "ACCEPT"
+	if strings.EqualFold(rule.Action, ActionDeny) {
+		target = "REJECT"
+	}
+	return strings.Join(append(args, "-j", target), " ")
+}
+
+// instanceMetadataRules returns the iptables-restore input blocking the access to the
+// instance metadata service for the IP family. The chain is inserted first in OUTPUT,
+// so that the attempts are counted even when other rules also block them.
+func instanceMetadataRules(ipv6 bool) string {
+	cidr := instanceMetadataIPv4CIDR
+	if ipv6 {
+		cidr = instanceMetadataIPv6CIDR
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "*filter")
+	fmt.Fprintf(&buf, ":%s - [0:0]\n", instanceMetadataChainName)
+	fmt.Fprintf(&buf, "-I OUTPUT 1 -j %s\n", instanceMetadataChainName)
+	fmt.Fprintf(&buf, "-A %s -d %s -m nfacct --nfacct-name %s -j REJECT\n",
+		instanceMetadataChainName, cidr, instanceMetadataNfacctName)
+	fmt.Fprintln(&buf, "COMMIT")
+	return buf.String()
+}
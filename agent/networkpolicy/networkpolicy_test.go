@@ -0,0 +1,118 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkpolicy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"rules": [
+		{"action": "deny", "cidr": "169.254.169.254"},
+		{"action": "allow", "cidr": "10.0.0.0/16", "protocol": "tcp", "fromPort": 443}
+	]}`), 0600))
+
+	policy, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Action: ActionDeny, CIDR: "169.254.169.254"},
+		{Action: ActionAllow, CIDR: "10.0.0.0/16", Protocol: ProtocolTCP, FromPort: 443},
+	}, policy.Rules)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"rules": [{"action": "drop", "cidr": "10.0.0.0/8"}]}`), 0600))
+	_, err = Load(path)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rule  Rule
+		valid bool
+	}{
+		{"cidr", Rule{Action: ActionDeny, CIDR: "10.0.0.0/8"}, true},
+		{"ipv6 cidr", Rule{Action: ActionDeny, CIDR: "fd00::/8"}, true},
+		{"address", Rule{Action: "ALLOW", CIDR: "10.0.0.1"}, true},
+		{"port range", Rule{Action: ActionDeny, CIDR: "0.0.0.0/0", Protocol: ProtocolUDP, FromPort: 53, ToPort: 54}, true},
+		{"invalid action", Rule{Action: "drop", CIDR: "10.0.0.0/8"}, false},
+		{"invalid cidr", Rule{Action: ActionDeny, CIDR: "10.0.0.0/33"}, false},
+		{"invalid protocol", Rule{Action: ActionDeny, CIDR: "10.0.0.0/8", Protocol: "icmp"}, false},
+		{"ports without protocol", Rule{Action: ActionDeny, CIDR: "10.0.0.0/8", FromPort: 80}, false},
+		{"invalid port", Rule{Action: ActionDeny, CIDR: "10.0.0.0/8", Protocol: ProtocolTCP, FromPort: 70000}, false},
+		{"inverted port range", Rule{Action: ActionDeny, CIDR: "10.0.0.0/8", Protocol: ProtocolTCP, FromPort: 90, ToPort: 80}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&Policy{Rules: []Rule{tc.rule}}).Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRulesFor(t *testing.T) {
+	policy := &Policy{Rules: []Rule{
+		{Action: ActionDeny, CIDR: "169.254.169.254"},
+		{Action: ActionAllow, CIDR: "10.0.0.0/16"},
+		{Action: ActionDeny, CIDR: "10.0.0.0/8", Protocol: ProtocolTCP, FromPort: 8000, ToPort: 9000},
+		{Action: ActionDeny, CIDR: "fd00:ec2::254", Protocol: ProtocolTCP, FromPort: 80},
+	}}
+
+	assert.Equal(t, `*filter
+:ECS-TASK-POLICY - [0:0]
+-A OUTPUT -j ECS-TASK-POLICY
+-A ECS-TASK-POLICY -o lo -j ACCEPT
+-A ECS-TASK-POLICY -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+-A ECS-TASK-POLICY -d 169.254.170.2/32 -j ACCEPT
+-A ECS-TASK-POLICY -d 169.254.169.254/32 -j REJECT
+-A ECS-TASK-POLICY -d 10.0.0.0/16 -j ACCEPT
+-A ECS-TASK-POLICY -d 10.0.0.0/8 -p tcp --dport 8000:9000 -j REJECT
+COMMIT
+`, policy.rulesFor(false))
+
+	assert.Equal(t, `*filter
+:ECS-TASK-POLICY - [0:0]
+-A OUTPUT -j ECS-TASK-POLICY
+-A ECS-TASK-POLICY -o lo -j ACCEPT
+-A ECS-TASK-POLICY -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+-A ECS-TASK-POLICY -d fd00:ec2::254/128 -p tcp --dport 80 -j REJECT
+COMMIT
+`, policy.rulesFor(true))
+
+	assert.Empty(t, (&Policy{}).rulesFor(false))
+}
+
+func TestInstanceMetadataRules(t *testing.T) {
+	assert.Equal(t, `*filter
+:ECS-TASK-IMDS - [0:0]
+-I OUTPUT 1 -j ECS-TASK-IMDS
+-A ECS-TASK-IMDS -d 169.254.169.254/32 -m nfacct --nfacct-name ecs-task-imds -j REJECT
+COMMIT
+`, instanceMetadataRules(false))
+	assert.Contains(t, instanceMetadataRules(true), "-A ECS-TASK-IMDS -d fd00:ec2::254/128 -m nfacct --nfacct-name ecs-task-imds -j REJECT")
+}
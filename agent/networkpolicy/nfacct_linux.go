@@ -0,0 +1,122 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkpolicy
+
+import (
+	"encoding/binary"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+)
+
+// Constants of the nfnetlink_acct netlink interface, from
+// include/uapi/linux/netfilter/nfnetlink.h and nfnetlink_acct.h
+const (
+	netlinkNetfilter  = 12
+	nfnetlinkV0       = 0
+	nfnlSubsysAcct    = 7
+	nfnlMsgAcctNew    = 0
+	nfnlMsgAcctGet    = 1
+	nfacctAttrName    = 1
+	nfacctAttrPackets = 2
+	// nlaTypeMask strips the nested and byte order flags of an attribute type
+	nlaTypeMask = 0x3fff
+)
+
+// nfgenmsg is the header of the nfnetlink messages
+type nfgenmsg struct {
+	family  uint8
+	version uint8
+	resID   uint16
+}
+
+func (msg *nfgenmsg) Len() int {
+	return 4
+}
+
+func (msg *nfgenmsg) Serialize() []byte {
+	b := make([]byte, msg.Len())
+	b[0] = msg.family
+	b[1] = msg.version
+	binary.BigEndian.PutUint16(b[2:], msg.resID)
+	return b
+}
+
+// nfacctRequest returns the nfnetlink_acct request of the type for the accounting object
+func nfacctRequest(msgType int, flags int, name string) *nl.NetlinkRequest {
+	req := nl.NewNetlinkRequest(nfnlSubsysAcct<<8|msgType, flags)
+	req.AddData(&nfgenmsg{family: syscall.AF_UNSPEC, version: nfnetlinkV0})
+	req.AddData(nl.NewRtAttr(nfacctAttrName, nl.ZeroTerminated(name)))
+	return req
+}
+
+// executeAt runs the netlink request on a netfilter netlink socket opened in the network
+// namespace, so that the thread of the agent never has to stay in it
+func executeAt(handle netns.NsHandle, req *nl.NetlinkRequest, resType uint16) ([][]byte, error) {
+	socket, err := nl.GetNetlinkSocketAt(handle, netns.None(), netlinkNetfilter)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open a netfilter netlink socket in the network namespace")
+	}
+	sockets := map[int]*nl.SocketHandle{netlinkNetfilter: {Socket: socket}}
+	defer sockets[netlinkNetfilter].Close()
+	req.Sockets = sockets
+	return req.Execute(netlinkNetfilter, resType)
+}
+
+// newNfacct creates the accounting object in the network namespace. An object that
+// already exists, and which the rules of the namespace may already use, is kept.
+func newNfacct(handle netns.NsHandle, name string) error {
+	req := nfacctRequest(nfnlMsgAcctNew, syscall.NLM_F_CREATE|syscall.NLM_F_ACK, name)
+	_, err := executeAt(handle, req, 0)
+	if err == syscall.EBUSY {
+		return nil
+	}
+	return errors.Wrapf(err, "unable to create the %s accounting object", name)
+}
+
+// nfacctPackets returns the packet counter of the accounting object in the network
+// namespace
+func nfacctPackets(handle netns.NsHandle, name string) (uint64, error) {
+	msgs, err := executeAt(handle, nfacctRequest(nfnlMsgAcctGet, syscall.NLM_F_ACK, name),
+		nfnlSubsysAcct<<8|nfnlMsgAcctNew)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to get the %s accounting object", name)
+	}
+	if len(msgs) == 0 {
+		return 0, errors.Errorf("no %s accounting object", name)
+	}
+	return parseNfacctPackets(msgs[0])
+}
+
+// parseNfacctPackets returns the packet counter of an nfnetlink_acct message
+func parseNfacctPackets(msg []byte) (uint64, error) {
+	header := &nfgenmsg{}
+	if len(msg) < header.Len() {
+		return 0, errors.New("short accounting object message")
+	}
+	attrs, err := nl.ParseRouteAttr(msg[header.Len():])
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to parse the accounting object message")
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nlaTypeMask == nfacctAttrPackets && len(attr.Value) == 8 {
+			return binary.BigEndian.Uint64(attr.Value), nil
+		}
+	}
+	return 0, errors.New("no packet counter in the accounting object message")
+}
@@ -0,0 +1,59 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkpolicy
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+)
+
+func TestNfacctRequest(t *testing.T) {
+	req := nfacctRequest(nfnlMsgAcctGet, syscall.NLM_F_ACK, "ecs-task-imds")
+	data := req.Serialize()
+	require.Len(t, data, syscall.SizeofNlMsghdr+4+20)
+
+	native := nl.NativeEndian()
+	assert.Equal(t, uint16(nfnlSubsysAcct<<8|nfnlMsgAcctGet), native.Uint16(data[4:6]))
+	assert.Equal(t, uint16(syscall.NLM_F_REQUEST|syscall.NLM_F_ACK), native.Uint16(data[6:8]))
+	// The nfgenmsg header, then the name attribute
+	assert.Equal(t, []byte{syscall.AF_UNSPEC, nfnetlinkV0, 0, 0}, data[16:20])
+	assert.Equal(t, uint16(18), native.Uint16(data[20:22]))
+	assert.Equal(t, uint16(nfacctAttrName), native.Uint16(data[22:24]))
+	assert.Equal(t, "ecs-task-imds\x00", string(data[24:38]))
+}
+
+func TestParseNfacctPackets(t *testing.T) {
+	packets := make([]byte, 8)
+	binary.BigEndian.PutUint64(packets, 42)
+	msg := []byte{syscall.AF_UNSPEC, nfnetlinkV0, 0, 0}
+	msg = append(msg, nl.NewRtAttr(nfacctAttrName, nl.ZeroTerminated("ecs-task-imds")).Serialize()...)
+	// The attribute type may carry the network byte order flag.
+	msg = append(msg, nl.NewRtAttr(nfacctAttrPackets|syscall.NLA_F_NET_BYTEORDER, packets).Serialize()...)
+
+	count, err := parseNfacctPackets(msg)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), count)
+
+	_, err = parseNfacctPackets(msg[:24])
+	assert.Error(t, err)
+	_, err = parseNfacctPackets(msg[:2])
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package neuron
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	devicePrefix  = "neuron"
+	coreCountFile = "core_count"
+)
+
+var (
+	// sysDevicesPath is where the Neuron driver exposes the devices
+	sysDevicesPath = "/sys/devices/virtual/neuron_device"
+	// devPath is where the device nodes are
+	devPath = "/dev"
+)
+
+// discoverDevices returns the Neuron devices of the instance, in the order of their
+// indexes. It returns no device when the Neuron driver isn't loaded
+func discoverDevices() ([]Device, error) {
+	paths, err := filepath.Glob(filepath.Join(sysDevicesPath, devicePrefix+"[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, path := range paths {
+		index, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), devicePrefix))
+		if err != nil {
+			continue
+		}
+		coreCount, err := ioutil.ReadFile(filepath.Join(path, coreCountFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the core count of neuron device %d: %w", index, err)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(string(coreCount)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the core count of neuron device %d: %w", index, err)
+		}
+		devices = append(devices, Device{
+			Index:     index,
+			Path:      filepath.Join(devPath, devicePrefix+strconv.Itoa(index)),
+			CoreCount: count,
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Index < devices[j].Index })
+	firstCore := 0
+	for i := range devices {
+		devices[i].FirstCore = firstCore
+		firstCore += devices[i].CoreCount
+	}
+	return devices, nil
+}
@@ -0,0 +1,70 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package neuron
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverDevices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "neuron")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { sysDevicesPath = "/sys/devices/virtual/neuron_device" }()
+	sysDevicesPath = dir
+
+	for name, coreCount := range map[string]string{"neuron10": "2\n", "neuron2": "4\n", "neuron0": "2\n"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name, coreCountFile), []byte(coreCount), 0644))
+	}
+
+	devices, err := discoverDevices()
+	require.NoError(t, err)
+	assert.Equal(t, []Device{
+		{Index: 0, Path: "/dev/neuron0", CoreCount: 2, FirstCore: 0},
+		{Index: 2, Path: "/dev/neuron2", CoreCount: 4, FirstCore: 2},
+		{Index: 10, Path: "/dev/neuron10", CoreCount: 2, FirstCore: 6},
+	}, devices)
+}
+
+func TestDiscoverDevicesWithoutDriver(t *testing.T) {
+	defer func() { sysDevicesPath = "/sys/devices/virtual/neuron_device" }()
+	sysDevicesPath = "/nonexistent"
+
+	devices, err := discoverDevices()
+	assert.NoError(t, err)
+	assert.Empty(t, devices)
+}
+
+func TestDiscoverDevicesInvalidCoreCount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "neuron")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { sysDevicesPath = "/sys/devices/virtual/neuron_device" }()
+	sysDevicesPath = dir
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "neuron0"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "neuron0", coreCountFile), []byte("two"), 0644))
+
+	_, err = discoverDevices()
+	assert.Error(t, err)
+}
@@ -0,0 +1,220 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package neuron discovers the Neuron devices of Inferentia and Trainium instances, and
+// keeps track of the neuron cores assigned to the containers of each task
+package neuron
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// coreIDPrefix prefixes the ids the neuron cores are registered with
+const coreIDPrefix = "neuron-core-"
+
+// Device is a Neuron device of the instance
+type Device struct {
+	// Index is the number of the device, as in /dev/neuron<Index>
+	Index int
+	// Path is the path of the device node
+	Path string
+	// CoreCount is the number of neuron cores of the device
+	CoreCount int
+	// FirstCore is the instance wide index of the first core of the device. The cores
+	// of the devices are numbered in the order of the devices
+	FirstCore int
+}
+
+// Assignment is the neuron cores assigned to a container, and the devices they belong to
+type Assignment struct {
+	// CoreIDs are the instance wide indexes of the cores
+	CoreIDs []int
+	// Devices are the paths of the devices of the cores, in the order of their indexes
+	Devices []string
+	// VisibleCores are the indexes of the cores among the cores of the devices, which is
+	// how the Neuron runtime numbers them in a container the devices are passed to
+	VisibleCores []int
+}
+
+// Manager discovers the Neuron devices of the instance and assigns their cores to tasks
+type Manager interface {
+	// Initialize discovers the Neuron devices of the instance
+	Initialize() error
+	// GetDevices returns the neuron cores as platform devices, to register them
+	GetDevices() []*ecs.PlatformDevice
+	// Assign assigns free cores to a container of the task
+	Assign(taskARN string, cores int) (Assignment, error)
+	// Reserve records the cores assigned to the task before the agent restarted
+	Reserve(taskARN string, coreIDs []int) error
+	// Release frees the cores assigned to the task
+	Release(taskARN string)
+}
+
+// manager implements Manager
+type manager struct {
+	devices []Device
+	// assignments maps the cores assigned to a task to its arn
+	assignments map[int]string
+	lock        sync.RWMutex
+}
+
+// NewManager returns a manager of the Neuron devices of the instance
+func NewManager() Manager {
+	return &manager{
+		assignments: make(map[int]string),
+	}
+}
+
+// newManagerWithDevices returns a manager of the given devices
+func newManagerWithDevices(devices []Device) *manager {
+	return &manager{
+		devices:     devices,
+		assignments: make(map[int]string),
+	}
+}
+
+// Initialize discovers the Neuron devices of the instance
+func (m *manager) Initialize() error {
+	devices, err := discoverDevices()
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.devices = devices
+	return nil
+}
+
+// GetDevices returns the neuron cores as platform devices, to register them
+func (m *manager) GetDevices() []*ecs.PlatformDevice {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	devices := make([]*ecs.PlatformDevice, 0)
+	for _, device := range m.devices {
+		for core := device.FirstCore; core < device.FirstCore+device.CoreCount; core++ {
+			devices = append(devices, &ecs.PlatformDevice{
+				Id:   aws.String(coreIDPrefix + strconv.Itoa(core)),
+				Type: aws.String(ecs.PlatformDeviceTypeNeuronCore),
+			})
+		}
+	}
+	return devices
+}
+
+// Assign assigns free cores to a container of the task. The cores are taken from a single
+// device when one has enough of them free, the one with the fewest, to keep the devices
+// with more free cores for larger containers
+func (m *manager) Assign(taskARN string, cores int) (Assignment, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if cores <= 0 {
+		return Assignment{}, fmt.Errorf("invalid number of neuron cores %d", cores)
+	}
+	var best []int
+	var allFree []int
+	for _, device := range m.devices {
+		free := m.freeCoresUnsafe(device)
+		allFree = append(allFree, free...)
+		if len(free) >= cores && (best == nil || len(free) < len(best)) {
+			best = free
+		}
+	}
+	if best == nil {
+		if len(allFree) < cores {
+			return Assignment{}, fmt.Errorf("%d neuron cores requested, but only %d are free", cores, len(allFree))
+		}
+		best = allFree
+	}
+
+	coreIDs := best[:cores]
+	for _, core := range coreIDs {
+		m.assignments[core] = taskARN
+	}
+	return m.assignmentUnsafe(coreIDs), nil
+}
+
+// Reserve records the cores assigned to the task before the agent restarted
+func (m *manager) Reserve(taskARN string, coreIDs []int) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, core := range coreIDs {
+		if m.deviceOfUnsafe(core) == nil {
+			return fmt.Errorf("neuron core %d does not exist", core)
+		}
+		if owner, ok := m.assignments[core]; ok && owner != taskARN {
+			return fmt.Errorf("neuron core %d is assigned to task %s", core, owner)
+		}
+	}
+	for _, core := range coreIDs {
+		m.assignments[core] = taskARN
+	}
+	return nil
+}
+
+// Release frees the cores assigned to the task
+func (m *manager) Release(taskARN string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for core, owner := range m.assignments {
+		if owner == taskARN {
+			delete(m.assignments, core)
+		}
+	}
+}
+
+func (m *manager) freeCoresUnsafe(device Device) []int {
+	var free []int
+	for core := device.FirstCore; core < device.FirstCore+device.CoreCount; core++ {
+		if _, assigned := m.assignments[core]; !assigned {
+			free = append(free, core)
+		}
+	}
+	return free
+}
+
+func (m *manager) deviceOfUnsafe(core int) *Device {
+	for i := range m.devices {
+		device := &m.devices[i]
+		if core >= device.FirstCore && core < device.FirstCore+device.CoreCount {
+			return device
+		}
+	}
+	return nil
+}
+
+func (m *manager) assignmentUnsafe(coreIDs []int) Assignment {
+	assignment := Assignment{CoreIDs: coreIDs}
+	// the devices are in core order, so the cores of the previous devices come first
+	firstCore := make(map[string]int)
+	visibleCores := 0
+	for _, core := range coreIDs {
+		device := m.deviceOfUnsafe(core)
+		if _, ok := firstCore[device.Path]; !ok {
+			firstCore[device.Path] = visibleCores
+			visibleCores += device.CoreCount
+			assignment.Devices = append(assignment.Devices, device.Path)
+		}
+		assignment.VisibleCores = append(assignment.VisibleCores,
+			firstCore[device.Path]+core-device.FirstCore)
+	}
+	return assignment
+}
@@ -0,0 +1,104 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package neuron
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDevices() []Device {
+	return []Device{
+		{Index: 0, Path: "/dev/neuron0", CoreCount: 2, FirstCore: 0},
+		{Index: 1, Path: "/dev/neuron1", CoreCount: 2, FirstCore: 2},
+	}
+}
+
+func TestGetDevices(t *testing.T) {
+	m := newManagerWithDevices(testDevices())
+
+	devices := m.GetDevices()
+	require.Len(t, devices, 4)
+	assert.Equal(t, "neuron-core-3", aws.StringValue(devices[3].Id))
+	assert.Equal(t, ecs.PlatformDeviceTypeNeuronCore, aws.StringValue(devices[3].Type))
+}
+
+func TestAssignOnASingleDevice(t *testing.T) {
+	m := newManagerWithDevices(testDevices())
+
+	assignment, err := m.Assign("task1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, Assignment{
+		CoreIDs:      []int{0},
+		Devices:      []string{"/dev/neuron0"},
+		VisibleCores: []int{0},
+	}, assignment)
+
+	// device 0 has a single free core left, so two cores are taken from device 1
+	assignment, err = m.Assign("task2", 2)
+	require.NoError(t, err)
+	assert.Equal(t, Assignment{
+		CoreIDs:      []int{2, 3},
+		Devices:      []string{"/dev/neuron1"},
+		VisibleCores: []int{0, 1},
+	}, assignment)
+
+	assignment, err = m.Assign("task1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, assignment.CoreIDs)
+	assert.Equal(t, []int{1}, assignment.VisibleCores)
+
+	_, err = m.Assign("task3", 1)
+	assert.Error(t, err)
+}
+
+func TestAssignAcrossDevices(t *testing.T) {
+	m := newManagerWithDevices(testDevices())
+
+	_, err := m.Assign("task1", 1)
+	require.NoError(t, err)
+	assignment, err := m.Assign("task2", 3)
+	require.NoError(t, err)
+	assert.Equal(t, Assignment{
+		CoreIDs:      []int{1, 2, 3},
+		Devices:      []string{"/dev/neuron0", "/dev/neuron1"},
+		VisibleCores: []int{1, 2, 3},
+	}, assignment)
+
+	_, err = m.Assign("task3", 0)
+	assert.Error(t, err)
+}
+
+func TestReserveAndRelease(t *testing.T) {
+	m := newManagerWithDevices(testDevices())
+
+	require.NoError(t, m.Reserve("task1", []int{0, 1, 2}))
+	require.NoError(t, m.Reserve("task1", []int{0}))
+	assert.Error(t, m.Reserve("task2", []int{2}))
+	assert.Error(t, m.Reserve("task2", []int{4}))
+
+	_, err := m.Assign("task2", 2)
+	assert.Error(t, err)
+
+	m.Release("task1")
+	assignment, err := m.Assign("task2", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, assignment.CoreIDs)
+}
@@ -0,0 +1,169 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package numa places tasks on the NUMA nodes of the instance, keeping track of the
+// cpus and memory of each node the tasks placed on it reserve
+package numa
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Node is a NUMA node of the instance
+type Node struct {
+	// ID is the number of the node, which is also its cpuset memory node
+	ID int
+	// CPUs are the cpus of the node, in the cpuset list format (e.g. "0-15,32-47")
+	CPUs string
+	// CPUCount is the number of cpus of the node
+	CPUCount int
+	// MemoryMiB is the memory of the node, in MiB
+	MemoryMiB int64
+}
+
+// NodeAvailability is the cpus and memory of a NUMA node not reserved by tasks
+type NodeAvailability struct {
+	Node
+	// AvailableCPU is the number of vCPUs not reserved by tasks
+	AvailableCPU float64
+	// AvailableMemoryMiB is the memory not reserved by tasks, in MiB
+	AvailableMemoryMiB int64
+	// Tasks is the number of tasks placed on the node
+	Tasks int
+}
+
+// ExhaustedError is returned when no NUMA node has the cpus and the memory a task
+// requires available
+type ExhaustedError struct {
+	CPU       float64
+	MemoryMiB int64
+}
+
+func (err *ExhaustedError) Error() string {
+	return fmt.Sprintf("no NUMA node has %g vCPUs and %d MiB of memory available", err.CPU, err.MemoryMiB)
+}
+
+// reservation is the cpus and memory a task reserves on a node
+type reservation struct {
+	node      int
+	cpu       float64
+	memoryMiB int64
+}
+
+// Allocator keeps track of the cpus and memory of the NUMA nodes reserved by the tasks
+// placed on them
+type Allocator struct {
+	nodes        []Node
+	reservations map[string]reservation
+	lock         sync.Mutex
+}
+
+// NewAllocator returns an allocator for the NUMA nodes
+func NewAllocator(nodes []Node) *Allocator {
+	sorted := append([]Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return &Allocator{
+		nodes:        sorted,
+		reservations: make(map[string]reservation),
+	}
+}
+
+// Allocate places the task on the node with the least cpus available among the ones
+// that fit it, to keep the larger nodes free for larger tasks, and reserves the cpus
+// and memory the task requires on it. A task that is already placed keeps its node
+func (allocator *Allocator) Allocate(taskARN string, cpu float64, memoryMiB int64) (Node, error) {
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+
+	if existing, ok := allocator.reservations[taskARN]; ok {
+		if node, ok := allocator.nodeUnsafe(existing.node); ok {
+			return node, nil
+		}
+	}
+
+	var best *NodeAvailability
+	for _, availability := range allocator.availabilityUnsafe() {
+		if availability.AvailableCPU < cpu || availability.AvailableMemoryMiB < memoryMiB {
+			continue
+		}
+		if best == nil || availability.AvailableCPU < best.AvailableCPU {
+			candidate := availability
+			best = &candidate
+		}
+	}
+	if best == nil {
+		return Node{}, &ExhaustedError{CPU: cpu, MemoryMiB: memoryMiB}
+	}
+	allocator.reservations[taskARN] = reservation{node: best.ID, cpu: cpu, memoryMiB: memoryMiB}
+	return best.Node, nil
+}
+
+// Reserve records that the task is placed on the node, for the tasks placed before the
+// agent restarted. The node may be over-committed if it has shrunk since then
+func (allocator *Allocator) Reserve(taskARN string, nodeID int, cpu float64, memoryMiB int64) error {
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+
+	if _, ok := allocator.nodeUnsafe(nodeID); !ok {
+		return fmt.Errorf("NUMA node %d does not exist", nodeID)
+	}
+	allocator.reservations[taskARN] = reservation{node: nodeID, cpu: cpu, memoryMiB: memoryMiB}
+	return nil
+}
+
+// Release frees the cpus and memory the task reserves
+func (allocator *Allocator) Release(taskARN string) {
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+
+	delete(allocator.reservations, taskARN)
+}
+
+// Availability returns the cpus and memory of the nodes not reserved by tasks
+func (allocator *Allocator) Availability() []NodeAvailability {
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+
+	return allocator.availabilityUnsafe()
+}
+
+func (allocator *Allocator) availabilityUnsafe() []NodeAvailability {
+	availabilities := make([]NodeAvailability, len(allocator.nodes))
+	indexes := make(map[int]int)
+	for i, node := range allocator.nodes {
+		availabilities[i] = NodeAvailability{
+			Node:               node,
+			AvailableCPU:       float64(node.CPUCount),
+			AvailableMemoryMiB: node.MemoryMiB,
+		}
+		indexes[node.ID] = i
+	}
+	for _, reservation := range allocator.reservations {
+		availability := &availabilities[indexes[reservation.node]]
+		availability.AvailableCPU -= reservation.cpu
+		availability.AvailableMemoryMiB -= reservation.memoryMiB
+		availability.Tasks++
+	}
+	return availabilities
+}
+
+func (allocator *Allocator) nodeUnsafe(nodeID int) (Node, bool) {
+	for _, node := range allocator.nodes {
+		if node.ID == nodeID {
+			return node, true
+		}
+	}
+	return Node{}, false
+}
@@ -0,0 +1,93 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package numa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNodes() []Node {
+	return []Node{
+		{ID: 1, CPUs: "4-7", CPUCount: 4, MemoryMiB: 4096},
+		{ID: 0, CPUs: "0-3", CPUCount: 4, MemoryMiB: 4096},
+	}
+}
+
+func TestAllocateBestFit(t *testing.T) {
+	allocator := NewAllocator(testNodes())
+
+	node, err := allocator.Allocate("task1", 3, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 0, node.ID)
+
+	// node 0 has the least cpus available among the nodes that fit the task
+	node, err = allocator.Allocate("task2", 1, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 0, node.ID)
+
+	node, err = allocator.Allocate("task3", 1, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 1, node.ID)
+
+	availability := allocator.Availability()
+	require.Len(t, availability, 2)
+	assert.Equal(t, 0.0, availability[0].AvailableCPU)
+	assert.Equal(t, int64(2048), availability[0].AvailableMemoryMiB)
+	assert.Equal(t, 2, availability[0].Tasks)
+	assert.Equal(t, 3.0, availability[1].AvailableCPU)
+	assert.Equal(t, 1, availability[1].Tasks)
+}
+
+func TestAllocateKeepsTheNodeOfAPlacedTask(t *testing.T) {
+	allocator := NewAllocator(testNodes())
+
+	first, err := allocator.Allocate("task", 2, 1024)
+	require.NoError(t, err)
+	second, err := allocator.Allocate("task", 2, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, allocator.Availability()[first.ID].Tasks)
+}
+
+func TestAllocateExhausted(t *testing.T) {
+	allocator := NewAllocator(testNodes())
+
+	_, err := allocator.Allocate("task1", 5, 1024)
+	assert.Equal(t, &ExhaustedError{CPU: 5, MemoryMiB: 1024}, err)
+
+	_, err = allocator.Allocate("task2", 1, 8192)
+	assert.IsType(t, &ExhaustedError{}, err)
+}
+
+func TestReserveAndRelease(t *testing.T) {
+	allocator := NewAllocator(testNodes())
+
+	require.NoError(t, allocator.Reserve("task1", 1, 4, 4096))
+	assert.Error(t, allocator.Reserve("task2", 2, 1, 1024))
+
+	_, err := allocator.Allocate("task3", 4, 1024)
+	require.NoError(t, err)
+	_, err = allocator.Allocate("task4", 1, 1024)
+	assert.Error(t, err)
+
+	allocator.Release("task1")
+	node, err := allocator.Allocate("task4", 1, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 1, node.ID)
+}
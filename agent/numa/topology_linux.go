@@ -0,0 +1,130 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package numa
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cpuListFile = "cpulist"
+	meminfoFile = "meminfo"
+	kiBPerMiB   = 1024
+)
+
+// nodesPath is where the kernel exposes the NUMA nodes
+var nodesPath = "/sys/devices/system/node"
+
+// Topology returns the NUMA nodes of the instance that have cpus
+func Topology() ([]Node, error) {
+	paths, err := filepath.Glob(filepath.Join(nodesPath, "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no NUMA node found in %s", nodesPath)
+	}
+
+	var nodes []Node
+	for _, path := range paths {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "node"))
+		if err != nil {
+			continue
+		}
+		cpus, err := ioutil.ReadFile(filepath.Join(path, cpuListFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the cpus of NUMA node %d: %w", id, err)
+		}
+		cpuList := strings.TrimSpace(string(cpus))
+		cpuCount, err := countCPUs(cpuList)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the cpus of NUMA node %d: %w", id, err)
+		}
+		if cpuCount == 0 {
+			// memory-only nodes can't host tasks
+			continue
+		}
+		memoryMiB, err := nodeMemoryMiB(filepath.Join(path, meminfoFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the memory of NUMA node %d: %w", id, err)
+		}
+		nodes = append(nodes, Node{
+			ID:        id,
+			CPUs:      cpuList,
+			CPUCount:  cpuCount,
+			MemoryMiB: memoryMiB,
+		})
+	}
+	return nodes, nil
+}
+
+// countCPUs returns the number of cpus of a list in the cpuset list format
+func countCPUs(cpuList string) (int, error) {
+	if cpuList == "" {
+		return 0, nil
+	}
+	count := 0
+	for _, cpuRange := range strings.Split(cpuList, ",") {
+		bounds := strings.SplitN(cpuRange, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return 0, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, err
+			}
+		}
+		if last < first {
+			return 0, fmt.Errorf("invalid cpu range %s", cpuRange)
+		}
+		count += last - first + 1
+	}
+	return count, nil
+}
+
+// nodeMemoryMiB returns the total memory of a NUMA node from its meminfo file, whose lines
+// read like "Node 0 MemTotal:       32613256 kB"
+func nodeMemoryMiB(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 4 && fields[2] == "MemTotal:" {
+			memoryKiB, err := strconv.ParseInt(fields[3], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return memoryKiB / kiBPerMiB, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no MemTotal in %s", path)
+}
@@ -0,0 +1,83 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package numa
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeNode(t *testing.T, dir, name, cpus, meminfo string) {
+	nodeDir := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(nodeDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(nodeDir, cpuListFile), []byte(cpus), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(nodeDir, meminfoFile), []byte(meminfo), 0644))
+}
+
+func TestTopology(t *testing.T) {
+	dir, err := ioutil.TempDir("", "numa")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { nodesPath = "/sys/devices/system/node" }()
+	nodesPath = dir
+
+	writeNode(t, dir, "node0", "0-3,8-11\n", "Node 0 MemTotal:       8388608 kB\nNode 0 MemFree:        4194304 kB\n")
+	writeNode(t, dir, "node1", "4-7,12-15\n", "Node 1 MemTotal:       4194304 kB\n")
+	// memory-only node
+	writeNode(t, dir, "node2", "\n", "Node 2 MemTotal:       4194304 kB\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "power"), 0755))
+
+	nodes, err := Topology()
+	require.NoError(t, err)
+	assert.Equal(t, []Node{
+		{ID: 0, CPUs: "0-3,8-11", CPUCount: 8, MemoryMiB: 8192},
+		{ID: 1, CPUs: "4-7,12-15", CPUCount: 8, MemoryMiB: 4096},
+	}, nodes)
+}
+
+func TestTopologyNoNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "numa")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { nodesPath = "/sys/devices/system/node" }()
+	nodesPath = dir
+
+	_, err = Topology()
+	assert.Error(t, err)
+}
+
+func TestCountCPUs(t *testing.T) {
+	for cpuList, expected := range map[string]int{
+		"":           0,
+		"0":          1,
+		"0-3":        4,
+		"0-3,8,9-10": 7,
+	} {
+		count, err := countCPUs(cpuList)
+		assert.NoError(t, err, cpuList)
+		assert.Equal(t, expected, count, cpuList)
+	}
+
+	for _, cpuList := range []string{"a", "3-1", "0-b"} {
+		_, err := countCPUs(cpuList)
+		assert.Error(t, err, cpuList)
+	}
+}
@@ -34,6 +34,7 @@ const (
 
 type S3ClientCreator interface {
 	NewS3ClientForBucket(bucket, region string, creds credentials.IAMRoleCredentials) (s3client.S3Client, error)
+	NewS3UploaderForBucket(bucket, region string, creds *awscreds.Credentials) (s3client.S3Uploader, error)
 }
 
 func NewS3ClientCreator() S3ClientCreator {
@@ -62,6 +63,24 @@ func (*s3ClientCreator) NewS3ClientForBucket(bucket, region string,
 	return s3manager.NewDownloaderWithClient(s3.New(sessWithRegion)), nil
 }
 
+// NewS3UploaderForBucket returns a new S3 uploader based on the region of the bucket.
+func (*s3ClientCreator) NewS3UploaderForBucket(bucket, region string,
+	creds *awscreds.Credentials) (s3client.S3Uploader, error) {
+	cfg := aws.NewConfig().
+		WithHTTPClient(httpclient.New(roundtripTimeout, false)).
+		WithCredentials(creds).WithRegion(region)
+	sess := session.Must(session.NewSession(cfg))
+
+	svc := s3.New(sess)
+	bucketRegion, err := getRegionFromBucket(svc, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	sessWithRegion := session.Must(session.NewSession(cfg.WithRegion(bucketRegion)))
+	return s3manager.NewUploaderWithClient(s3.New(sessWithRegion)), nil
+}
+
 func getRegionFromBucket(svc *s3.S3, bucket string) (string, error) {
 	input := &s3.GetBucketLocationInput{
 		Bucket: aws.String(bucket),
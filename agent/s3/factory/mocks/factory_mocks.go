@@ -23,6 +23,7 @@ import (
 
 	credentials "github.com/aws/amazon-ecs-agent/agent/credentials"
 	s3 "github.com/aws/amazon-ecs-agent/agent/s3"
+	credentials0 "github.com/aws/aws-sdk-go/aws/credentials"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -63,3 +64,18 @@ func (mr *MockS3ClientCreatorMockRecorder) NewS3ClientForBucket(arg0, arg1, arg2
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewS3ClientForBucket", reflect.TypeOf((*MockS3ClientCreator)(nil).NewS3ClientForBucket), arg0, arg1, arg2)
 }
+
+// NewS3UploaderForBucket mocks base method
+func (m *MockS3ClientCreator) NewS3UploaderForBucket(arg0, arg1 string, arg2 *credentials0.Credentials) (s3.S3Uploader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewS3UploaderForBucket", arg0, arg1, arg2)
+	ret0, _ := ret[0].(s3.S3Uploader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewS3UploaderForBucket indicates an expected call of NewS3UploaderForBucket
+func (mr *MockS3ClientCreatorMockRecorder) NewS3UploaderForBucket(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewS3UploaderForBucket", reflect.TypeOf((*MockS3ClientCreator)(nil).NewS3UploaderForBucket), arg0, arg1, arg2)
+}
@@ -13,7 +13,7 @@
 //
 
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/aws/amazon-ecs-agent/agent/s3 (interfaces: S3Client)
+// Source: github.com/aws/amazon-ecs-agent/agent/s3 (interfaces: S3Client,S3Uploader)
 
 // Package mock_s3 is a generated GoMock package.
 package mock_s3
@@ -70,3 +70,46 @@ func (mr *MockS3ClientMockRecorder) DownloadWithContext(arg0, arg1, arg2 interfa
 	varargs := append([]interface{}{arg0, arg1, arg2}, arg3...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadWithContext", reflect.TypeOf((*MockS3Client)(nil).DownloadWithContext), varargs...)
 }
+
+// MockS3Uploader is a mock of S3Uploader interface
+type MockS3Uploader struct {
+	ctrl     *gomock.Controller
+	recorder *MockS3UploaderMockRecorder
+}
+
+// MockS3UploaderMockRecorder is the mock recorder for MockS3Uploader
+type MockS3UploaderMockRecorder struct {
+	mock *MockS3Uploader
+}
+
+// NewMockS3Uploader creates a new mock instance
+func NewMockS3Uploader(ctrl *gomock.Controller) *MockS3Uploader {
+	mock := &MockS3Uploader{ctrl: ctrl}
+	mock.recorder = &MockS3UploaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockS3Uploader) EXPECT() *MockS3UploaderMockRecorder {
+	return m.recorder
+}
+
+// UploadWithContext mocks base method
+func (m *MockS3Uploader) UploadWithContext(arg0 context.Context, arg1 *s3manager.UploadInput, arg2 ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UploadWithContext", varargs...)
+	ret0, _ := ret[0].(*s3manager.UploadOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadWithContext indicates an expected call of UploadWithContext
+func (mr *MockS3UploaderMockRecorder) UploadWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadWithContext", reflect.TypeOf((*MockS3Uploader)(nil).UploadWithContext), varargs...)
+}
@@ -16,11 +16,16 @@ package s3
 import (
 	"context"
 	"io"
+	"net/http"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/pkg/errors"
 )
 
@@ -30,10 +35,20 @@ const (
 
 // DownloadFile downloads a file from s3 and writes it with the writer.
 func DownloadFile(bucket, key string, timeout time.Duration, w io.WriterAt, client S3Client) error {
+	return DownloadFileIfMatch(bucket, key, "", timeout, w, client)
+}
+
+// DownloadFileIfMatch downloads a file from s3 and writes it with the writer, failing with
+// a PreconditionFailed error unless the ETag of the file is the given one. Any version of
+// the file is downloaded when the ETag is empty.
+func DownloadFileIfMatch(bucket, key, etag string, timeout time.Duration, w io.WriterAt, client S3Client) error {
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	}
+	if etag != "" {
+		input.IfMatch = aws.String(QuoteETag(etag))
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -41,6 +56,63 @@ func DownloadFile(bucket, key string, timeout time.Duration, w io.WriterAt, clie
 	return err
 }
 
+// ErrNotModified is returned by DownloadFileIfNoneMatch when the file has the given ETag.
+var ErrNotModified = errors.New("s3 file not modified")
+
+// DownloadFileIfNoneMatch downloads a file from s3 and writes it with the writer, and returns
+// the ETag of the file. It fails with ErrNotModified without writing anything if the ETag of
+// the file is the given one. The file is always downloaded when the ETag is empty.
+func DownloadFileIfNoneMatch(bucket, key, etag string, timeout time.Duration, w io.WriterAt,
+	client S3Client) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if etag != "" {
+		input.IfNoneMatch = aws.String(QuoteETag(etag))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var currentETag string
+	_, err := client.DownloadWithContext(ctx, w, input, func(downloader *s3manager.Downloader) {
+		// the ETag is read from the responses of the parts, which are downloaded one at a time
+		downloader.Concurrency = 1
+		downloader.RequestOptions = append(downloader.RequestOptions,
+			request.WithGetResponseHeader("ETag", &currentETag))
+	})
+	if err != nil {
+		if requestErr, ok := err.(awserr.RequestFailure); ok && requestErr.StatusCode() == http.StatusNotModified {
+			return etag, ErrNotModified
+		}
+		return "", err
+	}
+	return strings.Trim(currentETag, `"`), nil
+}
+
+// UploadFile uploads a file to s3, encrypted with the KMS key if one is specified.
+func UploadFile(bucket, key, kmsKeyID string, timeout time.Duration, r io.Reader, client S3Uploader) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	}
+	if kmsKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(kmsKeyID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err := client.UploadWithContext(ctx, input)
+	return err
+}
+
+// QuoteETag returns the ETag between double quotes, the way S3 returns and compares it
+func QuoteETag(etag string) string {
+	return `"` + strings.Trim(etag, `"`) + `"`
+}
+
 // ParseS3ARN parses an s3 ARN.
 func ParseS3ARN(s3ARN string) (bucket string, key string, err error) {
 	exp := regexp.MustCompile(s3ARNRegex)
@@ -18,11 +18,16 @@ package s3
 import (
 	"errors"
 	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	s3sdk "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
@@ -53,6 +58,72 @@ func TestDownloadFile(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestDownloadFileIfMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFile := mock_oswrapper.NewMockFile()
+	mockS3Client := mock_s3.NewMockS3Client(ctrl)
+
+	mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any()).Do(func(ctx aws.Context,
+		w io.WriterAt, input *s3sdk.GetObjectInput) {
+		assert.Equal(t, testBucket, aws.StringValue(input.Bucket))
+		assert.Equal(t, testKey, aws.StringValue(input.Key))
+		assert.Equal(t, `"abc123"`, aws.StringValue(input.IfMatch))
+	})
+
+	err := DownloadFileIfMatch(testBucket, testKey, "abc123", testTimeout, mockFile, mockS3Client)
+	assert.NoError(t, err)
+}
+
+func TestDownloadFileIfNoneMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFile := mock_oswrapper.NewMockFile()
+	mockS3Client := mock_s3.NewMockS3Client(ctrl)
+
+	mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any(), gomock.Any()).Do(func(ctx aws.Context,
+		w io.WriterAt, input *s3sdk.GetObjectInput, options ...func(*s3manager.Downloader)) {
+		assert.Equal(t, `"abc123"`, aws.StringValue(input.IfNoneMatch))
+		// the ETag of the response is returned
+		downloader := &s3manager.Downloader{}
+		for _, option := range options {
+			option(downloader)
+		}
+		assert.Equal(t, 1, downloader.Concurrency)
+		req := &request.Request{HTTPResponse: &http.Response{Header: http.Header{"Etag": []string{`"def456"`}}}}
+		for _, option := range downloader.RequestOptions {
+			option(req)
+		}
+		req.Handlers.Complete.Run(req)
+	})
+
+	etag, err := DownloadFileIfNoneMatch(testBucket, testKey, "abc123", testTimeout, mockFile, mockS3Client)
+	assert.NoError(t, err)
+	assert.Equal(t, "def456", etag)
+}
+
+func TestDownloadFileIfNoneMatchNotModified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFile := mock_oswrapper.NewMockFile()
+	mockS3Client := mock_s3.NewMockS3Client(ctrl)
+
+	mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any(), gomock.Any()).Return(int64(0),
+		awserr.NewRequestFailure(awserr.New("NotModified", "Not Modified", nil), http.StatusNotModified, "id"))
+
+	etag, err := DownloadFileIfNoneMatch(testBucket, testKey, "abc123", testTimeout, mockFile, mockS3Client)
+	assert.Equal(t, ErrNotModified, err)
+	assert.Equal(t, "abc123", etag)
+}
+
+func TestQuoteETag(t *testing.T) {
+	assert.Equal(t, `"abc123"`, QuoteETag("abc123"))
+	assert.Equal(t, `"abc123"`, QuoteETag(`"abc123"`))
+}
+
 func TestDownloadFileError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -66,6 +137,42 @@ func TestDownloadFileError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestUploadFile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockS3Uploader := mock_s3.NewMockS3Uploader(ctrl)
+	body := strings.NewReader("content")
+
+	mockS3Uploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).Do(func(ctx aws.Context,
+		input *s3manager.UploadInput) {
+		assert.Equal(t, testBucket, aws.StringValue(input.Bucket))
+		assert.Equal(t, testKey, aws.StringValue(input.Key))
+		assert.Equal(t, body, input.Body)
+		assert.Equal(t, s3sdk.ServerSideEncryptionAwsKms, aws.StringValue(input.ServerSideEncryption))
+		assert.Equal(t, "key-id", aws.StringValue(input.SSEKMSKeyId))
+	})
+
+	err := UploadFile(testBucket, testKey, "key-id", testTimeout, body, mockS3Uploader)
+	assert.NoError(t, err)
+}
+
+func TestUploadFileWithoutKMSKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockS3Uploader := mock_s3.NewMockS3Uploader(ctrl)
+
+	mockS3Uploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).Do(func(ctx aws.Context,
+		input *s3manager.UploadInput) {
+		assert.Nil(t, input.ServerSideEncryption)
+		assert.Nil(t, input.SSEKMSKeyId)
+	}).Return(nil, errors.New("test error"))
+
+	err := UploadFile(testBucket, testKey, "", testTimeout, strings.NewReader("content"), mockS3Uploader)
+	assert.Error(t, err)
+}
+
 func TestParseS3ARN(t *testing.T) {
 	bucket, key, err := ParseS3ARN("arn:aws:s3:::bucket/key")
 	assert.NoError(t, err)
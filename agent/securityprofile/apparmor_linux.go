@@ -0,0 +1,39 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package securityprofile
+
+import (
+	"bytes"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// apparmorParser is the command that loads AppArmor profiles in the kernel
+const apparmorParser = "apparmor_parser"
+
+// loadAppArmorProfile loads the AppArmor profile in the kernel, replacing the profile
+// with the same name if one is already loaded. It's swappable for testing
+var loadAppArmorProfile = func(content []byte) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(apparmorParser, "--replace")
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "%s failed: %s", apparmorParser, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
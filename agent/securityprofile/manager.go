@@ -0,0 +1,277 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package securityprofile fetches the seccomp and AppArmor profiles the containers
+// reference by name, from a local directory or from S3, and caches them
+package securityprofile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/credentials/instancecreds"
+	s3client "github.com/aws/amazon-ecs-agent/agent/s3"
+	s3factory "github.com/aws/amazon-ecs-agent/agent/s3/factory"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// Types of the security profiles
+const (
+	// TypeSeccomp is the type of the seccomp profiles, which are passed to docker as is
+	TypeSeccomp = "seccomp"
+	// TypeAppArmor is the type of the AppArmor profiles, which are loaded in the kernel
+	// before being referenced by name
+	TypeAppArmor = "apparmor"
+)
+
+const (
+	// s3DownloadTimeout is how long the download of a profile from S3 can take
+	s3DownloadTimeout = 30 * time.Second
+	// seccompExtension is the extension of the files of the seccomp profiles
+	seccompExtension = ".json"
+)
+
+var (
+	// profileNameRegex matches the names of the profiles, which are also file names
+	profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+	// s3LocationRegex matches the ARNs of S3 buckets and of folders in them
+	s3LocationRegex = regexp.MustCompile(`^arn:[^:]+:s3:::([^/]+)/?(.*)$`)
+)
+
+// Profile is a seccomp or AppArmor profile
+type Profile struct {
+	// Type is the type of the profile, seccomp or apparmor
+	Type string
+	// Name is the name the profile is referenced by
+	Name string
+	// Source is the path or the S3 ARN the profile was read from
+	Source string
+	// Content is the profile
+	Content []byte
+}
+
+// NotFoundError is returned when neither the directory nor S3 has the profile
+type NotFoundError struct {
+	Type string
+	Name string
+}
+
+func (err *NotFoundError) Error() string {
+	return fmt.Sprintf("%s profile %s not found", err.Type, err.Name)
+}
+
+// Manager fetches the security profiles the containers reference
+type Manager interface {
+	// Get returns the profile of the type with the name. AppArmor profiles are loaded in
+	// the kernel, so that the containers can be confined by them
+	Get(profileType, name string) (*Profile, error)
+}
+
+// manager looks the profiles up in the profiles directory, then in S3, and caches them
+type manager struct {
+	dir             string
+	s3ARN           string
+	region          string
+	ttl             time.Duration
+	s3ClientCreator s3factory.S3ClientCreator
+	// swappable for testing
+	getCredentials func() (credentials.IAMRoleCredentials, error)
+
+	lock   sync.Mutex
+	cache  map[string]cacheEntry
+	loaded map[string]bool
+	now    func() time.Time
+}
+
+// cacheEntry is a cached profile, with when it was fetched
+type cacheEntry struct {
+	profile   *Profile
+	fetchedAt time.Time
+}
+
+// NewManager returns a manager of the profiles of the profiles directory and of the S3
+// location of the config
+func NewManager(cfg *config.Config, s3ClientCreator s3factory.S3ClientCreator) Manager {
+	return &manager{
+		dir:             cfg.SecurityProfilesDir,
+		s3ARN:           cfg.SecurityProfilesS3ARN,
+		region:          cfg.AWSRegion,
+		ttl:             cfg.SecurityProfilesCacheTTL,
+		s3ClientCreator: s3ClientCreator,
+		getCredentials:  instanceCredentials,
+		cache:           make(map[string]cacheEntry),
+		loaded:          make(map[string]bool),
+		now:             time.Now,
+	}
+}
+
+// Get returns the profile of the type with the name, from the cache if it was fetched
+// less than the cache TTL ago
+func (m *manager) Get(profileType, name string) (*Profile, error) {
+	if profileType != TypeSeccomp && profileType != TypeAppArmor {
+		return nil, errors.Errorf("unknown security profile type %q", profileType)
+	}
+	if !profileNameRegex.MatchString(name) {
+		return nil, errors.Errorf("invalid %s profile name %q", profileType, name)
+	}
+
+	key := profileType + "/" + name
+	m.lock.Lock()
+	entry, ok := m.cache[key]
+	m.lock.Unlock()
+	if ok && m.now().Sub(entry.fetchedAt) < m.ttl {
+		seelog.Debugf("Using cached %s profile %s", profileType, name)
+		return entry.profile, nil
+	}
+
+	profile, err := m.fetch(profileType, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(profile); err != nil {
+		return nil, err
+	}
+	if profileType == TypeAppArmor {
+		if err := m.load(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.ttl > 0 {
+		m.lock.Lock()
+		m.cache[key] = cacheEntry{profile: profile, fetchedAt: m.now()}
+		m.lock.Unlock()
+	}
+	return profile, nil
+}
+
+// fetch reads the profile from the profiles directory, or downloads it from S3 when the
+// directory doesn't have it
+func (m *manager) fetch(profileType, name string) (*Profile, error) {
+	file := name
+	if profileType == TypeSeccomp {
+		file += seccompExtension
+	}
+
+	if m.dir != "" {
+		profilePath := filepath.Join(m.dir, profileType, file)
+		content, err := ioutil.ReadFile(profilePath)
+		if err == nil {
+			return &Profile{Type: profileType, Name: name, Source: profilePath, Content: content}, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "unable to read %s profile %s", profileType, name)
+		}
+	}
+
+	if m.s3ARN == "" {
+		return nil, &NotFoundError{Type: profileType, Name: name}
+	}
+	match := s3LocationRegex.FindStringSubmatch(m.s3ARN)
+	if match == nil {
+		return nil, errors.Errorf("invalid security profiles s3 arn: %s", m.s3ARN)
+	}
+	bucket, key := match[1], path.Join(match[2], profileType, file)
+	content, err := m.download(bucket, key)
+	if err != nil {
+		if aerr, ok := errors.Cause(err).(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, &NotFoundError{Type: profileType, Name: name}
+		}
+		return nil, errors.Wrapf(err, "unable to download %s profile %s from bucket %s", profileType, name, bucket)
+	}
+	return &Profile{
+		Type:    profileType,
+		Name:    name,
+		Source:  fmt.Sprintf("arn:aws:s3:::%s/%s", bucket, key),
+		Content: content,
+	}, nil
+}
+
+// download downloads the object from S3 with the instance credentials
+func (m *manager) download(bucket, key string) ([]byte, error) {
+	creds, err := m.getCredentials()
+	if err != nil {
+		return nil, err
+	}
+	client, err := m.s3ClientCreator.NewS3ClientForBucket(bucket, m.region, creds)
+	if err != nil {
+		return nil, err
+	}
+	buffer := aws.NewWriteAtBuffer([]byte{})
+	if err := s3client.DownloadFile(bucket, key, s3DownloadTimeout, buffer, client); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// instanceCredentials returns the instance credentials the profiles are downloaded with
+func instanceCredentials() (credentials.IAMRoleCredentials, error) {
+	creds, err := instancecreds.GetCredentials().Get()
+	if err != nil {
+		return credentials.IAMRoleCredentials{}, errors.Wrap(err, "unable to get the instance credentials")
+	}
+	return credentials.IAMRoleCredentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, nil
+}
+
+// load loads an AppArmor profile in the kernel, unless the same profile was already
+// loaded
+func (m *manager) load(profile *Profile) error {
+	key := profile.Name + "\x00" + string(profile.Content)
+	m.lock.Lock()
+	loaded := m.loaded[key]
+	m.lock.Unlock()
+	if loaded {
+		return nil
+	}
+	if err := loadAppArmorProfile(profile.Content); err != nil {
+		return errors.Wrapf(err, "unable to load apparmor profile %s", profile.Name)
+	}
+	m.lock.Lock()
+	m.loaded[key] = true
+	m.lock.Unlock()
+	return nil
+}
+
+// validate checks that seccomp profiles are JSON documents, and that AppArmor profiles
+// declare a profile with their name, which is the name docker confines containers by
+func validate(profile *Profile) error {
+	switch profile.Type {
+	case TypeSeccomp:
+		if !json.Valid(profile.Content) {
+			return errors.Errorf("seccomp profile %s is not a valid json document", profile.Name)
+		}
+	case TypeAppArmor:
+		declaration := regexp.MustCompile(`(?m)^\s*profile\s+` + regexp.QuoteMeta(profile.Name) + `[\s{]`)
+		if !declaration.Match(profile.Content) {
+			return errors.Errorf("apparmor profile %s does not declare a profile named %s", profile.Name, profile.Name)
+		}
+	}
+	return nil
+}
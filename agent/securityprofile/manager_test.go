@@ -0,0 +1,181 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package securityprofile
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	mock_s3 "github.com/aws/amazon-ecs-agent/agent/s3/mocks"
+	mock_factory "github.com/aws/amazon-ecs-agent/agent/s3/factory/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSeccompProfile  = `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": []}`
+	testAppArmorProfile = "#include <tunables/global>\nprofile strict flags=(attach_disconnected) {\n  file,\n}\n"
+)
+
+// newTestManager returns a manager of the profiles of a temporary directory, holding
+// the profiles of the files
+func newTestManager(t *testing.T, s3ARN string, s3ClientCreator *mock_factory.MockS3ClientCreator,
+	files map[string]string) (*manager, func()) {
+	dir, err := ioutil.TempDir("", "security-profiles")
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	m := NewManager(&config.Config{
+		SecurityProfilesDir:      dir,
+		SecurityProfilesS3ARN:    s3ARN,
+		SecurityProfilesCacheTTL: time.Hour,
+		AWSRegion:                "us-west-2",
+	}, s3ClientCreator).(*manager)
+	m.getCredentials = func() (credentials.IAMRoleCredentials, error) {
+		return credentials.IAMRoleCredentials{AccessKeyID: "id"}, nil
+	}
+	return m, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetSeccompProfileFromDir(t *testing.T) {
+	m, cleanup := newTestManager(t, "", nil, map[string]string{
+		"seccomp/strict.json": testSeccompProfile,
+	})
+	defer cleanup()
+
+	profile, err := m.Get(TypeSeccomp, "strict")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(m.dir, "seccomp", "strict.json"), profile.Source)
+	assert.Equal(t, testSeccompProfile, string(profile.Content))
+
+	// the cached profile is returned until the cache TTL elapses
+	require.NoError(t, os.Remove(profile.Source))
+	_, err = m.Get(TypeSeccomp, "strict")
+	assert.NoError(t, err)
+	m.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = m.Get(TypeSeccomp, "strict")
+	assert.IsType(t, &NotFoundError{}, err)
+}
+
+func TestGetAppArmorProfileLoadsItOnce(t *testing.T) {
+	defer func(load func([]byte) error) { loadAppArmorProfile = load }(loadAppArmorProfile)
+	loads := 0
+	loadAppArmorProfile = func(content []byte) error {
+		loads++
+		assert.Equal(t, testAppArmorProfile, string(content))
+		return nil
+	}
+	m, cleanup := newTestManager(t, "", nil, map[string]string{
+		"apparmor/strict": testAppArmorProfile,
+	})
+	defer cleanup()
+	m.ttl = -1
+
+	for i := 0; i < 2; i++ {
+		profile, err := m.Get(TypeAppArmor, "strict")
+		require.NoError(t, err)
+		assert.Equal(t, "strict", profile.Name)
+	}
+	assert.Equal(t, 1, loads, "The same profile should only be loaded once")
+}
+
+func TestGetAppArmorProfileLoadError(t *testing.T) {
+	defer func(load func([]byte) error) { loadAppArmorProfile = load }(loadAppArmorProfile)
+	loadAppArmorProfile = func(content []byte) error {
+		return errors.New("apparmor is not enabled")
+	}
+	m, cleanup := newTestManager(t, "", nil, map[string]string{
+		"apparmor/strict": testAppArmorProfile,
+	})
+	defer cleanup()
+
+	_, err := m.Get(TypeAppArmor, "strict")
+	assert.Error(t, err)
+}
+
+func TestGetInvalidProfiles(t *testing.T) {
+	m, cleanup := newTestManager(t, "", nil, map[string]string{
+		"seccomp/broken.json": "{",
+		"apparmor/other":      testAppArmorProfile,
+	})
+	defer cleanup()
+
+	for _, tc := range []struct {
+		profileType string
+		name        string
+	}{
+		{"selinux", "strict"},
+		{TypeSeccomp, "../../etc/passwd"},
+		{TypeSeccomp, "broken"},
+		{TypeAppArmor, "other"},
+	} {
+		_, err := m.Get(tc.profileType, tc.name)
+		assert.Error(t, err, "%s profile %s should be invalid", tc.profileType, tc.name)
+	}
+}
+
+func TestGetMissingProfile(t *testing.T) {
+	m, cleanup := newTestManager(t, "", nil, nil)
+	defer cleanup()
+
+	_, err := m.Get(TypeSeccomp, "strict")
+	assert.Equal(t, &NotFoundError{Type: TypeSeccomp, Name: "strict"}, err)
+}
+
+func TestGetProfileFromS3(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s3ClientCreator := mock_factory.NewMockS3ClientCreator(ctrl)
+	s3Client := mock_s3.NewMockS3Client(ctrl)
+	m, cleanup := newTestManager(t, "arn:aws:s3:::profiles-bucket/ecs", s3ClientCreator, nil)
+	defer cleanup()
+
+	s3ClientCreator.EXPECT().NewS3ClientForBucket("profiles-bucket", "us-west-2",
+		credentials.IAMRoleCredentials{AccessKeyID: "id"}).Return(s3Client, nil).Times(2)
+	gomock.InOrder(
+		s3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput, options ...interface{}) (int64, error) {
+				assert.Equal(t, "ecs/seccomp/strict.json", aws.StringValue(input.Key))
+				_, err := w.WriteAt([]byte(testSeccompProfile), 0)
+				return int64(len(testSeccompProfile)), err
+			}),
+		s3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(int64(0), awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)),
+	)
+
+	profile, err := m.Get(TypeSeccomp, "strict")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:s3:::profiles-bucket/ecs/seccomp/strict.json", profile.Source)
+	assert.Equal(t, testSeccompProfile, string(profile.Content))
+
+	_, err = m.Get(TypeSeccomp, "missing")
+	assert.IsType(t, &NotFoundError{}, err)
+}
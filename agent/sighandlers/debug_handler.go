@@ -16,12 +16,14 @@
 package sighandlers
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"runtime"
 	"syscall"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/diagnostics"
 	"github.com/cihub/seelog"
 )
 
@@ -39,3 +41,44 @@ func StartDebugHandler() {
 		}
 	}()
 }
+
+// StartDiagnosticsBundleHandler writes a diagnostics bundle to dir every time
+// the agent receives SIGUSR2, until ctx is done.
+func StartDiagnosticsBundleHandler(ctx context.Context, bundler *diagnostics.Bundler, dir string) {
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(signalChannel)
+		for {
+			select {
+			case <-signalChannel:
+				path, err := bundler.WriteFile(ctx, dir)
+				if err != nil {
+					seelog.Errorf("Unable to write diagnostics bundle: %v", err)
+					continue
+				}
+				seelog.Infof("Diagnostics bundle written to %s", path)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// StartConfigReloadHandler calls reload every time the agent receives SIGHUP, until ctx
+// is done.
+func StartConfigReloadHandler(ctx context.Context, reload func()) {
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signalChannel)
+		for {
+			select {
+			case <-signalChannel:
+				reload()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -15,5 +15,21 @@
 
 package sighandlers
 
+import (
+	"context"
+
+	"github.com/aws/amazon-ecs-agent/agent/diagnostics"
+)
+
 func StartDebugHandler() {
 }
+
+// StartDiagnosticsBundleHandler is a no-op on windows, which has no SIGUSR2;
+// the bundle is available from the introspection endpoint instead
+func StartDiagnosticsBundleHandler(ctx context.Context, bundler *diagnostics.Bundler, dir string) {
+}
+
+// StartConfigReloadHandler is a no-op on windows, which has no SIGHUP; the config is
+// still reloaded when the config file changes
+func StartConfigReloadHandler(ctx context.Context, reload func()) {
+}
@@ -19,4 +19,5 @@ import (
 
 type SSMClient interface {
 	GetParameters(*ssm.GetParametersInput) (*ssm.GetParametersOutput, error)
+	DescribeParameters(*ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error)
 }
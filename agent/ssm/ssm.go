@@ -34,6 +34,55 @@ func GetParametersFromSSM(names []string, client SSMClient) (map[string]string,
 	return getParameters(names, client, false)
 }
 
+// GetParameterWithVersionFromSSM makes the api call to the AWS SSM parameter store to
+// retrieve the value of a parameter along with its version
+func GetParameterWithVersionFromSSM(name string, client SSMClient) (string, int64, error) {
+	in := &ssm.GetParametersInput{
+		Names:          []*string{aws.String(name)},
+		WithDecryption: aws.Bool(false),
+	}
+
+	out, err := client.GetParameters(in)
+	if err != nil {
+		return "", 0, err
+	}
+	values, err := extractSSMValues(out)
+	if err != nil {
+		return "", 0, err
+	}
+	for _, parameter := range out.Parameters {
+		if aws.StringValue(parameter.Name) == name {
+			return values[name], aws.Int64Value(parameter.Version), nil
+		}
+	}
+	return "", 0, fmt.Errorf("parameter %s not found", name)
+}
+
+// GetParameterVersionFromSSM makes the api call to the AWS SSM parameter store to
+// retrieve the current version of a parameter, without its value
+func GetParameterVersionFromSSM(name string, client SSMClient) (int64, error) {
+	in := &ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{
+			{
+				Key:    aws.String(ssm.ParametersFilterKeyName),
+				Option: aws.String("Equals"),
+				Values: []*string{aws.String(name)},
+			},
+		},
+	}
+
+	out, err := client.DescribeParameters(in)
+	if err != nil {
+		return 0, err
+	}
+	for _, parameter := range out.Parameters {
+		if aws.StringValue(parameter.Name) == name {
+			return aws.Int64Value(parameter.Version), nil
+		}
+	}
+	return 0, fmt.Errorf("parameter %s not found", name)
+}
+
 func getParameters(names []string, client SSMClient, withDecryption bool) (map[string]string, error) {
 	var params []*string
 	for _, name := range names {
@@ -125,3 +125,41 @@ func TestGetParametersFromSSM(t *testing.T) {
 		})
 	}
 }
+
+type mockDescribeParameters struct {
+	SSMClient
+	Resp ssm.DescribeParametersOutput
+}
+
+func (m mockDescribeParameters) DescribeParameters(input *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+	return &m.Resp, nil
+}
+
+func TestGetParameterWithVersionFromSSM(t *testing.T) {
+	ssmClient := mockGetParameters{Resp: ssm.GetParametersOutput{
+		Parameters: []*ssm.Parameter{{
+			Name:    aws.String(validParam1),
+			Value:   aws.String(validValue1),
+			Version: aws.Int64(3),
+		}},
+	}}
+	value, version, err := GetParameterWithVersionFromSSM(validParam1, ssmClient)
+	assert.NoError(t, err)
+	assert.Equal(t, validValue1, value)
+	assert.Equal(t, int64(3), version)
+}
+
+func TestGetParameterVersionFromSSM(t *testing.T) {
+	ssmClient := mockDescribeParameters{Resp: ssm.DescribeParametersOutput{
+		Parameters: []*ssm.ParameterMetadata{{
+			Name:    aws.String(validParam1),
+			Version: aws.Int64(3),
+		}},
+	}}
+	version, err := GetParameterVersionFromSSM(validParam1, ssmClient)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+
+	_, err = GetParameterVersionFromSSM(invalidParam1, ssmClient)
+	assert.Error(t, err)
+}
@@ -277,8 +277,8 @@ func createFakeContainerStats() []*ContainerStats {
 		TxPackets: 60,
 	}
 	return []*ContainerStats{
-		{22400432, 1839104, uint64(100), uint64(200), netStats, parseNanoTime("2015-02-12T21:22:05.131117533Z")},
-		{116499979, 3649536, uint64(300), uint64(400), netStats, parseNanoTime("2015-02-12T21:22:05.232291187Z")},
+		{22400432, 1839104, uint64(100), uint64(200), uint64(10), uint64(20), netStats, parseNanoTime("2015-02-12T21:22:05.131117533Z")},
+		{116499979, 3649536, uint64(300), uint64(400), uint64(30), uint64(40), netStats, parseNanoTime("2015-02-12T21:22:05.232291187Z")},
 	}
 }
 
@@ -333,6 +333,9 @@ func (engine *MockTaskEngine) SaveState() error {
 	return nil
 }
 
+func (engine *MockTaskEngine) ReloadConfig(cfg config.ReloadableConfig) {
+}
+
 func (engine *MockTaskEngine) Capabilities() []*ecs.Attribute {
 	return nil
 }
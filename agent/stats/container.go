@@ -25,8 +25,13 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/stats/resolver"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
 )
 
+// minimumStatsSnapshotInterval is the minimum time between two stats samples collected for the
+// snapshots of a container
+const minimumStatsSnapshotInterval = time.Second
+
 func newStatsContainer(dockerID string, client dockerapi.DockerClient, resolver resolver.ContainerMetadataResolver,
 	cfg *config.Config) (*StatsContainer, error) {
 	dockerContainer, err := resolver.ResolveContainer(dockerID)
@@ -39,6 +44,7 @@ func newStatsContainer(dockerID string, client dockerapi.DockerClient, resolver
 			DockerID:    dockerID,
 			Name:        dockerContainer.Container.Name,
 			NetworkMode: dockerContainer.Container.GetNetworkMode(),
+			GPUIDs:      dockerContainer.Container.GPUIDs,
 		},
 		ctx:      ctx,
 		cancel:   cancel,
@@ -60,6 +66,9 @@ func (container *StatsContainer) StartStatsCollection() {
 	}
 	container.statsQueue = NewQueue(queueSize)
 	go container.collect()
+	if container.config != nil && container.config.ContainerDiskUsagePollInterval > 0 {
+		go container.collectDiskUsage(container.config.ContainerDiskUsagePollInterval)
+	}
 }
 
 func (container *StatsContainer) StopStatsCollection() {
@@ -144,6 +153,79 @@ func (container *StatsContainer) processStatsStream() error {
 	}
 }
 
+// snapshot returns a fresh stats sample for the container, outside of the regular collection
+// cycle. The sample isn't added to the stats queue, so it doesn't change the metrics sent to ECS.
+// Samples are collected at most once per minimumStatsSnapshotInterval, the latest sample of the
+// container is returned to the requests received in between.
+func (container *StatsContainer) snapshot() (*types.StatsJSON, error) {
+	dockerID := container.containerMetadata.DockerID
+	if container.client == nil {
+		return nil, errors.New("container snapshot: Client is not set.")
+	}
+
+	container.snapshotLock.Lock()
+	defer container.snapshotLock.Unlock()
+
+	latest := container.lastSnapshot
+	if lastStat := container.statsQueue.GetLastStat(); lastStat != nil && (latest == nil || lastStat.Read.After(latest.Read)) {
+		latest = lastStat
+	}
+	if latest != nil && time.Since(latest.Read) < minimumStatsSnapshotInterval {
+		return latest, nil
+	}
+
+	rawStat, err := container.client.StatsSnapshot(container.ctx, dockerID, dockerclient.StatsInactivityTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDockerStats(rawStat); err != nil {
+		return nil, err
+	}
+	container.lastSnapshot = rawStat
+	return rawStat, nil
+}
+
+// collectDiskUsage measures the disk space used by the container on every interval, until
+// stats collection is stopped.
+func (container *StatsContainer) collectDiskUsage(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := container.updateDiskUsage(); err != nil {
+			seelog.Debugf("Container [%s]: error measuring disk usage: %v", container.containerMetadata.DockerID, err)
+		}
+		select {
+		case <-container.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (container *StatsContainer) updateDiskUsage() error {
+	if container.client == nil {
+		return errors.New("container updateDiskUsage: Client is not set.")
+	}
+	containerJSON, err := container.client.InspectContainerSize(container.ctx, container.containerMetadata.DockerID,
+		dockerclient.InspectContainerTimeout)
+	if err != nil {
+		return err
+	}
+	diskUsage := &DiskUsage{
+		Read: time.Now(),
+	}
+	if containerJSON.ContainerJSONBase != nil {
+		if containerJSON.SizeRw != nil && *containerJSON.SizeRw > 0 {
+			diskUsage.WritableLayerSizeBytes = uint64(*containerJSON.SizeRw)
+		}
+		if containerJSON.State != nil {
+			diskUsage.BindMountsSizeBytes = bindMountsSize(containerJSON.State.Pid, containerJSON.Mounts)
+		}
+	}
+	container.statsQueue.SetDiskUsage(diskUsage)
+	return nil
+}
+
 func (container *StatsContainer) terminal() (bool, error) {
 	dockerContainer, err := container.resolver.ResolveContainer(container.containerMetadata.DockerID)
 	if err != nil {
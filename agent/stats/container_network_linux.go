@@ -0,0 +1,337 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/nswrapper"
+	"github.com/containernetworking/plugins/pkg/ns"
+
+	"github.com/cihub/seelog"
+	dockerstats "github.com/docker/docker/api/types"
+	"github.com/vishvananda/netlink/nl"
+)
+
+const (
+	// hostProcFSPath is the path at which the host procfs is mounted in the agent container.
+	hostProcFSPath = "/host/proc"
+	// socketLinkPrefix is the prefix of the target of the file descriptor links of sockets
+	// in procfs, followed by the inode of the socket.
+	socketLinkPrefix = "socket:["
+
+	// inetDiagInfo is the inet_diag extension carrying the tcp_info struct of a socket.
+	inetDiagInfo = 2
+	// sizeofInetDiagRequest is the size of the inet_diag_req_v2 struct.
+	sizeofInetDiagRequest = 56
+	// sizeofInetDiagMessage is the size of the inet_diag_msg struct.
+	sizeofInetDiagMessage = 72
+	// inetDiagMessageInodeOffset is the offset of the idiag_inode field in inet_diag_msg.
+	inetDiagMessageInodeOffset = 68
+	// tcpInfoBytesAckedOffset and tcpInfoBytesReceivedOffset are the offsets of the
+	// tcpi_bytes_acked and tcpi_bytes_received fields in tcp_info, available since
+	// Linux 4.2.
+	tcpInfoBytesAckedOffset    = 120
+	tcpInfoBytesReceivedOffset = 128
+)
+
+// socketBytes holds the bytes sent and received on TCP sockets.
+type socketBytes struct {
+	sent     uint64
+	received uint64
+}
+
+// containerNetworkAccountant splits the network stats of an awsvpc task among the
+// containers of the task. The containers share the network namespace of the pause
+// container, so there are no per container interfaces to read stats from. Instead, the
+// growth of the task interface counters between two polls is split according to the
+// bytes sent and received on the TCP sockets held by the processes of each container over
+// the same period.
+type containerNetworkAccountant struct {
+	// listSockets returns the bytes sent and received on each TCP socket of the task
+	// network namespace, keyed by socket inode.
+	listSockets func() (map[uint64]socketBytes, error)
+	// socketOwners returns the docker id of the container holding each socket, keyed
+	// by socket inode.
+	socketOwners func(dockerIDs []string) (map[uint64]string, error)
+	// lastSockets holds the socket counters read at the previous poll.
+	lastSockets map[uint64]socketBytes
+	// lastLinkStats holds the task interface counters read at the previous poll.
+	lastLinkStats map[string]dockerstats.NetworkStats
+	// containerStats holds the network stats attributed to each container so far.
+	containerStats map[string]map[string]dockerstats.NetworkStats
+}
+
+func newContainerNetworkAccountant(netNSPath string, nsWrapper nswrapper.NS) *containerNetworkAccountant {
+	return &containerNetworkAccountant{
+		listSockets: func() (map[uint64]socketBytes, error) {
+			var sockets map[uint64]socketBytes
+			err := nsWrapper.WithNetNSPath(netNSPath, func(ns.NetNS) error {
+				var listErr error
+				sockets, listErr = listTCPSockets()
+				return listErr
+			})
+			return sockets, err
+		},
+		socketOwners: func(dockerIDs []string) (map[uint64]string, error) {
+			return socketOwnersFromProcFS(hostProcFSPath, dockerIDs)
+		},
+		lastSockets:    make(map[uint64]socketBytes),
+		lastLinkStats:  make(map[string]dockerstats.NetworkStats),
+		containerStats: make(map[string]map[string]dockerstats.NetworkStats),
+	}
+}
+
+// attribute splits the growth of the task interface counters since the previous call
+// among the given containers, and returns the network stats attributed to each container
+// so far. The growth is split evenly when none of the container sockets carried any
+// traffic, or when the sockets can't be read.
+func (accountant *containerNetworkAccountant) attribute(dockerIDs []string,
+	linkStats map[string]dockerstats.NetworkStats) map[string]map[string]dockerstats.NetworkStats {
+	weights, err := accountant.socketWeights(dockerIDs)
+	if err != nil {
+		seelog.Debugf("Unable to read the container sockets, splitting network stats evenly: %v", err)
+	}
+
+	result := make(map[string]map[string]dockerstats.NetworkStats, len(dockerIDs))
+	for _, dockerID := range dockerIDs {
+		stats, ok := accountant.containerStats[dockerID]
+		if !ok {
+			stats = make(map[string]dockerstats.NetworkStats, len(linkStats))
+		}
+		for device, current := range linkStats {
+			delta := networkStatsDelta(current, accountant.lastLinkStats[device])
+			rxShare, txShare := shareOf(weights, dockerID, len(dockerIDs))
+			stats[device] = addNetworkStats(stats[device], delta, rxShare, txShare)
+		}
+		result[dockerID] = stats
+	}
+	accountant.containerStats = result
+	accountant.lastLinkStats = linkStats
+
+	// Hand out copies, the maps are updated in place on the next call.
+	attributed := make(map[string]map[string]dockerstats.NetworkStats, len(result))
+	for dockerID, stats := range result {
+		attributed[dockerID] = make(map[string]dockerstats.NetworkStats, len(stats))
+		for device, deviceStats := range stats {
+			attributed[dockerID][device] = deviceStats
+		}
+	}
+	return attributed
+}
+
+// socketWeights returns the bytes sent and received by each container since the previous
+// call, on the TCP sockets held by its processes.
+func (accountant *containerNetworkAccountant) socketWeights(dockerIDs []string) (map[string]socketBytes, error) {
+	sockets, err := accountant.listSockets()
+	if err != nil {
+		return nil, err
+	}
+	owners, err := accountant.socketOwners(dockerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make(map[string]socketBytes, len(dockerIDs))
+	for inode, current := range sockets {
+		owner, ok := owners[inode]
+		if !ok {
+			continue
+		}
+		last := accountant.lastSockets[inode]
+		weight := weights[owner]
+		weight.sent += counterDelta(current.sent, last.sent)
+		weight.received += counterDelta(current.received, last.received)
+		weights[owner] = weight
+	}
+	accountant.lastSockets = sockets
+	return weights, nil
+}
+
+// shareOf returns the share of the received and sent traffic attributed to a container.
+func shareOf(weights map[string]socketBytes, dockerID string, numberOfContainers int) (float64, float64) {
+	var totalSent, totalReceived uint64
+	for _, weight := range weights {
+		totalSent += weight.sent
+		totalReceived += weight.received
+	}
+	evenShare := 1 / float64(numberOfContainers)
+	rxShare, txShare := evenShare, evenShare
+	if totalReceived > 0 {
+		rxShare = float64(weights[dockerID].received) / float64(totalReceived)
+	}
+	if totalSent > 0 {
+		txShare = float64(weights[dockerID].sent) / float64(totalSent)
+	}
+	return rxShare, txShare
+}
+
+// counterDelta returns the growth of a counter, treating a counter lower than its
+// previous value as reset.
+func counterDelta(current, last uint64) uint64 {
+	if current < last {
+		return current
+	}
+	return current - last
+}
+
+func networkStatsDelta(current, last dockerstats.NetworkStats) dockerstats.NetworkStats {
+	return dockerstats.NetworkStats{
+		RxBytes:   counterDelta(current.RxBytes, last.RxBytes),
+		RxPackets: counterDelta(current.RxPackets, last.RxPackets),
+		RxErrors:  counterDelta(current.RxErrors, last.RxErrors),
+		RxDropped: counterDelta(current.RxDropped, last.RxDropped),
+		TxBytes:   counterDelta(current.TxBytes, last.TxBytes),
+		TxPackets: counterDelta(current.TxPackets, last.TxPackets),
+		TxErrors:  counterDelta(current.TxErrors, last.TxErrors),
+		TxDropped: counterDelta(current.TxDropped, last.TxDropped),
+	}
+}
+
+func addNetworkStats(stats, delta dockerstats.NetworkStats, rxShare, txShare float64) dockerstats.NetworkStats {
+	scale := func(value uint64, share float64) uint64 {
+		return uint64(float64(value) * share)
+	}
+	stats.RxBytes += scale(delta.RxBytes, rxShare)
+	stats.RxPackets += scale(delta.RxPackets, rxShare)
+	stats.RxErrors += scale(delta.RxErrors, rxShare)
+	stats.RxDropped += scale(delta.RxDropped, rxShare)
+	stats.TxBytes += scale(delta.TxBytes, txShare)
+	stats.TxPackets += scale(delta.TxPackets, txShare)
+	stats.TxErrors += scale(delta.TxErrors, txShare)
+	stats.TxDropped += scale(delta.TxDropped, txShare)
+	return stats
+}
+
+// socketOwnersFromProcFS maps the inodes of the sockets held by the processes of the given
+// containers to the docker id of their container. The processes of a container are the
+// ones whose cgroup path contains the docker id, which holds for both the cgroupfs and
+// the systemd cgroup drivers.
+func socketOwnersFromProcFS(procFSPath string, dockerIDs []string) (map[uint64]string, error) {
+	entries, err := ioutil.ReadDir(procFSPath)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[uint64]string)
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		pidPath := filepath.Join(procFSPath, entry.Name())
+		cgroups, err := ioutil.ReadFile(filepath.Join(pidPath, "cgroup"))
+		if err != nil {
+			// The process may have exited since the directory was listed.
+			continue
+		}
+		owner := ""
+		for _, dockerID := range dockerIDs {
+			if dockerID != "" && strings.Contains(string(cgroups), dockerID) {
+				owner = dockerID
+				break
+			}
+		}
+		if owner == "" {
+			continue
+		}
+		fds, err := ioutil.ReadDir(filepath.Join(pidPath, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(pidPath, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(target, socketLinkPrefix) {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(
+				strings.TrimPrefix(target, socketLinkPrefix), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			owners[inode] = owner
+		}
+	}
+	return owners, nil
+}
+
+// inetDiagRequest is the inet_diag_req_v2 struct, dumping the sockets of a family along
+// with their tcp_info.
+type inetDiagRequest struct {
+	family uint8
+}
+
+func (req *inetDiagRequest) Len() int { return sizeofInetDiagRequest }
+
+func (req *inetDiagRequest) Serialize() []byte {
+	b := make([]byte, sizeofInetDiagRequest)
+	b[0] = req.family
+	b[1] = syscall.IPPROTO_TCP
+	b[2] = 1 << (inetDiagInfo - 1)
+	// Sockets in any state.
+	nl.NativeEndian().PutUint32(b[4:8], ^uint32(0))
+	return b
+}
+
+// listTCPSockets returns the bytes sent and received on the TCP sockets of the current
+// network namespace, keyed by socket inode.
+func listTCPSockets() (map[uint64]socketBytes, error) {
+	sockets := make(map[uint64]socketBytes)
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		req := nl.NewNetlinkRequest(nl.SOCK_DIAG_BY_FAMILY, syscall.NLM_F_DUMP)
+		req.AddData(&inetDiagRequest{family: family})
+		msgs, err := req.Execute(syscall.NETLINK_INET_DIAG, nl.SOCK_DIAG_BY_FAMILY)
+		if err != nil {
+			return nil, fmt.Errorf("unable to dump tcp sockets: %v", err)
+		}
+		for _, msg := range msgs {
+			inode, bytes, ok := parseInetDiagMessage(msg)
+			if ok {
+				sockets[inode] = bytes
+			}
+		}
+	}
+	return sockets, nil
+}
+
+// parseInetDiagMessage returns the inode and the byte counters of the socket described by
+// an inet_diag_msg, if the message carries them.
+func parseInetDiagMessage(msg []byte) (uint64, socketBytes, bool) {
+	if len(msg) < sizeofInetDiagMessage {
+		return 0, socketBytes{}, false
+	}
+	native := nl.NativeEndian()
+	inode := uint64(native.Uint32(msg[inetDiagMessageInodeOffset:sizeofInetDiagMessage]))
+	attrs, err := nl.ParseRouteAttr(msg[sizeofInetDiagMessage:])
+	if err != nil {
+		return 0, socketBytes{}, false
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type != inetDiagInfo || len(attr.Value) < tcpInfoBytesReceivedOffset+8 {
+			continue
+		}
+		return inode, socketBytes{
+			sent:     native.Uint64(attr.Value[tcpInfoBytesAckedOffset : tcpInfoBytesAckedOffset+8]),
+			received: native.Uint64(attr.Value[tcpInfoBytesReceivedOffset : tcpInfoBytesReceivedOffset+8]),
+		}, true
+	}
+	return 0, socketBytes{}, false
+}
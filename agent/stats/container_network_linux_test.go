@@ -0,0 +1,154 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+)
+
+func newTestContainerNetworkAccountant(sockets *map[uint64]socketBytes, owners map[uint64]string,
+	socketsErr error) *containerNetworkAccountant {
+	return &containerNetworkAccountant{
+		listSockets: func() (map[uint64]socketBytes, error) {
+			return *sockets, socketsErr
+		},
+		socketOwners: func([]string) (map[uint64]string, error) {
+			return owners, nil
+		},
+		lastSockets:    make(map[uint64]socketBytes),
+		lastLinkStats:  make(map[string]types.NetworkStats),
+		containerStats: make(map[string]map[string]types.NetworkStats),
+	}
+}
+
+func TestContainerNetworkAccountantAttribute(t *testing.T) {
+	sockets := map[uint64]socketBytes{
+		1: {sent: 300, received: 100},
+		2: {sent: 100, received: 300},
+		3: {sent: 1000, received: 1000},
+	}
+	owners := map[uint64]string{1: "c1", 2: "c2"}
+	accountant := newTestContainerNetworkAccountant(&sockets, owners, nil)
+
+	attributed := accountant.attribute([]string{"c1", "c2"}, map[string]types.NetworkStats{
+		"eth1": {RxBytes: 1000, TxBytes: 2000, RxPackets: 100, TxPackets: 200},
+	})
+	assert.EqualValues(t, 250, attributed["c1"]["eth1"].RxBytes)
+	assert.EqualValues(t, 25, attributed["c1"]["eth1"].RxPackets)
+	assert.EqualValues(t, 1500, attributed["c1"]["eth1"].TxBytes)
+	assert.EqualValues(t, 750, attributed["c2"]["eth1"].RxBytes)
+	assert.EqualValues(t, 500, attributed["c2"]["eth1"].TxBytes)
+
+	// Only the growth since the previous poll is split according to the new weights.
+	sockets = map[uint64]socketBytes{
+		1: {sent: 300, received: 100},
+		2: {sent: 500, received: 700},
+	}
+	attributed = accountant.attribute([]string{"c1", "c2"}, map[string]types.NetworkStats{
+		"eth1": {RxBytes: 2000, TxBytes: 3000, RxPackets: 200, TxPackets: 300},
+	})
+	assert.EqualValues(t, 250, attributed["c1"]["eth1"].RxBytes)
+	assert.EqualValues(t, 1500, attributed["c1"]["eth1"].TxBytes)
+	assert.EqualValues(t, 1750, attributed["c2"]["eth1"].RxBytes)
+	assert.EqualValues(t, 1500, attributed["c2"]["eth1"].TxBytes)
+}
+
+func TestContainerNetworkAccountantAttributeEvenly(t *testing.T) {
+	testCases := []struct {
+		name       string
+		sockets    map[uint64]socketBytes
+		socketsErr error
+	}{
+		{
+			name:    "no traffic on the container sockets",
+			sockets: map[uint64]socketBytes{1: {}},
+		},
+		{
+			name:       "sockets can't be read",
+			socketsErr: errors.New("operation not permitted"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			accountant := newTestContainerNetworkAccountant(&tc.sockets, map[uint64]string{1: "c1"}, tc.socketsErr)
+			attributed := accountant.attribute([]string{"c1", "c2"}, map[string]types.NetworkStats{
+				"eth1": {RxBytes: 1000, TxBytes: 2000},
+			})
+			for _, dockerID := range []string{"c1", "c2"} {
+				assert.EqualValues(t, 500, attributed[dockerID]["eth1"].RxBytes)
+				assert.EqualValues(t, 1000, attributed[dockerID]["eth1"].TxBytes)
+			}
+		})
+	}
+}
+
+func TestContainerNetworkAccountantLinkReset(t *testing.T) {
+	sockets := map[uint64]socketBytes{}
+	accountant := newTestContainerNetworkAccountant(&sockets, nil, nil)
+
+	accountant.attribute([]string{"c1"}, map[string]types.NetworkStats{"eth1": {RxBytes: 1000}})
+	attributed := accountant.attribute([]string{"c1"}, map[string]types.NetworkStats{"eth1": {RxBytes: 10}})
+	assert.EqualValues(t, 1010, attributed["c1"]["eth1"].RxBytes)
+}
+
+func TestSocketOwnersFromProcFS(t *testing.T) {
+	procFS, err := ioutil.TempDir("", "procfs")
+	require.NoError(t, err)
+	defer os.RemoveAll(procFS)
+
+	createProcess := func(pid, cgroup string, fds map[string]string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(procFS, pid, "fd"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(procFS, pid, "cgroup"), []byte(cgroup), 0644))
+		for fd, target := range fds {
+			require.NoError(t, os.Symlink(target, filepath.Join(procFS, pid, "fd", fd)))
+		}
+	}
+	createProcess("10", "4:memory:/ecs/task/c1\n", map[string]string{"3": "socket:[100]", "4": "/dev/null"})
+	createProcess("11", "0::/system.slice/docker-c2.scope\n", map[string]string{"3": "socket:[200]"})
+	createProcess("12", "4:memory:/ecs/task/other\n", map[string]string{"3": "socket:[300]"})
+	require.NoError(t, os.MkdirAll(filepath.Join(procFS, "sys"), 0755))
+
+	owners, err := socketOwnersFromProcFS(procFS, []string{"c1", "c2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]string{100: "c1", 200: "c2"}, owners)
+}
+
+func TestParseInetDiagMessage(t *testing.T) {
+	native := nl.NativeEndian()
+	tcpInfo := make([]byte, tcpInfoBytesReceivedOffset+8)
+	native.PutUint64(tcpInfo[tcpInfoBytesAckedOffset:], 1234)
+	native.PutUint64(tcpInfo[tcpInfoBytesReceivedOffset:], 5678)
+	msg := make([]byte, sizeofInetDiagMessage)
+	native.PutUint32(msg[inetDiagMessageInodeOffset:], 42)
+	msg = append(msg, nl.NewRtAttr(inetDiagInfo, tcpInfo).Serialize()...)
+
+	inode, bytes, ok := parseInetDiagMessage(msg)
+	require.True(t, ok)
+	assert.EqualValues(t, 42, inode)
+	assert.Equal(t, socketBytes{sent: 1234, received: 5678}, bytes)
+
+	_, _, ok = parseInetDiagMessage(msg[:sizeofInetDiagMessage])
+	assert.False(t, ok, "message without tcp_info")
+}
@@ -18,6 +18,7 @@ package stats
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"testing"
@@ -208,3 +209,159 @@ func TestContainerStatsCollectionStopsIfContainerIsTerminal(t *testing.T) {
 	case <-ctx.Done():
 	}
 }
+
+func TestContainerUpdateDiskUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	dockerID := "container1"
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	sizeRw := int64(4096)
+	mockDockerClient.EXPECT().InspectContainerSize(ctx, dockerID, dockerclient.InspectContainerTimeout).Return(
+		&types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:     dockerID,
+				SizeRw: &sizeRw,
+				State:  &types.ContainerState{},
+			},
+		}, nil)
+
+	container := &StatsContainer{
+		containerMetadata: &ContainerMetadata{
+			DockerID: dockerID,
+		},
+		ctx:        ctx,
+		cancel:     cancel,
+		client:     mockDockerClient,
+		statsQueue: NewQueue(1),
+	}
+	if err := container.updateDiskUsage(); err != nil {
+		t.Fatal("Error updating disk usage:", err)
+	}
+	diskUsage := container.statsQueue.GetDiskUsage()
+	if diskUsage == nil {
+		t.Fatal("Disk usage not set")
+	}
+	if diskUsage.WritableLayerSizeBytes != uint64(sizeRw) {
+		t.Error("Writable layer size incorrectly set: ", diskUsage.WritableLayerSizeBytes)
+	}
+	if diskUsage.BindMountsSizeBytes != 0 {
+		t.Error("Bind mounts size incorrectly set: ", diskUsage.BindMountsSizeBytes)
+	}
+}
+
+func TestContainerStatsSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	dockerID := "container1"
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	dockerStat := &types.StatsJSON{}
+	json.Unmarshal([]byte(`
+		{
+			"memory_stats": {"usage":1024, "privateworkingset":1024},
+			"cpu_stats":{
+				"cpu_usage":{
+					"percpu_usage":[100],
+					"total_usage":100
+				}
+			}
+		}`), dockerStat)
+	dockerStat.Read = time.Now()
+	mockDockerClient.EXPECT().StatsSnapshot(ctx, dockerID, dockerclient.StatsInactivityTimeout).Return(dockerStat, nil)
+
+	container := &StatsContainer{
+		containerMetadata: &ContainerMetadata{
+			DockerID: dockerID,
+		},
+		ctx:        ctx,
+		cancel:     cancel,
+		client:     mockDockerClient,
+		statsQueue: NewQueue(1),
+	}
+	snapshot, err := container.snapshot()
+	if err != nil {
+		t.Fatal("Error collecting stats snapshot:", err)
+	}
+	if snapshot != dockerStat {
+		t.Error("Stats snapshot not returned")
+	}
+	if container.statsQueue.GetLastStat() != nil {
+		t.Error("Stats snapshot unexpectedly added to the queue")
+	}
+
+	// Snapshots requested right after are served from the latest sample
+	snapshot, err = container.snapshot()
+	if err != nil {
+		t.Fatal("Error collecting stats snapshot:", err)
+	}
+	if snapshot != dockerStat {
+		t.Error("Latest stats snapshot not returned")
+	}
+}
+
+func TestContainerStatsSnapshotUsesRecentSample(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	dockerID := "container1"
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	dockerStat := &types.StatsJSON{}
+	dockerStat.Read = time.Now()
+	dockerStat.CPUStats.CPUUsage.PercpuUsage = []uint64{100}
+	dockerStat.CPUStats.CPUUsage.TotalUsage = 100
+
+	container := &StatsContainer{
+		containerMetadata: &ContainerMetadata{
+			DockerID: dockerID,
+		},
+		ctx:        ctx,
+		cancel:     cancel,
+		client:     mockDockerClient,
+		statsQueue: NewQueue(1),
+	}
+	if err := container.statsQueue.Add(dockerStat); err != nil {
+		t.Fatal("Error adding stats to the queue:", err)
+	}
+	// No sample is collected, as the regular collection just got one
+	snapshot, err := container.snapshot()
+	if err != nil {
+		t.Fatal("Error collecting stats snapshot:", err)
+	}
+	if snapshot != dockerStat {
+		t.Error("Recent stats sample not returned")
+	}
+}
+
+func TestContainerStatsSnapshotError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	dockerID := "container1"
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	mockDockerClient.EXPECT().StatsSnapshot(ctx, dockerID, dockerclient.StatsInactivityTimeout).Return(nil, errors.New("error"))
+
+	container := &StatsContainer{
+		containerMetadata: &ContainerMetadata{
+			DockerID: dockerID,
+		},
+		ctx:        ctx,
+		cancel:     cancel,
+		client:     mockDockerClient,
+		statsQueue: NewQueue(1),
+	}
+	if _, err := container.snapshot(); err == nil {
+		t.Error("Expected error collecting stats snapshot")
+	}
+	if container.statsQueue.GetLastStat() != nil {
+		t.Error("Stats queue unexpectedly updated")
+	}
+}
@@ -0,0 +1,74 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// bindMountsSize returns the disk space used by the files in the bind mounts of a
+// container. The bind mounts are walked through the root of the container process in
+// the host procfs, as their sources on the host aren't mounted in the agent container.
+func bindMountsSize(pid int, mounts []types.MountPoint) uint64 {
+	if pid == 0 {
+		return 0
+	}
+	root := filepath.Join(hostProcFSPath, strconv.Itoa(pid), "root")
+	var size uint64
+	for _, mountPoint := range mounts {
+		if mountPoint.Type != mount.TypeBind {
+			continue
+		}
+		size += directorySize(filepath.Join(root, mountPoint.Destination))
+	}
+	return size
+}
+
+// directorySize returns the disk space used by the files under a path. Files that can't
+// be read are skipped, and so are the mounts under the path, such as volumes and other
+// bind mounts, whose files are on other devices.
+func directorySize(path string) uint64 {
+	var rootStat syscall.Stat_t
+	if err := syscall.Stat(path, &rootStat); err != nil {
+		return 0
+	}
+	var size uint64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		if stat.Dev != rootStat.Dev {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// count the allocated blocks, so that sparse files don't inflate the usage
+		size += uint64(stat.Blocks) * 512
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,66 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectorySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "disk-usage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file"), make([]byte, 64*1024), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "nested", "file"), make([]byte, 64*1024), 0644))
+
+	// the directories themselves use some blocks as well
+	assert.True(t, directorySize(dir) >= 128*1024, "unexpected directory size")
+	assert.Zero(t, directorySize(filepath.Join(dir, "missing")))
+}
+
+func TestDirectorySizeSkipsOtherDevices(t *testing.T) {
+	// /dev/shm is usually a tmpfs mounted under /dev
+	var devStat, shmStat syscall.Stat_t
+	if syscall.Stat("/dev", &devStat) != nil || syscall.Stat("/dev/shm", &shmStat) != nil || devStat.Dev == shmStat.Dev {
+		t.Skip("/dev/shm is not a separate mount")
+	}
+	file, err := ioutil.TempFile("/dev/shm", "disk-usage")
+	if err != nil {
+		t.Skipf("unable to write to /dev/shm: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	sizeBefore := directorySize("/dev")
+	_, err = file.Write(make([]byte, 1024*1024))
+	require.NoError(t, err)
+	require.NoError(t, file.Sync())
+	assert.True(t, directorySize("/dev/shm") >= 1024*1024, "unexpected size of the mount itself")
+	assert.True(t, directorySize("/dev") < sizeBefore+1024*1024, "files on other devices should not be counted")
+}
+
+func TestBindMountsSizeWithoutProcess(t *testing.T) {
+	assert.Zero(t, bindMountsSize(0, nil))
+}
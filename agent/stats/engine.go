@@ -67,6 +67,9 @@ type DockerContainerMetadataResolver struct {
 type Engine interface {
 	GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error)
 	ContainerDockerStats(taskARN string, containerID string) (*types.StatsJSON, *NetworkStatsPerSec, error)
+	ContainerDockerStatsSnapshot(taskARN string, containerID string) (*types.StatsJSON, *NetworkStatsPerSec, error)
+	ContainerDiskUsage(taskARN string, containerID string) (*DiskUsage, error)
+	ContainerGPUStats(taskARN string, containerID string) ([]*GPUStats, error)
 	GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error)
 }
 
@@ -90,6 +93,8 @@ type DockerStatsEngine struct {
 	// tasksToDefinitions maps task arns to task definition name and family metadata objects.
 	tasksToDefinitions map[string]*taskDefinition
 	taskToTaskStats    map[string]*StatsTask
+	// gpuMetrics samples the metrics of the GPUs on the instance, if GPU metrics are enabled.
+	gpuMetrics *gpuMetricsCollector
 }
 
 // ResolveTask resolves the api task object, given container id.
@@ -224,6 +229,13 @@ func (engine *DockerStatsEngine) MustInit(ctx context.Context, taskEngine ecseng
 		seelog.Warnf("Synchronize the container state failed, err: %v", err)
 	}
 
+	if engine.config.GPUSupportEnabled && engine.config.GPUMetricsEnabled.Enabled() {
+		if sampler := newGPUSampler(); sampler != nil {
+			engine.gpuMetrics = newGPUMetricsCollector(sampler)
+			go engine.gpuMetrics.collect(engine.ctx, gpuMetricsSamplingInterval)
+		}
+	}
+
 	go engine.waitToStop()
 	return nil
 }
@@ -281,7 +293,8 @@ func (engine *DockerStatsEngine) addToStatsTaskMapUnsafe(task *apitask.Task, doc
 			}
 			containerpid := strconv.Itoa(containerInspect.State.Pid)
 			statsTaskContainer, err = newStatsTaskContainer(task.Arn, containerpid, numberOfContainers,
-				engine.resolver, engine.config.PollingMetricsWaitDuration)
+				engine.resolver, engine.config.PollingMetricsWaitDuration,
+				engine.config.AWSVPCContainerNetworkStats.Enabled())
 			if err != nil {
 				return
 			}
@@ -678,6 +691,10 @@ func (engine *DockerStatsEngine) taskContainerMetricsUnsafe(taskArn string) ([]*
 			containerMetric.StorageStatsSet = storageStatsSet
 		}
 
+		if engine.gpuMetrics != nil && len(container.containerMetadata.GPUIDs) > 0 {
+			containerMetric.GpuMetrics = engine.gpuMetrics.GetGPUMetrics(container.containerMetadata.GPUIDs)
+		}
+
 		task, err := engine.resolver.ResolveTask(dockerID)
 		if err != nil {
 			seelog.Warnf("Task not found for container ID: %s", dockerID)
@@ -702,7 +719,12 @@ func (engine *DockerStatsEngine) taskContainerMetricsUnsafe(taskArn string) ([]*
 				} else {
 					// do not add network stats for pause container
 					if dockerContainer.Container.Type != apicontainer.ContainerCNIPause {
-						networkStats, err := taskStatsMap.StatsQueue.GetNetworkStatsSet()
+						networkStatsQueue := taskStatsMap.StatsQueue
+						if containerStatsQueue := taskStatsMap.ContainerStatsQueue(dockerID); containerStatsQueue != nil {
+							// use the network stats attributed to the container when available
+							networkStatsQueue = containerStatsQueue
+						}
+						networkStats, err := networkStatsQueue.GetNetworkStatsSet()
 						if err != nil {
 							seelog.Warnf("error getting network stats: %v, task: %v", err, taskArn)
 						} else {
@@ -751,6 +773,9 @@ func (engine *DockerStatsEngine) resetStatsUnsafe() {
 			container.statsQueue.Reset()
 		}
 	}
+	if engine.gpuMetrics != nil {
+		engine.gpuMetrics.Reset()
+	}
 }
 
 // ContainerDockerStats returns the last stored raw docker stats object for a container
@@ -782,10 +807,15 @@ func (engine *DockerStatsEngine) ContainerDockerStats(taskARN string, containerI
 	if task.IsNetworkModeAWSVPC() {
 		taskStats, ok := taskToTaskStats[taskARN]
 		if ok {
-			if taskStats.StatsQueue.GetLastStat() != nil {
-				containerStats.Networks = taskStats.StatsQueue.GetLastStat().Networks
+			networkStatsQueue := taskStats.StatsQueue
+			if containerStatsQueue := taskStats.ContainerStatsQueue(containerID); containerStatsQueue != nil {
+				// use the network stats attributed to the container when available
+				networkStatsQueue = containerStatsQueue
 			}
-			containerNetworkRateStats = taskStats.StatsQueue.GetLastNetworkStatPerSec()
+			if networkStatsQueue.GetLastStat() != nil {
+				containerStats.Networks = networkStatsQueue.GetLastStat().Networks
+			}
+			containerNetworkRateStats = networkStatsQueue.GetLastNetworkStatPerSec()
 		} else {
 			seelog.Warnf("Network stats not found for container %s", containerID)
 		}
@@ -793,3 +823,77 @@ func (engine *DockerStatsEngine) ContainerDockerStats(taskARN string, containerI
 
 	return containerStats, containerNetworkRateStats, nil
 }
+
+// ContainerDiskUsage returns the last measured disk usage of a container, or nil if the
+// disk usage of the container hasn't been measured
+// ContainerDockerStatsSnapshot collects a fresh stats sample for the specified container, instead of
+// waiting for the next collection cycle, and returns it along with the latest network rate stats.
+// The network stats of containers in awsvpc tasks come from the last regular sample of the task.
+func (engine *DockerStatsEngine) ContainerDockerStatsSnapshot(taskARN string, containerID string) (*types.StatsJSON, *NetworkStatsPerSec, error) {
+	engine.lock.RLock()
+	containerIDToStatsContainer, ok := engine.tasksToContainers[taskARN]
+	if !ok {
+		engine.lock.RUnlock()
+		return nil, nil, errors.Errorf("stats engine: task '%s' for container '%s' not found",
+			taskARN, containerID)
+	}
+	container, ok := containerIDToStatsContainer[containerID]
+	engine.lock.RUnlock()
+	if !ok {
+		return nil, nil, errors.Errorf("stats engine: container not found: %s", containerID)
+	}
+
+	// the sample is collected without holding the engine lock, as it can take a couple of seconds
+	snapshot, err := container.snapshot()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "stats engine: unable to collect stats snapshot for container %s", containerID)
+	}
+	lastStats, networkRateStats, err := engine.ContainerDockerStats(taskARN, containerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	containerStats := *snapshot
+	if containerStats.Networks == nil && lastStats != nil {
+		containerStats.Networks = lastStats.Networks
+	}
+	return &containerStats, networkRateStats, nil
+}
+
+func (engine *DockerStatsEngine) ContainerDiskUsage(taskARN string, containerID string) (*DiskUsage, error) {
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	containerIDToStatsContainer, ok := engine.tasksToContainers[taskARN]
+	if !ok {
+		return nil, errors.Errorf("stats engine: task '%s' for container '%s' not found",
+			taskARN, containerID)
+	}
+
+	container, ok := containerIDToStatsContainer[containerID]
+	if !ok {
+		return nil, errors.Errorf("stats engine: container not found: %s", containerID)
+	}
+	return container.statsQueue.GetDiskUsage(), nil
+}
+
+// ContainerGPUStats returns the last sample of the GPUs assigned to a container, or nil if
+// GPU metrics aren't enabled.
+func (engine *DockerStatsEngine) ContainerGPUStats(taskARN string, containerID string) ([]*GPUStats, error) {
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	containerIDToStatsContainer, ok := engine.tasksToContainers[taskARN]
+	if !ok {
+		return nil, errors.Errorf("stats engine: task '%s' for container '%s' not found",
+			taskARN, containerID)
+	}
+
+	container, ok := containerIDToStatsContainer[containerID]
+	if !ok {
+		return nil, errors.Errorf("stats engine: container not found: %s", containerID)
+	}
+	if engine.gpuMetrics == nil {
+		return nil, nil
+	}
+	return engine.gpuMetrics.GetLastGPUStats(container.containerMetadata.GPUIDs), nil
+}
@@ -27,6 +27,7 @@ import (
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
 	mock_resolver "github.com/aws/amazon-ecs-agent/agent/stats/resolver/mock"
@@ -233,6 +234,102 @@ func TestStatsEngineMetadataInStatsSets(t *testing.T) {
 	validateIdleContainerMetrics(t, engine)
 }
 
+func TestStatsEngineContainerDockerStatsSnapshot(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(mockCtrl)
+	t1 := &apitask.Task{Arn: "t1", Family: "f1"}
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(&apicontainer.DockerContainer{
+		Container: &apicontainer.Container{
+			Name: "test",
+		},
+	}, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	resolver.EXPECT().ResolveTaskByARN(gomock.Any()).Return(t1, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineContainerDockerStatsSnapshot"))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	engine.ctx = ctx
+	engine.resolver = resolver
+	engine.client = mockDockerClient
+	engine.addAndStartStatsContainer("c1")
+
+	snapshot := &types.StatsJSON{}
+	snapshot.Read = time.Now()
+	snapshot.CPUStats.CPUUsage.PercpuUsage = []uint64{100}
+	snapshot.CPUStats.CPUUsage.TotalUsage = 100
+	mockDockerClient.EXPECT().StatsSnapshot(gomock.Any(), "c1", dockerclient.StatsInactivityTimeout).Return(snapshot, nil)
+
+	dockerStat, _, err := engine.ContainerDockerStatsSnapshot("t1", "c1")
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot, dockerStat)
+
+	_, _, err = engine.ContainerDockerStatsSnapshot("t2", "c1")
+	assert.Error(t, err)
+	_, _, err = engine.ContainerDockerStatsSnapshot("t1", "c2")
+	assert.Error(t, err)
+}
+
+func TestStatsEngineGPUMetrics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(mockCtrl)
+	t1 := &apitask.Task{Arn: "t1", Family: "f1"}
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(&apicontainer.DockerContainer{
+		Container: &apicontainer.Container{
+			Name:   "test",
+			GPUIDs: []string{"gpu1"},
+		},
+	}, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	resolver.EXPECT().ResolveTaskByARN(gomock.Any()).Return(t1, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineGPUMetrics"))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	engine.ctx = ctx
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.gpuMetrics = newGPUMetricsCollector(func() ([]*GPUStats, error) {
+		return []*GPUStats{
+			{GPUID: "gpu1", UtilizationPercent: 45},
+			{GPUID: "gpu2", UtilizationPercent: 10},
+		}, nil
+	})
+	require.NoError(t, engine.gpuMetrics.update())
+	engine.addAndStartStatsContainer("c1")
+	containerStats := createFakeContainerStats()
+	containers, _ := engine.tasksToContainers["t1"]
+	for _, statsContainer := range containers {
+		for i := 0; i < 2; i++ {
+			statsContainer.statsQueue.add(containerStats[i])
+		}
+	}
+
+	_, taskMetrics, err := engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	require.Len(t, taskMetrics, 1)
+	require.Len(t, taskMetrics[0].ContainerMetrics, 1)
+	gpuMetrics := taskMetrics[0].ContainerMetrics[0].GpuMetrics
+	require.Len(t, gpuMetrics, 1)
+	assert.Equal(t, "gpu1", aws.StringValue(gpuMetrics[0].GpuId))
+	assert.Equal(t, float64(45), aws.Float64Value(gpuMetrics[0].UtilizationStatsSet.Sum))
+	// the aggregated samples are reset once published
+	assert.Empty(t, engine.gpuMetrics.GetGPUMetrics([]string{"gpu1"}))
+
+	gpuStats, err := engine.ContainerGPUStats("t1", "c1")
+	require.NoError(t, err)
+	require.Len(t, gpuStats, 1)
+	assert.Equal(t, "gpu1", gpuStats[0].GPUID)
+}
+
 func TestStatsEngineInvalidTaskEngine(t *testing.T) {
 	statsEngine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineInvalidTaskEngine"))
 	taskEngine := &MockTaskEngine{}
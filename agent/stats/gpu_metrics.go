@@ -0,0 +1,205 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cihub/seelog"
+)
+
+// gpuMetricsSamplingInterval is how often the metrics of the GPUs are sampled
+const gpuMetricsSamplingInterval = 5 * time.Second
+
+// errGPUMetricsUnavailable is returned by samplers that can't sample the GPU metrics on this
+// instance, the collection is stopped as retrying won't help
+var errGPUMetricsUnavailable = errors.New("nvidia-smi isn't installed on the host")
+
+// gpuSampler returns a sample of the metrics of each GPU on the instance.
+type gpuSampler func() ([]*GPUStats, error)
+
+// gpuMetricsCollector periodically samples the metrics of the GPUs on the instance, and
+// aggregates the samples taken between two publishes of the container metrics.
+type gpuMetricsCollector struct {
+	sample gpuSampler
+	lock   sync.RWMutex
+	// lastStats maps GPU ids to the last sample of the GPU.
+	lastStats map[string]*GPUStats
+	// aggregates maps GPU ids to the aggregate of the samples taken since the last reset.
+	aggregates map[string]*gpuStatsAggregate
+}
+
+// gpuStatsAggregate holds the min, max and sum of the samples of a GPU.
+type gpuStatsAggregate struct {
+	sampleCount   int64
+	utilization   floatAggregate
+	temperature   floatAggregate
+	memoryUsedMin uint64
+	memoryUsedMax uint64
+	memoryUsedSum uint64
+}
+
+type floatAggregate struct {
+	min, max, sum float64
+}
+
+func newGPUMetricsCollector(sample gpuSampler) *gpuMetricsCollector {
+	return &gpuMetricsCollector{
+		sample:     sample,
+		lastStats:  make(map[string]*GPUStats),
+		aggregates: make(map[string]*gpuStatsAggregate),
+	}
+}
+
+// collect samples the metrics of the GPUs on every interval, until the context is cancelled
+// or the GPU metrics turn out to be unavailable. Only the first of consecutive sampling errors
+// is logged as a warning.
+func (collector *gpuMetricsCollector) collect(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		err := collector.update()
+		switch {
+		case err == errGPUMetricsUnavailable:
+			seelog.Warnf("GPU metrics are disabled: %v", err)
+			return
+		case err != nil && !failing:
+			seelog.Warnf("Error sampling GPU metrics, logging further errors at debug level: %v", err)
+			failing = true
+		case err != nil:
+			seelog.Debugf("Error sampling GPU metrics: %v", err)
+		case failing:
+			seelog.Info("Sampling GPU metrics succeeded again")
+			failing = false
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (collector *gpuMetricsCollector) update() error {
+	gpuStats, err := collector.sample()
+	if err != nil {
+		return err
+	}
+
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	for _, stats := range gpuStats {
+		collector.lastStats[stats.GPUID] = stats
+		aggregate, ok := collector.aggregates[stats.GPUID]
+		if !ok {
+			aggregate = &gpuStatsAggregate{
+				utilization:   floatAggregate{min: math.MaxFloat64, max: -math.MaxFloat64},
+				temperature:   floatAggregate{min: math.MaxFloat64, max: -math.MaxFloat64},
+				memoryUsedMin: math.MaxUint64,
+			}
+			collector.aggregates[stats.GPUID] = aggregate
+		}
+		aggregate.add(stats)
+	}
+	return nil
+}
+
+func (aggregate *gpuStatsAggregate) add(stats *GPUStats) {
+	aggregate.sampleCount++
+	aggregate.utilization.add(stats.UtilizationPercent)
+	aggregate.temperature.add(stats.TemperatureCelsius)
+	if stats.MemoryUsedBytes < aggregate.memoryUsedMin {
+		aggregate.memoryUsedMin = stats.MemoryUsedBytes
+	}
+	if stats.MemoryUsedBytes > aggregate.memoryUsedMax {
+		aggregate.memoryUsedMax = stats.MemoryUsedBytes
+	}
+	aggregate.memoryUsedSum += stats.MemoryUsedBytes
+}
+
+func (aggregate *floatAggregate) add(value float64) {
+	aggregate.min = math.Min(aggregate.min, value)
+	aggregate.max = math.Max(aggregate.max, value)
+	aggregate.sum += value
+}
+
+func (aggregate *floatAggregate) statsSet(sampleCount int64) *ecstcs.CWStatsSet {
+	return &ecstcs.CWStatsSet{
+		Max:         aws.Float64(aggregate.max),
+		Min:         aws.Float64(aggregate.min),
+		SampleCount: aws.Int64(sampleCount),
+		Sum:         aws.Float64(aggregate.sum),
+	}
+}
+
+// GetGPUMetrics returns the metrics aggregated since the last reset for the specified GPUs.
+// GPUs that haven't been sampled since the last reset are left out.
+func (collector *gpuMetricsCollector) GetGPUMetrics(gpuIDs []string) []*ecstcs.GpuMetric {
+	collector.lock.RLock()
+	defer collector.lock.RUnlock()
+
+	var gpuMetrics []*ecstcs.GpuMetric
+	for _, gpuID := range gpuIDs {
+		aggregate, ok := collector.aggregates[gpuID]
+		if !ok {
+			continue
+		}
+		baseMin, overflowMin := getInt64WithOverflow(aggregate.memoryUsedMin)
+		baseMax, overflowMax := getInt64WithOverflow(aggregate.memoryUsedMax)
+		baseSum, overflowSum := getInt64WithOverflow(aggregate.memoryUsedSum)
+		gpuMetrics = append(gpuMetrics, &ecstcs.GpuMetric{
+			GpuId: aws.String(gpuID),
+			MemoryUsedStatsSet: &ecstcs.ULongStatsSet{
+				Max:         &baseMax,
+				OverflowMax: &overflowMax,
+				Min:         &baseMin,
+				OverflowMin: &overflowMin,
+				SampleCount: aws.Int64(aggregate.sampleCount),
+				Sum:         &baseSum,
+				OverflowSum: &overflowSum,
+			},
+			TemperatureStatsSet: aggregate.temperature.statsSet(aggregate.sampleCount),
+			UtilizationStatsSet: aggregate.utilization.statsSet(aggregate.sampleCount),
+		})
+	}
+	return gpuMetrics
+}
+
+// GetLastGPUStats returns the last sample of the specified GPUs.
+func (collector *gpuMetricsCollector) GetLastGPUStats(gpuIDs []string) []*GPUStats {
+	collector.lock.RLock()
+	defer collector.lock.RUnlock()
+
+	var gpuStats []*GPUStats
+	for _, gpuID := range gpuIDs {
+		if stats, ok := collector.lastStats[gpuID]; ok {
+			gpuStats = append(gpuStats, stats)
+		}
+	}
+	return gpuStats
+}
+
+// Reset discards the samples aggregated so far, once they have been published.
+func (collector *gpuMetricsCollector) Reset() {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	collector.aggregates = make(map[string]*gpuStatsAggregate)
+}
@@ -0,0 +1,49 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/gpu"
+)
+
+// newGPUSampler returns a sampler of the GPU metrics reported by NVML.
+func newGPUSampler() gpuSampler {
+	client := gpu.NewNVMLClient()
+	return func() ([]*GPUStats, error) {
+		metrics, err := client.DeviceMetrics()
+		if err == gpu.ErrNvidiaSMINotFound {
+			return nil, errGPUMetricsUnavailable
+		}
+		if err != nil {
+			return nil, err
+		}
+		read := time.Now()
+		gpuStats := make([]*GPUStats, 0, len(metrics))
+		for _, m := range metrics {
+			gpuStats = append(gpuStats, &GPUStats{
+				GPUID:              m.GPUID,
+				UtilizationPercent: m.UtilizationPercent,
+				MemoryUsedBytes:    m.MemoryUsedBytes,
+				MemoryTotalBytes:   m.MemoryTotalBytes,
+				TemperatureCelsius: m.TemperatureCelsius,
+				Read:               read,
+			})
+		}
+		return gpuStats, nil
+	}
+}
@@ -0,0 +1,120 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGPUSampler(samples ...[]*GPUStats) gpuSampler {
+	i := 0
+	return func() ([]*GPUStats, error) {
+		if i >= len(samples) {
+			return nil, errors.New("no more samples")
+		}
+		sample := samples[i]
+		i++
+		return sample, nil
+	}
+}
+
+func TestGPUMetricsCollectorGetGPUMetrics(t *testing.T) {
+	collector := newGPUMetricsCollector(newTestGPUSampler(
+		[]*GPUStats{
+			{GPUID: "gpu1", UtilizationPercent: 20, MemoryUsedBytes: 1024, TemperatureCelsius: 50},
+			{GPUID: "gpu2", UtilizationPercent: 90, MemoryUsedBytes: 4096, TemperatureCelsius: 70},
+		},
+		[]*GPUStats{
+			{GPUID: "gpu1", UtilizationPercent: 60, MemoryUsedBytes: 3072, TemperatureCelsius: 54},
+		},
+	))
+	require.NoError(t, collector.update())
+	require.NoError(t, collector.update())
+	assert.Error(t, collector.update())
+
+	gpuMetrics := collector.GetGPUMetrics([]string{"gpu1", "gpu3"})
+	require.Len(t, gpuMetrics, 1)
+	gpuMetric := gpuMetrics[0]
+	assert.Equal(t, "gpu1", aws.StringValue(gpuMetric.GpuId))
+	assert.NoError(t, gpuMetric.Validate())
+
+	assert.Equal(t, int64(2), aws.Int64Value(gpuMetric.UtilizationStatsSet.SampleCount))
+	assert.Equal(t, float64(20), aws.Float64Value(gpuMetric.UtilizationStatsSet.Min))
+	assert.Equal(t, float64(60), aws.Float64Value(gpuMetric.UtilizationStatsSet.Max))
+	assert.Equal(t, float64(80), aws.Float64Value(gpuMetric.UtilizationStatsSet.Sum))
+
+	assert.Equal(t, int64(2), aws.Int64Value(gpuMetric.MemoryUsedStatsSet.SampleCount))
+	assert.Equal(t, int64(1024), aws.Int64Value(gpuMetric.MemoryUsedStatsSet.Min))
+	assert.Equal(t, int64(3072), aws.Int64Value(gpuMetric.MemoryUsedStatsSet.Max))
+	assert.Equal(t, int64(4096), aws.Int64Value(gpuMetric.MemoryUsedStatsSet.Sum))
+
+	assert.Equal(t, float64(50), aws.Float64Value(gpuMetric.TemperatureStatsSet.Min))
+	assert.Equal(t, float64(54), aws.Float64Value(gpuMetric.TemperatureStatsSet.Max))
+	assert.Equal(t, float64(104), aws.Float64Value(gpuMetric.TemperatureStatsSet.Sum))
+
+	// samples are discarded once published, the last sample is kept
+	collector.Reset()
+	assert.Empty(t, collector.GetGPUMetrics([]string{"gpu1", "gpu2"}))
+	gpuStats := collector.GetLastGPUStats([]string{"gpu1", "gpu2"})
+	require.Len(t, gpuStats, 2)
+	assert.Equal(t, float64(60), gpuStats[0].UtilizationPercent)
+	assert.Equal(t, float64(90), gpuStats[1].UtilizationPercent)
+}
+
+func TestGPUMetricsCollectorCollect(t *testing.T) {
+	collector := newGPUMetricsCollector(newTestGPUSampler(
+		[]*GPUStats{{GPUID: "gpu1", UtilizationPercent: 20}},
+	))
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		collector.collect(ctx, time.Hour)
+		close(done)
+	}()
+
+	// the GPUs are sampled as soon as the collection starts
+	for i := 0; i < 100 && len(collector.GetLastGPUStats([]string{"gpu1"})) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, collector.GetGPUMetrics([]string{"gpu1"}), 1)
+	cancel()
+	<-done
+}
+
+func TestGPUMetricsCollectorCollectStopsWhenUnavailable(t *testing.T) {
+	collector := newGPUMetricsCollector(func() ([]*GPUStats, error) {
+		return nil, errGPUMetricsUnavailable
+	})
+	done := make(chan struct{})
+	go func() {
+		collector.collect(context.TODO(), time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("GPU metrics collection didn't stop")
+	}
+	assert.Empty(t, collector.GetLastGPUStats([]string{"gpu1"}))
+}
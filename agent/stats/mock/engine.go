@@ -50,6 +50,21 @@ func (m *MockEngine) EXPECT() *MockEngineMockRecorder {
 	return m.recorder
 }
 
+// ContainerDiskUsage mocks base method
+func (m *MockEngine) ContainerDiskUsage(arg0, arg1 string) (*stats.DiskUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerDiskUsage", arg0, arg1)
+	ret0, _ := ret[0].(*stats.DiskUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerDiskUsage indicates an expected call of ContainerDiskUsage
+func (mr *MockEngineMockRecorder) ContainerDiskUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerDiskUsage", reflect.TypeOf((*MockEngine)(nil).ContainerDiskUsage), arg0, arg1)
+}
+
 // ContainerDockerStats mocks base method
 func (m *MockEngine) ContainerDockerStats(arg0, arg1 string) (*types.StatsJSON, *stats.NetworkStatsPerSec, error) {
 	m.ctrl.T.Helper()
@@ -66,6 +81,37 @@ func (mr *MockEngineMockRecorder) ContainerDockerStats(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerDockerStats", reflect.TypeOf((*MockEngine)(nil).ContainerDockerStats), arg0, arg1)
 }
 
+// ContainerDockerStatsSnapshot mocks base method
+func (m *MockEngine) ContainerDockerStatsSnapshot(arg0, arg1 string) (*types.StatsJSON, *stats.NetworkStatsPerSec, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerDockerStatsSnapshot", arg0, arg1)
+	ret0, _ := ret[0].(*types.StatsJSON)
+	ret1, _ := ret[1].(*stats.NetworkStatsPerSec)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ContainerDockerStatsSnapshot indicates an expected call of ContainerDockerStatsSnapshot
+func (mr *MockEngineMockRecorder) ContainerDockerStatsSnapshot(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerDockerStatsSnapshot", reflect.TypeOf((*MockEngine)(nil).ContainerDockerStatsSnapshot), arg0, arg1)
+}
+
+// ContainerGPUStats mocks base method
+func (m *MockEngine) ContainerGPUStats(arg0, arg1 string) ([]*stats.GPUStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerGPUStats", arg0, arg1)
+	ret0, _ := ret[0].([]*stats.GPUStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerGPUStats indicates an expected call of ContainerGPUStats
+func (mr *MockEngineMockRecorder) ContainerGPUStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerGPUStats", reflect.TypeOf((*MockEngine)(nil).ContainerGPUStats), arg0, arg1)
+}
+
 // GetInstanceMetrics mocks base method
 func (m *MockEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
 	m.ctrl.T.Helper()
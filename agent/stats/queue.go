@@ -37,6 +37,7 @@ type Queue struct {
 	maxSize               int
 	lastStat              *types.StatsJSON
 	lastNetworkStatPerSec *NetworkStatsPerSec
+	diskUsage             *DiskUsage
 	lock                  sync.RWMutex
 }
 
@@ -87,6 +88,9 @@ func (queue *Queue) add(rawStat *ContainerStats) {
 		MemoryUsageInMegs: uint32(rawStat.memoryUsage / BytesInMiB),
 		StorageReadBytes:  rawStat.storageReadBytes,
 		StorageWriteBytes: rawStat.storageWriteBytes,
+		StorageReadOps:    rawStat.storageReadOps,
+		StorageWriteOps:   rawStat.storageWriteOps,
+		DiskUsage:         queue.diskUsage,
 		NetworkStats:      rawStat.networkStats,
 		Timestamp:         rawStat.timestamp,
 		cpuUsage:          rawStat.cpuUsage,
@@ -140,6 +144,23 @@ func (queue *Queue) add(rawStat *ContainerStats) {
 	queue.buffer = append(queue.buffer, stat)
 }
 
+// SetDiskUsage sets the disk usage of the container, reported along with the stats added
+// to the queue from now on.
+func (queue *Queue) SetDiskUsage(diskUsage *DiskUsage) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	queue.diskUsage = diskUsage
+}
+
+// GetDiskUsage returns the last measured disk usage of the container.
+func (queue *Queue) GetDiskUsage() *DiskUsage {
+	queue.lock.RLock()
+	defer queue.lock.RUnlock()
+
+	return queue.diskUsage
+}
+
 // GetLastStat returns the last recorded raw statistics object from docker
 func (queue *Queue) GetLastStat() *types.StatsJSON {
 	queue.lock.RLock()
@@ -177,6 +198,27 @@ func (queue *Queue) GetStorageStatsSet() (*ecstcs.StorageStatsSet, error) {
 	if err != nil {
 		seelog.Warnf("Error getting storage write size bytes: %v", err)
 	}
+	var opsErr error
+	storageStatsSet.ReadOps, opsErr = queue.getULongStatsSet(getStorageReadOps)
+	if opsErr != nil {
+		seelog.Warnf("Error getting storage read ops: %v", opsErr)
+	}
+	storageStatsSet.WriteOps, opsErr = queue.getULongStatsSet(getStorageWriteOps)
+	if opsErr != nil {
+		seelog.Warnf("Error getting storage write ops: %v", opsErr)
+	}
+	if queue.GetDiskUsage() != nil {
+		// the disk usage is only reported once it's been measured
+		var diskUsageErr error
+		storageStatsSet.WritableLayerSizeBytes, diskUsageErr = queue.getULongStatsSet(getWritableLayerSizeBytes)
+		if diskUsageErr != nil {
+			seelog.Warnf("Error getting writable layer size bytes: %v", diskUsageErr)
+		}
+		storageStatsSet.BindMountsSizeBytes, diskUsageErr = queue.getULongStatsSet(getBindMountsSizeBytes)
+		if diskUsageErr != nil {
+			seelog.Warnf("Error getting bind mounts size bytes: %v", diskUsageErr)
+		}
+	}
 	return storageStatsSet, err
 }
 
@@ -313,6 +355,28 @@ func getStorageWriteBytes(s *UsageStats) uint64 {
 	return s.StorageWriteBytes
 }
 
+func getStorageReadOps(s *UsageStats) uint64 {
+	return s.StorageReadOps
+}
+
+func getStorageWriteOps(s *UsageStats) uint64 {
+	return s.StorageWriteOps
+}
+
+func getWritableLayerSizeBytes(s *UsageStats) uint64 {
+	if s.DiskUsage == nil {
+		return 0
+	}
+	return s.DiskUsage.WritableLayerSizeBytes
+}
+
+func getBindMountsSizeBytes(s *UsageStats) uint64 {
+	if s.DiskUsage == nil {
+		return 0
+	}
+	return s.DiskUsage.BindMountsSizeBytes
+}
+
 // getInt64WithOverflow truncates a uint64 to fit an int64
 // it returns overflow as a second int64
 func getInt64WithOverflow(uintStat uint64) (int64, int64) {
@@ -356,6 +356,31 @@ func TestQueueUintStats(t *testing.T) {
 	assert.Equal(t, *storageReadStatsSet.OverflowSum, predictableInt64Overflow-1)
 }
 
+func TestQueueStorageOpsAndDiskUsage(t *testing.T) {
+	queue := NewQueue(4)
+	timestamp := parseNanoTime("2015-02-12T21:22:05.131117533Z")
+	queue.add(&ContainerStats{storageReadOps: 10, storageWriteOps: 20, timestamp: timestamp})
+	queue.add(&ContainerStats{storageReadOps: 30, storageWriteOps: 40, timestamp: timestamp.Add(time.Second)})
+
+	storageStatsSet, err := queue.GetStorageStatsSet()
+	require.NoError(t, err)
+	assert.Equal(t, int64(40), *storageStatsSet.ReadOps.Sum)
+	assert.Equal(t, int64(60), *storageStatsSet.WriteOps.Sum)
+	assert.Nil(t, storageStatsSet.WritableLayerSizeBytes, "disk usage should not be reported before it's measured")
+	assert.Nil(t, storageStatsSet.BindMountsSizeBytes, "disk usage should not be reported before it's measured")
+
+	queue.SetDiskUsage(&DiskUsage{WritableLayerSizeBytes: 100, BindMountsSizeBytes: 200})
+	queue.add(&ContainerStats{storageReadOps: 50, storageWriteOps: 60, timestamp: timestamp.Add(2 * time.Second)})
+
+	storageStatsSet, err = queue.GetStorageStatsSet()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), *storageStatsSet.ReadOps.SampleCount)
+	assert.Equal(t, int64(100), *storageStatsSet.WritableLayerSizeBytes.Max)
+	assert.Equal(t, int64(0), *storageStatsSet.WritableLayerSizeBytes.Min)
+	assert.Equal(t, int64(200), *storageStatsSet.BindMountsSizeBytes.Max)
+	assert.Equal(t, uint64(200), queue.buffer[2].DiskUsage.BindMountsSizeBytes)
+}
+
 func TestQueueAddPredictableHighMemoryUtilization(t *testing.T) {
 	timestamps := getTimestamps()
 	queueLength := 5
@@ -18,8 +18,10 @@ package stats
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
@@ -56,15 +58,23 @@ type StatsTask struct {
 	nswrapperinterface    nswrapper.NS
 	netlinkinterface      netlinkwrapper.NetLink
 	metricPublishInterval time.Duration
+	// containerNetworkAccountant attributes the task network stats to the containers
+	// of the task. It's nil when the stats are split evenly between the containers.
+	containerNetworkAccountant *containerNetworkAccountant
+	// containerStatsQueues holds the network stats attributed to each container of
+	// the task, keyed by docker id.
+	containerStatsQueues map[string]*Queue
+	lock                 sync.RWMutex
 }
 
 func newStatsTaskContainer(taskARN string, containerPID string, numberOfContainers int,
-	resolver resolver.ContainerMetadataResolver, publishInterval time.Duration) (*StatsTask, error) {
+	resolver resolver.ContainerMetadataResolver, publishInterval time.Duration,
+	containerNetworkStats bool) (*StatsTask, error) {
 	nsAgent := nswrapper.NewNS()
 	netlinkclient := netlinkwrapper.New()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	return &StatsTask{
+	statsTask := &StatsTask{
 		TaskMetadata: &TaskMetadata{
 			TaskArn:          taskARN,
 			ContainerPID:     containerPID,
@@ -76,13 +86,21 @@ func newStatsTaskContainer(taskARN string, containerPID string, numberOfContaine
 		netlinkinterface:      netlinkclient,
 		nswrapperinterface:    nsAgent,
 		metricPublishInterval: publishInterval,
-	}, nil
+	}
+	if containerNetworkStats {
+		statsTask.containerNetworkAccountant = newContainerNetworkAccountant(
+			fmt.Sprintf(ecscni.NetnsFormat, containerPID), nsAgent)
+	}
+	return statsTask, nil
 }
 
 func (task *StatsTask) StartStatsCollection() {
 	queueSize := int(config.DefaultContainerMetricsPublishInterval.Seconds() * 4)
 	task.StatsQueue = NewQueue(queueSize)
 	task.StatsQueue.Reset()
+	task.lock.Lock()
+	task.containerStatsQueues = make(map[string]*Queue)
+	task.lock.Unlock()
 	go task.collect()
 }
 
@@ -90,6 +108,65 @@ func (task *StatsTask) StopStatsCollection() {
 	task.Cancel()
 }
 
+// ContainerStatsQueue returns the queue of the network stats attributed to a container
+// of the task, or nil if the task network stats are split evenly between its containers.
+func (task *StatsTask) ContainerStatsQueue(dockerID string) *Queue {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	return task.containerStatsQueues[dockerID]
+}
+
+// addContainerNetworkStats adds the network stats attributed to each container of the
+// task to the queue of the container.
+func (task *StatsTask) addContainerNetworkStats(linkStats map[string]dockerstats.NetworkStats, read time.Time) {
+	dockerIDs, err := task.containerDockerIDs()
+	if err != nil {
+		seelog.Debugf("Unable to resolve the containers of task %s: %v", task.TaskMetadata.TaskArn, err)
+		return
+	}
+
+	attributed := task.containerNetworkAccountant.attribute(dockerIDs, linkStats)
+
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	queues := make(map[string]*Queue, len(attributed))
+	for dockerID, networkStats := range attributed {
+		queue, ok := task.containerStatsQueues[dockerID]
+		if !ok {
+			queue = NewQueue(task.StatsQueue.maxSize)
+		}
+		if err := queue.Add(&types.StatsJSON{
+			Networks: networkStats,
+			Stats: types.Stats{
+				Read: read,
+			},
+		}); err != nil {
+			seelog.Warnf("Task [%s]: error converting stats for container %s: %v",
+				task.TaskMetadata.TaskArn, dockerID, err)
+		}
+		queues[dockerID] = queue
+	}
+	task.containerStatsQueues = queues
+}
+
+// containerDockerIDs returns the docker ids of the containers of the task sharing the
+// network namespace of the pause container.
+func (task *StatsTask) containerDockerIDs() ([]string, error) {
+	resolvedTask, err := task.Resolver.ResolveTaskByARN(task.TaskMetadata.TaskArn)
+	if err != nil {
+		return nil, err
+	}
+	var dockerIDs []string
+	for _, container := range resolvedTask.Containers {
+		if container.Type == apicontainer.ContainerCNIPause || container.GetRuntimeID() == "" {
+			continue
+		}
+		dockerIDs = append(dockerIDs, container.GetRuntimeID())
+	}
+	return dockerIDs, nil
+}
+
 func (taskStat *StatsTask) collect() {
 	taskArn := taskStat.TaskMetadata.TaskArn
 	backoff := retry.NewExponentialBackoff(time.Second*1, time.Second*10, 0.5, 2)
@@ -221,6 +298,7 @@ func (taskStat *StatsTask) getAWSVPCNetworkStats() (<-chan *types.StatsJSON, <-c
 					}
 				}
 				networkStats := make(map[string]dockerstats.NetworkStats, len(taskStat.TaskMetadata.DeviceName))
+				linkStats := make(map[string]dockerstats.NetworkStats, len(taskStat.TaskMetadata.DeviceName))
 				for _, device := range taskStat.TaskMetadata.DeviceName {
 					var link netlinklib.Link
 					err := taskStat.nswrapperinterface.WithNetNSPath(fmt.Sprintf(ecscni.NetnsFormat,
@@ -240,12 +318,17 @@ func (taskStat *StatsTask) getAWSVPCNetworkStats() (<-chan *types.StatsJSON, <-c
 					netLinkStats := link.Attrs().Statistics
 					networkStats[link.Attrs().Name] = linkStatsToDockerStats(netLinkStats,
 						uint64(taskStat.TaskMetadata.NumberContainers))
+					linkStats[link.Attrs().Name] = linkStatsToDockerStats(netLinkStats, 1)
 				}
 
+				read := time.Now()
+				if taskStat.containerNetworkAccountant != nil {
+					taskStat.addContainerNetworkStats(linkStats, read)
+				}
 				dockerStats := &types.StatsJSON{
 					Networks: networkStats,
 					Stats: types.Stats{
-						Read: time.Now(),
+						Read: read,
 					},
 				}
 				statsC <- dockerStats
@@ -30,6 +30,7 @@ import (
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/vishvananda/netlink"
 )
 
@@ -189,3 +190,66 @@ func TestGetDeviceList(t *testing.T) {
 
 	assert.ElementsMatch(t, []string{"link1device", "link2device"}, deviceNames)
 }
+
+func TestTaskStatsCollectionContainerNetworkStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(ctrl)
+	mockNS := mock_nswrapper.NewMockNS(ctrl)
+	mockNetLink := mock_netlink.NewMockNetLink(ctrl)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	sockets := map[uint64]socketBytes{1: {sent: 10, received: 30}, 2: {sent: 30, received: 10}}
+	taskStats := &StatsTask{
+		TaskMetadata: &TaskMetadata{
+			TaskArn:          "task1",
+			ContainerPID:     "23",
+			DeviceName:       []string{"device1"},
+			NumberContainers: 2,
+		},
+		Ctx:                        ctx,
+		Cancel:                     cancel,
+		Resolver:                   resolver,
+		netlinkinterface:           mockNetLink,
+		nswrapperinterface:         mockNS,
+		metricPublishInterval:      time.Second,
+		containerNetworkAccountant: newTestContainerNetworkAccountant(&sockets, map[uint64]string{1: "c1", 2: "c2"}, nil),
+	}
+
+	testTask := &apitask.Task{
+		Containers: []*apicontainer.Container{
+			{Name: "c1", RuntimeID: "c1"},
+			{Name: "c2", RuntimeID: "c2"},
+			{Name: "pause", RuntimeID: "pause", Type: apicontainer.ContainerCNIPause},
+		},
+		KnownStatusUnsafe: apitaskstatus.TaskRunning,
+	}
+	resolver.EXPECT().ResolveTaskByARN(gomock.Any()).Return(testTask, nil).AnyTimes()
+	mockNS.EXPECT().WithNetNSPath(gomock.Any(),
+		gomock.Any()).Do(func(nsPath interface{}, toRun func(n ns.NetNS) error) error {
+		return toRun(nil)
+	}).AnyTimes()
+	mockNetLink.EXPECT().LinkByName(gomock.Any()).Return(&netlink.Device{
+		LinkAttrs: netlink.LinkAttrs{
+			Name: "device1",
+			Statistics: &netlink.LinkStatistics{
+				RxBytes: uint64(100),
+				TxBytes: uint64(200),
+			},
+		},
+	}, nil).AnyTimes()
+
+	taskStats.StartStatsCollection()
+	time.Sleep(checkPointSleep)
+	taskStats.StopStatsCollection()
+
+	assert.Nil(t, taskStats.ContainerStatsQueue("pause"))
+	c1Stats := taskStats.ContainerStatsQueue("c1").GetLastStat()
+	require.NotNil(t, c1Stats)
+	assert.EqualValues(t, 75, c1Stats.Networks["device1"].RxBytes)
+	assert.EqualValues(t, 50, c1Stats.Networks["device1"].TxBytes)
+	c2Stats := taskStats.ContainerStatsQueue("c2").GetLastStat()
+	require.NotNil(t, c2Stats)
+	assert.EqualValues(t, 25, c2Stats.Networks["device1"].RxBytes)
+	assert.EqualValues(t, 150, c2Stats.Networks["device1"].TxBytes)
+}
@@ -30,7 +30,8 @@ type StatsTask struct {
 }
 
 func newStatsTaskContainer(taskARN string, containerPID string, numberOfContainers int,
-	resolver resolver.ContainerMetadataResolver, publishInterval time.Duration) (*StatsTask, error) {
+	resolver resolver.ContainerMetadataResolver, publishInterval time.Duration,
+	containerNetworkStats bool) (*StatsTask, error) {
 	return nil, errors.New("Unsupported platform")
 }
 
@@ -41,3 +42,8 @@ func (task *StatsTask) StartStatsCollection() {
 func (task *StatsTask) StopStatsCollection() {
 	// AWSVPC network stats only supported on linux
 }
+
+func (task *StatsTask) ContainerStatsQueue(dockerID string) *Queue {
+	// AWSVPC network stats only supported on linux
+	return nil
+}
@@ -14,6 +14,7 @@
 package stats
 
 import (
+	"sync"
 	"time"
 
 	"context"
@@ -21,6 +22,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/stats/resolver"
+	"github.com/docker/docker/api/types"
 )
 
 // ContainerStats encapsulates the raw CPU and memory utilization from cgroup fs.
@@ -29,6 +31,8 @@ type ContainerStats struct {
 	memoryUsage       uint64
 	storageReadBytes  uint64
 	storageWriteBytes uint64
+	storageReadOps    uint64
+	storageWriteOps   uint64
 	networkStats      *NetworkStats
 	timestamp         time.Time
 }
@@ -47,12 +51,33 @@ type NetworkStats struct {
 	TxBytesPerSecond float32 `json:"txBytesPerSecond"`
 }
 
+// DiskUsage contains the disk space used by the files written to the writable layer
+// of a container, and by the files in its bind mounts, as measured at Read.
+type DiskUsage struct {
+	WritableLayerSizeBytes uint64    `json:"writable_layer_size_bytes"`
+	BindMountsSizeBytes    uint64    `json:"bind_mounts_size_bytes"`
+	Read                   time.Time `json:"read"`
+}
+
+// GPUStats contains a sample of the utilization of a GPU, as read at Read.
+type GPUStats struct {
+	GPUID              string    `json:"gpu_id"`
+	UtilizationPercent float64   `json:"utilization_percent"`
+	MemoryUsedBytes    uint64    `json:"memory_used_bytes"`
+	MemoryTotalBytes   uint64    `json:"memory_total_bytes"`
+	TemperatureCelsius float64   `json:"temperature_celsius"`
+	Read               time.Time `json:"read"`
+}
+
 // UsageStats abstracts the format in which the queue stores data.
 type UsageStats struct {
 	CPUUsagePerc      float32       `json:"cpuUsagePerc"`
 	MemoryUsageInMegs uint32        `json:"memoryUsageInMegs"`
 	StorageReadBytes  uint64        `json:"storageReadBytes"`
 	StorageWriteBytes uint64        `json:"storageWriteBytes"`
+	StorageReadOps    uint64        `json:"storageReadOps"`
+	StorageWriteOps   uint64        `json:"storageWriteOps"`
+	DiskUsage         *DiskUsage    `json:"diskUsage,omitempty"`
 	NetworkStats      *NetworkStats `json:"networkStats"`
 	Timestamp         time.Time     `json:"timestamp"`
 	cpuUsage          uint64
@@ -62,9 +87,10 @@ type UsageStats struct {
 
 // ContainerMetadata contains meta-data information for a container.
 type ContainerMetadata struct {
-	DockerID    string `json:"-"`
-	Name        string `json:"-"`
-	NetworkMode string `json:"-"`
+	DockerID    string   `json:"-"`
+	Name        string   `json:"-"`
+	NetworkMode string   `json:"-"`
+	GPUIDs      []string `json:"-"`
 }
 
 // TaskMetadata contains meta-data information for a task.
@@ -85,6 +111,11 @@ type StatsContainer struct {
 	statsQueue        *Queue
 	resolver          resolver.ContainerMetadataResolver
 	config            *config.Config
+	// snapshotLock serializes the stats snapshots of the container, and protects lastSnapshot
+	snapshotLock sync.Mutex
+	// lastSnapshot is the latest stats snapshot of the container, which is served again to the
+	// snapshot requests received less than minimumStatsSnapshotInterval after it
+	lastSnapshot *types.StatsJSON
 }
 
 // taskDefinition encapsulates family and version strings for a task definition
@@ -26,12 +26,15 @@ func dockerStatsToContainerStats(dockerStats *types.StatsJSON) (*ContainerStats,
 	cpuUsage := dockerStats.CPUStats.CPUUsage.TotalUsage / numCores
 	memoryUsage := dockerStats.MemoryStats.Usage - dockerStats.MemoryStats.Stats["cache"]
 	storageReadBytes, storageWriteBytes := getStorageStats(dockerStats)
+	storageReadOps, storageWriteOps := getStorageOps(dockerStats)
 	networkStats := getNetworkStats(dockerStats)
 	return &ContainerStats{
 		cpuUsage:          cpuUsage,
 		memoryUsage:       memoryUsage,
 		storageReadBytes:  storageReadBytes,
 		storageWriteBytes: storageWriteBytes,
+		storageReadOps:    storageReadOps,
+		storageWriteOps:   storageWriteOps,
 		networkStats:      networkStats,
 		timestamp:         dockerStats.Read,
 	}, nil
@@ -66,3 +69,18 @@ func getStorageStats(dockerStats *types.StatsJSON) (uint64, uint64) {
 	}
 	return storageReadBytes, storageWriteBytes
 }
+
+func getStorageOps(dockerStats *types.StatsJSON) (uint64, uint64) {
+	// aggregate the block io operations serviced for the container
+	storageReadOps := uint64(0)
+	storageWriteOps := uint64(0)
+	for _, blockStat := range dockerStats.BlkioStats.IoServicedRecursive {
+		switch blockStat.Op {
+		case "Read":
+			storageReadOps += blockStat.Value
+		case "Write":
+			storageWriteOps += blockStat.Value
+		}
+	}
+	return storageReadOps, storageWriteOps
+}
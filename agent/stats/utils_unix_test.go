@@ -41,6 +41,9 @@ func TestDockerStatsToContainerStats(t *testing.T) {
 	// storage bytes check
 	assert.Equal(t, uint64(3), containerStats.storageReadBytes, "unexpected value for storageReadBytes", containerStats.storageReadBytes)
 	assert.Equal(t, uint64(15), containerStats.storageWriteBytes, "Unexpected value for storageWriteBytes", containerStats.storageWriteBytes)
+	// storage ops check
+	assert.Equal(t, uint64(2), containerStats.storageReadOps, "unexpected value for storageReadOps")
+	assert.Equal(t, uint64(4), containerStats.storageWriteOps, "unexpected value for storageWriteOps")
 	// network stats check
 	netStats := containerStats.networkStats
 	assert.NotNil(t, netStats, "networkStats should not be nil")
@@ -33,6 +33,8 @@ func dockerStatsToContainerStats(dockerStats *types.StatsJSON) (*ContainerStats,
 		timestamp:         dockerStats.Read,
 		storageReadBytes:  storageReadBytes,
 		storageWriteBytes: storageWriteBytes,
+		storageReadOps:    dockerStats.StorageStats.ReadCountNormalized,
+		storageWriteOps:   dockerStats.StorageStats.WriteCountNormalized,
 		networkStats:      networkStats,
 	}, nil
 }
@@ -30,10 +30,12 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/asm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretcache"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 )
 
 const (
@@ -69,6 +71,11 @@ type ASMSecretResource struct {
 	// needed mostly for testing.
 	asmClientCreator factory.ClientCreator
 
+	// secretCache holds the secret values fetched for the other tasks, which the task
+	// reads unless it's refreshing its secrets
+	secretCache       *secretcache.Cache
+	bypassSecretCache bool
+
 	// terminalReason should be set for resource creation failures. This ensures
 	// the resource object carries some context for why provisioning failed.
 	terminalReason     string
@@ -83,7 +90,8 @@ func NewASMSecretResource(taskARN string,
 	asmSecrets map[string]apicontainer.Secret,
 	executionCredentialsID string,
 	credentialsManager credentials.Manager,
-	asmClientCreator factory.ClientCreator) *ASMSecretResource {
+	asmClientCreator factory.ClientCreator,
+	secretCache *secretcache.Cache) *ASMSecretResource {
 
 	s := &ASMSecretResource{
 		taskARN:                taskARN,
@@ -91,6 +99,7 @@ func NewASMSecretResource(taskARN string,
 		credentialsManager:     credentialsManager,
 		executionCredentialsID: executionCredentialsID,
 		asmClientCreator:       asmClientCreator,
+		secretCache:            secretCache,
 	}
 
 	s.initStatusToTransition()
@@ -266,16 +275,30 @@ func (secret *ASMSecretResource) Create() error {
 
 	var wg sync.WaitGroup
 
-	// Get the maximum number of errors to be returned, which will be one error per goroutine
+	// Get the maximum number of errors to be returned, which will be one error per secret
 	errorEvents := make(chan error, len(secret.requiredSecrets))
 
 	seelog.Infof("ASM secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
 	secret.secretData = make(map[string]string)
 
+	asmClients := make(map[string]secretsmanageriface.SecretsManagerAPI)
 	for _, asmsecret := range secret.getRequiredSecrets() {
+		if _, ok := asmClients[asmsecret.Region]; !ok {
+			asmClients[asmsecret.Region] = secret.asmClientCreator.NewASMClient(asmsecret.Region, iamCredentials)
+		}
+	}
+
+	fetches := secret.getSecretFetches(errorEvents)
+	if !secret.bypassSecretCache && secret.anyFetchMissingFromCache(fetches, iamCredentials) {
+		// the tasks started at the same time fetch their secrets at different times, so
+		// that the first one can cache the secrets the others need
+		secret.secretCache.Jitter()
+	}
+
+	for _, fetch := range fetches {
 		wg.Add(1)
-		// Spin up goroutine per secret to speed up processing time
-		go secret.retrieveASMSecretValue(asmsecret, iamCredentials, &wg, errorEvents)
+		// Spin up goroutine per secret version to speed up processing time
+		go secret.retrieveASMSecretValue(fetch, asmClients[fetch.region], iamCredentials, &wg, errorEvents)
 	}
 
 	wg.Wait()
@@ -294,37 +317,146 @@ func (secret *ASMSecretResource) Create() error {
 	return nil
 }
 
-// retrieveASMSecretValue reads secret value from cache first, if not exists, call GetSecretFromASM to retrieve value
-// AWS secrets Manager
-func (secret *ASMSecretResource) retrieveASMSecretValue(apiSecret apicontainer.Secret, iamCredentials credentials.IAMRoleCredentials, wg *sync.WaitGroup, errorEvents chan error) {
-	defer wg.Done()
+// Refresh fetches the secret values from AWS Secrets Manager again, bypassing the values
+// cached for the other tasks. The cached values are only replaced if all of the secrets
+// could be retrieved.
+func (secret *ASMSecretResource) Refresh() (func() error, func(), error) {
+	secret.lock.RLock()
+	refreshed := &ASMSecretResource{
+		taskARN:                secret.taskARN,
+		credentialsManager:     secret.credentialsManager,
+		executionCredentialsID: secret.executionCredentialsID,
+		requiredSecrets:        secret.requiredSecrets,
+		asmClientCreator:       secret.asmClientCreator,
+		secretCache:            secret.secretCache,
+		bypassSecretCache:      true,
+	}
+	secret.lock.RUnlock()
+	if err := refreshed.Create(); err != nil {
+		return nil, nil, err
+	}
 
-	asmClient := secret.asmClientCreator.NewASMClient(apiSecret.Region, iamCredentials)
-	seelog.Infof("ASM secret resource: retrieving resource for secret %v in region %s for task: [%s]", apiSecret.ValueFrom, apiSecret.Region, secret.taskARN)
-	input, jsonKey, err := getASMParametersFromInput(apiSecret.ValueFrom)
-	if err != nil {
-		errorEvents <- fmt.Errorf("trying to retrieve secret with value %s resulted in error: %v", apiSecret.ValueFrom, err)
-		return
+	secret.lock.Lock()
+	previous := secret.secretData
+	secret.secretData = refreshed.secretData
+	secret.lock.Unlock()
+	rollback := func() error {
+		secret.lock.Lock()
+		defer secret.lock.Unlock()
+		secret.secretData = previous
+		return nil
 	}
+	return rollback, func() {}, nil
+}
+
+// asmSecretFetch is a call to AWS Secrets Manager retrieving a version of a secret, for all
+// of the secrets of the task that read its value or the values of its JSON keys
+type asmSecretFetch struct {
+	region   string
+	input    *secretsmanager.GetSecretValueInput
+	secrets  []apicontainer.Secret
+	jsonKeys []string
+}
+
+// getSecretFetches groups the required secrets by the version of the secret they read, so
+// that each version is retrieved once. The secrets that can't be parsed are reported to
+// errorEvents.
+func (secret *ASMSecretResource) getSecretFetches(errorEvents chan error) []*asmSecretFetch {
+	var fetches []*asmSecretFetch
+	fetchByVersion := make(map[string]*asmSecretFetch)
+	for _, apiSecret := range secret.getRequiredSecrets() {
+		input, jsonKey, err := getASMParametersFromInput(apiSecret.ValueFrom)
+		if err != nil {
+			errorEvents <- fmt.Errorf("trying to retrieve secret with value %s resulted in error: %v", apiSecret.ValueFrom, err)
+			continue
+		}
 
-	if input.SecretId == nil {
-		errorEvents <- fmt.Errorf("could not find a secretsmanager secretID from value %s", apiSecret.ValueFrom)
-		return
+		if input.SecretId == nil {
+			errorEvents <- fmt.Errorf("could not find a secretsmanager secretID from value %s", apiSecret.ValueFrom)
+			continue
+		}
 
+		version := apiSecret.Region + arnDelimiter + asmSecretVersion(input)
+		fetch, ok := fetchByVersion[version]
+		if !ok {
+			fetch = &asmSecretFetch{
+				region: apiSecret.Region,
+				input:  input,
+			}
+			fetchByVersion[version] = fetch
+			fetches = append(fetches, fetch)
+		}
+		fetch.secrets = append(fetch.secrets, apiSecret)
+		fetch.jsonKeys = append(fetch.jsonKeys, jsonKey)
 	}
+	return fetches
+}
 
-	secretValue, err := asm.GetSecretFromASMWithInput(input, asmClient, jsonKey)
-	if err != nil {
-		errorEvents <- fmt.Errorf("fetching secret data from AWS Secrets Manager in region %s: %v", apiSecret.Region, err)
-		return
+// anyFetchMissingFromCache returns whether any of the secret versions isn't cached for the
+// other tasks
+func (secret *ASMSecretResource) anyFetchMissingFromCache(fetches []*asmSecretFetch,
+	iamCredentials credentials.IAMRoleCredentials) bool {
+	for _, fetch := range fetches {
+		if _, ok := secret.secretCache.Get(asmSecretCacheKey(fetch, iamCredentials)); !ok {
+			return true
+		}
 	}
+	return false
+}
 
-	secret.lock.Lock()
-	defer secret.lock.Unlock()
+// retrieveASMSecretValue reads the secret version from cache first, if not exists, calls
+// GetSecretStringFromASMWithInput to retrieve it from AWS Secrets Manager. The values of
+// the secrets reading the version are then extracted from it.
+func (secret *ASMSecretResource) retrieveASMSecretValue(fetch *asmSecretFetch, asmClient secretsmanageriface.SecretsManagerAPI,
+	iamCredentials credentials.IAMRoleCredentials, wg *sync.WaitGroup, errorEvents chan error) {
+	defer wg.Done()
 
-	// put secret value in secretData
-	secretKey := apiSecret.GetSecretResourceCacheKey()
-	secret.secretData[secretKey] = secretValue
+	secretID := aws.StringValue(fetch.input.SecretId)
+	cacheKey := asmSecretCacheKey(fetch, iamCredentials)
+	secretString, ok := "", false
+	if !secret.bypassSecretCache {
+		secretString, ok = secret.secretCache.Get(cacheKey)
+	}
+	if !ok {
+		seelog.Infof("ASM secret resource: retrieving resource for secret %s in region %s for task: [%s]", secretID, fetch.region, secret.taskARN)
+		var err error
+		secretString, err = asm.GetSecretStringFromASMWithInput(fetch.input, asmClient)
+		if err != nil {
+			errorEvents <- fmt.Errorf("fetching secret data from AWS Secrets Manager in region %s: %v", fetch.region, err)
+			return
+		}
+		secret.secretCache.Set(cacheKey, secretString)
+	}
+
+	for i, apiSecret := range fetch.secrets {
+		secretValue, err := asm.ExtractJSONKeyFromSecretString(secretID, secretString, fetch.jsonKeys[i])
+		if err != nil {
+			errorEvents <- fmt.Errorf("fetching secret data from AWS Secrets Manager in region %s: %v", fetch.region, err)
+			continue
+		}
+
+		// put secret value in secretData
+		secret.SetCachedSecretValue(apiSecret.GetSecretResourceCacheKey(), secretValue)
+	}
+}
+
+// asmSecretVersion returns the secret id, version stage and version id of the secret read
+// by the input
+func asmSecretVersion(input *secretsmanager.GetSecretValueInput) string {
+	return strings.Join([]string{
+		aws.StringValue(input.SecretId),
+		aws.StringValue(input.VersionStage),
+		aws.StringValue(input.VersionId),
+	}, arnDelimiter)
+}
+
+func asmSecretCacheKey(fetch *asmSecretFetch, iamCredentials credentials.IAMRoleCredentials) secretcache.Key {
+	return secretcache.Key{
+		Service: secretcache.ServiceASM,
+		Region:  fetch.region,
+		RoleARN: iamCredentials.RoleArn,
+		ID:      asmSecretVersion(fetch.input),
+	}
 }
 
 func pointerOrNil(in string) *string {
@@ -446,6 +578,7 @@ func (secret *ASMSecretResource) Initialize(resourceFields *taskresource.Resourc
 	secret.initStatusToTransition()
 	secret.credentialsManager = resourceFields.CredentialsManager
 	secret.asmClientCreator = resourceFields.ASMClientCreator
+	secret.secretCache = resourceFields.SecretCache
 
 	// if task hasn't turn to 'created' status, and it's desire status is 'running'
 	// the resource status needs to be reset to 'NONE' status so the secret value
@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretcache"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
@@ -321,6 +322,61 @@ func TestCreateWithASMParametersJSONKeySpecified(t *testing.T) {
 	assert.Equal(t, secretValue, value)
 }
 
+func TestCreateWithSecretCache(t *testing.T) {
+	valueFromOtherKey := valueFrom1 + ":some-other-key:version-stage:version-id"
+	secretKeyOtherKey := valueFromOtherKey + secretCacheJoinChar + regionKeyWest
+	requiredSecretData := map[string]apicontainer.Secret{
+		secretKeyParams:   sampleSecret(secretName1, valueFromParams, region1),
+		secretKeyOtherKey: sampleSecret(secretName2, valueFromOtherKey, region1),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	asmClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	mockASMClient := mock_secretsmanageriface.NewMockSecretsManagerAPI(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{
+		RoleArn: "arn:aws:iam::123456789012:role/execution-role",
+	}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+
+	asmSecretValue := &secretsmanager.GetSecretValueOutput{
+		SecretString: aws.String(secretValueJson),
+	}
+
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true).Times(2)
+	asmClientCreator.EXPECT().NewASMClient(region1, iamRoleCreds).Return(mockASMClient).Times(2)
+	// both keys are read from a single call for the version of the secret
+	mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Do(func(in *secretsmanager.GetSecretValueInput) {
+		assert.Equal(t, valueFrom1, aws.StringValue(in.SecretId))
+		assert.Equal(t, "version-stage", aws.StringValue(in.VersionStage))
+		assert.Equal(t, "version-id", aws.StringValue(in.VersionId))
+	}).Return(asmSecretValue, nil).Times(1)
+
+	cache := secretcache.New(time.Minute, 0)
+	asmRes := NewASMSecretResource(taskARN, requiredSecretData, executionCredentialsID,
+		credentialsManager, asmClientCreator, cache)
+	require.NoError(t, asmRes.Create())
+
+	// another task with the same execution role reads the cached value
+	otherRes := NewASMSecretResource("task2", requiredSecretData, executionCredentialsID,
+		credentialsManager, asmClientCreator, cache)
+	require.NoError(t, otherRes.Create())
+
+	for _, res := range []*ASMSecretResource{asmRes, otherRes} {
+		value, ok := res.GetCachedSecretValue(secretKeyParams)
+		require.True(t, ok)
+		assert.Equal(t, secretValue, value)
+		value, ok = res.GetCachedSecretValue(secretKeyOtherKey)
+		require.True(t, ok)
+		assert.Equal(t, "secret2", value)
+	}
+}
+
 func sampleSecret(secretName string, valueFrom string, region string) apicontainer.Secret {
 	return apicontainer.Secret{
 		Name:      secretName,
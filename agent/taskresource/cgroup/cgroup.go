@@ -280,6 +280,11 @@ func (cgroup *CgroupResource) setupTaskCgroup() error {
 		return fmt.Errorf("cgroup resource [%s]: setup cgroup: unable to create cgroup at %s: %w", cgroup.taskARN, cgroupRoot, err)
 	}
 
+	// the memory hierarchy is always enabled on the unified hierarchy
+	if cgroup.control.Unified() {
+		return nil
+	}
+
 	// enabling cgroup memory hierarchy by doing 'echo 1 > memory.use_hierarchy'
 	memoryHierarchyPath := filepath.Join(cgroup.cgroupMountPath, memorySubsystem, cgroupRoot, memoryUseHierarchy)
 	err = cgroup.ioutil.WriteFile(memoryHierarchyPath, enableMemoryHierarchy, rootReadOnlyPermissions)
@@ -371,6 +376,13 @@ func (cgroup *CgroupResource) GetCgroupMountPath() string {
 	return cgroup.cgroupMountPath
 }
 
+// GetResourceSpec returns the linux resource spec the cgroup is created with
+func (cgroup *CgroupResource) GetResourceSpec() specs.LinuxResources {
+	cgroup.lock.RLock()
+	defer cgroup.lock.RUnlock()
+	return cgroup.resourceSpec
+}
+
 // Initialize initializes the resource fileds in cgroup
 func (cgroup *CgroupResource) Initialize(resourceFields *taskresource.ResourceFields,
 	taskKnownStatus status.TaskStatus,
@@ -54,12 +54,32 @@ func TestCreateHappyPath(t *testing.T) {
 	gomock.InOrder(
 		mockControl.EXPECT().Exists(gomock.Any()).Return(false),
 		mockControl.EXPECT().Create(gomock.Any()).Return(nil, nil),
+		mockControl.EXPECT().Unified().Return(false),
 		mockIO.EXPECT().WriteFile(cgroupMemoryPath, gomock.Any(), gomock.Any()).Return(nil),
 	)
 	cgroupResource := NewCgroupResource("taskArn", mockControl, mockIO, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
 	assert.NoError(t, cgroupResource.Create())
 }
 
+func TestCreateUnifiedHierarchy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockControl := mock_control.NewMockControl(ctrl)
+	mockIO := mock_ioutilwrapper.NewMockIOUtil(ctrl)
+
+	cgroupRoot := fmt.Sprintf("/ecs/%s", taskID)
+
+	// memory.use_hierarchy doesn't exist on the unified hierarchy
+	gomock.InOrder(
+		mockControl.EXPECT().Exists(gomock.Any()).Return(false),
+		mockControl.EXPECT().Create(gomock.Any()).Return(nil, nil),
+		mockControl.EXPECT().Unified().Return(true),
+	)
+	cgroupResource := NewCgroupResource("taskArn", mockControl, mockIO, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
+	assert.NoError(t, cgroupResource.Create())
+}
+
 func TestCreateCgroupPathExists(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
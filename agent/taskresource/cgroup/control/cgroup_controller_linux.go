@@ -30,8 +30,12 @@ type control struct {
 	factory.CgroupFactory
 }
 
-// New is used to obtain a new cgroup control object
+// New is used to obtain a new cgroup control object, for the unified hierarchy when the
+// host has it mounted in place of the v1 hierarchies
 func New() Control {
+	if isUnified(defaultCgroupMountPath) {
+		return newUnifiedControl(defaultCgroupMountPath)
+	}
 	return newControl(&factory.GlobalCgroupFactory{})
 }
 
@@ -91,6 +95,11 @@ func (c *control) Exists(cgroupPath string) bool {
 	return true
 }
 
+// Unified returns false, as the controller manages the v1 hierarchies
+func (c *control) Unified() bool {
+	return false
+}
+
 // validateCgroupSpec checks the cgroup spec for valid path and specifications
 func validateCgroupSpec(cgroupSpec *Spec) error {
 	if cgroupSpec == nil {
@@ -0,0 +1,260 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cihub/seelog"
+	"github.com/containerd/cgroups"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const (
+	// defaultCgroupMountPath is where the cgroup hierarchies are mounted
+	defaultCgroupMountPath = "/sys/fs/cgroup"
+
+	cgroupControllersFile    = "cgroup.controllers"
+	cgroupSubtreeControlFile = "cgroup.subtree_control"
+	cpuWeightFile            = "cpu.weight"
+	cpuMaxFile               = "cpu.max"
+	cpusetCpusFile           = "cpuset.cpus"
+	cpusetMemsFile           = "cpuset.mems"
+	memoryMaxFile            = "memory.max"
+	ioMaxFile                = "io.max"
+
+	cgroupDirPermissions  = os.FileMode(0755)
+	cgroupFilePermissions = os.FileMode(0644)
+
+	// defaultCPUPeriod is the cpu period of the cpu quotas given without one, in microseconds
+	defaultCPUPeriod = 100000
+)
+
+// unifiedControllers are the controllers the task cgroups use on the unified hierarchy
+var unifiedControllers = map[string]struct{}{
+	"cpu":    {},
+	"cpuset": {},
+	"io":     {},
+	"memory": {},
+	"pids":   {},
+}
+
+// unifiedControl implements the cgroup Control interface on hosts with the unified
+// (cgroup v2) hierarchy, which the cgroups library doesn't manage. It writes the
+// resource spec to the interface files of the cgroups itself
+type unifiedControl struct {
+	mountPath string
+}
+
+// isUnified returns true if the cgroup hierarchy mounted at the path is the unified one
+func isUnified(mountPath string) bool {
+	_, err := os.Stat(filepath.Join(mountPath, cgroupControllersFile))
+	return err == nil
+}
+
+// newUnifiedControl returns a cgroup controller for the unified hierarchy mounted at the path
+func newUnifiedControl(mountPath string) Control {
+	return &unifiedControl{
+		mountPath: mountPath,
+	}
+}
+
+// Create creates a new cgroup based off the spec post validation. As the cgroups library
+// doesn't manage the unified hierarchy, there's no cgroup object to return
+func (c *unifiedControl) Create(cgroupSpec *Spec) (cgroups.Cgroup, error) {
+	err := validateCgroupSpec(cgroupSpec)
+	if err != nil {
+		return nil, fmt.Errorf("cgroup create: failed to validate spec: %w", err)
+	}
+
+	seelog.Infof("Creating cgroup %s on the unified hierarchy", cgroupSpec.Root)
+	cgroupPath, err := c.mkdir(cgroupSpec.Root)
+	if err != nil {
+		return nil, fmt.Errorf("cgroup create: unable to create cgroup: %w", err)
+	}
+	if err := applyUnifiedResources(cgroupPath, cgroupSpec.Specs); err != nil {
+		// remove the cgroup so that it's created again on retry
+		os.Remove(cgroupPath)
+		return nil, fmt.Errorf("cgroup create: unable to apply resources: %w", err)
+	}
+	return nil, nil
+}
+
+// mkdir creates the cgroup at the root, and its parents, enabling the controllers the task
+// cgroups use in the parents
+func (c *unifiedControl) mkdir(root string) (string, error) {
+	cgroupPath := c.mountPath
+	for _, name := range strings.Split(strings.Trim(filepath.Clean(root), "/"), "/") {
+		if err := enableUnifiedControllers(cgroupPath); err != nil {
+			return "", err
+		}
+		cgroupPath = filepath.Join(cgroupPath, name)
+		if err := os.Mkdir(cgroupPath, cgroupDirPermissions); err != nil && !os.IsExist(err) {
+			return "", err
+		}
+	}
+	return cgroupPath, nil
+}
+
+// Remove is used to delete the cgroup
+func (c *unifiedControl) Remove(cgroupPath string) error {
+	seelog.Debugf("Removing cgroup %s", cgroupPath)
+
+	err := os.Remove(filepath.Join(c.mountPath, cgroupPath))
+	if os.IsNotExist(err) {
+		// use the %w verb to wrap the error to be unwrapped by errors.Is()
+		return fmt.Errorf("cgroup remove: %w", cgroups.ErrCgroupDeleted)
+	}
+	if err != nil {
+		return fmt.Errorf("cgroup remove: unable to delete cgroup: %w", err)
+	}
+	return nil
+}
+
+// Exists is used to verify the existence of a cgroup
+func (c *unifiedControl) Exists(cgroupPath string) bool {
+	seelog.Debugf("Checking existence of cgroup: %s", cgroupPath)
+
+	info, err := os.Stat(filepath.Join(c.mountPath, cgroupPath))
+	return err == nil && info.IsDir()
+}
+
+// Unified returns true, as the controller manages the unified hierarchy
+func (c *unifiedControl) Unified() bool {
+	return true
+}
+
+// enableUnifiedControllers enables the controllers the task cgroups use, among the ones
+// available, in the children of the cgroup
+func enableUnifiedControllers(cgroupPath string) error {
+	available, err := ioutil.ReadFile(filepath.Join(cgroupPath, cgroupControllersFile))
+	if err != nil {
+		return err
+	}
+	var controllers []string
+	for _, controller := range strings.Fields(string(available)) {
+		if _, ok := unifiedControllers[controller]; ok {
+			controllers = append(controllers, "+"+controller)
+		}
+	}
+	if len(controllers) == 0 {
+		return nil
+	}
+	return writeCgroupFile(cgroupPath, cgroupSubtreeControlFile, strings.Join(controllers, " "))
+}
+
+// applyUnifiedResources writes the resource spec to the interface files of the cgroup
+func applyUnifiedResources(cgroupPath string, resources *specs.LinuxResources) error {
+	if cpu := resources.CPU; cpu != nil {
+		if cpu.Shares != nil && *cpu.Shares > 0 {
+			weight := strconv.FormatUint(cpuSharesToWeight(*cpu.Shares), 10)
+			if err := writeCgroupFile(cgroupPath, cpuWeightFile, weight); err != nil {
+				return err
+			}
+		}
+		if cpu.Quota != nil && *cpu.Quota > 0 {
+			period := uint64(defaultCPUPeriod)
+			if cpu.Period != nil && *cpu.Period > 0 {
+				period = *cpu.Period
+			}
+			if err := writeCgroupFile(cgroupPath, cpuMaxFile, fmt.Sprintf("%d %d", *cpu.Quota, period)); err != nil {
+				return err
+			}
+		}
+		if cpu.Cpus != "" {
+			if err := writeCgroupFile(cgroupPath, cpusetCpusFile, cpu.Cpus); err != nil {
+				return err
+			}
+		}
+		if cpu.Mems != "" {
+			if err := writeCgroupFile(cgroupPath, cpusetMemsFile, cpu.Mems); err != nil {
+				return err
+			}
+		}
+	}
+	if memory := resources.Memory; memory != nil && memory.Limit != nil && *memory.Limit > 0 {
+		if err := writeCgroupFile(cgroupPath, memoryMaxFile, strconv.FormatInt(*memory.Limit, 10)); err != nil {
+			return err
+		}
+	}
+	if resources.BlockIO != nil {
+		if err := writeIOMax(cgroupPath, resources.BlockIO); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cpuSharesToWeight converts cpu shares, in [2, 262144], to a cpu weight, in [1, 10000],
+// the same way the OCI runtimes do
+func cpuSharesToWeight(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	}
+	if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142
+}
+
+// writeIOMax writes the io throttling of the spec to the io.max file of the cgroup, one
+// device per write as the kernel expects
+func writeIOMax(cgroupPath string, blockIO *specs.LinuxBlockIO) error {
+	var devices []string
+	limits := make(map[string][]string)
+	throttle := func(key string, throttleDevices []specs.LinuxThrottleDevice) {
+		for _, device := range throttleDevices {
+			name := fmt.Sprintf("%d:%d", device.Major, device.Minor)
+			if _, ok := limits[name]; !ok {
+				devices = append(devices, name)
+			}
+			limits[name] = append(limits[name], fmt.Sprintf("%s=%d", key, device.Rate))
+		}
+	}
+	throttle("rbps", blockIO.ThrottleReadBpsDevice)
+	throttle("wbps", blockIO.ThrottleWriteBpsDevice)
+	throttle("riops", blockIO.ThrottleReadIOPSDevice)
+	throttle("wiops", blockIO.ThrottleWriteIOPSDevice)
+	if len(devices) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(filepath.Join(cgroupPath, ioMaxFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, cgroupFilePermissions)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, device := range devices {
+		line := device + " " + strings.Join(limits[device], " ") + "\n"
+		if _, err := file.WriteString(line); err != nil {
+			return fmt.Errorf("unable to write %s to %s: %w", strings.TrimSpace(line), ioMaxFile, err)
+		}
+	}
+	return nil
+}
+
+// writeCgroupFile writes the value to the interface file of the cgroup
+func writeCgroupFile(cgroupPath, file, value string) error {
+	if err := ioutil.WriteFile(filepath.Join(cgroupPath, file), []byte(value), cgroupFilePermissions); err != nil {
+		return fmt.Errorf("unable to write %s to %s: %w", value, file, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/cgroups"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupUnifiedHierarchy creates a fake unified hierarchy with the ecs cgroup root, as the
+// kernel would create the cgroup.controllers files
+func setupUnifiedHierarchy(t *testing.T) string {
+	mountPath, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountPath, cgroupControllersFile),
+		[]byte("cpuset cpu io memory hugetlb pids rdma\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(mountPath, "ecs"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountPath, "ecs", cgroupControllersFile),
+		[]byte("cpuset cpu io memory pids\n"), 0644))
+	return mountPath
+}
+
+func readCgroupFile(t *testing.T, path ...string) string {
+	value, err := ioutil.ReadFile(filepath.Join(path...))
+	require.NoError(t, err)
+	return string(value)
+}
+
+func TestIsUnified(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t)
+	defer os.RemoveAll(mountPath)
+
+	assert.True(t, isUnified(mountPath))
+	assert.False(t, isUnified(filepath.Join(mountPath, "missing")))
+}
+
+func TestUnifiedCreate(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t)
+	defer os.RemoveAll(mountPath)
+
+	quota := int64(50000)
+	period := uint64(100000)
+	memory := int64(512 * 1024 * 1024)
+	throttleDevice := func(major, minor int64, rate uint64) specs.LinuxThrottleDevice {
+		device := specs.LinuxThrottleDevice{Rate: rate}
+		device.Major, device.Minor = major, minor
+		return device
+	}
+	control := newUnifiedControl(mountPath)
+	res, err := control.Create(&Spec{testCgroupRoot, &specs.LinuxResources{
+		CPU: &specs.LinuxCPU{
+			Quota:  &quota,
+			Period: &period,
+			Cpus:   "0-3,6",
+			Mems:   "0",
+		},
+		Memory: &specs.LinuxMemory{
+			Limit: &memory,
+		},
+		BlockIO: &specs.LinuxBlockIO{
+			ThrottleReadBpsDevice:   []specs.LinuxThrottleDevice{throttleDevice(259, 0, 1048576)},
+			ThrottleWriteBpsDevice:  []specs.LinuxThrottleDevice{throttleDevice(259, 1, 2097152)},
+			ThrottleWriteIOPSDevice: []specs.LinuxThrottleDevice{throttleDevice(259, 0, 100)},
+		},
+	}})
+	assert.Nil(t, res)
+	require.NoError(t, err)
+	assert.True(t, control.Exists(testCgroupRoot))
+	assert.True(t, control.Unified())
+
+	assert.Equal(t, "+cpuset +cpu +io +memory +pids", readCgroupFile(t, mountPath, cgroupSubtreeControlFile))
+	assert.Equal(t, "+cpuset +cpu +io +memory +pids", readCgroupFile(t, mountPath, "ecs", cgroupSubtreeControlFile))
+	cgroupPath := filepath.Join(mountPath, testCgroupRoot)
+	assert.Equal(t, "50000 100000", readCgroupFile(t, cgroupPath, cpuMaxFile))
+	assert.Equal(t, "0-3,6", readCgroupFile(t, cgroupPath, cpusetCpusFile))
+	assert.Equal(t, "0", readCgroupFile(t, cgroupPath, cpusetMemsFile))
+	assert.Equal(t, "536870912", readCgroupFile(t, cgroupPath, memoryMaxFile))
+	assert.Equal(t, "259:0 rbps=1048576 wiops=100\n259:1 wbps=2097152\n", readCgroupFile(t, cgroupPath, ioMaxFile))
+}
+
+func TestUnifiedCreateCPUShares(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t)
+	defer os.RemoveAll(mountPath)
+
+	shares := uint64(1024)
+	control := newUnifiedControl(mountPath)
+	_, err := control.Create(&Spec{testCgroupRoot, &specs.LinuxResources{
+		CPU: &specs.LinuxCPU{
+			Shares: &shares,
+		},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "39", readCgroupFile(t, mountPath, testCgroupRoot, cpuWeightFile))
+}
+
+func TestUnifiedCreateErrorCase(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t)
+	defer os.RemoveAll(mountPath)
+
+	control := newUnifiedControl(mountPath)
+	_, err := control.Create(&Spec{Root: testCgroupRoot})
+	assert.Error(t, err)
+
+	// the parent isn't a cgroup
+	_, err = control.Create(&Spec{"/missing/foo", &specs.LinuxResources{}})
+	assert.Error(t, err)
+	assert.False(t, control.Exists("/missing/foo"))
+}
+
+func TestUnifiedRemove(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t)
+	defer os.RemoveAll(mountPath)
+
+	control := newUnifiedControl(mountPath)
+	_, err := control.Create(&Spec{testCgroupRoot, &specs.LinuxResources{}})
+	require.NoError(t, err)
+
+	assert.NoError(t, control.Remove(testCgroupRoot))
+	assert.False(t, control.Exists(testCgroupRoot))
+	assert.True(t, errors.Is(control.Remove(testCgroupRoot), cgroups.ErrCgroupDeleted))
+}
+
+func TestUnifiedInit(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t)
+	defer os.RemoveAll(mountPath)
+
+	control := newUnifiedControl(mountPath)
+	assert.NoError(t, control.Init())
+	assert.True(t, control.Exists("/ecs"))
+}
+
+func TestCPUSharesToWeight(t *testing.T) {
+	assert.Equal(t, uint64(1), cpuSharesToWeight(0))
+	assert.Equal(t, uint64(1), cpuSharesToWeight(2))
+	assert.Equal(t, uint64(39), cpuSharesToWeight(1024))
+	assert.Equal(t, uint64(10000), cpuSharesToWeight(262144))
+	assert.Equal(t, uint64(10000), cpuSharesToWeight(1000000))
+}
@@ -24,6 +24,16 @@ import (
 
 // Init is used to setup the cgroup root for ecs
 func (c *control) Init() error {
+	return initRoot(c)
+}
+
+// Init is used to setup the cgroup root for ecs
+func (c *unifiedControl) Init() error {
+	return initRoot(c)
+}
+
+// initRoot creates the cgroup root for ecs with the controller
+func initRoot(c Control) error {
 	seelog.Infof("Creating root ecs cgroup: %s", config.DefaultTaskCgroupPrefix)
 
 	// Build cgroup spec
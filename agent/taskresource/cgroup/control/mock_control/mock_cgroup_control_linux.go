@@ -105,3 +105,17 @@ func (mr *MockControlMockRecorder) Remove(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockControl)(nil).Remove), arg0)
 }
+
+// Unified mocks base method
+func (m *MockControl) Unified() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unified")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Unified indicates an expected call of Unified
+func (mr *MockControlMockRecorder) Unified() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unified", reflect.TypeOf((*MockControl)(nil).Unified))
+}
@@ -34,4 +34,5 @@ type Control interface {
 	Remove(cgroupPath string) error
 	Exists(cgroupPath string) bool
 	Init() error
+	Unified() bool
 }
@@ -0,0 +1,114 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentialspec
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// credentialSpecCache caches the credential specs fetched from S3, SSM and Secrets
+// Manager, so that the tasks that use the same credential spec don't need to fetch
+// it every time they start
+type credentialSpecCache struct {
+	lock    sync.Mutex
+	entries map[credentialSpecCacheKey]credentialSpecCacheEntry
+	now     func() time.Time
+}
+
+// credentialSpecCacheKey identifies a cached credential spec. The execution role
+// it was fetched with is part of the key, so that a task can only use a cached
+// credential spec if its execution role was allowed to fetch it
+type credentialSpecCacheKey struct {
+	roleARN     string
+	credSpecARN string
+}
+
+// credentialSpecCacheEntry is a cached credential spec, with the version of it that
+// was fetched: the ETag of the S3 object, the version of the SSM parameter or the
+// version id of the secret
+type credentialSpecCacheEntry struct {
+	data      string
+	version   string
+	fetchedAt time.Time
+}
+
+// errNotModified is returned by a fetchFunc when the cached version of the credential
+// spec is still the current one
+var errNotModified = errors.New("credentialspec not modified")
+
+// fetchFunc fetches a credential spec unless its current version is the given cached
+// version, in which case it returns errNotModified, and returns the credential spec with
+// its version. The cached version is empty when the credential spec isn't cached, and
+// the returned version is empty when the current version can't be told, which keeps the
+// credential spec from being used from the cache.
+type fetchFunc func(cachedVersion string) (data string, version string, err error)
+
+// cache is shared by the credentialspec resources of all the tasks
+var cache = newCredentialSpecCache()
+
+func newCredentialSpecCache() *credentialSpecCache {
+	return &credentialSpecCache{
+		entries: make(map[credentialSpecCacheKey]credentialSpecCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// get returns the credential spec with the key from the cache if it was fetched
+// less than ttl ago and it's still the current version. Otherwise the credential
+// spec is fetched again and cached. Caching is disabled when ttl isn't positive.
+func (c *credentialSpecCache) get(key credentialSpecCacheKey, ttl time.Duration, fetch fetchFunc) (string, error) {
+	if ttl <= 0 {
+		data, _, err := fetch("")
+		return data, err
+	}
+
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	c.lock.Unlock()
+	cachedVersion := ""
+	if ok && entry.version != "" && c.now().Sub(entry.fetchedAt) < ttl {
+		cachedVersion = entry.version
+	}
+
+	data, version, err := fetch(cachedVersion)
+	if err == errNotModified {
+		seelog.Debugf("Using cached credentialspec %s version %s fetched with role %s",
+			key.credSpecARN, cachedVersion, key.roleARN)
+		return entry.data, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = credentialSpecCacheEntry{
+		data:      data,
+		version:   version,
+		fetchedAt: c.now(),
+	}
+	return data, nil
+}
+
+// invalidate removes the credential spec with the key from the cache
+func (c *credentialSpecCache) invalidate(key credentialSpecCacheKey) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.entries, key)
+}
@@ -0,0 +1,156 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentialspec
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCacheKey = credentialSpecCacheKey{
+	roleARN:     "arn:aws:iam::123456789012:role/execution",
+	credSpecARN: "arn:aws:ssm:us-west-2:123456789012:parameter/test",
+}
+
+// countingFetch returns a fetch function that counts its calls, and returns the data
+// with version 1 unless version 1 is the cached version
+func countingFetch(data string, calls *int) fetchFunc {
+	return versionedFetch(data, "1", calls)
+}
+
+// versionedFetch returns a fetch function for the given version of the data that counts
+// the calls that fetch the data
+func versionedFetch(data, version string, calls *int) fetchFunc {
+	return func(cachedVersion string) (string, string, error) {
+		if version != "" && cachedVersion == version {
+			return "", "", errNotModified
+		}
+		*calls++
+		return data, version, nil
+	}
+}
+
+func TestCredentialSpecCacheGet(t *testing.T) {
+	c := newCredentialSpecCache()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	calls := 0
+	data, err := c.get(testCacheKey, time.Hour, countingFetch("credspec", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, "credspec", data)
+
+	// Cached until the ttl expires
+	now = now.Add(59 * time.Minute)
+	data, err = c.get(testCacheKey, time.Hour, countingFetch("updated", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, "credspec", data)
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(time.Minute)
+	data, err = c.get(testCacheKey, time.Hour, countingFetch("updated", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, "updated", data)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCredentialSpecCacheDisabled(t *testing.T) {
+	c := newCredentialSpecCache()
+	calls := 0
+	for i := 0; i < 2; i++ {
+		_, err := c.get(testCacheKey, 0, countingFetch("credspec", &calls))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+	assert.Empty(t, c.entries)
+}
+
+func TestCredentialSpecCacheKeyedByRole(t *testing.T) {
+	c := newCredentialSpecCache()
+	calls := 0
+	_, err := c.get(testCacheKey, time.Hour, countingFetch("credspec", &calls))
+	require.NoError(t, err)
+
+	// A task with another execution role fetches the credential spec with its own role
+	otherRoleKey := testCacheKey
+	otherRoleKey.roleARN = "arn:aws:iam::123456789012:role/other"
+	_, err = c.get(otherRoleKey, time.Hour, func(string) (string, string, error) {
+		return "", "", errors.New("access denied")
+	})
+	assert.Error(t, err)
+
+	data, err := c.get(testCacheKey, time.Hour, countingFetch("updated", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, "credspec", data)
+	assert.Equal(t, 1, calls)
+}
+
+func TestCredentialSpecCacheFetchError(t *testing.T) {
+	c := newCredentialSpecCache()
+	_, err := c.get(testCacheKey, time.Hour, func(string) (string, string, error) {
+		return "", "", errors.New("access denied")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, c.entries)
+}
+
+func TestCredentialSpecCacheInvalidate(t *testing.T) {
+	c := newCredentialSpecCache()
+	calls := 0
+	_, err := c.get(testCacheKey, time.Hour, countingFetch("credspec", &calls))
+	require.NoError(t, err)
+
+	c.invalidate(testCacheKey)
+	_, err = c.get(testCacheKey, time.Hour, countingFetch("credspec", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCredentialSpecCacheVersionChanged(t *testing.T) {
+	c := newCredentialSpecCache()
+	calls := 0
+	_, err := c.get(testCacheKey, time.Hour, versionedFetch("credspec", "1", &calls))
+	require.NoError(t, err)
+
+	// The cached credential spec isn't used once it has a new version
+	data, err := c.get(testCacheKey, time.Hour, versionedFetch("updated", "2", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, "updated", data)
+	assert.Equal(t, 2, calls)
+
+	data, err = c.get(testCacheKey, time.Hour, versionedFetch("updated", "2", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, "updated", data)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCredentialSpecCacheVersionUnknown(t *testing.T) {
+	c := newCredentialSpecCache()
+	calls := 0
+	for i := 0; i < 2; i++ {
+		_, err := c.get(testCacheKey, time.Hour, func(cachedVersion string) (string, string, error) {
+			assert.Empty(t, cachedVersion)
+			calls++
+			return "credspec", "", nil
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +28,8 @@ import (
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
 	"github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/asm"
+	asmfactory "github.com/aws/amazon-ecs-agent/agent/asm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/s3"
 	s3factory "github.com/aws/amazon-ecs-agent/agent/s3/factory"
@@ -36,6 +39,7 @@ import (
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
 	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
@@ -79,6 +83,12 @@ type CredentialSpecResource struct {
 	// s3ClientCreator is a factory interface that creates new S3 clients. This is
 	// needed mostly for testing.
 	s3ClientCreator s3factory.S3ClientCreator
+	// asmClientCreator is a factory interface that creates new Secrets Manager
+	// clients. This is needed mostly for testing.
+	asmClientCreator asmfactory.ClientCreator
+	// cacheTTL is how long the credentialspecs fetched from S3, SSM and Secrets
+	// Manager are cached for the other tasks. Caching is disabled when it isn't positive
+	cacheTTL time.Duration
 	// credentialSpecResourceLocation is the location for all the tasks' credentialspec artifacts
 	credentialSpecResourceLocation string
 	// required for processing credentialspecs
@@ -89,6 +99,7 @@ type CredentialSpecResource struct {
 	// * key := credentialspec:file://credentialspec.json, value := credentialspec=file://credentialspec.json
 	// * key := credentialspec:s3ARN, value := credentialspec=file://CredentialSpecResourceLocation/s3_taskARN_fileName.json
 	// * key := credentialspec:ssmARN, value := credentialspec=file://CredentialSpecResourceLocation/ssm_taskARN_param.json
+	// * key := credentialspec:asmARN, value := credentialspec=file://CredentialSpecResourceLocation/asm_taskARN_secret.json
 	CredSpecMap map[string]string
 	// lock is used for fields that are accessed and updated concurrently
 	lock sync.RWMutex
@@ -100,7 +111,9 @@ func NewCredentialSpecResource(taskARN, region string,
 	executionCredentialsID string,
 	credentialsManager credentials.Manager,
 	ssmClientCreator ssmfactory.SSMClientCreator,
-	s3ClientCreator s3factory.S3ClientCreator) (*CredentialSpecResource, error) {
+	s3ClientCreator s3factory.S3ClientCreator,
+	asmClientCreator asmfactory.ClientCreator,
+	cacheTTL time.Duration) (*CredentialSpecResource, error) {
 
 	s := &CredentialSpecResource{
 		taskARN:                 taskARN,
@@ -110,6 +123,8 @@ func NewCredentialSpecResource(taskARN, region string,
 		executionCredentialsID:  executionCredentialsID,
 		ssmClientCreator:        ssmClientCreator,
 		s3ClientCreator:         s3ClientCreator,
+		asmClientCreator:        asmClientCreator,
+		cacheTTL:                cacheTTL,
 		CredSpecMap:             make(map[string]string),
 		ioutil:                  ioutilwrapper.NewIOUtil(),
 	}
@@ -137,6 +152,7 @@ func (cs *CredentialSpecResource) Initialize(resourceFields *taskresource.Resour
 	cs.credentialsManager = resourceFields.CredentialsManager
 	cs.ssmClientCreator = resourceFields.SSMClientCreator
 	cs.s3ClientCreator = resourceFields.S3ClientCreator
+	cs.asmClientCreator = resourceFields.ASMClientCreator
 	cs.initStatusToTransition()
 }
 
@@ -340,6 +356,12 @@ func (cs *CredentialSpecResource) Create() error {
 			return err
 		}
 
+		// The credentialspec was already handled before the task was restarted, fetch
+		// it again in case it was updated
+		if _, err := cs.GetTargetMapping(credSpecStr); err == nil {
+			cache.invalidate(credentialSpecCacheKey{roleARN: iamCredentials.RoleArn, credSpecARN: credSpecValue})
+		}
+
 		parsedARNService := parsedARN.Service
 		if parsedARNService == "s3" {
 			err = cs.handleS3CredentialspecFile(credSpecStr, credSpecValue, iamCredentials)
@@ -355,8 +377,15 @@ func (cs *CredentialSpecResource) Create() error {
 				cs.setTerminalReason(err.Error())
 				return err
 			}
+		} else if parsedARNService == "secretsmanager" {
+			err = cs.handleASMCredentialspecFile(credSpecStr, credSpecValue, iamCredentials)
+			if err != nil {
+				seelog.Errorf("Failed to handle the credentialspec file from Secrets Manager: %v", err)
+				cs.setTerminalReason(err.Error())
+				return err
+			}
 		} else {
-			err = errors.New("unsupported credentialspec ARN, only s3/ssm ARNs are valid")
+			err = errors.New("unsupported credentialspec ARN, only s3/ssm/secretsmanager ARNs are valid")
 			cs.setTerminalReason(err.Error())
 			return err
 		}
@@ -402,7 +431,26 @@ func (cs *CredentialSpecResource) handleS3CredentialspecFile(originalCredentials
 		return err
 	}
 
-	s3Client, err := cs.s3ClientCreator.NewS3ClientForBucket(bucket, cs.region, iamCredentials)
+	s3Data, err := cache.get(credentialSpecCacheKey{roleARN: iamCredentials.RoleArn, credSpecARN: credentialspecS3ARN}, cs.cacheTTL, func(cachedVersion string) (string, string, error) {
+		s3Client, err := cs.s3ClientCreator.NewS3ClientForBucket(bucket, cs.region, iamCredentials)
+		if err != nil {
+			return "", "", err
+		}
+		buffer := aws.NewWriteAtBuffer([]byte{})
+		if cs.cacheTTL <= 0 {
+			// The ETag is only needed to tell whether a cached credentialspec is current
+			err := s3.DownloadFile(bucket, key, s3DownloadTimeout, buffer, s3Client)
+			return string(buffer.Bytes()), "", err
+		}
+		etag, err := s3.DownloadFileIfNoneMatch(bucket, key, cachedVersion, s3DownloadTimeout, buffer, s3Client)
+		if err == s3.ErrNotModified {
+			return "", "", errNotModified
+		}
+		if err != nil {
+			return "", "", err
+		}
+		return string(buffer.Bytes()), etag, nil
+	})
 	if err != nil {
 		cs.setTerminalReason(err.Error())
 		return err
@@ -417,7 +465,8 @@ func (cs *CredentialSpecResource) handleS3CredentialspecFile(originalCredentials
 
 	localCredSpecFilePath := fmt.Sprintf("%s\\s3_%v_%s", cs.credentialSpecResourceLocation, taskArnSplit[length-1], resourceBase)
 	err = cs.writeS3File(func(file oswrapper.File) error {
-		return s3.DownloadFile(bucket, key, s3DownloadTimeout, file, s3Client)
+		_, err := file.Write([]byte(s3Data))
+		return err
 	}, localCredSpecFilePath)
 	if err != nil {
 		cs.setTerminalReason(err.Error())
@@ -443,25 +492,35 @@ func (cs *CredentialSpecResource) handleSSMCredentialspecFile(originalCredential
 		return err
 	}
 
-	ssmClient := cs.ssmClientCreator.NewSSMClient(cs.region, iamCredentials)
-
 	ssmParam := filepath.Base(parsedARN.Resource)
-	ssmParams := []string{ssmParam}
-
-	ssmParamMap, err := ssm.GetParametersFromSSM(ssmParams, ssmClient)
+	ssmParamData, err := cache.get(credentialSpecCacheKey{roleARN: iamCredentials.RoleArn, credSpecARN: credentialspecSSMARN}, cs.cacheTTL, func(cachedVersion string) (string, string, error) {
+		ssmClient := cs.ssmClientCreator.NewSSMClient(cs.region, iamCredentials)
+		if cachedVersion != "" {
+			version, err := ssm.GetParameterVersionFromSSM(ssmParam, ssmClient)
+			if err != nil {
+				seelog.Infof("Unable to get the version of credentialspec %s, fetching it again: %v", credentialspecSSMARN, err)
+			} else if ssmParameterVersion(version) == cachedVersion {
+				return "", "", errNotModified
+			}
+		}
+		data, version, err := ssm.GetParameterWithVersionFromSSM(ssmParam, ssmClient)
+		if err != nil {
+			return "", "", err
+		}
+		return data, ssmParameterVersion(version), nil
+	})
 	if err != nil {
 		cs.setTerminalReason(err.Error())
 		return err
 	}
 
-	ssmParamData := ssmParamMap[ssmParam]
 	taskArnSplit := strings.Split(cs.taskARN, "/")
 	length := len(taskArnSplit)
 	if length < 2 {
 		return errors.New("Failed to retrieve taskId from taskArn.")
 	}
 	localCredSpecFilePath := fmt.Sprintf("%s\\ssm_%v_%s", cs.credentialSpecResourceLocation, taskArnSplit[length-1], ssmParam)
-	err = cs.writeSSMFile(ssmParamData, localCredSpecFilePath)
+	err = cs.writeCredentialSpecFile(ssmParamData, localCredSpecFilePath)
 	if err != nil {
 		cs.setTerminalReason(err.Error())
 		return err
@@ -473,6 +532,65 @@ func (cs *CredentialSpecResource) handleSSMCredentialspecFile(originalCredential
 	return nil
 }
 
+func (cs *CredentialSpecResource) handleASMCredentialspecFile(originalCredentialspec, credentialspecASMARN string, iamCredentials credentials.IAMRoleCredentials) error {
+	if iamCredentials == (credentials.IAMRoleCredentials{}) {
+		err := errors.New("credentialspec resource: unable to find execution role credentials")
+		cs.setTerminalReason(err.Error())
+		return err
+	}
+
+	parsedARN, err := arn.Parse(credentialspecASMARN)
+	if err != nil {
+		cs.setTerminalReason(err.Error())
+		return err
+	}
+
+	secretData, err := cache.get(credentialSpecCacheKey{roleARN: iamCredentials.RoleArn, credSpecARN: credentialspecASMARN}, cs.cacheTTL, func(cachedVersion string) (string, string, error) {
+		asmClient := cs.asmClientCreator.NewASMClient(parsedARN.Region, iamCredentials)
+		if cachedVersion != "" {
+			versionID, err := asm.GetSecretVersionFromASM(credentialspecASMARN, asmClient)
+			if err != nil {
+				seelog.Infof("Unable to get the version of credentialspec %s, fetching it again: %v", credentialspecASMARN, err)
+			} else if versionID == cachedVersion {
+				return "", "", errNotModified
+			}
+		}
+		return asm.GetSecretWithVersionFromASM(credentialspecASMARN, asmClient)
+	})
+	if err != nil {
+		cs.setTerminalReason(err.Error())
+		return err
+	}
+
+	// Secret ARN resources look like secret:name-AbCdEf
+	secretName := strings.TrimPrefix(parsedARN.Resource, "secret:")
+	taskArnSplit := strings.Split(cs.taskARN, "/")
+	length := len(taskArnSplit)
+	if length < 2 {
+		return errors.New("Failed to retrieve taskId from taskArn.")
+	}
+	localCredSpecFilePath := fmt.Sprintf("%s\\asm_%v_%s", cs.credentialSpecResourceLocation, taskArnSplit[length-1], secretName)
+	err = cs.writeCredentialSpecFile(secretData, localCredSpecFilePath)
+	if err != nil {
+		cs.setTerminalReason(err.Error())
+		return err
+	}
+
+	dockerHostconfigSecOptCredSpec := fmt.Sprintf("credentialspec=file://%s", filepath.Base(localCredSpecFilePath))
+	cs.updateCredSpecMapping(originalCredentialspec, dockerHostconfigSecOptCredSpec)
+
+	return nil
+}
+
+// ssmParameterVersion returns the version of an SSM parameter as the version of a cached
+// credentialspec, which is empty when the version is unknown
+func ssmParameterVersion(version int64) string {
+	if version <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(version, 10)
+}
+
 var rename = os.Rename
 
 func (cs *CredentialSpecResource) writeS3File(writeFunc func(file oswrapper.File) error, filePath string) error {
@@ -500,7 +618,7 @@ func (cs *CredentialSpecResource) writeS3File(writeFunc func(file oswrapper.File
 	return nil
 }
 
-func (cs *CredentialSpecResource) writeSSMFile(ssmParamData, filePath string) error {
+func (cs *CredentialSpecResource) writeCredentialSpecFile(ssmParamData, filePath string) error {
 	return cs.ioutil.WriteFile(filePath, []byte(ssmParamData), filePerm)
 }
 
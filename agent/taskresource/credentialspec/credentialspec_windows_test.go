@@ -24,6 +24,8 @@ import (
 	"github.com/pkg/errors"
 
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	mock_asm_factory "github.com/aws/amazon-ecs-agent/agent/asm/factory/mocks"
+	mock_secretsmanageriface "github.com/aws/amazon-ecs-agent/agent/asm/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
 	mock_s3_factory "github.com/aws/amazon-ecs-agent/agent/s3/factory/mocks"
@@ -35,6 +37,7 @@ import (
 	mock_ioutilwrapper "github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper/mocks"
 	mock_oswrapper "github.com/aws/amazon-ecs-agent/agent/utils/oswrapper/mocks"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -418,8 +421,8 @@ func TestHandleS3CredentialspecFile(t *testing.T) {
 	}
 	gomock.InOrder(
 		s3ClientCreator.EXPECT().NewS3ClientForBucket(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockS3Client, nil),
+		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), nil),
 		mockIO.EXPECT().TempFile(gomock.Any(), gomock.Any()).Return(mockFile, nil),
-		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any()).Return(int64(0), nil),
 	)
 
 	err := cs.handleS3CredentialspecFile(s3CredentialSpec, credentialSpecS3ARN, iamCredentials)
@@ -528,14 +531,176 @@ func TestHandleS3CredentialspecFileWriteErr(t *testing.T) {
 
 	gomock.InOrder(
 		s3ClientCreator.EXPECT().NewS3ClientForBucket(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockS3Client, nil),
+		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), nil),
 		mockIO.EXPECT().TempFile(gomock.Any(), gomock.Any()).Return(mockFile, nil),
-		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any()).Return(int64(0), nil),
 	)
 
 	err := cs.handleS3CredentialspecFile(s3CredentialSpec, credentialSpecS3ARN, iamCredentials)
 	assert.Error(t, err)
 }
 
+func TestHandleASMCredentialspecFile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	asmClientCreator := mock_asm_factory.NewMockClientCreator(ctrl)
+	mockIO := mock_ioutilwrapper.NewMockIOUtil(ctrl)
+	mockASMClient := mock_secretsmanageriface.NewMockSecretsManagerAPI(ctrl)
+	iamCredentials := credentials.IAMRoleCredentials{
+		CredentialsID: "test-cred-id",
+	}
+
+	credentialSpecASMARN := "arn:aws:secretsmanager:us-west-2:123456789012:secret:test-AbCdEf"
+	asmCredentialSpec := "credentialspec:" + credentialSpecASMARN
+	expectedFileCredentialSpec := "credentialspec=file://asm_12345-678901234-56789_test-AbCdEf"
+
+	cs := &CredentialSpecResource{
+		knownStatusUnsafe:       resourcestatus.ResourceCreated,
+		desiredStatusUnsafe:     resourcestatus.ResourceCreated,
+		requiredCredentialSpecs: []string{asmCredentialSpec},
+		CredSpecMap:             map[string]string{},
+		taskARN:                 taskARN,
+		ioutil:                  mockIO,
+	}
+	cs.Initialize(&taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+			ASMClientCreator:   asmClientCreator,
+			CredentialsManager: credentialsManager,
+		},
+	}, apitaskstatus.TaskStatusNone, apitaskstatus.TaskRunning)
+
+	testData := "test-cred-spec-data"
+	gomock.InOrder(
+		asmClientCreator.EXPECT().NewASMClient("us-west-2", iamCredentials).Return(mockASMClient),
+		mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Do(func(input *secretsmanager.GetSecretValueInput) {
+			assert.Equal(t, credentialSpecASMARN, aws.StringValue(input.SecretId))
+		}).Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String(testData)}, nil),
+		mockIO.EXPECT().WriteFile(gomock.Any(), []byte(testData), gomock.Any()).Return(nil),
+	)
+
+	err := cs.handleASMCredentialspecFile(asmCredentialSpec, credentialSpecASMARN, iamCredentials)
+	assert.NoError(t, err)
+
+	targetCredentialSpecFile, err := cs.GetTargetMapping(asmCredentialSpec)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedFileCredentialSpec, targetCredentialSpecFile)
+}
+
+func TestHandleSSMCredentialspecFileCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ssmClientCreator := mock_factory.NewMockSSMClientCreator(ctrl)
+	mockIO := mock_ioutilwrapper.NewMockIOUtil(ctrl)
+	mockSSMClient := mock_ssmiface.NewMockSSMClient(ctrl)
+	iamCredentials := credentials.IAMRoleCredentials{
+		CredentialsID: "test-cred-id",
+	}
+
+	credentialSpecSSMARN := "arn:aws:ssm:us-west-2:123456789012:parameter/cached"
+	ssmCredentialSpec := "credentialspec:" + credentialSpecSSMARN
+	defer cache.invalidate(credentialSpecCacheKey{roleARN: iamCredentials.RoleArn, credSpecARN: credentialSpecSSMARN})
+
+	newResource := func() *CredentialSpecResource {
+		cs := &CredentialSpecResource{
+			requiredCredentialSpecs: []string{ssmCredentialSpec},
+			CredSpecMap:             map[string]string{},
+			taskARN:                 taskARN,
+			ioutil:                  mockIO,
+			cacheTTL:                time.Hour,
+		}
+		cs.Initialize(&taskresource.ResourceFields{
+			ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+				SSMClientCreator: ssmClientCreator,
+			},
+		}, apitaskstatus.TaskStatusNone, apitaskstatus.TaskRunning)
+		return cs
+	}
+
+	testData := "test-cred-spec-data"
+	ssmClientOutput := &ssm.GetParametersOutput{
+		InvalidParameters: []*string{},
+		Parameters: []*ssm.Parameter{
+			&ssm.Parameter{
+				Name:    aws.String("cached"),
+				Value:   aws.String(testData),
+				Version: aws.Int64(1),
+			},
+		},
+	}
+	// The credentialspec is only fetched once for both tasks, the second one only checks
+	// that its version didn't change
+	ssmClientCreator.EXPECT().NewSSMClient(gomock.Any(), gomock.Any()).Return(mockSSMClient).Times(2)
+	mockSSMClient.EXPECT().GetParameters(gomock.Any()).Return(ssmClientOutput, nil).Times(1)
+	mockSSMClient.EXPECT().DescribeParameters(gomock.Any()).Return(&ssm.DescribeParametersOutput{
+		Parameters: []*ssm.ParameterMetadata{{
+			Name:    aws.String("cached"),
+			Version: aws.Int64(1),
+		}},
+	}, nil).Times(1)
+	mockIO.EXPECT().WriteFile(gomock.Any(), []byte(testData), gomock.Any()).Return(nil).Times(2)
+
+	assert.NoError(t, newResource().handleSSMCredentialspecFile(ssmCredentialSpec, credentialSpecSSMARN, iamCredentials))
+	assert.NoError(t, newResource().handleSSMCredentialspecFile(ssmCredentialSpec, credentialSpecSSMARN, iamCredentials))
+}
+
+func TestHandleASMCredentialspecFileCachedVersionChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	asmClientCreator := mock_asm_factory.NewMockClientCreator(ctrl)
+	mockIO := mock_ioutilwrapper.NewMockIOUtil(ctrl)
+	mockASMClient := mock_secretsmanageriface.NewMockSecretsManagerAPI(ctrl)
+	iamCredentials := credentials.IAMRoleCredentials{
+		CredentialsID: "test-cred-id",
+	}
+
+	credentialSpecASMARN := "arn:aws:secretsmanager:us-west-2:123456789012:secret:cached-AbCdEf"
+	asmCredentialSpec := "credentialspec:" + credentialSpecASMARN
+	defer cache.invalidate(credentialSpecCacheKey{roleARN: iamCredentials.RoleArn, credSpecARN: credentialSpecASMARN})
+
+	newResource := func() *CredentialSpecResource {
+		cs := &CredentialSpecResource{
+			requiredCredentialSpecs: []string{asmCredentialSpec},
+			CredSpecMap:             map[string]string{},
+			taskARN:                 taskARN,
+			ioutil:                  mockIO,
+			cacheTTL:                time.Hour,
+		}
+		cs.Initialize(&taskresource.ResourceFields{
+			ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+				ASMClientCreator: asmClientCreator,
+			},
+		}, apitaskstatus.TaskStatusNone, apitaskstatus.TaskRunning)
+		return cs
+	}
+
+	// The credentialspec is fetched again for the second task as it has a new version
+	asmClientCreator.EXPECT().NewASMClient("us-west-2", iamCredentials).Return(mockASMClient).Times(2)
+	gomock.InOrder(
+		mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+			SecretString: aws.String("test-cred-spec-data"),
+			VersionId:    aws.String("v1"),
+		}, nil),
+		mockIO.EXPECT().WriteFile(gomock.Any(), []byte("test-cred-spec-data"), gomock.Any()).Return(nil),
+		mockASMClient.EXPECT().DescribeSecret(gomock.Any()).Return(&secretsmanager.DescribeSecretOutput{
+			VersionIdsToStages: map[string][]*string{
+				"v1": {aws.String("AWSPREVIOUS")},
+				"v2": {aws.String("AWSCURRENT")},
+			},
+		}, nil),
+		mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+			SecretString: aws.String("updated-cred-spec-data"),
+			VersionId:    aws.String("v2"),
+		}, nil),
+		mockIO.EXPECT().WriteFile(gomock.Any(), []byte("updated-cred-spec-data"), gomock.Any()).Return(nil),
+	)
+
+	assert.NoError(t, newResource().handleASMCredentialspecFile(asmCredentialSpec, credentialSpecASMARN, iamCredentials))
+	assert.NoError(t, newResource().handleASMCredentialspecFile(asmCredentialSpec, credentialSpecASMARN, iamCredentials))
+}
+
 func TestHandlerS3CredentialspecCredMissingErr(t *testing.T) {
 	cs := &CredentialSpecResource{}
 
@@ -648,8 +813,8 @@ func TestCreateS3(t *testing.T) {
 	gomock.InOrder(
 		credentialsManager.EXPECT().GetTaskCredentials(gomock.Any()).Return(creds, true),
 		s3ClientCreator.EXPECT().NewS3ClientForBucket(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockS3Client, nil),
+		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), nil),
 		mockIO.EXPECT().TempFile(gomock.Any(), gomock.Any()).Return(mockFile, nil),
-		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any()).Return(int64(0), nil),
 	)
 
 	assert.NoError(t, cs.Create())
@@ -14,10 +14,16 @@
 package envFiles
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -35,6 +41,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
 )
@@ -53,7 +60,36 @@ const (
 	renameBackoffMultiple = 1.5
 	renameRetryAttempts   = 5
 
+	// envFileRefreshDirSuffix and envFilePreviousDirSuffix are the suffixes of the directories
+	// where the env files are downloaded to and the previous env files are kept during a refresh
+	envFileRefreshDirSuffix  = ".refresh"
+	envFilePreviousDirSuffix = ".previous"
+
 	s3DownloadTimeout = 30 * time.Second
+
+	// envFileCacheDirName is the directory of the env files pinned by a checksum, which
+	// are kept for the other tasks using the same version. Cluster names can't start
+	// with a dot, so it doesn't collide with the directories of the clusters.
+	envFileCacheDirName = ".cache"
+	// envFileCacheMaxAge is how long the cached env files are kept after they were last used
+	envFileCacheMaxAge = 24 * time.Hour
+
+	// The reason codes prefixing the errors the env files can't be retrieved with, which
+	// become the stopped reason of the task
+	envFileInvalidReferenceReason = "EnvironmentFileInvalidReference"
+	envFileAccessDeniedReason     = "EnvironmentFileAccessDenied"
+	envFileNotFoundReason         = "EnvironmentFileNotFound"
+	envFileChecksumMismatchReason = "EnvironmentFileChecksumMismatch"
+	envFileDownloadErrorReason    = "EnvironmentFileDownloadError"
+)
+
+var (
+	// sha256Regex matches the hex encoded sha256 checksums the env files can be pinned by
+	sha256Regex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+	// etagRegex matches the ETags of S3 objects the env files can be pinned by, the hex
+	// encoded digest of the object followed by the number of parts of a multipart upload,
+	// with or without the double quotes S3 returns them between
+	etagRegex = regexp.MustCompile(`^(?:"[0-9a-fA-F]{32}(?:-[0-9]+)?"|[0-9a-fA-F]{32}(?:-[0-9]+)?)$`)
 )
 
 // EnvironmentFileResource represents envfile as a task resource
@@ -63,6 +99,7 @@ type EnvironmentFileResource struct {
 	taskARN       string
 	region        string
 	resourceDir   string // path to store env var files
+	cacheDir      string // path to cache the env files pinned by a checksum, if any
 	containerName string
 
 	// env file related attributes
@@ -105,6 +142,7 @@ func NewEnvironmentFileResource(cluster, taskARN, region, dataDir, containerName
 	taskID := taskARNFields[len(taskARNFields)-1]
 	// we save envfiles for a task to path: /var/lib/ecs/data/envfiles/cluster_name/task_id/
 	envfileResource.resourceDir = filepath.Join(dataDir, envFileDirPath, cluster, taskID)
+	envfileResource.cacheDir = filepath.Join(dataDir, envFileDirPath, envFileCacheDirName)
 
 	envfileResource.initStatusToTransition()
 	return envfileResource, nil
@@ -300,6 +338,8 @@ func (envfile *EnvironmentFileResource) Create() error {
 		return err
 	}
 
+	envfile.pruneEnvfileCache()
+
 	var wg sync.WaitGroup
 	errorEvents := make(chan error, len(envfile.environmentFilesSource))
 
@@ -308,7 +348,7 @@ func (envfile *EnvironmentFileResource) Create() error {
 		wg.Add(1)
 		// if we support types besides S3 ARN, we will need to add filtering before the below method is called
 		// call an additional go routine per env file
-		go envfile.downloadEnvfileFromS3(envfileSource.Value, iamCredentials, &wg, errorEvents)
+		go envfile.downloadEnvfileFromS3(envfileSource, iamCredentials, &wg, errorEvents)
 	}
 
 	wg.Wait()
@@ -328,6 +368,63 @@ func (envfile *EnvironmentFileResource) Create() error {
 	return nil
 }
 
+// Refresh downloads the env files again into a staging directory, and swaps it with the
+// directory of the resource once all of them are downloaded. The previous files are kept
+// until the refresh is committed, so that they can be restored.
+func (envfile *EnvironmentFileResource) Refresh() (func() error, func(), error) {
+	refreshed := &EnvironmentFileResource{
+		taskARN:                envfile.taskARN,
+		region:                 envfile.region,
+		resourceDir:            envfile.resourceDir + envFileRefreshDirSuffix,
+		cacheDir:               envfile.cacheDir,
+		containerName:          envfile.containerName,
+		environmentFilesSource: envfile.environmentFilesSource,
+		executionCredentialsID: envfile.executionCredentialsID,
+		credentialsManager:     envfile.credentialsManager,
+		s3ClientCreator:        envfile.s3ClientCreator,
+		ioutil:                 envfile.ioutil,
+		bufio:                  envfile.bufio,
+	}
+	if err := removeAll(refreshed.resourceDir); err != nil {
+		return nil, nil, err
+	}
+	if err := mkdirAll(refreshed.resourceDir, os.ModePerm); err != nil {
+		return nil, nil, err
+	}
+	if err := refreshed.Create(); err != nil {
+		removeAll(refreshed.resourceDir)
+		return nil, nil, err
+	}
+
+	previousDir := envfile.resourceDir + envFilePreviousDirSuffix
+	if err := removeAll(previousDir); err != nil {
+		removeAll(refreshed.resourceDir)
+		return nil, nil, err
+	}
+	if err := rename(envfile.resourceDir, previousDir); err != nil {
+		removeAll(refreshed.resourceDir)
+		return nil, nil, err
+	}
+	if err := rename(refreshed.resourceDir, envfile.resourceDir); err != nil {
+		removeAll(refreshed.resourceDir)
+		rename(previousDir, envfile.resourceDir)
+		return nil, nil, err
+	}
+
+	rollback := func() error {
+		if err := removeAll(envfile.resourceDir); err != nil {
+			return err
+		}
+		return rename(previousDir, envfile.resourceDir)
+	}
+	commit := func() {
+		if err := removeAll(previousDir); err != nil {
+			seelog.Warnf("Unable to remove previous envfiles at %s: %v", previousDir, err)
+		}
+	}
+	return rollback, commit, nil
+}
+
 var mkdirAll = os.MkdirAll
 
 // createEnvfileDirectory creates the directory that we will be writing the
@@ -344,41 +441,253 @@ func (envfile *EnvironmentFileResource) createEnvfileDirectory(bucket, key strin
 	return nil
 }
 
-func (envfile *EnvironmentFileResource) downloadEnvfileFromS3(envFilePath string, iamCredentials credentials.IAMRoleCredentials,
-	wg *sync.WaitGroup, errorEvents chan error) {
+func (envfile *EnvironmentFileResource) downloadEnvfileFromS3(envfileSource apicontainer.EnvironmentFile,
+	iamCredentials credentials.IAMRoleCredentials, wg *sync.WaitGroup, errorEvents chan error) {
 	defer wg.Done()
 
+	envFilePath := envfileSource.Value
 	bucket, key, err := s3.ParseS3ARN(envFilePath)
 	if err != nil {
-		errorEvents <- fmt.Errorf("unable to parse bucket and key from s3 ARN specified in environmentFile %s, error: %v", envFilePath, err)
+		errorEvents <- fmt.Errorf("%s: unable to parse bucket and key from s3 ARN specified in environmentFile %s, error: %v",
+			envFileInvalidReferenceReason, envFilePath, err)
+		return
+	}
+
+	if err := validateEnvfileReference(bucket, key, envfileSource); err != nil {
+		errorEvents <- fmt.Errorf("%s: invalid environmentFile %s, error: %v", envFileInvalidReferenceReason, envFilePath, err)
+		return
+	}
+
+	// we save envfiles to path: /var/lib/ecs/data/envfiles/cluster_name/task_id/${s3bucketname}/${s3filename.env}
+	downloadPath := filepath.Join(envfile.resourceDir, bucket, key)
+	cachePath := envfile.envfileCachePath(iamCredentials.RoleArn, bucket, key, envfileSource)
+	if envfile.copyEnvfileFromCache(cachePath, bucket, key, downloadPath, envfileSource.Sha256) {
 		return
 	}
 
 	s3Client, err := envfile.s3ClientCreator.NewS3ClientForBucket(bucket, envfile.region, iamCredentials)
 	if err != nil {
-		errorEvents <- fmt.Errorf("unable to initialize s3 client for bucket %s, error: %v", bucket, err)
+		errorEvents <- fmt.Errorf("%s: unable to initialize s3 client for bucket %s, error: %v",
+			envfileFailureReason(err), bucket, err)
 		return
 	}
 
 	err = envfile.createEnvfileDirectory(bucket, key)
 	if err != nil {
-		errorEvents <- fmt.Errorf("unable to initialize envfile resource directory, error: %v", err)
+		errorEvents <- fmt.Errorf("%s: unable to initialize envfile resource directory, error: %v", envFileDownloadErrorReason, err)
 		return
 	}
 
 	seelog.Debugf("Downloading envfile with bucket name %v and key name %v", bucket, key)
-	// we save envfiles to path: /var/lib/ecs/data/envfiles/cluster_name/task_id/${s3bucketname}/${s3filename.env}
-	downloadPath := filepath.Join(envfile.resourceDir, bucket, key)
 	err = envfile.writeEnvFile(func(file oswrapper.File) error {
-		return s3.DownloadFile(bucket, key, s3DownloadTimeout, file, s3Client)
+		if err := s3.DownloadFileIfMatch(bucket, key, envfileSource.ETag, s3DownloadTimeout, file, s3Client); err != nil {
+			return err
+		}
+		return verifyEnvfileChecksum(file.Name(), envfileSource.Sha256)
 	}, downloadPath)
 
 	if err != nil {
-		errorEvents <- fmt.Errorf("unable to download env file with key %s from bucket %s, error: %v", key, bucket, err)
+		errorEvents <- fmt.Errorf("%s: unable to download env file with key %s from bucket %s, error: %v",
+			envfileFailureReason(err), key, bucket, err)
 		return
 	}
 
 	seelog.Debugf("Downloaded envfile from s3 and saved to %s", downloadPath)
+	envfile.storeEnvfileInCache(downloadPath, cachePath)
+}
+
+// checksumMismatchError is returned when the content of an env file doesn't match the
+// SHA256 checksum it's pinned to
+type checksumMismatchError struct {
+	expected string
+	actual   string
+}
+
+func (err *checksumMismatchError) Error() string {
+	return fmt.Sprintf("expected sha256 checksum %s, got %s", err.expected, err.actual)
+}
+
+// envfileFailureReason returns the reason code of an error retrieving an env file
+func envfileFailureReason(err error) string {
+	if _, ok := errors.Cause(err).(*checksumMismatchError); ok {
+		return envFileChecksumMismatchReason
+	}
+	if reqErr, ok := errors.Cause(err).(awserr.RequestFailure); ok {
+		switch reqErr.StatusCode() {
+		case http.StatusForbidden:
+			return envFileAccessDeniedReason
+		case http.StatusNotFound:
+			return envFileNotFoundReason
+		case http.StatusPreconditionFailed:
+			// the ETag of the file isn't the one it's pinned to
+			return envFileChecksumMismatchReason
+		}
+	}
+	if awsErr, ok := errors.Cause(err).(awserr.Error); ok {
+		switch awsErr.Code() {
+		case "AccessDenied", "Forbidden":
+			return envFileAccessDeniedReason
+		case "NoSuchKey", "NoSuchBucket", "NotFound":
+			return envFileNotFoundReason
+		case "PreconditionFailed":
+			return envFileChecksumMismatchReason
+		}
+	}
+	return envFileDownloadErrorReason
+}
+
+// verifyEnvfileChecksum returns a checksumMismatchError unless the SHA256 checksum of the
+// file is the expected one. Any content is accepted when no checksum is expected.
+func verifyEnvfileChecksum(path, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	file, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return &checksumMismatchError{expected: expected, actual: actual}
+	}
+	return nil
+}
+
+// validateEnvfileReference checks that the bucket, the key and the version the env file is
+// pinned to, which all become part of the paths of the file, can't point outside of the
+// directories of the resource and of the cache
+func validateEnvfileReference(bucket, key string, envfileSource apicontainer.EnvironmentFile) error {
+	for _, segment := range strings.Split(bucket+"/"+key, "/") {
+		if segment == ".." {
+			return errors.Errorf("the bucket and key can't contain %q", segment)
+		}
+	}
+	if envfileSource.Sha256 != "" && !sha256Regex.MatchString(envfileSource.Sha256) {
+		return errors.Errorf("invalid sha256 checksum %q, expected 64 hex characters", envfileSource.Sha256)
+	}
+	if envfileSource.ETag != "" && !etagRegex.MatchString(envfileSource.ETag) {
+		return errors.Errorf("invalid ETag %q, expected a hex digest optionally followed by a number of parts",
+			envfileSource.ETag)
+	}
+	return nil
+}
+
+// envfileCachePath returns where the version of the env file is cached for the execution
+// role, or an empty string when its version isn't pinned by a checksum. The files are only
+// shared by the tasks with the same execution role, so that a task never reads a file its
+// own role isn't allowed to download.
+func (envfile *EnvironmentFileResource) envfileCachePath(roleARN, bucket, key string, envfileSource apicontainer.EnvironmentFile) string {
+	if envfile.cacheDir == "" || roleARN == "" {
+		return ""
+	}
+	roleSum := sha256.Sum256([]byte(roleARN))
+	roleDir := filepath.Join(envfile.cacheDir, hex.EncodeToString(roleSum[:]))
+	switch {
+	case envfileSource.Sha256 != "":
+		return filepath.Join(roleDir, bucket, key, "sha256-"+strings.ToLower(envfileSource.Sha256))
+	case envfileSource.ETag != "":
+		return filepath.Join(roleDir, bucket, key, "etag-"+strings.Trim(envfileSource.ETag, `"`))
+	default:
+		return ""
+	}
+}
+
+// copyEnvfileFromCache copies the cached version of the env file to the directory of the
+// resource, and returns whether it was cached
+func (envfile *EnvironmentFileResource) copyEnvfileFromCache(cachePath, bucket, key, downloadPath, sha256Sum string) bool {
+	if cachePath == "" {
+		return false
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		return false
+	}
+	if err := envfile.createEnvfileDirectory(bucket, key); err != nil {
+		return false
+	}
+	err := envfile.writeEnvFile(func(file oswrapper.File) error {
+		cached, err := open(cachePath)
+		if err != nil {
+			return err
+		}
+		defer cached.Close()
+		if _, err := io.Copy(file, cached); err != nil {
+			return err
+		}
+		return verifyEnvfileChecksum(file.Name(), sha256Sum)
+	}, downloadPath)
+	if err != nil {
+		seelog.Warnf("Unable to use the cached envfile at %s, downloading it instead: %v", cachePath, err)
+		os.Remove(cachePath)
+		return false
+	}
+
+	// the cached files are kept as long as they're used
+	now := time.Now()
+	os.Chtimes(cachePath, now, now)
+	seelog.Debugf("Copied envfile with bucket name %v and key name %v from cache to %s", bucket, key, downloadPath)
+	return true
+}
+
+// storeEnvfileInCache copies the downloaded env file to the cache, if its version is pinned
+func (envfile *EnvironmentFileResource) storeEnvfileInCache(downloadPath, cachePath string) {
+	if cachePath == "" {
+		return
+	}
+	if err := storeEnvfileInCache(downloadPath, cachePath); err != nil {
+		seelog.Warnf("Unable to cache the envfile downloaded to %s: %v", downloadPath, err)
+	}
+}
+
+func storeEnvfileInCache(downloadPath, cachePath string) error {
+	if err := mkdirAll(filepath.Dir(cachePath), os.ModePerm); err != nil {
+		return err
+	}
+	downloaded, err := open(downloadPath)
+	if err != nil {
+		return err
+	}
+	defer downloaded.Close()
+
+	// the file is renamed once written, so that the tasks reading the cache
+	// concurrently never see a partial file
+	tmpFile, err := ioutil.TempFile(filepath.Dir(cachePath), envTempFilePrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+	if _, err := io.Copy(tmpFile, downloaded); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), cachePath)
+}
+
+// pruneEnvfileCache removes the cached env files that weren't used recently
+func (envfile *EnvironmentFileResource) pruneEnvfileCache() {
+	if envfile.cacheDir == "" {
+		return
+	}
+	cutoff := time.Now().Add(-envFileCacheMaxAge)
+	filepath.Walk(envfile.cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the cache may not exist yet, or be pruned concurrently
+			return nil
+		}
+		if !info.IsDir() && info.ModTime().Before(cutoff) {
+			seelog.Debugf("Removing envfile cached at %s, unused since %s", path, info.ModTime())
+			os.Remove(path)
+		}
+		return nil
+	})
 }
 
 var rename = os.Rename
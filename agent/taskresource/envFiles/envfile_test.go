@@ -16,11 +16,16 @@
 package envFiles
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/aws/amazon-ecs-agent/agent/api/container"
@@ -30,11 +35,14 @@ import (
 	mock_factory "github.com/aws/amazon-ecs-agent/agent/s3/factory/mocks"
 	mock_s3 "github.com/aws/amazon-ecs-agent/agent/s3/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/utils/bufiowrapper"
 	mock_bufio "github.com/aws/amazon-ecs-agent/agent/utils/bufiowrapper/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
 	mock_ioutilwrapper "github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
 	mock_oswrapper "github.com/aws/amazon-ecs-agent/agent/utils/oswrapper/mocks"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -47,6 +55,8 @@ const (
 	taskARN                = "arn:aws:ecs:us-east-2:01234567891011:task/testCluster/abcdef12-34gh-idkl-mno5-pqrst6789"
 	resourceDir            = "resourceDir"
 	iamRoleARN             = "iamRoleARN"
+	executionRoleARN       = "arn:aws:iam::123456789012:role/executionRole"
+	testETag               = "d41d8cd98f00b204e9800998ecf8427e"
 	accessKeyId            = "accessKey"
 	secretAccessKey        = "secret"
 	s3Bucket               = "s3Bucket"
@@ -439,3 +449,220 @@ func TestReadEnvVarsUnableToReadEnvfile(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+func newPinnedEnvfileResource(t *testing.T, dataDir, taskID string, envfiles []container.EnvironmentFile,
+	mockCredentialsManager *mock_credentials.MockManager, mockS3ClientCreator *mock_factory.MockS3ClientCreator) *EnvironmentFileResource {
+	envfileResource := newMockEnvfileResource(envfiles, mockCredentialsManager, mockS3ClientCreator, nil)
+	envfileResource.resourceDir = filepath.Join(dataDir, cluster, taskID)
+	envfileResource.cacheDir = filepath.Join(dataDir, envFileCacheDirName)
+	envfileResource.ioutil = ioutilwrapper.NewIOUtil()
+	envfileResource.bufio = bufiowrapper.NewBufio()
+	assert.NoError(t, os.MkdirAll(envfileResource.resourceDir, os.ModePerm))
+	return envfileResource
+}
+
+func TestCreateWithChecksumCachesEnvFile(t *testing.T) {
+	_, _, mockCredentialsManager, mockS3ClientCreator, mockS3Client, done := setup(t)
+	defer done()
+
+	dataDir, err := ioutil.TempDir("", "envfiles")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	content := "KEY=value\n"
+	sum := sha256.Sum256([]byte(content))
+	envfile := sampleEnvironmentFile(fmt.Sprintf("arn:aws:s3:::%s/%s", s3Bucket, s3Key), "s3")
+	envfile.Sha256 = hex.EncodeToString(sum[:])
+	envfile.ETag = testETag
+	creds := credentials.TaskIAMRoleCredentials{ARN: iamRoleARN,
+		IAMRoleCredentials: credentials.IAMRoleCredentials{RoleArn: executionRoleARN}}
+
+	mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true).Times(2)
+	// the file is only downloaded by the first task
+	mockS3ClientCreator.EXPECT().NewS3ClientForBucket(s3Bucket, region, creds.IAMRoleCredentials).Return(mockS3Client, nil)
+	mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput) {
+			assert.Equal(t, `"`+testETag+`"`, aws.StringValue(input.IfMatch))
+			w.WriteAt([]byte(content), 0)
+		}).Return(int64(len(content)), nil)
+
+	for _, taskID := range []string{"task1", "task2"} {
+		envfileResource := newPinnedEnvfileResource(t, dataDir, taskID, []container.EnvironmentFile{envfile},
+			mockCredentialsManager, mockS3ClientCreator)
+		assert.NoError(t, envfileResource.Create())
+
+		envVarsList, err := envfileResource.ReadEnvVarsFromEnvfiles()
+		assert.NoError(t, err)
+		assert.Equal(t, []map[string]string{{"KEY": "value"}}, envVarsList)
+	}
+	roleSum := sha256.Sum256([]byte(executionRoleARN))
+	_, err = os.Stat(filepath.Join(dataDir, envFileCacheDirName, hex.EncodeToString(roleSum[:]), s3Bucket, s3Key,
+		"sha256-"+envfile.Sha256))
+	assert.NoError(t, err)
+}
+
+func TestCreateWithChecksumDoesntShareCacheAcrossRoles(t *testing.T) {
+	_, _, mockCredentialsManager, mockS3ClientCreator, mockS3Client, done := setup(t)
+	defer done()
+
+	dataDir, err := ioutil.TempDir("", "envfiles")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	content := "KEY=value\n"
+	sum := sha256.Sum256([]byte(content))
+	envfile := sampleEnvironmentFile(fmt.Sprintf("arn:aws:s3:::%s/%s", s3Bucket, s3Key), "s3")
+	envfile.Sha256 = hex.EncodeToString(sum[:])
+	firstCreds := credentials.TaskIAMRoleCredentials{ARN: iamRoleARN,
+		IAMRoleCredentials: credentials.IAMRoleCredentials{RoleArn: executionRoleARN}}
+	secondCreds := credentials.TaskIAMRoleCredentials{ARN: iamRoleARN,
+		IAMRoleCredentials: credentials.IAMRoleCredentials{RoleArn: executionRoleARN + "-other"}}
+
+	gomock.InOrder(
+		mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(firstCreds, true),
+		mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(secondCreds, true),
+	)
+	// the task with the other role downloads the file with its own credentials
+	mockS3ClientCreator.EXPECT().NewS3ClientForBucket(s3Bucket, region, firstCreds.IAMRoleCredentials).Return(mockS3Client, nil)
+	mockS3ClientCreator.EXPECT().NewS3ClientForBucket(s3Bucket, region, secondCreds.IAMRoleCredentials).Return(mockS3Client, nil)
+	mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput) {
+			w.WriteAt([]byte(content), 0)
+		}).Return(int64(len(content)), nil).Times(2)
+
+	for _, taskID := range []string{"task1", "task2"} {
+		envfileResource := newPinnedEnvfileResource(t, dataDir, taskID, []container.EnvironmentFile{envfile},
+			mockCredentialsManager, mockS3ClientCreator)
+		assert.NoError(t, envfileResource.Create())
+	}
+}
+
+func TestEnvfileCachePathRequiresRoleARN(t *testing.T) {
+	envfileResource := &EnvironmentFileResource{cacheDir: "cache"}
+	envfile := container.EnvironmentFile{Sha256: strings.Repeat("0", 64)}
+
+	assert.Empty(t, envfileResource.envfileCachePath("", s3Bucket, s3Key, envfile))
+	assert.NotEmpty(t, envfileResource.envfileCachePath(executionRoleARN, s3Bucket, s3Key, envfile))
+}
+
+func TestCreateWithChecksumMismatch(t *testing.T) {
+	_, _, mockCredentialsManager, mockS3ClientCreator, mockS3Client, done := setup(t)
+	defer done()
+
+	dataDir, err := ioutil.TempDir("", "envfiles")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	envfile := sampleEnvironmentFile(fmt.Sprintf("arn:aws:s3:::%s/%s", s3Bucket, s3Key), "s3")
+	sum := sha256.Sum256([]byte("KEY=value\n"))
+	envfile.Sha256 = hex.EncodeToString(sum[:])
+	creds := credentials.TaskIAMRoleCredentials{ARN: iamRoleARN}
+
+	mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+	mockS3ClientCreator.EXPECT().NewS3ClientForBucket(s3Bucket, region, creds.IAMRoleCredentials).Return(mockS3Client, nil)
+	mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput) {
+			w.WriteAt([]byte("KEY=tampered\n"), 0)
+		}).Return(int64(0), nil)
+
+	envfileResource := newPinnedEnvfileResource(t, dataDir, "task1", []container.EnvironmentFile{envfile},
+		mockCredentialsManager, mockS3ClientCreator)
+	assert.Error(t, envfileResource.Create())
+	assert.True(t, strings.HasPrefix(envfileResource.GetTerminalReason(), envFileChecksumMismatchReason+": "))
+
+	_, err = os.Stat(filepath.Join(dataDir, cluster, "task1", s3Bucket, s3Key))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dataDir, envFileCacheDirName, s3Bucket, s3Key))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCreateWithInvalidReference(t *testing.T) {
+	testCases := []struct {
+		name   string
+		arn    string
+		sha256 string
+		etag   string
+	}{
+		{name: "sha256 with a path", arn: "arn:aws:s3:::%s/%s", sha256: "../../../../data/ecs_agent_data.db"},
+		{name: "short sha256", arn: "arn:aws:s3:::%s/%s", sha256: "0123456789abcdef"},
+		{name: "etag with a path", arn: "arn:aws:s3:::%s/%s", etag: "../../../../data/ecs_agent_data.db"},
+		{name: "unbalanced etag quotes", arn: "arn:aws:s3:::%s/%s", etag: `"` + testETag},
+		{name: "key with a path", arn: "arn:aws:s3:::%s/../../%s"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
+			defer done()
+
+			dataDir, err := ioutil.TempDir("", "envfiles")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dataDir)
+
+			envfile := sampleEnvironmentFile(fmt.Sprintf(tc.arn, s3Bucket, s3Key), "s3")
+			envfile.Sha256 = tc.sha256
+			envfile.ETag = tc.etag
+			creds := credentials.TaskIAMRoleCredentials{ARN: iamRoleARN,
+				IAMRoleCredentials: credentials.IAMRoleCredentials{RoleArn: executionRoleARN}}
+			// nothing is read from the cache nor downloaded
+			mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+
+			envfileResource := newPinnedEnvfileResource(t, dataDir, "task1", []container.EnvironmentFile{envfile},
+				mockCredentialsManager, mockS3ClientCreator)
+			assert.Error(t, envfileResource.Create())
+			assert.True(t, strings.HasPrefix(envfileResource.GetTerminalReason(), envFileInvalidReferenceReason+": "))
+		})
+	}
+}
+
+func TestValidateEnvfileReference(t *testing.T) {
+	for _, etag := range []string{testETag, `"` + testETag + `"`, testETag + "-12", `"` + testETag + `-12"`} {
+		assert.NoError(t, validateEnvfileReference(s3Bucket, s3Key, container.EnvironmentFile{ETag: etag}), etag)
+	}
+	assert.NoError(t, validateEnvfileReference(s3Bucket, s3Key,
+		container.EnvironmentFile{Sha256: strings.Repeat("aB", 32)}))
+}
+
+func TestEnvfileFailureReason(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{
+			name:   "access denied",
+			err:    awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), http.StatusForbidden, "id"),
+			reason: envFileAccessDeniedReason,
+		},
+		{
+			name:   "no such key",
+			err:    awserr.NewRequestFailure(awserr.New("NoSuchKey", "not found", nil), http.StatusNotFound, "id"),
+			reason: envFileNotFoundReason,
+		},
+		{
+			name:   "no such bucket",
+			err:    awserr.New("NoSuchBucket", "not found", nil),
+			reason: envFileNotFoundReason,
+		},
+		{
+			name:   "etag mismatch",
+			err:    awserr.NewRequestFailure(awserr.New("PreconditionFailed", "precondition failed", nil), http.StatusPreconditionFailed, "id"),
+			reason: envFileChecksumMismatchReason,
+		},
+		{
+			name:   "checksum mismatch",
+			err:    &checksumMismatchError{expected: "a", actual: "b"},
+			reason: envFileChecksumMismatchReason,
+		},
+		{
+			name:   "other error",
+			err:    errors.New("connection reset"),
+			reason: envFileDownloadErrorReason,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reason, envfileFailureReason(tc.err))
+		})
+	}
+}
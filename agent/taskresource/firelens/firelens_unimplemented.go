@@ -34,6 +34,10 @@ const (
 	FirelensConfigTypeFluentd = "fluentd"
 	// FirelensConfigTypeFluentbit is the type of a fluentbit firelens container.
 	FirelensConfigTypeFluentbit = "fluentbit"
+	// FirelensConfigTypeOtelcol is the type of an OpenTelemetry Collector firelens container.
+	FirelensConfigTypeOtelcol = "otelcol"
+	// OtelcolConfigFile is the name of the config file generated for an OpenTelemetry Collector firelens container.
+	OtelcolConfigFile = "otelcol.yaml"
 	// ExternalConfigTypeOption is the option that specifies the type of an external config file to be included as
 	// part of the config file generated by agent. Its allowed values are "s3" and "file".
 	ExternalConfigTypeOption = "config-file-type"
@@ -52,7 +56,7 @@ type FirelensResource struct{}
 // NewFirelensResource returns a new FirelensResource.
 func NewFirelensResource(cluster, taskARN, taskDefinition, ec2InstanceID, dataDir, firelensConfigType, region, networkMode string,
 	firelensOptions map[string]string, containerToLogOptions map[string]map[string]string, credentialsManager credentials.Manager,
-	executionCredentialsID string) (*FirelensResource, error) {
+	executionCredentialsID, logRouterUser string) (*FirelensResource, error) {
 	return nil, errors.New("not implemented")
 }
 
@@ -152,6 +156,17 @@ func (firelens *FirelensResource) UnmarshalJSON(b []byte) error {
 	return errors.New("not implemented")
 }
 
+// GetConfigReloadEnabled returns whether the config of the firelens container is periodically reloaded.
+func (firelens *FirelensResource) GetConfigReloadEnabled() bool {
+	return false
+}
+
+// ReloadConfig reloads the config of the firelens container.
+func (firelens *FirelensResource) ReloadConfig(containerToLogOptions map[string]map[string]string,
+	hasSecretValues bool) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
 // Initialize fills in the resource fields.
 func (firelens *FirelensResource) Initialize(resourceFields *taskresource.ResourceFields,
 	taskKnownStatus status.TaskStatus,
@@ -15,7 +15,10 @@
 package firelens
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -23,6 +26,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	generator "github.com/awslabs/go-config-generator-for-fluentd-and-fluentbit"
 	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
 
@@ -58,13 +63,27 @@ const (
 	// ExternalConfigTypeOption is s3, the value for this option should be an s3 arn; when ExternalConfigTypeOption is
 	// file, the value for this option should be a path to the config file inside the firelens container.
 	externalConfigValueOption = "config-file-value"
+	// configReloadEnableOption is the option that specifies whether the agent periodically regenerates the config
+	// of the firelens container, and signals the container to reload it when it changed.
+	configReloadEnableOption = "enable-config-reload"
+	// fluentbitHotReloadService is the service section prepended to the config of fluentbit firelens containers
+	// with config reload enabled, as fluentbit only reloads its config on SIGHUP when hot reload is enabled.
+	fluentbitHotReloadService = "[SERVICE]\n    Hot_Reload On\n"
 
 	s3DownloadTimeout = 30 * time.Second
+
+	// otelcolDefaultUID is the user the OpenTelemetry Collector images run as when the firelens container
+	// doesn't specify one.
+	otelcolDefaultUID = 10001
+	// fluentDefaultUID is the user the fluentd and fluent bit images run as when the firelens container doesn't
+	// specify one.
+	fluentDefaultUID = 0
 )
 
-// FirelensResource models fluentd/fluentbit firelens container related resources as a task resource.
+// FirelensResource models fluentd/fluentbit/otelcol firelens container related resources as a task resource.
 type FirelensResource struct {
-	// Fields that are specific to firelens resource. They are only set at initialization so are not protected by lock.
+	// Fields that are specific to firelens resource. They are only set at initialization so are not protected by lock,
+	// except containerToLogOptions which is also replaced when the config is reloaded.
 	cluster                string
 	taskARN                string
 	taskDefinition         string
@@ -79,6 +98,8 @@ type FirelensResource struct {
 	externalConfigType     string
 	externalConfigValue    string
 	networkMode            string
+	configReloadEnabled    bool
+	logRouterUID           int
 	ioutil                 ioutilwrapper.IOUtil
 	s3ClientCreator        factory.S3ClientCreator
 
@@ -96,7 +117,7 @@ type FirelensResource struct {
 // NewFirelensResource returns a new FirelensResource.
 func NewFirelensResource(cluster, taskARN, taskDefinition, ec2InstanceID, dataDir, firelensConfigType, region, networkMode string,
 	firelensOptions map[string]string, containerToLogOptions map[string]map[string]string, credentialsManager credentials.Manager,
-	executionCredentialsID string) (*FirelensResource, error) {
+	executionCredentialsID, logRouterUser string) (*FirelensResource, error) {
 	firelensResource := &FirelensResource{
 		cluster:                cluster,
 		taskARN:                taskARN,
@@ -121,10 +142,32 @@ func NewFirelensResource(cluster, taskARN, taskDefinition, ec2InstanceID, dataDi
 		return nil, errors.Wrap(err, "error parsing firelens options")
 	}
 
+	defaultUID := fluentDefaultUID
+	if firelensConfigType == FirelensConfigTypeOtelcol {
+		defaultUID = otelcolDefaultUID
+	}
+	firelensResource.logRouterUID, err = parseLogRouterUID(logRouterUser, defaultUID)
+	if err != nil {
+		return nil, err
+	}
+
 	firelensResource.initStatusToTransition()
 	return firelensResource, nil
 }
 
+// parseLogRouterUID returns the uid the log router container runs as, or the default uid of its image when no user
+// is set. The user has to be numeric as the agent can't look up names in the passwd file of the log router image.
+func parseLogRouterUID(user string, defaultUID int) (int, error) {
+	if user == "" {
+		return defaultUID, nil
+	}
+	uid, err := strconv.Atoi(strings.SplitN(user, ":", 2)[0])
+	if err != nil || uid < 0 {
+		return 0, errors.Errorf("user %s of the firelens container must be a numeric uid or uid:gid", user)
+	}
+	return uid, nil
+}
+
 func (firelens *FirelensResource) parseOptions(options map[string]string) error {
 	if _, ok := options[ecsLogMetadataEnableOption]; ok {
 		val := options[ecsLogMetadataEnableOption]
@@ -142,6 +185,11 @@ func (firelens *FirelensResource) parseOptions(options map[string]string) error
 		if externalConfigType != ExternalConfigTypeS3 && externalConfigType != ExternalConfigTypeFile {
 			return errors.Errorf("invalid value %s is specified for option %s", externalConfigType, ExternalConfigTypeOption)
 		}
+		// The OpenTelemetry Collector can't include other config files in its config.
+		if firelens.firelensConfigType == FirelensConfigTypeOtelcol {
+			return errors.Errorf("option %s is not supported for firelens configuration type %s",
+				ExternalConfigTypeOption, FirelensConfigTypeOtelcol)
+		}
 		firelens.externalConfigType = externalConfigType
 
 		externalConfigValue, ok := options[externalConfigValueOption]
@@ -151,6 +199,15 @@ func (firelens *FirelensResource) parseOptions(options map[string]string) error
 		firelens.externalConfigValue = externalConfigValue
 	}
 
+	if val, ok := options[configReloadEnableOption]; ok {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			seelog.Warnf("Invalid value for firelens container option %s was specified: %s. Ignoring it.", configReloadEnableOption, val)
+		} else {
+			firelens.configReloadEnabled = b
+		}
+	}
+
 	return nil
 }
 
@@ -186,6 +243,9 @@ func (firelens *FirelensResource) GetECSMetadataEnabled() bool {
 
 // GetContainerToLogOptions returns a map of containers' log options.
 func (firelens *FirelensResource) GetContainerToLogOptions() map[string]map[string]string {
+	firelens.lock.RLock()
+	defer firelens.lock.RUnlock()
+
 	return firelens.containerToLogOptions
 }
 
@@ -205,6 +265,11 @@ func (firelens *FirelensResource) GetExternalConfigValue() string {
 	return firelens.externalConfigValue
 }
 
+// GetConfigReloadEnabled returns whether the config of the firelens container is periodically reloaded.
+func (firelens *FirelensResource) GetConfigReloadEnabled() bool {
+	return firelens.configReloadEnabled
+}
+
 // Initialize initializes the resource.
 func (firelens *FirelensResource) Initialize(resourceFields *taskresource.ResourceFields,
 	taskKnownStatus status.TaskStatus, taskDesiredStatus status.TaskStatus) {
@@ -395,7 +460,8 @@ func (firelens *FirelensResource) GetCreatedAt() time.Time {
 func (firelens *FirelensResource) Create() error {
 	// Fail fast if firelens configuration type is invalid.
 	if firelens.firelensConfigType != FirelensConfigTypeFluentd &&
-		firelens.firelensConfigType != FirelensConfigTypeFluentbit {
+		firelens.firelensConfigType != FirelensConfigTypeFluentbit &&
+		firelens.firelensConfigType != FirelensConfigTypeOtelcol {
 		err := errors.New(fmt.Sprintf("invalid firelens configuration type: %s", firelens.firelensConfigType))
 		firelens.setTerminalReason(err.Error())
 		return err
@@ -429,6 +495,10 @@ func (firelens *FirelensResource) Create() error {
 
 var mkdirAll = os.MkdirAll
 
+var chmod = os.Chmod
+
+var chown = os.Chown
+
 // createDirectories creates two directories:
 //  - $(DATA_DIR)/firelens/$(TASK_ID)/config: used to store firelens config file. The config file under this directory
 //    will be mounted to the firelens container at an expected path.
@@ -451,12 +521,29 @@ func (firelens *FirelensResource) createDirectories() error {
 	if err != nil {
 		return errors.Wrap(err, "unable to create socket directory")
 	}
+
+	// The OpenTelemetry Collector images don't run as root, unlike the fluentd ones for which FLUENT_UID is set,
+	// so the socket directory is handed over to the user of the collector for it to create its socket there.
+	if firelens.firelensConfigType == FirelensConfigTypeOtelcol {
+		err = chown(socketDir, firelens.logRouterUID, -1)
+		if err != nil {
+			return errors.Wrap(err, "unable to set owner of socket directory")
+		}
+		err = chmod(socketDir, 0700)
+		if err != nil {
+			return errors.Wrap(err, "unable to set permissions of socket directory")
+		}
+	}
 	return nil
 }
 
 // generateConfigFile generates a firelens config file at $(RESOURCE_DIR)/config/fluent.conf.
 // This contains configs needed by the firelens container.
 func (firelens *FirelensResource) generateConfigFile() error {
+	if firelens.firelensConfigType == FirelensConfigTypeOtelcol {
+		return firelens.generateOtelcolConfigFile()
+	}
+
 	config, err := firelens.generateConfig()
 	if err != nil {
 		return errors.Wrap(err, "unable to generate firelens config")
@@ -464,11 +551,39 @@ func (firelens *FirelensResource) generateConfigFile() error {
 
 	confFilePath := filepath.Join(firelens.resourceDir, "config", "fluent.conf")
 	err = firelens.writeConfigFile(func(file oswrapper.File) error {
-		if firelens.firelensConfigType == FirelensConfigTypeFluentd {
-			return config.WriteFluentdConfig(file)
-		} else {
-			return config.WriteFluentBitConfig(file)
+		return firelens.writeFluentConfig(config, file)
+	}, confFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to generate firelens config file")
+	}
+
+	seelog.Infof("Generated firelens config file at: %s", confFilePath)
+	return nil
+}
+
+// writeFluentConfig writes the fluentd or fluentbit config to w.
+func (firelens *FirelensResource) writeFluentConfig(config generator.FluentConfig, w io.Writer) error {
+	if firelens.firelensConfigType == FirelensConfigTypeFluentd {
+		return config.WriteFluentdConfig(w)
+	}
+	if firelens.configReloadEnabled {
+		if _, err := io.WriteString(w, fluentbitHotReloadService); err != nil {
+			return err
 		}
+	}
+	return config.WriteFluentBitConfig(w)
+}
+
+// generateOtelcolConfigFile generates an OpenTelemetry Collector config file at $(RESOURCE_DIR)/config/otelcol.yaml.
+func (firelens *FirelensResource) generateOtelcolConfigFile() error {
+	config, err := firelens.generateOtelcolConfig()
+	if err != nil {
+		return errors.Wrap(err, "unable to generate firelens config")
+	}
+
+	confFilePath := filepath.Join(firelens.resourceDir, "config", OtelcolConfigFile)
+	err = firelens.writeConfigFile(func(file oswrapper.File) error {
+		return writeOtelcolConfig(config, file)
 	}, confFilePath)
 	if err != nil {
 		return errors.Wrapf(err, "unable to generate firelens config file")
@@ -481,32 +596,168 @@ func (firelens *FirelensResource) generateConfigFile() error {
 // downloadConfigFromS3 downloads an external config file from S3 and saves it at ${RESOURCE_DIR}/config/external.conf.
 // The generated firelens config file fluent.conf will have a reference to include this file.
 func (firelens *FirelensResource) downloadConfigFromS3() error {
+	download, err := firelens.s3ConfigDownloader()
+	if err != nil {
+		return err
+	}
+
+	confFilePath := filepath.Join(firelens.resourceDir, "config", "external.conf")
+	err = firelens.writeConfigFile(func(file oswrapper.File) error {
+		return download(file)
+	}, confFilePath)
+	if err != nil {
+		return err
+	}
+
+	seelog.Debugf("Downloaded firelens config file from s3 and saved to: %s", confFilePath)
+	return nil
+}
+
+// s3ConfigDownloader returns a function that downloads the external config file from S3 to a writer.
+func (firelens *FirelensResource) s3ConfigDownloader() (func(w io.WriterAt) error, error) {
 	creds, ok := firelens.credentialsManager.GetTaskCredentials(firelens.executionCredentialsID)
 	if !ok {
-		return errors.New("unable to get execution role credentials")
+		return nil, errors.New("unable to get execution role credentials")
 	}
 
 	bucket, key, err := s3.ParseS3ARN(firelens.externalConfigValue)
 	if err != nil {
-		return errors.Wrap(err, "unable to parse bucket and key from s3 arn")
+		return nil, errors.Wrap(err, "unable to parse bucket and key from s3 arn")
 	}
 
 	s3Client, err := firelens.s3ClientCreator.NewS3ClientForBucket(bucket, firelens.region, creds.GetIAMRoleCredentials())
 	if err != nil {
-		return errors.Wrapf(err, "unable to initialize s3 client for bucket %s", bucket)
+		return nil, errors.Wrapf(err, "unable to initialize s3 client for bucket %s", bucket)
 	}
 
-	confFilePath := filepath.Join(firelens.resourceDir, "config", "external.conf")
-	err = firelens.writeConfigFile(func(file oswrapper.File) error {
-		return s3.DownloadFile(bucket, key, s3DownloadTimeout, file, s3Client)
-	}, confFilePath)
+	return func(w io.WriterAt) error {
+		err := s3.DownloadFile(bucket, key, s3DownloadTimeout, w, s3Client)
+		if err != nil {
+			return errors.Wrapf(err, "unable to download s3 config %s from bucket %s", key, bucket)
+		}
+		return nil
+	}, nil
+}
+
+var readFile = ioutil.ReadFile
+
+// ReloadConfig downloads the external config file from S3 again and regenerates the config file of the firelens
+// container from the given log options, and returns whether either of them changed. When the log options hold secret
+// values instead of references to the environment of the firelens container, the config file is made readable by the
+// log router user only.
+func (firelens *FirelensResource) ReloadConfig(containerToLogOptions map[string]map[string]string,
+	hasSecretValues bool) (bool, error) {
+	firelens.lock.Lock()
+	firelens.containerToLogOptions = containerToLogOptions
+	firelens.lock.Unlock()
+
+	configDir := filepath.Join(firelens.resourceDir, "config")
+	changed := false
+	if firelens.externalConfigType == ExternalConfigTypeS3 {
+		download, err := firelens.s3ConfigDownloader()
+		if err != nil {
+			return false, errors.Wrap(err, "unable to download firelens s3 config file")
+		}
+		buf := aws.NewWriteAtBuffer([]byte{})
+		err = download(buf)
+		if err != nil {
+			return false, errors.Wrap(err, "unable to download firelens s3 config file")
+		}
+		changed, err = firelens.updateConfigFile(filepath.Join(configDir, "external.conf"), buf.Bytes(), false)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var buf bytes.Buffer
+	confFilePath := filepath.Join(configDir, "fluent.conf")
+	if firelens.firelensConfigType == FirelensConfigTypeOtelcol {
+		confFilePath = filepath.Join(configDir, OtelcolConfigFile)
+		config, err := firelens.generateOtelcolConfig()
+		if err != nil {
+			return false, errors.Wrap(err, "unable to generate firelens config")
+		}
+		err = writeOtelcolConfig(config, &buf)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		config, err := firelens.generateConfig()
+		if err != nil {
+			return false, errors.Wrap(err, "unable to generate firelens config")
+		}
+		err = firelens.writeFluentConfig(config, &buf)
+		if err != nil {
+			return false, err
+		}
+	}
+	updated, err := firelens.updateConfigFile(confFilePath, buf.Bytes(), hasSecretValues)
+	if err != nil {
+		return false, err
+	}
+	return changed || updated, nil
+}
+
+var remove = os.Remove
 
+// updateConfigFile replaces a config file if its content changed, and returns whether it did. The new content is
+// written to a temp file in the same directory, which is renamed over the config file once it's complete. When it
+// holds secret values, the temp file is made readable by the log router user only before anything is written to it,
+// so that the secrets are never exposed if that fails.
+func (firelens *FirelensResource) updateConfigFile(filePath string, content []byte, private bool) (bool, error) {
+	current, err := readFile(filePath)
 	if err != nil {
-		return errors.Wrapf(err, "unable to download s3 config %s from bucket %s", key, bucket)
+		return false, errors.Wrapf(err, "unable to read firelens config file %s", filePath)
+	}
+	if bytes.Equal(current, content) {
+		return false, nil
 	}
 
-	seelog.Debugf("Downloaded firelens config file from s3 and saved to: %s", confFilePath)
-	return nil
+	temp, err := firelens.ioutil.TempFile(filepath.Dir(filePath), tempFile)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to create temp file for firelens config file %s", filePath)
+	}
+	defer temp.Close()
+
+	err = firelens.writeTempConfigFile(temp, content, private)
+	if err != nil {
+		remove(temp.Name())
+		return false, errors.Wrapf(err, "unable to update firelens config file %s", filePath)
+	}
+
+	err = rename(temp.Name(), filePath)
+	if err != nil {
+		remove(temp.Name())
+		return false, errors.Wrapf(err, "unable to update firelens config file %s", filePath)
+	}
+	seelog.Infof("Updated firelens config file at: %s", filePath)
+	return true, nil
+}
+
+// writeTempConfigFile sets the owner and permissions of a new temp config file and writes the content to it.
+func (firelens *FirelensResource) writeTempConfigFile(temp oswrapper.File, content []byte, private bool) error {
+	if private {
+		err := temp.Chmod(0600)
+		if err != nil {
+			return errors.Wrap(err, "unable to set permissions")
+		}
+		err = chown(temp.Name(), firelens.logRouterUID, -1)
+		if err != nil {
+			return errors.Wrap(err, "unable to set owner")
+		}
+	} else {
+		err := temp.Chmod(os.FileMode(configFilePerm))
+		if err != nil {
+			return errors.Wrap(err, "unable to set permissions")
+		}
+	}
+
+	_, err := temp.Write(content)
+	if err != nil {
+		return err
+	}
+	// Persist the config file to disk.
+	return temp.Sync()
 }
 
 var rename = os.Rename
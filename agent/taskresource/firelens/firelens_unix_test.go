@@ -15,8 +15,13 @@
 package firelens
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -210,6 +215,72 @@ func TestCreateFirelensResourceFluentbit(t *testing.T) {
 	assert.NoError(t, firelensResource.Create())
 }
 
+func TestCreateFirelensResourceOtelcol(t *testing.T) {
+	mockFile, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeOtelcol, bridgeNetworkMode, testOtelcolOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+
+	firelensResource.logRouterUID = otelcolDefaultUID
+
+	defer mockRename()()
+	var chmodPath, chownPath string
+	var chmodMode os.FileMode
+	var chownUID int
+	chmod = func(name string, mode os.FileMode) error {
+		chmodPath = name
+		chmodMode = mode
+		return nil
+	}
+	chown = func(name string, uid, gid int) error {
+		chownPath = name
+		chownUID = uid
+		return nil
+	}
+	defer func() {
+		chmod = os.Chmod
+		chown = os.Chown
+	}()
+	gomock.InOrder(
+		mockIOUtil.EXPECT().TempFile(testResourceDir, tempFile).Return(mockFile, nil),
+	)
+
+	assert.NoError(t, firelensResource.Create())
+	assert.Equal(t, testResourceDir+"/socket", chownPath)
+	assert.Equal(t, otelcolDefaultUID, chownUID)
+	assert.Equal(t, testResourceDir+"/socket", chmodPath)
+	assert.Equal(t, os.FileMode(0700), chmodMode)
+}
+
+func TestParseLogRouterUID(t *testing.T) {
+	testCases := []struct {
+		user        string
+		defaultUID  int
+		expectedUID int
+		shouldError bool
+	}{
+		{user: "", defaultUID: otelcolDefaultUID, expectedUID: otelcolDefaultUID},
+		{user: "", defaultUID: fluentDefaultUID, expectedUID: fluentDefaultUID},
+		{user: "1000", expectedUID: 1000},
+		{user: "1000:1000", expectedUID: 1000},
+		{user: "otel", shouldError: true},
+		{user: "-1", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.user, func(t *testing.T) {
+			uid, err := parseLogRouterUID(tc.user, tc.defaultUID)
+			if tc.shouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedUID, uid)
+		})
+	}
+}
+
 func TestCreateFirelensResourceInvalidType(t *testing.T) {
 	_, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
 	defer done()
@@ -442,6 +513,194 @@ func TestCreateFirelensResourceWithS3ConfigDownloadFailure(t *testing.T) {
 	assert.NotEmpty(t, firelensResource.terminalReason)
 }
 
+func TestReloadConfigWithS3Config(t *testing.T) {
+	mockFile, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, mockS3Client, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeFluentbit, bridgeNetworkMode, testFluentbitOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+	err := firelensResource.parseOptions(map[string]string{
+		"config-file-type":     "s3",
+		"config-file-value":    "arn:aws:s3:::bucket/key",
+		"enable-config-reload": "true",
+	})
+	require.NoError(t, err)
+	assert.True(t, firelensResource.GetConfigReloadEnabled())
+
+	generatedConfig, err := firelensResource.generateConfig()
+	require.NoError(t, err)
+	var config bytes.Buffer
+	require.NoError(t, firelensResource.writeFluentConfig(generatedConfig, &config))
+	assert.True(t, strings.HasPrefix(config.String(), fluentbitHotReloadService))
+	readFile = func(filename string) ([]byte, error) {
+		if filename == filepath.Join(testResourceDir, "config", "fluent.conf") {
+			return config.Bytes(), nil
+		}
+		return []byte("old external config"), nil
+	}
+	defer func() {
+		readFile = ioutil.ReadFile
+	}()
+
+	creds := credentials.TaskIAMRoleCredentials{
+		ARN: "arn",
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			AccessKeyID:     "id",
+			SecretAccessKey: "key",
+		},
+	}
+	gomock.InOrder(
+		mockCredentialsManager.EXPECT().GetTaskCredentials(testExecutionCredentialsID).Return(creds, true),
+		mockS3ClientCreator.EXPECT().NewS3ClientForBucket("bucket", testRegion, creds.IAMRoleCredentials).Return(mockS3Client, nil),
+		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput) {
+				w.WriteAt([]byte("new external config"), 0)
+			}).Return(int64(0), nil),
+		// only the external config file changed
+		mockIOUtil.EXPECT().TempFile(filepath.Join(testResourceDir, "config"), tempFile).Return(mockFile, nil),
+	)
+	var renamedPath string
+	rename = func(oldpath, newpath string) error {
+		renamedPath = newpath
+		return nil
+	}
+	defer func() {
+		rename = os.Rename
+	}()
+
+	changed, err := firelensResource.ReloadConfig(firelensResource.containerToLogOptions, false)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, filepath.Join(testResourceDir, "config", "external.conf"), renamedPath)
+}
+
+func TestReloadConfigUnchanged(t *testing.T) {
+	_, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeFluentd, bridgeNetworkMode, testFluentdOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+	generatedConfig, err := firelensResource.generateConfig()
+	require.NoError(t, err)
+	var config bytes.Buffer
+	require.NoError(t, firelensResource.writeFluentConfig(generatedConfig, &config))
+	readFile = func(filename string) ([]byte, error) {
+		return config.Bytes(), nil
+	}
+	defer func() {
+		readFile = ioutil.ReadFile
+	}()
+
+	changed, err := firelensResource.ReloadConfig(firelensResource.containerToLogOptions, false)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestReloadConfigWithSecretValues(t *testing.T) {
+	_, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeFluentbit, bridgeNetworkMode, testFluentbitOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+	firelensResource.logRouterUID = 1000
+	readFile = func(filename string) ([]byte, error) {
+		return []byte("stale"), nil
+	}
+	var steps []string
+	var chownUID int
+	chown = func(name string, uid, gid int) error {
+		steps = append(steps, "chown "+name)
+		chownUID = uid
+		return nil
+	}
+	rename = func(oldpath, newpath string) error {
+		steps = append(steps, "rename "+oldpath+" "+newpath)
+		return nil
+	}
+	defer func() {
+		readFile = ioutil.ReadFile
+		chown = os.Chown
+		rename = os.Rename
+	}()
+
+	confFilePath := filepath.Join(testResourceDir, "config", "fluent.conf")
+	mockFile := &mock_oswrapper.MockFile{
+		NameImpl: func() string {
+			return testTempFile
+		},
+		ChmodImpl: func(mode os.FileMode) error {
+			steps = append(steps, fmt.Sprintf("chmod %o", mode))
+			return nil
+		},
+		WriteImpl: func(content []byte) (int, error) {
+			assert.Contains(t, string(content), "rotated-secret")
+			steps = append(steps, "write")
+			return len(content), nil
+		},
+		SyncImpl: func() error {
+			return nil
+		},
+	}
+	mockIOUtil.EXPECT().TempFile(filepath.Join(testResourceDir, "config"), tempFile).Return(mockFile, nil)
+
+	changed, err := firelensResource.ReloadConfig(map[string]map[string]string{
+		"container": {
+			"Name":   "cloudwatch",
+			"secret": "rotated-secret",
+		},
+	}, true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 1000, chownUID)
+	assert.Equal(t, []string{
+		"chmod 600",
+		"chown " + testTempFile,
+		"write",
+		"rename " + testTempFile + " " + confFilePath,
+	}, steps)
+}
+
+func TestReloadConfigWithSecretValuesChownError(t *testing.T) {
+	mockFile, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeFluentbit, bridgeNetworkMode, testFluentbitOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+	readFile = func(filename string) ([]byte, error) {
+		return []byte("stale"), nil
+	}
+	chown = func(name string, uid, gid int) error {
+		return errors.New("test error")
+	}
+	renamed := false
+	rename = func(oldpath, newpath string) error {
+		renamed = true
+		return nil
+	}
+	var removedPath string
+	remove = func(name string) error {
+		removedPath = name
+		return nil
+	}
+	defer func() {
+		readFile = ioutil.ReadFile
+		chown = os.Chown
+		rename = os.Rename
+		remove = os.Remove
+	}()
+	mockIOUtil.EXPECT().TempFile(filepath.Join(testResourceDir, "config"), tempFile).Return(mockFile, nil)
+
+	_, err := firelensResource.ReloadConfig(map[string]map[string]string{
+		"container": {
+			"Name":   "cloudwatch",
+			"secret": "rotated-secret",
+		},
+	}, true)
+	assert.Error(t, err)
+	assert.False(t, renamed, "config file should not be replaced when the temp file can't be handed over")
+	assert.Equal(t, mockFile.Name(), removedPath)
+}
+
 func TestCleanupFirelensResource(t *testing.T) {
 	_, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
 	defer done()
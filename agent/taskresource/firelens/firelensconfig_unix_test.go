@@ -304,7 +304,7 @@ func TestGenerateFluentdBridgeModeConfig(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentd, testRegion, bridgeNetworkMode, testFirelensOptionsFile, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	config, err := firelensResource.generateConfig()
@@ -323,7 +323,7 @@ func TestGenerateFluentdAWSVPCModeConfig(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentd, testRegion, awsvpcNetworkMode, testFirelensOptionsFile, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	config, err := firelensResource.generateConfig()
@@ -342,7 +342,7 @@ func TestGenerateFluentdDefaultModeConfig(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentd, testRegion, "", testFirelensOptionsFile, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	config, err := firelensResource.generateConfig()
@@ -361,7 +361,7 @@ func TestGenerateFluentbitConfig(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentbit, testRegion, bridgeNetworkMode, testFirelensOptionsS3, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	config, err := firelensResource.generateConfig()
@@ -382,7 +382,7 @@ func TestGenerateFluentdConfigMissingOutputName(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentd, testRegion, bridgeNetworkMode, testFirelensOptionsFile, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	_, err = firelensResource.generateConfig()
@@ -398,7 +398,7 @@ func TestGenerateFLuentbitConfigMissingOutputName(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentbit, testRegion, bridgeNetworkMode, testFirelensOptionsFile, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	_, err = firelensResource.generateConfig()
@@ -417,7 +417,7 @@ func TestGenerateConfigWithECSMetadataDisabled(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentd, testRegion, bridgeNetworkMode, testFirelensOptions, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	config, err := firelensResource.generateConfig()
@@ -439,7 +439,7 @@ func TestGenerateConfigWithoutOutputSection(t *testing.T) {
 
 	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
 		testDataDir, FirelensConfigTypeFluentbit, testRegion, bridgeNetworkMode, testFirelensOptionsS3, containerToLogOptions,
-		nil, testExecutionCredentialsID)
+		nil, testExecutionCredentialsID, "")
 	require.NoError(t, err)
 
 	config, err := firelensResource.generateConfig()
@@ -43,6 +43,9 @@ type firelensResourceJSON struct {
 	KnownStatus   *FirelensStatus
 	AppliedStatus *FirelensStatus
 	NetworkMode   string
+
+	ConfigReloadEnabled bool `json:",omitempty"`
+	LogRouterUID        int  `json:",omitempty"`
 }
 
 // MarshalJSON marshals a FirelensResource object into bytes of json.
@@ -70,6 +73,8 @@ func (firelens *FirelensResource) MarshalJSON() ([]byte, error) {
 		TerminalReason:         firelens.terminalReason,
 		CreatedAt:              firelens.createdAtUnsafe,
 		NetworkMode:            firelens.networkMode,
+		ConfigReloadEnabled:    firelens.configReloadEnabled,
+		LogRouterUID:           firelens.logRouterUID,
 		DesiredStatus: func() *FirelensStatus {
 			desiredStatus := firelens.desiredStatusUnsafe
 			s := FirelensStatus(desiredStatus)
@@ -120,6 +125,8 @@ func (firelens *FirelensResource) UnmarshalJSON(b []byte) error {
 	firelens.knownStatusUnsafe = resourcestatus.ResourceStatus(*temp.KnownStatus)
 	firelens.appliedStatusUnsafe = resourcestatus.ResourceStatus(*temp.AppliedStatus)
 	firelens.networkMode = temp.NetworkMode
+	firelens.configReloadEnabled = temp.ConfigReloadEnabled
+	firelens.logRouterUID = temp.LogRouterUID
 
 	return nil
 }
@@ -50,6 +50,7 @@ func TestMarshalUnmarshalJSON(t *testing.T) {
 		knownStatusUnsafe:      resourcestatus.ResourceCreated,
 		appliedStatusUnsafe:    resourcestatus.ResourceCreated,
 		networkMode:            bridgeNetworkMode,
+		configReloadEnabled:    true,
 	}
 
 	bytes, err := json.Marshal(firelensResIn)
@@ -75,4 +76,5 @@ func TestMarshalUnmarshalJSON(t *testing.T) {
 	assert.Equal(t, resourcestatus.ResourceCreated, firelensResOut.appliedStatusUnsafe)
 	assert.Equal(t, testTerminalResason, firelensResOut.terminalReason)
 	assert.Equal(t, bridgeNetworkMode, firelensResOut.networkMode)
+	assert.True(t, firelensResOut.configReloadEnabled)
 }
@@ -0,0 +1,261 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package firelens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FirelensConfigTypeOtelcol is the type of an OpenTelemetry Collector firelens container.
+	FirelensConfigTypeOtelcol = "otelcol"
+
+	// OtelcolConfigFile is the name of the config file generated for an OpenTelemetry Collector firelens container.
+	OtelcolConfigFile = "otelcol.yaml"
+
+	// exporterLogOptionKeyOtelcol is the key for the log option that specifies the exporter type for the
+	// OpenTelemetry Collector.
+	exporterLogOptionKeyOtelcol = "exporter"
+
+	// socketPathPrefixOtelcol is the prefix of the endpoint of a receiver listening on a unix socket.
+	socketPathPrefixOtelcol = "unix://"
+
+	// receiverNameForward is the name of the receiver of the logs sent by the fluentd log driver of the containers.
+	receiverNameForward = "fluentforward"
+	// receiverNameForwardTCP is the name of the fluentforward receiver listening on the tcp socket.
+	receiverNameForwardTCP = "fluentforward/tcp"
+	// receiverNameOTLP is the name of the receiver of the telemetry the containers send with the OTLP protocol.
+	receiverNameOTLP = "otlp"
+	// otlpGRPCPortValue and otlpHTTPPortValue are the ports of the OTLP receiver.
+	otlpGRPCPortValue = "4317"
+	otlpHTTPPortValue = "4318"
+
+	// processorNameECSMetadata is the name of the processor adding ecs metadata to the telemetry.
+	processorNameECSMetadata = "resource/ecs"
+	// processorNameBatch is the name of the processor batching telemetry before it's exported.
+	processorNameBatch = "batch"
+
+	// fluentTagAttribute is the attribute in which the fluentforward receiver records the tag of a log.
+	fluentTagAttribute = "fluent.tag"
+)
+
+// otlpExporterTypes are the exporters that can export all telemetry signals received through OTLP.
+var otlpExporterTypes = map[string]bool{
+	"otlp":     true,
+	"otlphttp": true,
+}
+
+// generateOtelcolConfig generates the config of an OpenTelemetry Collector firelens container. The logs of each
+// container using the firelens container are received from their fluentd log driver, like for fluentd and fluentbit,
+// and go through a pipeline exporting them with the exporter specified in the log options of the container. The
+// traces, metrics and logs the containers send with the OTLP protocol are exported with the OTLP exporters of
+// these pipelines.
+func (firelens *FirelensResource) generateOtelcolConfig() (map[string]interface{}, error) {
+	receivers := map[string]interface{}{
+		receiverNameForward: map[string]interface{}{
+			"endpoint": socketPathPrefixOtelcol + socketPath,
+		},
+	}
+	forwardReceivers := []string{receiverNameForward}
+	// As for fluentd and fluentbit, containers can also send logs to the tcp socket in bridge and awsvpc modes.
+	otlpBindValue := inputAWSVPCBindValue
+	if firelens.networkMode == bridgeNetworkMode || firelens.networkMode == awsvpcNetworkMode {
+		forwardBindValue := inputAWSVPCBindValue
+		if firelens.networkMode == bridgeNetworkMode {
+			forwardBindValue = inputBridgeBindValue
+			otlpBindValue = inputBridgeBindValue
+		}
+		receivers[receiverNameForwardTCP] = map[string]interface{}{
+			"endpoint": forwardBindValue + ":" + inputPortValue,
+		}
+		forwardReceivers = append(forwardReceivers, receiverNameForwardTCP)
+	}
+
+	processors := map[string]interface{}{
+		processorNameBatch: map[string]interface{}{},
+	}
+	var commonProcessors []string
+	if firelens.ecsMetadataEnabled {
+		// Add ecs metadata fields to the telemetry.
+		attributes := []interface{}{
+			upsertAttribute("ecs_cluster", firelens.cluster),
+			upsertAttribute("ecs_task_arn", firelens.taskARN),
+			upsertAttribute("ecs_task_definition", firelens.taskDefinition),
+		}
+		if firelens.ec2InstanceID != "" {
+			attributes = append(attributes, upsertAttribute("ec2_instance_id", firelens.ec2InstanceID))
+		}
+		processors[processorNameECSMetadata] = map[string]interface{}{
+			"attributes": attributes,
+		}
+		commonProcessors = append(commonProcessors, processorNameECSMetadata)
+	}
+	commonProcessors = append(commonProcessors, processorNameBatch)
+
+	// Sort the containers so that the config is the same every time it's generated.
+	var containerNames []string
+	for containerName := range firelens.containerToLogOptions {
+		containerNames = append(containerNames, containerName)
+	}
+	sort.Strings(containerNames)
+
+	exporters := make(map[string]interface{})
+	pipelines := make(map[string]interface{})
+	var otlpExporters []string
+	for _, containerName := range containerNames {
+		exporterType, exporter, filterConditions, err := parseOtelcolLogOptions(firelens.containerToLogOptions[containerName])
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply log options of container %s to firelens config: %v", containerName, err)
+		}
+		if exporterType == "" {
+			continue
+		}
+		exporterName := exporterType + "/" + containerName
+		exporters[exporterName] = exporter
+		if otlpExporterTypes[exporterType] {
+			otlpExporters = append(otlpExporters, exporterName)
+		}
+
+		// Only keep the logs of the container in its pipeline, the tag of the logs is set by its log driver.
+		filterName := "filter/" + containerName
+		filterConditions = append([]string{fmt.Sprintf(`not IsMatch(attributes["%s"], "^%s-firelens")`,
+			fluentTagAttribute, containerName)}, filterConditions...)
+		processors[filterName] = map[string]interface{}{
+			"error_mode": "ignore",
+			"logs": map[string]interface{}{
+				"log_record": filterConditions,
+			},
+		}
+		pipelines["logs/"+containerName] = map[string]interface{}{
+			"receivers":  forwardReceivers,
+			"processors": append([]string{filterName}, commonProcessors...),
+			"exporters":  []string{exporterName},
+		}
+	}
+	if len(pipelines) == 0 {
+		return nil, errors.Errorf("no container using the firelens container specifies log option %s, "+
+			"which is required for firelens configuration of type %s", exporterLogOptionKeyOtelcol, FirelensConfigTypeOtelcol)
+	}
+
+	if len(otlpExporters) > 0 {
+		receivers[receiverNameOTLP] = map[string]interface{}{
+			"protocols": map[string]interface{}{
+				"grpc": map[string]interface{}{"endpoint": otlpBindValue + ":" + otlpGRPCPortValue},
+				"http": map[string]interface{}{"endpoint": otlpBindValue + ":" + otlpHTTPPortValue},
+			},
+		}
+		for _, signal := range []string{"traces", "metrics", "logs/otlp"} {
+			pipelines[signal] = map[string]interface{}{
+				"receivers":  []string{receiverNameOTLP},
+				"processors": commonProcessors,
+				"exporters":  otlpExporters,
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"receivers":  receivers,
+		"processors": processors,
+		"exporters":  exporters,
+		"service": map[string]interface{}{
+			"pipelines": pipelines,
+		},
+	}, nil
+}
+
+// parseOtelcolLogOptions returns the type and the settings of the exporter specified in the log options of a
+// container, and the conditions of the logs of the container to drop.
+// logOptions is a set of key-value pairs, which includes the following:
+//  1. exporter (required when there are exporter settings specified, i.e. the ones in 4): the type of the exporter.
+//  2. include-pattern (optional): a regex specifying the logs to be included.
+//  3. exclude-pattern (optional): a regex specifying the logs to be excluded.
+//  4. All other key-value pairs are customer specified settings for the exporter. Keys containing dots specify
+//     nested settings, e.g. "headers.x-api-key".
+func parseOtelcolLogOptions(logOptions map[string]string) (string, map[string]interface{}, []string, error) {
+	exporter := make(map[string]interface{})
+	var filterConditions []string
+	for key, value := range logOptions {
+		switch key {
+		case exporterLogOptionKeyOtelcol:
+			continue
+		case includePatternKey:
+			filterConditions = append(filterConditions, fmt.Sprintf("not IsMatch(body, %s)", ottlString(value)))
+		case excludePatternKey:
+			filterConditions = append(filterConditions, fmt.Sprintf("IsMatch(body, %s)", ottlString(value)))
+		default: // This is an exporter specific setting.
+			setExporterSetting(exporter, strings.Split(key, "."), value)
+		}
+	}
+	sort.Strings(filterConditions)
+
+	exporterType, ok := logOptions[exporterLogOptionKeyOtelcol]
+	// If there are some exporter settings specified, there must be an exporter type.
+	if len(exporter) > 0 && !ok {
+		return "", nil, nil, errors.Errorf("missing output key %s which is required for firelens configuration of type %s",
+			exporterLogOptionKeyOtelcol, FirelensConfigTypeOtelcol)
+	}
+	return exporterType, exporter, filterConditions, nil
+}
+
+// setExporterSetting sets a setting of an exporter, creating the maps of the nested settings it belongs to.
+// Booleans and numbers are set as such, since the collector doesn't convert strings to them.
+func setExporterSetting(settings map[string]interface{}, path []string, value string) {
+	for _, key := range path[:len(path)-1] {
+		nested, ok := settings[key].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			settings[key] = nested
+		}
+		settings = nested
+	}
+	key := path[len(path)-1]
+	if value == "true" || value == "false" {
+		settings[key] = value == "true"
+	} else if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		settings[key] = i
+	} else {
+		settings[key] = value
+	}
+}
+
+func upsertAttribute(key, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"key":    key,
+		"value":  value,
+		"action": "upsert",
+	}
+}
+
+// ottlString returns a string literal of the OpenTelemetry Transformation Language.
+func ottlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// writeOtelcolConfig writes the config of an OpenTelemetry Collector firelens container. The config is written as
+// JSON, which is valid YAML.
+func writeOtelcolConfig(config map[string]interface{}, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config)
+}
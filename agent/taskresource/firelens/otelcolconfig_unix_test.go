@@ -0,0 +1,247 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package firelens
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testOtelcolOptions = map[string]string{
+		"exporter":                 "otlphttp",
+		"endpoint":                 "https://otlp.example.com",
+		"headers.x-api-key":        "${env:api-key_0}",
+		"compression":              "gzip",
+		"retry_on_failure.enabled": "true",
+		"include-pattern":          "failure",
+		"exclude-pattern":          `"success"`,
+	}
+
+	expectedOtelcolBridgeModeConfig = `{
+  "exporters": {
+    "otlphttp/container": {
+      "compression": "gzip",
+      "endpoint": "https://otlp.example.com",
+      "headers": {
+        "x-api-key": "${env:api-key_0}"
+      },
+      "retry_on_failure": {
+        "enabled": true
+      }
+    }
+  },
+  "processors": {
+    "batch": {},
+    "filter/container": {
+      "error_mode": "ignore",
+      "logs": {
+        "log_record": [
+          "not IsMatch(attributes[\"fluent.tag\"], \"^container-firelens\")",
+          "IsMatch(body, \"\\\"success\\\"\")",
+          "not IsMatch(body, \"failure\")"
+        ]
+      }
+    },
+    "resource/ecs": {
+      "attributes": [
+        {
+          "action": "upsert",
+          "key": "ecs_cluster",
+          "value": "mycluster"
+        },
+        {
+          "action": "upsert",
+          "key": "ecs_task_arn",
+          "value": "arn:aws:ecs:us-east-2:01234567891011:task/mycluster/3de392df-6bfa-470b-97ed-aa6f482cd7a"
+        },
+        {
+          "action": "upsert",
+          "key": "ecs_task_definition",
+          "value": "taskdefinition:1"
+        },
+        {
+          "action": "upsert",
+          "key": "ec2_instance_id",
+          "value": "i-123456789a"
+        }
+      ]
+    }
+  },
+  "receivers": {
+    "fluentforward": {
+      "endpoint": "unix:///var/run/fluent.sock"
+    },
+    "fluentforward/tcp": {
+      "endpoint": "0.0.0.0:24224"
+    },
+    "otlp": {
+      "protocols": {
+        "grpc": {
+          "endpoint": "0.0.0.0:4317"
+        },
+        "http": {
+          "endpoint": "0.0.0.0:4318"
+        }
+      }
+    }
+  },
+  "service": {
+    "pipelines": {
+      "logs/container": {
+        "exporters": [
+          "otlphttp/container"
+        ],
+        "processors": [
+          "filter/container",
+          "resource/ecs",
+          "batch"
+        ],
+        "receivers": [
+          "fluentforward",
+          "fluentforward/tcp"
+        ]
+      },
+      "logs/otlp": {
+        "exporters": [
+          "otlphttp/container"
+        ],
+        "processors": [
+          "resource/ecs",
+          "batch"
+        ],
+        "receivers": [
+          "otlp"
+        ]
+      },
+      "metrics": {
+        "exporters": [
+          "otlphttp/container"
+        ],
+        "processors": [
+          "resource/ecs",
+          "batch"
+        ],
+        "receivers": [
+          "otlp"
+        ]
+      },
+      "traces": {
+        "exporters": [
+          "otlphttp/container"
+        ],
+        "processors": [
+          "resource/ecs",
+          "batch"
+        ],
+        "receivers": [
+          "otlp"
+        ]
+      }
+    }
+  }
+}
+`
+)
+
+func TestGenerateOtelcolBridgeModeConfig(t *testing.T) {
+	containerToLogOptions := map[string]map[string]string{
+		"container": testOtelcolOptions,
+	}
+
+	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
+		testDataDir, FirelensConfigTypeOtelcol, testRegion, bridgeNetworkMode, nil, containerToLogOptions,
+		nil, testExecutionCredentialsID, "")
+	require.NoError(t, err)
+
+	config, err := firelensResource.generateOtelcolConfig()
+	assert.NoError(t, err)
+
+	configBytes := new(bytes.Buffer)
+	err = writeOtelcolConfig(config, configBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedOtelcolBridgeModeConfig, configBytes.String())
+}
+
+func TestGenerateOtelcolDefaultModeConfig(t *testing.T) {
+	containerToLogOptions := map[string]map[string]string{
+		"app": {
+			"exporter":       "awscloudwatchlogs",
+			"log_group_name": "my-group",
+		},
+		"sidecar": {},
+	}
+
+	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
+		testDataDir, FirelensConfigTypeOtelcol, testRegion, "", map[string]string{"enable-ecs-log-metadata": "false"},
+		containerToLogOptions, nil, testExecutionCredentialsID, "")
+	require.NoError(t, err)
+
+	config, err := firelensResource.generateOtelcolConfig()
+	require.NoError(t, err)
+
+	receivers := config["receivers"].(map[string]interface{})
+	assert.Len(t, receivers, 1, "Only the socket receiver should be added without OTLP exporters in default mode")
+	assert.Contains(t, receivers, receiverNameForward)
+	assert.NotContains(t, config["processors"], processorNameECSMetadata)
+	pipelines := config["service"].(map[string]interface{})["pipelines"].(map[string]interface{})
+	assert.Len(t, pipelines, 1, "Containers without an exporter shouldn't have a pipeline")
+	assert.Equal(t, map[string]interface{}{
+		"receivers":  []string{receiverNameForward},
+		"processors": []string{"filter/app", processorNameBatch},
+		"exporters":  []string{"awscloudwatchlogs/app"},
+	}, pipelines["logs/app"])
+}
+
+func TestGenerateOtelcolConfigMissingExporter(t *testing.T) {
+	containerToLogOptions := map[string]map[string]string{
+		"container": {
+			"endpoint": "https://otlp.example.com",
+		},
+	}
+
+	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
+		testDataDir, FirelensConfigTypeOtelcol, testRegion, bridgeNetworkMode, nil, containerToLogOptions,
+		nil, testExecutionCredentialsID, "")
+	require.NoError(t, err)
+
+	_, err = firelensResource.generateOtelcolConfig()
+	assert.Error(t, err)
+}
+
+func TestGenerateOtelcolConfigWithoutPipeline(t *testing.T) {
+	containerToLogOptions := map[string]map[string]string{
+		"container": {},
+	}
+
+	firelensResource, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
+		testDataDir, FirelensConfigTypeOtelcol, testRegion, bridgeNetworkMode, nil, containerToLogOptions,
+		nil, testExecutionCredentialsID, "")
+	require.NoError(t, err)
+
+	_, err = firelensResource.generateOtelcolConfig()
+	assert.Error(t, err, "The collector can't run without any pipeline")
+}
+
+func TestOtelcolExternalConfigNotSupported(t *testing.T) {
+	_, err := NewFirelensResource(testCluster, testTaskARN, testTaskDefinition, testEC2InstanceID,
+		testDataDir, FirelensConfigTypeOtelcol, testRegion, bridgeNetworkMode, testFirelensOptionsFile, nil,
+		nil, testExecutionCredentialsID, "")
+	assert.Error(t, err)
+}
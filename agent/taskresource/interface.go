@@ -80,6 +80,25 @@ type TaskResource interface {
 	json.Unmarshaler
 }
 
+// RefreshableResource is a task resource whose content can be fetched again while the task is
+// running, so that the containers created afterwards use the new content
+type RefreshableResource interface {
+	TaskResource
+	// Refresh fetches the content of the resource again and replaces the current content with
+	// it. The returned rollback function restores the previous content, and the returned commit
+	// function discards it once the refresh can no longer be rolled back.
+	Refresh() (rollback func() error, commit func(), err error)
+}
+
+// SecretResource is a task resource retrieving the values of the container secrets of a
+// secret provider
+type SecretResource interface {
+	TaskResource
+	// GetCachedSecretValue returns the value of the secret with the given
+	// resource cache key
+	GetCachedSecretValue(secretKey string) (string, bool)
+}
+
 // TransitionDependenciesMap is a map of the dependent resource status to other
 // dependencies that must be satisfied.
 type TransitionDependenciesMap map[resourcestatus.ResourceStatus]TransitionDependencySet
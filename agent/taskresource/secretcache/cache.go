@@ -0,0 +1,120 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package secretcache holds the values of the secrets fetched for tasks for a while,
+// so that the tasks started together with the same secrets, such as the replicas of
+// a service, don't all fetch them.
+package secretcache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// ServiceSSM is the service of the secrets stored in SSM Parameter Store
+	ServiceSSM = "ssm"
+	// ServiceASM is the service of the secrets stored in AWS Secrets Manager
+	ServiceASM = "asm"
+)
+
+// Key identifies a cached secret value. The values are cached per role, so that tasks
+// only read the values their execution role could fetch itself.
+type Key struct {
+	Service string
+	Region  string
+	RoleARN string
+	// ID is the name of the SSM parameter, or the secret id, version stage and version
+	// id of the secret in AWS Secrets Manager
+	ID string
+}
+
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+// Cache holds the secret values for the configured TTL. A nil Cache, or one with no
+// TTL, doesn't cache anything.
+type Cache struct {
+	ttl     time.Duration
+	jitter  time.Duration
+	entries map[Key]entry
+	lock    sync.Mutex
+
+	// now and sleep are replaced by tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// New returns a cache that holds the values for ttl, and delays their fetches by up to
+// jitter
+func New(ttl, jitter time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		jitter:  jitter,
+		entries: make(map[Key]entry),
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// Get returns the cached value of the secret, if it hasn't expired
+func (c *Cache) Get(key Key) (string, bool) {
+	if c == nil || c.ttl <= 0 || key.RoleARN == "" {
+		return "", false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return e.value, true
+}
+
+// Set caches the value of the secret for the TTL of the cache
+func (c *Cache) Set(key Key, value string) {
+	if c == nil || c.ttl <= 0 || key.RoleARN == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry{
+		value:   value,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// Jitter waits for a random delay up to the configured jitter, to spread the fetches
+// of the tasks started together, so that the first one can cache the values the
+// others need. It doesn't wait when the cache is disabled.
+func (c *Cache) Jitter() {
+	if c == nil || c.ttl <= 0 || c.jitter <= 0 {
+		return
+	}
+	c.sleep(time.Duration(rand.Int63n(int64(c.jitter))))
+}
@@ -0,0 +1,88 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = Key{
+	Service: ServiceSSM,
+	Region:  "us-west-2",
+	RoleARN: "arn:aws:iam::123456789012:role/execution",
+	ID:      "/db/password",
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Now()
+	cache := New(time.Minute, 0)
+	cache.now = func() time.Time { return now }
+
+	_, ok := cache.Get(testKey)
+	assert.False(t, ok)
+
+	cache.Set(testKey, "secret")
+	value, ok := cache.Get(testKey)
+	assert.True(t, ok)
+	assert.Equal(t, "secret", value)
+
+	otherRole := testKey
+	otherRole.RoleARN = "arn:aws:iam::123456789012:role/other"
+	_, ok = cache.Get(otherRole)
+	assert.False(t, ok, "values should not be shared across roles")
+
+	now = now.Add(time.Minute)
+	_, ok = cache.Get(testKey)
+	assert.False(t, ok, "expired values should not be returned")
+	assert.Empty(t, cache.entries)
+}
+
+func TestCacheDisabled(t *testing.T) {
+	var nilCache *Cache
+	nilCache.Set(testKey, "secret")
+	_, ok := nilCache.Get(testKey)
+	assert.False(t, ok)
+	nilCache.Jitter()
+
+	cache := New(0, 0)
+	cache.Set(testKey, "secret")
+	_, ok = cache.Get(testKey)
+	assert.False(t, ok)
+
+	cache = New(time.Minute, 0)
+	noRole := testKey
+	noRole.RoleARN = ""
+	cache.Set(noRole, "secret")
+	_, ok = cache.Get(noRole)
+	assert.False(t, ok, "values fetched without a role should not be cached")
+}
+
+func TestCacheJitter(t *testing.T) {
+	cache := New(time.Minute, time.Second)
+	var slept []time.Duration
+	cache.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	for i := 0; i < 10; i++ {
+		cache.Jitter()
+	}
+	assert.Len(t, slept, 10)
+	for _, d := range slept {
+		assert.True(t, d >= 0 && d < time.Second)
+	}
+}
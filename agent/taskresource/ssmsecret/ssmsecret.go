@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/ssm"
 	"github.com/aws/amazon-ecs-agent/agent/ssm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretcache"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 )
 
@@ -65,6 +66,11 @@ type SSMSecretResource struct {
 	// needed mostly for testing.
 	ssmClientCreator factory.SSMClientCreator
 
+	// secretCache holds the secret values fetched for the other tasks, which the task
+	// reads unless it's refreshing its secrets
+	secretCache       *secretcache.Cache
+	bypassSecretCache bool
+
 	// terminalReason should be set for resource creation failures. This ensures
 	// the resource object carries some context for why provisioning failed.
 	terminalReason     string
@@ -79,7 +85,8 @@ func NewSSMSecretResource(taskARN string,
 	ssmSecrets map[string][]apicontainer.Secret,
 	executionCredentialsID string,
 	credentialsManager credentials.Manager,
-	ssmClientCreator factory.SSMClientCreator) *SSMSecretResource {
+	ssmClientCreator factory.SSMClientCreator,
+	secretCache *secretcache.Cache) *SSMSecretResource {
 
 	s := &SSMSecretResource{
 		taskARN:                taskARN,
@@ -87,6 +94,7 @@ func NewSSMSecretResource(taskARN string,
 		credentialsManager:     credentialsManager,
 		executionCredentialsID: executionCredentialsID,
 		ssmClientCreator:       ssmClientCreator,
+		secretCache:            secretCache,
 	}
 
 	s.initStatusToTransition()
@@ -288,6 +296,37 @@ func (secret *SSMSecretResource) Create() error {
 	}
 }
 
+// Refresh fetches the secret values from SSM again, bypassing the values cached for the
+// other tasks. The cached values are only replaced if all of the secrets could be retrieved.
+func (secret *SSMSecretResource) Refresh() (func() error, func(), error) {
+	secret.lock.RLock()
+	refreshed := &SSMSecretResource{
+		taskARN:                secret.taskARN,
+		credentialsManager:     secret.credentialsManager,
+		executionCredentialsID: secret.executionCredentialsID,
+		requiredSecrets:        secret.requiredSecrets,
+		ssmClientCreator:       secret.ssmClientCreator,
+		secretCache:            secret.secretCache,
+		bypassSecretCache:      true,
+	}
+	secret.lock.RUnlock()
+	if err := refreshed.Create(); err != nil {
+		return nil, nil, err
+	}
+
+	secret.lock.Lock()
+	previous := secret.secretData
+	secret.secretData = refreshed.secretData
+	secret.lock.Unlock()
+	rollback := func() error {
+		secret.lock.Lock()
+		defer secret.lock.Unlock()
+		secret.secretData = previous
+		return nil
+	}
+	return rollback, func() {}, nil
+}
+
 // getGoRoutineMaxNum calculates the maximum number of goroutines that we need to spin up
 // to retrieve secret values from SSM parameter store. Assume each goroutine initiates one
 // SSM GetParameters call and each call will have 10 parameters
@@ -300,7 +339,8 @@ func (secret *SSMSecretResource) getGoRoutineMaxNum() int {
 }
 
 // retrieveSSMSecretValuesByRegion reads secret values from cache first, if not exists, batches secrets based on field
-// valueFrom and call retrieveSSMSecretValues to retrieve values from SSM
+// valueFrom and call retrieveSSMSecretValues to retrieve values from SSM. The secrets used by several containers of
+// the task are retrieved once.
 func (secret *SSMSecretResource) retrieveSSMSecretValuesByRegion(region string, secrets []apicontainer.Secret, iamCredentials credentials.IAMRoleCredentials, wg *sync.WaitGroup, errorEvents chan error) {
 	seelog.Infof("ssm secret resource: retrieving secrets for region %s in task: [%s]", region, secret.taskARN)
 	defer wg.Done()
@@ -308,11 +348,15 @@ func (secret *SSMSecretResource) retrieveSSMSecretValuesByRegion(region string,
 	var wgPerRegion sync.WaitGroup
 	var secretNames []string
 
-	for _, s := range secrets {
-		secretKey := s.GetSecretResourceCacheKey()
-		if _, ok := secret.GetCachedSecretValue(secretKey); ok {
-			continue
-		}
+	missing := secret.secretsMissingFromCache(region, secrets, iamCredentials)
+	if len(missing) > 0 && !secret.bypassSecretCache {
+		// the tasks started at the same time fetch their secrets at different times, so
+		// that the first one can cache the secrets the others need
+		secret.secretCache.Jitter()
+		missing = secret.secretsMissingFromCache(region, missing, iamCredentials)
+	}
+
+	for _, s := range missing {
 		secretNames = append(secretNames, s.ValueFrom)
 		if len(secretNames) == MaxBatchNum {
 			secretNamesTmp := make([]string, MaxBatchNum)
@@ -330,6 +374,38 @@ func (secret *SSMSecretResource) retrieveSSMSecretValuesByRegion(region string,
 	wgPerRegion.Wait()
 }
 
+// secretsMissingFromCache returns the secrets that are neither retrieved for the task nor
+// cached for the other tasks, without duplicates. The cached values are copied to the task.
+func (secret *SSMSecretResource) secretsMissingFromCache(region string, secrets []apicontainer.Secret,
+	iamCredentials credentials.IAMRoleCredentials) []apicontainer.Secret {
+	var missing []apicontainer.Secret
+	names := make(map[string]bool)
+	for _, s := range secrets {
+		secretKey := s.GetSecretResourceCacheKey()
+		if _, ok := secret.GetCachedSecretValue(secretKey); ok || names[s.ValueFrom] {
+			continue
+		}
+		if !secret.bypassSecretCache {
+			if value, ok := secret.secretCache.Get(ssmSecretCacheKey(region, s.ValueFrom, iamCredentials)); ok {
+				secret.SetCachedSecretValue(secretKey, value)
+				continue
+			}
+		}
+		names[s.ValueFrom] = true
+		missing = append(missing, s)
+	}
+	return missing
+}
+
+func ssmSecretCacheKey(region, name string, iamCredentials credentials.IAMRoleCredentials) secretcache.Key {
+	return secretcache.Key{
+		Service: secretcache.ServiceSSM,
+		Region:  region,
+		RoleARN: iamCredentials.RoleArn,
+		ID:      name,
+	}
+}
+
 // retrieveSSMSecretValues retrieves secret values from SSM parameter store and caches them into memory
 func (secret *SSMSecretResource) retrieveSSMSecretValues(region string, names []string, iamCredentials credentials.IAMRoleCredentials, wg *sync.WaitGroup, errorEvents chan error) {
 	defer wg.Done()
@@ -349,6 +425,7 @@ func (secret *SSMSecretResource) retrieveSSMSecretValues(region string, names []
 	for secretName, secretValue := range secValueMap {
 		secretKey := secretName + "_" + region
 		secret.secretData[secretKey] = secretValue
+		secret.secretCache.Set(ssmSecretCacheKey(region, secretName, iamCredentials), secretValue)
 	}
 }
 
@@ -412,6 +489,7 @@ func (secret *SSMSecretResource) Initialize(resourceFields *taskresource.Resourc
 	secret.initStatusToTransition()
 	secret.credentialsManager = resourceFields.CredentialsManager
 	secret.ssmClientCreator = resourceFields.SSMClientCreator
+	secret.secretCache = resourceFields.SecretCache
 
 	// if task hasn't turn to 'created' status, and it's desire status is 'running'
 	// the resource status needs to be reset to 'NONE' status so the secret value
@@ -28,6 +28,7 @@ import (
 	mock_factory "github.com/aws/amazon-ecs-agent/agent/ssm/factory/mocks"
 	mock_ssm "github.com/aws/amazon-ecs-agent/agent/ssm/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretcache"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -393,6 +394,137 @@ func TestCreateReturnError(t *testing.T) {
 	assert.Equal(t, expectedError, ssmRes.GetTerminalReason())
 }
 
+func TestRefresh(t *testing.T) {
+	requiredSecretData := map[string][]apicontainer.Secret{
+		region1: {
+			{
+				Name:      secretName1,
+				ValueFrom: valueFrom1,
+				Region:    region1,
+				Provider:  "ssm",
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	ssmClientCreator := mock_factory.NewMockSSMClientCreator(ctrl)
+	mockSSMClient := mock_ssm.NewMockSSMClient(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true).Times(2)
+	ssmClientCreator.EXPECT().NewSSMClient(region1, iamRoleCreds).Return(mockSSMClient).Times(2)
+	gomock.InOrder(
+		mockSSMClient.EXPECT().GetParameters(gomock.Any()).Return(&ssm.GetParametersOutput{
+			InvalidParameters: []*string{aws.String(valueFrom1)},
+		}, nil),
+		mockSSMClient.EXPECT().GetParameters(gomock.Any()).Return(&ssm.GetParametersOutput{
+			Parameters: []*ssm.Parameter{
+				{
+					Name:  aws.String(valueFrom1),
+					Value: aws.String("rotated-secret-value"),
+				},
+			},
+		}, nil),
+	)
+
+	ssmRes := &SSMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets:        requiredSecretData,
+		credentialsManager:     credentialsManager,
+		ssmClientCreator:       ssmClientCreator,
+		secretData: map[string]string{
+			secretKeyWest1: secretValue,
+		},
+	}
+
+	// A failed refresh keeps the cached value and doesn't fail the resource
+	_, _, err := ssmRes.Refresh()
+	assert.Error(t, err)
+	assert.Empty(t, ssmRes.GetTerminalReason())
+	value, _ := ssmRes.GetCachedSecretValue(secretKeyWest1)
+	assert.Equal(t, secretValue, value)
+
+	rollback, commit, err := ssmRes.Refresh()
+	require.NoError(t, err)
+	value, _ = ssmRes.GetCachedSecretValue(secretKeyWest1)
+	assert.Equal(t, "rotated-secret-value", value)
+
+	require.NoError(t, rollback())
+	commit()
+	value, _ = ssmRes.GetCachedSecretValue(secretKeyWest1)
+	assert.Equal(t, secretValue, value)
+}
+
+func TestCreateWithSecretCache(t *testing.T) {
+	requiredSecretData := map[string][]apicontainer.Secret{
+		region1: {
+			{
+				Name:      secretName1,
+				ValueFrom: valueFrom1,
+				Region:    region1,
+				Provider:  "ssm",
+			},
+			{
+				Name:      secretName2,
+				ValueFrom: valueFrom1,
+				Region:    region1,
+				Provider:  "ssm",
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	ssmClientCreator := mock_factory.NewMockSSMClientCreator(ctrl)
+	mockSSMClient := mock_ssm.NewMockSSMClient(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{
+		RoleArn: "arn:aws:iam::123456789012:role/execution-role",
+	}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+	ssmOutput := &ssm.GetParametersOutput{
+		Parameters: []*ssm.Parameter{
+			{
+				Name:  aws.String(valueFrom1),
+				Value: aws.String(secretValue),
+			},
+		},
+	}
+
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true).Times(3)
+	ssmClientCreator.EXPECT().NewSSMClient(region1, iamRoleCreds).Return(mockSSMClient).Times(2)
+	// the secret used by both containers is fetched once, and again on refresh
+	mockSSMClient.EXPECT().GetParameters(gomock.Any()).Do(func(in *ssm.GetParametersInput) {
+		assert.Equal(t, []*string{aws.String(valueFrom1)}, in.Names)
+	}).Return(ssmOutput, nil).Times(2)
+
+	cache := secretcache.New(time.Minute, 0)
+	ssmRes := NewSSMSecretResource(taskARN, requiredSecretData, executionCredentialsID,
+		credentialsManager, ssmClientCreator, cache)
+	require.NoError(t, ssmRes.Create())
+
+	// another task with the same execution role reads the cached value
+	otherRes := NewSSMSecretResource("task2", requiredSecretData, executionCredentialsID,
+		credentialsManager, ssmClientCreator, cache)
+	require.NoError(t, otherRes.Create())
+	value, ok := otherRes.GetCachedSecretValue(secretKeyWest1)
+	require.True(t, ok)
+	assert.Equal(t, secretValue, value)
+
+	_, _, err := otherRes.Refresh()
+	require.NoError(t, err)
+}
+
 func TestGetGoRoutineMaxNumTwoRegions(t *testing.T) {
 	requiredSecretData := make(map[string][]apicontainer.Secret)
 	secretsInRegion1 := []apicontainer.Secret{
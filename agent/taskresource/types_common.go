@@ -18,7 +18,9 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	fsxfactory "github.com/aws/amazon-ecs-agent/agent/fsx/factory"
 	ssmfactory "github.com/aws/amazon-ecs-agent/agent/ssm/factory"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretcache"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
+	vaultfactory "github.com/aws/amazon-ecs-agent/agent/vault/factory"
 )
 
 type ResourceFieldsCommon struct {
@@ -26,6 +28,10 @@ type ResourceFieldsCommon struct {
 	ASMClientCreator   asmfactory.ClientCreator
 	SSMClientCreator   ssmfactory.SSMClientCreator
 	FSxClientCreator   fsxfactory.FSxClientCreator
+	VaultClientCreator vaultfactory.ClientCreator
 	CredentialsManager credentials.Manager
 	EC2InstanceID      string
+	// SecretCache holds the secret values fetched from SSM and Secrets Manager for the
+	// other tasks
+	SecretCache *secretcache.Cache
 }
@@ -20,6 +20,7 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/gpu"
+	"github.com/aws/amazon-ecs-agent/agent/neuron"
 	cgroup "github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
 )
 
@@ -31,4 +32,5 @@ type ResourceFields struct {
 	Ctx              context.Context
 	DockerClient     dockerapi.DockerClient
 	NvidiaGPUManager gpu.GPUManager
+	NeuronManager    neuron.Manager
 }
@@ -0,0 +1,565 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vaultsecret
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	"github.com/aws/amazon-ecs-agent/agent/vault"
+	"github.com/aws/amazon-ecs-agent/agent/vault/factory"
+)
+
+const (
+	// ResourceName is the name of the vaultsecret resource
+	ResourceName = "vaultsecret"
+)
+
+// VaultSecretResource represents secrets as a task resource.
+// The secrets are stored in the KV version 2 secrets engines of HashiCorp Vault.
+type VaultSecretResource struct {
+	taskARN             string
+	createdAt           time.Time
+	desiredStatusUnsafe resourcestatus.ResourceStatus
+	knownStatusUnsafe   resourcestatus.ResourceStatus
+	// appliedStatus is the status that has been "applied" (e.g., we've called some
+	// operation such as 'Create' on the resource) but we don't yet know that the
+	// application was successful, which may then change the known status. This is
+	// used while progressing resource states in progressTask() of task manager
+	appliedStatus                      resourcestatus.ResourceStatus
+	resourceStatusToTransitionFunction map[resourcestatus.ResourceStatus]func() error
+	credentialsManager                 credentials.Manager
+	// credentialsID is the ID of the task role credentials, which log in to Vault. The
+	// execution role credentials are used instead when the task has no role.
+	credentialsID          string
+	executionCredentialsID string
+
+	// map to store all vault deduped secrets in the task, key is a combination of valueFrom and region
+	requiredSecrets map[string]apicontainer.Secret
+	// map to store secret values, key is a combination of valueFrom and region
+	secretData map[string]string
+
+	// vaultClientCreator is a factory interface that logs in to Vault. This is
+	// needed mostly for testing.
+	vaultClientCreator factory.ClientCreator
+
+	// terminalReason should be set for resource creation failures. This ensures
+	// the resource object carries some context for why provisioning failed.
+	terminalReason     string
+	terminalReasonOnce sync.Once
+
+	// lock is used for fields that are accessed and updated concurrently
+	lock sync.RWMutex
+}
+
+// NewVaultSecretResource creates a new VaultSecretResource object
+func NewVaultSecretResource(taskARN string,
+	vaultSecrets map[string]apicontainer.Secret,
+	credentialsID string,
+	executionCredentialsID string,
+	credentialsManager credentials.Manager,
+	vaultClientCreator factory.ClientCreator) *VaultSecretResource {
+
+	s := &VaultSecretResource{
+		taskARN:                taskARN,
+		requiredSecrets:        vaultSecrets,
+		credentialsManager:     credentialsManager,
+		credentialsID:          credentialsID,
+		executionCredentialsID: executionCredentialsID,
+		vaultClientCreator:     vaultClientCreator,
+	}
+
+	s.initStatusToTransition()
+	return s
+}
+
+func (secret *VaultSecretResource) initStatusToTransition() {
+	resourceStatusToTransitionFunction := map[resourcestatus.ResourceStatus]func() error{
+		resourcestatus.ResourceStatus(VaultSecretCreated): secret.Create,
+	}
+	secret.resourceStatusToTransitionFunction = resourceStatusToTransitionFunction
+}
+
+func (secret *VaultSecretResource) setTerminalReason(reason string) {
+	secret.terminalReasonOnce.Do(func() {
+		seelog.Infof("Vault secret resource: setting terminal reason for vault secret resource in task: [%s]", secret.taskARN)
+		secret.terminalReason = reason
+	})
+}
+
+// GetTerminalReason returns an error string to propagate up through to task
+// state change messages
+func (secret *VaultSecretResource) GetTerminalReason() string {
+	return secret.terminalReason
+}
+
+// SetDesiredStatus safely sets the desired status of the resource
+func (secret *VaultSecretResource) SetDesiredStatus(status resourcestatus.ResourceStatus) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.desiredStatusUnsafe = status
+}
+
+// GetDesiredStatus safely returns the desired status of the task
+func (secret *VaultSecretResource) GetDesiredStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.desiredStatusUnsafe
+}
+
+// GetName safely returns the name of the resource
+func (secret *VaultSecretResource) GetName() string {
+	return ResourceName
+}
+
+// DesiredTerminal returns true if the secret's desired status is REMOVED
+func (secret *VaultSecretResource) DesiredTerminal() bool {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.desiredStatusUnsafe == resourcestatus.ResourceStatus(VaultSecretRemoved)
+}
+
+// KnownCreated returns true if the secret's known status is CREATED
+func (secret *VaultSecretResource) KnownCreated() bool {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.knownStatusUnsafe == resourcestatus.ResourceStatus(VaultSecretCreated)
+}
+
+// TerminalStatus returns the last transition state of vaultsecret
+func (secret *VaultSecretResource) TerminalStatus() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(VaultSecretRemoved)
+}
+
+// NextKnownState returns the state that the resource should
+// progress to based on its `KnownState`.
+func (secret *VaultSecretResource) NextKnownState() resourcestatus.ResourceStatus {
+	return secret.GetKnownStatus() + 1
+}
+
+// ApplyTransition calls the function required to move to the specified status
+func (secret *VaultSecretResource) ApplyTransition(nextState resourcestatus.ResourceStatus) error {
+	transitionFunc, ok := secret.resourceStatusToTransitionFunction[nextState]
+	if !ok {
+		return errors.Errorf("resource [%s]: transition to %s impossible", secret.GetName(),
+			secret.StatusString(nextState))
+	}
+	return transitionFunc()
+}
+
+// SteadyState returns the transition state of the resource defined as "ready"
+func (secret *VaultSecretResource) SteadyState() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(VaultSecretCreated)
+}
+
+// SetKnownStatus safely sets the currently known status of the resource
+func (secret *VaultSecretResource) SetKnownStatus(status resourcestatus.ResourceStatus) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.knownStatusUnsafe = status
+	secret.updateAppliedStatusUnsafe(status)
+}
+
+// updateAppliedStatusUnsafe updates the resource transitioning status
+func (secret *VaultSecretResource) updateAppliedStatusUnsafe(knownStatus resourcestatus.ResourceStatus) {
+	if secret.appliedStatus == resourcestatus.ResourceStatus(VaultSecretStatusNone) {
+		return
+	}
+
+	// Check if the resource transition has already finished
+	if secret.appliedStatus <= knownStatus {
+		secret.appliedStatus = resourcestatus.ResourceStatus(VaultSecretStatusNone)
+	}
+}
+
+// SetAppliedStatus sets the applied status of resource and returns whether
+// the resource is already in a transition
+func (secret *VaultSecretResource) SetAppliedStatus(status resourcestatus.ResourceStatus) bool {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.appliedStatus != resourcestatus.ResourceStatus(VaultSecretStatusNone) {
+		// return false to indicate the set operation failed
+		return false
+	}
+
+	secret.appliedStatus = status
+	return true
+}
+
+// GetKnownStatus safely returns the currently known status of the task
+func (secret *VaultSecretResource) GetKnownStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.knownStatusUnsafe
+}
+
+// StatusString returns the string of the vault secret resource status
+func (secret *VaultSecretResource) StatusString(status resourcestatus.ResourceStatus) string {
+	return VaultSecretStatus(status).String()
+}
+
+// SetCreatedAt sets the timestamp for resource's creation time
+func (secret *VaultSecretResource) SetCreatedAt(createdAt time.Time) {
+	if createdAt.IsZero() {
+		return
+	}
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.createdAt = createdAt
+}
+
+// GetCreatedAt sets the timestamp for resource's creation time
+func (secret *VaultSecretResource) GetCreatedAt() time.Time {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.createdAt
+}
+
+// Create logs in to Vault with the credentials of the task and retrieves the secrets.
+// It spins up multiple goroutines in order to retrieve values in parallel. The token
+// is revoked once the secrets are read, as the secrets are only read once per task.
+func (secret *VaultSecretResource) Create() error {
+	iamCredentials, err := secret.getIAMCredentials()
+	if err != nil {
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+
+	seelog.Infof("Vault secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
+	vaultClient, err := secret.vaultClientCreator.NewVaultClient(iamCredentials)
+	if err != nil {
+		err = fmt.Errorf("fetching secret data from vault: %v", err)
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+	defer func() {
+		if err := vaultClient.RevokeSelf(); err != nil {
+			seelog.Warnf("Vault secret resource: unable to revoke the vault token of task [%s]: %v",
+				secret.taskARN, err)
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	// Get the maximum number of errors to be returned, which will be one error per secret
+	errorEvents := make(chan error, len(secret.requiredSecrets))
+	secret.secretData = make(map[string]string)
+
+	for _, fetch := range secret.getSecretFetches(errorEvents) {
+		wg.Add(1)
+		// Spin up goroutine per secret version to speed up processing time
+		go secret.retrieveVaultSecretValue(fetch, vaultClient, &wg, errorEvents)
+	}
+
+	wg.Wait()
+	close(errorEvents)
+
+	if len(errorEvents) > 0 {
+		var terminalReasons []string
+		for err := range errorEvents {
+			terminalReasons = append(terminalReasons, err.Error())
+		}
+
+		errorString := strings.Join(terminalReasons, ";")
+		secret.setTerminalReason(errorString)
+		return errors.New(errorString)
+	}
+	return nil
+}
+
+// getIAMCredentials returns the task role credentials, or the execution role
+// credentials when the task has no role
+func (secret *VaultSecretResource) getIAMCredentials() (credentials.IAMRoleCredentials, error) {
+	for _, credentialsID := range []string{secret.getCredentialsID(), secret.getExecutionCredentialsID()} {
+		if credentialsID == "" {
+			continue
+		}
+		if taskCredentials, ok := secret.credentialsManager.GetTaskCredentials(credentialsID); ok {
+			return taskCredentials.GetIAMRoleCredentials(), nil
+		}
+	}
+	// No need to log here. managedTask.applyResourceState already does that
+	return credentials.IAMRoleCredentials{}, errors.New("Vault secret resource: unable to find task role or execution role credentials")
+}
+
+// Refresh fetches the secret values from Vault again. The values are only replaced if
+// all of the secrets could be retrieved.
+func (secret *VaultSecretResource) Refresh() (func() error, func(), error) {
+	secret.lock.RLock()
+	refreshed := &VaultSecretResource{
+		taskARN:                secret.taskARN,
+		credentialsManager:     secret.credentialsManager,
+		credentialsID:          secret.credentialsID,
+		executionCredentialsID: secret.executionCredentialsID,
+		requiredSecrets:        secret.requiredSecrets,
+		vaultClientCreator:     secret.vaultClientCreator,
+	}
+	secret.lock.RUnlock()
+	if err := refreshed.Create(); err != nil {
+		return nil, nil, err
+	}
+
+	secret.lock.Lock()
+	previous := secret.secretData
+	secret.secretData = refreshed.secretData
+	secret.lock.Unlock()
+	rollback := func() error {
+		secret.lock.Lock()
+		defer secret.lock.Unlock()
+		secret.secretData = previous
+		return nil
+	}
+	return rollback, func() {}, nil
+}
+
+// vaultSecretFetch is a read of a version of a Vault secret, for all of the secrets of
+// the task that read its data or the values of its keys
+type vaultSecretFetch struct {
+	ref     vault.SecretReference
+	secrets []apicontainer.Secret
+	keys    []string
+}
+
+// getSecretFetches groups the required secrets by the version of the secret they read, so
+// that each version is read once. The secrets that can't be parsed are reported to
+// errorEvents.
+func (secret *VaultSecretResource) getSecretFetches(errorEvents chan error) []*vaultSecretFetch {
+	var fetches []*vaultSecretFetch
+	fetchByVersion := make(map[string]*vaultSecretFetch)
+	for _, apiSecret := range secret.getRequiredSecrets() {
+		ref, err := vault.ParseSecretReference(apiSecret.ValueFrom)
+		if err != nil {
+			errorEvents <- fmt.Errorf("trying to retrieve secret with value %s resulted in error: %v", apiSecret.ValueFrom, err)
+			continue
+		}
+
+		version := ref.Mount + "/" + ref.Path + "?" + strconv.Itoa(ref.Version)
+		fetch, ok := fetchByVersion[version]
+		if !ok {
+			fetch = &vaultSecretFetch{ref: ref}
+			fetchByVersion[version] = fetch
+			fetches = append(fetches, fetch)
+		}
+		fetch.secrets = append(fetch.secrets, apiSecret)
+		fetch.keys = append(fetch.keys, ref.Key)
+	}
+	return fetches
+}
+
+// retrieveVaultSecretValue reads the secret version from Vault, then extracts the values of
+// the secrets reading it
+func (secret *VaultSecretResource) retrieveVaultSecretValue(fetch *vaultSecretFetch, vaultClient vault.Client,
+	wg *sync.WaitGroup, errorEvents chan error) {
+	defer wg.Done()
+
+	seelog.Infof("Vault secret resource: retrieving resource for secret %s/%s for task: [%s]", fetch.ref.Mount, fetch.ref.Path, secret.taskARN)
+	data, err := vaultClient.ReadKVSecret(fetch.ref.Mount, fetch.ref.Path, fetch.ref.Version)
+	if err != nil {
+		errorEvents <- fmt.Errorf("fetching secret data from vault: %v", err)
+		return
+	}
+
+	for i, apiSecret := range fetch.secrets {
+		secretValue, err := vault.GetSecretValue(data, fetch.keys[i])
+		if err != nil {
+			errorEvents <- fmt.Errorf("fetching secret data from vault for secret %s/%s: %v", fetch.ref.Mount, fetch.ref.Path, err)
+			continue
+		}
+
+		// put secret value in secretData
+		secret.SetCachedSecretValue(apiSecret.GetSecretResourceCacheKey(), secretValue)
+	}
+}
+
+// getRequiredSecrets returns the requiredSecrets field of vaultsecret task resource
+func (secret *VaultSecretResource) getRequiredSecrets() map[string]apicontainer.Secret {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.requiredSecrets
+}
+
+// getCredentialsID returns the task role's credential ID
+func (secret *VaultSecretResource) getCredentialsID() string {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.credentialsID
+}
+
+// getExecutionCredentialsID returns the execution role's credential ID
+func (secret *VaultSecretResource) getExecutionCredentialsID() string {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.executionCredentialsID
+}
+
+// Cleanup removes the secret value created for the task
+func (secret *VaultSecretResource) Cleanup() error {
+	secret.clearVaultSecretValue()
+	return nil
+}
+
+// clearVaultSecretValue cycles through the collection of secret value data and
+// removes them from the task
+func (secret *VaultSecretResource) clearVaultSecretValue() {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	for key := range secret.secretData {
+		delete(secret.secretData, key)
+	}
+}
+
+// GetCachedSecretValue retrieves the secret value from secretData field
+func (secret *VaultSecretResource) GetCachedSecretValue(secretKey string) (string, bool) {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	s, ok := secret.secretData[secretKey]
+	return s, ok
+}
+
+// SetCachedSecretValue set the secret value in the secretData field given the key and value
+func (secret *VaultSecretResource) SetCachedSecretValue(secretKey string, secretValue string) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.secretData == nil {
+		secret.secretData = make(map[string]string)
+	}
+
+	secret.secretData[secretKey] = secretValue
+}
+
+func (secret *VaultSecretResource) Initialize(resourceFields *taskresource.ResourceFields,
+	taskKnownStatus status.TaskStatus,
+	taskDesiredStatus status.TaskStatus) {
+	secret.initStatusToTransition()
+	secret.credentialsManager = resourceFields.CredentialsManager
+	secret.vaultClientCreator = resourceFields.VaultClientCreator
+
+	// if task hasn't turn to 'created' status, and it's desire status is 'running'
+	// the resource status needs to be reset to 'NONE' status so the secret value
+	// will be retrieved again
+	if taskKnownStatus < status.TaskCreated &&
+		taskDesiredStatus <= status.TaskRunning {
+		secret.SetKnownStatus(resourcestatus.ResourceStatusNone)
+	}
+}
+
+type VaultSecretResourceJSON struct {
+	TaskARN                string                         `json:"taskARN"`
+	CreatedAt              *time.Time                     `json:"createdAt,omitempty"`
+	DesiredStatus          *VaultSecretStatus             `json:"desiredStatus"`
+	KnownStatus            *VaultSecretStatus             `json:"knownStatus"`
+	RequiredSecrets        map[string]apicontainer.Secret `json:"secretResources"`
+	CredentialsID          string                         `json:"credentialsID"`
+	ExecutionCredentialsID string                         `json:"executionCredentialsID"`
+}
+
+// MarshalJSON serialises the VaultSecretResource struct to JSON
+func (secret *VaultSecretResource) MarshalJSON() ([]byte, error) {
+	if secret == nil {
+		return nil, errors.New("vaultsecret resource is nil")
+	}
+	createdAt := secret.GetCreatedAt()
+	return json.Marshal(VaultSecretResourceJSON{
+		TaskARN:   secret.taskARN,
+		CreatedAt: &createdAt,
+		DesiredStatus: func() *VaultSecretStatus {
+			desiredState := secret.GetDesiredStatus()
+			s := VaultSecretStatus(desiredState)
+			return &s
+		}(),
+		KnownStatus: func() *VaultSecretStatus {
+			knownState := secret.GetKnownStatus()
+			s := VaultSecretStatus(knownState)
+			return &s
+		}(),
+		RequiredSecrets:        secret.getRequiredSecrets(),
+		CredentialsID:          secret.getCredentialsID(),
+		ExecutionCredentialsID: secret.getExecutionCredentialsID(),
+	})
+}
+
+// UnmarshalJSON deserialises the raw JSON to a VaultSecretResource struct
+func (secret *VaultSecretResource) UnmarshalJSON(b []byte) error {
+	temp := VaultSecretResourceJSON{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return err
+	}
+
+	if temp.DesiredStatus != nil {
+		secret.SetDesiredStatus(resourcestatus.ResourceStatus(*temp.DesiredStatus))
+	}
+	if temp.KnownStatus != nil {
+		secret.SetKnownStatus(resourcestatus.ResourceStatus(*temp.KnownStatus))
+	}
+	if temp.CreatedAt != nil && !temp.CreatedAt.IsZero() {
+		secret.SetCreatedAt(*temp.CreatedAt)
+	}
+	if temp.RequiredSecrets != nil {
+		secret.requiredSecrets = temp.RequiredSecrets
+	}
+	secret.taskARN = temp.TaskARN
+	secret.credentialsID = temp.CredentialsID
+	secret.executionCredentialsID = temp.ExecutionCredentialsID
+
+	return nil
+}
+
+// GetAppliedStatus safely returns the currently applied status of the resource
+func (secret *VaultSecretResource) GetAppliedStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.appliedStatus
+}
+
+func (secret *VaultSecretResource) DependOnTaskNetwork() bool {
+	return false
+}
+
+func (secret *VaultSecretResource) BuildContainerDependency(containerName string, satisfied apicontainerstatus.ContainerStatus,
+	dependent resourcestatus.ResourceStatus) {
+}
+
+func (secret *VaultSecretResource) GetContainerDependencies(dependent resourcestatus.ResourceStatus) []apicontainer.ContainerDependency {
+	return nil
+}
@@ -0,0 +1,282 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vaultsecret
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	mock_factory "github.com/aws/amazon-ecs-agent/agent/vault/factory/mocks"
+	mock_vault "github.com/aws/amazon-ecs-agent/agent/vault/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	credentialsID          = "task-creds-id"
+	executionCredentialsID = "exec-creds-id"
+	taskARN                = "task1"
+	valueFromPassword      = "secret/db#password"
+	valueFromUser          = "secret/db#user"
+	valueFromAPIKey        = "secret/api?version=2"
+)
+
+func sampleSecret(name, valueFrom string) apicontainer.Secret {
+	return apicontainer.Secret{
+		Name:      name,
+		ValueFrom: valueFrom,
+		Provider:  apicontainer.SecretProviderVault,
+		Type:      apicontainer.SecretTypeEnv,
+	}
+}
+
+func secretKey(valueFrom string) string {
+	secret := sampleSecret("", valueFrom)
+	return secret.GetSecretResourceCacheKey()
+}
+
+func sampleRequiredSecrets() map[string]apicontainer.Secret {
+	secrets := make(map[string]apicontainer.Secret)
+	for name, valueFrom := range map[string]string{
+		"DB_PASSWORD": valueFromPassword,
+		"DB_USER":     valueFromUser,
+		"API_KEY":     valueFromAPIKey,
+	} {
+		secret := sampleSecret(name, valueFrom)
+		secrets[secret.GetSecretResourceCacheKey()] = secret
+	}
+	return secrets
+}
+
+func TestCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	vaultClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	vaultClient := mock_vault.NewMockClient(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{RoleArn: "task-role"}
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).Return(
+		credentials.TaskIAMRoleCredentials{IAMRoleCredentials: iamRoleCreds}, true)
+	vaultClientCreator.EXPECT().NewVaultClient(iamRoleCreds).Return(vaultClient, nil)
+	vaultClient.EXPECT().RevokeSelf().Return(nil)
+	// the two keys of the same secret are read at once
+	vaultClient.EXPECT().ReadKVSecret("secret", "db", 0).Return(map[string]interface{}{
+		"user":     "admin",
+		"password": "hunter2",
+	}, nil)
+	vaultClient.EXPECT().ReadKVSecret("secret", "api", 2).Return(map[string]interface{}{
+		"key": "abc",
+	}, nil)
+
+	vaultRes := NewVaultSecretResource(taskARN, sampleRequiredSecrets(), credentialsID, executionCredentialsID,
+		credentialsManager, vaultClientCreator)
+	require.NoError(t, vaultRes.Create())
+
+	value, ok := vaultRes.GetCachedSecretValue(secretKey(valueFromPassword))
+	require.True(t, ok)
+	assert.Equal(t, "hunter2", value)
+	value, ok = vaultRes.GetCachedSecretValue(secretKey(valueFromUser))
+	require.True(t, ok)
+	assert.Equal(t, "admin", value)
+	value, ok = vaultRes.GetCachedSecretValue(secretKey(valueFromAPIKey))
+	require.True(t, ok)
+	assert.JSONEq(t, `{"key":"abc"}`, value)
+}
+
+func TestCreateWithExecutionRoleCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	vaultClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	vaultClient := mock_vault.NewMockClient(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{RoleArn: "execution-role"}
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(
+		credentials.TaskIAMRoleCredentials{IAMRoleCredentials: iamRoleCreds}, true)
+	vaultClientCreator.EXPECT().NewVaultClient(iamRoleCreds).Return(vaultClient, nil)
+	vaultClient.EXPECT().RevokeSelf().Return(nil)
+	vaultClient.EXPECT().ReadKVSecret("secret", "db", 0).Return(map[string]interface{}{
+		"user":     "admin",
+		"password": "hunter2",
+	}, nil)
+
+	secret := sampleSecret("DB_PASSWORD", valueFromPassword)
+	vaultRes := NewVaultSecretResource(taskARN, map[string]apicontainer.Secret{
+		secret.GetSecretResourceCacheKey(): secret,
+	}, "", executionCredentialsID, credentialsManager, vaultClientCreator)
+	require.NoError(t, vaultRes.Create())
+
+	value, ok := vaultRes.GetCachedSecretValue(secret.GetSecretResourceCacheKey())
+	require.True(t, ok)
+	assert.Equal(t, "hunter2", value)
+}
+
+func TestCreateNoCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).Return(credentials.TaskIAMRoleCredentials{}, false)
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(credentials.TaskIAMRoleCredentials{}, false)
+
+	vaultRes := NewVaultSecretResource(taskARN, sampleRequiredSecrets(), credentialsID, executionCredentialsID,
+		credentialsManager, mock_factory.NewMockClientCreator(ctrl))
+	assert.Error(t, vaultRes.Create())
+	assert.NotEmpty(t, vaultRes.GetTerminalReason())
+}
+
+func TestCreateReturnErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	vaultClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	vaultClient := mock_vault.NewMockClient(ctrl)
+
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).Return(credentials.TaskIAMRoleCredentials{}, true)
+	vaultClientCreator.EXPECT().NewVaultClient(gomock.Any()).Return(vaultClient, nil)
+	vaultClient.EXPECT().RevokeSelf().Return(nil)
+	vaultClient.EXPECT().ReadKVSecret("secret", "db", 0).Return(map[string]interface{}{
+		"password": "hunter2",
+	}, nil)
+	vaultClient.EXPECT().ReadKVSecret("secret", "api", 2).Return(nil, errors.New("permission denied"))
+
+	vaultRes := NewVaultSecretResource(taskARN, sampleRequiredSecrets(), credentialsID, executionCredentialsID,
+		credentialsManager, vaultClientCreator)
+	err := vaultRes.Create()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+	// the user key is missing from the secret
+	assert.Contains(t, err.Error(), "user")
+}
+
+func TestCreateLoginError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	vaultClientCreator := mock_factory.NewMockClientCreator(ctrl)
+
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).Return(credentials.TaskIAMRoleCredentials{}, true)
+	vaultClientCreator.EXPECT().NewVaultClient(gomock.Any()).Return(nil, errors.New("login failed"))
+
+	vaultRes := NewVaultSecretResource(taskARN, sampleRequiredSecrets(), credentialsID, executionCredentialsID,
+		credentialsManager, vaultClientCreator)
+	assert.Error(t, vaultRes.Create())
+	assert.Contains(t, vaultRes.GetTerminalReason(), "login failed")
+}
+
+func TestRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	vaultClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	vaultClient := mock_vault.NewMockClient(ctrl)
+
+	secret := sampleSecret("DB_PASSWORD", valueFromPassword)
+	secretKey := secret.GetSecretResourceCacheKey()
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).Return(credentials.TaskIAMRoleCredentials{}, true)
+	vaultClientCreator.EXPECT().NewVaultClient(gomock.Any()).Return(vaultClient, nil)
+	vaultClient.EXPECT().RevokeSelf().Return(nil)
+	vaultClient.EXPECT().ReadKVSecret("secret", "db", 0).Return(map[string]interface{}{
+		"password": "rotated",
+	}, nil)
+
+	vaultRes := NewVaultSecretResource(taskARN, map[string]apicontainer.Secret{secretKey: secret},
+		credentialsID, executionCredentialsID, credentialsManager, vaultClientCreator)
+	vaultRes.SetCachedSecretValue(secretKey, "hunter2")
+
+	rollback, commit, err := vaultRes.Refresh()
+	require.NoError(t, err)
+	value, _ := vaultRes.GetCachedSecretValue(secretKey)
+	assert.Equal(t, "rotated", value)
+
+	require.NoError(t, rollback())
+	value, _ = vaultRes.GetCachedSecretValue(secretKey)
+	assert.Equal(t, "hunter2", value)
+	commit()
+}
+
+func TestMarshalUnmarshalJSON(t *testing.T) {
+	vaultResIn := &VaultSecretResource{
+		taskARN:                taskARN,
+		credentialsID:          credentialsID,
+		executionCredentialsID: executionCredentialsID,
+		createdAt:              time.Now(),
+		knownStatusUnsafe:      resourcestatus.ResourceCreated,
+		desiredStatusUnsafe:    resourcestatus.ResourceCreated,
+		requiredSecrets:        sampleRequiredSecrets(),
+	}
+
+	bytes, err := json.Marshal(vaultResIn)
+	require.NoError(t, err)
+
+	vaultResOut := &VaultSecretResource{}
+	err = json.Unmarshal(bytes, vaultResOut)
+	require.NoError(t, err)
+	assert.Equal(t, vaultResIn.taskARN, vaultResOut.taskARN)
+	assert.WithinDuration(t, vaultResIn.createdAt, vaultResOut.createdAt, time.Microsecond)
+	assert.Equal(t, vaultResIn.desiredStatusUnsafe, vaultResOut.desiredStatusUnsafe)
+	assert.Equal(t, vaultResIn.knownStatusUnsafe, vaultResOut.knownStatusUnsafe)
+	assert.Equal(t, vaultResIn.credentialsID, vaultResOut.credentialsID)
+	assert.Equal(t, vaultResIn.executionCredentialsID, vaultResOut.executionCredentialsID)
+	assert.Equal(t, vaultResIn.requiredSecrets, vaultResOut.requiredSecrets)
+}
+
+func TestInitialize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	vaultClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	vaultRes := &VaultSecretResource{
+		knownStatusUnsafe:   resourcestatus.ResourceCreated,
+		desiredStatusUnsafe: resourcestatus.ResourceCreated,
+	}
+	vaultRes.Initialize(&taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+			VaultClientCreator: vaultClientCreator,
+			CredentialsManager: credentialsManager,
+		},
+	}, apitaskstatus.TaskStatusNone, apitaskstatus.TaskRunning)
+	assert.Equal(t, resourcestatus.ResourceStatusNone, vaultRes.GetKnownStatus())
+	assert.Equal(t, resourcestatus.ResourceCreated, vaultRes.GetDesiredStatus())
+	assert.Equal(t, vaultClientCreator, vaultRes.vaultClientCreator)
+}
+
+func TestClearVaultSecretValue(t *testing.T) {
+	vaultRes := &VaultSecretResource{
+		secretData: map[string]string{
+			"db_name":     "db_value",
+			"secret_name": "secret_value",
+		},
+	}
+	vaultRes.clearVaultSecretValue()
+	assert.Equal(t, 0, len(vaultRes.secretData))
+}
@@ -49,7 +49,9 @@ type VolumeResource struct {
 	Name       string
 	VolumeType string
 	// VolumeConfig contains docker specific volume fields
-	VolumeConfig            DockerVolumeConfig
+	VolumeConfig DockerVolumeConfig
+	// Encryption is set when the volume is backed by an encrypted device
+	Encryption              *EncryptionConfig
 	pauseContainerPIDUnsafe string
 
 	createdAtUnsafe     time.Time
@@ -315,8 +317,23 @@ func (vol *VolumeResource) SourcePath() string {
 	return vol.GetMountPoint()
 }
 
+// IsEphemeral returns true if the volume is a task scoped volume of the local driver
+// without driver options, whose data is only kept for the lifetime of the task
+func (vol *VolumeResource) IsEphemeral() bool {
+	return vol.VolumeType != EFSVolumeType && vol.VolumeConfig.Scope == TaskScope &&
+		(vol.VolumeConfig.Driver == "" || vol.VolumeConfig.Driver == DockerLocalVolumeDriver) &&
+		len(vol.VolumeConfig.DriverOpts) == 0
+}
+
 // Create performs resource creation
 func (vol *VolumeResource) Create() error {
+	if vol.Encryption != nil {
+		seelog.Debugf("Creating encrypted device for volume %s", vol.VolumeConfig.DockerVolumeName)
+		if err := vol.Encryption.setup(vol.ctx); err != nil {
+			vol.setTerminalReason(err.Error())
+			return err
+		}
+	}
 	seelog.Debugf("Creating volume with name %s using driver %s", vol.VolumeConfig.DockerVolumeName, vol.VolumeConfig.Driver)
 	volumeResponse := vol.client.CreateVolume(
 		vol.ctx,
@@ -328,6 +345,11 @@ func (vol *VolumeResource) Create() error {
 
 	if volumeResponse.Error != nil {
 		vol.setTerminalReason(volumeResponse.Error.Error())
+		if vol.Encryption != nil {
+			if err := vol.Encryption.cleanup(vol.ctx); err != nil {
+				seelog.Warnf("Unable to remove encrypted device of volume %s: %v", vol.Name, err)
+			}
+		}
 		return volumeResponse.Error
 	}
 
@@ -337,6 +359,9 @@ func (vol *VolumeResource) Create() error {
 }
 
 func (vol *VolumeResource) getDriverOpts() map[string]string {
+	if vol.Encryption != nil {
+		return vol.Encryption.driverOpts()
+	}
 	opts := vol.VolumeConfig.DriverOpts
 	if vol.VolumeConfig.Driver != ECSVolumePlugin {
 		return opts
@@ -368,6 +393,13 @@ func (vol *VolumeResource) Cleanup() error {
 		vol.setTerminalReason(err.Error())
 		return err
 	}
+	if vol.Encryption != nil {
+		seelog.Debugf("Removing encrypted device of volume %s", vol.Name)
+		if err := vol.Encryption.cleanup(vol.ctx); err != nil {
+			vol.setTerminalReason(err.Error())
+			return err
+		}
+	}
 	return nil
 }
 
@@ -379,6 +411,7 @@ type volumeResourceJSON struct {
 	CreatedAt         time.Time          `json:"createdAt"`
 	DesiredStatus     *VolumeStatus      `json:"desiredStatus"`
 	KnownStatus       *VolumeStatus      `json:"knownStatus"`
+	Encryption        *EncryptionConfig  `json:"encryption,omitempty"`
 }
 
 // MarshalJSON marshals VolumeResource object using duplicate struct VolumeResourceJSON
@@ -393,6 +426,7 @@ func (vol *VolumeResource) MarshalJSON() ([]byte, error) {
 		vol.GetCreatedAt(),
 		func() *VolumeStatus { desiredState := VolumeStatus(vol.GetDesiredStatus()); return &desiredState }(),
 		func() *VolumeStatus { knownState := VolumeStatus(vol.GetKnownStatus()); return &knownState }(),
+		vol.Encryption,
 	})
 }
 
@@ -406,6 +440,7 @@ func (vol *VolumeResource) UnmarshalJSON(b []byte) error {
 
 	vol.Name = temp.Name
 	vol.VolumeConfig = temp.VolumeConfig
+	vol.Encryption = temp.Encryption
 	vol.SetPauseContainerPID(temp.PauseContainerPID)
 	if temp.DesiredStatus != nil {
 		vol.SetDesiredStatus(resourcestatus.ResourceStatus(*temp.DesiredStatus))
@@ -236,6 +236,20 @@ func TestUnmarshall(t *testing.T) {
 	assert.Equal(t, resourcestatus.ResourceStatus(VolumeStatusNone), unmarshalledVolume.GetKnownStatus())
 }
 
+func TestMarshalUnmarshalEncryptedVolume(t *testing.T) {
+	volume, _ := NewVolumeResource(context.TODO(), "scratch", "host", "volumeName", TaskScope, false,
+		DockerLocalVolumeDriver, map[string]string{}, map[string]string{}, nil)
+	volume.Encryption = NewEncryptionConfig("/data/encrypted-volumes", "volumeName", 16)
+
+	bytes, err := volume.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), "\"encryption\":{\"backingFile\":\"/data/encrypted-volumes/volumeName.img\",\"sizeMB\":16}")
+
+	unmarshalledVolume := &VolumeResource{}
+	assert.NoError(t, unmarshalledVolume.UnmarshalJSON(bytes))
+	assert.Equal(t, volume.Encryption, unmarshalledVolume.Encryption)
+}
+
 func TestNewVolumeResource(t *testing.T) {
 	testCases := []struct {
 		description   string
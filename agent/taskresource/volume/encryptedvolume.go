@@ -0,0 +1,76 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package volume
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+)
+
+const (
+	// EncryptedVolumesDir is the directory, relative to the data directory of the agent,
+	// keeping the backing files of the encrypted ephemeral volumes
+	EncryptedVolumesDir = "encrypted-volumes"
+	// encryptedVolumeFSType is the filesystem created on the encrypted devices
+	encryptedVolumeFSType = "ext4"
+	// encryptedDeviceNamePrefix is the prefix of the names of the dm-crypt devices
+	encryptedDeviceNamePrefix = "ecs-crypt-"
+	// encryptedDeviceNameHashLength is the number of hex characters of the hash of the
+	// backing file in the device names, which are limited to 127 characters
+	encryptedDeviceNameHashLength = 24
+)
+
+// devMapperDir is the directory of the device mapper devices, swappable for testing
+var devMapperDir = "/dev/mapper"
+
+// EncryptionConfig describes the dm-crypt device backing an encrypted ephemeral volume.
+// The key of the device isn't part of it: the key is generated when the device is opened,
+// never written to disk, and destroyed by the kernel when the device is closed.
+type EncryptionConfig struct {
+	// BackingFile is the sparse file the encrypted device is created on
+	BackingFile string `json:"backingFile"`
+	// SizeMB is the size, in MB, of the encrypted device
+	SizeMB int `json:"sizeMB"`
+}
+
+// NewEncryptionConfig returns the config of the encrypted device of a docker volume, whose
+// backing file is kept in the directory
+func NewEncryptionConfig(dir string, dockerVolumeName string, sizeMB int) *EncryptionConfig {
+	return &EncryptionConfig{
+		BackingFile: filepath.Join(dir, dockerVolumeName+".img"),
+		SizeMB:      sizeMB,
+	}
+}
+
+// deviceName returns the name of the dm-crypt device, which is derived from the backing
+// file so that it can be closed after an agent restart
+func (cfg *EncryptionConfig) deviceName() string {
+	sum := sha256.Sum256([]byte(cfg.BackingFile))
+	return encryptedDeviceNamePrefix + hex.EncodeToString(sum[:])[:encryptedDeviceNameHashLength]
+}
+
+// devicePath returns the path of the dm-crypt device
+func (cfg *EncryptionConfig) devicePath() string {
+	return filepath.Join(devMapperDir, cfg.deviceName())
+}
+
+// driverOpts returns the options of the docker local volume driver mounting the filesystem
+// of the encrypted device
+func (cfg *EncryptionConfig) driverOpts() map[string]string {
+	return map[string]string{
+		"type":   encryptedVolumeFSType,
+		"device": cfg.devicePath(),
+	}
+}
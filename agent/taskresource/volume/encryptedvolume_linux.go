@@ -0,0 +1,172 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package volume
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	cryptsetupBinary = "cryptsetup"
+	mkfsBinary       = "mkfs." + encryptedVolumeFSType
+	// encryptedDeviceCipher and encryptedDeviceKeySizeBits are the cipher and the key size
+	// of the encrypted devices. XTS splits the key in two, for AES-256.
+	encryptedDeviceCipher      = "aes-xts-plain64"
+	encryptedDeviceKeySizeBits = 512
+	bytesPerMB                 = 1024 * 1024
+	// capSysAdmin is the bit of CAP_SYS_ADMIN in the capability sets, which device mapper
+	// and loop devices require
+	capSysAdmin = 21
+)
+
+var (
+	// execCommand is swappable for testing
+	execCommand = exec.CommandContext
+	// lookPath, procSelfStatusPath and loopControlPath are swappable for testing
+	lookPath           = exec.LookPath
+	procSelfStatusPath = "/proc/self/status"
+	loopControlPath    = "/dev/loop-control"
+)
+
+// CheckEncryptedVolumeSupport checks that the agent can set up encrypted volumes: cryptsetup
+// and mkfs have to be installed in the agent container, which has to run with CAP_SYS_ADMIN and
+// the device mapper and loop control devices of the host.
+func CheckEncryptedVolumeSupport() error {
+	for _, binary := range []string{cryptsetupBinary, mkfsBinary} {
+		if _, err := lookPath(binary); err != nil {
+			return errors.Wrapf(err, "%s isn't installed", binary)
+		}
+	}
+	hasCap, err := hasEffectiveCapability(capSysAdmin)
+	if err != nil {
+		return err
+	}
+	if !hasCap {
+		return errors.New("the agent doesn't have the CAP_SYS_ADMIN capability")
+	}
+	for _, device := range []string{filepath.Join(devMapperDir, "control"), loopControlPath} {
+		if _, err := os.Stat(device); err != nil {
+			return errors.Wrapf(err, "device %s isn't available", device)
+		}
+	}
+	return nil
+}
+
+// hasEffectiveCapability returns whether the capability is in the effective set of the process
+func hasEffectiveCapability(capability uint) (bool, error) {
+	file, err := os.Open(procSelfStatusPath)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to read the capabilities of the agent")
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		capabilities, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false, errors.Wrapf(err, "unable to parse the capabilities of the agent")
+		}
+		return capabilities&(1<<capability) != 0, nil
+	}
+	return false, errors.New("unable to find the capabilities of the agent")
+}
+
+// setup creates the sparse backing file of the volume, opens a dm-crypt device on it with
+// a random key, and creates a filesystem on the device. The key is passed to cryptsetup on
+// stdin, and only lives in the kernel once the device is open.
+func (cfg *EncryptionConfig) setup(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(cfg.BackingFile), 0700); err != nil {
+		return errors.Wrap(err, "unable to create the directory of the encrypted volume")
+	}
+	file, err := os.OpenFile(cfg.BackingFile, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "unable to create the backing file of the encrypted volume")
+	}
+	err = file.Truncate(int64(cfg.SizeMB) * bytesPerMB)
+	file.Close()
+	if err != nil {
+		return errors.Wrap(err, "unable to size the backing file of the encrypted volume")
+	}
+
+	// a device left open by a previous attempt can't be reused, as its key is gone
+	if err := cfg.close(ctx); err != nil {
+		return err
+	}
+	key := make([]byte, encryptedDeviceKeySizeBits/8)
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
+	if _, err := rand.Read(key); err != nil {
+		return errors.Wrap(err, "unable to generate the key of the encrypted volume")
+	}
+	if err := runCommand(ctx, bytes.NewReader(key), cryptsetupBinary, "open", "--type", "plain",
+		"--cipher", encryptedDeviceCipher, "--key-size", strconv.Itoa(encryptedDeviceKeySizeBits),
+		"--key-file", "-", cfg.BackingFile, cfg.deviceName()); err != nil {
+		return err
+	}
+	if err := runCommand(ctx, nil, mkfsBinary, "-q", "-F", cfg.devicePath()); err != nil {
+		cfg.close(ctx)
+		return err
+	}
+	return nil
+}
+
+// cleanup closes the dm-crypt device of the volume, which destroys its key, and removes its
+// backing file
+func (cfg *EncryptionConfig) cleanup(ctx context.Context) error {
+	if err := cfg.close(ctx); err != nil {
+		return err
+	}
+	if err := os.Remove(cfg.BackingFile); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "unable to remove the backing file of the encrypted volume")
+	}
+	return nil
+}
+
+// close closes the dm-crypt device of the volume, if it's open
+func (cfg *EncryptionConfig) close(ctx context.Context) error {
+	if _, err := os.Stat(cfg.devicePath()); os.IsNotExist(err) {
+		return nil
+	}
+	return runCommand(ctx, nil, cryptsetupBinary, "close", cfg.deviceName())
+}
+
+func runCommand(ctx context.Context, stdin io.Reader, name string, args ...string) error {
+	cmd := execCommand(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "%s %s failed: %s", name, args[0], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
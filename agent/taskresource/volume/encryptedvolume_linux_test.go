@@ -0,0 +1,177 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package volume
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupFakeCryptsetup records the commands run, and opens and closes the dm-crypt devices
+// as files in a temporary directory
+func setupFakeCryptsetup(t *testing.T) (*[]string, func()) {
+	devMapperDir = t.TempDir()
+	var commands []string
+	execCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		commands = append(commands, name+" "+args[0])
+		switch {
+		case name == cryptsetupBinary && args[0] == "open":
+			require.NoError(t, ioutil.WriteFile(filepath.Join(devMapperDir, args[len(args)-1]), nil, 0600))
+		case name == cryptsetupBinary && args[0] == "close":
+			require.NoError(t, os.Remove(filepath.Join(devMapperDir, args[1])))
+		}
+		cs := []string{"-test.run=TestHelperProcess", "--", name}
+		cmd := exec.CommandContext(ctx, os.Args[0], append(cs, args...)...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	return &commands, func() {
+		devMapperDir = "/dev/mapper"
+		execCommand = exec.CommandContext
+	}
+}
+
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	os.Exit(0)
+}
+
+func TestCreateAndCleanupEncryptedVolume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock_dockerapi.NewMockDockerClient(ctrl)
+	commands, cleanup := setupFakeCryptsetup(t)
+	defer cleanup()
+
+	name := "ecs-family-1-scratch-abcdef"
+	volume, err := NewVolumeResource(context.TODO(), "scratch", "host", name, TaskScope, false,
+		DockerLocalVolumeDriver, map[string]string{}, map[string]string{}, mockClient)
+	require.NoError(t, err)
+	require.True(t, volume.IsEphemeral())
+	volume.Encryption = NewEncryptionConfig(t.TempDir(), name, 16)
+	devicePath := filepath.Join(devMapperDir, volume.Encryption.deviceName())
+
+	mockClient.EXPECT().CreateVolume(gomock.Any(), name, DockerLocalVolumeDriver,
+		map[string]string{"type": "ext4", "device": devicePath}, gomock.Any(), dockerclient.CreateVolumeTimeout).Return(
+		dockerapi.SDKVolumeResponse{DockerVolume: &types.Volume{Name: name}})
+	require.NoError(t, volume.Create())
+	assert.Equal(t, []string{"cryptsetup open", "mkfs.ext4 -q"}, *commands)
+	info, err := os.Stat(volume.Encryption.BackingFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(16*bytesPerMB), info.Size())
+	assert.FileExists(t, devicePath)
+
+	mockClient.EXPECT().RemoveVolume(gomock.Any(), name, dockerclient.RemoveVolumeTimeout).Return(nil)
+	require.NoError(t, volume.Cleanup())
+	assert.Equal(t, "cryptsetup close", (*commands)[2])
+	_, err = os.Stat(devicePath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(volume.Encryption.BackingFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCreateEncryptedVolumeClosesDeviceOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mock_dockerapi.NewMockDockerClient(ctrl)
+	commands, cleanup := setupFakeCryptsetup(t)
+	defer cleanup()
+
+	name := "ecs-family-1-scratch-abcdef"
+	volume, err := NewVolumeResource(context.TODO(), "scratch", "host", name, TaskScope, false,
+		DockerLocalVolumeDriver, map[string]string{}, map[string]string{}, mockClient)
+	require.NoError(t, err)
+	volume.Encryption = NewEncryptionConfig(t.TempDir(), name, 16)
+
+	mockClient.EXPECT().CreateVolume(gomock.Any(), name, DockerLocalVolumeDriver, gomock.Any(), gomock.Any(),
+		dockerclient.CreateVolumeTimeout).Return(dockerapi.SDKVolumeResponse{Error: assert.AnError})
+	assert.Error(t, volume.Create())
+	assert.Equal(t, "cryptsetup close", (*commands)[len(*commands)-1])
+	_, err = os.Stat(volume.Encryption.BackingFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestEncryptedVolumeDeviceName(t *testing.T) {
+	cfg := NewEncryptionConfig("/data/encrypted-volumes", strings.Repeat("a", 255), 16)
+	assert.True(t, strings.HasPrefix(cfg.deviceName(), encryptedDeviceNamePrefix))
+	assert.Len(t, cfg.deviceName(), len(encryptedDeviceNamePrefix)+encryptedDeviceNameHashLength)
+	assert.Equal(t, cfg.deviceName(), NewEncryptionConfig("/data/encrypted-volumes", strings.Repeat("a", 255), 32).deviceName())
+}
+
+// setupEncryptedVolumeSupport fakes the binaries, capabilities and devices needed to set up
+// encrypted volumes
+func setupEncryptedVolumeSupport(t *testing.T, capEff string) func() {
+	devMapperDir = t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(devMapperDir, "control"), nil, 0600))
+	loopControlPath = filepath.Join(t.TempDir(), "loop-control")
+	require.NoError(t, ioutil.WriteFile(loopControlPath, nil, 0600))
+	procSelfStatusPath = filepath.Join(t.TempDir(), "status")
+	require.NoError(t, ioutil.WriteFile(procSelfStatusPath,
+		[]byte("Name:\tagent\nCapPrm:\t"+capEff+"\nCapEff:\t"+capEff+"\n"), 0600))
+	lookPath = func(file string) (string, error) {
+		return "/usr/sbin/" + file, nil
+	}
+	return func() {
+		devMapperDir = "/dev/mapper"
+		loopControlPath = "/dev/loop-control"
+		procSelfStatusPath = "/proc/self/status"
+		lookPath = exec.LookPath
+	}
+}
+
+func TestCheckEncryptedVolumeSupport(t *testing.T) {
+	cleanup := setupEncryptedVolumeSupport(t, "000001ffffffffff")
+	defer cleanup()
+
+	assert.NoError(t, CheckEncryptedVolumeSupport())
+}
+
+func TestCheckEncryptedVolumeSupportWithoutCapSysAdmin(t *testing.T) {
+	// the default capabilities of docker containers
+	cleanup := setupEncryptedVolumeSupport(t, "00000000a80425fb")
+	defer cleanup()
+
+	err := CheckEncryptedVolumeSupport()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CAP_SYS_ADMIN")
+}
+
+func TestCheckEncryptedVolumeSupportWithoutCryptsetup(t *testing.T) {
+	cleanup := setupEncryptedVolumeSupport(t, "000001ffffffffff")
+	defer cleanup()
+	lookPath = func(file string) (string, error) {
+		return "", exec.ErrNotFound
+	}
+
+	err := CheckEncryptedVolumeSupport()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), cryptsetupBinary)
+}
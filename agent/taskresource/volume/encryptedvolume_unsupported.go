@@ -0,0 +1,37 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package volume
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// CheckEncryptedVolumeSupport returns an error, as the encrypted volumes are backed by
+// dm-crypt devices
+func CheckEncryptedVolumeSupport() error {
+	return errors.New("encrypted volumes are only supported on Linux")
+}
+
+// setup isn't supported, as the encrypted volumes are backed by dm-crypt devices
+func (cfg *EncryptionConfig) setup(ctx context.Context) error {
+	return errors.New("encrypted volumes are only supported on Linux")
+}
+
+func (cfg *EncryptionConfig) cleanup(ctx context.Context) error {
+	return nil
+}
@@ -48,24 +48,29 @@ type clientServer struct {
 	cancel                 context.CancelFunc
 	disableResourceMetrics bool
 	publishMetricsInterval time.Duration
+	buffer                 *TelemetryBuffer
 	wsclient.ClientServerImpl
 }
 
 // New returns a client/server to bidirectionally communicate with the backend.
 // The returned struct should have both 'Connect' and 'Serve' called upon it
-// before being used.
+// before being used. If buffer is not nil, the requests that can't be sent are
+// buffered in it, and the buffered requests are sent before any other once the
+// client is serving.
 func New(url string,
 	cfg *config.Config,
 	credentialProvider *credentials.Credentials,
 	statsEngine stats.Engine,
 	publishMetricsInterval time.Duration,
 	rwTimeout time.Duration,
-	disableResourceMetrics bool) wsclient.ClientServer {
+	disableResourceMetrics bool,
+	buffer *TelemetryBuffer) wsclient.ClientServer {
 	cs := &clientServer{
 		statsEngine:            statsEngine,
 		publishTicker:          nil,
 		publishHealthTicker:    nil,
 		publishMetricsInterval: publishMetricsInterval,
+		buffer:                 buffer,
 	}
 	cs.URL = url
 	cs.AgentConfig = cfg
@@ -98,10 +103,15 @@ func (cs *clientServer) Serve() error {
 	cs.publishTicker = time.NewTicker(cs.publishMetricsInterval)
 	cs.publishHealthTicker = time.NewTicker(cs.publishMetricsInterval)
 
-	if !cs.disableResourceMetrics {
-		go cs.publishMetrics()
-	}
-	go cs.publishHealthMetrics()
+	go func() {
+		// Send the telemetry buffered while disconnected first, so that the
+		// backend receives the telemetry in order
+		cs.flushBuffer()
+		if !cs.disableResourceMetrics {
+			go cs.publishMetrics()
+		}
+		go cs.publishHealthMetrics()
+	}()
 
 	return cs.ConsumeMessages()
 }
@@ -176,9 +186,12 @@ func (cs *clientServer) publishMetricsOnce() error {
 	}
 
 	// Make the publish metrics request to the backend.
-	for _, request := range requests {
+	for i, request := range requests {
 		err = cs.MakeRequest(request)
 		if err != nil {
+			for _, unsent := range requests[i:] {
+				cs.bufferRequest(unsent)
+			}
 			return err
 		}
 	}
@@ -263,9 +276,12 @@ func (cs *clientServer) publishHealthMetricsOnce() error {
 		return err
 	}
 	// Make the publish metrics request to the backend.
-	for _, request := range requests {
+	for i, request := range requests {
 		err = cs.MakeRequest(request)
 		if err != nil {
+			for _, unsent := range requests[i:] {
+				cs.bufferRequest(unsent)
+			}
 			return err
 		}
 	}
@@ -308,6 +324,53 @@ func (cs *clientServer) createPublishHealthRequests() ([]*ecstcs.PublishHealthRe
 	return requests, nil
 }
 
+// flushBuffer sends the requests buffered while the client was disconnected
+func (cs *clientServer) flushBuffer() {
+	if cs.buffer == nil || cs.buffer.Len() == 0 {
+		return
+	}
+	seelog.Infof("Sending %d buffered telemetry requests to TCS", cs.buffer.Len())
+	if err := cs.buffer.Flush(cs.MakeRequest); err != nil {
+		seelog.Warnf("Unable to send buffered telemetry requests: %v", err)
+	}
+}
+
+// bufferRequest buffers a request that could not be sent, to send it on the next connection
+func (cs *clientServer) bufferRequest(request interface{}) {
+	if cs.buffer == nil {
+		return
+	}
+	if err := cs.buffer.Add(request); err != nil {
+		seelog.Warnf("Unable to buffer telemetry request: %v", err)
+	}
+}
+
+// BufferTelemetry collects the metrics and the health of the tasks and adds them to the
+// buffer. It's used to keep publishing telemetry while there is no connection to TCS.
+func BufferTelemetry(statsEngine stats.Engine, buffer *TelemetryBuffer, disableResourceMetrics bool) error {
+	cs := &clientServer{
+		statsEngine: statsEngine,
+		buffer:      buffer,
+	}
+	if !disableResourceMetrics {
+		requests, err := cs.metricsToPublishMetricRequests()
+		if err != nil {
+			return err
+		}
+		for _, request := range requests {
+			cs.bufferRequest(request)
+		}
+	}
+	requests, err := cs.createPublishHealthRequests()
+	if err != nil {
+		return err
+	}
+	for _, request := range requests {
+		cs.bufferRequest(request)
+	}
+	return nil
+}
+
 // copyMetricsMetadata creates a new MetricsMetadata object from a given MetricsMetadata object.
 // It copies all the fields from the source object to the new object and sets the 'Fin' field
 // as specified by the argument.
@@ -60,6 +60,18 @@ func (*mockStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*mockStatsEngine) ContainerDockerStatsSnapshot(taskARN string, id string) (*types.StatsJSON, *stats.NetworkStatsPerSec, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerDiskUsage(taskARN string, id string) (*stats.DiskUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerGPUStats(taskARN string, id string) ([]*stats.GPUStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*mockStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -74,6 +86,18 @@ func (*emptyStatsEngine) ContainerDockerStats(taskARN string, id string) (*types
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*emptyStatsEngine) ContainerDockerStatsSnapshot(taskARN string, id string) (*types.StatsJSON, *stats.NetworkStatsPerSec, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (*emptyStatsEngine) ContainerDiskUsage(taskARN string, id string) (*stats.DiskUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*emptyStatsEngine) ContainerGPUStats(taskARN string, id string) ([]*stats.GPUStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*emptyStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -94,6 +118,18 @@ func (*idleStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*idleStatsEngine) ContainerDockerStatsSnapshot(taskARN string, id string) (*types.StatsJSON, *stats.NetworkStatsPerSec, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (*idleStatsEngine) ContainerDiskUsage(taskARN string, id string) (*stats.DiskUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*idleStatsEngine) ContainerGPUStats(taskARN string, id string) ([]*stats.GPUStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*idleStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -122,6 +158,18 @@ func (*nonIdleStatsEngine) ContainerDockerStats(taskARN string, id string) (*typ
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*nonIdleStatsEngine) ContainerDockerStatsSnapshot(taskARN string, id string) (*types.StatsJSON, *stats.NetworkStatsPerSec, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (*nonIdleStatsEngine) ContainerDiskUsage(taskARN string, id string) (*stats.DiskUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*nonIdleStatsEngine) ContainerGPUStats(taskARN string, id string) ([]*stats.GPUStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*nonIdleStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -246,7 +294,7 @@ func testCS(conn *mock_wsconn.MockWebsocketConn) wsclient.ClientServer {
 		AcceptInsecureCert: true,
 	}
 	cs := New("https://aws.amazon.com/ecs", cfg, testCreds, &mockStatsEngine{},
-		testPublishMetricsInterval, rwTimeout, false).(*clientServer)
+		testPublishMetricsInterval, rwTimeout, false, nil).(*clientServer)
 	cs.SetConnection(conn)
 	return cs
 }
@@ -313,7 +361,7 @@ func TestMetricsDisabled(t *testing.T) {
 
 	cfg := config.DefaultConfig()
 
-	cs := New("", &cfg, testCreds, mockStatsEngine, testPublishMetricsInterval, rwTimeout, true)
+	cs := New("", &cfg, testCreds, mockStatsEngine, testPublishMetricsInterval, rwTimeout, true, nil)
 	cs.SetConnection(conn)
 
 	published := make(chan struct{})
@@ -348,7 +396,7 @@ func TestCreatePublishHealthRequestsEmpty(t *testing.T) {
 	mockStatsEngine := mock_stats.NewMockEngine(ctrl)
 	cfg := config.DefaultConfig()
 
-	cs := New("", &cfg, testCreds, mockStatsEngine, testPublishMetricsInterval, rwTimeout, true)
+	cs := New("", &cfg, testCreds, mockStatsEngine, testPublishMetricsInterval, rwTimeout, true, nil)
 	cs.SetConnection(conn)
 
 	mockStatsEngine.EXPECT().GetTaskHealthMetrics().Return(nil, nil, stats.EmptyHealthMetricsError)
@@ -368,7 +416,7 @@ func TestCreatePublishHealthRequests(t *testing.T) {
 	mockStatsEngine := mock_stats.NewMockEngine(ctrl)
 	cfg := config.DefaultConfig()
 
-	cs := New("", &cfg, testCreds, mockStatsEngine, testPublishMetricsInterval, rwTimeout, true)
+	cs := New("", &cfg, testCreds, mockStatsEngine, testPublishMetricsInterval, rwTimeout, true, nil)
 	cs.SetConnection(conn)
 
 	testMetadata := &ecstcs.HealthMetadata{
@@ -0,0 +1,254 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcsclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	metricsBufferEntryKind  = "metrics"
+	healthBufferEntryKind   = "health"
+	bufferEntryExtension    = ".json.gz"
+	bufferEntryNameFormat   = "%020d-%s" + bufferEntryExtension
+	telemetryBufferDirPerm  = 0700
+	telemetryBufferFilePerm = 0600
+)
+
+// TelemetryBuffer keeps the telemetry requests that could not be sent to TCS on disk,
+// so that they are sent in order once the agent is connected again instead of being
+// dropped. The requests are gzip-compressed, and the oldest ones are dropped once the
+// buffer reaches its maximum size.
+type TelemetryBuffer struct {
+	dir     string
+	maxSize int64
+	lock    sync.Mutex
+	size    int64
+	nextSeq uint64
+	entries []telemetryBufferEntry
+}
+
+type telemetryBufferEntry struct {
+	seq  uint64
+	kind string
+	size int64
+}
+
+// NewTelemetryBuffer returns a buffer of at most maxSize bytes stored in dir. The
+// requests already buffered in dir, e.g. before the agent restarted, are kept.
+func NewTelemetryBuffer(dir string, maxSize int64) (*TelemetryBuffer, error) {
+	if err := os.MkdirAll(dir, telemetryBufferDirPerm); err != nil {
+		return nil, errors.Wrap(err, "telemetry buffer: unable to create buffer directory")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "telemetry buffer: unable to read buffer directory")
+	}
+
+	buffer := &TelemetryBuffer{
+		dir:     dir,
+		maxSize: maxSize,
+	}
+	for _, file := range files {
+		entry, ok := parseTelemetryBufferEntry(file.Name())
+		if !ok {
+			continue
+		}
+		entry.size = file.Size()
+		buffer.entries = append(buffer.entries, entry)
+		buffer.size += entry.size
+		if entry.seq >= buffer.nextSeq {
+			buffer.nextSeq = entry.seq + 1
+		}
+	}
+	sort.Slice(buffer.entries, func(i, j int) bool {
+		return buffer.entries[i].seq < buffer.entries[j].seq
+	})
+	buffer.lock.Lock()
+	buffer.trimUnsafe(0)
+	buffer.lock.Unlock()
+	return buffer, nil
+}
+
+// Len returns the number of buffered requests
+func (buffer *TelemetryBuffer) Len() int {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	return len(buffer.entries)
+}
+
+// Add buffers a PublishMetricsRequest or a PublishHealthRequest, dropping the oldest
+// buffered requests if there isn't enough room left for it
+func (buffer *TelemetryBuffer) Add(request interface{}) error {
+	var kind string
+	switch request.(type) {
+	case *ecstcs.PublishMetricsRequest:
+		kind = metricsBufferEntryKind
+	case *ecstcs.PublishHealthRequest:
+		kind = healthBufferEntryKind
+	default:
+		return errors.Errorf("telemetry buffer: unsupported request type %T", request)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "telemetry buffer: unable to encode request")
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(payload); err != nil {
+		return errors.Wrap(err, "telemetry buffer: unable to compress request")
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "telemetry buffer: unable to compress request")
+	}
+	size := int64(compressed.Len())
+	if size > buffer.maxSize {
+		return errors.Errorf("telemetry buffer: request of %d bytes exceeds the buffer size of %d bytes",
+			size, buffer.maxSize)
+	}
+
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	buffer.trimUnsafe(size)
+	entry := telemetryBufferEntry{
+		seq:  buffer.nextSeq,
+		kind: kind,
+		size: size,
+	}
+	if err := ioutil.WriteFile(buffer.entryPath(entry), compressed.Bytes(), telemetryBufferFilePerm); err != nil {
+		return errors.Wrap(err, "telemetry buffer: unable to write request")
+	}
+	buffer.nextSeq++
+	buffer.entries = append(buffer.entries, entry)
+	buffer.size += size
+	return nil
+}
+
+// Flush sends the buffered requests in the order they were added. A request is removed
+// from the buffer once it's sent; Flush stops at the first request that can't be sent,
+// which is kept to be sent on the next flush.
+func (buffer *TelemetryBuffer) Flush(send func(request interface{}) error) error {
+	for {
+		buffer.lock.Lock()
+		if len(buffer.entries) == 0 {
+			buffer.lock.Unlock()
+			return nil
+		}
+		entry := buffer.entries[0]
+		buffer.lock.Unlock()
+
+		request, err := buffer.read(entry)
+		if err != nil {
+			seelog.Warnf("Dropping buffered telemetry request that can't be read: %v", err)
+			buffer.remove(entry)
+			continue
+		}
+		if err := send(request); err != nil {
+			return err
+		}
+		buffer.remove(entry)
+	}
+}
+
+// read decodes the request of a buffered entry
+func (buffer *TelemetryBuffer) read(entry telemetryBufferEntry) (interface{}, error) {
+	file, err := os.Open(buffer.entryPath(entry))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var request interface{}
+	switch entry.kind {
+	case metricsBufferEntryKind:
+		request = &ecstcs.PublishMetricsRequest{}
+	default:
+		request = &ecstcs.PublishHealthRequest{}
+	}
+	if err := json.NewDecoder(reader).Decode(request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// remove removes an entry from the buffer, unless it was already dropped while it was
+// being sent
+func (buffer *TelemetryBuffer) remove(entry telemetryBufferEntry) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	if len(buffer.entries) == 0 || buffer.entries[0].seq != entry.seq {
+		return
+	}
+	buffer.removeOldestUnsafe()
+}
+
+// trimUnsafe drops the oldest entries until there's room for an entry of the given size
+func (buffer *TelemetryBuffer) trimUnsafe(size int64) {
+	dropped := 0
+	for len(buffer.entries) > 0 && buffer.size+size > buffer.maxSize {
+		buffer.removeOldestUnsafe()
+		dropped++
+	}
+	if dropped > 0 {
+		seelog.Warnf("Telemetry buffer is full, dropped the %d oldest buffered requests", dropped)
+	}
+}
+
+func (buffer *TelemetryBuffer) removeOldestUnsafe() {
+	entry := buffer.entries[0]
+	if err := os.Remove(buffer.entryPath(entry)); err != nil && !os.IsNotExist(err) {
+		seelog.Warnf("Unable to remove buffered telemetry request: %v", err)
+	}
+	buffer.entries = buffer.entries[1:]
+	buffer.size -= entry.size
+}
+
+func (buffer *TelemetryBuffer) entryPath(entry telemetryBufferEntry) string {
+	return filepath.Join(buffer.dir, fmt.Sprintf(bufferEntryNameFormat, entry.seq, entry.kind))
+}
+
+// parseTelemetryBufferEntry parses the sequence number and the kind of request of an
+// entry from the name of its file
+func parseTelemetryBufferEntry(name string) (telemetryBufferEntry, bool) {
+	if !strings.HasSuffix(name, bufferEntryExtension) {
+		return telemetryBufferEntry{}, false
+	}
+	var entry telemetryBufferEntry
+	if _, err := fmt.Sscanf(strings.TrimSuffix(name, bufferEntryExtension), "%d-%s", &entry.seq, &entry.kind); err != nil {
+		return telemetryBufferEntry{}, false
+	}
+	if entry.kind != metricsBufferEntryKind && entry.kind != healthBufferEntryKind {
+		return telemetryBufferEntry{}, false
+	}
+	return entry, true
+}
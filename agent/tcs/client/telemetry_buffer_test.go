@@ -0,0 +1,172 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcsclient
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	mock_wsconn "github.com/aws/amazon-ecs-agent/agent/wsclient/wsconn/mock"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMetricsRequest(messageID string) *ecstcs.PublishMetricsRequest {
+	return ecstcs.NewPublishMetricsRequest(&ecstcs.MetricsMetadata{
+		Cluster:           aws.String(testCluster),
+		ContainerInstance: aws.String(testContainerInstance),
+		Idle:              aws.Bool(true),
+		Fin:               aws.Bool(true),
+		MessageId:         aws.String(messageID),
+	}, nil)
+}
+
+func newTestHealthRequest(messageID string) *ecstcs.PublishHealthRequest {
+	return ecstcs.NewPublishHealthMetricsRequest(&ecstcs.HealthMetadata{
+		Cluster:           aws.String(testCluster),
+		ContainerInstance: aws.String(testContainerInstance),
+		Fin:               aws.Bool(true),
+		MessageId:         aws.String(messageID),
+	}, nil)
+}
+
+// requestMessageID returns the message id of a buffered request
+func requestMessageID(t *testing.T, request interface{}) string {
+	switch r := request.(type) {
+	case *ecstcs.PublishMetricsRequest:
+		return aws.StringValue(r.Metadata.MessageId)
+	case *ecstcs.PublishHealthRequest:
+		return aws.StringValue(r.Metadata.MessageId)
+	}
+	t.Fatalf("Unexpected request type %T", request)
+	return ""
+}
+
+func newTestTelemetryBuffer(t *testing.T, maxSize int64) (*TelemetryBuffer, string) {
+	dir, err := ioutil.TempDir("", "telemetry-buffer")
+	require.NoError(t, err)
+	buffer, err := NewTelemetryBuffer(dir, maxSize)
+	require.NoError(t, err)
+	return buffer, dir
+}
+
+func TestTelemetryBufferFlushesInOrder(t *testing.T) {
+	buffer, dir := newTestTelemetryBuffer(t, 1024*1024)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, buffer.Add(newTestMetricsRequest("1")))
+	require.NoError(t, buffer.Add(newTestHealthRequest("2")))
+	require.NoError(t, buffer.Add(newTestMetricsRequest("3")))
+	assert.Error(t, buffer.Add(&ecstcs.HeartbeatMessage{}))
+	assert.Equal(t, 3, buffer.Len())
+
+	var sent []string
+	err := buffer.Flush(func(request interface{}) error {
+		sent = append(sent, requestMessageID(t, request))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, sent)
+	assert.Equal(t, 0, buffer.Len())
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestTelemetryBufferDropsOldestRequestsWhenFull(t *testing.T) {
+	buffer, dir := newTestTelemetryBuffer(t, 1024*1024)
+	defer os.RemoveAll(dir)
+	require.NoError(t, buffer.Add(newTestMetricsRequest("1")))
+	entrySize := buffer.size
+
+	// Make room for two requests only
+	buffer.maxSize = 2*entrySize + entrySize/2
+	require.NoError(t, buffer.Add(newTestMetricsRequest("2")))
+	require.NoError(t, buffer.Add(newTestMetricsRequest("3")))
+	assert.Equal(t, 2, buffer.Len())
+
+	var sent []string
+	buffer.Flush(func(request interface{}) error {
+		sent = append(sent, requestMessageID(t, request))
+		return nil
+	})
+	assert.Equal(t, []string{"2", "3"}, sent)
+}
+
+func TestTelemetryBufferKeepsUnsentRequests(t *testing.T) {
+	buffer, dir := newTestTelemetryBuffer(t, 1024*1024)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, buffer.Add(newTestMetricsRequest("1")))
+	require.NoError(t, buffer.Add(newTestHealthRequest("2")))
+	err := buffer.Flush(func(request interface{}) error {
+		if requestMessageID(t, request) == "2" {
+			return errors.New("connection closed")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, buffer.Len())
+
+	// The unsent request is still buffered after a restart, before the new ones
+	restored, err := NewTelemetryBuffer(dir, 1024*1024)
+	require.NoError(t, err)
+	require.NoError(t, restored.Add(newTestMetricsRequest("3")))
+	var sent []string
+	restored.Flush(func(request interface{}) error {
+		sent = append(sent, requestMessageID(t, request))
+		return nil
+	})
+	assert.Equal(t, []string{"2", "3"}, sent)
+}
+
+func TestBufferTelemetry(t *testing.T) {
+	buffer, dir := newTestTelemetryBuffer(t, 1024*1024)
+	defer os.RemoveAll(dir)
+
+	// 21 task metrics are sent in 3 requests
+	assert.NoError(t, BufferTelemetry(newNonIdleStatsEngine(21), buffer, false))
+	assert.Equal(t, 3, buffer.Len())
+
+	assert.NoError(t, BufferTelemetry(newNonIdleStatsEngine(21), buffer, true))
+	assert.Equal(t, 3, buffer.Len())
+}
+
+func TestPublishMetricsOnceBuffersUnsentRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	buffer, dir := newTestTelemetryBuffer(t, 1024*1024)
+	defer os.RemoveAll(dir)
+
+	conn := mock_wsconn.NewMockWebsocketConn(ctrl)
+	conn.EXPECT().SetWriteDeadline(gomock.Any()).Return(nil)
+	conn.EXPECT().WriteMessage(gomock.Any(), gomock.Any()).Return(errors.New("connection closed"))
+
+	cs := testCS(conn).(*clientServer)
+	cs.statsEngine = &idleStatsEngine{}
+	cs.buffer = buffer
+	assert.Error(t, cs.publishMetricsOnce())
+	assert.Equal(t, 1, buffer.Len())
+
+	conn.EXPECT().SetWriteDeadline(gomock.Any()).Return(nil)
+	conn.EXPECT().WriteMessage(gomock.Any(), gomock.Any()).Return(nil)
+	cs.flushBuffer()
+	assert.Equal(t, 0, buffer.Len())
+}
@@ -75,8 +75,10 @@ func StartMetricsSession(params *TelemetrySessionParams) {
 // the time the websocket client starts using it.
 func StartSession(params *TelemetrySessionParams, statsEngine stats.Engine) error {
 	backoff := retry.NewExponentialBackoff(time.Second, 1*time.Minute, 0.2, 2)
+	bufferer := newTelemetryBufferer(params.Cfg, statsEngine)
+	go bufferer.start(params.Ctx, config.DefaultContainerMetricsPublishInterval)
 	for {
-		tcsError := startTelemetrySession(params, statsEngine)
+		tcsError := startTelemetrySession(params, statsEngine, bufferer)
 		if tcsError == nil || tcsError == io.EOF {
 			seelog.Info("TCS Websocket connection closed for a valid reason")
 			backoff.Reset()
@@ -93,7 +95,8 @@ func StartSession(params *TelemetrySessionParams, statsEngine stats.Engine) erro
 	}
 }
 
-func startTelemetrySession(params *TelemetrySessionParams, statsEngine stats.Engine) error {
+func startTelemetrySession(params *TelemetrySessionParams, statsEngine stats.Engine,
+	bufferer *telemetryBufferer) error {
 	tcsEndpoint, err := params.ECSClient.DiscoverTelemetryEndpoint(params.ContainerInstanceArn)
 	if err != nil {
 		seelog.Errorf("tcs: unable to discover poll endpoint: %v", err)
@@ -102,7 +105,7 @@ func startTelemetrySession(params *TelemetrySessionParams, statsEngine stats.Eng
 	url := formatURL(tcsEndpoint, params.Cfg.Cluster, params.ContainerInstanceArn, params.TaskEngine)
 	return startSession(params.Ctx, url, params.Cfg, params.CredentialProvider, statsEngine,
 		defaultHeartbeatTimeout, defaultHeartbeatJitter, config.DefaultContainerMetricsPublishInterval,
-		params.DeregisterInstanceEventStream)
+		params.DeregisterInstanceEventStream, bufferer)
 }
 
 func startSession(
@@ -113,9 +116,10 @@ func startSession(
 	statsEngine stats.Engine,
 	heartbeatTimeout, heartbeatJitter,
 	publishMetricsInterval time.Duration,
-	deregisterInstanceEventStream *eventstream.EventStream) error {
+	deregisterInstanceEventStream *eventstream.EventStream,
+	bufferer *telemetryBufferer) error {
 	client := tcsclient.New(url, cfg, credentialProvider, statsEngine,
-		publishMetricsInterval, wsRWTimeout, cfg.DisableMetrics.Enabled())
+		publishMetricsInterval, wsRWTimeout, cfg.DisableMetrics.Enabled(), bufferer.telemetryBuffer())
 	defer client.Close()
 
 	err := deregisterInstanceEventStream.Subscribe(deregisterContainerInstanceHandler, client.Disconnect)
@@ -130,6 +134,8 @@ func startSession(
 		return err
 	}
 	seelog.Info("Connected to TCS endpoint")
+	bufferer.setConnected(true)
+	defer bufferer.setConnected(false)
 	// start a timer and listens for tcs heartbeats/acks. The timer is reset when
 	// we receive a heartbeat from the server or when a publish metrics message
 	// is acked.
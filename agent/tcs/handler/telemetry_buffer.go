@@ -0,0 +1,109 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcshandler
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	tcsclient "github.com/aws/amazon-ecs-agent/agent/tcs/client"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// telemetryBufferDir is the directory of the telemetry buffer in the data directory
+	telemetryBufferDir = "telemetry"
+	bytesInMB          = 1024 * 1024
+)
+
+// telemetryBufferer keeps collecting the telemetry while there is no connection to TCS,
+// and buffers it so that it's sent once the agent is connected again
+type telemetryBufferer struct {
+	buffer                 *tcsclient.TelemetryBuffer
+	statsEngine            stats.Engine
+	disableResourceMetrics bool
+	lock                   sync.RWMutex
+	connected              bool
+}
+
+// newTelemetryBufferer returns a bufferer if the telemetry buffer is enabled in the config,
+// and nil otherwise
+func newTelemetryBufferer(cfg *config.Config, statsEngine stats.Engine) *telemetryBufferer {
+	if cfg.TelemetryBufferSizeMB <= 0 {
+		return nil
+	}
+	buffer, err := tcsclient.NewTelemetryBuffer(filepath.Join(cfg.DataDir, telemetryBufferDir),
+		int64(cfg.TelemetryBufferSizeMB)*bytesInMB)
+	if err != nil {
+		seelog.Warnf("Unable to create the telemetry buffer, telemetry won't be buffered while disconnected: %v", err)
+		return nil
+	}
+	return &telemetryBufferer{
+		buffer:                 buffer,
+		statsEngine:            statsEngine,
+		disableResourceMetrics: cfg.DisableMetrics.Enabled(),
+	}
+}
+
+// telemetryBuffer returns the buffer of the bufferer, which may be nil
+func (bufferer *telemetryBufferer) telemetryBuffer() *tcsclient.TelemetryBuffer {
+	if bufferer == nil {
+		return nil
+	}
+	return bufferer.buffer
+}
+
+// setConnected records whether the agent is connected to TCS
+func (bufferer *telemetryBufferer) setConnected(connected bool) {
+	if bufferer == nil {
+		return
+	}
+	bufferer.lock.Lock()
+	defer bufferer.lock.Unlock()
+	bufferer.connected = connected
+}
+
+func (bufferer *telemetryBufferer) isConnected() bool {
+	bufferer.lock.RLock()
+	defer bufferer.lock.RUnlock()
+	return bufferer.connected
+}
+
+// start buffers the telemetry on each publish interval during which the agent is not
+// connected to TCS, until the context is done
+func (bufferer *telemetryBufferer) start(ctx context.Context, publishInterval time.Duration) {
+	if bufferer == nil {
+		return
+	}
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if bufferer.isConnected() {
+				continue
+			}
+			err := tcsclient.BufferTelemetry(bufferer.statsEngine, bufferer.buffer, bufferer.disableResourceMetrics)
+			if err != nil {
+				seelog.Warnf("Unable to buffer telemetry while disconnected from TCS: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcshandler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelemetryBuffererDisabled(t *testing.T) {
+	bufferer := newTelemetryBufferer(&config.Config{}, &mockStatsEngine{})
+	assert.Nil(t, bufferer)
+	assert.Nil(t, bufferer.telemetryBuffer())
+	bufferer.setConnected(true)
+	bufferer.start(context.TODO(), time.Millisecond)
+}
+
+func TestTelemetryBuffererBuffersWhileDisconnected(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "telemetry-bufferer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	bufferer := newTelemetryBufferer(&config.Config{
+		DataDir:               dataDir,
+		TelemetryBufferSizeMB: 1,
+	}, &mockStatsEngine{})
+	require.NotNil(t, bufferer)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	bufferer.setConnected(true)
+	go bufferer.start(ctx, testPublishMetricsInterval)
+	time.Sleep(20 * testPublishMetricsInterval)
+	assert.Equal(t, 0, bufferer.telemetryBuffer().Len())
+
+	bufferer.setConnected(false)
+	for i := 0; i < 100 && bufferer.telemetryBuffer().Len() == 0; i++ {
+		time.Sleep(10 * testPublishMetricsInterval)
+	}
+	assert.NotZero(t, bufferer.telemetryBuffer().Len())
+}
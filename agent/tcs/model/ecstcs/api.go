@@ -162,6 +162,8 @@ type ContainerMetric struct {
 
 	CpuStatsSet *CWStatsSet `locationName:"cpuStatsSet" type:"structure"`
 
+	GpuMetrics []*GpuMetric `locationName:"gpuMetrics" type:"list"`
+
 	MemoryStatsSet *CWStatsSet `locationName:"memoryStatsSet" type:"structure"`
 
 	NetworkStatsSet *NetworkStatsSet `locationName:"networkStatsSet" type:"structure"`
@@ -182,6 +184,16 @@ func (s ContainerMetric) GoString() string {
 // Validate inspects the fields of the type to determine if they are valid.
 func (s *ContainerMetric) Validate() error {
 	invalidParams := request.ErrInvalidParams{Context: "ContainerMetric"}
+	if s.GpuMetrics != nil {
+		for i, v := range s.GpuMetrics {
+			if v == nil {
+				continue
+			}
+			if err := v.Validate(); err != nil {
+				invalidParams.AddNested(fmt.Sprintf("%s[%v]", "GpuMetrics", i), err.(request.ErrInvalidParams))
+			}
+		}
+	}
 	if s.NetworkStatsSet != nil {
 		if err := s.NetworkStatsSet.Validate(); err != nil {
 			invalidParams.AddNested("NetworkStatsSet", err.(request.ErrInvalidParams))
@@ -199,6 +211,43 @@ func (s *ContainerMetric) Validate() error {
 	return nil
 }
 
+type GpuMetric struct {
+	_ struct{} `type:"structure"`
+
+	GpuId *string `locationName:"gpuId" type:"string"`
+
+	MemoryUsedStatsSet *ULongStatsSet `locationName:"memoryUsedStatsSet" type:"structure"`
+
+	TemperatureStatsSet *CWStatsSet `locationName:"temperatureStatsSet" type:"structure"`
+
+	UtilizationStatsSet *CWStatsSet `locationName:"utilizationStatsSet" type:"structure"`
+}
+
+// String returns the string representation
+func (s GpuMetric) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s GpuMetric) GoString() string {
+	return s.String()
+}
+
+// Validate inspects the fields of the type to determine if they are valid.
+func (s *GpuMetric) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "GpuMetric"}
+	if s.MemoryUsedStatsSet != nil {
+		if err := s.MemoryUsedStatsSet.Validate(); err != nil {
+			invalidParams.AddNested("MemoryUsedStatsSet", err.(request.ErrInvalidParams))
+		}
+	}
+
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
 type HealthMetadata struct {
 	_ struct{} `type:"structure"`
 
@@ -753,8 +802,16 @@ func (s StopTelemetrySessionMessage) GoString() string {
 type StorageStatsSet struct {
 	_ struct{} `type:"structure"`
 
+	BindMountsSizeBytes *ULongStatsSet `locationName:"bindMountsSizeBytes" type:"structure"`
+
+	ReadOps *ULongStatsSet `locationName:"readOps" type:"structure"`
+
 	ReadSizeBytes *ULongStatsSet `locationName:"readSizeBytes" type:"structure"`
 
+	WritableLayerSizeBytes *ULongStatsSet `locationName:"writableLayerSizeBytes" type:"structure"`
+
+	WriteOps *ULongStatsSet `locationName:"writeOps" type:"structure"`
+
 	WriteSizeBytes *ULongStatsSet `locationName:"writeSizeBytes" type:"structure"`
 }
 
@@ -771,11 +828,31 @@ func (s StorageStatsSet) GoString() string {
 // Validate inspects the fields of the type to determine if they are valid.
 func (s *StorageStatsSet) Validate() error {
 	invalidParams := request.ErrInvalidParams{Context: "StorageStatsSet"}
+	if s.BindMountsSizeBytes != nil {
+		if err := s.BindMountsSizeBytes.Validate(); err != nil {
+			invalidParams.AddNested("BindMountsSizeBytes", err.(request.ErrInvalidParams))
+		}
+	}
+	if s.ReadOps != nil {
+		if err := s.ReadOps.Validate(); err != nil {
+			invalidParams.AddNested("ReadOps", err.(request.ErrInvalidParams))
+		}
+	}
 	if s.ReadSizeBytes != nil {
 		if err := s.ReadSizeBytes.Validate(); err != nil {
 			invalidParams.AddNested("ReadSizeBytes", err.(request.ErrInvalidParams))
 		}
 	}
+	if s.WritableLayerSizeBytes != nil {
+		if err := s.WritableLayerSizeBytes.Validate(); err != nil {
+			invalidParams.AddNested("WritableLayerSizeBytes", err.(request.ErrInvalidParams))
+		}
+	}
+	if s.WriteOps != nil {
+		if err := s.WriteOps.Validate(); err != nil {
+			invalidParams.AddNested("WriteOps", err.(request.ErrInvalidParams))
+		}
+	}
 	if s.WriteSizeBytes != nil {
 		if err := s.WriteSizeBytes.Validate(); err != nil {
 			invalidParams.AddNested("WriteSizeBytes", err.(request.ErrInvalidParams))
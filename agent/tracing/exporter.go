@@ -0,0 +1,220 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/version"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// tracesPath is the path of the OTLP/HTTP traces endpoint
+	tracesPath = "/v1/traces"
+	// maxQueuedSpans is the maximum number of spans waiting to be exported, the
+	// spans ended when the queue is full are dropped
+	maxQueuedSpans = 2048
+	// maxExportBatchSize is the maximum number of spans exported in one request
+	maxExportBatchSize = 256
+	// exportInterval is the interval at which the queued spans are exported
+	exportInterval = 5 * time.Second
+	// exportTimeout is the timeout of the export requests
+	exportTimeout = 10 * time.Second
+
+	instrumentationScope = "github.com/aws/amazon-ecs-agent/agent"
+
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+)
+
+// Tracer queues the ended spans and exports them in batches
+type Tracer struct {
+	endpoint string
+	resource []Attribute
+	spans    chan *Span
+	client   *http.Client
+}
+
+func newTracer(endpoint string, resource []Attribute) *Tracer {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(endpoint, tracesPath) {
+		endpoint += tracesPath
+	}
+	return &Tracer{
+		endpoint: endpoint,
+		resource: resource,
+		spans:    make(chan *Span, maxQueuedSpans),
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+}
+
+// export queues an ended span, without ever blocking the traced operation
+func (tracer *Tracer) export(span *Span) {
+	select {
+	case tracer.spans <- span:
+	default:
+		seelog.Debugf("Dropping span %s, the export queue is full", span.name)
+	}
+}
+
+// run exports the queued spans until the context is cancelled
+func (tracer *Tracer) run(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	for {
+		select {
+		case span := <-tracer.spans:
+			batch = append(batch, span)
+			if len(batch) >= maxExportBatchSize {
+				tracer.exportBatch(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				tracer.exportBatch(batch)
+				batch = nil
+			}
+		case <-ctx.Done():
+			// export the spans ended before the agent stopped
+			for len(tracer.spans) > 0 {
+				batch = append(batch, <-tracer.spans)
+			}
+			if len(batch) > 0 {
+				tracer.exportBatch(batch)
+			}
+			return
+		}
+	}
+}
+
+func (tracer *Tracer) exportBatch(batch []*Span) {
+	if err := tracer.send(batch); err != nil {
+		seelog.Warnf("Unable to export %d spans to %s: %v", len(batch), tracer.endpoint, err)
+	}
+}
+
+// send sends the spans to the collector, with the JSON encoding of OTLP/HTTP
+func (tracer *Tracer) send(batch []*Span) error {
+	body, err := json.Marshal(tracer.exportRequest(batch))
+	if err != nil {
+		return err
+	}
+	response, err := tracer.client.Post(tracer.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("status %d: %s", response.StatusCode, responseBody)
+	}
+	return nil
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (tracer *Tracer) exportRequest(batch []*Span) *otlpExportRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		status := otlpStatus{Code: statusCodeOk}
+		if span.err != nil {
+			status = otlpStatus{Code: statusCodeError, Message: span.err.Error()}
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:           hex.EncodeToString(span.traceID[:]),
+			SpanID:            hex.EncodeToString(span.spanID[:]),
+			Name:              span.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attributes),
+			Status:            status,
+		})
+	}
+	return &otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: otlpAttributes(tracer.resource)},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: instrumentationScope, Version: version.Version},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attributes []Attribute) []otlpAttribute {
+	otlp := make([]otlpAttribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		otlp = append(otlp, otlpAttribute{Key: attribute.Key, Value: otlpAnyValue{StringValue: attribute.Value}})
+	}
+	return otlp
+}
@@ -0,0 +1,173 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tracing exports spans of the agent operations to an OpenTelemetry
+// collector with the OTLP/HTTP protocol, so that operators can correlate the
+// operations of a task, like image pulls and container starts, across their fleet.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/version"
+)
+
+const (
+	// TaskARNKey is the attribute key of the task ARN
+	TaskARNKey = "aws.ecs.task.arn"
+	// ContainerNameKey is the attribute key of the container name
+	ContainerNameKey = "container.name"
+	// ImageKey is the attribute key of the container image
+	ImageKey = "container.image.name"
+	// MessageIDKey is the attribute key of the ID of an ACS message
+	MessageIDKey = "aws.ecs.acs.message_id"
+	// TaskCountKey is the attribute key of the number of tasks of an ACS message
+	TaskCountKey = "aws.ecs.acs.task_count"
+	// EventTypeKey is the attribute key of the type of a state change
+	EventTypeKey = "aws.ecs.state_change.type"
+
+	serviceName = "ecs-agent"
+)
+
+var (
+	globalTracer *Tracer
+	tracerLock   sync.RWMutex
+)
+
+// Attribute is a string attribute of a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String returns a string attribute
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an agent operation being traced. The methods of a nil span do nothing,
+// so that the operations are traced the same way whether tracing is enabled or not.
+type Span struct {
+	name       string
+	traceID    [16]byte
+	spanID     [8]byte
+	start      time.Time
+	end        time.Time
+	attributes []Attribute
+	err        error
+	tracer     *Tracer
+}
+
+// Init starts exporting the spans to the OTLP/HTTP endpoint of the configuration,
+// until the context is cancelled. Tracing is disabled if no endpoint is configured.
+func Init(ctx context.Context, cfg *config.Config) {
+	if cfg.OTelExporterEndpoint == "" {
+		return
+	}
+	resource := []Attribute{
+		String("service.name", serviceName),
+		String("service.version", version.Version),
+	}
+	if cfg.Cluster != "" {
+		resource = append(resource, String("aws.ecs.cluster.arn", cfg.Cluster))
+	}
+	tracer := newTracer(cfg.OTelExporterEndpoint, resource)
+	go tracer.run(ctx)
+
+	tracerLock.Lock()
+	defer tracerLock.Unlock()
+	globalTracer = tracer
+}
+
+func getGlobalTracer() *Tracer {
+	tracerLock.RLock()
+	defer tracerLock.RUnlock()
+	return globalTracer
+}
+
+// StartSpan starts the span of an agent operation in a new trace, or returns nil if
+// tracing is disabled
+func StartSpan(name string, attributes ...Attribute) *Span {
+	tracer := getGlobalTracer()
+	if tracer == nil {
+		return nil
+	}
+	span := newSpan(tracer, name, attributes)
+	rand.Read(span.traceID[:])
+	return span
+}
+
+// StartTaskSpan starts the span of an operation of a task, or returns nil if tracing
+// is disabled. The trace ID is derived from the task ARN, so that all the operations
+// of a task share the same trace.
+func StartTaskSpan(taskARN string, name string, attributes ...Attribute) *Span {
+	tracer := getGlobalTracer()
+	if tracer == nil {
+		return nil
+	}
+	span := newSpan(tracer, name, append([]Attribute{String(TaskARNKey, taskARN)}, attributes...))
+	span.traceID = taskTraceID(taskARN)
+	return span
+}
+
+func newSpan(tracer *Tracer, name string, attributes []Attribute) *Span {
+	span := &Span{
+		name:       name,
+		start:      time.Now(),
+		attributes: attributes,
+		tracer:     tracer,
+	}
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// taskTraceID returns the trace ID of the operations of a task
+func taskTraceID(taskARN string) [16]byte {
+	var traceID [16]byte
+	hash := sha256.Sum256([]byte(taskARN))
+	copy(traceID[:], hash[:])
+	return traceID
+}
+
+// SetAttributes adds attributes to the span
+func (span *Span) SetAttributes(attributes ...Attribute) {
+	if span == nil {
+		return
+	}
+	span.attributes = append(span.attributes, attributes...)
+}
+
+// End ends the span, with the error of the operation if it failed, and queues it
+// for export
+func (span *Span) End(err error) {
+	if span == nil {
+		return
+	}
+	span.end = time.Now()
+	span.err = err
+	span.tracer.export(span)
+}
+
+// TraceID returns the hex encoded trace ID of the span
+func (span *Span) TraceID() string {
+	if span == nil {
+		return ""
+	}
+	return hex.EncodeToString(span.traceID[:])
+}
@@ -0,0 +1,127 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracingDisabled(t *testing.T) {
+	Init(context.Background(), &config.Config{})
+	span := StartTaskSpan("arn:aws:ecs:us-west-2:123456789012:task/cluster/id", "PullImage")
+	assert.Nil(t, span)
+	// the methods of a nil span do nothing
+	span.SetAttributes(String(ContainerNameKey, "app"))
+	span.End(errors.New("pull failed"))
+	assert.Empty(t, span.TraceID())
+}
+
+func TestTaskSpansShareTrace(t *testing.T) {
+	tracer := newTracer("http://localhost:4318", nil)
+	setGlobalTracer(t, tracer)
+
+	taskARN := "arn:aws:ecs:us-west-2:123456789012:task/cluster/id"
+	pull := StartTaskSpan(taskARN, "PullImage")
+	start := StartTaskSpan(taskARN, "StartContainer")
+	other := StartTaskSpan("arn:aws:ecs:us-west-2:123456789012:task/cluster/other", "StartContainer")
+	payload := StartSpan("HandleACSPayload")
+	assert.Equal(t, pull.TraceID(), start.TraceID())
+	assert.NotEqual(t, pull.spanID, start.spanID)
+	assert.NotEqual(t, pull.TraceID(), other.TraceID())
+	assert.NotEqual(t, pull.TraceID(), payload.TraceID())
+	assert.Equal(t, []Attribute{{Key: TaskARNKey, Value: taskARN}}, pull.attributes)
+}
+
+func TestExportSpans(t *testing.T) {
+	requests := make(chan otlpExportRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var request otlpExportRequest
+		require.NoError(t, json.Unmarshal(body, &request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	setGlobalTracer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	Init(ctx, &config.Config{OTelExporterEndpoint: server.URL, Cluster: "default"})
+
+	taskARN := "arn:aws:ecs:us-west-2:123456789012:task/default/id"
+	span := StartTaskSpan(taskARN, "StartContainer", String(ContainerNameKey, "app"))
+	span.End(errors.New("container start failed"))
+	cancel()
+
+	var request otlpExportRequest
+	select {
+	case request = <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the spans to be exported")
+	}
+	require.Len(t, request.ResourceSpans, 1)
+	assert.Contains(t, request.ResourceSpans[0].Resource.Attributes,
+		otlpAttribute{Key: "service.name", Value: otlpAnyValue{StringValue: serviceName}})
+	assert.Contains(t, request.ResourceSpans[0].Resource.Attributes,
+		otlpAttribute{Key: "aws.ecs.cluster.arn", Value: otlpAnyValue{StringValue: "default"}})
+	require.Len(t, request.ResourceSpans[0].ScopeSpans, 1)
+	require.Len(t, request.ResourceSpans[0].ScopeSpans[0].Spans, 1)
+	exported := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "StartContainer", exported.Name)
+	assert.Equal(t, span.TraceID(), exported.TraceID)
+	assert.Len(t, exported.SpanID, 16)
+	assert.Equal(t, []otlpAttribute{
+		{Key: TaskARNKey, Value: otlpAnyValue{StringValue: taskARN}},
+		{Key: ContainerNameKey, Value: otlpAnyValue{StringValue: "app"}},
+	}, exported.Attributes)
+	assert.Equal(t, otlpStatus{Code: statusCodeError, Message: "container start failed"}, exported.Status)
+	assert.NotEmpty(t, exported.StartTimeUnixNano)
+	assert.NotEmpty(t, exported.EndTimeUnixNano)
+}
+
+func TestExportQueueFull(t *testing.T) {
+	tracer := newTracer("http://localhost:4318/v1/traces", nil)
+	assert.Equal(t, "http://localhost:4318/v1/traces", tracer.endpoint)
+	for i := 0; i < maxQueuedSpans+1; i++ {
+		// ending a span never blocks, even when the spans aren't exported
+		(&Span{name: "StartContainer", tracer: tracer}).End(nil)
+	}
+	assert.Len(t, tracer.spans, maxQueuedSpans)
+}
+
+func setGlobalTracer(t *testing.T, tracer *Tracer) {
+	tracerLock.Lock()
+	defer tracerLock.Unlock()
+	previous := globalTracer
+	globalTracer = tracer
+	t.Cleanup(func() {
+		tracerLock.Lock()
+		defer tracerLock.Unlock()
+		globalTracer = previous
+	})
+}
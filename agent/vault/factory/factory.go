@@ -0,0 +1,44 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package factory
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+	"github.com/aws/amazon-ecs-agent/agent/vault"
+)
+
+const (
+	roundtripTimeout = 5 * time.Second
+)
+
+// ClientCreator logs in to Vault with the credentials of tasks
+type ClientCreator interface {
+	NewVaultClient(creds credentials.IAMRoleCredentials) (vault.Client, error)
+}
+
+// NewClientCreator returns a ClientCreator logging in to the configured Vault server
+func NewClientCreator(cfg vault.Config) ClientCreator {
+	return &vaultClientCreator{cfg: cfg}
+}
+
+type vaultClientCreator struct {
+	cfg vault.Config
+}
+
+func (creator *vaultClientCreator) NewVaultClient(creds credentials.IAMRoleCredentials) (vault.Client, error) {
+	return vault.Login(creator.cfg, creds, httpclient.New(roundtripTimeout, false))
+}
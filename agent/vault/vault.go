@@ -0,0 +1,278 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package vault retrieves secrets from the KV version 2 secrets engine of HashiCorp Vault,
+// logging in with the AWS auth method of Vault
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/aws-sdk-go/aws"
+	awscreds "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultAuthMount is the path the AWS auth method is mounted at by default
+	DefaultAuthMount = "aws"
+
+	tokenHeader       = "X-Vault-Token"
+	namespaceHeader   = "X-Vault-Namespace"
+	iamServerIDHeader = "X-Vault-AWS-IAM-Server-ID"
+	// stsRegion is the region of the global STS endpoint, which Vault validates the
+	// signed GetCallerIdentity requests against by default
+	stsRegion = "us-east-1"
+)
+
+// Config is the Vault server the secrets are read from, and how to log in to it
+type Config struct {
+	// Address is the URL of the Vault server, such as https://vault.example.com:8200
+	Address string
+	// Namespace is the Vault Enterprise namespace of the secrets, if any
+	Namespace string
+	// AuthMount is the path the AWS auth method is mounted at
+	AuthMount string
+	// AuthRole is the Vault role to log in with. Vault defaults to the name of the IAM
+	// role of the credentials when it's empty
+	AuthRole string
+	// IAMServerID is the value of the server ID header the AWS auth method requires,
+	// if it's configured to
+	IAMServerID string
+}
+
+// Client reads secrets from Vault
+type Client interface {
+	// ReadKVSecret returns the data of the version of the secret stored at the path of
+	// the KV version 2 secrets engine mounted at mount. The current version is read when
+	// version is 0.
+	ReadKVSecret(mount, path string, version int) (map[string]interface{}, error)
+	// RevokeSelf revokes the token of the client, which can't be used afterwards
+	RevokeSelf() error
+}
+
+// SecretReference is a secret of a KV version 2 secrets engine, referenced by the
+// valueFrom of a container secret as mount/path[?version=n][#key]
+type SecretReference struct {
+	Mount   string
+	Path    string
+	Version int
+	// Key is the key of the data of the secret whose value is used. The whole data is
+	// used, encoded as JSON, when it's empty
+	Key string
+}
+
+// ParseSecretReference parses the valueFrom of a container secret. The first element of
+// the path is the mount of the secrets engine.
+func ParseSecretReference(valueFrom string) (SecretReference, error) {
+	u, err := url.Parse(valueFrom)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return SecretReference{}, errors.Errorf("invalid vault secret %q, expected mount/path[?version=n][#key]", valueFrom)
+	}
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return SecretReference{}, errors.Errorf("invalid vault secret %q, expected mount/path[?version=n][#key]", valueFrom)
+	}
+	ref := SecretReference{
+		Mount: parts[0],
+		Path:  parts[1],
+		Key:   u.Fragment,
+	}
+	if version := u.Query().Get("version"); version != "" {
+		ref.Version, err = strconv.Atoi(version)
+		if err != nil || ref.Version < 0 {
+			return SecretReference{}, errors.Errorf("invalid version %q of vault secret %q", version, valueFrom)
+		}
+	}
+	return ref, nil
+}
+
+// GetSecretValue returns the value of the key of the data of a secret, or the data
+// encoded as JSON when no key is given
+func GetSecretValue(data map[string]interface{}, key string) (string, error) {
+	if key == "" {
+		encoded, err := json.Marshal(data)
+		return string(encoded), err
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", errors.Errorf("secret retrieved from vault did not contain key %s", key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+type httpClient struct {
+	cfg    Config
+	token  string
+	client *http.Client
+}
+
+// Login logs in to Vault with the AWS auth method, by sending it a GetCallerIdentity
+// request signed with the credentials, and returns a client using the resulting token
+func Login(cfg Config, creds credentials.IAMRoleCredentials, client *http.Client) (Client, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("vault address is not configured")
+	}
+	if cfg.AuthMount == "" {
+		cfg.AuthMount = DefaultAuthMount
+	}
+	loginData, err := generateLoginData(cfg, creds)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to sign vault login request")
+	}
+	body, err := json.Marshal(loginData)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &httpClient{cfg: cfg, client: client}
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := c.do(http.MethodPost, fmt.Sprintf("auth/%s/login", strings.Trim(cfg.AuthMount, "/")), nil, body, &resp); err != nil {
+		return nil, errors.Wrap(err, "unable to log in to vault")
+	}
+	if resp.Auth.ClientToken == "" {
+		return nil, errors.New("unable to log in to vault: no token returned")
+	}
+	c.token = resp.Auth.ClientToken
+	return c, nil
+}
+
+// generateLoginData returns the login parameters of the IAM method of the AWS auth
+// method: a GetCallerIdentity request signed with the credentials, which Vault sends
+// to STS to authenticate the caller
+func generateLoginData(cfg Config, creds credentials.IAMRoleCredentials) (map[string]string, error) {
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion(stsRegion).
+		WithCredentials(awscreds.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)))
+	if err != nil {
+		return nil, err
+	}
+	req, _ := sts.New(sess).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	if cfg.IAMServerID != "" {
+		req.HTTPRequest.Header.Add(iamServerIDHeader, cfg.IAMServerID)
+	}
+	if err := req.Sign(); err != nil {
+		return nil, err
+	}
+	headers, err := json.Marshal(req.HTTPRequest.Header)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := ioutil.ReadAll(req.HTTPRequest.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	loginData := map[string]string{
+		"iam_http_request_method": req.HTTPRequest.Method,
+		"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(req.HTTPRequest.URL.String())),
+		"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+		"iam_request_body":        base64.StdEncoding.EncodeToString(requestBody),
+	}
+	if cfg.AuthRole != "" {
+		loginData["role"] = cfg.AuthRole
+	}
+	return loginData, nil
+}
+
+// ReadKVSecret reads the secret with the KV version 2 API
+func (c *httpClient) ReadKVSecret(mount, path string, version int) (map[string]interface{}, error) {
+	query := url.Values{}
+	if version > 0 {
+		query.Set("version", strconv.Itoa(version))
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := c.do(http.MethodGet, fmt.Sprintf("%s/data/%s", strings.Trim(mount, "/"), strings.Trim(path, "/")), query, nil, &resp); err != nil {
+		return nil, errors.Wrapf(err, "secret %s/%s", mount, path)
+	}
+	if resp.Data.Data == nil {
+		return nil, errors.Errorf("secret %s/%s has no data", mount, path)
+	}
+	return resp.Data.Data, nil
+}
+
+// RevokeSelf revokes the token the client logged in with
+func (c *httpClient) RevokeSelf() error {
+	if err := c.do(http.MethodPost, "auth/token/revoke-self", nil, nil, nil); err != nil {
+		return errors.Wrap(err, "unable to revoke vault token")
+	}
+	return nil
+}
+
+// do sends a request to the Vault API and decodes its response into out, unless the
+// response has no content
+func (c *httpClient) do(method, path string, query url.Values, body []byte, out interface{}) error {
+	u := strings.TrimRight(c.cfg.Address, "/") + "/v1/" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set(tokenHeader, c.token)
+	}
+	if c.cfg.Namespace != "" {
+		req.Header.Set(namespaceHeader, c.cfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return errors.Errorf("vault responded with status %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+		return errors.Errorf("vault responded with status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,157 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCreds = credentials.IAMRoleCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "secret",
+	SessionToken:    "session",
+}
+
+func TestParseSecretReference(t *testing.T) {
+	testCases := []struct {
+		valueFrom string
+		ref       SecretReference
+		err       bool
+	}{
+		{valueFrom: "secret/db", ref: SecretReference{Mount: "secret", Path: "db"}},
+		{valueFrom: "secret/team/db#password", ref: SecretReference{Mount: "secret", Path: "team/db", Key: "password"}},
+		{valueFrom: "kv/db?version=3#user", ref: SecretReference{Mount: "kv", Path: "db", Version: 3, Key: "user"}},
+		{valueFrom: "secret", err: true},
+		{valueFrom: "secret/", err: true},
+		{valueFrom: "https://vault/secret/db", err: true},
+		{valueFrom: "secret/db?version=latest", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.valueFrom, func(t *testing.T) {
+			ref, err := ParseSecretReference(tc.valueFrom)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ref, ref)
+		})
+	}
+}
+
+func TestGetSecretValue(t *testing.T) {
+	data := map[string]interface{}{
+		"password": "hunter2",
+		"port":     float64(5432),
+	}
+
+	value, err := GetSecretValue(data, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	value, err = GetSecretValue(data, "port")
+	require.NoError(t, err)
+	assert.Equal(t, "5432", value)
+
+	value, err = GetSecretValue(data, "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"password":"hunter2","port":5432}`, value)
+
+	_, err = GetSecretValue(data, "user")
+	assert.Error(t, err)
+}
+
+func TestLoginAndReadKVSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "team", r.Header.Get(namespaceHeader))
+		switch r.URL.Path {
+		case "/v1/auth/aws-ecs/login":
+			assert.Equal(t, http.MethodPost, r.Method)
+			var loginData map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&loginData))
+			assert.Equal(t, "ecs-task", loginData["role"])
+			assert.Equal(t, http.MethodPost, loginData["iam_http_request_method"])
+			url, err := base64.StdEncoding.DecodeString(loginData["iam_request_url"])
+			require.NoError(t, err)
+			assert.Equal(t, "https://sts.amazonaws.com/", string(url))
+			headers, err := base64.StdEncoding.DecodeString(loginData["iam_request_headers"])
+			require.NoError(t, err)
+			var signedHeaders http.Header
+			require.NoError(t, json.Unmarshal(headers, &signedHeaders))
+			assert.Equal(t, "vault.example.com", signedHeaders.Get(iamServerIDHeader))
+			assert.Contains(t, signedHeaders.Get("Authorization"), "Credential=AKIDEXAMPLE/")
+			w.Write([]byte(`{"auth":{"client_token":"token"}}`))
+		case "/v1/secret/data/db":
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Equal(t, "token", r.Header.Get(tokenHeader))
+			assert.Equal(t, "2", r.URL.Query().Get("version"))
+			w.Write([]byte(`{"data":{"data":{"password":"hunter2"},"metadata":{"version":2}}}`))
+		case "/v1/auth/token/revoke-self":
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "token", r.Header.Get(tokenHeader))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := Login(Config{
+		Address:     server.URL,
+		Namespace:   "team",
+		AuthMount:   "aws-ecs",
+		AuthRole:    "ecs-task",
+		IAMServerID: "vault.example.com",
+	}, testCreds, server.Client())
+	require.NoError(t, err)
+
+	data, err := client.ReadKVSecret("secret", "db", 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"password": "hunter2"}, data)
+
+	_, err = client.ReadKVSecret("secret", "missing", 0)
+	assert.Error(t, err)
+
+	assert.NoError(t, client.RevokeSelf())
+}
+
+func TestLoginError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/auth/aws/login", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":["entry for role ecs-task not found"]}`))
+	}))
+	defer server.Close()
+
+	_, err := Login(Config{Address: server.URL}, testCreds, server.Client())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "entry for role ecs-task not found")
+}
+
+func TestLoginWithoutAddress(t *testing.T) {
+	_, err := Login(Config{}, testCreds, http.DefaultClient)
+	assert.Error(t, err)
+}
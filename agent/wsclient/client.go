@@ -168,8 +168,15 @@ func (cs *ClientServerImpl) Connect() error {
 	}
 
 	timeoutDialer := &net.Dialer{Timeout: wsConnectTimeout}
-	tlsConfig := &tls.Config{ServerName: parsedURL.Host, InsecureSkipVerify: cs.AgentConfig.AcceptInsecureCert}
+	tlsConfig := &tls.Config{ServerName: parsedURL.Hostname(), InsecureSkipVerify: cs.AgentConfig.AcceptInsecureCert}
 	cipher.WithSupportedCipherSuites(tlsConfig)
+	if cs.AgentConfig.ACSCABundle != "" {
+		rootCAs, err := loadCABundle(cs.AgentConfig.ACSCABundle)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
 
 	// Ensure that NO_PROXY gets set
 	noProxy := os.Getenv("NO_PROXY")
@@ -188,7 +195,7 @@ func (cs *ClientServerImpl) Connect() error {
 		ReadBufferSize:   readBufSize,
 		WriteBufferSize:  writeBufSize,
 		TLSClientConfig:  tlsConfig,
-		Proxy:            http.ProxyFromEnvironment,
+		Proxy:            proxyFromEnvironment,
 		NetDial:          timeoutDialer.Dial,
 		HandshakeTimeout: wsHandshakeTimeout,
 	}
@@ -0,0 +1,129 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package wsclient
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// proxyFromEnvironment returns the proxy to connect to the backend through, from the
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables or their lower case
+// versions. Unlike http.ProxyFromEnvironment, the variables are read on every
+// connection, so that the NO_PROXY default set by the client is always honored.
+// The websocket dialer only tunnels through http proxies.
+func proxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	var proxy string
+	if req.URL.Scheme == "https" {
+		proxy = getEnvAny("HTTPS_PROXY", "https_proxy")
+	} else {
+		proxy = getEnvAny("HTTP_PROXY", "http_proxy")
+	}
+	if proxy == "" || !useProxy(req.URL.Hostname(), getEnvAny("NO_PROXY", "no_proxy")) {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		// The proxy may be set without a scheme, such as "proxy:3128"
+		proxyURL, err = url.Parse("http://" + proxy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "websocket client: invalid proxy address %s", proxy)
+		}
+	}
+	if proxyURL.Scheme != "http" {
+		return nil, errors.Errorf("websocket client: unsupported proxy scheme %s, only http proxies are supported",
+			proxyURL.Scheme)
+	}
+	return proxyURL, nil
+}
+
+// useProxy returns false if the host is a loopback address or matches one of the
+// comma separated entries of noProxy. An entry can be "*", an IP address, a CIDR
+// block or a domain name, which also matches its subdomains.
+func useProxy(host, noProxy string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return false
+	}
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return false
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return false
+			}
+			continue
+		}
+		if entryHost, _, err := net.SplitHostPort(entry); err == nil {
+			entry = entryHost
+		}
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return false
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// loadCABundle returns the system root CAs along with the CAs of the PEM bundle at path,
+// such as the CA of a TLS intercepting proxy
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		seelog.Warnf("Unable to load the system root CAs, only the CAs of %s will be trusted: %v", path, err)
+		pool = x509.NewCertPool()
+	}
+	bundle, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "websocket client: unable to read CA bundle %s", path)
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.Errorf("websocket client: no certificate found in CA bundle %s", path)
+	}
+	return pool, nil
+}